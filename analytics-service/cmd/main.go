@@ -13,21 +13,35 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"analytics-service/internal/cache"
 	"analytics-service/internal/config"
+	"analytics-service/internal/events"
 	"analytics-service/internal/handlers"
 	"analytics-service/internal/integrations"
 	"analytics-service/internal/middleware"
 	"analytics-service/internal/repository"
 	"analytics-service/internal/service"
+	"analytics-service/internal/settings"
+	"analytics-service/internal/tracing"
+	"shared/jobs"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
+	// Set up tracing before anything opens connections, so they are instrumented
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
 	// Connect to MongoDB
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -49,36 +63,174 @@ func main() {
 		log.Printf("Warning: Failed to create indexes: %v", err)
 	}
 
-	// Get collections
+	// Get collections; aggregations read reporting collections, which may be served by secondaries
 	collections := mongoDB.GetCollections()
+	reportingCollections := mongoDB.GetReportingCollections()
 
 	// Initialize repositories
 	reportRepo := repository.NewReportRepository(collections.Reports)
-	anomalyRepo := repository.NewAnomalyRepository(collections.Anomalies)
-	timeSeriesRepo := repository.NewTimeSeriesRepository(collections.TimeSeries)
-	kpiRepo := repository.NewKPIRepository(collections.KPIs)
+	anomalyRepo := repository.NewAnomalyRepository(collections.Anomalies, reportingCollections.Anomalies)
+	timeSeriesRepo := repository.NewTimeSeriesRepository(collections.TimeSeries, reportingCollections.TimeSeries, mongoDB.QueryHints())
+	kpiRepo := repository.NewKPIRepository(collections.KPIs, reportingCollections.KPIs)
+	executionRepo := repository.NewOptimizationExecutionRepository(collections.OptimizationExecutions)
+	budgetRepo := repository.NewBudgetRepository(collections.Budgets)
+	savingsGoalRepo := repository.NewSavingsGoalRepository(collections.SavingsGoals)
+	kpiDefinitionRepo := repository.NewKPIDefinitionRepository(collections.KPIDefinitions)
+	buildingAttributesRepo := repository.NewBuildingAttributesRepository(collections.BuildingAttributes)
+	trendAlertRepo := repository.NewTrendAlertRepository(collections.TrendAlerts)
+	mvReportRepo := repository.NewMVReportRepository(collections.MVReports)
+	activityRepo := repository.NewDeviceActivityRepository(collections.DeviceActivities)
+	detectorConfigRepo := repository.NewDetectorConfigRepository(collections.DetectorConfigs)
+	shadowAnomalyRepo := repository.NewShadowAnomalyRepository(collections.ShadowAnomalies)
 
 	// Initialize external integrations
 	securityClient := integrations.NewSecurityClient(cfg)
 	iotClient := integrations.NewIoTClient(cfg)
 	forecastClient := integrations.NewForecastClient(cfg)
 
+	// Connect to the inter-service event bus
+	var eventBus *events.Bus
+	if cfg.Events.Enabled {
+		eventBus = events.NewBus(cfg, "analytics-service")
+		defer eventBus.Close()
+	}
+
+	// Runtime settings: stored overrides are loaded once every tunable is registered
+	settingsStore := settings.NewStore(collections.Settings, collections.SettingsHistory)
+
+	// Persistent job queue shared by asynchronous work
+	jobQueue := jobs.NewQueue(collections.Jobs, "analytics-service", cfg.Auth.ServiceSecret, cfg.Jobs.PollInterval)
+
+	// Optional Redis cache for hot reads; without an address every lookup misses
+	hotCache := cache.New(cache.Config{
+		Addr:     cfg.Cache.RedisAddr,
+		Password: cfg.Cache.RedisPassword,
+		DB:       cfg.Cache.RedisDB,
+		Prefix:   "analytics-service",
+		Timeout:  cfg.Cache.Timeout,
+		TTLs: map[string]time.Duration{
+			service.BuildingDashboardCacheKind: cfg.Cache.BuildingDashboardTTL,
+			service.OverviewDashboardCacheKind: cfg.Cache.OverviewDashboardTTL,
+		},
+	})
+	defer hotCache.Close()
+
 	// Initialize services
-	reportService := service.NewReportService(reportRepo, iotClient, forecastClient)
-	anomalyService := service.NewAnomalyService(anomalyRepo, iotClient)
-	timeSeriesService := service.NewTimeSeriesService(timeSeriesRepo, iotClient)
-	kpiService := service.NewKPIService(kpiRepo, anomalyRepo, iotClient)
-	dashboardService := service.NewDashboardService(anomalyRepo, kpiRepo, iotClient, forecastClient)
+	weatherNormalizer := service.NewWeatherNormalizer(timeSeriesRepo, forecastClient)
+	reportService := service.NewReportService(reportRepo, iotClient, forecastClient, securityClient, weatherNormalizer, jobQueue)
+	var rootCauseAnalyzer *service.RootCauseAnalyzer
+	if cfg.Analytics.RootCauseEnabled {
+		rootCauseAnalyzer = service.NewRootCauseAnalyzer(activityRepo, executionRepo, forecastClient, cfg.Analytics.RootCauseLookback)
+	}
+	detectorService := service.NewDetectorService(detectorConfigRepo, shadowAnomalyRepo, anomalyRepo, cfg.Analytics.ForecastDeviationIntervals)
+	anomalyService := service.NewAnomalyService(anomalyRepo, timeSeriesRepo, iotClient, forecastClient, eventBus, hotCache, rootCauseAnalyzer, detectorService)
+	timeSeriesService := service.NewTimeSeriesService(timeSeriesRepo, anomalyRepo, executionRepo, iotClient)
+	kpiService := service.NewKPIService(kpiRepo, anomalyRepo, timeSeriesRepo, trendAlertRepo, kpiDefinitionRepo, buildingAttributesRepo, iotClient, weatherNormalizer, eventBus)
+	dashboardService := service.NewDashboardService(anomalyRepo, kpiRepo, timeSeriesRepo, executionRepo, savingsGoalRepo, iotClient, forecastClient, hotCache)
+	graphQLService := service.NewGraphQLService(anomalyService, kpiService, timeSeriesService, iotClient, forecastClient)
+	budgetService := service.NewBudgetService(budgetRepo, timeSeriesRepo, forecastClient, eventBus)
+	savingsGoalService := service.NewSavingsGoalService(savingsGoalRepo, timeSeriesRepo, forecastClient, eventBus)
+	costService := service.NewCostService(timeSeriesRepo, forecastClient)
+	mvService := service.NewMVService(mvReportRepo, executionRepo, timeSeriesRepo, forecastClient, eventBus)
+
+	// Start background workers
+	workerCtx, workerCancel := context.WithCancel(context.Background())
+	defer workerCancel()
+	go jobQueue.Start(workerCtx, cfg.Jobs.Workers)
+
+	// Initialize data retention
+	retentionService := service.NewRetentionService(cfg.Retention.DryRun, cfg.Retention.Interval)
+	retentionService.Register("reports", cfg.Retention.Reports, reportRepo.CountOlderThan, reportRepo.DeleteOlderThan)
+	retentionService.Register("anomalies", cfg.Retention.Anomalies, anomalyRepo.CountOlderThan, anomalyRepo.DeleteOlderThan)
+	retentionService.Register("shadow_anomalies", cfg.Retention.Anomalies, shadowAnomalyRepo.CountOlderThan, shadowAnomalyRepo.DeleteOlderThan)
+	retentionDryRun := settingsStore.RegisterBool("retention.dry_run",
+		"Only count the records retention would purge", cfg.Retention.DryRun)
+	retentionDryRun.OnChange(func() { retentionService.SetDryRun(retentionDryRun.Get()) })
+	for collection, retention := range map[string]time.Duration{
+		"reports":          cfg.Retention.Reports,
+		"anomalies":        cfg.Retention.Anomalies,
+		"shadow_anomalies": cfg.Retention.Anomalies,
+	} {
+		collection := collection
+		days := settingsStore.RegisterInt("retention."+collection+"_days",
+			"Days "+collection+" records are kept; 0 disables purging", int(retention.Hours()/24), 0, 3650)
+		days.OnChange(func() { retentionService.SetRetention(collection, time.Duration(days.Get())*24*time.Hour) })
+	}
+
+	// Apply stored settings, then pick up changes made by other instances
+	if err := settingsStore.Load(ctx); err != nil {
+		log.Printf("Warning: Failed to load settings, using defaults: %v", err)
+	}
+	go settingsStore.Start(workerCtx, cfg.Settings.ReloadInterval)
+
+	if cfg.Retention.Enabled {
+		go retentionService.StartWorker(workerCtx)
+	}
+
+	// Track monthly energy budgets and notify when thresholds are crossed
+	if cfg.Analytics.BudgetTrackingEnabled {
+		go budgetService.StartWorker(workerCtx, cfg.Analytics.BudgetTrackingInterval)
+	}
+
+	// Track savings goals against their baselines and notify when milestones are reached
+	if cfg.Analytics.SavingsGoalTrackingEnabled {
+		go savingsGoalService.StartWorker(workerCtx, cfg.Analytics.SavingsGoalTrackingInterval)
+	}
+
+	// Detect buildings whose consumption keeps rising week over week
+	if cfg.Analytics.TrendDetectionEnabled {
+		go kpiService.StartTrendWorker(workerCtx, cfg.Analytics.TrendDetectionInterval)
+	}
+
+	// Evaluate custom KPIs and alert when they miss their targets
+	if cfg.Analytics.CustomKPIEvaluationEnabled {
+		go kpiService.StartCustomKPIWorker(workerCtx, cfg.Analytics.KPICalculationInterval)
+	}
+
+	// Raise anomalies when consumption stays above the latest forecast's confidence bounds
+	if cfg.Analytics.ForecastDeviationEnabled && cfg.Analytics.ForecastDeviationIntervals > 0 {
+		go anomalyService.StartForecastDeviationWorker(workerCtx, cfg.Analytics.ForecastDeviationInterval, cfg.Analytics.ForecastDeviationIntervals)
+	}
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(securityClient)
+	authMiddleware := middleware.NewAuthMiddleware(securityClient, cfg.Auth)
+
+	// Consume events published by other services
+	if eventBus != nil {
+		deactivationService := service.NewDeactivationService(budgetRepo, savingsGoalRepo, securityClient)
+		if err := events.NewSubscriber(eventBus, executionRepo, activityRepo, authMiddleware.Permissions(), deactivationService, dashboardService).Start(); err != nil {
+			log.Printf("Warning: Failed to subscribe to events: %v", err)
+		}
+	}
+
+	// Initialize health checks
+	healthService := service.NewHealthService("analytics-service")
+	healthService.Register("mongodb", true, mongoDB.HealthCheck)
+	healthService.Register("security_service", false, securityClient.HealthCheck)
+	healthService.Register("iot_service", false, iotClient.HealthCheck)
+	healthService.Register("forecast_service", false, forecastClient.HealthCheck)
+	if eventBus != nil {
+		healthService.Register("event_bus", false, eventBus.HealthCheck)
+	}
+	if hotCache.Enabled() {
+		healthService.Register("redis", false, hotCache.HealthCheck)
+	}
 
 	// Initialize handlers
 	reportHandler := handlers.NewReportHandler(reportService, securityClient)
-	anomalyHandler := handlers.NewAnomalyHandler(anomalyService, securityClient)
+	anomalyHandler := handlers.NewAnomalyHandler(anomalyService, detectorService, securityClient)
 	timeSeriesHandler := handlers.NewTimeSeriesHandler(timeSeriesService)
 	kpiHandler := handlers.NewKPIHandler(kpiService, securityClient)
 	dashboardHandler := handlers.NewDashboardHandler(dashboardService)
+	healthHandler := handlers.NewHealthHandler(healthService, hotCache)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
+	authEventsHandler := handlers.NewAuthEventsHandler(authMiddleware.Permissions())
+	graphQLHandler := handlers.NewGraphQLHandler(graphQLService)
+	budgetHandler := handlers.NewBudgetHandler(budgetService, savingsGoalService, securityClient)
+	costHandler := handlers.NewCostHandler(costService)
+	jobHandler := handlers.NewJobHandler(jobQueue)
+	settingsHandler := handlers.NewSettingsHandler(settingsStore, securityClient)
+	mvHandler := handlers.NewMVHandler(mvService, securityClient)
 
 	// Create router
 	router := handlers.NewRouter(
@@ -87,12 +239,31 @@ func main() {
 		timeSeriesHandler,
 		kpiHandler,
 		dashboardHandler,
+		healthHandler,
+		retentionHandler,
+		authEventsHandler,
+		graphQLHandler,
+		budgetHandler,
+		costHandler,
+		jobHandler,
+		settingsHandler,
+		mvHandler,
 		authMiddleware,
 	)
 
+	// Meter API calls for billing
+	if eventBus != nil {
+		router.UsageMeter = middleware.NewUsageMeter()
+		go router.UsageMeter.StartReporter(workerCtx, cfg.Events.UsageReportInterval, func(ctx context.Context, apiCalls []events.UsageCount) {
+			if len(apiCalls) > 0 {
+				eventBus.Publish(ctx, events.UsageReported, &events.UsageReportedData{Service: "analytics-service", APICalls: apiCalls, ReportedAt: time.Now()})
+			}
+		})
+	}
+
 	// Create Gin engine and setup routes
 	engine := gin.New()
-	router.SetupRoutes(engine)
+	router.SetupRoutes(engine, cfg.HTTP)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -125,5 +296,10 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Flush the spans still buffered for export
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+
 	log.Println("Server exited properly")
 }
@@ -0,0 +1,232 @@
+// Package cache keeps the results of hot reads, such as device states, latest forecasts and
+// dashboard aggregates, in Redis so they are not recomputed on every request. Caching is
+// optional: without a Redis address every lookup misses and writes are skipped, so callers use
+// the cache unconditionally. Values are stored as JSON under "<prefix>:<kind>:<key>".
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config holds the Redis connection and the time-to-live of each kind of cached object
+type Config struct {
+	Addr       string // host:port of the Redis server; empty disables the cache
+	Password   string
+	DB         int
+	Prefix     string // prepended to every key, e.g. the service name
+	Timeout    time.Duration
+	PoolSize   int
+	DefaultTTL time.Duration
+	TTLs       map[string]time.Duration // per kind, overriding DefaultTTL
+}
+
+// KindStats counts the cache traffic of one kind of object since the service started
+type KindStats struct {
+	Kind           string  `json:"kind"`
+	TTLSeconds     int64   `json:"ttlSeconds"`
+	Hits           int64   `json:"hits"`
+	Misses         int64   `json:"misses"`
+	Sets           int64   `json:"sets"`
+	Invalidations  int64   `json:"invalidations"`
+	Errors         int64   `json:"errors"`
+	HitRatePercent float64 `json:"hitRatePercent"`
+}
+
+// Stats reports whether caching is enabled and the traffic per kind of object
+type Stats struct {
+	Enabled bool        `json:"enabled"`
+	Addr    string      `json:"addr,omitempty"`
+	Kinds   []KindStats `json:"kinds"`
+}
+
+// Cache is a Redis-backed cache of JSON values. A nil Cache is valid and disabled.
+type Cache struct {
+	client     *client
+	addr       string
+	prefix     string
+	defaultTTL time.Duration
+	ttls       map[string]time.Duration
+
+	mu    sync.Mutex
+	stats map[string]*KindStats
+}
+
+// New creates a cache. It does not connect until the first command, so an unreachable Redis
+// server only turns lookups into misses.
+func New(cfg Config) *Cache {
+	c := &Cache{
+		addr:       cfg.Addr,
+		prefix:     cfg.Prefix,
+		defaultTTL: cfg.DefaultTTL,
+		ttls:       cfg.TTLs,
+		stats:      make(map[string]*KindStats),
+	}
+	if c.defaultTTL <= 0 {
+		c.defaultTTL = time.Minute
+	}
+	if cfg.Addr != "" {
+		c.client = newClient(cfg.Addr, cfg.Password, cfg.DB, cfg.Timeout, cfg.PoolSize)
+	}
+	for kind := range cfg.TTLs {
+		c.kindStats(kind)
+	}
+	return c
+}
+
+// Enabled reports whether a Redis server is configured
+func (c *Cache) Enabled() bool {
+	return c != nil && c.client != nil
+}
+
+// TTL returns how long objects of a kind are kept
+func (c *Cache) TTL(kind string) time.Duration {
+	if ttl, ok := c.ttls[kind]; ok && ttl > 0 {
+		return ttl
+	}
+	return c.defaultTTL
+}
+
+// Get looks up a cached value. Missing entries, values that no longer decode and Redis errors
+// are all misses.
+func Get[T any](ctx context.Context, c *Cache, kind, key string) (T, bool) {
+	var value T
+	if !c.Enabled() {
+		return value, false
+	}
+
+	reply, err := c.client.do(ctx, "GET", c.key(kind, key))
+	if err != nil {
+		c.count(kind, func(s *KindStats) { s.Errors++; s.Misses++ })
+		return value, false
+	}
+	data, ok := reply.([]byte)
+	if !ok || json.Unmarshal(data, &value) != nil {
+		c.count(kind, func(s *KindStats) { s.Misses++ })
+		return value, false
+	}
+
+	c.count(kind, func(s *KindStats) { s.Hits++ })
+	return value, true
+}
+
+// Set stores a value for the TTL of its kind. Failures are counted, not returned, since the
+// value can always be recomputed.
+func Set[T any](ctx context.Context, c *Cache, kind, key string, value T) {
+	if !c.Enabled() {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err == nil {
+		ttl := c.TTL(kind)
+		_, err = c.client.do(ctx, "SET", c.key(kind, key), string(data), "PX", itoa(ttl.Milliseconds()))
+	}
+	if err != nil {
+		c.count(kind, func(s *KindStats) { s.Errors++ })
+		return
+	}
+	c.count(kind, func(s *KindStats) { s.Sets++ })
+}
+
+// GetOrLoad returns the cached value or loads and caches it. Errors of load are returned and
+// not cached.
+func GetOrLoad[T any](ctx context.Context, c *Cache, kind, key string, load func() (T, error)) (T, error) {
+	if value, ok := Get[T](ctx, c, kind, key); ok {
+		return value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	Set(ctx, c, kind, key, value)
+	return value, nil
+}
+
+// Invalidate removes cached values after the data they were computed from changed
+func (c *Cache) Invalidate(ctx context.Context, kind string, keys ...string) {
+	if !c.Enabled() || len(keys) == 0 {
+		return
+	}
+
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, c.key(kind, key))
+	}
+	if _, err := c.client.do(ctx, args...); err != nil {
+		c.count(kind, func(s *KindStats) { s.Errors++ })
+		return
+	}
+	c.count(kind, func(s *KindStats) { s.Invalidations += int64(len(keys)) })
+}
+
+// Stats reports the cache traffic per kind, sorted by kind
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{Kinds: []KindStats{}}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := Stats{Enabled: c.Enabled(), Addr: c.addr, Kinds: make([]KindStats, 0, len(c.stats))}
+	for kind, kindStats := range c.stats {
+		s := *kindStats
+		s.TTLSeconds = int64(c.TTL(kind).Seconds())
+		if lookups := s.Hits + s.Misses; lookups > 0 {
+			s.HitRatePercent = float64(s.Hits) / float64(lookups) * 100
+		}
+		stats.Kinds = append(stats.Kinds, s)
+	}
+	sort.Slice(stats.Kinds, func(i, j int) bool { return stats.Kinds[i].Kind < stats.Kinds[j].Kind })
+	return stats
+}
+
+// HealthCheck pings the Redis server
+func (c *Cache) HealthCheck(ctx context.Context) error {
+	if !c.Enabled() {
+		return nil
+	}
+	_, err := c.client.do(ctx, "PING")
+	return err
+}
+
+// Close closes the idle Redis connections
+func (c *Cache) Close() {
+	if c.Enabled() {
+		c.client.close()
+	}
+}
+
+// key builds the Redis key of a cached object
+func (c *Cache) key(kind, key string) string {
+	parts := []string{kind, key}
+	if c.prefix != "" {
+		parts = append([]string{c.prefix}, parts...)
+	}
+	return strings.Join(parts, ":")
+}
+
+// count updates the statistics of a kind
+func (c *Cache) count(kind string, update func(*KindStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(c.kindStats(kind))
+}
+
+// kindStats returns the statistics of a kind, creating them on first use. Callers hold mu,
+// except New.
+func (c *Cache) kindStats(kind string) *KindStats {
+	stats, ok := c.stats[kind]
+	if !ok {
+		stats = &KindStats{Kind: kind}
+		c.stats[kind] = stats
+	}
+	return stats
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// defaultTimeout bounds a Redis command when no timeout is configured
+const defaultTimeout = 500 * time.Millisecond
+
+// defaultPoolSize is the number of idle connections kept when no pool size is configured
+const defaultPoolSize = 10
+
+// redisError is an error reply from the Redis server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// conn is a connection to the Redis server with its reply reader
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// client speaks the Redis protocol (RESP) over a small pool of connections. It supports the
+// few commands the cache needs and keeps the services free of a Redis driver dependency.
+type client struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+	idle     chan *conn
+}
+
+// newClient creates a client for a Redis server
+func newClient(addr, password string, db int, timeout time.Duration, poolSize int) *client {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if poolSize <= 0 {
+		poolSize = defaultPoolSize
+	}
+	return &client{
+		addr:     addr,
+		password: password,
+		db:       db,
+		timeout:  timeout,
+		idle:     make(chan *conn, poolSize),
+	}
+}
+
+// do sends a command and returns its reply: nil, a string, an int64, a []byte or a
+// []interface{} of those. Connections that fail are discarded.
+func (c *client) do(ctx context.Context, args ...string) (interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.roundTrip(ctx, cn, args)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// get takes an idle connection or dials a new one, authenticating and selecting the database
+func (c *client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if c.password != "" {
+		if _, err := c.roundTrip(ctx, cn, []string{"AUTH", c.password}); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := c.roundTrip(ctx, cn, []string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// put returns a connection to the pool, closing it when the pool is full
+func (c *client) put(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+// close closes the idle connections
+func (c *client) close() {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// roundTrip writes a command as an array of bulk strings and reads the reply
+func (c *client) roundTrip(ctx context.Context, cn *conn, args []string) (interface{}, error) {
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	if _, err := cn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return readReply(cn.reader)
+}
+
+// readReply parses one RESP reply
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	body := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", line[0])
+}
+
+// itoa formats an integer command argument
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
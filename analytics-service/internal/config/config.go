@@ -5,9 +5,12 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+
+	"shared/signing"
 )
 
 // Config holds all application configuration
@@ -15,11 +18,19 @@ type Config struct {
 	Server    ServerConfig
 	MongoDB   MongoDBConfig
 	Security  SecurityServiceConfig
+	Auth      AuthConfig
 	IoT       IoTServiceConfig
 	Forecast  ForecastServiceConfig
 	Storage   StorageServiceConfig
 	Analytics AnalyticsConfig
+	Retention RetentionConfig
+	Settings  SettingsConfig
+	Events    EventsConfig
+	Jobs      JobsConfig
+	Cache     CacheConfig
 	Logging   LoggingConfig
+	Tracing   TracingConfig
+	HTTP      HTTPConfig
 }
 
 // StorageServiceConfig holds Storage service integration settings
@@ -40,6 +51,24 @@ type MongoDBConfig struct {
 	URI      string
 	Database string
 	Timeout  time.Duration
+	// ReplicaSet, ReadPreference and WriteConcern are left to the URI and driver defaults when empty
+	ReplicaSet             string
+	ReadPreference         string
+	WriteConcern           string
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	ServerSelectionTimeout time.Duration
+	RetryWrites            bool
+
+	// Reporting settings route the heavy aggregations of dashboards, reports and KPIs away from the
+	// primary. ReportingURI connects a separate client, e.g. to analytics nodes of the replica set;
+	// when empty the main connection is shared with ReportingReadPreference applied to reporting
+	// reads. ReportingMaxStaleness of zero leaves staleness unbounded; the driver requires at least 90s.
+	ReportingURI            string
+	ReportingReadPreference string
+	ReportingMaxStaleness   time.Duration
+	ReportingMaxPoolSize    uint64
+	QueryHints              bool // hint the time-range indexes to aggregations
 }
 
 // SecurityServiceConfig holds Security service integration settings
@@ -48,6 +77,27 @@ type SecurityServiceConfig struct {
 	Timeout time.Duration
 }
 
+// AuthConfig holds token validation settings.
+// With local validation enabled, tokens are verified against the Security service signing key
+// instead of a round trip per request; JWTSecret pins the key instead of fetching it.
+type AuthConfig struct {
+	LocalValidation    bool
+	JWTSecret          string
+	SigningKeyRefresh  time.Duration
+	PermissionCacheTTL time.Duration
+
+	// ServiceKey is the shared secret internal requests are signed with. ServiceSecret overrides
+	// it for requests this service sends, and ServiceSecrets for requests from the named services.
+	// Requests from services without their own secret are only verified with ServiceKey when
+	// SharedSecretDevMode is set, since any service could otherwise sign as another.
+	ServiceKey          string
+	ServiceSecret       string
+	ServiceSecrets      map[string]string
+	SharedSecretDevMode bool
+	SignatureWindow     time.Duration
+	AcceptServiceKey    bool // also accept the unsigned X-Service-Key header during migration
+}
+
 // IoTServiceConfig holds IoT service integration settings
 type IoTServiceConfig struct {
 	URL     string
@@ -66,6 +116,64 @@ type AnalyticsConfig struct {
 	KPICalculationInterval        time.Duration
 	ReportRetentionDays           int
 	TimeSeriesAggregationInterval time.Duration
+	BudgetTrackingEnabled         bool
+	BudgetTrackingInterval        time.Duration
+	SavingsGoalTrackingEnabled    bool
+	SavingsGoalTrackingInterval   time.Duration
+	TrendDetectionEnabled         bool
+	TrendDetectionInterval        time.Duration
+	ForecastDeviationEnabled      bool
+	ForecastDeviationInterval     time.Duration
+	ForecastDeviationIntervals    int
+	RootCauseEnabled              bool
+	RootCauseLookback             time.Duration
+	CustomKPIEvaluationEnabled    bool
+}
+
+// RetentionConfig holds per-collection data retention settings.
+// A retention of zero days disables purging for that collection.
+type RetentionConfig struct {
+	Enabled   bool
+	DryRun    bool
+	Interval  time.Duration
+	Reports   time.Duration
+	Anomalies time.Duration
+}
+
+// SettingsConfig holds runtime settings store settings
+type SettingsConfig struct {
+	ReloadInterval time.Duration // How often settings changed by other instances are picked up
+}
+
+// EventsConfig holds inter-service event bus settings.
+// Events are exchanged through the MQTT broker shared by all services.
+type EventsConfig struct {
+	Enabled  bool
+	Broker   string
+	Port     int
+	Username string
+	Password string
+	ClientID string
+	QoS      byte
+	// UsageReportInterval is how often the metered API usage is published for billing
+	UsageReportInterval time.Duration
+}
+
+// JobsConfig holds settings of the persistent job queue that runs asynchronous work
+type JobsConfig struct {
+	Workers      int
+	PollInterval time.Duration // how often idle workers check for due jobs
+}
+
+// CacheConfig holds settings of the optional Redis cache for hot reads.
+// An empty address disables caching.
+type CacheConfig struct {
+	RedisAddr            string
+	RedisPassword        string
+	RedisDB              int
+	Timeout              time.Duration
+	BuildingDashboardTTL time.Duration
+	OverviewDashboardTTL time.Duration
 }
 
 // LoggingConfig holds logging configuration
@@ -74,6 +182,15 @@ type LoggingConfig struct {
 	Format string
 }
 
+// TracingConfig holds OpenTelemetry tracing settings. Spans are exported over OTLP/HTTP.
+type TracingConfig struct {
+	Enabled     bool
+	Endpoint    string  // collector host:port, or a full URL of its traces endpoint
+	Insecure    bool    // export over plain HTTP
+	ServiceName string  // service.name of the exported spans
+	SampleRatio float64 // share of traces started by this service that are recorded
+}
+
 // Load reads configuration from environment variables
 func Load() *Config {
 	// Load .env file if it exists
@@ -81,21 +198,47 @@ func Load() *Config {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	mode := getEnv("GIN_MODE", "debug")
+
 	return &Config{
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8084"),
 			Host: getEnv("SERVER_HOST", "0.0.0.0"),
-			Mode: getEnv("GIN_MODE", "debug"),
+			Mode: mode,
 		},
 		MongoDB: MongoDBConfig{
-			URI:      getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-			Database: getEnv("MONGODB_DATABASE", "analytics_service"),
-			Timeout:  time.Duration(getEnvAsInt("MONGODB_TIMEOUT", 10)) * time.Second,
+			URI:                     getEnv("MONGODB_URI", "mongodb://localhost:27017"),
+			Database:                getEnv("MONGODB_DATABASE", "analytics_service"),
+			Timeout:                 time.Duration(getEnvAsInt("MONGODB_TIMEOUT", 10)) * time.Second,
+			ReplicaSet:              getEnv("MONGODB_REPLICA_SET", ""),
+			ReadPreference:          getEnv("MONGODB_READ_PREFERENCE", ""),
+			WriteConcern:            getEnv("MONGODB_WRITE_CONCERN", ""),
+			MaxPoolSize:             uint64(getEnvAsInt("MONGODB_MAX_POOL_SIZE", 100)),
+			MinPoolSize:             uint64(getEnvAsInt("MONGODB_MIN_POOL_SIZE", 10)),
+			ServerSelectionTimeout:  time.Duration(getEnvAsInt("MONGODB_SERVER_SELECTION_TIMEOUT", 30)) * time.Second,
+			RetryWrites:             getEnvAsBool("MONGODB_RETRY_WRITES", true),
+			ReportingURI:            getEnv("MONGODB_REPORTING_URI", ""),
+			ReportingReadPreference: getEnv("MONGODB_REPORTING_READ_PREFERENCE", "secondaryPreferred"),
+			ReportingMaxStaleness:   time.Duration(getEnvAsInt("MONGODB_REPORTING_MAX_STALENESS_SECONDS", 0)) * time.Second,
+			ReportingMaxPoolSize:    uint64(getEnvAsInt("MONGODB_REPORTING_MAX_POOL_SIZE", 20)),
+			QueryHints:              getEnvAsBool("MONGODB_QUERY_HINTS", true),
 		},
 		Security: SecurityServiceConfig{
 			URL:     getEnv("SECURITY_SERVICE_URL", "http://localhost:8080"),
 			Timeout: time.Duration(getEnvAsInt("SECURITY_SERVICE_TIMEOUT", 10)) * time.Second,
 		},
+		Auth: AuthConfig{
+			LocalValidation:     getEnv("AUTH_LOCAL_VALIDATION", "false") == "true",
+			JWTSecret:           getEnv("AUTH_JWT_SECRET", ""),
+			SigningKeyRefresh:   time.Duration(getEnvAsInt("AUTH_SIGNING_KEY_REFRESH_MINUTES", 60)) * time.Minute,
+			PermissionCacheTTL:  time.Duration(getEnvAsInt("AUTH_PERMISSION_CACHE_TTL_SECONDS", 30)) * time.Second,
+			ServiceKey:          getEnv("INTERNAL_SERVICE_KEY", ""),
+			ServiceSecret:       getEnv("INTERNAL_SERVICE_SECRET", getEnv("INTERNAL_SERVICE_KEY", "")),
+			ServiceSecrets:      signing.ParseSecrets(getEnv("INTERNAL_SERVICE_SECRETS", "")),
+			SharedSecretDevMode: getEnvAsBool("INTERNAL_SHARED_SECRET_DEV_MODE", false),
+			SignatureWindow:     time.Duration(getEnvAsInt("INTERNAL_SIGNATURE_WINDOW_SECONDS", 300)) * time.Second,
+			AcceptServiceKey:    getEnvAsBool("INTERNAL_ACCEPT_SERVICE_KEY", false),
+		},
 		IoT: IoTServiceConfig{
 			URL:     getEnv("IOT_SERVICE_URL", "http://localhost:8083"),
 			Timeout: time.Duration(getEnvAsInt("IOT_SERVICE_TIMEOUT", 10)) * time.Second,
@@ -113,14 +256,73 @@ func Load() *Config {
 			KPICalculationInterval:        time.Duration(getEnvAsInt("ANALYTICS_KPI_CALCULATION_INTERVAL", 60)) * time.Minute,
 			ReportRetentionDays:           getEnvAsInt("ANALYTICS_REPORT_RETENTION_DAYS", 90),
 			TimeSeriesAggregationInterval: time.Duration(getEnvAsInt("ANALYTICS_TIME_SERIES_AGGREGATION_INTERVAL", 60)) * time.Minute,
+			BudgetTrackingEnabled:         getEnvAsBool("ANALYTICS_BUDGET_TRACKING_ENABLED", true),
+			BudgetTrackingInterval:        time.Duration(getEnvAsInt("ANALYTICS_BUDGET_TRACKING_INTERVAL", 60)) * time.Minute,
+			SavingsGoalTrackingEnabled:    getEnvAsBool("ANALYTICS_SAVINGS_GOAL_TRACKING_ENABLED", true),
+			SavingsGoalTrackingInterval:   time.Duration(getEnvAsInt("ANALYTICS_SAVINGS_GOAL_TRACKING_INTERVAL", 360)) * time.Minute,
+			TrendDetectionEnabled:         getEnvAsBool("ANALYTICS_TREND_DETECTION_ENABLED", true),
+			TrendDetectionInterval:        time.Duration(getEnvAsInt("ANALYTICS_TREND_DETECTION_INTERVAL", 360)) * time.Minute,
+			ForecastDeviationEnabled:      getEnvAsBool("ANALYTICS_FORECAST_DEVIATION_ENABLED", true),
+			ForecastDeviationInterval:     time.Duration(getEnvAsInt("ANALYTICS_FORECAST_DEVIATION_INTERVAL", 60)) * time.Minute,
+			ForecastDeviationIntervals:    getEnvAsInt("ANALYTICS_FORECAST_DEVIATION_INTERVALS", 3),
+			RootCauseEnabled:              getEnvAsBool("ANALYTICS_ROOT_CAUSE_ENABLED", true),
+			RootCauseLookback:             time.Duration(getEnvAsInt("ANALYTICS_ROOT_CAUSE_LOOKBACK_HOURS", 6)) * time.Hour,
+			CustomKPIEvaluationEnabled:    getEnvAsBool("ANALYTICS_CUSTOM_KPI_EVALUATION_ENABLED", true),
+		},
+		Retention: RetentionConfig{
+			Enabled:   getEnv("RETENTION_ENABLED", "true") == "true",
+			DryRun:    getEnv("RETENTION_DRY_RUN", "false") == "true",
+			Interval:  time.Duration(getEnvAsInt("RETENTION_INTERVAL_HOURS", 24)) * time.Hour,
+			Reports:   time.Duration(getEnvAsInt("RETENTION_REPORT_DAYS", getEnvAsInt("ANALYTICS_REPORT_RETENTION_DAYS", 90))) * 24 * time.Hour,
+			Anomalies: time.Duration(getEnvAsInt("RETENTION_ANOMALY_DAYS", 180)) * 24 * time.Hour,
+		},
+		Settings: SettingsConfig{
+			ReloadInterval: time.Duration(getEnvAsInt("SETTINGS_RELOAD_INTERVAL_SECONDS", 30)) * time.Second,
+		},
+		Events: EventsConfig{
+			Enabled:  getEnv("EVENTS_ENABLED", "false") == "true",
+			Broker:   getEnv("EVENT_BUS_BROKER", "localhost"),
+			Port:     getEnvAsInt("EVENT_BUS_PORT", 1883),
+			Username: getEnv("EVENT_BUS_USERNAME", ""),
+			Password: getEnv("EVENT_BUS_PASSWORD", ""),
+			ClientID: getEnv("EVENT_BUS_CLIENT_ID", "analytics-service-events"),
+			QoS:      byte(getEnvAsInt("EVENT_BUS_QOS", 1)),
+
+			UsageReportInterval: time.Duration(getEnvAsInt("EVENT_BUS_USAGE_REPORT_INTERVAL_SECONDS", 60)) * time.Second,
+		},
+		Jobs: JobsConfig{
+			Workers:      getEnvAsInt("JOB_WORKERS", 4),
+			PollInterval: time.Duration(getEnvAsInt("JOB_POLL_INTERVAL_MS", 1000)) * time.Millisecond,
+		},
+		Cache: CacheConfig{
+			RedisAddr:            getEnv("REDIS_ADDR", ""),
+			RedisPassword:        getEnv("REDIS_PASSWORD", ""),
+			RedisDB:              getEnvAsInt("REDIS_DB", 0),
+			Timeout:              time.Duration(getEnvAsInt("REDIS_TIMEOUT_MS", 500)) * time.Millisecond,
+			BuildingDashboardTTL: time.Duration(getEnvAsInt("CACHE_BUILDING_DASHBOARD_TTL_SECONDS", 60)) * time.Second,
+			OverviewDashboardTTL: time.Duration(getEnvAsInt("CACHE_OVERVIEW_DASHBOARD_TTL_SECONDS", 60)) * time.Second,
 		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "debug"),
 			Format: getEnv("LOG_FORMAT", "json"),
 		},
+		Tracing: TracingConfig{
+			Enabled:     getEnvAsBool("TRACING_ENABLED", false),
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
+			Insecure:    getEnvAsBool("OTEL_EXPORTER_OTLP_INSECURE", true),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "analytics-service"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1.0),
+		},
+		HTTP: loadHTTPConfig(mode),
 	}
 }
 
+// Validate checks settings that would make the service unsafe or unusable, so startup fails
+// instead of serving with them
+func (c *Config) Validate() error {
+	return c.HTTP.Validate(c.Server.Mode)
+}
+
 // getEnv retrieves an environment variable with a default fallback
 func getEnv(key, defaultVal string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -129,6 +331,17 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+// getEnvAsList retrieves a comma-separated environment variable as a list, skipping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getEnvAsInt retrieves an environment variable as an integer
 func getEnvAsInt(key string, defaultVal int) int {
 	if value, exists := os.LookupEnv(key); exists {
@@ -148,3 +361,13 @@ func getEnvAsBool(key string, defaultVal bool) bool {
 	}
 	return defaultVal
 }
+
+// getEnvAsFloat retrieves an environment variable as a float
+func getEnvAsFloat(key string, defaultVal float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultVal
+}
@@ -0,0 +1,325 @@
+// Package events publishes and consumes domain events shared between services over the MQTT broker
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Type identifies a domain event
+type Type string
+
+// Domain events exchanged between services
+const (
+	CommandApplied    Type = "command_applied"
+	CommandTimedOut   Type = "command_timed_out"
+	AnomalyDetected   Type = "anomaly_detected"
+	ForecastCompleted Type = "forecast_completed"
+	ScenarioExecuted  Type = "scenario_executed"
+	UserDeactivated   Type = "user_deactivated"
+
+	BudgetThresholdCrossed Type = "budget_threshold_crossed"
+
+	CommandApprovalRequested Type = "command_approval_requested"
+
+	NotificationFailureRateExceeded Type = "notification_failure_rate_exceeded"
+
+	SavingsVerified Type = "savings_verified"
+
+	ComfortGuardrailTriggered Type = "comfort_guardrail_triggered"
+
+	UsageReported Type = "usage_reported"
+
+	DeviceStatusChanged Type = "device_status_changed"
+
+	KPITargetMissed Type = "kpi_target_missed"
+
+	SavingsGoalMilestoneReached Type = "savings_goal_milestone_reached"
+)
+
+// TopicPrefix is the root of all event topics on the broker
+const TopicPrefix = "energy/events"
+
+// Topic returns the broker topic an event type is published on
+func Topic(eventType Type) string {
+	return TopicPrefix + "/" + string(eventType)
+}
+
+// Event is the envelope every domain event is published in
+type Event struct {
+	ID         string    `json:"id"`
+	Type       Type      `json:"type"`
+	Source     string    `json:"source"`
+	OccurredAt time.Time `json:"occurredAt"`
+	// TraceParent is the W3C trace context the event was published in, if it was traced
+	TraceParent string          `json:"traceparent,omitempty"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// SignedEvent is the message an event is published as: the encoded event and the signature the
+// publishing service made over it with its internal service secret. The signature covers the
+// topic, the source, the time the event occurred and its ID, which receivers use as a nonce.
+type SignedEvent struct {
+	Event     json.RawMessage `json:"event"`
+	Signature string          `json:"signature"`
+}
+
+// Sources are the services events are accepted from
+var Sources = map[string]bool{
+	"security-service":    true,
+	"iot-control-service": true,
+	"forecast-service":    true,
+	"analytics-service":   true,
+}
+
+// Decode unmarshals the event payload into v
+func (e *Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// CommandAppliedData is published by the IoT service when a device acknowledges a command
+type CommandAppliedData struct {
+	CommandID  string                 `json:"commandId"`
+	DeviceID   string                 `json:"deviceId"`
+	BuildingID string                 `json:"buildingId,omitempty"`
+	Command    string                 `json:"command"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	IssuedBy   string                 `json:"issuedBy,omitempty"`
+	AppliedAt  time.Time              `json:"appliedAt"`
+}
+
+// CommandTimedOutData is published by the IoT service when a device does not acknowledge a command
+// in time. ScenarioID and SourceScenarioID are set for commands of optimization scenarios, and
+// RetryCommandID when the command was sent again.
+type CommandTimedOutData struct {
+	CommandID        string    `json:"commandId"`
+	DeviceID         string    `json:"deviceId"`
+	BuildingID       string    `json:"buildingId,omitempty"`
+	Command          string    `json:"command"`
+	IssuedBy         string    `json:"issuedBy,omitempty"`
+	ScenarioID       string    `json:"scenarioId,omitempty"`
+	SourceScenarioID string    `json:"sourceScenarioId,omitempty"`
+	RetryCommandID   string    `json:"retryCommandId,omitempty"`
+	TimedOutAt       time.Time `json:"timedOutAt"`
+}
+
+// CommandApprovalRequestedData is published by the IoT service when a high-impact command waits
+// for approval. Approvers must confirm it before ExpiresAt, and not as the user who requested it.
+type CommandApprovalRequestedData struct {
+	CommandID   string                 `json:"commandId"`
+	DeviceID    string                 `json:"deviceId"`
+	BuildingID  string                 `json:"buildingId,omitempty"`
+	DeviceType  string                 `json:"deviceType"`
+	Command     string                 `json:"command"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+	RequestedBy string                 `json:"requestedBy,omitempty"`
+	RequestedAt time.Time              `json:"requestedAt"`
+	ExpiresAt   time.Time              `json:"expiresAt"`
+}
+
+// AnomalyDetectedData is published by the Analytics service for each new anomaly
+type AnomalyDetectedData struct {
+	AnomalyID  string    `json:"anomalyId"`
+	DeviceID   string    `json:"deviceId"`
+	BuildingID string    `json:"buildingId,omitempty"`
+	Type       string    `json:"type"`
+	Severity   string    `json:"severity"`
+	DetectedAt time.Time `json:"detectedAt"`
+}
+
+// ForecastCompletedData is published by the Forecast service when predictions are stored.
+// Prediction is set for device forecasts so subscribers need not fetch the predictions.
+type ForecastCompletedData struct {
+	ForecastID   string             `json:"forecastId"`
+	BuildingID   string             `json:"buildingId"`
+	DeviceID     string             `json:"deviceId,omitempty"`
+	Type         string             `json:"type"`
+	ModelUsed    string             `json:"modelUsed"`
+	HorizonHours int                `json:"horizonHours"`
+	Prediction   *PredictionSummary `json:"prediction,omitempty"`
+	CompletedAt  time.Time          `json:"completedAt"`
+}
+
+// PredictionSummary is the predicted consumption of a device forecast. Trend is INCREASING,
+// DECREASING or STABLE, from the change between the first and last predicted values.
+type PredictionSummary struct {
+	CurrentConsumption float64          `json:"currentConsumption"`
+	Trend              string           `json:"trend"`
+	TrendPercentage    float64          `json:"trendPercentage"`
+	PeakValue          float64          `json:"peakValue"`
+	PeakAt             time.Time        `json:"peakAt"`
+	Values             []PredictedValue `json:"values"`
+}
+
+// PredictedValue is a single predicted value of a device forecast
+type PredictedValue struct {
+	Timestamp      time.Time `json:"timestamp"`
+	PredictedValue float64   `json:"predictedValue"`
+	LowerBound     float64   `json:"lowerBound"`
+	UpperBound     float64   `json:"upperBound"`
+	Unit           string    `json:"unit"`
+}
+
+// ScenarioExecutedData is published by the IoT service when an optimization scenario finishes.
+// SourceScenarioID is the Forecast service scenario the execution was requested for and
+// ScenarioType its optimization type, e.g. DEMAND_RESPONSE.
+type ScenarioExecutedData struct {
+	ScenarioID       string           `json:"scenarioId"`
+	SourceScenarioID string           `json:"sourceScenarioId,omitempty"`
+	ScenarioType     string           `json:"scenarioType,omitempty"`
+	BuildingID       string           `json:"buildingId"`
+	Status           string           `json:"status"`
+	ActionsApplied   int              `json:"actionsApplied"`
+	ActionsFailed    int              `json:"actionsFailed"`
+	Actions          []ExecutedAction `json:"actions,omitempty"`
+	StartedAt        time.Time        `json:"startedAt"`
+	CompletedAt      time.Time        `json:"completedAt"`
+}
+
+// ExecutedAction is the outcome of a single action of an executed scenario
+type ExecutedAction struct {
+	DeviceID   string    `json:"deviceId"`
+	Command    string    `json:"command"`
+	Status     string    `json:"status"`
+	CommandID  string    `json:"commandId,omitempty"`
+	ExecutedAt time.Time `json:"executedAt"`
+}
+
+// UserDeactivatedData is published by the Security service when a user loses access
+type UserDeactivatedData struct {
+	UserID        string    `json:"userId"`
+	Username      string    `json:"username,omitempty"`
+	Reason        string    `json:"reason"`
+	DeactivatedBy string    `json:"deactivatedBy,omitempty"`
+	DeactivatedAt time.Time `json:"deactivatedAt"`
+}
+
+// BudgetThresholdCrossedData is published by the Analytics service the first time in a month that
+// an energy budget's consumption reaches a threshold, given as a percentage of the monthly limit
+type BudgetThresholdCrossedData struct {
+	BudgetID         string    `json:"budgetId"`
+	Name             string    `json:"name"`
+	BuildingID       string    `json:"buildingId"`
+	DeviceID         string    `json:"deviceId,omitempty"`
+	Month            string    `json:"month"`
+	Threshold        int       `json:"threshold"`
+	LimitKWh         float64   `json:"limitKwh"`
+	ConsumedKWh      float64   `json:"consumedKwh"`
+	ProjectedKWh     float64   `json:"projectedKwh"`
+	NotifyUserIDs    []string  `json:"notifyUserIds,omitempty"`
+	GenerateScenario bool      `json:"generateScenario"`
+	CrossedAt        time.Time `json:"crossedAt"`
+}
+
+// NotificationFailureRateExceededData is published by the Security service when the share of failed
+// notifications of a channel rises above the alert threshold over the rolling window
+type NotificationFailureRateExceededData struct {
+	Channel            string           `json:"channel"`
+	FailureRatePercent float64          `json:"failureRatePercent"`
+	ThresholdPercent   int              `json:"thresholdPercent"`
+	Failed             int64            `json:"failed"`
+	Attempted          int64            `json:"attempted"`
+	FailedByProvider   map[string]int64 `json:"failedByProvider,omitempty"`
+	WindowMinutes      int              `json:"windowMinutes"`
+	DetectedAt         time.Time        `json:"detectedAt"`
+}
+
+// SavingsVerifiedData is published by the Analytics service when an M&V report verifies the savings
+// of an executed scenario. SourceScenarioID is the Forecast service scenario the execution was
+// requested for. The mean hourly demands of the changed devices are set for option A reports.
+type SavingsVerifiedData struct {
+	ReportID         string    `json:"reportId"`
+	ScenarioID       string    `json:"scenarioId"`
+	SourceScenarioID string    `json:"sourceScenarioId,omitempty"`
+	BuildingID       string    `json:"buildingId"`
+	Option           string    `json:"option"`
+	DeviceIDs        []string  `json:"deviceIds,omitempty"`
+	BaselineMeanKWh  float64   `json:"baselineMeanKwh,omitempty"`
+	ReportingMeanKWh float64   `json:"reportingMeanKwh,omitempty"`
+	SavingsKWh       float64   `json:"savingsKwh"`
+	SavingsPercent   float64   `json:"savingsPercent"`
+	Significant      bool      `json:"significant"`
+	ReportingFrom    time.Time `json:"reportingFrom"`
+	ReportingTo      time.Time `json:"reportingTo"`
+	VerifiedAt       time.Time `json:"verifiedAt"`
+}
+
+// ComfortGuardrailTriggeredData is published by the IoT service when the temperature a device reports
+// leaves the comfort band of an optimization scenario acting on it. The scenario's actions on the
+// device are rolled back, and Paused is set when the scenario was still executing and was paused.
+// Bound is MIN or MAX, the side of the band that was crossed, and Limit its temperature.
+type ComfortGuardrailTriggeredData struct {
+	ScenarioID           string    `json:"scenarioId"`
+	SourceScenarioID     string    `json:"sourceScenarioId,omitempty"`
+	BuildingID           string    `json:"buildingId"`
+	DeviceID             string    `json:"deviceId"`
+	Temperature          float64   `json:"temperature"`
+	Bound                string    `json:"bound"`
+	Limit                float64   `json:"limit"`
+	ActionsRolledBack    int       `json:"actionsRolledBack"`
+	ActionsNotRolledBack int       `json:"actionsNotRolledBack"`
+	Paused               bool      `json:"paused"`
+	TriggeredAt          time.Time `json:"triggeredAt"`
+}
+
+// UsageReportedData is published periodically by each service with the usage it metered since its
+// previous report, for billing. APICalls counts authenticated API requests and Devices is the
+// number of devices monitored in each building at ReportedAt, reported by the IoT service only.
+// OrgID is empty when the request was not scoped to an organization; the building's owner is used.
+type UsageReportedData struct {
+	Service    string       `json:"service"`
+	APICalls   []UsageCount `json:"apiCalls,omitempty"`
+	Devices    []UsageCount `json:"devices,omitempty"`
+	ReportedAt time.Time    `json:"reportedAt"`
+}
+
+// UsageCount is a metered quantity of an organization or building
+type UsageCount struct {
+	OrgID      string `json:"orgId,omitempty"`
+	BuildingID string `json:"buildingId,omitempty"`
+	Count      int64  `json:"count"`
+}
+
+// DeviceStatusChangedData is published by the IoT service when a device's status changes, e.g. it
+// reports telemetry again after being offline or is put into maintenance
+type DeviceStatusChangedData struct {
+	DeviceID       string    `json:"deviceId"`
+	BuildingID     string    `json:"buildingId,omitempty"`
+	PreviousStatus string    `json:"previousStatus"`
+	Status         string    `json:"status"`
+	ChangedBy      string    `json:"changedBy,omitempty"`
+	ChangedAt      time.Time `json:"changedAt"`
+}
+
+// KPITargetMissedData is published by the Analytics service when a building's custom KPI misses the
+// target it met, or had not been measured against, when the KPIs were previously calculated.
+// Comparison is AT_MOST or AT_LEAST, the side of the target the KPI should stay on.
+type KPITargetMissedData struct {
+	DefinitionID  string    `json:"definitionId"`
+	Key           string    `json:"key"`
+	Name          string    `json:"name"`
+	BuildingID    string    `json:"buildingId"`
+	Period        string    `json:"period"`
+	Value         float64   `json:"value"`
+	Unit          string    `json:"unit,omitempty"`
+	Comparison    string    `json:"comparison"`
+	Target        float64   `json:"target"`
+	NotifyUserIDs []string  `json:"notifyUserIds,omitempty"`
+	EvaluatedAt   time.Time `json:"evaluatedAt"`
+}
+
+// SavingsGoalMilestoneReachedData is published by the Analytics service the first time a savings
+// goal's savings so far reach a milestone, given as a percentage of the savings targeted for the year
+type SavingsGoalMilestoneReachedData struct {
+	GoalID                  string    `json:"goalId"`
+	Name                    string    `json:"name"`
+	BuildingID              string    `json:"buildingId"`
+	Year                    int       `json:"year"`
+	Milestone               int       `json:"milestone"`
+	TargetPercent           float64   `json:"targetPercent"`
+	SavedKWh                float64   `json:"savedKwh"`
+	SavedPercent            float64   `json:"savedPercent"`
+	ProjectedSavingsPercent float64   `json:"projectedSavingsPercent"`
+	NotifyUserIDs           []string  `json:"notifyUserIds,omitempty"`
+	ReachedAt               time.Time `json:"reachedAt"`
+}
@@ -0,0 +1,193 @@
+package events
+
+import (
+	"context"
+	"log"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/tracing"
+)
+
+// ExecutionRecorder stores optimization scenarios executed by the IoT service
+type ExecutionRecorder interface {
+	Upsert(ctx context.Context, execution *models.OptimizationExecution) error
+}
+
+// ActivityRecorder stores device activity announced by the IoT service
+type ActivityRecorder interface {
+	Record(ctx context.Context, activity *models.DeviceActivity) error
+}
+
+// PermissionInvalidator drops cached token validations for a user
+type PermissionInvalidator interface {
+	InvalidateUser(userID string)
+}
+
+// UserReleaser releases the resources a deactivated user owns
+type UserReleaser interface {
+	ReleaseUser(ctx context.Context, userID, actorID string) error
+}
+
+// DashboardInvalidator drops the cached dashboards of a building
+type DashboardInvalidator interface {
+	InvalidateBuilding(ctx context.Context, buildingID string)
+}
+
+// Subscriber reacts to domain events published by other services
+type Subscriber struct {
+	bus         *Bus
+	executions  ExecutionRecorder
+	activities  ActivityRecorder
+	permissions PermissionInvalidator
+	users       UserReleaser
+	dashboards  DashboardInvalidator
+}
+
+// NewSubscriber creates the Analytics service event subscriber
+func NewSubscriber(bus *Bus, executions ExecutionRecorder, activities ActivityRecorder, permissions PermissionInvalidator, users UserReleaser, dashboards DashboardInvalidator) *Subscriber {
+	return &Subscriber{
+		bus:         bus,
+		executions:  executions,
+		activities:  activities,
+		permissions: permissions,
+		users:       users,
+		dashboards:  dashboards,
+	}
+}
+
+// Start registers handlers for the events this service consumes
+func (s *Subscriber) Start() error {
+	if err := s.bus.Subscribe(ScenarioExecuted, s.onScenarioExecuted); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(CommandApplied, s.onCommandApplied); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(CommandTimedOut, s.onCommandTimedOut); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(DeviceStatusChanged, s.onDeviceStatusChanged); err != nil {
+		return err
+	}
+	return s.bus.Subscribe(UserDeactivated, s.onUserDeactivated)
+}
+
+// onScenarioExecuted records an executed scenario for building dashboards
+func (s *Subscriber) onScenarioExecuted(ctx context.Context, event *Event) error {
+	var data ScenarioExecutedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+	tracing.SetScenario(ctx, data.ScenarioID, data.BuildingID)
+
+	actions := make([]models.OptimizationActionOutcome, len(data.Actions))
+	for i, action := range data.Actions {
+		actions[i] = models.OptimizationActionOutcome{
+			DeviceID:   action.DeviceID,
+			Command:    action.Command,
+			Status:     action.Status,
+			CommandID:  action.CommandID,
+			ExecutedAt: action.ExecutedAt,
+		}
+	}
+
+	err := s.executions.Upsert(ctx, &models.OptimizationExecution{
+		ScenarioID:       data.ScenarioID,
+		SourceScenarioID: data.SourceScenarioID,
+		ScenarioType:     data.ScenarioType,
+		BuildingID:       data.BuildingID,
+		Status:           data.Status,
+		ActionsApplied:   data.ActionsApplied,
+		ActionsFailed:    data.ActionsFailed,
+		Actions:          actions,
+		StartedAt:        data.StartedAt,
+		CompletedAt:      data.CompletedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.dashboards.InvalidateBuilding(ctx, data.BuildingID)
+	return nil
+}
+
+// onCommandApplied records a command a device applied, to explain anomalies
+func (s *Subscriber) onCommandApplied(ctx context.Context, event *Event) error {
+	var data CommandAppliedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	return s.recordActivity(ctx, &models.DeviceActivity{
+		ActivityID: event.ID,
+		Kind:       models.ActivityCommandApplied,
+		DeviceID:   data.DeviceID,
+		BuildingID: data.BuildingID,
+		Command:    data.Command,
+		CommandID:  data.CommandID,
+		IssuedBy:   data.IssuedBy,
+		OccurredAt: data.AppliedAt,
+	})
+}
+
+// onCommandTimedOut records a command a device did not acknowledge, to explain anomalies
+func (s *Subscriber) onCommandTimedOut(ctx context.Context, event *Event) error {
+	var data CommandTimedOutData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	return s.recordActivity(ctx, &models.DeviceActivity{
+		ActivityID: event.ID,
+		Kind:       models.ActivityCommandTimedOut,
+		DeviceID:   data.DeviceID,
+		BuildingID: data.BuildingID,
+		Command:    data.Command,
+		CommandID:  data.CommandID,
+		IssuedBy:   data.IssuedBy,
+		OccurredAt: data.TimedOutAt,
+	})
+}
+
+// onDeviceStatusChanged records a device status change, to explain anomalies
+func (s *Subscriber) onDeviceStatusChanged(ctx context.Context, event *Event) error {
+	var data DeviceStatusChangedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	return s.recordActivity(ctx, &models.DeviceActivity{
+		ActivityID:     event.ID,
+		Kind:           models.ActivityStatusChanged,
+		DeviceID:       data.DeviceID,
+		BuildingID:     data.BuildingID,
+		PreviousStatus: data.PreviousStatus,
+		Status:         data.Status,
+		IssuedBy:       data.ChangedBy,
+		OccurredAt:     data.ChangedAt,
+	})
+}
+
+// recordActivity stores device activity. Activity of devices outside a building cannot be
+// related to anomalies and is skipped.
+func (s *Subscriber) recordActivity(ctx context.Context, activity *models.DeviceActivity) error {
+	tracing.SetEntity(ctx, activity.BuildingID, activity.DeviceID)
+	if activity.BuildingID == "" {
+		return nil
+	}
+	return s.activities.Record(ctx, activity)
+}
+
+// onUserDeactivated stops accepting cached or locally validated tokens of a deactivated user
+// and removes them from budget alerts
+func (s *Subscriber) onUserDeactivated(ctx context.Context, event *Event) error {
+	var data UserDeactivatedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	s.permissions.InvalidateUser(data.UserID)
+	log.Printf("Invalidated cached permissions of deactivated user %s", data.UserID)
+
+	return s.users.ReleaseUser(ctx, data.UserID, data.DeactivatedBy)
+}
@@ -0,0 +1,514 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Request is a GraphQL request
+type Request struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Error is a GraphQL error. Field errors carry the path of the field that failed.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Result is the response to a GraphQL request
+type Result struct {
+	Data   interface{} `json:"data"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Execute runs a query operation against the schema. Root is passed to every resolver.
+// Requests that fail to parse or validate have no data; a failing field is set to null
+// and reported in the errors while the rest of the query still resolves.
+func (s *Schema) Execute(ctx context.Context, req *Request, root interface{}) *Result {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Result{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Result{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return &Result{Errors: []*Error{{Message: fmt.Sprintf("%s operations are not supported", op.kind)}}}
+	}
+
+	variables, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return &Result{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	ex := &execution{doc: doc, root: root, variables: variables}
+	if errs := ex.validate(s.Query, op.selections, make(map[string]bool)); len(errs) > 0 {
+		return &Result{Errors: errs}
+	}
+	if err := ex.checkLimits(s.Query, op.selections, s.Limits.withDefaults()); err != nil {
+		return &Result{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	data := ex.executeSelectionSet(ctx, s.Query, nil, op.selections, nil)
+	return &Result{Data: data, Errors: ex.errors}
+}
+
+// operation returns the operation to run, which must be named when there are several
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("operation name is required when the document contains several operations")
+		}
+		return d.operations[0], nil
+	}
+
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// coerceVariables applies defaults and checks required variables were provided.
+// Values are checked against argument types where they are used.
+func coerceVariables(op *operation, provided map[string]interface{}) (map[string]interface{}, error) {
+	variables := make(map[string]interface{}, len(op.variables))
+	for _, def := range op.variables {
+		value, ok := provided[def.name]
+		if !ok && def.defaultValue != nil {
+			value, ok = def.defaultValue, true
+		}
+		if (!ok || value == nil) && def.typ.nonNull {
+			return nil, fmt.Errorf("variable $%s of required type %s was not provided", def.name, def.typ)
+		}
+		if ok {
+			variables[def.name] = value
+		}
+	}
+	return variables, nil
+}
+
+// execution holds the state of a single request
+type execution struct {
+	doc       *document
+	root      interface{}
+	variables map[string]interface{}
+
+	mu     sync.Mutex
+	errors []*Error
+}
+
+func (ex *execution) addError(err error, path []interface{}) {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	ex.errors = append(ex.errors, &Error{Message: err.Error(), Path: path})
+}
+
+// validate checks that every selected field exists and has its required arguments, and that
+// only object fields have sub-selections
+func (ex *execution) validate(obj *Object, selections []selection, visited map[string]bool) []*Error {
+	var errs []*Error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, &Error{Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if sel.name == "__typename" {
+				if len(sel.selections) > 0 {
+					fail("field \"__typename\" must not have a selection since type \"String\" has no subfields")
+				}
+				continue
+			}
+
+			def, ok := obj.Fields[sel.name]
+			if !ok {
+				fail("cannot query field %q on type %q", sel.name, obj.Name)
+				continue
+			}
+
+			for _, name := range sortedKeys(sel.arguments) {
+				if _, ok := def.Args[name]; !ok {
+					fail("unknown argument %q on field %q of type %q", name, sel.name, obj.Name)
+				}
+			}
+			for _, name := range sortedKeys(def.Args) {
+				if _, ok := sel.arguments[name]; !ok && def.Args[name].Required {
+					fail("field %q argument %q of type %s! is required", sel.name, name, def.Args[name].Type)
+				}
+			}
+
+			if child, ok := namedType(def.Type).(*Object); ok {
+				if len(sel.selections) == 0 {
+					fail("field %q of type %q must have a selection of subfields", sel.name, def.Type)
+					continue
+				}
+				errs = append(errs, ex.validate(child, sel.selections, visited)...)
+			} else if len(sel.selections) > 0 {
+				fail("field %q must not have a selection since type %q has no subfields", sel.name, def.Type)
+			}
+
+		case *fragmentSpread:
+			frag, ok := ex.doc.fragments[sel.name]
+			if !ok {
+				fail("unknown fragment %q", sel.name)
+				continue
+			}
+			if frag.typeCondition != obj.Name {
+				fail("fragment %q cannot be spread here as it is defined on type %q, not %q", sel.name, frag.typeCondition, obj.Name)
+				continue
+			}
+			if visited[sel.name] {
+				continue
+			}
+			visited[sel.name] = true
+			errs = append(errs, ex.validate(obj, frag.selections, visited)...)
+
+		case *inlineFragment:
+			if sel.typeCondition != "" && sel.typeCondition != obj.Name {
+				fail("fragment cannot be spread here as it is defined on type %q, not %q", sel.typeCondition, obj.Name)
+				continue
+			}
+			errs = append(errs, ex.validate(obj, sel.selections, visited)...)
+		}
+	}
+
+	return errs
+}
+
+// fieldGroup is the set of fields selected under the same response key
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+// collectFields flattens fragments and applies @skip and @include, grouping fields by response key
+func (ex *execution) collectFields(obj *Object, selections []selection, groups []*fieldGroup, index map[string]int, visited map[string]bool) []*fieldGroup {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if !ex.included(sel.directives) {
+				continue
+			}
+			key := sel.responseKey()
+			if i, ok := index[key]; ok {
+				groups[i].fields = append(groups[i].fields, sel)
+				continue
+			}
+			index[key] = len(groups)
+			groups = append(groups, &fieldGroup{key: key, fields: []*field{sel}})
+
+		case *fragmentSpread:
+			if visited[sel.name] || !ex.included(sel.directives) {
+				continue
+			}
+			visited[sel.name] = true
+			if frag, ok := ex.doc.fragments[sel.name]; ok && frag.typeCondition == obj.Name {
+				groups = ex.collectFields(obj, frag.selections, groups, index, visited)
+			}
+
+		case *inlineFragment:
+			if !ex.included(sel.directives) || (sel.typeCondition != "" && sel.typeCondition != obj.Name) {
+				continue
+			}
+			groups = ex.collectFields(obj, sel.selections, groups, index, visited)
+		}
+	}
+	return groups
+}
+
+// included evaluates the @skip and @include directives
+func (ex *execution) included(directives []*directive) bool {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			continue
+		}
+		condition, _ := ex.resolveValue(d.arguments["if"])
+		if value, _ := condition.(bool); value == (d.name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+// executeSelectionSet resolves the selected fields of an object. Fields with resolvers run
+// concurrently so independent lookups, typically calls to other services, overlap.
+func (ex *execution) executeSelectionSet(ctx context.Context, obj *Object, source map[string]interface{}, selections []selection, path []interface{}) *orderedMap {
+	groups := ex.collectFields(obj, selections, nil, make(map[string]int), make(map[string]bool))
+	result := newOrderedMap(len(groups))
+
+	var wg sync.WaitGroup
+	for _, group := range groups {
+		if group.fields[0].name == "__typename" {
+			result.set(group.key, obj.Name)
+			continue
+		}
+
+		def := obj.Fields[group.fields[0].name]
+		fieldPath := appendPath(path, group.key)
+		result.set(group.key, nil)
+
+		if def.Resolve == nil {
+			result.set(group.key, ex.executeField(ctx, def, group, source, fieldPath))
+			continue
+		}
+
+		wg.Add(1)
+		go func(group *fieldGroup) {
+			defer wg.Done()
+			result.set(group.key, ex.executeField(ctx, def, group, source, fieldPath))
+		}(group)
+	}
+	wg.Wait()
+
+	return result
+}
+
+// executeField resolves a field and completes its value
+func (ex *execution) executeField(ctx context.Context, def *Field, group *fieldGroup, source map[string]interface{}, path []interface{}) (value interface{}) {
+	f := group.fields[0]
+
+	defer func() {
+		if r := recover(); r != nil {
+			ex.addError(fmt.Errorf("internal error resolving field %q: %v", f.name, r), path)
+			value = nil
+		}
+	}()
+
+	args, err := ex.coerceArguments(def, f)
+	if err != nil {
+		ex.addError(err, path)
+		return nil
+	}
+
+	var resolved interface{}
+	if def.Resolve != nil {
+		resolved, err = def.Resolve(ResolveParams{
+			Context: ctx,
+			Root:    ex.root,
+			Source:  source,
+			Args:    args,
+		})
+		if err != nil {
+			ex.addError(err, path)
+			return nil
+		}
+	} else if source != nil {
+		resolved = source[f.name]
+	}
+
+	// Sub-selections of fields sharing a response key are merged
+	var selections []selection
+	for _, f := range group.fields {
+		selections = append(selections, f.selections...)
+	}
+
+	return ex.completeValue(ctx, def.Type, selections, resolved, path)
+}
+
+// completeValue serializes a resolved value according to its type
+func (ex *execution) completeValue(ctx context.Context, t Type, selections []selection, value interface{}, path []interface{}) interface{} {
+	if isNil(value) {
+		return nil
+	}
+
+	switch t := t.(type) {
+	case *Scalar:
+		serialized, ok := t.Serialize(value)
+		if !ok {
+			ex.addError(fmt.Errorf("%s cannot represent value: %v", t.Name, value), path)
+			return nil
+		}
+		return serialized
+
+	case *List:
+		items := reflect.ValueOf(value)
+		if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+			ex.addError(fmt.Errorf("expected a list for %s, found %T", t, value), path)
+			return nil
+		}
+		completed := make([]interface{}, items.Len())
+		for i := range completed {
+			completed[i] = ex.completeValue(ctx, t.OfType, selections, items.Index(i).Interface(), appendPath(path, i))
+		}
+		return completed
+
+	case *Object:
+		source, err := toObject(value)
+		if err != nil {
+			ex.addError(fmt.Errorf("expected an object for %s: %v", t.Name, err), path)
+			return nil
+		}
+		return ex.executeSelectionSet(ctx, t, source, selections, path)
+	}
+
+	ex.addError(fmt.Errorf("unsupported type %s", t), path)
+	return nil
+}
+
+// coerceArguments resolves variables in a field's arguments and converts them to their types
+func (ex *execution) coerceArguments(def *Field, f *field) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(def.Args))
+	for name, arg := range def.Args {
+		var value interface{}
+		raw, ok := f.arguments[name]
+		if ok {
+			value, ok = ex.resolveValue(raw)
+		}
+
+		if !ok || value == nil {
+			if arg.Default != nil {
+				args[name] = arg.Default
+			} else if arg.Required {
+				return nil, fmt.Errorf("argument %q of type %s! is required", name, arg.Type)
+			}
+			continue
+		}
+
+		coerced, valid := arg.Type.ParseValue(value)
+		if !valid {
+			return nil, fmt.Errorf("argument %q has invalid value %v: expected %s", name, value, arg.Type)
+		}
+		args[name] = coerced
+	}
+	return args, nil
+}
+
+// resolveValue substitutes variables in an argument value. It reports false for a variable
+// that was not provided.
+func (ex *execution) resolveValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case variable:
+		resolved, ok := ex.variables[string(v)]
+		return resolved, ok
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i], _ = ex.resolveValue(item)
+		}
+		return list, true
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			object[key], _ = ex.resolveValue(item)
+		}
+		return object, true
+	}
+	return value, true
+}
+
+// toObject decodes a resolved value as a JSON object so fields can be read by name
+func toObject(value interface{}) (map[string]interface{}, error) {
+	if object, ok := value.(map[string]interface{}); ok {
+		return object, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func namedType(t Type) Type {
+	for {
+		list, ok := t.(*List)
+		if !ok {
+			return t
+		}
+		t = list.OfType
+	}
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// appendPath returns a copy of the path with an element added, so concurrently resolved
+// fields never share a backing array
+func appendPath(path []interface{}, element interface{}) []interface{} {
+	extended := make([]interface{}, len(path)+1)
+	copy(extended, path)
+	extended[len(path)] = element
+	return extended
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// orderedMap is a JSON object that keeps fields in the order they were selected
+type orderedMap struct {
+	mu     sync.Mutex
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap(size int) *orderedMap {
+	return &orderedMap{
+		keys:   make([]string, 0, size),
+		values: make(map[string]interface{}, size),
+	}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// MarshalJSON encodes the fields in selection order
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
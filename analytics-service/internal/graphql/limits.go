@@ -0,0 +1,159 @@
+package graphql
+
+import "fmt"
+
+// Limits bounds the work a single query can cause. Zero fields take the defaults.
+type Limits struct {
+	// MaxDepth is how deeply fields may be nested
+	MaxDepth int
+	// MaxComplexity is the estimated number of resolver calls a query may make
+	MaxComplexity int
+	// MaxAliases is how many aliased fields a query may select
+	MaxAliases int
+}
+
+// Default limits, generous enough for the dashboards built on the schema
+const (
+	DefaultMaxDepth      = 10
+	DefaultMaxComplexity = 1000
+	DefaultMaxAliases    = 20
+)
+
+// defaultListSize is the number of items assumed for a list field without a limit argument
+const defaultListSize = 10
+
+// maxEstimate caps cost estimates so deeply multiplied lists cannot overflow. Anything this
+// large is over every limit anyway.
+const maxEstimate = 1 << 30
+
+// withDefaults fills in unset limits
+func (l Limits) withDefaults() Limits {
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultMaxDepth
+	}
+	if l.MaxComplexity <= 0 {
+		l.MaxComplexity = DefaultMaxComplexity
+	}
+	if l.MaxAliases <= 0 {
+		l.MaxAliases = DefaultMaxAliases
+	}
+	return l
+}
+
+// cost is the measured size of a selection set
+type cost struct {
+	depth      int
+	complexity int
+	aliases    int
+}
+
+// checkLimits rejects a validated query that is nested too deeply, would call too many
+// resolvers or selects too many aliases
+func (ex *execution) checkLimits(obj *Object, selections []selection, limits Limits) error {
+	c, err := ex.measure(obj, selections, make(map[string]*cost))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.depth > limits.MaxDepth:
+		return fmt.Errorf("query depth %d exceeds the limit of %d", c.depth, limits.MaxDepth)
+	case c.complexity > limits.MaxComplexity:
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", c.complexity, limits.MaxComplexity)
+	case c.aliases > limits.MaxAliases:
+		return fmt.Errorf("query uses %d aliases, more than the limit of %d", c.aliases, limits.MaxAliases)
+	}
+	return nil
+}
+
+// measure computes the cost of selections on obj. A field with a resolver costs one call, and
+// the selections below a list are counted once per item it is expected to return. Fragments
+// are measured once and reused at every spread; a fragment that spreads itself is an error.
+func (ex *execution) measure(obj *Object, selections []selection, fragments map[string]*cost) (cost, error) {
+	var total cost
+	add := func(c cost) {
+		total.depth = max(total.depth, c.depth)
+		total.complexity = min(total.complexity+c.complexity, maxEstimate)
+		total.aliases = min(total.aliases+c.aliases, maxEstimate)
+	}
+
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			var c cost
+			if sel.alias != "" {
+				c.aliases = 1
+			}
+			def, ok := obj.Fields[sel.name]
+			if !ok {
+				// __typename
+				add(c)
+				continue
+			}
+			if def.Resolve != nil {
+				c.complexity = 1
+			}
+
+			if child, ok := namedType(def.Type).(*Object); ok {
+				children, err := ex.measure(child, sel.selections, fragments)
+				if err != nil {
+					return cost{}, err
+				}
+				c.depth = children.depth
+				c.complexity += min(children.complexity*ex.listSize(def, sel), maxEstimate)
+				c.aliases += children.aliases
+			}
+			c.depth++
+			add(c)
+
+		case *fragmentSpread:
+			c, seen := fragments[sel.name]
+			if seen && c == nil {
+				return cost{}, fmt.Errorf("fragment %q spreads itself", sel.name)
+			}
+			if !seen {
+				fragments[sel.name] = nil
+				measured, err := ex.measure(obj, ex.doc.fragments[sel.name].selections, fragments)
+				if err != nil {
+					return cost{}, err
+				}
+				c = &measured
+				fragments[sel.name] = c
+			}
+			add(*c)
+
+		case *inlineFragment:
+			c, err := ex.measure(obj, sel.selections, fragments)
+			if err != nil {
+				return cost{}, err
+			}
+			add(c)
+		}
+	}
+
+	return total, nil
+}
+
+// listSize is the number of items a field is expected to return: the value of its limit
+// argument for lists that have one, defaultListSize for other lists and one for objects
+func (ex *execution) listSize(def *Field, f *field) int {
+	if _, ok := def.Type.(*List); !ok {
+		return 1
+	}
+	arg, ok := def.Args["limit"]
+	if !ok {
+		return defaultListSize
+	}
+
+	value, ok := f.arguments["limit"]
+	if ok {
+		value, ok = ex.resolveValue(value)
+	}
+	if !ok || value == nil {
+		value = arg.Default
+	}
+	if limit, ok := toInt(value); ok && limit > 0 {
+		return limit
+	}
+	return defaultListSize
+}
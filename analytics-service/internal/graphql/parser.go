@@ -0,0 +1,785 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed GraphQL request document
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query, mutation or subscription definition
+type operation struct {
+	kind       string
+	name       string
+	variables  []*variableDefinition
+	directives []*directive
+	selections []selection
+}
+
+// variableDefinition declares a variable accepted by an operation
+type variableDefinition struct {
+	name         string
+	typ          *typeRef
+	defaultValue interface{}
+}
+
+// typeRef is a reference to a named, list or non-null type
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	name := t.name
+	if t.elem != nil {
+		name = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		name += "!"
+	}
+	return name
+}
+
+// fragment is a named fragment definition
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+}
+
+// selection is a *field, *fragmentSpread or *inlineFragment
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  map[string]interface{}
+	directives []*directive
+	selections []selection
+}
+
+// responseKey returns the key under which the field appears in the result
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+}
+
+type directive struct {
+	name      string
+	arguments map[string]interface{}
+}
+
+// variable is a reference to an operation variable inside an argument value
+type variable string
+
+// enumValue is an unquoted enum literal inside an argument value
+type enumValue string
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of document"
+	case tokenString:
+		return strconv.Quote(t.value)
+	default:
+		return fmt.Sprintf("%q", t.value)
+	}
+}
+
+// lexer splits a document into tokens, skipping whitespace, commas and comments
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunctuator, value: "...", pos: start}, nil
+	case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunctuator, value: string(c), pos: start}, nil
+	case isNameStart(c):
+		for l.pos < len(l.src) && isNameContinue(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		return l.readNumber()
+	case c == '"':
+		return l.readString()
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, fmt.Errorf("syntax error at position %d: unexpected character %q", start, r)
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) readNumber() (token, error) {
+	start := l.pos
+	kind := tokenInt
+
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	if !l.readDigits() {
+		return token{}, fmt.Errorf("syntax error at position %d: invalid number", start)
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		if !l.readDigits() {
+			return token{}, fmt.Errorf("syntax error at position %d: invalid number", start)
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if !l.readDigits() {
+			return token{}, fmt.Errorf("syntax error at position %d: invalid number", start)
+		}
+	}
+
+	return token{kind: kind, value: l.src[start:l.pos], pos: start}, nil
+}
+
+func (l *lexer) readDigits() bool {
+	start := l.pos
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+	return l.pos > start
+}
+
+func (l *lexer) readString() (token, error) {
+	start := l.pos
+
+	// Block strings are taken verbatim
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		end := strings.Index(l.src[l.pos+3:], `"""`)
+		if end < 0 {
+			return token{}, fmt.Errorf("syntax error at position %d: unterminated string", start)
+		}
+		value := l.src[l.pos+3 : l.pos+3+end]
+		l.pos += end + 6
+		return token{kind: tokenString, value: value, pos: start}, nil
+	}
+
+	var sb strings.Builder
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokenString, value: sb.String(), pos: start}, nil
+		case c == '\n' || c == '\r':
+			return token{}, fmt.Errorf("syntax error at position %d: unterminated string", start)
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, fmt.Errorf("syntax error at position %d: unterminated string", start)
+			}
+			escape := l.src[l.pos+1]
+			l.pos += 2
+			switch escape {
+			case '"', '\\', '/':
+				sb.WriteByte(escape)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, fmt.Errorf("syntax error at position %d: invalid unicode escape", l.pos)
+				}
+				code, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, fmt.Errorf("syntax error at position %d: invalid unicode escape", l.pos)
+				}
+				sb.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				return token{}, fmt.Errorf("syntax error at position %d: invalid escape sequence \\%c", l.pos-2, escape)
+			}
+		default:
+			sb.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	return token{}, fmt.Errorf("syntax error at position %d: unterminated string", start)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// maxNesting bounds how deeply selection sets and values may be nested, so a document cannot
+// exhaust the stack while it is parsed
+const maxNesting = 64
+
+// parser builds a document from the token stream
+type parser struct {
+	lex   lexer
+	tok   token
+	depth int
+}
+
+// parse parses a GraphQL request document
+func parse(src string) (*document, error) {
+	p := &parser{lex: lexer{src: src}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections})
+		case p.tok.kind == tokenName && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.tok.kind == tokenName && p.tok.value == "fragment":
+			frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := doc.fragments[frag.name]; exists {
+				return nil, fmt.Errorf("there can be only one fragment named %q", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document does not contain an operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// peek reports whether the current token is the given punctuator
+func (p *parser) peek(punctuator string) bool {
+	return p.tok.kind == tokenPunctuator && p.tok.value == punctuator
+}
+
+func (p *parser) unexpected() error {
+	return fmt.Errorf("syntax error at position %d: unexpected %s", p.tok.pos, p.tok)
+}
+
+// expect consumes the given punctuator
+// nest enters a nested selection set or value. Callers must decrement depth when they leave it.
+func (p *parser) nest() error {
+	p.depth++
+	if p.depth > maxNesting {
+		return fmt.Errorf("syntax error at position %d: document is nested more than %d levels deep", p.tok.pos, maxNesting)
+	}
+	return nil
+}
+
+func (p *parser) expect(punctuator string) error {
+	if !p.peek(punctuator) {
+		return fmt.Errorf("syntax error at position %d: expected %q, found %s", p.tok.pos, punctuator, p.tok)
+	}
+	return p.advance()
+}
+
+// expectName consumes a name and returns it
+func (p *parser) expectName() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", fmt.Errorf("syntax error at position %d: expected name, found %s", p.tok.pos, p.tok)
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+// expectKeyword consumes the given name
+func (p *parser) expectKeyword(keyword string) error {
+	if p.tok.kind != tokenName || p.tok.value != keyword {
+		return fmt.Errorf("syntax error at position %d: expected %q, found %s", p.tok.pos, keyword, p.tok)
+	}
+	return p.advance()
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{kind: p.tok.value}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName {
+		op.name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek("(") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.peek(")") {
+			def, err := p.parseVariableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.variables = append(op.variables, def)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if op.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if op.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) parseVariableDefinition() (*variableDefinition, error) {
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+
+	def := &variableDefinition{name: name}
+	if def.typ, err = p.parseTypeRef(); err != nil {
+		return nil, err
+	}
+
+	if p.peek("=") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if def.defaultValue, err = p.parseValue(true); err != nil {
+			return nil, err
+		}
+	}
+
+	// Directives on variable definitions have no effect here
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+func (p *parser) parseTypeRef() (*typeRef, error) {
+	ref := &typeRef{}
+	if p.peek("[") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		elem, err := p.parseTypeRef()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		ref.elem = elem
+	} else {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		ref.name = name
+	}
+
+	if p.peek("!") {
+		ref.nonNull = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	return ref, nil
+}
+
+func (p *parser) parseFragment() (*fragment, error) {
+	if err := p.expectKeyword("fragment"); err != nil {
+		return nil, err
+	}
+
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, fmt.Errorf("syntax error: fragment cannot be named \"on\"")
+	}
+	if err := p.expectKeyword("on"); err != nil {
+		return nil, err
+	}
+
+	frag := &fragment{name: name}
+	if frag.typeCondition, err = p.expectName(); err != nil {
+		return nil, err
+	}
+	if frag.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if frag.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	defer func() { p.depth-- }()
+	if err := p.nest(); err != nil {
+		return nil, err
+	}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []selection
+	for !p.peek("}") {
+		if p.tok.kind == tokenEOF {
+			return nil, p.unexpected()
+		}
+
+		var sel selection
+		var err error
+		if p.peek("...") {
+			sel, err = p.parseFragmentSelection()
+		} else {
+			sel, err = p.parseField()
+		}
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("syntax error at position %d: selection set cannot be empty", p.tok.pos)
+	}
+	return selections, p.advance()
+}
+
+func (p *parser) parseFragmentSelection() (selection, error) {
+	if err := p.expect("..."); err != nil {
+		return nil, err
+	}
+
+	// A name other than "on" is a named fragment spread
+	if p.tok.kind == tokenName && p.tok.value != "on" {
+		spread := &fragmentSpread{name: p.tok.value}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if spread.directives, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		return spread, nil
+	}
+
+	inline := &inlineFragment{}
+	var err error
+	if p.tok.kind == tokenName {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if inline.typeCondition, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+	if inline.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if inline.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return inline, nil
+}
+
+func (p *parser) parseField() (*field, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &field{name: name}
+	if p.peek(":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		f.alias = name
+		if f.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.arguments, err = p.parseArguments(false); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) parseArguments(constant bool) (map[string]interface{}, error) {
+	if !p.peek("(") {
+		return nil, nil
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	arguments := make(map[string]interface{})
+	for !p.peek(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := arguments[name]; exists {
+			return nil, fmt.Errorf("there can be only one argument named %q", name)
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arguments[name], err = p.parseValue(constant); err != nil {
+			return nil, err
+		}
+	}
+	return arguments, p.advance()
+}
+
+func (p *parser) parseDirectives() ([]*directive, error) {
+	var directives []*directive
+	for p.peek("@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		arguments, err := p.parseArguments(false)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, &directive{name: name, arguments: arguments})
+	}
+	return directives, nil
+}
+
+// parseValue parses an argument value. Constant values may not reference variables.
+func (p *parser) parseValue(constant bool) (interface{}, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenPunctuator:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, fmt.Errorf("syntax error at position %d: variables are not allowed here", tok.pos)
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			return variable(name), nil
+		case "[":
+			return p.parseList(constant)
+		case "{":
+			return p.parseObject(constant)
+		}
+	case tokenInt:
+		value, err := strconv.Atoi(tok.value)
+		if err != nil {
+			return nil, fmt.Errorf("syntax error at position %d: integer %s out of range", tok.pos, tok.value)
+		}
+		return value, p.advance()
+	case tokenFloat:
+		value, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("syntax error at position %d: invalid float %s", tok.pos, tok.value)
+		}
+		return value, p.advance()
+	case tokenString:
+		return tok.value, p.advance()
+	case tokenName:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		switch tok.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return enumValue(tok.value), nil
+	}
+
+	return nil, p.unexpected()
+}
+
+func (p *parser) parseList(constant bool) (interface{}, error) {
+	defer func() { p.depth-- }()
+	if err := p.nest(); err != nil {
+		return nil, err
+	}
+
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+
+	list := []interface{}{}
+	for !p.peek("]") {
+		if p.tok.kind == tokenEOF {
+			return nil, p.unexpected()
+		}
+		value, err := p.parseValue(constant)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, p.advance()
+}
+
+func (p *parser) parseObject(constant bool) (interface{}, error) {
+	defer func() { p.depth-- }()
+	if err := p.nest(); err != nil {
+		return nil, err
+	}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	object := make(map[string]interface{})
+	for !p.peek("}") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if object[name], err = p.parseValue(constant); err != nil {
+			return nil, err
+		}
+	}
+	return object, p.advance()
+}
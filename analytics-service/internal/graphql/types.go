@@ -0,0 +1,251 @@
+// Package graphql implements a small GraphQL query engine: a schema of object types with
+// field resolvers, and an executor for query operations against it.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Type is a GraphQL output type: a *Scalar, *List or *Object
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type
+type Scalar struct {
+	Name string
+	// Serialize converts a resolved value to its JSON representation
+	Serialize func(value interface{}) (interface{}, bool)
+	// ParseValue converts an argument or variable value to the Go value passed to resolvers
+	ParseValue func(value interface{}) (interface{}, bool)
+}
+
+func (s *Scalar) String() string {
+	return s.Name
+}
+
+// List is a list of another type
+type List struct {
+	OfType Type
+}
+
+func (l *List) String() string {
+	return "[" + l.OfType.String() + "]"
+}
+
+// Object is a type with named fields
+type Object struct {
+	Name   string
+	Fields Fields
+}
+
+func (o *Object) String() string {
+	return o.Name
+}
+
+// Fields maps field names to their definitions
+type Fields map[string]*Field
+
+// Field defines a field of an object type. Fields without a resolver read the value of the
+// same name from their parent object.
+type Field struct {
+	Type    Type
+	Args    Args
+	Resolve ResolveFunc
+}
+
+// Args maps argument names to their definitions
+type Args map[string]*Argument
+
+// Argument defines a field argument. Optional arguments that are not given take the default
+// value, or are left out of the resolver's arguments if there is none.
+type Argument struct {
+	Type     *Scalar
+	Required bool
+	Default  interface{}
+}
+
+// ResolveParams is passed to field resolvers
+type ResolveParams struct {
+	Context context.Context
+	// Root is the root value given to Execute
+	Root interface{}
+	// Source is the parent object, decoded as a JSON object
+	Source map[string]interface{}
+	Args   map[string]interface{}
+}
+
+// ResolveFunc resolves the value of a field
+type ResolveFunc func(p ResolveParams) (interface{}, error)
+
+// Schema is an executable schema
+type Schema struct {
+	Query *Object
+	// Limits bounds the size of the queries the schema executes
+	Limits Limits
+}
+
+// Built-in scalars. Time values are RFC 3339 strings and JSON values are passed through as-is.
+var (
+	String = &Scalar{
+		Name: "String",
+		Serialize: func(value interface{}) (interface{}, bool) {
+			switch v := value.(type) {
+			case string:
+				return v, true
+			case fmt.Stringer:
+				return v.String(), true
+			}
+			return nil, false
+		},
+		ParseValue: func(value interface{}) (interface{}, bool) {
+			v, ok := value.(string)
+			return v, ok
+		},
+	}
+
+	ID = &Scalar{
+		Name: "ID",
+		Serialize: func(value interface{}) (interface{}, bool) {
+			switch v := value.(type) {
+			case string:
+				return v, true
+			case fmt.Stringer:
+				return v.String(), true
+			}
+			if n, ok := toInt(value); ok {
+				return strconv.Itoa(n), true
+			}
+			return nil, false
+		},
+		ParseValue: func(value interface{}) (interface{}, bool) {
+			if v, ok := value.(string); ok {
+				return v, true
+			}
+			if n, ok := toInt(value); ok {
+				return strconv.Itoa(n), true
+			}
+			return nil, false
+		},
+	}
+
+	Int = &Scalar{
+		Name: "Int",
+		Serialize: func(value interface{}) (interface{}, bool) {
+			return toInt(value)
+		},
+		ParseValue: func(value interface{}) (interface{}, bool) {
+			return toInt(value)
+		},
+	}
+
+	Float = &Scalar{
+		Name: "Float",
+		Serialize: func(value interface{}) (interface{}, bool) {
+			return toFloat(value)
+		},
+		ParseValue: func(value interface{}) (interface{}, bool) {
+			return toFloat(value)
+		},
+	}
+
+	Boolean = &Scalar{
+		Name: "Boolean",
+		Serialize: func(value interface{}) (interface{}, bool) {
+			v, ok := value.(bool)
+			return v, ok
+		},
+		ParseValue: func(value interface{}) (interface{}, bool) {
+			v, ok := value.(bool)
+			return v, ok
+		},
+	}
+
+	Time = &Scalar{
+		Name: "Time",
+		Serialize: func(value interface{}) (interface{}, bool) {
+			switch v := value.(type) {
+			case string:
+				return v, true
+			case time.Time:
+				return v.Format(time.RFC3339Nano), true
+			case *time.Time:
+				return v.Format(time.RFC3339Nano), true
+			}
+			return nil, false
+		},
+		ParseValue: func(value interface{}) (interface{}, bool) {
+			s, ok := value.(string)
+			if !ok {
+				return nil, false
+			}
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, false
+			}
+			return t, true
+		},
+	}
+
+	JSON = &Scalar{
+		Name: "JSON",
+		Serialize: func(value interface{}) (interface{}, bool) {
+			return value, true
+		},
+		ParseValue: func(value interface{}) (interface{}, bool) {
+			return value, true
+		},
+	}
+)
+
+// toInt converts whole numbers that fit in 32 bits, as the GraphQL Int type requires
+func toInt(value interface{}) (int, bool) {
+	var f float64
+	switch v := value.(type) {
+	case int:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case float64:
+		f = v
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil {
+			return 0, false
+		}
+		f = n
+	default:
+		return 0, false
+	}
+
+	if f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
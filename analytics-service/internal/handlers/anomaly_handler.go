@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,8 +13,9 @@ import (
 
 // AnomalyHandler handles anomaly-related requests
 type AnomalyHandler struct {
-	anomalyService *service.AnomalyService
-	securityClient interface {
+	anomalyService  *service.AnomalyService
+	detectorService *service.DetectorService
+	securityClient  interface {
 		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
 	}
 }
@@ -21,13 +23,15 @@ type AnomalyHandler struct {
 // NewAnomalyHandler creates a new anomaly handler
 func NewAnomalyHandler(
 	anomalyService *service.AnomalyService,
+	detectorService *service.DetectorService,
 	securityClient interface {
 		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
 	},
 ) *AnomalyHandler {
 	return &AnomalyHandler{
-		anomalyService: anomalyService,
-		securityClient: securityClient,
+		anomalyService:  anomalyService,
+		detectorService: detectorService,
+		securityClient:  securityClient,
 	}
 }
 
@@ -70,11 +74,7 @@ func (h *AnomalyHandler) GetAnomaly(c *gin.Context) {
 func (h *AnomalyHandler) ListAnomalies(c *gin.Context) {
 	var req models.ListAnomaliesRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid query parameters",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid query parameters", err)
 		return
 	}
 
@@ -112,16 +112,33 @@ func (h *AnomalyHandler) ListAnomalies(c *gin.Context) {
 	}, ""))
 }
 
+// AnalyzeRootCauses handles re-ranking the candidate root causes of an anomaly
+// POST /analytics/anomalies/{anomalyId}/root-causes
+func (h *AnomalyHandler) AnalyzeRootCauses(c *gin.Context) {
+	anomalyID := c.Param("anomalyId")
+
+	response, err := h.anomalyService.AnalyzeRootCauses(c.Request.Context(), anomalyID)
+	if err != nil {
+		switch {
+		case err.Error() == "anomaly not found":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeAnomalyNotFound, err.Error(), ""))
+		case errors.Is(err, service.ErrRootCauseAnalysisDisabled):
+			c.JSON(http.StatusConflict, models.NewErrorResponse(models.ErrCodeConflict, err.Error(), ""))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Root causes analyzed successfully"))
+}
+
 // AcknowledgeAnomaly handles anomaly acknowledgment
 // POST /analytics/anomalies/acknowledge
 func (h *AnomalyHandler) AcknowledgeAnomaly(c *gin.Context) {
 	var req models.AcknowledgeAnomalyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"analytics-service/internal/middleware"
+	"analytics-service/internal/models"
+	"analytics-service/internal/service"
+)
+
+// BudgetHandler handles energy budget and savings goal requests
+type BudgetHandler struct {
+	budgetService      *service.BudgetService
+	savingsGoalService *service.SavingsGoalService
+	securityClient     interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewBudgetHandler creates a new budget handler
+func NewBudgetHandler(
+	budgetService *service.BudgetService,
+	savingsGoalService *service.SavingsGoalService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *BudgetHandler {
+	return &BudgetHandler{
+		budgetService:      budgetService,
+		savingsGoalService: savingsGoalService,
+		securityClient:     securityClient,
+	}
+}
+
+// CreateBudget handles budget creation
+// POST /analytics/budgets
+func (h *BudgetHandler) CreateBudget(c *gin.Context) {
+	var req models.EnergyBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.budgetService.CreateBudget(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "CREATE_BUDGET", "energy_budget", "",
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			map[string]interface{}{"buildingId": req.BuildingID, "deviceId": req.DeviceID},
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "CREATE_BUDGET", "energy_budget", response.ID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"buildingId": req.BuildingID, "deviceId": req.DeviceID, "monthlyLimitKwh": req.MonthlyLimitKWh},
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Budget created successfully"))
+}
+
+// ListBudgets handles budget listing
+// GET /analytics/budgets
+func (h *BudgetHandler) ListBudgets(c *gin.Context) {
+	var req models.ListBudgetsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	responses, total, err := h.budgetService.ListBudgets(c.Request.Context(), req.BuildingID, req.DeviceID, req.Page, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"budgets": responses,
+		"total":   total,
+		"page":    req.Page,
+		"limit":   req.Limit,
+	}, ""))
+}
+
+// GetBudget handles budget retrieval
+// GET /analytics/budgets/{budgetId}
+func (h *BudgetHandler) GetBudget(c *gin.Context) {
+	response, err := h.budgetService.GetBudget(c.Request.Context(), c.Param("budgetId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetBudgetStatus handles budget status retrieval
+// GET /analytics/budgets/{budgetId}/status
+func (h *BudgetHandler) GetBudgetStatus(c *gin.Context) {
+	response, err := h.budgetService.GetBudgetStatus(c.Request.Context(), c.Param("budgetId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// UpdateBudget handles budget updates
+// PUT /analytics/budgets/{budgetId}
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	budgetID := c.Param("budgetId")
+
+	var req models.EnergyBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.budgetService.UpdateBudget(c.Request.Context(), budgetID, &req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "UPDATE_BUDGET", "energy_budget", budgetID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "UPDATE_BUDGET", "energy_budget", budgetID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"monthlyLimitKwh": req.MonthlyLimitKWh},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Budget updated successfully"))
+}
+
+// DeleteBudget handles budget deletion
+// DELETE /analytics/budgets/{budgetId}
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	budgetID := c.Param("budgetId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.budgetService.DeleteBudget(c.Request.Context(), budgetID); err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "DELETE_BUDGET", "energy_budget", budgetID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "DELETE_BUDGET", "energy_budget", budgetID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Budget deleted successfully"))
+}
+
+// respondError maps budget and savings goal service errors to HTTP responses
+func (h *BudgetHandler) respondError(c *gin.Context, err error) {
+	if strings.HasPrefix(err.Error(), "validation failed") {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+		return
+	}
+
+	switch err.Error() {
+	case "budget not found", "savings goal not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case "invalid budget ID format", "invalid savings goal ID format":
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	case "budget already exists for this building or device":
+		c.JSON(http.StatusConflict, models.NewErrorResponse(models.ErrCodeConflict, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"analytics-service/internal/middleware"
+	"analytics-service/internal/models"
+	"analytics-service/internal/service"
+)
+
+// CostHandler handles energy cost HTTP requests
+type CostHandler struct {
+	costService *service.CostService
+}
+
+// NewCostHandler creates a new cost handler
+func NewCostHandler(costService *service.CostService) *CostHandler {
+	return &CostHandler{costService: costService}
+}
+
+// GetCost handles pricing a building's consumption over a period
+// GET /analytics/cost?buildingId=&period=
+func (h *CostHandler) GetCost(c *gin.Context) {
+	var query models.CostQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	response, err := h.costService.GetCost(c.Request.Context(), &query, middleware.GetToken(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// CompareCosts handles pricing a baseline and an optimized load profile for another service
+// POST /internal/cost/compare
+func (h *CostHandler) CompareCosts(c *gin.Context) {
+	var req models.CostComparisonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.costService.CompareCosts(&req), ""))
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -53,7 +55,11 @@ func (h *DashboardHandler) GetBuildingDashboard(c *gin.Context) {
 		return
 	}
 
+	// Kiosk tokens are not accepted by other services, so their data is fetched with the service key
 	token := middleware.GetToken(c)
+	if middleware.GetKiosk(c) != nil {
+		token = ""
+	}
 
 	response, err := h.dashboardService.GetBuildingDashboard(c.Request.Context(), buildingID, token)
 	if err != nil {
@@ -67,3 +73,81 @@ func (h *DashboardHandler) GetBuildingDashboard(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
 }
+
+// GetPortfolioDashboard handles portfolio dashboard retrieval across buildings
+// GET /analytics/dashboards/portfolio
+func (h *DashboardHandler) GetPortfolioDashboard(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			"days must be between 1 and 365",
+			"",
+		))
+		return
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -days)
+	token := middleware.GetToken(c)
+
+	// Super admins see every building and organization admins the buildings of their organization;
+	// other users only see buildings their devices belong to
+	restrictToAccessible := !middleware.SeesAllBuildings(c)
+
+	response, err := h.dashboardService.GetPortfolioDashboard(c.Request.Context(), from, to, restrictToAccessible, token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetZoneConsumption handles rolling a building's consumption up by floor, zone or room
+// GET /analytics/zone-consumption?buildingId=&level=&period=
+func (h *DashboardHandler) GetZoneConsumption(c *gin.Context) {
+	var query models.ZoneConsumptionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	response, err := h.dashboardService.GetZoneConsumption(c.Request.Context(), &query, middleware.GetToken(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetTopConsumers handles ranking a building's devices by consumption
+// GET /analytics/top-consumers?buildingId=&period=
+func (h *DashboardHandler) GetTopConsumers(c *gin.Context) {
+	var query models.TopConsumersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	response, err := h.dashboardService.GetTopConsumers(c.Request.Context(), &query, middleware.GetToken(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
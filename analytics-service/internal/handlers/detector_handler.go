@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"analytics-service/internal/middleware"
+	"analytics-service/internal/models"
+)
+
+// ListDetectorConfigs handles anomaly detector configuration listing
+// GET /analytics/anomaly-detectors
+func (h *AnomalyHandler) ListDetectorConfigs(c *gin.Context) {
+	var req models.ListDetectorConfigsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	configs, err := h.detectorService.ListConfigs(c.Request.Context(), &req)
+	if err != nil {
+		h.respondDetectorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"configs": configs,
+		"total":   len(configs),
+	}, ""))
+}
+
+// GetLiveDetectorConfigs handles retrieval of the configuration each detector currently runs with
+// GET /analytics/anomaly-detectors/live
+func (h *AnomalyHandler) GetLiveDetectorConfigs(c *gin.Context) {
+	configs, err := h.detectorService.LiveConfigs(c.Request.Context())
+	if err != nil {
+		h.respondDetectorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(configs, ""))
+}
+
+// GetDetectorConfig handles anomaly detector configuration retrieval
+// GET /analytics/anomaly-detectors/{configId}
+func (h *AnomalyHandler) GetDetectorConfig(c *gin.Context) {
+	config, err := h.detectorService.GetConfig(c.Request.Context(), c.Param("configId"))
+	if err != nil {
+		h.respondDetectorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(config, ""))
+}
+
+// CreateDetectorConfig handles creation of a shadow anomaly detector configuration
+// POST /analytics/anomaly-detectors
+func (h *AnomalyHandler) CreateDetectorConfig(c *gin.Context) {
+	var req models.DetectorConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	config, err := h.detectorService.CreateConfig(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "CREATE_DETECTOR_CONFIG", "detector_config", "",
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			map[string]interface{}{"detector": req.Detector, "name": req.Name},
+		)
+		h.respondDetectorError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "CREATE_DETECTOR_CONFIG", "detector_config", config.ConfigID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"detector": config.Detector, "name": config.Name, "params": config.Params},
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(config, "Detector configuration created in shadow mode"))
+}
+
+// UpdateDetectorConfig handles changes to a shadow anomaly detector configuration
+// PUT /analytics/anomaly-detectors/{configId}
+func (h *AnomalyHandler) UpdateDetectorConfig(c *gin.Context) {
+	configID := c.Param("configId")
+
+	var req models.UpdateDetectorConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	config, err := h.detectorService.UpdateConfig(c.Request.Context(), configID, &req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "UPDATE_DETECTOR_CONFIG", "detector_config", configID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			nil,
+		)
+		h.respondDetectorError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "UPDATE_DETECTOR_CONFIG", "detector_config", configID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"name": config.Name, "params": config.Params},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(config, "Detector configuration updated successfully"))
+}
+
+// DeleteDetectorConfig handles removal of a shadow anomaly detector configuration
+// DELETE /analytics/anomaly-detectors/{configId}
+func (h *AnomalyHandler) DeleteDetectorConfig(c *gin.Context) {
+	configID := c.Param("configId")
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.detectorService.DeleteConfig(c.Request.Context(), configID); err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "DELETE_DETECTOR_CONFIG", "detector_config", configID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			nil,
+		)
+		h.respondDetectorError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "DELETE_DETECTOR_CONFIG", "detector_config", configID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Detector configuration deleted successfully"))
+}
+
+// PromoteDetectorConfig handles making a shadow configuration its detector's live configuration
+// POST /analytics/anomaly-detectors/{configId}/promote
+func (h *AnomalyHandler) PromoteDetectorConfig(c *gin.Context) {
+	configID := c.Param("configId")
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	config, err := h.detectorService.PromoteConfig(c.Request.Context(), configID, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "PROMOTE_DETECTOR_CONFIG", "detector_config", configID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			nil,
+		)
+		h.respondDetectorError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "PROMOTE_DETECTOR_CONFIG", "detector_config", configID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"detector": config.Detector, "params": config.Params},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(config, "Detector configuration promoted to live"))
+}
+
+// ListShadowAnomalies handles listing the anomalies a configuration recorded in shadow mode
+// GET /analytics/anomaly-detectors/{configId}/shadow-anomalies
+func (h *AnomalyHandler) ListShadowAnomalies(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	anomalies, total, err := h.detectorService.ListShadowAnomalies(c.Request.Context(), c.Param("configId"), page, limit)
+	if err != nil {
+		h.respondDetectorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"anomalies": anomalies,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	}, ""))
+}
+
+// CompareDetectorConfig handles scoring a configuration and the live detector against reviewed anomalies
+// GET /analytics/anomaly-detectors/{configId}/comparison
+func (h *AnomalyHandler) CompareDetectorConfig(c *gin.Context) {
+	var req models.DetectorComparisonRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	comparison, err := h.detectorService.CompareConfig(c.Request.Context(), c.Param("configId"), &req)
+	if err != nil {
+		h.respondDetectorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(comparison, ""))
+}
+
+// respondDetectorError maps anomaly detector configuration errors to HTTP responses
+func (h *AnomalyHandler) respondDetectorError(c *gin.Context, err error) {
+	if strings.HasPrefix(err.Error(), "validation failed") {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+		return
+	}
+
+	switch err.Error() {
+	case "detector configuration not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
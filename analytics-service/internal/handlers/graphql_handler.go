@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"analytics-service/internal/graphql"
+	"analytics-service/internal/middleware"
+	"analytics-service/internal/models"
+	"analytics-service/internal/service"
+)
+
+// GraphQLHandler handles GraphQL queries from dashboard clients
+type GraphQLHandler struct {
+	graphQLService *service.GraphQLService
+}
+
+// NewGraphQLHandler creates a new GraphQL handler
+func NewGraphQLHandler(graphQLService *service.GraphQLService) *GraphQLHandler {
+	return &GraphQLHandler{
+		graphQLService: graphQLService,
+	}
+}
+
+// Query handles a GraphQL query sent as a JSON body, or as query parameters on GET.
+// Results use the standard GraphQL response format rather than the API envelope.
+// POST /analytics/graphql
+// GET /analytics/graphql
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req graphql.Request
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+					models.ErrCodeValidationFailed,
+					"Invalid query parameters",
+					"variables must be a JSON object",
+				))
+				return
+			}
+		}
+		if req.Query == "" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				"Invalid query parameters",
+				"query is required",
+			))
+			return
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	token := middleware.GetToken(c)
+
+	result := h.graphQLService.Execute(c.Request.Context(), &req, token)
+	c.JSON(http.StatusOK, result)
+}
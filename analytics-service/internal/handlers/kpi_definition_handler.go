@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"analytics-service/internal/middleware"
+	"analytics-service/internal/models"
+)
+
+// ListKPIVariables handles listing the variables custom KPI formulas can read
+// GET /analytics/kpi-definitions/variables
+func (h *KPIHandler) ListKPIVariables(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.kpiService.ListKPIVariables(), ""))
+}
+
+// ValidateKPIFormula handles checking a custom KPI formula and inferring its unit
+// POST /analytics/kpi-definitions/validate
+func (h *KPIHandler) ValidateKPIFormula(c *gin.Context) {
+	var req models.KPIFormulaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.kpiService.ValidateKPIFormula(&req), ""))
+}
+
+// CreateKPIDefinition handles custom KPI creation
+// POST /analytics/kpi-definitions
+func (h *KPIHandler) CreateKPIDefinition(c *gin.Context) {
+	var req models.KPIDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.kpiService.CreateKPIDefinition(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "CREATE_KPI_DEFINITION", "kpi_definition", "",
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			map[string]interface{}{"key": req.Key, "expression": req.Expression},
+		)
+		h.respondDefinitionError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "CREATE_KPI_DEFINITION", "kpi_definition", response.ID.Hex(),
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"key": response.Key, "expression": response.Expression, "unit": response.Unit},
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "KPI definition created successfully"))
+}
+
+// ListKPIDefinitions handles custom KPI listing
+// GET /analytics/kpi-definitions
+func (h *KPIHandler) ListKPIDefinitions(c *gin.Context) {
+	var req models.ListKPIDefinitionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	definitions, total, err := h.kpiService.ListKPIDefinitions(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"definitions": definitions,
+		"total":       total,
+		"page":        req.Page,
+		"limit":       req.Limit,
+	}, ""))
+}
+
+// GetKPIDefinition handles custom KPI retrieval
+// GET /analytics/kpi-definitions/{definitionId}
+func (h *KPIHandler) GetKPIDefinition(c *gin.Context) {
+	response, err := h.kpiService.GetKPIDefinition(c.Request.Context(), c.Param("definitionId"))
+	if err != nil {
+		h.respondDefinitionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// UpdateKPIDefinition handles custom KPI updates
+// PUT /analytics/kpi-definitions/{definitionId}
+func (h *KPIHandler) UpdateKPIDefinition(c *gin.Context) {
+	definitionID := c.Param("definitionId")
+
+	var req models.KPIDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.kpiService.UpdateKPIDefinition(c.Request.Context(), definitionID, &req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "UPDATE_KPI_DEFINITION", "kpi_definition", definitionID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			nil,
+		)
+		h.respondDefinitionError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "UPDATE_KPI_DEFINITION", "kpi_definition", definitionID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"key": response.Key, "expression": response.Expression, "unit": response.Unit},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "KPI definition updated successfully"))
+}
+
+// DeleteKPIDefinition handles custom KPI deletion
+// DELETE /analytics/kpi-definitions/{definitionId}
+func (h *KPIHandler) DeleteKPIDefinition(c *gin.Context) {
+	definitionID := c.Param("definitionId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.kpiService.DeleteKPIDefinition(c.Request.Context(), definitionID); err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "DELETE_KPI_DEFINITION", "kpi_definition", definitionID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			nil,
+		)
+		h.respondDefinitionError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "DELETE_KPI_DEFINITION", "kpi_definition", definitionID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "KPI definition deleted successfully"))
+}
+
+// GetBuildingAttributes handles retrieval of the building attributes KPI formulas read
+// GET /analytics/kpi-definitions/buildings/{buildingId}/attributes
+func (h *KPIHandler) GetBuildingAttributes(c *gin.Context) {
+	response, err := h.kpiService.GetBuildingAttributes(c.Request.Context(), c.Param("buildingId"))
+	if err != nil {
+		h.respondDefinitionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// SetBuildingAttributes handles setting the building attributes KPI formulas read
+// PUT /analytics/kpi-definitions/buildings/{buildingId}/attributes
+func (h *KPIHandler) SetBuildingAttributes(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	var req models.BuildingAttributesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	response, err := h.kpiService.SetBuildingAttributes(c.Request.Context(), buildingID, &req, userID)
+	if err != nil {
+		h.respondDefinitionError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "UPDATE_BUILDING_ATTRIBUTES", "building", buildingID,
+		"SUCCESS", "", middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"floorArea": req.FloorArea, "occupants": req.Occupants},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Building attributes updated successfully"))
+}
+
+// respondDefinitionError maps custom KPI errors to HTTP responses
+func (h *KPIHandler) respondDefinitionError(c *gin.Context, err error) {
+	if strings.HasPrefix(err.Error(), "validation failed") {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+		return
+	}
+
+	switch err.Error() {
+	case "KPI definition not found", "building attributes not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case "invalid KPI definition ID format":
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	case "KPI definition already exists with this key":
+		c.JSON(http.StatusConflict, models.NewErrorResponse(models.ErrCodeConflict, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
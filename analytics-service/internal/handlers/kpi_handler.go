@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -58,6 +59,69 @@ func (h *KPIHandler) GetKPIs(c *gin.Context) {
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
 }
 
+// GetTrends handles KPI trend analysis and regression detection
+// GET /analytics/kpi/{buildingId}/trends?metric=&weeks=
+func (h *KPIHandler) GetTrends(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	var query models.TrendQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	response, err := h.kpiService.GetTrends(c.Request.Context(), buildingID, &query)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "weeks must be") || strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeKPICalculationFailed,
+				err.Error(),
+				"",
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetEnergyBalance handles retrieval of a building's energy balance with its generation,
+// grid exchange, battery flows and self-consumption
+// GET /analytics/kpi/:buildingId/energy-balance
+func (h *KPIHandler) GetEnergyBalance(c *gin.Context) {
+	var query models.EnergyBalanceQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	response, err := h.kpiService.GetEnergyBalance(c.Request.Context(), c.Param("buildingId"), &query)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeKPICalculationFailed,
+				err.Error(),
+				"",
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
 // CalculateKPIs handles KPI calculation
 // POST /analytics/kpi/calculate
 func (h *KPIHandler) CalculateKPIs(c *gin.Context) {
@@ -91,3 +155,46 @@ func (h *KPIHandler) CalculateKPIs(c *gin.Context) {
 	)
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "KPIs calculated successfully"))
 }
+
+// CompareBuildings handles comparing the normalized KPIs of a cohort of buildings
+// POST /analytics/compare
+func (h *KPIHandler) CompareBuildings(c *gin.Context) {
+	var req models.BuildingComparisonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	token := middleware.GetToken(c)
+
+	// Super admins can compare every building and organization admins the buildings of their
+	// organization; other users only buildings their devices belong to
+	restrictToAccessible := !middleware.SeesAllBuildings(c)
+
+	response, err := h.kpiService.CompareBuildings(c.Request.Context(), &req, restrictToAccessible, token)
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		case strings.HasPrefix(err.Error(), "access denied"):
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeKPICalculationFailed,
+				err.Error(),
+				"",
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"analytics-service/internal/middleware"
+	"analytics-service/internal/models"
+	"analytics-service/internal/service"
+)
+
+// MVHandler handles measurement and verification requests
+type MVHandler struct {
+	mvService      *service.MVService
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewMVHandler creates a new M&V handler
+func NewMVHandler(
+	mvService *service.MVService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *MVHandler {
+	return &MVHandler{
+		mvService:      mvService,
+		securityClient: securityClient,
+	}
+}
+
+// CreateReport verifies the savings of an executed optimization scenario
+// POST /analytics/mv-reports
+func (h *MVHandler) CreateReport(c *gin.Context) {
+	var req models.MVReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"scenarioId": req.ScenarioID, "option": req.Option}
+
+	response, err := h.mvService.CreateReport(c.Request.Context(), &req, userID, middleware.GetToken(c))
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "CREATE_MV_REPORT", "mv_report", "",
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			details,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	details["savingsKwh"] = response.Savings.SavingsKWh
+	details["uncertaintyKwh"] = response.Savings.UncertaintyKWh
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "CREATE_MV_REPORT", "mv_report", response.ID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		details,
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "M&V report created successfully"))
+}
+
+// ListReports handles M&V report listing
+// GET /analytics/mv-reports
+func (h *MVHandler) ListReports(c *gin.Context) {
+	var req models.ListMVReportsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	responses, total, err := h.mvService.ListReports(c.Request.Context(), req.BuildingID, req.ScenarioID, req.Page, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"reports": responses,
+		"total":   total,
+		"page":    req.Page,
+		"limit":   req.Limit,
+	}, ""))
+}
+
+// GetReport handles M&V report retrieval
+// GET /analytics/mv-reports/{reportId}
+func (h *MVHandler) GetReport(c *gin.Context) {
+	response, err := h.mvService.GetReport(c.Request.Context(), c.Param("reportId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// respondError maps M&V service errors to HTTP responses
+func (h *MVHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "M&V report not found", err.Error() == "optimization execution not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case err.Error() == "invalid M&V report ID format", strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	case strings.HasPrefix(err.Error(), "baseline model could not be fitted"):
+		c.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
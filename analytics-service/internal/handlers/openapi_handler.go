@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"analytics-service/internal/cache"
+	"analytics-service/internal/models"
+	"analytics-service/internal/openapi"
+	"analytics-service/internal/settings"
+	"analytics-service/internal/validation"
+)
+
+// operations describes the query parameters, request bodies and response data of the handlers
+// for the OpenAPI document. Routes of other handlers are listed without schemas.
+var operations = map[string]openapi.Operation{
+	"AnomalyHandler.GetAnomaly":                {Response: models.AnomalyResponse{}},
+	"AnomalyHandler.ListAnomalies":             {Query: models.ListAnomaliesRequest{}},
+	"AnomalyHandler.AcknowledgeAnomaly":        {Body: models.AcknowledgeAnomalyRequest{}, Response: models.AnomalyResponse{}},
+	"AnomalyHandler.AnalyzeRootCauses":         {Response: models.AnomalyResponse{}},
+	"AnomalyHandler.ListDetectorConfigs":       {Query: models.ListDetectorConfigsRequest{}},
+	"AnomalyHandler.GetDetectorConfig":         {Response: models.DetectorConfig{}},
+	"AnomalyHandler.CreateDetectorConfig":      {Body: models.DetectorConfigRequest{}, Response: models.DetectorConfig{}},
+	"AnomalyHandler.UpdateDetectorConfig":      {Body: models.UpdateDetectorConfigRequest{}, Response: models.DetectorConfig{}},
+	"AnomalyHandler.PromoteDetectorConfig":     {Response: models.DetectorConfig{}},
+	"AnomalyHandler.CompareDetectorConfig":     {Query: models.DetectorComparisonRequest{}, Response: models.DetectorComparison{}},
+	"AuthEventsHandler.RoleChanged":            {Body: models.RoleChangeEvent{}},
+	"BudgetHandler.CreateBudget":               {Body: models.EnergyBudgetRequest{}, Response: models.EnergyBudgetResponse{}},
+	"BudgetHandler.ListBudgets":                {Query: models.ListBudgetsRequest{}},
+	"BudgetHandler.GetBudget":                  {Response: models.EnergyBudgetResponse{}},
+	"BudgetHandler.GetBudgetStatus":            {Response: models.EnergyBudgetStatus{}},
+	"BudgetHandler.UpdateBudget":               {Body: models.EnergyBudgetRequest{}, Response: models.EnergyBudgetResponse{}},
+	"BudgetHandler.CreateSavingsGoal":          {Body: models.SavingsGoalRequest{}, Response: models.SavingsGoalResponse{}},
+	"BudgetHandler.ListSavingsGoals":           {Query: models.ListSavingsGoalsRequest{}},
+	"BudgetHandler.GetSavingsGoal":             {Response: models.SavingsGoalResponse{}},
+	"BudgetHandler.GetSavingsGoalProgress":     {Response: models.SavingsGoalProgress{}},
+	"BudgetHandler.UpdateSavingsGoal":          {Body: models.SavingsGoalRequest{}, Response: models.SavingsGoalResponse{}},
+	"CostHandler.GetCost":                      {Query: models.CostQuery{}, Response: models.CostBreakdown{}},
+	"CostHandler.CompareCosts":                 {Body: models.CostComparisonRequest{}},
+	"DashboardHandler.GetOverviewDashboard":    {Response: models.DashboardOverview{}},
+	"DashboardHandler.GetBuildingDashboard":    {Response: models.BuildingDashboard{}},
+	"DashboardHandler.GetPortfolioDashboard":   {Response: models.PortfolioDashboard{}},
+	"DashboardHandler.GetTopConsumers":         {Query: models.TopConsumersQuery{}, Response: models.TopConsumers{}},
+	"DashboardHandler.GetZoneConsumption":      {Query: models.ZoneConsumptionQuery{}, Response: models.ZoneConsumption{}},
+	"HealthHandler.GetCacheStats":              {Response: cache.Stats{}},
+	"KPIHandler.GetKPIs":                       {Response: models.KPIResponse{}},
+	"KPIHandler.GetTrends":                     {Query: models.TrendQuery{}, Response: models.KPITrend{}},
+	"KPIHandler.GetEnergyBalance":              {Query: models.EnergyBalanceQuery{}, Response: models.EnergyBalance{}},
+	"KPIHandler.CalculateKPIs":                 {Response: models.KPIResponse{}},
+	"KPIHandler.CompareBuildings":              {Body: models.BuildingComparisonRequest{}, Response: models.BuildingComparison{}},
+	"KPIHandler.ListKPIVariables":              {Response: []models.KPIVariable{}},
+	"KPIHandler.ValidateKPIFormula":            {Body: models.KPIFormulaRequest{}, Response: models.KPIFormulaValidation{}},
+	"KPIHandler.CreateKPIDefinition":           {Body: models.KPIDefinitionRequest{}, Response: models.KPIDefinition{}},
+	"KPIHandler.ListKPIDefinitions":            {Query: models.ListKPIDefinitionsRequest{}},
+	"KPIHandler.GetKPIDefinition":              {Response: models.KPIDefinition{}},
+	"KPIHandler.UpdateKPIDefinition":           {Body: models.KPIDefinitionRequest{}, Response: models.KPIDefinition{}},
+	"KPIHandler.GetBuildingAttributes":         {Response: models.BuildingAttributes{}},
+	"KPIHandler.SetBuildingAttributes":         {Body: models.BuildingAttributesRequest{}, Response: models.BuildingAttributes{}},
+	"MVHandler.CreateReport":                   {Body: models.MVReportRequest{}, Response: models.MVReportResponse{}},
+	"MVHandler.ListReports":                    {Query: models.ListMVReportsRequest{}},
+	"MVHandler.GetReport":                      {Response: models.MVReportResponse{}},
+	"ReportHandler.GenerateReport":             {Body: models.GenerateReportRequest{}, Response: models.ReportResponse{}},
+	"ReportHandler.GetReport":                  {Response: models.ReportResponse{}},
+	"ReportHandler.ListReports":                {Query: models.ListReportsRequest{}},
+	"ReportHandler.SearchReports":              {Query: models.SearchReportsRequest{}, Response: models.SearchResponse{}},
+	"TimeSeriesHandler.QueryTimeSeries":        {Body: models.TimeSeriesQueryRequest{}, Response: []*models.TimeSeriesResponse{}},
+	"TimeSeriesHandler.GetAnnotatedTimeSeries": {Query: models.AnnotatedTimeSeriesQuery{}, Response: models.AnnotatedTimeSeriesResponse{}},
+	"TimeSeriesHandler.GetDeviceModeBreakdown": {Query: models.DeviceModeBreakdownQuery{}, Response: models.DeviceModeBreakdown{}},
+	"SettingsHandler.UpdateSetting":            {Body: settings.UpdateRequest{}},
+}
+
+// OpenAPI serves the OpenAPI 3 document of the service. It is built on the first request, once
+// every route is registered on the engine.
+// GET /openapi.json
+func OpenAPI(engine *gin.Engine) gin.HandlerFunc {
+	var once sync.Once
+	var document openapi.Document
+	return func(c *gin.Context) {
+		once.Do(func() {
+			document = openapi.Build(openapi.Info{
+				Title:       "Analytics Service API",
+				Version:     "1.0.0",
+				Description: "Dashboards, KPIs, reports, anomaly detection and time-series analysis",
+			}, engine.Routes(), operations)
+		})
+		c.JSON(http.StatusOK, document)
+	}
+}
+
+// respondValidationError responds to a request that failed binding, listing the fields that failed
+func respondValidationError(c *gin.Context, message string, err error) {
+	c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(message, err.Error(), validation.FieldErrors(err)))
+}
@@ -2,6 +2,7 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,7 @@ type ReportHandler struct {
 	reportService *service.ReportService
 	securityClient interface {
 		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+		CheckPermission(ctx context.Context, userID, resource, action string) (bool, error)
 	}
 }
 
@@ -24,6 +26,7 @@ func NewReportHandler(
 	reportService *service.ReportService,
 	securityClient interface {
 		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+		CheckPermission(ctx context.Context, userID, resource, action string) (bool, error)
 	},
 ) *ReportHandler {
 	return &ReportHandler{
@@ -37,20 +40,15 @@ func NewReportHandler(
 func (h *ReportHandler) GenerateReport(c *gin.Context) {
 	var req models.GenerateReportRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
 	userID := middleware.GetUserID(c)
-	token := middleware.GetToken(c)
 	ipAddress := middleware.GetClientIP(c)
 	userAgent := middleware.GetUserAgent(c)
 
-	response, err := h.reportService.GenerateReport(c.Request.Context(), &req, userID, token)
+	response, err := h.reportService.GenerateReport(c.Request.Context(), &req, userID)
 	if err != nil {
 		h.securityClient.AuditLog(
 			c.Request.Context(), userID, "", "GENERATE_REPORT", "report", "",
@@ -104,6 +102,12 @@ func (h *ReportHandler) GetReport(c *gin.Context) {
 		return
 	}
 
+	// Report downloads are audited so building access reviews can see who read them
+	h.securityClient.AuditLog(
+		c.Request.Context(), middleware.GetUserID(c), "", "DOWNLOAD_REPORT", "report", reportID,
+		"SUCCESS", "", middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"type": response.Type, "buildingId": response.BuildingID},
+	)
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
 }
 
@@ -112,11 +116,7 @@ func (h *ReportHandler) GetReport(c *gin.Context) {
 func (h *ReportHandler) ListReports(c *gin.Context) {
 	var req models.ListReportsRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid query parameters",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid query parameters", err)
 		return
 	}
 
@@ -151,3 +151,52 @@ func (h *ReportHandler) ListReports(c *gin.Context) {
 		"limit":   req.Limit,
 	}, ""))
 }
+
+// SearchReports handles searching reports by free text and filters with cursor pagination.
+// Callers without read access to reports receive no results.
+// GET /analytics/search
+func (h *ReportHandler) SearchReports(c *gin.Context) {
+	var req models.SearchReportsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	allowed := middleware.HasRole(c, "admin")
+	if !allowed {
+		var err error
+		allowed, err = h.securityClient.CheckPermission(c.Request.Context(), middleware.GetUserID(c), "reports", "read")
+		if err != nil {
+			c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+				models.ErrCodeExternalAPIError,
+				"Failed to check permissions",
+				err.Error(),
+			))
+			return
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(&models.SearchResponse{Results: []*models.SearchResult{}}, ""))
+		return
+	}
+
+	response, err := h.reportService.SearchReports(c.Request.Context(), &req)
+	if err != nil {
+		if err.Error() == "invalid cursor" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				"Invalid cursor",
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
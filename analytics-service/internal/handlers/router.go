@@ -3,17 +3,31 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 
+	"analytics-service/internal/config"
 	"analytics-service/internal/middleware"
+	"analytics-service/internal/validation"
 )
 
 // Router holds all handler dependencies
 type Router struct {
-	ReportHandler      *ReportHandler
-	AnomalyHandler     *AnomalyHandler
-	TimeSeriesHandler  *TimeSeriesHandler
-	KPIHandler         *KPIHandler
-	DashboardHandler   *DashboardHandler
-	AuthMiddleware     *middleware.AuthMiddleware
+	ReportHandler     *ReportHandler
+	AnomalyHandler    *AnomalyHandler
+	TimeSeriesHandler *TimeSeriesHandler
+	KPIHandler        *KPIHandler
+	DashboardHandler  *DashboardHandler
+	HealthHandler     *HealthHandler
+	RetentionHandler  *RetentionHandler
+	AuthEventsHandler *AuthEventsHandler
+	GraphQLHandler    *GraphQLHandler
+	BudgetHandler     *BudgetHandler
+	CostHandler       *CostHandler
+	JobHandler        *JobHandler
+	SettingsHandler   *SettingsHandler
+	MVHandler         *MVHandler
+	AuthMiddleware    *middleware.AuthMiddleware
+
+	// UsageMeter meters API calls for billing when set
+	UsageMeter *middleware.UsageMeter
 }
 
 // NewRouter creates a new router with all handlers
@@ -23,6 +37,15 @@ func NewRouter(
 	timeSeriesHandler *TimeSeriesHandler,
 	kpiHandler *KPIHandler,
 	dashboardHandler *DashboardHandler,
+	healthHandler *HealthHandler,
+	retentionHandler *RetentionHandler,
+	authEventsHandler *AuthEventsHandler,
+	graphQLHandler *GraphQLHandler,
+	budgetHandler *BudgetHandler,
+	costHandler *CostHandler,
+	jobHandler *JobHandler,
+	settingsHandler *SettingsHandler,
+	mvHandler *MVHandler,
 	authMiddleware *middleware.AuthMiddleware,
 ) *Router {
 	return &Router{
@@ -31,26 +54,50 @@ func NewRouter(
 		TimeSeriesHandler: timeSeriesHandler,
 		KPIHandler:        kpiHandler,
 		DashboardHandler:  dashboardHandler,
+		HealthHandler:     healthHandler,
+		RetentionHandler:  retentionHandler,
+		AuthEventsHandler: authEventsHandler,
+		GraphQLHandler:    graphQLHandler,
+		BudgetHandler:     budgetHandler,
+		CostHandler:       costHandler,
+		JobHandler:        jobHandler,
+		SettingsHandler:   settingsHandler,
+		MVHandler:         mvHandler,
 		AuthMiddleware:    authMiddleware,
 	}
 }
 
-// SetupRoutes configures all API routes
-func (r *Router) SetupRoutes(engine *gin.Engine) {
+// SetupRoutes configures all API routes, applying the given CORS and security header policies
+func (r *Router) SetupRoutes(engine *gin.Engine, policy config.HTTPConfig) {
+	// Register the custom binding rules and report validation errors by JSON field name
+	validation.Register()
+
 	// Apply common middleware
 	engine.Use(middleware.Recovery())
+	engine.Use(middleware.Tracing())
 	engine.Use(middleware.RequestID())
-	engine.Use(middleware.CORS())
-	engine.Use(middleware.SecurityHeaders())
+	engine.Use(middleware.Localization())
+	engine.Use(middleware.CORS(policy.CORS))
+	engine.Use(middleware.SecurityHeaders(policy.Headers))
 	engine.Use(middleware.RequestLogger())
+	if r.UsageMeter != nil {
+		engine.Use(r.UsageMeter.Middleware())
+	}
+
+	// Health check endpoints
+	engine.GET("/health", r.HealthHandler.Health)
+	engine.GET("/health/deep", r.HealthHandler.DeepHealth)
 
-	// Health check endpoint
-	engine.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "healthy",
-			"service": "analytics-service",
-		})
-	})
+	// OpenAPI document of the routes, for generating clients
+	engine.GET("/openapi.json", OpenAPI(engine))
+
+	// Internal notifications from other services
+	internal := engine.Group("/internal")
+	internal.Use(r.AuthMiddleware.RequireServiceKey())
+	{
+		internal.POST("/auth/role-changed", r.AuthEventsHandler.RoleChanged)
+		internal.POST("/cost/compare", r.CostHandler.CompareCosts)
+	}
 
 	// API v1 routes
 	api := engine.Group("/api/v1")
@@ -60,6 +107,10 @@ func (r *Router) SetupRoutes(engine *gin.Engine) {
 		r.setupTimeSeriesRoutes(api)
 		r.setupKPIRoutes(api)
 		r.setupDashboardRoutes(api)
+		r.setupGraphQLRoutes(api)
+		r.setupBudgetRoutes(api)
+		r.setupMVRoutes(api)
+		r.setupAdminRoutes(api)
 	}
 
 	// Legacy routes (without /api/v1 prefix for backward compatibility)
@@ -75,6 +126,7 @@ func (r *Router) setupReportRoutes(rg *gin.RouterGroup) {
 		reports.GET("/:reportId", r.ReportHandler.GetReport)
 		reports.POST("/generate", r.ReportHandler.GenerateReport)
 	}
+	rg.GET("/analytics/search", r.AuthMiddleware.RequireAuth(), r.ReportHandler.SearchReports)
 }
 
 // setupAnomalyRoutes configures anomaly routes
@@ -84,8 +136,23 @@ func (r *Router) setupAnomalyRoutes(rg *gin.RouterGroup) {
 	{
 		anomalies.GET("", r.AnomalyHandler.ListAnomalies)
 		anomalies.GET("/:anomalyId", r.AnomalyHandler.GetAnomaly)
+		anomalies.POST("/:anomalyId/root-causes", r.AnomalyHandler.AnalyzeRootCauses)
 		anomalies.POST("/acknowledge", r.AnomalyHandler.AcknowledgeAnomaly)
 	}
+
+	detectors := rg.Group("/analytics/anomaly-detectors")
+	detectors.Use(r.AuthMiddleware.RequireAuth())
+	{
+		detectors.GET("", r.AnomalyHandler.ListDetectorConfigs)
+		detectors.POST("", r.AuthMiddleware.RequireAdmin(), r.AnomalyHandler.CreateDetectorConfig)
+		detectors.GET("/live", r.AnomalyHandler.GetLiveDetectorConfigs)
+		detectors.GET("/:configId", r.AnomalyHandler.GetDetectorConfig)
+		detectors.PUT("/:configId", r.AuthMiddleware.RequireAdmin(), r.AnomalyHandler.UpdateDetectorConfig)
+		detectors.DELETE("/:configId", r.AuthMiddleware.RequireAdmin(), r.AnomalyHandler.DeleteDetectorConfig)
+		detectors.POST("/:configId/promote", r.AuthMiddleware.RequireAdmin(), r.AnomalyHandler.PromoteDetectorConfig)
+		detectors.GET("/:configId/shadow-anomalies", r.AnomalyHandler.ListShadowAnomalies)
+		detectors.GET("/:configId/comparison", r.AnomalyHandler.CompareDetectorConfig)
+	}
 }
 
 // setupTimeSeriesRoutes configures time-series routes
@@ -94,27 +161,116 @@ func (r *Router) setupTimeSeriesRoutes(rg *gin.RouterGroup) {
 	timeseries.Use(r.AuthMiddleware.RequireAuth())
 	{
 		timeseries.POST("/query", r.TimeSeriesHandler.QueryTimeSeries)
+		timeseries.GET("/:buildingId/annotated", r.TimeSeriesHandler.GetAnnotatedTimeSeries)
 	}
+	rg.GET("/analytics/devices/:id/mode-breakdown", r.AuthMiddleware.RequireAuth(), r.TimeSeriesHandler.GetDeviceModeBreakdown)
 }
 
 // setupKPIRoutes configures KPI routes
 func (r *Router) setupKPIRoutes(rg *gin.RouterGroup) {
 	kpi := rg.Group("/analytics/kpi")
-	kpi.Use(r.AuthMiddleware.RequireAuth())
 	{
-		kpi.GET("", r.KPIHandler.GetKPIs)
-		kpi.GET("/:buildingId", r.KPIHandler.GetKPIs)
-		kpi.POST("/calculate", r.KPIHandler.CalculateKPIs)
+		kpi.GET("", r.AuthMiddleware.RequireAuth(), r.KPIHandler.GetKPIs)
+		kpi.GET("/:buildingId", r.AuthMiddleware.RequireAuthOrKiosk("buildingId"), r.KPIHandler.GetKPIs)
+		kpi.GET("/:buildingId/trends", r.AuthMiddleware.RequireAuth(), r.KPIHandler.GetTrends)
+		kpi.GET("/:buildingId/energy-balance", r.AuthMiddleware.RequireAuth(), r.KPIHandler.GetEnergyBalance)
+		kpi.POST("/calculate", r.AuthMiddleware.RequireAuth(), r.KPIHandler.CalculateKPIs)
+	}
+
+	definitions := rg.Group("/analytics/kpi-definitions")
+	definitions.Use(r.AuthMiddleware.RequireAuth())
+	{
+		definitions.GET("", r.KPIHandler.ListKPIDefinitions)
+		definitions.POST("", r.AuthMiddleware.RequireAdmin(), r.KPIHandler.CreateKPIDefinition)
+		definitions.GET("/variables", r.KPIHandler.ListKPIVariables)
+		definitions.POST("/validate", r.KPIHandler.ValidateKPIFormula)
+		definitions.GET("/buildings/:buildingId/attributes", r.KPIHandler.GetBuildingAttributes)
+		definitions.PUT("/buildings/:buildingId/attributes", r.AuthMiddleware.RequireAdmin(), r.KPIHandler.SetBuildingAttributes)
+		definitions.GET("/:definitionId", r.KPIHandler.GetKPIDefinition)
+		definitions.PUT("/:definitionId", r.AuthMiddleware.RequireAdmin(), r.KPIHandler.UpdateKPIDefinition)
+		definitions.DELETE("/:definitionId", r.AuthMiddleware.RequireAdmin(), r.KPIHandler.DeleteKPIDefinition)
 	}
 }
 
 // setupDashboardRoutes configures dashboard routes
 func (r *Router) setupDashboardRoutes(rg *gin.RouterGroup) {
 	dashboards := rg.Group("/analytics/dashboards")
-	dashboards.Use(r.AuthMiddleware.RequireAuth())
 	{
-		dashboards.GET("/overview", r.DashboardHandler.GetOverviewDashboard)
-		dashboards.GET("/building/:buildingId", r.DashboardHandler.GetBuildingDashboard)
+		dashboards.GET("/overview", r.AuthMiddleware.RequireAuth(), r.DashboardHandler.GetOverviewDashboard)
+		dashboards.GET("/portfolio", r.AuthMiddleware.RequireAuth(), r.DashboardHandler.GetPortfolioDashboard)
+		dashboards.GET("/building/:buildingId", r.AuthMiddleware.RequireAuthOrKiosk("buildingId"), r.DashboardHandler.GetBuildingDashboard)
+	}
+	rg.GET("/analytics/top-consumers", r.AuthMiddleware.RequireAuth(), r.DashboardHandler.GetTopConsumers)
+	rg.GET("/analytics/zone-consumption", r.AuthMiddleware.RequireAuth(), r.DashboardHandler.GetZoneConsumption)
+	rg.GET("/analytics/cost", r.AuthMiddleware.RequireAuth(), r.CostHandler.GetCost)
+	rg.POST("/analytics/compare", r.AuthMiddleware.RequireAuth(), r.KPIHandler.CompareBuildings)
+}
+
+// setupGraphQLRoutes configures the GraphQL endpoint
+func (r *Router) setupGraphQLRoutes(rg *gin.RouterGroup) {
+	graphQL := rg.Group("/analytics/graphql")
+	graphQL.Use(r.AuthMiddleware.RequireAuth())
+	{
+		graphQL.GET("", r.GraphQLHandler.Query)
+		graphQL.POST("", r.GraphQLHandler.Query)
+	}
+}
+
+// setupBudgetRoutes configures energy budget and savings goal routes
+func (r *Router) setupBudgetRoutes(rg *gin.RouterGroup) {
+	budgets := rg.Group("/analytics/budgets")
+	budgets.Use(r.AuthMiddleware.RequireAuth())
+	{
+		budgets.GET("", r.BudgetHandler.ListBudgets)
+		budgets.POST("", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.CreateBudget)
+		budgets.GET("/:budgetId", r.BudgetHandler.GetBudget)
+		budgets.GET("/:budgetId/status", r.BudgetHandler.GetBudgetStatus)
+		budgets.PUT("/:budgetId", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.UpdateBudget)
+		budgets.DELETE("/:budgetId", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.DeleteBudget)
+	}
+
+	goals := rg.Group("/analytics/savings-goals")
+	goals.Use(r.AuthMiddleware.RequireAuth())
+	{
+		goals.GET("", r.BudgetHandler.ListSavingsGoals)
+		goals.POST("", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.CreateSavingsGoal)
+		goals.GET("/:goalId", r.BudgetHandler.GetSavingsGoal)
+		goals.GET("/:goalId/progress", r.BudgetHandler.GetSavingsGoalProgress)
+		goals.PUT("/:goalId", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.UpdateSavingsGoal)
+		goals.DELETE("/:goalId", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.DeleteSavingsGoal)
+	}
+}
+
+// setupMVRoutes configures measurement and verification routes
+func (r *Router) setupMVRoutes(rg *gin.RouterGroup) {
+	mv := rg.Group("/analytics/mv-reports")
+	mv.Use(r.AuthMiddleware.RequireAuth())
+	{
+		mv.GET("", r.MVHandler.ListReports)
+		mv.POST("", r.MVHandler.CreateReport)
+		mv.GET("/:reportId", r.MVHandler.GetReport)
+	}
+}
+
+// setupAdminRoutes configures administrative maintenance routes
+func (r *Router) setupAdminRoutes(rg *gin.RouterGroup) {
+	admin := rg.Group("/analytics/admin")
+	admin.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		admin.GET("/retention", r.RetentionHandler.GetStatus)
+		admin.GET("/cache", r.HealthHandler.GetCacheStats)
+		admin.POST("/retention/run", r.RetentionHandler.RunNow)
+		admin.GET("/jobs", r.JobHandler.ListJobs)
+		admin.GET("/jobs/stats", r.JobHandler.GetStats)
+		admin.GET("/jobs/:jobId", r.JobHandler.GetJob)
+		admin.POST("/jobs/:jobId/retry", r.JobHandler.RetryJob)
+		admin.POST("/jobs/:jobId/cancel", r.JobHandler.CancelJob)
+		admin.GET("/settings", r.SettingsHandler.ListSettings)
+		admin.GET("/settings/history", r.SettingsHandler.GetHistory)
+		admin.GET("/settings/:key", r.SettingsHandler.GetSetting)
+		admin.PUT("/settings/:key", r.SettingsHandler.UpdateSetting)
+		admin.DELETE("/settings/:key", r.SettingsHandler.ResetSetting)
+		admin.GET("/settings/:key/history", r.SettingsHandler.GetHistory)
 	}
 }
 
@@ -128,6 +284,7 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 		reports.GET("/:reportId", r.ReportHandler.GetReport)
 		reports.POST("/generate", r.ReportHandler.GenerateReport)
 	}
+	engine.GET("/analytics/search", r.AuthMiddleware.RequireAuth(), r.ReportHandler.SearchReports)
 
 	// Anomaly routes
 	anomalies := engine.Group("/analytics/anomalies")
@@ -135,30 +292,129 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 	{
 		anomalies.GET("", r.AnomalyHandler.ListAnomalies)
 		anomalies.GET("/:anomalyId", r.AnomalyHandler.GetAnomaly)
+		anomalies.POST("/:anomalyId/root-causes", r.AnomalyHandler.AnalyzeRootCauses)
 		anomalies.POST("/acknowledge", r.AnomalyHandler.AcknowledgeAnomaly)
 	}
 
+	// Anomaly detector configuration routes
+	detectors := engine.Group("/analytics/anomaly-detectors")
+	detectors.Use(r.AuthMiddleware.RequireAuth())
+	{
+		detectors.GET("", r.AnomalyHandler.ListDetectorConfigs)
+		detectors.POST("", r.AuthMiddleware.RequireAdmin(), r.AnomalyHandler.CreateDetectorConfig)
+		detectors.GET("/live", r.AnomalyHandler.GetLiveDetectorConfigs)
+		detectors.GET("/:configId", r.AnomalyHandler.GetDetectorConfig)
+		detectors.PUT("/:configId", r.AuthMiddleware.RequireAdmin(), r.AnomalyHandler.UpdateDetectorConfig)
+		detectors.DELETE("/:configId", r.AuthMiddleware.RequireAdmin(), r.AnomalyHandler.DeleteDetectorConfig)
+		detectors.POST("/:configId/promote", r.AuthMiddleware.RequireAdmin(), r.AnomalyHandler.PromoteDetectorConfig)
+		detectors.GET("/:configId/shadow-anomalies", r.AnomalyHandler.ListShadowAnomalies)
+		detectors.GET("/:configId/comparison", r.AnomalyHandler.CompareDetectorConfig)
+	}
+
 	// Time-series routes
 	timeseries := engine.Group("/analytics/time-series")
 	timeseries.Use(r.AuthMiddleware.RequireAuth())
 	{
 		timeseries.POST("/query", r.TimeSeriesHandler.QueryTimeSeries)
+		timeseries.GET("/:buildingId/annotated", r.TimeSeriesHandler.GetAnnotatedTimeSeries)
 	}
+	engine.GET("/analytics/devices/:id/mode-breakdown", r.AuthMiddleware.RequireAuth(), r.TimeSeriesHandler.GetDeviceModeBreakdown)
 
 	// KPI routes
 	kpi := engine.Group("/analytics/kpi")
-	kpi.Use(r.AuthMiddleware.RequireAuth())
 	{
-		kpi.GET("", r.KPIHandler.GetKPIs)
-		kpi.GET("/:buildingId", r.KPIHandler.GetKPIs)
-		kpi.POST("/calculate", r.KPIHandler.CalculateKPIs)
+		kpi.GET("", r.AuthMiddleware.RequireAuth(), r.KPIHandler.GetKPIs)
+		kpi.GET("/:buildingId", r.AuthMiddleware.RequireAuthOrKiosk("buildingId"), r.KPIHandler.GetKPIs)
+		kpi.GET("/:buildingId/trends", r.AuthMiddleware.RequireAuth(), r.KPIHandler.GetTrends)
+		kpi.GET("/:buildingId/energy-balance", r.AuthMiddleware.RequireAuth(), r.KPIHandler.GetEnergyBalance)
+		kpi.POST("/calculate", r.AuthMiddleware.RequireAuth(), r.KPIHandler.CalculateKPIs)
+	}
+
+	// Custom KPI routes
+	definitions := engine.Group("/analytics/kpi-definitions")
+	definitions.Use(r.AuthMiddleware.RequireAuth())
+	{
+		definitions.GET("", r.KPIHandler.ListKPIDefinitions)
+		definitions.POST("", r.AuthMiddleware.RequireAdmin(), r.KPIHandler.CreateKPIDefinition)
+		definitions.GET("/variables", r.KPIHandler.ListKPIVariables)
+		definitions.POST("/validate", r.KPIHandler.ValidateKPIFormula)
+		definitions.GET("/buildings/:buildingId/attributes", r.KPIHandler.GetBuildingAttributes)
+		definitions.PUT("/buildings/:buildingId/attributes", r.AuthMiddleware.RequireAdmin(), r.KPIHandler.SetBuildingAttributes)
+		definitions.GET("/:definitionId", r.KPIHandler.GetKPIDefinition)
+		definitions.PUT("/:definitionId", r.AuthMiddleware.RequireAdmin(), r.KPIHandler.UpdateKPIDefinition)
+		definitions.DELETE("/:definitionId", r.AuthMiddleware.RequireAdmin(), r.KPIHandler.DeleteKPIDefinition)
 	}
 
 	// Dashboard routes
 	dashboards := engine.Group("/analytics/dashboards")
-	dashboards.Use(r.AuthMiddleware.RequireAuth())
 	{
-		dashboards.GET("/overview", r.DashboardHandler.GetOverviewDashboard)
-		dashboards.GET("/building/:buildingId", r.DashboardHandler.GetBuildingDashboard)
+		dashboards.GET("/overview", r.AuthMiddleware.RequireAuth(), r.DashboardHandler.GetOverviewDashboard)
+		dashboards.GET("/portfolio", r.AuthMiddleware.RequireAuth(), r.DashboardHandler.GetPortfolioDashboard)
+		dashboards.GET("/building/:buildingId", r.AuthMiddleware.RequireAuthOrKiosk("buildingId"), r.DashboardHandler.GetBuildingDashboard)
+	}
+	engine.GET("/analytics/top-consumers", r.AuthMiddleware.RequireAuth(), r.DashboardHandler.GetTopConsumers)
+	engine.GET("/analytics/zone-consumption", r.AuthMiddleware.RequireAuth(), r.DashboardHandler.GetZoneConsumption)
+	engine.GET("/analytics/cost", r.AuthMiddleware.RequireAuth(), r.CostHandler.GetCost)
+	engine.POST("/analytics/compare", r.AuthMiddleware.RequireAuth(), r.KPIHandler.CompareBuildings)
+
+	// GraphQL routes
+	graphQL := engine.Group("/analytics/graphql")
+	graphQL.Use(r.AuthMiddleware.RequireAuth())
+	{
+		graphQL.GET("", r.GraphQLHandler.Query)
+		graphQL.POST("", r.GraphQLHandler.Query)
+	}
+
+	// Budget routes
+	budgets := engine.Group("/analytics/budgets")
+	budgets.Use(r.AuthMiddleware.RequireAuth())
+	{
+		budgets.GET("", r.BudgetHandler.ListBudgets)
+		budgets.POST("", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.CreateBudget)
+		budgets.GET("/:budgetId", r.BudgetHandler.GetBudget)
+		budgets.GET("/:budgetId/status", r.BudgetHandler.GetBudgetStatus)
+		budgets.PUT("/:budgetId", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.UpdateBudget)
+		budgets.DELETE("/:budgetId", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.DeleteBudget)
+	}
+
+	// Savings goal routes
+	goals := engine.Group("/analytics/savings-goals")
+	goals.Use(r.AuthMiddleware.RequireAuth())
+	{
+		goals.GET("", r.BudgetHandler.ListSavingsGoals)
+		goals.POST("", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.CreateSavingsGoal)
+		goals.GET("/:goalId", r.BudgetHandler.GetSavingsGoal)
+		goals.GET("/:goalId/progress", r.BudgetHandler.GetSavingsGoalProgress)
+		goals.PUT("/:goalId", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.UpdateSavingsGoal)
+		goals.DELETE("/:goalId", r.AuthMiddleware.RequireAdmin(), r.BudgetHandler.DeleteSavingsGoal)
+	}
+
+	// M&V routes
+	mv := engine.Group("/analytics/mv-reports")
+	mv.Use(r.AuthMiddleware.RequireAuth())
+	{
+		mv.GET("", r.MVHandler.ListReports)
+		mv.POST("", r.MVHandler.CreateReport)
+		mv.GET("/:reportId", r.MVHandler.GetReport)
+	}
+
+	// Admin routes
+	admin := engine.Group("/analytics/admin")
+	admin.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		admin.GET("/retention", r.RetentionHandler.GetStatus)
+		admin.GET("/cache", r.HealthHandler.GetCacheStats)
+		admin.POST("/retention/run", r.RetentionHandler.RunNow)
+		admin.GET("/jobs", r.JobHandler.ListJobs)
+		admin.GET("/jobs/stats", r.JobHandler.GetStats)
+		admin.GET("/jobs/:jobId", r.JobHandler.GetJob)
+		admin.POST("/jobs/:jobId/retry", r.JobHandler.RetryJob)
+		admin.POST("/jobs/:jobId/cancel", r.JobHandler.CancelJob)
+		admin.GET("/settings", r.SettingsHandler.ListSettings)
+		admin.GET("/settings/history", r.SettingsHandler.GetHistory)
+		admin.GET("/settings/:key", r.SettingsHandler.GetSetting)
+		admin.PUT("/settings/:key", r.SettingsHandler.UpdateSetting)
+		admin.DELETE("/settings/:key", r.SettingsHandler.ResetSetting)
+		admin.GET("/settings/:key/history", r.SettingsHandler.GetHistory)
 	}
 }
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"analytics-service/internal/middleware"
+	"analytics-service/internal/models"
+)
+
+// CreateSavingsGoal handles savings goal creation
+// POST /analytics/savings-goals
+func (h *BudgetHandler) CreateSavingsGoal(c *gin.Context) {
+	var req models.SavingsGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.savingsGoalService.CreateGoal(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "CREATE_SAVINGS_GOAL", "savings_goal", "",
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			map[string]interface{}{"buildingId": req.BuildingID, "year": req.Year},
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "CREATE_SAVINGS_GOAL", "savings_goal", response.ID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"buildingId": req.BuildingID, "year": req.Year, "targetPercent": req.TargetPercent},
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Savings goal created successfully"))
+}
+
+// ListSavingsGoals handles savings goal listing
+// GET /analytics/savings-goals
+func (h *BudgetHandler) ListSavingsGoals(c *gin.Context) {
+	var req models.ListSavingsGoalsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	responses, total, err := h.savingsGoalService.ListGoals(c.Request.Context(), req.BuildingID, req.Year, req.Page, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"goals": responses,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	}, ""))
+}
+
+// GetSavingsGoal handles savings goal retrieval
+// GET /analytics/savings-goals/{goalId}
+func (h *BudgetHandler) GetSavingsGoal(c *gin.Context) {
+	response, err := h.savingsGoalService.GetGoal(c.Request.Context(), c.Param("goalId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetSavingsGoalProgress handles savings goal progress retrieval
+// GET /analytics/savings-goals/{goalId}/progress
+func (h *BudgetHandler) GetSavingsGoalProgress(c *gin.Context) {
+	response, err := h.savingsGoalService.GetGoalProgress(c.Request.Context(), c.Param("goalId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// UpdateSavingsGoal handles savings goal updates
+// PUT /analytics/savings-goals/{goalId}
+func (h *BudgetHandler) UpdateSavingsGoal(c *gin.Context) {
+	goalID := c.Param("goalId")
+
+	var req models.SavingsGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.savingsGoalService.UpdateGoal(c.Request.Context(), goalID, &req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "UPDATE_SAVINGS_GOAL", "savings_goal", goalID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "UPDATE_SAVINGS_GOAL", "savings_goal", goalID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"year": req.Year, "targetPercent": req.TargetPercent},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Savings goal updated successfully"))
+}
+
+// DeleteSavingsGoal handles savings goal deletion
+// DELETE /analytics/savings-goals/{goalId}
+func (h *BudgetHandler) DeleteSavingsGoal(c *gin.Context) {
+	goalID := c.Param("goalId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.savingsGoalService.DeleteGoal(c.Request.Context(), goalID); err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "DELETE_SAVINGS_GOAL", "savings_goal", goalID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "DELETE_SAVINGS_GOAL", "savings_goal", goalID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Savings goal deleted successfully"))
+}
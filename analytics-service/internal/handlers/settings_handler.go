@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"analytics-service/internal/middleware"
+	"analytics-service/internal/models"
+	"analytics-service/internal/settings"
+)
+
+// SettingsHandler handles runtime settings administration requests
+type SettingsHandler struct {
+	store          *settings.Store
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(
+	store *settings.Store,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *SettingsHandler {
+	return &SettingsHandler{
+		store:          store,
+		securityClient: securityClient,
+	}
+}
+
+// ListSettings handles listing the runtime settings and their current values
+// GET /admin/settings
+func (h *SettingsHandler) ListSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.store.List(), ""))
+}
+
+// GetSetting handles retrieving a runtime setting
+// GET /admin/settings/{key}
+func (h *SettingsHandler) GetSetting(c *gin.Context) {
+	setting, err := h.store.Get(c.Param("key"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(setting, ""))
+}
+
+// UpdateSetting handles changing a runtime setting
+// PUT /admin/settings/{key}
+func (h *SettingsHandler) UpdateSetting(c *gin.Context) {
+	key := c.Param("key")
+
+	var req settings.UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Value == nil {
+		details := "value is required"
+		if err != nil {
+			details = err.Error()
+		}
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			"Invalid request body",
+			details,
+		))
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	setting, err := h.store.Set(c.Request.Context(), key, req.Value, userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_SETTING", "setting", key, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_SETTING", "setting", key, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"value": setting.Value})
+	c.JSON(http.StatusOK, models.NewSuccessResponse(setting, "Setting updated successfully"))
+}
+
+// ResetSetting handles returning a runtime setting to its default
+// DELETE /admin/settings/{key}
+func (h *SettingsHandler) ResetSetting(c *gin.Context) {
+	key := c.Param("key")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	setting, err := h.store.Reset(c.Request.Context(), key, userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "RESET_SETTING", "setting", key, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "RESET_SETTING", "setting", key, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(setting, "Setting reset to default"))
+}
+
+// GetHistory handles listing setting changes, of one setting or of all settings
+// GET /admin/settings/history?page=&limit=
+// GET /admin/settings/{key}/history?page=&limit=
+func (h *SettingsHandler) GetHistory(c *gin.Context) {
+	key := c.Param("key")
+	if key != "" {
+		if _, err := h.store.Get(key); err != nil {
+			h.respondError(c, err)
+			return
+		}
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	changes, total, err := h.store.History(c.Request.Context(), key, page, limit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"changes": changes,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	}, ""))
+}
+
+// respondError maps settings store errors to HTTP responses
+func (h *SettingsHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "setting not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -27,11 +28,7 @@ func NewTimeSeriesHandler(timeSeriesService *service.TimeSeriesService) *TimeSer
 func (h *TimeSeriesHandler) QueryTimeSeries(c *gin.Context) {
 	var req models.TimeSeriesQueryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -49,3 +46,65 @@ func (h *TimeSeriesHandler) QueryTimeSeries(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(responses, ""))
 }
+
+// GetAnnotatedTimeSeries handles retrieving a building's time-series with event annotations
+// GET /analytics/time-series/:buildingId/annotated
+func (h *TimeSeriesHandler) GetAnnotatedTimeSeries(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	var req models.AnnotatedTimeSeriesQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	response, err := h.timeSeriesService.GetAnnotatedTimeSeries(c.Request.Context(), buildingID, &req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetDeviceModeBreakdown handles attributing a device's consumption to its operating modes
+// GET /analytics/devices/:id/mode-breakdown
+func (h *TimeSeriesHandler) GetDeviceModeBreakdown(c *gin.Context) {
+	var req models.DeviceModeBreakdownQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	response, err := h.timeSeriesService.GetDeviceModeBreakdown(c.Request.Context(), c.Param("id"), &req, middleware.GetToken(c))
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to build mode breakdown",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
@@ -0,0 +1,274 @@
+package i18n
+
+// spanishCatalog holds the Spanish translations
+var spanishCatalog = &catalog{
+	Messages: map[string]string{
+		// Requests
+		"Invalid request body":                                  "Cuerpo de la solicitud no válido",
+		"Invalid query parameters":                              "Parámetros de consulta no válidos",
+		"Failed to read request body":                           "No se pudo leer el cuerpo de la solicitud",
+		"Invalid 'from' date format":                            "Formato de fecha 'from' no válido",
+		"Invalid 'to' date format":                              "Formato de fecha 'to' no válido",
+		"Invalid 'format' parameter":                            "Parámetro 'format' no válido",
+		"Invalid 'at' parameter, expected RFC3339 timestamp":    "Parámetro 'at' no válido, se esperaba una marca de tiempo RFC3339",
+		"'from' must be before 'to'":                            "'from' debe ser anterior a 'to'",
+		"to must be after from":                                 "to debe ser posterior a from",
+		"from and to query parameters are required":             "Los parámetros de consulta from y to son obligatorios",
+		"region or buildingId query parameter is required":      "Se requiere el parámetro de consulta region o buildingId",
+		"Invalid cursor":                                        "Cursor no válido",
+		"invalid cursor":                                        "cursor no válido",
+		"Invalid user ID format":                                "Formato de ID de usuario no válido",
+		"Invalid roles format":                                  "Formato de roles no válido",
+		"Invalid conflict resolution":                           "Resolución de conflictos no válida",
+		"Idempotency-Key header must not exceed 255 characters": "La cabecera Idempotency-Key no debe superar los 255 caracteres",
+		"Request body must contain an iCalendar document":       "El cuerpo de la solicitud debe contener un documento iCalendar",
+		"no updates provided":                                   "no se proporcionaron cambios",
+		"must be a number":                                      "debe ser un número",
+		"must be an integer":                                    "debe ser un número entero",
+		"must be true or false":                                 "debe ser true o false",
+		"days must be between 1 and 365":                        "days debe estar entre 1 y 365",
+		"hours must be between 1 and 168":                       "hours debe estar entre 1 y 168",
+		"degree day range must not exceed 400 days":             "el rango de grados-día no debe superar los 400 días",
+
+		// Authentication and authorization
+		"Authorization header is required":                       "Se requiere la cabecera Authorization",
+		"Invalid authorization header format":                    "Formato de la cabecera Authorization no válido",
+		"Insufficient permissions":                               "Permisos insuficientes",
+		"Kiosk tokens cannot access this endpoint":               "Los tokens de quiosco no pueden acceder a este endpoint",
+		"This action is not allowed while impersonating a user":  "Esta acción no está permitida mientras se suplanta a un usuario",
+		"Valid service signature is required":                    "Se requiere una firma de servicio válida",
+		"Invalid service signature":                              "Firma de servicio no válida",
+		"request is not signed":                                  "la solicitud no está firmada",
+		"invalid request signature":                              "firma de la solicitud no válida",
+		"request nonce has already been used":                    "el nonce de la solicitud ya se ha utilizado",
+		"request timestamp outside the allowed window":           "la marca de tiempo de la solicitud está fuera del intervalo permitido",
+		"unknown calling service":                                "servicio llamante desconocido",
+		"credentials not found for service":                      "no se encontraron credenciales para el servicio",
+		"Login successful":                                       "Inicio de sesión correcto",
+		"Logout successful":                                      "Cierre de sesión correcto",
+		"Failed to logout":                                       "No se pudo cerrar la sesión",
+		"Token refresh failed":                                   "No se pudo renovar el token",
+		"Failed to validate token":                               "No se pudo validar el token",
+		"Failed to check permissions":                            "No se pudieron comprobar los permisos",
+		"Failed to change password":                              "No se pudo cambiar la contraseña",
+		"Failed to impersonate user":                             "No se pudo suplantar al usuario",
+		"Impersonation started":                                  "Suplantación iniciada",
+		"Permission cache invalidated":                           "Caché de permisos invalidada",
+		"invalid username or password":                           "usuario o contraseña no válidos",
+		"account is disabled":                                    "la cuenta está desactivada",
+		"current password is incorrect":                          "la contraseña actual es incorrecta",
+		"new password must differ from the current password":     "la nueva contraseña debe ser distinta de la actual",
+		"refresh token has expired":                              "el token de actualización ha caducado",
+		"refresh token not found or revoked":                     "token de actualización no encontrado o revocado",
+		"token mismatch":                                         "los tokens no coinciden",
+		"cannot impersonate an admin":                            "no se puede suplantar a un administrador",
+		"cannot impersonate yourself":                            "no puede suplantarse a sí mismo",
+		"impersonator is no longer allowed to impersonate":       "el suplantador ya no tiene permiso para suplantar",
+		"cannot delete your own account":                         "no puede eliminar su propia cuenta",
+		"cannot delete system role":                              "no se puede eliminar un rol del sistema",
+		"cannot modify description of system role":               "no se puede modificar la descripción de un rol del sistema",
+		"one or more roles do not exist":                         "uno o varios roles no existen",
+		"email is already in use":                                "el correo electrónico ya está en uso",
+		"user with this username or email already exists":        "ya existe un usuario con ese nombre o correo electrónico",
+		"parent groups would create a cycle":                     "los grupos padre crearían un ciclo",
+		"Failed to compute role impact":                          "No se pudo calcular el impacto del rol",
+		"Failed to preview role change":                          "No se pudo previsualizar el cambio de rol",
+		"Failed to export signing keys":                          "No se pudieron exportar las claves de firma",
+		"Failed to rotate signing key":                           "No se pudo rotar la clave de firma",
+		"Failed to revoke kiosk token":                           "No se pudo revocar el token de quiosco",
+		"kiosk token is already revoked":                         "el token de quiosco ya está revocado",
+		"Provisioning token created; it will not be shown again": "Token de aprovisionamiento creado; no se volverá a mostrar",
+		"provisioning token is invalid, expired or already used": "el token de aprovisionamiento no es válido, ha caducado o ya se ha utilizado",
+
+		// Devices and commands
+		"Command already accepted":                                       "Comando ya aceptado",
+		"Dry run completed; no commands were sent":                       "Simulación completada; no se enviaron comandos",
+		"Overriding the command rate limit requires the admin role":      "Omitir el límite de comandos requiere el rol de administrador",
+		"overrideRateLimit applies to immediate commands only":           "overrideRateLimit solo se aplica a comandos inmediatos",
+		"scheduled command has already finished":                         "el comando programado ya ha finalizado",
+		"scheduled command is not active":                                "el comando programado no está activo",
+		"scheduled command is not paused":                                "el comando programado no está en pausa",
+		"scheduled command was modified concurrently":                    "el comando programado se modificó simultáneamente",
+		"no telemetry found for device":                                  "no se encontró telemetría para el dispositivo",
+		"no time-series data found for device":                           "no se encontraron series temporales para el dispositivo",
+		"Scenario conflicts with approved or executing scenarios":        "El escenario entra en conflicto con escenarios aprobados o en ejecución",
+		"validation failed: scheduledAt must be in the future":           "error de validación: scheduledAt debe estar en el futuro",
+		"validation failed: scheduledAt and cron are mutually exclusive": "error de validación: scheduledAt y cron son mutuamente excluyentes",
+		"validation failed: cron expression never matches":               "error de validación: la expresión cron nunca coincide",
+
+		// Forecasts and analytics
+		"Forecast generation started":                                        "Generación del pronóstico iniciada",
+		"Forecast cache invalidated":                                         "Caché de pronósticos invalidada",
+		"Report generation started":                                          "Generación del informe iniciada",
+		"Retention run completed":                                            "Depuración por retención completada",
+		"Weather rule evaluated":                                             "Regla meteorológica evaluada",
+		"Setting reset to default":                                           "Ajuste restablecido al valor predeterminado",
+		"Job cancelled":                                                      "Tarea cancelada",
+		"Job queued for retry":                                               "Tarea puesta en cola para reintentar",
+		"Failed to calculate degree days":                                    "No se pudieron calcular los grados-día",
+		"long-horizon forecasts are disabled":                                "los pronósticos a largo plazo están desactivados",
+		"no forecasts found for this building":                               "no se encontraron pronósticos para este edificio",
+		"no peak load predictions found for this building":                   "no se encontraron predicciones de pico de carga para este edificio",
+		"no energy provider configured for building":                         "no hay ningún proveedor de energía configurado para el edificio",
+		"no energy provider configured for region":                           "no hay ningún proveedor de energía configurado para la región",
+		"budget already exists for this building or device":                  "ya existe un presupuesto para este edificio o dispositivo",
+		"tariff for this region already exists":                              "ya existe una tarifa para esta región",
+		"calendar day already exists":                                        "el día del calendario ya existe",
+		"device type already exists":                                         "el tipo de dispositivo ya existe",
+		"baseline model could not be fitted: degree day terms are collinear": "no se pudo ajustar el modelo de referencia: los términos de grados-día son colineales",
+		"validation failed: baseline period has too few days with consumption and weather data": "error de validación: el período de referencia tiene muy pocos días con datos de consumo y meteorológicos",
+		"validation failed: reporting period has no days with consumption and weather data":     "error de validación: el período de informe no tiene días con datos de consumo y meteorológicos",
+		"validation failed: scenario has no applied actions to verify":                          "error de validación: el escenario no tiene acciones aplicadas que verificar",
+		"validation failed: no whole day has passed since the scenario completed":               "error de validación: aún no ha pasado un día completo desde que finalizó el escenario",
+		"validation failed: not enough hourly data for the scenario's devices":                  "error de validación: no hay suficientes datos horarios para los dispositivos del escenario",
+
+		// Peak load mitigation actions and recommendations
+		"Monitor energy consumption closely during this period":                                          "Vigilar de cerca el consumo de energía durante este período",
+		"Consider load shedding for non-essential equipment":                                             "Considerar la desconexión de cargas de los equipos no esenciales",
+		"Activate demand response programs":                                                              "Activar los programas de respuesta a la demanda",
+		"Temporarily reduce HVAC setpoints":                                                              "Reducir temporalmente las consignas de climatización",
+		"Dim lighting in unoccupied areas":                                                               "Atenuar la iluminación en las zonas desocupadas",
+		"Pre-cool/pre-heat building before peak period":                                                  "Preenfriar/precalentar el edificio antes del período pico",
+		"Reduce HVAC intensity during peak":                                                              "Reducir la intensidad de la climatización durante el pico",
+		"Shift flexible loads to off-peak hours":                                                         "Trasladar las cargas flexibles a horas valle",
+		"Optimize HVAC schedules":                                                                        "Optimizar los horarios de climatización",
+		"Review lighting schedules":                                                                      "Revisar los horarios de iluminación",
+		"No significant peak periods detected. Continue monitoring.":                                     "No se detectaron períodos pico significativos. Continúe con la supervisión.",
+		"Critical peaks detected - immediate action recommended":                                         "Picos críticos detectados - se recomienda actuar de inmediato",
+		"Consider enrolling in utility demand response programs":                                         "Considerar la inscripción en los programas de respuesta a la demanda de la compañía eléctrica",
+		"High peaks detected - review energy management strategies":                                      "Picos altos detectados - revisar las estrategias de gestión energética",
+		"Review and optimize HVAC schedules":                                                             "Revisar y optimizar los horarios de climatización",
+		"Consider shifting flexible loads to off-peak hours":                                             "Considerar trasladar las cargas flexibles a horas valle",
+		"Optimize HVAC Setpoints":                                                                        "Optimizar las consignas de climatización",
+		"Current HVAC setpoints can be adjusted to reduce energy consumption while maintaining comfort.": "Las consignas de climatización actuales pueden ajustarse para reducir el consumo de energía manteniendo el confort.",
+		"Increase cooling setpoint by 2°C during peak hours":                                             "Aumentar la consigna de refrigeración 2°C durante las horas pico",
+		"Review current HVAC schedules":                                                                  "Revisar los horarios de climatización actuales",
+		"Adjust cooling setpoint from 22°C to 24°C during peak hours (14:00-18:00)":                      "Ajustar la consigna de refrigeración de 22°C a 24°C durante las horas pico (14:00-18:00)",
+		"Monitor comfort levels and adjust if needed":                                                    "Vigilar el nivel de confort y ajustar si es necesario",
+		"Implement Lighting Schedules":                                                                   "Implantar horarios de iluminación",
+		"Lighting in common areas can be scheduled to reduce unnecessary usage.":                         "La iluminación de las zonas comunes puede programarse para reducir el uso innecesario.",
+		"Configure automatic lighting schedules":                                                         "Configurar horarios de iluminación automáticos",
+		"Identify common areas with extended lighting hours":                                             "Identificar las zonas comunes con horarios de iluminación prolongados",
+		"Configure occupancy-based or scheduled lighting":                                                "Configurar iluminación programada o basada en la ocupación",
+		"Set dimming levels for daylight harvesting":                                                     "Definir niveles de atenuación para aprovechar la luz natural",
+		"Equipment Upgrade Assessment":                                                                   "Evaluación de la renovación de equipos",
+		"Some equipment may benefit from efficiency upgrades.":                                           "Algunos equipos podrían beneficiarse de mejoras de eficiencia.",
+		"Schedule equipment efficiency audit":                                                            "Programar una auditoría de eficiencia de los equipos",
+		"List all major energy-consuming equipment":                                                      "Enumerar todos los equipos de mayor consumo energético",
+		"Assess age and efficiency ratings":                                                              "Evaluar su antigüedad y su clase de eficiencia",
+		"Evaluate upgrade options and ROI":                                                               "Evaluar las opciones de renovación y su retorno de la inversión",
+	},
+	Patterns: map[string]string{
+		"{} created successfully":                        "{}: creación correcta",
+		"{} updated successfully":                        "{}: actualización correcta",
+		"{} deleted successfully":                        "{}: eliminación correcta",
+		"{} restored successfully":                       "{}: restauración correcta",
+		"{} saved successfully":                          "{}: guardado correcto",
+		"{} revoked successfully":                        "{}: revocación correcta",
+		"{} imported successfully":                       "{}: importación correcta",
+		"{} generated successfully":                      "{}: generación correcta",
+		"{} registered successfully":                     "{}: registro correcto",
+		"{} ingested successfully":                       "{}: ingesta correcta",
+		"{} applied successfully":                        "{}: aplicación correcta",
+		"{} calculated successfully":                     "{}: cálculo correcto",
+		"{} acknowledged successfully":                   "{}: confirmación correcta",
+		"{} rotated successfully":                        "{}: rotación correcta",
+		"{} changed successfully":                        "{}: cambio correcto",
+		"{} added successfully":                          "{}: adición correcta",
+		"{} removed successfully":                        "{}: retirada correcta",
+		"{} refreshed successfully":                      "{}: renovación correcta",
+		"{} sent successfully":                           "{}: envío correcto",
+		"{} scheduled successfully":                      "{}: programación correcta",
+		"{} sent to IoT service successfully":            "{}: envío al servicio IoT correcto",
+		"{} not found":                                   "no se encontró: {}",
+		"{} is required":                                 "Se requiere: {}",
+		"{} query parameter is required":                 "Se requiere el parámetro de consulta {}",
+		"invalid {} ID format":                           "formato de ID no válido ({})",
+		"{} with this ID already exists":                 "ya existe un elemento con este ID ({})",
+		"{} with this name already exists":               "ya existe un elemento con este nombre ({})",
+		"Failed to retrieve {}":                          "Error al obtener: {}",
+		"Failed to get {}":                               "Error al obtener: {}",
+		"Failed to create {}":                            "Error al crear: {}",
+		"Failed to update {}":                            "Error al actualizar: {}",
+		"Failed to delete {}":                            "Error al eliminar: {}",
+		"Failed to restore {}":                           "Error al restaurar: {}",
+		"Failed to send {}":                              "Error al enviar: {}",
+		"validation failed: {}":                          "error de validación: {}",
+		"Detected {} peak period(s) requiring attention": "Se detectaron {} período(s) pico que requieren atención",
+	},
+	Terms: map[string]string{
+		"anomaly":                  "anomalía",
+		"anomaly id":               "ID de anomalía",
+		"audit log":                "registro de auditoría",
+		"audit logs":               "registros de auditoría",
+		"authorization header":     "cabecera Authorization",
+		"automation rule":          "regla de automatización",
+		"budget":                   "presupuesto",
+		"building":                 "edificio",
+		"building id":              "ID de edificio",
+		"bulk telemetry":           "telemetría masiva",
+		"calendar day":             "día del calendario",
+		"command":                  "comando",
+		"deleted device":           "dispositivo eliminado",
+		"deleted user":             "usuario eliminado",
+		"deleted users":            "usuarios eliminados",
+		"device":                   "dispositivo",
+		"device calibration":       "calibración del dispositivo",
+		"device id":                "ID de dispositivo",
+		"devices":                  "dispositivos",
+		"device type":              "tipo de dispositivo",
+		"energy provider":          "proveedor de energía",
+		"feature snapshot":         "instantánea de características",
+		"forecast":                 "pronóstico",
+		"forecast actuals":         "valores reales del pronóstico",
+		"forecast id":              "ID de pronóstico",
+		"forecast status":          "estado del pronóstico",
+		"group":                    "grupo",
+		"group member":             "miembro del grupo",
+		"group members":            "miembros del grupo",
+		"groups":                   "grupos",
+		"holidays":                 "días festivos",
+		"impersonator":             "suplantador",
+		"job":                      "tarea",
+		"kiosk token":              "token de quiosco",
+		"kiosk tokens":             "tokens de quiosco",
+		"kpi":                      "KPI",
+		"kpis":                     "KPI",
+		"m&v report":               "informe M&V",
+		"notification":             "notificación",
+		"notification logs":        "registros de notificaciones",
+		"notification preferences": "preferencias de notificación",
+		"notification statistics":  "estadísticas de notificaciones",
+		"occupancy schedule":       "horario de ocupación",
+		"optimization execution":   "ejecución de optimización",
+		"optimization scenario":    "escenario de optimización",
+		"password":                 "contraseña",
+		"peak load":                "pico de carga",
+		"peak load prediction":     "predicción de pico de carga",
+		"portfolio scenario":       "escenario de cartera",
+		"profile":                  "perfil",
+		"provisioning token":       "token de aprovisionamiento",
+		"recommendation":           "recomendación",
+		"refresh token":            "token de actualización",
+		"report":                   "informe",
+		"report id":                "ID de informe",
+		"role":                     "rol",
+		"roles":                    "roles",
+		"scenario":                 "escenario",
+		"scenario id":              "ID de escenario",
+		"scheduled command":        "comando programado",
+		"setting":                  "ajuste",
+		"signing key":              "clave de firma",
+		"signing keys":             "claves de firma",
+		"tariff":                   "tarifa",
+		"telemetry":                "telemetría",
+		"token":                    "token",
+		"user":                     "usuario",
+		"user id":                  "ID de usuario",
+		"user roles":               "roles del usuario",
+		"users":                    "usuarios",
+		"weather forecast":         "previsión meteorológica",
+		"weather history":          "historial meteorológico",
+		"weather rule":             "regla meteorológica",
+	},
+}
@@ -1,14 +1,18 @@
 package integrations
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"analytics-service/internal/config"
 	"analytics-service/internal/models"
+	"analytics-service/internal/tracing"
+	"shared/signing"
 )
 
 // ForecastClient handles communication with the Forecast & Optimization service
@@ -20,13 +24,16 @@ type ForecastClient struct {
 // NewForecastClient creates a new forecast client
 func NewForecastClient(cfg *config.Config) *ForecastClient {
 	return &ForecastClient{
-		httpClient: &http.Client{
-			Timeout: cfg.Forecast.Timeout,
-		},
-		baseURL: cfg.Forecast.URL,
+		httpClient: tracing.WrapClient(signing.NewClient(cfg.Forecast.Timeout, ServiceName, cfg.Auth.ServiceSecret)),
+		baseURL:    cfg.Forecast.URL,
 	}
 }
 
+// HealthCheck checks if the forecast service is reachable
+func (c *ForecastClient) HealthCheck(ctx context.Context) error {
+	return checkServiceHealth(ctx, c.httpClient, c.baseURL+"/health")
+}
+
 // GetLatestForecast retrieves the latest forecast for a building
 func (c *ForecastClient) GetLatestForecast(ctx context.Context, buildingID string, authToken string) (map[string]interface{}, error) {
 	reqURL := fmt.Sprintf("%s/forecast/latest?buildingId=%s", c.baseURL, url.QueryEscape(buildingID))
@@ -63,3 +70,287 @@ func (c *ForecastClient) GetLatestForecast(ctx context.Context, buildingID strin
 
 	return nil, fmt.Errorf("invalid response format")
 }
+
+// GetStoredForecast retrieves the latest completed forecast of a building without triggering
+// a refresh. It authenticates with the service key, so background workers can use it.
+// A building without forecasts returns nil.
+func (c *ForecastClient) GetStoredForecast(ctx context.Context, buildingID string) (map[string]interface{}, error) {
+	reqURL := fmt.Sprintf("%s/internal/forecast/latest?buildingId=%s", c.baseURL, url.QueryEscape(buildingID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("forecast service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp models.APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("forecast service error: %s", apiResp.Error.Message)
+	}
+
+	if dataMap, ok := apiResp.Data.(map[string]interface{}); ok {
+		return dataMap, nil
+	}
+
+	return nil, fmt.Errorf("invalid response format")
+}
+
+// GetStoredForecasts retrieves the latest completed forecasts of several buildings in one
+// request, keyed by building ID. Like GetStoredForecast it authenticates with the service key.
+// Buildings without forecasts are left out.
+func (c *ForecastClient) GetStoredForecasts(ctx context.Context, buildingIDs []string) (map[string]map[string]interface{}, error) {
+	forecasts := make(map[string]map[string]interface{})
+	if len(buildingIDs) == 0 {
+		return forecasts, nil
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{"buildingIds": buildingIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/internal/forecast/latest/batch", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("forecast service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp models.APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("forecast service error: %s", apiResp.Error.Message)
+	}
+
+	items, ok := apiResp.Data.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid response format")
+	}
+	for _, item := range items {
+		if forecast, ok := item.(map[string]interface{}); ok {
+			if buildingID, _ := forecast["buildingId"].(string); buildingID != "" {
+				forecasts[buildingID] = forecast
+			}
+		}
+	}
+
+	return forecasts, nil
+}
+
+// ListScenarios retrieves a building's optimization scenarios, optionally filtered by status
+func (c *ForecastClient) ListScenarios(ctx context.Context, buildingID, status string, limit int, authToken string) ([]map[string]interface{}, error) {
+	reqURL := fmt.Sprintf("%s/optimization/scenarios?buildingId=%s&limit=%d", c.baseURL, url.QueryEscape(buildingID), limit)
+	if status != "" {
+		reqURL += "&status=" + url.QueryEscape(status)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+authToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("forecast service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp models.APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("forecast service error: %s", apiResp.Error.Message)
+	}
+
+	dataMap, ok := apiResp.Data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid response format")
+	}
+
+	scenariosData, ok := dataMap["scenarios"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("scenarios data not found in response")
+	}
+
+	result := make([]map[string]interface{}, len(scenariosData))
+	for i, item := range scenariosData {
+		if itemMap, ok := item.(map[string]interface{}); ok {
+			result[i] = itemMap
+		}
+	}
+
+	return result, nil
+}
+
+// GetScenario retrieves an optimization scenario by ID
+func (c *ForecastClient) GetScenario(ctx context.Context, scenarioID string, authToken string) (map[string]interface{}, error) {
+	reqURL := fmt.Sprintf("%s/optimization/scenario/%s", c.baseURL, url.PathEscape(scenarioID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+authToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("forecast service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp models.APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("forecast service error: %s", apiResp.Error.Message)
+	}
+
+	if dataMap, ok := apiResp.Data.(map[string]interface{}); ok {
+		return dataMap, nil
+	}
+
+	return nil, fmt.Errorf("invalid response format")
+}
+
+// GetDegreeDays retrieves heating and cooling degree days of a building location for [from, to)
+func (c *ForecastClient) GetDegreeDays(ctx context.Context, buildingID string, from, to time.Time, authToken string) (*models.DegreeDays, error) {
+	return c.getDegreeDays(ctx, "/weather/degree-days", buildingID, from, to, authToken)
+}
+
+// GetStoredDegreeDays retrieves the degree days of a building location for [from, to) with the
+// service key, so background workers can use it
+func (c *ForecastClient) GetStoredDegreeDays(ctx context.Context, buildingID string, from, to time.Time) (*models.DegreeDays, error) {
+	return c.getDegreeDays(ctx, "/internal/weather/degree-days", buildingID, from, to, "")
+}
+
+// getDegreeDays requests degree days from a forecast service path, with the user's token if given
+func (c *ForecastClient) getDegreeDays(ctx context.Context, path, buildingID string, from, to time.Time, authToken string) (*models.DegreeDays, error) {
+	params := url.Values{}
+	params.Set("buildingId", buildingID)
+	params.Set("from", from.UTC().Format(time.RFC3339))
+	params.Set("to", to.UTC().Format(time.RFC3339))
+	reqURL := fmt.Sprintf("%s%s?%s", c.baseURL, path, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+authToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("forecast service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp struct {
+		Success bool              `json:"success"`
+		Data    models.DegreeDays `json:"data"`
+		Error   *models.APIError  `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.Success {
+		if apiResp.Error != nil {
+			return nil, fmt.Errorf("forecast service error: %s", apiResp.Error.Message)
+		}
+		return nil, fmt.Errorf("forecast service error")
+	}
+
+	return &apiResp.Data, nil
+}
+
+// GetCurrentTariff retrieves the tariff in effect for a region, as resolved by the forecast service
+func (c *ForecastClient) GetCurrentTariff(ctx context.Context, region string, authToken string) (*models.Tariff, error) {
+	reqURL := fmt.Sprintf("%s/tariffs/current?region=%s", c.baseURL, url.QueryEscape(region))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+authToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("forecast service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp struct {
+		Success bool             `json:"success"`
+		Data    models.Tariff    `json:"data"`
+		Error   *models.APIError `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.Success {
+		if apiResp.Error != nil {
+			return nil, fmt.Errorf("forecast service error: %s", apiResp.Error.Message)
+		}
+		return nil, fmt.Errorf("forecast service error")
+	}
+
+	return &apiResp.Data, nil
+}
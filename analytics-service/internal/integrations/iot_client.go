@@ -10,6 +10,8 @@ import (
 
 	"analytics-service/internal/config"
 	"analytics-service/internal/models"
+	"analytics-service/internal/tracing"
+	"shared/signing"
 )
 
 // IoTClient handles communication with the IoT & Control service
@@ -21,13 +23,16 @@ type IoTClient struct {
 // NewIoTClient creates a new IoT client
 func NewIoTClient(cfg *config.Config) *IoTClient {
 	return &IoTClient{
-		httpClient: &http.Client{
-			Timeout: cfg.IoT.Timeout,
-		},
-		baseURL: cfg.IoT.URL,
+		httpClient: tracing.WrapClient(signing.NewClient(cfg.IoT.Timeout, ServiceName, cfg.Auth.ServiceSecret)),
+		baseURL:    cfg.IoT.URL,
 	}
 }
 
+// HealthCheck checks if the IoT control service is reachable
+func (c *IoTClient) HealthCheck(ctx context.Context) error {
+	return checkServiceHealth(ctx, c.httpClient, c.baseURL+"/health")
+}
+
 // GetTelemetryHistory retrieves historical telemetry data
 func (c *IoTClient) GetTelemetryHistory(ctx context.Context, deviceID string, from, to time.Time, page, limit int, authToken string) ([]map[string]interface{}, error) {
 	reqURL := fmt.Sprintf("%s/iot/telemetry/history?deviceId=%s&from=%s&to=%s&page=%d&limit=%d",
@@ -100,6 +105,24 @@ func (c *IoTClient) GetDevices(ctx context.Context, buildingID string, authToken
 
 	req.Header.Set("Authorization", "Bearer "+authToken)
 
+	return c.fetchDevices(req)
+}
+
+// GetBuildingDevices retrieves a building's devices without a user token.
+// It authenticates with the service key, for kiosk dashboards.
+func (c *IoTClient) GetBuildingDevices(ctx context.Context, buildingID string) ([]map[string]interface{}, error) {
+	reqURL := fmt.Sprintf("%s/internal/devices?buildingId=%s", c.baseURL, url.QueryEscape(buildingID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	return c.fetchDevices(req)
+}
+
+// fetchDevices sends a device list request and extracts the devices from the response
+func (c *IoTClient) fetchDevices(req *http.Request) ([]map[string]interface{}, error) {
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -140,6 +163,61 @@ func (c *IoTClient) GetDevices(ctx context.Context, buildingID string, authToken
 	return result, nil
 }
 
+// GetLocations retrieves the locations of a building's hierarchy, optionally of one level
+func (c *IoTClient) GetLocations(ctx context.Context, buildingID, level string, authToken string) ([]map[string]interface{}, error) {
+	query := url.Values{}
+	query.Set("buildingId", buildingID)
+	if level != "" {
+		query.Set("level", level)
+	}
+	reqURL := fmt.Sprintf("%s/iot/locations?%s", c.baseURL, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+authToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("IoT service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp models.APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("IoT service error: %s", apiResp.Error.Message)
+	}
+
+	dataMap, ok := apiResp.Data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid response format")
+	}
+
+	locationsData, ok := dataMap["locations"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("locations data not found in response")
+	}
+
+	result := make([]map[string]interface{}, 0, len(locationsData))
+	for _, item := range locationsData {
+		if itemMap, ok := item.(map[string]interface{}); ok {
+			result = append(result, itemMap)
+		}
+	}
+
+	return result, nil
+}
+
 // GetDeviceState retrieves device state
 func (c *IoTClient) GetDeviceState(ctx context.Context, deviceID string, authToken string) (map[string]interface{}, error) {
 	reqURL := fmt.Sprintf("%s/iot/state/%s", c.baseURL, url.QueryEscape(deviceID))
@@ -176,3 +254,43 @@ func (c *IoTClient) GetDeviceState(ctx context.Context, deviceID string, authTok
 
 	return nil, fmt.Errorf("invalid response format")
 }
+
+// GetDevice retrieves a single device
+func (c *IoTClient) GetDevice(ctx context.Context, deviceID string, authToken string) (map[string]interface{}, error) {
+	reqURL := fmt.Sprintf("%s/iot/devices/%s", c.baseURL, url.PathEscape(deviceID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+authToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("IoT service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp models.APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("IoT service error: %s", apiResp.Error.Message)
+	}
+
+	if dataMap, ok := apiResp.Data.(map[string]interface{}); ok {
+		return dataMap, nil
+	}
+
+	return nil, fmt.Errorf("invalid response format")
+}
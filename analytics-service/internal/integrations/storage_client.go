@@ -12,6 +12,7 @@ import (
 
 	"analytics-service/internal/config"
 	"analytics-service/internal/models"
+	"analytics-service/internal/tracing"
 )
 
 // StorageClient handles communication with the external Storage service
@@ -26,12 +27,18 @@ type StorageClient struct {
 func NewStorageClient(cfg *config.Config) *StorageClient {
 	return &StorageClient{
 		httpClient: &http.Client{
-			Timeout: cfg.Storage.Timeout,
+			Timeout:   cfg.Storage.Timeout,
+			Transport: tracing.NewTransport(http.DefaultTransport),
 		},
 		baseURL: cfg.Storage.URL,
 	}
 }
 
+// HealthCheck checks if the storage service is reachable
+func (c *StorageClient) HealthCheck(ctx context.Context) error {
+	return checkServiceHealth(ctx, c.httpClient, c.baseURL+"/health")
+}
+
 // SaveReport saves a report to the storage service
 // POST /storage/analytics/reports
 func (c *StorageClient) SaveReport(ctx context.Context, report *models.Report, authToken string) error {
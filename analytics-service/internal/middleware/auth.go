@@ -2,30 +2,71 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"analytics-service/internal/config"
 	"analytics-service/internal/integrations"
 	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
+	"shared/signing"
 )
 
 // AuthMiddleware handles JWT authentication via Security service
 type AuthMiddleware struct {
 	securityClient *integrations.SecurityClient
+	localValidator *LocalValidator
+	permissions    *PermissionCache
+
+	// Internal requests must be signed; the plain service key is accepted only when enabled
+	serviceVerifier  *signing.Verifier
+	serviceKey       string
+	acceptServiceKey bool
 }
 
 // NewAuthMiddleware creates a new auth middleware instance
-func NewAuthMiddleware(securityClient *integrations.SecurityClient) *AuthMiddleware {
-	return &AuthMiddleware{
+func NewAuthMiddleware(securityClient *integrations.SecurityClient, cfg config.AuthConfig) *AuthMiddleware {
+	m := &AuthMiddleware{
 		securityClient: securityClient,
+		permissions:    NewPermissionCache(cfg.PermissionCacheTTL),
+
+		serviceVerifier:  signing.NewVerifier(cfg.ServiceSecrets, cfg.ServiceKey, cfg.SharedSecretDevMode, cfg.SignatureWindow),
+		serviceKey:       cfg.ServiceKey,
+		acceptServiceKey: cfg.AcceptServiceKey,
+	}
+	if cfg.LocalValidation {
+		m.localValidator = NewLocalValidator(securityClient, cfg.JWTSecret, cfg.SigningKeyRefresh)
 	}
+	return m
+}
+
+// Permissions returns the permission cache so role changes can invalidate it
+func (m *AuthMiddleware) Permissions() *PermissionCache {
+	return m.permissions
 }
 
-// RequireAuth validates the access token via Security service and sets user info in context
+// RequireAuth validates the access token via Security service and sets user info in context.
+// Kiosk tokens are rejected.
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
+	return m.authenticate("")
+}
+
+// RequireAuthOrKiosk is RequireAuth for read-only dashboard routes, additionally accepting
+// kiosk tokens issued for the building named by the buildingParam route parameter
+func (m *AuthMiddleware) RequireAuthOrKiosk(buildingParam string) gin.HandlerFunc {
+	return m.authenticate(buildingParam)
+}
+
+// authenticate validates the access token; kiosk tokens pass only when kioskBuildingParam
+// is set and names their building
+func (m *AuthMiddleware) authenticate(kioskBuildingParam string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -47,26 +88,140 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		// Validate token via Security service
-		validationResp, err := m.securityClient.ValidateToken(c.Request.Context(), token)
+		validationResp, err := m.validateToken(c.Request.Context(), token)
 		if err != nil || !validationResp.Valid {
 			code := models.ErrCodeTokenInvalid
-			if validationResp != nil && strings.Contains(validationResp.Message, "expired") {
-				code = models.ErrCodeTokenExpired
+			details := ""
+			if validationResp != nil {
+				details = validationResp.Message
+				if strings.Contains(validationResp.Message, "expired") {
+					code = models.ErrCodeTokenExpired
+				}
 			}
 			c.AbortWithStatusJSON(http.StatusUnauthorized, models.NewErrorResponse(
 				code,
 				"Invalid or expired token",
-				validationResp.Message,
+				details,
 			))
 			return
 		}
 
+		if kiosk := validationResp.Kiosk; kiosk != nil {
+			if kioskBuildingParam == "" || c.Request.Method != http.MethodGet || c.Param(kioskBuildingParam) != kiosk.BuildingID {
+				c.AbortWithStatusJSON(http.StatusForbidden, models.NewErrorResponse(
+					models.ErrCodeForbidden,
+					"Kiosk tokens cannot access this endpoint",
+					"",
+				))
+				return
+			}
+			c.Set("kiosk", kiosk)
+		}
+
 		// Set user info in context
 		c.Set("userID", validationResp.UserID)
 		c.Set("roles", validationResp.Roles)
+		c.Set("groups", validationResp.Groups)
 		c.Set("token", token)
 
+		// Repository queries of the request are limited to the buildings of the user's organization
+		if validationResp.Tenant != nil {
+			c.Request = c.Request.WithContext(tenant.WithScope(c.Request.Context(), validationResp.Tenant))
+		}
+
+		if len(validationResp.Masks) > 0 {
+			defer maskResponses(c, validationResp.Masks)()
+		}
+
+		if validationResp.Impersonation == nil {
+			c.Next()
+			return
+		}
+
+		c.Set("impersonation", validationResp.Impersonation)
+		c.Next()
+		m.auditImpersonatedRequest(c, validationResp)
+	}
+}
+
+// auditImpersonatedRequest records a request made by an admin acting as another user
+func (m *AuthMiddleware) auditImpersonatedRequest(c *gin.Context, validationResp *models.TokenValidationResponse) {
+	status := "SUCCESS"
+	if c.Writer.Status() >= http.StatusBadRequest {
+		status = "FAILURE"
+	}
+
+	m.securityClient.AuditLog(
+		c.Request.Context(), validationResp.UserID, "", "IMPERSONATED_REQUEST", "api", c.Request.URL.Path,
+		status, "", GetClientIP(c), GetUserAgent(c), c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{
+			"impersonatorId":       validationResp.Impersonation.ImpersonatorID,
+			"impersonatorUsername": validationResp.Impersonation.ImpersonatorUsername,
+			"reason":               validationResp.Impersonation.Reason,
+			"statusCode":           c.Writer.Status(),
+		},
+	)
+}
+
+// validateToken resolves a token from the permission cache, then locally when enabled,
+// and otherwise via the Security service
+func (m *AuthMiddleware) validateToken(ctx context.Context, token string) (*models.TokenValidationResponse, error) {
+	if cached, ok := m.permissions.Get(token); ok {
+		return cached, nil
+	}
+
+	// Impersonation and kiosk tokens are always checked remotely so revocations are honoured
+	if m.localValidator != nil {
+		resp, issuedAt, err := m.localValidator.Validate(ctx, token)
+		remoteOnly := err == nil && (resp.Impersonation != nil || resp.Kiosk != nil)
+		if err == nil && (!resp.Valid || (!remoteOnly && !m.permissions.IsStale(resp.UserID, issuedAt))) {
+			m.permissions.Put(token, resp)
+			return resp, nil
+		}
+	}
+
+	resp, err := m.securityClient.ValidateToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	m.permissions.Put(token, resp)
+	return resp, nil
+}
+
+// RequireServiceKey restricts internal endpoints to other services that sign their requests
+// with their service secret. Replayed requests are rejected.
+func (m *AuthMiddleware) RequireServiceKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m.acceptServiceKey && m.serviceKey != "" {
+			key := c.GetHeader(integrations.ServiceKeyHeader)
+			if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(m.serviceKey)) == 1 {
+				c.Next()
+				return
+			}
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				"Failed to read request body",
+				err.Error(),
+			))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		service, err := m.serviceVerifier.Verify(c.Request, body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				"Invalid service signature",
+				err.Error(),
+			))
+			return
+		}
+
+		c.Set("callerService", service)
 		c.Next()
 	}
 }
@@ -157,6 +312,19 @@ func GetUserRoles(c *gin.Context) []string {
 	return []string{}
 }
 
+// GetUserGroups retrieves the user's groups from context, including groups inherited through
+// parent groups
+func GetUserGroups(c *gin.Context) []string {
+	groups, exists := c.Get("groups")
+	if !exists {
+		return []string{}
+	}
+	if groupsList, ok := groups.([]string); ok {
+		return groupsList
+	}
+	return []string{}
+}
+
 // GetToken retrieves the access token from context
 func GetToken(c *gin.Context) string {
 	token, exists := c.Get("token")
@@ -169,6 +337,30 @@ func GetToken(c *gin.Context) string {
 	return ""
 }
 
+// GetImpersonation retrieves the impersonation details from context, or nil for regular tokens
+func GetImpersonation(c *gin.Context) *models.Impersonation {
+	impersonation, exists := c.Get("impersonation")
+	if !exists {
+		return nil
+	}
+	if i, ok := impersonation.(*models.Impersonation); ok {
+		return i
+	}
+	return nil
+}
+
+// GetKiosk retrieves the kiosk scope from context, or nil for user tokens
+func GetKiosk(c *gin.Context) *models.KioskScope {
+	kiosk, exists := c.Get("kiosk")
+	if !exists {
+		return nil
+	}
+	if k, ok := kiosk.(*models.KioskScope); ok {
+		return k
+	}
+	return nil
+}
+
 // HasRole checks if the user has a specific role
 func HasRole(c *gin.Context, role string) bool {
 	roles := GetUserRoles(c)
@@ -179,3 +371,23 @@ func HasRole(c *gin.Context, role string) bool {
 	}
 	return false
 }
+
+// IsSuperAdmin checks if the user holds the super admin role. Unlike HasRole, the admin role does
+// not satisfy it, since admins of an organization are limited to its buildings.
+func IsSuperAdmin(c *gin.Context) bool {
+	for _, r := range GetUserRoles(c) {
+		if r == "super_admin" {
+			return true
+		}
+	}
+	return false
+}
+
+// SeesAllBuildings checks if the user may view buildings without holding devices in them: super
+// admins see every building, and admins of an organization the buildings of its tenant scope
+func SeesAllBuildings(c *gin.Context) bool {
+	if IsSuperAdmin(c) {
+		return true
+	}
+	return HasRole(c, "admin") && tenant.FromContext(c.Request.Context()) != nil
+}
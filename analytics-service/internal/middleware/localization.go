@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"analytics-service/internal/i18n"
+)
+
+// Localization negotiates the language of the request from its Accept-Language header and
+// translates the messages of JSON responses into it. The language is stored in the gin context
+// and the request context for handlers that localize generated texts.
+func Localization() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		c.Set("language", lang)
+		c.Request = c.Request.WithContext(i18n.WithLanguage(c.Request.Context(), lang))
+		c.Header("Content-Language", lang)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+
+		if lang == i18n.DefaultLanguage {
+			c.Next()
+			return
+		}
+		// The writer is restored even when a handler panics, so the recovery response is not lost
+		w := &localizingWriter{ResponseWriter: c.Writer, lang: lang}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// GetLanguage retrieves the negotiated language of the request
+func GetLanguage(c *gin.Context) string {
+	if lang, ok := c.Get("language"); ok {
+		if l, ok := lang.(string); ok {
+			return l
+		}
+	}
+	return i18n.DefaultLanguage
+}
+
+// localizingWriter buffers JSON responses so their messages can be translated before they are
+// sent. Other responses, such as exports and event streams, pass through unchanged.
+type localizingWriter struct {
+	gin.ResponseWriter
+	lang      string
+	body      bytes.Buffer
+	decided   bool
+	buffering bool
+}
+
+// Write buffers JSON bodies and writes anything else straight through
+func (w *localizingWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	}
+	if !w.buffering {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+// WriteString buffers JSON bodies and writes anything else straight through
+func (w *localizingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush is deferred until the buffered response is complete
+func (w *localizingWriter) Flush() {
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// finish translates the message and error message of the buffered API response and sends it.
+// Bodies that are not API responses are sent as they are.
+func (w *localizingWriter) finish() {
+	if !w.buffering || w.body.Len() == 0 {
+		return
+	}
+
+	data := w.body.Bytes()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var payload map[string]interface{}
+	if err := decoder.Decode(&payload); err == nil && translateMessages(payload, w.lang) {
+		if translated, err := json.Marshal(payload); err == nil {
+			data = translated
+		}
+	}
+	w.ResponseWriter.Write(data)
+}
+
+// translateMessages translates the "message" and "error.message" fields of an API response and
+// reports whether any were found
+func translateMessages(payload map[string]interface{}, lang string) bool {
+	found := false
+	if msg, ok := payload["message"].(string); ok {
+		payload["message"] = i18n.Translate(lang, msg)
+		found = true
+	}
+	if apiErr, ok := payload["error"].(map[string]interface{}); ok {
+		if msg, ok := apiErr["message"].(string); ok {
+			apiErr["message"] = i18n.Translate(lang, msg)
+			found = true
+		}
+	}
+	return found
+}
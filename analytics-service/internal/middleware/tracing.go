@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"analytics-service/internal/tracing"
+)
+
+// Tracing runs each request in a server span, continuing the trace of the caller's traceparent
+// header. The span is tagged with the building and device the request names in its path or query.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracing.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+			),
+		)
+		defer span.End()
+
+		buildingID := c.Param("buildingId")
+		if buildingID == "" {
+			buildingID = c.Query("buildingId")
+		}
+		deviceID := c.Param("deviceId")
+		if deviceID == "" {
+			deviceID = c.Query("deviceId")
+		}
+		span.SetAttributes(tracing.EntityAttributes(buildingID, deviceID)...)
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -26,6 +26,10 @@ const (
 	AnomalyStatusFalsePositive AnomalyStatus = "FALSE_POSITIVE"
 )
 
+// AnomalyTypeForecastDeviation marks consumption that stayed above the latest forecast's upper
+// bound for several consecutive intervals
+const AnomalyTypeForecastDeviation = "FORECAST_DEVIATION"
+
 // Anomaly represents a detected anomaly
 type Anomaly struct {
 	ID          primitive.ObjectID          `bson:"_id,omitempty" json:"id"`
@@ -40,6 +44,8 @@ type Anomaly struct {
 	AcknowledgedAt *time.Time               `bson:"acknowledged_at,omitempty" json:"acknowledgedAt,omitempty"`
 	AcknowledgedBy string                    `bson:"acknowledged_by,omitempty" json:"acknowledgedBy,omitempty"`
 	ResolvedAt  *time.Time                  `bson:"resolved_at,omitempty" json:"resolvedAt,omitempty"`
+	RootCauses  []RootCause                 `bson:"root_causes,omitempty" json:"rootCauses,omitempty"` // Ranked, most likely first
+	RootCausesAnalyzedAt *time.Time         `bson:"root_causes_analyzed_at,omitempty" json:"rootCausesAnalyzedAt,omitempty"`
 	CreatedAt   time.Time                   `bson:"created_at" json:"createdAt"`
 	UpdatedAt   time.Time                   `bson:"updated_at" json:"updatedAt"`
 }
@@ -58,6 +64,8 @@ type AnomalyResponse struct {
 	AcknowledgedAt *time.Time            `json:"acknowledgedAt,omitempty"`
 	AcknowledgedBy string                `json:"acknowledgedBy,omitempty"`
 	ResolvedAt    *time.Time             `json:"resolvedAt,omitempty"`
+	RootCauses    []RootCause            `json:"rootCauses"`
+	RootCausesAnalyzedAt *time.Time      `json:"rootCausesAnalyzedAt,omitempty"`
 	CreatedAt     time.Time              `json:"createdAt"`
 }
 
@@ -76,6 +84,8 @@ func (a *Anomaly) ToResponse() *AnomalyResponse {
 		AcknowledgedAt: a.AcknowledgedAt,
 		AcknowledgedBy: a.AcknowledgedBy,
 		ResolvedAt:    a.ResolvedAt,
+		RootCauses:    a.RootCauses,
+		RootCausesAnalyzedAt: a.RootCausesAnalyzedAt,
 		CreatedAt:     a.CreatedAt,
 	}
 }
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BudgetScope identifies whether a budget limits a whole building or a single device
+type BudgetScope string
+
+const (
+	BudgetScopeBuilding BudgetScope = "BUILDING"
+	BudgetScopeDevice   BudgetScope = "DEVICE"
+)
+
+// BudgetState summarizes month-to-date consumption against a budget
+type BudgetState string
+
+const (
+	BudgetStateOnTrack  BudgetState = "ON_TRACK"
+	BudgetStateAtRisk   BudgetState = "AT_RISK"
+	BudgetStateWarning  BudgetState = "WARNING"
+	BudgetStateExceeded BudgetState = "EXCEEDED"
+)
+
+// ProjectionSource identifies how consumption to month-end was projected
+type ProjectionSource string
+
+const (
+	ProjectionSourceForecast ProjectionSource = "FORECAST"
+	ProjectionSourceRunRate  ProjectionSource = "RUN_RATE"
+)
+
+// EnergyBudget is a monthly energy limit for a building, or for one of its devices
+type EnergyBudget struct {
+	ID                   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name                 string             `bson:"name" json:"name"`
+	Scope                BudgetScope        `bson:"scope" json:"scope"`
+	BuildingID           string             `bson:"building_id" json:"buildingId"`
+	DeviceID             string             `bson:"device_id,omitempty" json:"deviceId,omitempty"`
+	MonthlyLimitKWh      float64            `bson:"monthly_limit_kwh" json:"monthlyLimitKwh"`
+	NotifyUserIDs        []string           `bson:"notify_user_ids,omitempty" json:"notifyUserIds,omitempty"`
+	AutoGenerateScenario bool               `bson:"auto_generate_scenario" json:"autoGenerateScenario"`
+	Tracking             BudgetTracking     `bson:"tracking" json:"tracking"`
+	CreatedBy            string             `bson:"created_by" json:"createdBy"`
+	CreatedAt            time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt            time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// BudgetTracking is the month-to-date state kept by the budget tracking worker.
+// ThresholdsCrossed lists the thresholds already notified for Month.
+type BudgetTracking struct {
+	Month             string     `bson:"month,omitempty" json:"month,omitempty"`
+	ConsumedKWh       float64    `bson:"consumed_kwh" json:"consumedKwh"`
+	ThresholdsCrossed []int      `bson:"thresholds_crossed,omitempty" json:"thresholdsCrossed,omitempty"`
+	EvaluatedAt       *time.Time `bson:"evaluated_at,omitempty" json:"evaluatedAt,omitempty"`
+}
+
+// EnergyBudgetResponse represents an energy budget in API responses
+type EnergyBudgetResponse struct {
+	ID                   string         `json:"id"`
+	Name                 string         `json:"name"`
+	Scope                string         `json:"scope"`
+	BuildingID           string         `json:"buildingId"`
+	DeviceID             string         `json:"deviceId,omitempty"`
+	MonthlyLimitKWh      float64        `json:"monthlyLimitKwh"`
+	NotifyUserIDs        []string       `json:"notifyUserIds,omitempty"`
+	AutoGenerateScenario bool           `json:"autoGenerateScenario"`
+	Tracking             BudgetTracking `json:"tracking"`
+	CreatedBy            string         `json:"createdBy"`
+	CreatedAt            time.Time      `json:"createdAt"`
+	UpdatedAt            time.Time      `json:"updatedAt"`
+}
+
+// ToResponse converts an EnergyBudget to EnergyBudgetResponse
+func (b *EnergyBudget) ToResponse() *EnergyBudgetResponse {
+	return &EnergyBudgetResponse{
+		ID:                   b.ID.Hex(),
+		Name:                 b.Name,
+		Scope:                string(b.Scope),
+		BuildingID:           b.BuildingID,
+		DeviceID:             b.DeviceID,
+		MonthlyLimitKWh:      b.MonthlyLimitKWh,
+		NotifyUserIDs:        b.NotifyUserIDs,
+		AutoGenerateScenario: b.AutoGenerateScenario,
+		Tracking:             b.Tracking,
+		CreatedBy:            b.CreatedBy,
+		CreatedAt:            b.CreatedAt,
+		UpdatedAt:            b.UpdatedAt,
+	}
+}
+
+// EnergyBudgetRequest represents a request to create or replace an energy budget.
+// Budgets with a device ID limit that device; others limit the whole building.
+type EnergyBudgetRequest struct {
+	Name                 string   `json:"name"`
+	BuildingID           string   `json:"buildingId" binding:"required"`
+	DeviceID             string   `json:"deviceId"`
+	MonthlyLimitKWh      float64  `json:"monthlyLimitKwh" binding:"required,gt=0"`
+	NotifyUserIDs        []string `json:"notifyUserIds"`
+	AutoGenerateScenario bool     `json:"autoGenerateScenario"`
+}
+
+// ListBudgetsRequest represents query parameters for listing budgets
+type ListBudgetsRequest struct {
+	BuildingID string `form:"buildingId"`
+	DeviceID   string `form:"deviceId"`
+	Page       int    `form:"page"`
+	Limit      int    `form:"limit"`
+}
+
+// EnergyBudgetStatus reports month-to-date consumption of a budget and its projection to month-end
+type EnergyBudgetStatus struct {
+	BudgetID          string           `json:"budgetId"`
+	Name              string           `json:"name"`
+	Scope             string           `json:"scope"`
+	BuildingID        string           `json:"buildingId"`
+	DeviceID          string           `json:"deviceId,omitempty"`
+	Month             string           `json:"month"`
+	LimitKWh          float64          `json:"limitKwh"`
+	ConsumedKWh       float64          `json:"consumedKwh"`
+	PercentConsumed   float64          `json:"percentConsumed"`
+	ProjectedKWh      float64          `json:"projectedKwh"`
+	ProjectedPercent  float64          `json:"projectedPercent"`
+	ProjectionSource  ProjectionSource `json:"projectionSource"`
+	ForecastID        string           `json:"forecastId,omitempty"`
+	State             BudgetState      `json:"state"`
+	ThresholdsCrossed []int            `json:"thresholdsCrossed"`
+	CalculatedAt      time.Time        `json:"calculatedAt"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+)
+
+// BuildingProfile holds the size of a building used to normalize its consumption
+type BuildingProfile struct {
+	BuildingID string  `json:"buildingId" binding:"required"`
+	FloorArea  float64 `json:"floorArea" binding:"required,gt=0"`   // m²
+	Occupants  int     `json:"occupants" binding:"omitempty,min=0"` // 0 leaves out per-occupant KPIs
+}
+
+// BuildingComparisonRequest asks for the normalized KPIs of a cohort of buildings over a period
+type BuildingComparisonRequest struct {
+	Buildings []BuildingProfile `json:"buildings" binding:"required,min=2,max=50,dive"`
+	From      time.Time         `json:"from" binding:"required"`
+	To        time.Time         `json:"to" binding:"required,gtfield=From,maxspan=From 8784h"`
+}
+
+// BuildingComparison ranks a cohort of buildings by consumption per m², highest first
+type BuildingComparison struct {
+	From      time.Time                `json:"from"`
+	To        time.Time                `json:"to"`
+	Days      int                      `json:"days"`
+	Cohort    CohortSummary            `json:"cohort"`
+	Buildings []BuildingComparisonItem `json:"buildings"`
+	UpdatedAt time.Time                `json:"updatedAt"`
+}
+
+// CohortSummary holds the normalized KPIs of the whole cohort
+type CohortSummary struct {
+	BuildingCount          int      `json:"buildingCount"`
+	TotalConsumption       float64  `json:"totalConsumption"`
+	TotalFloorArea         float64  `json:"totalFloorArea"`
+	ConsumptionPerM2       float64  `json:"consumptionPerM2"`
+	ConsumptionPerOccupant *float64 `json:"consumptionPerOccupant"` // Nil when no building has occupants
+	MedianPerM2            float64  `json:"medianPerM2"`
+}
+
+// BuildingComparisonItem is a building's row in a cohort comparison. Peer values are the
+// combined KPIs of the other buildings and deltas are relative to them.
+type BuildingComparisonItem struct {
+	Rank                       int      `json:"rank"`
+	BuildingID                 string   `json:"buildingId"`
+	FloorArea                  float64  `json:"floorArea"`
+	Occupants                  int      `json:"occupants"`
+	DaysWithData               int      `json:"daysWithData"`
+	TotalConsumption           float64  `json:"totalConsumption"`
+	ConsumptionPerM2           float64  `json:"consumptionPerM2"`
+	ConsumptionPerOccupant     *float64 `json:"consumptionPerOccupant"` // Nil without occupants
+	PeerConsumptionPerM2       float64  `json:"peerConsumptionPerM2"`
+	DeltaPerM2Percent          *float64 `json:"deltaPerM2Percent"` // Nil when peers have no consumption
+	PeerConsumptionPerOccupant *float64 `json:"peerConsumptionPerOccupant"`
+	DeltaPerOccupantPercent    *float64 `json:"deltaPerOccupantPercent"`
+	// Significance tests the building's daily consumption per m² against its peers'
+	Significance ComparisonSignificance `json:"significance"`
+}
+
+// ComparisonSignificance is the result of a Welch's t-test between the daily consumption per m²
+// of a building and of its peers. PValue is nil with fewer than two days of data on either side.
+type ComparisonSignificance struct {
+	TStatistic  float64  `json:"tStatistic"`
+	PValue      *float64 `json:"pValue"`
+	Significant bool     `json:"significant"` // PValue below 0.05
+}
+
+// BuildingDailyConsumption holds a building's consumption on one day produced by aggregation
+type BuildingDailyConsumption struct {
+	BuildingID string    `bson:"building_id"`
+	Day        time.Time `bson:"day"`
+	MainMeter  float64   `bson:"main_meter"`
+	Metered    float64   `bson:"metered"`
+}
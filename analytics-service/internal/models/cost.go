@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+)
+
+// CostQuery represents the query parameters of a building's energy cost
+type CostQuery struct {
+	BuildingID string `form:"buildingId" binding:"required"`
+	Period     string `form:"period" binding:"omitempty,oneof=DAILY WEEKLY MONTHLY"`
+	Region     string `form:"region"` // Tariff region, "default" when omitted
+}
+
+// Tariff is a tariff as resolved by the Forecast service
+type Tariff struct {
+	Region         string         `json:"region"`
+	BaseRate       float64        `json:"baseRate,omitempty"`
+	CurrentRate    float64        `json:"currentRate"`
+	OffPeakRate    float64        `json:"offPeakRate"`
+	Currency       string         `json:"currency"`
+	TimeOfUseRates []TariffRate   `json:"timeOfUseRates,omitempty"`
+	DemandCharges  []DemandCharge `json:"demandCharges,omitempty"`
+}
+
+// TariffRate is a time-of-use energy rate
+type TariffRate struct {
+	Name       string  `json:"name"`
+	RatePerKWh float64 `json:"ratePerKWh"`
+	StartHour  int     `json:"startHour"`
+	EndHour    int     `json:"endHour"`
+	DaysOfWeek []int   `json:"daysOfWeek,omitempty"` // 0 = Sunday, empty = every day
+}
+
+// DemandCharge is a charge on the peak demand within a window of hours
+type DemandCharge struct {
+	Name      string  `json:"name"`
+	RatePerKW float64 `json:"ratePerKW"`
+	StartHour int     `json:"startHour"`
+	EndHour   int     `json:"endHour"`
+}
+
+// LoadInterval is the energy used over one interval of a load profile
+type LoadInterval struct {
+	Start     time.Time `json:"start" binding:"required"`
+	Minutes   int       `json:"minutes" binding:"omitempty,min=1"` // Defaults to 60
+	EnergyKWh float64   `json:"energyKWh"`
+}
+
+// CostBreakdown is the cost of a load profile under a tariff, split into energy charges per
+// time-of-use rate and demand charges
+type CostBreakdown struct {
+	BuildingID    string             `json:"buildingId,omitempty"`
+	Period        string             `json:"period,omitempty"`
+	From          time.Time          `json:"from"`
+	To            time.Time          `json:"to"`
+	Region        string             `json:"region"`
+	Currency      string             `json:"currency"`
+	EnergyKWh     float64            `json:"energyKWh"`
+	EnergyCost    float64            `json:"energyCost"`
+	DemandCost    float64            `json:"demandCost"`
+	TotalCost     float64            `json:"totalCost"`
+	AverageRate   float64            `json:"averageRate"` // Total cost per kWh
+	Rates         []RateCost         `json:"rates"`
+	DemandCharges []DemandChargeCost `json:"demandCharges"`
+}
+
+// RateCost is the energy billed at one time-of-use rate
+type RateCost struct {
+	Name       string  `json:"name"`
+	RatePerKWh float64 `json:"ratePerKWh"`
+	EnergyKWh  float64 `json:"energyKWh"`
+	Cost       float64 `json:"cost"`
+}
+
+// DemandChargeCost is a demand charge billed on the peak demand within its window.
+// Share is the part of the monthly charge billed for the period.
+type DemandChargeCost struct {
+	Name      string     `json:"name"`
+	RatePerKW float64    `json:"ratePerKW"`
+	PeakKW    float64    `json:"peakKW"`
+	PeakAt    *time.Time `json:"peakAt,omitempty"`
+	Share     float64    `json:"share"`
+	Cost      float64    `json:"cost"`
+}
+
+// CostComparisonRequest asks for the cost of a baseline load profile and an optimized one
+type CostComparisonRequest struct {
+	Tariff    *Tariff        `json:"tariff" binding:"required"`
+	Baseline  []LoadInterval `json:"baseline" binding:"required,min=1,dive"`
+	Optimized []LoadInterval `json:"optimized" binding:"required,min=1,dive"`
+}
+
+// CostComparison is the cost difference between a baseline and an optimized load profile
+type CostComparison struct {
+	Baseline      *CostBreakdown `json:"baseline"`
+	Optimized     *CostBreakdown `json:"optimized"`
+	Currency      string         `json:"currency"`
+	EnergySavings float64        `json:"energySavings"`
+	DemandSavings float64        `json:"demandSavings"`
+	TotalSavings  float64        `json:"totalSavings"`
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Anomaly detectors whose configuration can be tuned
+const (
+	DetectorTemperatureRange  = "TEMPERATURE_RANGE"
+	DetectorConsumptionSpike  = "CONSUMPTION_SPIKE"
+	DetectorForecastDeviation = "FORECAST_DEVIATION"
+)
+
+// DetectorAnomalyTypes maps each detector to the type of the anomalies it raises
+var DetectorAnomalyTypes = map[string]string{
+	DetectorTemperatureRange:  "TEMPERATURE_OUT_OF_RANGE",
+	DetectorConsumptionSpike:  "CONSUMPTION_SPIKE",
+	DetectorForecastDeviation: AnomalyTypeForecastDeviation,
+}
+
+// DetectorMode is how a detector configuration takes part in detection
+type DetectorMode string
+
+const (
+	// DetectorModeLive configurations raise anomalies; each detector has at most one
+	DetectorModeLive DetectorMode = "LIVE"
+	// DetectorModeShadow configurations run alongside the live one and only record the anomalies
+	// they would have raised
+	DetectorModeShadow DetectorMode = "SHADOW"
+	// DetectorModeRetired configurations were replaced by a promoted one and no longer run
+	DetectorModeRetired DetectorMode = "RETIRED"
+)
+
+// DetectorParams are the thresholds of a detector. Each detector uses its own: the temperature
+// range, the consumption per reading above which a spike is raised, or the number of consecutive
+// forecast intervals above the upper bound.
+type DetectorParams struct {
+	MinTemperature       *float64 `bson:"min_temperature,omitempty" json:"minTemperature,omitempty"`             // °C
+	MaxTemperature       *float64 `bson:"max_temperature,omitempty" json:"maxTemperature,omitempty"`             // °C
+	ConsumptionThreshold *float64 `bson:"consumption_threshold,omitempty" json:"consumptionThreshold,omitempty"` // kWh per reading
+	MinIntervals         int      `bson:"min_intervals,omitempty" json:"minIntervals,omitempty"`
+}
+
+// DetectorConfig is a configuration of an anomaly detector. New and modified configurations start
+// in shadow mode and replace the live configuration once promoted. A detector without a stored
+// live configuration runs with the built-in defaults.
+type DetectorConfig struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ConfigID   string             `bson:"config_id" json:"configId"`
+	Detector   string             `bson:"detector" json:"detector"`
+	Name       string             `bson:"name" json:"name"`
+	Mode       DetectorMode       `bson:"mode" json:"mode"`
+	Params     DetectorParams     `bson:"params" json:"params"`
+	CreatedBy  string             `bson:"created_by,omitempty" json:"createdBy,omitempty"`
+	PromotedBy string             `bson:"promoted_by,omitempty" json:"promotedBy,omitempty"`
+	PromotedAt *time.Time         `bson:"promoted_at,omitempty" json:"promotedAt,omitempty"`
+	RetiredAt  *time.Time         `bson:"retired_at,omitempty" json:"retiredAt,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// DetectorConfigRequest represents a request to create a shadow detector configuration.
+// Thresholds left out are taken from the detector's live configuration.
+type DetectorConfigRequest struct {
+	Detector string         `json:"detector" binding:"required,oneof=TEMPERATURE_RANGE CONSUMPTION_SPIKE FORECAST_DEVIATION"`
+	Name     string         `json:"name" binding:"required,max=100"`
+	Params   DetectorParams `json:"params"`
+}
+
+// UpdateDetectorConfigRequest represents a request to change a shadow configuration's thresholds
+type UpdateDetectorConfigRequest struct {
+	Name   string         `json:"name" binding:"required,max=100"`
+	Params DetectorParams `json:"params"`
+}
+
+// ListDetectorConfigsRequest represents query parameters for listing detector configurations
+type ListDetectorConfigsRequest struct {
+	Detector string `form:"detector" binding:"omitempty,oneof=TEMPERATURE_RANGE CONSUMPTION_SPIKE FORECAST_DEVIATION"`
+	Mode     string `form:"mode" binding:"omitempty,oneof=LIVE SHADOW RETIRED"`
+}
+
+// ShadowAnomaly is an anomaly a shadow detector configuration would have raised. Nobody is
+// notified about it.
+type ShadowAnomaly struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ConfigID   string                 `bson:"config_id" json:"configId"`
+	Detector   string                 `bson:"detector" json:"detector"`
+	DeviceID   string                 `bson:"device_id,omitempty" json:"deviceId,omitempty"`
+	BuildingID string                 `bson:"building_id" json:"buildingId"`
+	Type       string                 `bson:"type" json:"type"`
+	Severity   AnomalySeverity        `bson:"severity" json:"severity"`
+	Details    map[string]interface{} `bson:"details" json:"details"`
+	DetectedAt time.Time              `bson:"detected_at" json:"detectedAt"`
+	CreatedAt  time.Time              `bson:"created_at" json:"createdAt"`
+}
+
+// DetectorComparisonRequest represents query parameters for comparing a shadow configuration with
+// the live detector. Alerts and anomalies of the same building and device match when they were
+// detected at most ToleranceMinutes apart.
+type DetectorComparisonRequest struct {
+	From             time.Time `form:"from"`
+	To               time.Time `form:"to"`
+	ToleranceMinutes int       `form:"toleranceMinutes" binding:"omitempty,min=1,max=1440"`
+}
+
+// DetectionQuality scores a detector configuration's alerts against the live anomalies operators
+// reviewed: acknowledged and resolved anomalies are confirmed, those marked false positive are not.
+// Precision leaves out alerts that only match unreviewed anomalies; Novel alerts, which match no
+// live anomaly at all, count against it.
+type DetectionQuality struct {
+	Alerts         int      `json:"alerts"`
+	TruePositives  int      `json:"truePositives"`
+	FalsePositives int      `json:"falsePositives"`
+	Novel          int      `json:"novel"`
+	Unreviewed     int      `json:"unreviewed"`
+	ConfirmedFound int      `json:"confirmedFound"` // Confirmed anomalies matched by at least one alert
+	Precision      *float64 `json:"precision"`      // Nil without reviewed alerts
+	Recall         *float64 `json:"recall"`         // Nil without confirmed anomalies
+}
+
+// DetectorComparison compares a shadow configuration with the live detector over a period
+type DetectorComparison struct {
+	Config             *DetectorConfig  `json:"config"`
+	LiveConfig         *DetectorConfig  `json:"liveConfig"`
+	AnomalyType        string           `json:"anomalyType"`
+	From               time.Time        `json:"from"`
+	To                 time.Time        `json:"to"`
+	ToleranceMinutes   int              `json:"toleranceMinutes"`
+	ConfirmedAnomalies int              `json:"confirmedAnomalies"`
+	Shadow             DetectionQuality `json:"shadow"`
+	Live               DetectionQuality `json:"live"`
+}
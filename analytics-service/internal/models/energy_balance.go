@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Energy flow metrics of time-series aggregates of sites with on-site generation and storage, in kWh.
+// They are reported next to consumption, which stays the gross consumption of the site.
+const (
+	MetricGeneration       = "generation"
+	MetricGridImport       = "gridImport"
+	MetricGridExport       = "gridExport"
+	MetricBatteryCharge    = "batteryCharge"
+	MetricBatteryDischarge = "batteryDischarge"
+)
+
+// EnergyBalanceQuery represents the query parameters of a building's energy balance
+type EnergyBalanceQuery struct {
+	From time.Time `form:"from"`
+	To   time.Time `form:"to" binding:"omitempty,gtfield=From"`
+}
+
+// EnergyFlows holds a building's energy flows totalled from daily aggregates, in kWh.
+// GridRecords counts the aggregates that metered the grid connection.
+type EnergyFlows struct {
+	Consumption      float64 `bson:"consumption" json:"consumption"`
+	Generation       float64 `bson:"generation" json:"generation"`
+	GridImport       float64 `bson:"grid_import" json:"gridImport"`
+	GridExport       float64 `bson:"grid_export" json:"gridExport"`
+	BatteryCharge    float64 `bson:"battery_charge" json:"batteryCharge"`
+	BatteryDischarge float64 `bson:"battery_discharge" json:"batteryDischarge"`
+	GridRecords      int64   `bson:"grid_records" json:"-"`
+}
+
+// HasOnSiteEnergy reports whether the building generated, stored or metered grid energy
+func (f *EnergyFlows) HasOnSiteEnergy() bool {
+	return f.Generation > 0 || f.BatteryCharge > 0 || f.BatteryDischarge > 0 || f.GridRecords > 0
+}
+
+// EnergyBalance relates a building's consumption to its generation, grid exchange and storage.
+// NetConsumption is the grid import minus the export where the grid connection is metered, and
+// the consumption minus the generation otherwise. Ratios are percentages and are omitted when
+// the flows they relate to were not reported.
+type EnergyBalance struct {
+	BuildingID           string      `json:"buildingId"`
+	From                 time.Time   `json:"from"`
+	To                   time.Time   `json:"to"`
+	Flows                EnergyFlows `json:"flows"`
+	GridMetered          bool        `json:"gridMetered"`
+	NetConsumption       float64     `json:"netConsumption"`
+	SelfConsumptionRatio *float64    `json:"selfConsumptionRatio,omitempty"` // Share of the generation used on site
+	SelfSufficiencyRatio *float64    `json:"selfSufficiencyRatio,omitempty"` // Share of the consumption not drawn from the grid
+	BatteryEfficiency    *float64    `json:"batteryEfficiency,omitempty"`    // Energy discharged per energy charged
+}
+
+// Metrics returns the balance as KPI metrics
+func (b *EnergyBalance) Metrics() map[string]interface{} {
+	metrics := map[string]interface{}{
+		"netConsumption":       b.NetConsumption,
+		MetricGeneration:       b.Flows.Generation,
+		MetricGridImport:       b.Flows.GridImport,
+		MetricGridExport:       b.Flows.GridExport,
+		MetricBatteryCharge:    b.Flows.BatteryCharge,
+		MetricBatteryDischarge: b.Flows.BatteryDischarge,
+	}
+	if b.SelfConsumptionRatio != nil {
+		metrics["selfConsumptionRatio"] = *b.SelfConsumptionRatio
+	}
+	if b.SelfSufficiencyRatio != nil {
+		metrics["selfSufficiencyRatio"] = *b.SelfSufficiencyRatio
+	}
+	if b.BatteryEfficiency != nil {
+		metrics["batteryEfficiency"] = *b.BatteryEfficiency
+	}
+	return metrics
+}
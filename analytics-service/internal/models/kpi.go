@@ -12,7 +12,8 @@ type KPI struct {
 	BuildingID   string                 `bson:"building_id,omitempty" json:"buildingId,omitempty"` // Empty for system-wide KPIs
 	CalculatedAt time.Time              `bson:"calculated_at" json:"calculatedAt"`
 	Metrics      map[string]interface{} `bson:"metrics" json:"metrics"`
-	Period       string                 `bson:"period" json:"period"` // "DAILY", "WEEKLY", "MONTHLY"
+	Period       string                 `bson:"period" json:"period"`                       // "DAILY", "WEEKLY", "MONTHLY"
+	Targets      []KPITargetStatus      `bson:"targets,omitempty" json:"targets,omitempty"` // custom KPIs measured against their targets
 	CreatedAt    time.Time              `bson:"created_at" json:"createdAt"`
 	UpdatedAt    time.Time              `bson:"updated_at" json:"updatedAt"`
 }
@@ -24,6 +25,7 @@ type KPIResponse struct {
 	CalculatedAt time.Time              `json:"calculatedAt"`
 	Metrics      map[string]interface{} `json:"metrics"`
 	Period       string                 `json:"period"`
+	Targets      []KPITargetStatus      `json:"targets,omitempty"`
 	CreatedAt    time.Time              `json:"createdAt"`
 }
 
@@ -35,6 +37,7 @@ func (k *KPI) ToResponse() *KPIResponse {
 		CalculatedAt: k.CalculatedAt,
 		Metrics:      k.Metrics,
 		Period:       k.Period,
+		Targets:      k.Targets,
 		CreatedAt:    k.CreatedAt,
 	}
 }
@@ -60,5 +63,9 @@ type BuildingDashboard struct {
 	// Integration: ForecastSummary contains prediction data from Forecast service
 	ForecastSummary map[string]interface{} `json:"forecastSummary,omitempty"`
 	RecentTelemetry []TimeSeriesResponse   `json:"recentTelemetry"`
-	UpdatedAt       time.Time              `json:"updatedAt"`
+	// RecentOptimizations lists optimization scenarios executed by the IoT service
+	RecentOptimizations []OptimizationExecutionResponse `json:"recentOptimizations"`
+	// SavingsGoals are cards of the building's savings goals for the current year
+	SavingsGoals []SavingsGoalCard `json:"savingsGoals"`
+	UpdatedAt    time.Time         `json:"updatedAt"`
 }
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KPITargetComparison states which side of a KPI target is on target
+type KPITargetComparison string
+
+const (
+	KPITargetAtMost  KPITargetComparison = "AT_MOST"  // the KPI meets its target at or below the value
+	KPITargetAtLeast KPITargetComparison = "AT_LEAST" // the KPI meets its target at or above the value
+)
+
+// KPITarget is the value a KPI is expected to stay on one side of
+type KPITarget struct {
+	Comparison KPITargetComparison `bson:"comparison" json:"comparison" binding:"required,oneof=AT_MOST AT_LEAST"`
+	Value      float64             `bson:"value" json:"value"`
+}
+
+// Met reports whether a KPI value meets the target
+func (t *KPITarget) Met(value float64) bool {
+	if t.Comparison == KPITargetAtLeast {
+		return value >= t.Value
+	}
+	return value <= t.Value
+}
+
+// KPIDefinition is a custom KPI an admin defines as a formula over the variables of the KPI
+// calculation. Its value is stored under Key in the KPI metrics of every building it applies to,
+// next to the built-in metrics. Definitions of an organization apply to its buildings only.
+type KPIDefinition struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID       string             `bson:"org_id,omitempty" json:"orgId,omitempty"`
+	Key         string             `bson:"key" json:"key"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Expression  string             `bson:"expression" json:"expression"`
+	Unit        string             `bson:"unit" json:"unit"`           // inferred from the variables; empty for ratios
+	Variables   []string           `bson:"variables" json:"variables"` // variables the expression reads
+	// BuildingIDs limits the KPI to some buildings; empty applies it to every building
+	BuildingIDs   []string   `bson:"building_ids,omitempty" json:"buildingIds,omitempty"`
+	Target        *KPITarget `bson:"target,omitempty" json:"target,omitempty"`
+	NotifyUserIDs []string   `bson:"notify_user_ids,omitempty" json:"notifyUserIds,omitempty"`
+	Enabled       bool       `bson:"enabled" json:"enabled"`
+	CreatedBy     string     `bson:"created_by" json:"createdBy"`
+	CreatedAt     time.Time  `bson:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time  `bson:"updated_at" json:"updatedAt"`
+}
+
+// AppliesTo reports whether the KPI is evaluated for a building
+func (d *KPIDefinition) AppliesTo(buildingID string) bool {
+	if len(d.BuildingIDs) == 0 {
+		return true
+	}
+	for _, id := range d.BuildingIDs {
+		if id == buildingID {
+			return true
+		}
+	}
+	return false
+}
+
+// KPIDefinitionRequest represents a request to create or replace a custom KPI. A unit, when
+// given, must match the unit inferred from the expression.
+type KPIDefinitionRequest struct {
+	Key           string     `json:"key" binding:"required,max=64"`
+	Name          string     `json:"name" binding:"required,max=100"`
+	Description   string     `json:"description" binding:"max=500"`
+	Expression    string     `json:"expression" binding:"required,max=500"`
+	Unit          *string    `json:"unit"`
+	BuildingIDs   []string   `json:"buildingIds"`
+	Target        *KPITarget `json:"target"`
+	NotifyUserIDs []string   `json:"notifyUserIds"`
+	Enabled       *bool      `json:"enabled"` // defaults to true
+}
+
+// ListKPIDefinitionsRequest represents query parameters for listing custom KPIs
+type ListKPIDefinitionsRequest struct {
+	BuildingID string `form:"buildingId"`
+	Page       int    `form:"page"`
+	Limit      int    `form:"limit"`
+}
+
+// KPIFormulaRequest represents a formula to validate without saving it
+type KPIFormulaRequest struct {
+	Expression string `json:"expression" binding:"required,max=500"`
+}
+
+// KPIFormulaValidation reports whether a formula is valid, the unit of its result and the
+// variables it reads
+type KPIFormulaValidation struct {
+	Expression string   `json:"expression"`
+	Valid      bool     `json:"valid"`
+	Unit       string   `json:"unit"`
+	Variables  []string `json:"variables"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// KPIVariable is a value formulas can read, with its unit
+type KPIVariable struct {
+	Name        string `json:"name"`
+	Unit        string `json:"unit"`
+	Description string `json:"description"`
+	// Weekly reports whether the variable has weekly history, which trends of the KPI need
+	Weekly bool `json:"weekly"`
+}
+
+// KPITargetStatus is a KPI value measured against its target when the KPIs were calculated
+type KPITargetStatus struct {
+	Metric     string              `bson:"metric" json:"metric"`
+	Name       string              `bson:"name" json:"name"`
+	Value      float64             `bson:"value" json:"value"`
+	Unit       string              `bson:"unit" json:"unit"`
+	Comparison KPITargetComparison `bson:"comparison" json:"comparison"`
+	Target     float64             `bson:"target" json:"target"`
+	Met        bool                `bson:"met" json:"met"`
+}
+
+// BuildingAttributes holds the size of a building that KPI formulas read
+type BuildingAttributes struct {
+	BuildingID string    `bson:"building_id" json:"buildingId"`
+	FloorArea  float64   `bson:"floor_area" json:"floorArea"` // m²
+	Occupants  int       `bson:"occupants" json:"occupants"`  // 0 when unknown
+	UpdatedBy  string    `bson:"updated_by" json:"updatedBy"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+// BuildingAttributesRequest represents a request to set a building's attributes
+type BuildingAttributesRequest struct {
+	FloorArea float64 `json:"floorArea" binding:"required,gt=0"`   // m²
+	Occupants int     `json:"occupants" binding:"omitempty,min=0"` // 0 when unknown
+}
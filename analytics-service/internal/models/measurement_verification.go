@@ -0,0 +1,157 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MVOption identifies the IPMVP measurement and verification option used to verify savings
+type MVOption string
+
+const (
+	// MVOptionA verifies savings of the retrofitted devices from a measured key parameter,
+	// their hourly demand, and stipulated operating hours
+	MVOptionA MVOption = "A"
+	// MVOptionC verifies whole-building savings against a baseline regression on degree days
+	MVOptionC MVOption = "C"
+)
+
+// MVReportRequest represents a request to verify the savings of an executed optimization scenario.
+// Baseline and reporting periods default to 90 and 30 days for option C and 14 days each for
+// option A. OperatingHours is option A's stipulated hours over the reporting period and defaults
+// to every hour of it.
+type MVReportRequest struct {
+	ScenarioID      string   `json:"scenarioId" binding:"required"`
+	Option          MVOption `json:"option" binding:"required,oneof=A C"`
+	BaselineDays    int      `json:"baselineDays" binding:"omitempty,min=7,max=365"`
+	ReportingDays   int      `json:"reportingDays" binding:"omitempty,min=1,max=365"`
+	OperatingHours  float64  `json:"operatingHours" binding:"omitempty,gt=0"`
+	ConfidenceLevel float64  `json:"confidenceLevel" binding:"omitempty,gte=50,lt=100"`
+}
+
+// ListMVReportsRequest represents query parameters for listing M&V reports
+type ListMVReportsRequest struct {
+	BuildingID string `form:"buildingId"`
+	ScenarioID string `form:"scenarioId"`
+	Page       int    `form:"page"`
+	Limit      int    `form:"limit"`
+}
+
+// MVReport is an auditable measurement and verification of the savings of an executed
+// optimization scenario. It keeps the periods, data and model the savings were calculated from.
+type MVReport struct {
+	ID                   primitive.ObjectID `bson:"_id,omitempty"`
+	ScenarioID           string             `bson:"scenario_id"`
+	ScenarioType         string             `bson:"scenario_type,omitempty"`
+	BuildingID           string             `bson:"building_id"`
+	Option               MVOption           `bson:"option"`
+	ExecutionStartedAt   time.Time          `bson:"execution_started_at"`
+	ExecutionCompletedAt time.Time          `bson:"execution_completed_at"`
+	BaselineFrom         time.Time          `bson:"baseline_from"`
+	BaselineTo           time.Time          `bson:"baseline_to"`
+	ReportingFrom        time.Time          `bson:"reporting_from"`
+	ReportingTo          time.Time          `bson:"reporting_to"`
+	ConfidenceLevel      float64            `bson:"confidence_level"`
+	Model                *MVRegressionModel `bson:"model,omitempty"`
+	KeyParameter         *MVKeyParameter    `bson:"key_parameter,omitempty"`
+	Savings              MVSavings          `bson:"savings"`
+	Warnings             []string           `bson:"warnings,omitempty"`
+	CreatedBy            string             `bson:"created_by"`
+	CreatedAt            time.Time          `bson:"created_at"`
+}
+
+// MVRegressionModel is option C's baseline model: daily consumption regressed on heating and
+// cooling degree days over the baseline period. Variables lists the degree day terms with
+// enough variation in the baseline to be fitted; a coefficient is zero when its term is left out.
+type MVRegressionModel struct {
+	Variables          []string `bson:"variables" json:"variables"`
+	Intercept          float64  `bson:"intercept" json:"intercept"`
+	HeatingCoefficient float64  `bson:"heating_coefficient" json:"heatingCoefficient"`
+	CoolingCoefficient float64  `bson:"cooling_coefficient" json:"coolingCoefficient"`
+	BaseTemperature    float64  `bson:"base_temperature" json:"baseTemperature"`
+	BaselineDays       int      `bson:"baseline_days" json:"baselineDays"`
+	ReportingDays      int      `bson:"reporting_days" json:"reportingDays"`
+	ExcludedDays       int      `bson:"excluded_days" json:"excludedDays"`
+	RSquared           float64  `bson:"r_squared" json:"rSquared"`
+	CVRMSEPercent      float64  `bson:"cv_rmse_percent" json:"cvRmsePercent"`
+	NMBEPercent        float64  `bson:"nmbe_percent" json:"nmbePercent"`
+	Autocorrelation    float64  `bson:"autocorrelation" json:"autocorrelation"`
+	DegreesOfFreedom   int      `bson:"degrees_of_freedom" json:"degreesOfFreedom"`
+	MeetsGuideline14   bool     `bson:"meets_guideline_14" json:"meetsGuideline14"`
+}
+
+// MVKeyParameter is option A's measured key parameter: the combined hourly demand of the
+// devices the scenario changed, before and after, applied to stipulated operating hours
+type MVKeyParameter struct {
+	DeviceIDs                []string `bson:"device_ids" json:"deviceIds"`
+	BaselineMeanKWh          float64  `bson:"baseline_mean_kwh" json:"baselineMeanKwh"`
+	BaselineStdDevKWh        float64  `bson:"baseline_std_dev_kwh" json:"baselineStdDevKwh"`
+	BaselineSamples          int      `bson:"baseline_samples" json:"baselineSamples"`
+	ReportingMeanKWh         float64  `bson:"reporting_mean_kwh" json:"reportingMeanKwh"`
+	ReportingStdDevKWh       float64  `bson:"reporting_std_dev_kwh" json:"reportingStdDevKwh"`
+	ReportingSamples         int      `bson:"reporting_samples" json:"reportingSamples"`
+	StipulatedOperatingHours float64  `bson:"stipulated_operating_hours" json:"stipulatedOperatingHours"`
+}
+
+// MVSavings holds the verified savings over the reporting period. AdjustedBaselineKWh is the
+// consumption expected without the scenario under reporting period conditions. The savings lie
+// between LowerKWh and UpperKWh at the report's confidence level.
+type MVSavings struct {
+	AdjustedBaselineKWh float64 `bson:"adjusted_baseline_kwh" json:"adjustedBaselineKwh"`
+	ReportingKWh        float64 `bson:"reporting_kwh" json:"reportingKwh"`
+	SavingsKWh          float64 `bson:"savings_kwh" json:"savingsKwh"`
+	SavingsPercent      float64 `bson:"savings_percent" json:"savingsPercent"`
+	UncertaintyKWh      float64 `bson:"uncertainty_kwh" json:"uncertaintyKwh"`
+	UncertaintyPercent  float64 `bson:"uncertainty_percent" json:"uncertaintyPercent"`
+	LowerKWh            float64 `bson:"lower_kwh" json:"lowerKwh"`
+	UpperKWh            float64 `bson:"upper_kwh" json:"upperKwh"`
+	TValue              float64 `bson:"t_value" json:"tValue"`
+	Significant         bool    `bson:"significant" json:"significant"`
+}
+
+// MVReportResponse represents an M&V report in API responses
+type MVReportResponse struct {
+	ID                   string             `json:"id"`
+	ScenarioID           string             `json:"scenarioId"`
+	ScenarioType         string             `json:"scenarioType,omitempty"`
+	BuildingID           string             `json:"buildingId"`
+	Option               MVOption           `json:"option"`
+	ExecutionStartedAt   time.Time          `json:"executionStartedAt"`
+	ExecutionCompletedAt time.Time          `json:"executionCompletedAt"`
+	BaselineFrom         time.Time          `json:"baselineFrom"`
+	BaselineTo           time.Time          `json:"baselineTo"`
+	ReportingFrom        time.Time          `json:"reportingFrom"`
+	ReportingTo          time.Time          `json:"reportingTo"`
+	ConfidenceLevel      float64            `json:"confidenceLevel"`
+	Model                *MVRegressionModel `json:"model,omitempty"`
+	KeyParameter         *MVKeyParameter    `json:"keyParameter,omitempty"`
+	Savings              MVSavings          `json:"savings"`
+	Warnings             []string           `json:"warnings,omitempty"`
+	CreatedBy            string             `json:"createdBy"`
+	CreatedAt            time.Time          `json:"createdAt"`
+}
+
+// ToResponse converts an MVReport to MVReportResponse
+func (r *MVReport) ToResponse() *MVReportResponse {
+	return &MVReportResponse{
+		ID:                   r.ID.Hex(),
+		ScenarioID:           r.ScenarioID,
+		ScenarioType:         r.ScenarioType,
+		BuildingID:           r.BuildingID,
+		Option:               r.Option,
+		ExecutionStartedAt:   r.ExecutionStartedAt,
+		ExecutionCompletedAt: r.ExecutionCompletedAt,
+		BaselineFrom:         r.BaselineFrom,
+		BaselineTo:           r.BaselineTo,
+		ReportingFrom:        r.ReportingFrom,
+		ReportingTo:          r.ReportingTo,
+		ConfidenceLevel:      r.ConfidenceLevel,
+		Model:                r.Model,
+		KeyParameter:         r.KeyParameter,
+		Savings:              r.Savings,
+		Warnings:             r.Warnings,
+		CreatedBy:            r.CreatedBy,
+		CreatedAt:            r.CreatedAt,
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// DeviceModeBreakdownQuery represents the query parameters of a device's operating mode breakdown.
+// BaselineMode and EcoMode name the modes compared to measure the saving of the eco mode.
+type DeviceModeBreakdownQuery struct {
+	From                  time.Time `form:"from"`
+	To                    time.Time `form:"to" binding:"omitempty,gtfield=From"`
+	MaxGapMinutes         int       `form:"maxGapMinutes" binding:"omitempty,min=1,max=1440"`
+	BaselineMode          string    `form:"baselineMode"`
+	EcoMode               string    `form:"ecoMode"`
+	AssumedSavingsPercent *float64  `form:"assumedSavingsPercent" binding:"omitempty,min=0,max=100"`
+}
+
+// DeviceModeUsage is the time and energy a device spent in one operating mode
+type DeviceModeUsage struct {
+	Mode           string   `json:"mode"`
+	Hours          float64  `json:"hours"`
+	EnergyKWh      float64  `json:"energyKwh"`
+	SharePercent   *float64 `json:"sharePercent,omitempty"` // Share of the attributed energy, omitted when none was used
+	AveragePowerKW float64  `json:"averagePowerKw"`
+}
+
+// EcoModeSavings compares the average power of a device in its eco mode with its baseline mode.
+// The gap is the observed saving minus the saving optimization scenarios assume, so a negative
+// gap means the eco mode saves less than assumed.
+type EcoModeSavings struct {
+	BaselineMode           string   `json:"baselineMode"`
+	EcoMode                string   `json:"ecoMode"`
+	BaselineAveragePowerKW float64  `json:"baselineAveragePowerKw"`
+	EcoAveragePowerKW      float64  `json:"ecoAveragePowerKw"`
+	ObservedSavingsPercent *float64 `json:"observedSavingsPercent,omitempty"` // Omitted without baseline consumption
+	AssumedSavingsPercent  float64  `json:"assumedSavingsPercent"`
+	SavingsGapPercent      *float64 `json:"savingsGapPercent,omitempty"`
+}
+
+// DeviceModeBreakdown attributes a device's consumption to the operating modes reported in its
+// telemetry. The time between consecutive readings belongs to the mode of the earlier reading;
+// gaps longer than the maximum gap are not attributed and count as uncovered.
+type DeviceModeBreakdown struct {
+	DeviceID       string            `json:"deviceId"`
+	From           time.Time         `json:"from"`
+	To             time.Time         `json:"to"`
+	Readings       int               `json:"readings"`
+	MaxGapMinutes  int               `json:"maxGapMinutes"`
+	Modes          []DeviceModeUsage `json:"modes"`
+	TotalHours     float64           `json:"totalHours"`
+	TotalEnergyKWh float64           `json:"totalEnergyKwh"`
+	UncoveredHours float64           `json:"uncoveredHours"`
+	EcoSavings     *EcoModeSavings   `json:"ecoSavings,omitempty"` // Set when the device was in both compared modes
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OptimizationExecution records an optimization scenario executed by the IoT service,
+// as announced on the event bus
+type OptimizationExecution struct {
+	ID               primitive.ObjectID          `bson:"_id,omitempty" json:"id"`
+	ScenarioID       string                      `bson:"scenario_id" json:"scenarioId"`
+	SourceScenarioID string                      `bson:"source_scenario_id,omitempty" json:"sourceScenarioId,omitempty"`
+	ScenarioType     string                      `bson:"scenario_type,omitempty" json:"scenarioType,omitempty"`
+	BuildingID       string                      `bson:"building_id" json:"buildingId"`
+	Status           string                      `bson:"status" json:"status"`
+	ActionsApplied   int                         `bson:"actions_applied" json:"actionsApplied"`
+	ActionsFailed    int                         `bson:"actions_failed" json:"actionsFailed"`
+	Actions          []OptimizationActionOutcome `bson:"actions,omitempty" json:"actions,omitempty"`
+	StartedAt        time.Time                   `bson:"started_at,omitempty" json:"startedAt,omitempty"`
+	CompletedAt      time.Time                   `bson:"completed_at" json:"completedAt"`
+	CreatedAt        time.Time                   `bson:"created_at" json:"createdAt"`
+}
+
+// ScenarioTypeDemandResponse marks executions of demand response scenarios
+const ScenarioTypeDemandResponse = "DEMAND_RESPONSE"
+
+// ActionStatusApplied marks actions the device confirmed it applied
+const ActionStatusApplied = "APPLIED"
+
+// OptimizationActionOutcome records how a single action of an executed scenario ended
+type OptimizationActionOutcome struct {
+	DeviceID   string    `bson:"device_id" json:"deviceId"`
+	Command    string    `bson:"command" json:"command"`
+	Status     string    `bson:"status" json:"status"`
+	CommandID  string    `bson:"command_id,omitempty" json:"commandId,omitempty"`
+	ExecutedAt time.Time `bson:"executed_at" json:"executedAt"`
+}
+
+// OptimizationExecutionResponse represents an optimization execution in API responses
+type OptimizationExecutionResponse struct {
+	ScenarioID       string    `json:"scenarioId"`
+	SourceScenarioID string    `json:"sourceScenarioId,omitempty"`
+	ScenarioType     string    `json:"scenarioType,omitempty"`
+	Status           string    `json:"status"`
+	ActionsApplied   int       `json:"actionsApplied"`
+	ActionsFailed    int       `json:"actionsFailed"`
+	CompletedAt      time.Time `json:"completedAt"`
+}
+
+// ToResponse converts an OptimizationExecution to OptimizationExecutionResponse
+func (e *OptimizationExecution) ToResponse() *OptimizationExecutionResponse {
+	return &OptimizationExecutionResponse{
+		ScenarioID:       e.ScenarioID,
+		SourceScenarioID: e.SourceScenarioID,
+		ScenarioType:     e.ScenarioType,
+		Status:           e.Status,
+		ActionsApplied:   e.ActionsApplied,
+		ActionsFailed:    e.ActionsFailed,
+		CompletedAt:      e.CompletedAt,
+	}
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+)
+
+// PortfolioDashboard represents aggregated metrics across all accessible buildings
+type PortfolioDashboard struct {
+	From      time.Time             `json:"from"`
+	To        time.Time             `json:"to"`
+	Totals    PortfolioTotals       `json:"totals"`
+	Buildings []BuildingPerformance `json:"buildings"` // Ranked worst performers first
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+// PortfolioTotals represents portfolio-wide totals
+type PortfolioTotals struct {
+	BuildingCount            int     `json:"buildingCount"`
+	TotalConsumption         float64 `json:"totalConsumption"`
+	ForecastConsumption      float64 `json:"forecastConsumption"`
+	ForecastDeviationPercent float64 `json:"forecastDeviationPercent"`
+	TotalSavings             float64 `json:"totalSavings"`
+	ActiveAnomalies          int64   `json:"activeAnomalies"`
+	CriticalAnomalies        int64   `json:"criticalAnomalies"`
+}
+
+// BuildingPerformance represents a single building's row in the portfolio dashboard
+type BuildingPerformance struct {
+	Rank                     int                    `json:"rank"`
+	BuildingID               string                 `json:"buildingId"`
+	PerformanceScore         float64                `json:"performanceScore"` // 0-100, higher is better
+	TotalConsumption         float64                `json:"totalConsumption"`
+	ForecastConsumption      float64                `json:"forecastConsumption"`
+	ForecastDeviationPercent float64                `json:"forecastDeviationPercent"`
+	Savings                  float64                `json:"savings"`
+	ActiveAnomalies          int64                  `json:"activeAnomalies"`
+	CriticalAnomalies        int64                  `json:"criticalAnomalies"`
+	KPIs                     map[string]interface{} `json:"kpis"`
+	Links                    map[string]string      `json:"links"`
+}
+
+// BuildingAnomalyCounts holds per-building anomaly counts produced by aggregation
+type BuildingAnomalyCounts struct {
+	BuildingID string `bson:"_id"`
+	Active     int64  `bson:"active"`
+	Critical   int64  `bson:"critical"`
+}
+
+// BuildingConsumption holds per-building consumption totals produced by aggregation
+type BuildingConsumption struct {
+	BuildingID string  `bson:"_id"`
+	Actual     float64 `bson:"actual"`
+}
+
+// BuildingSavings holds per-building verified savings produced by aggregation
+type BuildingSavings struct {
+	BuildingID string  `bson:"_id"`
+	SavingsKWh float64 `bson:"savings_kwh"`
+}
+
+// BuildingPeriod is the time range measured for one building in a portfolio aggregation
+type BuildingPeriod struct {
+	BuildingID string
+	From       time.Time
+	To         time.Time
+}
+
+// BuildingHourlyConsumption holds one building's hourly consumption produced by aggregation,
+// separating the main meter from submetered devices
+type BuildingHourlyConsumption struct {
+	BuildingID string    `bson:"building_id"`
+	Timestamp  time.Time `bson:"timestamp"`
+	MainMeter  float64   `bson:"main_meter"`
+	Metered    float64   `bson:"metered"`
+}
+
+// BuildingForecast compares a building's latest forecast with the consumption measured in a range.
+// Predicted totals the forecast intervals in the range; the measured totals only cover the
+// intervals consumption was reported for, so that they can be compared.
+type BuildingForecast struct {
+	Predicted         float64
+	MeasuredPredicted float64
+	MeasuredActual    float64
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // APIResponse represents a standard API response wrapper
 type APIResponse struct {
 	Success bool        `json:"success"`
@@ -10,9 +12,19 @@ type APIResponse struct {
 
 // APIError represents an error in the API response
 type APIError struct {
-	Code    string `json:"code"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details string       `json:"details,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"` // Fields that failed validation
+}
+
+// FieldError describes a request field that failed validation.
+// Rule is the binding rule it broke, e.g. "oneof", and Param the rule's parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
 	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
 }
 
 // NewSuccessResponse creates a successful API response
@@ -36,6 +48,13 @@ func NewErrorResponse(code, message, details string) *APIResponse {
 	}
 }
 
+// NewValidationErrorResponse creates an error API response for a request that failed validation
+func NewValidationErrorResponse(message, details string, fields []FieldError) *APIResponse {
+	response := NewErrorResponse(ErrCodeValidationFailed, message, details)
+	response.Error.Fields = fields
+	return response
+}
+
 // Common error codes
 const (
 	ErrCodeInvalidRequest     = "INVALID_REQUEST"
@@ -55,10 +74,75 @@ const (
 
 // TokenValidationResponse represents the response from security service
 type TokenValidationResponse struct {
-	Valid   bool     `json:"valid"`
-	UserID  string   `json:"userId,omitempty"`
-	Roles   []string `json:"roles,omitempty"`
-	Message string   `json:"message,omitempty"`
+	Valid         bool           `json:"valid"`
+	UserID        string         `json:"userId,omitempty"`
+	Roles         []string       `json:"roles,omitempty"`
+	Groups        []string       `json:"groups,omitempty"`
+	Message       string         `json:"message,omitempty"`
+	Impersonation *Impersonation `json:"impersonation,omitempty"`
+	Kiosk         *KioskScope    `json:"kiosk,omitempty"`
+	Tenant        *TenantScope   `json:"tenant,omitempty"`
+	// Masks lists the response fields the caller may not see and how to mask them
+	Masks map[string]string `json:"masks,omitempty"`
+}
+
+// KioskScope limits a read-only kiosk token to one building's dashboards
+type KioskScope struct {
+	TokenID    string `json:"tokenId"`
+	BuildingID string `json:"buildingId"`
+}
+
+// TenantScope limits a token to the buildings of one organization
+type TenantScope struct {
+	OrgID       string   `json:"orgId"`
+	BuildingIDs []string `json:"buildingIds"`
+}
+
+// AllowsBuilding reports whether the building belongs to the organization
+func (t *TenantScope) AllowsBuilding(buildingID string) bool {
+	for _, id := range t.BuildingIDs {
+		if id == buildingID {
+			return true
+		}
+	}
+	return false
+}
+
+// Impersonation identifies the admin acting as a user with an impersonation token
+type Impersonation struct {
+	ImpersonatorID       string `json:"impersonatorId"`
+	ImpersonatorUsername string `json:"impersonatorUsername"`
+	Reason               string `json:"reason"`
+	Banner               string `json:"banner"`
+}
+
+// SigningKeyResponse represents the token verification keys published by the security service.
+// Algorithm, Key and KeyID describe the current key; Keys also lists rotated-out keys that still
+// verify tokens during their grace period.
+type SigningKeyResponse struct {
+	Algorithm string            `json:"algorithm"`
+	Key       string            `json:"key"`
+	KeyID     string            `json:"keyId"`
+	Issuer    string            `json:"issuer"`
+	Keys      []VerificationKey `json:"keys"`
+}
+
+// VerificationKey is a key tokens are verified with, matched by the token's "kid" header.
+// Key is the base64-encoded secret for HS256 and the PEM-encoded public key otherwise.
+type VerificationKey struct {
+	KeyID     string     `json:"keyId"`
+	Algorithm string     `json:"algorithm"`
+	Key       string     `json:"key"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// RoleChangeEvent is pushed by the security service when a user's roles or status change,
+// or when a role definition is updated. An empty UserID affects every user.
+type RoleChangeEvent struct {
+	Type       string    `json:"type"`
+	UserID     string    `json:"userId,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
 }
 
 // AuditLogRequest represents a request to log an audit event
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// RetentionCollectionStatus reports the retention policy and purge counters of a collection.
+// In dry-run mode LastAffected is the number of records that would have been purged.
+type RetentionCollectionStatus struct {
+	Collection    string     `json:"collection"`
+	RetentionDays int        `json:"retentionDays"`
+	Enabled       bool       `json:"enabled"`
+	LastRunAt     *time.Time `json:"lastRunAt,omitempty"`
+	LastAffected  int64      `json:"lastAffected"`
+	TotalPurged   int64      `json:"totalPurged"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// RetentionStatus reports the retention subsystem state of a service
+type RetentionStatus struct {
+	DryRun          bool                        `json:"dryRun"`
+	IntervalMinutes int                         `json:"intervalMinutes"`
+	Collections     []RetentionCollectionStatus `json:"collections"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Device activity recorded from IoT service events
+const (
+	ActivityCommandApplied  = "COMMAND_APPLIED"
+	ActivityCommandTimedOut = "COMMAND_TIMED_OUT"
+	ActivityStatusChanged   = "STATUS_CHANGED"
+)
+
+// DeviceActivity is something that happened to a device, as announced on the event bus. It is
+// kept for a month to explain anomalies.
+type DeviceActivity struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ActivityID     string             `bson:"activity_id" json:"activityId"` // ID of the event it was recorded from
+	Kind           string             `bson:"kind" json:"kind"`
+	DeviceID       string             `bson:"device_id" json:"deviceId"`
+	BuildingID     string             `bson:"building_id" json:"buildingId"`
+	Command        string             `bson:"command,omitempty" json:"command,omitempty"`
+	CommandID      string             `bson:"command_id,omitempty" json:"commandId,omitempty"`
+	IssuedBy       string             `bson:"issued_by,omitempty" json:"issuedBy,omitempty"`
+	PreviousStatus string             `bson:"previous_status,omitempty" json:"previousStatus,omitempty"`
+	Status         string             `bson:"status,omitempty" json:"status,omitempty"`
+	OccurredAt     time.Time          `bson:"occurred_at" json:"occurredAt"`
+	CreatedAt      time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// Categories of root causes suggested for anomalies
+const (
+	RootCauseCommand      = "COMMAND"
+	RootCauseOptimization = "OPTIMIZATION"
+	RootCauseWeather      = "WEATHER"
+	RootCauseDeviceStatus = "DEVICE_STATUS"
+)
+
+// RootCause is a candidate explanation of an anomaly: something that happened in its building
+// shortly before or during it. Confidence is between 0 and 1 and grows with how directly the
+// cause concerns the anomalous device and how close in time it happened. Reference identifies
+// the command or optimization scenario.
+type RootCause struct {
+	Category   string                 `bson:"category" json:"category"`
+	Confidence float64                `bson:"confidence" json:"confidence"`
+	Summary    string                 `bson:"summary" json:"summary"`
+	DeviceID   string                 `bson:"device_id,omitempty" json:"deviceId,omitempty"`
+	Reference  string                 `bson:"reference,omitempty" json:"reference,omitempty"`
+	OccurredAt *time.Time             `bson:"occurred_at,omitempty" json:"occurredAt,omitempty"`
+	Evidence   map[string]interface{} `bson:"evidence,omitempty" json:"evidence,omitempty"`
+}
+
+// RootCauseEvidence is what happened in an anomaly's building around its window
+type RootCauseEvidence struct {
+	Activities []*DeviceActivity
+	Executions []*OptimizationExecution
+	DegreeDays *DegreeDays
+}
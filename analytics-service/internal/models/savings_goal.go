@@ -0,0 +1,175 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SavingsGoalState summarizes a savings goal's progress towards its target
+type SavingsGoalState string
+
+const (
+	// SavingsGoalStateAchieved goals have saved the year's target already
+	SavingsGoalStateAchieved SavingsGoalState = "ACHIEVED"
+	// SavingsGoalStateOnTrack goals are projected to reach the target by year-end
+	SavingsGoalStateOnTrack SavingsGoalState = "ON_TRACK"
+	// SavingsGoalStateAtRisk goals are projected to fall short of the target
+	SavingsGoalStateAtRisk SavingsGoalState = "AT_RISK"
+	// SavingsGoalStateNoBaseline goals have no consumption recorded in their baseline year
+	SavingsGoalStateNoBaseline SavingsGoalState = "NO_BASELINE"
+)
+
+// ProjectionSourceBaselineTrend projects the rest of a year from the baseline year's consumption
+// over the same period, scaled by how consumption compares with the baseline so far
+const ProjectionSourceBaselineTrend ProjectionSource = "BASELINE_TREND"
+
+// SavingsGoal is a target to reduce a building's energy consumption in a calendar year (UTC)
+// by TargetPercent compared with its baseline year
+type SavingsGoal struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name          string              `bson:"name" json:"name"`
+	BuildingID    string              `bson:"building_id" json:"buildingId"`
+	Year          int                 `bson:"year" json:"year"`
+	BaselineYear  int                 `bson:"baseline_year" json:"baselineYear"`
+	TargetPercent float64             `bson:"target_percent" json:"targetPercent"`
+	NotifyUserIDs []string            `bson:"notify_user_ids,omitempty" json:"notifyUserIds,omitempty"`
+	Tracking      SavingsGoalTracking `bson:"tracking" json:"tracking"`
+	CreatedBy     string              `bson:"created_by" json:"createdBy"`
+	CreatedAt     time.Time           `bson:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updatedAt"`
+}
+
+// SavingsGoalTracking is the progress kept by the savings goal tracking worker.
+// MilestonesReached lists the milestones already notified; Final is set once the year has ended.
+type SavingsGoalTracking struct {
+	Months                  []SavingsGoalMonth `bson:"months,omitempty" json:"months,omitempty"`
+	SavedKWh                float64            `bson:"saved_kwh" json:"savedKwh"`
+	SavedPercent            float64            `bson:"saved_percent" json:"savedPercent"`
+	ProgressPercent         float64            `bson:"progress_percent" json:"progressPercent"`
+	ProjectedSavingsPercent float64            `bson:"projected_savings_percent" json:"projectedSavingsPercent"`
+	State                   SavingsGoalState   `bson:"state,omitempty" json:"state,omitempty"`
+	MilestonesReached       []int              `bson:"milestones_reached,omitempty" json:"milestonesReached,omitempty"`
+	Final                   bool               `bson:"final" json:"final"`
+	EvaluatedAt             *time.Time         `bson:"evaluated_at,omitempty" json:"evaluatedAt,omitempty"`
+}
+
+// SavingsGoalMonth compares a month's consumption with the same month of the baseline year.
+// The current month is Partial and compares the days up to yesterday only.
+type SavingsGoalMonth struct {
+	Month        string  `bson:"month" json:"month"`
+	BaselineKWh  float64 `bson:"baseline_kwh" json:"baselineKwh"`
+	ActualKWh    float64 `bson:"actual_kwh" json:"actualKwh"`
+	SavedKWh     float64 `bson:"saved_kwh" json:"savedKwh"`
+	SavedPercent float64 `bson:"saved_percent" json:"savedPercent"`
+	Partial      bool    `bson:"partial,omitempty" json:"partial,omitempty"`
+}
+
+// SavingsGoalResponse represents a savings goal in API responses
+type SavingsGoalResponse struct {
+	ID            string              `json:"id"`
+	Name          string              `json:"name"`
+	BuildingID    string              `json:"buildingId"`
+	Year          int                 `json:"year"`
+	BaselineYear  int                 `json:"baselineYear"`
+	TargetPercent float64             `json:"targetPercent"`
+	NotifyUserIDs []string            `json:"notifyUserIds,omitempty"`
+	Tracking      SavingsGoalTracking `json:"tracking"`
+	CreatedBy     string              `json:"createdBy"`
+	CreatedAt     time.Time           `json:"createdAt"`
+	UpdatedAt     time.Time           `json:"updatedAt"`
+}
+
+// ToResponse converts a SavingsGoal to SavingsGoalResponse
+func (g *SavingsGoal) ToResponse() *SavingsGoalResponse {
+	return &SavingsGoalResponse{
+		ID:            g.ID.Hex(),
+		Name:          g.Name,
+		BuildingID:    g.BuildingID,
+		Year:          g.Year,
+		BaselineYear:  g.BaselineYear,
+		TargetPercent: g.TargetPercent,
+		NotifyUserIDs: g.NotifyUserIDs,
+		Tracking:      g.Tracking,
+		CreatedBy:     g.CreatedBy,
+		CreatedAt:     g.CreatedAt,
+		UpdatedAt:     g.UpdatedAt,
+	}
+}
+
+// SavingsGoalRequest represents a request to create or replace a savings goal.
+// The baseline year defaults to the year before the goal's year.
+type SavingsGoalRequest struct {
+	Name          string   `json:"name" binding:"max=100"`
+	BuildingID    string   `json:"buildingId" binding:"required"`
+	Year          int      `json:"year" binding:"required,min=2000,max=2100"`
+	BaselineYear  int      `json:"baselineYear" binding:"omitempty,min=2000,ltfield=Year"`
+	TargetPercent float64  `json:"targetPercent" binding:"required,gt=0,lt=100"`
+	NotifyUserIDs []string `json:"notifyUserIds"`
+}
+
+// ListSavingsGoalsRequest represents query parameters for listing savings goals
+type ListSavingsGoalsRequest struct {
+	BuildingID string `form:"buildingId"`
+	Year       int    `form:"year"`
+	Page       int    `form:"page"`
+	Limit      int    `form:"limit"`
+}
+
+// SavingsGoalProgress reports a savings goal's savings so far and its projected attainment at
+// year-end. Savings are baseline minus actual consumption; progress is the share of the year's
+// target savings achieved so far, so an on-track goal reaches about 50% by mid-year.
+type SavingsGoalProgress struct {
+	GoalID                  string             `json:"goalId"`
+	Name                    string             `json:"name"`
+	BuildingID              string             `json:"buildingId"`
+	Year                    int                `json:"year"`
+	BaselineYear            int                `json:"baselineYear"`
+	TargetPercent           float64            `json:"targetPercent"`
+	BaselineKWh             float64            `json:"baselineKwh"` // The whole baseline year
+	TargetKWh               float64            `json:"targetKwh"`   // Consumption allowed for the year
+	TargetSavingsKWh        float64            `json:"targetSavingsKwh"`
+	BaselineToDateKWh       float64            `json:"baselineToDateKwh"`
+	ActualToDateKWh         float64            `json:"actualToDateKwh"`
+	SavedKWh                float64            `json:"savedKwh"`
+	SavedPercent            float64            `json:"savedPercent"`
+	ProgressPercent         float64            `json:"progressPercent"`
+	ProjectedKWh            float64            `json:"projectedKwh"`
+	ProjectedSavingsPercent float64            `json:"projectedSavingsPercent"`
+	ProjectionSource        ProjectionSource   `json:"projectionSource,omitempty"` // Empty once the year has ended
+	ForecastID              string             `json:"forecastId,omitempty"`
+	State                   SavingsGoalState   `json:"state"`
+	Months                  []SavingsGoalMonth `json:"months"`
+	MilestonesReached       []int              `json:"milestonesReached"`
+	CalculatedAt            time.Time          `json:"calculatedAt"`
+}
+
+// SavingsGoalCard summarizes a savings goal's tracked progress on a building dashboard
+type SavingsGoalCard struct {
+	GoalID                  string           `json:"goalId"`
+	Name                    string           `json:"name"`
+	Year                    int              `json:"year"`
+	TargetPercent           float64          `json:"targetPercent"`
+	SavedPercent            float64          `json:"savedPercent"`
+	ProgressPercent         float64          `json:"progressPercent"`
+	ProjectedSavingsPercent float64          `json:"projectedSavingsPercent"`
+	State                   SavingsGoalState `json:"state,omitempty"` // Empty until the goal was first tracked
+	MilestonesReached       []int            `json:"milestonesReached,omitempty"`
+	EvaluatedAt             *time.Time       `json:"evaluatedAt,omitempty"`
+}
+
+// ToCard converts a SavingsGoal to a dashboard card
+func (g *SavingsGoal) ToCard() SavingsGoalCard {
+	return SavingsGoalCard{
+		GoalID:                  g.ID.Hex(),
+		Name:                    g.Name,
+		Year:                    g.Year,
+		TargetPercent:           g.TargetPercent,
+		SavedPercent:            g.Tracking.SavedPercent,
+		ProgressPercent:         g.Tracking.ProgressPercent,
+		ProjectedSavingsPercent: g.Tracking.ProjectedSavingsPercent,
+		State:                   g.Tracking.State,
+		MilestonesReached:       g.Tracking.MilestonesReached,
+		EvaluatedAt:             g.Tracking.EvaluatedAt,
+	}
+}
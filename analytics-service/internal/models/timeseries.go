@@ -52,7 +52,58 @@ type TimeSeriesQueryRequest struct {
 	DeviceIDs       []string    `json:"deviceIds,omitempty"`
 	BuildingID      string      `json:"buildingId,omitempty"`
 	From            time.Time   `json:"from" binding:"required"`
-	To              time.Time   `json:"to" binding:"required"`
+	To              time.Time   `json:"to" binding:"required,gtfield=From"`
 	AggregationType string      `json:"aggregationType" binding:"required,oneof=HOURLY DAILY WEEKLY MONTHLY"`
 	Metrics         []string    `json:"metrics,omitempty"`
 }
+
+// AnnotatedTimeSeriesQuery represents the query parameters of an annotated building time-series
+type AnnotatedTimeSeriesQuery struct {
+	From            time.Time `form:"from"`
+	To              time.Time `form:"to" binding:"omitempty,gtfield=From"`
+	AggregationType string    `form:"aggregationType" binding:"omitempty,oneof=HOURLY DAILY WEEKLY MONTHLY"`
+	Metric          string    `form:"metric"`
+}
+
+// AnnotationType identifies what an annotation on a time-series marks
+type AnnotationType string
+
+const (
+	AnnotationTypeAnomaly            AnnotationType = "ANOMALY"
+	AnnotationTypeOptimizationAction AnnotationType = "OPTIMIZATION_ACTION"
+	AnnotationTypeDemandResponse     AnnotationType = "DEMAND_RESPONSE"
+)
+
+// TimeSeriesAnnotation marks an event that explains a change in a time-series.
+// EndTime is set for annotations that span a period, such as demand response events.
+type TimeSeriesAnnotation struct {
+	Type        AnnotationType `json:"type"`
+	Timestamp   time.Time      `json:"timestamp"`
+	EndTime     *time.Time     `json:"endTime,omitempty"`
+	DeviceID    string         `json:"deviceId,omitempty"`
+	ReferenceID string         `json:"referenceId"`
+	Label       string         `json:"label"`
+	Severity    string         `json:"severity,omitempty"`
+	Status      string         `json:"status,omitempty"`
+}
+
+// AnnotatedTimeSeriesPoint is a building-wide time-series bucket with the annotations that start in it.
+// Value is nil for buckets that only hold annotations.
+type AnnotatedTimeSeriesPoint struct {
+	Timestamp   time.Time              `json:"timestamp"`
+	Value       *float64               `json:"value"`
+	DeviceCount int                    `json:"deviceCount"`
+	Annotations []TimeSeriesAnnotation `json:"annotations,omitempty"`
+}
+
+// AnnotatedTimeSeriesResponse represents a building consumption series merged with anomalies,
+// executed optimization actions and demand response events on one timeline
+type AnnotatedTimeSeriesResponse struct {
+	BuildingID       string                      `json:"buildingId"`
+	Metric           string                      `json:"metric"`
+	AggregationType  string                      `json:"aggregationType"`
+	From             time.Time                   `json:"from"`
+	To               time.Time                   `json:"to"`
+	Points           []*AnnotatedTimeSeriesPoint `json:"points"`
+	AnnotationCounts map[AnnotationType]int      `json:"annotationCounts"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+// TopConsumersQuery represents the query parameters of a building's top consumers
+type TopConsumersQuery struct {
+	BuildingID string `form:"buildingId" binding:"required"`
+	Period     string `form:"period" binding:"omitempty,oneof=DAILY WEEKLY MONTHLY"`
+	Limit      int    `form:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+// TopConsumers ranks a building's devices by consumption over a period and accounts for
+// the load no device is submetering
+type TopConsumers struct {
+	BuildingID               string           `json:"buildingId"`
+	Period                   string           `json:"period"`
+	From                     time.Time        `json:"from"`
+	To                       time.Time        `json:"to"`
+	TotalConsumption         float64          `json:"totalConsumption"`
+	PreviousTotalConsumption float64          `json:"previousTotalConsumption"`
+	ChangePercent            *float64         `json:"changePercent"` // Nil without previous period data
+	MeteredConsumption       float64          `json:"meteredConsumption"`
+	Devices                  []DeviceConsumer `json:"devices"` // Highest consumption first
+	Unmetered                UnmeteredLoad    `json:"unmetered"`
+	UpdatedAt                time.Time        `json:"updatedAt"`
+}
+
+// DeviceConsumer is a device's row in the top consumers ranking
+type DeviceConsumer struct {
+	Rank                int      `json:"rank"`
+	DeviceID            string   `json:"deviceId"`
+	Type                string   `json:"type,omitempty"`
+	Model               string   `json:"model,omitempty"`
+	Consumption         float64  `json:"consumption"`
+	SharePercent        float64  `json:"sharePercent"`
+	PreviousConsumption float64  `json:"previousConsumption"`
+	ChangePercent       *float64 `json:"changePercent"` // Nil without previous period data
+}
+
+// UnmeteredLoad is the building's consumption not covered by any device (main meter minus
+// submetered devices), split into estimated components from its hourly profile
+type UnmeteredLoad struct {
+	Consumption  float64 `json:"consumption"`
+	SharePercent float64 `json:"sharePercent"`
+	// BaseLoad is the always-on load, estimated from the lowest hours of the unmetered profile
+	BaseLoad float64 `json:"baseLoad"`
+	// OccupiedHoursLoad and AfterHoursLoad split the remaining variable load by when it occurred
+	OccupiedHoursLoad float64 `json:"occupiedHoursLoad"`
+	AfterHoursLoad    float64 `json:"afterHoursLoad"`
+	// Disaggregated is false when there was no hourly data to split the unmetered load with
+	Disaggregated bool `json:"disaggregated"`
+}
+
+// DeviceConsumption holds a device's consumption total produced by aggregation.
+// An empty DeviceID holds the building's main meter.
+type DeviceConsumption struct {
+	DeviceID    string  `bson:"_id"`
+	Consumption float64 `bson:"consumption"`
+}
+
+// HourlyConsumption holds a building's consumption in one hour produced by aggregation
+type HourlyConsumption struct {
+	Timestamp time.Time `bson:"_id"`
+	MainMeter float64   `bson:"main_meter"`
+	Metered   float64   `bson:"metered"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TrendDirection describes the direction of a statistically significant KPI trend
+type TrendDirection string
+
+const (
+	TrendDirectionUp   TrendDirection = "UP"
+	TrendDirectionDown TrendDirection = "DOWN"
+	TrendDirectionFlat TrendDirection = "FLAT" // no significant trend
+)
+
+// TrendQuery represents query parameters for KPI trend analysis
+type TrendQuery struct {
+	Metric string `form:"metric"`                                 // daily aggregate metric totalled per week; defaults to consumption
+	Weeks  int    `form:"weeks" binding:"omitempty,min=4,max=26"` // number of full weeks analysed; defaults to 8
+}
+
+// TrendPoint is the weekly total of a metric
+type TrendPoint struct {
+	WeekStart time.Time `json:"weekStart"`
+	Value     float64   `json:"value"`
+}
+
+// DeviceContribution is a device's share of a building's trend
+type DeviceContribution struct {
+	DeviceID     string  `bson:"device_id" json:"deviceId"`
+	Slope        float64 `bson:"slope" json:"slope"`
+	SharePercent float64 `bson:"share_percent" json:"sharePercent"`
+}
+
+// KPITrend is the rolling weekly trend of a building metric.
+// Slope is the change per week from a least-squares fit; Confidence is the two-sided
+// significance of the slope (1 - p-value of a t-test).
+type KPITrend struct {
+	BuildingID           string               `json:"buildingId"`
+	Metric               string               `json:"metric"`
+	From                 time.Time            `json:"from"`
+	To                   time.Time            `json:"to"`
+	Direction            TrendDirection       `json:"direction"`
+	Slope                float64              `json:"slope"`
+	SlopePercent         float64              `json:"slopePercent"` // slope relative to the weekly mean
+	Confidence           float64              `json:"confidence"`
+	ConsecutiveIncreases int                  `json:"consecutiveIncreases"`
+	Regression           bool                 `json:"regression"`
+	Points               []TrendPoint         `json:"points"`
+	ContributingDevices  []DeviceContribution `json:"contributingDevices"`
+	Alert                *TrendAlert          `json:"alert,omitempty"`
+}
+
+// TrendAlert records a detected KPI regression. One alert is kept per building, metric and week.
+type TrendAlert struct {
+	ID                   primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	BuildingID           string               `bson:"building_id" json:"buildingId"`
+	Metric               string               `bson:"metric" json:"metric"`
+	WeekStart            time.Time            `bson:"week_start" json:"weekStart"`
+	Slope                float64              `bson:"slope" json:"slope"`
+	SlopePercent         float64              `bson:"slope_percent" json:"slopePercent"`
+	Confidence           float64              `bson:"confidence" json:"confidence"`
+	ConsecutiveIncreases int                  `bson:"consecutive_increases" json:"consecutiveIncreases"`
+	ContributingDevices  []DeviceContribution `bson:"contributing_devices" json:"contributingDevices"`
+	DetectedAt           time.Time            `bson:"detected_at" json:"detectedAt"`
+	UpdatedAt            time.Time            `bson:"updated_at" json:"updatedAt"`
+}
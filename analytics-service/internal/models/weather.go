@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// DegreeDays holds the heating and cooling degree days of a building location over a period,
+// as calculated by the Forecast service
+type DegreeDays struct {
+	BuildingID        string            `json:"buildingId"`
+	From              time.Time         `json:"from"`
+	To                time.Time         `json:"to"`
+	BaseTemperature   float64           `json:"baseTemperature"`
+	HeatingDegreeDays float64           `json:"heatingDegreeDays"`
+	CoolingDegreeDays float64           `json:"coolingDegreeDays"`
+	MissingDays       int               `json:"missingDays"`
+	Days              []DailyDegreeDays `json:"days,omitempty"`
+}
+
+// DailyDegreeDays holds the heating and cooling degree days of one day
+type DailyDegreeDays struct {
+	Date              string  `json:"date"`
+	MeanTemperature   float64 `json:"meanTemperature"`
+	HeatingDegreeDays float64 `json:"heatingDegreeDays"`
+	CoolingDegreeDays float64 `json:"coolingDegreeDays"`
+}
+
+// Total returns the combined heating and cooling degree days
+func (d *DegreeDays) Total() float64 {
+	return d.HeatingDegreeDays + d.CoolingDegreeDays
+}
+
+// WeatherNormalizedConsumption compares a period's consumption with a reference period after
+// correcting for weather. NormalizedConsumptionKWh is the period's consumption scaled to the
+// degree days of the reference period, so a heatwave or cold snap does not skew the comparison.
+type WeatherNormalizedConsumption struct {
+	From                     time.Time `json:"from"`
+	To                       time.Time `json:"to"`
+	ReferenceFrom            time.Time `json:"referenceFrom"`
+	ReferenceTo              time.Time `json:"referenceTo"`
+	BaseTemperature          float64   `json:"baseTemperature"`
+	ConsumptionKWh           float64   `json:"consumptionKwh"`
+	HeatingDegreeDays        float64   `json:"heatingDegreeDays"`
+	CoolingDegreeDays        float64   `json:"coolingDegreeDays"`
+	ConsumptionPerDegreeDay  float64   `json:"consumptionPerDegreeDay"`
+	ReferenceConsumptionKWh  float64   `json:"referenceConsumptionKwh"`
+	ReferenceHeatingDD       float64   `json:"referenceHeatingDegreeDays"`
+	ReferenceCoolingDD       float64   `json:"referenceCoolingDegreeDays"`
+	NormalizedConsumptionKWh float64   `json:"normalizedConsumptionKwh"`
+	ChangePercent            float64   `json:"changePercent"`
+	NormalizedChangePercent  float64   `json:"normalizedChangePercent"`
+}
+
+// Metrics flattens the comparison into KPI metrics, raw and normalized side by side
+func (w *WeatherNormalizedConsumption) Metrics() map[string]interface{} {
+	return map[string]interface{}{
+		"consumptionKwh":                     w.ConsumptionKWh,
+		"heatingDegreeDays":                  w.HeatingDegreeDays,
+		"coolingDegreeDays":                  w.CoolingDegreeDays,
+		"consumptionPerDegreeDay":            w.ConsumptionPerDegreeDay,
+		"referenceConsumptionKwh":            w.ReferenceConsumptionKWh,
+		"normalizedConsumptionKwh":           w.NormalizedConsumptionKWh,
+		"consumptionChangePercent":           w.ChangePercent,
+		"normalizedConsumptionChangePercent": w.NormalizedChangePercent,
+		"degreeDayBaseTemperature":           w.BaseTemperature,
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// ZoneConsumptionQuery represents the query parameters of a building's consumption rolled up by
+// the floors, zones or rooms of the location hierarchy
+type ZoneConsumptionQuery struct {
+	BuildingID string `form:"buildingId" binding:"required"`
+	Level      string `form:"level" binding:"omitempty,oneof=FLOOR ZONE ROOM"`
+	Period     string `form:"period" binding:"omitempty,oneof=DAILY WEEKLY MONTHLY"`
+}
+
+// ZoneConsumption is a building's submetered consumption over a period, rolled up by the locations
+// of one level of the hierarchy. Devices not assigned to a location of that level are reported as
+// unassigned.
+type ZoneConsumption struct {
+	BuildingID       string         `json:"buildingId"`
+	Level            string         `json:"level"`
+	Period           string         `json:"period"`
+	From             time.Time      `json:"from"`
+	To               time.Time      `json:"to"`
+	TotalConsumption float64        `json:"totalConsumption"`
+	Zones            []ZoneConsumer `json:"zones"` // Highest consumption first
+	Unassigned       ZoneConsumer   `json:"unassigned"`
+	UpdatedAt        time.Time      `json:"updatedAt"`
+}
+
+// ZoneConsumer is a location's row in a zone consumption rollup
+type ZoneConsumer struct {
+	LocationID          string   `json:"locationId,omitempty"`
+	Name                string   `json:"name,omitempty"`
+	Area                float64  `json:"area,omitempty"` // m²
+	Devices             int      `json:"devices"`
+	OnlineDevices       int      `json:"onlineDevices"`
+	Consumption         float64  `json:"consumption"`
+	SharePercent        float64  `json:"sharePercent"`
+	PreviousConsumption float64  `json:"previousConsumption"`
+	ChangePercent       *float64 `json:"changePercent"`       // Nil without previous period data
+	Intensity           *float64 `json:"intensity,omitempty"` // kWh/m², nil without an area
+}
@@ -11,16 +11,22 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
 )
 
-// AnomalyRepository handles anomaly database operations
+// AnomalyRepository handles anomaly database operations. Cross-building aggregations read through the
+// reporting collection, which may be served by secondaries.
 type AnomalyRepository struct {
 	collection *mongo.Collection
+	reporting  *mongo.Collection
 }
 
-// NewAnomalyRepository creates a new anomaly repository
-func NewAnomalyRepository(collection *mongo.Collection) *AnomalyRepository {
-	return &AnomalyRepository{collection: collection}
+// NewAnomalyRepository creates a new anomaly repository. A nil reporting collection reads from the primary collection.
+func NewAnomalyRepository(collection, reporting *mongo.Collection) *AnomalyRepository {
+	if reporting == nil {
+		reporting = collection
+	}
+	return &AnomalyRepository{collection: collection, reporting: reporting}
 }
 
 // Create inserts a new anomaly
@@ -48,7 +54,7 @@ func (r *AnomalyRepository) FindByID(ctx context.Context, id string) (*models.An
 	}
 
 	var anomaly models.Anomaly
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&anomaly)
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id")).Decode(&anomaly)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("anomaly not found")
@@ -62,7 +68,7 @@ func (r *AnomalyRepository) FindByID(ctx context.Context, id string) (*models.An
 // FindByAnomalyID retrieves an anomaly by its anomaly_id field
 func (r *AnomalyRepository) FindByAnomalyID(ctx context.Context, anomalyID string) (*models.Anomaly, error) {
 	var anomaly models.Anomaly
-	err := r.collection.FindOne(ctx, bson.M{"anomaly_id": anomalyID}).Decode(&anomaly)
+	err := r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"anomaly_id": anomalyID}, "building_id")).Decode(&anomaly)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("anomaly not found")
@@ -100,6 +106,8 @@ func (r *AnomalyRepository) FindAll(ctx context.Context, deviceID, buildingID, a
 		filter["status"] = status
 	}
 
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
+
 	// Get total count
 	total, err := r.collection.CountDocuments(ctx, filter)
 	if err != nil {
@@ -126,6 +134,69 @@ func (r *AnomalyRepository) FindAll(ctx context.Context, deviceID, buildingID, a
 	return anomalies, total, nil
 }
 
+// FindByBuildingInRange retrieves anomalies of a building detected within the range, oldest first
+func (r *AnomalyRepository) FindByBuildingInRange(ctx context.Context, buildingID string, from, to time.Time) ([]*models.Anomaly, error) {
+	filter := bson.M{
+		"building_id": buildingID,
+		"detected_at": bson.M{"$gte": from, "$lte": to},
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "detected_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var anomalies []*models.Anomaly
+	if err := cursor.All(ctx, &anomalies); err != nil {
+		return nil, err
+	}
+
+	return anomalies, nil
+}
+
+// FindByTypeInRange retrieves anomalies of a type detected within the range, oldest first
+func (r *AnomalyRepository) FindByTypeInRange(ctx context.Context, anomalyType string, from, to time.Time) ([]*models.Anomaly, error) {
+	filter := tenant.BuildingFilter(ctx, bson.M{
+		"type":        anomalyType,
+		"detected_at": bson.M{"$gte": from, "$lte": to},
+	}, "building_id")
+
+	findOptions := options.Find().
+		SetLimit(maxComparisonRecords).
+		SetSort(bson.D{{Key: "detected_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var anomalies []*models.Anomaly
+	if err := cursor.All(ctx, &anomalies); err != nil {
+		return nil, err
+	}
+
+	return anomalies, nil
+}
+
+// ExistsForForecastRun checks whether an anomaly of the type was already raised for the run of
+// forecast intervals starting at runStart
+func (r *AnomalyRepository) ExistsForForecastRun(ctx context.Context, buildingID, anomalyType, forecastID string, runStart time.Time) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"building_id":        buildingID,
+		"type":               anomalyType,
+		"details.forecastId": forecastID,
+		"details.runStart":   runStart,
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 // Update updates an anomaly
 func (r *AnomalyRepository) Update(ctx context.Context, id string, updates bson.M) (*models.Anomaly, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -137,7 +208,7 @@ func (r *AnomalyRepository) Update(ctx context.Context, id string, updates bson.
 
 	result := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"_id": objectID},
+		tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"),
 		bson.M{"$set": updates},
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	)
@@ -164,3 +235,62 @@ func (r *AnomalyRepository) CountByBuildingAndStatus(ctx context.Context, buildi
 	filter := bson.M{"building_id": buildingID, "status": status}
 	return r.collection.CountDocuments(ctx, filter)
 }
+
+// CountActiveByBuilding counts unresolved anomalies per building in a single aggregation.
+// An empty buildingIDs slice includes every building.
+func (r *AnomalyRepository) CountActiveByBuilding(ctx context.Context, buildingIDs []string) ([]*models.BuildingAnomalyCounts, error) {
+	match := bson.M{
+		"status":      bson.M{"$in": []models.AnomalyStatus{models.AnomalyStatusNew, models.AnomalyStatusAcknowledged}},
+		"building_id": bson.M{"$exists": true, "$ne": ""},
+	}
+	if len(buildingIDs) > 0 {
+		match["building_id"] = bson.M{"$in": buildingIDs}
+	}
+	match = tenant.BuildingFilter(ctx, match, "building_id")
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{
+			"$group": bson.M{
+				"_id":    "$building_id",
+				"active": bson.M{"$sum": 1},
+				"critical": bson.M{"$sum": bson.M{
+					"$cond": []interface{}{bson.M{"$eq": []interface{}{"$severity", models.AnomalySeverityCritical}}, 1, 0},
+				}},
+			},
+		},
+	}
+
+	cursor, err := r.reporting.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var counts []*models.BuildingAnomalyCounts
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// CountOlderThan counts anomalies created before the given time
+func (r *AnomalyRepository) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+}
+
+// DeleteOlderThan removes anomalies created before the given time
+func (r *AnomalyRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
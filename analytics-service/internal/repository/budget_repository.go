@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
+)
+
+// BudgetRepository handles energy budget database operations
+type BudgetRepository struct {
+	collection *mongo.Collection
+}
+
+// NewBudgetRepository creates a new budget repository
+func NewBudgetRepository(collection *mongo.Collection) *BudgetRepository {
+	return &BudgetRepository{collection: collection}
+}
+
+// Create inserts a new budget
+func (r *BudgetRepository) Create(ctx context.Context, budget *models.EnergyBudget) (*models.EnergyBudget, error) {
+	budget.CreatedAt = time.Now()
+	budget.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, budget)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("budget already exists for this building or device")
+		}
+		return nil, err
+	}
+
+	budget.ID = result.InsertedID.(primitive.ObjectID)
+	return budget, nil
+}
+
+// FindByID retrieves a budget by ID
+func (r *BudgetRepository) FindByID(ctx context.Context, id string) (*models.EnergyBudget, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid budget ID format")
+	}
+
+	var budget models.EnergyBudget
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id")).Decode(&budget)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("budget not found")
+		}
+		return nil, err
+	}
+
+	return &budget, nil
+}
+
+// FindAll retrieves budgets with filters and pagination
+func (r *BudgetRepository) FindAll(ctx context.Context, buildingID, deviceID string, page, limit int) ([]*models.EnergyBudget, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	skip := int64((page - 1) * limit)
+	filter := bson.M{}
+
+	if buildingID != "" {
+		filter["building_id"] = buildingID
+	}
+	if deviceID != "" {
+		filter["device_id"] = deviceID
+	}
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(skip).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "building_id", Value: 1}, {Key: "device_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var budgets []*models.EnergyBudget
+	if err := cursor.All(ctx, &budgets); err != nil {
+		return nil, 0, err
+	}
+
+	return budgets, total, nil
+}
+
+// FindEach calls fn for every budget, stopping at the first error
+func (r *BudgetRepository) FindEach(ctx context.Context, fn func(budget *models.EnergyBudget) error) error {
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var budget models.EnergyBudget
+		if err := cursor.Decode(&budget); err != nil {
+			return err
+		}
+		if err := fn(&budget); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// Update updates a budget's settings
+func (r *BudgetRepository) Update(ctx context.Context, id string, updates bson.M) (*models.EnergyBudget, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid budget ID format")
+	}
+
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"),
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var budget models.EnergyBudget
+	if err := result.Decode(&budget); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("budget not found")
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("budget already exists for this building or device")
+		}
+		return nil, err
+	}
+
+	return &budget, nil
+}
+
+// UpdateTracking stores the month-to-date state computed by the tracking worker
+func (r *BudgetRepository) UpdateTracking(ctx context.Context, id primitive.ObjectID, tracking models.BudgetTracking) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"tracking": tracking}})
+	return err
+}
+
+// Delete removes a budget
+func (r *BudgetRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid budget ID format")
+	}
+
+	result, err := r.collection.DeleteOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("budget not found")
+	}
+
+	return nil
+}
+
+// RemoveNotifyUser removes a user from the alert recipients of every budget, returning how many budgets changed
+func (r *BudgetRepository) RemoveNotifyUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"notify_user_ids": userID},
+		bson.M{
+			"$pull": bson.M{"notify_user_ids": userID},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
+)
+
+// maxComparisonRecords caps the shadow and live anomalies loaded to compare a configuration
+const maxComparisonRecords = 10000
+
+// DetectorConfigRepository handles anomaly detector configuration database operations
+type DetectorConfigRepository struct {
+	collection *mongo.Collection
+}
+
+// NewDetectorConfigRepository creates a new detector configuration repository
+func NewDetectorConfigRepository(collection *mongo.Collection) *DetectorConfigRepository {
+	return &DetectorConfigRepository{collection: collection}
+}
+
+// Create inserts a new detector configuration
+func (r *DetectorConfigRepository) Create(ctx context.Context, config *models.DetectorConfig) (*models.DetectorConfig, error) {
+	config.CreatedAt = time.Now()
+	config.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	config.ID = result.InsertedID.(primitive.ObjectID)
+	return config, nil
+}
+
+// FindByConfigID retrieves a detector configuration by its config_id field
+func (r *DetectorConfigRepository) FindByConfigID(ctx context.Context, configID string) (*models.DetectorConfig, error) {
+	var config models.DetectorConfig
+	err := r.collection.FindOne(ctx, bson.M{"config_id": configID}).Decode(&config)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("detector configuration not found")
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+// FindAll retrieves detector configurations, optionally of one detector or mode, newest first
+func (r *DetectorConfigRepository) FindAll(ctx context.Context, detector string, mode models.DetectorMode) ([]*models.DetectorConfig, error) {
+	filter := bson.M{}
+	if detector != "" {
+		filter["detector"] = detector
+	}
+	if mode != "" {
+		filter["mode"] = mode
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	configs := []*models.DetectorConfig{}
+	if err := cursor.All(ctx, &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// FindLive retrieves the live configuration of each detector that has one. Should a promotion
+// have been interrupted, the most recently promoted configuration wins.
+func (r *DetectorConfigRepository) FindLive(ctx context.Context) (map[string]*models.DetectorConfig, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"mode": models.DetectorModeLive},
+		options.Find().SetSort(bson.D{{Key: "promoted_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var configs []*models.DetectorConfig
+	if err := cursor.All(ctx, &configs); err != nil {
+		return nil, err
+	}
+
+	live := make(map[string]*models.DetectorConfig, len(configs))
+	for _, config := range configs {
+		live[config.Detector] = config
+	}
+	return live, nil
+}
+
+// Update sets fields of a detector configuration in the given mode and returns the updated document
+func (r *DetectorConfigRepository) Update(ctx context.Context, configID string, mode models.DetectorMode, updates bson.M) (*models.DetectorConfig, error) {
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"config_id": configID, "mode": mode},
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var config models.DetectorConfig
+	if err := result.Decode(&config); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("detector configuration not found")
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+// RetireLive retires the live configurations of a detector other than the given one
+func (r *DetectorConfigRepository) RetireLive(ctx context.Context, detector, keepConfigID string) error {
+	now := time.Now()
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"detector": detector, "mode": models.DetectorModeLive, "config_id": bson.M{"$ne": keepConfigID}},
+		bson.M{"$set": bson.M{"mode": models.DetectorModeRetired, "retired_at": now, "updated_at": now}},
+	)
+	return err
+}
+
+// Delete removes a detector configuration in the given mode
+func (r *DetectorConfigRepository) Delete(ctx context.Context, configID string, mode models.DetectorMode) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"config_id": configID, "mode": mode})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("detector configuration not found")
+	}
+	return nil
+}
+
+// ShadowAnomalyRepository handles the anomalies shadow detector configurations would have raised.
+// Requests scoped to an organization only see those of its buildings.
+type ShadowAnomalyRepository struct {
+	collection *mongo.Collection
+}
+
+// NewShadowAnomalyRepository creates a new shadow anomaly repository
+func NewShadowAnomalyRepository(collection *mongo.Collection) *ShadowAnomalyRepository {
+	return &ShadowAnomalyRepository{collection: collection}
+}
+
+// Create inserts a new shadow anomaly
+func (r *ShadowAnomalyRepository) Create(ctx context.Context, anomaly *models.ShadowAnomaly) error {
+	anomaly.CreatedAt = time.Now()
+	result, err := r.collection.InsertOne(ctx, anomaly)
+	if err != nil {
+		return err
+	}
+	anomaly.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindByConfig retrieves the shadow anomalies of a configuration with pagination, newest first
+func (r *ShadowAnomalyRepository) FindByConfig(ctx context.Context, configID string, page, limit int) ([]*models.ShadowAnomaly, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := tenant.BuildingFilter(ctx, bson.M{"config_id": configID}, "building_id")
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "detected_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	anomalies := []*models.ShadowAnomaly{}
+	if err := cursor.All(ctx, &anomalies); err != nil {
+		return nil, 0, err
+	}
+	return anomalies, total, nil
+}
+
+// FindByConfigInRange retrieves the shadow anomalies of a configuration detected within the range,
+// oldest first
+func (r *ShadowAnomalyRepository) FindByConfigInRange(ctx context.Context, configID string, from, to time.Time) ([]*models.ShadowAnomaly, error) {
+	filter := tenant.BuildingFilter(ctx, bson.M{
+		"config_id":   configID,
+		"detected_at": bson.M{"$gte": from, "$lte": to},
+	}, "building_id")
+
+	findOptions := options.Find().
+		SetLimit(maxComparisonRecords).
+		SetSort(bson.D{{Key: "detected_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var anomalies []*models.ShadowAnomaly
+	if err := cursor.All(ctx, &anomalies); err != nil {
+		return nil, err
+	}
+	return anomalies, nil
+}
+
+// ExistsForForecastRun checks whether a configuration already recorded a shadow anomaly for the
+// run of forecast intervals starting at runStart
+func (r *ShadowAnomalyRepository) ExistsForForecastRun(ctx context.Context, configID, buildingID, forecastID string, runStart time.Time) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"config_id":          configID,
+		"building_id":        buildingID,
+		"details.forecastId": forecastID,
+		"details.runStart":   runStart,
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteByConfig removes the shadow anomalies of a configuration
+func (r *ShadowAnomalyRepository) DeleteByConfig(ctx context.Context, configID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"config_id": configID})
+	return err
+}
+
+// CountOlderThan counts shadow anomalies created before the given time
+func (r *ShadowAnomalyRepository) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+}
+
+// DeleteOlderThan removes shadow anomalies created before the given time
+func (r *ShadowAnomalyRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"analytics-service/internal/models"
+)
+
+// DeviceActivityRepository handles device activity database operations
+type DeviceActivityRepository struct {
+	collection *mongo.Collection
+}
+
+// NewDeviceActivityRepository creates a new device activity repository
+func NewDeviceActivityRepository(collection *mongo.Collection) *DeviceActivityRepository {
+	return &DeviceActivityRepository{collection: collection}
+}
+
+// Record stores an activity once per event, so redelivered events are recorded once
+func (r *DeviceActivityRepository) Record(ctx context.Context, activity *models.DeviceActivity) error {
+	activity.CreatedAt = time.Now()
+
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"activity_id": activity.ActivityID},
+		bson.M{"$setOnInsert": activity},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// FindByBuildingInRange retrieves the activities of a building in [from, to], oldest first
+func (r *DeviceActivityRepository) FindByBuildingInRange(ctx context.Context, buildingID string, from, to time.Time) ([]*models.DeviceActivity, error) {
+	filter := bson.M{
+		"building_id": buildingID,
+		"occurred_at": bson.M{"$gte": from, "$lte": to},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "occurred_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var activities []*models.DeviceActivity
+	if err := cursor.All(ctx, &activities); err != nil {
+		return nil, err
+	}
+
+	return activities, nil
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
+)
+
+// KPIDefinitionRepository handles custom KPI definition database operations. Requests scoped
+// to an organization only see its definitions.
+type KPIDefinitionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewKPIDefinitionRepository creates a new KPI definition repository
+func NewKPIDefinitionRepository(collection *mongo.Collection) *KPIDefinitionRepository {
+	return &KPIDefinitionRepository{collection: collection}
+}
+
+// orgFilter restricts a filter to the definitions of the organization ctx is scoped to
+func orgFilter(ctx context.Context, filter bson.M) bson.M {
+	if scope := tenant.FromContext(ctx); scope != nil {
+		filter["org_id"] = scope.OrgID
+	}
+	return filter
+}
+
+// Create inserts a new KPI definition
+func (r *KPIDefinitionRepository) Create(ctx context.Context, definition *models.KPIDefinition) (*models.KPIDefinition, error) {
+	definition.CreatedAt = time.Now()
+	definition.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, definition)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("KPI definition already exists with this key")
+		}
+		return nil, err
+	}
+
+	definition.ID = result.InsertedID.(primitive.ObjectID)
+	return definition, nil
+}
+
+// FindByID retrieves a KPI definition by ID
+func (r *KPIDefinitionRepository) FindByID(ctx context.Context, id string) (*models.KPIDefinition, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid KPI definition ID format")
+	}
+
+	var definition models.KPIDefinition
+	err = r.collection.FindOne(ctx, orgFilter(ctx, bson.M{"_id": objectID})).Decode(&definition)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("KPI definition not found")
+		}
+		return nil, err
+	}
+
+	return &definition, nil
+}
+
+// FindByKey retrieves the enabled KPI definition with a key that applies to a building
+func (r *KPIDefinitionRepository) FindByKey(ctx context.Context, key, buildingID string) (*models.KPIDefinition, error) {
+	definitions, err := r.FindEnabled(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Organization definitions are listed last and take precedence over global ones
+	var found *models.KPIDefinition
+	for _, definition := range definitions {
+		if definition.Key == key && definition.AppliesTo(buildingID) {
+			found = definition
+		}
+	}
+	if found == nil {
+		return nil, errors.New("KPI definition not found")
+	}
+	return found, nil
+}
+
+// FindAll retrieves KPI definitions with pagination. A building ID lists the definitions
+// that apply to the building.
+func (r *KPIDefinitionRepository) FindAll(ctx context.Context, buildingID string, page, limit int) ([]*models.KPIDefinition, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	skip := int64((page - 1) * limit)
+	filter := bson.M{}
+	if buildingID != "" {
+		filter["$or"] = []bson.M{
+			{"building_ids": buildingID},
+			{"building_ids": bson.M{"$exists": false}},
+		}
+	}
+	filter = orgFilter(ctx, filter)
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(skip).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "key", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var definitions []*models.KPIDefinition
+	if err := cursor.All(ctx, &definitions); err != nil {
+		return nil, 0, err
+	}
+
+	return definitions, total, nil
+}
+
+// FindEnabled retrieves the enabled KPI definitions, global definitions first. Requests
+// scoped to an organization see the global definitions and their own.
+func (r *KPIDefinitionRepository) FindEnabled(ctx context.Context) ([]*models.KPIDefinition, error) {
+	filter := bson.M{"enabled": true}
+	if scope := tenant.FromContext(ctx); scope != nil {
+		filter["org_id"] = bson.M{"$in": []interface{}{nil, scope.OrgID}}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "org_id", Value: 1}, {Key: "key", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var definitions []*models.KPIDefinition
+	if err := cursor.All(ctx, &definitions); err != nil {
+		return nil, err
+	}
+
+	return definitions, nil
+}
+
+// Update updates a KPI definition
+func (r *KPIDefinitionRepository) Update(ctx context.Context, id string, updates bson.M) (*models.KPIDefinition, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid KPI definition ID format")
+	}
+
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		orgFilter(ctx, bson.M{"_id": objectID}),
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var definition models.KPIDefinition
+	if err := result.Decode(&definition); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("KPI definition not found")
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("KPI definition already exists with this key")
+		}
+		return nil, err
+	}
+
+	return &definition, nil
+}
+
+// Delete removes a KPI definition
+func (r *KPIDefinitionRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid KPI definition ID format")
+	}
+
+	result, err := r.collection.DeleteOne(ctx, orgFilter(ctx, bson.M{"_id": objectID}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("KPI definition not found")
+	}
+
+	return nil
+}
+
+// BuildingAttributesRepository handles building attribute database operations
+type BuildingAttributesRepository struct {
+	collection *mongo.Collection
+}
+
+// NewBuildingAttributesRepository creates a new building attributes repository
+func NewBuildingAttributesRepository(collection *mongo.Collection) *BuildingAttributesRepository {
+	return &BuildingAttributesRepository{collection: collection}
+}
+
+// FindByBuilding retrieves the attributes of a building
+func (r *BuildingAttributesRepository) FindByBuilding(ctx context.Context, buildingID string) (*models.BuildingAttributes, error) {
+	var attributes models.BuildingAttributes
+	err := r.collection.FindOne(ctx, bson.M{"building_id": buildingID}).Decode(&attributes)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("building attributes not found")
+		}
+		return nil, err
+	}
+
+	return &attributes, nil
+}
+
+// Upsert creates or replaces the attributes of a building
+func (r *BuildingAttributesRepository) Upsert(ctx context.Context, attributes *models.BuildingAttributes) (*models.BuildingAttributes, error) {
+	attributes.UpdatedAt = time.Now()
+
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"building_id": attributes.BuildingID}, attributes, options.Replace().SetUpsert(true))
+	if err != nil {
+		return nil, err
+	}
+
+	return attributes, nil
+}
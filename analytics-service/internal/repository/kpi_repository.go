@@ -11,16 +11,22 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
 )
 
-// KPIRepository handles KPI database operations
+// KPIRepository handles KPI database operations. Cross-building aggregations read through the
+// reporting collection, which may be served by secondaries.
 type KPIRepository struct {
 	collection *mongo.Collection
+	reporting  *mongo.Collection
 }
 
-// NewKPIRepository creates a new KPI repository
-func NewKPIRepository(collection *mongo.Collection) *KPIRepository {
-	return &KPIRepository{collection: collection}
+// NewKPIRepository creates a new KPI repository. A nil reporting collection reads from the primary collection.
+func NewKPIRepository(collection, reporting *mongo.Collection) *KPIRepository {
+	if reporting == nil {
+		reporting = collection
+	}
+	return &KPIRepository{collection: collection, reporting: reporting}
 }
 
 // Create inserts a new KPI record
@@ -75,6 +81,7 @@ func (r *KPIRepository) UpdateOrCreate(ctx context.Context, kpi *models.KPI) (*m
 		"$set": bson.M{
 			"calculated_at": kpi.CalculatedAt,
 			"metrics":       kpi.Metrics,
+			"targets":       kpi.Targets,
 			"updated_at":    time.Now(),
 		},
 		"$setOnInsert": bson.M{
@@ -91,3 +98,73 @@ func (r *KPIRepository) UpdateOrCreate(ctx context.Context, kpi *models.KPI) (*m
 	// Retrieve the updated/created document
 	return r.FindLatest(ctx, kpi.BuildingID, kpi.Period)
 }
+
+// MergeMetrics sets some metrics of a building's KPI for a period, keeping its other metrics,
+// and replaces the statuses of its targets. The KPI is created when there is none yet.
+func (r *KPIRepository) MergeMetrics(ctx context.Context, buildingID, period string, metrics map[string]interface{}, targets []models.KPITargetStatus) error {
+	set := bson.M{
+		"targets":    targets,
+		"updated_at": time.Now(),
+	}
+	for key, value := range metrics {
+		set["metrics."+key] = value
+	}
+
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"building_id": buildingID, "period": period},
+		bson.M{
+			"$set": set,
+			"$setOnInsert": bson.M{
+				"calculated_at": time.Now(),
+				"created_at":    time.Now(),
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// FindLatestPerBuilding retrieves the latest KPI metrics for each building in a single aggregation.
+// An empty buildingIDs slice includes every building.
+func (r *KPIRepository) FindLatestPerBuilding(ctx context.Context, period string, buildingIDs []string) (map[string]map[string]interface{}, error) {
+	match := bson.M{
+		"period":      period,
+		"building_id": bson.M{"$exists": true, "$ne": ""},
+	}
+	if len(buildingIDs) > 0 {
+		match["building_id"] = bson.M{"$in": buildingIDs}
+	}
+	match = tenant.BuildingFilter(ctx, match, "building_id")
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$sort": bson.M{"calculated_at": -1}},
+		{
+			"$group": bson.M{
+				"_id":     "$building_id",
+				"metrics": bson.M{"$first": "$metrics"},
+			},
+		},
+	}
+
+	cursor, err := r.reporting.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	result := make(map[string]map[string]interface{})
+	for cursor.Next(ctx) {
+		var doc struct {
+			BuildingID string                 `bson:"_id"`
+			Metrics    map[string]interface{} `bson:"metrics"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			continue
+		}
+		result[doc.BuildingID] = doc.Metrics
+	}
+
+	return result, cursor.Err()
+}
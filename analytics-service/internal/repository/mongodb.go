@@ -5,28 +5,58 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/description"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 
 	"analytics-service/internal/config"
+	"analytics-service/internal/models"
+	"analytics-service/internal/tracing"
+	"shared/jobs"
 )
 
-// MongoDB holds the database connection and collections
+// MongoDB holds the database connection and collections.
+// Reporting is the database handle aggregations read through; it uses the reporting read
+// preference, and the separate reporting client when one is configured.
 type MongoDB struct {
-	Client   *mongo.Client
-	Database *mongo.Database
-	config   *config.Config
+	Client          *mongo.Client
+	Database        *mongo.Database
+	Reporting       *mongo.Database
+	reportingClient *mongo.Client
+	config          *config.Config
+	monitor         *topologyMonitor
+	indexesReady    bool
 }
 
 // Collections holds references to all MongoDB collections
 type Collections struct {
-	Reports    *mongo.Collection
-	Anomalies  *mongo.Collection
-	TimeSeries *mongo.Collection
-	KPIs       *mongo.Collection
+	Reports                *mongo.Collection
+	Anomalies              *mongo.Collection
+	TimeSeries             *mongo.Collection
+	KPIs                   *mongo.Collection
+	OptimizationExecutions *mongo.Collection
+	Budgets                *mongo.Collection
+	TrendAlerts            *mongo.Collection
+	Jobs                   *mongo.Collection
+	Settings               *mongo.Collection
+	SettingsHistory        *mongo.Collection
+	MVReports              *mongo.Collection
+	DeviceActivities       *mongo.Collection
+	KPIDefinitions         *mongo.Collection
+	BuildingAttributes     *mongo.Collection
+	DetectorConfigs        *mongo.Collection
+	ShadowAnomalies        *mongo.Collection
+	SavingsGoals           *mongo.Collection
 }
 
 // NewMongoDB creates a new MongoDB connection
@@ -34,12 +64,38 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoDB.Timeout)
 	defer cancel()
 
+	monitor := &topologyMonitor{}
+
 	// Create client options
 	clientOptions := options.Client().
 		ApplyURI(cfg.MongoDB.URI).
-		SetMaxPoolSize(100).
-		SetMinPoolSize(10).
-		SetMaxConnIdleTime(30 * time.Second)
+		SetMaxPoolSize(cfg.MongoDB.MaxPoolSize).
+		SetMinPoolSize(cfg.MongoDB.MinPoolSize).
+		SetMaxConnIdleTime(30 * time.Second).
+		SetServerSelectionTimeout(cfg.MongoDB.ServerSelectionTimeout).
+		SetRetryWrites(cfg.MongoDB.RetryWrites).
+		SetServerMonitor(&event.ServerMonitor{
+			TopologyDescriptionChanged: monitor.topologyChanged,
+		}).
+		SetMonitor(tracing.NewMongoMonitor())
+
+	if cfg.MongoDB.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(cfg.MongoDB.ReplicaSet)
+	}
+	if cfg.MongoDB.ReadPreference != "" {
+		readPref, err := parseReadPreference(cfg.MongoDB.ReadPreference)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetReadPreference(readPref)
+	}
+	if cfg.MongoDB.WriteConcern != "" {
+		writeConcern, err := parseWriteConcern(cfg.MongoDB.WriteConcern)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetWriteConcern(writeConcern)
+	}
 
 	// Connect to MongoDB
 	client, err := mongo.Connect(ctx, clientOptions)
@@ -54,25 +110,222 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 
 	log.Printf("Connected to MongoDB: %s", cfg.MongoDB.Database)
 
-	return &MongoDB{
+	db := &MongoDB{
 		Client:   client,
 		Database: client.Database(cfg.MongoDB.Database),
 		config:   cfg,
-	}, nil
+		monitor:  monitor,
+	}
+	if err := db.connectReporting(ctx); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	return db, nil
+}
+
+// connectReporting sets up the database handle for reporting reads: a separate client when a
+// reporting URI is configured, otherwise the main client with the reporting read preference.
+// The reporting client is not pinged on startup, so reporting falls back to errors on its own
+// queries rather than keeping the service from starting.
+func (m *MongoDB) connectReporting(ctx context.Context) error {
+	cfg := m.config.MongoDB
+	readPref, err := reportingReadPreference(cfg.ReportingReadPreference, cfg.ReportingMaxStaleness)
+	if err != nil {
+		return err
+	}
+
+	if cfg.ReportingURI == "" {
+		m.Reporting = m.Client.Database(cfg.Database, options.Database().SetReadPreference(readPref))
+		return nil
+	}
+
+	clientOptions := options.Client().
+		ApplyURI(cfg.ReportingURI).
+		SetMaxPoolSize(cfg.ReportingMaxPoolSize).
+		SetMaxConnIdleTime(30 * time.Second).
+		SetServerSelectionTimeout(cfg.ServerSelectionTimeout).
+		SetReadPreference(readPref).
+		SetMonitor(tracing.NewMongoMonitor())
+	if cfg.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(cfg.ReplicaSet)
+	}
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return fmt.Errorf("failed to connect reporting MongoDB client: %w", err)
+	}
+	log.Printf("Connected reporting MongoDB client with read preference %s", readPref.Mode())
+
+	m.reportingClient = client
+	m.Reporting = client.Database(cfg.Database)
+	return nil
+}
+
+// parseReadPreference parses a read preference mode such as "primary" or "secondaryPreferred"
+func parseReadPreference(value string) (*readpref.ReadPref, error) {
+	mode, err := readpref.ModeFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MongoDB read preference %q: %w", value, err)
+	}
+	return readpref.New(mode)
+}
+
+// reportingReadPreference parses the reporting read preference mode with an optional bound on
+// how far behind the primary a secondary may be to serve reporting reads
+func reportingReadPreference(value string, maxStaleness time.Duration) (*readpref.ReadPref, error) {
+	mode, err := readpref.ModeFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MongoDB reporting read preference %q: %w", value, err)
+	}
+	if maxStaleness <= 0 {
+		return readpref.New(mode)
+	}
+	if mode == readpref.PrimaryMode {
+		return nil, fmt.Errorf("MongoDB reporting max staleness cannot be used with read preference primary")
+	}
+	if maxStaleness < 90*time.Second {
+		return nil, fmt.Errorf("MongoDB reporting max staleness must be at least 90 seconds")
+	}
+	return readpref.New(mode, readpref.WithMaxStaleness(maxStaleness))
+}
+
+// parseWriteConcern accepts "majority" or the number of members that must acknowledge a write
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	if strings.EqualFold(value, "majority") {
+		return writeconcern.Majority(), nil
+	}
+	w, err := strconv.Atoi(value)
+	if err != nil || w < 0 {
+		return nil, fmt.Errorf("invalid MongoDB write concern %q: must be \"majority\" or a number", value)
+	}
+	return &writeconcern.WriteConcern{W: w}, nil
+}
+
+// topologyMonitor follows driver topology events to track the primary and any replica set
+// members that have become unreachable
+type topologyMonitor struct {
+	mu          sync.Mutex
+	primary     string
+	unavailable []string
+}
+
+// topologyChanged is called by the driver with the topology locked, so it must not run operations
+func (t *topologyMonitor) topologyChanged(e *event.TopologyDescriptionChangedEvent) {
+	var primary string
+	var unavailable []string
+	for _, server := range e.NewDescription.Servers {
+		switch {
+		case server.Kind == description.RSPrimary:
+			primary = server.Addr.String()
+		case server.Kind == description.RSGhost,
+			server.Kind == description.Unknown && server.LastError != nil:
+			// Members start as Unknown until their first heartbeat; only failed ones count
+			unavailable = append(unavailable, server.Addr.String())
+		}
+	}
+	sort.Strings(unavailable)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if primary != t.primary {
+		if primary == "" {
+			log.Printf("MongoDB primary %s is no longer available", t.primary)
+		} else {
+			log.Printf("MongoDB primary is now %s", primary)
+		}
+	}
+	if strings.Join(unavailable, ",") != strings.Join(t.unavailable, ",") {
+		if len(unavailable) > 0 {
+			log.Printf("MongoDB members unreachable: %s", strings.Join(unavailable, ", "))
+		} else {
+			log.Printf("All MongoDB members reachable")
+		}
+	}
+
+	t.primary = primary
+	t.unavailable = unavailable
+}
+
+// status reports unreachable members as a degraded state
+func (t *topologyMonitor) status() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.unavailable) == 0 {
+		return nil
+	}
+	return &models.DegradedError{
+		Reason: fmt.Sprintf("MongoDB members unreachable: %s", strings.Join(t.unavailable, ", ")),
+	}
 }
 
 // GetCollections returns all collection references
 func (m *MongoDB) GetCollections() *Collections {
+	return collectionsOf(m.Database)
+}
+
+// GetReportingCollections returns the collection references reporting aggregations read through.
+// Reads may be served by secondaries and lag behind recent writes.
+func (m *MongoDB) GetReportingCollections() *Collections {
+	return collectionsOf(m.Reporting)
+}
+
+// QueryHints reports whether aggregations should hint the time-range indexes. Hints are only
+// used once the indexes were created, since a hint on a missing index fails the query.
+func (m *MongoDB) QueryHints() bool {
+	return m.config.MongoDB.QueryHints && m.indexesReady
+}
+
+// collectionsOf returns the collection references of a database handle
+func collectionsOf(database *mongo.Database) *Collections {
 	return &Collections{
-		Reports:    m.Database.Collection("reports"),
-		Anomalies:  m.Database.Collection("anomalies"),
-		TimeSeries: m.Database.Collection("time_series"),
-		KPIs:       m.Database.Collection("kpis"),
+		Reports:                database.Collection("reports"),
+		Anomalies:              database.Collection("anomalies"),
+		TimeSeries:             database.Collection("time_series"),
+		KPIs:                   database.Collection("kpis"),
+		OptimizationExecutions: database.Collection("optimization_executions"),
+		Budgets:                database.Collection("energy_budgets"),
+		TrendAlerts:            database.Collection("kpi_trend_alerts"),
+		Jobs:                   database.Collection("jobs"),
+		Settings:               database.Collection("settings"),
+		SettingsHistory:        database.Collection("settings_history"),
+		MVReports:              database.Collection("mv_reports"),
+		DeviceActivities:       database.Collection("device_activities"),
+		KPIDefinitions:         database.Collection("kpi_definitions"),
+		BuildingAttributes:     database.Collection("building_attributes"),
+		DetectorConfigs:        database.Collection("detector_configs"),
+		ShadowAnomalies:        database.Collection("shadow_anomalies"),
+		SavingsGoals:           database.Collection("savings_goals"),
 	}
 }
 
-// Close closes the MongoDB connection
+// Ping verifies the MongoDB primary is reachable
+func (m *MongoDB) Ping(ctx context.Context) error {
+	return m.Client.Ping(ctx, readpref.Primary())
+}
+
+// HealthCheck verifies the MongoDB primary is reachable and reports the connection as degraded
+// while other replica set members or the reporting client's servers are unreachable
+func (m *MongoDB) HealthCheck(ctx context.Context) error {
+	if err := m.Ping(ctx); err != nil {
+		return err
+	}
+	if m.reportingClient != nil {
+		if err := m.reportingClient.Ping(ctx, nil); err != nil {
+			return &models.DegradedError{Reason: fmt.Sprintf("reporting MongoDB unreachable: %v", err)}
+		}
+	}
+	return m.monitor.status()
+}
+
+// Close closes the MongoDB connections
 func (m *MongoDB) Close(ctx context.Context) error {
+	if m.reportingClient != nil {
+		if err := m.reportingClient.Disconnect(ctx); err != nil {
+			log.Printf("Failed to disconnect reporting MongoDB client: %v", err)
+		}
+	}
 	if err := m.Client.Disconnect(ctx); err != nil {
 		return fmt.Errorf("failed to disconnect from MongoDB: %w", err)
 	}
@@ -96,6 +349,10 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: map[string]interface{}{"type": 1, "status": 1},
 		},
+		{
+			Keys:    map[string]interface{}{"report_id": "text", "type": "text", "building_id": "text"},
+			Options: options.Index().SetName("report_search"),
+		},
 		{
 			Keys:    map[string]interface{}{"generated_at": 1},
 			Options: options.Index().SetExpireAfterSeconds(7776000), // 90 days TTL
@@ -120,6 +377,9 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: map[string]interface{}{"status": 1, "severity": 1},
 		},
+		{
+			Keys: bson.D{{Key: "type", Value: 1}, {Key: "detected_at", Value: 1}},
+		},
 	}
 	if _, err := collections.Anomalies.Indexes().CreateMany(ctx, anomalyIndexes); err != nil {
 		return fmt.Errorf("failed to create anomaly indexes: %w", err)
@@ -136,6 +396,16 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: map[string]interface{}{"aggregation_type": 1, "timestamp": -1},
 		},
+		// Time-range aggregations match on an aggregation type and buildings, optionally devices,
+		// then scan a timestamp range; equality fields lead so the range is scanned per building
+		{
+			Keys:    bson.D{{Key: "aggregation_type", Value: 1}, {Key: "building_id", Value: 1}, {Key: "timestamp", Value: 1}},
+			Options: options.Index().SetName(timeRangeBuildingIndex),
+		},
+		{
+			Keys:    bson.D{{Key: "aggregation_type", Value: 1}, {Key: "building_id", Value: 1}, {Key: "device_id", Value: 1}, {Key: "timestamp", Value: 1}},
+			Options: options.Index().SetName(timeRangeDeviceIndex),
+		},
 		{
 			Keys:    map[string]interface{}{"timestamp": 1},
 			Options: options.Index().SetExpireAfterSeconds(31536000), // 1 year TTL
@@ -158,6 +428,165 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		return fmt.Errorf("failed to create KPI indexes: %w", err)
 	}
 
+	// Optimization executions collection indexes
+	executionIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"scenario_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "building_id", Value: 1}, {Key: "completed_at", Value: -1}},
+		},
+	}
+	if _, err := collections.OptimizationExecutions.Indexes().CreateMany(ctx, executionIndexes); err != nil {
+		return fmt.Errorf("failed to create optimization execution indexes: %w", err)
+	}
+
+	// Device activity collection indexes: kept for 30 days to explain anomalies
+	activityIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "building_id", Value: 1}, {Key: "occurred_at", Value: -1}},
+		},
+		{
+			Keys:    map[string]interface{}{"activity_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    map[string]interface{}{"occurred_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(2592000), // 30 days TTL
+		},
+	}
+	if _, err := collections.DeviceActivities.Indexes().CreateMany(ctx, activityIndexes); err != nil {
+		return fmt.Errorf("failed to create device activity indexes: %w", err)
+	}
+
+	// Energy budgets collection indexes: one budget per building and per device
+	budgetIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "building_id", Value: 1}, {Key: "device_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := collections.Budgets.Indexes().CreateMany(ctx, budgetIndexes); err != nil {
+		return fmt.Errorf("failed to create budget indexes: %w", err)
+	}
+
+	// KPI trend alerts collection indexes: one alert per building, metric and week
+	trendAlertIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "building_id", Value: 1}, {Key: "metric", Value: 1}, {Key: "week_start", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "building_id", Value: 1}, {Key: "week_start", Value: -1}},
+		},
+	}
+	if _, err := collections.TrendAlerts.Indexes().CreateMany(ctx, trendAlertIndexes); err != nil {
+		return fmt.Errorf("failed to create trend alert indexes: %w", err)
+	}
+
+	// Custom KPI definitions collection indexes: keys are unique within an organization
+	kpiDefinitionIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "org_id", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := collections.KPIDefinitions.Indexes().CreateMany(ctx, kpiDefinitionIndexes); err != nil {
+		return fmt.Errorf("failed to create KPI definition indexes: %w", err)
+	}
+
+	// Building attributes collection indexes: one document per building
+	buildingAttributesIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "building_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := collections.BuildingAttributes.Indexes().CreateMany(ctx, buildingAttributesIndexes); err != nil {
+		return fmt.Errorf("failed to create building attributes indexes: %w", err)
+	}
+
+	// Detector configuration collection indexes
+	detectorConfigIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "config_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "detector", Value: 1}, {Key: "mode", Value: 1}},
+		},
+	}
+	if _, err := collections.DetectorConfigs.Indexes().CreateMany(ctx, detectorConfigIndexes); err != nil {
+		return fmt.Errorf("failed to create detector configuration indexes: %w", err)
+	}
+
+	// Shadow anomalies collection indexes
+	shadowAnomalyIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "config_id", Value: 1}, {Key: "detected_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: 1}},
+		},
+	}
+	if _, err := collections.ShadowAnomalies.Indexes().CreateMany(ctx, shadowAnomalyIndexes); err != nil {
+		return fmt.Errorf("failed to create shadow anomaly indexes: %w", err)
+	}
+
+	// Savings goals collection indexes: listed per building and year, tracked until final
+	savingsGoalIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "building_id", Value: 1}, {Key: "year", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "year", Value: 1}, {Key: "tracking.final", Value: 1}},
+		},
+	}
+	if _, err := collections.SavingsGoals.Indexes().CreateMany(ctx, savingsGoalIndexes); err != nil {
+		return fmt.Errorf("failed to create savings goal indexes: %w", err)
+	}
+
+	// Job queue indexes: workers claim by type, status and run time; finished jobs expire
+	jobIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "type", Value: 1}, {Key: "status", Value: 1}, {Key: "run_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "finished_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(jobs.Retention.Seconds())),
+		},
+	}
+	if _, err := collections.Jobs.Indexes().CreateMany(ctx, jobIndexes); err != nil {
+		return fmt.Errorf("failed to create job indexes: %w", err)
+	}
+
+	// Settings history indexes: changes are listed newest first, overall and per setting
+	settingsHistoryIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "changed_at", Value: -1}}},
+		{Keys: bson.D{{Key: "changed_at", Value: -1}}},
+	}
+	if _, err := collections.SettingsHistory.Indexes().CreateMany(ctx, settingsHistoryIndexes); err != nil {
+		return fmt.Errorf("failed to create settings history indexes: %w", err)
+	}
+
+	// M&V report indexes
+	mvReportIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "building_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "scenario_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+	if _, err := collections.MVReports.Indexes().CreateMany(ctx, mvReportIndexes); err != nil {
+		return fmt.Errorf("failed to create M&V report indexes: %w", err)
+	}
+
+	m.indexesReady = true
 	log.Println("MongoDB indexes created successfully")
 	return nil
 }
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
+)
+
+// MVReportRepository handles measurement and verification report database operations.
+// Reports are never changed once created, so they remain an audit trail of verified savings.
+type MVReportRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMVReportRepository creates a new M&V report repository
+func NewMVReportRepository(collection *mongo.Collection) *MVReportRepository {
+	return &MVReportRepository{collection: collection}
+}
+
+// Create inserts a new M&V report
+func (r *MVReportRepository) Create(ctx context.Context, report *models.MVReport) (*models.MVReport, error) {
+	report.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, report)
+	if err != nil {
+		return nil, err
+	}
+
+	report.ID = result.InsertedID.(primitive.ObjectID)
+	return report, nil
+}
+
+// FindByID retrieves an M&V report by ID
+func (r *MVReportRepository) FindByID(ctx context.Context, id string) (*models.MVReport, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid M&V report ID format")
+	}
+
+	var report models.MVReport
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id")).Decode(&report)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("M&V report not found")
+		}
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// FindAll retrieves M&V reports with filters and pagination, newest first
+func (r *MVReportRepository) FindAll(ctx context.Context, buildingID, scenarioID string, page, limit int) ([]*models.MVReport, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	skip := int64((page - 1) * limit)
+	filter := bson.M{}
+
+	if buildingID != "" {
+		filter["building_id"] = buildingID
+	}
+	if scenarioID != "" {
+		filter["scenario_id"] = scenarioID
+	}
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(skip).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var reports []*models.MVReport
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, 0, err
+	}
+
+	return reports, total, nil
+}
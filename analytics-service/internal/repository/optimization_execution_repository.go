@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
+)
+
+// OptimizationExecutionRepository handles optimization execution database operations
+type OptimizationExecutionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewOptimizationExecutionRepository creates a new optimization execution repository
+func NewOptimizationExecutionRepository(collection *mongo.Collection) *OptimizationExecutionRepository {
+	return &OptimizationExecutionRepository{collection: collection}
+}
+
+// Upsert stores an execution, replacing an earlier record of the same scenario
+// so redelivered events are recorded once
+func (r *OptimizationExecutionRepository) Upsert(ctx context.Context, execution *models.OptimizationExecution) error {
+	execution.CreatedAt = time.Now()
+
+	_, err := r.collection.ReplaceOne(
+		ctx,
+		bson.M{"scenario_id": execution.ScenarioID},
+		execution,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// FindByScenarioID retrieves the execution of a scenario
+func (r *OptimizationExecutionRepository) FindByScenarioID(ctx context.Context, scenarioID string) (*models.OptimizationExecution, error) {
+	var execution models.OptimizationExecution
+	err := r.collection.FindOne(ctx, bson.M{"scenario_id": scenarioID}).Decode(&execution)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("optimization execution not found")
+		}
+		return nil, err
+	}
+
+	return &execution, nil
+}
+
+// FindRecentByBuilding retrieves the latest executions for a building
+func (r *OptimizationExecutionRepository) FindRecentByBuilding(ctx context.Context, buildingID string, limit int) ([]*models.OptimizationExecution, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "completed_at", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"building_id": buildingID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var executions []*models.OptimizationExecution
+	if err := cursor.All(ctx, &executions); err != nil {
+		return nil, err
+	}
+
+	return executions, nil
+}
+
+// SumVerifiedSavingsByBuilding totals per building the savings verified by the latest M&V report
+// of each execution completed in the range, in a single aggregation. Executions without a report
+// add no savings. An empty buildingIDs slice includes every building.
+func (r *OptimizationExecutionRepository) SumVerifiedSavingsByBuilding(ctx context.Context, from, to time.Time, buildingIDs []string) ([]*models.BuildingSavings, error) {
+	match := bson.M{
+		"completed_at": bson.M{"$gte": from, "$lte": to},
+		"building_id":  bson.M{"$exists": true, "$ne": ""},
+	}
+	if len(buildingIDs) > 0 {
+		match["building_id"] = bson.M{"$in": buildingIDs}
+	}
+	match = tenant.BuildingFilter(ctx, match, "building_id")
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{
+			"$lookup": bson.M{
+				"from": "mv_reports",
+				"let":  bson.M{"scenarioId": "$scenario_id"},
+				"pipeline": []bson.M{
+					{"$match": bson.M{"$expr": bson.M{"$eq": []interface{}{"$scenario_id", "$$scenarioId"}}}},
+					{"$sort": bson.M{"created_at": -1}},
+					{"$limit": 1},
+				},
+				"as": "report",
+			},
+		},
+		{"$unwind": "$report"},
+		{
+			"$group": bson.M{
+				"_id":         "$building_id",
+				"savings_kwh": bson.M{"$sum": "$report.savings.savings_kwh"},
+			},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var savings []*models.BuildingSavings
+	if err := cursor.All(ctx, &savings); err != nil {
+		return nil, err
+	}
+
+	return savings, nil
+}
+
+// FindByBuildingInRange retrieves executions of a building that were running at any time in the range
+func (r *OptimizationExecutionRepository) FindByBuildingInRange(ctx context.Context, buildingID string, from, to time.Time) ([]*models.OptimizationExecution, error) {
+	filter := bson.M{
+		"building_id":  buildingID,
+		"completed_at": bson.M{"$gte": from},
+		// Executions recorded before start times were published only have a completion time
+		"$or": []bson.M{
+			{"started_at": bson.M{"$lte": to}},
+			{"completed_at": bson.M{"$lte": to}},
+		},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "completed_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var executions []*models.OptimizationExecution
+	if err := cursor.All(ctx, &executions); err != nil {
+		return nil, err
+	}
+
+	return executions, nil
+}
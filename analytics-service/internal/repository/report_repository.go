@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
 )
 
 // ReportRepository handles report database operations
@@ -48,7 +49,7 @@ func (r *ReportRepository) FindByID(ctx context.Context, id string) (*models.Rep
 	}
 
 	var report models.Report
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&report)
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id")).Decode(&report)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("report not found")
@@ -62,7 +63,7 @@ func (r *ReportRepository) FindByID(ctx context.Context, id string) (*models.Rep
 // FindByReportID retrieves a report by its report_id field
 func (r *ReportRepository) FindByReportID(ctx context.Context, reportID string) (*models.Report, error) {
 	var report models.Report
-	err := r.collection.FindOne(ctx, bson.M{"report_id": reportID}).Decode(&report)
+	err := r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"report_id": reportID}, "building_id")).Decode(&report)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("report not found")
@@ -93,6 +94,7 @@ func (r *ReportRepository) FindAll(ctx context.Context, buildingID, reportType,
 	if status != "" {
 		filter["status"] = status
 	}
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
 
 	// Get total count
 	total, err := r.collection.CountDocuments(ctx, filter)
@@ -120,6 +122,36 @@ func (r *ReportRepository) FindAll(ctx context.Context, buildingID, reportType,
 	return reports, total, nil
 }
 
+// Search retrieves up to limit reports matching the search, most relevant first.
+// A nil cursor starts from the best match.
+func (r *ReportRepository) Search(ctx context.Context, req *models.SearchReportsRequest, after *models.SearchCursor, limit int) ([]*models.ReportSearchHit, error) {
+	filter := bson.M{}
+	if req.BuildingID != "" {
+		filter["building_id"] = req.BuildingID
+	}
+	if req.Type != "" {
+		filter["type"] = req.Type
+	}
+	if req.Status != "" {
+		filter["status"] = req.Status
+	}
+
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
+
+	cursor, err := r.collection.Aggregate(ctx, searchPipeline(req.Query, filter, after, limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hits []*models.ReportSearchHit
+	if err := cursor.All(ctx, &hits); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
 // Update updates a report
 func (r *ReportRepository) Update(ctx context.Context, id string, updates bson.M) (*models.Report, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -131,7 +163,7 @@ func (r *ReportRepository) Update(ctx context.Context, id string, updates bson.M
 
 	result := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"_id": objectID},
+		tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"),
 		bson.M{"$set": updates},
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	)
@@ -146,3 +178,23 @@ func (r *ReportRepository) Update(ctx context.Context, id string, updates bson.M
 
 	return &report, nil
 }
+
+// CountOlderThan counts reports created before the given time
+func (r *ReportRepository) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+}
+
+// DeleteOlderThan removes reports created before the given time
+func (r *ReportRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
+)
+
+// SavingsGoalRepository handles savings goal database operations
+type SavingsGoalRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSavingsGoalRepository creates a new savings goal repository
+func NewSavingsGoalRepository(collection *mongo.Collection) *SavingsGoalRepository {
+	return &SavingsGoalRepository{collection: collection}
+}
+
+// Create inserts a new savings goal
+func (r *SavingsGoalRepository) Create(ctx context.Context, goal *models.SavingsGoal) (*models.SavingsGoal, error) {
+	goal.CreatedAt = time.Now()
+	goal.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, goal)
+	if err != nil {
+		return nil, err
+	}
+
+	goal.ID = result.InsertedID.(primitive.ObjectID)
+	return goal, nil
+}
+
+// FindByID retrieves a savings goal by ID
+func (r *SavingsGoalRepository) FindByID(ctx context.Context, id string) (*models.SavingsGoal, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid savings goal ID format")
+	}
+
+	var goal models.SavingsGoal
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id")).Decode(&goal)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("savings goal not found")
+		}
+		return nil, err
+	}
+
+	return &goal, nil
+}
+
+// FindAll retrieves savings goals with filters and pagination, latest year first
+func (r *SavingsGoalRepository) FindAll(ctx context.Context, buildingID string, year, page, limit int) ([]*models.SavingsGoal, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	skip := int64((page - 1) * limit)
+	filter := bson.M{}
+
+	if buildingID != "" {
+		filter["building_id"] = buildingID
+	}
+	if year != 0 {
+		filter["year"] = year
+	}
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(skip).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "year", Value: -1}, {Key: "building_id", Value: 1}, {Key: "created_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var goals []*models.SavingsGoal
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, 0, err
+	}
+
+	return goals, total, nil
+}
+
+// FindByBuildingAndYear retrieves the savings goals of a building for a year, oldest first
+func (r *SavingsGoalRepository) FindByBuildingAndYear(ctx context.Context, buildingID string, year int) ([]*models.SavingsGoal, error) {
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"building_id": buildingID, "year": year},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	goals := []*models.SavingsGoal{}
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+// FindEachActive calls fn for every savings goal whose year has begun by the given year and whose
+// tracking is not final, stopping at the first error
+func (r *SavingsGoalRepository) FindEachActive(ctx context.Context, year int, fn func(goal *models.SavingsGoal) error) error {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"year":           bson.M{"$lte": year},
+		"tracking.final": bson.M{"$ne": true},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var goal models.SavingsGoal
+		if err := cursor.Decode(&goal); err != nil {
+			return err
+		}
+		if err := fn(&goal); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// Update updates a savings goal's settings
+func (r *SavingsGoalRepository) Update(ctx context.Context, id string, updates bson.M) (*models.SavingsGoal, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid savings goal ID format")
+	}
+
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"),
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var goal models.SavingsGoal
+	if err := result.Decode(&goal); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("savings goal not found")
+		}
+		return nil, err
+	}
+
+	return &goal, nil
+}
+
+// UpdateTracking stores the progress computed by the tracking worker
+func (r *SavingsGoalRepository) UpdateTracking(ctx context.Context, id primitive.ObjectID, tracking models.SavingsGoalTracking) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"tracking": tracking}})
+	return err
+}
+
+// Delete removes a savings goal
+func (r *SavingsGoalRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid savings goal ID format")
+	}
+
+	result, err := r.collection.DeleteOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("savings goal not found")
+	}
+
+	return nil
+}
+
+// RemoveNotifyUser removes a user from the milestone recipients of every savings goal, returning
+// how many goals changed
+func (r *SavingsGoalRepository) RemoveNotifyUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"notify_user_ids": userID},
+		bson.M{
+			"$pull": bson.M{"notify_user_ids": userID},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
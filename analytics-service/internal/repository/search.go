@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"analytics-service/internal/models"
+)
+
+// searchPipeline builds an aggregation that returns up to limit documents matching the filter
+// and, when query is set, the collection's text index. Matches are ordered by text relevance
+// (stored in search_score), then newest first, and start after the cursor when one is given.
+func searchPipeline(query string, filter bson.M, after *models.SearchCursor, limit int) mongo.Pipeline {
+	match := bson.M{}
+	for key, value := range filter {
+		match[key] = value
+	}
+	if query != "" {
+		match["$text"] = bson.M{"$search": query}
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+	sort := bson.D{{Key: "_id", Value: -1}}
+	if query != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"search_score": bson.M{"$meta": "textScore"}}}})
+		sort = bson.D{{Key: "search_score", Value: -1}, {Key: "_id", Value: -1}}
+	}
+
+	if after != nil {
+		keyset := bson.M{"_id": bson.M{"$lt": after.ID}}
+		if query != "" {
+			keyset = bson.M{"$or": []bson.M{
+				{"search_score": bson.M{"$lt": after.Score}},
+				{"search_score": after.Score, "_id": bson.M{"$lt": after.ID}},
+			}}
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: keyset}})
+	}
+
+	return append(pipeline,
+		bson.D{{Key: "$sort", Value: sort}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+}
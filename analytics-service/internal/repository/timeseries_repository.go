@@ -11,16 +11,40 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
 )
 
-// TimeSeriesRepository handles time-series database operations
+// Names of the time_series indexes tuned for time-range aggregations
+const (
+	timeRangeBuildingIndex = "time_range_building"
+	timeRangeDeviceIndex   = "time_range_device"
+)
+
+// TimeSeriesRepository handles time-series database operations. Queries and aggregations read
+// through the reporting collection, which may be served by secondaries.
 type TimeSeriesRepository struct {
 	collection *mongo.Collection
+	reporting  *mongo.Collection
+	queryHints bool
 }
 
-// NewTimeSeriesRepository creates a new time-series repository
-func NewTimeSeriesRepository(collection *mongo.Collection) *TimeSeriesRepository {
-	return &TimeSeriesRepository{collection: collection}
+// NewTimeSeriesRepository creates a new time-series repository. A nil reporting collection reads
+// from the primary collection; queryHints makes aggregations hint the time-range indexes.
+func NewTimeSeriesRepository(collection, reporting *mongo.Collection, queryHints bool) *TimeSeriesRepository {
+	if reporting == nil {
+		reporting = collection
+	}
+	return &TimeSeriesRepository{collection: collection, reporting: reporting, queryHints: queryHints}
+}
+
+// aggregate runs a time-range aggregation on the reporting collection, hinting the given index
+// when query hints are enabled
+func (r *TimeSeriesRepository) aggregate(ctx context.Context, pipeline []bson.M, index string) (*mongo.Cursor, error) {
+	opts := options.Aggregate()
+	if r.queryHints && index != "" {
+		opts.SetHint(index)
+	}
+	return r.reporting.Aggregate(ctx, pipeline, opts)
 }
 
 // Create inserts a new time-series record
@@ -69,11 +93,12 @@ func (r *TimeSeriesRepository) Query(ctx context.Context, req *models.TimeSeries
 	if req.BuildingID != "" {
 		filter["building_id"] = req.BuildingID
 	}
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
 
 	findOptions := options.Find().
 		SetSort(bson.D{{Key: "timestamp", Value: 1}})
 
-	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	cursor, err := r.reporting.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -89,7 +114,7 @@ func (r *TimeSeriesRepository) Query(ctx context.Context, req *models.TimeSeries
 
 // Aggregate performs MongoDB aggregation pipeline for time-series data
 func (r *TimeSeriesRepository) Aggregate(ctx context.Context, pipeline []bson.M) ([]bson.M, error) {
-	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	cursor, err := r.aggregate(ctx, pipeline, "")
 	if err != nil {
 		return nil, err
 	}
@@ -119,3 +144,369 @@ func (r *TimeSeriesRepository) FindLatestByDevice(ctx context.Context, deviceID
 
 	return &ts, nil
 }
+
+// SumConsumptionByBuilding totals actual consumption per building
+// from daily aggregates in a single aggregation. An empty buildingIDs slice includes every building.
+func (r *TimeSeriesRepository) SumConsumptionByBuilding(ctx context.Context, from, to time.Time, buildingIDs []string) ([]*models.BuildingConsumption, error) {
+	match := bson.M{
+		"timestamp": bson.M{
+			"$gte": from,
+			"$lte": to,
+		},
+		"aggregation_type": models.AggregationTypeDaily,
+		"building_id":      bson.M{"$exists": true, "$ne": ""},
+	}
+	if len(buildingIDs) > 0 {
+		match["building_id"] = bson.M{"$in": buildingIDs}
+	}
+	match = tenant.BuildingFilter(ctx, match, "building_id")
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{
+			"$group": bson.M{
+				"_id":    "$building_id",
+				"actual": bson.M{"$sum": bson.M{"$ifNull": []interface{}{"$metrics.consumption", 0}}},
+			},
+		},
+	}
+
+	cursor, err := r.aggregate(ctx, pipeline, timeRangeBuildingIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []*models.BuildingConsumption
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// SumConsumption totals consumption from daily aggregates of a building, or of one of its devices
+// when deviceID is set
+func (r *TimeSeriesRepository) SumConsumption(ctx context.Context, buildingID, deviceID string, from, to time.Time) (float64, error) {
+	match := bson.M{
+		"building_id": buildingID,
+		"timestamp": bson.M{
+			"$gte": from,
+			"$lte": to,
+		},
+		"aggregation_type": models.AggregationTypeDaily,
+	}
+	index := timeRangeBuildingIndex
+	if deviceID != "" {
+		match["device_id"] = deviceID
+		index = timeRangeDeviceIndex
+	}
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{
+			"$group": bson.M{
+				"_id":   nil,
+				"total": bson.M{"$sum": bson.M{"$ifNull": []interface{}{"$metrics.consumption", 0}}},
+			},
+		},
+	}
+
+	cursor, err := r.aggregate(ctx, pipeline, index)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []struct {
+		Total float64 `bson:"total"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return 0, err
+	}
+	if len(totals) == 0 {
+		return 0, nil
+	}
+
+	return totals[0].Total, nil
+}
+
+// SumEnergyFlows totals a building's consumption, generation, grid exchange and battery flows
+// from daily aggregates, counting the aggregates that metered the grid connection
+func (r *TimeSeriesRepository) SumEnergyFlows(ctx context.Context, buildingID string, from, to time.Time) (*models.EnergyFlows, error) {
+	sum := func(metric string) bson.M {
+		return bson.M{"$sum": bson.M{"$ifNull": []interface{}{"$metrics." + metric, 0}}}
+	}
+	// Missing metrics compare below null, so only reported grid readings are counted
+	gridMetered := bson.M{"$or": []bson.M{
+		{"$gt": []interface{}{"$metrics." + models.MetricGridImport, nil}},
+		{"$gt": []interface{}{"$metrics." + models.MetricGridExport, nil}},
+	}}
+
+	pipeline := []bson.M{
+		{
+			"$match": tenant.BuildingFilter(ctx, bson.M{
+				"building_id": buildingID,
+				"timestamp": bson.M{
+					"$gte": from,
+					"$lte": to,
+				},
+				"aggregation_type": models.AggregationTypeDaily,
+			}, "building_id"),
+		},
+		{
+			"$group": bson.M{
+				"_id":               nil,
+				"consumption":       sum("consumption"),
+				"generation":        sum(models.MetricGeneration),
+				"grid_import":       sum(models.MetricGridImport),
+				"grid_export":       sum(models.MetricGridExport),
+				"battery_charge":    sum(models.MetricBatteryCharge),
+				"battery_discharge": sum(models.MetricBatteryDischarge),
+				"grid_records":      bson.M{"$sum": bson.M{"$cond": []interface{}{gridMetered, 1, 0}}},
+			},
+		},
+	}
+
+	cursor, err := r.aggregate(ctx, pipeline, timeRangeBuildingIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []*models.EnergyFlows
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+	if len(totals) == 0 {
+		return &models.EnergyFlows{}, nil
+	}
+
+	return totals[0], nil
+}
+
+// SumConsumptionByDevice totals consumption per device of a building from daily aggregates.
+// Records without a device ID come from the building's main meter and are totalled under an empty ID.
+func (r *TimeSeriesRepository) SumConsumptionByDevice(ctx context.Context, buildingID string, from, to time.Time) ([]*models.DeviceConsumption, error) {
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{
+				"building_id": buildingID,
+				"timestamp": bson.M{
+					"$gte": from,
+					"$lte": to,
+				},
+				"aggregation_type": models.AggregationTypeDaily,
+			},
+		},
+		{
+			"$group": bson.M{
+				"_id":         bson.M{"$ifNull": []interface{}{"$device_id", ""}},
+				"consumption": bson.M{"$sum": bson.M{"$ifNull": []interface{}{"$metrics.consumption", 0}}},
+			},
+		},
+	}
+
+	cursor, err := r.aggregate(ctx, pipeline, timeRangeBuildingIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []*models.DeviceConsumption
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// SumHourlyConsumption totals a building's hourly aggregates per hour, separating the main meter
+// from submetered devices
+func (r *TimeSeriesRepository) SumHourlyConsumption(ctx context.Context, buildingID string, from, to time.Time) ([]*models.HourlyConsumption, error) {
+	consumption := bson.M{"$ifNull": []interface{}{"$metrics.consumption", 0}}
+	isMainMeter := bson.M{"$eq": []interface{}{bson.M{"$ifNull": []interface{}{"$device_id", ""}}, ""}}
+
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{
+				"building_id": buildingID,
+				"timestamp": bson.M{
+					"$gte": from,
+					"$lte": to,
+				},
+				"aggregation_type": models.AggregationTypeHourly,
+			},
+		},
+		{
+			"$group": bson.M{
+				"_id":        "$timestamp",
+				"main_meter": bson.M{"$sum": bson.M{"$cond": []interface{}{isMainMeter, consumption, 0}}},
+				"metered":    bson.M{"$sum": bson.M{"$cond": []interface{}{isMainMeter, 0, consumption}}},
+			},
+		},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := r.aggregate(ctx, pipeline, timeRangeBuildingIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hours []*models.HourlyConsumption
+	if err := cursor.All(ctx, &hours); err != nil {
+		return nil, err
+	}
+
+	return hours, nil
+}
+
+// SumHourlyConsumptionByBuilding totals the hourly aggregates of several buildings per building
+// and hour in a single aggregation, each building over its own period, separating the main meter
+// from submetered devices
+func (r *TimeSeriesRepository) SumHourlyConsumptionByBuilding(ctx context.Context, periods []models.BuildingPeriod) ([]*models.BuildingHourlyConsumption, error) {
+	if len(periods) == 0 {
+		return nil, nil
+	}
+
+	consumption := bson.M{"$ifNull": []interface{}{"$metrics.consumption", 0}}
+	isMainMeter := bson.M{"$eq": []interface{}{bson.M{"$ifNull": []interface{}{"$device_id", ""}}, ""}}
+
+	ranges := make([]bson.M, 0, len(periods))
+	for _, period := range periods {
+		ranges = append(ranges, bson.M{
+			"building_id": period.BuildingID,
+			"timestamp": bson.M{
+				"$gte": period.From,
+				"$lte": period.To,
+			},
+		})
+	}
+	match := tenant.BuildingFilter(ctx, bson.M{
+		"aggregation_type": models.AggregationTypeHourly,
+		"$or":              ranges,
+	}, "building_id")
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{
+			"$group": bson.M{
+				"_id":        bson.M{"building_id": "$building_id", "timestamp": "$timestamp"},
+				"main_meter": bson.M{"$sum": bson.M{"$cond": []interface{}{isMainMeter, consumption, 0}}},
+				"metered":    bson.M{"$sum": bson.M{"$cond": []interface{}{isMainMeter, 0, consumption}}},
+			},
+		},
+		{
+			"$project": bson.M{
+				"_id":         0,
+				"building_id": "$_id.building_id",
+				"timestamp":   "$_id.timestamp",
+				"main_meter":  1,
+				"metered":     1,
+			},
+		},
+		{"$sort": bson.M{"building_id": 1, "timestamp": 1}},
+	}
+
+	cursor, err := r.aggregate(ctx, pipeline, timeRangeBuildingIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hours []*models.BuildingHourlyConsumption
+	if err := cursor.All(ctx, &hours); err != nil {
+		return nil, err
+	}
+
+	return hours, nil
+}
+
+// SumHourlyDeviceConsumption totals the hourly aggregates of the given devices of a building per
+// hour. The combined consumption of each hour is returned in Metered.
+func (r *TimeSeriesRepository) SumHourlyDeviceConsumption(ctx context.Context, buildingID string, deviceIDs []string, from, to time.Time) ([]*models.HourlyConsumption, error) {
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{
+				"building_id": buildingID,
+				"device_id":   bson.M{"$in": deviceIDs},
+				"timestamp": bson.M{
+					"$gte": from,
+					"$lte": to,
+				},
+				"aggregation_type": models.AggregationTypeHourly,
+			},
+		},
+		{
+			"$group": bson.M{
+				"_id":     "$timestamp",
+				"metered": bson.M{"$sum": bson.M{"$ifNull": []interface{}{"$metrics.consumption", 0}}},
+			},
+		},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := r.aggregate(ctx, pipeline, timeRangeDeviceIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hours []*models.HourlyConsumption
+	if err := cursor.All(ctx, &hours); err != nil {
+		return nil, err
+	}
+
+	return hours, nil
+}
+
+// SumDailyConsumptionByBuilding totals the daily aggregates of the given buildings per building
+// and day, separating the main meter from submetered devices
+func (r *TimeSeriesRepository) SumDailyConsumptionByBuilding(ctx context.Context, buildingIDs []string, from, to time.Time) ([]*models.BuildingDailyConsumption, error) {
+	consumption := bson.M{"$ifNull": []interface{}{"$metrics.consumption", 0}}
+	isMainMeter := bson.M{"$eq": []interface{}{bson.M{"$ifNull": []interface{}{"$device_id", ""}}, ""}}
+
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{
+				"building_id": bson.M{"$in": buildingIDs},
+				"timestamp": bson.M{
+					"$gte": from,
+					"$lte": to,
+				},
+				"aggregation_type": models.AggregationTypeDaily,
+			},
+		},
+		{
+			"$group": bson.M{
+				"_id":        bson.M{"building_id": "$building_id", "day": "$timestamp"},
+				"main_meter": bson.M{"$sum": bson.M{"$cond": []interface{}{isMainMeter, consumption, 0}}},
+				"metered":    bson.M{"$sum": bson.M{"$cond": []interface{}{isMainMeter, 0, consumption}}},
+			},
+		},
+		{
+			"$project": bson.M{
+				"_id":         0,
+				"building_id": "$_id.building_id",
+				"day":         "$_id.day",
+				"main_meter":  1,
+				"metered":     1,
+			},
+		},
+		{"$sort": bson.D{{Key: "building_id", Value: 1}, {Key: "day", Value: 1}}},
+	}
+
+	cursor, err := r.aggregate(ctx, pipeline, timeRangeBuildingIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var days []*models.BuildingDailyConsumption
+	if err := cursor.All(ctx, &days); err != nil {
+		return nil, err
+	}
+
+	return days, nil
+}
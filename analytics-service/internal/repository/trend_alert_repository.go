@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"analytics-service/internal/models"
+)
+
+// TrendAlertRepository handles KPI trend alert database operations
+type TrendAlertRepository struct {
+	collection *mongo.Collection
+}
+
+// NewTrendAlertRepository creates a new trend alert repository
+func NewTrendAlertRepository(collection *mongo.Collection) *TrendAlertRepository {
+	return &TrendAlertRepository{collection: collection}
+}
+
+// Upsert stores an alert, updating the existing alert of the same building, metric and week
+func (r *TrendAlertRepository) Upsert(ctx context.Context, alert *models.TrendAlert) (*models.TrendAlert, error) {
+	now := time.Now()
+	filter := bson.M{
+		"building_id": alert.BuildingID,
+		"metric":      alert.Metric,
+		"week_start":  alert.WeekStart,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"slope":                 alert.Slope,
+			"slope_percent":         alert.SlopePercent,
+			"confidence":            alert.Confidence,
+			"consecutive_increases": alert.ConsecutiveIncreases,
+			"contributing_devices":  alert.ContributingDevices,
+			"updated_at":            now,
+		},
+		"$setOnInsert": bson.M{
+			"detected_at": now,
+		},
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	var stored models.TrendAlert
+	if err := result.Decode(&stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// FindByBuilding retrieves the most recent alerts of a building
+func (r *TrendAlertRepository) FindByBuilding(ctx context.Context, buildingID string, limit int) ([]*models.TrendAlert, error) {
+	findOptions := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "week_start", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"building_id": buildingID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	alerts := []*models.TrendAlert{}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
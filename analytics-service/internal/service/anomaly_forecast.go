@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"analytics-service/internal/models"
+)
+
+// forecastDeviationLookback is how far back forecast intervals are compared with actuals
+const forecastDeviationLookback = 24 * time.Hour
+
+// forecastInterval is one forecast prediction compared with the consumption measured over it
+type forecastInterval struct {
+	start      time.Time
+	end        time.Time
+	predicted  float64
+	upperBound float64
+	actual     float64
+	measured   bool
+}
+
+// DetectForecastDeviations compares the consumption of every building that reported data in the
+// lookback period with the confidence bounds of its latest forecast, and raises an anomaly when
+// actuals exceed the upper bound for at least minIntervals consecutive intervals. Each run of
+// exceeding intervals is raised once. A stored live forecast deviation configuration overrides
+// minIntervals; shadow configurations record the runs they would have raised. Returns the number
+// of anomalies raised.
+func (s *AnomalyService) DetectForecastDeviations(ctx context.Context, minIntervals int) (int, error) {
+	now := time.Now().UTC()
+	buildings, err := s.timeSeriesRepo.SumConsumptionByBuilding(ctx, now.Add(-forecastDeviationLookback), now, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	live, err := s.liveConfigs(ctx)
+	if err != nil {
+		log.Printf("Failed to load live detector configurations, using the built-in ones: %v", err)
+	}
+	if config := live[models.DetectorForecastDeviation]; !config.ID.IsZero() {
+		minIntervals = config.Params.MinIntervals
+	}
+	shadows := s.shadowConfigs(ctx)[models.DetectorForecastDeviation]
+
+	raised := 0
+	for _, building := range buildings {
+		anomaly, err := s.detectForecastDeviation(ctx, building.BuildingID, minIntervals, shadows, now)
+		if err != nil {
+			log.Printf("Failed to check forecast deviation of building %s: %v", building.BuildingID, err)
+			continue
+		}
+		if anomaly != nil {
+			raised++
+		}
+	}
+	return raised, nil
+}
+
+// detectForecastDeviation checks one building against its latest forecast and raises an anomaly
+// for the latest qualifying run of intervals above the upper bound, if it was not raised before
+func (s *AnomalyService) detectForecastDeviation(ctx context.Context, buildingID string, minIntervals int, shadows []*models.DetectorConfig, now time.Time) (*models.Anomaly, error) {
+	forecast, err := s.forecastClient.GetStoredForecast(ctx, buildingID)
+	if err != nil || forecast == nil {
+		return nil, err
+	}
+	forecastID, _ := forecast["id"].(string)
+
+	intervals := forecastIntervals(forecast, now.Add(-forecastDeviationLookback), now)
+	if len(intervals) == 0 {
+		return nil, nil
+	}
+
+	hours, err := s.timeSeriesRepo.SumHourlyConsumption(ctx, buildingID, intervals[0].start, intervals[len(intervals)-1].end.Add(-time.Millisecond))
+	if err != nil {
+		return nil, err
+	}
+	measureIntervals(intervals, hours)
+
+	run := latestRunAboveBound(intervals)
+	for _, config := range shadows {
+		if len(run) > 0 && len(run) >= config.Params.MinIntervals {
+			s.recordShadowForecastDeviation(ctx, config, buildingID, forecastID, run)
+		}
+	}
+	if len(run) < minIntervals {
+		return nil, nil
+	}
+
+	exists, err := s.anomalyRepo.ExistsForForecastRun(ctx, buildingID, models.AnomalyTypeForecastDeviation, forecastID, run[0].start)
+	if err != nil || exists {
+		return nil, err
+	}
+
+	details, maxPercent := forecastDeviationDetails(forecastID, run)
+	anomaly := s.createAnomaly("", buildingID, models.AnomalyTypeForecastDeviation, forecastDeviationSeverity(maxPercent), details)
+	return s.saveAnomaly(ctx, anomaly)
+}
+
+// recordShadowForecastDeviation records the run a shadow configuration would have raised, once
+func (s *AnomalyService) recordShadowForecastDeviation(ctx context.Context, config *models.DetectorConfig, buildingID, forecastID string, run []forecastInterval) {
+	recorded, err := s.detectors.ShadowForecastRunRecorded(ctx, config.ConfigID, buildingID, forecastID, run[0].start)
+	if err != nil {
+		log.Printf("Failed to check shadow anomalies of detector configuration %s: %v", config.ConfigID, err)
+		return
+	}
+	if recorded {
+		return
+	}
+
+	details, maxPercent := forecastDeviationDetails(forecastID, run)
+	s.detectors.RecordShadowAnomaly(ctx, config, "", buildingID, forecastDeviationSeverity(maxPercent), details)
+}
+
+// forecastIntervals returns the forecast predictions whose interval lies within [from, to), in
+// time order. Each prediction covers the interval up to the next one.
+func forecastIntervals(forecast map[string]interface{}, from, to time.Time) []forecastInterval {
+	items, _ := forecast["predictions"].([]interface{})
+
+	all := make([]forecastInterval, 0, len(items))
+	for _, item := range items {
+		p, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		raw, _ := p["timestamp"].(string)
+		timestamp, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			continue
+		}
+		predicted, _ := p["predictedValue"].(float64)
+		upperBound, _ := p["upperBound"].(float64)
+		all = append(all, forecastInterval{start: timestamp, predicted: predicted, upperBound: upperBound})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].start.Before(all[j].start) })
+
+	step := time.Hour
+	if len(all) > 1 {
+		if d := all[1].start.Sub(all[0].start); d > 0 {
+			step = d
+		}
+	}
+
+	intervals := make([]forecastInterval, 0, len(all))
+	for _, interval := range all {
+		interval.end = interval.start.Add(step)
+		if interval.start.Before(from) || interval.end.After(to) {
+			continue
+		}
+		intervals = append(intervals, interval)
+	}
+	return intervals
+}
+
+// measureIntervals adds hourly consumption to the forecast intervals the hours fall in
+func measureIntervals(intervals []forecastInterval, hours []*models.HourlyConsumption) {
+	for _, hour := range hours {
+		// The main meter covers the whole building; fall back to submeters without one
+		actual := hour.MainMeter
+		if actual <= 0 {
+			actual = hour.Metered
+		}
+		for i := range intervals {
+			if !hour.Timestamp.Before(intervals[i].start) && hour.Timestamp.Before(intervals[i].end) {
+				intervals[i].actual += actual
+				intervals[i].measured = true
+				break
+			}
+		}
+	}
+}
+
+// latestRunAboveBound returns the latest run of consecutive measured intervals whose actual
+// consumption exceeds the forecast's upper bound. Intervals without data break a run.
+func latestRunAboveBound(intervals []forecastInterval) []forecastInterval {
+	var latest, current []forecastInterval
+	for _, interval := range intervals {
+		if interval.measured && interval.actual > interval.upperBound {
+			current = append(current, interval)
+			latest = current
+			continue
+		}
+		current = nil
+	}
+	return latest
+}
+
+// forecastDeviationDetails explains a forecast deviation anomaly: the forecast it was measured
+// against, the run of intervals above the upper bound and how far actuals exceeded it. Also
+// returns the largest deviation as a percentage of the upper bound.
+func forecastDeviationDetails(forecastID string, run []forecastInterval) (map[string]interface{}, float64) {
+	points := make([]map[string]interface{}, len(run))
+	var totalDeviation, maxDeviation, maxPercent float64
+	for i, interval := range run {
+		deviation := interval.actual - interval.upperBound
+		percent := 0.0
+		if interval.upperBound > 0 {
+			percent = deviation / interval.upperBound * 100
+		}
+		totalDeviation += deviation
+		maxDeviation = math.Max(maxDeviation, deviation)
+		maxPercent = math.Max(maxPercent, percent)
+
+		points[i] = map[string]interface{}{
+			"timestamp":        interval.start,
+			"actual":           roundTo2(interval.actual),
+			"predicted":        roundTo2(interval.predicted),
+			"upperBound":       roundTo2(interval.upperBound),
+			"deviation":        roundTo2(deviation),
+			"deviationPercent": roundTo2(percent),
+		}
+	}
+
+	return map[string]interface{}{
+		"forecastId":           forecastID,
+		"runStart":             run[0].start,
+		"runEnd":               run[len(run)-1].end,
+		"consecutiveIntervals": len(run),
+		"totalDeviation":       roundTo2(totalDeviation),
+		"maxDeviation":         roundTo2(maxDeviation),
+		"maxDeviationPercent":  roundTo2(maxPercent),
+		"intervals":            points,
+	}, maxPercent
+}
+
+// forecastDeviationSeverity grades how far actuals rose above the forecast's upper bound
+func forecastDeviationSeverity(maxDeviationPercent float64) models.AnomalySeverity {
+	switch {
+	case maxDeviationPercent >= 100:
+		return models.AnomalySeverityCritical
+	case maxDeviationPercent >= 50:
+		return models.AnomalySeverityHigh
+	case maxDeviationPercent >= 20:
+		return models.AnomalySeverityMedium
+	default:
+		return models.AnomalySeverityLow
+	}
+}
+
+// StartForecastDeviationWorker periodically checks consumption against forecasts until the
+// context is cancelled
+func (s *AnomalyService) StartForecastDeviationWorker(ctx context.Context, interval time.Duration, minIntervals int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DetectForecastDeviations(ctx, minIntervals); err != nil {
+				log.Printf("Failed to detect forecast deviations: %v", err)
+			}
+		}
+	}
+}
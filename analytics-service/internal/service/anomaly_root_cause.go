@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
+)
+
+const (
+	// defaultRootCauseLookback is how long before an anomaly things are considered as its cause
+	defaultRootCauseLookback = 6 * time.Hour
+	// weatherBaselineDays is how many days before an anomaly its weather is compared with
+	weatherBaselineDays = 7
+	// minWeatherSwing is the change in mean outdoor temperature, in °C, suggested as a cause
+	minWeatherSwing = 3.0
+	// minRootCauseConfidence drops candidates too unlikely to be worth showing
+	minRootCauseConfidence = 0.1
+	// maxRootCauses is how many candidate causes are attached to an anomaly
+	maxRootCauses = 5
+)
+
+// ErrRootCauseAnalysisDisabled is returned when root causes are requested while the analysis is
+// turned off
+var ErrRootCauseAnalysisDisabled = errors.New("root cause analysis is disabled")
+
+// RootCauseAnalyzer suggests why an anomaly happened by correlating its window with the
+// commands, optimization scenarios, weather and device status changes of its building
+type RootCauseAnalyzer struct {
+	activityRepo   *repository.DeviceActivityRepository
+	executionRepo  *repository.OptimizationExecutionRepository
+	forecastClient interface {
+		GetStoredDegreeDays(ctx context.Context, buildingID string, from, to time.Time) (*models.DegreeDays, error)
+	}
+	lookback time.Duration
+}
+
+// NewRootCauseAnalyzer creates a new root cause analyzer
+func NewRootCauseAnalyzer(
+	activityRepo *repository.DeviceActivityRepository,
+	executionRepo *repository.OptimizationExecutionRepository,
+	forecastClient interface {
+		GetStoredDegreeDays(ctx context.Context, buildingID string, from, to time.Time) (*models.DegreeDays, error)
+	},
+	lookback time.Duration,
+) *RootCauseAnalyzer {
+	if lookback <= 0 {
+		lookback = defaultRootCauseLookback
+	}
+	return &RootCauseAnalyzer{
+		activityRepo:   activityRepo,
+		executionRepo:  executionRepo,
+		forecastClient: forecastClient,
+		lookback:       lookback,
+	}
+}
+
+// Analyze gathers what happened in the anomaly's building around its window and ranks the
+// candidate causes. A source that cannot be read is logged and left out, so the anomaly still
+// gets the causes the others suggest.
+func (a *RootCauseAnalyzer) Analyze(ctx context.Context, anomaly *models.Anomaly) []models.RootCause {
+	if anomaly.BuildingID == "" {
+		return []models.RootCause{}
+	}
+
+	windowStart, windowEnd := AnomalyWindow(anomaly)
+	from := windowStart.Add(-a.lookback)
+	evidence := &models.RootCauseEvidence{}
+
+	activities, err := a.activityRepo.FindByBuildingInRange(ctx, anomaly.BuildingID, from, windowEnd)
+	if err != nil {
+		log.Printf("Failed to load device activity for root causes of anomaly %s: %v", anomaly.AnomalyID, err)
+	} else {
+		evidence.Activities = activities
+	}
+
+	executions, err := a.executionRepo.FindByBuildingInRange(ctx, anomaly.BuildingID, from, windowEnd)
+	if err != nil {
+		log.Printf("Failed to load optimization executions for root causes of anomaly %s: %v", anomaly.AnomalyID, err)
+	} else {
+		evidence.Executions = executions
+	}
+
+	degreeDays, err := a.forecastClient.GetStoredDegreeDays(ctx, anomaly.BuildingID, windowStart.AddDate(0, 0, -weatherBaselineDays), windowEnd)
+	if err != nil {
+		log.Printf("Failed to load weather for root causes of anomaly %s: %v", anomaly.AnomalyID, err)
+	} else {
+		evidence.DegreeDays = degreeDays
+	}
+
+	return RankRootCauses(anomaly, evidence, a.lookback)
+}
+
+// AnomalyWindow returns when an anomaly was happening: the run of intervals recorded in its
+// details, or the moment it was detected
+func AnomalyWindow(anomaly *models.Anomaly) (time.Time, time.Time) {
+	start, okStart := detailTime(anomaly.Details["runStart"])
+	end, okEnd := detailTime(anomaly.Details["runEnd"])
+	switch {
+	case okStart && okEnd && !end.Before(start):
+		return start, end
+	case okStart:
+		return start, start
+	}
+	return anomaly.DetectedAt, anomaly.DetectedAt
+}
+
+// detailTime reads a time from anomaly details, which hold time.Time when the anomaly was just
+// detected and primitive.DateTime once it was read back from MongoDB
+func detailTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case primitive.DateTime:
+		return v.Time(), true
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// RankRootCauses scores the evidence around an anomaly's window as candidate causes, most
+// likely first. Each candidate's base confidence reflects how directly it concerns the
+// anomalous device and is scaled down the longer before the window it happened, to half at
+// lookback before it. Commands sent by an optimization scenario are attributed to the scenario.
+func RankRootCauses(anomaly *models.Anomaly, evidence *models.RootCauseEvidence, lookback time.Duration) []models.RootCause {
+	if lookback <= 0 {
+		lookback = defaultRootCauseLookback
+	}
+	windowStart, windowEnd := AnomalyWindow(anomaly)
+
+	proximity := func(at time.Time) (float64, bool) {
+		switch {
+		case at.After(windowEnd), at.Before(windowStart.Add(-lookback)):
+			return 0, false
+		case !at.Before(windowStart):
+			return 1, true
+		}
+		return 1 - float64(windowStart.Sub(at))/float64(lookback), true
+	}
+	// base picks the confidence for a cause on the anomalous device, on another device, or on
+	// any device when the anomaly concerns the whole building
+	base := func(deviceID string, same, other float64) float64 {
+		switch {
+		case anomaly.DeviceID == "":
+			return (same + other) / 2
+		case deviceID == anomaly.DeviceID:
+			return same
+		}
+		return other
+	}
+	score := func(base, closeness float64) float64 {
+		return roundTo2(base * (0.5 + 0.5*closeness))
+	}
+
+	causes := make([]models.RootCause, 0)
+	scenarioCommands := make(map[string]bool)
+
+	for _, execution := range evidence.Executions {
+		at := execution.StartedAt
+		if at.IsZero() {
+			at = execution.CompletedAt
+		}
+		if at.After(windowEnd) {
+			continue
+		}
+		// A scenario still running when the anomaly began is as close as it gets
+		lastActive := execution.CompletedAt
+		if lastActive.After(windowEnd) || lastActive.Before(at) {
+			lastActive = windowEnd
+		}
+		closeness, ok := proximity(lastActive)
+		if !ok {
+			continue
+		}
+
+		touched := false
+		for _, action := range execution.Actions {
+			if action.CommandID != "" {
+				scenarioCommands[action.CommandID] = true
+			}
+			if anomaly.DeviceID != "" && action.DeviceID == anomaly.DeviceID {
+				touched = true
+			}
+		}
+
+		confidence := 0.5
+		summary := fmt.Sprintf("optimization scenario %s ran with %d actions applied", execution.ScenarioID, execution.ActionsApplied)
+		switch {
+		case touched:
+			confidence = 0.75
+			summary = fmt.Sprintf("optimization scenario %s sent commands to device %s", execution.ScenarioID, anomaly.DeviceID)
+		case anomaly.DeviceID == "":
+			confidence = 0.6
+		}
+
+		occurredAt := at
+		causes = append(causes, models.RootCause{
+			Category:   models.RootCauseOptimization,
+			Confidence: score(confidence, closeness),
+			Summary:    summary,
+			Reference:  execution.ScenarioID,
+			OccurredAt: &occurredAt,
+			Evidence: map[string]interface{}{
+				"scenarioType":   execution.ScenarioType,
+				"status":         execution.Status,
+				"actionsApplied": execution.ActionsApplied,
+				"actionsFailed":  execution.ActionsFailed,
+			},
+		})
+	}
+
+	for _, activity := range evidence.Activities {
+		closeness, ok := proximity(activity.OccurredAt)
+		if !ok {
+			continue
+		}
+		occurredAt := activity.OccurredAt
+
+		switch activity.Kind {
+		case models.ActivityCommandApplied, models.ActivityCommandTimedOut:
+			if scenarioCommands[activity.CommandID] {
+				continue
+			}
+			confidence := base(activity.DeviceID, 0.7, 0.35)
+			summary := fmt.Sprintf("command %s was applied to device %s", activity.Command, activity.DeviceID)
+			if activity.Kind == models.ActivityCommandTimedOut {
+				confidence = base(activity.DeviceID, 0.5, 0.25)
+				summary = fmt.Sprintf("command %s sent to device %s was not acknowledged", activity.Command, activity.DeviceID)
+			}
+			if activity.IssuedBy != "" {
+				summary += " (issued by " + activity.IssuedBy + ")"
+			}
+			causes = append(causes, models.RootCause{
+				Category:   models.RootCauseCommand,
+				Confidence: score(confidence, closeness),
+				Summary:    summary,
+				DeviceID:   activity.DeviceID,
+				Reference:  activity.CommandID,
+				OccurredAt: &occurredAt,
+				Evidence: map[string]interface{}{
+					"command":  activity.Command,
+					"kind":     activity.Kind,
+					"issuedBy": activity.IssuedBy,
+				},
+			})
+		case models.ActivityStatusChanged:
+			causes = append(causes, models.RootCause{
+				Category:   models.RootCauseDeviceStatus,
+				Confidence: score(base(activity.DeviceID, 0.6, 0.3), closeness),
+				Summary:    fmt.Sprintf("device %s went from %s to %s", activity.DeviceID, activity.PreviousStatus, activity.Status),
+				DeviceID:   activity.DeviceID,
+				OccurredAt: &occurredAt,
+				Evidence: map[string]interface{}{
+					"previousStatus": activity.PreviousStatus,
+					"status":         activity.Status,
+				},
+			})
+		}
+	}
+
+	if cause, ok := weatherRootCause(evidence.DegreeDays, windowStart); ok {
+		causes = append(causes, cause)
+	}
+
+	kept := causes[:0]
+	for _, cause := range causes {
+		if cause.Confidence >= minRootCauseConfidence {
+			kept = append(kept, cause)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Confidence > kept[j].Confidence
+	})
+	if len(kept) > maxRootCauses {
+		kept = kept[:maxRootCauses]
+	}
+	return kept
+}
+
+// weatherRootCause suggests the weather when the mean outdoor temperature on the days of the
+// anomaly swung at least minWeatherSwing from the days before it. The larger the swing, the
+// higher the confidence.
+func weatherRootCause(degreeDays *models.DegreeDays, windowStart time.Time) (models.RootCause, bool) {
+	if degreeDays == nil {
+		return models.RootCause{}, false
+	}
+
+	firstDay := windowStart.UTC().Format("2006-01-02")
+	var anomalySum, baselineSum float64
+	var anomalyDays, baselineDays int
+	for _, day := range degreeDays.Days {
+		if day.Date >= firstDay {
+			anomalySum += day.MeanTemperature
+			anomalyDays++
+		} else {
+			baselineSum += day.MeanTemperature
+			baselineDays++
+		}
+	}
+	if anomalyDays == 0 || baselineDays == 0 {
+		return models.RootCause{}, false
+	}
+
+	mean := anomalySum / float64(anomalyDays)
+	baseline := baselineSum / float64(baselineDays)
+	swing := mean - baseline
+	if math.Abs(swing) < minWeatherSwing {
+		return models.RootCause{}, false
+	}
+
+	direction := "above"
+	if swing < 0 {
+		direction = "below"
+	}
+	return models.RootCause{
+		Category:   models.RootCauseWeather,
+		Confidence: roundTo2(math.Min(0.9, 0.3+0.06*math.Abs(swing))),
+		Summary: fmt.Sprintf("outdoor temperature averaged %.1f°C, %.1f°C %s the previous %d days",
+			mean, math.Abs(swing), direction, baselineDays),
+		Evidence: map[string]interface{}{
+			"meanTemperature":     roundTo2(mean),
+			"baselineTemperature": roundTo2(baseline),
+			"swing":               roundTo2(swing),
+		},
+	}, true
+}
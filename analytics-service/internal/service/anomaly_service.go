@@ -3,35 +3,83 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
 
+	"analytics-service/internal/cache"
+	"analytics-service/internal/events"
 	"analytics-service/internal/models"
 	"analytics-service/internal/repository"
 )
 
 // AnomalyService handles anomaly detection business logic
 type AnomalyService struct {
-	anomalyRepo *repository.AnomalyRepository
-	iotClient   interface {
+	anomalyRepo    *repository.AnomalyRepository
+	timeSeriesRepo *repository.TimeSeriesRepository
+	eventBus       *events.Bus
+	iotClient      interface {
 		GetTelemetryHistory(ctx context.Context, deviceID string, from, to time.Time, page, limit int, authToken string) ([]map[string]interface{}, error)
 		GetDevices(ctx context.Context, buildingID string, authToken string) ([]map[string]interface{}, error)
 	}
+	forecastClient interface {
+		GetStoredForecast(ctx context.Context, buildingID string) (map[string]interface{}, error)
+	}
+	dashboardCache *cache.Cache
+	rootCauses     *RootCauseAnalyzer
+	detectors      *DetectorService
 }
 
 // NewAnomalyService creates a new anomaly service
 func NewAnomalyService(
 	anomalyRepo *repository.AnomalyRepository,
+	timeSeriesRepo *repository.TimeSeriesRepository,
 	iotClient interface {
 		GetTelemetryHistory(ctx context.Context, deviceID string, from, to time.Time, page, limit int, authToken string) ([]map[string]interface{}, error)
 		GetDevices(ctx context.Context, buildingID string, authToken string) ([]map[string]interface{}, error)
 	},
+	forecastClient interface {
+		GetStoredForecast(ctx context.Context, buildingID string) (map[string]interface{}, error)
+	},
+	eventBus *events.Bus,
+	dashboardCache *cache.Cache,
+	rootCauses *RootCauseAnalyzer,
+	detectors *DetectorService,
 ) *AnomalyService {
 	return &AnomalyService{
-		anomalyRepo: anomalyRepo,
-		iotClient:   iotClient,
+		anomalyRepo:    anomalyRepo,
+		timeSeriesRepo: timeSeriesRepo,
+		eventBus:       eventBus,
+		iotClient:      iotClient,
+		forecastClient: forecastClient,
+		dashboardCache: dashboardCache,
+		rootCauses:     rootCauses,
+		detectors:      detectors,
+	}
+}
+
+// liveConfigs returns the live configuration of every detector, or the built-in ones when
+// detector configurations are disabled
+func (s *AnomalyService) liveConfigs(ctx context.Context) (map[string]*models.DetectorConfig, error) {
+	if s.detectors == nil {
+		return DefaultDetectorConfigs(0), nil
+	}
+	return s.detectors.LiveConfigs(ctx)
+}
+
+// shadowConfigs returns the shadow configurations by detector. Failures to load them are logged,
+// as shadow detection must never disturb live detection.
+func (s *AnomalyService) shadowConfigs(ctx context.Context) map[string][]*models.DetectorConfig {
+	if s.detectors == nil {
+		return nil
+	}
+	shadows, err := s.detectors.ShadowConfigs(ctx)
+	if err != nil {
+		log.Printf("Failed to load shadow detector configurations: %v", err)
+		return nil
 	}
+	return shadows
 }
 
 // DetectAnomalies detects anomalies in telemetry data
@@ -45,30 +93,28 @@ func (s *AnomalyService) DetectAnomalies(ctx context.Context, deviceID, building
 		return nil, fmt.Errorf("failed to get telemetry: %w", err)
 	}
 
+	live, err := s.liveConfigs(ctx)
+	if err != nil {
+		log.Printf("Failed to load live detector configurations, using the built-in ones: %v", err)
+	}
+	shadows := s.shadowConfigs(ctx)
+
 	anomalies := make([]*models.Anomaly, 0)
 
-	// Simple anomaly detection: check for values outside normal range
+	// Check each reading against the temperature range and consumption spike detectors
 	for _, t := range telemetry {
 		if metrics, ok := t["metrics"].(map[string]interface{}); ok {
-			// Check temperature anomalies
-			if temp, ok := metrics["temperature"].(float64); ok {
-				if temp > 30.0 || temp < 10.0 {
-					anomaly := s.createAnomaly(deviceID, buildingID, "TEMPERATURE_OUT_OF_RANGE", models.AnomalySeverityHigh, map[string]interface{}{
-						"temperature": temp,
-						"threshold":   "10-30°C",
-					})
+			for _, detector := range []string{models.DetectorTemperatureRange, models.DetectorConsumptionSpike} {
+				if severity, details, ok := evaluateReading(live[detector], metrics); ok {
+					anomaly := s.createAnomaly(deviceID, buildingID, models.DetectorAnomalyTypes[detector], severity, details)
 					anomalies = append(anomalies, anomaly)
 				}
-			}
 
-			// Check consumption spikes
-			if consumption, ok := metrics["consumption"].(float64); ok {
-				if consumption > 1000.0 { // Threshold example
-					anomaly := s.createAnomaly(deviceID, buildingID, "CONSUMPTION_SPIKE", models.AnomalySeverityMedium, map[string]interface{}{
-						"consumption": consumption,
-						"threshold":   1000.0,
-					})
-					anomalies = append(anomalies, anomaly)
+				// Shadow configurations only record what they would have raised
+				for _, config := range shadows[detector] {
+					if severity, details, ok := evaluateReading(config, metrics); ok {
+						s.detectors.RecordShadowAnomaly(ctx, config, deviceID, buildingID, severity, details)
+					}
 				}
 			}
 		}
@@ -77,7 +123,7 @@ func (s *AnomalyService) DetectAnomalies(ctx context.Context, deviceID, building
 	// Save anomalies
 	responses := make([]*models.AnomalyResponse, 0)
 	for _, anomaly := range anomalies {
-		created, err := s.anomalyRepo.Create(ctx, anomaly)
+		created, err := s.saveAnomaly(ctx, anomaly)
 		if err != nil {
 			continue
 		}
@@ -87,6 +133,33 @@ func (s *AnomalyService) DetectAnomalies(ctx context.Context, deviceID, building
 	return responses, nil
 }
 
+// saveAnomaly stores a detected anomaly with its candidate root causes and announces it on
+// the event bus
+func (s *AnomalyService) saveAnomaly(ctx context.Context, anomaly *models.Anomaly) (*models.Anomaly, error) {
+	if s.rootCauses != nil {
+		now := time.Now()
+		anomaly.RootCauses = s.rootCauses.Analyze(ctx, anomaly)
+		anomaly.RootCausesAnalyzedAt = &now
+	}
+
+	created, err := s.anomalyRepo.Create(ctx, anomaly)
+	if err != nil {
+		return nil, err
+	}
+	invalidateDashboards(ctx, s.dashboardCache, created.BuildingID)
+
+	s.eventBus.Publish(ctx, events.AnomalyDetected, &events.AnomalyDetectedData{
+		AnomalyID:  created.AnomalyID,
+		DeviceID:   created.DeviceID,
+		BuildingID: created.BuildingID,
+		Type:       created.Type,
+		Severity:   string(created.Severity),
+		DetectedAt: created.DetectedAt,
+	})
+
+	return created, nil
+}
+
 // createAnomaly creates an anomaly record
 func (s *AnomalyService) createAnomaly(deviceID, buildingID, anomalyType string, severity models.AnomalySeverity, details map[string]interface{}) *models.Anomaly {
 	return &models.Anomaly{
@@ -125,6 +198,30 @@ func (s *AnomalyService) ListAnomalies(ctx context.Context, deviceID, buildingID
 	return responses, total, nil
 }
 
+// AnalyzeRootCauses ranks the candidate root causes of an anomaly again, picking up device
+// activity and weather recorded since it was detected
+func (s *AnomalyService) AnalyzeRootCauses(ctx context.Context, anomalyID string) (*models.AnomalyResponse, error) {
+	if s.rootCauses == nil {
+		return nil, ErrRootCauseAnalysisDisabled
+	}
+
+	anomaly, err := s.anomalyRepo.FindByAnomalyID(ctx, anomalyID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updated, err := s.anomalyRepo.Update(ctx, anomaly.ID.Hex(), map[string]interface{}{
+		"root_causes":             s.rootCauses.Analyze(ctx, anomaly),
+		"root_causes_analyzed_at": now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated.ToResponse(), nil
+}
+
 // AcknowledgeAnomaly acknowledges an anomaly
 func (s *AnomalyService) AcknowledgeAnomaly(ctx context.Context, anomalyID, userID string) (*models.AnomalyResponse, error) {
 	anomaly, err := s.anomalyRepo.FindByAnomalyID(ctx, anomalyID)
@@ -143,6 +240,7 @@ func (s *AnomalyService) AcknowledgeAnomaly(ctx context.Context, anomalyID, user
 	if err != nil {
 		return nil, err
 	}
+	invalidateDashboards(ctx, s.dashboardCache, anomaly.BuildingID)
 
 	return updated.ToResponse(), nil
 }
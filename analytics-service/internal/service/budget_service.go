@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"analytics-service/internal/events"
+	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
+	"analytics-service/internal/tenant"
+)
+
+// Percentages of a budget's monthly limit that trigger notifications when first reached in a month
+const (
+	budgetWarningPercent = 80
+	budgetLimitPercent   = 100
+)
+
+var budgetThresholds = []int{budgetWarningPercent, budgetLimitPercent}
+
+// BudgetService handles energy budget management and tracking
+type BudgetService struct {
+	budgetRepo     *repository.BudgetRepository
+	timeSeriesRepo *repository.TimeSeriesRepository
+	eventBus       *events.Bus
+	forecastClient interface {
+		GetStoredForecast(ctx context.Context, buildingID string) (map[string]interface{}, error)
+	}
+}
+
+// NewBudgetService creates a new budget service
+func NewBudgetService(
+	budgetRepo *repository.BudgetRepository,
+	timeSeriesRepo *repository.TimeSeriesRepository,
+	forecastClient interface {
+		GetStoredForecast(ctx context.Context, buildingID string) (map[string]interface{}, error)
+	},
+	eventBus *events.Bus,
+) *BudgetService {
+	return &BudgetService{
+		budgetRepo:     budgetRepo,
+		timeSeriesRepo: timeSeriesRepo,
+		eventBus:       eventBus,
+		forecastClient: forecastClient,
+	}
+}
+
+// CreateBudget creates a monthly energy budget for a building or device
+func (s *BudgetService) CreateBudget(ctx context.Context, req *models.EnergyBudgetRequest, userID string) (*models.EnergyBudgetResponse, error) {
+	if !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
+
+	budget := &models.EnergyBudget{CreatedBy: userID}
+	applyBudgetRequest(budget, req)
+
+	created, err := s.budgetRepo.Create(ctx, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	return created.ToResponse(), nil
+}
+
+// GetBudget retrieves a budget by ID
+func (s *BudgetService) GetBudget(ctx context.Context, id string) (*models.EnergyBudgetResponse, error) {
+	budget, err := s.budgetRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return budget.ToResponse(), nil
+}
+
+// ListBudgets lists budgets with filters
+func (s *BudgetService) ListBudgets(ctx context.Context, buildingID, deviceID string, page, limit int) ([]*models.EnergyBudgetResponse, int64, error) {
+	budgets, total, err := s.budgetRepo.FindAll(ctx, buildingID, deviceID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]*models.EnergyBudgetResponse, len(budgets))
+	for i, budget := range budgets {
+		responses[i] = budget.ToResponse()
+	}
+
+	return responses, total, nil
+}
+
+// UpdateBudget replaces a budget's settings. Month-to-date tracking is kept, so thresholds
+// already notified this month are not notified again.
+func (s *BudgetService) UpdateBudget(ctx context.Context, id string, req *models.EnergyBudgetRequest) (*models.EnergyBudgetResponse, error) {
+	budget := &models.EnergyBudget{}
+	applyBudgetRequest(budget, req)
+
+	updated, err := s.budgetRepo.Update(ctx, id, bson.M{
+		"name":                   budget.Name,
+		"scope":                  budget.Scope,
+		"building_id":            budget.BuildingID,
+		"device_id":              budget.DeviceID,
+		"monthly_limit_kwh":      budget.MonthlyLimitKWh,
+		"notify_user_ids":        budget.NotifyUserIDs,
+		"auto_generate_scenario": budget.AutoGenerateScenario,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated.ToResponse(), nil
+}
+
+// DeleteBudget deletes a budget
+func (s *BudgetService) DeleteBudget(ctx context.Context, id string) error {
+	return s.budgetRepo.Delete(ctx, id)
+}
+
+// GetBudgetStatus calculates a budget's month-to-date consumption and month-end projection
+func (s *BudgetService) GetBudgetStatus(ctx context.Context, id string) (*models.EnergyBudgetStatus, error) {
+	budget, err := s.budgetRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.calculateStatus(ctx, budget, time.Now())
+}
+
+// TrackBudgets recalculates the month-to-date consumption of every budget and publishes an
+// event for each budget that reaches a new threshold this month
+func (s *BudgetService) TrackBudgets(ctx context.Context) error {
+	now := time.Now()
+	return s.budgetRepo.FindEach(ctx, func(budget *models.EnergyBudget) error {
+		if err := s.track(ctx, budget, now); err != nil {
+			log.Printf("Failed to track budget %s: %v", budget.ID.Hex(), err)
+		}
+		return nil
+	})
+}
+
+// StartWorker periodically tracks budgets until the context is cancelled
+func (s *BudgetService) StartWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.TrackBudgets(ctx); err != nil {
+				log.Printf("Failed to track budgets: %v", err)
+			}
+		}
+	}
+}
+
+// track stores a budget's month-to-date state. When several thresholds are reached at once only
+// the highest is published, so recipients get one notification per evaluation.
+func (s *BudgetService) track(ctx context.Context, budget *models.EnergyBudget, now time.Time) error {
+	status, err := s.calculateStatus(ctx, budget, now)
+	if err != nil {
+		return err
+	}
+
+	crossed := status.ThresholdsCrossed
+	reached := 0
+	for _, threshold := range budgetThresholds {
+		if status.PercentConsumed >= float64(threshold) && !containsThreshold(crossed, threshold) {
+			crossed = append(crossed, threshold)
+			reached = threshold
+		}
+	}
+
+	tracking := models.BudgetTracking{
+		Month:             status.Month,
+		ConsumedKWh:       status.ConsumedKWh,
+		ThresholdsCrossed: crossed,
+		EvaluatedAt:       &now,
+	}
+	if err := s.budgetRepo.UpdateTracking(ctx, budget.ID, tracking); err != nil {
+		return err
+	}
+
+	if reached > 0 {
+		log.Printf("Budget %s reached %d%% of its %s limit", budget.ID.Hex(), reached, status.Month)
+		s.eventBus.Publish(ctx, events.BudgetThresholdCrossed, &events.BudgetThresholdCrossedData{
+			BudgetID:         budget.ID.Hex(),
+			Name:             budget.Name,
+			BuildingID:       budget.BuildingID,
+			DeviceID:         budget.DeviceID,
+			Month:            status.Month,
+			Threshold:        reached,
+			LimitKWh:         status.LimitKWh,
+			ConsumedKWh:      status.ConsumedKWh,
+			ProjectedKWh:     status.ProjectedKWh,
+			NotifyUserIDs:    budget.NotifyUserIDs,
+			GenerateScenario: budget.AutoGenerateScenario,
+			CrossedAt:        now,
+		})
+	}
+
+	return nil
+}
+
+// calculateStatus totals consumption for the calendar month (UTC) containing now
+func (s *BudgetService) calculateStatus(ctx context.Context, budget *models.EnergyBudget, now time.Time) (*models.EnergyBudgetStatus, error) {
+	now = now.UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	month := monthStart.Format("2006-01")
+
+	consumed, err := s.timeSeriesRepo.SumConsumption(ctx, budget.BuildingID, budget.DeviceID, monthStart, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum consumption: %w", err)
+	}
+
+	projected, source, forecastID := s.projectConsumption(ctx, budget, consumed, monthStart, monthEnd, now)
+
+	thresholds := []int{}
+	if budget.Tracking.Month == month {
+		thresholds = append(thresholds, budget.Tracking.ThresholdsCrossed...)
+	}
+
+	status := &models.EnergyBudgetStatus{
+		BudgetID:          budget.ID.Hex(),
+		Name:              budget.Name,
+		Scope:             string(budget.Scope),
+		BuildingID:        budget.BuildingID,
+		DeviceID:          budget.DeviceID,
+		Month:             month,
+		LimitKWh:          budget.MonthlyLimitKWh,
+		ConsumedKWh:       roundTo2(consumed),
+		PercentConsumed:   budgetPercent(consumed, budget.MonthlyLimitKWh),
+		ProjectedKWh:      roundTo2(projected),
+		ProjectedPercent:  budgetPercent(projected, budget.MonthlyLimitKWh),
+		ProjectionSource:  source,
+		ForecastID:        forecastID,
+		ThresholdsCrossed: thresholds,
+		CalculatedAt:      now,
+	}
+
+	switch {
+	case status.PercentConsumed >= budgetLimitPercent:
+		status.State = models.BudgetStateExceeded
+	case status.PercentConsumed >= budgetWarningPercent:
+		status.State = models.BudgetStateWarning
+	case status.ProjectedPercent > budgetLimitPercent:
+		status.State = models.BudgetStateAtRisk
+	default:
+		status.State = models.BudgetStateOnTrack
+	}
+
+	return status, nil
+}
+
+// projectConsumption estimates consumption at month-end. Building budgets add the latest
+// forecast's predictions for the rest of the month; the month-to-date run rate covers device
+// budgets and any part of the month beyond the forecast horizon.
+func (s *BudgetService) projectConsumption(ctx context.Context, budget *models.EnergyBudget, consumed float64, monthStart, monthEnd, now time.Time) (float64, models.ProjectionSource, string) {
+	runRate := 0.0
+	if elapsed := now.Sub(monthStart).Hours(); elapsed > 0 {
+		runRate = consumed / elapsed
+	}
+
+	if budget.Scope == models.BudgetScopeBuilding {
+		forecast, err := s.forecastClient.GetStoredForecast(ctx, budget.BuildingID)
+		if err != nil {
+			log.Printf("Failed to get forecast for budget %s, projecting from run rate: %v", budget.ID.Hex(), err)
+		}
+		if forecast != nil {
+			predicted, coveredUntil := sumPredictions(forecast, now, monthEnd)
+			if coveredUntil.After(now) {
+				forecastID, _ := forecast["id"].(string)
+				uncovered := monthEnd.Sub(coveredUntil).Hours()
+				return consumed + predicted + runRate*uncovered, models.ProjectionSourceForecast, forecastID
+			}
+		}
+	}
+
+	return consumed + runRate*monthEnd.Sub(now).Hours(), models.ProjectionSourceRunRate, ""
+}
+
+// sumPredictions totals forecast predictions in [from, to) and returns the end of the period they
+// cover. Each prediction covers the interval up to the next one.
+func sumPredictions(forecast map[string]interface{}, from, to time.Time) (float64, time.Time) {
+	items, _ := forecast["predictions"].([]interface{})
+
+	type prediction struct {
+		timestamp time.Time
+		value     float64
+	}
+	predictions := make([]prediction, 0, len(items))
+	for _, item := range items {
+		p, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		raw, _ := p["timestamp"].(string)
+		timestamp, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			continue
+		}
+		value, _ := p["predictedValue"].(float64)
+		predictions = append(predictions, prediction{timestamp: timestamp, value: value})
+	}
+
+	step := time.Hour
+	if len(predictions) > 1 {
+		if d := predictions[1].timestamp.Sub(predictions[0].timestamp); d > 0 {
+			step = d
+		}
+	}
+
+	total := 0.0
+	coveredUntil := from
+	for _, p := range predictions {
+		if p.timestamp.Before(from) || !p.timestamp.Before(to) {
+			continue
+		}
+		total += p.value
+		if end := p.timestamp.Add(step); end.After(coveredUntil) {
+			coveredUntil = end
+		}
+	}
+	if coveredUntil.After(to) {
+		coveredUntil = to
+	}
+
+	return total, coveredUntil
+}
+
+// applyBudgetRequest copies request fields onto a budget, deriving its scope and a default name
+func applyBudgetRequest(budget *models.EnergyBudget, req *models.EnergyBudgetRequest) {
+	budget.BuildingID = req.BuildingID
+	budget.DeviceID = req.DeviceID
+	budget.MonthlyLimitKWh = req.MonthlyLimitKWh
+	budget.NotifyUserIDs = req.NotifyUserIDs
+	budget.AutoGenerateScenario = req.AutoGenerateScenario
+
+	budget.Scope = models.BudgetScopeBuilding
+	if req.DeviceID != "" {
+		budget.Scope = models.BudgetScopeDevice
+	}
+
+	budget.Name = req.Name
+	if budget.Name == "" {
+		if req.DeviceID != "" {
+			budget.Name = fmt.Sprintf("Device %s monthly budget", req.DeviceID)
+		} else {
+			budget.Name = fmt.Sprintf("Building %s monthly budget", req.BuildingID)
+		}
+	}
+}
+
+func budgetPercent(value, limit float64) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	return roundTo2(value / limit * 100)
+}
+
+func roundTo2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func containsThreshold(thresholds []int, threshold int) bool {
+	for _, t := range thresholds {
+		if t == threshold {
+			return true
+		}
+	}
+	return false
+}
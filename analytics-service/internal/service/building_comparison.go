@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
+)
+
+const (
+	// maxComparisonRange bounds the period of a building comparison
+	maxComparisonRange = 366 * 24 * time.Hour
+	// comparisonSignificanceLevel is the p-value below which a building differs from its peers
+	comparisonSignificanceLevel = 0.05
+)
+
+// CompareBuildings normalizes the consumption of a cohort of buildings by floor area and
+// occupants over a period, ranks them by consumption per m² and compares each building with the
+// rest of the cohort. A building's daily consumption is its main meter, or the sum of its
+// submetered devices when that is higher. Unless restrictToAccessible is false, every building
+// must belong to a device the caller can see, and a tenant-scoped caller may only compare the
+// buildings of its organization.
+func (s *KPIService) CompareBuildings(ctx context.Context, req *models.BuildingComparisonRequest, restrictToAccessible bool, authToken string) (*models.BuildingComparison, error) {
+	from, to := req.From.UTC(), req.To.UTC()
+	if !to.After(from) {
+		return nil, fmt.Errorf("validation failed: from must be before to")
+	}
+	if to.Sub(from) > maxComparisonRange {
+		return nil, fmt.Errorf("validation failed: period must not exceed 366 days")
+	}
+
+	buildingIDs := make([]string, 0, len(req.Buildings))
+	seen := make(map[string]bool)
+	for _, building := range req.Buildings {
+		if seen[building.BuildingID] {
+			return nil, fmt.Errorf("validation failed: building %s is listed more than once", building.BuildingID)
+		}
+		if !tenant.AllowsBuilding(ctx, building.BuildingID) {
+			return nil, fmt.Errorf("access denied to building %s", building.BuildingID)
+		}
+		seen[building.BuildingID] = true
+		buildingIDs = append(buildingIDs, building.BuildingID)
+	}
+
+	if restrictToAccessible {
+		devices, err := s.iotClient.GetDevices(ctx, "", authToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve accessible buildings: %w", err)
+		}
+		accessible := make(map[string]bool)
+		for _, id := range extractBuildingIDs(devices) {
+			accessible[id] = true
+		}
+		for _, id := range buildingIDs {
+			if !accessible[id] {
+				return nil, fmt.Errorf("access denied to building %s", id)
+			}
+		}
+	}
+
+	days, err := s.timeSeriesRepo.SumDailyConsumptionByBuilding(ctx, buildingIDs, from, to.Add(-time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate consumption: %w", err)
+	}
+	daily := make(map[string][]float64)
+	for _, day := range days {
+		daily[day.BuildingID] = append(daily[day.BuildingID], math.Max(day.MainMeter, day.Metered))
+	}
+
+	comparison := &models.BuildingComparison{
+		From:      from,
+		To:        to,
+		Days:      int(math.Ceil(to.Sub(from).Hours() / 24)),
+		Buildings: make([]models.BuildingComparisonItem, 0, len(req.Buildings)),
+		UpdatedAt: time.Now(),
+	}
+
+	// Totals and daily consumption per m² of each building, and of the cohort
+	totals := make(map[string]float64)
+	intensities := make(map[string][]float64)
+	var cohortConsumption, cohortArea, cohortOccupantConsumption float64
+	cohortOccupants := 0
+	for _, building := range req.Buildings {
+		for _, value := range daily[building.BuildingID] {
+			totals[building.BuildingID] += value
+			intensities[building.BuildingID] = append(intensities[building.BuildingID], value/building.FloorArea)
+		}
+		cohortConsumption += totals[building.BuildingID]
+		cohortArea += building.FloorArea
+		if building.Occupants > 0 {
+			cohortOccupantConsumption += totals[building.BuildingID]
+			cohortOccupants += building.Occupants
+		}
+	}
+
+	perM2 := make([]float64, 0, len(req.Buildings))
+	for _, building := range req.Buildings {
+		total := totals[building.BuildingID]
+		item := models.BuildingComparisonItem{
+			BuildingID:       building.BuildingID,
+			FloorArea:        building.FloorArea,
+			Occupants:        building.Occupants,
+			DaysWithData:     len(daily[building.BuildingID]),
+			TotalConsumption: roundTo2(total),
+			ConsumptionPerM2: roundTo4(total / building.FloorArea),
+		}
+		perM2 = append(perM2, total/building.FloorArea)
+
+		peerConsumption := cohortConsumption - total
+		peerArea := cohortArea - building.FloorArea
+		peerPerM2 := peerConsumption / peerArea
+		item.PeerConsumptionPerM2 = roundTo4(peerPerM2)
+		item.DeltaPerM2Percent = deltaPercent(total/building.FloorArea, peerPerM2)
+
+		peerOccupantConsumption, peerOccupants := cohortOccupantConsumption, cohortOccupants
+		if building.Occupants > 0 {
+			perOccupant := total / float64(building.Occupants)
+			item.ConsumptionPerOccupant = roundedPtr(perOccupant)
+			peerOccupantConsumption -= total
+			peerOccupants -= building.Occupants
+		}
+		if peerOccupants > 0 {
+			peerPerOccupant := peerOccupantConsumption / float64(peerOccupants)
+			item.PeerConsumptionPerOccupant = roundedPtr(peerPerOccupant)
+			if building.Occupants > 0 {
+				item.DeltaPerOccupantPercent = deltaPercent(total/float64(building.Occupants), peerPerOccupant)
+			}
+		}
+
+		var peerIntensities []float64
+		for _, peer := range req.Buildings {
+			if peer.BuildingID != building.BuildingID {
+				peerIntensities = append(peerIntensities, intensities[peer.BuildingID]...)
+			}
+		}
+		item.Significance = welchTest(intensities[building.BuildingID], peerIntensities)
+
+		comparison.Buildings = append(comparison.Buildings, item)
+	}
+
+	sort.SliceStable(comparison.Buildings, func(i, j int) bool {
+		return comparison.Buildings[i].ConsumptionPerM2 > comparison.Buildings[j].ConsumptionPerM2
+	})
+	for i := range comparison.Buildings {
+		comparison.Buildings[i].Rank = i + 1
+	}
+
+	comparison.Cohort = models.CohortSummary{
+		BuildingCount:    len(req.Buildings),
+		TotalConsumption: roundTo2(cohortConsumption),
+		TotalFloorArea:   roundTo2(cohortArea),
+		ConsumptionPerM2: roundTo4(cohortConsumption / cohortArea),
+		MedianPerM2:      roundTo4(medianOf(perM2)),
+	}
+	if cohortOccupants > 0 {
+		comparison.Cohort.ConsumptionPerOccupant = roundedPtr(cohortOccupantConsumption / float64(cohortOccupants))
+	}
+
+	return comparison, nil
+}
+
+// welchTest compares the means of two samples with Welch's unequal variances t-test
+func welchTest(sample, peers []float64) models.ComparisonSignificance {
+	var result models.ComparisonSignificance
+	n1, n2 := float64(len(sample)), float64(len(peers))
+	if n1 < 2 || n2 < 2 {
+		return result
+	}
+
+	mean1, mean2 := meanOf(sample), meanOf(peers)
+	se1 := sampleVariance(sample, mean1) / n1
+	se2 := sampleVariance(peers, mean2) / n2
+	if se1+se2 == 0 {
+		return result
+	}
+
+	t := (mean1 - mean2) / math.Sqrt(se1+se2)
+	df := (se1 + se2) * (se1 + se2) / (se1*se1/(n1-1) + se2*se2/(n2-1))
+	pValue := regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t))
+
+	result.TStatistic = roundTo2(t)
+	result.Significant = pValue < comparisonSignificanceLevel
+	pValue = roundTo4(pValue)
+	result.PValue = &pValue
+	return result
+}
+
+// sampleVariance returns the unbiased variance of the values around their mean
+func sampleVariance(values []float64, mean float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += (value - mean) * (value - mean)
+	}
+	return sum / float64(len(values)-1)
+}
+
+// medianOf returns the median of the values
+func medianOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// deltaPercent returns how much higher value is than reference in percent, or nil without a reference
+func deltaPercent(value, reference float64) *float64 {
+	if reference == 0 || math.IsNaN(reference) {
+		return nil
+	}
+	return roundedPtr((value - reference) / reference * 100)
+}
+
+// roundedPtr returns a pointer to the value rounded to two decimals
+func roundedPtr(value float64) *float64 {
+	rounded := roundTo2(value)
+	return &rounded
+}
+
+// roundTo4 rounds a normalized value to four decimals
+func roundTo4(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
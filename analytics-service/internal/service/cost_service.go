@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
+)
+
+const (
+	defaultCostPeriod = "MONTHLY"
+	defaultCostRegion = "default"
+	// demandBillingDays is the length of the billing period demand charges are quoted for
+	demandBillingDays = 30
+	baseRateName      = "BASE"
+)
+
+// CostService prices energy use with time-of-use rates and demand charges. It is the single
+// place costs are calculated, so analytics and forecast savings agree on what energy costs.
+type CostService struct {
+	timeSeriesRepo *repository.TimeSeriesRepository
+	forecastClient interface {
+		GetCurrentTariff(ctx context.Context, region string, authToken string) (*models.Tariff, error)
+	}
+}
+
+// NewCostService creates a new cost service
+func NewCostService(
+	timeSeriesRepo *repository.TimeSeriesRepository,
+	forecastClient interface {
+		GetCurrentTariff(ctx context.Context, region string, authToken string) (*models.Tariff, error)
+	},
+) *CostService {
+	return &CostService{
+		timeSeriesRepo: timeSeriesRepo,
+		forecastClient: forecastClient,
+	}
+}
+
+// GetCost prices a building's hourly consumption over the last full period with the region's
+// tariff. Demand charges are quoted per month, so shorter periods are billed their share of them.
+func (s *CostService) GetCost(ctx context.Context, query *models.CostQuery, authToken string) (*models.CostBreakdown, error) {
+	period := query.Period
+	if period == "" {
+		period = defaultCostPeriod
+	}
+	days, ok := reportingPeriodDays[period]
+	if !ok {
+		return nil, fmt.Errorf("invalid period: %s", period)
+	}
+	region := query.Region
+	if region == "" {
+		region = defaultCostRegion
+	}
+
+	tariff, err := s.forecastClient.GetCurrentTariff(ctx, region, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tariff: %w", err)
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -days)
+
+	hours, err := s.timeSeriesRepo.SumHourlyConsumption(ctx, query.BuildingID, from, to.Add(-time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate consumption: %w", err)
+	}
+
+	// The main meter covers the whole building; without one, the submetered devices are all there is
+	intervals := make([]models.LoadInterval, 0, len(hours))
+	for _, hour := range hours {
+		intervals = append(intervals, models.LoadInterval{
+			Start:     hour.Timestamp,
+			Minutes:   60,
+			EnergyKWh: math.Max(hour.MainMeter, hour.Metered),
+		})
+	}
+
+	breakdown := PriceLoad(tariff, intervals, math.Min(float64(days)/demandBillingDays, 1))
+	breakdown.BuildingID = query.BuildingID
+	breakdown.Period = period
+	breakdown.From = from
+	breakdown.To = to
+	return breakdown, nil
+}
+
+// CompareCosts prices a baseline and an optimized load profile with the same tariff. A peak
+// reduction is credited with the full monthly demand charge, as that peak would set the bill.
+func (s *CostService) CompareCosts(req *models.CostComparisonRequest) *models.CostComparison {
+	baseline := PriceLoad(req.Tariff, req.Baseline, 1)
+	optimized := PriceLoad(req.Tariff, req.Optimized, 1)
+
+	return &models.CostComparison{
+		Baseline:      baseline,
+		Optimized:     optimized,
+		Currency:      baseline.Currency,
+		EnergySavings: roundTo2(baseline.EnergyCost - optimized.EnergyCost),
+		DemandSavings: roundTo2(baseline.DemandCost - optimized.DemandCost),
+		TotalSavings:  roundTo2(baseline.TotalCost - optimized.TotalCost),
+	}
+}
+
+// PriceLoad prices each interval's energy at the time-of-use rate in effect when it starts and
+// bills each demand charge on the highest average demand of an interval within its window,
+// scaled by demandShare
+func PriceLoad(tariff *models.Tariff, intervals []models.LoadInterval, demandShare float64) *models.CostBreakdown {
+	breakdown := &models.CostBreakdown{
+		Region:        tariff.Region,
+		Currency:      tariff.Currency,
+		Rates:         []models.RateCost{},
+		DemandCharges: []models.DemandChargeCost{},
+	}
+
+	rateIndex := make(map[string]int)
+	peaks := make([]models.DemandChargeCost, len(tariff.DemandCharges))
+	for i, charge := range tariff.DemandCharges {
+		peaks[i] = models.DemandChargeCost{Name: charge.Name, RatePerKW: charge.RatePerKW, Share: demandShare}
+	}
+
+	for _, interval := range intervals {
+		minutes := interval.Minutes
+		if minutes <= 0 {
+			minutes = 60
+		}
+		start := interval.Start.UTC()
+		end := start.Add(time.Duration(minutes) * time.Minute)
+		if breakdown.From.IsZero() || start.Before(breakdown.From) {
+			breakdown.From = start
+		}
+		if end.After(breakdown.To) {
+			breakdown.To = end
+		}
+
+		name, rate := rateAt(tariff, start)
+		i, ok := rateIndex[name]
+		if !ok {
+			i = len(breakdown.Rates)
+			rateIndex[name] = i
+			breakdown.Rates = append(breakdown.Rates, models.RateCost{Name: name, RatePerKWh: rate})
+		}
+		breakdown.Rates[i].EnergyKWh += interval.EnergyKWh
+		breakdown.Rates[i].Cost += interval.EnergyKWh * rate
+		breakdown.EnergyKWh += interval.EnergyKWh
+
+		demand := interval.EnergyKWh / (float64(minutes) / 60)
+		for i, charge := range tariff.DemandCharges {
+			if hourInWindow(start.Hour(), charge.StartHour, charge.EndHour) && demand > peaks[i].PeakKW {
+				peakAt := start
+				peaks[i].PeakKW = demand
+				peaks[i].PeakAt = &peakAt
+			}
+		}
+	}
+
+	for i := range breakdown.Rates {
+		breakdown.EnergyCost += breakdown.Rates[i].Cost
+		breakdown.Rates[i].EnergyKWh = roundTo2(breakdown.Rates[i].EnergyKWh)
+		breakdown.Rates[i].Cost = roundTo2(breakdown.Rates[i].Cost)
+	}
+	for _, peak := range peaks {
+		peak.Cost = peak.PeakKW * peak.RatePerKW * demandShare
+		breakdown.DemandCost += peak.Cost
+		peak.PeakKW = roundTo2(peak.PeakKW)
+		peak.Cost = roundTo2(peak.Cost)
+		breakdown.DemandCharges = append(breakdown.DemandCharges, peak)
+	}
+
+	total := breakdown.EnergyCost + breakdown.DemandCost
+	if breakdown.EnergyKWh > 0 {
+		breakdown.AverageRate = math.Round(total/breakdown.EnergyKWh*10000) / 10000
+	}
+	breakdown.EnergyKWh = roundTo2(breakdown.EnergyKWh)
+	breakdown.EnergyCost = roundTo2(breakdown.EnergyCost)
+	breakdown.DemandCost = roundTo2(breakdown.DemandCost)
+	breakdown.TotalCost = roundTo2(total)
+	return breakdown
+}
+
+// rateAt returns the name and rate of the energy rate in effect at a time (UTC). Like the
+// Forecast service, the last matching time-of-use window wins; outside every window the base
+// rate applies.
+func rateAt(tariff *models.Tariff, t time.Time) (string, float64) {
+	name, rate := baseRateName, baseRate(tariff)
+	for _, window := range tariff.TimeOfUseRates {
+		if !dayInWindow(int(t.Weekday()), window.DaysOfWeek) || !hourInWindow(t.Hour(), window.StartHour, window.EndHour) {
+			continue
+		}
+		name, rate = window.Name, window.RatePerKWh
+	}
+	return name, rate
+}
+
+// baseRate returns the rate outside every time-of-use window. Tariffs from external sources may
+// not state it; then a flat tariff's current rate, or the off-peak rate, stands in for it.
+func baseRate(tariff *models.Tariff) float64 {
+	switch {
+	case tariff.BaseRate > 0:
+		return tariff.BaseRate
+	case len(tariff.TimeOfUseRates) == 0 || tariff.OffPeakRate <= 0:
+		return tariff.CurrentRate
+	}
+	return tariff.OffPeakRate
+}
+
+// dayInWindow checks whether a weekday (0 = Sunday) is one of the window's days; no days means every day
+func dayInWindow(day int, days []int) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// hourInWindow checks whether an hour falls within [start, end), wrapping past midnight.
+// Equal start and end hours cover the whole day.
+func hourInWindow(hour, start, end int) bool {
+	if start == end {
+		return true
+	}
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
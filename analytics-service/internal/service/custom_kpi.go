@@ -0,0 +1,513 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"analytics-service/internal/events"
+	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
+)
+
+// kpiKeyPattern restricts custom KPI keys to identifiers usable as metric names
+var kpiKeyPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// builtInKPIMetrics are the metrics of the KPI calculation, which custom KPIs cannot replace
+var builtInKPIMetrics = map[string]bool{
+	"totalDevices": true, "onlineDevices": true, "deviceAvailability": true, "activeAnomalies": true,
+	"consumptionKwh": true, "heatingDegreeDays": true, "coolingDegreeDays": true, "consumptionPerDegreeDay": true,
+	"referenceConsumptionKwh": true, "normalizedConsumptionKwh": true, "consumptionChangePercent": true,
+	"normalizedConsumptionChangePercent": true, "degreeDayBaseTemperature": true,
+	"netConsumption": true, models.MetricGeneration: true, models.MetricGridImport: true, models.MetricGridExport: true,
+	models.MetricBatteryCharge: true, models.MetricBatteryDischarge: true,
+	"selfConsumptionRatio": true, "selfSufficiencyRatio": true, "batteryEfficiency": true,
+}
+
+// ListKPIVariables returns the variables KPI formulas can read
+func (s *KPIService) ListKPIVariables() []models.KPIVariable {
+	return KPIVariables()
+}
+
+// ValidateKPIFormula checks a formula and infers its unit without saving it
+func (s *KPIService) ValidateKPIFormula(req *models.KPIFormulaRequest) *models.KPIFormulaValidation {
+	return ValidateKPIFormula(req.Expression)
+}
+
+// CreateKPIDefinition creates a custom KPI. Definitions created within an organization apply to
+// its buildings, by default all the buildings it has when the KPI is created.
+func (s *KPIService) CreateKPIDefinition(ctx context.Context, req *models.KPIDefinitionRequest, userID string) (*models.KPIDefinition, error) {
+	definition := &models.KPIDefinition{CreatedBy: userID}
+	if err := applyKPIDefinitionRequest(ctx, definition, req); err != nil {
+		return nil, err
+	}
+	if scope := tenant.FromContext(ctx); scope != nil {
+		definition.OrgID = scope.OrgID
+		if len(definition.BuildingIDs) == 0 {
+			definition.BuildingIDs = append([]string{}, scope.BuildingIDs...)
+		}
+	}
+
+	return s.definitionRepo.Create(ctx, definition)
+}
+
+// GetKPIDefinition retrieves a custom KPI by ID
+func (s *KPIService) GetKPIDefinition(ctx context.Context, id string) (*models.KPIDefinition, error) {
+	return s.definitionRepo.FindByID(ctx, id)
+}
+
+// ListKPIDefinitions lists custom KPIs, optionally those applying to a building
+func (s *KPIService) ListKPIDefinitions(ctx context.Context, req *models.ListKPIDefinitionsRequest) ([]*models.KPIDefinition, int64, error) {
+	return s.definitionRepo.FindAll(ctx, req.BuildingID, req.Page, req.Limit)
+}
+
+// UpdateKPIDefinition replaces a custom KPI. Values already calculated keep their former
+// formula until the KPIs are next calculated.
+func (s *KPIService) UpdateKPIDefinition(ctx context.Context, id string, req *models.KPIDefinitionRequest) (*models.KPIDefinition, error) {
+	definition := &models.KPIDefinition{}
+	if err := applyKPIDefinitionRequest(ctx, definition, req); err != nil {
+		return nil, err
+	}
+	if scope := tenant.FromContext(ctx); scope != nil && len(definition.BuildingIDs) == 0 {
+		definition.BuildingIDs = append([]string{}, scope.BuildingIDs...)
+	}
+
+	return s.definitionRepo.Update(ctx, id, bson.M{
+		"key":             definition.Key,
+		"name":            definition.Name,
+		"description":     definition.Description,
+		"expression":      definition.Expression,
+		"unit":            definition.Unit,
+		"variables":       definition.Variables,
+		"building_ids":    definition.BuildingIDs,
+		"target":          definition.Target,
+		"notify_user_ids": definition.NotifyUserIDs,
+		"enabled":         definition.Enabled,
+	})
+}
+
+// DeleteKPIDefinition deletes a custom KPI
+func (s *KPIService) DeleteKPIDefinition(ctx context.Context, id string) error {
+	return s.definitionRepo.Delete(ctx, id)
+}
+
+// applyKPIDefinitionRequest validates a custom KPI request and copies it into a definition,
+// with the unit and variables of its formula
+func applyKPIDefinitionRequest(ctx context.Context, definition *models.KPIDefinition, req *models.KPIDefinitionRequest) error {
+	if !kpiKeyPattern.MatchString(req.Key) {
+		return fmt.Errorf("validation failed: key must start with a letter and contain only letters, digits and underscores")
+	}
+	if _, variable := kpiVariableUnits()[req.Key]; variable || builtInKPIMetrics[req.Key] {
+		return fmt.Errorf("validation failed: key %s is a built-in KPI or variable", req.Key)
+	}
+	for _, buildingID := range req.BuildingIDs {
+		if !tenant.AllowsBuilding(ctx, buildingID) {
+			return fmt.Errorf("validation failed: building %s does not belong to your organization", buildingID)
+		}
+	}
+
+	validation := ValidateKPIFormula(req.Expression)
+	if !validation.Valid {
+		return fmt.Errorf("validation failed: %s", strings.Join(validation.Errors, "; "))
+	}
+	if req.Unit != nil && *req.Unit != validation.Unit {
+		return fmt.Errorf("validation failed: the formula's unit is %q, not %q", validation.Unit, *req.Unit)
+	}
+
+	definition.Key = req.Key
+	definition.Name = req.Name
+	definition.Description = req.Description
+	definition.Expression = strings.TrimSpace(req.Expression)
+	definition.Unit = validation.Unit
+	definition.Variables = validation.Variables
+	definition.BuildingIDs = req.BuildingIDs
+	definition.Target = req.Target
+	definition.NotifyUserIDs = req.NotifyUserIDs
+	definition.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
+
+// GetBuildingAttributes retrieves the attributes of a building KPI formulas read
+func (s *KPIService) GetBuildingAttributes(ctx context.Context, buildingID string) (*models.BuildingAttributes, error) {
+	if !tenant.AllowsBuilding(ctx, buildingID) {
+		return nil, fmt.Errorf("building attributes not found")
+	}
+	return s.attributesRepo.FindByBuilding(ctx, buildingID)
+}
+
+// SetBuildingAttributes sets the attributes of a building KPI formulas read
+func (s *KPIService) SetBuildingAttributes(ctx context.Context, buildingID string, req *models.BuildingAttributesRequest, userID string) (*models.BuildingAttributes, error) {
+	if !tenant.AllowsBuilding(ctx, buildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", buildingID)
+	}
+	return s.attributesRepo.Upsert(ctx, &models.BuildingAttributes{
+		BuildingID: buildingID,
+		FloorArea:  req.FloorArea,
+		Occupants:  req.Occupants,
+		UpdatedBy:  userID,
+	})
+}
+
+// customKPIValue is a custom KPI evaluated for a building
+type customKPIValue struct {
+	definition *models.KPIDefinition
+	value      float64
+}
+
+// EvaluateCustomKPIs evaluates the custom KPIs of every building they apply to and stores them
+// with the building's daily KPIs, keeping the built-in metrics last calculated. KPIs applying to
+// every building are evaluated for the buildings with consumption in the last week.
+// Returns how many buildings were evaluated.
+func (s *KPIService) EvaluateCustomKPIs(ctx context.Context) (int, error) {
+	definitions, err := s.definitionRepo.FindEnabled(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(definitions) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	buildings := make(map[string]bool)
+	allBuildings := false
+	for _, definition := range definitions {
+		if len(definition.BuildingIDs) == 0 {
+			allBuildings = true
+		}
+		for _, buildingID := range definition.BuildingIDs {
+			buildings[buildingID] = true
+		}
+	}
+	if allBuildings {
+		to := now.UTC().Truncate(24 * time.Hour)
+		consumers, err := s.timeSeriesRepo.SumConsumptionByBuilding(ctx, to.AddDate(0, 0, -7), to, nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to find buildings with recent data: %w", err)
+		}
+		for _, consumer := range consumers {
+			buildings[consumer.BuildingID] = true
+		}
+	}
+
+	const period = "DAILY"
+	evaluated := 0
+	for buildingID := range buildings {
+		metrics := map[string]interface{}{}
+		var previous []models.KPITargetStatus
+		if kpi, err := s.kpiRepo.FindLatest(ctx, buildingID, period); err == nil {
+			metrics = kpi.Metrics
+			previous = kpi.Targets
+		}
+
+		from, to, _, _ := kpiComparisonWindows(period, now)
+		values := s.evaluateCustomKPIs(ctx, definitions, buildingID, from, to, metrics)
+		if len(values) == 0 {
+			continue
+		}
+
+		custom := make(map[string]interface{}, len(values))
+		for _, value := range values {
+			custom[value.definition.Key] = value.value
+		}
+		targets := kpiTargetStatuses(values)
+		if err := s.kpiRepo.MergeMetrics(ctx, buildingID, period, custom, targets); err != nil {
+			log.Printf("Failed to store custom KPIs of building %s: %v", buildingID, err)
+			continue
+		}
+		s.publishMissedTargets(ctx, buildingID, period, previous, values, now)
+		evaluated++
+	}
+
+	return evaluated, nil
+}
+
+// StartCustomKPIWorker periodically evaluates custom KPIs until the context is cancelled
+func (s *KPIService) StartCustomKPIWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.EvaluateCustomKPIs(ctx); err != nil {
+				log.Printf("Failed to evaluate custom KPIs: %v", err)
+			}
+		}
+	}
+}
+
+// evaluateCustomKPIs evaluates the definitions applying to a building over a period, reading the
+// built-in metrics already calculated. A KPI whose variables are not available, or that divides
+// by zero, is left out. When an organization's KPI has the key of a global one, it replaces it.
+func (s *KPIService) evaluateCustomKPIs(ctx context.Context, definitions []*models.KPIDefinition, buildingID string, from, to time.Time, metrics map[string]interface{}) []customKPIValue {
+	var values []customKPIValue
+	index := make(map[string]int)
+	var inputs map[string]float64
+
+	for _, definition := range definitions {
+		if !definition.AppliesTo(buildingID) {
+			continue
+		}
+		formula, err := ParseKPIFormula(definition.Expression)
+		if err != nil {
+			log.Printf("Invalid formula of custom KPI %s: %v", definition.Key, err)
+			continue
+		}
+		if inputs == nil {
+			inputs = s.formulaInputs(ctx, buildingID, from, to, metrics)
+		}
+		value, err := formula.Evaluate(inputs)
+		if err != nil {
+			log.Printf("Custom KPI %s of building %s not evaluated: %v", definition.Key, buildingID, err)
+			continue
+		}
+
+		result := customKPIValue{definition: definition, value: value}
+		if i, ok := index[definition.Key]; ok {
+			values[i] = result
+		} else {
+			index[definition.Key] = len(values)
+			values = append(values, result)
+		}
+	}
+
+	return values
+}
+
+// formulaInputs collects the values of the formula variables for a building over a period
+func (s *KPIService) formulaInputs(ctx context.Context, buildingID string, from, to time.Time, metrics map[string]interface{}) map[string]float64 {
+	inputs := make(map[string]float64)
+	for _, variable := range kpiVariables {
+		if variable.metric == "" {
+			continue
+		}
+		if value, ok := metricValue(metrics[variable.metric]); ok {
+			inputs[variable.Name] = value
+		}
+	}
+
+	flows, err := s.timeSeriesRepo.SumEnergyFlows(ctx, buildingID, from, to)
+	if err != nil {
+		log.Printf("Failed to total energy flows of building %s for custom KPIs: %v", buildingID, err)
+	} else {
+		inputs[VariableConsumption] = flows.Consumption
+		inputs[VariableGeneration] = flows.Generation
+		inputs[VariableGridImport] = flows.GridImport
+		inputs[VariableGridExport] = flows.GridExport
+	}
+
+	s.addBuildingInputs(ctx, buildingID, inputs)
+	addPeriodInputs(inputs, from, to)
+	return inputs
+}
+
+// addBuildingInputs adds the floor area and occupants of the building to the formula inputs
+func (s *KPIService) addBuildingInputs(ctx context.Context, buildingID string, inputs map[string]float64) {
+	if s.attributesRepo == nil {
+		return
+	}
+	attributes, err := s.attributesRepo.FindByBuilding(ctx, buildingID)
+	if err != nil {
+		return
+	}
+	if attributes.FloorArea > 0 {
+		inputs[VariableFloorArea] = attributes.FloorArea
+	}
+	if attributes.Occupants > 0 {
+		inputs[VariableOccupants] = float64(attributes.Occupants)
+	}
+}
+
+// addPeriodInputs adds the lengths of a period to the formula inputs
+func addPeriodInputs(inputs map[string]float64, from, to time.Time) {
+	occupied := 0
+	for hour := from.UTC().Truncate(time.Hour); hour.Before(to); hour = hour.Add(time.Hour) {
+		if isOccupiedHour(hour) {
+			occupied++
+		}
+	}
+	inputs[VariableOccupiedHours] = float64(occupied)
+	inputs[VariablePeriodHours] = to.Sub(from).Hours()
+	inputs[VariablePeriodDays] = to.Sub(from).Hours() / 24
+}
+
+// metricValue converts a stored KPI metric to a number
+func metricValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// kpiTargetStatuses measures the custom KPIs that have a target against it
+func kpiTargetStatuses(values []customKPIValue) []models.KPITargetStatus {
+	var statuses []models.KPITargetStatus
+	for _, value := range values {
+		target := value.definition.Target
+		if target == nil {
+			continue
+		}
+		statuses = append(statuses, models.KPITargetStatus{
+			Metric:     value.definition.Key,
+			Name:       value.definition.Name,
+			Value:      value.value,
+			Unit:       value.definition.Unit,
+			Comparison: target.Comparison,
+			Target:     target.Value,
+			Met:        target.Met(value.value),
+		})
+	}
+	return statuses
+}
+
+// publishMissedTargets publishes an event for every custom KPI that misses its target and did
+// not miss it when the KPIs were previously calculated, so a KPI staying off target alerts once
+func (s *KPIService) publishMissedTargets(ctx context.Context, buildingID, period string, previous []models.KPITargetStatus, values []customKPIValue, now time.Time) {
+	missedBefore := make(map[string]bool)
+	for _, status := range previous {
+		missedBefore[status.Metric] = !status.Met
+	}
+
+	for _, value := range values {
+		definition := value.definition
+		if definition.Target == nil || definition.Target.Met(value.value) || missedBefore[definition.Key] {
+			continue
+		}
+		log.Printf("Custom KPI %s of building %s is %.4g %s, missing its target of %s %.4g",
+			definition.Key, buildingID, value.value, definition.Unit, definition.Target.Comparison, definition.Target.Value)
+		s.eventBus.Publish(ctx, events.KPITargetMissed, &events.KPITargetMissedData{
+			DefinitionID:  definition.ID.Hex(),
+			Key:           definition.Key,
+			Name:          definition.Name,
+			BuildingID:    buildingID,
+			Period:        period,
+			Value:         value.value,
+			Unit:          definition.Unit,
+			Comparison:    string(definition.Target.Comparison),
+			Target:        definition.Target.Value,
+			NotifyUserIDs: definition.NotifyUserIDs,
+			EvaluatedAt:   now,
+		})
+	}
+}
+
+// hasWeeklyHistory reports whether every variable of a custom KPI has weekly history, so the
+// KPI can be trended
+func hasWeeklyHistory(definition *models.KPIDefinition) bool {
+	weekly := make(map[string]bool, len(kpiVariables))
+	for _, variable := range kpiVariables {
+		weekly[variable.Name] = variable.Weekly
+	}
+	for _, name := range definition.Variables {
+		if !weekly[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// analyzeCustomTrend evaluates a custom KPI for each week from the weekly totals of the daily
+// aggregates it reads, and fits a linear trend to the weeks it could be evaluated for
+func (s *KPIService) analyzeCustomTrend(ctx context.Context, buildingID string, definition *models.KPIDefinition, weeks int, now time.Time) (*models.KPITrend, error) {
+	formula, err := ParseKPIFormula(definition.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid formula of custom KPI %s: %w", definition.Key, err)
+	}
+
+	series := make(map[string]string) // variable -> daily time-series metric
+	var missing []string
+	for _, name := range formula.Variables() {
+		for _, variable := range kpiVariables {
+			if variable.Name != name {
+				continue
+			}
+			if !variable.Weekly {
+				missing = append(missing, name)
+			} else if variable.series != "" {
+				series[name] = variable.series
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("validation failed: trends of %s need weekly history, which %s do not have",
+			definition.Key, strings.Join(missing, ", "))
+	}
+
+	const week = 7 * 24 * time.Hour
+	to := now.UTC().Truncate(24 * time.Hour)
+	from := to.Add(-time.Duration(weeks) * week)
+
+	records, err := s.timeSeriesRepo.Query(ctx, &models.TimeSeriesQueryRequest{
+		BuildingID:      buildingID,
+		From:            from,
+		To:              to.Add(-time.Millisecond),
+		AggregationType: string(models.AggregationTypeDaily),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time-series: %w", err)
+	}
+
+	totals := make(map[string]*weeklyTotals, len(series))
+	for name := range series {
+		totals[name] = newWeeklyTotals(weeks)
+	}
+	for _, record := range records {
+		index := int(record.Timestamp.Sub(from) / week)
+		if index < 0 || index >= weeks {
+			continue
+		}
+		for name, metric := range series {
+			if value, ok := record.Metrics[metric].(float64); ok {
+				totals[name].add(index, value)
+			}
+		}
+	}
+
+	building := make(map[string]float64)
+	s.addBuildingInputs(ctx, buildingID, building)
+
+	var xs, ys []float64
+	for i := 0; i < weeks; i++ {
+		inputs := make(map[string]float64, len(building)+len(series)+3)
+		for name, value := range building {
+			inputs[name] = value
+		}
+		weekStart := from.Add(time.Duration(i) * week)
+		addPeriodInputs(inputs, weekStart, weekStart.Add(week))
+
+		hasData := len(series) == 0
+		for name, weekly := range totals {
+			if weekly.hasData[i] {
+				inputs[name] = weekly.values[i]
+				hasData = true
+			}
+		}
+		if !hasData {
+			continue
+		}
+		// Weeks without data for every series the KPI reads are left out by the evaluation
+		value, err := formula.Evaluate(inputs)
+		if err != nil {
+			continue
+		}
+		xs = append(xs, float64(i))
+		ys = append(ys, value)
+	}
+
+	trend, _ := newKPITrend(buildingID, definition.Key, from, to, xs, ys)
+	return trend, nil
+}
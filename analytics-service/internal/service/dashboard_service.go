@@ -2,45 +2,97 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
 	"time"
 
+	"analytics-service/internal/cache"
 	"analytics-service/internal/models"
 	"analytics-service/internal/repository"
+	"analytics-service/internal/tenant"
 )
 
+// recentOptimizationsLimit bounds the executed scenarios shown on a building dashboard
+const recentOptimizationsLimit = 5
+
+const (
+	// BuildingDashboardCacheKind is the cache kind of building dashboards, keyed by building ID
+	// and whether they were built for a user or a kiosk
+	BuildingDashboardCacheKind = "building_dashboard"
+	// OverviewDashboardCacheKind is the cache kind of overview dashboards, keyed by organization
+	OverviewDashboardCacheKind = "overview_dashboard"
+)
+
+// globalOverviewKey is the overview cache key of callers not scoped to an organization
+const globalOverviewKey = "global"
+
 // DashboardService handles dashboard business logic
 type DashboardService struct {
-	anomalyRepo *repository.AnomalyRepository
-	kpiRepo     *repository.KPIRepository
-	iotClient   interface {
+	anomalyRepo    *repository.AnomalyRepository
+	kpiRepo        *repository.KPIRepository
+	timeSeriesRepo *repository.TimeSeriesRepository
+	executionRepo  *repository.OptimizationExecutionRepository
+	goalRepo       *repository.SavingsGoalRepository
+	iotClient      interface {
 		GetDevices(ctx context.Context, buildingID string, authToken string) ([]map[string]interface{}, error)
+		GetBuildingDevices(ctx context.Context, buildingID string) ([]map[string]interface{}, error)
+		GetLocations(ctx context.Context, buildingID, level string, authToken string) ([]map[string]interface{}, error)
 	}
 	forecastClient interface {
 		GetLatestForecast(ctx context.Context, buildingID string, authToken string) (map[string]interface{}, error)
+		GetStoredForecast(ctx context.Context, buildingID string) (map[string]interface{}, error)
+		GetStoredForecasts(ctx context.Context, buildingIDs []string) (map[string]map[string]interface{}, error)
 	}
+	cache *cache.Cache
 }
 
-// NewDashboardService creates a new dashboard service
+// NewDashboardService creates a new dashboard service. The cache may be nil.
 func NewDashboardService(
 	anomalyRepo *repository.AnomalyRepository,
 	kpiRepo *repository.KPIRepository,
+	timeSeriesRepo *repository.TimeSeriesRepository,
+	executionRepo *repository.OptimizationExecutionRepository,
+	goalRepo *repository.SavingsGoalRepository,
 	iotClient interface {
 		GetDevices(ctx context.Context, buildingID string, authToken string) ([]map[string]interface{}, error)
+		GetBuildingDevices(ctx context.Context, buildingID string) ([]map[string]interface{}, error)
+		GetLocations(ctx context.Context, buildingID, level string, authToken string) ([]map[string]interface{}, error)
 	},
 	forecastClient interface {
 		GetLatestForecast(ctx context.Context, buildingID string, authToken string) (map[string]interface{}, error)
+		GetStoredForecast(ctx context.Context, buildingID string) (map[string]interface{}, error)
+		GetStoredForecasts(ctx context.Context, buildingIDs []string) (map[string]map[string]interface{}, error)
 	},
+	dashboardCache *cache.Cache,
 ) *DashboardService {
 	return &DashboardService{
 		anomalyRepo:    anomalyRepo,
 		kpiRepo:        kpiRepo,
+		timeSeriesRepo: timeSeriesRepo,
+		executionRepo:  executionRepo,
+		goalRepo:       goalRepo,
 		iotClient:      iotClient,
 		forecastClient: forecastClient,
+		cache:          dashboardCache,
 	}
 }
 
-// GetOverviewDashboard retrieves system-wide dashboard overview
+// GetOverviewDashboard retrieves system-wide dashboard overview. Overviews are cached per
+// organization, since the devices counted depend on the caller's organization.
 func (s *DashboardService) GetOverviewDashboard(ctx context.Context, authToken string) (*models.DashboardOverview, error) {
+	key := globalOverviewKey
+	if scope := tenant.FromContext(ctx); scope != nil {
+		key = scope.OrgID
+	}
+	return cache.GetOrLoad(ctx, s.cache, OverviewDashboardCacheKind, key, func() (*models.DashboardOverview, error) {
+		return s.buildOverviewDashboard(ctx, authToken)
+	})
+}
+
+// buildOverviewDashboard aggregates the overview dashboard
+func (s *DashboardService) buildOverviewDashboard(ctx context.Context, authToken string) (*models.DashboardOverview, error) {
 	// Get all devices
 	devices, err := s.iotClient.GetDevices(ctx, "", authToken)
 	if err != nil {
@@ -83,10 +135,50 @@ func (s *DashboardService) GetOverviewDashboard(ctx context.Context, authToken s
 }
 
 // GetBuildingDashboard retrieves building-specific dashboard
-// Integration: Fetches forecast data from Forecast service to show predictions on dashboard
+// Integration: Fetches forecast data from Forecast service to show predictions on dashboard.
+// Without an authToken (kiosk displays) other services are called with the service key.
+// Dashboards are cached only for callers allowed to access the building, so the cache never
+// serves another organization's data.
 func (s *DashboardService) GetBuildingDashboard(ctx context.Context, buildingID string, authToken string) (*models.BuildingDashboard, error) {
+	if !tenant.AllowsBuilding(ctx, buildingID) {
+		return s.buildBuildingDashboard(ctx, buildingID, authToken)
+	}
+	return cache.GetOrLoad(ctx, s.cache, BuildingDashboardCacheKind, buildingDashboardKey(buildingID, authToken == ""), func() (*models.BuildingDashboard, error) {
+		return s.buildBuildingDashboard(ctx, buildingID, authToken)
+	})
+}
+
+// InvalidateBuilding drops the cached dashboards showing a building after its anomalies or
+// executed optimizations changed. Overviews of organizations expire with their TTL.
+func (s *DashboardService) InvalidateBuilding(ctx context.Context, buildingID string) {
+	invalidateDashboards(ctx, s.cache, buildingID)
+}
+
+// invalidateDashboards drops the cached dashboards of a building and the global overview
+func invalidateDashboards(ctx context.Context, c *cache.Cache, buildingID string) {
+	c.Invalidate(ctx, BuildingDashboardCacheKind, buildingDashboardKey(buildingID, false), buildingDashboardKey(buildingID, true))
+	c.Invalidate(ctx, OverviewDashboardCacheKind, globalOverviewKey)
+}
+
+// buildingDashboardKey is the cache key of a building dashboard. Kiosk dashboards show the stored
+// forecast instead of a refreshed one, so they are cached separately.
+func buildingDashboardKey(buildingID string, kiosk bool) string {
+	if kiosk {
+		return buildingID + ":kiosk"
+	}
+	return buildingID
+}
+
+// buildBuildingDashboard aggregates the dashboard of a building
+func (s *DashboardService) buildBuildingDashboard(ctx context.Context, buildingID string, authToken string) (*models.BuildingDashboard, error) {
 	// Get devices for building
-	devices, err := s.iotClient.GetDevices(ctx, buildingID, authToken)
+	var devices []map[string]interface{}
+	var err error
+	if authToken == "" {
+		devices, err = s.iotClient.GetBuildingDevices(ctx, buildingID)
+	} else {
+		devices, err = s.iotClient.GetDevices(ctx, buildingID, authToken)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +204,12 @@ func (s *DashboardService) GetBuildingDashboard(ctx context.Context, buildingID
 	// This provides prediction data to display on the building dashboard
 	var forecastSummary map[string]interface{}
 	if s.forecastClient != nil {
-		forecast, err := s.forecastClient.GetLatestForecast(ctx, buildingID, authToken)
+		var forecast map[string]interface{}
+		if authToken == "" {
+			forecast, err = s.forecastClient.GetStoredForecast(ctx, buildingID)
+		} else {
+			forecast, err = s.forecastClient.GetLatestForecast(ctx, buildingID, authToken)
+		}
 		if err == nil && forecast != nil {
 			forecastSummary = forecast
 			// Extract key forecast metrics to add to KPIs
@@ -129,14 +226,337 @@ func (s *DashboardService) GetBuildingDashboard(ctx context.Context, buildingID
 		}
 	}
 
+	// Executed optimization scenarios are recorded from the event bus
+	recentOptimizations := []models.OptimizationExecutionResponse{}
+	executions, _ := s.executionRepo.FindRecentByBuilding(ctx, buildingID, recentOptimizationsLimit)
+	for _, execution := range executions {
+		recentOptimizations = append(recentOptimizations, *execution.ToResponse())
+	}
+
+	// Savings goals show the progress last stored by the tracking worker
+	savingsGoals := []models.SavingsGoalCard{}
+	goals, _ := s.goalRepo.FindByBuildingAndYear(ctx, buildingID, time.Now().UTC().Year())
+	for _, goal := range goals {
+		savingsGoals = append(savingsGoals, goal.ToCard())
+	}
+
 	return &models.BuildingDashboard{
-		BuildingID:        buildingID,
-		DeviceCount:       len(devices),
-		OnlineDeviceCount: onlineCount,
-		ActiveAnomalies:   int(activeAnomalies),
-		KPIs:              kpiMetrics,
-		ForecastSummary:   forecastSummary,
-		RecentTelemetry:   []models.TimeSeriesResponse{}, // Would be populated from time-series
-		UpdatedAt:         time.Now(),
+		BuildingID:          buildingID,
+		DeviceCount:         len(devices),
+		OnlineDeviceCount:   onlineCount,
+		ActiveAnomalies:     int(activeAnomalies),
+		KPIs:                kpiMetrics,
+		ForecastSummary:     forecastSummary,
+		RecentTelemetry:     []models.TimeSeriesResponse{}, // Would be populated from time-series
+		RecentOptimizations: recentOptimizations,
+		SavingsGoals:        savingsGoals,
+		UpdatedAt:           time.Now(),
 	}, nil
 }
+
+// PortfolioStore holds the per-building aggregations a portfolio dashboard is built from. Each
+// method answers for every building with a single query. An empty buildingIDs slice includes
+// every building.
+type PortfolioStore interface {
+	FindLatestPerBuilding(ctx context.Context, period string, buildingIDs []string) (map[string]map[string]interface{}, error)
+	CountActiveByBuilding(ctx context.Context, buildingIDs []string) ([]*models.BuildingAnomalyCounts, error)
+	SumConsumptionByBuilding(ctx context.Context, from, to time.Time, buildingIDs []string) ([]*models.BuildingConsumption, error)
+	SumHourlyConsumptionByBuilding(ctx context.Context, periods []models.BuildingPeriod) ([]*models.BuildingHourlyConsumption, error)
+	SumVerifiedSavingsByBuilding(ctx context.Context, from, to time.Time, buildingIDs []string) ([]*models.BuildingSavings, error)
+}
+
+// PortfolioForecasts fetches the latest stored forecasts of several buildings with one request
+type PortfolioForecasts interface {
+	GetStoredForecasts(ctx context.Context, buildingIDs []string) (map[string]map[string]interface{}, error)
+}
+
+// portfolioRepositories serves a portfolio store from the repositories of the dashboard service
+type portfolioRepositories struct {
+	*repository.KPIRepository
+	*repository.AnomalyRepository
+	*repository.TimeSeriesRepository
+	*repository.OptimizationExecutionRepository
+}
+
+// GetPortfolioDashboard aggregates KPIs, anomalies, consumption, forecast accuracy and savings
+// across buildings. When restrictToAccessible is set, only buildings that contain devices
+// visible to the caller are included; otherwise a tenant-scoped caller gets every building of
+// its organization.
+func (s *DashboardService) GetPortfolioDashboard(ctx context.Context, from, to time.Time, restrictToAccessible bool, authToken string) (*models.PortfolioDashboard, error) {
+	var buildingIDs []string
+	scope := tenant.FromContext(ctx)
+	switch {
+	case restrictToAccessible:
+		devices, err := s.iotClient.GetDevices(ctx, "", authToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve accessible buildings: %w", err)
+		}
+		buildingIDs = extractBuildingIDs(devices)
+	case scope != nil:
+		buildingIDs = append([]string{}, scope.BuildingIDs...)
+	}
+	if (restrictToAccessible || scope != nil) && len(buildingIDs) == 0 {
+		return &models.PortfolioDashboard{
+			From:      from,
+			To:        to,
+			Buildings: []models.BuildingPerformance{},
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	store := portfolioRepositories{s.kpiRepo, s.anomalyRepo, s.timeSeriesRepo, s.executionRepo}
+	return LoadPortfolioDashboard(ctx, store, s.forecastClient, from, to, buildingIDs)
+}
+
+// LoadPortfolioDashboard builds the portfolio dashboard of buildingIDs, or of every building when
+// it is empty. Each metric is computed with a single aggregation grouped by building, and the
+// forecasts of the buildings that reported consumption are fetched with one request and compared
+// with their hourly consumption in one more aggregation, so the number of queries does not grow
+// with the number of buildings.
+func LoadPortfolioDashboard(ctx context.Context, store PortfolioStore, forecastSource PortfolioForecasts, from, to time.Time, buildingIDs []string) (*models.PortfolioDashboard, error) {
+	kpisByBuilding, err := store.FindLatestPerBuilding(ctx, "DAILY", buildingIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate KPIs: %w", err)
+	}
+
+	anomalyCounts, err := store.CountActiveByBuilding(ctx, buildingIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate anomalies: %w", err)
+	}
+
+	consumption, err := store.SumConsumptionByBuilding(ctx, from, to, buildingIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate consumption: %w", err)
+	}
+
+	savings, err := store.SumVerifiedSavingsByBuilding(ctx, from, to, buildingIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate savings: %w", err)
+	}
+
+	forecasts, err := portfolioForecasts(ctx, store, forecastSource, consumption, from, to)
+	if err != nil {
+		// Forecast accuracy is left out rather than failing the dashboard
+		log.Printf("Failed to compare portfolio forecasts: %v", err)
+	}
+
+	return BuildPortfolioDashboard(from, to, buildingIDs, kpisByBuilding, anomalyCounts, consumption, savings, forecasts), nil
+}
+
+// portfolioForecasts compares the latest stored forecast of each building that reported
+// consumption with the consumption measured over its intervals in the range. Buildings without a
+// forecast in the range are left out.
+func portfolioForecasts(ctx context.Context, store PortfolioStore, forecastSource PortfolioForecasts, consumption []*models.BuildingConsumption, from, to time.Time) (map[string]*models.BuildingForecast, error) {
+	summaries := make(map[string]*models.BuildingForecast)
+	if len(consumption) == 0 {
+		return summaries, nil
+	}
+
+	buildingIDs := make([]string, 0, len(consumption))
+	for _, total := range consumption {
+		buildingIDs = append(buildingIDs, total.BuildingID)
+	}
+	forecasts, err := forecastSource.GetStoredForecasts(ctx, buildingIDs)
+	if err != nil {
+		return summaries, err
+	}
+
+	periods := make([]models.BuildingPeriod, 0, len(forecasts))
+	for _, buildingID := range buildingIDs {
+		forecast, ok := forecasts[buildingID]
+		if !ok {
+			continue
+		}
+		if intervals := forecastIntervals(forecast, from, to); len(intervals) > 0 {
+			periods = append(periods, models.BuildingPeriod{
+				BuildingID: buildingID,
+				From:       intervals[0].start,
+				To:         intervals[len(intervals)-1].end.Add(-time.Millisecond),
+			})
+		}
+	}
+	if len(periods) == 0 {
+		return summaries, nil
+	}
+
+	rows, err := store.SumHourlyConsumptionByBuilding(ctx, periods)
+	if err != nil {
+		return summaries, err
+	}
+	hours := make(map[string][]*models.HourlyConsumption)
+	for _, row := range rows {
+		hours[row.BuildingID] = append(hours[row.BuildingID], &models.HourlyConsumption{
+			Timestamp: row.Timestamp,
+			MainMeter: row.MainMeter,
+			Metered:   row.Metered,
+		})
+	}
+
+	for _, period := range periods {
+		summaries[period.BuildingID] = SummarizeBuildingForecast(forecasts[period.BuildingID], hours[period.BuildingID], from, to)
+	}
+	return summaries, nil
+}
+
+// SummarizeBuildingForecast totals the predictions of a forecast in the range, and the predicted
+// and measured consumption of the intervals hourly consumption was reported for
+func SummarizeBuildingForecast(forecast map[string]interface{}, hours []*models.HourlyConsumption, from, to time.Time) *models.BuildingForecast {
+	intervals := forecastIntervals(forecast, from, to)
+	measureIntervals(intervals, hours)
+
+	summary := &models.BuildingForecast{}
+	for _, interval := range intervals {
+		summary.Predicted += interval.predicted
+		if interval.measured {
+			summary.MeasuredPredicted += interval.predicted
+			summary.MeasuredActual += interval.actual
+		}
+	}
+	return summary
+}
+
+// BuildPortfolioDashboard merges the per-building aggregation results into a portfolio
+// dashboard, ranking the worst performers first. Buildings in buildingIDs are included even
+// without data. Forecast deviations compare the intervals consumption was reported for.
+func BuildPortfolioDashboard(
+	from, to time.Time,
+	buildingIDs []string,
+	kpisByBuilding map[string]map[string]interface{},
+	anomalyCounts []*models.BuildingAnomalyCounts,
+	consumption []*models.BuildingConsumption,
+	savings []*models.BuildingSavings,
+	forecasts map[string]*models.BuildingForecast,
+) *models.PortfolioDashboard {
+	// Merge aggregation results by building
+	buildings := make(map[string]*models.BuildingPerformance)
+	getBuilding := func(buildingID string) *models.BuildingPerformance {
+		if b, ok := buildings[buildingID]; ok {
+			return b
+		}
+		b := &models.BuildingPerformance{
+			BuildingID: buildingID,
+			KPIs:       make(map[string]interface{}),
+			Links:      buildingDrillDownLinks(buildingID),
+		}
+		buildings[buildingID] = b
+		return b
+	}
+
+	for _, id := range buildingIDs {
+		getBuilding(id)
+	}
+	for buildingID, metrics := range kpisByBuilding {
+		if metrics != nil {
+			getBuilding(buildingID).KPIs = metrics
+		}
+	}
+	for _, counts := range anomalyCounts {
+		b := getBuilding(counts.BuildingID)
+		b.ActiveAnomalies = counts.Active
+		b.CriticalAnomalies = counts.Critical
+	}
+	for _, total := range consumption {
+		getBuilding(total.BuildingID).TotalConsumption = total.Actual
+	}
+	for _, total := range savings {
+		getBuilding(total.BuildingID).Savings = total.SavingsKWh
+	}
+
+	dashboard := &models.PortfolioDashboard{
+		From:      from,
+		To:        to,
+		Buildings: make([]models.BuildingPerformance, 0, len(buildings)),
+		UpdatedAt: time.Now(),
+	}
+
+	var measuredActual, measuredPredicted float64
+	for _, b := range buildings {
+		if forecast := forecasts[b.BuildingID]; forecast != nil {
+			b.ForecastConsumption = forecast.Predicted
+			b.ForecastDeviationPercent = forecastDeviationPercent(forecast.MeasuredActual, forecast.MeasuredPredicted)
+			measuredActual += forecast.MeasuredActual
+			measuredPredicted += forecast.MeasuredPredicted
+		}
+		b.PerformanceScore = performanceScore(b)
+
+		dashboard.Totals.TotalConsumption += b.TotalConsumption
+		dashboard.Totals.ForecastConsumption += b.ForecastConsumption
+		dashboard.Totals.TotalSavings += b.Savings
+		dashboard.Totals.ActiveAnomalies += b.ActiveAnomalies
+		dashboard.Totals.CriticalAnomalies += b.CriticalAnomalies
+
+		dashboard.Buildings = append(dashboard.Buildings, *b)
+	}
+	dashboard.Totals.BuildingCount = len(dashboard.Buildings)
+	dashboard.Totals.ForecastDeviationPercent = forecastDeviationPercent(measuredActual, measuredPredicted)
+
+	// Rank worst performers first; break ties by highest consumption
+	sort.SliceStable(dashboard.Buildings, func(i, j int) bool {
+		a, b := dashboard.Buildings[i], dashboard.Buildings[j]
+		if a.PerformanceScore != b.PerformanceScore {
+			return a.PerformanceScore < b.PerformanceScore
+		}
+		if a.TotalConsumption != b.TotalConsumption {
+			return a.TotalConsumption > b.TotalConsumption
+		}
+		return a.BuildingID < b.BuildingID
+	})
+	for i := range dashboard.Buildings {
+		dashboard.Buildings[i].Rank = i + 1
+	}
+
+	return dashboard
+}
+
+// extractBuildingIDs collects the distinct building IDs from a device list
+func extractBuildingIDs(devices []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var buildingIDs []string
+	for _, device := range devices {
+		buildingID, _ := device["buildingId"].(string)
+		if buildingID == "" {
+			if location, ok := device["location"].(map[string]interface{}); ok {
+				buildingID, _ = location["buildingId"].(string)
+			}
+		}
+		if buildingID != "" && !seen[buildingID] {
+			seen[buildingID] = true
+			buildingIDs = append(buildingIDs, buildingID)
+		}
+	}
+	return buildingIDs
+}
+
+// buildingDrillDownLinks returns API links for drilling into a single building
+func buildingDrillDownLinks(buildingID string) map[string]string {
+	return map[string]string{
+		"dashboard": "/api/v1/analytics/dashboards/building/" + buildingID,
+		"kpis":      "/api/v1/analytics/kpi/" + buildingID,
+		"anomalies": "/api/v1/analytics/anomalies?buildingId=" + buildingID,
+	}
+}
+
+// forecastDeviationPercent returns how far actual consumption deviated from the forecast
+func forecastDeviationPercent(actual, forecast float64) float64 {
+	if forecast == 0 {
+		return 0
+	}
+	return math.Round((actual-forecast)/forecast*10000) / 100
+}
+
+// performanceScore rates a building from 0 (worst) to 100 (best).
+// Critical anomalies weigh heaviest, followed by other open anomalies,
+// consumption above forecast and low device availability.
+func performanceScore(b *models.BuildingPerformance) float64 {
+	penalty := float64(b.CriticalAnomalies)*15 + float64(b.ActiveAnomalies-b.CriticalAnomalies)*5
+
+	if b.ForecastDeviationPercent > 0 {
+		penalty += math.Min(b.ForecastDeviationPercent, 50)
+	}
+
+	if availability, ok := b.KPIs["deviceAvailability"].(float64); ok && availability < 100 {
+		penalty += (100 - availability) * 0.25
+	}
+
+	return math.Max(0, math.Round((100-penalty)*100)/100)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+
+	"analytics-service/internal/integrations"
+	"analytics-service/internal/repository"
+)
+
+// DeactivationService releases what users deactivated in the Security service own in this service
+type DeactivationService struct {
+	budgetRepo      *repository.BudgetRepository
+	savingsGoalRepo *repository.SavingsGoalRepository
+	securityClient  *integrations.SecurityClient
+}
+
+// NewDeactivationService creates a new deactivation service
+func NewDeactivationService(budgetRepo *repository.BudgetRepository, savingsGoalRepo *repository.SavingsGoalRepository, securityClient *integrations.SecurityClient) *DeactivationService {
+	return &DeactivationService{
+		budgetRepo:      budgetRepo,
+		savingsGoalRepo: savingsGoalRepo,
+		securityClient:  securityClient,
+	}
+}
+
+// ReleaseUser stops budget threshold and savings goal milestone alerts to a deactivated user and
+// records the result in the audit log. Budgets and goals the user created stay in place, as they
+// belong to the building.
+func (s *DeactivationService) ReleaseUser(ctx context.Context, userID, actorID string) error {
+	removed, err := s.budgetRepo.RemoveNotifyUser(ctx, userID)
+	var goalsRemoved int64
+	if err == nil {
+		goalsRemoved, err = s.savingsGoalRepo.RemoveNotifyUser(ctx, userID)
+	}
+
+	status, errorMsg := "SUCCESS", ""
+	if err != nil {
+		status, errorMsg = "FAILURE", err.Error()
+	}
+	s.securityClient.AuditLog(
+		ctx, actorID, "", "USER_DEACTIVATION_CASCADE", "user", userID,
+		status, errorMsg, "", "", "", "",
+		map[string]interface{}{"budgetAlertsRemoved": removed, "savingsGoalAlertsRemoved": goalsRemoved},
+	)
+
+	return err
+}
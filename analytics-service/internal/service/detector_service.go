@@ -0,0 +1,456 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
+)
+
+const (
+	// defaultComparisonPeriod is how far back a shadow configuration is compared by default
+	defaultComparisonPeriod = 7 * 24 * time.Hour
+	// defaultComparisonTolerance is how far apart a shadow alert and a live anomaly may be detected
+	// and still match
+	defaultComparisonTolerance = 60
+	// maxDetectorIntervals bounds the consecutive forecast intervals a deviation may require
+	maxDetectorIntervals = 24
+)
+
+// Built-in thresholds of the detectors without a stored live configuration
+var (
+	defaultMinTemperature       = 10.0
+	defaultMaxTemperature       = 30.0
+	defaultConsumptionThreshold = 1000.0
+)
+
+// DetectorService manages anomaly detector configurations. Shadow configurations run alongside
+// the live ones and record the anomalies they would have raised, so they can be compared with the
+// anomalies operators reviewed before they are promoted.
+type DetectorService struct {
+	configRepo          *repository.DetectorConfigRepository
+	shadowRepo          *repository.ShadowAnomalyRepository
+	anomalyRepo         *repository.AnomalyRepository
+	defaultMinIntervals int
+}
+
+// NewDetectorService creates a new detector service. defaultMinIntervals is the consecutive
+// forecast intervals a deviation needs while no forecast deviation configuration is live.
+func NewDetectorService(
+	configRepo *repository.DetectorConfigRepository,
+	shadowRepo *repository.ShadowAnomalyRepository,
+	anomalyRepo *repository.AnomalyRepository,
+	defaultMinIntervals int,
+) *DetectorService {
+	return &DetectorService{
+		configRepo:          configRepo,
+		shadowRepo:          shadowRepo,
+		anomalyRepo:         anomalyRepo,
+		defaultMinIntervals: defaultMinIntervals,
+	}
+}
+
+// DefaultDetectorConfigs returns the built-in configuration of every detector
+func DefaultDetectorConfigs(minIntervals int) map[string]*models.DetectorConfig {
+	return map[string]*models.DetectorConfig{
+		models.DetectorTemperatureRange: {
+			Detector: models.DetectorTemperatureRange,
+			Name:     "Built-in temperature range",
+			Mode:     models.DetectorModeLive,
+			Params:   models.DetectorParams{MinTemperature: &defaultMinTemperature, MaxTemperature: &defaultMaxTemperature},
+		},
+		models.DetectorConsumptionSpike: {
+			Detector: models.DetectorConsumptionSpike,
+			Name:     "Built-in consumption spike",
+			Mode:     models.DetectorModeLive,
+			Params:   models.DetectorParams{ConsumptionThreshold: &defaultConsumptionThreshold},
+		},
+		models.DetectorForecastDeviation: {
+			Detector: models.DetectorForecastDeviation,
+			Name:     "Built-in forecast deviation",
+			Mode:     models.DetectorModeLive,
+			Params:   models.DetectorParams{MinIntervals: minIntervals},
+		},
+	}
+}
+
+// LiveConfigs returns the live configuration of every detector, falling back to the built-in
+// configuration for detectors without a stored one
+func (s *DetectorService) LiveConfigs(ctx context.Context) (map[string]*models.DetectorConfig, error) {
+	live := DefaultDetectorConfigs(s.defaultMinIntervals)
+	stored, err := s.configRepo.FindLive(ctx)
+	if err != nil {
+		return live, err
+	}
+	for detector, config := range stored {
+		live[detector] = config
+	}
+	return live, nil
+}
+
+// ShadowConfigs returns the shadow configurations, grouped by detector
+func (s *DetectorService) ShadowConfigs(ctx context.Context) (map[string][]*models.DetectorConfig, error) {
+	configs, err := s.configRepo.FindAll(ctx, "", models.DetectorModeShadow)
+	if err != nil {
+		return nil, err
+	}
+	shadows := make(map[string][]*models.DetectorConfig)
+	for _, config := range configs {
+		shadows[config.Detector] = append(shadows[config.Detector], config)
+	}
+	return shadows, nil
+}
+
+// CreateConfig creates a shadow configuration. Thresholds left out are taken from the detector's
+// live configuration.
+func (s *DetectorService) CreateConfig(ctx context.Context, req *models.DetectorConfigRequest, userID string) (*models.DetectorConfig, error) {
+	live, err := s.LiveConfigs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load live configurations: %w", err)
+	}
+
+	params := mergeDetectorParams(live[req.Detector].Params, req.Params)
+	if err := validateDetectorParams(req.Detector, params); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	return s.configRepo.Create(ctx, &models.DetectorConfig{
+		ConfigID:  uuid.New().String(),
+		Detector:  req.Detector,
+		Name:      req.Name,
+		Mode:      models.DetectorModeShadow,
+		Params:    params,
+		CreatedBy: userID,
+	})
+}
+
+// GetConfig retrieves a detector configuration
+func (s *DetectorService) GetConfig(ctx context.Context, configID string) (*models.DetectorConfig, error) {
+	return s.configRepo.FindByConfigID(ctx, configID)
+}
+
+// ListConfigs lists detector configurations, newest first
+func (s *DetectorService) ListConfigs(ctx context.Context, req *models.ListDetectorConfigsRequest) ([]*models.DetectorConfig, error) {
+	return s.configRepo.FindAll(ctx, req.Detector, models.DetectorMode(req.Mode))
+}
+
+// UpdateConfig changes a shadow configuration's thresholds. Live configurations are changed by
+// promoting a shadow configuration. The anomalies recorded with the old thresholds are discarded.
+func (s *DetectorService) UpdateConfig(ctx context.Context, configID string, req *models.UpdateDetectorConfigRequest) (*models.DetectorConfig, error) {
+	config, err := s.shadowConfig(ctx, configID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := mergeDetectorParams(config.Params, req.Params)
+	if err := validateDetectorParams(config.Detector, params); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	updated, err := s.configRepo.Update(ctx, configID, models.DetectorModeShadow, bson.M{"name": req.Name, "params": params})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.shadowRepo.DeleteByConfig(ctx, configID); err != nil {
+		log.Printf("Failed to discard shadow anomalies of detector configuration %s: %v", configID, err)
+	}
+	return updated, nil
+}
+
+// DeleteConfig removes a shadow configuration and the anomalies it recorded
+func (s *DetectorService) DeleteConfig(ctx context.Context, configID string) error {
+	if _, err := s.shadowConfig(ctx, configID); err != nil {
+		return err
+	}
+	if err := s.configRepo.Delete(ctx, configID, models.DetectorModeShadow); err != nil {
+		return err
+	}
+	return s.shadowRepo.DeleteByConfig(ctx, configID)
+}
+
+// PromoteConfig makes a shadow configuration its detector's live configuration and retires the
+// one it replaces. The anomalies it recorded in shadow mode are kept for reference.
+func (s *DetectorService) PromoteConfig(ctx context.Context, configID, userID string) (*models.DetectorConfig, error) {
+	config, err := s.shadowConfig(ctx, configID)
+	if err != nil {
+		return nil, err
+	}
+
+	promoted, err := s.configRepo.Update(ctx, configID, models.DetectorModeShadow, bson.M{
+		"mode":        models.DetectorModeLive,
+		"promoted_by": userID,
+		"promoted_at": time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.configRepo.RetireLive(ctx, config.Detector, configID); err != nil {
+		return nil, fmt.Errorf("failed to retire the previous live configuration: %w", err)
+	}
+	return promoted, nil
+}
+
+// shadowConfig retrieves a configuration that must be in shadow mode
+func (s *DetectorService) shadowConfig(ctx context.Context, configID string) (*models.DetectorConfig, error) {
+	config, err := s.configRepo.FindByConfigID(ctx, configID)
+	if err != nil {
+		return nil, err
+	}
+	if config.Mode != models.DetectorModeShadow {
+		return nil, fmt.Errorf("validation failed: configuration is %s; only shadow configurations can be changed, deleted or promoted", config.Mode)
+	}
+	return config, nil
+}
+
+// RecordShadowAnomaly stores an anomaly a shadow configuration would have raised. Failures are
+// logged, as shadow detection must never disturb live detection.
+func (s *DetectorService) RecordShadowAnomaly(ctx context.Context, config *models.DetectorConfig, deviceID, buildingID string, severity models.AnomalySeverity, details map[string]interface{}) {
+	err := s.shadowRepo.Create(ctx, &models.ShadowAnomaly{
+		ConfigID:   config.ConfigID,
+		Detector:   config.Detector,
+		DeviceID:   deviceID,
+		BuildingID: buildingID,
+		Type:       models.DetectorAnomalyTypes[config.Detector],
+		Severity:   severity,
+		Details:    details,
+		DetectedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to record shadow anomaly of detector configuration %s: %v", config.ConfigID, err)
+	}
+}
+
+// ShadowForecastRunRecorded checks whether a configuration already recorded the forecast deviation
+// run starting at runStart
+func (s *DetectorService) ShadowForecastRunRecorded(ctx context.Context, configID, buildingID, forecastID string, runStart time.Time) (bool, error) {
+	return s.shadowRepo.ExistsForForecastRun(ctx, configID, buildingID, forecastID, runStart)
+}
+
+// ListShadowAnomalies lists the anomalies a configuration recorded in shadow mode, newest first
+func (s *DetectorService) ListShadowAnomalies(ctx context.Context, configID string, page, limit int) ([]*models.ShadowAnomaly, int64, error) {
+	if _, err := s.configRepo.FindByConfigID(ctx, configID); err != nil {
+		return nil, 0, err
+	}
+	return s.shadowRepo.FindByConfig(ctx, configID, page, limit)
+}
+
+// CompareConfig scores a shadow configuration's alerts and the live detector's anomalies over a
+// period against the anomalies operators acknowledged, resolved or marked as false positives.
+// The period defaults to the last seven days.
+func (s *DetectorService) CompareConfig(ctx context.Context, configID string, req *models.DetectorComparisonRequest) (*models.DetectorComparison, error) {
+	config, err := s.configRepo.FindByConfigID(ctx, configID)
+	if err != nil {
+		return nil, err
+	}
+
+	to := req.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := req.From
+	if from.IsZero() {
+		from = to.Add(-defaultComparisonPeriod)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("validation failed: from must be before to")
+	}
+	tolerance := req.ToleranceMinutes
+	if tolerance == 0 {
+		tolerance = defaultComparisonTolerance
+	}
+
+	live, err := s.LiveConfigs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load live configurations: %w", err)
+	}
+	anomalyType := models.DetectorAnomalyTypes[config.Detector]
+	anomalies, err := s.anomalyRepo.FindByTypeInRange(ctx, anomalyType, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load anomalies: %w", err)
+	}
+	alerts, err := s.shadowRepo.FindByConfigInRange(ctx, configID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shadow anomalies: %w", err)
+	}
+
+	comparison := &models.DetectorComparison{
+		Config:           config,
+		LiveConfig:       live[config.Detector],
+		AnomalyType:      anomalyType,
+		From:             from,
+		To:               to,
+		ToleranceMinutes: tolerance,
+		Shadow:           ScoreShadowAlerts(alerts, anomalies, time.Duration(tolerance)*time.Minute),
+		Live:             scoreLiveAnomalies(anomalies),
+	}
+	for _, anomaly := range anomalies {
+		if isConfirmedAnomaly(anomaly) {
+			comparison.ConfirmedAnomalies++
+		}
+	}
+	return comparison, nil
+}
+
+// ScoreShadowAlerts scores shadow alerts against live anomalies. An alert matches the anomalies of
+// the same building and device detected at most tolerance apart; it is a true positive when one of
+// them was confirmed, a false positive when they were all marked false positives or when it matches
+// none, and unreviewed otherwise.
+func ScoreShadowAlerts(alerts []*models.ShadowAnomaly, anomalies []*models.Anomaly, tolerance time.Duration) models.DetectionQuality {
+	bySource := make(map[string][]*models.Anomaly)
+	confirmed := 0
+	for _, anomaly := range anomalies {
+		key := anomaly.BuildingID + "/" + anomaly.DeviceID
+		bySource[key] = append(bySource[key], anomaly)
+		if isConfirmedAnomaly(anomaly) {
+			confirmed++
+		}
+	}
+
+	quality := models.DetectionQuality{Alerts: len(alerts)}
+	found := make(map[*models.Anomaly]bool)
+	for _, alert := range alerts {
+		var matched, matchedConfirmed, matchedFalsePositive bool
+		for _, anomaly := range bySource[alert.BuildingID+"/"+alert.DeviceID] {
+			if math.Abs(float64(alert.DetectedAt.Sub(anomaly.DetectedAt))) > float64(tolerance) {
+				continue
+			}
+			matched = true
+			switch {
+			case isConfirmedAnomaly(anomaly):
+				matchedConfirmed = true
+				found[anomaly] = true
+			case anomaly.Status == models.AnomalyStatusFalsePositive:
+				matchedFalsePositive = true
+			}
+		}
+
+		switch {
+		case matchedConfirmed:
+			quality.TruePositives++
+		case matchedFalsePositive:
+			quality.FalsePositives++
+		case !matched:
+			quality.Novel++
+		default:
+			quality.Unreviewed++
+		}
+	}
+
+	quality.ConfirmedFound = len(found)
+	quality.Precision = ratio(quality.TruePositives, quality.TruePositives+quality.FalsePositives+quality.Novel)
+	quality.Recall = ratio(quality.ConfirmedFound, confirmed)
+	return quality
+}
+
+// scoreLiveAnomalies scores the live detector's anomalies by how operators reviewed them. Every
+// confirmed anomaly was found by the live detector, so its recall is complete.
+func scoreLiveAnomalies(anomalies []*models.Anomaly) models.DetectionQuality {
+	quality := models.DetectionQuality{Alerts: len(anomalies)}
+	for _, anomaly := range anomalies {
+		switch {
+		case isConfirmedAnomaly(anomaly):
+			quality.TruePositives++
+		case anomaly.Status == models.AnomalyStatusFalsePositive:
+			quality.FalsePositives++
+		default:
+			quality.Unreviewed++
+		}
+	}
+	quality.ConfirmedFound = quality.TruePositives
+	quality.Precision = ratio(quality.TruePositives, quality.TruePositives+quality.FalsePositives)
+	quality.Recall = ratio(quality.ConfirmedFound, quality.TruePositives)
+	return quality
+}
+
+// isConfirmedAnomaly reports whether operators confirmed an anomaly by acknowledging or resolving it
+func isConfirmedAnomaly(anomaly *models.Anomaly) bool {
+	return anomaly.Status == models.AnomalyStatusAcknowledged || anomaly.Status == models.AnomalyStatusResolved
+}
+
+// ratio returns part / total rounded to four decimals, or nil when total is zero
+func ratio(part, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	value := math.Round(float64(part)/float64(total)*10000) / 10000
+	return &value
+}
+
+// mergeDetectorParams overrides base thresholds with those set in changes
+func mergeDetectorParams(base, changes models.DetectorParams) models.DetectorParams {
+	if changes.MinTemperature != nil {
+		base.MinTemperature = changes.MinTemperature
+	}
+	if changes.MaxTemperature != nil {
+		base.MaxTemperature = changes.MaxTemperature
+	}
+	if changes.ConsumptionThreshold != nil {
+		base.ConsumptionThreshold = changes.ConsumptionThreshold
+	}
+	if changes.MinIntervals != 0 {
+		base.MinIntervals = changes.MinIntervals
+	}
+	return base
+}
+
+// validateDetectorParams checks that a detector has the thresholds it needs
+func validateDetectorParams(detector string, params models.DetectorParams) error {
+	switch detector {
+	case models.DetectorTemperatureRange:
+		if params.MinTemperature == nil || params.MaxTemperature == nil {
+			return fmt.Errorf("minTemperature and maxTemperature are required")
+		}
+		if *params.MinTemperature >= *params.MaxTemperature {
+			return fmt.Errorf("minTemperature must be below maxTemperature")
+		}
+	case models.DetectorConsumptionSpike:
+		if params.ConsumptionThreshold == nil || *params.ConsumptionThreshold <= 0 {
+			return fmt.Errorf("consumptionThreshold must be positive")
+		}
+	case models.DetectorForecastDeviation:
+		if params.MinIntervals < 1 || params.MinIntervals > maxDetectorIntervals {
+			return fmt.Errorf("minIntervals must be between 1 and %d", maxDetectorIntervals)
+		}
+	default:
+		return fmt.Errorf("unknown detector %s", detector)
+	}
+	return nil
+}
+
+// evaluateReading applies a temperature range or consumption spike configuration to the metrics
+// of a telemetry reading. Returns the severity and details of the anomaly it raises, if any.
+func evaluateReading(config *models.DetectorConfig, metrics map[string]interface{}) (models.AnomalySeverity, map[string]interface{}, bool) {
+	params := config.Params
+	switch config.Detector {
+	case models.DetectorTemperatureRange:
+		temp, ok := metrics["temperature"].(float64)
+		if !ok || params.MinTemperature == nil || params.MaxTemperature == nil {
+			return "", nil, false
+		}
+		if temp > *params.MaxTemperature || temp < *params.MinTemperature {
+			return models.AnomalySeverityHigh, map[string]interface{}{
+				"temperature": temp,
+				"threshold":   fmt.Sprintf("%g-%g°C", *params.MinTemperature, *params.MaxTemperature),
+			}, true
+		}
+	case models.DetectorConsumptionSpike:
+		consumption, ok := metrics["consumption"].(float64)
+		if !ok || params.ConsumptionThreshold == nil {
+			return "", nil, false
+		}
+		if consumption > *params.ConsumptionThreshold {
+			return models.AnomalySeverityMedium, map[string]interface{}{
+				"consumption": consumption,
+				"threshold":   *params.ConsumptionThreshold,
+			}, true
+		}
+	}
+	return "", nil, false
+}
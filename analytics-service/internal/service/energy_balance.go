@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"analytics-service/internal/models"
+)
+
+// defaultEnergyBalanceDays is the period of an energy balance when none is requested
+const defaultEnergyBalanceDays = 30
+
+// GetEnergyBalance totals a building's consumption, generation, grid exchange and battery flows
+// over a period, by default the last 30 days, and derives its net consumption and
+// self-consumption KPIs
+func (s *KPIService) GetEnergyBalance(ctx context.Context, buildingID string, query *models.EnergyBalanceQuery) (*models.EnergyBalance, error) {
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.AddDate(0, 0, -defaultEnergyBalanceDays)
+	}
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("validation failed: from timestamp must be before to timestamp")
+	}
+
+	flows, err := s.timeSeriesRepo.SumEnergyFlows(ctx, buildingID, query.From, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total energy flows: %w", err)
+	}
+
+	return energyBalance(buildingID, query.From, query.To, flows), nil
+}
+
+// energyBalance derives the net consumption and self-consumption KPIs of a building's energy flows.
+// Generation that was neither exported nor stored is used on site, and where the grid connection
+// is metered, consumption not imported from the grid was covered by generation or batteries.
+func energyBalance(buildingID string, from, to time.Time, flows *models.EnergyFlows) *models.EnergyBalance {
+	balance := &models.EnergyBalance{
+		BuildingID:  buildingID,
+		From:        from,
+		To:          to,
+		Flows:       *flows,
+		GridMetered: flows.GridRecords > 0,
+	}
+
+	if balance.GridMetered {
+		balance.NetConsumption = roundTo2(flows.GridImport - flows.GridExport)
+		if flows.Consumption > 0 {
+			balance.SelfSufficiencyRatio = roundedPtr(clampPercent((flows.Consumption - flows.GridImport) / flows.Consumption * 100))
+		}
+	} else {
+		balance.NetConsumption = roundTo2(flows.Consumption - flows.Generation)
+	}
+
+	if flows.Generation > 0 {
+		balance.SelfConsumptionRatio = roundedPtr(clampPercent((flows.Generation - flows.GridExport) / flows.Generation * 100))
+	}
+	if flows.BatteryCharge > 0 {
+		balance.BatteryEfficiency = roundedPtr(flows.BatteryDischarge / flows.BatteryCharge * 100)
+	}
+
+	return balance
+}
+
+// clampPercent keeps a percentage between 0 and 100, absorbing metering inaccuracies
+func clampPercent(value float64) float64 {
+	return math.Min(math.Max(value, 0), 100)
+}
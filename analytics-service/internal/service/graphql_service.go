@@ -0,0 +1,487 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"analytics-service/internal/graphql"
+	"analytics-service/internal/models"
+)
+
+// maxGraphQLTelemetryPoints bounds the telemetry returned for a single device field
+const maxGraphQLTelemetryPoints = 1000
+
+// GraphQLService exposes devices, telemetry, anomalies, KPIs, forecasts and optimization
+// scenarios through one GraphQL schema so a dashboard can load a whole page in one request.
+// Data owned by other services is fetched from them with the caller's token.
+type GraphQLService struct {
+	schema            *graphql.Schema
+	anomalyService    *AnomalyService
+	kpiService        *KPIService
+	timeSeriesService *TimeSeriesService
+	iotClient         interface {
+		GetDevices(ctx context.Context, buildingID string, authToken string) ([]map[string]interface{}, error)
+		GetDevice(ctx context.Context, deviceID string, authToken string) (map[string]interface{}, error)
+		GetDeviceState(ctx context.Context, deviceID string, authToken string) (map[string]interface{}, error)
+		GetTelemetryHistory(ctx context.Context, deviceID string, from, to time.Time, page, limit int, authToken string) ([]map[string]interface{}, error)
+	}
+	forecastClient interface {
+		GetLatestForecast(ctx context.Context, buildingID string, authToken string) (map[string]interface{}, error)
+		ListScenarios(ctx context.Context, buildingID, status string, limit int, authToken string) ([]map[string]interface{}, error)
+		GetScenario(ctx context.Context, scenarioID string, authToken string) (map[string]interface{}, error)
+	}
+}
+
+// NewGraphQLService creates a new GraphQL service
+func NewGraphQLService(
+	anomalyService *AnomalyService,
+	kpiService *KPIService,
+	timeSeriesService *TimeSeriesService,
+	iotClient interface {
+		GetDevices(ctx context.Context, buildingID string, authToken string) ([]map[string]interface{}, error)
+		GetDevice(ctx context.Context, deviceID string, authToken string) (map[string]interface{}, error)
+		GetDeviceState(ctx context.Context, deviceID string, authToken string) (map[string]interface{}, error)
+		GetTelemetryHistory(ctx context.Context, deviceID string, from, to time.Time, page, limit int, authToken string) ([]map[string]interface{}, error)
+	},
+	forecastClient interface {
+		GetLatestForecast(ctx context.Context, buildingID string, authToken string) (map[string]interface{}, error)
+		ListScenarios(ctx context.Context, buildingID, status string, limit int, authToken string) ([]map[string]interface{}, error)
+		GetScenario(ctx context.Context, scenarioID string, authToken string) (map[string]interface{}, error)
+	},
+) *GraphQLService {
+	s := &GraphQLService{
+		anomalyService:    anomalyService,
+		kpiService:        kpiService,
+		timeSeriesService: timeSeriesService,
+		iotClient:         iotClient,
+		forecastClient:    forecastClient,
+	}
+	s.schema = s.buildSchema()
+	return s
+}
+
+// graphQLRoot carries per-request values to the resolvers
+type graphQLRoot struct {
+	authToken string
+}
+
+// Execute runs a GraphQL query on behalf of the caller
+func (s *GraphQLService) Execute(ctx context.Context, req *graphql.Request, authToken string) *graphql.Result {
+	return s.schema.Execute(ctx, req, &graphQLRoot{authToken: authToken})
+}
+
+// buildSchema defines the GraphQL types and wires their fields to the existing services
+func (s *GraphQLService) buildSchema() *graphql.Schema {
+	telemetryType := &graphql.Object{
+		Name: "Telemetry",
+		Fields: graphql.Fields{
+			"id":         {Type: graphql.ID},
+			"deviceId":   {Type: graphql.ID},
+			"timestamp":  {Type: graphql.Time},
+			"metrics":    {Type: graphql.JSON},
+			"rawMetrics": {Type: graphql.JSON},
+			"source":     {Type: graphql.String},
+		},
+	}
+
+	anomalyType := &graphql.Object{
+		Name: "Anomaly",
+		Fields: graphql.Fields{
+			"id":             {Type: graphql.ID},
+			"anomalyId":      {Type: graphql.ID},
+			"deviceId":       {Type: graphql.ID},
+			"buildingId":     {Type: graphql.ID},
+			"type":           {Type: graphql.String},
+			"severity":       {Type: graphql.String},
+			"status":         {Type: graphql.String},
+			"details":        {Type: graphql.JSON},
+			"detectedAt":     {Type: graphql.Time},
+			"acknowledgedAt": {Type: graphql.Time},
+			"acknowledgedBy": {Type: graphql.String},
+			"resolvedAt":     {Type: graphql.Time},
+			"createdAt":      {Type: graphql.Time},
+		},
+	}
+
+	kpiType := &graphql.Object{
+		Name: "KPI",
+		Fields: graphql.Fields{
+			"id":           {Type: graphql.ID},
+			"buildingId":   {Type: graphql.ID},
+			"period":       {Type: graphql.String},
+			"calculatedAt": {Type: graphql.Time},
+			"metrics":      {Type: graphql.JSON},
+		},
+	}
+
+	forecastType := &graphql.Object{
+		Name: "Forecast",
+		Fields: graphql.Fields{
+			"id":           {Type: graphql.ID},
+			"buildingId":   {Type: graphql.ID},
+			"deviceId":     {Type: graphql.ID},
+			"type":         {Type: graphql.String},
+			"status":       {Type: graphql.String},
+			"horizonHours": {Type: graphql.Int},
+			"startTime":    {Type: graphql.Time},
+			"endTime":      {Type: graphql.Time},
+			"predictions":  {Type: graphql.JSON},
+			"accuracy":     {Type: graphql.JSON},
+			"modelUsed":    {Type: graphql.String},
+			"freshness":    {Type: graphql.JSON},
+			"errorMessage": {Type: graphql.String},
+			"createdAt":    {Type: graphql.Time},
+		},
+	}
+
+	scenarioType := &graphql.Object{
+		Name: "Scenario",
+		Fields: graphql.Fields{
+			"id":              {Type: graphql.ID},
+			"buildingId":      {Type: graphql.ID},
+			"name":            {Type: graphql.String},
+			"description":     {Type: graphql.String},
+			"type":            {Type: graphql.String},
+			"status":          {Type: graphql.String},
+			"forecastId":      {Type: graphql.ID},
+			"priority":        {Type: graphql.Int},
+			"scheduledStart":  {Type: graphql.Time},
+			"scheduledEnd":    {Type: graphql.Time},
+			"actions":         {Type: graphql.JSON},
+			"expectedSavings": {Type: graphql.JSON},
+			"actualSavings":   {Type: graphql.JSON},
+			"constraints":     {Type: graphql.JSON},
+			"conflicts":       {Type: graphql.JSON},
+			"errorMessage":    {Type: graphql.String},
+			"createdBy":       {Type: graphql.String},
+			"approvedBy":      {Type: graphql.String},
+			"createdAt":       {Type: graphql.Time},
+		},
+	}
+
+	deviceType := &graphql.Object{
+		Name: "Device",
+		Fields: graphql.Fields{
+			"id":           {Type: graphql.ID},
+			"deviceId":     {Type: graphql.ID},
+			"type":         {Type: graphql.String},
+			"model":        {Type: graphql.String},
+			"status":       {Type: graphql.String},
+			"lastSeen":     {Type: graphql.Time},
+			"location":     {Type: graphql.JSON},
+			"capabilities": {Type: &graphql.List{OfType: graphql.String}},
+			"metadata":     {Type: graphql.JSON},
+			"calibration":  {Type: graphql.JSON},
+			"buildingId": {
+				Type: graphql.ID,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					location, _ := p.Source["location"].(map[string]interface{})
+					return location["buildingId"], nil
+				},
+			},
+			"state": {
+				Type: graphql.JSON,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.iotClient.GetDeviceState(p.Context, sourceString(p, "deviceId"), graphQLToken(p))
+				},
+			},
+			"telemetry": {
+				Type: &graphql.List{OfType: telemetryType},
+				Args: graphql.Args{
+					"from":  {Type: graphql.Time},
+					"to":    {Type: graphql.Time},
+					"limit": {Type: graphql.Int, Default: 100},
+				},
+				Resolve: s.resolveDeviceTelemetry,
+			},
+			"anomalies": {
+				Type: &graphql.List{OfType: anomalyType},
+				Args: graphql.Args{
+					"status": {Type: graphql.String},
+					"limit":  {Type: graphql.Int, Default: 20},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					anomalies, _, err := s.anomalyService.ListAnomalies(p.Context, sourceString(p, "deviceId"), "", "", "", stringArg(p, "status"), 1, intArg(p, "limit"))
+					return anomalies, err
+				},
+			},
+		},
+	}
+
+	buildingType := &graphql.Object{
+		Name: "Building",
+		Fields: graphql.Fields{
+			"buildingId": {Type: graphql.ID},
+			"devices": {
+				Type: &graphql.List{OfType: deviceType},
+				Args: graphql.Args{
+					"type":   {Type: graphql.String},
+					"status": {Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.resolveDevices(p, sourceString(p, "buildingId"))
+				},
+			},
+			"anomalies": {
+				Type: &graphql.List{OfType: anomalyType},
+				Args: graphql.Args{
+					"severity": {Type: graphql.String},
+					"status":   {Type: graphql.String},
+					"limit":    {Type: graphql.Int, Default: 20},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					anomalies, _, err := s.anomalyService.ListAnomalies(p.Context, "", sourceString(p, "buildingId"), "", stringArg(p, "severity"), stringArg(p, "status"), 1, intArg(p, "limit"))
+					return anomalies, err
+				},
+			},
+			"kpis": {
+				Type: kpiType,
+				Args: graphql.Args{
+					"period": {Type: graphql.String, Default: "DAILY"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.resolveKPIs(p, sourceString(p, "buildingId"))
+				},
+			},
+			"forecast": {
+				Type: forecastType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.forecastClient.GetLatestForecast(p.Context, sourceString(p, "buildingId"), graphQLToken(p))
+				},
+			},
+			"scenarios": {
+				Type: &graphql.List{OfType: scenarioType},
+				Args: graphql.Args{
+					"status": {Type: graphql.String},
+					"limit":  {Type: graphql.Int, Default: 20},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.forecastClient.ListScenarios(p.Context, sourceString(p, "buildingId"), stringArg(p, "status"), intArg(p, "limit"), graphQLToken(p))
+				},
+			},
+			"timeSeries": {
+				Type: graphql.JSON,
+				Args: graphql.Args{
+					"from":            {Type: graphql.Time},
+					"to":              {Type: graphql.Time},
+					"aggregationType": {Type: graphql.String, Default: string(models.AggregationTypeHourly)},
+					"metric":          {Type: graphql.String, Default: "consumption"},
+				},
+				Resolve: s.resolveBuildingTimeSeries,
+			},
+		},
+	}
+
+	query := &graphql.Object{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"building": {
+				Type: buildingType,
+				Args: graphql.Args{
+					"buildingId": {Type: graphql.ID, Required: true},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return map[string]interface{}{"buildingId": stringArg(p, "buildingId")}, nil
+				},
+			},
+			"devices": {
+				Type: &graphql.List{OfType: deviceType},
+				Args: graphql.Args{
+					"buildingId": {Type: graphql.ID},
+					"type":       {Type: graphql.String},
+					"status":     {Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.resolveDevices(p, stringArg(p, "buildingId"))
+				},
+			},
+			"device": {
+				Type: deviceType,
+				Args: graphql.Args{
+					"deviceId": {Type: graphql.ID, Required: true},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.iotClient.GetDevice(p.Context, stringArg(p, "deviceId"), graphQLToken(p))
+				},
+			},
+			"anomalies": {
+				Type: &graphql.List{OfType: anomalyType},
+				Args: graphql.Args{
+					"deviceId":   {Type: graphql.ID},
+					"buildingId": {Type: graphql.ID},
+					"type":       {Type: graphql.String},
+					"severity":   {Type: graphql.String},
+					"status":     {Type: graphql.String},
+					"page":       {Type: graphql.Int, Default: 1},
+					"limit":      {Type: graphql.Int, Default: 20},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					anomalies, _, err := s.anomalyService.ListAnomalies(
+						p.Context,
+						stringArg(p, "deviceId"),
+						stringArg(p, "buildingId"),
+						stringArg(p, "type"),
+						stringArg(p, "severity"),
+						stringArg(p, "status"),
+						intArg(p, "page"),
+						intArg(p, "limit"),
+					)
+					return anomalies, err
+				},
+			},
+			"anomaly": {
+				Type: anomalyType,
+				Args: graphql.Args{
+					"anomalyId": {Type: graphql.ID, Required: true},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					anomaly, err := s.anomalyService.GetAnomaly(p.Context, stringArg(p, "anomalyId"))
+					if err != nil && err.Error() == "anomaly not found" {
+						return nil, nil
+					}
+					return anomaly, err
+				},
+			},
+			"kpis": {
+				Type: kpiType,
+				Args: graphql.Args{
+					"buildingId": {Type: graphql.ID},
+					"period":     {Type: graphql.String, Default: "DAILY"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.resolveKPIs(p, stringArg(p, "buildingId"))
+				},
+			},
+			"forecast": {
+				Type: forecastType,
+				Args: graphql.Args{
+					"buildingId": {Type: graphql.ID, Required: true},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.forecastClient.GetLatestForecast(p.Context, stringArg(p, "buildingId"), graphQLToken(p))
+				},
+			},
+			"scenarios": {
+				Type: &graphql.List{OfType: scenarioType},
+				Args: graphql.Args{
+					"buildingId": {Type: graphql.ID, Required: true},
+					"status":     {Type: graphql.String},
+					"limit":      {Type: graphql.Int, Default: 20},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.forecastClient.ListScenarios(p.Context, stringArg(p, "buildingId"), stringArg(p, "status"), intArg(p, "limit"), graphQLToken(p))
+				},
+			},
+			"scenario": {
+				Type: scenarioType,
+				Args: graphql.Args{
+					"scenarioId": {Type: graphql.ID, Required: true},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.forecastClient.GetScenario(p.Context, stringArg(p, "scenarioId"), graphQLToken(p))
+				},
+			},
+		},
+	}
+
+	return &graphql.Schema{Query: query}
+}
+
+// resolveDevices lists a building's devices, or all devices, filtered by type and status
+func (s *GraphQLService) resolveDevices(p graphql.ResolveParams, buildingID string) (interface{}, error) {
+	devices, err := s.iotClient.GetDevices(p.Context, buildingID, graphQLToken(p))
+	if err != nil {
+		return nil, err
+	}
+
+	deviceType, status := stringArg(p, "type"), stringArg(p, "status")
+	filtered := make([]map[string]interface{}, 0, len(devices))
+	for _, device := range devices {
+		if deviceType != "" && device["type"] != deviceType {
+			continue
+		}
+		if status != "" && device["status"] != status {
+			continue
+		}
+		filtered = append(filtered, device)
+	}
+
+	return filtered, nil
+}
+
+// resolveDeviceTelemetry returns a device's telemetry, by default for the last 24 hours
+func (s *GraphQLService) resolveDeviceTelemetry(p graphql.ResolveParams) (interface{}, error) {
+	to := timeArg(p, "to")
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := timeArg(p, "from")
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+
+	limit := intArg(p, "limit")
+	if limit < 1 || limit > maxGraphQLTelemetryPoints {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxGraphQLTelemetryPoints)
+	}
+
+	return s.iotClient.GetTelemetryHistory(p.Context, sourceString(p, "deviceId"), from, to, 1, limit, graphQLToken(p))
+}
+
+// resolveKPIs returns the latest KPIs, or null when none have been calculated yet
+func (s *GraphQLService) resolveKPIs(p graphql.ResolveParams, buildingID string) (interface{}, error) {
+	kpis, err := s.kpiService.GetKPIs(p.Context, buildingID, stringArg(p, "period"))
+	if err != nil {
+		if err.Error() == "KPI not found" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return kpis, nil
+}
+
+// resolveBuildingTimeSeries returns the building's annotated time-series
+func (s *GraphQLService) resolveBuildingTimeSeries(p graphql.ResolveParams) (interface{}, error) {
+	aggregationType := models.AggregationType(stringArg(p, "aggregationType"))
+	switch aggregationType {
+	case models.AggregationTypeHourly, models.AggregationTypeDaily, models.AggregationTypeWeekly, models.AggregationTypeMonthly:
+	default:
+		return nil, fmt.Errorf("aggregationType must be one of HOURLY, DAILY, WEEKLY, MONTHLY")
+	}
+
+	return s.timeSeriesService.GetAnnotatedTimeSeries(p.Context, sourceString(p, "buildingId"), &models.AnnotatedTimeSeriesQuery{
+		From:            timeArg(p, "from"),
+		To:              timeArg(p, "to"),
+		AggregationType: string(aggregationType),
+		Metric:          stringArg(p, "metric"),
+	})
+}
+
+// graphQLToken returns the caller's token, forwarded to the services that own the data
+func graphQLToken(p graphql.ResolveParams) string {
+	if root, ok := p.Root.(*graphQLRoot); ok {
+		return root.authToken
+	}
+	return ""
+}
+
+func sourceString(p graphql.ResolveParams, key string) string {
+	value, _ := p.Source[key].(string)
+	return value
+}
+
+func stringArg(p graphql.ResolveParams, name string) string {
+	value, _ := p.Args[name].(string)
+	return value
+}
+
+func intArg(p graphql.ResolveParams, name string) int {
+	value, _ := p.Args[name].(int)
+	return value
+}
+
+func timeArg(p graphql.ResolveParams, name string) time.Time {
+	value, _ := p.Args[name].(time.Time)
+	return value
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"analytics-service/internal/models"
+)
+
+// healthCheckTimeout bounds each dependency check so a hung dependency cannot stall readiness probes
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheckFunc checks a single dependency, returning an error when it is unavailable
+type HealthCheckFunc func(ctx context.Context) error
+
+// healthComponent is a registered dependency check
+type healthComponent struct {
+	name     string
+	critical bool
+	check    HealthCheckFunc
+}
+
+// HealthService aggregates the health of the service's dependencies
+type HealthService struct {
+	serviceName string
+	components  []healthComponent
+}
+
+// NewHealthService creates a new health service
+func NewHealthService(serviceName string) *HealthService {
+	return &HealthService{serviceName: serviceName}
+}
+
+// Register adds a dependency check. Failing critical components make the service unhealthy;
+// failing non-critical components, and checks returning a models.DegradedError, only degrade it.
+func (s *HealthService) Register(name string, critical bool, check HealthCheckFunc) {
+	s.components = append(s.components, healthComponent{name: name, critical: critical, check: check})
+}
+
+// Check runs all dependency checks concurrently and aggregates the results
+func (s *HealthService) Check(ctx context.Context) *models.DeepHealthResponse {
+	response := &models.DeepHealthResponse{
+		Status:     models.HealthStatusHealthy,
+		Service:    s.serviceName,
+		Timestamp:  time.Now(),
+		Components: make(map[string]models.ComponentHealth, len(s.components)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, component := range s.components {
+		wg.Add(1)
+		go func(component healthComponent) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := component.check(checkCtx)
+
+			health := models.ComponentHealth{
+				Status:    models.HealthStatusHealthy,
+				Critical:  component.critical,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			var degraded *models.DegradedError
+			switch {
+			case errors.As(err, &degraded):
+				health.Status = models.HealthStatusDegraded
+				health.Error = err.Error()
+			case err != nil:
+				health.Status = models.HealthStatusUnhealthy
+				health.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Components[component.name] = health
+			if err == nil {
+				return
+			}
+			if component.critical && health.Status == models.HealthStatusUnhealthy {
+				response.Status = models.HealthStatusUnhealthy
+			} else if response.Status == models.HealthStatusHealthy {
+				response.Status = models.HealthStatusDegraded
+			}
+		}(component)
+	}
+	wg.Wait()
+
+	return response
+}
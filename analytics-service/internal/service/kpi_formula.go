@@ -0,0 +1,542 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"analytics-service/internal/models"
+)
+
+// KPI formula variables. Period variables cover the window the KPIs are calculated for.
+const (
+	VariableConsumption           = "consumption"
+	VariableGeneration            = "generation"
+	VariableGridImport            = "grid_import"
+	VariableGridExport            = "grid_export"
+	VariableNetConsumption        = "net_consumption"
+	VariableNormalizedConsumption = "normalized_consumption"
+	VariableHeatingDegreeDays     = "heating_degree_days"
+	VariableCoolingDegreeDays     = "cooling_degree_days"
+	VariableTotalDevices          = "total_devices"
+	VariableOnlineDevices         = "online_devices"
+	VariableDeviceAvailability    = "device_availability"
+	VariableActiveAnomalies       = "active_anomalies"
+	VariableFloorArea             = "floor_area"
+	VariableOccupants             = "occupants"
+	VariableOccupiedHours         = "occupied_hours"
+	VariablePeriodHours           = "period_hours"
+	VariablePeriodDays            = "period_days"
+)
+
+// kpiVariable is a formula variable, read from a built-in KPI metric, a daily time-series
+// metric totalled over the period, or computed from the building and the period
+type kpiVariable struct {
+	models.KPIVariable
+	metric string // built-in KPI metric the variable reads, if any
+	series string // daily time-series metric the variable totals, if any
+}
+
+// kpiVariables lists the variables KPI formulas can read
+var kpiVariables = []kpiVariable{
+	{KPIVariable: models.KPIVariable{Name: VariableConsumption, Unit: "kWh", Description: "Consumption over the period", Weekly: true}, series: "consumption"},
+	{KPIVariable: models.KPIVariable{Name: VariableGeneration, Unit: "kWh", Description: "On-site generation over the period", Weekly: true}, series: models.MetricGeneration},
+	{KPIVariable: models.KPIVariable{Name: VariableGridImport, Unit: "kWh", Description: "Energy imported from the grid over the period", Weekly: true}, series: models.MetricGridImport},
+	{KPIVariable: models.KPIVariable{Name: VariableGridExport, Unit: "kWh", Description: "Energy exported to the grid over the period", Weekly: true}, series: models.MetricGridExport},
+	{KPIVariable: models.KPIVariable{Name: VariableNetConsumption, Unit: "kWh", Description: "Grid import less export, or consumption less generation"}, metric: "netConsumption"},
+	{KPIVariable: models.KPIVariable{Name: VariableNormalizedConsumption, Unit: "kWh", Description: "Consumption normalized to the weather of the reference period"}, metric: "normalizedConsumptionKwh"},
+	{KPIVariable: models.KPIVariable{Name: VariableHeatingDegreeDays, Unit: "°C·d", Description: "Heating degree days over the period"}, metric: "heatingDegreeDays"},
+	{KPIVariable: models.KPIVariable{Name: VariableCoolingDegreeDays, Unit: "°C·d", Description: "Cooling degree days over the period"}, metric: "coolingDegreeDays"},
+	{KPIVariable: models.KPIVariable{Name: VariableTotalDevices, Unit: "device", Description: "Devices of the building"}, metric: "totalDevices"},
+	{KPIVariable: models.KPIVariable{Name: VariableOnlineDevices, Unit: "device", Description: "Devices online when the KPIs were calculated"}, metric: "onlineDevices"},
+	{KPIVariable: models.KPIVariable{Name: VariableDeviceAvailability, Unit: "%", Description: "Share of devices online"}, metric: "deviceAvailability"},
+	{KPIVariable: models.KPIVariable{Name: VariableActiveAnomalies, Unit: "anomaly", Description: "Anomalies not yet acknowledged"}, metric: "activeAnomalies"},
+	{KPIVariable: models.KPIVariable{Name: VariableFloorArea, Unit: "m²", Description: "Floor area from the building attributes", Weekly: true}},
+	{KPIVariable: models.KPIVariable{Name: VariableOccupants, Unit: "person", Description: "Occupants from the building attributes", Weekly: true}},
+	{KPIVariable: models.KPIVariable{Name: VariableOccupiedHours, Unit: "h", Description: "Weekday occupied hours (07:00-19:00 UTC) in the period", Weekly: true}},
+	{KPIVariable: models.KPIVariable{Name: VariablePeriodHours, Unit: "h", Description: "Hours in the period", Weekly: true}},
+	{KPIVariable: models.KPIVariable{Name: VariablePeriodDays, Unit: "d", Description: "Days in the period", Weekly: true}},
+}
+
+// KPIVariables returns the variables KPI formulas can read
+func KPIVariables() []models.KPIVariable {
+	variables := make([]models.KPIVariable, len(kpiVariables))
+	for i, variable := range kpiVariables {
+		variables[i] = variable.KPIVariable
+	}
+	return variables
+}
+
+// kpiVariableUnits returns the unit of every formula variable
+func kpiVariableUnits() map[string]string {
+	units := make(map[string]string, len(kpiVariables))
+	for _, variable := range kpiVariables {
+		units[variable.Name] = variable.Unit
+	}
+	return units
+}
+
+// ValidateKPIFormula parses a formula and infers its unit from the variables it reads
+func ValidateKPIFormula(expression string) *models.KPIFormulaValidation {
+	validation := &models.KPIFormulaValidation{Expression: expression, Variables: []string{}}
+
+	formula, err := ParseKPIFormula(expression)
+	if err != nil {
+		validation.Errors = []string{err.Error()}
+		return validation
+	}
+	validation.Variables = formula.Variables()
+
+	unit, errs := formula.InferUnit(kpiVariableUnits())
+	if len(errs) > 0 {
+		for _, err := range errs {
+			validation.Errors = append(validation.Errors, err.Error())
+		}
+		return validation
+	}
+
+	validation.Valid = true
+	validation.Unit = unit
+	return validation
+}
+
+// KPIFormula is a parsed KPI expression: numbers and variables combined with + - * / and
+// parentheses
+type KPIFormula struct {
+	root      formulaNode
+	variables []string
+}
+
+// ParseKPIFormula parses a KPI expression, reporting the position of the first syntax error
+func ParseKPIFormula(expression string) (*KPIFormula, error) {
+	tokens, err := tokenizeFormula(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &formulaParser{tokens: tokens}
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if token := p.peek(); token.kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected %s at position %d", token, token.pos)
+	}
+
+	formula := &KPIFormula{root: root}
+	seen := make(map[string]bool)
+	walkFormula(root, func(node formulaNode) {
+		if variable, ok := node.(*variableNode); ok && !seen[variable.name] {
+			seen[variable.name] = true
+			formula.variables = append(formula.variables, variable.name)
+		}
+	})
+	sort.Strings(formula.variables)
+	return formula, nil
+}
+
+// Variables returns the variables the formula reads, sorted by name
+func (f *KPIFormula) Variables() []string {
+	return append([]string{}, f.variables...)
+}
+
+// InferUnit returns the unit of the formula's result from the units of its variables, like
+// kWh/m²/h for consumption / floor_area / occupied_hours. Variables missing from units are unknown,
+// and adding or subtracting values of different units is an error. Numbers take the unit of the
+// value they are added to, and are unitless factors otherwise.
+func (f *KPIFormula) InferUnit(units map[string]string) (string, []error) {
+	var errs []error
+	unit, _ := f.root.unit(units, &errs)
+	if len(errs) > 0 {
+		return "", errs
+	}
+	return unit.String(), nil
+}
+
+// Evaluate computes the formula from the values of its variables. A variable without a value,
+// a division by zero or a result that is not a finite number is an error.
+func (f *KPIFormula) Evaluate(values map[string]float64) (float64, error) {
+	value, err := f.root.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errors.New("result is not a finite number")
+	}
+	return value, nil
+}
+
+// formulaUnit is a product of unit symbols raised to powers, kept in order of appearance
+type formulaUnit []unitFactor
+
+type unitFactor struct {
+	symbol string
+	power  int
+}
+
+// newFormulaUnit returns the unit of a variable. An empty symbol is unitless.
+func newFormulaUnit(symbol string) formulaUnit {
+	if symbol == "" {
+		return nil
+	}
+	return formulaUnit{{symbol: symbol, power: 1}}
+}
+
+// times returns the product of two units, raising other to sign (1 or -1) first
+func (u formulaUnit) times(other formulaUnit, sign int) formulaUnit {
+	result := append(formulaUnit{}, u...)
+	for _, factor := range other {
+		found := false
+		for i := range result {
+			if result[i].symbol == factor.symbol {
+				result[i].power += sign * factor.power
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, unitFactor{symbol: factor.symbol, power: sign * factor.power})
+		}
+	}
+
+	// Drop the symbols that cancelled out
+	reduced := result[:0]
+	for _, factor := range result {
+		if factor.power != 0 {
+			reduced = append(reduced, factor)
+		}
+	}
+	return reduced
+}
+
+// equals reports whether two units have the same symbols and powers
+func (u formulaUnit) equals(other formulaUnit) bool {
+	return len(u.times(other, -1)) == 0
+}
+
+// String formats the unit as its numerator followed by each denominator, like kWh/m²/h.
+// A unitless value formats as "".
+func (u formulaUnit) String() string {
+	var numerator []string
+	var denominators []string
+	for _, factor := range u {
+		if factor.power > 0 {
+			numerator = append(numerator, unitFactorString(factor.symbol, factor.power))
+		} else {
+			denominators = append(denominators, unitFactorString(factor.symbol, -factor.power))
+		}
+	}
+
+	result := strings.Join(numerator, "·")
+	if result == "" && len(denominators) > 0 {
+		result = "1"
+	}
+	for _, denominator := range denominators {
+		result += "/" + denominator
+	}
+	return result
+}
+
+// describe formats the unit for error messages, naming unitless values
+func (u formulaUnit) describe() string {
+	if len(u) == 0 {
+		return "a unitless value"
+	}
+	return u.String()
+}
+
+// unitFactorString formats a unit symbol raised to a power, bracketing symbols that already
+// carry an exponent, like (m²)²
+func unitFactorString(symbol string, power int) string {
+	if power == 1 {
+		return symbol
+	}
+	if strings.HasSuffix(symbol, "²") || strings.HasSuffix(symbol, "³") {
+		symbol = "(" + symbol + ")"
+	}
+	switch power {
+	case 2:
+		return symbol + "²"
+	case 3:
+		return symbol + "³"
+	default:
+		return symbol + "^" + strconv.Itoa(power)
+	}
+}
+
+// formulaNode is a node of a parsed formula. unit returns the node's unit and whether the node
+// is a constant, whose unit adapts to the value it is added to.
+type formulaNode interface {
+	eval(values map[string]float64) (float64, error)
+	unit(units map[string]string, errs *[]error) (formulaUnit, bool)
+}
+
+type numberNode struct {
+	value float64
+}
+
+func (n *numberNode) eval(map[string]float64) (float64, error) {
+	return n.value, nil
+}
+
+func (n *numberNode) unit(map[string]string, *[]error) (formulaUnit, bool) {
+	return nil, true
+}
+
+type variableNode struct {
+	name string
+	pos  int
+}
+
+func (n *variableNode) eval(values map[string]float64) (float64, error) {
+	value, ok := values[n.name]
+	if !ok {
+		return 0, fmt.Errorf("%s is not available", n.name)
+	}
+	return value, nil
+}
+
+func (n *variableNode) unit(units map[string]string, errs *[]error) (formulaUnit, bool) {
+	symbol, ok := units[n.name]
+	if !ok {
+		*errs = append(*errs, fmt.Errorf("unknown variable %q at position %d", n.name, n.pos))
+		return nil, false
+	}
+	return newFormulaUnit(symbol), false
+}
+
+type negationNode struct {
+	operand formulaNode
+}
+
+func (n *negationNode) eval(values map[string]float64) (float64, error) {
+	value, err := n.operand.eval(values)
+	return -value, err
+}
+
+func (n *negationNode) unit(units map[string]string, errs *[]error) (formulaUnit, bool) {
+	return n.operand.unit(units, errs)
+}
+
+type binaryNode struct {
+	op          byte
+	left, right formulaNode
+	pos         int
+}
+
+func (n *binaryNode) eval(values map[string]float64) (float64, error) {
+	left, err := n.left.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.eval(values)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	default:
+		if right == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return left / right, nil
+	}
+}
+
+func (n *binaryNode) unit(units map[string]string, errs *[]error) (formulaUnit, bool) {
+	left, leftConstant := n.left.unit(units, errs)
+	right, rightConstant := n.right.unit(units, errs)
+
+	switch n.op {
+	case '*':
+		return left.times(right, 1), leftConstant && rightConstant
+	case '/':
+		return left.times(right, -1), leftConstant && rightConstant
+	}
+
+	switch {
+	case leftConstant:
+		return right, rightConstant
+	case rightConstant || left.equals(right):
+		return left, false
+	}
+	verb := "add"
+	if n.op == '-' {
+		verb = "subtract"
+	}
+	*errs = append(*errs, fmt.Errorf("cannot %s %s and %s at position %d", verb, left.describe(), right.describe(), n.pos))
+	return left, false
+}
+
+// walkFormula calls fn for every node of a formula
+func walkFormula(node formulaNode, fn func(formulaNode)) {
+	fn(node)
+	switch n := node.(type) {
+	case *negationNode:
+		walkFormula(n.operand, fn)
+	case *binaryNode:
+		walkFormula(n.left, fn)
+		walkFormula(n.right, fn)
+	}
+}
+
+type formulaTokenKind int
+
+const (
+	tokenEnd formulaTokenKind = iota
+	tokenNumber
+	tokenIdentifier
+	tokenOperator
+	tokenOpenParen
+	tokenCloseParen
+)
+
+// formulaToken is a token of a formula, with its 1-based position
+type formulaToken struct {
+	kind  formulaTokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+func (t formulaToken) String() string {
+	if t.kind == tokenEnd {
+		return "end of formula"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// tokenizeFormula splits a formula into tokens. Identifiers are lowercase letters, digits and
+// underscores starting with a letter.
+func tokenizeFormula(expression string) ([]formulaToken, error) {
+	var tokens []formulaToken
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			i++
+		case strings.ContainsRune("+-*/", r):
+			tokens = append(tokens, formulaToken{kind: tokenOperator, text: string(r), pos: pos})
+			i++
+		case r == '(':
+			tokens = append(tokens, formulaToken{kind: tokenOpenParen, text: "(", pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, formulaToken{kind: tokenCloseParen, text: ")", pos: pos})
+			i++
+		case (r >= '0' && r <= '9') || r == '.':
+			start := i
+			for i < len(runes) && ((runes[i] >= '0' && runes[i] <= '9') || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", text, pos)
+			}
+			tokens = append(tokens, formulaToken{kind: tokenNumber, text: text, value: value, pos: pos})
+		case r >= 'a' && r <= 'z':
+			start := i
+			for i < len(runes) && ((runes[i] >= 'a' && runes[i] <= 'z') || (runes[i] >= '0' && runes[i] <= '9') || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, formulaToken{kind: tokenIdentifier, text: string(runes[start:i]), pos: pos})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, pos)
+		}
+	}
+	return append(tokens, formulaToken{kind: tokenEnd, pos: len(runes) + 1}), nil
+}
+
+// formulaParser is a recursive descent parser over the tokens of a formula:
+//
+//	sum     = product { ("+" | "-") product }
+//	product = factor { ("*" | "/") factor }
+//	factor  = "-" factor | "(" sum ")" | number | identifier
+type formulaParser struct {
+	tokens []formulaToken
+	next   int
+}
+
+func (p *formulaParser) peek() formulaToken {
+	return p.tokens[p.next]
+}
+
+func (p *formulaParser) advance() formulaToken {
+	token := p.tokens[p.next]
+	if token.kind != tokenEnd {
+		p.next++
+	}
+	return token
+}
+
+func (p *formulaParser) parseSum() (formulaNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for token := p.peek(); token.kind == tokenOperator && (token.text == "+" || token.text == "-"); token = p.peek() {
+		p.advance()
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: token.text[0], left: left, right: right, pos: token.pos}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseProduct() (formulaNode, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for token := p.peek(); token.kind == tokenOperator && (token.text == "*" || token.text == "/"); token = p.peek() {
+		p.advance()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: token.text[0], left: left, right: right, pos: token.pos}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseFactor() (formulaNode, error) {
+	token := p.advance()
+	switch token.kind {
+	case tokenNumber:
+		return &numberNode{value: token.value}, nil
+	case tokenIdentifier:
+		return &variableNode{name: token.text, pos: token.pos}, nil
+	case tokenOpenParen:
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenCloseParen {
+			return nil, fmt.Errorf("expected \")\" at position %d, found %s", closing.pos, closing)
+		}
+		return inner, nil
+	case tokenOperator:
+		if token.text == "-" {
+			operand, err := p.parseFactor()
+			if err != nil {
+				return nil, err
+			}
+			return &negationNode{operand: operand}, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a number, variable or \"(\" at position %d, found %s", token.pos, token)
+}
@@ -3,8 +3,12 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
+	"math"
+	"sort"
 	"time"
 
+	"analytics-service/internal/events"
 	"analytics-service/internal/models"
 	"analytics-service/internal/repository"
 )
@@ -13,23 +17,41 @@ import (
 type KPIService struct {
 	kpiRepo   *repository.KPIRepository
 	anomalyRepo *repository.AnomalyRepository
+	timeSeriesRepo *repository.TimeSeriesRepository
+	trendAlertRepo *repository.TrendAlertRepository
+	definitionRepo *repository.KPIDefinitionRepository
+	attributesRepo    *repository.BuildingAttributesRepository
+	eventBus       *events.Bus
 	iotClient interface {
 		GetDevices(ctx context.Context, buildingID string, authToken string) ([]map[string]interface{}, error)
 	}
+	normalizer *WeatherNormalizer
 }
 
 // NewKPIService creates a new KPI service
 func NewKPIService(
 	kpiRepo *repository.KPIRepository,
 	anomalyRepo *repository.AnomalyRepository,
+	timeSeriesRepo *repository.TimeSeriesRepository,
+	trendAlertRepo *repository.TrendAlertRepository,
+	definitionRepo *repository.KPIDefinitionRepository,
+	attributesRepo *repository.BuildingAttributesRepository,
 	iotClient interface {
 		GetDevices(ctx context.Context, buildingID string, authToken string) ([]map[string]interface{}, error)
 	},
+	normalizer *WeatherNormalizer,
+	eventBus *events.Bus,
 ) *KPIService {
 	return &KPIService{
 		kpiRepo:    kpiRepo,
 		anomalyRepo: anomalyRepo,
+		timeSeriesRepo: timeSeriesRepo,
+		trendAlertRepo: trendAlertRepo,
+		definitionRepo: definitionRepo,
+		attributesRepo:    attributesRepo,
+		eventBus:       eventBus,
 		iotClient:  iotClient,
+		normalizer: normalizer,
 	}
 }
 
@@ -56,18 +78,65 @@ func (s *KPIService) CalculateKPIs(ctx context.Context, buildingID, period strin
 	anomalyCount, _ := s.anomalyRepo.CountByStatus(ctx, "NEW")
 	metrics["activeAnomalies"] = anomalyCount
 
+	from, to, refFrom, refTo := kpiComparisonWindows(period, time.Now())
+
+	// Add raw and weather-normalized consumption of the building
+	if buildingID != "" && s.normalizer != nil {
+		normalized, err := s.normalizer.Normalize(ctx, buildingID, from, to, refFrom, refTo, authToken)
+		if err != nil {
+			log.Printf("Failed to normalize consumption for building %s: %v", buildingID, err)
+		} else {
+			for key, value := range normalized.Metrics() {
+				metrics[key] = value
+			}
+		}
+	}
+
+	// Add net consumption and self-consumption of buildings with generation or storage
+	if buildingID != "" {
+		flows, err := s.timeSeriesRepo.SumEnergyFlows(ctx, buildingID, from, to)
+		if err != nil {
+			log.Printf("Failed to total energy flows for building %s: %v", buildingID, err)
+		} else if flows.HasOnSiteEnergy() {
+			for key, value := range energyBalance(buildingID, from, to, flows).Metrics() {
+				metrics[key] = value
+			}
+		}
+	}
+
+	// Evaluate the building's custom KPIs over the built-in metrics
+	var custom []customKPIValue
+	if buildingID != "" {
+		definitions, err := s.definitionRepo.FindEnabled(ctx)
+		if err != nil {
+			log.Printf("Failed to load custom KPIs: %v", err)
+		} else {
+			custom = s.evaluateCustomKPIs(ctx, definitions, buildingID, from, to, metrics)
+			for _, value := range custom {
+				metrics[value.definition.Key] = value.value
+			}
+		}
+	}
+
+	var previousTargets []models.KPITargetStatus
+	if previous, err := s.kpiRepo.FindLatest(ctx, buildingID, period); err == nil {
+		previousTargets = previous.Targets
+	}
+
 	// Create or update KPI
 	kpi := &models.KPI{
 		BuildingID:   buildingID,
 		CalculatedAt: time.Now(),
 		Metrics:     metrics,
 		Period:      period,
+		Targets:     kpiTargetStatuses(custom),
 	}
 
 	updated, err := s.kpiRepo.UpdateOrCreate(ctx, kpi)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save KPI: %w", err)
 	}
+	s.publishMissedTargets(ctx, buildingID, period, previousTargets, custom, kpi.CalculatedAt)
 
 	return updated.ToResponse(), nil
 }
@@ -85,3 +154,369 @@ func (s *KPIService) GetKPIs(ctx context.Context, buildingID, period string) (*m
 
 	return kpi.ToResponse(), nil
 }
+
+// Trend analysis settings
+const (
+	defaultTrendWeeks = 8
+	minTrendWeeks     = 4
+	maxTrendWeeks     = 26
+	// trendSignificance is the confidence a slope needs to count as a trend
+	trendSignificance = 0.95
+	// regressionStreak is how many consecutive weekly increases make an upward trend a regression
+	regressionStreak       = 3
+	maxContributingDevices = 5
+)
+
+// GetTrends computes the rolling weekly trend of a building metric, or of a custom KPI, over
+// the last full weeks, and stores a TrendAlert when the metric is regressing
+func (s *KPIService) GetTrends(ctx context.Context, buildingID string, query *models.TrendQuery) (*models.KPITrend, error) {
+	metric := query.Metric
+	if metric == "" {
+		metric = "consumption"
+	}
+	weeks := query.Weeks
+	if weeks == 0 {
+		weeks = defaultTrendWeeks
+	}
+	if weeks < minTrendWeeks || weeks > maxTrendWeeks {
+		return nil, fmt.Errorf("weeks must be between %d and %d", minTrendWeeks, maxTrendWeeks)
+	}
+
+	var trend *models.KPITrend
+	if definition, err := s.definitionRepo.FindByKey(ctx, metric, buildingID); err == nil {
+		trend, err = s.analyzeCustomTrend(ctx, buildingID, definition, weeks, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		trend, err = s.analyzeTrend(ctx, buildingID, metric, weeks, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if trend.Regression {
+		alert, err := s.trendAlertRepo.Upsert(ctx, &models.TrendAlert{
+			BuildingID:           buildingID,
+			Metric:               metric,
+			WeekStart:            trend.Points[len(trend.Points)-1].WeekStart,
+			Slope:                trend.Slope,
+			SlopePercent:         trend.SlopePercent,
+			Confidence:           trend.Confidence,
+			ConsecutiveIncreases: trend.ConsecutiveIncreases,
+			ContributingDevices:  trend.ContributingDevices,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save trend alert: %w", err)
+		}
+		trend.Alert = alert
+	}
+
+	return trend, nil
+}
+
+// DetectRegressions analyses the default consumption trend of every building with recent data,
+// and the trends of the custom KPIs with weekly history applying to it, and returns how many
+// trends are regressing
+func (s *KPIService) DetectRegressions(ctx context.Context) (int, error) {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	buildings, err := s.timeSeriesRepo.SumConsumptionByBuilding(ctx, to.AddDate(0, 0, -7*defaultTrendWeeks), to, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	definitions, err := s.definitionRepo.FindEnabled(ctx)
+	if err != nil {
+		log.Printf("Failed to load custom KPIs for trend detection: %v", err)
+	}
+
+	regressions := 0
+	for _, building := range buildings {
+		queries := []*models.TrendQuery{{}}
+		for _, definition := range definitions {
+			if definition.AppliesTo(building.BuildingID) && hasWeeklyHistory(definition) {
+				queries = append(queries, &models.TrendQuery{Metric: definition.Key})
+			}
+		}
+
+		for _, query := range queries {
+			trend, err := s.GetTrends(ctx, building.BuildingID, query)
+			if err != nil {
+				log.Printf("Failed to analyze trend of building %s: %v", building.BuildingID, err)
+				continue
+			}
+			if trend.Regression {
+				regressions++
+				log.Printf("%s of building %s has risen %d weeks in a row (%.1f%% per week)",
+					trend.Metric, building.BuildingID, trend.ConsecutiveIncreases, trend.SlopePercent)
+			}
+		}
+	}
+	return regressions, nil
+}
+
+// StartTrendWorker periodically detects KPI regressions until the context is cancelled
+func (s *KPIService) StartTrendWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DetectRegressions(ctx); err != nil {
+				log.Printf("Failed to detect KPI regressions: %v", err)
+			}
+		}
+	}
+}
+
+// analyzeTrend totals a metric per week from daily aggregates and fits a linear trend to the
+// building and to each of its devices. Weeks are the 7-day blocks ending at the start of today (UTC);
+// weeks without data are left out of the fit.
+func (s *KPIService) analyzeTrend(ctx context.Context, buildingID, metric string, weeks int, now time.Time) (*models.KPITrend, error) {
+	const week = 7 * 24 * time.Hour
+	to := now.UTC().Truncate(24 * time.Hour)
+	from := to.Add(-time.Duration(weeks) * week)
+
+	records, err := s.timeSeriesRepo.Query(ctx, &models.TimeSeriesQueryRequest{
+		BuildingID:      buildingID,
+		From:            from,
+		To:              to.Add(-time.Millisecond),
+		AggregationType: string(models.AggregationTypeDaily),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time-series: %w", err)
+	}
+
+	buildingTotals := newWeeklyTotals(weeks)
+	deviceTotals := make(map[string]*weeklyTotals)
+	for _, record := range records {
+		value, ok := record.Metrics[metric].(float64)
+		if !ok {
+			continue
+		}
+		index := int(record.Timestamp.Sub(from) / week)
+		if index < 0 || index >= weeks {
+			continue
+		}
+		buildingTotals.add(index, value)
+		if record.DeviceID != "" {
+			if deviceTotals[record.DeviceID] == nil {
+				deviceTotals[record.DeviceID] = newWeeklyTotals(weeks)
+			}
+			deviceTotals[record.DeviceID].add(index, value)
+		}
+	}
+
+	xs, ys := buildingTotals.series()
+	trend, slope := newKPITrend(buildingID, metric, from, to, xs, ys)
+
+	if slope > 0 {
+		for deviceID, totals := range deviceTotals {
+			deviceSlope, _ := linearTrend(totals.series())
+			if deviceSlope <= 0 {
+				continue
+			}
+			trend.ContributingDevices = append(trend.ContributingDevices, models.DeviceContribution{
+				DeviceID:     deviceID,
+				Slope:        roundTo2(deviceSlope),
+				SharePercent: roundTo2(deviceSlope / slope * 100),
+			})
+		}
+		sort.Slice(trend.ContributingDevices, func(i, j int) bool {
+			return trend.ContributingDevices[i].Slope > trend.ContributingDevices[j].Slope
+		})
+		if len(trend.ContributingDevices) > maxContributingDevices {
+			trend.ContributingDevices = trend.ContributingDevices[:maxContributingDevices]
+		}
+	}
+
+	return trend, nil
+}
+
+// newKPITrend fits a linear trend to the weekly values of a metric, given by week index, and
+// returns the trend with its unrounded slope. Weeks count from the start of the analysed window.
+func newKPITrend(buildingID, metric string, from, to time.Time, xs, ys []float64) (*models.KPITrend, float64) {
+	const week = 7 * 24 * time.Hour
+	trend := &models.KPITrend{
+		BuildingID:          buildingID,
+		Metric:              metric,
+		From:                from,
+		To:                  to,
+		Direction:           models.TrendDirectionFlat,
+		Points:              []models.TrendPoint{},
+		ContributingDevices: []models.DeviceContribution{},
+	}
+
+	for i := range xs {
+		trend.Points = append(trend.Points, models.TrendPoint{
+			WeekStart: from.Add(time.Duration(xs[i]) * week),
+			Value:     roundTo2(ys[i]),
+		})
+	}
+
+	slope, confidence := linearTrend(xs, ys)
+	trend.Slope = roundTo2(slope)
+	trend.Confidence = math.Round(confidence*1000) / 1000
+	if mean := meanOf(ys); mean != 0 {
+		trend.SlopePercent = roundTo2(slope / mean * 100)
+	}
+	if confidence >= trendSignificance {
+		if slope > 0 {
+			trend.Direction = models.TrendDirectionUp
+		} else if slope < 0 {
+			trend.Direction = models.TrendDirectionDown
+		}
+	}
+
+	// Count the increases between the most recent consecutive weeks
+	for i := len(xs) - 1; i > 0 && xs[i]-xs[i-1] == 1 && ys[i] > ys[i-1]; i-- {
+		trend.ConsecutiveIncreases++
+	}
+	trend.Regression = trend.Direction == models.TrendDirectionUp && trend.ConsecutiveIncreases >= regressionStreak
+
+	return trend, slope
+}
+
+// weeklyTotals sums metric values per week, remembering which weeks had data
+type weeklyTotals struct {
+	values  []float64
+	hasData []bool
+}
+
+func newWeeklyTotals(weeks int) *weeklyTotals {
+	return &weeklyTotals{values: make([]float64, weeks), hasData: make([]bool, weeks)}
+}
+
+func (w *weeklyTotals) add(index int, value float64) {
+	w.values[index] += value
+	w.hasData[index] = true
+}
+
+// series returns the week indexes and totals of the weeks with data
+func (w *weeklyTotals) series() ([]float64, []float64) {
+	var xs, ys []float64
+	for i, value := range w.values {
+		if w.hasData[i] {
+			xs = append(xs, float64(i))
+			ys = append(ys, value)
+		}
+	}
+	return xs, ys
+}
+
+// linearTrend fits y = a + b*x by least squares and returns the slope b with its two-sided
+// confidence from a t-test against a zero slope. Fewer than three points give no confidence.
+func linearTrend(xs, ys []float64) (slope, confidence float64) {
+	n := len(xs)
+	if n < 2 {
+		return 0, 0
+	}
+
+	meanX, meanY := meanOf(xs), meanOf(ys)
+	var sxx, sxy float64
+	for i := range xs {
+		sxx += (xs[i] - meanX) * (xs[i] - meanX)
+		sxy += (xs[i] - meanX) * (ys[i] - meanY)
+	}
+	if sxx == 0 {
+		return 0, 0
+	}
+	slope = sxy / sxx
+	if n < 3 {
+		return slope, 0
+	}
+
+	intercept := meanY - slope*meanX
+	var sse float64
+	for i := range xs {
+		residual := ys[i] - (intercept + slope*xs[i])
+		sse += residual * residual
+	}
+	if sse == 0 {
+		if slope == 0 {
+			return 0, 0
+		}
+		return slope, 1
+	}
+
+	df := float64(n - 2)
+	t := slope / math.Sqrt(sse/df/sxx)
+	pValue := regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t))
+	return slope, 1 - pValue
+}
+
+// meanOf returns the arithmetic mean of the values
+func meanOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// regularizedIncompleteBeta evaluates I_x(a, b), used for the Student's t distribution
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction of the incomplete beta function
+// with the modified Lentz method
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 3e-14
+		tiny          = 1e-300
+	)
+
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	result := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		for _, numerator := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + numerator*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + numerator/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			result *= d * c
+		}
+		if math.Abs(d*c-1) < epsilon {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"analytics-service/internal/models"
+)
+
+const (
+	// defaultModeGapMinutes is the longest time between readings attributed to a mode by default
+	defaultModeGapMinutes = 15
+
+	// maxModeBreakdownDays limits the period of a mode breakdown
+	maxModeBreakdownDays = 31
+
+	// modeTelemetryPageSize is the page size used to read telemetry history from the IoT service
+	modeTelemetryPageSize = 1000
+
+	// defaultAssumedEcoSavingsPercent is the power reduction the Forecast service's device
+	// optimizations assume when they move a device to ECO_MODE
+	defaultAssumedEcoSavingsPercent = 15.0
+)
+
+// GetDeviceModeBreakdown attributes a device's consumption over a period, by default the last
+// 7 days, to the operating modes reported in its telemetry, and compares the average power in
+// the eco mode with the baseline mode. Energy between two readings is the change of the
+// cumulative energy meter, or the power of the earlier reading over the time between them when
+// the meter is missing or was reset.
+func (s *TimeSeriesService) GetDeviceModeBreakdown(ctx context.Context, deviceID string, query *models.DeviceModeBreakdownQuery, authToken string) (*models.DeviceModeBreakdown, error) {
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-7 * 24 * time.Hour)
+	}
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("validation failed: from timestamp must be before to timestamp")
+	}
+	if query.To.Sub(query.From) > maxModeBreakdownDays*24*time.Hour {
+		return nil, fmt.Errorf("validation failed: period must not exceed %d days", maxModeBreakdownDays)
+	}
+	if query.MaxGapMinutes == 0 {
+		query.MaxGapMinutes = defaultModeGapMinutes
+	}
+	baselineMode := strings.ToUpper(query.BaselineMode)
+	if baselineMode == "" {
+		baselineMode = "ON"
+	}
+	ecoMode := strings.ToUpper(query.EcoMode)
+	if ecoMode == "" {
+		ecoMode = "ECO_MODE"
+	}
+
+	readings, err := s.modeReadings(ctx, deviceID, query.From, query.To, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get telemetry: %w", err)
+	}
+
+	breakdown := &models.DeviceModeBreakdown{
+		DeviceID:      deviceID,
+		From:          query.From,
+		To:            query.To,
+		Readings:      len(readings),
+		MaxGapMinutes: query.MaxGapMinutes,
+		Modes:         []models.DeviceModeUsage{},
+	}
+
+	maxGap := time.Duration(query.MaxGapMinutes) * time.Minute
+	usage := make(map[string]*models.DeviceModeUsage)
+	totalEnergy := 0.0
+	for i := 0; i+1 < len(readings); i++ {
+		current, next := readings[i], readings[i+1]
+		elapsed := next.timestamp.Sub(current.timestamp)
+		if elapsed > maxGap {
+			breakdown.UncoveredHours += elapsed.Hours()
+			continue
+		}
+
+		energy := 0.0
+		switch {
+		case current.energy != nil && next.energy != nil && *next.energy >= *current.energy:
+			energy = *next.energy - *current.energy
+		case current.power != nil:
+			energy = *current.power * elapsed.Hours()
+		}
+
+		mode, ok := usage[current.mode]
+		if !ok {
+			mode = &models.DeviceModeUsage{Mode: current.mode}
+			usage[current.mode] = mode
+		}
+		mode.Hours += elapsed.Hours()
+		mode.EnergyKWh += energy
+		breakdown.TotalHours += elapsed.Hours()
+		totalEnergy += energy
+	}
+
+	for _, mode := range usage {
+		if mode.Hours > 0 {
+			mode.AveragePowerKW = roundTo4(mode.EnergyKWh / mode.Hours)
+		}
+		if totalEnergy > 0 {
+			mode.SharePercent = roundedPtr(mode.EnergyKWh / totalEnergy * 100)
+		}
+		mode.Hours = roundTo4(mode.Hours)
+		mode.EnergyKWh = roundTo4(mode.EnergyKWh)
+		breakdown.Modes = append(breakdown.Modes, *mode)
+	}
+	sort.Slice(breakdown.Modes, func(i, j int) bool { return breakdown.Modes[i].EnergyKWh > breakdown.Modes[j].EnergyKWh })
+
+	breakdown.TotalHours = roundTo4(breakdown.TotalHours)
+	breakdown.TotalEnergyKWh = roundTo4(totalEnergy)
+	breakdown.UncoveredHours = roundTo4(breakdown.UncoveredHours)
+
+	baseline, eco := usage[baselineMode], usage[ecoMode]
+	if baseline != nil && eco != nil {
+		assumed := defaultAssumedEcoSavingsPercent
+		if query.AssumedSavingsPercent != nil {
+			assumed = *query.AssumedSavingsPercent
+		}
+		savings := &models.EcoModeSavings{
+			BaselineMode:           baselineMode,
+			EcoMode:                ecoMode,
+			BaselineAveragePowerKW: baseline.AveragePowerKW,
+			EcoAveragePowerKW:      eco.AveragePowerKW,
+			AssumedSavingsPercent:  assumed,
+		}
+		if baseline.AveragePowerKW > 0 {
+			observed := (baseline.AveragePowerKW - eco.AveragePowerKW) / baseline.AveragePowerKW * 100
+			savings.ObservedSavingsPercent = roundedPtr(observed)
+			savings.SavingsGapPercent = roundedPtr(observed - assumed)
+		}
+		breakdown.EcoSavings = savings
+	}
+
+	return breakdown, nil
+}
+
+// modeReading is a telemetry reading reduced to what the mode breakdown needs
+type modeReading struct {
+	timestamp time.Time
+	mode      string
+	power     *float64 // kW
+	energy    *float64 // Cumulative kWh
+}
+
+// modeReadings reads a device's telemetry in [from, to] from the IoT service, oldest first
+func (s *TimeSeriesService) modeReadings(ctx context.Context, deviceID string, from, to time.Time, authToken string) ([]modeReading, error) {
+	var readings []modeReading
+	for page := 1; ; page++ {
+		telemetry, err := s.iotClient.GetTelemetryHistory(ctx, deviceID, from, to, page, modeTelemetryPageSize, authToken)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range telemetry {
+			raw, _ := t["timestamp"].(string)
+			timestamp, err := time.Parse(time.RFC3339Nano, raw)
+			if err != nil {
+				continue
+			}
+			metrics, _ := t["metrics"].(map[string]interface{})
+			reading := modeReading{timestamp: timestamp, mode: operatingMode(metrics)}
+			if power, ok := metrics["power"].(float64); ok {
+				reading.power = &power
+			}
+			if energy, ok := metrics["energy"].(float64); ok {
+				reading.energy = &energy
+			}
+			readings = append(readings, reading)
+		}
+
+		if len(telemetry) < modeTelemetryPageSize {
+			break
+		}
+	}
+
+	sort.Slice(readings, func(i, j int) bool { return readings[i].timestamp.Before(readings[j].timestamp) })
+	return readings, nil
+}
+
+// operatingMode derives the operating mode of a telemetry reading: its reported state, OFF for
+// devices switched off, otherwise the reported mode or status
+func operatingMode(metrics map[string]interface{}) string {
+	if state, ok := metrics["state"].(string); ok && state != "" {
+		return strings.ToUpper(state)
+	}
+	status, _ := metrics["status"].(string)
+	if strings.EqualFold(status, "OFF") {
+		return "OFF"
+	}
+	if mode, ok := metrics["mode"].(string); ok && mode != "" {
+		return strings.ToUpper(mode)
+	}
+	if status != "" {
+		return strings.ToUpper(status)
+	}
+	return "UNKNOWN"
+}
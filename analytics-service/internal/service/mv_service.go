@@ -0,0 +1,559 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"analytics-service/internal/events"
+	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
+)
+
+const (
+	defaultMVConfidenceLevel    = 90.0
+	defaultOptionCBaselineDays  = 90
+	defaultOptionCReportingDays = 30
+	defaultOptionABaselineDays  = 14
+	defaultOptionAReportingDays = 14
+	// guideline14MaxCVRMSE is the largest CV(RMSE), in percent, of a daily baseline model
+	// accepted under ASHRAE Guideline 14
+	guideline14MaxCVRMSE = 25.0
+	// minDegreeDayVariance is the smallest baseline variance of a degree day term that is fitted
+	minDegreeDayVariance = 1e-6
+)
+
+// MVService verifies the savings of executed optimization scenarios following IPMVP option A
+// (retrofit isolation with a measured key parameter) and option C (whole facility). Savings are
+// reported with their uncertainty, following ASHRAE Guideline 14.
+type MVService struct {
+	reportRepo     *repository.MVReportRepository
+	executionRepo  *repository.OptimizationExecutionRepository
+	timeSeriesRepo *repository.TimeSeriesRepository
+	forecastClient interface {
+		GetDegreeDays(ctx context.Context, buildingID string, from, to time.Time, authToken string) (*models.DegreeDays, error)
+	}
+	eventBus *events.Bus
+}
+
+// NewMVService creates a new M&V service
+func NewMVService(
+	reportRepo *repository.MVReportRepository,
+	executionRepo *repository.OptimizationExecutionRepository,
+	timeSeriesRepo *repository.TimeSeriesRepository,
+	forecastClient interface {
+		GetDegreeDays(ctx context.Context, buildingID string, from, to time.Time, authToken string) (*models.DegreeDays, error)
+	},
+	eventBus *events.Bus,
+) *MVService {
+	return &MVService{
+		reportRepo:     reportRepo,
+		executionRepo:  executionRepo,
+		timeSeriesRepo: timeSeriesRepo,
+		forecastClient: forecastClient,
+		eventBus:       eventBus,
+	}
+}
+
+// CreateReport verifies the savings of an executed scenario and stores the report. The baseline
+// period is the whole days before the execution started and the reporting period the whole days
+// after it completed, shortened to the days that have ended. Reports of scenarios generated by the
+// Forecast service are published so it can learn how much of the expected savings are delivered.
+func (s *MVService) CreateReport(ctx context.Context, req *models.MVReportRequest, userID, authToken string) (*models.MVReportResponse, error) {
+	execution, err := s.executionRepo.FindByScenarioID(ctx, req.ScenarioID)
+	if err != nil {
+		return nil, err
+	}
+
+	baselineDays, reportingDays := defaultOptionCBaselineDays, defaultOptionCReportingDays
+	if req.Option == models.MVOptionA {
+		baselineDays, reportingDays = defaultOptionABaselineDays, defaultOptionAReportingDays
+	}
+	if req.BaselineDays > 0 {
+		baselineDays = req.BaselineDays
+	}
+	if req.ReportingDays > 0 {
+		reportingDays = req.ReportingDays
+	}
+	confidence := req.ConfidenceLevel
+	if confidence == 0 {
+		confidence = defaultMVConfidenceLevel
+	}
+
+	startedAt := execution.StartedAt
+	if startedAt.IsZero() {
+		startedAt = execution.CompletedAt
+	}
+
+	report := &models.MVReport{
+		ScenarioID:           execution.ScenarioID,
+		ScenarioType:         execution.ScenarioType,
+		BuildingID:           execution.BuildingID,
+		Option:               req.Option,
+		ExecutionStartedAt:   startedAt,
+		ExecutionCompletedAt: execution.CompletedAt,
+		ConfidenceLevel:      confidence,
+		CreatedBy:            userID,
+	}
+	report.BaselineTo = startedAt.UTC().Truncate(24 * time.Hour)
+	report.BaselineFrom = report.BaselineTo.AddDate(0, 0, -baselineDays)
+	report.ReportingFrom = execution.CompletedAt.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	report.ReportingTo = report.ReportingFrom.AddDate(0, 0, reportingDays)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if report.ReportingTo.After(today) {
+		report.ReportingTo = today
+		if !report.ReportingTo.After(report.ReportingFrom) {
+			return nil, errors.New("validation failed: no whole day has passed since the scenario completed")
+		}
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"reporting period shortened to %d days because later days have not ended yet",
+			int(report.ReportingTo.Sub(report.ReportingFrom).Hours()/24)))
+	}
+
+	if execution.ActionsFailed > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d of the scenario's actions failed", execution.ActionsFailed))
+	}
+
+	switch req.Option {
+	case models.MVOptionC:
+		err = s.verifyWholeBuilding(ctx, report, authToken)
+	case models.MVOptionA:
+		err = s.verifyKeyParameter(ctx, report, execution, req.OperatingHours)
+	default:
+		err = fmt.Errorf("validation failed: unsupported option %q", req.Option)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !report.Savings.Significant {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"savings are not distinguishable from zero at %g%% confidence", confidence))
+	}
+
+	created, err := s.reportRepo.Create(ctx, report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store M&V report: %w", err)
+	}
+
+	if execution.SourceScenarioID != "" {
+		verified := &events.SavingsVerifiedData{
+			ReportID:         created.ID.Hex(),
+			ScenarioID:       created.ScenarioID,
+			SourceScenarioID: execution.SourceScenarioID,
+			BuildingID:       created.BuildingID,
+			Option:           string(created.Option),
+			SavingsKWh:       created.Savings.SavingsKWh,
+			SavingsPercent:   created.Savings.SavingsPercent,
+			Significant:      created.Savings.Significant,
+			ReportingFrom:    created.ReportingFrom,
+			ReportingTo:      created.ReportingTo,
+			VerifiedAt:       created.CreatedAt,
+		}
+		if key := created.KeyParameter; key != nil {
+			verified.DeviceIDs = key.DeviceIDs
+			verified.BaselineMeanKWh = key.BaselineMeanKWh
+			verified.ReportingMeanKWh = key.ReportingMeanKWh
+		}
+		s.eventBus.Publish(ctx, events.SavingsVerified, verified)
+	}
+	return created.ToResponse(), nil
+}
+
+// GetReport retrieves an M&V report by ID
+func (s *MVService) GetReport(ctx context.Context, id string) (*models.MVReportResponse, error) {
+	report, err := s.reportRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return report.ToResponse(), nil
+}
+
+// ListReports retrieves M&V reports with filters and pagination
+func (s *MVService) ListReports(ctx context.Context, buildingID, scenarioID string, page, limit int) ([]*models.MVReportResponse, int64, error) {
+	reports, total, err := s.reportRepo.FindAll(ctx, buildingID, scenarioID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]*models.MVReportResponse, len(reports))
+	for i, report := range reports {
+		responses[i] = report.ToResponse()
+	}
+	return responses, total, nil
+}
+
+// mvDay is one day of whole-building consumption with its degree days
+type mvDay struct {
+	consumption float64
+	heating     float64
+	cooling     float64
+}
+
+// verifyWholeBuilding applies option C: a regression of the building's daily consumption on
+// heating and cooling degree days is fitted over the baseline period and predicts what the
+// building would have used under the weather of the reporting period.
+func (s *MVService) verifyWholeBuilding(ctx context.Context, report *models.MVReport, authToken string) error {
+	baseline, baseTemperature, baselineExcluded, err := s.dailyObservations(ctx, report.BuildingID, report.BaselineFrom, report.BaselineTo, authToken)
+	if err != nil {
+		return err
+	}
+	reporting, _, reportingExcluded, err := s.dailyObservations(ctx, report.BuildingID, report.ReportingFrom, report.ReportingTo, authToken)
+	if err != nil {
+		return err
+	}
+	if len(reporting) == 0 {
+		return errors.New("validation failed: reporting period has no days with consumption and weather data")
+	}
+
+	regression, err := fitDegreeDayRegression(baseline)
+	if err != nil {
+		return err
+	}
+
+	n, k := float64(len(baseline)), float64(len(regression.coefficients))
+	df := n - k
+	var sse, sst, residualSum, lagProduct, meanConsumption float64
+	for _, day := range baseline {
+		meanConsumption += day.consumption / n
+	}
+	previous := 0.0
+	for i, day := range baseline {
+		residual := day.consumption - regression.predict(day)
+		sse += residual * residual
+		sst += (day.consumption - meanConsumption) * (day.consumption - meanConsumption)
+		residualSum += residual
+		if i > 0 {
+			lagProduct += residual * previous
+		}
+		previous = residual
+	}
+
+	model := &models.MVRegressionModel{
+		Variables:        regression.variables(),
+		Intercept:        roundTo4(regression.coefficients[0]),
+		BaseTemperature:  baseTemperature,
+		BaselineDays:     len(baseline),
+		ReportingDays:    len(reporting),
+		ExcludedDays:     baselineExcluded + reportingExcluded,
+		DegreesOfFreedom: int(df),
+	}
+	model.HeatingCoefficient, model.CoolingCoefficient = regression.degreeDayCoefficients()
+	model.HeatingCoefficient, model.CoolingCoefficient = roundTo4(model.HeatingCoefficient), roundTo4(model.CoolingCoefficient)
+
+	cvRMSE := 0.0
+	if meanConsumption > 0 {
+		cvRMSE = math.Sqrt(sse/df) / meanConsumption
+		model.NMBEPercent = roundTo2(residualSum / (df * meanConsumption) * 100)
+	}
+	if sst > 0 {
+		model.RSquared = roundTo4(1 - sse/sst)
+	}
+	// Positive lag-1 autocorrelation of the residuals means fewer independent observations
+	rho := 0.0
+	if sse > 0 {
+		rho = math.Min(math.Max(lagProduct/sse, 0), 0.99)
+	}
+	model.Autocorrelation = roundTo4(rho)
+	model.CVRMSEPercent = roundTo2(cvRMSE * 100)
+	model.MeetsGuideline14 = meanConsumption > 0 && cvRMSE*100 <= guideline14MaxCVRMSE
+	report.Model = model
+
+	var adjusted, actual float64
+	for _, day := range reporting {
+		adjusted += regression.predict(day)
+		actual += day.consumption
+	}
+
+	// ASHRAE Guideline 14 savings uncertainty of a regression baseline
+	m := float64(len(reporting))
+	effectiveN := n * (1 - rho) / (1 + rho)
+	t := studentTQuantile(report.ConfidenceLevel, df)
+	uncertainty := t * 1.26 * cvRMSE * math.Abs(adjusted) * math.Sqrt((n/effectiveN)*(1+2/n)/m)
+	report.Savings = mvSavings(adjusted, actual, uncertainty, t)
+
+	if !model.MeetsGuideline14 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"baseline model CV(RMSE) of %.1f%% exceeds the %g%% ASHRAE Guideline 14 limit for daily models",
+			model.CVRMSEPercent, guideline14MaxCVRMSE))
+	}
+	if model.ExcludedDays > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"%d days without weather data were left out", model.ExcludedDays))
+	}
+	return nil
+}
+
+// dailyObservations pairs a building's daily consumption in [from, to) with the day's degree
+// days and returns them in date order with the degree day base temperature. Days without weather
+// data are left out and counted.
+func (s *MVService) dailyObservations(ctx context.Context, buildingID string, from, to time.Time, authToken string) ([]mvDay, float64, int, error) {
+	// Daily aggregates are matched inclusively, so stop just before the period end
+	days, err := s.timeSeriesRepo.SumDailyConsumptionByBuilding(ctx, []string{buildingID}, from, to.Add(-time.Millisecond))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to aggregate consumption: %w", err)
+	}
+	degreeDays, err := s.forecastClient.GetDegreeDays(ctx, buildingID, from, to, authToken)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to get degree days: %w", err)
+	}
+
+	byDate := make(map[string]models.DailyDegreeDays, len(degreeDays.Days))
+	for _, day := range degreeDays.Days {
+		byDate[day.Date] = day
+	}
+
+	observations := make([]mvDay, 0, len(days))
+	excluded := 0
+	for _, day := range days {
+		weather, ok := byDate[day.Day.UTC().Format("2006-01-02")]
+		if !ok {
+			excluded++
+			continue
+		}
+		observations = append(observations, mvDay{
+			consumption: math.Max(day.MainMeter, day.Metered),
+			heating:     weather.HeatingDegreeDays,
+			cooling:     weather.CoolingDegreeDays,
+		})
+	}
+
+	return observations, degreeDays.BaseTemperature, excluded, nil
+}
+
+// verifyKeyParameter applies option A: the combined hourly demand of the devices the scenario
+// changed is measured before and after it, and the difference is applied to the stipulated
+// operating hours of the reporting period
+func (s *MVService) verifyKeyParameter(ctx context.Context, report *models.MVReport, execution *models.OptimizationExecution, operatingHours float64) error {
+	seen := make(map[string]bool)
+	var deviceIDs []string
+	for _, action := range execution.Actions {
+		if action.Status == models.ActionStatusApplied && action.DeviceID != "" && !seen[action.DeviceID] {
+			seen[action.DeviceID] = true
+			deviceIDs = append(deviceIDs, action.DeviceID)
+		}
+	}
+	if len(deviceIDs) == 0 {
+		return errors.New("validation failed: scenario has no applied actions to verify")
+	}
+	sort.Strings(deviceIDs)
+
+	baseline, err := s.hourlyDemand(ctx, report.BuildingID, deviceIDs, report.BaselineFrom, report.BaselineTo)
+	if err != nil {
+		return err
+	}
+	reporting, err := s.hourlyDemand(ctx, report.BuildingID, deviceIDs, report.ReportingFrom, report.ReportingTo)
+	if err != nil {
+		return err
+	}
+	if len(baseline) < 2 || len(reporting) < 2 {
+		return errors.New("validation failed: not enough hourly data for the scenario's devices")
+	}
+
+	if operatingHours == 0 {
+		operatingHours = report.ReportingTo.Sub(report.ReportingFrom).Hours()
+	}
+
+	nB, nR := float64(len(baseline)), float64(len(reporting))
+	meanB, meanR := meanOf(baseline), meanOf(reporting)
+	varB, varR := sampleVariance(baseline, meanB), sampleVariance(reporting, meanR)
+
+	report.KeyParameter = &models.MVKeyParameter{
+		DeviceIDs:                deviceIDs,
+		BaselineMeanKWh:          roundTo4(meanB),
+		BaselineStdDevKWh:        roundTo4(math.Sqrt(varB)),
+		BaselineSamples:          len(baseline),
+		ReportingMeanKWh:         roundTo4(meanR),
+		ReportingStdDevKWh:       roundTo4(math.Sqrt(varR)),
+		ReportingSamples:         len(reporting),
+		StipulatedOperatingHours: operatingHours,
+	}
+
+	// Welch's standard error of the difference in mean demand, scaled to the operating hours
+	seB, seR := varB/nB, varR/nR
+	df := nB + nR - 2
+	if seB+seR > 0 {
+		df = (seB + seR) * (seB + seR) / (seB*seB/(nB-1) + seR*seR/(nR-1))
+	}
+	t := studentTQuantile(report.ConfidenceLevel, df)
+	uncertainty := t * math.Sqrt(seB+seR) * operatingHours
+	report.Savings = mvSavings(meanB*operatingHours, meanR*operatingHours, uncertainty, t)
+	return nil
+}
+
+// hourlyDemand returns the combined consumption of the devices in each hour of [from, to)
+func (s *MVService) hourlyDemand(ctx context.Context, buildingID string, deviceIDs []string, from, to time.Time) ([]float64, error) {
+	hours, err := s.timeSeriesRepo.SumHourlyDeviceConsumption(ctx, buildingID, deviceIDs, from, to.Add(-time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate device consumption: %w", err)
+	}
+
+	values := make([]float64, len(hours))
+	for i, hour := range hours {
+		values[i] = hour.Metered
+	}
+	return values, nil
+}
+
+// mvSavings summarizes savings with their uncertainty at the confidence of the t value.
+// Savings are significant when the uncertainty interval excludes zero.
+func mvSavings(adjustedBaseline, reporting, uncertainty, t float64) models.MVSavings {
+	savings := adjustedBaseline - reporting
+	result := models.MVSavings{
+		AdjustedBaselineKWh: roundTo2(adjustedBaseline),
+		ReportingKWh:        roundTo2(reporting),
+		SavingsKWh:          roundTo2(savings),
+		UncertaintyKWh:      roundTo2(uncertainty),
+		LowerKWh:            roundTo2(savings - uncertainty),
+		UpperKWh:            roundTo2(savings + uncertainty),
+		TValue:              roundTo4(t),
+		Significant:         math.Abs(savings) > uncertainty,
+	}
+	if adjustedBaseline != 0 {
+		result.SavingsPercent = roundTo2(savings / adjustedBaseline * 100)
+	}
+	if savings != 0 {
+		result.UncertaintyPercent = roundTo2(uncertainty / math.Abs(savings) * 100)
+	}
+	return result
+}
+
+// degreeDayRegression is an ordinary least squares fit of daily consumption on the degree day
+// terms with enough variation in the baseline. The intercept comes first in coefficients.
+type degreeDayRegression struct {
+	heating      bool
+	cooling      bool
+	coefficients []float64
+}
+
+// fitDegreeDayRegression fits daily consumption to heating and cooling degree days by solving
+// the normal equations. Terms that hardly vary over the baseline, such as cooling in winter, are
+// left out so the fit stays well defined.
+func fitDegreeDayRegression(days []mvDay) (*degreeDayRegression, error) {
+	regression := &degreeDayRegression{}
+	if len(days) > 1 {
+		heating, cooling := make([]float64, len(days)), make([]float64, len(days))
+		for i, day := range days {
+			heating[i], cooling[i] = day.heating, day.cooling
+		}
+		regression.heating = sampleVariance(heating, meanOf(heating)) > minDegreeDayVariance
+		regression.cooling = sampleVariance(cooling, meanOf(cooling)) > minDegreeDayVariance
+	}
+
+	k := len(regression.row(mvDay{}))
+	if len(days) < k+2 {
+		return nil, errors.New("validation failed: baseline period has too few days with consumption and weather data")
+	}
+
+	// Normal equations XᵀX β = Xᵀy as an augmented matrix
+	matrix := make([][]float64, k)
+	for i := range matrix {
+		matrix[i] = make([]float64, k+1)
+	}
+	for _, day := range days {
+		row := regression.row(day)
+		for i := 0; i < k; i++ {
+			for j := 0; j < k; j++ {
+				matrix[i][j] += row[i] * row[j]
+			}
+			matrix[i][k] += row[i] * day.consumption
+		}
+	}
+
+	// Gaussian elimination with partial pivoting
+	for col := 0; col < k; col++ {
+		pivot := col
+		for r := col + 1; r < k; r++ {
+			if math.Abs(matrix[r][col]) > math.Abs(matrix[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(matrix[pivot][col]) < 1e-12 {
+			return nil, errors.New("baseline model could not be fitted: degree day terms are collinear")
+		}
+		matrix[col], matrix[pivot] = matrix[pivot], matrix[col]
+		for r := col + 1; r < k; r++ {
+			factor := matrix[r][col] / matrix[col][col]
+			for c := col; c <= k; c++ {
+				matrix[r][c] -= factor * matrix[col][c]
+			}
+		}
+	}
+
+	regression.coefficients = make([]float64, k)
+	for i := k - 1; i >= 0; i-- {
+		sum := matrix[i][k]
+		for j := i + 1; j < k; j++ {
+			sum -= matrix[i][j] * regression.coefficients[j]
+		}
+		regression.coefficients[i] = sum / matrix[i][i]
+	}
+
+	return regression, nil
+}
+
+// row returns the regressors of a day: a constant, then the fitted degree day terms
+func (r *degreeDayRegression) row(day mvDay) []float64 {
+	row := []float64{1}
+	if r.heating {
+		row = append(row, day.heating)
+	}
+	if r.cooling {
+		row = append(row, day.cooling)
+	}
+	return row
+}
+
+// predict returns the consumption the model expects on a day
+func (r *degreeDayRegression) predict(day mvDay) float64 {
+	prediction := 0.0
+	for i, value := range r.row(day) {
+		prediction += r.coefficients[i] * value
+	}
+	return prediction
+}
+
+// variables names the fitted degree day terms
+func (r *degreeDayRegression) variables() []string {
+	variables := []string{}
+	if r.heating {
+		variables = append(variables, "heatingDegreeDays")
+	}
+	if r.cooling {
+		variables = append(variables, "coolingDegreeDays")
+	}
+	return variables
+}
+
+// degreeDayCoefficients returns the heating and cooling coefficients, zero for terms left out
+func (r *degreeDayRegression) degreeDayCoefficients() (heating, cooling float64) {
+	index := 1
+	if r.heating {
+		heating = r.coefficients[index]
+		index++
+	}
+	if r.cooling {
+		cooling = r.coefficients[index]
+	}
+	return heating, cooling
+}
+
+// studentTQuantile returns the two-sided critical value of Student's t distribution with df
+// degrees of freedom at a confidence level given in percent
+func studentTQuantile(confidence, df float64) float64 {
+	alpha := 1 - confidence/100
+	low, high := 0.0, 1000.0
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if regularizedIncompleteBeta(df/2, 0.5, df/(df+mid*mid)) > alpha {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
@@ -3,6 +3,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -10,10 +11,22 @@ import (
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 
+	"analytics-service/internal/integrations"
 	"analytics-service/internal/models"
 	"analytics-service/internal/repository"
+	"analytics-service/internal/tenant"
+	"shared/jobs"
 )
 
+// reportGenerationJob is the job type generating the content of requested reports
+const reportGenerationJob = "report_generation"
+
+// reportJobPayload identifies a report awaiting content and the request it was generated for
+type reportJobPayload struct {
+	ReportID string                       `bson:"report_id"`
+	Request  models.GenerateReportRequest `bson:"request"`
+}
+
 // ReportService handles report business logic
 type ReportService struct {
 	reportRepo *repository.ReportRepository
@@ -24,6 +37,11 @@ type ReportService struct {
 	forecastClient interface {
 		GetLatestForecast(ctx context.Context, buildingID string, authToken string) (map[string]interface{}, error)
 	}
+	securityClient interface {
+		DelegateToken(ctx context.Context, userID, orgID string) (string, error)
+	}
+	normalizer *WeatherNormalizer
+	jobQueue   *jobs.Queue
 }
 
 // NewReportService creates a new report service
@@ -36,20 +54,37 @@ func NewReportService(
 	forecastClient interface {
 		GetLatestForecast(ctx context.Context, buildingID string, authToken string) (map[string]interface{}, error)
 	},
+	securityClient interface {
+		DelegateToken(ctx context.Context, userID, orgID string) (string, error)
+	},
+	normalizer *WeatherNormalizer,
+	jobQueue *jobs.Queue,
 ) *ReportService {
-	return &ReportService{
+	s := &ReportService{
 		reportRepo:     reportRepo,
 		iotClient:      iotClient,
 		forecastClient: forecastClient,
+		securityClient: securityClient,
+		normalizer:     normalizer,
+		jobQueue:       jobQueue,
 	}
+	jobQueue.Register(reportGenerationJob, s.processReportJob, jobs.Options{
+		RequiresIdentity: true,
+		OnFailed:         s.failReportJob,
+	})
+	return s
 }
 
-// GenerateReport generates an analytical report
-func (s *ReportService) GenerateReport(ctx context.Context, req *models.GenerateReportRequest, userID, authToken string) (*models.ReportResponse, error) {
+// GenerateReport generates an analytical report. The content is generated by a job acting for
+// userID in the organization of the request.
+func (s *ReportService) GenerateReport(ctx context.Context, req *models.GenerateReportRequest, userID string) (*models.ReportResponse, error) {
 	// Validate request
 	if err := s.validateGenerateReport(req); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
+	if req.BuildingID != "" && !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
 
 	// Generate report ID
 	reportID := uuid.New().String()
@@ -71,13 +106,74 @@ func (s *ReportService) GenerateReport(ctx context.Context, req *models.Generate
 	}
 
 	// Generate report content asynchronously
-	go s.generateReportContent(context.Background(), createdReport, req, authToken)
+	payload := &reportJobPayload{ReportID: createdReport.ID.Hex(), Request: *req}
+	identity := &jobs.Identity{UserID: userID}
+	if scope := tenant.FromContext(ctx); scope != nil {
+		identity.OrgID = scope.OrgID
+	}
+	if _, err := s.jobQueue.Enqueue(ctx, reportGenerationJob, payload, identity); err != nil {
+		if _, updateErr := s.reportRepo.Update(ctx, createdReport.ID.Hex(), bson.M{"status": models.ReportStatusFailed}); updateErr != nil {
+			log.Printf("Failed to fail report %s: %v", createdReport.ReportID, updateErr)
+		}
+		return nil, err
+	}
 
 	return createdReport.ToResponse(), nil
 }
 
+// processReportJob generates the content of a queued report. A report that is no longer
+// generating, because an interrupted run finished it, is skipped.
+func (s *ReportService) processReportJob(ctx context.Context, job *jobs.Job) error {
+	var payload reportJobPayload
+	if err := job.Decode(&payload); err != nil {
+		return jobs.Permanent(fmt.Errorf("invalid report job payload: %w", err))
+	}
+
+	report, err := s.reportRepo.FindByID(ctx, payload.ReportID)
+	if err != nil {
+		if err.Error() == "report not found" || err.Error() == "invalid report ID format" {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	if report.Status != models.ReportStatusGenerating {
+		return nil
+	}
+
+	// The submitter is authorized again on every run, so a report stops once they lose access
+	authToken, err := s.securityClient.DelegateToken(ctx, job.Identity.UserID, job.Identity.OrgID)
+	if errors.Is(err, integrations.ErrDelegationDenied) {
+		return jobs.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.generateReportContent(ctx, report, &payload.Request, authToken)
+}
+
+// failReportJob marks the report of a job that failed for good as failed
+func (s *ReportService) failReportJob(ctx context.Context, job *jobs.Job, reason string) {
+	var payload reportJobPayload
+	if err := job.Decode(&payload); err != nil {
+		return
+	}
+
+	report, err := s.reportRepo.FindByID(ctx, payload.ReportID)
+	if err != nil || report.Status != models.ReportStatusGenerating {
+		return
+	}
+	updates := bson.M{
+		"status":  models.ReportStatusFailed,
+		"content": map[string]interface{}{"error": reason},
+	}
+	if _, err := s.reportRepo.Update(ctx, payload.ReportID, updates); err != nil {
+		log.Printf("Failed to fail report %s: %v", report.ReportID, err)
+	}
+}
+
 // generateReportContent generates the actual report content
-func (s *ReportService) generateReportContent(ctx context.Context, report *models.Report, req *models.GenerateReportRequest, authToken string) {
+func (s *ReportService) generateReportContent(ctx context.Context, report *models.Report, req *models.GenerateReportRequest, authToken string) error {
 	content := make(map[string]interface{})
 
 	// Get devices for the building
@@ -115,10 +211,10 @@ func (s *ReportService) generateReportContent(ctx context.Context, report *model
 		"generated_at": time.Now(),
 	}
 
-	_, err = s.reportRepo.Update(ctx, report.ID.Hex(), updates)
-	if err != nil {
-		log.Printf("Failed to update report: %v", err)
+	if _, err := s.reportRepo.Update(ctx, report.ID.Hex(), updates); err != nil {
+		return fmt.Errorf("failed to update report: %w", err)
 	}
+	return nil
 }
 
 // generateEnergyConsumptionReport generates energy consumption report
@@ -165,6 +261,17 @@ func (s *ReportService) generateEnergyConsumptionReport(ctx context.Context, req
 	content["deviceConsumptions"] = deviceConsumptions
 	content["averageConsumption"] = totalConsumption / float64(len(devices))
 
+	// Compare with the preceding period of equal length, corrected for weather
+	if req.BuildingID != "" && s.normalizer != nil && req.To.After(req.From) {
+		refFrom := req.From.Add(-req.To.Sub(req.From))
+		normalized, err := s.normalizer.Normalize(ctx, req.BuildingID, req.From, req.To, refFrom, req.From, authToken)
+		if err != nil {
+			log.Printf("Failed to normalize consumption for report: %v", err)
+		} else {
+			content["weatherNormalization"] = normalized.Metrics()
+		}
+	}
+
 	return content
 }
 
@@ -228,6 +335,52 @@ func (s *ReportService) ListReports(ctx context.Context, buildingID, reportType,
 	return responses, total, nil
 }
 
+// SearchReports searches reports by free text and filters, most relevant first. Results are
+// paged with the cursor returned by the previous page.
+func (s *ReportService) SearchReports(ctx context.Context, req *models.SearchReportsRequest) (*models.SearchResponse, error) {
+	var after *models.SearchCursor
+	if req.Cursor != "" {
+		cursor, err := models.DecodeSearchCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = cursor
+	}
+	limit := req.Limit
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	// One extra hit tells whether another page follows
+	hits, err := s.reportRepo.Search(ctx, req, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.SearchResponse{Results: []*models.SearchResult{}}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[len(hits)-1]
+		response.NextCursor = models.SearchCursor{Score: last.Score, ID: last.ID}.Encode()
+	}
+	for _, hit := range hits {
+		title := hit.Type
+		if hit.BuildingID != "" {
+			title = fmt.Sprintf("%s - %s", hit.Type, hit.BuildingID)
+		}
+		response.Results = append(response.Results, &models.SearchResult{
+			Type:       models.SearchResultReport,
+			ID:         hit.ReportID,
+			Title:      title,
+			BuildingID: hit.BuildingID,
+			Score:      hit.Score,
+			Data:       hit.Report.ToResponse(),
+		})
+	}
+
+	return response, nil
+}
+
 // validateGenerateReport validates report generation request
 func (s *ReportService) validateGenerateReport(req *models.GenerateReportRequest) error {
 	if req.Type == "" {
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"analytics-service/internal/events"
+	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
+	"analytics-service/internal/tenant"
+)
+
+// savingsGoalMilestones are the percentages of a goal's target savings that trigger a
+// notification when first reached
+var savingsGoalMilestones = []int{50, 75, 100}
+
+// SavingsGoalService handles savings goal management and tracking
+type SavingsGoalService struct {
+	goalRepo       *repository.SavingsGoalRepository
+	timeSeriesRepo *repository.TimeSeriesRepository
+	eventBus       *events.Bus
+	forecastClient interface {
+		GetStoredForecast(ctx context.Context, buildingID string) (map[string]interface{}, error)
+	}
+}
+
+// NewSavingsGoalService creates a new savings goal service
+func NewSavingsGoalService(
+	goalRepo *repository.SavingsGoalRepository,
+	timeSeriesRepo *repository.TimeSeriesRepository,
+	forecastClient interface {
+		GetStoredForecast(ctx context.Context, buildingID string) (map[string]interface{}, error)
+	},
+	eventBus *events.Bus,
+) *SavingsGoalService {
+	return &SavingsGoalService{
+		goalRepo:       goalRepo,
+		timeSeriesRepo: timeSeriesRepo,
+		eventBus:       eventBus,
+		forecastClient: forecastClient,
+	}
+}
+
+// CreateGoal creates a savings goal for a building
+func (s *SavingsGoalService) CreateGoal(ctx context.Context, req *models.SavingsGoalRequest, userID string) (*models.SavingsGoalResponse, error) {
+	if !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
+
+	goal := &models.SavingsGoal{CreatedBy: userID}
+	applySavingsGoalRequest(goal, req)
+
+	created, err := s.goalRepo.Create(ctx, goal)
+	if err != nil {
+		return nil, err
+	}
+
+	return created.ToResponse(), nil
+}
+
+// GetGoal retrieves a savings goal by ID
+func (s *SavingsGoalService) GetGoal(ctx context.Context, id string) (*models.SavingsGoalResponse, error) {
+	goal, err := s.goalRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return goal.ToResponse(), nil
+}
+
+// ListGoals lists savings goals with filters
+func (s *SavingsGoalService) ListGoals(ctx context.Context, buildingID string, year, page, limit int) ([]*models.SavingsGoalResponse, int64, error) {
+	goals, total, err := s.goalRepo.FindAll(ctx, buildingID, year, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]*models.SavingsGoalResponse, len(goals))
+	for i, goal := range goals {
+		responses[i] = goal.ToResponse()
+	}
+
+	return responses, total, nil
+}
+
+// UpdateGoal replaces a savings goal's settings. Tracking is kept, so milestones already notified
+// are not notified again, unless the building or the years compared changed.
+func (s *SavingsGoalService) UpdateGoal(ctx context.Context, id string, req *models.SavingsGoalRequest) (*models.SavingsGoalResponse, error) {
+	if !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
+
+	existing, err := s.goalRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	goal := &models.SavingsGoal{}
+	applySavingsGoalRequest(goal, req)
+
+	updates := bson.M{
+		"name":            goal.Name,
+		"building_id":     goal.BuildingID,
+		"year":            goal.Year,
+		"baseline_year":   goal.BaselineYear,
+		"target_percent":  goal.TargetPercent,
+		"notify_user_ids": goal.NotifyUserIDs,
+	}
+	if goal.BuildingID != existing.BuildingID || goal.Year != existing.Year || goal.BaselineYear != existing.BaselineYear {
+		updates["tracking"] = models.SavingsGoalTracking{}
+	}
+
+	updated, err := s.goalRepo.Update(ctx, id, updates)
+	if err != nil {
+		return nil, err
+	}
+
+	return updated.ToResponse(), nil
+}
+
+// DeleteGoal deletes a savings goal
+func (s *SavingsGoalService) DeleteGoal(ctx context.Context, id string) error {
+	return s.goalRepo.Delete(ctx, id)
+}
+
+// GetGoalProgress calculates a savings goal's progress and projected year-end attainment
+func (s *SavingsGoalService) GetGoalProgress(ctx context.Context, id string) (*models.SavingsGoalProgress, error) {
+	goal, err := s.goalRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.calculateProgress(ctx, goal, time.Now())
+}
+
+// TrackGoals recalculates the progress of every savings goal whose year has begun and publishes an
+// event for each goal that reaches a new milestone
+func (s *SavingsGoalService) TrackGoals(ctx context.Context) error {
+	now := time.Now()
+	return s.goalRepo.FindEachActive(ctx, now.UTC().Year(), func(goal *models.SavingsGoal) error {
+		if err := s.track(ctx, goal, now); err != nil {
+			log.Printf("Failed to track savings goal %s: %v", goal.ID.Hex(), err)
+		}
+		return nil
+	})
+}
+
+// StartWorker periodically tracks savings goals until the context is cancelled
+func (s *SavingsGoalService) StartWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.TrackGoals(ctx); err != nil {
+				log.Printf("Failed to track savings goals: %v", err)
+			}
+		}
+	}
+}
+
+// track stores a savings goal's progress. When several milestones are reached at once only the
+// highest is published, so recipients get one notification per evaluation.
+func (s *SavingsGoalService) track(ctx context.Context, goal *models.SavingsGoal, now time.Time) error {
+	progress, err := s.calculateProgress(ctx, goal, now)
+	if err != nil {
+		return err
+	}
+
+	milestones, reached := ReachedMilestones(progress.ProgressPercent, progress.MilestonesReached)
+
+	tracking := models.SavingsGoalTracking{
+		Months:                  progress.Months,
+		SavedKWh:                progress.SavedKWh,
+		SavedPercent:            progress.SavedPercent,
+		ProgressPercent:         progress.ProgressPercent,
+		ProjectedSavingsPercent: progress.ProjectedSavingsPercent,
+		State:                   progress.State,
+		MilestonesReached:       milestones,
+		Final:                   !now.UTC().Before(time.Date(goal.Year+1, time.January, 2, 0, 0, 0, 0, time.UTC)),
+		EvaluatedAt:             &now,
+	}
+	if err := s.goalRepo.UpdateTracking(ctx, goal.ID, tracking); err != nil {
+		return err
+	}
+
+	if reached > 0 {
+		log.Printf("Savings goal %s reached %d%% of its %d target", goal.ID.Hex(), reached, goal.Year)
+		s.eventBus.Publish(ctx, events.SavingsGoalMilestoneReached, &events.SavingsGoalMilestoneReachedData{
+			GoalID:                  goal.ID.Hex(),
+			Name:                    goal.Name,
+			BuildingID:              goal.BuildingID,
+			Year:                    goal.Year,
+			Milestone:               reached,
+			TargetPercent:           goal.TargetPercent,
+			SavedKWh:                progress.SavedKWh,
+			SavedPercent:            progress.SavedPercent,
+			ProjectedSavingsPercent: progress.ProjectedSavingsPercent,
+			NotifyUserIDs:           goal.NotifyUserIDs,
+			ReachedAt:               now,
+		})
+	}
+
+	return nil
+}
+
+// calculateProgress compares consumption with the baseline year month by month, over the whole
+// days of the goal's year that have passed, and projects consumption to year-end
+func (s *SavingsGoalService) calculateProgress(ctx context.Context, goal *models.SavingsGoal, now time.Time) (*models.SavingsGoalProgress, error) {
+	yearStart := time.Date(goal.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	yearEnd := yearStart.AddDate(1, 0, 0)
+	shift := goal.BaselineYear - goal.Year
+
+	now = now.UTC()
+	toDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if toDate.Before(yearStart) {
+		toDate = yearStart
+	}
+	if toDate.After(yearEnd) {
+		toDate = yearEnd
+	}
+
+	months := []models.SavingsGoalMonth{}
+	for monthStart := yearStart; monthStart.Before(toDate); monthStart = monthStart.AddDate(0, 1, 0) {
+		monthEnd := monthStart.AddDate(0, 1, 0)
+		partial := monthEnd.After(toDate)
+		if partial {
+			monthEnd = toDate
+		}
+
+		actual, err := s.sumConsumption(ctx, goal.BuildingID, monthStart, monthEnd)
+		if err != nil {
+			return nil, err
+		}
+		baseline, err := s.sumConsumption(ctx, goal.BuildingID, monthStart.AddDate(shift, 0, 0), monthEnd.AddDate(shift, 0, 0))
+		if err != nil {
+			return nil, err
+		}
+		months = append(months, savingsGoalMonth(monthStart.Format("2006-01"), baseline, actual, partial))
+	}
+
+	baselineYear, err := s.sumConsumption(ctx, goal.BuildingID, yearStart.AddDate(shift, 0, 0), yearEnd.AddDate(shift, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	var baselineToDate, actualToDate float64
+	for _, month := range months {
+		baselineToDate += month.BaselineKWh
+		actualToDate += month.ActualKWh
+	}
+
+	projected, source, forecastID := actualToDate, models.ProjectionSource(""), ""
+	if toDate.Before(yearEnd) {
+		projected, source, forecastID, err = s.projectConsumption(ctx, goal, actualToDate, baselineToDate, toDate, yearEnd, shift)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	progress := SummarizeSavingsGoal(goal, months, baselineYear, projected)
+	progress.ProjectionSource = source
+	progress.ForecastID = forecastID
+	progress.CalculatedAt = now
+	return progress, nil
+}
+
+// projectConsumption estimates the goal year's consumption: consumption so far, the latest
+// forecast's predictions for the period it covers, and for the rest of the year the baseline
+// year's consumption over the same period scaled by how consumption compares with the baseline so far
+func (s *SavingsGoalService) projectConsumption(ctx context.Context, goal *models.SavingsGoal, actualToDate, baselineToDate float64, from, yearEnd time.Time, shift int) (float64, models.ProjectionSource, string, error) {
+	projected := actualToDate
+	source, forecastID := models.ProjectionSourceBaselineTrend, ""
+
+	coveredUntil := from
+	forecast, err := s.forecastClient.GetStoredForecast(ctx, goal.BuildingID)
+	if err != nil {
+		log.Printf("Failed to get forecast for savings goal %s, projecting from the baseline: %v", goal.ID.Hex(), err)
+	}
+	if forecast != nil {
+		predicted, until := sumPredictions(forecast, from, yearEnd)
+		if until.After(from) {
+			projected += predicted
+			coveredUntil = until
+			source = models.ProjectionSourceForecast
+			forecastID, _ = forecast["id"].(string)
+		}
+	}
+
+	if coveredUntil.Before(yearEnd) {
+		remaining, err := s.sumConsumption(ctx, goal.BuildingID, coveredUntil.AddDate(shift, 0, 0), yearEnd.AddDate(shift, 0, 0))
+		if err != nil {
+			return 0, "", "", err
+		}
+		trend := 1.0
+		if baselineToDate > 0 {
+			trend = actualToDate / baselineToDate
+		}
+		projected += remaining * trend
+	}
+
+	return projected, source, forecastID, nil
+}
+
+// sumConsumption totals a building's daily consumption in [from, to)
+func (s *SavingsGoalService) sumConsumption(ctx context.Context, buildingID string, from, to time.Time) (float64, error) {
+	total, err := s.timeSeriesRepo.SumConsumption(ctx, buildingID, "", from, to.Add(-time.Second))
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum consumption: %w", err)
+	}
+	return total, nil
+}
+
+// SummarizeSavingsGoal computes a savings goal's progress from its monthly comparisons, the
+// consumption of the whole baseline year and the consumption projected for the goal's year
+func SummarizeSavingsGoal(goal *models.SavingsGoal, months []models.SavingsGoalMonth, baselineYearKWh, projectedKWh float64) *models.SavingsGoalProgress {
+	var baselineToDate, actualToDate float64
+	for _, month := range months {
+		baselineToDate += month.BaselineKWh
+		actualToDate += month.ActualKWh
+	}
+	saved := baselineToDate - actualToDate
+	targetSavings := baselineYearKWh * goal.TargetPercent / 100
+
+	progress := &models.SavingsGoalProgress{
+		GoalID:                  goal.ID.Hex(),
+		Name:                    goal.Name,
+		BuildingID:              goal.BuildingID,
+		Year:                    goal.Year,
+		BaselineYear:            goal.BaselineYear,
+		TargetPercent:           goal.TargetPercent,
+		BaselineKWh:             roundTo2(baselineYearKWh),
+		TargetKWh:               roundTo2(baselineYearKWh - targetSavings),
+		TargetSavingsKWh:        roundTo2(targetSavings),
+		BaselineToDateKWh:       roundTo2(baselineToDate),
+		ActualToDateKWh:         roundTo2(actualToDate),
+		SavedKWh:                roundTo2(saved),
+		SavedPercent:            budgetPercent(saved, baselineToDate),
+		ProgressPercent:         budgetPercent(saved, targetSavings),
+		ProjectedKWh:            roundTo2(projectedKWh),
+		ProjectedSavingsPercent: budgetPercent(baselineYearKWh-projectedKWh, baselineYearKWh),
+		Months:                  months,
+		MilestonesReached:       append([]int{}, goal.Tracking.MilestonesReached...),
+	}
+
+	switch {
+	case baselineYearKWh <= 0:
+		progress.State = models.SavingsGoalStateNoBaseline
+	case progress.ProgressPercent >= 100:
+		progress.State = models.SavingsGoalStateAchieved
+	case progress.ProjectedSavingsPercent >= goal.TargetPercent:
+		progress.State = models.SavingsGoalStateOnTrack
+	default:
+		progress.State = models.SavingsGoalStateAtRisk
+	}
+
+	return progress
+}
+
+// ReachedMilestones adds the milestones progress has reached to those already reached and returns
+// them with the highest newly reached milestone, or 0 when none was
+func ReachedMilestones(progressPercent float64, reached []int) ([]int, int) {
+	milestones := append([]int{}, reached...)
+	highest := 0
+	for _, milestone := range savingsGoalMilestones {
+		if progressPercent >= float64(milestone) && !containsThreshold(milestones, milestone) {
+			milestones = append(milestones, milestone)
+			highest = milestone
+		}
+	}
+	return milestones, highest
+}
+
+// savingsGoalMonth compares a month's actual consumption with its baseline
+func savingsGoalMonth(month string, baseline, actual float64, partial bool) models.SavingsGoalMonth {
+	return models.SavingsGoalMonth{
+		Month:        month,
+		BaselineKWh:  roundTo2(baseline),
+		ActualKWh:    roundTo2(actual),
+		SavedKWh:     roundTo2(baseline - actual),
+		SavedPercent: budgetPercent(baseline-actual, baseline),
+		Partial:      partial,
+	}
+}
+
+// applySavingsGoalRequest copies request fields onto a savings goal, defaulting the baseline year
+// and name
+func applySavingsGoalRequest(goal *models.SavingsGoal, req *models.SavingsGoalRequest) {
+	goal.BuildingID = req.BuildingID
+	goal.Year = req.Year
+	goal.TargetPercent = req.TargetPercent
+	goal.NotifyUserIDs = req.NotifyUserIDs
+
+	goal.BaselineYear = req.BaselineYear
+	if goal.BaselineYear == 0 {
+		goal.BaselineYear = req.Year - 1
+	}
+
+	goal.Name = req.Name
+	if goal.Name == "" {
+		goal.Name = fmt.Sprintf("Reduce building %s consumption %g%% in %d", req.BuildingID, req.TargetPercent, req.Year)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"analytics-service/internal/models"
@@ -12,6 +13,8 @@ import (
 // TimeSeriesService handles time-series data business logic
 type TimeSeriesService struct {
 	timeSeriesRepo *repository.TimeSeriesRepository
+	anomalyRepo    *repository.AnomalyRepository
+	executionRepo  *repository.OptimizationExecutionRepository
 	iotClient      interface {
 		GetTelemetryHistory(ctx context.Context, deviceID string, from, to time.Time, page, limit int, authToken string) ([]map[string]interface{}, error)
 	}
@@ -20,12 +23,16 @@ type TimeSeriesService struct {
 // NewTimeSeriesService creates a new time-series service
 func NewTimeSeriesService(
 	timeSeriesRepo *repository.TimeSeriesRepository,
+	anomalyRepo *repository.AnomalyRepository,
+	executionRepo *repository.OptimizationExecutionRepository,
 	iotClient interface {
 		GetTelemetryHistory(ctx context.Context, deviceID string, from, to time.Time, page, limit int, authToken string) ([]map[string]interface{}, error)
 	},
 ) *TimeSeriesService {
 	return &TimeSeriesService{
 		timeSeriesRepo: timeSeriesRepo,
+		anomalyRepo:    anomalyRepo,
+		executionRepo:  executionRepo,
 		iotClient:      iotClient,
 	}
 }
@@ -59,6 +66,161 @@ func (s *TimeSeriesService) QueryTimeSeries(ctx context.Context, req *models.Tim
 	return responses, nil
 }
 
+// GetAnnotatedTimeSeries returns a building's consumption series merged with anomalies, executed
+// optimization actions and demand response events, each aligned to the series bucket it falls in
+func (s *TimeSeriesService) GetAnnotatedTimeSeries(ctx context.Context, buildingID string, query *models.AnnotatedTimeSeriesQuery) (*models.AnnotatedTimeSeriesResponse, error) {
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-24 * time.Hour)
+	}
+	if query.AggregationType == "" {
+		query.AggregationType = string(models.AggregationTypeHourly)
+	}
+	if query.Metric == "" {
+		query.Metric = "consumption"
+	}
+	if query.From.After(query.To) {
+		return nil, fmt.Errorf("validation failed: from timestamp must be before to timestamp")
+	}
+
+	aggType := models.AggregationType(query.AggregationType)
+
+	series, err := s.timeSeriesRepo.Query(ctx, &models.TimeSeriesQueryRequest{
+		BuildingID:      buildingID,
+		From:            query.From,
+		To:              query.To,
+		AggregationType: query.AggregationType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time-series: %w", err)
+	}
+
+	anomalies, err := s.anomalyRepo.FindByBuildingInRange(ctx, buildingID, query.From, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query anomalies: %w", err)
+	}
+
+	executions, err := s.executionRepo.FindByBuildingInRange(ctx, buildingID, query.From, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query optimization executions: %w", err)
+	}
+
+	points := make(map[int64]*models.AnnotatedTimeSeriesPoint)
+	pointAt := func(t time.Time) *models.AnnotatedTimeSeriesPoint {
+		if t.Before(query.From) {
+			t = query.From
+		}
+		start := bucketStart(t, aggType)
+		point, ok := points[start.Unix()]
+		if !ok {
+			point = &models.AnnotatedTimeSeriesPoint{Timestamp: start}
+			points[start.Unix()] = point
+		}
+		return point
+	}
+
+	// Device series are summed into one building-wide value per bucket
+	for _, ts := range series {
+		value, ok := ts.Metrics[query.Metric].(float64)
+		if !ok {
+			continue
+		}
+		point := pointAt(ts.Timestamp)
+		if point.Value == nil {
+			point.Value = new(float64)
+		}
+		*point.Value += value
+		point.DeviceCount++
+	}
+
+	counts := make(map[models.AnnotationType]int)
+	annotate := func(annotation models.TimeSeriesAnnotation) {
+		point := pointAt(annotation.Timestamp)
+		point.Annotations = append(point.Annotations, annotation)
+		counts[annotation.Type]++
+	}
+
+	for _, anomaly := range anomalies {
+		annotate(models.TimeSeriesAnnotation{
+			Type:        models.AnnotationTypeAnomaly,
+			Timestamp:   anomaly.DetectedAt,
+			DeviceID:    anomaly.DeviceID,
+			ReferenceID: anomaly.AnomalyID,
+			Label:       fmt.Sprintf("%s anomaly", anomaly.Type),
+			Severity:    string(anomaly.Severity),
+			Status:      string(anomaly.Status),
+		})
+	}
+
+	for _, execution := range executions {
+		startedAt := execution.StartedAt
+		if startedAt.IsZero() {
+			startedAt = execution.CompletedAt
+		}
+
+		if execution.ScenarioType == models.ScenarioTypeDemandResponse {
+			completedAt := execution.CompletedAt
+			annotate(models.TimeSeriesAnnotation{
+				Type:        models.AnnotationTypeDemandResponse,
+				Timestamp:   startedAt,
+				EndTime:     &completedAt,
+				ReferenceID: execution.ScenarioID,
+				Label:       "Demand response event",
+				Status:      execution.Status,
+			})
+		}
+
+		// Executions recorded before action outcomes were published are shown as a whole
+		if len(execution.Actions) == 0 {
+			annotate(models.TimeSeriesAnnotation{
+				Type:        models.AnnotationTypeOptimizationAction,
+				Timestamp:   execution.CompletedAt,
+				ReferenceID: execution.ScenarioID,
+				Label:       fmt.Sprintf("Optimization executed: %d actions applied, %d failed", execution.ActionsApplied, execution.ActionsFailed),
+				Status:      execution.Status,
+			})
+			continue
+		}
+
+		for _, action := range execution.Actions {
+			if action.ExecutedAt.Before(query.From) || action.ExecutedAt.After(query.To) {
+				continue
+			}
+			annotate(models.TimeSeriesAnnotation{
+				Type:        models.AnnotationTypeOptimizationAction,
+				Timestamp:   action.ExecutedAt,
+				DeviceID:    action.DeviceID,
+				ReferenceID: execution.ScenarioID,
+				Label:       fmt.Sprintf("%s command", action.Command),
+				Status:      action.Status,
+			})
+		}
+	}
+
+	response := &models.AnnotatedTimeSeriesResponse{
+		BuildingID:       buildingID,
+		Metric:           query.Metric,
+		AggregationType:  query.AggregationType,
+		From:             query.From,
+		To:               query.To,
+		Points:           make([]*models.AnnotatedTimeSeriesPoint, 0, len(points)),
+		AnnotationCounts: counts,
+	}
+	for _, point := range points {
+		sort.SliceStable(point.Annotations, func(i, j int) bool {
+			return point.Annotations[i].Timestamp.Before(point.Annotations[j].Timestamp)
+		})
+		response.Points = append(response.Points, point)
+	}
+	sort.Slice(response.Points, func(i, j int) bool {
+		return response.Points[i].Timestamp.Before(response.Points[j].Timestamp)
+	})
+
+	return response, nil
+}
+
 // queryFromIoTService queries telemetry from IoT service and aggregates it
 func (s *TimeSeriesService) queryFromIoTService(ctx context.Context, req *models.TimeSeriesQueryRequest, aggType models.AggregationType, authToken string) ([]*models.TimeSeriesResponse, error) {
 	allData := make([]map[string]interface{}, 0)
@@ -99,20 +261,7 @@ func (s *TimeSeriesService) aggregateData(data []map[string]interface{}, aggType
 			continue
 		}
 
-		var key string
-		switch aggType {
-		case models.AggregationTypeHourly:
-			key = t.Truncate(time.Hour).Format(time.RFC3339)
-		case models.AggregationTypeDaily:
-			key = t.Truncate(24 * time.Hour).Format(time.RFC3339)
-		case models.AggregationTypeWeekly:
-			key = t.Truncate(7 * 24 * time.Hour).Format(time.RFC3339)
-		case models.AggregationTypeMonthly:
-			key = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).Format(time.RFC3339)
-		default:
-			key = t.Truncate(time.Hour).Format(time.RFC3339)
-		}
-
+		key := bucketStart(t, aggType).Format(time.RFC3339)
 		groups[key] = append(groups[key], item)
 	}
 
@@ -143,6 +292,20 @@ func (s *TimeSeriesService) aggregateData(data []map[string]interface{}, aggType
 	return results
 }
 
+// bucketStart returns the start of the aggregation period containing t
+func bucketStart(t time.Time, aggType models.AggregationType) time.Time {
+	switch aggType {
+	case models.AggregationTypeDaily:
+		return t.Truncate(24 * time.Hour)
+	case models.AggregationTypeWeekly:
+		return t.Truncate(7 * 24 * time.Hour)
+	case models.AggregationTypeMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return t.Truncate(time.Hour)
+	}
+}
+
 // aggregateMetrics aggregates metrics from multiple data points
 func (s *TimeSeriesService) aggregateMetrics(data []map[string]interface{}) map[string]interface{} {
 	metrics := make(map[string]interface{})
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"analytics-service/internal/models"
+)
+
+const (
+	defaultTopConsumersPeriod = "WEEKLY"
+	defaultTopConsumersLimit  = 10
+	// baseLoadPercentile is the share of the quietest unmetered hours whose level is taken as the always-on load
+	baseLoadPercentile = 0.1
+	// Occupied hours are weekdays between these hours (UTC)
+	occupiedHoursStart = 7
+	occupiedHoursEnd   = 19
+)
+
+// reportingPeriodDays is the length in days of each reporting period
+var reportingPeriodDays = map[string]int{
+	"DAILY":   1,
+	"WEEKLY":  7,
+	"MONTHLY": 30,
+}
+
+// GetTopConsumers ranks a building's devices by consumption over the last full period and compares
+// each with the period before. Consumption on the building's main meter that no device accounts for
+// is reported as unmetered load and split into base, occupied-hours and after-hours load.
+func (s *DashboardService) GetTopConsumers(ctx context.Context, query *models.TopConsumersQuery, authToken string) (*models.TopConsumers, error) {
+	period := query.Period
+	if period == "" {
+		period = defaultTopConsumersPeriod
+	}
+	days, ok := reportingPeriodDays[period]
+	if !ok {
+		return nil, fmt.Errorf("invalid period: %s", period)
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultTopConsumersLimit
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -days)
+	previousFrom := from.AddDate(0, 0, -days)
+
+	current, err := s.timeSeriesRepo.SumConsumptionByDevice(ctx, query.BuildingID, from, to.Add(-time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate consumption: %w", err)
+	}
+	previous, err := s.timeSeriesRepo.SumConsumptionByDevice(ctx, query.BuildingID, previousFrom, from.Add(-time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate previous consumption: %w", err)
+	}
+
+	mainMeter, devices := splitMainMeter(current)
+	previousMainMeter, previousDevices := splitMainMeter(previous)
+
+	metered := sumConsumption(devices)
+	total := math.Max(mainMeter, metered)
+	previousTotal := math.Max(previousMainMeter, sumConsumption(previousDevices))
+
+	// Device details are optional; the ranking is still useful without them
+	details := make(map[string]map[string]interface{})
+	if buildingDevices, err := s.iotClient.GetDevices(ctx, query.BuildingID, authToken); err == nil {
+		for _, device := range buildingDevices {
+			if deviceID, ok := device["deviceId"].(string); ok {
+				details[deviceID] = device
+			}
+		}
+	}
+
+	consumers := make([]models.DeviceConsumer, 0, len(devices))
+	for deviceID, consumption := range devices {
+		if consumption <= 0 {
+			continue
+		}
+		consumer := models.DeviceConsumer{
+			DeviceID:            deviceID,
+			Consumption:         roundTo2(consumption),
+			SharePercent:        sharePercent(consumption, total),
+			PreviousConsumption: roundTo2(previousDevices[deviceID]),
+			ChangePercent:       changePercent(consumption, previousDevices[deviceID]),
+		}
+		if device, ok := details[deviceID]; ok {
+			consumer.Type, _ = device["type"].(string)
+			consumer.Model, _ = device["model"].(string)
+		}
+		consumers = append(consumers, consumer)
+	}
+
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].Consumption != consumers[j].Consumption {
+			return consumers[i].Consumption > consumers[j].Consumption
+		}
+		return consumers[i].DeviceID < consumers[j].DeviceID
+	})
+	if len(consumers) > limit {
+		consumers = consumers[:limit]
+	}
+	for i := range consumers {
+		consumers[i].Rank = i + 1
+	}
+
+	return &models.TopConsumers{
+		BuildingID:               query.BuildingID,
+		Period:                   period,
+		From:                     from,
+		To:                       to,
+		TotalConsumption:         roundTo2(total),
+		PreviousTotalConsumption: roundTo2(previousTotal),
+		ChangePercent:            changePercent(total, previousTotal),
+		MeteredConsumption:       roundTo2(metered),
+		Devices:                  consumers,
+		Unmetered:                s.disaggregateUnmetered(ctx, query.BuildingID, from, to, total-metered, total),
+		UpdatedAt:                time.Now(),
+	}, nil
+}
+
+// disaggregateUnmetered splits unmetered consumption using the building's hourly profile.
+// Each hour's unmetered load is the main meter reading minus the submetered devices; the level of
+// the quietest hours is taken as always-on base load and the rest is variable load, attributed to
+// occupied or after hours. The components are scaled to the unmetered total from daily aggregates.
+func (s *DashboardService) disaggregateUnmetered(ctx context.Context, buildingID string, from, to time.Time, unmetered, total float64) models.UnmeteredLoad {
+	unmetered = math.Max(unmetered, 0)
+	load := models.UnmeteredLoad{
+		Consumption:  roundTo2(unmetered),
+		SharePercent: sharePercent(unmetered, total),
+	}
+	if unmetered == 0 {
+		return load
+	}
+
+	hours, err := s.timeSeriesRepo.SumHourlyConsumption(ctx, buildingID, from, to.Add(-time.Millisecond))
+	if err != nil {
+		return load
+	}
+
+	var residuals []float64
+	var timestamps []time.Time
+	for _, hour := range hours {
+		// Hours without a main meter reading say nothing about unmetered load
+		if hour.MainMeter <= 0 {
+			continue
+		}
+		residuals = append(residuals, math.Max(hour.MainMeter-hour.Metered, 0))
+		timestamps = append(timestamps, hour.Timestamp)
+	}
+	if len(residuals) == 0 {
+		return load
+	}
+
+	sorted := append([]float64(nil), residuals...)
+	sort.Float64s(sorted)
+	baseLevel := sorted[int(baseLoadPercentile*float64(len(sorted)-1))]
+
+	var base, occupied, afterHours float64
+	for i, residual := range residuals {
+		hourBase := math.Min(baseLevel, residual)
+		base += hourBase
+		if isOccupiedHour(timestamps[i]) {
+			occupied += residual - hourBase
+		} else {
+			afterHours += residual - hourBase
+		}
+	}
+
+	profileTotal := base + occupied + afterHours
+	if profileTotal == 0 {
+		return load
+	}
+	scale := unmetered / profileTotal
+	load.BaseLoad = roundTo2(base * scale)
+	load.OccupiedHoursLoad = roundTo2(occupied * scale)
+	load.AfterHoursLoad = roundTo2(afterHours * scale)
+	load.Disaggregated = true
+	return load
+}
+
+// splitMainMeter separates the main meter total from per-device totals
+func splitMainMeter(totals []*models.DeviceConsumption) (float64, map[string]float64) {
+	mainMeter := 0.0
+	devices := make(map[string]float64, len(totals))
+	for _, total := range totals {
+		if total.DeviceID == "" {
+			mainMeter += total.Consumption
+		} else {
+			devices[total.DeviceID] += total.Consumption
+		}
+	}
+	return mainMeter, devices
+}
+
+// sumConsumption totals per-device consumption
+func sumConsumption(devices map[string]float64) float64 {
+	total := 0.0
+	for _, consumption := range devices {
+		total += consumption
+	}
+	return total
+}
+
+// sharePercent returns part as a percentage of total
+func sharePercent(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return roundTo2(part / total * 100)
+}
+
+// changePercent returns the change from previous to current, or nil without previous consumption
+func changePercent(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := roundTo2((current - previous) / previous * 100)
+	return &change
+}
+
+// isOccupiedHour reports whether an hour falls within weekday occupied hours
+func isOccupiedHour(t time.Time) bool {
+	t = t.UTC()
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	return t.Hour() >= occupiedHoursStart && t.Hour() < occupiedHoursEnd
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
+)
+
+// WeatherNormalizer corrects building consumption for weather using heating and cooling
+// degree days from the Forecast service
+type WeatherNormalizer struct {
+	timeSeriesRepo *repository.TimeSeriesRepository
+	forecastClient interface {
+		GetDegreeDays(ctx context.Context, buildingID string, from, to time.Time, authToken string) (*models.DegreeDays, error)
+	}
+}
+
+// NewWeatherNormalizer creates a new weather normalizer
+func NewWeatherNormalizer(
+	timeSeriesRepo *repository.TimeSeriesRepository,
+	forecastClient interface {
+		GetDegreeDays(ctx context.Context, buildingID string, from, to time.Time, authToken string) (*models.DegreeDays, error)
+	},
+) *WeatherNormalizer {
+	return &WeatherNormalizer{
+		timeSeriesRepo: timeSeriesRepo,
+		forecastClient: forecastClient,
+	}
+}
+
+// Normalize compares a building's consumption in [from, to) with the reference period
+// [refFrom, refTo). The period's consumption is scaled by the ratio of reference to period
+// degree days; when either period has no degree days the raw consumption is kept.
+func (n *WeatherNormalizer) Normalize(ctx context.Context, buildingID string, from, to, refFrom, refTo time.Time, authToken string) (*models.WeatherNormalizedConsumption, error) {
+	// Daily aggregates are matched inclusively, so stop just before the period end
+	consumption, err := n.timeSeriesRepo.SumConsumption(ctx, buildingID, "", from, to.Add(-time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum consumption: %w", err)
+	}
+	refConsumption, err := n.timeSeriesRepo.SumConsumption(ctx, buildingID, "", refFrom, refTo.Add(-time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum reference consumption: %w", err)
+	}
+
+	degreeDays, err := n.forecastClient.GetDegreeDays(ctx, buildingID, from, to, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get degree days: %w", err)
+	}
+	refDegreeDays, err := n.forecastClient.GetDegreeDays(ctx, buildingID, refFrom, refTo, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reference degree days: %w", err)
+	}
+
+	result := &models.WeatherNormalizedConsumption{
+		From:                     from,
+		To:                       to,
+		ReferenceFrom:            refFrom,
+		ReferenceTo:              refTo,
+		BaseTemperature:          degreeDays.BaseTemperature,
+		ConsumptionKWh:           roundTo2(consumption),
+		HeatingDegreeDays:        degreeDays.HeatingDegreeDays,
+		CoolingDegreeDays:        degreeDays.CoolingDegreeDays,
+		ReferenceConsumptionKWh:  roundTo2(refConsumption),
+		ReferenceHeatingDD:       refDegreeDays.HeatingDegreeDays,
+		ReferenceCoolingDD:       refDegreeDays.CoolingDegreeDays,
+		NormalizedConsumptionKWh: roundTo2(consumption),
+	}
+
+	if total := degreeDays.Total(); total > 0 {
+		result.ConsumptionPerDegreeDay = roundTo2(consumption / total)
+		if refTotal := refDegreeDays.Total(); refTotal > 0 {
+			result.NormalizedConsumptionKWh = roundTo2(consumption * refTotal / total)
+		}
+	}
+
+	if refConsumption > 0 {
+		result.ChangePercent = roundTo2((consumption - refConsumption) / refConsumption * 100)
+		result.NormalizedChangePercent = roundTo2((result.NormalizedConsumptionKWh - refConsumption) / refConsumption * 100)
+	}
+
+	return result, nil
+}
+
+// kpiComparisonWindows returns the UTC day-aligned period a KPI covers and the period
+// of equal length it is compared with
+func kpiComparisonWindows(period string, now time.Time) (from, to, refFrom, refTo time.Time) {
+	today := now.UTC().Truncate(24 * time.Hour)
+
+	switch period {
+	case "WEEKLY":
+		to = today
+		from = to.AddDate(0, 0, -7)
+		refTo = from
+		refFrom = refTo.AddDate(0, 0, -7)
+	case "MONTHLY":
+		to = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+		from = to.AddDate(0, -1, 0)
+		refTo = from
+		refFrom = refTo.AddDate(0, -1, 0)
+	default:
+		to = today
+		from = to.AddDate(0, 0, -1)
+		refTo = from
+		refFrom = refTo.AddDate(0, 0, -1)
+	}
+
+	return from, to, refFrom, refTo
+}
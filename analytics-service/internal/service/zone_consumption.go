@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"analytics-service/internal/models"
+)
+
+// defaultZoneLevel is the level of the location hierarchy consumption is rolled up by by default
+const defaultZoneLevel = "ZONE"
+
+// zoneLevelFields maps the levels consumption can be rolled up by to the device hierarchy field
+// holding the device's location of that level
+var zoneLevelFields = map[string]string{
+	"FLOOR": "floorId",
+	"ZONE":  "zoneId",
+	"ROOM":  "roomId",
+}
+
+// GetZoneConsumption rolls a building's submetered consumption over the last full period up by the
+// floors, zones or rooms its devices are assigned to, and compares each with the period before.
+// Every location of the level is listed, including those without consumption; the main meter is
+// left out, as it cannot be placed in a zone.
+func (s *DashboardService) GetZoneConsumption(ctx context.Context, query *models.ZoneConsumptionQuery, authToken string) (*models.ZoneConsumption, error) {
+	period := query.Period
+	if period == "" {
+		period = defaultTopConsumersPeriod
+	}
+	days, ok := reportingPeriodDays[period]
+	if !ok {
+		return nil, fmt.Errorf("invalid period: %s", period)
+	}
+	level := query.Level
+	if level == "" {
+		level = defaultZoneLevel
+	}
+	field, ok := zoneLevelFields[level]
+	if !ok {
+		return nil, fmt.Errorf("invalid level: %s", level)
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -days)
+	previousFrom := from.AddDate(0, 0, -days)
+
+	current, err := s.timeSeriesRepo.SumConsumptionByDevice(ctx, query.BuildingID, from, to.Add(-time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate consumption: %w", err)
+	}
+	previous, err := s.timeSeriesRepo.SumConsumptionByDevice(ctx, query.BuildingID, previousFrom, from.Add(-time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate previous consumption: %w", err)
+	}
+	_, devices := splitMainMeter(current)
+	_, previousDevices := splitMainMeter(previous)
+
+	buildingDevices, err := s.iotClient.GetDevices(ctx, query.BuildingID, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch devices: %w", err)
+	}
+
+	zones := make(map[string]*models.ZoneConsumer)
+	zoneOf := func(locationID string) *models.ZoneConsumer {
+		zone, ok := zones[locationID]
+		if !ok {
+			zone = &models.ZoneConsumer{LocationID: locationID}
+			zones[locationID] = zone
+		}
+		return zone
+	}
+
+	// Location names and areas are optional; the rollup is still useful without them
+	if locations, err := s.iotClient.GetLocations(ctx, query.BuildingID, level, authToken); err == nil {
+		for _, location := range locations {
+			locationID, _ := location["locationId"].(string)
+			if locationID == "" {
+				continue
+			}
+			zone := zoneOf(locationID)
+			zone.Name, _ = location["name"].(string)
+			zone.Area, _ = location["area"].(float64)
+		}
+	}
+
+	unassigned := &models.ZoneConsumer{}
+	placed := make(map[string]bool, len(buildingDevices))
+	for _, device := range buildingDevices {
+		deviceID, _ := device["deviceId"].(string)
+		if deviceID == "" {
+			continue
+		}
+		placed[deviceID] = true
+
+		zone := unassigned
+		if locationID := deviceLocationAt(device, field); locationID != "" {
+			zone = zoneOf(locationID)
+		}
+		zone.Devices++
+		if status, _ := device["status"].(string); status == "ONLINE" {
+			zone.OnlineDevices++
+		}
+		zone.Consumption += devices[deviceID]
+		zone.PreviousConsumption += previousDevices[deviceID]
+	}
+
+	// Consumption of devices the IoT service no longer lists cannot be placed in a zone
+	for deviceID, consumption := range devices {
+		if !placed[deviceID] {
+			unassigned.Consumption += consumption
+		}
+	}
+	for deviceID, consumption := range previousDevices {
+		if !placed[deviceID] {
+			unassigned.PreviousConsumption += consumption
+		}
+	}
+
+	total := sumConsumption(devices)
+	rows := make([]models.ZoneConsumer, 0, len(zones))
+	for _, zone := range zones {
+		rows = append(rows, finishZoneConsumer(*zone, total))
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Consumption != rows[j].Consumption {
+			return rows[i].Consumption > rows[j].Consumption
+		}
+		return rows[i].LocationID < rows[j].LocationID
+	})
+
+	return &models.ZoneConsumption{
+		BuildingID:       query.BuildingID,
+		Level:            level,
+		Period:           period,
+		From:             from,
+		To:               to,
+		TotalConsumption: roundTo2(total),
+		Zones:            rows,
+		Unassigned:       finishZoneConsumer(*unassigned, total),
+		UpdatedAt:        time.Now(),
+	}, nil
+}
+
+// deviceLocationAt returns the ID of a device's location at the given hierarchy field, or an
+// empty string when the device is not assigned to a location of that level
+func deviceLocationAt(device map[string]interface{}, field string) string {
+	location, _ := device["location"].(map[string]interface{})
+	hierarchy, _ := location["hierarchy"].(map[string]interface{})
+	locationID, _ := hierarchy[field].(string)
+	return locationID
+}
+
+// finishZoneConsumer rounds a zone's totals and derives its share, change and intensity
+func finishZoneConsumer(zone models.ZoneConsumer, total float64) models.ZoneConsumer {
+	zone.SharePercent = sharePercent(zone.Consumption, total)
+	zone.ChangePercent = changePercent(zone.Consumption, zone.PreviousConsumption)
+	if zone.Area > 0 {
+		intensity := roundTo2(zone.Consumption / zone.Area)
+		zone.Intensity = &intensity
+	}
+	zone.Consumption = roundTo2(zone.Consumption)
+	zone.PreviousConsumption = roundTo2(zone.PreviousConsumption)
+	return zone
+}
@@ -0,0 +1,143 @@
+// Package tracing instruments the service with OpenTelemetry. Spans are exported to an OTLP
+// collector, and the trace context travels between services in W3C traceparent headers and in
+// the envelope of domain events, so one request can be followed through every service it reaches.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"analytics-service/internal/config"
+)
+
+// instrumentationName names the tracer every span of the service is started with
+const instrumentationName = "energy-management/tracing"
+
+// Span attributes identifying what a span concerns, so traces can be searched by them
+const (
+	BuildingIDKey = attribute.Key("building.id")
+	DeviceIDKey   = attribute.Key("device.id")
+	ScenarioIDKey = attribute.Key("scenario.id")
+)
+
+// traceParentHeader is the W3C header the trace context is propagated in
+const traceParentHeader = "traceparent"
+
+// Init installs the W3C trace context propagator and, when tracing is enabled, a tracer provider
+// exporting spans over OTLP/HTTP. The returned function flushes buffered spans on shutdown.
+// With tracing disabled no spans are recorded, but incoming trace context is still passed on to
+// the services called, so a disabled service does not break the traces of the others.
+func Init(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{}
+	if strings.Contains(cfg.Endpoint, "://") {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	} else {
+		opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	// Traces started by another service keep its sampling decision, so they are never cut short
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	log.Printf("Tracing enabled, exporting spans of %s to %s", cfg.ServiceName, cfg.Endpoint)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span, as a child of the span in ctx if there is one
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// StartChild starts a span only when ctx already carries a trace, and otherwise returns ctx with
+// a span that records nothing. Used for work that also runs in background loops, which would
+// flood the collector with single-span traces.
+func StartChild(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return Start(ctx, name, opts...)
+}
+
+// SetEntity records the building and device the current span concerns. Empty IDs are skipped.
+func SetEntity(ctx context.Context, buildingID, deviceID string) {
+	trace.SpanFromContext(ctx).SetAttributes(EntityAttributes(buildingID, deviceID)...)
+}
+
+// SetScenario records the optimization scenario, and its building, the current span concerns
+func SetScenario(ctx context.Context, scenarioID, buildingID string) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(ScenarioIDKey.String(scenarioID))
+	span.SetAttributes(EntityAttributes(buildingID, "")...)
+}
+
+// EntityAttributes returns the attributes of a building and device, skipping empty IDs
+func EntityAttributes(buildingID, deviceID string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if buildingID != "" {
+		attrs = append(attrs, BuildingIDKey.String(buildingID))
+	}
+	if deviceID != "" {
+		attrs = append(attrs, DeviceIDKey.String(deviceID))
+	}
+	return attrs
+}
+
+// RecordError marks a span as failed. A nil error leaves it untouched.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// TraceParent returns the traceparent of the span in ctx, or "" when there is none
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier.Get(traceParentHeader)
+}
+
+// WithTraceParent returns ctx continuing the trace of a traceparent. An empty or malformed
+// traceparent leaves ctx unchanged.
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{traceParentHeader: traceParent})
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"analytics-service/internal/middleware"
+	"analytics-service/internal/models"
+	"analytics-service/internal/tenant"
+)
+
+// TestSeesAllBuildings tests which callers may view buildings without holding devices in them
+func TestSeesAllBuildings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	scope := &models.TenantScope{OrgID: "org-1", BuildingIDs: []string{"building-1"}}
+
+	tests := []struct {
+		name  string
+		roles []string
+		scope *models.TenantScope
+		want  bool
+	}{
+		{"super admin", []string{"admin", "super_admin"}, nil, true},
+		{"organization admin", []string{"admin"}, scope, true},
+		{"admin outside any organization", []string{"admin"}, nil, false},
+		{"operator in an organization", []string{"operator"}, scope, false},
+		{"viewer", []string{"viewer"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/analytics/dashboards/portfolio", nil)
+			if tt.scope != nil {
+				c.Request = c.Request.WithContext(tenant.WithScope(c.Request.Context(), tt.scope))
+			}
+			c.Set("roles", tt.roles)
+
+			assert.Equal(t, tt.want, middleware.SeesAllBuildings(c))
+			assert.Equal(t, tt.want && tt.scope == nil, middleware.IsSuperAdmin(c))
+		})
+	}
+}
@@ -2,50 +2,31 @@ package tests
 
 import (
 	"context"
-	"errors"
 	"testing"
 	"time"
 
 	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
 	"analytics-service/internal/service"
-)
-
-// MockAnomalyRepository is a mock implementation for testing
-type MockAnomalyRepository struct {
-	anomalies map[string]*models.Anomaly
-}
 
-func (m *MockAnomalyRepository) Create(ctx context.Context, anomaly *models.Anomaly) (*models.Anomaly, error) {
-	if m.anomalies == nil {
-		m.anomalies = make(map[string]*models.Anomaly)
-	}
-	m.anomalies[anomaly.AnomalyID] = anomaly
-	return anomaly, nil
-}
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
 
-func (m *MockAnomalyRepository) FindByAnomalyID(ctx context.Context, anomalyID string) (*models.Anomaly, error) {
-	if anomaly, exists := m.anomalies[anomalyID]; exists {
-		return anomaly, nil
-	}
-	return nil, errors.New("anomaly not found")
-}
+// setupTestDB creates a test database connection
+func setupTestDB(t *testing.T) (*mongo.Database, func()) {
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
+	require.NoError(t, err)
 
-func (m *MockAnomalyRepository) FindAll(ctx context.Context, deviceID, buildingID, anomalyType, severity, status string, page, limit int) ([]*models.Anomaly, int64, error) {
-	results := make([]*models.Anomaly, 0)
-	for _, anomaly := range m.anomalies {
-		results = append(results, anomaly)
+	db := client.Database("test_analytics_service")
+	cleanup := func() {
+		db.Drop(ctx)
+		client.Disconnect(ctx)
 	}
-	return results, int64(len(results)), nil
-}
 
-func (m *MockAnomalyRepository) CountByStatus(ctx context.Context, status string) (int64, error) {
-	count := int64(0)
-	for _, anomaly := range m.anomalies {
-		if string(anomaly.Status) == status {
-			count++
-		}
-	}
-	return count, nil
+	return db, cleanup
 }
 
 // MockIoTClientForAnomaly is a mock implementation for testing
@@ -70,12 +51,16 @@ func (m *MockIoTClientForAnomaly) GetDevices(ctx context.Context, buildingID str
 
 // TestAnomalyDetection tests anomaly detection
 func TestAnomalyDetection(t *testing.T) {
-	// Setup mocks
-	mockAnomalyRepo := &MockAnomalyRepository{}
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Setup repositories and mocks
+	anomalyRepo := repository.NewAnomalyRepository(db.Collection("anomalies"), db.Collection("anomalies"))
+	timeSeriesRepo := repository.NewTimeSeriesRepository(db.Collection("time_series"), db.Collection("time_series"), false)
 	mockIoTClient := &MockIoTClientForAnomaly{}
 
-	// Create service
-	anomalyService := service.NewAnomalyService(mockAnomalyRepo, mockIoTClient)
+	// Create service with the built-in detector configurations
+	anomalyService := service.NewAnomalyService(anomalyRepo, timeSeriesRepo, mockIoTClient, nil, nil, nil, nil, nil)
 
 	// Test anomaly detection
 	ctx := context.Background()
@@ -97,4 +82,10 @@ func TestAnomalyDetection(t *testing.T) {
 	if anomaly.Severity != string(models.AnomalySeverityHigh) {
 		t.Errorf("Expected severity HIGH, got %s", anomaly.Severity)
 	}
+	// Detected anomalies are stored
+	stored, err := anomalyRepo.FindByAnomalyID(ctx, anomaly.AnomalyID)
+	require.NoError(t, err)
+	if stored.DeviceID != "device-001" {
+		t.Errorf("Expected stored device ID device-001, got %s", stored.DeviceID)
+	}
 }
@@ -0,0 +1,162 @@
+// Package benchmark measures analytics queries against a real MongoDB. The benchmarks are skipped
+// unless ANALYTICS_BENCH_MONGODB_URI points at a server they may create a scratch database on.
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"analytics-service/internal/config"
+	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
+)
+
+const (
+	benchBuildings = 20
+	benchDevices   = 10
+	benchDays      = 60
+)
+
+// benchStart is the first hour of the seeded time series
+var benchStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// BenchmarkTimeRangeAggregations compares the time-range aggregations of the time-series
+// repository before and after the reporting changes: "baseline" reads from the primary with only
+// the original time_series indexes, "tuned" reads through the reporting collection with the
+// time-range indexes created and hinted. Run with -benchtime to taste, e.g.
+//
+//	ANALYTICS_BENCH_MONGODB_URI=mongodb://localhost:27017 go test ./tests/benchmark -bench . -benchtime 200x
+func BenchmarkTimeRangeAggregations(b *testing.B) {
+	uri := os.Getenv("ANALYTICS_BENCH_MONGODB_URI")
+	if uri == "" {
+		b.Skip("ANALYTICS_BENCH_MONGODB_URI not set")
+	}
+
+	cfg := &config.Config{
+		MongoDB: config.MongoDBConfig{
+			URI:                     uri,
+			Database:                fmt.Sprintf("analytics_bench_%d", time.Now().UnixNano()),
+			Timeout:                 30 * time.Second,
+			MaxPoolSize:             20,
+			ServerSelectionTimeout:  10 * time.Second,
+			RetryWrites:             true,
+			ReportingReadPreference: "secondaryPreferred",
+			ReportingMaxPoolSize:    20,
+			QueryHints:              true,
+		},
+	}
+
+	ctx := context.Background()
+	db, err := repository.NewMongoDB(cfg)
+	if err != nil {
+		b.Fatalf("connect: %v", err)
+	}
+	defer func() {
+		db.Database.Drop(ctx)
+		db.Close(ctx)
+	}()
+
+	collections := db.GetCollections()
+	if err := seedTimeSeries(ctx, collections.TimeSeries); err != nil {
+		b.Fatalf("seed: %v", err)
+	}
+
+	// Indexes of time_series before the time-range indexes were added
+	baselineIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "device_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "building_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "aggregation_type", Value: 1}, {Key: "timestamp", Value: -1}}},
+	}
+	if _, err := collections.TimeSeries.Indexes().CreateMany(ctx, baselineIndexes); err != nil {
+		b.Fatalf("baseline indexes: %v", err)
+	}
+
+	b.Run("baseline", func(b *testing.B) {
+		repo := repository.NewTimeSeriesRepository(collections.TimeSeries, nil, false)
+		runAggregations(b, repo)
+	})
+
+	if err := db.CreateIndexes(ctx); err != nil {
+		b.Fatalf("create indexes: %v", err)
+	}
+
+	b.Run("tuned", func(b *testing.B) {
+		repo := repository.NewTimeSeriesRepository(collections.TimeSeries, db.GetReportingCollections().TimeSeries, db.QueryHints())
+		runAggregations(b, repo)
+	})
+}
+
+// runAggregations runs the hourly and daily time-range aggregations used by dashboards and cost
+// reports for one building and week per iteration
+func runAggregations(b *testing.B, repo *repository.TimeSeriesRepository) {
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buildingID := fmt.Sprintf("building-%d", i%benchBuildings)
+		from := benchStart.AddDate(0, 0, i%(benchDays-7))
+		to := from.AddDate(0, 0, 7)
+
+		if _, err := repo.SumHourlyConsumption(ctx, buildingID, from, to); err != nil {
+			b.Fatalf("hourly consumption: %v", err)
+		}
+		if _, err := repo.SumHourlyDeviceConsumption(ctx, buildingID, []string{buildingID + "-device-1"}, from, to); err != nil {
+			b.Fatalf("hourly device consumption: %v", err)
+		}
+		if _, err := repo.SumConsumptionByDevice(ctx, buildingID, from, to); err != nil {
+			b.Fatalf("consumption by device: %v", err)
+		}
+	}
+}
+
+// seedTimeSeries inserts hourly and daily aggregates for every building and device
+func seedTimeSeries(ctx context.Context, collection *mongo.Collection) error {
+	var batch []interface{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		_, err := collection.InsertMany(ctx, batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for building := 0; building < benchBuildings; building++ {
+		buildingID := fmt.Sprintf("building-%d", building)
+		for device := 0; device < benchDevices; device++ {
+			deviceID := fmt.Sprintf("%s-device-%d", buildingID, device)
+			for hour := 0; hour < benchDays*24; hour++ {
+				timestamp := benchStart.Add(time.Duration(hour) * time.Hour)
+				batch = append(batch, models.TimeSeries{
+					DeviceID:        deviceID,
+					BuildingID:      buildingID,
+					Timestamp:       timestamp,
+					AggregationType: models.AggregationTypeHourly,
+					Metrics:         map[string]interface{}{"consumption": float64(device+hour%24) / 10},
+					CreatedAt:       timestamp,
+				})
+				if hour%24 == 0 {
+					batch = append(batch, models.TimeSeries{
+						DeviceID:        deviceID,
+						BuildingID:      buildingID,
+						Timestamp:       timestamp,
+						AggregationType: models.AggregationTypeDaily,
+						Metrics:         map[string]interface{}{"consumption": float64(device) * 2.4},
+						CreatedAt:       timestamp,
+					})
+				}
+				if len(batch) >= 5000 {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return flush()
+}
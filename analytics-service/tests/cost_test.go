@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/service"
+)
+
+// touTariff has a weekday peak window, an overnight off-peak window that wraps past midnight
+// and a weekend window, with a base rate outside them
+func touTariff() *models.Tariff {
+	return &models.Tariff{
+		Region:   "test",
+		BaseRate: 0.20,
+		Currency: "EUR",
+		TimeOfUseRates: []models.TariffRate{
+			{Name: "peak", RatePerKWh: 0.35, StartHour: 17, EndHour: 21, DaysOfWeek: []int{1, 2, 3, 4, 5}},
+			{Name: "night", RatePerKWh: 0.10, StartHour: 22, EndHour: 6},
+			{Name: "weekend", RatePerKWh: 0.15, StartHour: 10, EndHour: 16, DaysOfWeek: []int{0, 6}},
+		},
+	}
+}
+
+// rateAtHour prices one kWh used in the hour starting at t and returns the rate applied
+func rateAtHour(t *testing.T, tariff *models.Tariff, at time.Time) models.RateCost {
+	t.Helper()
+	breakdown := service.PriceLoad(tariff, []models.LoadInterval{{Start: at, Minutes: 60, EnergyKWh: 1}}, 1)
+	require.Len(t, breakdown.Rates, 1)
+	return breakdown.Rates[0]
+}
+
+func TestPriceLoad_TimeOfUseWindows(t *testing.T) {
+	// 1 July 2024 is a Monday
+	monday := func(hour int) time.Time { return time.Date(2024, 7, 1, hour, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		wantName string
+		wantRate float64
+	}{
+		{"before the peak window", monday(16), "BASE", 0.20},
+		{"start hour is inside", monday(17), "peak", 0.35},
+		{"last hour is inside", monday(20), "peak", 0.35},
+		{"end hour is outside", monday(21), "BASE", 0.20},
+		{"within the hour is priced by its start", monday(20).Add(59 * time.Minute), "peak", 0.35},
+		{"overnight window before midnight", monday(22), "night", 0.10},
+		{"overnight window at 23", monday(23), "night", 0.10},
+		{"overnight window at midnight", monday(0), "night", 0.10},
+		{"overnight window last hour", monday(5), "night", 0.10},
+		{"overnight window end hour", monday(6), "BASE", 0.20},
+		{"midday outside every window", monday(12), "BASE", 0.20},
+		{"peak window skips weekends", time.Date(2024, 7, 6, 18, 0, 0, 0, time.UTC), "BASE", 0.20},
+		{"weekend window on saturday", time.Date(2024, 7, 6, 10, 0, 0, 0, time.UTC), "weekend", 0.15},
+		{"weekend window on sunday", time.Date(2024, 7, 7, 15, 0, 0, 0, time.UTC), "weekend", 0.15},
+		{"weekend window not on weekdays", monday(10), "BASE", 0.20},
+		{"overnight window on weekends", time.Date(2024, 7, 7, 2, 0, 0, 0, time.UTC), "night", 0.10},
+		{"priced in UTC", time.Date(2024, 7, 1, 19, 0, 0, 0, time.FixedZone("UTC+3", 3*3600)), "BASE", 0.20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate := rateAtHour(t, touTariff(), tt.at)
+			assert.Equal(t, tt.wantName, rate.Name)
+			assert.Equal(t, tt.wantRate, rate.RatePerKWh)
+		})
+	}
+}
+
+func TestPriceLoad_OverlappingWindows(t *testing.T) {
+	tariff := &models.Tariff{
+		BaseRate: 0.20,
+		TimeOfUseRates: []models.TariffRate{
+			{Name: "day", RatePerKWh: 0.25, StartHour: 8, EndHour: 20},
+			{Name: "shoulder", RatePerKWh: 0.30, StartHour: 16, EndHour: 18},
+			{Name: "all-day", RatePerKWh: 0.12, StartHour: 0, EndHour: 0, DaysOfWeek: []int{0}},
+		},
+	}
+
+	// The last matching window wins
+	assert.Equal(t, "shoulder", rateAtHour(t, tariff, time.Date(2024, 7, 1, 16, 0, 0, 0, time.UTC)).Name)
+	assert.Equal(t, "day", rateAtHour(t, tariff, time.Date(2024, 7, 1, 18, 0, 0, 0, time.UTC)).Name)
+	// Equal start and end hours cover the whole day
+	assert.Equal(t, "all-day", rateAtHour(t, tariff, time.Date(2024, 7, 7, 3, 0, 0, 0, time.UTC)).Name)
+	assert.Equal(t, "all-day", rateAtHour(t, tariff, time.Date(2024, 7, 7, 17, 0, 0, 0, time.UTC)).Name)
+}
+
+func TestPriceLoad_BaseRateFallback(t *testing.T) {
+	at := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	// Without a base rate, a tariff with windows falls back to its off-peak rate
+	tariff := &models.Tariff{CurrentRate: 0.30, OffPeakRate: 0.18, TimeOfUseRates: []models.TariffRate{{Name: "night", RatePerKWh: 0.10, StartHour: 22, EndHour: 6}}}
+	assert.Equal(t, 0.18, rateAtHour(t, tariff, at).RatePerKWh)
+
+	// and a flat tariff to its current rate
+	tariff = &models.Tariff{CurrentRate: 0.30, OffPeakRate: 0.18}
+	assert.Equal(t, 0.30, rateAtHour(t, tariff, at).RatePerKWh)
+}
+
+func TestPriceLoad_OvernightDemandCharge(t *testing.T) {
+	tariff := &models.Tariff{
+		BaseRate:      0.20,
+		DemandCharges: []models.DemandCharge{{Name: "night demand", RatePerKW: 10, StartHour: 22, EndHour: 2}},
+	}
+	start := time.Date(2024, 7, 1, 20, 0, 0, 0, time.UTC)
+	intervals := []models.LoadInterval{
+		{Start: start, Minutes: 60, EnergyKWh: 50},                      // 20:00, outside the window
+		{Start: start.Add(2 * time.Hour), Minutes: 60, EnergyKWh: 10},   // 22:00
+		{Start: start.Add(5 * time.Hour), Minutes: 30, EnergyKWh: 8},    // 01:00, 16 kW
+		{Start: start.Add(6 * time.Hour), Minutes: 60, EnergyKWh: 40},   // 02:00, the end hour is outside
+		{Start: start.Add(5*time.Hour + 30*time.Minute), EnergyKWh: 12}, // 01:30, defaults to 60 minutes
+	}
+
+	breakdown := service.PriceLoad(tariff, intervals, 0.5)
+	require.Len(t, breakdown.DemandCharges, 1)
+	charge := breakdown.DemandCharges[0]
+	assert.Equal(t, 16.0, charge.PeakKW)
+	require.NotNil(t, charge.PeakAt)
+	assert.Equal(t, start.Add(5*time.Hour), *charge.PeakAt)
+	assert.Equal(t, 80.0, charge.Cost, "16 kW at 10 per kW with half the month's charge")
+	assert.Equal(t, 120.0, breakdown.EnergyKWh)
+}
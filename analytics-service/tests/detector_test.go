@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreShadowAlerts(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	anomaly := func(deviceID string, offset time.Duration, status models.AnomalyStatus) *models.Anomaly {
+		return &models.Anomaly{BuildingID: "b1", DeviceID: deviceID, Status: status, DetectedAt: base.Add(offset)}
+	}
+	alert := func(deviceID string, offset time.Duration) *models.ShadowAnomaly {
+		return &models.ShadowAnomaly{BuildingID: "b1", DeviceID: deviceID, DetectedAt: base.Add(offset)}
+	}
+
+	anomalies := []*models.Anomaly{
+		anomaly("d1", 0, models.AnomalyStatusAcknowledged),
+		anomaly("d2", 0, models.AnomalyStatusResolved),
+		anomaly("d3", 0, models.AnomalyStatusFalsePositive),
+		anomaly("d4", 0, models.AnomalyStatusNew),
+	}
+	alerts := []*models.ShadowAnomaly{
+		alert("d1", 10*time.Minute),  // confirmed within tolerance
+		alert("d1", -20*time.Minute), // same confirmed anomaly again
+		alert("d2", 2*time.Hour),     // outside tolerance: novel
+		alert("d3", 0),               // false positive
+		alert("d4", 0),               // unreviewed
+		alert("d5", 0),               // no live anomaly: novel
+	}
+
+	quality := service.ScoreShadowAlerts(alerts, anomalies, 30*time.Minute)
+	assert.Equal(t, 6, quality.Alerts)
+	assert.Equal(t, 2, quality.TruePositives)
+	assert.Equal(t, 1, quality.FalsePositives)
+	assert.Equal(t, 2, quality.Novel)
+	assert.Equal(t, 1, quality.Unreviewed)
+	assert.Equal(t, 1, quality.ConfirmedFound)
+	require.NotNil(t, quality.Precision)
+	assert.Equal(t, 0.4, *quality.Precision)
+	require.NotNil(t, quality.Recall)
+	assert.Equal(t, 0.5, *quality.Recall)
+}
+
+func TestScoreShadowAlertsWithoutReviews(t *testing.T) {
+	quality := service.ScoreShadowAlerts(nil, []*models.Anomaly{
+		{BuildingID: "b1", DeviceID: "d1", Status: models.AnomalyStatusNew, DetectedAt: time.Now()},
+	}, time.Hour)
+	assert.Zero(t, quality.Alerts)
+	assert.Nil(t, quality.Precision)
+	assert.Nil(t, quality.Recall)
+}
+
+func TestDefaultDetectorConfigs(t *testing.T) {
+	configs := service.DefaultDetectorConfigs(3)
+	for detector := range models.DetectorAnomalyTypes {
+		config, ok := configs[detector]
+		require.True(t, ok, detector)
+		assert.Equal(t, models.DetectorModeLive, config.Mode)
+	}
+	assert.Equal(t, 3, configs[models.DetectorForecastDeviation].Params.MinIntervals)
+	assert.Equal(t, 30.0, *configs[models.DetectorTemperatureRange].Params.MaxTemperature)
+}
@@ -0,0 +1,407 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"analytics-service/internal/graphql"
+	"analytics-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSchema is a small schema of buildings and their meters
+func testSchema() *graphql.Schema {
+	meterType := &graphql.Object{
+		Name: "Meter",
+		Fields: graphql.Fields{
+			"meterId": {Type: graphql.ID},
+			"reading": {Type: graphql.Float},
+			"count":   {Type: graphql.Int},
+			"broken": {
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return nil, errors.New("meter offline")
+				},
+			},
+		},
+	}
+
+	buildingType := &graphql.Object{
+		Name: "Building",
+		Fields: graphql.Fields{
+			"buildingId": {Type: graphql.ID},
+			"name":       {Type: graphql.String},
+			"meters": {
+				Type: &graphql.List{OfType: meterType},
+				Args: graphql.Args{"limit": {Type: graphql.Int, Default: 2}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					meters := []map[string]interface{}{
+						{"meterId": p.Source["buildingId"].(string) + "-m1", "reading": 1.5, "count": 1},
+						{"meterId": p.Source["buildingId"].(string) + "-m2", "reading": 2.5, "count": 2},
+						{"meterId": p.Source["buildingId"].(string) + "-m3", "reading": 3.5, "count": 1 << 40},
+					}
+					return meters[:p.Args["limit"].(int)], nil
+				},
+			},
+		},
+	}
+
+	return &graphql.Schema{Query: &graphql.Object{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"building": {
+				Type: buildingType,
+				Args: graphql.Args{"buildingId": {Type: graphql.ID, Required: true}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id := p.Args["buildingId"].(string)
+					if id == "missing" {
+						return nil, nil
+					}
+					return map[string]interface{}{"buildingId": id, "name": "Building " + id}, nil
+				},
+			},
+			"since": {
+				Type: graphql.Time,
+				Args: graphql.Args{"at": {Type: graphql.Time, Required: true}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Args["at"].(time.Time).Add(time.Hour), nil
+				},
+			},
+			"token": {
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Root.(string), nil
+				},
+			},
+			"panics": {
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					panic("boom")
+				},
+			},
+		},
+	}}
+}
+
+// execute runs a query against the test schema and returns the JSON response
+func execute(t *testing.T, req *graphql.Request) (string, *graphql.Result) {
+	t.Helper()
+	result := testSchema().Execute(context.Background(), req, "root-token")
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	return string(data), result
+}
+
+func TestGraphQLSelections(t *testing.T) {
+	response, result := execute(t, &graphql.Request{Query: `
+		query Page($id: ID!, $withMeters: Boolean = true) {
+			main: building(buildingId: $id) {
+				__typename
+				...Names
+				meters(limit: 1) @include(if: $withMeters) { meterId reading }
+			}
+			other: building(buildingId: "b2") {
+				... on Building { buildingId }
+				meters @skip(if: true) { meterId }
+			}
+			token
+		}
+		fragment Names on Building { buildingId name }
+	`, Variables: map[string]interface{}{"id": "b1"}})
+
+	assert.Empty(t, result.Errors)
+	// Fields appear in selection order, under their aliases
+	assert.JSONEq(t, `{"data": {
+		"main": {"__typename": "Building", "buildingId": "b1", "name": "Building b1", "meters": [{"meterId": "b1-m1", "reading": 1.5}]},
+		"other": {"buildingId": "b2"},
+		"token": "root-token"
+	}}`, response)
+	assert.Regexp(t, `^\{"data":\{"main":\{"__typename":"Building","buildingId":"b1","name"`, response)
+}
+
+func TestGraphQLArguments(t *testing.T) {
+	// Defaults apply to missing arguments
+	response, result := execute(t, &graphql.Request{Query: `{ building(buildingId: "b1") { meters { meterId } } }`})
+	assert.Empty(t, result.Errors)
+	assert.JSONEq(t, `{"data": {"building": {"meters": [{"meterId": "b1-m1"}, {"meterId": "b1-m2"}]}}}`, response)
+
+	// Time arguments are parsed and serialized as RFC 3339
+	response, result = execute(t, &graphql.Request{
+		Query:     `query($at: Time!) { since(at: $at) }`,
+		Variables: map[string]interface{}{"at": "2024-03-01T10:00:00Z"},
+	})
+	assert.Empty(t, result.Errors)
+	assert.JSONEq(t, `{"data": {"since": "2024-03-01T11:00:00Z"}}`, response)
+
+	// An invalid argument value fails the field only
+	response, result = execute(t, &graphql.Request{Query: `{ since(at: "yesterday") token }`})
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Message, `argument "at" has invalid value`)
+	assert.Equal(t, []interface{}{"since"}, result.Errors[0].Path)
+	assert.JSONEq(t, `{"since": null, "token": "root-token"}`, mustJSON(t, result.Data))
+
+	// Required variables must be provided
+	_, result = execute(t, &graphql.Request{Query: `query($id: ID!) { building(buildingId: $id) { name } }`})
+	assert.Nil(t, result.Data)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "variable $id of required type ID! was not provided", result.Errors[0].Message)
+}
+
+func TestGraphQLFieldErrors(t *testing.T) {
+	response, result := execute(t, &graphql.Request{Query: `{
+		building(buildingId: "b1") { meters(limit: 3) { meterId count broken } }
+		missing: building(buildingId: "missing") { name }
+		panics
+	}`})
+
+	// Failing fields are null and reported with their path; the rest of the query resolves
+	assert.JSONEq(t, `{
+		"building": {"meters": [
+			{"meterId": "b1-m1", "count": 1, "broken": null},
+			{"meterId": "b1-m2", "count": 2, "broken": null},
+			{"meterId": "b1-m3", "count": null, "broken": null}
+		]},
+		"missing": null,
+		"panics": null
+	}`, mustJSON(t, result.Data), response)
+
+	messages := make(map[string]string)
+	for _, err := range result.Errors {
+		path, _ := json.Marshal(err.Path)
+		messages[string(path)] = err.Message
+	}
+	assert.Len(t, messages, 5)
+	assert.Equal(t, "meter offline", messages[`["building","meters",0,"broken"]`])
+	assert.Contains(t, messages[`["building","meters",2,"count"]`], "Int cannot represent value")
+	assert.Contains(t, messages[`["panics"]`], `internal error resolving field "panics"`)
+}
+
+func TestGraphQLValidation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		query   string
+		message string
+	}{
+		"syntax error":        {`{ building(buildingId: "b1") { name }`, ""},
+		"unknown field":       {`{ weather }`, `cannot query field "weather" on type "Query"`},
+		"unknown argument":    {`{ token(format: "short") }`, `unknown argument "format" on field "token" of type "Query"`},
+		"missing argument":    {`{ building { name } }`, `field "building" argument "buildingId" of type ID! is required`},
+		"missing selection":   {`{ building(buildingId: "b1") }`, `field "building" of type "Building" must have a selection of subfields`},
+		"selection on scalar": {`{ token { length } }`, `field "token" must not have a selection since type "String" has no subfields`},
+		"unknown fragment":    {`{ building(buildingId: "b1") { ...Details } }`, `unknown fragment "Details"`},
+		"mutation":            {`mutation { token }`, "mutation operations are not supported"},
+		"ambiguous operation": {`query A { token } query B { token }`, "operation name is required when the document contains several operations"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, result := execute(t, &graphql.Request{Query: tc.query})
+			assert.Nil(t, result.Data, "invalid requests have no data")
+			require.NotEmpty(t, result.Errors)
+			if tc.message != "" {
+				assert.Equal(t, tc.message, result.Errors[0].Message)
+			}
+		})
+	}
+
+	// A named operation can be selected from several
+	response, result := execute(t, &graphql.Request{Query: `query A { a: token } query B { b: token }`, OperationName: "B"})
+	assert.Empty(t, result.Errors)
+	assert.JSONEq(t, `{"data": {"b": "root-token"}}`, response)
+}
+
+// TestGraphQLLimits tests that queries over the depth, complexity or alias limits are rejected
+// before anything is resolved
+func TestGraphQLLimits(t *testing.T) {
+	for name, tc := range map[string]struct {
+		limits    graphql.Limits
+		query     string
+		variables map[string]interface{}
+		message   string
+	}{
+		"too deep": {
+			limits:  graphql.Limits{MaxDepth: 2},
+			query:   `{ building(buildingId: "b1") { meters { meterId } } }`,
+			message: "query depth 3 exceeds the limit of 2",
+		},
+		"too complex": {
+			limits:    graphql.Limits{MaxComplexity: 10},
+			query:     `query Meters($limit: Int) { building(buildingId: "b1") { meters(limit: $limit) { broken } } }`,
+			variables: map[string]interface{}{"limit": 20},
+			message:   "query complexity 22 exceeds the limit of 10",
+		},
+		"too many aliases": {
+			limits:  graphql.Limits{MaxAliases: 2},
+			query:   `{ a: token b: token c: token }`,
+			message: "query uses 3 aliases, more than the limit of 2",
+		},
+		"aliases in fragments": {
+			limits:  graphql.Limits{MaxAliases: 2},
+			query:   `{ building(buildingId: "b1") { ...Names } } fragment Names on Building { a: name b: name c: name }`,
+			message: "query uses 3 aliases, more than the limit of 2",
+		},
+		"fragment cycle": {
+			query:   `{ building(buildingId: "b1") { ...A } } fragment A on Building { name ...B } fragment B on Building { ...A }`,
+			message: `fragment "A" spreads itself`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			schema := testSchema()
+			schema.Limits = tc.limits
+			result := schema.Execute(context.Background(), &graphql.Request{Query: tc.query, Variables: tc.variables}, "root-token")
+			assert.Nil(t, result.Data, "rejected queries have no data")
+			require.Len(t, result.Errors, 1)
+			assert.Equal(t, tc.message, result.Errors[0].Message)
+		})
+	}
+
+	// A query at every limit runs
+	schema := testSchema()
+	schema.Limits = graphql.Limits{MaxDepth: 3, MaxComplexity: 2, MaxAliases: 1}
+	result := schema.Execute(context.Background(), &graphql.Request{Query: `{ building(buildingId: "b1") { meters { id: meterId } } }`}, "root-token")
+	assert.Empty(t, result.Errors)
+	assert.NotNil(t, result.Data)
+
+	// Documents nested past the parser's limit are rejected without recursing through them
+	for _, query := range []string{
+		strings.Repeat("{ building ", 100) + strings.Repeat("}", 100),
+		`{ token(format: ` + strings.Repeat("[", 100) + strings.Repeat("]", 100) + `) }`,
+	} {
+		_, result := execute(t, &graphql.Request{Query: query})
+		assert.Nil(t, result.Data)
+		require.Len(t, result.Errors, 1)
+		assert.Contains(t, result.Errors[0].Message, "nested more than 64 levels deep")
+	}
+}
+
+func mustJSON(t *testing.T, value interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// graphQLIoTClient serves devices and telemetry from memory and records the tokens it was called with
+type graphQLIoTClient struct {
+	mu     sync.Mutex
+	tokens []string
+	limits []int
+}
+
+func (c *graphQLIoTClient) record(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens = append(c.tokens, token)
+}
+
+func (c *graphQLIoTClient) GetDevices(ctx context.Context, buildingID string, authToken string) ([]map[string]interface{}, error) {
+	c.record(authToken)
+	if buildingID == "unreachable" {
+		return nil, errors.New("iot service unavailable")
+	}
+	return []map[string]interface{}{
+		{"deviceId": "hvac-1", "type": "HVAC", "status": "ONLINE", "location": map[string]interface{}{"buildingId": buildingID}},
+		{"deviceId": "meter-1", "type": "METER", "status": "ONLINE", "location": map[string]interface{}{"buildingId": buildingID}},
+		{"deviceId": "hvac-2", "type": "HVAC", "status": "OFFLINE", "location": map[string]interface{}{"buildingId": buildingID}},
+	}, nil
+}
+
+func (c *graphQLIoTClient) GetDevice(ctx context.Context, deviceID string, authToken string) (map[string]interface{}, error) {
+	c.record(authToken)
+	return map[string]interface{}{"deviceId": deviceID, "type": "HVAC"}, nil
+}
+
+func (c *graphQLIoTClient) GetDeviceState(ctx context.Context, deviceID string, authToken string) (map[string]interface{}, error) {
+	c.record(authToken)
+	return map[string]interface{}{"power": "ON"}, nil
+}
+
+func (c *graphQLIoTClient) GetTelemetryHistory(ctx context.Context, deviceID string, from, to time.Time, page, limit int, authToken string) ([]map[string]interface{}, error) {
+	c.record(authToken)
+	c.mu.Lock()
+	c.limits = append(c.limits, limit)
+	c.mu.Unlock()
+	return []map[string]interface{}{
+		{"deviceId": deviceID, "timestamp": to.Format(time.RFC3339), "metrics": map[string]interface{}{"temperature": 21.5}},
+	}, nil
+}
+
+// graphQLForecastClient serves a forecast and scenarios from memory
+type graphQLForecastClient struct{}
+
+func (c *graphQLForecastClient) GetLatestForecast(ctx context.Context, buildingID string, authToken string) (map[string]interface{}, error) {
+	return map[string]interface{}{"buildingId": buildingID, "status": "COMPLETED", "horizonHours": 24}, nil
+}
+
+func (c *graphQLForecastClient) ListScenarios(ctx context.Context, buildingID, status string, limit int, authToken string) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{{"id": "scenario-1", "buildingId": buildingID, "status": status, "priority": limit}}, nil
+}
+
+func (c *graphQLForecastClient) GetScenario(ctx context.Context, scenarioID string, authToken string) (map[string]interface{}, error) {
+	return nil, errors.New("scenario not found")
+}
+
+func TestGraphQLServiceBuildingPage(t *testing.T) {
+	iotClient := &graphQLIoTClient{}
+	graphQLService := service.NewGraphQLService(nil, nil, nil, iotClient, &graphQLForecastClient{})
+
+	result := graphQLService.Execute(context.Background(), &graphql.Request{Query: `{
+		building(buildingId: "b1") {
+			devices(type: "HVAC", status: "ONLINE") {
+				deviceId
+				buildingId
+				state
+				telemetry(limit: 5) { deviceId metrics }
+			}
+			forecast { status horizonHours }
+			scenarios(status: "APPROVED") { id status priority }
+		}
+	}`}, "user-token")
+
+	assert.Empty(t, result.Errors)
+	assert.JSONEq(t, `{"building": {
+		"devices": [{
+			"deviceId": "hvac-1",
+			"buildingId": "b1",
+			"state": {"power": "ON"},
+			"telemetry": [{"deviceId": "hvac-1", "metrics": {"temperature": 21.5}}]
+		}],
+		"forecast": {"status": "COMPLETED", "horizonHours": 24},
+		"scenarios": [{"id": "scenario-1", "status": "APPROVED", "priority": 20}]
+	}}`, mustJSON(t, result.Data))
+
+	// Calls to other services are made with the caller's token
+	require.NotEmpty(t, iotClient.tokens)
+	for _, token := range iotClient.tokens {
+		assert.Equal(t, "user-token", token)
+	}
+	assert.Equal(t, []int{5}, iotClient.limits)
+}
+
+func TestGraphQLServiceErrors(t *testing.T) {
+	iotClient := &graphQLIoTClient{}
+	graphQLService := service.NewGraphQLService(nil, nil, nil, iotClient, &graphQLForecastClient{})
+
+	result := graphQLService.Execute(context.Background(), &graphql.Request{Query: `{
+		unreachable: devices(buildingId: "unreachable") { deviceId }
+		device(deviceId: "hvac-1") { telemetry(limit: 5000) { deviceId } }
+		scenario(scenarioId: "missing") { id }
+	}`}, "user-token")
+
+	assert.JSONEq(t, `{"unreachable": null, "device": {"telemetry": null}, "scenario": null}`, mustJSON(t, result.Data))
+	messages := make(map[string]string)
+	for _, err := range result.Errors {
+		path, _ := json.Marshal(err.Path)
+		messages[string(path)] = err.Message
+	}
+	assert.Equal(t, map[string]string{
+		`["unreachable"]`:        "iot service unavailable",
+		`["device","telemetry"]`: "limit must be between 1 and 1000",
+		`["scenario"]`:           "scenario not found",
+	}, messages)
+	assert.Empty(t, iotClient.limits, "telemetry beyond the limit is not requested")
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"testing"
+
+	"analytics-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKPIFormulaParse(t *testing.T) {
+	t.Run("variables sorted by name", func(t *testing.T) {
+		formula, err := service.ParseKPIFormula("(consumption - generation) / floor_area / consumption")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"consumption", "floor_area", "generation"}, formula.Variables())
+	})
+
+	t.Run("invalid formulas", func(t *testing.T) {
+		for _, expression := range []string{"", "consumption +", "(consumption", "consumption floor_area", "consumption $ 2", "1.2.3"} {
+			_, err := service.ParseKPIFormula(expression)
+			assert.Error(t, err, expression)
+		}
+	})
+}
+
+func TestKPIFormulaUnits(t *testing.T) {
+	units := map[string]string{
+		"consumption":    "kWh",
+		"generation":     "kWh",
+		"floor_area":     "m²",
+		"occupied_hours": "h",
+	}
+
+	tests := []struct {
+		expression string
+		unit       string
+	}{
+		{"consumption / floor_area / occupied_hours", "kWh/m²/h"},
+		{"(consumption - generation) / floor_area", "kWh/m²"},
+		{"generation / consumption * 100", ""},
+		{"consumption * 1000 + 5", "kWh"},
+		{"floor_area * floor_area", "(m²)²"},
+	}
+	for _, tt := range tests {
+		formula, err := service.ParseKPIFormula(tt.expression)
+		require.NoError(t, err)
+		unit, errs := formula.InferUnit(units)
+		assert.Empty(t, errs, tt.expression)
+		assert.Equal(t, tt.unit, unit, tt.expression)
+	}
+
+	t.Run("adding different units fails", func(t *testing.T) {
+		formula, err := service.ParseKPIFormula("consumption + floor_area")
+		require.NoError(t, err)
+		_, errs := formula.InferUnit(units)
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), "cannot add kWh and m²")
+	})
+
+	t.Run("unknown variable fails", func(t *testing.T) {
+		formula, err := service.ParseKPIFormula("consumption / volume")
+		require.NoError(t, err)
+		_, errs := formula.InferUnit(units)
+		assert.NotEmpty(t, errs)
+	})
+}
+
+func TestKPIFormulaEvaluate(t *testing.T) {
+	formula, err := service.ParseKPIFormula("consumption / floor_area / occupied_hours")
+	require.NoError(t, err)
+
+	value, err := formula.Evaluate(map[string]float64{"consumption": 1200, "floor_area": 400, "occupied_hours": 60})
+	require.NoError(t, err)
+	assert.InDelta(t, 0.05, value, 1e-9)
+
+	_, err = formula.Evaluate(map[string]float64{"consumption": 1200, "occupied_hours": 60})
+	assert.Error(t, err, "missing variable")
+
+	_, err = formula.Evaluate(map[string]float64{"consumption": 1200, "floor_area": 0, "occupied_hours": 60})
+	assert.Error(t, err, "division by zero")
+
+	negation, err := service.ParseKPIFormula("-(generation - consumption) * 2")
+	require.NoError(t, err)
+	value, err = negation.Evaluate(map[string]float64{"generation": 10, "consumption": 25})
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, value)
+}
+
+func TestValidateKPIFormula(t *testing.T) {
+	validation := service.ValidateKPIFormula("consumption / floor_area")
+	assert.True(t, validation.Valid)
+	assert.Equal(t, "kWh/m²", validation.Unit)
+	assert.Equal(t, []string{"consumption", "floor_area"}, validation.Variables)
+
+	validation = service.ValidateKPIFormula("consumption + heating_degree_days")
+	assert.False(t, validation.Valid)
+	assert.NotEmpty(t, validation.Errors)
+}
@@ -2,41 +2,13 @@ package tests
 
 import (
 	"context"
-	"errors"
 	"testing"
 
-	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
 	"analytics-service/internal/service"
-)
-
-// MockKPIRepository is a mock implementation for testing
-type MockKPIRepository struct {
-	kpis map[string]*models.KPI
-}
-
-func (m *MockKPIRepository) FindLatest(ctx context.Context, buildingID, period string) (*models.KPI, error) {
-	key := buildingID + ":" + period
-	if kpi, exists := m.kpis[key]; exists {
-		return kpi, nil
-	}
-	return nil, errors.New("KPI not found")
-}
-
-func (m *MockKPIRepository) UpdateOrCreate(ctx context.Context, kpi *models.KPI) (*models.KPI, error) {
-	if m.kpis == nil {
-		m.kpis = make(map[string]*models.KPI)
-	}
-	key := kpi.BuildingID + ":" + kpi.Period
-	m.kpis[key] = kpi
-	return kpi, nil
-}
-
-// MockAnomalyRepositoryForKPI is a mock implementation for testing
-type MockAnomalyRepositoryForKPI struct{}
 
-func (m *MockAnomalyRepositoryForKPI) CountByStatus(ctx context.Context, status string) (int64, error) {
-	return 5, nil
-}
+	"github.com/stretchr/testify/assert"
+)
 
 // MockIoTClientForKPI is a mock implementation for testing
 type MockIoTClientForKPI struct{}
@@ -51,13 +23,27 @@ func (m *MockIoTClientForKPI) GetDevices(ctx context.Context, buildingID string,
 
 // TestKPICalculation tests KPI calculation
 func TestKPICalculation(t *testing.T) {
-	// Setup mocks
-	mockKPIRepo := &MockKPIRepository{}
-	mockAnomalyRepo := &MockAnomalyRepositoryForKPI{}
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Setup repositories and mocks
+	kpiRepo := repository.NewKPIRepository(db.Collection("kpis"), db.Collection("kpis"))
+	anomalyRepo := repository.NewAnomalyRepository(db.Collection("anomalies"), db.Collection("anomalies"))
+	timeSeriesRepo := repository.NewTimeSeriesRepository(db.Collection("time_series"), db.Collection("time_series"), false)
 	mockIoTClient := &MockIoTClientForKPI{}
 
 	// Create service
-	kpiService := service.NewKPIService(mockKPIRepo, mockAnomalyRepo, mockIoTClient)
+	kpiService := service.NewKPIService(
+		kpiRepo,
+		anomalyRepo,
+		timeSeriesRepo,
+		repository.NewTrendAlertRepository(db.Collection("kpi_trend_alerts")),
+		repository.NewKPIDefinitionRepository(db.Collection("kpi_definitions")),
+		repository.NewBuildingAttributesRepository(db.Collection("building_attributes")),
+		mockIoTClient,
+		nil,
+		nil,
+	)
 
 	// Test KPI calculation
 	ctx := context.Background()
@@ -70,11 +56,8 @@ func TestKPICalculation(t *testing.T) {
 		t.Error("Expected metrics to be calculated")
 	}
 
-	if totalDevices, ok := response.Metrics["totalDevices"].(int); !ok || totalDevices != 3 {
-		t.Errorf("Expected 3 total devices, got %v", totalDevices)
-	}
-
-	if onlineDevices, ok := response.Metrics["onlineDevices"].(int); !ok || onlineDevices != 2 {
-		t.Errorf("Expected 2 online devices, got %v", onlineDevices)
-	}
+	// Metrics are read back from the database, which stores small integers as int32
+	assert.EqualValues(t, 3, response.Metrics["totalDevices"], "total devices")
+	assert.EqualValues(t, 2, response.Metrics["onlineDevices"], "online devices")
+	assert.EqualValues(t, 0, response.Metrics["activeAnomalies"], "active anomalies")
 }
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSummarizeBuildingForecast tests comparing a stored forecast with the hours measured in range
+func TestSummarizeBuildingForecast(t *testing.T) {
+	start := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	forecast := map[string]interface{}{
+		"id": "forecast-1",
+		"predictions": []interface{}{
+			map[string]interface{}{"timestamp": start.Format(time.RFC3339), "predictedValue": 100.0},
+			map[string]interface{}{"timestamp": start.Add(time.Hour).Format(time.RFC3339), "predictedValue": 120.0},
+			map[string]interface{}{"timestamp": start.Add(2 * time.Hour).Format(time.RFC3339), "predictedValue": 80.0},
+			// Outside the range
+			map[string]interface{}{"timestamp": start.Add(5 * time.Hour).Format(time.RFC3339), "predictedValue": 500.0},
+		},
+	}
+	hours := []*models.HourlyConsumption{
+		{Timestamp: start, MainMeter: 110},
+		// Submeters are used without a main meter reading
+		{Timestamp: start.Add(time.Hour), Metered: 132},
+	}
+
+	summary := service.SummarizeBuildingForecast(forecast, hours, start, start.Add(3*time.Hour))
+
+	assert.Equal(t, 300.0, summary.Predicted)
+	assert.Equal(t, 220.0, summary.MeasuredPredicted)
+	assert.Equal(t, 242.0, summary.MeasuredActual)
+}
+
+// TestBuildPortfolioDashboard tests that portfolio totals carry forecast and verified savings
+func TestBuildPortfolioDashboard(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	dashboard := service.BuildPortfolioDashboard(
+		from, to,
+		nil,
+		map[string]map[string]interface{}{"building-1": {"deviceAvailability": 100.0}},
+		[]*models.BuildingAnomalyCounts{{BuildingID: "building-2", Active: 2, Critical: 1}},
+		[]*models.BuildingConsumption{
+			{BuildingID: "building-1", Actual: 1000},
+			{BuildingID: "building-2", Actual: 3000},
+		},
+		[]*models.BuildingSavings{{BuildingID: "building-1", SavingsKWh: 150}},
+		map[string]*models.BuildingForecast{
+			"building-1": {Predicted: 1200, MeasuredPredicted: 1000, MeasuredActual: 1100},
+			"building-2": {Predicted: 2500, MeasuredPredicted: 2500, MeasuredActual: 2500},
+		},
+	)
+
+	assert.Equal(t, 2, dashboard.Totals.BuildingCount)
+	assert.Equal(t, 4000.0, dashboard.Totals.TotalConsumption)
+	assert.Equal(t, 3700.0, dashboard.Totals.ForecastConsumption)
+	assert.Equal(t, 150.0, dashboard.Totals.TotalSavings)
+	// 3600 kWh measured against 3500 kWh predicted over the same intervals
+	assert.Equal(t, 2.86, dashboard.Totals.ForecastDeviationPercent)
+
+	require.Len(t, dashboard.Buildings, 2)
+	worst, best := dashboard.Buildings[0], dashboard.Buildings[1]
+	assert.Equal(t, "building-2", worst.BuildingID)
+	assert.Equal(t, 1, worst.Rank)
+	assert.Equal(t, "building-1", best.BuildingID)
+	assert.Equal(t, 1200.0, best.ForecastConsumption)
+	assert.Equal(t, 10.0, best.ForecastDeviationPercent)
+	assert.Equal(t, 150.0, best.Savings)
+}
+
+// fakePortfolioStore serves the same aggregates for every building and counts the queries made
+type fakePortfolioStore struct {
+	start   time.Time
+	queries int
+}
+
+func (f *fakePortfolioStore) FindLatestPerBuilding(ctx context.Context, period string, buildingIDs []string) (map[string]map[string]interface{}, error) {
+	f.queries++
+	return map[string]map[string]interface{}{}, nil
+}
+
+func (f *fakePortfolioStore) CountActiveByBuilding(ctx context.Context, buildingIDs []string) ([]*models.BuildingAnomalyCounts, error) {
+	f.queries++
+	return nil, nil
+}
+
+func (f *fakePortfolioStore) SumConsumptionByBuilding(ctx context.Context, from, to time.Time, buildingIDs []string) ([]*models.BuildingConsumption, error) {
+	f.queries++
+	totals := make([]*models.BuildingConsumption, 0, len(buildingIDs))
+	for _, id := range buildingIDs {
+		totals = append(totals, &models.BuildingConsumption{BuildingID: id, Actual: 250})
+	}
+	return totals, nil
+}
+
+func (f *fakePortfolioStore) SumHourlyConsumptionByBuilding(ctx context.Context, periods []models.BuildingPeriod) ([]*models.BuildingHourlyConsumption, error) {
+	f.queries++
+	var hours []*models.BuildingHourlyConsumption
+	for _, period := range periods {
+		hours = append(hours, &models.BuildingHourlyConsumption{BuildingID: period.BuildingID, Timestamp: f.start, MainMeter: 110})
+	}
+	return hours, nil
+}
+
+func (f *fakePortfolioStore) SumVerifiedSavingsByBuilding(ctx context.Context, from, to time.Time, buildingIDs []string) ([]*models.BuildingSavings, error) {
+	f.queries++
+	return nil, nil
+}
+
+// fakePortfolioForecasts serves a two-hour forecast for every building and counts the requests made
+type fakePortfolioForecasts struct {
+	start    time.Time
+	requests int
+}
+
+func (f *fakePortfolioForecasts) GetStoredForecasts(ctx context.Context, buildingIDs []string) (map[string]map[string]interface{}, error) {
+	f.requests++
+	forecasts := make(map[string]map[string]interface{})
+	for _, id := range buildingIDs {
+		forecasts[id] = map[string]interface{}{
+			"buildingId": id,
+			"predictions": []interface{}{
+				map[string]interface{}{"timestamp": f.start.Format(time.RFC3339), "predictedValue": 100.0},
+				map[string]interface{}{"timestamp": f.start.Add(time.Hour).Format(time.RFC3339), "predictedValue": 120.0},
+			},
+		}
+	}
+	return forecasts, nil
+}
+
+// TestLoadPortfolioDashboard_QueryCount tests that the queries of a portfolio dashboard do not
+// grow with the number of buildings
+func TestLoadPortfolioDashboard_QueryCount(t *testing.T) {
+	start := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	from, to := start, start.Add(2*time.Hour)
+
+	counts := func(buildings int) (int, int) {
+		store := &fakePortfolioStore{start: start}
+		forecasts := &fakePortfolioForecasts{start: start}
+		buildingIDs := make([]string, 0, buildings)
+		for i := 0; i < buildings; i++ {
+			buildingIDs = append(buildingIDs, fmt.Sprintf("building-%d", i))
+		}
+
+		dashboard, err := service.LoadPortfolioDashboard(context.Background(), store, forecasts, from, to, buildingIDs)
+		require.NoError(t, err)
+		require.Len(t, dashboard.Buildings, buildings)
+		for _, building := range dashboard.Buildings {
+			assert.Equal(t, 220.0, building.ForecastConsumption)
+		}
+		return store.queries, forecasts.requests
+	}
+
+	queries, requests := counts(1)
+	assert.Equal(t, 5, queries)
+	assert.Equal(t, 1, requests)
+
+	queries, requests = counts(200)
+	assert.Equal(t, 5, queries)
+	assert.Equal(t, 1, requests)
+}
@@ -2,33 +2,17 @@ package tests
 
 import (
 	"context"
-	"errors"
 	"testing"
 	"time"
 
 	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
 	"analytics-service/internal/service"
-)
-
-// MockReportRepository is a mock implementation for testing
-type MockReportRepository struct {
-	reports map[string]*models.Report
-}
-
-func (m *MockReportRepository) Create(ctx context.Context, report *models.Report) (*models.Report, error) {
-	if m.reports == nil {
-		m.reports = make(map[string]*models.Report)
-	}
-	m.reports[report.ReportID] = report
-	return report, nil
-}
+	"shared/jobs"
 
-func (m *MockReportRepository) FindByReportID(ctx context.Context, reportID string) (*models.Report, error) {
-	if report, exists := m.reports[reportID]; exists {
-		return report, nil
-	}
-	return nil, errors.New("report not found")
-}
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
 
 // MockIoTClient is a mock implementation for testing
 type MockIoTClient struct{}
@@ -66,13 +50,17 @@ func (m *MockForecastClient) GetLatestForecast(ctx context.Context, buildingID s
 
 // TestReportGeneration tests report generation
 func TestReportGeneration(t *testing.T) {
-	// Setup mocks
-	mockReportRepo := &MockReportRepository{}
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Setup repositories and mocks
+	reportRepo := repository.NewReportRepository(db.Collection("reports"))
+	jobQueue := jobs.NewQueue(db.Collection("jobs"), "analytics-service", "job-secret", time.Second)
 	mockIoTClient := &MockIoTClient{}
 	mockForecastClient := &MockForecastClient{}
 
-	// Create service
-	reportService := service.NewReportService(mockReportRepo, mockIoTClient, mockForecastClient)
+	// Create service; the queue is not started, so the report stays queued
+	reportService := service.NewReportService(reportRepo, mockIoTClient, mockForecastClient, nil, nil, jobQueue)
 
 	// Test report generation
 	req := &models.GenerateReportRequest{
@@ -83,7 +71,7 @@ func TestReportGeneration(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	response, err := reportService.GenerateReport(ctx, req, "user-001", "token")
+	response, err := reportService.GenerateReport(ctx, req, "user-001")
 	if err != nil {
 		t.Fatalf("Failed to generate report: %v", err)
 	}
@@ -95,4 +83,15 @@ func TestReportGeneration(t *testing.T) {
 	if response.Status != string(models.ReportStatusGenerating) {
 		t.Errorf("Expected status GENERATING, got %s", response.Status)
 	}
+	// The report is stored and its content generation queued
+	stored, err := reportRepo.FindByReportID(ctx, response.ReportID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReportStatusGenerating, stored.Status)
+
+	queued, total, err := jobQueue.List(ctx, string(jobs.StatusPending), "", 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.NotNil(t, queued[0].Identity)
+	assert.Equal(t, "user-001", queued[0].Identity.UserID)
+	assert.NotEmpty(t, queued[0].Identity.Signature)
 }
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/repository"
+	"analytics-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestRetentionPurge tests that retention purges only records created before the cutoff, that
+// a dry run only counts them and that disabled policies are skipped
+func TestRetentionPurge(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	reports := db.Collection("reports")
+	anomalies := db.Collection("anomalies")
+	reportRepo := repository.NewReportRepository(reports)
+	anomalyRepo := repository.NewAnomalyRepository(anomalies, anomalies)
+
+	// Insert directly, as the repositories stamp the current time
+	old := time.Now().AddDate(0, 0, -100)
+	recent := time.Now().AddDate(0, 0, -10)
+	for reportID, createdAt := range map[string]time.Time{"old-1": old, "old-2": old, "recent": recent} {
+		_, err := reports.InsertOne(ctx, &models.Report{ReportID: reportID, Status: models.ReportStatusCompleted, CreatedAt: createdAt})
+		require.NoError(t, err)
+	}
+	_, err := anomalies.InsertOne(ctx, &models.Anomaly{AnomalyID: "old", CreatedAt: old})
+	require.NoError(t, err)
+
+	retention := service.NewRetentionService(true, time.Hour)
+	retention.Register("reports", 90*24*time.Hour, reportRepo.CountOlderThan, reportRepo.DeleteOlderThan)
+	retention.Register("anomalies", 0, anomalyRepo.CountOlderThan, anomalyRepo.DeleteOlderThan)
+
+	status := retention.Run(ctx)
+	require.Len(t, status.Collections, 2)
+	assert.True(t, status.DryRun)
+	assert.Equal(t, int64(2), status.Collections[0].LastAffected)
+	assert.Zero(t, status.Collections[0].TotalPurged)
+	assert.False(t, status.Collections[1].Enabled)
+	assert.Nil(t, status.Collections[1].LastRunAt)
+
+	total, err := reports.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total, "a dry run deletes nothing")
+
+	retention.SetDryRun(false)
+	status = retention.Run(ctx)
+	assert.Equal(t, int64(2), status.Collections[0].TotalPurged)
+
+	_, err = reportRepo.FindByReportID(ctx, "recent")
+	assert.NoError(t, err)
+	_, err = reportRepo.FindByReportID(ctx, "old-1")
+	assert.Error(t, err)
+
+	total, err = anomalies.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total, "disabled policies purge nothing")
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"testing"
+
+	"analytics-service/internal/models"
+	"analytics-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeSavingsGoal(t *testing.T) {
+	goal := &models.SavingsGoal{
+		Name:          "Reduce consumption 10%",
+		BuildingID:    "building-1",
+		Year:          2025,
+		BaselineYear:  2024,
+		TargetPercent: 10,
+	}
+	months := []models.SavingsGoalMonth{
+		{Month: "2025-01", BaselineKWh: 1000, ActualKWh: 900},
+		{Month: "2025-02", BaselineKWh: 1000, ActualKWh: 850},
+	}
+
+	t.Run("on track", func(t *testing.T) {
+		progress := service.SummarizeSavingsGoal(goal, months, 12000, 10500)
+
+		assert.Equal(t, 1200.0, progress.TargetSavingsKWh)
+		assert.Equal(t, 10800.0, progress.TargetKWh)
+		assert.Equal(t, 250.0, progress.SavedKWh)
+		assert.Equal(t, 12.5, progress.SavedPercent)
+		// 250 of the 1200 kWh to save this year
+		assert.Equal(t, 20.83, progress.ProgressPercent)
+		assert.Equal(t, 12.5, progress.ProjectedSavingsPercent)
+		assert.Equal(t, models.SavingsGoalStateOnTrack, progress.State)
+	})
+
+	t.Run("at risk", func(t *testing.T) {
+		progress := service.SummarizeSavingsGoal(goal, months, 12000, 11200)
+
+		assert.Equal(t, 6.67, progress.ProjectedSavingsPercent)
+		assert.Equal(t, models.SavingsGoalStateAtRisk, progress.State)
+	})
+
+	t.Run("achieved", func(t *testing.T) {
+		saving := []models.SavingsGoalMonth{
+			{Month: "2025-01", BaselineKWh: 2000, ActualKWh: 1200},
+			{Month: "2025-02", BaselineKWh: 2000, ActualKWh: 1500},
+		}
+		progress := service.SummarizeSavingsGoal(goal, saving, 12000, 11500)
+
+		assert.Equal(t, 108.33, progress.ProgressPercent)
+		assert.Equal(t, models.SavingsGoalStateAchieved, progress.State)
+	})
+
+	t.Run("no baseline", func(t *testing.T) {
+		progress := service.SummarizeSavingsGoal(goal, nil, 0, 5000)
+
+		assert.Zero(t, progress.ProgressPercent)
+		assert.Zero(t, progress.ProjectedSavingsPercent)
+		assert.Equal(t, models.SavingsGoalStateNoBaseline, progress.State)
+	})
+}
+
+func TestReachedMilestones(t *testing.T) {
+	milestones, highest := service.ReachedMilestones(40, nil)
+	assert.Empty(t, milestones)
+	assert.Zero(t, highest)
+
+	milestones, highest = service.ReachedMilestones(55, nil)
+	assert.Equal(t, []int{50}, milestones)
+	assert.Equal(t, 50, highest)
+
+	// Only the highest of the milestones reached at once is announced
+	milestones, highest = service.ReachedMilestones(104, []int{50})
+	assert.Equal(t, []int{50, 75, 100}, milestones)
+	assert.Equal(t, 100, highest)
+
+	// Milestones already reached are not reached again
+	milestones, highest = service.ReachedMilestones(80, []int{50, 75})
+	assert.Equal(t, []int{50, 75}, milestones)
+	assert.Zero(t, highest)
+}
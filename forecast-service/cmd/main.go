@@ -13,21 +13,35 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"forecast-service/internal/cache"
 	"forecast-service/internal/config"
+	"forecast-service/internal/events"
 	"forecast-service/internal/handlers"
 	"forecast-service/internal/integrations"
 	"forecast-service/internal/middleware"
 	"forecast-service/internal/repository"
 	"forecast-service/internal/service"
+	"forecast-service/internal/settings"
+	"forecast-service/internal/tracing"
+	"shared/jobs"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
+	// Set up tracing before anything opens connections, so they are instrumented
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
 	// Connect to MongoDB
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -57,21 +71,84 @@ func main() {
 	peakLoadRepo := repository.NewPeakLoadRepository(collections.PeakLoads)
 	optimizationRepo := repository.NewOptimizationRepository(collections.OptimizationScenarios)
 	recommendationRepo := repository.NewRecommendationRepository(collections.Recommendations)
+	tariffRepo := repository.NewTariffRepository(collections.Tariffs)
+	occupancyRepo := repository.NewOccupancyRepository(collections.OccupancySchedules)
+	calendarRepo := repository.NewCalendarRepository(collections.CalendarDays)
+	buildingRepo := repository.NewBuildingRepository(collections.Buildings)
+	automationRepo := repository.NewAutomationRepository(collections.AutomationRules)
+	featureRepo := repository.NewFeatureRepository(collections.FeatureSnapshots)
+	correctionFactorRepo := repository.NewCorrectionFactorRepository(collections.CorrectionFactors)
+	scenarioTemplateRepo := repository.NewScenarioTemplateRepository(collections.ScenarioTemplates)
+	demandContractRepo := repository.NewDemandContractRepository(collections.DemandContracts)
 
 	// Initialize external integrations
 	securityClient := integrations.NewSecurityClient(cfg)
 	externalClient := integrations.NewExternalClient(cfg)
 	iotClient := integrations.NewIoTClient(cfg)
+	callbackClient := integrations.NewCallbackClient(cfg)
+	// Integration: AnalyticsClient prices expected savings with the analytics cost engine
+	analyticsClient := integrations.NewAnalyticsClient(cfg)
+
+	// Connect to the inter-service event bus
+	var eventBus *events.Bus
+	if cfg.Events.Enabled {
+		eventBus = events.NewBus(cfg, "forecast-service")
+		defer eventBus.Close()
+	}
 
 	// Initialize services
+	// Local tariffs take precedence over the external tariff API
+	tariffService := service.NewTariffService(tariffRepo, featureRepo, externalClient)
+	// Weather and tariffs fetched for forecasts are reused while fresh and kept for provenance
+	featureStore := service.NewFeatureStore(featureRepo, externalClient, tariffService, cfg.Forecast.WeatherFreshness, cfg.Forecast.TariffFreshness)
+	// Occupancy schedules drive time-of-day patterns and occupancy-aware optimization
+	occupancyService := service.NewOccupancyService(occupancyRepo, calendarRepo, buildingRepo, iotClient)
+	calendarService := service.NewCalendarService(calendarRepo, occupancyRepo)
+	buildingService := service.NewBuildingService(buildingRepo, demandContractRepo)
+
+	// Degree days for weather-normalized consumption comparisons
+	weatherService := service.NewWeatherService(externalClient, cfg.Forecast.DegreeDayBaseTemperature)
+
+	// Persistent job queue shared by asynchronous work
+	jobQueue := jobs.NewQueue(collections.Jobs, "forecast-service", cfg.Auth.ServiceSecret, cfg.Jobs.PollInterval)
+
+	// Runtime settings: tunables are registered by the services below and stored overrides are
+	// loaded once registration is complete
+	settingsStore := settings.NewStore(collections.Settings, collections.SettingsHistory)
+
+	// Optional Redis cache for hot reads; without an address every lookup misses
+	hotCache := cache.New(cache.Config{
+		Addr:     cfg.Cache.RedisAddr,
+		Password: cfg.Cache.RedisPassword,
+		DB:       cfg.Cache.RedisDB,
+		Prefix:   "forecast-service",
+		Timeout:  cfg.Cache.Timeout,
+		TTLs:     map[string]time.Duration{service.LatestForecastCacheKind: cfg.Cache.LatestForecastTTL},
+	})
+	defer hotCache.Close()
+
+	// Forecasting models selectable per request via modelType
+	modelRegistry := service.NewDefaultForecastModelRegistry(externalClient)
+
 	forecastService := service.NewForecastService(
 		forecastRepo,
 		peakLoadRepo,
 		securityClient,
 		externalClient,
+		featureStore,
+		occupancyService,
+		modelRegistry,
+		eventBus,
+		callbackClient,
+		jobQueue,
+		hotCache,
+		settingsStore,
 		cfg,
 	)
 
+	// Correction factors learned from verified savings scale the expected savings of new scenarios
+	feedbackService := service.NewOptimizationFeedbackService(correctionFactorRepo, optimizationRepo)
+
 	optimizationService := service.NewOptimizationService(
 		optimizationRepo,
 		forecastRepo,
@@ -79,25 +156,163 @@ func main() {
 		iotClient,
 		externalClient,
 		securityClient,
+		analyticsClient,
+		tariffService,
+		occupancyService,
+		feedbackService,
+		scenarioTemplateRepo,
+		demandContractRepo,
+		settingsStore,
+		cfg,
 	)
 
+	// Scenario templates fill in the type, constraints, devices and timing of generated scenarios
+	scenarioTemplateService := service.NewScenarioTemplateService(scenarioTemplateRepo)
+
+	// Demand charge forecasts price the billing-period peak and the effect of peak shaving on it
+	demandChargeService := service.NewDemandChargeService(
+		forecastRepo,
+		optimizationRepo,
+		tariffService,
+		externalClient,
+		occupancyService,
+	)
+
+	// Forecast breakdowns split building forecasts into the device-level forecasts of the building
+	forecastBreakdownService := service.NewForecastBreakdownService(forecastRepo, iotClient, occupancyService)
+
+	// Automation rules generate pre-conditioning scenarios ahead of forecast peaks
+	automationService := service.NewAutomationService(
+		automationRepo,
+		forecastRepo,
+		externalClient,
+		tariffService,
+		optimizationService,
+	)
+
+	automationEnabled := settingsStore.RegisterBool("automation.enabled",
+		"Evaluate automation rules periodically", cfg.Automation.Enabled)
+
+	// Initialize data retention
+	retentionService := service.NewRetentionService(cfg.Retention.DryRun, cfg.Retention.Interval)
+	retentionService.Register("forecasts", cfg.Retention.Forecasts, forecastRepo.CountOlderThan, forecastRepo.DeleteOlderThan)
+	retentionService.Register("peak_loads", cfg.Retention.PeakLoads, peakLoadRepo.CountOlderThan, peakLoadRepo.DeleteOlderThan)
+	retentionService.Register("feature_snapshots", cfg.Retention.Features, featureRepo.CountOlderThan, featureRepo.DeleteOlderThan)
+	retentionDryRun := settingsStore.RegisterBool("retention.dry_run",
+		"Only count the records retention would purge", cfg.Retention.DryRun)
+	retentionDryRun.OnChange(func() { retentionService.SetDryRun(retentionDryRun.Get()) })
+	for collection, retention := range map[string]time.Duration{
+		"forecasts":         cfg.Retention.Forecasts,
+		"peak_loads":        cfg.Retention.PeakLoads,
+		"feature_snapshots": cfg.Retention.Features,
+	} {
+		collection := collection
+		days := settingsStore.RegisterInt("retention."+collection+"_days",
+			"Days "+collection+" records are kept; 0 disables purging", int(retention.Hours()/24), 0, 3650)
+		days.OnChange(func() { retentionService.SetRetention(collection, time.Duration(days.Get())*24*time.Hour) })
+	}
+
+	// Apply stored settings before the workers start, then pick up changes made by other instances
+	if err := settingsStore.Load(ctx); err != nil {
+		log.Printf("Warning: Failed to load settings, using defaults: %v", err)
+	}
+
+	// Start background workers
+	workerCtx, workerCancel := context.WithCancel(context.Background())
+	defer workerCancel()
+	go settingsStore.Start(workerCtx, cfg.Settings.ReloadInterval)
+	go jobQueue.Start(workerCtx, cfg.Jobs.Workers)
+	go forecastService.StartActualsWorker(workerCtx, cfg.Forecast.ActualsInterval)
+	go automationService.StartEvaluationWorker(workerCtx, cfg.Automation.EvaluationInterval, automationEnabled.Get)
+	if cfg.Retention.Enabled {
+		go retentionService.StartWorker(workerCtx)
+	}
+
+	// Initialize health checks
+	healthService := service.NewHealthService("forecast-service")
+	healthService.Register("mongodb", true, mongoDB.HealthCheck)
+	healthService.Register("security_service", false, securityClient.HealthCheck)
+	healthService.Register("iot_service", false, iotClient.HealthCheck)
+	healthService.Register("analytics_service", false, analyticsClient.HealthCheck)
+	if eventBus != nil {
+		healthService.Register("event_bus", false, eventBus.HealthCheck)
+	}
+	if hotCache.Enabled() {
+		healthService.Register("redis", false, hotCache.HealthCheck)
+	}
+	for _, name := range []string{"weather", "tariff", "ml", "storage"} {
+		name := name
+		healthService.Register(name+"_api", false, func(ctx context.Context) error {
+			return externalClient.CheckService(ctx, name)
+		})
+	}
+
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(securityClient)
+	authMiddleware := middleware.NewAuthMiddleware(securityClient, cfg.Auth)
+
+	// Consume events published by other services
+	if eventBus != nil {
+		deactivationService := service.NewDeactivationService(optimizationRepo, automationRepo, securityClient)
+		subscriber := events.NewSubscriber(eventBus, optimizationRepo, optimizationService, forecastService, authMiddleware.Permissions(), deactivationService, feedbackService)
+		if err := subscriber.Start(); err != nil {
+			log.Printf("Warning: Failed to subscribe to events: %v", err)
+		}
+	}
 
 	// Initialize handlers
 	forecastHandler := handlers.NewForecastHandler(forecastService, securityClient)
 	optimizationHandler := handlers.NewOptimizationHandler(optimizationService, securityClient)
+	tariffHandler := handlers.NewTariffHandler(tariffService, securityClient)
+	occupancyHandler := handlers.NewOccupancyHandler(occupancyService, securityClient)
+	calendarHandler := handlers.NewCalendarHandler(calendarService, securityClient)
+	buildingHandler := handlers.NewBuildingHandler(buildingService, securityClient)
+	automationHandler := handlers.NewAutomationHandler(automationService, securityClient)
+	weatherHandler := handlers.NewWeatherHandler(weatherService)
+	demandChargeHandler := handlers.NewDemandChargeHandler(demandChargeService)
+	breakdownHandler := handlers.NewForecastBreakdownHandler(forecastBreakdownService)
+	feedbackHandler := handlers.NewOptimizationFeedbackHandler(feedbackService)
+	templateHandler := handlers.NewScenarioTemplateHandler(scenarioTemplateService, optimizationService, securityClient)
+	healthHandler := handlers.NewHealthHandler(healthService, hotCache)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
+	authEventsHandler := handlers.NewAuthEventsHandler(authMiddleware.Permissions())
+	jobHandler := handlers.NewJobHandler(jobQueue)
+	settingsHandler := handlers.NewSettingsHandler(settingsStore, securityClient)
 
 	// Create router
 	router := handlers.NewRouter(
 		forecastHandler,
 		optimizationHandler,
+		tariffHandler,
+		occupancyHandler,
+		calendarHandler,
+		buildingHandler,
+		automationHandler,
+		weatherHandler,
+		demandChargeHandler,
+		breakdownHandler,
+		feedbackHandler,
+		templateHandler,
+		healthHandler,
+		retentionHandler,
+		authEventsHandler,
+		jobHandler,
+		settingsHandler,
 		authMiddleware,
 	)
 
+	// Meter API calls for billing
+	if eventBus != nil {
+		router.UsageMeter = middleware.NewUsageMeter()
+		go router.UsageMeter.StartReporter(workerCtx, cfg.Events.UsageReportInterval, func(ctx context.Context, apiCalls []events.UsageCount) {
+			if len(apiCalls) > 0 {
+				eventBus.Publish(ctx, events.UsageReported, &events.UsageReportedData{Service: "forecast-service", APICalls: apiCalls, ReportedAt: time.Now()})
+			}
+		})
+	}
+
 	// Create Gin engine and setup routes
 	engine := gin.New()
-	router.SetupRoutes(engine)
+	router.SetupRoutes(engine, cfg.HTTP)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -130,6 +345,11 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Flush the spans still buffered for export
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+
 	log.Println("Server exited properly")
 }
 
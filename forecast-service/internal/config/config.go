@@ -4,21 +4,36 @@ package config
 import (
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+
+	"shared/signing"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Server    ServerConfig
-	MongoDB   MongoDBConfig
-	Security  SecurityServiceConfig
-	IoT       IoTServiceConfig
-	External  ExternalAPIsConfig
-	Forecast  ForecastConfig
-	Logging   LoggingConfig
+	Server       ServerConfig
+	MongoDB      MongoDBConfig
+	Security     SecurityServiceConfig
+	Auth         AuthConfig
+	IoT          IoTServiceConfig
+	Analytics    AnalyticsServiceConfig
+	External     ExternalAPIsConfig
+	Forecast     ForecastConfig
+	Optimization OptimizationConfig
+	Automation   AutomationConfig
+	Retention    RetentionConfig
+	Settings     SettingsConfig
+	Events       EventsConfig
+	Jobs         JobsConfig
+	Cache        CacheConfig
+	Logging      LoggingConfig
+	Tracing      TracingConfig
+	HTTP         HTTPConfig
 }
 
 // ServerConfig holds server-related configuration
@@ -33,6 +48,14 @@ type MongoDBConfig struct {
 	URI      string
 	Database string
 	Timeout  time.Duration
+	// ReplicaSet, ReadPreference and WriteConcern are left to the URI and driver defaults when empty
+	ReplicaSet             string
+	ReadPreference         string
+	WriteConcern           string
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	ServerSelectionTimeout time.Duration
+	RetryWrites            bool
 }
 
 // SecurityServiceConfig holds Security service integration settings
@@ -41,12 +64,39 @@ type SecurityServiceConfig struct {
 	Timeout time.Duration
 }
 
+// AuthConfig holds token validation settings.
+// With local validation enabled, tokens are verified against the Security service signing key
+// instead of a round trip per request; JWTSecret pins the key instead of fetching it.
+type AuthConfig struct {
+	LocalValidation    bool
+	JWTSecret          string
+	SigningKeyRefresh  time.Duration
+	PermissionCacheTTL time.Duration
+
+	// ServiceKey is the shared secret internal requests are signed with. ServiceSecret overrides
+	// it for requests this service sends, and ServiceSecrets for requests from the named services.
+	// Requests from services without their own secret are only verified with ServiceKey when
+	// SharedSecretDevMode is set, since any service could otherwise sign as another.
+	ServiceKey          string
+	ServiceSecret       string
+	ServiceSecrets      map[string]string
+	SharedSecretDevMode bool
+	SignatureWindow     time.Duration
+	AcceptServiceKey    bool // also accept the unsigned X-Service-Key header during migration
+}
+
 // IoTServiceConfig holds IoT service integration settings
 type IoTServiceConfig struct {
 	URL     string
 	Timeout time.Duration
 }
 
+// AnalyticsServiceConfig holds Analytics service integration settings
+type AnalyticsServiceConfig struct {
+	URL     string
+	Timeout time.Duration
+}
+
 // ExternalAPIsConfig holds external API endpoints
 type ExternalAPIsConfig struct {
 	WeatherURL string
@@ -59,7 +109,74 @@ type ExternalAPIsConfig struct {
 type ForecastConfig struct {
 	DefaultHorizonHours      int
 	MaxHorizonHours          int
+	LongHorizonMaxHours      int // Cap for daily and weekly forecasts; 0 disables them
 	PeakLoadThresholdPercent float64
+	CacheMaxAge              time.Duration
+	DegreeDayBaseTemperature float64
+	JobTimeout               time.Duration // Bounds an asynchronous forecast generation job
+	CallbackTimeout          time.Duration
+	WeatherFreshness         time.Duration // How long fetched weather is reused from the feature store
+	TariffFreshness          time.Duration // How long resolved tariffs are reused, within the same hour
+	ActualsInterval          time.Duration // How often actuals are backfilled into past predictions
+	ActualsGrace             time.Duration // How long after a forecast ends late actuals are still backfilled
+	Quantiles                []float64     // Quantiles predicted for forecasts requested without any
+}
+
+// OptimizationConfig holds optimization scenario settings
+type OptimizationConfig struct {
+	ClampToCapabilities bool // Move action targets outside a device's capabilities to the limit instead of rejecting them
+}
+
+// AutomationConfig holds automation rule engine settings
+type AutomationConfig struct {
+	Enabled            bool
+	EvaluationInterval time.Duration
+}
+
+// RetentionConfig holds per-collection data retention settings.
+// A retention of zero days disables purging for that collection.
+type RetentionConfig struct {
+	Enabled   bool
+	DryRun    bool
+	Interval  time.Duration
+	Forecasts time.Duration
+	PeakLoads time.Duration
+	Features  time.Duration
+}
+
+// SettingsConfig holds runtime settings store settings
+type SettingsConfig struct {
+	ReloadInterval time.Duration // How often settings changed by other instances are picked up
+}
+
+// EventsConfig holds inter-service event bus settings.
+// Events are exchanged through the MQTT broker shared by all services.
+type EventsConfig struct {
+	Enabled  bool
+	Broker   string
+	Port     int
+	Username string
+	Password string
+	ClientID string
+	QoS      byte
+	// UsageReportInterval is how often the metered API usage is published for billing
+	UsageReportInterval time.Duration
+}
+
+// JobsConfig holds settings of the persistent job queue that runs asynchronous work
+type JobsConfig struct {
+	Workers      int
+	PollInterval time.Duration // how often idle workers check for due jobs
+}
+
+// CacheConfig holds settings of the optional Redis cache for hot reads.
+// An empty address disables caching.
+type CacheConfig struct {
+	RedisAddr         string
+	RedisPassword     string
+	RedisDB           int
+	Timeout           time.Duration
+	LatestForecastTTL time.Duration
 }
 
 // LoggingConfig holds logging configuration
@@ -68,6 +185,15 @@ type LoggingConfig struct {
 	Format string
 }
 
+// TracingConfig holds OpenTelemetry tracing settings. Spans are exported over OTLP/HTTP.
+type TracingConfig struct {
+	Enabled     bool
+	Endpoint    string  // collector host:port, or a full URL of its traces endpoint
+	Insecure    bool    // export over plain HTTP
+	ServiceName string  // service.name of the exported spans
+	SampleRatio float64 // share of traces started by this service that are recorded
+}
+
 // Load reads configuration from environment variables
 func Load() *Config {
 	// Load .env file if it exists
@@ -75,25 +201,50 @@ func Load() *Config {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	mode := getEnv("GIN_MODE", "debug")
+
 	return &Config{
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8082"),
 			Host: getEnv("SERVER_HOST", "0.0.0.0"),
-			Mode: getEnv("GIN_MODE", "debug"),
+			Mode: mode,
 		},
 		MongoDB: MongoDBConfig{
-			URI:      getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-			Database: getEnv("MONGODB_DATABASE", "forecast_service"),
-			Timeout:  time.Duration(getEnvAsInt("MONGODB_TIMEOUT", 10)) * time.Second,
+			URI:                    getEnv("MONGODB_URI", "mongodb://localhost:27017"),
+			Database:               getEnv("MONGODB_DATABASE", "forecast_service"),
+			Timeout:                time.Duration(getEnvAsInt("MONGODB_TIMEOUT", 10)) * time.Second,
+			ReplicaSet:             getEnv("MONGODB_REPLICA_SET", ""),
+			ReadPreference:         getEnv("MONGODB_READ_PREFERENCE", ""),
+			WriteConcern:           getEnv("MONGODB_WRITE_CONCERN", ""),
+			MaxPoolSize:            uint64(getEnvAsInt("MONGODB_MAX_POOL_SIZE", 100)),
+			MinPoolSize:            uint64(getEnvAsInt("MONGODB_MIN_POOL_SIZE", 10)),
+			ServerSelectionTimeout: time.Duration(getEnvAsInt("MONGODB_SERVER_SELECTION_TIMEOUT", 30)) * time.Second,
+			RetryWrites:            getEnvAsBool("MONGODB_RETRY_WRITES", true),
 		},
 		Security: SecurityServiceConfig{
 			URL:     getEnv("SECURITY_SERVICE_URL", "http://localhost:8080"),
 			Timeout: time.Duration(getEnvAsInt("SECURITY_SERVICE_TIMEOUT", 10)) * time.Second,
 		},
+		Auth: AuthConfig{
+			LocalValidation:     getEnv("AUTH_LOCAL_VALIDATION", "false") == "true",
+			JWTSecret:           getEnv("AUTH_JWT_SECRET", ""),
+			SigningKeyRefresh:   time.Duration(getEnvAsInt("AUTH_SIGNING_KEY_REFRESH_MINUTES", 60)) * time.Minute,
+			PermissionCacheTTL:  time.Duration(getEnvAsInt("AUTH_PERMISSION_CACHE_TTL_SECONDS", 30)) * time.Second,
+			ServiceKey:          getEnv("INTERNAL_SERVICE_KEY", ""),
+			ServiceSecret:       getEnv("INTERNAL_SERVICE_SECRET", getEnv("INTERNAL_SERVICE_KEY", "")),
+			ServiceSecrets:      signing.ParseSecrets(getEnv("INTERNAL_SERVICE_SECRETS", "")),
+			SharedSecretDevMode: getEnvAsBool("INTERNAL_SHARED_SECRET_DEV_MODE", false),
+			SignatureWindow:     time.Duration(getEnvAsInt("INTERNAL_SIGNATURE_WINDOW_SECONDS", 300)) * time.Second,
+			AcceptServiceKey:    getEnvAsBool("INTERNAL_ACCEPT_SERVICE_KEY", false),
+		},
 		IoT: IoTServiceConfig{
 			URL:     getEnv("IOT_SERVICE_URL", "http://localhost:8083"),
 			Timeout: time.Duration(getEnvAsInt("IOT_SERVICE_TIMEOUT", 10)) * time.Second,
 		},
+		Analytics: AnalyticsServiceConfig{
+			URL:     getEnv("ANALYTICS_SERVICE_URL", "http://localhost:8084"),
+			Timeout: time.Duration(getEnvAsInt("ANALYTICS_SERVICE_TIMEOUT", 10)) * time.Second,
+		},
 		External: ExternalAPIsConfig{
 			WeatherURL: getEnv("WEATHER_API_URL", "http://localhost:8084/external/weather"),
 			TariffURL:  getEnv("TARIFF_API_URL", "http://localhost:8084/external/tariffs"),
@@ -103,15 +254,79 @@ func Load() *Config {
 		Forecast: ForecastConfig{
 			DefaultHorizonHours:      getEnvAsInt("FORECAST_DEFAULT_HORIZON_HOURS", 24),
 			MaxHorizonHours:          getEnvAsInt("FORECAST_MAX_HORIZON_HOURS", 168),
+			LongHorizonMaxHours:      getEnvAsInt("FORECAST_LONG_HORIZON_MAX_HOURS", 8*7*24),
 			PeakLoadThresholdPercent: getEnvAsFloat("PEAK_LOAD_THRESHOLD_PERCENTAGE", 80.0),
+			CacheMaxAge:              time.Duration(getEnvAsInt("FORECAST_CACHE_MAX_AGE_MINUTES", 60)) * time.Minute,
+			DegreeDayBaseTemperature: getEnvAsFloat("FORECAST_DEGREE_DAY_BASE_TEMPERATURE", 18.0),
+			JobTimeout:               time.Duration(getEnvAsInt("FORECAST_JOB_TIMEOUT_SECONDS", 300)) * time.Second,
+			CallbackTimeout:          time.Duration(getEnvAsInt("FORECAST_CALLBACK_TIMEOUT_SECONDS", 10)) * time.Second,
+			WeatherFreshness:         time.Duration(getEnvAsInt("FORECAST_WEATHER_FRESHNESS_MINUTES", 30)) * time.Minute,
+			TariffFreshness:          time.Duration(getEnvAsInt("FORECAST_TARIFF_FRESHNESS_MINUTES", 60)) * time.Minute,
+			ActualsInterval:          time.Duration(getEnvAsInt("FORECAST_ACTUALS_INTERVAL_MINUTES", 60)) * time.Minute,
+			ActualsGrace:             time.Duration(getEnvAsInt("FORECAST_ACTUALS_GRACE_HOURS", 48)) * time.Hour,
+			Quantiles:                getEnvAsQuantiles("FORECAST_QUANTILES", []float64{0.1, 0.5, 0.9}),
+		},
+		Optimization: OptimizationConfig{
+			ClampToCapabilities: getEnvAsBool("OPTIMIZATION_CLAMP_TO_CAPABILITIES", false),
+		},
+		Automation: AutomationConfig{
+			Enabled:            getEnv("AUTOMATION_ENABLED", "true") == "true",
+			EvaluationInterval: time.Duration(getEnvAsInt("AUTOMATION_EVALUATION_INTERVAL_MINUTES", 30)) * time.Minute,
+		},
+		Retention: RetentionConfig{
+			Enabled:   getEnv("RETENTION_ENABLED", "true") == "true",
+			DryRun:    getEnv("RETENTION_DRY_RUN", "false") == "true",
+			Interval:  time.Duration(getEnvAsInt("RETENTION_INTERVAL_HOURS", 24)) * time.Hour,
+			Forecasts: time.Duration(getEnvAsInt("RETENTION_FORECAST_DAYS", 90)) * 24 * time.Hour,
+			PeakLoads: time.Duration(getEnvAsInt("RETENTION_PEAK_LOAD_DAYS", 90)) * 24 * time.Hour,
+			Features:  time.Duration(getEnvAsInt("RETENTION_FEATURE_SNAPSHOT_DAYS", 90)) * 24 * time.Hour,
+		},
+		Settings: SettingsConfig{
+			ReloadInterval: time.Duration(getEnvAsInt("SETTINGS_RELOAD_INTERVAL_SECONDS", 30)) * time.Second,
+		},
+		Events: EventsConfig{
+			Enabled:  getEnv("EVENTS_ENABLED", "false") == "true",
+			Broker:   getEnv("EVENT_BUS_BROKER", "localhost"),
+			Port:     getEnvAsInt("EVENT_BUS_PORT", 1883),
+			Username: getEnv("EVENT_BUS_USERNAME", ""),
+			Password: getEnv("EVENT_BUS_PASSWORD", ""),
+			ClientID: getEnv("EVENT_BUS_CLIENT_ID", "forecast-service-events"),
+			QoS:      byte(getEnvAsInt("EVENT_BUS_QOS", 1)),
+
+			UsageReportInterval: time.Duration(getEnvAsInt("EVENT_BUS_USAGE_REPORT_INTERVAL_SECONDS", 60)) * time.Second,
+		},
+		Jobs: JobsConfig{
+			Workers:      getEnvAsInt("JOB_WORKERS", 4),
+			PollInterval: time.Duration(getEnvAsInt("JOB_POLL_INTERVAL_MS", 1000)) * time.Millisecond,
+		},
+		Cache: CacheConfig{
+			RedisAddr:         getEnv("REDIS_ADDR", ""),
+			RedisPassword:     getEnv("REDIS_PASSWORD", ""),
+			RedisDB:           getEnvAsInt("REDIS_DB", 0),
+			Timeout:           time.Duration(getEnvAsInt("REDIS_TIMEOUT_MS", 500)) * time.Millisecond,
+			LatestForecastTTL: time.Duration(getEnvAsInt("CACHE_LATEST_FORECAST_TTL_SECONDS", 300)) * time.Second,
 		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "debug"),
 			Format: getEnv("LOG_FORMAT", "json"),
 		},
+		Tracing: TracingConfig{
+			Enabled:     getEnvAsBool("TRACING_ENABLED", false),
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
+			Insecure:    getEnvAsBool("OTEL_EXPORTER_OTLP_INSECURE", true),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "forecast-service"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1.0),
+		},
+		HTTP: loadHTTPConfig(mode),
 	}
 }
 
+// Validate checks settings that would make the service unsafe or unusable, so startup fails
+// instead of serving with them
+func (c *Config) Validate() error {
+	return c.HTTP.Validate(c.Server.Mode)
+}
+
 // getEnv retrieves an environment variable with a default fallback
 func getEnv(key, defaultVal string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -120,6 +335,17 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+// getEnvAsList retrieves a comma-separated environment variable as a list, skipping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getEnvAsInt retrieves an environment variable as an integer
 func getEnvAsInt(key string, defaultVal int) int {
 	if value, exists := os.LookupEnv(key); exists {
@@ -130,6 +356,15 @@ func getEnvAsInt(key string, defaultVal int) int {
 	return defaultVal
 }
 
+func getEnvAsBool(key string, defaultVal bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultVal
+}
+
 // getEnvAsFloat retrieves an environment variable as a float
 func getEnvAsFloat(key string, defaultVal float64) float64 {
 	if value, exists := os.LookupEnv(key); exists {
@@ -139,3 +374,24 @@ func getEnvAsFloat(key string, defaultVal float64) float64 {
 	}
 	return defaultVal
 }
+
+// getEnvAsQuantiles retrieves a comma-separated list of quantiles between 0 and 1, sorted
+// ascending. The default is used when any entry is not a valid quantile.
+func getEnvAsQuantiles(key string, defaultVal []float64) []float64 {
+	values := getEnvAsList(key)
+	if len(values) == 0 {
+		return defaultVal
+	}
+
+	quantiles := make([]float64, 0, len(values))
+	for _, value := range values {
+		quantile, err := strconv.ParseFloat(value, 64)
+		if err != nil || quantile <= 0 || quantile >= 1 {
+			log.Printf("Ignoring %s: %q is not a quantile between 0 and 1", key, value)
+			return defaultVal
+		}
+		quantiles = append(quantiles, quantile)
+	}
+	sort.Float64s(quantiles)
+	return quantiles
+}
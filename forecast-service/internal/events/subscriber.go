@@ -0,0 +1,247 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"forecast-service/internal/models"
+)
+
+// ScenarioTracker records the outcome of optimization scenarios
+type ScenarioTracker interface {
+	UpdateStatus(ctx context.Context, id string, status models.OptimizationStatus, errorMsg string) error
+	AddExecutionLog(ctx context.Context, id string, entry models.ExecutionLogEntry) error
+}
+
+// ScenarioGenerator generates optimization scenarios
+type ScenarioGenerator interface {
+	GenerateOptimization(ctx context.Context, req *models.OptimizationGenerateRequest, userID, authToken string) (*models.OptimizationScenarioResponse, error)
+}
+
+// ForecastCache invalidates cached forecasts of a building
+type ForecastCache interface {
+	InvalidateCache(buildingID string)
+}
+
+// PermissionInvalidator drops cached token validations for a user
+type PermissionInvalidator interface {
+	InvalidateUser(userID string)
+}
+
+// UserReleaser releases the resources a deactivated user owns
+type UserReleaser interface {
+	ReleaseUser(ctx context.Context, userID, actorID string) error
+}
+
+// SavingsReconciler learns from the verified savings of executed scenarios
+type SavingsReconciler interface {
+	ReconcileScenario(ctx context.Context, verified *models.VerifiedSavings) error
+}
+
+// Subscriber reacts to domain events published by other services
+type Subscriber struct {
+	bus         *Bus
+	scenarios   ScenarioTracker
+	generator   ScenarioGenerator
+	forecasts   ForecastCache
+	permissions PermissionInvalidator
+	users       UserReleaser
+	savings     SavingsReconciler
+}
+
+// NewSubscriber creates the Forecast service event subscriber
+func NewSubscriber(bus *Bus, scenarios ScenarioTracker, generator ScenarioGenerator, forecasts ForecastCache, permissions PermissionInvalidator, users UserReleaser, savings SavingsReconciler) *Subscriber {
+	return &Subscriber{
+		bus:         bus,
+		scenarios:   scenarios,
+		generator:   generator,
+		forecasts:   forecasts,
+		permissions: permissions,
+		users:       users,
+		savings:     savings,
+	}
+}
+
+// Start registers handlers for the events this service consumes
+func (s *Subscriber) Start() error {
+	if err := s.bus.Subscribe(ScenarioExecuted, s.onScenarioExecuted); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(CommandTimedOut, s.onCommandTimedOut); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(BudgetThresholdCrossed, s.onBudgetThresholdCrossed); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(SavingsVerified, s.onSavingsVerified); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(ComfortGuardrailTriggered, s.onComfortGuardrailTriggered); err != nil {
+		return err
+	}
+	return s.bus.Subscribe(UserDeactivated, s.onUserDeactivated)
+}
+
+// onScenarioExecuted closes out a scenario sent to the IoT service and refreshes forecasts
+// of the building, whose load profile the executed actions have changed
+func (s *Subscriber) onScenarioExecuted(ctx context.Context, event *Event) error {
+	var data ScenarioExecutedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	s.forecasts.InvalidateCache(data.BuildingID)
+
+	if data.SourceScenarioID == "" {
+		return nil
+	}
+
+	// A paused execution is not finished; it completes once resumed
+	if data.Status == "PAUSED" {
+		return s.scenarios.AddExecutionLog(ctx, data.SourceScenarioID, models.ExecutionLogEntry{
+			Level:   "WARNING",
+			Message: fmt.Sprintf("Execution %s paused by the comfort guardrail: %d actions applied, %d failed", data.ScenarioID, data.ActionsApplied, data.ActionsFailed),
+		})
+	}
+
+	status := models.OptimizationStatusCompleted
+	level := "INFO"
+	errorMsg := ""
+	if data.ActionsApplied == 0 && data.ActionsFailed > 0 {
+		status = models.OptimizationStatusFailed
+		level = "ERROR"
+		errorMsg = "all actions failed"
+	} else if data.ActionsFailed > 0 {
+		level = "WARNING"
+	}
+
+	if err := s.scenarios.UpdateStatus(ctx, data.SourceScenarioID, status, errorMsg); err != nil {
+		return err
+	}
+
+	return s.scenarios.AddExecutionLog(ctx, data.SourceScenarioID, models.ExecutionLogEntry{
+		Level:   level,
+		Message: fmt.Sprintf("Execution %s finished: %d actions applied, %d failed", data.ScenarioID, data.ActionsApplied, data.ActionsFailed),
+	})
+}
+
+// onCommandTimedOut records in the execution log of a scenario sent to the IoT service that a
+// device did not acknowledge one of its commands
+func (s *Subscriber) onCommandTimedOut(ctx context.Context, event *Event) error {
+	var data CommandTimedOutData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	if data.SourceScenarioID == "" {
+		return nil
+	}
+
+	message := fmt.Sprintf("Command %s (%s) to device %s timed out without acknowledgment", data.CommandID, data.Command, data.DeviceID)
+	if data.RetryCommandID != "" {
+		message += fmt.Sprintf("; sent again as %s", data.RetryCommandID)
+	}
+
+	return s.scenarios.AddExecutionLog(ctx, data.SourceScenarioID, models.ExecutionLogEntry{
+		Level:   "WARNING",
+		Message: message,
+	})
+}
+
+// onComfortGuardrailTriggered records in the execution log of a scenario sent to the IoT service
+// that the comfort guardrail rolled back its actions on a device that left the comfort band
+func (s *Subscriber) onComfortGuardrailTriggered(ctx context.Context, event *Event) error {
+	var data ComfortGuardrailTriggeredData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	if data.SourceScenarioID == "" {
+		return nil
+	}
+
+	direction := "below the minimum"
+	if data.Bound == "MAX" {
+		direction = "above the maximum"
+	}
+	message := fmt.Sprintf("Comfort guardrail: device %s reported %.1f°C, %s of %.1f°C; %d actions rolled back",
+		data.DeviceID, data.Temperature, direction, data.Limit, data.ActionsRolledBack)
+	if data.ActionsNotRolledBack > 0 {
+		message += fmt.Sprintf(", %d could not be rolled back", data.ActionsNotRolledBack)
+	}
+	if data.Paused {
+		message += fmt.Sprintf("; execution %s paused", data.ScenarioID)
+	}
+
+	return s.scenarios.AddExecutionLog(ctx, data.SourceScenarioID, models.ExecutionLogEntry{
+		Level:   "WARNING",
+		Message: message,
+	})
+}
+
+// onBudgetThresholdCrossed generates a draft COST_REDUCTION scenario for budgets that opted in,
+// for operators to review before sending it to the IoT service
+func (s *Subscriber) onBudgetThresholdCrossed(ctx context.Context, event *Event) error {
+	var data BudgetThresholdCrossedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	if !data.GenerateScenario {
+		return nil
+	}
+
+	scenario, err := s.generator.GenerateOptimization(ctx, &models.OptimizationGenerateRequest{
+		BuildingID: data.BuildingID,
+		Name:       fmt.Sprintf("%s - %d%% of %s budget", data.Name, data.Threshold, data.Month),
+		Type:       models.OptimizationTypeCostReduction,
+		Constraints: models.OptimizationConstraints{
+			PreserveComfort: true,
+		},
+	}, "budget", "")
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Generated scenario %s for budget %s at %d%%", scenario.ID, data.BudgetID, data.Threshold)
+	return nil
+}
+
+// onSavingsVerified records the verified savings of a scenario generated by this service and
+// updates the correction factors applied to the expected savings of new scenarios
+func (s *Subscriber) onSavingsVerified(ctx context.Context, event *Event) error {
+	var data SavingsVerifiedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	if data.SourceScenarioID == "" {
+		return nil
+	}
+
+	return s.savings.ReconcileScenario(ctx, &models.VerifiedSavings{
+		ReportID:         data.ReportID,
+		ScenarioID:       data.SourceScenarioID,
+		Option:           data.Option,
+		DeviceIDs:        data.DeviceIDs,
+		BaselineMeanKWh:  data.BaselineMeanKWh,
+		ReportingMeanKWh: data.ReportingMeanKWh,
+		SavingsKWh:       data.SavingsKWh,
+		SavingsPercent:   data.SavingsPercent,
+	})
+}
+
+// onUserDeactivated stops accepting cached or locally validated tokens of a deactivated user,
+// withdraws their pending scenario approvals and pauses their automation rules
+func (s *Subscriber) onUserDeactivated(ctx context.Context, event *Event) error {
+	var data UserDeactivatedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	s.permissions.InvalidateUser(data.UserID)
+	log.Printf("Invalidated cached permissions of deactivated user %s", data.UserID)
+
+	return s.users.ReleaseUser(ctx, data.UserID, data.DeactivatedBy)
+}
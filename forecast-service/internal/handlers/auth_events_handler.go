@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/middleware"
+	"forecast-service/internal/models"
+)
+
+// AuthEventsHandler handles authorization change notifications from the Security service
+type AuthEventsHandler struct {
+	permissions *middleware.PermissionCache
+}
+
+// NewAuthEventsHandler creates a new auth events handler
+func NewAuthEventsHandler(permissions *middleware.PermissionCache) *AuthEventsHandler {
+	return &AuthEventsHandler{permissions: permissions}
+}
+
+// RoleChanged drops cached permissions affected by a role change
+// POST /internal/auth/role-changed
+func (h *AuthEventsHandler) RoleChanged(c *gin.Context) {
+	var event models.RoleChangeEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	if event.UserID != "" {
+		h.permissions.InvalidateUser(event.UserID)
+	} else {
+		h.permissions.InvalidateAll()
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Permission cache invalidated"))
+}
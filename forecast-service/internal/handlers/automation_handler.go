@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/middleware"
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+)
+
+// AutomationHandler handles automation rule requests
+type AutomationHandler struct {
+	automationService *service.AutomationService
+	securityClient    interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewAutomationHandler creates a new automation handler
+func NewAutomationHandler(
+	automationService *service.AutomationService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *AutomationHandler {
+	return &AutomationHandler{
+		automationService: automationService,
+		securityClient:    securityClient,
+	}
+}
+
+// CreateRule handles automation rule creation
+// POST /automation/rules
+func (h *AutomationHandler) CreateRule(c *gin.Context) {
+	var req models.AutomationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	rule, err := h.automationService.CreateRule(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "CREATE_AUTOMATION_RULE", "automation_rule", "", "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"buildingId": req.BuildingID})
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "CREATE_AUTOMATION_RULE", "automation_rule", rule.ID.Hex(), "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"buildingId": req.BuildingID})
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(rule, "Automation rule created successfully"))
+}
+
+// ListRules handles automation rule listing
+// GET /automation/rules?buildingId=
+func (h *AutomationHandler) ListRules(c *gin.Context) {
+	rules, err := h.automationService.ListRules(c.Request.Context(), c.Query("buildingId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(rules, ""))
+}
+
+// GetRule handles automation rule retrieval
+// GET /automation/rules/:ruleId
+func (h *AutomationHandler) GetRule(c *gin.Context) {
+	rule, err := h.automationService.GetRule(c.Request.Context(), c.Param("ruleId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(rule, ""))
+}
+
+// UpdateRule handles replacing an automation rule definition
+// PUT /automation/rules/:ruleId
+func (h *AutomationHandler) UpdateRule(c *gin.Context) {
+	ruleID := c.Param("ruleId")
+
+	var req models.AutomationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	rule, err := h.automationService.UpdateRule(c.Request.Context(), ruleID, &req)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_AUTOMATION_RULE", "automation_rule", ruleID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_AUTOMATION_RULE", "automation_rule", ruleID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(rule, "Automation rule updated successfully"))
+}
+
+// SetRuleEnabled handles enabling or disabling an automation rule
+// PATCH /automation/rules/:ruleId/enabled
+func (h *AutomationHandler) SetRuleEnabled(c *gin.Context) {
+	ruleID := c.Param("ruleId")
+
+	var req models.AutomationRuleToggleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	action := "DISABLE_AUTOMATION_RULE"
+	if *req.Enabled {
+		action = "ENABLE_AUTOMATION_RULE"
+	}
+
+	rule, err := h.automationService.SetRuleEnabled(c.Request.Context(), ruleID, *req.Enabled)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", action, "automation_rule", ruleID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", action, "automation_rule", ruleID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(rule, "Automation rule updated successfully"))
+}
+
+// DeleteRule handles automation rule deletion
+// DELETE /automation/rules/:ruleId
+func (h *AutomationHandler) DeleteRule(c *gin.Context) {
+	ruleID := c.Param("ruleId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.automationService.DeleteRule(c.Request.Context(), ruleID); err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_AUTOMATION_RULE", "automation_rule", ruleID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_AUTOMATION_RULE", "automation_rule", ruleID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Automation rule deleted successfully"))
+}
+
+// EvaluateRule handles evaluating a rule immediately, generating a scenario if it matches
+// POST /automation/rules/:ruleId/evaluate
+func (h *AutomationHandler) EvaluateRule(c *gin.Context) {
+	ruleID := c.Param("ruleId")
+	userID := middleware.GetUserID(c)
+	token := middleware.GetToken(c)
+
+	evaluation, err := h.automationService.EvaluateRule(c.Request.Context(), ruleID, userID, token)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	if evaluation.Triggered {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "TRIGGER_AUTOMATION_RULE", "automation_rule", ruleID, "SUCCESS", "", middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method, map[string]interface{}{"scenarioId": evaluation.ScenarioID})
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(evaluation, ""))
+}
+
+// respondError maps automation service errors to HTTP responses
+func (h *AutomationHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "automation rule not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case err.Error() == "invalid automation rule ID format":
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeInvalidRequest, err.Error(), ""))
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/middleware"
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+)
+
+// BuildingHandler handles building registry requests
+type BuildingHandler struct {
+	buildingService *service.BuildingService
+	securityClient  interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewBuildingHandler creates a new building handler
+func NewBuildingHandler(
+	buildingService *service.BuildingService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *BuildingHandler {
+	return &BuildingHandler{
+		buildingService: buildingService,
+		securityClient:  securityClient,
+	}
+}
+
+// ListBuildings handles listing registered buildings
+// GET /buildings
+func (h *BuildingHandler) ListBuildings(c *gin.Context) {
+	buildings, err := h.buildingService.ListBuildings(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(buildings, ""))
+}
+
+// GetBuilding handles building retrieval
+// GET /buildings/:buildingId, GET /internal/buildings/:buildingId
+func (h *BuildingHandler) GetBuilding(c *gin.Context) {
+	building, err := h.buildingService.GetBuilding(c.Request.Context(), c.Param("buildingId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(building, ""))
+}
+
+// SaveBuilding handles registering a building or replacing its entry
+// PUT /buildings/:buildingId
+func (h *BuildingHandler) SaveBuilding(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	var req models.BuildingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.buildingService.SaveBuilding(c.Request.Context(), buildingID, &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_BUILDING", "building", buildingID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_BUILDING", "building", buildingID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"timezone": req.TimeZone})
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Building saved successfully"))
+}
+
+// DeleteBuilding handles removing a building from the registry
+// DELETE /buildings/:buildingId
+func (h *BuildingHandler) DeleteBuilding(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.buildingService.DeleteBuilding(c.Request.Context(), buildingID); err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_BUILDING", "building", buildingID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_BUILDING", "building", buildingID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Building deleted successfully"))
+}
+
+// GetDemandContract handles demand contract retrieval
+// GET /buildings/:buildingId/demand-contract
+func (h *BuildingHandler) GetDemandContract(c *gin.Context) {
+	contract, err := h.buildingService.GetDemandContract(c.Request.Context(), c.Param("buildingId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(contract, ""))
+}
+
+// SaveDemandContract handles setting the demand contract of a building
+// PUT /buildings/:buildingId/demand-contract
+func (h *BuildingHandler) SaveDemandContract(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	var req models.DemandContractRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	contract, err := h.buildingService.SaveDemandContract(c.Request.Context(), buildingID, &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_DEMAND_CONTRACT", "building", buildingID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_DEMAND_CONTRACT", "building", buildingID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{
+		"maxDemandKW":      contract.MaxDemandKW,
+		"penaltyRatePerKW": contract.PenaltyRatePerKW,
+		"windowMinutes":    contract.WindowMinutes,
+	})
+	c.JSON(http.StatusOK, models.NewSuccessResponse(contract, "Demand contract saved successfully"))
+}
+
+// DeleteDemandContract handles removing the demand contract of a building
+// DELETE /buildings/:buildingId/demand-contract
+func (h *BuildingHandler) DeleteDemandContract(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.buildingService.DeleteDemandContract(c.Request.Context(), buildingID); err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_DEMAND_CONTRACT", "building", buildingID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_DEMAND_CONTRACT", "building", buildingID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Demand contract deleted successfully"))
+}
+
+// respondError maps building service errors to HTTP responses
+func (h *BuildingHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "building not found", err.Error() == "demand contract not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
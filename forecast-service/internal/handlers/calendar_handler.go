@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/middleware"
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+)
+
+// CalendarHandler handles business calendar requests
+type CalendarHandler struct {
+	calendarService *service.CalendarService
+	securityClient  interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewCalendarHandler creates a new business calendar handler
+func NewCalendarHandler(
+	calendarService *service.CalendarService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: calendarService,
+		securityClient:  securityClient,
+	}
+}
+
+// CreateDay handles adding a holiday, half-day or special event to the business calendar
+// POST /calendar/days
+func (h *CalendarHandler) CreateDay(c *gin.Context) {
+	var req models.CalendarDayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"buildingId": req.BuildingID, "region": req.Region, "date": req.Date, "type": req.Type}
+
+	response, err := h.calendarService.CreateDay(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "CREATE_CALENDAR_DAY", "calendar_day", "", "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "CREATE_CALENDAR_DAY", "calendar_day", response.ID.Hex(), "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Calendar day created successfully"))
+}
+
+// ListDays handles listing business calendar entries
+// GET /calendar/days?buildingId=&region=&from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *CalendarHandler) ListDays(c *gin.Context) {
+	var req models.ListCalendarDaysRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	days, err := h.calendarService.ListDays(c.Request.Context(), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"days":  days,
+		"total": len(days),
+	}, ""))
+}
+
+// GetDay handles business calendar entry retrieval
+// GET /calendar/days/:dayId
+func (h *CalendarHandler) GetDay(c *gin.Context) {
+	response, err := h.calendarService.GetDay(c.Request.Context(), c.Param("dayId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// UpdateDay handles replacing a business calendar entry
+// PUT /calendar/days/:dayId
+func (h *CalendarHandler) UpdateDay(c *gin.Context) {
+	dayID := c.Param("dayId")
+
+	var req models.CalendarDayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.calendarService.UpdateDay(c.Request.Context(), dayID, &req)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_CALENDAR_DAY", "calendar_day", dayID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_CALENDAR_DAY", "calendar_day", dayID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"date": response.Date, "type": response.Type})
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Calendar day updated successfully"))
+}
+
+// DeleteDay handles business calendar entry deletion
+// DELETE /calendar/days/:dayId
+func (h *CalendarHandler) DeleteDay(c *gin.Context) {
+	dayID := c.Param("dayId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.calendarService.DeleteDay(c.Request.Context(), dayID); err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_CALENDAR_DAY", "calendar_day", dayID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_CALENDAR_DAY", "calendar_day", dayID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Calendar day deleted successfully"))
+}
+
+// respondError maps business calendar service errors to HTTP responses
+func (h *CalendarHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "calendar day not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case err.Error() == "invalid calendar day ID format", strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	case strings.Contains(err.Error(), "already exists"):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(models.ErrCodeConflict, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/middleware"
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+)
+
+// DemandChargeHandler handles demand charge forecast requests
+type DemandChargeHandler struct {
+	demandChargeService *service.DemandChargeService
+}
+
+// NewDemandChargeHandler creates a new demand charge handler
+func NewDemandChargeHandler(demandChargeService *service.DemandChargeService) *DemandChargeHandler {
+	return &DemandChargeHandler{demandChargeService: demandChargeService}
+}
+
+// GetDemandChargeForecast handles the billing-period demand charge forecast of a building
+// GET /forecast/demand-charge?buildingId=&region=&scenarioId=
+func (h *DemandChargeHandler) GetDemandChargeForecast(c *gin.Context) {
+	var req models.DemandChargeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	response, err := h.demandChargeService.ForecastDemandCharge(c.Request.Context(), &req, middleware.GetToken(c))
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "validation failed"), err.Error() == "invalid scenario ID format":
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				err.Error(),
+				"",
+			))
+		case strings.HasPrefix(err.Error(), "consumption history unavailable"):
+			c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+				models.ErrCodeExternalAPIError,
+				"Failed to retrieve consumption history",
+				err.Error(),
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to forecast demand charge",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
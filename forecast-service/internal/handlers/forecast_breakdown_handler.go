@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/middleware"
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+)
+
+// ForecastBreakdownHandler handles forecast drill-down requests
+type ForecastBreakdownHandler struct {
+	breakdownService *service.ForecastBreakdownService
+}
+
+// NewForecastBreakdownHandler creates a new forecast breakdown handler
+func NewForecastBreakdownHandler(breakdownService *service.ForecastBreakdownService) *ForecastBreakdownHandler {
+	return &ForecastBreakdownHandler{breakdownService: breakdownService}
+}
+
+// GetForecastBreakdown decomposes a building forecast into the contributions of its devices
+// GET /forecast/:id/breakdown
+func (h *ForecastBreakdownHandler) GetForecastBreakdown(c *gin.Context) {
+	response, err := h.breakdownService.GetBreakdown(c.Request.Context(), c.Param("id"), middleware.GetToken(c))
+	if err != nil {
+		switch {
+		case err.Error() == "invalid forecast ID format", strings.HasPrefix(err.Error(), "validation failed"):
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		case err.Error() == "forecast not found":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to build forecast breakdown",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
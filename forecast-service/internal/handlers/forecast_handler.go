@@ -3,6 +3,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -29,27 +30,33 @@ func NewForecastHandler(forecastService *service.ForecastService, securityClient
 	}
 }
 
-// GenerateForecast handles forecast generation
+// GenerateForecast queues forecast generation and responds with the forecast in PROCESSING state
 // POST /forecast/generate
 func (h *ForecastHandler) GenerateForecast(c *gin.Context) {
 	var req models.ForecastGenerateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
 	userID := middleware.GetUserID(c)
-	token := middleware.GetToken(c)
 	ipAddress := middleware.GetClientIP(c)
 	userAgent := middleware.GetUserAgent(c)
 
-	response, err := h.forecastService.GenerateForecast(c.Request.Context(), &req, userID, token)
+	response, err := h.forecastService.SubmitForecast(c.Request.Context(), &req, userID)
 	if err != nil {
 		h.securityClient.AuditLog(c.Request.Context(), userID, "", "GENERATE_FORECAST", "forecast", "", "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"buildingId": req.BuildingID})
+		if strings.HasPrefix(err.Error(), "unknown forecast model") ||
+			strings.HasPrefix(err.Error(), "invalid forecast horizon") ||
+			strings.HasPrefix(err.Error(), "validation failed") ||
+			err.Error() == "long-horizon forecasts are disabled" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			models.ErrCodeForecastFailed,
 			err.Error(),
@@ -59,7 +66,13 @@ func (h *ForecastHandler) GenerateForecast(c *gin.Context) {
 	}
 
 	h.securityClient.AuditLog(c.Request.Context(), userID, "", "GENERATE_FORECAST", "forecast", response.ID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"buildingId": req.BuildingID})
-	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Forecast generated successfully"))
+	c.JSON(http.StatusAccepted, models.NewSuccessResponse(response, "Forecast generation started"))
+}
+
+// ListModels lists the registered forecasting models and their metadata
+// GET /forecast/models
+func (h *ForecastHandler) ListModels(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.forecastService.ListModels(), ""))
 }
 
 // GeneratePeakLoad handles peak load prediction
@@ -67,11 +80,7 @@ func (h *ForecastHandler) GenerateForecast(c *gin.Context) {
 func (h *ForecastHandler) GeneratePeakLoad(c *gin.Context) {
 	var req models.PeakLoadRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -92,11 +101,12 @@ func (h *ForecastHandler) GeneratePeakLoad(c *gin.Context) {
 	}
 
 	h.securityClient.AuditLog(c.Request.Context(), userID, "", "GENERATE_PEAK_LOAD", "peak_load", response.ID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"buildingId": req.BuildingID})
+	response.Localize(middleware.GetLanguage(c))
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Peak load prediction generated successfully"))
 }
 
-// GetLatestForecast retrieves the latest forecast for a building
-// GET /forecast/latest?buildingId=
+// GetLatestForecast retrieves the latest forecast for a building, regenerating it when stale
+// GET /forecast/latest?buildingId=&type=&refresh=
 func (h *ForecastHandler) GetLatestForecast(c *gin.Context) {
 	buildingID := c.Query("buildingId")
 	if buildingID == "" {
@@ -113,21 +123,103 @@ func (h *ForecastHandler) GetLatestForecast(c *gin.Context) {
 		forecastType = models.ForecastTypeDemand
 	}
 
-	response, err := h.forecastService.GetLatestForecast(c.Request.Context(), buildingID, forecastType)
+	forceRefresh := c.Query("refresh") == "true"
+	userID := middleware.GetUserID(c)
+	token := middleware.GetToken(c)
+
+	response, err := h.forecastService.GetLatestForecast(c.Request.Context(), buildingID, forecastType, forceRefresh, userID, token)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.NewErrorResponse(
-			models.ErrCodeNotFound,
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve forecast",
 			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetStoredForecast handles internal retrieval of the latest stored forecast for background jobs
+// of other services, which have no user token to generate one with
+// GET /internal/forecast/latest
+func (h *ForecastHandler) GetStoredForecast(c *gin.Context) {
+	buildingID := c.Query("buildingId")
+	if buildingID == "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			"buildingId query parameter is required",
 			"",
 		))
 		return
 	}
 
+	forecastType := models.ForecastType(c.DefaultQuery("type", string(models.ForecastTypeDemand)))
+
+	response, err := h.forecastService.GetStoredForecast(c.Request.Context(), buildingID, forecastType)
+	if err != nil {
+		if err.Error() == "no forecasts found for this building" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve forecast",
+			err.Error(),
+		))
+		return
+	}
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
 }
 
+// GetStoredForecasts handles internal retrieval of the latest stored forecasts of several
+// buildings at once. Buildings without forecasts are left out of the response.
+// POST /internal/forecast/latest/batch
+func (h *ForecastHandler) GetStoredForecasts(c *gin.Context) {
+	var req models.StoredForecastsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+	if req.Type == "" {
+		req.Type = models.ForecastTypeDemand
+	}
+
+	response, err := h.forecastService.GetStoredForecasts(c.Request.Context(), req.BuildingIDs, req.Type)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve forecasts",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// InvalidateCache marks the cached forecasts of a building as stale
+// POST /forecast/invalidate
+func (h *ForecastHandler) InvalidateCache(c *gin.Context) {
+	var req models.ForecastInvalidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	h.forecastService.InvalidateCache(req.BuildingID)
+
+	h.securityClient.AuditLog(c.Request.Context(), middleware.GetUserID(c), "", "INVALIDATE_FORECAST_CACHE", "forecast", req.BuildingID, "SUCCESS", "", middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Forecast cache invalidated"))
+}
+
 // GetForecastByID retrieves a forecast by ID
-// GET /forecast/:id
+// GET /forecast/:id?fields=
 func (h *ForecastHandler) GetForecastByID(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -139,7 +231,17 @@ func (h *ForecastHandler) GetForecastByID(c *gin.Context) {
 		return
 	}
 
-	response, err := h.forecastService.GetForecastByID(c.Request.Context(), id)
+	fields, err := models.ParseFieldSelection(c.Query("fields"), models.Forecast{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	response, err := h.forecastService.GetForecastByID(c.Request.Context(), id, fields)
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.NewErrorResponse(
 			models.ErrCodeNotFound,
@@ -149,6 +251,80 @@ func (h *ForecastHandler) GetForecastByID(c *gin.Context) {
 		return
 	}
 
+	data, err := fields.Apply(response)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(data, ""))
+}
+
+// GetForecastStatus reports the generation status of a forecast
+// GET /forecast/:id/status
+func (h *ForecastHandler) GetForecastStatus(c *gin.Context) {
+	response, err := h.forecastService.GetForecastStatus(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if err.Error() == "invalid forecast ID format" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		if err.Error() == "forecast not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve forecast status",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetForecastWithActuals retrieves a forecast with actual consumption next to its past predictions
+// GET /forecast/:id/with-actuals
+func (h *ForecastHandler) GetForecastWithActuals(c *gin.Context) {
+	response, err := h.forecastService.GetForecastWithActuals(c.Request.Context(), c.Param("id"), middleware.GetToken(c))
+	if err != nil {
+		if err.Error() == "invalid forecast ID format" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		if err.Error() == "forecast not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve forecast actuals",
+			err.Error(),
+		))
+		return
+	}
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
 }
 
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/middleware"
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+)
+
+// maxCalendarImportSize limits the size of uploaded iCalendar documents
+const maxCalendarImportSize = 1 << 20
+
+// OccupancyHandler handles building occupancy schedule requests
+type OccupancyHandler struct {
+	occupancyService *service.OccupancyService
+	securityClient   interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewOccupancyHandler creates a new occupancy handler
+func NewOccupancyHandler(
+	occupancyService *service.OccupancyService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *OccupancyHandler {
+	return &OccupancyHandler{
+		occupancyService: occupancyService,
+		securityClient:   securityClient,
+	}
+}
+
+// GetSchedule handles occupancy schedule retrieval (default business hours if none is stored)
+// GET /occupancy/:buildingId
+func (h *OccupancyHandler) GetSchedule(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	schedule := h.occupancyService.GetSchedule(c.Request.Context(), buildingID)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(schedule.ToResponse(), ""))
+}
+
+// SaveSchedule handles creating or replacing an occupancy schedule
+// PUT /occupancy/:buildingId
+func (h *OccupancyHandler) SaveSchedule(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	var req models.OccupancyScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.occupancyService.SaveSchedule(c.Request.Context(), buildingID, &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_OCCUPANCY_SCHEDULE", "occupancy_schedule", buildingID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_OCCUPANCY_SCHEDULE", "occupancy_schedule", buildingID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Occupancy schedule saved successfully"))
+}
+
+// DeleteSchedule handles occupancy schedule deletion
+// DELETE /occupancy/:buildingId
+func (h *OccupancyHandler) DeleteSchedule(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.occupancyService.DeleteSchedule(c.Request.Context(), buildingID); err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_OCCUPANCY_SCHEDULE", "occupancy_schedule", buildingID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_OCCUPANCY_SCHEDULE", "occupancy_schedule", buildingID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Occupancy schedule deleted successfully"))
+}
+
+// ImportHolidays handles importing holidays from an iCalendar (.ics) document
+// POST /occupancy/:buildingId/holidays/import
+func (h *OccupancyHandler) ImportHolidays(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCalendarImportSize))
+	if err != nil || len(body) == 0 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeInvalidRequest,
+			"Request body must contain an iCalendar document",
+			"",
+		))
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	result, err := h.occupancyService.ImportHolidays(c.Request.Context(), buildingID, string(body), userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "IMPORT_HOLIDAYS", "occupancy_schedule", buildingID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "IMPORT_HOLIDAYS", "occupancy_schedule", buildingID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"imported": result.Imported, "skipped": result.Skipped})
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result, "Holidays imported successfully"))
+}
+
+// GetOccupancyStatus handles resolving occupancy at a point in time
+// GET /occupancy/:buildingId/status?at=RFC3339
+func (h *OccupancyHandler) GetOccupancyStatus(c *gin.Context) {
+	buildingID := c.Param("buildingId")
+	token := middleware.GetToken(c)
+
+	at := time.Now()
+	if atStr := c.Query("at"); atStr != "" {
+		parsed, err := time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				"Invalid 'at' parameter, expected RFC3339 timestamp",
+				err.Error(),
+			))
+			return
+		}
+		at = parsed
+	}
+
+	status := h.occupancyService.GetOccupancyStatus(c.Request.Context(), buildingID, at, token)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(status, ""))
+}
+
+// respondError maps occupancy service errors to HTTP responses
+func (h *OccupancyHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "occupancy schedule not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
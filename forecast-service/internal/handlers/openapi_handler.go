@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/cache"
+	"forecast-service/internal/models"
+	"forecast-service/internal/openapi"
+	"forecast-service/internal/settings"
+	"forecast-service/internal/validation"
+)
+
+// operations describes the query parameters, request bodies and response data of the handlers
+// for the OpenAPI document. Routes of other handlers are listed without schemas.
+var operations = map[string]openapi.Operation{
+	"AuthEventsHandler.RoleChanged":                     {Body: models.RoleChangeEvent{}},
+	"AutomationHandler.CreateRule":                      {Body: models.AutomationRuleRequest{}, Response: models.AutomationRule{}},
+	"AutomationHandler.ListRules":                       {Response: []*models.AutomationRule{}},
+	"AutomationHandler.GetRule":                         {Response: models.AutomationRule{}},
+	"AutomationHandler.UpdateRule":                      {Body: models.AutomationRuleRequest{}, Response: models.AutomationRule{}},
+	"AutomationHandler.SetRuleEnabled":                  {Body: models.AutomationRuleToggleRequest{}, Response: models.AutomationRule{}},
+	"AutomationHandler.EvaluateRule":                    {Response: models.AutomationEvaluation{}},
+	"BuildingHandler.ListBuildings":                     {Response: []*models.BuildingResponse{}},
+	"BuildingHandler.GetBuilding":                       {Response: models.BuildingResponse{}},
+	"BuildingHandler.SaveBuilding":                      {Body: models.BuildingRequest{}, Response: models.BuildingResponse{}},
+	"BuildingHandler.GetDemandContract":                 {Response: models.DemandContract{}},
+	"BuildingHandler.SaveDemandContract":                {Body: models.DemandContractRequest{}, Response: models.DemandContract{}},
+	"CalendarHandler.CreateDay":                         {Body: models.CalendarDayRequest{}, Response: models.CalendarDay{}},
+	"CalendarHandler.ListDays":                          {Query: models.ListCalendarDaysRequest{}},
+	"CalendarHandler.GetDay":                            {Response: models.CalendarDay{}},
+	"CalendarHandler.UpdateDay":                         {Body: models.CalendarDayRequest{}, Response: models.CalendarDay{}},
+	"DemandChargeHandler.GetDemandChargeForecast":       {Query: models.DemandChargeRequest{}, Response: models.DemandChargeForecast{}},
+	"ForecastBreakdownHandler.GetForecastBreakdown":     {Response: models.ForecastBreakdown{}},
+	"ForecastHandler.GenerateForecast":                  {Body: models.ForecastGenerateRequest{}, Response: models.ForecastResponse{}},
+	"ForecastHandler.GeneratePeakLoad":                  {Body: models.PeakLoadRequest{}, Response: models.PeakLoadResponse{}},
+	"ForecastHandler.GetLatestForecast":                 {Response: models.ForecastResponse{}},
+	"ForecastHandler.GetStoredForecast":                 {Response: models.ForecastResponse{}},
+	"ForecastHandler.GetStoredForecasts":                {Body: models.StoredForecastsRequest{}, Response: []*models.ForecastResponse{}},
+	"ForecastHandler.InvalidateCache":                   {Body: models.ForecastInvalidateRequest{}},
+	"ForecastHandler.GetForecastStatus":                 {Response: models.ForecastStatusResponse{}},
+	"ForecastHandler.GetForecastWithActuals":            {Response: models.ForecastWithActualsResponse{}},
+	"ForecastHandler.GetDevicePrediction":               {Response: models.DevicePrediction{}},
+	"HealthHandler.GetCacheStats":                       {Response: cache.Stats{}},
+	"OccupancyHandler.SaveSchedule":                     {Body: models.OccupancyScheduleRequest{}, Response: models.OccupancyScheduleResponse{}},
+	"OccupancyHandler.ImportHolidays":                   {Response: models.HolidayImportResult{}},
+	"OptimizationFeedbackHandler.ListCorrectionFactors": {Query: models.CorrectionFactorQuery{}},
+	"OptimizationHandler.GenerateOptimization":          {Body: models.OptimizationGenerateRequest{}, Response: models.OptimizationScenarioResponse{}},
+	"OptimizationHandler.GeneratePortfolio":             {Body: models.PortfolioGenerateRequest{}, Response: models.OptimizationScenarioResponse{}},
+	"OptimizationHandler.SearchScenarios":               {Query: models.SearchScenariosRequest{}, Response: models.SearchResponse{}},
+	"OptimizationHandler.GetRecommendations":            {Response: models.RecommendationsResponse{}},
+	"OptimizationHandler.ReplayOptimization":            {Body: models.OptimizationReplayRequest{}, Response: models.OptimizationReplay{}},
+	"OptimizationHandler.ImportScenario":                {Body: models.ScenarioImportRequest{}, Response: models.OptimizationScenarioResponse{}},
+	"OptimizationHandler.SendToIoT":                     {Body: models.SendToIoTRequest{}, Response: models.SendToIoTResponse{}},
+	"OptimizationHandler.GetScenarioConflicts":          {Response: models.ScenarioConflictsResponse{}},
+	"OptimizationHandler.GetDeviceOptimization":         {Response: models.DeviceOptimization{}},
+	"ScenarioTemplateHandler.CreateTemplate":            {Body: models.ScenarioTemplateRequest{}, Response: models.ScenarioTemplate{}},
+	"ScenarioTemplateHandler.ListTemplates":             {Query: models.ListScenarioTemplatesRequest{}, Response: []*models.ScenarioTemplate{}},
+	"ScenarioTemplateHandler.GetTemplate":               {Response: models.ScenarioTemplate{}},
+	"ScenarioTemplateHandler.UpdateTemplate":            {Body: models.ScenarioTemplateRequest{}, Response: models.ScenarioTemplate{}},
+	"ScenarioTemplateHandler.ApplyTemplate":             {Body: models.ApplyScenarioTemplateRequest{}, Response: models.OptimizationScenarioResponse{}},
+	"TariffHandler.CreateTariff":                        {Body: models.TariffCreateRequest{}, Response: models.TariffScheduleResponse{}},
+	"TariffHandler.GetCurrentTariff":                    {Response: models.Tariff{}},
+	"TariffHandler.GetTariff":                           {Response: models.TariffScheduleResponse{}},
+	"TariffHandler.UpdateTariff":                        {Body: models.TariffUpdateRequest{}, Response: models.TariffScheduleResponse{}},
+	"WeatherHandler.GetDegreeDays":                      {Query: models.DegreeDaysRequest{}, Response: models.DegreeDays{}},
+	"WeatherHandler.GetWeatherForecast":                 {Query: models.WeatherForecastRequest{}},
+	"SettingsHandler.UpdateSetting":                     {Body: settings.UpdateRequest{}},
+}
+
+// OpenAPI serves the OpenAPI 3 document of the service. It is built on the first request, once
+// every route is registered on the engine.
+// GET /openapi.json
+func OpenAPI(engine *gin.Engine) gin.HandlerFunc {
+	var once sync.Once
+	var document openapi.Document
+	return func(c *gin.Context) {
+		once.Do(func() {
+			document = openapi.Build(openapi.Info{
+				Title:       "Forecast Service API",
+				Version:     "1.0.0",
+				Description: "Energy demand forecasting, tariffs and optimization scenarios",
+			}, engine.Routes(), operations)
+		})
+		c.JSON(http.StatusOK, document)
+	}
+}
+
+// respondValidationError responds to a request that failed binding, listing the fields that failed
+func respondValidationError(c *gin.Context, message string, err error) {
+	c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(message, err.Error(), validation.FieldErrors(err)))
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+)
+
+// OptimizationFeedbackHandler handles requests for the correction factors learned from verified savings
+type OptimizationFeedbackHandler struct {
+	feedbackService *service.OptimizationFeedbackService
+}
+
+// NewOptimizationFeedbackHandler creates a new optimization feedback handler
+func NewOptimizationFeedbackHandler(feedbackService *service.OptimizationFeedbackService) *OptimizationFeedbackHandler {
+	return &OptimizationFeedbackHandler{feedbackService: feedbackService}
+}
+
+// ListCorrectionFactors lists the correction factors applied to expected savings
+// GET /optimization/correction-factors
+func (h *OptimizationFeedbackHandler) ListCorrectionFactors(c *gin.Context) {
+	var query models.CorrectionFactorQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	factors, err := h.feedbackService.ListFactors(c.Request.Context(), &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to list correction factors",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"factors": factors,
+		"total":   len(factors),
+	}, ""))
+}
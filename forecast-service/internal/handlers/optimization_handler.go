@@ -2,9 +2,16 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 
 	"forecast-service/internal/middleware"
 	"forecast-service/internal/models"
@@ -16,6 +23,7 @@ type OptimizationHandler struct {
 	optimizationService *service.OptimizationService
 	securityClient      interface {
 		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+		CheckPermission(ctx context.Context, userID, resource, action string) (bool, error)
 	}
 }
 
@@ -24,6 +32,7 @@ func NewOptimizationHandler(
 	optimizationService *service.OptimizationService,
 	securityClient interface {
 		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+		CheckPermission(ctx context.Context, userID, resource, action string) (bool, error)
 	},
 ) *OptimizationHandler {
 	return &OptimizationHandler{
@@ -37,11 +46,7 @@ func NewOptimizationHandler(
 func (h *OptimizationHandler) GenerateOptimization(c *gin.Context) {
 	var req models.OptimizationGenerateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -53,6 +58,23 @@ func (h *OptimizationHandler) GenerateOptimization(c *gin.Context) {
 	response, err := h.optimizationService.GenerateOptimization(c.Request.Context(), &req, userID, token)
 	if err != nil {
 		h.securityClient.AuditLog(c.Request.Context(), userID, "", "GENERATE_OPTIMIZATION", "optimization", "", "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"buildingId": req.BuildingID})
+		var capabilityErr *service.CapabilityError
+		if errors.As(err, &capabilityErr) {
+			c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(
+				"Scenario actions exceed device capabilities",
+				err.Error(),
+				capabilityErr.FieldErrors(),
+			))
+			return
+		}
+		if err.Error() == "scenario template not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+			return
+		}
+		if strings.HasPrefix(err.Error(), "validation failed") || err.Error() == "invalid scenario template ID format" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			models.ErrCodeOptimizationFailed,
 			err.Error(),
@@ -65,6 +87,84 @@ func (h *OptimizationHandler) GenerateOptimization(c *gin.Context) {
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Optimization scenario generated successfully"))
 }
 
+// GeneratePortfolio handles generation of a portfolio scenario spanning several buildings
+// POST /optimization/portfolio/generate
+func (h *OptimizationHandler) GeneratePortfolio(c *gin.Context) {
+	var req models.PortfolioGenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	token := middleware.GetToken(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.optimizationService.GeneratePortfolio(c.Request.Context(), &req, userID, token)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "GENERATE_PORTFOLIO_OPTIMIZATION", "optimization", "", "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"buildingIds": req.BuildingIDs})
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeOptimizationFailed,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "GENERATE_PORTFOLIO_OPTIMIZATION", "optimization", response.ID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"buildingIds": req.BuildingIDs})
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Portfolio scenario generated successfully"))
+}
+
+// SearchScenarios handles searching optimization scenarios by free text and filters with cursor
+// pagination. Callers without read access to energy data receive no results.
+// GET /optimization/search
+func (h *OptimizationHandler) SearchScenarios(c *gin.Context) {
+	var req models.SearchScenariosRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	allowed := middleware.HasRole(c, "admin")
+	if !allowed {
+		var err error
+		allowed, err = h.securityClient.CheckPermission(c.Request.Context(), middleware.GetUserID(c), "energy", "read")
+		if err != nil {
+			c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+				models.ErrCodeExternalAPIError,
+				"Failed to check permissions",
+				err.Error(),
+			))
+			return
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(&models.SearchResponse{Results: []*models.SearchResult{}}, ""))
+		return
+	}
+
+	response, err := h.optimizationService.SearchScenarios(c.Request.Context(), &req)
+	if err != nil {
+		if err.Error() == "invalid cursor" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				"Invalid cursor",
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
 // GetRecommendations retrieves energy-saving recommendations for a building
 // GET /optimization/recommendations/:buildingId
 func (h *OptimizationHandler) GetRecommendations(c *gin.Context) {
@@ -89,11 +189,67 @@ func (h *OptimizationHandler) GetRecommendations(c *gin.Context) {
 		return
 	}
 
+	response.Localize(middleware.GetLanguage(c))
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
 }
 
+// ListScenarios lists optimization scenarios for a building
+// GET /optimization/scenarios?fields=
+func (h *OptimizationHandler) ListScenarios(c *gin.Context) {
+	buildingID := c.Query("buildingId")
+	if buildingID == "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			"Building ID is required",
+			"",
+		))
+		return
+	}
+
+	status := models.OptimizationStatus(c.Query("status"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	fields, err := models.ParseFieldSelection(c.Query("fields"), models.OptimizationScenario{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	responses, total, err := h.optimizationService.ListScenarios(c.Request.Context(), buildingID, status, page, limit, fields)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	scenarios, err := fields.Apply(responses)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"scenarios": scenarios,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	}, ""))
+}
+
 // GetScenario retrieves an optimization scenario by ID
-// GET /optimization/scenario/:scenarioId
+// GET /optimization/scenario/:scenarioId?fields=
 func (h *OptimizationHandler) GetScenario(c *gin.Context) {
 	scenarioID := c.Param("scenarioId")
 	if scenarioID == "" {
@@ -105,7 +261,17 @@ func (h *OptimizationHandler) GetScenario(c *gin.Context) {
 		return
 	}
 
-	response, err := h.optimizationService.GetScenario(c.Request.Context(), scenarioID)
+	fields, err := models.ParseFieldSelection(c.Query("fields"), models.OptimizationScenario{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	response, err := h.optimizationService.GetScenario(c.Request.Context(), scenarioID, fields)
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.NewErrorResponse(
 			models.ErrCodeNotFound,
@@ -115,7 +281,120 @@ func (h *OptimizationHandler) GetScenario(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+	data, err := fields.Apply(response)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(data, ""))
+}
+
+// ExportScenario downloads a scenario in the portable format used to reuse it in other buildings
+// GET /optimization/scenario/:scenarioId/export?format=json|yaml
+func (h *OptimizationHandler) ExportScenario(c *gin.Context) {
+	scenarioID := c.Param("scenarioId")
+
+	format := strings.ToLower(c.DefaultQuery("format", models.ScenarioExportFormatJSON))
+	if format != models.ScenarioExportFormatJSON && format != models.ScenarioExportFormatYAML {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			"Invalid 'format' parameter",
+			"Supported formats: json, yaml",
+		))
+		return
+	}
+
+	export, err := h.optimizationService.ExportScenario(c.Request.Context(), scenarioID)
+	if err != nil {
+		h.respondTransferError(c, err)
+		return
+	}
+
+	// Round-trip through JSON so YAML documents use the same field names
+	body, err := json.Marshal(export)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+		return
+	}
+
+	filename := fmt.Sprintf("scenario-%s.%s", scenarioID, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == models.ScenarioExportFormatJSON {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+		return
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+		return
+	}
+	c.YAML(http.StatusOK, document)
+}
+
+// ImportScenario creates a draft scenario in a building from an exported scenario. The body is
+// JSON, or YAML when sent with a YAML content type.
+// POST /optimization/import
+func (h *OptimizationHandler) ImportScenario(c *gin.Context) {
+	var req models.ScenarioImportRequest
+	if err := bindScenarioImport(c, &req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	token := middleware.GetToken(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"buildingId": req.BuildingID, "sourceBuildingId": req.Scenario.SourceBuildingID}
+
+	response, err := h.optimizationService.ImportScenario(c.Request.Context(), &req, userID, token)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "IMPORT_OPTIMIZATION", "optimization", "", "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details)
+		h.respondTransferError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "IMPORT_OPTIMIZATION", "optimization", response.ID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Optimization scenario imported successfully"))
+}
+
+// bindScenarioImport binds an import request from JSON, or from YAML converted to JSON so both
+// formats share the JSON field names
+func bindScenarioImport(c *gin.Context, req *models.ScenarioImportRequest) error {
+	if !strings.Contains(c.ContentType(), "yaml") {
+		return c.ShouldBindJSON(req)
+	}
+
+	var document map[string]interface{}
+	if err := c.ShouldBindYAML(&document); err != nil {
+		return err
+	}
+	body, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, req); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(req)
+}
+
+// respondTransferError maps scenario export and import errors to HTTP responses
+func (h *OptimizationHandler) respondTransferError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "optimization scenario not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case err.Error() == "invalid scenario ID format", strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
 }
 
 // SendToIoT sends an optimization scenario to IoT service
@@ -123,10 +402,15 @@ func (h *OptimizationHandler) GetScenario(c *gin.Context) {
 func (h *OptimizationHandler) SendToIoT(c *gin.Context) {
 	var req models.SendToIoTRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	if req.ConflictResolution != "" && !req.ConflictResolution.IsValid() {
 		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
 			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
+			"Invalid conflict resolution",
+			"conflictResolution must be one of REJECT, PRIORITY, MERGE",
 		))
 		return
 	}
@@ -147,10 +431,96 @@ func (h *OptimizationHandler) SendToIoT(c *gin.Context) {
 		return
 	}
 
+	// Unresolved conflicts block approval; the conflicting scenarios are returned for review
+	if !response.Success && len(response.Conflicts) > 0 {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "SEND_TO_IOT", "optimization", req.ScenarioID, "FAILURE", "scenario conflicts", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"conflicts": len(response.Conflicts)})
+		errResponse := models.NewErrorResponse(
+			models.ErrCodeConflict,
+			"Scenario conflicts with approved or executing scenarios",
+			"Resolve the conflicts or retry with conflictResolution PRIORITY or MERGE",
+		)
+		errResponse.Data = response
+		c.JSON(http.StatusConflict, errResponse)
+		return
+	}
+
 	h.securityClient.AuditLog(c.Request.Context(), userID, "", "SEND_TO_IOT", "optimization", req.ScenarioID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Scenario sent to IoT service successfully"))
 }
 
+// ReplayOptimization simulates an optimization strategy over a past period and compares it with
+// what actually happened
+// POST /optimization/replay
+func (h *OptimizationHandler) ReplayOptimization(c *gin.Context) {
+	var req models.OptimizationReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	response, err := h.optimizationService.ReplayOptimization(c.Request.Context(), &req, middleware.GetToken(c))
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		case strings.HasPrefix(err.Error(), "consumption history unavailable"):
+			c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+				models.ErrCodeExternalAPIError,
+				"Failed to retrieve consumption history",
+				err.Error(),
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeOptimizationFailed,
+				err.Error(),
+				"",
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetScenarioConflicts lists active scenarios that conflict with a scenario
+// GET /optimization/scenario/:scenarioId/conflicts
+func (h *OptimizationHandler) GetScenarioConflicts(c *gin.Context) {
+	scenarioID := c.Param("scenarioId")
+	if scenarioID == "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			"Scenario ID is required",
+			"",
+		))
+		return
+	}
+
+	response, err := h.optimizationService.GetScenarioConflicts(c.Request.Context(), scenarioID)
+	if err != nil {
+		switch err.Error() {
+		case "optimization scenario not found", "invalid scenario ID format":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeOptimizationFailed,
+				err.Error(),
+				"",
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
 // GetDeviceOptimization retrieves optimization recommendations for a device
 // GET /forecast/optimization/:deviceId
 func (h *OptimizationHandler) GetDeviceOptimization(c *gin.Context) {
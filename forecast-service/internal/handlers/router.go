@@ -3,51 +3,127 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 
+	"forecast-service/internal/config"
 	"forecast-service/internal/middleware"
+	"forecast-service/internal/validation"
 )
 
 // Router holds all handler dependencies
 type Router struct {
-	ForecastHandler      *ForecastHandler
-	OptimizationHandler  *OptimizationHandler
-	AuthMiddleware       *middleware.AuthMiddleware
+	ForecastHandler     *ForecastHandler
+	OptimizationHandler *OptimizationHandler
+	TariffHandler       *TariffHandler
+	OccupancyHandler    *OccupancyHandler
+	CalendarHandler     *CalendarHandler
+	BuildingHandler     *BuildingHandler
+	AutomationHandler   *AutomationHandler
+	WeatherHandler      *WeatherHandler
+	DemandChargeHandler *DemandChargeHandler
+	BreakdownHandler    *ForecastBreakdownHandler
+	FeedbackHandler     *OptimizationFeedbackHandler
+	TemplateHandler     *ScenarioTemplateHandler
+	HealthHandler       *HealthHandler
+	RetentionHandler    *RetentionHandler
+	AuthEventsHandler   *AuthEventsHandler
+	JobHandler          *JobHandler
+	SettingsHandler     *SettingsHandler
+	AuthMiddleware      *middleware.AuthMiddleware
+
+	// UsageMeter meters API calls for billing when set
+	UsageMeter *middleware.UsageMeter
 }
 
 // NewRouter creates a new router with all handlers
 func NewRouter(
 	forecastHandler *ForecastHandler,
 	optimizationHandler *OptimizationHandler,
+	tariffHandler *TariffHandler,
+	occupancyHandler *OccupancyHandler,
+	calendarHandler *CalendarHandler,
+	buildingHandler *BuildingHandler,
+	automationHandler *AutomationHandler,
+	weatherHandler *WeatherHandler,
+	demandChargeHandler *DemandChargeHandler,
+	breakdownHandler *ForecastBreakdownHandler,
+	feedbackHandler *OptimizationFeedbackHandler,
+	templateHandler *ScenarioTemplateHandler,
+	healthHandler *HealthHandler,
+	retentionHandler *RetentionHandler,
+	authEventsHandler *AuthEventsHandler,
+	jobHandler *JobHandler,
+	settingsHandler *SettingsHandler,
 	authMiddleware *middleware.AuthMiddleware,
 ) *Router {
 	return &Router{
 		ForecastHandler:     forecastHandler,
 		OptimizationHandler: optimizationHandler,
+		TariffHandler:       tariffHandler,
+		OccupancyHandler:    occupancyHandler,
+		CalendarHandler:     calendarHandler,
+		BuildingHandler:     buildingHandler,
+		AutomationHandler:   automationHandler,
+		WeatherHandler:      weatherHandler,
+		DemandChargeHandler: demandChargeHandler,
+		BreakdownHandler:    breakdownHandler,
+		FeedbackHandler:     feedbackHandler,
+		TemplateHandler:     templateHandler,
+		HealthHandler:       healthHandler,
+		RetentionHandler:    retentionHandler,
+		AuthEventsHandler:   authEventsHandler,
+		JobHandler:          jobHandler,
+		SettingsHandler:     settingsHandler,
 		AuthMiddleware:      authMiddleware,
 	}
 }
 
-// SetupRoutes configures all API routes
-func (r *Router) SetupRoutes(engine *gin.Engine) {
+// SetupRoutes configures all API routes, applying the given CORS and security header policies
+func (r *Router) SetupRoutes(engine *gin.Engine, policy config.HTTPConfig) {
+	// Register the custom binding rules and report validation errors by JSON field name
+	validation.Register()
+
 	// Apply common middleware
 	engine.Use(middleware.Recovery())
+	engine.Use(middleware.Tracing())
 	engine.Use(middleware.RequestID())
-	engine.Use(middleware.CORS())
-	engine.Use(middleware.SecurityHeaders())
+	engine.Use(middleware.Localization())
+	engine.Use(middleware.CORS(policy.CORS))
+	engine.Use(middleware.SecurityHeaders(policy.Headers))
 	engine.Use(middleware.RequestLogger())
+	if r.UsageMeter != nil {
+		engine.Use(r.UsageMeter.Middleware())
+	}
+
+	// Health check endpoints
+	engine.GET("/health", r.HealthHandler.Health)
+	engine.GET("/health/deep", r.HealthHandler.DeepHealth)
 
-	// Health check endpoint
-	engine.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "healthy",
-			"service": "forecast-service",
-		})
-	})
+	// OpenAPI document of the routes, for generating clients
+	engine.GET("/openapi.json", OpenAPI(engine))
+
+	// Internal notifications from other services
+	internal := engine.Group("/internal")
+	internal.Use(r.AuthMiddleware.RequireServiceKey())
+	{
+		internal.POST("/auth/role-changed", r.AuthEventsHandler.RoleChanged)
+		internal.GET("/forecast/latest", r.ForecastHandler.GetStoredForecast)
+		internal.POST("/forecast/latest/batch", r.ForecastHandler.GetStoredForecasts)
+		internal.GET("/weather/forecast", r.WeatherHandler.GetWeatherForecast)
+		internal.GET("/weather/degree-days", r.WeatherHandler.GetDegreeDays)
+		internal.GET("/buildings/:buildingId", r.BuildingHandler.GetBuilding)
+	}
 
 	// API v1 routes
 	api := engine.Group("/api/v1")
 	{
 		r.setupForecastRoutes(api)
 		r.setupOptimizationRoutes(api)
+		r.setupTariffRoutes(api)
+		r.setupOccupancyRoutes(api)
+		r.setupCalendarRoutes(api)
+		r.setupBuildingRoutes(api)
+		r.setupAutomationRoutes(api)
+		r.setupWeatherRoutes(api)
+		r.setupAdminRoutes(api)
 	}
 
 	// Legacy routes (without /api/v1 prefix for backward compatibility)
@@ -62,7 +138,14 @@ func (r *Router) setupForecastRoutes(rg *gin.RouterGroup) {
 		forecast.POST("/generate", r.ForecastHandler.GenerateForecast)
 		forecast.POST("/peak-load", r.ForecastHandler.GeneratePeakLoad)
 		forecast.GET("/latest", r.ForecastHandler.GetLatestForecast)
+		forecast.POST("/invalidate", r.AuthMiddleware.RequireAdmin(), r.ForecastHandler.InvalidateCache)
+		forecast.GET("/models", r.ForecastHandler.ListModels)
+		forecast.GET("/demand-charge", r.DemandChargeHandler.GetDemandChargeForecast)
 		forecast.GET("/prediction/:deviceId", r.ForecastHandler.GetDevicePrediction)
+		forecast.GET("/:id", r.ForecastHandler.GetForecastByID)
+		forecast.GET("/:id/status", r.ForecastHandler.GetForecastStatus)
+		forecast.GET("/:id/with-actuals", r.ForecastHandler.GetForecastWithActuals)
+		forecast.GET("/:id/breakdown", r.BreakdownHandler.GetForecastBreakdown)
 	}
 
 	// Optimization endpoint for device (used in forecast routes)
@@ -75,9 +158,124 @@ func (r *Router) setupOptimizationRoutes(rg *gin.RouterGroup) {
 	optimization.Use(r.AuthMiddleware.RequireAuth())
 	{
 		optimization.POST("/generate", r.OptimizationHandler.GenerateOptimization)
+		optimization.POST("/portfolio/generate", r.OptimizationHandler.GeneratePortfolio)
+		optimization.POST("/replay", r.OptimizationHandler.ReplayOptimization)
 		optimization.GET("/recommendations/:buildingId", r.OptimizationHandler.GetRecommendations)
+		optimization.GET("/scenarios", r.OptimizationHandler.ListScenarios)
+		optimization.GET("/search", r.OptimizationHandler.SearchScenarios)
+		optimization.GET("/correction-factors", r.FeedbackHandler.ListCorrectionFactors)
 		optimization.GET("/scenario/:scenarioId", r.OptimizationHandler.GetScenario)
+		optimization.GET("/scenario/:scenarioId/conflicts", r.OptimizationHandler.GetScenarioConflicts)
+		optimization.GET("/scenario/:scenarioId/export", r.OptimizationHandler.ExportScenario)
+		optimization.POST("/import", r.OptimizationHandler.ImportScenario)
 		optimization.POST("/send-to-iot", r.OptimizationHandler.SendToIoT)
+		optimization.GET("/templates", r.TemplateHandler.ListTemplates)
+		optimization.GET("/templates/:templateId", r.TemplateHandler.GetTemplate)
+		optimization.POST("/templates/:templateId/apply", r.TemplateHandler.ApplyTemplate)
+		optimization.POST("/templates", r.AuthMiddleware.RequireAdmin(), r.TemplateHandler.CreateTemplate)
+		optimization.PUT("/templates/:templateId", r.AuthMiddleware.RequireAdmin(), r.TemplateHandler.UpdateTemplate)
+		optimization.DELETE("/templates/:templateId", r.AuthMiddleware.RequireAdmin(), r.TemplateHandler.DeleteTemplate)
+	}
+}
+
+// setupTariffRoutes configures local tariff management routes
+func (r *Router) setupTariffRoutes(rg *gin.RouterGroup) {
+	tariffs := rg.Group("/tariffs")
+	tariffs.Use(r.AuthMiddleware.RequireAuth())
+	{
+		tariffs.GET("", r.TariffHandler.ListTariffs)
+		tariffs.GET("/current", r.TariffHandler.GetCurrentTariff)
+		tariffs.GET("/:tariffId", r.TariffHandler.GetTariff)
+		tariffs.POST("", r.AuthMiddleware.RequireAdmin(), r.TariffHandler.CreateTariff)
+		tariffs.PUT("/:tariffId", r.AuthMiddleware.RequireAdmin(), r.TariffHandler.UpdateTariff)
+		tariffs.DELETE("/:tariffId", r.AuthMiddleware.RequireAdmin(), r.TariffHandler.DeleteTariff)
+	}
+}
+
+// setupOccupancyRoutes configures building occupancy schedule routes
+func (r *Router) setupOccupancyRoutes(rg *gin.RouterGroup) {
+	occupancy := rg.Group("/occupancy")
+	occupancy.Use(r.AuthMiddleware.RequireAuth())
+	{
+		occupancy.GET("/:buildingId", r.OccupancyHandler.GetSchedule)
+		occupancy.GET("/:buildingId/status", r.OccupancyHandler.GetOccupancyStatus)
+		occupancy.PUT("/:buildingId", r.AuthMiddleware.RequireAdmin(), r.OccupancyHandler.SaveSchedule)
+		occupancy.DELETE("/:buildingId", r.AuthMiddleware.RequireAdmin(), r.OccupancyHandler.DeleteSchedule)
+		occupancy.POST("/:buildingId/holidays/import", r.AuthMiddleware.RequireAdmin(), r.OccupancyHandler.ImportHolidays)
+	}
+}
+
+// setupBuildingRoutes configures building registry routes
+func (r *Router) setupBuildingRoutes(rg *gin.RouterGroup) {
+	buildings := rg.Group("/buildings")
+	buildings.Use(r.AuthMiddleware.RequireAuth())
+	{
+		buildings.GET("", r.BuildingHandler.ListBuildings)
+		buildings.GET("/:buildingId", r.BuildingHandler.GetBuilding)
+		buildings.PUT("/:buildingId", r.AuthMiddleware.RequireAdmin(), r.BuildingHandler.SaveBuilding)
+		buildings.DELETE("/:buildingId", r.AuthMiddleware.RequireAdmin(), r.BuildingHandler.DeleteBuilding)
+		buildings.GET("/:buildingId/demand-contract", r.BuildingHandler.GetDemandContract)
+		buildings.PUT("/:buildingId/demand-contract", r.AuthMiddleware.RequireAdmin(), r.BuildingHandler.SaveDemandContract)
+		buildings.DELETE("/:buildingId/demand-contract", r.AuthMiddleware.RequireAdmin(), r.BuildingHandler.DeleteDemandContract)
+	}
+}
+
+// setupCalendarRoutes configures business calendar routes
+func (r *Router) setupCalendarRoutes(rg *gin.RouterGroup) {
+	days := rg.Group("/calendar/days")
+	days.Use(r.AuthMiddleware.RequireAuth())
+	{
+		days.GET("", r.CalendarHandler.ListDays)
+		days.GET("/:dayId", r.CalendarHandler.GetDay)
+		days.POST("", r.AuthMiddleware.RequireAdmin(), r.CalendarHandler.CreateDay)
+		days.PUT("/:dayId", r.AuthMiddleware.RequireAdmin(), r.CalendarHandler.UpdateDay)
+		days.DELETE("/:dayId", r.AuthMiddleware.RequireAdmin(), r.CalendarHandler.DeleteDay)
+	}
+}
+
+// setupAutomationRoutes configures pre-conditioning automation rule routes
+func (r *Router) setupAutomationRoutes(rg *gin.RouterGroup) {
+	rules := rg.Group("/automation/rules")
+	rules.Use(r.AuthMiddleware.RequireAuth())
+	{
+		rules.GET("", r.AutomationHandler.ListRules)
+		rules.GET("/:ruleId", r.AutomationHandler.GetRule)
+		rules.POST("", r.AuthMiddleware.RequireAdmin(), r.AutomationHandler.CreateRule)
+		rules.PUT("/:ruleId", r.AuthMiddleware.RequireAdmin(), r.AutomationHandler.UpdateRule)
+		rules.PATCH("/:ruleId/enabled", r.AuthMiddleware.RequireAdmin(), r.AutomationHandler.SetRuleEnabled)
+		rules.DELETE("/:ruleId", r.AuthMiddleware.RequireAdmin(), r.AutomationHandler.DeleteRule)
+		rules.POST("/:ruleId/evaluate", r.AuthMiddleware.RequireAdmin(), r.AutomationHandler.EvaluateRule)
+	}
+}
+
+// setupWeatherRoutes configures weather statistics routes
+func (r *Router) setupWeatherRoutes(rg *gin.RouterGroup) {
+	weather := rg.Group("/weather")
+	weather.Use(r.AuthMiddleware.RequireAuth())
+	{
+		weather.GET("/degree-days", r.WeatherHandler.GetDegreeDays)
+	}
+}
+
+// setupAdminRoutes configures administrative maintenance routes
+func (r *Router) setupAdminRoutes(rg *gin.RouterGroup) {
+	admin := rg.Group("/admin")
+	admin.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		admin.GET("/retention", r.RetentionHandler.GetStatus)
+		admin.POST("/retention/run", r.RetentionHandler.RunNow)
+		admin.GET("/cache", r.HealthHandler.GetCacheStats)
+		admin.GET("/jobs", r.JobHandler.ListJobs)
+		admin.GET("/jobs/stats", r.JobHandler.GetStats)
+		admin.GET("/jobs/:jobId", r.JobHandler.GetJob)
+		admin.POST("/jobs/:jobId/retry", r.JobHandler.RetryJob)
+		admin.POST("/jobs/:jobId/cancel", r.JobHandler.CancelJob)
+		admin.GET("/settings", r.SettingsHandler.ListSettings)
+		admin.GET("/settings/history", r.SettingsHandler.GetHistory)
+		admin.GET("/settings/:key", r.SettingsHandler.GetSetting)
+		admin.PUT("/settings/:key", r.SettingsHandler.UpdateSetting)
+		admin.DELETE("/settings/:key", r.SettingsHandler.ResetSetting)
+		admin.GET("/settings/:key/history", r.SettingsHandler.GetHistory)
 	}
 }
 
@@ -90,7 +288,14 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 		forecast.POST("/generate", r.ForecastHandler.GenerateForecast)
 		forecast.POST("/peak-load", r.ForecastHandler.GeneratePeakLoad)
 		forecast.GET("/latest", r.ForecastHandler.GetLatestForecast)
+		forecast.POST("/invalidate", r.AuthMiddleware.RequireAdmin(), r.ForecastHandler.InvalidateCache)
+		forecast.GET("/models", r.ForecastHandler.ListModels)
+		forecast.GET("/demand-charge", r.DemandChargeHandler.GetDemandChargeForecast)
 		forecast.GET("/prediction/:deviceId", r.ForecastHandler.GetDevicePrediction)
+		forecast.GET("/:id", r.ForecastHandler.GetForecastByID)
+		forecast.GET("/:id/status", r.ForecastHandler.GetForecastStatus)
+		forecast.GET("/:id/with-actuals", r.ForecastHandler.GetForecastWithActuals)
+		forecast.GET("/:id/breakdown", r.BreakdownHandler.GetForecastBreakdown)
 		forecast.GET("/optimization/:deviceId", r.OptimizationHandler.GetDeviceOptimization)
 	}
 
@@ -99,9 +304,109 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 	optimization.Use(r.AuthMiddleware.RequireAuth())
 	{
 		optimization.POST("/generate", r.OptimizationHandler.GenerateOptimization)
+		optimization.POST("/portfolio/generate", r.OptimizationHandler.GeneratePortfolio)
+		optimization.POST("/replay", r.OptimizationHandler.ReplayOptimization)
 		optimization.GET("/recommendations/:buildingId", r.OptimizationHandler.GetRecommendations)
+		optimization.GET("/scenarios", r.OptimizationHandler.ListScenarios)
+		optimization.GET("/search", r.OptimizationHandler.SearchScenarios)
+		optimization.GET("/correction-factors", r.FeedbackHandler.ListCorrectionFactors)
 		optimization.GET("/scenario/:scenarioId", r.OptimizationHandler.GetScenario)
+		optimization.GET("/scenario/:scenarioId/conflicts", r.OptimizationHandler.GetScenarioConflicts)
+		optimization.GET("/scenario/:scenarioId/export", r.OptimizationHandler.ExportScenario)
+		optimization.POST("/import", r.OptimizationHandler.ImportScenario)
 		optimization.POST("/send-to-iot", r.OptimizationHandler.SendToIoT)
+		optimization.GET("/templates", r.TemplateHandler.ListTemplates)
+		optimization.GET("/templates/:templateId", r.TemplateHandler.GetTemplate)
+		optimization.POST("/templates/:templateId/apply", r.TemplateHandler.ApplyTemplate)
+		optimization.POST("/templates", r.AuthMiddleware.RequireAdmin(), r.TemplateHandler.CreateTemplate)
+		optimization.PUT("/templates/:templateId", r.AuthMiddleware.RequireAdmin(), r.TemplateHandler.UpdateTemplate)
+		optimization.DELETE("/templates/:templateId", r.AuthMiddleware.RequireAdmin(), r.TemplateHandler.DeleteTemplate)
+	}
+
+	// Tariff routes
+	tariffs := engine.Group("/tariffs")
+	tariffs.Use(r.AuthMiddleware.RequireAuth())
+	{
+		tariffs.GET("", r.TariffHandler.ListTariffs)
+		tariffs.GET("/current", r.TariffHandler.GetCurrentTariff)
+		tariffs.GET("/:tariffId", r.TariffHandler.GetTariff)
+		tariffs.POST("", r.AuthMiddleware.RequireAdmin(), r.TariffHandler.CreateTariff)
+		tariffs.PUT("/:tariffId", r.AuthMiddleware.RequireAdmin(), r.TariffHandler.UpdateTariff)
+		tariffs.DELETE("/:tariffId", r.AuthMiddleware.RequireAdmin(), r.TariffHandler.DeleteTariff)
+	}
+
+	// Occupancy routes
+	occupancy := engine.Group("/occupancy")
+	occupancy.Use(r.AuthMiddleware.RequireAuth())
+	{
+		occupancy.GET("/:buildingId", r.OccupancyHandler.GetSchedule)
+		occupancy.GET("/:buildingId/status", r.OccupancyHandler.GetOccupancyStatus)
+		occupancy.PUT("/:buildingId", r.AuthMiddleware.RequireAdmin(), r.OccupancyHandler.SaveSchedule)
+		occupancy.DELETE("/:buildingId", r.AuthMiddleware.RequireAdmin(), r.OccupancyHandler.DeleteSchedule)
+		occupancy.POST("/:buildingId/holidays/import", r.AuthMiddleware.RequireAdmin(), r.OccupancyHandler.ImportHolidays)
+	}
+
+	// Building registry routes
+	buildings := engine.Group("/buildings")
+	buildings.Use(r.AuthMiddleware.RequireAuth())
+	{
+		buildings.GET("", r.BuildingHandler.ListBuildings)
+		buildings.GET("/:buildingId", r.BuildingHandler.GetBuilding)
+		buildings.PUT("/:buildingId", r.AuthMiddleware.RequireAdmin(), r.BuildingHandler.SaveBuilding)
+		buildings.DELETE("/:buildingId", r.AuthMiddleware.RequireAdmin(), r.BuildingHandler.DeleteBuilding)
+		buildings.GET("/:buildingId/demand-contract", r.BuildingHandler.GetDemandContract)
+		buildings.PUT("/:buildingId/demand-contract", r.AuthMiddleware.RequireAdmin(), r.BuildingHandler.SaveDemandContract)
+		buildings.DELETE("/:buildingId/demand-contract", r.AuthMiddleware.RequireAdmin(), r.BuildingHandler.DeleteDemandContract)
+	}
+
+	// Business calendar routes
+	days := engine.Group("/calendar/days")
+	days.Use(r.AuthMiddleware.RequireAuth())
+	{
+		days.GET("", r.CalendarHandler.ListDays)
+		days.GET("/:dayId", r.CalendarHandler.GetDay)
+		days.POST("", r.AuthMiddleware.RequireAdmin(), r.CalendarHandler.CreateDay)
+		days.PUT("/:dayId", r.AuthMiddleware.RequireAdmin(), r.CalendarHandler.UpdateDay)
+		days.DELETE("/:dayId", r.AuthMiddleware.RequireAdmin(), r.CalendarHandler.DeleteDay)
+	}
+
+	// Automation routes
+	rules := engine.Group("/automation/rules")
+	rules.Use(r.AuthMiddleware.RequireAuth())
+	{
+		rules.GET("", r.AutomationHandler.ListRules)
+		rules.GET("/:ruleId", r.AutomationHandler.GetRule)
+		rules.POST("", r.AuthMiddleware.RequireAdmin(), r.AutomationHandler.CreateRule)
+		rules.PUT("/:ruleId", r.AuthMiddleware.RequireAdmin(), r.AutomationHandler.UpdateRule)
+		rules.PATCH("/:ruleId/enabled", r.AuthMiddleware.RequireAdmin(), r.AutomationHandler.SetRuleEnabled)
+		rules.DELETE("/:ruleId", r.AuthMiddleware.RequireAdmin(), r.AutomationHandler.DeleteRule)
+		rules.POST("/:ruleId/evaluate", r.AuthMiddleware.RequireAdmin(), r.AutomationHandler.EvaluateRule)
+	}
+
+	// Weather routes
+	weather := engine.Group("/weather")
+	weather.Use(r.AuthMiddleware.RequireAuth())
+	{
+		weather.GET("/degree-days", r.WeatherHandler.GetDegreeDays)
 	}
-}
 
+	// Admin routes
+	admin := engine.Group("/admin")
+	admin.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		admin.GET("/retention", r.RetentionHandler.GetStatus)
+		admin.POST("/retention/run", r.RetentionHandler.RunNow)
+		admin.GET("/cache", r.HealthHandler.GetCacheStats)
+		admin.GET("/jobs", r.JobHandler.ListJobs)
+		admin.GET("/jobs/stats", r.JobHandler.GetStats)
+		admin.GET("/jobs/:jobId", r.JobHandler.GetJob)
+		admin.POST("/jobs/:jobId/retry", r.JobHandler.RetryJob)
+		admin.POST("/jobs/:jobId/cancel", r.JobHandler.CancelJob)
+		admin.GET("/settings", r.SettingsHandler.ListSettings)
+		admin.GET("/settings/history", r.SettingsHandler.GetHistory)
+		admin.GET("/settings/:key", r.SettingsHandler.GetSetting)
+		admin.PUT("/settings/:key", r.SettingsHandler.UpdateSetting)
+		admin.DELETE("/settings/:key", r.SettingsHandler.ResetSetting)
+		admin.GET("/settings/:key/history", r.SettingsHandler.GetHistory)
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/middleware"
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+)
+
+// ScenarioTemplateHandler handles optimization scenario template requests
+type ScenarioTemplateHandler struct {
+	templateService     *service.ScenarioTemplateService
+	optimizationService *service.OptimizationService
+	securityClient      interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewScenarioTemplateHandler creates a new scenario template handler
+func NewScenarioTemplateHandler(
+	templateService *service.ScenarioTemplateService,
+	optimizationService *service.OptimizationService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *ScenarioTemplateHandler {
+	return &ScenarioTemplateHandler{
+		templateService:     templateService,
+		optimizationService: optimizationService,
+		securityClient:      securityClient,
+	}
+}
+
+// CreateTemplate handles scenario template creation
+// POST /optimization/templates
+func (h *ScenarioTemplateHandler) CreateTemplate(c *gin.Context) {
+	var req models.ScenarioTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	template, err := h.templateService.CreateTemplate(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "CREATE_SCENARIO_TEMPLATE", "scenario_template", "", "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"name": req.Name})
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "CREATE_SCENARIO_TEMPLATE", "scenario_template", template.ID.Hex(), "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"name": req.Name})
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(template, "Scenario template created successfully"))
+}
+
+// ListTemplates handles scenario template listing
+// GET /optimization/templates?type=
+func (h *ScenarioTemplateHandler) ListTemplates(c *gin.Context) {
+	var req models.ListScenarioTemplatesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	templates, err := h.templateService.ListTemplates(c.Request.Context(), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(templates, ""))
+}
+
+// GetTemplate handles scenario template retrieval
+// GET /optimization/templates/:templateId
+func (h *ScenarioTemplateHandler) GetTemplate(c *gin.Context) {
+	template, err := h.templateService.GetTemplate(c.Request.Context(), c.Param("templateId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(template, ""))
+}
+
+// UpdateTemplate handles replacing a scenario template definition
+// PUT /optimization/templates/:templateId
+func (h *ScenarioTemplateHandler) UpdateTemplate(c *gin.Context) {
+	templateID := c.Param("templateId")
+
+	var req models.ScenarioTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	template, err := h.templateService.UpdateTemplate(c.Request.Context(), templateID, &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_SCENARIO_TEMPLATE", "scenario_template", templateID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_SCENARIO_TEMPLATE", "scenario_template", templateID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(template, "Scenario template updated successfully"))
+}
+
+// DeleteTemplate handles scenario template deletion
+// DELETE /optimization/templates/:templateId
+func (h *ScenarioTemplateHandler) DeleteTemplate(c *gin.Context) {
+	templateID := c.Param("templateId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.templateService.DeleteTemplate(c.Request.Context(), templateID); err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_SCENARIO_TEMPLATE", "scenario_template", templateID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_SCENARIO_TEMPLATE", "scenario_template", templateID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Scenario template deleted successfully"))
+}
+
+// ApplyTemplate handles generating a scenario for a building from a template
+// POST /optimization/templates/:templateId/apply
+func (h *ScenarioTemplateHandler) ApplyTemplate(c *gin.Context) {
+	templateID := c.Param("templateId")
+
+	var req models.ApplyScenarioTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	token := middleware.GetToken(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"buildingId": req.BuildingID, "templateId": templateID}
+
+	response, err := h.optimizationService.GenerateOptimization(c.Request.Context(), req.GenerateRequest(templateID), userID, token)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "GENERATE_OPTIMIZATION", "optimization", "", "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details)
+		var capabilityErr *service.CapabilityError
+		if errors.As(err, &capabilityErr) {
+			c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(
+				"Scenario actions exceed device capabilities",
+				err.Error(),
+				capabilityErr.FieldErrors(),
+			))
+			return
+		}
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "GENERATE_OPTIMIZATION", "optimization", response.ID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Optimization scenario generated successfully"))
+}
+
+// respondError maps scenario template errors to HTTP responses
+func (h *ScenarioTemplateHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "scenario template not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case err.Error() == "invalid scenario template ID format":
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeInvalidRequest, err.Error(), ""))
+	case strings.Contains(err.Error(), "already exists"):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(models.ErrCodeConflict, err.Error(), ""))
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/middleware"
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+)
+
+// TariffHandler handles local tariff management requests
+type TariffHandler struct {
+	tariffService  *service.TariffService
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewTariffHandler creates a new tariff handler
+func NewTariffHandler(
+	tariffService *service.TariffService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *TariffHandler {
+	return &TariffHandler{
+		tariffService:  tariffService,
+		securityClient: securityClient,
+	}
+}
+
+// CreateTariff handles local tariff creation
+// POST /tariffs
+func (h *TariffHandler) CreateTariff(c *gin.Context) {
+	var req models.TariffCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.tariffService.CreateTariff(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "CREATE_TARIFF", "tariff", "", "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"region": req.Region})
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "CREATE_TARIFF", "tariff", response.ID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"region": req.Region})
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Tariff created successfully"))
+}
+
+// ListTariffs handles local tariff listing
+// GET /tariffs
+func (h *TariffHandler) ListTariffs(c *gin.Context) {
+	region := c.Query("region")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	responses, total, err := h.tariffService.ListTariffs(c.Request.Context(), region, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"tariffs": responses,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	}, ""))
+}
+
+// GetCurrentTariff resolves the tariff in effect for a region (local first, then external)
+// GET /tariffs/current
+func (h *TariffHandler) GetCurrentTariff(c *gin.Context) {
+	region := c.DefaultQuery("region", "default")
+	token := middleware.GetToken(c)
+
+	response, err := h.tariffService.GetCurrentTariff(c.Request.Context(), region, token)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeNotFound,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetTariff handles local tariff retrieval
+// GET /tariffs/:tariffId
+func (h *TariffHandler) GetTariff(c *gin.Context) {
+	tariffID := c.Param("tariffId")
+
+	response, err := h.tariffService.GetTariff(c.Request.Context(), tariffID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// UpdateTariff handles local tariff updates
+// PUT /tariffs/:tariffId
+func (h *TariffHandler) UpdateTariff(c *gin.Context) {
+	tariffID := c.Param("tariffId")
+
+	var req models.TariffUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.tariffService.UpdateTariff(c.Request.Context(), tariffID, &req)
+	if err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_TARIFF", "tariff", tariffID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "UPDATE_TARIFF", "tariff", tariffID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, map[string]interface{}{"region": response.Region})
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Tariff updated successfully"))
+}
+
+// DeleteTariff handles local tariff deletion
+// DELETE /tariffs/:tariffId
+func (h *TariffHandler) DeleteTariff(c *gin.Context) {
+	tariffID := c.Param("tariffId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.tariffService.DeleteTariff(c.Request.Context(), tariffID); err != nil {
+		h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_TARIFF", "tariff", tariffID, "FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(c.Request.Context(), userID, "", "DELETE_TARIFF", "tariff", tariffID, "SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Tariff deleted successfully"))
+}
+
+// respondError maps tariff service errors to HTTP responses
+func (h *TariffHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "tariff not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case err.Error() == "invalid tariff ID format", strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	case strings.Contains(err.Error(), "already exists"):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(models.ErrCodeConflict, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, err.Error(), ""))
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/middleware"
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+)
+
+// WeatherHandler handles weather statistics requests
+type WeatherHandler struct {
+	weatherService *service.WeatherService
+}
+
+// NewWeatherHandler creates a new weather handler
+func NewWeatherHandler(weatherService *service.WeatherService) *WeatherHandler {
+	return &WeatherHandler{weatherService: weatherService}
+}
+
+// GetDegreeDays handles heating and cooling degree day calculation. It is also mounted for
+// background jobs of other services.
+// GET /weather/degree-days?buildingId=&from=&to=&baseTemperature=
+// GET /internal/weather/degree-days?buildingId=&from=&to=&baseTemperature=
+func (h *WeatherHandler) GetDegreeDays(c *gin.Context) {
+	var req models.DegreeDaysRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	response, err := h.weatherService.GetDegreeDays(c.Request.Context(), &req, middleware.GetToken(c))
+	if err != nil {
+		switch {
+		case err.Error() == "to must be after from", err.Error() == "degree day range must not exceed 400 days":
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		case strings.HasPrefix(err.Error(), "weather history API"):
+			c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+				models.ErrCodeExternalAPIError,
+				"Failed to retrieve weather history",
+				err.Error(),
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to calculate degree days",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetWeatherForecast handles internal retrieval of a building location's weather forecast for
+// background jobs of other services
+// GET /internal/weather/forecast?buildingId=&hours=
+func (h *WeatherHandler) GetWeatherForecast(c *gin.Context) {
+	var req models.WeatherForecastRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	points, err := h.weatherService.GetWeatherForecast(c.Request.Context(), &req)
+	if err != nil {
+		switch {
+		case err.Error() == "hours must be between 1 and 168":
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		case strings.HasPrefix(err.Error(), "weather forecast API"):
+			c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+				models.ErrCodeExternalAPIError,
+				"Failed to retrieve weather forecast",
+				err.Error(),
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to retrieve weather forecast",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(points, ""))
+}
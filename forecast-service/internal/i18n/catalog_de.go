@@ -0,0 +1,274 @@
+package i18n
+
+// germanCatalog holds the German translations
+var germanCatalog = &catalog{
+	Messages: map[string]string{
+		// Requests
+		"Invalid request body":                                  "Ungültiger Anfragetext",
+		"Invalid query parameters":                              "Ungültige Abfrageparameter",
+		"Failed to read request body":                           "Anfragetext konnte nicht gelesen werden",
+		"Invalid 'from' date format":                            "Ungültiges Datumsformat für 'from'",
+		"Invalid 'to' date format":                              "Ungültiges Datumsformat für 'to'",
+		"Invalid 'format' parameter":                            "Ungültiger Parameter 'format'",
+		"Invalid 'at' parameter, expected RFC3339 timestamp":    "Ungültiger Parameter 'at', erwartet wird ein RFC3339-Zeitstempel",
+		"'from' must be before 'to'":                            "'from' muss vor 'to' liegen",
+		"to must be after from":                                 "to muss nach from liegen",
+		"from and to query parameters are required":             "Die Abfrageparameter from und to sind erforderlich",
+		"region or buildingId query parameter is required":      "Der Abfrageparameter region oder buildingId ist erforderlich",
+		"Invalid cursor":                                        "Ungültiger Cursor",
+		"invalid cursor":                                        "ungültiger Cursor",
+		"Invalid user ID format":                                "Ungültiges Format der Benutzer-ID",
+		"Invalid roles format":                                  "Ungültiges Rollenformat",
+		"Invalid conflict resolution":                           "Ungültige Konfliktauflösung",
+		"Idempotency-Key header must not exceed 255 characters": "Der Header Idempotency-Key darf höchstens 255 Zeichen lang sein",
+		"Request body must contain an iCalendar document":       "Der Anfragetext muss ein iCalendar-Dokument enthalten",
+		"no updates provided":                                   "keine Änderungen angegeben",
+		"must be a number":                                      "muss eine Zahl sein",
+		"must be an integer":                                    "muss eine ganze Zahl sein",
+		"must be true or false":                                 "muss true oder false sein",
+		"days must be between 1 and 365":                        "days muss zwischen 1 und 365 liegen",
+		"hours must be between 1 and 168":                       "hours muss zwischen 1 und 168 liegen",
+		"degree day range must not exceed 400 days":             "der Gradtagzeitraum darf 400 Tage nicht überschreiten",
+
+		// Authentication and authorization
+		"Authorization header is required":                       "Der Authorization-Header ist erforderlich",
+		"Invalid authorization header format":                    "Ungültiges Format des Authorization-Headers",
+		"Insufficient permissions":                               "Unzureichende Berechtigungen",
+		"Kiosk tokens cannot access this endpoint":               "Kiosk-Token haben keinen Zugriff auf diesen Endpunkt",
+		"This action is not allowed while impersonating a user":  "Diese Aktion ist während der Identitätsübernahme nicht erlaubt",
+		"Valid service signature is required":                    "Eine gültige Dienstsignatur ist erforderlich",
+		"Invalid service signature":                              "Ungültige Dienstsignatur",
+		"request is not signed":                                  "die Anfrage ist nicht signiert",
+		"invalid request signature":                              "ungültige Anfragesignatur",
+		"request nonce has already been used":                    "die Nonce der Anfrage wurde bereits verwendet",
+		"request timestamp outside the allowed window":           "der Zeitstempel der Anfrage liegt außerhalb des erlaubten Zeitfensters",
+		"unknown calling service":                                "unbekannter aufrufender Dienst",
+		"credentials not found for service":                      "keine Zugangsdaten für den Dienst gefunden",
+		"Login successful":                                       "Anmeldung erfolgreich",
+		"Logout successful":                                      "Abmeldung erfolgreich",
+		"Failed to logout":                                       "Abmeldung fehlgeschlagen",
+		"Token refresh failed":                                   "Token-Erneuerung fehlgeschlagen",
+		"Failed to validate token":                               "Token konnte nicht validiert werden",
+		"Failed to check permissions":                            "Berechtigungen konnten nicht geprüft werden",
+		"Failed to change password":                              "Passwort konnte nicht geändert werden",
+		"Failed to impersonate user":                             "Identitätsübernahme fehlgeschlagen",
+		"Impersonation started":                                  "Identitätsübernahme gestartet",
+		"Permission cache invalidated":                           "Berechtigungscache invalidiert",
+		"invalid username or password":                           "ungültiger Benutzername oder ungültiges Passwort",
+		"account is disabled":                                    "das Konto ist deaktiviert",
+		"current password is incorrect":                          "das aktuelle Passwort ist falsch",
+		"new password must differ from the current password":     "das neue Passwort muss sich vom aktuellen unterscheiden",
+		"refresh token has expired":                              "das Refresh-Token ist abgelaufen",
+		"refresh token not found or revoked":                     "Refresh-Token nicht gefunden oder widerrufen",
+		"token mismatch":                                         "Token stimmt nicht überein",
+		"cannot impersonate an admin":                            "die Identität eines Administrators kann nicht übernommen werden",
+		"cannot impersonate yourself":                            "die eigene Identität kann nicht übernommen werden",
+		"impersonator is no longer allowed to impersonate":       "der Benutzer darf keine Identitäten mehr übernehmen",
+		"cannot delete your own account":                         "das eigene Konto kann nicht gelöscht werden",
+		"cannot delete system role":                              "Systemrollen können nicht gelöscht werden",
+		"cannot modify description of system role":               "die Beschreibung einer Systemrolle kann nicht geändert werden",
+		"one or more roles do not exist":                         "eine oder mehrere Rollen existieren nicht",
+		"email is already in use":                                "die E-Mail-Adresse wird bereits verwendet",
+		"user with this username or email already exists":        "ein Benutzer mit diesem Benutzernamen oder dieser E-Mail-Adresse existiert bereits",
+		"parent groups would create a cycle":                     "die übergeordneten Gruppen würden einen Zyklus bilden",
+		"Failed to compute role impact":                          "Auswirkungen der Rolle konnten nicht berechnet werden",
+		"Failed to preview role change":                          "Vorschau der Rollenänderung fehlgeschlagen",
+		"Failed to export signing keys":                          "Signaturschlüssel konnten nicht exportiert werden",
+		"Failed to rotate signing key":                           "Signaturschlüssel konnte nicht rotiert werden",
+		"Failed to revoke kiosk token":                           "Kiosk-Token konnte nicht widerrufen werden",
+		"kiosk token is already revoked":                         "das Kiosk-Token ist bereits widerrufen",
+		"Provisioning token created; it will not be shown again": "Bereitstellungstoken erstellt; es wird nicht erneut angezeigt",
+		"provisioning token is invalid, expired or already used": "das Bereitstellungstoken ist ungültig, abgelaufen oder bereits verwendet",
+
+		// Devices and commands
+		"Command already accepted":                                       "Befehl bereits angenommen",
+		"Dry run completed; no commands were sent":                       "Probelauf abgeschlossen; es wurden keine Befehle gesendet",
+		"Overriding the command rate limit requires the admin role":      "Das Überschreiben des Befehlslimits erfordert die Administratorrolle",
+		"overrideRateLimit applies to immediate commands only":           "overrideRateLimit gilt nur für sofortige Befehle",
+		"scheduled command has already finished":                         "der geplante Befehl ist bereits abgeschlossen",
+		"scheduled command is not active":                                "der geplante Befehl ist nicht aktiv",
+		"scheduled command is not paused":                                "der geplante Befehl ist nicht pausiert",
+		"scheduled command was modified concurrently":                    "der geplante Befehl wurde gleichzeitig geändert",
+		"no telemetry found for device":                                  "keine Telemetrie für das Gerät gefunden",
+		"no time-series data found for device":                           "keine Zeitreihendaten für das Gerät gefunden",
+		"Scenario conflicts with approved or executing scenarios":        "Das Szenario steht im Konflikt mit genehmigten oder laufenden Szenarien",
+		"validation failed: scheduledAt must be in the future":           "Validierung fehlgeschlagen: scheduledAt muss in der Zukunft liegen",
+		"validation failed: scheduledAt and cron are mutually exclusive": "Validierung fehlgeschlagen: scheduledAt und cron schließen sich gegenseitig aus",
+		"validation failed: cron expression never matches":               "Validierung fehlgeschlagen: der Cron-Ausdruck trifft nie zu",
+
+		// Forecasts and analytics
+		"Forecast generation started":                                        "Prognoseerstellung gestartet",
+		"Forecast cache invalidated":                                         "Prognosecache invalidiert",
+		"Report generation started":                                          "Berichterstellung gestartet",
+		"Retention run completed":                                            "Aufbewahrungslauf abgeschlossen",
+		"Weather rule evaluated":                                             "Wetterregel ausgewertet",
+		"Setting reset to default":                                           "Einstellung auf den Standardwert zurückgesetzt",
+		"Job cancelled":                                                      "Auftrag abgebrochen",
+		"Job queued for retry":                                               "Auftrag zur Wiederholung eingereiht",
+		"Failed to calculate degree days":                                    "Gradtage konnten nicht berechnet werden",
+		"long-horizon forecasts are disabled":                                "Langfristprognosen sind deaktiviert",
+		"no forecasts found for this building":                               "keine Prognosen für dieses Gebäude gefunden",
+		"no peak load predictions found for this building":                   "keine Spitzenlastprognosen für dieses Gebäude gefunden",
+		"no energy provider configured for building":                         "für das Gebäude ist kein Energieversorger konfiguriert",
+		"no energy provider configured for region":                           "für die Region ist kein Energieversorger konfiguriert",
+		"budget already exists for this building or device":                  "für dieses Gebäude oder Gerät existiert bereits ein Budget",
+		"tariff for this region already exists":                              "für diese Region existiert bereits ein Tarif",
+		"calendar day already exists":                                        "der Kalendertag existiert bereits",
+		"device type already exists":                                         "der Gerätetyp existiert bereits",
+		"baseline model could not be fitted: degree day terms are collinear": "das Basismodell konnte nicht angepasst werden: die Gradtagterme sind kollinear",
+		"validation failed: baseline period has too few days with consumption and weather data": "Validierung fehlgeschlagen: der Basiszeitraum enthält zu wenige Tage mit Verbrauchs- und Wetterdaten",
+		"validation failed: reporting period has no days with consumption and weather data":     "Validierung fehlgeschlagen: der Berichtszeitraum enthält keine Tage mit Verbrauchs- und Wetterdaten",
+		"validation failed: scenario has no applied actions to verify":                          "Validierung fehlgeschlagen: das Szenario hat keine angewendeten Maßnahmen zum Prüfen",
+		"validation failed: no whole day has passed since the scenario completed":               "Validierung fehlgeschlagen: seit Abschluss des Szenarios ist noch kein ganzer Tag vergangen",
+		"validation failed: not enough hourly data for the scenario's devices":                  "Validierung fehlgeschlagen: nicht genügend Stundendaten für die Geräte des Szenarios",
+
+		// Peak load mitigation actions and recommendations
+		"Monitor energy consumption closely during this period":                                          "Energieverbrauch in diesem Zeitraum genau überwachen",
+		"Consider load shedding for non-essential equipment":                                             "Lastabwurf für nicht notwendige Geräte in Betracht ziehen",
+		"Activate demand response programs":                                                              "Demand-Response-Programme aktivieren",
+		"Temporarily reduce HVAC setpoints":                                                              "HLK-Sollwerte vorübergehend senken",
+		"Dim lighting in unoccupied areas":                                                               "Beleuchtung in unbelegten Bereichen dimmen",
+		"Pre-cool/pre-heat building before peak period":                                                  "Gebäude vor dem Spitzenzeitraum vorkühlen/vorheizen",
+		"Reduce HVAC intensity during peak":                                                              "HLK-Leistung während der Spitze reduzieren",
+		"Shift flexible loads to off-peak hours":                                                         "Flexible Lasten in Nebenzeiten verschieben",
+		"Optimize HVAC schedules":                                                                        "HLK-Zeitpläne optimieren",
+		"Review lighting schedules":                                                                      "Beleuchtungszeitpläne überprüfen",
+		"No significant peak periods detected. Continue monitoring.":                                     "Keine nennenswerten Spitzenzeiträume erkannt. Überwachung fortsetzen.",
+		"Critical peaks detected - immediate action recommended":                                         "Kritische Spitzen erkannt - sofortiges Handeln empfohlen",
+		"Consider enrolling in utility demand response programs":                                         "Teilnahme an Demand-Response-Programmen des Versorgers in Betracht ziehen",
+		"High peaks detected - review energy management strategies":                                      "Hohe Spitzen erkannt - Energiemanagementstrategien überprüfen",
+		"Review and optimize HVAC schedules":                                                             "HLK-Zeitpläne überprüfen und optimieren",
+		"Consider shifting flexible loads to off-peak hours":                                             "Verschiebung flexibler Lasten in Nebenzeiten in Betracht ziehen",
+		"Optimize HVAC Setpoints":                                                                        "HLK-Sollwerte optimieren",
+		"Current HVAC setpoints can be adjusted to reduce energy consumption while maintaining comfort.": "Die aktuellen HLK-Sollwerte können angepasst werden, um den Energieverbrauch bei gleichbleibendem Komfort zu senken.",
+		"Increase cooling setpoint by 2°C during peak hours":                                             "Kühlsollwert während der Spitzenzeiten um 2°C erhöhen",
+		"Review current HVAC schedules":                                                                  "Aktuelle HLK-Zeitpläne überprüfen",
+		"Adjust cooling setpoint from 22°C to 24°C during peak hours (14:00-18:00)":                      "Kühlsollwert während der Spitzenzeiten (14:00-18:00) von 22°C auf 24°C anheben",
+		"Monitor comfort levels and adjust if needed":                                                    "Komfortniveau überwachen und bei Bedarf anpassen",
+		"Implement Lighting Schedules":                                                                   "Beleuchtungszeitpläne einführen",
+		"Lighting in common areas can be scheduled to reduce unnecessary usage.":                         "Die Beleuchtung in Gemeinschaftsbereichen kann zeitgesteuert werden, um unnötigen Verbrauch zu vermeiden.",
+		"Configure automatic lighting schedules":                                                         "Automatische Beleuchtungszeitpläne konfigurieren",
+		"Identify common areas with extended lighting hours":                                             "Gemeinschaftsbereiche mit langen Beleuchtungszeiten ermitteln",
+		"Configure occupancy-based or scheduled lighting":                                                "Belegungsabhängige oder zeitgesteuerte Beleuchtung konfigurieren",
+		"Set dimming levels for daylight harvesting":                                                     "Dimmstufen für Tageslichtnutzung festlegen",
+		"Equipment Upgrade Assessment":                                                                   "Bewertung von Geräte-Upgrades",
+		"Some equipment may benefit from efficiency upgrades.":                                           "Einige Geräte könnten von Effizienz-Upgrades profitieren.",
+		"Schedule equipment efficiency audit":                                                            "Effizienzprüfung der Geräte einplanen",
+		"List all major energy-consuming equipment":                                                      "Alle großen Energieverbraucher auflisten",
+		"Assess age and efficiency ratings":                                                              "Alter und Effizienzklassen bewerten",
+		"Evaluate upgrade options and ROI":                                                               "Upgrade-Optionen und Rendite bewerten",
+	},
+	Patterns: map[string]string{
+		"{} created successfully":                        "{} erfolgreich erstellt",
+		"{} updated successfully":                        "{} erfolgreich aktualisiert",
+		"{} deleted successfully":                        "{} erfolgreich gelöscht",
+		"{} restored successfully":                       "{} erfolgreich wiederhergestellt",
+		"{} saved successfully":                          "{} erfolgreich gespeichert",
+		"{} revoked successfully":                        "{} erfolgreich widerrufen",
+		"{} imported successfully":                       "{} erfolgreich importiert",
+		"{} generated successfully":                      "{} erfolgreich erstellt",
+		"{} registered successfully":                     "{} erfolgreich registriert",
+		"{} ingested successfully":                       "{} erfolgreich übernommen",
+		"{} applied successfully":                        "{} erfolgreich angewendet",
+		"{} calculated successfully":                     "{} erfolgreich berechnet",
+		"{} acknowledged successfully":                   "{} erfolgreich bestätigt",
+		"{} rotated successfully":                        "{} erfolgreich rotiert",
+		"{} changed successfully":                        "{} erfolgreich geändert",
+		"{} added successfully":                          "{} erfolgreich hinzugefügt",
+		"{} removed successfully":                        "{} erfolgreich entfernt",
+		"{} refreshed successfully":                      "{} erfolgreich erneuert",
+		"{} sent successfully":                           "{} erfolgreich gesendet",
+		"{} scheduled successfully":                      "{} erfolgreich geplant",
+		"{} sent to IoT service successfully":            "{} erfolgreich an den IoT-Dienst gesendet",
+		"{} not found":                                   "{} nicht gefunden",
+		"{} is required":                                 "{} ist erforderlich",
+		"{} query parameter is required":                 "Der Abfrageparameter {} ist erforderlich",
+		"invalid {} ID format":                           "ungültiges ID-Format ({})",
+		"{} with this ID already exists":                 "es existiert bereits ein Eintrag mit dieser ID ({})",
+		"{} with this name already exists":               "es existiert bereits ein Eintrag mit diesem Namen ({})",
+		"Failed to retrieve {}":                          "Fehler beim Abrufen: {}",
+		"Failed to get {}":                               "Fehler beim Abrufen: {}",
+		"Failed to create {}":                            "Fehler beim Erstellen: {}",
+		"Failed to update {}":                            "Fehler beim Aktualisieren: {}",
+		"Failed to delete {}":                            "Fehler beim Löschen: {}",
+		"Failed to restore {}":                           "Fehler beim Wiederherstellen: {}",
+		"Failed to send {}":                              "Fehler beim Senden: {}",
+		"validation failed: {}":                          "Validierung fehlgeschlagen: {}",
+		"Detected {} peak period(s) requiring attention": "{} Spitzenzeitraum/-zeiträume erkannt, die Aufmerksamkeit erfordern",
+	},
+	Terms: map[string]string{
+		"anomaly":                  "Anomalie",
+		"anomaly id":               "Anomalie-ID",
+		"audit log":                "Audit-Protokoll",
+		"audit logs":               "Audit-Protokolle",
+		"authorization header":     "Authorization-Header",
+		"automation rule":          "Automatisierungsregel",
+		"budget":                   "Budget",
+		"building":                 "Gebäude",
+		"building id":              "Gebäude-ID",
+		"bulk telemetry":           "Massentelemetrie",
+		"calendar day":             "Kalendertag",
+		"command":                  "Befehl",
+		"deleted device":           "Gelöschtes Gerät",
+		"deleted user":             "Gelöschter Benutzer",
+		"deleted users":            "Gelöschte Benutzer",
+		"device":                   "Gerät",
+		"device calibration":       "Gerätekalibrierung",
+		"device id":                "Geräte-ID",
+		"devices":                  "Geräte",
+		"device type":              "Gerätetyp",
+		"energy provider":          "Energieversorger",
+		"feature snapshot":         "Merkmals-Snapshot",
+		"forecast":                 "Prognose",
+		"forecast actuals":         "Prognose-Istwerte",
+		"forecast id":              "Prognose-ID",
+		"forecast status":          "Prognosestatus",
+		"group":                    "Gruppe",
+		"group member":             "Gruppenmitglied",
+		"group members":            "Gruppenmitglieder",
+		"groups":                   "Gruppen",
+		"holidays":                 "Feiertage",
+		"impersonator":             "Übernehmender Benutzer",
+		"job":                      "Auftrag",
+		"kiosk token":              "Kiosk-Token",
+		"kiosk tokens":             "Kiosk-Token",
+		"kpi":                      "KPI",
+		"kpis":                     "KPIs",
+		"m&v report":               "M&V-Bericht",
+		"notification":             "Benachrichtigung",
+		"notification logs":        "Benachrichtigungsprotokolle",
+		"notification preferences": "Benachrichtigungseinstellungen",
+		"notification statistics":  "Benachrichtigungsstatistik",
+		"occupancy schedule":       "Belegungsplan",
+		"optimization execution":   "Optimierungsausführung",
+		"optimization scenario":    "Optimierungsszenario",
+		"password":                 "Passwort",
+		"peak load":                "Spitzenlast",
+		"peak load prediction":     "Spitzenlastprognose",
+		"portfolio scenario":       "Portfolioszenario",
+		"profile":                  "Profil",
+		"provisioning token":       "Bereitstellungstoken",
+		"recommendation":           "Empfehlung",
+		"refresh token":            "Refresh-Token",
+		"report":                   "Bericht",
+		"report id":                "Berichts-ID",
+		"role":                     "Rolle",
+		"roles":                    "Rollen",
+		"scenario":                 "Szenario",
+		"scenario id":              "Szenario-ID",
+		"scheduled command":        "Geplanter Befehl",
+		"setting":                  "Einstellung",
+		"signing key":              "Signaturschlüssel",
+		"signing keys":             "Signaturschlüssel",
+		"tariff":                   "Tarif",
+		"telemetry":                "Telemetrie",
+		"token":                    "Token",
+		"user":                     "Benutzer",
+		"user id":                  "Benutzer-ID",
+		"user roles":               "Benutzerrollen",
+		"users":                    "Benutzer",
+		"weather forecast":         "Wettervorhersage",
+		"weather history":          "Wetterverlauf",
+		"weather rule":             "Wetterregel",
+	},
+}
@@ -0,0 +1,274 @@
+package i18n
+
+// frenchCatalog holds the French translations
+var frenchCatalog = &catalog{
+	Messages: map[string]string{
+		// Requests
+		"Invalid request body":                                  "Corps de requête invalide",
+		"Invalid query parameters":                              "Paramètres de requête invalides",
+		"Failed to read request body":                           "Impossible de lire le corps de la requête",
+		"Invalid 'from' date format":                            "Format de date 'from' invalide",
+		"Invalid 'to' date format":                              "Format de date 'to' invalide",
+		"Invalid 'format' parameter":                            "Paramètre 'format' invalide",
+		"Invalid 'at' parameter, expected RFC3339 timestamp":    "Paramètre 'at' invalide, horodatage RFC3339 attendu",
+		"'from' must be before 'to'":                            "'from' doit être antérieur à 'to'",
+		"to must be after from":                                 "to doit être postérieur à from",
+		"from and to query parameters are required":             "Les paramètres de requête from et to sont obligatoires",
+		"region or buildingId query parameter is required":      "Le paramètre de requête region ou buildingId est obligatoire",
+		"Invalid cursor":                                        "Curseur invalide",
+		"invalid cursor":                                        "curseur invalide",
+		"Invalid user ID format":                                "Format d'ID utilisateur invalide",
+		"Invalid roles format":                                  "Format des rôles invalide",
+		"Invalid conflict resolution":                           "Résolution de conflit invalide",
+		"Idempotency-Key header must not exceed 255 characters": "L'en-tête Idempotency-Key ne doit pas dépasser 255 caractères",
+		"Request body must contain an iCalendar document":       "Le corps de la requête doit contenir un document iCalendar",
+		"no updates provided":                                   "aucune modification fournie",
+		"must be a number":                                      "doit être un nombre",
+		"must be an integer":                                    "doit être un entier",
+		"must be true or false":                                 "doit être true ou false",
+		"days must be between 1 and 365":                        "days doit être compris entre 1 et 365",
+		"hours must be between 1 and 168":                       "hours doit être compris entre 1 et 168",
+		"degree day range must not exceed 400 days":             "la période de degrés-jours ne doit pas dépasser 400 jours",
+
+		// Authentication and authorization
+		"Authorization header is required":                       "L'en-tête Authorization est obligatoire",
+		"Invalid authorization header format":                    "Format de l'en-tête Authorization invalide",
+		"Insufficient permissions":                               "Autorisations insuffisantes",
+		"Kiosk tokens cannot access this endpoint":               "Les jetons kiosque n'ont pas accès à ce point de terminaison",
+		"This action is not allowed while impersonating a user":  "Cette action n'est pas autorisée pendant l'usurpation d'un utilisateur",
+		"Valid service signature is required":                    "Une signature de service valide est obligatoire",
+		"Invalid service signature":                              "Signature de service invalide",
+		"request is not signed":                                  "la requête n'est pas signée",
+		"invalid request signature":                              "signature de requête invalide",
+		"request nonce has already been used":                    "le nonce de la requête a déjà été utilisé",
+		"request timestamp outside the allowed window":           "l'horodatage de la requête est hors de la fenêtre autorisée",
+		"unknown calling service":                                "service appelant inconnu",
+		"credentials not found for service":                      "identifiants introuvables pour le service",
+		"Login successful":                                       "Connexion réussie",
+		"Logout successful":                                      "Déconnexion réussie",
+		"Failed to logout":                                       "Échec de la déconnexion",
+		"Token refresh failed":                                   "Échec du renouvellement du jeton",
+		"Failed to validate token":                               "Échec de la validation du jeton",
+		"Failed to check permissions":                            "Échec de la vérification des autorisations",
+		"Failed to change password":                              "Échec du changement de mot de passe",
+		"Failed to impersonate user":                             "Échec de l'usurpation de l'utilisateur",
+		"Impersonation started":                                  "Usurpation démarrée",
+		"Permission cache invalidated":                           "Cache des autorisations invalidé",
+		"invalid username or password":                           "nom d'utilisateur ou mot de passe invalide",
+		"account is disabled":                                    "le compte est désactivé",
+		"current password is incorrect":                          "le mot de passe actuel est incorrect",
+		"new password must differ from the current password":     "le nouveau mot de passe doit être différent de l'actuel",
+		"refresh token has expired":                              "le jeton de rafraîchissement a expiré",
+		"refresh token not found or revoked":                     "jeton de rafraîchissement introuvable ou révoqué",
+		"token mismatch":                                         "les jetons ne correspondent pas",
+		"cannot impersonate an admin":                            "impossible d'usurper un administrateur",
+		"cannot impersonate yourself":                            "impossible de s'usurper soi-même",
+		"impersonator is no longer allowed to impersonate":       "l'usurpateur n'est plus autorisé à usurper",
+		"cannot delete your own account":                         "impossible de supprimer votre propre compte",
+		"cannot delete system role":                              "impossible de supprimer un rôle système",
+		"cannot modify description of system role":               "impossible de modifier la description d'un rôle système",
+		"one or more roles do not exist":                         "un ou plusieurs rôles n'existent pas",
+		"email is already in use":                                "l'adresse e-mail est déjà utilisée",
+		"user with this username or email already exists":        "un utilisateur avec ce nom ou cette adresse e-mail existe déjà",
+		"parent groups would create a cycle":                     "les groupes parents créeraient un cycle",
+		"Failed to compute role impact":                          "Échec du calcul de l'impact du rôle",
+		"Failed to preview role change":                          "Échec de l'aperçu de la modification du rôle",
+		"Failed to export signing keys":                          "Échec de l'export des clés de signature",
+		"Failed to rotate signing key":                           "Échec de la rotation de la clé de signature",
+		"Failed to revoke kiosk token":                           "Échec de la révocation du jeton kiosque",
+		"kiosk token is already revoked":                         "le jeton kiosque est déjà révoqué",
+		"Provisioning token created; it will not be shown again": "Jeton de provisionnement créé ; il ne sera plus affiché",
+		"provisioning token is invalid, expired or already used": "le jeton de provisionnement est invalide, expiré ou déjà utilisé",
+
+		// Devices and commands
+		"Command already accepted":                                       "Commande déjà acceptée",
+		"Dry run completed; no commands were sent":                       "Simulation terminée ; aucune commande n'a été envoyée",
+		"Overriding the command rate limit requires the admin role":      "Le dépassement de la limite de commandes nécessite le rôle administrateur",
+		"overrideRateLimit applies to immediate commands only":           "overrideRateLimit ne s'applique qu'aux commandes immédiates",
+		"scheduled command has already finished":                         "la commande planifiée est déjà terminée",
+		"scheduled command is not active":                                "la commande planifiée n'est pas active",
+		"scheduled command is not paused":                                "la commande planifiée n'est pas en pause",
+		"scheduled command was modified concurrently":                    "la commande planifiée a été modifiée simultanément",
+		"no telemetry found for device":                                  "aucune télémétrie trouvée pour l'appareil",
+		"no time-series data found for device":                           "aucune série temporelle trouvée pour l'appareil",
+		"Scenario conflicts with approved or executing scenarios":        "Le scénario est en conflit avec des scénarios approuvés ou en cours",
+		"validation failed: scheduledAt must be in the future":           "échec de la validation : scheduledAt doit être dans le futur",
+		"validation failed: scheduledAt and cron are mutually exclusive": "échec de la validation : scheduledAt et cron sont mutuellement exclusifs",
+		"validation failed: cron expression never matches":               "échec de la validation : l'expression cron ne correspond jamais",
+
+		// Forecasts and analytics
+		"Forecast generation started":                                        "Génération de la prévision démarrée",
+		"Forecast cache invalidated":                                         "Cache des prévisions invalidé",
+		"Report generation started":                                          "Génération du rapport démarrée",
+		"Retention run completed":                                            "Purge de rétention terminée",
+		"Weather rule evaluated":                                             "Règle météo évaluée",
+		"Setting reset to default":                                           "Paramètre réinitialisé à sa valeur par défaut",
+		"Job cancelled":                                                      "Tâche annulée",
+		"Job queued for retry":                                               "Tâche remise en file pour une nouvelle tentative",
+		"Failed to calculate degree days":                                    "Échec du calcul des degrés-jours",
+		"long-horizon forecasts are disabled":                                "les prévisions à long terme sont désactivées",
+		"no forecasts found for this building":                               "aucune prévision trouvée pour ce bâtiment",
+		"no peak load predictions found for this building":                   "aucune prévision de pointe trouvée pour ce bâtiment",
+		"no energy provider configured for building":                         "aucun fournisseur d'énergie configuré pour le bâtiment",
+		"no energy provider configured for region":                           "aucun fournisseur d'énergie configuré pour la région",
+		"budget already exists for this building or device":                  "un budget existe déjà pour ce bâtiment ou cet appareil",
+		"tariff for this region already exists":                              "un tarif existe déjà pour cette région",
+		"calendar day already exists":                                        "le jour calendaire existe déjà",
+		"device type already exists":                                         "le type d'appareil existe déjà",
+		"baseline model could not be fitted: degree day terms are collinear": "impossible d'ajuster le modèle de référence : les termes de degrés-jours sont colinéaires",
+		"validation failed: baseline period has too few days with consumption and weather data": "échec de la validation : la période de référence compte trop peu de jours avec des données de consommation et météo",
+		"validation failed: reporting period has no days with consumption and weather data":     "échec de la validation : la période de suivi ne compte aucun jour avec des données de consommation et météo",
+		"validation failed: scenario has no applied actions to verify":                          "échec de la validation : le scénario n'a aucune action appliquée à vérifier",
+		"validation failed: no whole day has passed since the scenario completed":               "échec de la validation : aucune journée complète ne s'est écoulée depuis la fin du scénario",
+		"validation failed: not enough hourly data for the scenario's devices":                  "échec de la validation : données horaires insuffisantes pour les appareils du scénario",
+
+		// Peak load mitigation actions and recommendations
+		"Monitor energy consumption closely during this period":                                          "Surveiller de près la consommation d'énergie pendant cette période",
+		"Consider load shedding for non-essential equipment":                                             "Envisager le délestage des équipements non essentiels",
+		"Activate demand response programs":                                                              "Activer les programmes d'effacement",
+		"Temporarily reduce HVAC setpoints":                                                              "Réduire temporairement les consignes CVC",
+		"Dim lighting in unoccupied areas":                                                               "Baisser l'éclairage des zones inoccupées",
+		"Pre-cool/pre-heat building before peak period":                                                  "Pré-refroidir/préchauffer le bâtiment avant la période de pointe",
+		"Reduce HVAC intensity during peak":                                                              "Réduire l'intensité CVC pendant la pointe",
+		"Shift flexible loads to off-peak hours":                                                         "Décaler les charges flexibles vers les heures creuses",
+		"Optimize HVAC schedules":                                                                        "Optimiser les plannings CVC",
+		"Review lighting schedules":                                                                      "Revoir les plannings d'éclairage",
+		"No significant peak periods detected. Continue monitoring.":                                     "Aucune période de pointe significative détectée. Poursuivre la surveillance.",
+		"Critical peaks detected - immediate action recommended":                                         "Pointes critiques détectées - action immédiate recommandée",
+		"Consider enrolling in utility demand response programs":                                         "Envisager l'inscription aux programmes d'effacement du fournisseur",
+		"High peaks detected - review energy management strategies":                                      "Pointes élevées détectées - revoir les stratégies de gestion de l'énergie",
+		"Review and optimize HVAC schedules":                                                             "Revoir et optimiser les plannings CVC",
+		"Consider shifting flexible loads to off-peak hours":                                             "Envisager de décaler les charges flexibles vers les heures creuses",
+		"Optimize HVAC Setpoints":                                                                        "Optimiser les consignes CVC",
+		"Current HVAC setpoints can be adjusted to reduce energy consumption while maintaining comfort.": "Les consignes CVC actuelles peuvent être ajustées pour réduire la consommation d'énergie tout en préservant le confort.",
+		"Increase cooling setpoint by 2°C during peak hours":                                             "Augmenter la consigne de refroidissement de 2°C pendant les heures de pointe",
+		"Review current HVAC schedules":                                                                  "Revoir les plannings CVC actuels",
+		"Adjust cooling setpoint from 22°C to 24°C during peak hours (14:00-18:00)":                      "Passer la consigne de refroidissement de 22°C à 24°C pendant les heures de pointe (14:00-18:00)",
+		"Monitor comfort levels and adjust if needed":                                                    "Surveiller le niveau de confort et ajuster si nécessaire",
+		"Implement Lighting Schedules":                                                                   "Mettre en place des plannings d'éclairage",
+		"Lighting in common areas can be scheduled to reduce unnecessary usage.":                         "L'éclairage des parties communes peut être programmé pour éviter les usages inutiles.",
+		"Configure automatic lighting schedules":                                                         "Configurer des plannings d'éclairage automatiques",
+		"Identify common areas with extended lighting hours":                                             "Identifier les parties communes éclairées sur de longues plages",
+		"Configure occupancy-based or scheduled lighting":                                                "Configurer un éclairage programmé ou asservi à l'occupation",
+		"Set dimming levels for daylight harvesting":                                                     "Définir les niveaux de gradation pour exploiter la lumière du jour",
+		"Equipment Upgrade Assessment":                                                                   "Évaluation de la modernisation des équipements",
+		"Some equipment may benefit from efficiency upgrades.":                                           "Certains équipements pourraient bénéficier d'une amélioration de leur efficacité.",
+		"Schedule equipment efficiency audit":                                                            "Planifier un audit d'efficacité des équipements",
+		"List all major energy-consuming equipment":                                                      "Recenser tous les équipements fortement consommateurs",
+		"Assess age and efficiency ratings":                                                              "Évaluer leur âge et leur classe d'efficacité",
+		"Evaluate upgrade options and ROI":                                                               "Évaluer les options de modernisation et leur retour sur investissement",
+	},
+	Patterns: map[string]string{
+		"{} created successfully":                        "{} : création réussie",
+		"{} updated successfully":                        "{} : mise à jour réussie",
+		"{} deleted successfully":                        "{} : suppression réussie",
+		"{} restored successfully":                       "{} : restauration réussie",
+		"{} saved successfully":                          "{} : enregistrement réussi",
+		"{} revoked successfully":                        "{} : révocation réussie",
+		"{} imported successfully":                       "{} : importation réussie",
+		"{} generated successfully":                      "{} : génération réussie",
+		"{} registered successfully":                     "{} : enregistrement réussi",
+		"{} ingested successfully":                       "{} : ingestion réussie",
+		"{} applied successfully":                        "{} : application réussie",
+		"{} calculated successfully":                     "{} : calcul réussi",
+		"{} acknowledged successfully":                   "{} : acquittement réussi",
+		"{} rotated successfully":                        "{} : rotation réussie",
+		"{} changed successfully":                        "{} : modification réussie",
+		"{} added successfully":                          "{} : ajout réussi",
+		"{} removed successfully":                        "{} : retrait réussi",
+		"{} refreshed successfully":                      "{} : renouvellement réussi",
+		"{} sent successfully":                           "{} : envoi réussi",
+		"{} scheduled successfully":                      "{} : planification réussie",
+		"{} sent to IoT service successfully":            "{} : envoi au service IoT réussi",
+		"{} not found":                                   "{} introuvable",
+		"{} is required":                                 "{} obligatoire",
+		"{} query parameter is required":                 "Le paramètre de requête {} est obligatoire",
+		"invalid {} ID format":                           "format d'ID invalide ({})",
+		"{} with this ID already exists":                 "un élément avec cet ID existe déjà ({})",
+		"{} with this name already exists":               "un élément avec ce nom existe déjà ({})",
+		"Failed to retrieve {}":                          "Échec de la récupération : {}",
+		"Failed to get {}":                               "Échec de la récupération : {}",
+		"Failed to create {}":                            "Échec de la création : {}",
+		"Failed to update {}":                            "Échec de la mise à jour : {}",
+		"Failed to delete {}":                            "Échec de la suppression : {}",
+		"Failed to restore {}":                           "Échec de la restauration : {}",
+		"Failed to send {}":                              "Échec de l'envoi : {}",
+		"validation failed: {}":                          "échec de la validation : {}",
+		"Detected {} peak period(s) requiring attention": "{} période(s) de pointe nécessitant une attention détectée(s)",
+	},
+	Terms: map[string]string{
+		"anomaly":                  "anomalie",
+		"anomaly id":               "ID d'anomalie",
+		"audit log":                "journal d'audit",
+		"audit logs":               "journaux d'audit",
+		"authorization header":     "en-tête Authorization",
+		"automation rule":          "règle d'automatisation",
+		"budget":                   "budget",
+		"building":                 "bâtiment",
+		"building id":              "ID de bâtiment",
+		"bulk telemetry":           "télémétrie groupée",
+		"calendar day":             "jour calendaire",
+		"command":                  "commande",
+		"deleted device":           "appareil supprimé",
+		"deleted user":             "utilisateur supprimé",
+		"deleted users":            "utilisateurs supprimés",
+		"device":                   "appareil",
+		"device calibration":       "étalonnage de l'appareil",
+		"device id":                "ID d'appareil",
+		"devices":                  "appareils",
+		"device type":              "type d'appareil",
+		"energy provider":          "fournisseur d'énergie",
+		"feature snapshot":         "instantané des caractéristiques",
+		"forecast":                 "prévision",
+		"forecast actuals":         "valeurs réelles de la prévision",
+		"forecast id":              "ID de prévision",
+		"forecast status":          "état de la prévision",
+		"group":                    "groupe",
+		"group member":             "membre du groupe",
+		"group members":            "membres du groupe",
+		"groups":                   "groupes",
+		"holidays":                 "jours fériés",
+		"impersonator":             "usurpateur",
+		"job":                      "tâche",
+		"kiosk token":              "jeton kiosque",
+		"kiosk tokens":             "jetons kiosque",
+		"kpi":                      "KPI",
+		"kpis":                     "KPI",
+		"m&v report":               "rapport M&V",
+		"notification":             "notification",
+		"notification logs":        "journaux de notification",
+		"notification preferences": "préférences de notification",
+		"notification statistics":  "statistiques de notification",
+		"occupancy schedule":       "planning d'occupation",
+		"optimization execution":   "exécution d'optimisation",
+		"optimization scenario":    "scénario d'optimisation",
+		"password":                 "mot de passe",
+		"peak load":                "pointe de charge",
+		"peak load prediction":     "prévision de pointe",
+		"portfolio scenario":       "scénario de portefeuille",
+		"profile":                  "profil",
+		"provisioning token":       "jeton de provisionnement",
+		"recommendation":           "recommandation",
+		"refresh token":            "jeton de rafraîchissement",
+		"report":                   "rapport",
+		"report id":                "ID de rapport",
+		"role":                     "rôle",
+		"roles":                    "rôles",
+		"scenario":                 "scénario",
+		"scenario id":              "ID de scénario",
+		"scheduled command":        "commande planifiée",
+		"setting":                  "paramètre",
+		"signing key":              "clé de signature",
+		"signing keys":             "clés de signature",
+		"tariff":                   "tarif",
+		"telemetry":                "télémétrie",
+		"token":                    "jeton",
+		"user":                     "utilisateur",
+		"user id":                  "ID utilisateur",
+		"user roles":               "rôles de l'utilisateur",
+		"users":                    "utilisateurs",
+		"weather forecast":         "prévision météo",
+		"weather history":          "historique météo",
+		"weather rule":             "règle météo",
+	},
+}
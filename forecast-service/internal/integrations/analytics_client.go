@@ -0,0 +1,71 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"forecast-service/internal/config"
+	"forecast-service/internal/models"
+	"forecast-service/internal/tracing"
+	"shared/signing"
+)
+
+// AnalyticsClient handles communication with the Analytics service
+type AnalyticsClient struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewAnalyticsClient creates a new analytics client
+func NewAnalyticsClient(cfg *config.Config) *AnalyticsClient {
+	return &AnalyticsClient{
+		httpClient: tracing.WrapClient(signing.NewClient(cfg.Analytics.Timeout, ServiceName, cfg.Auth.ServiceSecret)),
+		baseURL:    cfg.Analytics.URL,
+	}
+}
+
+// HealthCheck checks if the analytics service is reachable
+func (c *AnalyticsClient) HealthCheck(ctx context.Context) error {
+	return checkServiceHealth(ctx, c.httpClient, c.baseURL+"/health")
+}
+
+// CompareCosts prices a baseline and an optimized load profile with the analytics cost engine.
+// It is a signed internal request, so background workers can use it.
+func (c *AnalyticsClient) CompareCosts(ctx context.Context, comparison *models.CostComparisonRequest) (*models.CostComparison, error) {
+	body, err := json.Marshal(comparison)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/internal/cost/compare", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("analytics service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp struct {
+		Success bool                  `json:"success"`
+		Data    models.CostComparison `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !apiResp.Success {
+		return nil, fmt.Errorf("analytics service error")
+	}
+
+	return &apiResp.Data, nil
+}
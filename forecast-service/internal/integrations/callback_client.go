@@ -0,0 +1,52 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"forecast-service/internal/config"
+	"forecast-service/internal/tracing"
+)
+
+// CallbackClient notifies client-supplied webhooks when asynchronous work finishes
+type CallbackClient struct {
+	httpClient *http.Client
+}
+
+// NewCallbackClient creates a new callback client
+func NewCallbackClient(cfg *config.Config) *CallbackClient {
+	return &CallbackClient{
+		httpClient: &http.Client{
+			Timeout:   cfg.Forecast.CallbackTimeout,
+			Transport: tracing.NewExternalTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// Notify posts the payload as JSON to the callback URL
+func (c *CallbackClient) Notify(ctx context.Context, callbackURL string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal callback payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
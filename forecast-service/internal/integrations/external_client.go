@@ -11,6 +11,7 @@ import (
 
 	"forecast-service/internal/config"
 	"forecast-service/internal/models"
+	"forecast-service/internal/tracing"
 )
 
 // ExternalClient handles communication with external APIs (weather, tariffs, ML, storage)
@@ -26,7 +27,8 @@ type ExternalClient struct {
 func NewExternalClient(cfg *config.Config) *ExternalClient {
 	return &ExternalClient{
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: tracing.NewExternalTransport(http.DefaultTransport),
 		},
 		weatherURL: cfg.External.WeatherURL,
 		tariffURL:  cfg.External.TariffURL,
@@ -101,7 +103,45 @@ func (c *ExternalClient) GetWeatherForecast(ctx context.Context, buildingID stri
 	return apiResp.Data, nil
 }
 
-// WeatherForecastPoint represents a point in weather forecast
+// GetWeatherHistory retrieves observed weather for a building location between from and to
+func (c *ExternalClient) GetWeatherHistory(ctx context.Context, buildingID string, from, to time.Time, authToken string) ([]WeatherForecastPoint, error) {
+	reqURL := fmt.Sprintf("%s/history?buildingId=%s&from=%s&to=%s",
+		c.weatherURL,
+		url.QueryEscape(buildingID),
+		url.QueryEscape(from.Format(time.RFC3339)),
+		url.QueryEscape(to.Format(time.RFC3339)),
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+authToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("weather history API returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp struct {
+		Success bool                   `json:"success"`
+		Data    []WeatherForecastPoint `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return apiResp.Data, nil
+}
+
+// WeatherForecastPoint represents a point in weather forecast, or an observation in weather history
 type WeatherForecastPoint struct {
 	Timestamp   time.Time `json:"timestamp"`
 	Temperature float64   `json:"temperature"`
@@ -196,6 +236,7 @@ type MLPredictionRequest struct {
 	TariffData       *models.Tariff                  `json:"tariffData,omitempty"`
 	HorizonHours     int                             `json:"horizonHours"`
 	ModelType        string                          `json:"modelType"` // LSTM, ARIMA, PROPHET, etc.
+	Quantiles        []float64                       `json:"quantiles,omitempty"` // Predictions carry these keyed by label, e.g. "p90"
 }
 
 // MLPredictionResponse represents a response from the ML model
@@ -260,16 +301,24 @@ func (c *ExternalClient) HealthCheck(ctx context.Context) map[string]bool {
 }
 
 func (c *ExternalClient) checkHealth(ctx context.Context, url string) bool {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return false
-	}
+	return checkServiceHealth(ctx, c.httpClient, url) == nil
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return false
+// CheckService checks a single external service (weather, tariff, ml or storage)
+func (c *ExternalClient) CheckService(ctx context.Context, name string) error {
+	var baseURL string
+	switch name {
+	case "weather":
+		baseURL = c.weatherURL
+	case "tariff":
+		baseURL = c.tariffURL
+	case "ml":
+		baseURL = c.mlURL
+	case "storage":
+		baseURL = c.storageURL
+	default:
+		return fmt.Errorf("unknown external service: %s", name)
 	}
-	defer resp.Body.Close()
 
-	return resp.StatusCode == http.StatusOK
+	return checkServiceHealth(ctx, c.httpClient, baseURL+"/health")
 }
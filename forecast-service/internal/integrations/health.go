@@ -0,0 +1,27 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// checkServiceHealth calls a dependency's health endpoint and expects 200 OK
+func checkServiceHealth(ctx context.Context, httpClient *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -6,9 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"forecast-service/internal/config"
 	"forecast-service/internal/models"
+	"forecast-service/internal/tracing"
 )
 
 // IoTClient handles communication with the IoT & Control service
@@ -21,12 +24,18 @@ type IoTClient struct {
 func NewIoTClient(cfg *config.Config) *IoTClient {
 	return &IoTClient{
 		httpClient: &http.Client{
-			Timeout: cfg.IoT.Timeout,
+			Timeout:   cfg.IoT.Timeout,
+			Transport: tracing.NewTransport(http.DefaultTransport),
 		},
 		baseURL: cfg.IoT.URL,
 	}
 }
 
+// HealthCheck checks if the IoT control service is reachable
+func (c *IoTClient) HealthCheck(ctx context.Context) error {
+	return checkServiceHealth(ctx, c.httpClient, c.baseURL+"/health")
+}
+
 // GetDeviceState retrieves the current state of a device
 func (c *IoTClient) GetDeviceState(ctx context.Context, deviceID string, authToken string) (*models.DeviceState, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/iot/state/%s", c.baseURL, deviceID), nil)
@@ -93,34 +102,123 @@ func (c *IoTClient) GetDevicesByBuilding(ctx context.Context, buildingID string,
 	return apiResp.Data, nil
 }
 
+// GetDeviceTypes retrieves the device type catalog
+func (c *IoTClient) GetDeviceTypes(ctx context.Context, authToken string) ([]models.DeviceType, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/iot/device-types", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+authToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get device types: status %d", resp.StatusCode)
+	}
+
+	var apiResp struct {
+		Success bool                `json:"success"`
+		Data    []models.DeviceType `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return apiResp.Data, nil
+}
+
 // ApplyOptimizationRequest represents the request to apply optimization
 type ApplyOptimizationRequest struct {
-	ScenarioID string                      `json:"scenarioId"`
-	BuildingID string                      `json:"buildingId"`
-	Actions    []models.OptimizationAction `json:"actions"`
-	ExecuteNow bool                        `json:"executeNow"`
-	DryRun     bool                        `json:"dryRun"`
+	ScenarioID   string      `json:"scenarioId"`
+	ScenarioType string      `json:"scenarioType"`
+	BuildingID   string      `json:"buildingId"`
+	Actions      []IoTAction `json:"actions"`
+	Comfort      *IoTComfort `json:"comfort,omitempty"`
+	ExecuteNow   bool        `json:"executeNow"`
+	DryRun       bool        `json:"dryRun"`
 }
 
-// ApplyOptimizationResponse represents the response from applying optimization
+// IoTComfort is the comfort band the IoT service's guardrail keeps while executing a scenario,
+// taken from the temperature constraints of the scenario
+type IoTComfort struct {
+	MinTemperature *float64 `json:"minTemperature,omitempty"`
+	MaxTemperature *float64 `json:"maxTemperature,omitempty"`
+}
+
+// iotComfort returns the comfort band of a scenario's constraints, or nil when it has no
+// temperature constraints
+func iotComfort(constraints models.OptimizationConstraints) *IoTComfort {
+	if constraints.MinTemperature == nil && constraints.MaxTemperature == nil {
+		return nil
+	}
+	return &IoTComfort{MinTemperature: constraints.MinTemperature, MaxTemperature: constraints.MaxTemperature}
+}
+
+// IoTAction is a scenario action in the form the IoT service executes: the action type is the
+// device command, and the numeric target value and duration are its params
+type IoTAction struct {
+	DeviceID string                 `json:"deviceId"`
+	Command  string                 `json:"command"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+}
+
+// ApplyOptimizationResponse represents the response from applying optimization.
+// Preview is set for dry runs.
 type ApplyOptimizationResponse struct {
-	Success        bool     `json:"success"`
-	ExecutionID    string   `json:"executionId"`
-	ActionsQueued  int      `json:"actionsQueued"`
-	ActionsSkipped int      `json:"actionsSkipped"`
-	Errors         []string `json:"errors,omitempty"`
-	Message        string   `json:"message,omitempty"`
+	Success        bool                    `json:"success"`
+	ExecutionID    string                  `json:"executionId"`
+	ActionsQueued  int                     `json:"actionsQueued"`
+	ActionsSkipped int                     `json:"actionsSkipped"`
+	Errors         []string                `json:"errors,omitempty"`
+	Message        string                  `json:"message,omitempty"`
+	Data           json.RawMessage         `json:"data,omitempty"`
+	Preview        *models.ScenarioPreview `json:"-"`
+}
+
+// iotActions converts scenario actions to the form the IoT service executes
+func iotActions(actions []models.OptimizationAction) []IoTAction {
+	converted := make([]IoTAction, len(actions))
+	for i, action := range actions {
+		params := map[string]interface{}{}
+		if value, ok := leadingNumber(action.TargetValue); ok {
+			params["value"] = value
+		}
+		if action.Duration > 0 {
+			params["durationMinutes"] = action.Duration
+		}
+		converted[i] = IoTAction{DeviceID: action.DeviceID, Command: action.ActionType, Params: params}
+	}
+	return converted
+}
+
+// leadingNumber parses the number a target value such as "24°C" or "12.5 kW" starts with
+func leadingNumber(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	end := 0
+	for end < len(value) && strings.ContainsRune("+-.0123456789", rune(value[end])) {
+		end++
+	}
+	number, err := strconv.ParseFloat(value[:end], 64)
+	return number, err == nil
 }
 
 // ApplyOptimization sends optimization actions to the IoT service
 // Uses /iot/optimization/applySecurity endpoint as per integration contract
 func (c *IoTClient) ApplyOptimization(ctx context.Context, scenario *models.OptimizationScenario, executeNow, dryRun bool, authToken string) (*ApplyOptimizationResponse, error) {
 	payload := ApplyOptimizationRequest{
-		ScenarioID: scenario.ID.Hex(),
-		BuildingID: scenario.BuildingID,
-		Actions:    scenario.Actions,
-		ExecuteNow: executeNow,
-		DryRun:     dryRun,
+		ScenarioID:   scenario.ID.Hex(),
+		ScenarioType: string(scenario.Type),
+		BuildingID:   scenario.BuildingID,
+		Actions:      iotActions(scenario.Actions),
+		Comfort:      iotComfort(scenario.Constraints),
+		ExecuteNow:   executeNow,
+		DryRun:       dryRun,
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -147,10 +245,20 @@ func (c *IoTClient) ApplyOptimization(ctx context.Context, scenario *models.Opti
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
 		return &result, fmt.Errorf("optimization apply failed: %s", result.Message)
 	}
 
+	if dryRun && len(result.Data) > 0 {
+		var preview models.ScenarioPreview
+		if err := json.Unmarshal(result.Data, &preview); err != nil {
+			return nil, fmt.Errorf("failed to decode dry run: %w", err)
+		}
+		result.Preview = &preview
+		result.ActionsQueued = preview.Summary.WouldSucceed
+		result.ActionsSkipped = preview.Summary.TotalActions - preview.Summary.WouldSucceed
+	}
+
 	return &result, nil
 }
 
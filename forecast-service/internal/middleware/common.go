@@ -2,9 +2,13 @@ package middleware
 
 import (
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/config"
 )
 
 // RequestLogger logs incoming requests
@@ -50,14 +54,46 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
-// CORS configures Cross-Origin Resource Sharing
-func CORS() gin.HandlerFunc {
+// CORS applies the configured cross-origin policy. Requests from origins outside the policy get
+// no CORS headers, so browsers block them; preflight requests are answered without reaching
+// the routes.
+func CORS(policy config.CORSConfig) gin.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(policy.AllowedOrigins))
+	for _, origin := range policy.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+	methods := strings.Join(policy.AllowedMethods, ", ")
+	headers := strings.Join(policy.AllowedHeaders, ", ")
+	exposed := strings.Join(policy.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(policy.MaxAge.Seconds()))
+
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Request-ID")
-		c.Header("Access-Control-Expose-Headers", "X-Request-ID")
-		c.Header("Access-Control-Max-Age", "86400")
+		origin := c.GetHeader("Origin")
+		switch {
+		case allowAll:
+			c.Header("Access-Control-Allow-Origin", "*")
+		case origin != "" && allowed[origin]:
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+			if policy.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		default:
+			origin = ""
+		}
+
+		if allowAll || origin != "" {
+			c.Header("Access-Control-Allow-Methods", methods)
+			c.Header("Access-Control-Allow-Headers", headers)
+			if exposed != "" {
+				c.Header("Access-Control-Expose-Headers", exposed)
+			}
+			c.Header("Access-Control-Max-Age", maxAge)
+		}
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -87,15 +123,34 @@ func Recovery() gin.HandlerFunc {
 	}
 }
 
-// SecurityHeaders adds security-related HTTP headers
-func SecurityHeaders() gin.HandlerFunc {
+// SecurityHeaders adds the configured security-related HTTP headers
+func SecurityHeaders(policy config.SecurityHeadersConfig) gin.HandlerFunc {
+	hsts := ""
+	if policy.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(policy.HSTSMaxAge.Seconds()))
+		if policy.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if policy.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
 	return func(c *gin.Context) {
 		c.Header("X-Content-Type-Options", "nosniff")
-		c.Header("X-Frame-Options", "DENY")
 		c.Header("X-XSS-Protection", "1; mode=block")
-		c.Header("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-		c.Header("Content-Security-Policy", "default-src 'self'")
-		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
+		if policy.FrameOptions != "" {
+			c.Header("X-Frame-Options", policy.FrameOptions)
+		}
+		if hsts != "" {
+			c.Header("Strict-Transport-Security", hsts)
+		}
+		if policy.ContentSecurityPolicy != "" {
+			c.Header("Content-Security-Policy", policy.ContentSecurityPolicy)
+		}
+		if policy.ReferrerPolicy != "" {
+			c.Header("Referrer-Policy", policy.ReferrerPolicy)
+		}
 		c.Next()
 	}
 }
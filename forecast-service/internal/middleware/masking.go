@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maskModePartial keeps part of a masked string; any other mask mode replaces the value with null
+const maskModePartial = "PARTIAL"
+
+// maskingWriter buffers JSON responses so the fields the caller may not see can be masked
+// before they are sent. Other responses, such as exports, pass through unchanged.
+type maskingWriter struct {
+	gin.ResponseWriter
+	masks     map[string]string
+	body      bytes.Buffer
+	decided   bool
+	buffering bool
+}
+
+// maskResponses masks the given fields in the JSON responses of the request. The returned
+// function sends the masked response and must run after the handlers.
+func maskResponses(c *gin.Context, masks map[string]string) func() {
+	w := &maskingWriter{ResponseWriter: c.Writer, masks: masks}
+	c.Writer = w
+	return w.finish
+}
+
+// Write buffers JSON bodies and writes anything else straight through
+func (w *maskingWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	}
+	if !w.buffering {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+// WriteString buffers JSON bodies and writes anything else straight through
+func (w *maskingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush is deferred until the buffered response is complete
+func (w *maskingWriter) Flush() {
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// finish masks the buffered JSON body and sends it. Bodies that cannot be parsed are sent as they are.
+func (w *maskingWriter) finish() {
+	if !w.buffering || w.body.Len() == 0 {
+		return
+	}
+
+	data := w.body.Bytes()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err == nil {
+		if masked, err := json.Marshal(maskValue(payload, w.masks)); err == nil {
+			data = masked
+		}
+	}
+	w.ResponseWriter.Write(data)
+}
+
+// maskValue masks the fields named in masks at any depth of a decoded JSON value
+func maskValue(value interface{}, masks map[string]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if mode, ok := masks[key]; ok {
+				v[key] = maskField(field, mode)
+				continue
+			}
+			v[key] = maskValue(field, masks)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = maskValue(v[i], masks)
+		}
+	}
+	return value
+}
+
+// maskField masks a single value. Partially masked strings keep the first character of an email
+// address and its domain, or the last four characters of anything else.
+func maskField(value interface{}, mode string) interface{} {
+	s, ok := value.(string)
+	if mode != maskModePartial || !ok {
+		return nil
+	}
+	if s == "" {
+		return s
+	}
+	if at := strings.LastIndex(s, "@"); at > 0 {
+		return s[:1] + "***" + s[at:]
+	}
+	if len(s) > 4 {
+		return "***" + s[len(s)-4:]
+	}
+	return "***"
+}
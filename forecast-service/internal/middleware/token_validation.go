@@ -0,0 +1,320 @@
+package middleware
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"forecast-service/internal/models"
+)
+
+// signingKeyRetryInterval limits how often a missing or rejected signing key is fetched again
+const signingKeyRetryInterval = 30 * time.Second
+
+// maxCachedTokens bounds the permission cache before expired entries are swept
+const maxCachedTokens = 10000
+
+// SigningKeySource fetches the token verification keys from the Security service
+type SigningKeySource interface {
+	GetSigningKey(ctx context.Context) (*models.SigningKeyResponse, error)
+}
+
+// tokenClaims mirrors the access token claims issued by the Security service
+type tokenClaims struct {
+	UserID        string                `json:"userId"`
+	Roles         []string              `json:"roles"`
+	Groups        []string              `json:"groups,omitempty"`
+	Impersonation *models.Impersonation `json:"impersonation,omitempty"`
+	Kiosk         *models.KioskScope    `json:"kiosk,omitempty"`
+	Tenant        *models.TenantScope   `json:"tenant,omitempty"`
+	Masks         map[string]string     `json:"masks,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// LocalValidator verifies access tokens with cached verification keys
+type LocalValidator struct {
+	source    SigningKeySource
+	staticKey []byte
+	refresh   time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]verificationKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// verificationKey is a parsed key published by the Security service
+type verificationKey struct {
+	algorithm string
+	key       interface{}
+	expiresAt *time.Time
+}
+
+// usable reports whether a key is known and not past its rotation grace period
+func (k verificationKey) usable(now time.Time) bool {
+	return k.key != nil && (k.expiresAt == nil || now.Before(*k.expiresAt))
+}
+
+// defaultKeyID identifies the configured signing key, which verifies tokens without a "kid" header
+const defaultKeyID = "default"
+
+// NewLocalValidator creates a local token validator.
+// A non-empty staticKey is used as-is for HS256 tokens and never fetched from the source.
+func NewLocalValidator(source SigningKeySource, staticKey string, refresh time.Duration) *LocalValidator {
+	return &LocalValidator{
+		source:    source,
+		staticKey: []byte(staticKey),
+		refresh:   refresh,
+	}
+}
+
+// Validate verifies a token's signature and expiry and returns its identity and issue time.
+// An error means the token could not be checked locally and should be validated remotely.
+func (v *LocalValidator) Validate(ctx context.Context, token string) (*models.TokenValidationResponse, time.Time, error) {
+	keyID := tokenKeyID(token)
+	key, err := v.verificationKey(ctx, keyID, false)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	claims, err := parseToken(token, key)
+	if errors.Is(err, jwt.ErrTokenSignatureInvalid) && len(v.staticKey) == 0 {
+		// The key may have been replaced since it was fetched
+		if key, err = v.verificationKey(ctx, keyID, true); err != nil {
+			return nil, time.Time{}, err
+		}
+		claims, err = parseToken(token, key)
+	}
+
+	if err != nil {
+		message := "Invalid token"
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			message = "Token has expired"
+		}
+		return &models.TokenValidationResponse{Valid: false, Message: message}, time.Time{}, nil
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
+	return &models.TokenValidationResponse{
+		Valid:         true,
+		UserID:        claims.UserID,
+		Roles:         claims.Roles,
+		Groups:        claims.Groups,
+		Impersonation: claims.Impersonation,
+		Kiosk:         claims.Kiosk,
+		Tenant:        claims.Tenant,
+		Masks:         claims.Masks,
+	}, issuedAt, nil
+}
+
+// verificationKey returns the cached key with the given ID, fetching the key set when the key
+// is unknown, the set is due for refresh or a refetch is forced
+func (v *LocalValidator) verificationKey(ctx context.Context, keyID string, force bool) (verificationKey, error) {
+	if len(v.staticKey) > 0 {
+		return verificationKey{algorithm: jwt.SigningMethodHS256.Alg(), key: v.staticKey}, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	key := v.keys[keyID]
+	known := key.usable(now)
+	due := !known || force || (v.refresh > 0 && now.Sub(v.fetchedAt) >= v.refresh)
+	if !due || now.Sub(v.lastAttempt) < signingKeyRetryInterval {
+		if !known {
+			return verificationKey{}, fmt.Errorf("signing key %s unavailable", keyID)
+		}
+		return key, nil
+	}
+	v.lastAttempt = now
+
+	resp, err := v.source.GetSigningKey(ctx)
+	if err == nil {
+		var keys map[string]verificationKey
+		if keys, err = parseVerificationKeys(resp); err == nil {
+			v.keys = keys
+			v.fetchedAt = now
+			key = keys[keyID]
+			known = key.usable(now)
+		}
+	}
+	if err != nil && !known {
+		return verificationKey{}, fmt.Errorf("failed to fetch signing key: %w", err)
+	}
+	if !known {
+		return verificationKey{}, fmt.Errorf("signing key %s unavailable", keyID)
+	}
+	return key, nil
+}
+
+// parseVerificationKeys decodes the published keys by key ID
+func parseVerificationKeys(resp *models.SigningKeyResponse) (map[string]verificationKey, error) {
+	published := resp.Keys
+	if len(published) == 0 {
+		// Security service versions without key rotation publish a single key
+		published = []models.VerificationKey{{KeyID: defaultKeyID, Algorithm: resp.Algorithm, Key: resp.Key}}
+	}
+
+	keys := make(map[string]verificationKey, len(published))
+	for _, k := range published {
+		var key interface{}
+		switch k.Algorithm {
+		case jwt.SigningMethodHS256.Alg():
+			secret, err := base64.StdEncoding.DecodeString(k.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode signing key %s: %w", k.KeyID, err)
+			}
+			key = secret
+		case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg():
+			block, _ := pem.Decode([]byte(k.Key))
+			if block == nil {
+				return nil, fmt.Errorf("failed to decode signing key %s", k.KeyID)
+			}
+			publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse signing key %s: %w", k.KeyID, err)
+			}
+			key = publicKey
+		default:
+			return nil, fmt.Errorf("unsupported signing algorithm: %s", k.Algorithm)
+		}
+		keys[k.KeyID] = verificationKey{algorithm: k.Algorithm, key: key, expiresAt: k.ExpiresAt}
+	}
+	return keys, nil
+}
+
+// tokenKeyID returns the ID of the key a token was signed with
+func tokenKeyID(token string) string {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return defaultKeyID
+	}
+	if keyID, _ := parsed.Header["kid"].(string); keyID != "" {
+		return keyID
+	}
+	return defaultKeyID
+}
+
+// parseToken verifies a token with the key's algorithm and returns its claims
+func parseToken(token string, key verificationKey) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return key.key, nil
+	}, jwt.WithValidMethods([]string{key.algorithm}))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// PermissionCache keeps token validation results for a short TTL and tracks role change
+// invalidations so tokens issued before a change are re-checked with the Security service.
+type PermissionCache struct {
+	ttl time.Duration
+
+	mu            sync.Mutex
+	entries       map[string]cachedValidation
+	staleBefore   map[string]time.Time
+	allStaleAfter time.Time
+}
+
+type cachedValidation struct {
+	response  *models.TokenValidationResponse
+	expiresAt time.Time
+}
+
+// NewPermissionCache creates a permission cache. A zero TTL disables caching.
+func NewPermissionCache(ttl time.Duration) *PermissionCache {
+	return &PermissionCache{
+		ttl:         ttl,
+		entries:     make(map[string]cachedValidation),
+		staleBefore: make(map[string]time.Time),
+	}
+}
+
+// Get returns a cached validation result for the token
+func (c *PermissionCache) Get(token string) (*models.TokenValidationResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[token]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, token)
+		return nil, false
+	}
+	return entry.response, true
+}
+
+// Put caches a successful validation result for the token
+func (c *PermissionCache) Put(token string, response *models.TokenValidationResponse) {
+	if c.ttl <= 0 || response == nil || !response.Valid {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxCachedTokens {
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= maxCachedTokens {
+			c.entries = make(map[string]cachedValidation)
+		}
+	}
+
+	c.entries[token] = cachedValidation{response: response, expiresAt: now.Add(c.ttl)}
+}
+
+// IsStale reports whether a token issued at issuedAt predates a role change for the user
+func (c *PermissionCache) IsStale(userID string, issuedAt time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if issuedAt.Before(c.allStaleAfter) {
+		return true
+	}
+	changedAt, ok := c.staleBefore[userID]
+	return ok && issuedAt.Before(changedAt)
+}
+
+// InvalidateUser drops cached validations for a user and marks their existing tokens stale
+func (c *PermissionCache) InvalidateUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if entry.response.UserID == userID {
+			delete(c.entries, key)
+		}
+	}
+	c.staleBefore[userID] = time.Now()
+}
+
+// InvalidateAll drops every cached validation and marks all existing tokens stale
+func (c *PermissionCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]cachedValidation)
+	c.staleBefore = make(map[string]time.Time)
+	c.allStaleAfter = time.Now()
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"forecast-service/internal/events"
+	"forecast-service/internal/tenant"
+)
+
+// usageKey identifies the organization and building an API call is metered for
+type usageKey struct {
+	orgID      string
+	buildingID string
+}
+
+// UsageMeter counts authenticated API calls per organization and building for billing. Counts
+// are kept in memory and handed to a reporter periodically, so calls are lost only when the
+// service stops between two reports.
+type UsageMeter struct {
+	mu     sync.Mutex
+	counts map[usageKey]int64
+}
+
+// NewUsageMeter creates an empty usage meter
+func NewUsageMeter() *UsageMeter {
+	return &UsageMeter{counts: make(map[usageKey]int64)}
+}
+
+// Middleware counts each request made by a user once it has been handled. The building is taken
+// from the buildingId path or query parameter when the request has one; requests without a user,
+// such as health checks and internal calls, are not metered.
+func (m *UsageMeter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if GetUserID(c) == "" {
+			return
+		}
+
+		key := usageKey{buildingID: c.Param("buildingId")}
+		if key.buildingID == "" {
+			key.buildingID = c.Query("buildingId")
+		}
+		if scope := tenant.FromContext(c.Request.Context()); scope != nil {
+			key.orgID = scope.OrgID
+		}
+
+		m.mu.Lock()
+		m.counts[key]++
+		m.mu.Unlock()
+	}
+}
+
+// Flush returns the calls counted since the previous flush and resets the meter
+func (m *UsageMeter) Flush() []events.UsageCount {
+	m.mu.Lock()
+	counts := m.counts
+	m.counts = make(map[usageKey]int64)
+	m.mu.Unlock()
+
+	flushed := make([]events.UsageCount, 0, len(counts))
+	for key, count := range counts {
+		flushed = append(flushed, events.UsageCount{OrgID: key.orgID, BuildingID: key.buildingID, Count: count})
+	}
+	return flushed
+}
+
+// StartReporter hands the metered calls to report at every interval until the context is
+// cancelled. Intervals without calls are still reported, so report can add usage of its own.
+func (m *UsageMeter) StartReporter(ctx context.Context, interval time.Duration, report func(ctx context.Context, apiCalls []events.UsageCount)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			report(context.Background(), m.Flush())
+			return
+		case <-ticker.C:
+			report(ctx, m.Flush())
+		}
+	}
+}
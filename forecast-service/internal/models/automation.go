@@ -0,0 +1,154 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AutomationAction represents what an automation rule does when its conditions match
+type AutomationAction string
+
+const (
+	AutomationActionPreCool AutomationAction = "PRE_COOL"
+	AutomationActionPreHeat AutomationAction = "PRE_HEAT"
+)
+
+// AutomationMetric identifies the value an automation condition is evaluated against.
+// Weather, tariff and forecast metrics are measured over the rule's peak window.
+type AutomationMetric string
+
+const (
+	AutomationMetricMaxTemperature    AutomationMetric = "WEATHER_MAX_TEMPERATURE"  // highest forecast temperature (°C)
+	AutomationMetricMinTemperature    AutomationMetric = "WEATHER_MIN_TEMPERATURE"  // lowest forecast temperature (°C)
+	AutomationMetricTemperatureChange AutomationMetric = "WEATHER_TEMPERATURE_RISE" // highest forecast minus current temperature (°C)
+	AutomationMetricTariffRate        AutomationMetric = "TARIFF_WINDOW_RATE"       // highest time-of-use rate (per kWh)
+	AutomationMetricTariffPeakRatio   AutomationMetric = "TARIFF_PEAK_RATIO"        // window rate divided by the off-peak rate
+	AutomationMetricForecastPeak      AutomationMetric = "FORECAST_PEAK_DEMAND"     // highest predicted demand from the latest forecast
+)
+
+// AutomationOperator represents a comparison operator
+type AutomationOperator string
+
+const (
+	AutomationOperatorGT  AutomationOperator = "GT"
+	AutomationOperatorGTE AutomationOperator = "GTE"
+	AutomationOperatorLT  AutomationOperator = "LT"
+	AutomationOperatorLTE AutomationOperator = "LTE"
+)
+
+// Compare applies the operator to an actual value and a threshold
+func (o AutomationOperator) Compare(actual, threshold float64) bool {
+	switch o {
+	case AutomationOperatorGT:
+		return actual > threshold
+	case AutomationOperatorGTE:
+		return actual >= threshold
+	case AutomationOperatorLT:
+		return actual < threshold
+	case AutomationOperatorLTE:
+		return actual <= threshold
+	}
+	return false
+}
+
+// Default peak window and pre-conditioning settings
+const (
+	DefaultAutomationPeakStartHour = 14
+	DefaultAutomationPeakEndHour   = 18
+	DefaultAutomationLeadHours     = 2
+)
+
+// AutomationCondition is a single threshold check; all conditions of a rule must match
+type AutomationCondition struct {
+	Metric    AutomationMetric   `bson:"metric" json:"metric" binding:"required"`
+	Operator  AutomationOperator `bson:"operator" json:"operator" binding:"required"`
+	Threshold float64            `bson:"threshold" json:"threshold"`
+}
+
+// AutomationRule pre-conditions a building ahead of a peak window when its conditions match
+type AutomationRule struct {
+	ID                primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	BuildingID        string                `bson:"building_id" json:"buildingId"`
+	Name              string                `bson:"name" json:"name"`
+	Enabled           bool                  `bson:"enabled" json:"enabled"`
+	Action            AutomationAction      `bson:"action" json:"action"`
+	Conditions        []AutomationCondition `bson:"conditions" json:"conditions"`
+	PeakStartHour     int                   `bson:"peak_start_hour" json:"peakStartHour"`
+	PeakEndHour       int                   `bson:"peak_end_hour" json:"peakEndHour"`
+	LeadHours         int                   `bson:"lead_hours" json:"leadHours"`
+	TargetTemperature float64               `bson:"target_temperature" json:"targetTemperature"` // setpoint while pre-conditioning
+	PeakTemperature   float64               `bson:"peak_temperature" json:"peakTemperature"`     // relaxed setpoint during the peak
+	LastEvaluatedAt   *time.Time            `bson:"last_evaluated_at,omitempty" json:"lastEvaluatedAt,omitempty"`
+	LastTriggeredFor  string                `bson:"last_triggered_for,omitempty" json:"lastTriggeredFor,omitempty"` // date of the last peak acted on
+	LastScenarioID    string                `bson:"last_scenario_id,omitempty" json:"lastScenarioId,omitempty"`
+	CreatedBy         string                `bson:"created_by" json:"createdBy"`
+	CreatedAt         time.Time             `bson:"created_at" json:"createdAt"`
+	UpdatedAt         time.Time             `bson:"updated_at" json:"updatedAt"`
+}
+
+// NextPeakWindow returns the next peak window whose pre-conditioning can still start after now
+func (r *AutomationRule) NextPeakWindow(now time.Time) (start, end time.Time) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start = day.Add(time.Duration(r.PeakStartHour) * time.Hour)
+	if start.Add(-time.Duration(r.LeadHours) * time.Hour).Before(now) {
+		start = start.AddDate(0, 0, 1)
+	}
+	end = start.Add(time.Duration(r.PeakEndHour-r.PeakStartHour) * time.Hour)
+	return start, end
+}
+
+// AutomationRuleRequest represents the request to create or replace an automation rule
+type AutomationRuleRequest struct {
+	BuildingID        string                `json:"buildingId" binding:"required"`
+	Name              string                `json:"name"`
+	Enabled           *bool                 `json:"enabled"`
+	Action            AutomationAction      `json:"action" binding:"required"`
+	Conditions        []AutomationCondition `json:"conditions" binding:"required,dive"`
+	PeakStartHour     *int                  `json:"peakStartHour"`
+	PeakEndHour       *int                  `json:"peakEndHour"`
+	LeadHours         int                   `json:"leadHours"`
+	TargetTemperature float64               `json:"targetTemperature"`
+	PeakTemperature   float64               `json:"peakTemperature"`
+}
+
+// AutomationRuleToggleRequest represents the request to enable or disable a rule
+type AutomationRuleToggleRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// AutomationConditionResult is the outcome of a single condition during evaluation
+type AutomationConditionResult struct {
+	Metric    AutomationMetric   `json:"metric"`
+	Operator  AutomationOperator `json:"operator"`
+	Threshold float64            `json:"threshold"`
+	Actual    *float64           `json:"actual,omitempty"`
+	Matched   bool               `json:"matched"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// AutomationEvaluation reports whether a rule fired and which scenario it generated
+type AutomationEvaluation struct {
+	RuleID      string                      `json:"ruleId"`
+	BuildingID  string                      `json:"buildingId"`
+	PeakStart   time.Time                   `json:"peakStart"`
+	PeakEnd     time.Time                   `json:"peakEnd"`
+	Matched     bool                        `json:"matched"`
+	Triggered   bool                        `json:"triggered"`
+	ScenarioID  string                      `json:"scenarioId,omitempty"`
+	Reason      string                      `json:"reason,omitempty"`
+	Conditions  []AutomationConditionResult `json:"conditions"`
+	EvaluatedAt time.Time                   `json:"evaluatedAt"`
+}
+
+// PreConditioningPlan describes a pre-conditioning scenario to generate for a peak window
+type PreConditioningPlan struct {
+	BuildingID        string
+	Action            AutomationAction
+	PeakStart         time.Time
+	PeakEnd           time.Time
+	LeadHours         int
+	TargetTemperature float64
+	PeakTemperature   float64
+	RuleName          string
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Building is a building registry entry. Its time zone is the one time-of-day settings of the
+// building are interpreted in: occupancy hours, scenario time windows and wall clock schedules.
+type Building struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BuildingID string             `bson:"building_id" json:"buildingId"`
+	Name       string             `bson:"name,omitempty" json:"name,omitempty"`
+	TimeZone   string             `bson:"timezone" json:"timezone"` // IANA name, e.g. "Europe/Berlin"
+	CreatedBy  string             `bson:"created_by" json:"createdBy"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// BuildingRequest represents the request to register a building or replace its entry
+type BuildingRequest struct {
+	Name     string `json:"name"`
+	TimeZone string `json:"timezone" binding:"required"`
+}
+
+// BuildingResponse represents a building registry entry in API responses. UTCOffset is the
+// building's current offset from UTC, e.g. "+02:00".
+type BuildingResponse struct {
+	ID         string    `json:"id"`
+	BuildingID string    `json:"buildingId"`
+	Name       string    `json:"name,omitempty"`
+	TimeZone   string    `json:"timezone"`
+	UTCOffset  string    `json:"utcOffset"`
+	CreatedBy  string    `json:"createdBy"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// ToResponse converts a Building to BuildingResponse
+func (b *Building) ToResponse() *BuildingResponse {
+	return &BuildingResponse{
+		ID:         b.ID.Hex(),
+		BuildingID: b.BuildingID,
+		Name:       b.Name,
+		TimeZone:   b.TimeZone,
+		UTCOffset:  time.Now().In(LoadTimeZone(b.TimeZone)).Format("-07:00"),
+		CreatedBy:  b.CreatedBy,
+		CreatedAt:  b.CreatedAt,
+		UpdatedAt:  b.UpdatedAt,
+	}
+}
+
+// LoadTimeZone returns the location of an IANA time zone name. Empty and unknown names are UTC.
+func LoadTimeZone(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// WallClockIn returns the time with t's wall clock reading in loc, for timestamps given
+// without a UTC offset
+func WallClockIn(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// wallClockLayouts are the accepted timestamp layouts without a UTC offset
+var wallClockLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// parseRequestTime parses an RFC 3339 timestamp, or a wall clock timestamp without a UTC offset.
+// Wall clock timestamps are returned in UTC with wallClock set, to be placed in a time zone later.
+func parseRequestTime(value string) (t time.Time, wallClock bool, err error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err = time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range wallClockLayouts {
+		if t, perr := time.Parse(layout, value); perr == nil {
+			return t, true, nil
+		}
+	}
+	return time.Time{}, false, err
+}
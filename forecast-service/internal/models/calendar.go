@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CalendarDayType classifies a day in the business calendar
+type CalendarDayType string
+
+const (
+	CalendarDayHoliday      CalendarDayType = "HOLIDAY"       // Closed all day, forecast like a weekend
+	CalendarDayHalfDay      CalendarDayType = "HALF_DAY"      // Closes early
+	CalendarDaySpecialEvent CalendarDayType = "SPECIAL_EVENT" // Different hours or load, e.g. an open day
+)
+
+// HalfDayDefaultCloseHour is the closing hour of half-days that do not set one
+const HalfDayDefaultCloseHour = 12
+
+// IsValid checks if the calendar day type is supported
+func (t CalendarDayType) IsValid() bool {
+	switch t {
+	case CalendarDayHoliday, CalendarDayHalfDay, CalendarDaySpecialEvent:
+		return true
+	}
+	return false
+}
+
+// CalendarDay is a business calendar entry for one building, or for every building in a region.
+// A building's own entry takes precedence over its region's entry for the same date.
+type CalendarDay struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BuildingID string             `bson:"building_id,omitempty" json:"buildingId,omitempty"`
+	Region     string             `bson:"region,omitempty" json:"region,omitempty"`
+	Date       string             `bson:"date" json:"date"` // YYYY-MM-DD in the building's time zone
+	Type       CalendarDayType    `bson:"type" json:"type"`
+	Name       string             `bson:"name" json:"name"`
+	// OpenHour and CloseHour replace the day's business hours; half-days only set CloseHour
+	OpenHour  *int `bson:"open_hour,omitempty" json:"openHour,omitempty"`
+	CloseHour *int `bson:"close_hour,omitempty" json:"closeHour,omitempty"`
+	// LoadFactor scales the forecast load of special events, e.g. 1.3 for a busy open day
+	LoadFactor float64   `bson:"load_factor,omitempty" json:"loadFactor,omitempty"`
+	CreatedBy  string    `bson:"created_by" json:"createdBy"`
+	CreatedAt  time.Time `bson:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+// CalendarDayRequest represents the request to create or replace a business calendar entry.
+// Exactly one of BuildingID and Region must be set.
+type CalendarDayRequest struct {
+	BuildingID string          `json:"buildingId"`
+	Region     string          `json:"region"`
+	Date       string          `json:"date" binding:"required"`
+	Type       CalendarDayType `json:"type" binding:"required"`
+	Name       string          `json:"name" binding:"required"`
+	OpenHour   *int            `json:"openHour"`
+	CloseHour  *int            `json:"closeHour"`
+	LoadFactor float64         `json:"loadFactor"`
+}
+
+// ListCalendarDaysRequest represents query parameters for listing business calendar entries.
+// Listing by building includes the entries of the building's region.
+type ListCalendarDaysRequest struct {
+	BuildingID string `form:"buildingId"`
+	Region     string `form:"region"`
+	From       string `form:"from"` // YYYY-MM-DD, inclusive
+	To         string `form:"to"`   // YYYY-MM-DD, inclusive
+}
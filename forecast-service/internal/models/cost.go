@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// LoadInterval is the energy used over one interval of a load profile
+type LoadInterval struct {
+	Start     time.Time `json:"start"`
+	Minutes   int       `json:"minutes"`
+	EnergyKWh float64   `json:"energyKWh"`
+}
+
+// CostComparisonRequest asks the Analytics service cost engine to price a baseline and an
+// optimized load profile with the same tariff
+type CostComparisonRequest struct {
+	Tariff    *Tariff        `json:"tariff"`
+	Baseline  []LoadInterval `json:"baseline"`
+	Optimized []LoadInterval `json:"optimized"`
+}
+
+// CostComparison is the cost difference between a baseline and an optimized load profile
+type CostComparison struct {
+	Currency      string  `json:"currency"`
+	EnergySavings float64 `json:"energySavings"`
+	DemandSavings float64 `json:"demandSavings"`
+	TotalSavings  float64 `json:"totalSavings"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// DemandChargeRequest represents query parameters for a demand charge forecast. Region selects
+// the tariff and defaults to "default"; ScenarioID optionally names a PEAK_SHAVING scenario whose
+// effect on the demand charge is estimated.
+type DemandChargeRequest struct {
+	BuildingID string `form:"buildingId" binding:"required"`
+	Region     string `form:"region"`
+	ScenarioID string `form:"scenarioId"`
+}
+
+// DemandChargeEstimate is the forecast billing-period peak and charge of one demand charge of the
+// tariff. UpperPeakKW uses the upper bound of the forecast for hours that have not been observed.
+// The previous period fields are omitted when no consumption was recorded in the charge window.
+type DemandChargeEstimate struct {
+	Name           string     `json:"name"`
+	RatePerKW      float64    `json:"ratePerKW"`
+	StartHour      int        `json:"startHour"`
+	EndHour        int        `json:"endHour"`
+	PeakKW         float64    `json:"peakKW"`
+	PeakAt         *time.Time `json:"peakAt,omitempty"`
+	PeakForecast   bool       `json:"peakForecast"` // Whether the peak lies in a forecast rather than an observed hour
+	UpperPeakKW    float64    `json:"upperPeakKW"`
+	Charge         float64    `json:"charge"`
+	UpperCharge    float64    `json:"upperCharge"`
+	PreviousPeakKW *float64   `json:"previousPeakKW,omitempty"`
+	PreviousCharge *float64   `json:"previousCharge,omitempty"`
+}
+
+// DemandChargeComparison compares the estimated charge with the previous billing period
+type DemandChargeComparison struct {
+	PreviousCharge float64  `json:"previousCharge"`
+	ChangeAmount   float64  `json:"changeAmount"`
+	ChangePercent  *float64 `json:"changePercent,omitempty"` // Omitted when the previous charge is zero
+}
+
+// DemandChargeShaving is the effect of a peak shaving scenario on one demand charge
+type DemandChargeShaving struct {
+	Name          string  `json:"name"`
+	PeakKW        float64 `json:"peakKW"`
+	ShavedPeakKW  float64 `json:"shavedPeakKW"`
+	Charge        float64 `json:"charge"`
+	ShavedCharge  float64 `json:"shavedCharge"`
+	ChargeSavings float64 `json:"chargeSavings"`
+}
+
+// DemandChargeScenarioImpact quantifies how much a PEAK_SHAVING scenario reduces the demand charge
+// of the billing period, apart from any energy cost savings. Actions outside the billing period
+// have no effect and are counted in ActionsOutsidePeriod.
+type DemandChargeScenarioImpact struct {
+	ScenarioID           string                `json:"scenarioId"`
+	ScenarioName         string                `json:"scenarioName"`
+	Status               OptimizationStatus    `json:"status"`
+	ActionsOutsidePeriod int                   `json:"actionsOutsidePeriod"`
+	Charges              []DemandChargeShaving `json:"charges"`
+	ChargeBefore         float64               `json:"chargeBefore"`
+	ChargeAfter          float64               `json:"chargeAfter"`
+	ChargeSavings        float64               `json:"chargeSavings"`
+}
+
+// DemandChargeForecast is the forecast demand charge of a building for the current billing
+// period, the calendar month in the building's time zone. Peaks combine the hourly load observed
+// so far with the latest demand forecast for the rest of the period; hours covered by neither are
+// counted in MissingHours. IntervalMinutes is the length of the forecast intervals peaks are
+// taken over: 15 or 30 when a sub-hourly forecast is available, otherwise 60.
+type DemandChargeForecast struct {
+	BuildingID      string                      `json:"buildingId"`
+	Region          string                      `json:"region"`
+	Currency        string                      `json:"currency"`
+	TimeZone        string                      `json:"timezone"`
+	PeriodStart     time.Time                   `json:"periodStart"`
+	PeriodEnd       time.Time                   `json:"periodEnd"`
+	ForecastID      string                      `json:"forecastId,omitempty"`
+	ObservedHours   int                         `json:"observedHours"`
+	ForecastHours   int                         `json:"forecastHours"`
+	MissingHours    int                         `json:"missingHours"`
+	IntervalMinutes int                         `json:"intervalMinutes"`
+	Charges         []DemandChargeEstimate      `json:"charges"`
+	EstimatedCharge float64                     `json:"estimatedCharge"`
+	UpperEstimate   float64                     `json:"upperEstimate"`
+	PreviousPeriod  *DemandChargeComparison     `json:"previousPeriod,omitempty"`
+	ScenarioImpact  *DemandChargeScenarioImpact `json:"scenarioImpact,omitempty"`
+	GeneratedAt     time.Time                   `json:"generatedAt"`
+}
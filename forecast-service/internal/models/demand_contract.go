@@ -0,0 +1,66 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DemandContract is the maximum demand a building contracted with its utility. Demand is
+// measured as the average load over windows of WindowMinutes; each kW of the highest window above
+// MaxDemandKW is charged PenaltyRatePerKW.
+type DemandContract struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BuildingID       string             `bson:"building_id" json:"buildingId"`
+	MaxDemandKW      float64            `bson:"max_demand_kw" json:"maxDemandKW"`
+	PenaltyRatePerKW float64            `bson:"penalty_rate_per_kw" json:"penaltyRatePerKW"`
+	WindowMinutes    int                `bson:"window_minutes" json:"windowMinutes"`
+	Currency         string             `bson:"currency" json:"currency"`
+	CreatedBy        string             `bson:"created_by" json:"createdBy"`
+	CreatedAt        time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// DefaultDemandWindowMinutes is the measurement window of contracts that do not state one
+const DefaultDemandWindowMinutes = 15
+
+// Window returns the length of the contract's measurement window
+func (c *DemandContract) Window() time.Duration {
+	if c.WindowMinutes <= 0 {
+		return DefaultDemandWindowMinutes * time.Minute
+	}
+	return time.Duration(c.WindowMinutes) * time.Minute
+}
+
+// Penalty returns the penalty charged for a peak demand of peakKW
+func (c *DemandContract) Penalty(peakKW float64) float64 {
+	return math.Max(0, peakKW-c.MaxDemandKW) * c.PenaltyRatePerKW
+}
+
+// DemandContractRequest represents the request to set the demand contract of a building
+type DemandContractRequest struct {
+	MaxDemandKW      float64 `json:"maxDemandKW" binding:"required,gt=0"`
+	PenaltyRatePerKW float64 `json:"penaltyRatePerKW" binding:"gte=0"`
+	WindowMinutes    int     `json:"windowMinutes" binding:"omitempty,oneof=15 30 60"`
+	Currency         string  `json:"currency"`
+}
+
+// ContractPenaltySavings is the penalty for exceeding a building's contracted maximum demand that
+// a scenario is projected to avoid. ProjectedPeakKW is the highest forecast demand during the
+// scenario, or the building's current load without a forecast; ShavedPeakKW is that demand less
+// the scenario's reductions.
+type ContractPenaltySavings struct {
+	MaxDemandKW     float64    `bson:"max_demand_kw" json:"maxDemandKW"`
+	WindowMinutes   int        `bson:"window_minutes" json:"windowMinutes"`
+	ProjectedPeakKW float64    `bson:"projected_peak_kw" json:"projectedPeakKW"`
+	PeakAt          *time.Time `bson:"peak_at,omitempty" json:"peakAt,omitempty"`
+	ShavedPeakKW    float64    `bson:"shaved_peak_kw" json:"shavedPeakKW"`
+	ExcessKW        float64    `bson:"excess_kw" json:"excessKW"`
+	RemainingExcess float64    `bson:"remaining_excess_kw" json:"remainingExcessKW"` // Above the limit even after the scenario
+	PenaltyBefore   float64    `bson:"penalty_before" json:"penaltyBefore"`
+	PenaltyAfter    float64    `bson:"penalty_after" json:"penaltyAfter"`
+	PenaltyAvoided  float64    `bson:"penalty_avoided" json:"penaltyAvoided"`
+	Currency        string     `bson:"currency" json:"currency"`
+	Forecast        bool       `bson:"forecast" json:"forecast"` // Whether the projected peak comes from a demand forecast
+}
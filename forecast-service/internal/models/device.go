@@ -39,12 +39,40 @@ type DaySchedule struct {
 // DeviceState represents the current state of a device from IoT service
 type DeviceState struct {
 	DeviceID       string                 `json:"deviceId"`
+	Type           string                 `json:"type,omitempty"` // device type catalog name
 	Status         string                 `json:"status"` // ONLINE, OFFLINE, ERROR
 	CurrentPower   float64                `json:"currentPower"` // in kW
 	CurrentState   string                 `json:"currentState"` // ON, OFF, STANDBY
 	LastReading    time.Time              `json:"lastReading"`
 	Parameters     map[string]interface{} `json:"parameters,omitempty"`
 	Controllable   bool                   `json:"controllable"`
+	Tags           []string               `json:"tags,omitempty"`
+}
+
+// DeviceType is an entry of the IoT service's device type catalog
+type DeviceType struct {
+	Name               string                 `json:"name"`
+	DisplayName        string                 `json:"displayName"`
+	SupportedCommands  []string               `json:"supportedCommands"`
+	TelemetryMetrics   []string               `json:"telemetryMetrics"`
+	Icon               string                 `json:"icon,omitempty"`
+	DefaultConstraints map[string]interface{} `json:"defaultConstraints,omitempty"`
+}
+
+// SupportsCommand reports whether devices of this type accept a command
+func (t *DeviceType) SupportsCommand(command string) bool {
+	for _, supported := range t.SupportedCommands {
+		if supported == command {
+			return true
+		}
+	}
+	return false
+}
+
+// Constraint returns a numeric default constraint such as maxTemperature
+func (t *DeviceType) Constraint(name string) (float64, bool) {
+	value, ok := t.DefaultConstraints[name].(float64)
+	return value, ok
 }
 
 // HistoricalConsumption represents historical consumption data
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FeatureKind identifies the kind of forecast input held in the feature store
+type FeatureKind string
+
+const (
+	FeatureKindWeather FeatureKind = "WEATHER"
+	FeatureKindTariff  FeatureKind = "TARIFF"
+)
+
+// Sources of feature snapshots
+const (
+	FeatureSourceWeatherAPI = "WEATHER_API"
+)
+
+// FeatureSnapshot is a forecast input fetched from an external source, kept so later forecasts
+// can reuse it while it is fresh and earlier forecasts can be traced back to the exact input.
+// Key is the building for weather and the region for tariffs.
+type FeatureSnapshot struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kind        FeatureKind        `bson:"kind" json:"kind"`
+	Key         string             `bson:"key" json:"key"`
+	Source      string             `bson:"source" json:"source"`
+	Weather     *Weather           `bson:"weather,omitempty" json:"weather,omitempty"`
+	Tariff      *Tariff            `bson:"tariff,omitempty" json:"tariff,omitempty"`
+	FetchedAt   time.Time          `bson:"fetched_at" json:"fetchedAt"`
+	Invalidated bool               `bson:"invalidated,omitempty" json:"invalidated,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// InputProvenance records where a forecast input came from
+type InputProvenance struct {
+	Kind       FeatureKind `bson:"kind" json:"kind"`
+	Key        string      `bson:"key" json:"key"`
+	Source     string      `bson:"source" json:"source"`
+	SnapshotID string      `bson:"snapshot_id,omitempty" json:"snapshotId,omitempty"`
+	FetchedAt  time.Time   `bson:"fetched_at" json:"fetchedAt"`
+	FromStore  bool        `bson:"from_store" json:"fromStore"` // Served from the feature store rather than fetched for this forecast
+}
+
+// Provenance returns the provenance of an input taken from this snapshot
+func (f *FeatureSnapshot) Provenance(fromStore bool) InputProvenance {
+	provenance := InputProvenance{
+		Kind:      f.Kind,
+		Key:       f.Key,
+		Source:    f.Source,
+		FetchedAt: f.FetchedAt,
+		FromStore: fromStore,
+	}
+	if !f.ID.IsZero() {
+		provenance.SnapshotID = f.ID.Hex()
+	}
+	return provenance
+}
@@ -26,6 +26,44 @@ const (
 	ForecastTypeLoad        ForecastType = "LOAD"
 )
 
+// ForecastResolution is the time step of forecast predictions
+type ForecastResolution string
+
+const (
+	ForecastResolutionQuarterHourly ForecastResolution = "QUARTER_HOURLY"
+	ForecastResolutionHalfHourly    ForecastResolution = "HALF_HOURLY"
+	ForecastResolutionHourly        ForecastResolution = "HOURLY"
+	ForecastResolutionDaily         ForecastResolution = "DAILY"
+	ForecastResolutionWeekly        ForecastResolution = "WEEKLY"
+)
+
+// Step returns the period one prediction covers
+func (r ForecastResolution) Step() time.Duration {
+	switch r {
+	case ForecastResolutionQuarterHourly:
+		return 15 * time.Minute
+	case ForecastResolutionHalfHourly:
+		return 30 * time.Minute
+	case ForecastResolutionDaily:
+		return 24 * time.Hour
+	case ForecastResolutionWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return time.Hour
+	}
+}
+
+// IsLongHorizon reports whether predictions are aggregated over days or weeks
+func (r ForecastResolution) IsLongHorizon() bool {
+	return r == ForecastResolutionDaily || r == ForecastResolutionWeekly
+}
+
+// IsSubHourly reports whether predictions are interpolated within the hour, e.g. for the
+// 15-minute intervals demand charges are billed on
+func (r ForecastResolution) IsSubHourly() bool {
+	return r == ForecastResolutionQuarterHourly || r == ForecastResolutionHalfHourly
+}
+
 // Forecast represents an energy demand forecast
 type Forecast struct {
 	ID              primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
@@ -34,10 +72,12 @@ type Forecast struct {
 	Type            ForecastType         `bson:"type" json:"type"`
 	Status          ForecastStatus       `bson:"status" json:"status"`
 	HorizonHours    int                  `bson:"horizon_hours" json:"horizonHours"`
+	Resolution      ForecastResolution   `bson:"resolution,omitempty" json:"resolution"`
 	StartTime       time.Time            `bson:"start_time" json:"startTime"`
 	EndTime         time.Time            `bson:"end_time" json:"endTime"`
 	Predictions     []ForecastPrediction `bson:"predictions" json:"predictions"`
 	Accuracy        *ForecastAccuracy    `bson:"accuracy,omitempty" json:"accuracy,omitempty"`
+	Actuals         *ForecastActuals     `bson:"actuals,omitempty" json:"actuals,omitempty"`
 	ModelUsed       string               `bson:"model_used" json:"modelUsed"`
 	InputParameters ForecastInputParams  `bson:"input_parameters" json:"inputParameters"`
 	Metadata        map[string]string    `bson:"metadata,omitempty" json:"metadata,omitempty"`
@@ -45,16 +85,52 @@ type Forecast struct {
 	UpdatedAt       time.Time            `bson:"updated_at" json:"updatedAt"`
 	CreatedBy       string               `bson:"created_by" json:"createdBy"`
 	ErrorMessage    string               `bson:"error_message,omitempty" json:"errorMessage,omitempty"`
+	CallbackURL     string               `bson:"callback_url,omitempty" json:"callbackUrl,omitempty"`
 }
 
-// ForecastPrediction represents a single prediction data point
+// ForecastPrediction represents a single prediction data point.
+// Hourly and sub-hourly predictions are average load in kW over their interval; daily and weekly predictions are energy in kWh
+// over the period starting at Timestamp.
 type ForecastPrediction struct {
-	Timestamp       time.Time `bson:"timestamp" json:"timestamp"`
-	PredictedValue  float64   `bson:"predicted_value" json:"predictedValue"`
-	LowerBound      float64   `bson:"lower_bound" json:"lowerBound"`
-	UpperBound      float64   `bson:"upper_bound" json:"upperBound"`
-	ConfidenceLevel float64   `bson:"confidence_level" json:"confidenceLevel"`
-	Unit            string    `bson:"unit" json:"unit"` // kWh, kW, etc.
+	Timestamp       time.Time          `bson:"timestamp" json:"timestamp"`
+	PredictedValue  float64            `bson:"predicted_value" json:"predictedValue"`
+	LowerBound      float64            `bson:"lower_bound" json:"lowerBound"`
+	UpperBound      float64            `bson:"upper_bound" json:"upperBound"`
+	ConfidenceLevel float64            `bson:"confidence_level" json:"confidenceLevel"`
+	Unit            string             `bson:"unit" json:"unit"` // kWh, kW, etc.
+	Resolution      ForecastResolution `bson:"resolution,omitempty" json:"resolution,omitempty"`
+	// Quantiles are the predicted values at the forecast's quantiles, keyed by label such as "p90":
+	// the actual value is expected to stay at or below each with that probability
+	Quantiles map[string]float64 `bson:"quantiles,omitempty" json:"quantiles,omitempty"`
+	// ActualValue is the observed value for the prediction's period, in the same unit, once the
+	// period has passed; Deviation is ActualValue minus PredictedValue
+	ActualValue      *float64 `bson:"actual_value,omitempty" json:"actualValue,omitempty"`
+	Deviation        *float64 `bson:"deviation,omitempty" json:"deviation,omitempty"`
+	DeviationPercent *float64 `bson:"deviation_percent,omitempty" json:"deviationPercent,omitempty"` // Nil when nothing was predicted
+}
+
+// ForecastActuals summarizes how a forecast compares with what actually happened so far
+type ForecastActuals struct {
+	PredictionsCompared int                `bson:"predictions_compared" json:"predictionsCompared"`
+	PredictionsTotal    int                `bson:"predictions_total" json:"predictionsTotal"`
+	MeanDeviation       float64            `bson:"mean_deviation" json:"meanDeviation"` // Positive when actuals ran above the forecast
+	MAE                 float64            `bson:"mae" json:"mae"`
+	MAPE                float64            `bson:"mape" json:"mape"`
+	WithinBoundsPercent float64            `bson:"within_bounds_percent" json:"withinBoundsPercent"`
+	Quantiles           []QuantileAccuracy `bson:"quantiles,omitempty" json:"quantiles,omitempty"`
+	MeanPinballLoss     *float64           `bson:"mean_pinball_loss,omitempty" json:"meanPinballLoss,omitempty"` // Averaged over the quantiles; nil without any
+	Complete            bool               `bson:"complete" json:"complete"`                                     // No further actuals will be backfilled
+	UpdatedAt           time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// QuantileAccuracy scores one quantile of a forecast against the actuals. A well calibrated
+// quantile has a coverage close to the quantile itself, e.g. 90% of actuals at or below p90.
+type QuantileAccuracy struct {
+	Quantile    float64 `bson:"quantile" json:"quantile"`
+	Label       string  `bson:"label" json:"label"`
+	PinballLoss float64 `bson:"pinball_loss" json:"pinballLoss"` // Mean over the compared predictions, in their unit
+	Coverage    float64 `bson:"coverage" json:"coverage"`         // Percentage of actuals at or below the quantile
+	Compared    int     `bson:"compared" json:"compared"`
 }
 
 // ForecastAccuracy represents forecast accuracy metrics
@@ -73,6 +149,8 @@ type ForecastInputParams struct {
 	SeasonalFactors   bool      `bson:"seasonal_factors" json:"seasonalFactors"`
 	WeatherData       *Weather  `bson:"weather_data,omitempty" json:"weatherData,omitempty"`
 	TariffData        *Tariff   `bson:"tariff_data,omitempty" json:"tariffData,omitempty"`
+	Provenance        []InputProvenance `bson:"provenance,omitempty" json:"provenance,omitempty"`
+	Quantiles         []float64 `bson:"quantiles,omitempty" json:"quantiles,omitempty"` // Quantiles predicted alongside each value, ascending
 }
 
 // Weather represents weather data used in forecasting
@@ -89,11 +167,14 @@ type Weather struct {
 // Tariff represents tariff data used in forecasting
 type Tariff struct {
 	Region        string       `bson:"region" json:"region"`
+	BaseRate      float64      `bson:"base_rate,omitempty" json:"baseRate,omitempty"` // Rate outside every time-of-use window
 	CurrentRate   float64      `bson:"current_rate" json:"currentRate"`
 	PeakRate      float64      `bson:"peak_rate" json:"peakRate"`
 	OffPeakRate   float64      `bson:"off_peak_rate" json:"offPeakRate"`
 	Currency      string       `bson:"currency" json:"currency"`
 	TimeOfUseRates []TariffRate `bson:"time_of_use_rates,omitempty" json:"timeOfUseRates,omitempty"`
+	DemandCharges  []DemandCharge `bson:"demand_charges,omitempty" json:"demandCharges,omitempty"`
+	Source         TariffSource   `bson:"source,omitempty" json:"source,omitempty"`
 }
 
 // TariffRate represents a time-of-use tariff rate
@@ -102,18 +183,23 @@ type TariffRate struct {
 	RatePerKWh float64 `bson:"rate_per_kwh" json:"ratePerKWh"`
 	StartHour  int     `bson:"start_hour" json:"startHour"`
 	EndHour    int     `bson:"end_hour" json:"endHour"`
+	DaysOfWeek []int   `bson:"days_of_week,omitempty" json:"daysOfWeek,omitempty"` // 0 = Sunday, empty = every day
 }
 
 // ForecastGenerateRequest represents the request to generate a forecast
 type ForecastGenerateRequest struct {
-	BuildingID     string            `json:"buildingId" binding:"required"`
-	DeviceID       string            `json:"deviceId"`
-	Type           ForecastType      `json:"type" binding:"required"`
-	HorizonHours   int               `json:"horizonHours"`
-	IncludeWeather bool              `json:"includeWeather"`
-	IncludeTariffs bool              `json:"includeTariffs"`
-	HistoricalDays int               `json:"historicalDays"`
-	Metadata       map[string]string `json:"metadata"`
+	BuildingID     string             `json:"buildingId" binding:"required"`
+	DeviceID       string             `json:"deviceId"`
+	Type           ForecastType       `json:"type" binding:"required,oneof=DEMAND CONSUMPTION LOAD"`
+	HorizonHours   int                `json:"horizonHours" binding:"omitempty,min=1,max=17568"` // Capped by the configured horizon limits
+	Resolution     ForecastResolution `json:"resolution" binding:"omitempty,oneof=QUARTER_HOURLY HALF_HOURLY HOURLY DAILY WEEKLY"`
+	IncludeWeather bool               `json:"includeWeather"`
+	IncludeTariffs bool               `json:"includeTariffs"`
+	HistoricalDays int                `json:"historicalDays" binding:"omitempty,min=1,max=3650"`
+	ModelType      string             `json:"modelType"` // Empty selects the default model chain
+	Metadata       map[string]string  `json:"metadata"`
+	CallbackURL    string             `json:"callbackUrl" binding:"omitempty,url"` // Notified when generation finishes
+	Quantiles      []float64          `json:"quantiles" binding:"omitempty,max=9,dive,gt=0,lt=1"` // Empty uses the configured quantiles
 }
 
 // ForecastResponse represents the forecast data returned in API responses
@@ -124,17 +210,26 @@ type ForecastResponse struct {
 	Type            ForecastType         `json:"type"`
 	Status          ForecastStatus       `json:"status"`
 	HorizonHours    int                  `json:"horizonHours"`
+	Resolution      ForecastResolution   `json:"resolution"`
 	StartTime       time.Time            `json:"startTime"`
 	EndTime         time.Time            `json:"endTime"`
 	Predictions     []ForecastPrediction `json:"predictions"`
 	Accuracy        *ForecastAccuracy    `json:"accuracy,omitempty"`
+	Quantiles       []float64            `json:"quantiles,omitempty"`
 	ModelUsed       string               `json:"modelUsed"`
 	CreatedAt       time.Time            `json:"createdAt"`
 	ErrorMessage    string               `json:"errorMessage,omitempty"`
+	Freshness       *ForecastFreshness   `json:"freshness,omitempty"`
 }
 
 // ToResponse converts a Forecast to ForecastResponse
 func (f *Forecast) ToResponse() *ForecastResponse {
+	resolution := f.Resolution
+	if resolution == "" {
+		// Forecasts created before resolutions were introduced are hourly
+		resolution = ForecastResolutionHourly
+	}
+
 	return &ForecastResponse{
 		ID:           f.ID.Hex(),
 		BuildingID:   f.BuildingID,
@@ -142,16 +237,110 @@ func (f *Forecast) ToResponse() *ForecastResponse {
 		Type:         f.Type,
 		Status:       f.Status,
 		HorizonHours: f.HorizonHours,
+		Resolution:   resolution,
 		StartTime:    f.StartTime,
 		EndTime:      f.EndTime,
 		Predictions:  f.Predictions,
 		Accuracy:     f.Accuracy,
+		Quantiles:    f.InputParameters.Quantiles,
 		ModelUsed:    f.ModelUsed,
 		CreatedAt:    f.CreatedAt,
 		ErrorMessage: f.ErrorMessage,
 	}
 }
 
+// InLocation returns the forecast's timestamps in the building's time zone, so they are rendered
+// with the building's UTC offset
+func (r *ForecastResponse) InLocation(loc *time.Location) *ForecastResponse {
+	r.StartTime = r.StartTime.In(loc)
+	r.EndTime = r.EndTime.In(loc)
+	r.CreatedAt = r.CreatedAt.In(loc)
+
+	predictions := make([]ForecastPrediction, len(r.Predictions))
+	for i, prediction := range r.Predictions {
+		prediction.Timestamp = prediction.Timestamp.In(loc)
+		predictions[i] = prediction
+	}
+	r.Predictions = predictions
+	if r.Freshness != nil {
+		r.Freshness.GeneratedAt = r.Freshness.GeneratedAt.In(loc)
+	}
+	return r
+}
+
+// ForecastWithActualsResponse is a forecast with the observed value next to each past prediction
+type ForecastWithActualsResponse struct {
+	*ForecastResponse
+	Actuals *ForecastActuals `json:"actuals"`
+}
+
+// ForecastStatusResponse reports the progress of an asynchronous forecast generation.
+// It is also the payload posted to the callback URL once generation finishes.
+type ForecastStatusResponse struct {
+	ID              string         `json:"id"`
+	BuildingID      string         `json:"buildingId"`
+	Type            ForecastType   `json:"type"`
+	Status          ForecastStatus `json:"status"`
+	ModelUsed       string         `json:"modelUsed"`
+	PredictionCount int            `json:"predictionCount"`
+	ErrorMessage    string         `json:"errorMessage,omitempty"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+}
+
+// ToStatusResponse converts a Forecast to ForecastStatusResponse
+func (f *Forecast) ToStatusResponse() *ForecastStatusResponse {
+	return &ForecastStatusResponse{
+		ID:              f.ID.Hex(),
+		BuildingID:      f.BuildingID,
+		Type:            f.Type,
+		Status:          f.Status,
+		ModelUsed:       f.ModelUsed,
+		PredictionCount: len(f.Predictions),
+		ErrorMessage:    f.ErrorMessage,
+		CreatedAt:       f.CreatedAt,
+		UpdatedAt:       f.UpdatedAt,
+	}
+}
+
+// ForecastSource describes where a returned forecast came from
+type ForecastSource string
+
+const (
+	ForecastSourceCache     ForecastSource = "CACHE"
+	ForecastSourceGenerated ForecastSource = "GENERATED"
+)
+
+// Reasons a cached forecast is considered stale
+const (
+	ForecastStaleExpired         = "EXPIRED"
+	ForecastStaleScheduleChanged = "SCHEDULE_CHANGED"
+	ForecastStaleInvalidated     = "INVALIDATED"
+)
+
+// ForecastFreshness describes how current a returned forecast is
+type ForecastFreshness struct {
+	Source        ForecastSource `json:"source"`
+	GeneratedAt   time.Time      `json:"generatedAt"`
+	AgeSeconds    int64          `json:"ageSeconds"`
+	MaxAgeSeconds int64          `json:"maxAgeSeconds"`
+	Stale         bool           `json:"stale"`
+	StaleReason   string         `json:"staleReason,omitempty"`
+	Refreshing    bool           `json:"refreshing"`
+}
+
+// ForecastInvalidateRequest represents a request to invalidate cached forecasts for a building
+type ForecastInvalidateRequest struct {
+	BuildingID string `json:"buildingId" binding:"required"`
+}
+
+// StoredForecastsRequest represents an internal request for the latest stored forecasts of
+// several buildings
+type StoredForecastsRequest struct {
+	BuildingIDs []string     `json:"buildingIds" binding:"required,min=1,dive,required"`
+	Type        ForecastType `json:"type"`
+}
+
 // DevicePrediction represents predicted consumption for a specific device
 type DevicePrediction struct {
 	DeviceID           string               `json:"deviceId"`
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// DeviceContribution is one device's predicted share of a building forecast interval
+type DeviceContribution struct {
+	DeviceID     string   `json:"deviceId"`
+	DeviceType   string   `json:"deviceType,omitempty"`
+	Value        float64  `json:"value"`
+	SharePercent *float64 `json:"sharePercent,omitempty"` // Omitted when the building total is not positive
+}
+
+// TypeContribution is the predicted share of all devices of one type in a forecast interval
+type TypeContribution struct {
+	DeviceType   string   `json:"deviceType"`
+	Value        float64  `json:"value"`
+	SharePercent *float64 `json:"sharePercent,omitempty"`
+}
+
+// ForecastBreakdownInterval decomposes one prediction of a building forecast. Residual is the
+// building total minus the device contributions: load of devices without a forecast, or the
+// difference between the models. It is negative when the device forecasts add up to more.
+type ForecastBreakdownInterval struct {
+	Timestamp       time.Time            `json:"timestamp"`
+	Total           float64              `json:"total"`
+	Contributions   []DeviceContribution `json:"contributions"`
+	ByType          []TypeContribution   `json:"byType,omitempty"`
+	Residual        float64              `json:"residual"`
+	ResidualPercent *float64             `json:"residualPercent,omitempty"`
+}
+
+// ForecastBreakdownDevice describes the device forecast a device's contributions come from
+type ForecastBreakdownDevice struct {
+	DeviceID         string    `json:"deviceId"`
+	DeviceType       string    `json:"deviceType,omitempty"`
+	ForecastID       string    `json:"forecastId"`
+	ModelUsed        string    `json:"modelUsed"`
+	ForecastedAt     time.Time `json:"forecastedAt"`
+	IntervalsCovered int       `json:"intervalsCovered"`
+	Total            float64   `json:"total"`
+	SharePercent     *float64  `json:"sharePercent,omitempty"` // Share of the building total over the covered intervals
+}
+
+// ForecastBreakdown decomposes a building forecast into the contributions of its devices, taken
+// from the latest completed device-level forecast of each device with the same type and
+// resolution. Values use the unit of the building forecast's predictions, and timestamps the
+// building's time zone.
+type ForecastBreakdown struct {
+	ForecastID  string                      `json:"forecastId"`
+	BuildingID  string                      `json:"buildingId"`
+	Type        ForecastType                `json:"type"`
+	Resolution  ForecastResolution          `json:"resolution"`
+	Unit        string                      `json:"unit"`
+	TimeZone    string                      `json:"timezone"`
+	Devices     []ForecastBreakdownDevice   `json:"devices"`
+	Intervals   []ForecastBreakdownInterval `json:"intervals"`
+	TypesKnown  bool                        `json:"typesKnown"` // Whether device types could be looked up in the IoT service
+	GeneratedAt time.Time                   `json:"generatedAt"`
+}
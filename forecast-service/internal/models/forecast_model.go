@@ -0,0 +1,20 @@
+package models
+
+// Forecast model names
+const (
+	ForecastModelNaiveSeasonal = "NAIVE_SEASONAL"
+	ForecastModelHoltWinters   = "HOLT_WINTERS"
+	ForecastModelExternalML    = "EXTERNAL_ML"
+	ForecastModelStatistical   = "STATISTICAL"
+	ForecastModelSynthetic     = "SYNTHETIC"
+)
+
+// ForecastModelInfo describes a registered forecasting model
+type ForecastModelInfo struct {
+	Name            string `json:"name"`
+	DisplayName     string `json:"displayName"`
+	Description     string `json:"description"`
+	External        bool   `json:"external"`        // Delegates to an external service
+	RequiresHistory bool   `json:"requiresHistory"` // Fails without historical consumption
+	MinHistoryHours int    `json:"minHistoryHours,omitempty"`
+}
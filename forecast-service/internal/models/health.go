@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Health status values reported by the health endpoints
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusDegraded  = "degraded"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// ComponentHealth represents the health of a single dependency
+type ComponentHealth struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DeepHealthResponse represents the aggregated health of a service and its dependencies.
+// The service is unhealthy when a critical component fails and degraded when any other does.
+type DeepHealthResponse struct {
+	Status     string                     `json:"status"`
+	Service    string                     `json:"service"`
+	Timestamp  time.Time                  `json:"timestamp"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// DegradedError is returned by health checks when a dependency is reachable but running with
+// reduced redundancy, so the component is reported degraded rather than unhealthy
+type DegradedError struct {
+	Reason string
+}
+
+func (e *DegradedError) Error() string {
+	return e.Reason
+}
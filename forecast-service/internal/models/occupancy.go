@@ -0,0 +1,281 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OccupancyPeriod classifies a point in time relative to a building's business hours
+type OccupancyPeriod string
+
+const (
+	OccupancyPeriodPreOpen    OccupancyPeriod = "PRE_OPEN"   // Ramp-up before the building opens
+	OccupancyPeriodOccupied   OccupancyPeriod = "OCCUPIED"   // Within business hours
+	OccupancyPeriodPostClose  OccupancyPeriod = "POST_CLOSE" // Wind-down after the building closes
+	OccupancyPeriodUnoccupied OccupancyPeriod = "UNOCCUPIED" // Outside business hours on an open day
+	OccupancyPeriodClosed     OccupancyPeriod = "CLOSED"     // Weekend, holiday or any day without business hours
+)
+
+// OccupancySource identifies how an occupancy status was determined
+type OccupancySource string
+
+const (
+	OccupancySourceSchedule OccupancySource = "SCHEDULE"
+	OccupancySourceDefault  OccupancySource = "DEFAULT"
+	OccupancySourceLive     OccupancySource = "LIVE"
+)
+
+// Holiday sources
+const (
+	HolidaySourceManual           = "MANUAL"
+	HolidaySourceCalendar         = "CALENDAR"
+	HolidaySourceBusinessCalendar = "BUSINESS_CALENDAR"
+)
+
+// OccupancyTransitionHours is the length of the ramp-up and wind-down periods around business hours
+const OccupancyTransitionHours = 3
+
+// OccupancySchedule represents the occupancy schedule of a building
+type OccupancySchedule struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	BuildingID    string               `bson:"building_id" json:"buildingId"`
+	TimeZone      string               `bson:"timezone,omitempty" json:"timezone,omitempty"`
+	Region        string               `bson:"region,omitempty" json:"region,omitempty"` // Business calendar region, e.g. "DE-BY"
+	BusinessHours []BusinessHours      `bson:"business_hours" json:"businessHours"`
+	Holidays      []Holiday            `bson:"holidays,omitempty" json:"holidays,omitempty"`
+	LiveOccupancy *LiveOccupancyConfig `bson:"live_occupancy,omitempty" json:"liveOccupancy,omitempty"`
+	CreatedBy     string               `bson:"created_by" json:"createdBy"`
+	CreatedAt     time.Time            `bson:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time            `bson:"updated_at" json:"updatedAt"`
+	// Calendar holds the business calendar entries of the building and its region, loaded with the schedule
+	Calendar []CalendarDay `bson:"-" json:"-"`
+}
+
+// BusinessHours represents the opening hours of a building on a day of the week
+type BusinessHours struct {
+	DayOfWeek int `bson:"day_of_week" json:"dayOfWeek"` // 0 = Sunday
+	OpenHour  int `bson:"open_hour" json:"openHour"`
+	CloseHour int `bson:"close_hour" json:"closeHour"`
+}
+
+// Holiday represents a day on which the building is closed
+type Holiday struct {
+	Date   string `bson:"date" json:"date"` // YYYY-MM-DD
+	Name   string `bson:"name" json:"name"`
+	Source string `bson:"source" json:"source"` // MANUAL, CALENDAR
+}
+
+// LiveOccupancyConfig configures occupancy sensors consulted for near-real-time decisions
+type LiveOccupancyConfig struct {
+	Enabled         bool     `bson:"enabled" json:"enabled"`
+	SensorDeviceIDs []string `bson:"sensor_device_ids" json:"sensorDeviceIds"`
+	Parameter       string   `bson:"parameter" json:"parameter"` // Device state parameter, e.g. "occupancy"
+	Threshold       float64  `bson:"threshold" json:"threshold"` // Readings at or above are considered occupied
+}
+
+// OccupancyStatus represents the resolved occupancy of a building at a point in time
+type OccupancyStatus struct {
+	BuildingID string          `json:"buildingId"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Occupied   bool            `json:"occupied"`
+	Period     OccupancyPeriod `json:"period"`
+	Source     OccupancySource `json:"source"`
+	Holiday    string          `json:"holiday,omitempty"`
+}
+
+// DefaultOccupancySchedule returns the schedule assumed for buildings without one (Mon-Fri, 9-17)
+func DefaultOccupancySchedule(buildingID string) *OccupancySchedule {
+	hours := make([]BusinessHours, 0, 5)
+	for day := time.Monday; day <= time.Friday; day++ {
+		hours = append(hours, BusinessHours{DayOfWeek: int(day), OpenHour: 9, CloseHour: 17})
+	}
+	return &OccupancySchedule{
+		BuildingID:    buildingID,
+		BusinessHours: hours,
+	}
+}
+
+// Location returns the schedule's time zone, UTC when none or an unknown one is configured
+func (s *OccupancySchedule) Location() *time.Location {
+	return LoadTimeZone(s.TimeZone)
+}
+
+// localTime converts t into the schedule's time zone, so server-local times are never used
+func (s *OccupancySchedule) localTime(t time.Time) time.Time {
+	return t.In(s.Location())
+}
+
+// HolidayAt returns the holiday that falls on the given time, if any. Business calendar
+// holidays count as well as the schedule's own.
+func (s *OccupancySchedule) HolidayAt(t time.Time) *Holiday {
+	date := s.localTime(t).Format("2006-01-02")
+	for i := range s.Holidays {
+		if s.Holidays[i].Date == date {
+			return &s.Holidays[i]
+		}
+	}
+	if day := s.CalendarDayAt(t); day != nil && day.Type == CalendarDayHoliday {
+		return &Holiday{Date: day.Date, Name: day.Name, Source: HolidaySourceBusinessCalendar}
+	}
+	return nil
+}
+
+// CalendarDayAt returns the business calendar entry for the day of the given time, preferring
+// the building's own entry over its region's
+func (s *OccupancySchedule) CalendarDayAt(t time.Time) *CalendarDay {
+	date := s.localTime(t).Format("2006-01-02")
+	var regional *CalendarDay
+	for i := range s.Calendar {
+		day := &s.Calendar[i]
+		if day.Date != date {
+			continue
+		}
+		if day.BuildingID != "" {
+			return day
+		}
+		regional = day
+	}
+	return regional
+}
+
+// LoadFactorAt returns how much a special event scales the load at the given time (1 otherwise)
+func (s *OccupancySchedule) LoadFactorAt(t time.Time) float64 {
+	if day := s.CalendarDayAt(t); day != nil && day.Type == CalendarDaySpecialEvent && day.LoadFactor > 0 {
+		return day.LoadFactor
+	}
+	return 1
+}
+
+// LastChanged returns when the schedule or its business calendar was last edited
+func (s *OccupancySchedule) LastChanged() time.Time {
+	changed := s.UpdatedAt
+	for _, day := range s.Calendar {
+		if day.UpdatedAt.After(changed) {
+			changed = day.UpdatedAt
+		}
+	}
+	return changed
+}
+
+// hoursFor returns the business hours for a day of the week, if the building opens that day
+func (s *OccupancySchedule) hoursFor(day time.Weekday) *BusinessHours {
+	for i := range s.BusinessHours {
+		if s.BusinessHours[i].DayOfWeek == int(day) {
+			return &s.BusinessHours[i]
+		}
+	}
+	return nil
+}
+
+// hoursOn returns the business hours of the day of the given local time: the weekday's hours,
+// shortened on half-days or replaced for special events with their own hours
+func (s *OccupancySchedule) hoursOn(local time.Time) *BusinessHours {
+	hours := s.hoursFor(local.Weekday())
+	day := s.CalendarDayAt(local)
+	if day == nil {
+		return hours
+	}
+
+	switch day.Type {
+	case CalendarDayHalfDay:
+		if hours == nil {
+			return nil
+		}
+		closeHour := HalfDayDefaultCloseHour
+		if day.CloseHour != nil {
+			closeHour = *day.CloseHour
+		}
+		if closeHour <= hours.OpenHour {
+			return nil
+		}
+		return &BusinessHours{DayOfWeek: hours.DayOfWeek, OpenHour: hours.OpenHour, CloseHour: min(closeHour, hours.CloseHour)}
+	case CalendarDaySpecialEvent:
+		if day.OpenHour != nil && day.CloseHour != nil {
+			return &BusinessHours{DayOfWeek: int(local.Weekday()), OpenHour: *day.OpenHour, CloseHour: *day.CloseHour}
+		}
+	}
+	return hours
+}
+
+// PeriodAt classifies the given time against business hours and holidays
+func (s *OccupancySchedule) PeriodAt(t time.Time) OccupancyPeriod {
+	if s.HolidayAt(t) != nil {
+		return OccupancyPeriodClosed
+	}
+
+	local := s.localTime(t)
+	hours := s.hoursOn(local)
+	if hours == nil {
+		return OccupancyPeriodClosed
+	}
+
+	hour := local.Hour()
+	switch {
+	case hour >= hours.OpenHour && hour < hours.CloseHour:
+		return OccupancyPeriodOccupied
+	case hour >= hours.OpenHour-OccupancyTransitionHours && hour < hours.OpenHour:
+		return OccupancyPeriodPreOpen
+	case hour >= hours.CloseHour && hour < hours.CloseHour+OccupancyTransitionHours:
+		return OccupancyPeriodPostClose
+	default:
+		return OccupancyPeriodUnoccupied
+	}
+}
+
+// IsOccupied checks whether the building is scheduled to be occupied at the given time
+func (s *OccupancySchedule) IsOccupied(t time.Time) bool {
+	return s.PeriodAt(t) == OccupancyPeriodOccupied
+}
+
+// OccupancyScheduleResponse represents an occupancy schedule in API responses
+type OccupancyScheduleResponse struct {
+	ID            string               `json:"id,omitempty"`
+	BuildingID    string               `json:"buildingId"`
+	TimeZone      string               `json:"timezone,omitempty"`
+	Region        string               `json:"region,omitempty"`
+	BusinessHours []BusinessHours      `json:"businessHours"`
+	Holidays      []Holiday            `json:"holidays,omitempty"`
+	LiveOccupancy *LiveOccupancyConfig `json:"liveOccupancy,omitempty"`
+	IsDefault     bool                 `json:"isDefault"`
+	CreatedBy     string               `json:"createdBy,omitempty"`
+	CreatedAt     time.Time            `json:"createdAt,omitempty"`
+	UpdatedAt     time.Time            `json:"updatedAt,omitempty"`
+}
+
+// ToResponse converts an OccupancySchedule to OccupancyScheduleResponse
+func (s *OccupancySchedule) ToResponse() *OccupancyScheduleResponse {
+	resp := &OccupancyScheduleResponse{
+		BuildingID:    s.BuildingID,
+		TimeZone:      s.TimeZone,
+		Region:        s.Region,
+		BusinessHours: s.BusinessHours,
+		Holidays:      s.Holidays,
+		LiveOccupancy: s.LiveOccupancy,
+		IsDefault:     s.ID.IsZero(),
+		CreatedBy:     s.CreatedBy,
+		CreatedAt:     s.CreatedAt,
+		UpdatedAt:     s.UpdatedAt,
+	}
+	if !s.ID.IsZero() {
+		resp.ID = s.ID.Hex()
+	}
+	return resp
+}
+
+// OccupancyScheduleRequest represents the request to create or replace an occupancy schedule
+type OccupancyScheduleRequest struct {
+	TimeZone      string               `json:"timezone"`
+	Region        string               `json:"region"`
+	BusinessHours []BusinessHours      `json:"businessHours" binding:"required"`
+	Holidays      []Holiday            `json:"holidays"`
+	LiveOccupancy *LiveOccupancyConfig `json:"liveOccupancy"`
+}
+
+// HolidayImportResult represents the outcome of a holiday calendar import
+type HolidayImportResult struct {
+	BuildingID string `json:"buildingId"`
+	Imported   int    `json:"imported"`
+	Skipped    int    `json:"skipped"`
+	Total      int    `json:"total"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -29,6 +31,8 @@ const (
 	OptimizationTypeEfficiency        OptimizationType = "EFFICIENCY"
 	OptimizationTypeComfort           OptimizationType = "COMFORT"
 	OptimizationTypeDemandResponse    OptimizationType = "DEMAND_RESPONSE"
+	OptimizationTypeBatteryDispatch   OptimizationType = "BATTERY_DISPATCH" // charges batteries off-peak and discharges them at peaks
+	OptimizationTypePortfolio         OptimizationType = "PORTFOLIO" // spans several buildings through child scenarios
 )
 
 // OptimizationScenario represents an optimization scenario
@@ -45,10 +49,15 @@ type OptimizationScenario struct {
 	Actions           []OptimizationAction    `bson:"actions" json:"actions"`
 	ExpectedSavings   Savings                 `bson:"expected_savings" json:"expectedSavings"`
 	ActualSavings     *Savings                `bson:"actual_savings,omitempty" json:"actualSavings,omitempty"`
+	Realization       *SavingsRealization     `bson:"realization,omitempty" json:"realization,omitempty"` // Set once correction factors were learned from the scenario
 	Constraints       OptimizationConstraints `bson:"constraints" json:"constraints"`
 	Priority          int                     `bson:"priority" json:"priority"` // 1-10, higher = more important
 	TariffData        *Tariff                 `bson:"tariff_data,omitempty" json:"tariffData,omitempty"`
+	BaselineLoadKW    float64                 `bson:"baseline_load_kw,omitempty" json:"baselineLoadKW,omitempty"` // Building load the actions change, used to price savings
 	WeatherData       *Weather                `bson:"weather_data,omitempty" json:"weatherData,omitempty"`
+	ParentScenarioID  string                  `bson:"parent_scenario_id,omitempty" json:"parentScenarioId,omitempty"` // Portfolio scenario this building scenario belongs to
+	Portfolio         *Portfolio              `bson:"portfolio,omitempty" json:"portfolio,omitempty"`                 // Set on PORTFOLIO scenarios
+	TemplateID        string                  `bson:"template_id,omitempty" json:"templateId,omitempty"`               // Template the scenario was generated from
 	CreatedAt         time.Time               `bson:"created_at" json:"createdAt"`
 	UpdatedAt         time.Time               `bson:"updated_at" json:"updatedAt"`
 	CreatedBy         string                  `bson:"created_by" json:"createdBy"`
@@ -56,6 +65,7 @@ type OptimizationScenario struct {
 	ApprovedAt        *time.Time              `bson:"approved_at,omitempty" json:"approvedAt,omitempty"`
 	ExecutionLog      []ExecutionLogEntry     `bson:"execution_log,omitempty" json:"executionLog,omitempty"`
 	ErrorMessage      string                  `bson:"error_message,omitempty" json:"errorMessage,omitempty"`
+	CapabilityWarnings []CapabilityViolation  `bson:"capability_warnings,omitempty" json:"capabilityWarnings,omitempty"` // Actions clamped to their device's capabilities
 }
 
 // OptimizationAction represents a single action in an optimization scenario
@@ -76,6 +86,22 @@ type OptimizationAction struct {
 	ErrorMessage    string    `bson:"error_message,omitempty" json:"errorMessage,omitempty"`
 }
 
+// CapabilityViolation describes an action that asks more of a device than its catalog entry
+// allows, e.g. a setpoint below the unit's minimum temperature. Field locates the action's value
+// in the scenario, e.g. "actions[2].targetValue".
+type CapabilityViolation struct {
+	Field      string  `bson:"field" json:"field"`
+	ActionID   string  `bson:"action_id" json:"actionId"`
+	DeviceID   string  `bson:"device_id" json:"deviceId"`
+	DeviceType string  `bson:"device_type" json:"deviceType"`
+	ActionType string  `bson:"action_type" json:"actionType"`
+	Capability string  `bson:"capability" json:"capability"` // catalog constraint, e.g. minTemperature, or supportedCommands
+	Requested  float64 `bson:"requested,omitempty" json:"requested,omitempty"`
+	Limit      float64 `bson:"limit,omitempty" json:"limit,omitempty"`
+	Clamped    bool    `bson:"clamped" json:"clamped"` // The target was moved to the limit
+	Message    string  `bson:"message" json:"message"`
+}
+
 // Savings represents energy and cost savings
 type Savings struct {
 	EnergyKWh       float64 `bson:"energy_kwh" json:"energyKWh"`
@@ -83,6 +109,8 @@ type Savings struct {
 	Currency        string  `bson:"currency" json:"currency"`
 	CO2ReductionKg  float64 `bson:"co2_reduction_kg" json:"co2ReductionKg"`
 	PercentReduction float64 `bson:"percent_reduction" json:"percentReduction"`
+	UncorrectedEnergyKWh float64 `bson:"uncorrected_energy_kwh,omitempty" json:"uncorrectedEnergyKWh,omitempty"` // Set when correction factors changed the estimate
+	ContractPenalty *ContractPenaltySavings `bson:"contract_penalty,omitempty" json:"contractPenalty,omitempty"` // Set on PEAK_SHAVING scenarios of buildings with a demand contract
 }
 
 // OptimizationConstraints represents constraints for optimization
@@ -97,7 +125,7 @@ type OptimizationConstraints struct {
 	TimeWindows         []TimeWindow `bson:"time_windows,omitempty" json:"timeWindows,omitempty"`
 }
 
-// TimeWindow represents a time window for optimization
+// TimeWindow represents a time window for optimization, in the building's time zone
 type TimeWindow struct {
 	StartTime string `bson:"start_time" json:"startTime"` // HH:MM format
 	EndTime   string `bson:"end_time" json:"endTime"`
@@ -116,7 +144,9 @@ type ExecutionLogEntry struct {
 type OptimizationGenerateRequest struct {
 	BuildingID      string                  `json:"buildingId" binding:"required"`
 	Name            string                  `json:"name"`
-	Type            OptimizationType        `json:"type" binding:"required"`
+	Type            OptimizationType        `json:"type" binding:"omitempty,oneof=COST_REDUCTION PEAK_SHAVING LOAD_BALANCING EFFICIENCY COMFORT DEMAND_RESPONSE BATTERY_DISPATCH"` // Required unless a template is given
+	TemplateID      string                  `json:"templateId"` // Fills in unset fields from a scenario template
+	DeviceTags      []string                `json:"deviceTags"` // Only devices with one of the tags are optimized
 	ScheduledStart  time.Time               `json:"scheduledStart"`
 	ScheduledEnd    time.Time               `json:"scheduledEnd"`
 	ForecastID      string                  `json:"forecastId"`
@@ -124,6 +154,50 @@ type OptimizationGenerateRequest struct {
 	UseWeatherData  bool                    `json:"useWeatherData"`
 	Constraints     OptimizationConstraints `json:"constraints"`
 	Priority        int                     `json:"priority"`
+
+	// Set when the scheduled start or end was given without a UTC offset
+	wallClockStart bool
+	wallClockEnd   bool
+}
+
+// UnmarshalJSON also accepts scheduledStart and scheduledEnd without a UTC offset, e.g.
+// "2024-07-01T08:00", as wall clock times in the building's time zone
+func (r *OptimizationGenerateRequest) UnmarshalJSON(data []byte) error {
+	type plain OptimizationGenerateRequest
+	aux := struct {
+		*plain
+		ScheduledStart string `json:"scheduledStart"`
+		ScheduledEnd   string `json:"scheduledEnd"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if r.ScheduledStart, r.wallClockStart, err = parseRequestTime(aux.ScheduledStart); err != nil {
+		return fmt.Errorf("invalid scheduledStart: %w", err)
+	}
+	if r.ScheduledEnd, r.wallClockEnd, err = parseRequestTime(aux.ScheduledEnd); err != nil {
+		return fmt.Errorf("invalid scheduledEnd: %w", err)
+	}
+	return nil
+}
+
+// HasWallClockTimes reports whether the scheduled start or end was given without a UTC offset
+func (r *OptimizationGenerateRequest) HasWallClockTimes() bool {
+	return r.wallClockStart || r.wallClockEnd
+}
+
+// ApplyTimeZone places scheduled times given without a UTC offset in the building's time zone
+func (r *OptimizationGenerateRequest) ApplyTimeZone(loc *time.Location) {
+	if r.wallClockStart {
+		r.ScheduledStart = WallClockIn(r.ScheduledStart, loc)
+		r.wallClockStart = false
+	}
+	if r.wallClockEnd {
+		r.ScheduledEnd = WallClockIn(r.ScheduledEnd, loc)
+		r.wallClockEnd = false
+	}
 }
 
 // OptimizationScenarioResponse represents the optimization scenario in API responses
@@ -140,12 +214,18 @@ type OptimizationScenarioResponse struct {
 	Actions         []OptimizationAction    `json:"actions"`
 	ExpectedSavings Savings                 `json:"expectedSavings"`
 	ActualSavings   *Savings                `json:"actualSavings,omitempty"`
+	Realization     *SavingsRealization     `json:"realization,omitempty"`
 	Constraints     OptimizationConstraints `json:"constraints"`
 	Priority        int                     `json:"priority"`
 	CreatedAt       time.Time               `json:"createdAt"`
 	CreatedBy       string                  `json:"createdBy"`
 	ApprovedBy      string                  `json:"approvedBy,omitempty"`
 	ErrorMessage    string                  `json:"errorMessage,omitempty"`
+	ParentScenarioID string                 `json:"parentScenarioId,omitempty"`
+	Portfolio       *Portfolio              `json:"portfolio,omitempty"`
+	TemplateID      string                  `json:"templateId,omitempty"`
+	Conflicts       []ScenarioConflict      `json:"conflicts,omitempty"`
+	CapabilityWarnings []CapabilityViolation `json:"capabilityWarnings,omitempty"`
 }
 
 // ToResponse converts an OptimizationScenario to OptimizationScenarioResponse
@@ -163,28 +243,153 @@ func (o *OptimizationScenario) ToResponse() *OptimizationScenarioResponse {
 		Actions:         o.Actions,
 		ExpectedSavings: o.ExpectedSavings,
 		ActualSavings:   o.ActualSavings,
+		Realization:     o.Realization,
 		Constraints:     o.Constraints,
 		Priority:        o.Priority,
 		CreatedAt:       o.CreatedAt,
 		CreatedBy:       o.CreatedBy,
 		ApprovedBy:      o.ApprovedBy,
 		ErrorMessage:    o.ErrorMessage,
+		ParentScenarioID: o.ParentScenarioID,
+		Portfolio:       o.Portfolio,
+		TemplateID:      o.TemplateID,
+		CapabilityWarnings: o.CapabilityWarnings,
+	}
+}
+
+// InLocation returns the scenario's timestamps in the building's time zone, so they are
+// rendered with the building's UTC offset
+func (r *OptimizationScenarioResponse) InLocation(loc *time.Location) *OptimizationScenarioResponse {
+	r.ScheduledStart = r.ScheduledStart.In(loc)
+	r.ScheduledEnd = r.ScheduledEnd.In(loc)
+	r.CreatedAt = r.CreatedAt.In(loc)
+
+	actions := make([]OptimizationAction, len(r.Actions))
+	for i, action := range r.Actions {
+		action.ScheduledTime = action.ScheduledTime.In(loc)
+		if action.ExecutedAt != nil {
+			executedAt := action.ExecutedAt.In(loc)
+			action.ExecutedAt = &executedAt
+		}
+		actions[i] = action
 	}
+	r.Actions = actions
+	return r
 }
 
 // SendToIoTRequest represents the request to send a scenario to IoT service
 type SendToIoTRequest struct {
-	ScenarioID  string `json:"scenarioId" binding:"required"`
-	ExecuteNow  bool   `json:"executeNow"`
-	DryRun      bool   `json:"dryRun"`
+	ScenarioID         string             `json:"scenarioId" binding:"required"`
+	ExecuteNow         bool               `json:"executeNow"`
+	DryRun             bool               `json:"dryRun"`
+	ConflictResolution ConflictResolution `json:"conflictResolution"`
 }
 
 // SendToIoTResponse represents the response from sending to IoT service
 type SendToIoTResponse struct {
-	Success       bool     `json:"success"`
-	ScenarioID    string   `json:"scenarioId"`
-	ActionsQueued int      `json:"actionsQueued"`
-	ActionsSkipped int     `json:"actionsSkipped"`
-	Errors        []string `json:"errors,omitempty"`
-	ExecutionID   string   `json:"executionId,omitempty"`
+	Success            bool               `json:"success"`
+	ScenarioID         string             `json:"scenarioId"`
+	ActionsQueued      int                `json:"actionsQueued"`
+	ActionsSkipped     int                `json:"actionsSkipped"`
+	Errors             []string           `json:"errors,omitempty"`
+	ExecutionID        string             `json:"executionId,omitempty"`
+	Conflicts          []ScenarioConflict `json:"conflicts,omitempty"`
+	CancelledScenarios []string           `json:"cancelledScenarios,omitempty"`
+	ActionsDropped     int                `json:"actionsDropped,omitempty"`
+	Preview            *ScenarioPreview   `json:"preview,omitempty"` // Set for dry runs
+	CapabilityViolations []CapabilityViolation `json:"capabilityViolations,omitempty"`
+}
+
+// ConflictResolution controls how conflicts with active scenarios are handled on approval
+type ConflictResolution string
+
+const (
+	// ConflictResolutionReject refuses to approve a conflicting scenario (default)
+	ConflictResolutionReject ConflictResolution = "REJECT"
+	// ConflictResolutionPriority cancels conflicting approved scenarios with a lower priority
+	ConflictResolutionPriority ConflictResolution = "PRIORITY"
+	// ConflictResolutionMerge drops the conflicting actions from the scenario being approved
+	ConflictResolutionMerge ConflictResolution = "MERGE"
+)
+
+// IsValid reports whether the conflict resolution is supported
+func (r ConflictResolution) IsValid() bool {
+	switch r {
+	case ConflictResolutionReject, ConflictResolutionPriority, ConflictResolutionMerge:
+		return true
+	}
+	return false
+}
+
+// ScenarioConflict describes an approved or executing scenario that controls the same
+// devices in an overlapping time window
+type ScenarioConflict struct {
+	ScenarioID   string             `json:"scenarioId"`
+	ScenarioName string             `json:"scenarioName"`
+	Status       OptimizationStatus `json:"status"`
+	Priority     int                `json:"priority"`
+	DeviceIDs    []string           `json:"deviceIds"`
+	OverlapStart time.Time          `json:"overlapStart"`
+	OverlapEnd   time.Time          `json:"overlapEnd"`
+}
+
+// ScenarioConflictsResponse represents the conflicts of a scenario in API responses
+type ScenarioConflictsResponse struct {
+	ScenarioID   string             `json:"scenarioId"`
+	HasConflicts bool               `json:"hasConflicts"`
+	Conflicts    []ScenarioConflict `json:"conflicts"`
+}
+
+// ScenarioPreview is the IoT service's dry run of a scenario: the predicted outcome of each
+// action against the current device state. No command is sent.
+type ScenarioPreview struct {
+	Actions     []PreviewAction `json:"actions"`
+	Summary     PreviewSummary  `json:"summary"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+}
+
+// Add merges the dry run of another scenario, such as another building of a portfolio
+func (p *ScenarioPreview) Add(other *ScenarioPreview) {
+	p.Actions = append(p.Actions, other.Actions...)
+	p.Summary.TotalActions += other.Summary.TotalActions
+	p.Summary.WouldSucceed += other.Summary.WouldSucceed
+	p.Summary.Skipped += other.Summary.Skipped
+	p.Summary.Conflicts += other.Summary.Conflicts
+	p.Summary.Failed += other.Summary.Failed
+	p.Summary.PowerChangeKW += other.Summary.PowerChangeKW
+	if other.GeneratedAt.After(p.GeneratedAt) {
+		p.GeneratedAt = other.GeneratedAt
+	}
+}
+
+// PreviewAction is the predicted outcome of a scenario action: SUCCEED, SKIP (device offline or
+// excluded), CONFLICT (device under maintenance or controlled by another scenario) or FAIL
+type PreviewAction struct {
+	DeviceID             string                 `json:"deviceId"`
+	Command              string                 `json:"command"`
+	Params               map[string]interface{} `json:"params,omitempty"`
+	Outcome              string                 `json:"outcome"`
+	Reason               string                 `json:"reason,omitempty"`
+	DeviceStatus         string                 `json:"deviceStatus,omitempty"`
+	ConflictingScenarios []string               `json:"conflictingScenarios,omitempty"`
+	Changes              []PreviewStateChange   `json:"changes,omitempty"`
+}
+
+// PreviewStateChange is the estimated change of a device metric
+type PreviewStateChange struct {
+	Metric       string   `json:"metric"`
+	Current      *float64 `json:"current,omitempty"`
+	Target       float64  `json:"target"`
+	Delta        *float64 `json:"delta,omitempty"`
+	DeltaPercent *float64 `json:"deltaPercent,omitempty"`
+}
+
+// PreviewSummary counts the predicted outcomes of a scenario's actions
+type PreviewSummary struct {
+	TotalActions  int     `json:"totalActions"`
+	WouldSucceed  int     `json:"wouldSucceed"`
+	Skipped       int     `json:"skipped"`
+	Conflicts     int     `json:"conflicts"`
+	Failed        int     `json:"failed"`
+	PowerChangeKW float64 `json:"powerChangeKw"`
 }
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// ScenarioExportFormatVersion is the version of the portable scenario format
+const ScenarioExportFormatVersion = 1
+
+// Scenario export file formats
+const (
+	ScenarioExportFormatJSON = "json"
+	ScenarioExportFormatYAML = "yaml"
+)
+
+// ScenarioExport is a portable copy of an optimization scenario that can be imported into another
+// building. It leaves out IDs, status and execution history; action times are offsets from the
+// scenario's start so the scenario can be rescheduled.
+type ScenarioExport struct {
+	FormatVersion    int                     `json:"formatVersion"`
+	Name             string                  `json:"name"`
+	Description      string                  `json:"description"`
+	Type             OptimizationType        `json:"type"`
+	Priority         int                     `json:"priority"`
+	DurationMinutes  int                     `json:"durationMinutes"`
+	SourceBuildingID string                  `json:"sourceBuildingId,omitempty"`
+	Constraints      OptimizationConstraints `json:"constraints"`
+	Actions          []ScenarioExportAction  `json:"actions"`
+	ExpectedSavings  Savings                 `json:"expectedSavings"`
+	ExportedAt       time.Time               `json:"exportedAt"`
+}
+
+// ScenarioExportAction is an action of an exported scenario
+type ScenarioExportAction struct {
+	DeviceID       string  `json:"deviceId"`
+	DeviceName     string  `json:"deviceName"`
+	DeviceType     string  `json:"deviceType"`
+	ActionType     string  `json:"actionType"`
+	TargetValue    string  `json:"targetValue"`
+	OffsetMinutes  int     `json:"offsetMinutes"` // after the scenario's start
+	Duration       int     `json:"duration"`      // in minutes
+	ExpectedImpact float64 `json:"expectedImpact"`
+}
+
+// ScenarioImportRequest represents the request to import an exported scenario into a building.
+// DeviceMapping maps device IDs of the exported scenario to devices of the target building;
+// unmapped devices keep their ID.
+type ScenarioImportRequest struct {
+	BuildingID     string            `json:"buildingId" binding:"required"`
+	Name           string            `json:"name"`
+	ScheduledStart time.Time         `json:"scheduledStart"`
+	DeviceMapping  map[string]string `json:"deviceMapping"`
+	Scenario       *ScenarioExport   `json:"scenario" binding:"required"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CorrectionFactor accumulates how much of the expected demand reduction of an action type was
+// delivered on a device type, or on a single device when DeviceID is set. The factor is the
+// delivered share: the actual reduction divided by the expected one, summed over the verified
+// scenarios the actions took part in.
+type CorrectionFactor struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	ActionType     string             `bson:"action_type"`
+	DeviceType     string             `bson:"device_type"`
+	DeviceID       string             `bson:"device_id"` // Empty for the factor of the whole device type
+	ExpectedKW     float64            `bson:"expected_kw"`
+	ActualKW       float64            `bson:"actual_kw"`
+	Samples        int                `bson:"samples"`
+	LastScenarioID string             `bson:"last_scenario_id"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
+}
+
+// Factor returns the delivered share of the expected reduction, 1 before any was observed
+func (f *CorrectionFactor) Factor() float64 {
+	if f.ExpectedKW <= 0 {
+		return 1
+	}
+	return f.ActualKW / f.ExpectedKW
+}
+
+// CorrectionFactorQuery represents query parameters for listing correction factors
+type CorrectionFactorQuery struct {
+	ActionType string `form:"actionType"`
+	DeviceType string `form:"deviceType"`
+	DeviceID   string `form:"deviceId"`
+}
+
+// CorrectionFactorResponse represents a correction factor in API responses. Applied is set once
+// the factor has enough samples to scale the expected savings of new scenarios.
+type CorrectionFactorResponse struct {
+	ActionType     string    `json:"actionType"`
+	DeviceType     string    `json:"deviceType"`
+	DeviceID       string    `json:"deviceId,omitempty"`
+	Factor         float64   `json:"factor"`
+	ExpectedKW     float64   `json:"expectedKW"`
+	ActualKW       float64   `json:"actualKW"`
+	Samples        int       `json:"samples"`
+	Applied        bool      `json:"applied"`
+	LastScenarioID string    `json:"lastScenarioId"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// VerifiedSavings is an M&V verification of the savings of an executed scenario. The mean hourly
+// demands of the changed devices are only measured by option A, which isolates the devices.
+type VerifiedSavings struct {
+	ReportID         string
+	ScenarioID       string
+	Option           string
+	DeviceIDs        []string
+	BaselineMeanKWh  float64
+	ReportingMeanKWh float64
+	SavingsKWh       float64
+	SavingsPercent   float64
+}
+
+// SavingsRealization records the verification a scenario's actions were learned from: the
+// demand reduction its changed devices were expected to deliver and the one measured
+type SavingsRealization struct {
+	ReportID     string    `bson:"report_id" json:"reportId"`
+	ExpectedKW   float64   `bson:"expected_kw" json:"expectedKW"`
+	ActualKW     float64   `bson:"actual_kw" json:"actualKW"`
+	Ratio        float64   `bson:"ratio" json:"ratio"` // Share of the expected reduction credited to each action
+	ReconciledAt time.Time `bson:"reconciled_at" json:"reconciledAt"`
+}
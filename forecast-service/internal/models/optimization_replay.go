@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// Replay pricing methods
+const (
+	ReplayPricedByCostEngine = "COST_ENGINE" // Analytics service cost engine, with demand charges
+	ReplayPricedByEnergyRate = "ENERGY_RATE" // Hourly energy rates of the tariff only
+)
+
+// OptimizationReplayRequest asks how an optimization strategy would have performed over a past
+// period. From and To are rounded down to whole hours; the period may be at most 31 days and must
+// have ended. Region selects the tariff and defaults to "default".
+type OptimizationReplayRequest struct {
+	BuildingID  string                  `json:"buildingId" binding:"required"`
+	Type        OptimizationType        `json:"type" binding:"required,oneof=COST_REDUCTION PEAK_SHAVING EFFICIENCY DEMAND_RESPONSE"`
+	From        time.Time               `json:"from" binding:"required"`
+	To          time.Time               `json:"to" binding:"required"`
+	Region      string                  `json:"region"`
+	DeviceTags  []string                `json:"deviceTags"` // Only devices with one of the tags are optimized
+	Constraints OptimizationConstraints `json:"constraints"`
+}
+
+// ReplaySavings is the energy and cost a strategy saved, or would have saved, over a replay period
+type ReplaySavings struct {
+	EnergyKWh        float64 `json:"energyKWh"`
+	CostAmount       float64 `json:"costAmount"`
+	CO2ReductionKg   float64 `json:"co2ReductionKg"`
+	PercentReduction float64 `json:"percentReduction"` // Of the building's consumption over the period
+}
+
+// ReplayDevice is what the strategy would have done with one device over the replay period
+type ReplayDevice struct {
+	DeviceID    string  `json:"deviceId"`
+	DeviceType  string  `json:"deviceType"`
+	ActionType  string  `json:"actionType,omitempty"` // Empty when the strategy never acted on the device
+	ActualKWh   float64 `json:"actualKWh"`
+	SavedKWh    float64 `json:"savedKWh"`
+	ActiveHours int     `json:"activeHours"` // Hours in which the strategy would have acted
+}
+
+// ReplayExecutedScenario is a scenario that was executed in the building during the replay
+// period. Savings are those verified by an M&V report where available, else those expected.
+type ReplayExecutedScenario struct {
+	ScenarioID     string           `json:"scenarioId"`
+	Name           string           `json:"name"`
+	Type           OptimizationType `json:"type"`
+	ScheduledStart time.Time        `json:"scheduledStart"`
+	ScheduledEnd   time.Time        `json:"scheduledEnd"`
+	EnergyKWh      float64          `json:"energyKWh"`
+	CostAmount     float64          `json:"costAmount"`
+	Verified       bool             `json:"verified"`
+}
+
+// ReplayHour is the hourly load profile of a replay, in kWh
+type ReplayHour struct {
+	Start        time.Time `json:"start"`
+	ActualKWh    float64   `json:"actualKWh"`
+	SimulatedKWh float64   `json:"simulatedKWh"`
+}
+
+// OptimizationReplay compares what an optimization strategy would have saved over a past period
+// with what the building actually used and the scenarios that actually ran. The strategy is
+// re-planned every hour from the devices' recorded consumption in that hour, the occupancy
+// schedule and the tariff in effect, so Simulated is the building's consumption less the savings
+// of those actions. Realized sums the executed scenarios, and Missed is Simulated less Realized;
+// it is negative where the scenarios that ran did better than the strategy.
+type OptimizationReplay struct {
+	BuildingID            string                   `json:"buildingId"`
+	Type                  OptimizationType         `json:"type"`
+	Region                string                   `json:"region"`
+	Currency              string                   `json:"currency"`
+	TimeZone              string                   `json:"timezone"`
+	From                  time.Time                `json:"from"`
+	To                    time.Time                `json:"to"`
+	Hours                 int                      `json:"hours"`
+	ObservedHours         int                      `json:"observedHours"` // Hours with recorded building consumption
+	PricedBy              string                   `json:"pricedBy"`
+	TariffSource          TariffSource             `json:"tariffSource,omitempty"`
+	ActualKWh             float64                  `json:"actualKWh"`
+	SimulatedKWh          float64                  `json:"simulatedKWh"`
+	Simulated             ReplaySavings            `json:"simulated"`
+	Realized              ReplaySavings            `json:"realized"`
+	Missed                ReplaySavings            `json:"missed"`
+	Devices               []ReplayDevice           `json:"devices"`
+	DevicesWithoutHistory []string                 `json:"devicesWithoutHistory,omitempty"`
+	ExecutedScenarios     []ReplayExecutedScenario `json:"executedScenarios"`
+	Hourly                []ReplayHour             `json:"hourly"`
+	GeneratedAt           time.Time                `json:"generatedAt"`
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"forecast-service/internal/i18n"
 )
 
 // PeakLoadSeverity represents the severity level of a peak load prediction
@@ -16,6 +18,17 @@ const (
 	PeakLoadSeverityCritical PeakLoadSeverity = "CRITICAL"
 )
 
+// PeakLoadBasis is the predicted value peak periods are detected on
+type PeakLoadBasis string
+
+const (
+	// PeakLoadBasisExpected detects peaks on the predicted value
+	PeakLoadBasisExpected PeakLoadBasis = "EXPECTED"
+	// PeakLoadBasisP90 detects peaks on the 90th percentile, which the load exceeds only one
+	// time in ten, for conservative planning
+	PeakLoadBasisP90 PeakLoadBasis = "P90"
+)
+
 // PeakLoad represents a peak load forecast
 type PeakLoad struct {
 	ID               primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
@@ -25,6 +38,7 @@ type PeakLoad struct {
 	BaselineLoad     float64               `bson:"baseline_load" json:"baselineLoad"`
 	MaxPredictedLoad float64               `bson:"max_predicted_load" json:"maxPredictedLoad"`
 	ThresholdPercent float64               `bson:"threshold_percent" json:"thresholdPercent"`
+	Basis            PeakLoadBasis         `bson:"basis,omitempty" json:"basis,omitempty"`
 	AnalysisPeriod   AnalysisPeriod        `bson:"analysis_period" json:"analysisPeriod"`
 	Contributing     []ContributingFactor  `bson:"contributing_factors" json:"contributingFactors"`
 	Recommendations  []string              `bson:"recommendations" json:"recommendations"`
@@ -61,9 +75,10 @@ type ContributingFactor struct {
 type PeakLoadRequest struct {
 	BuildingID       string    `json:"buildingId" binding:"required"`
 	AnalysisFromDate time.Time `json:"analysisFromDate"`
-	AnalysisToDate   time.Time `json:"analysisToDate"`
+	AnalysisToDate   time.Time `json:"analysisToDate" binding:"omitempty,gtfield=AnalysisFromDate"`
 	ThresholdPercent float64   `json:"thresholdPercent"` // Percentage above baseline to consider peak
 	IncludeWeather   bool      `json:"includeWeather"`
+	Conservative     bool      `json:"conservative"` // Detect peaks on the p90 quantile instead of the predicted value
 }
 
 // PeakLoadResponse represents the peak load data returned in API responses
@@ -75,6 +90,7 @@ type PeakLoadResponse struct {
 	BaselineLoad     float64              `json:"baselineLoad"`
 	MaxPredictedLoad float64              `json:"maxPredictedLoad"`
 	ThresholdPercent float64              `json:"thresholdPercent"`
+	Basis            PeakLoadBasis        `json:"basis,omitempty"`
 	AnalysisPeriod   AnalysisPeriod       `json:"analysisPeriod"`
 	Contributing     []ContributingFactor `json:"contributingFactors"`
 	Recommendations  []string             `json:"recommendations"`
@@ -91,6 +107,7 @@ func (p *PeakLoad) ToResponse() *PeakLoadResponse {
 		BaselineLoad:     p.BaselineLoad,
 		MaxPredictedLoad: p.MaxPredictedLoad,
 		ThresholdPercent: p.ThresholdPercent,
+		Basis:            p.Basis,
 		AnalysisPeriod:   p.AnalysisPeriod,
 		Contributing:     p.Contributing,
 		Recommendations:  p.Recommendations,
@@ -98,6 +115,20 @@ func (p *PeakLoad) ToResponse() *PeakLoadResponse {
 	}
 }
 
+// Localize translates the recommendations and mitigation actions into the given language
+func (r *PeakLoadResponse) Localize(lang string) {
+	r.Recommendations = i18n.TranslateAll(lang, r.Recommendations)
+	if r.PredictedPeaks == nil {
+		return
+	}
+	peaks := make([]PeakPeriod, len(r.PredictedPeaks))
+	for i, peak := range r.PredictedPeaks {
+		peak.MitigationActions = i18n.TranslateAll(lang, peak.MitigationActions)
+		peaks[i] = peak
+	}
+	r.PredictedPeaks = peaks
+}
+
 // PeakLoadSummary provides a summary of peak load analysis
 type PeakLoadSummary struct {
 	BuildingID          string  `json:"buildingId"`
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Portfolio describes how a PORTFOLIO scenario spreads a curtailment target across buildings
+type Portfolio struct {
+	BuildingIDs         []string              `bson:"building_ids" json:"buildingIds"`
+	ChildType           OptimizationType      `bson:"child_type" json:"childType"`
+	TargetReductionKW   float64               `bson:"target_reduction_kw" json:"targetReductionKW"`
+	ExpectedReductionKW float64               `bson:"expected_reduction_kw" json:"expectedReductionKW"`
+	ShortfallKW         float64               `bson:"shortfall_kw" json:"shortfallKW"` // Part of the target no building could shed
+	Allocations         []PortfolioAllocation `bson:"allocations" json:"allocations"`
+}
+
+// PortfolioAllocation is one building's share of a portfolio target and the child scenario meeting it
+type PortfolioAllocation struct {
+	BuildingID          string             `bson:"building_id" json:"buildingId"`
+	ScenarioID          string             `bson:"scenario_id" json:"scenarioId"`
+	ForecastLoadKW      float64            `bson:"forecast_load_kw" json:"forecastLoadKW"`
+	LoadSource          string             `bson:"load_source" json:"loadSource"` // FORECAST, CURRENT or NONE
+	Share               float64            `bson:"share" json:"share"`            // Share of the portfolio's forecast load, 0.0 to 1.0
+	TargetReductionKW   float64            `bson:"target_reduction_kw" json:"targetReductionKW"`
+	ExpectedReductionKW float64            `bson:"expected_reduction_kw" json:"expectedReductionKW"`
+	Status              OptimizationStatus `bson:"status" json:"status"`
+}
+
+// PortfolioGenerateRequest represents the request to generate a scenario spanning several buildings.
+// Type is the optimization type of the per-building scenarios and defaults to DEMAND_RESPONSE.
+type PortfolioGenerateRequest struct {
+	Name              string                  `json:"name"`
+	BuildingIDs       []string                `json:"buildingIds" binding:"required,min=2"`
+	TargetReductionKW float64                 `json:"targetReductionKW" binding:"required,gt=0"`
+	Type              OptimizationType        `json:"type"`
+	ScheduledStart    time.Time               `json:"scheduledStart"`
+	ScheduledEnd      time.Time               `json:"scheduledEnd"`
+	UseTariffData     bool                    `json:"useTariffData"`
+	Constraints       OptimizationConstraints `json:"constraints"`
+	Priority          int                     `json:"priority"`
+}
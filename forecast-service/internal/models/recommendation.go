@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"forecast-service/internal/i18n"
 )
 
 // RecommendationType represents the type of recommendation
@@ -98,3 +100,14 @@ func (r *Recommendation) ToRecommendationItem() RecommendationItem {
 		ImplementationSteps: r.ImplementationSteps,
 	}
 }
+
+// Localize translates the texts of the recommendations into the given language
+func (r *RecommendationsResponse) Localize(lang string) {
+	for i := range r.Recommendations {
+		item := &r.Recommendations[i]
+		item.Title = i18n.Translate(lang, item.Title)
+		item.Description = i18n.Translate(lang, item.Description)
+		item.ActionRequired = i18n.Translate(lang, item.ActionRequired)
+		item.ImplementationSteps = i18n.TranslateAll(lang, item.ImplementationSteps)
+	}
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ScenarioTemplate is a reusable optimization setup, e.g. "Summer Peak Shaving", that fills in
+// the type, constraints, devices and timing of scenarios generated for any building.
+// Templates without an organization are shared with every organization.
+type ScenarioTemplate struct {
+	ID             primitive.ObjectID      `bson:"_id,omitempty" json:"id"`
+	OrgID          string                  `bson:"org_id" json:"orgId,omitempty"`
+	Name           string                  `bson:"name" json:"name"`
+	Description    string                  `bson:"description,omitempty" json:"description,omitempty"`
+	Type           OptimizationType        `bson:"type" json:"type"`
+	Constraints    OptimizationConstraints `bson:"constraints" json:"constraints"`
+	DeviceTags     []string                `bson:"device_tags,omitempty" json:"deviceTags,omitempty"` // Only devices with one of the tags are optimized
+	Schedule       *TemplateSchedule       `bson:"schedule,omitempty" json:"schedule,omitempty"`
+	Priority       int                     `bson:"priority,omitempty" json:"priority,omitempty"`
+	UseTariffData  bool                    `bson:"use_tariff_data" json:"useTariffData"`
+	UseWeatherData bool                    `bson:"use_weather_data" json:"useWeatherData"`
+	CreatedBy      string                  `bson:"created_by" json:"createdBy"`
+	UpdatedBy      string                  `bson:"updated_by,omitempty" json:"updatedBy,omitempty"`
+	CreatedAt      time.Time               `bson:"created_at" json:"createdAt"`
+	UpdatedAt      time.Time               `bson:"updated_at" json:"updatedAt"`
+}
+
+// TemplateSchedule is when scenarios of a template run, in the building's time zone: the next
+// StartTime on one of DaysOfWeek ("Monday" to "Sunday", or "Holiday" for business calendar
+// holidays), for DurationMinutes. All days are allowed when DaysOfWeek is empty.
+type TemplateSchedule struct {
+	StartTime       string   `bson:"start_time" json:"startTime" binding:"required"` // HH:MM format
+	DurationMinutes int      `bson:"duration_minutes" json:"durationMinutes" binding:"required,min=15,max=10080"`
+	DaysOfWeek      []string `bson:"days_of_week,omitempty" json:"daysOfWeek,omitempty"`
+}
+
+// ScenarioTemplateRequest represents a request to create or replace a scenario template
+type ScenarioTemplateRequest struct {
+	Name           string                  `json:"name" binding:"required,max=100"`
+	Description    string                  `json:"description" binding:"max=500"`
+	Type           OptimizationType        `json:"type" binding:"required,oneof=COST_REDUCTION PEAK_SHAVING LOAD_BALANCING EFFICIENCY COMFORT DEMAND_RESPONSE BATTERY_DISPATCH"`
+	Constraints    OptimizationConstraints `json:"constraints"`
+	DeviceTags     []string                `json:"deviceTags" binding:"max=20"`
+	Schedule       *TemplateSchedule       `json:"schedule"`
+	Priority       int                     `json:"priority" binding:"min=0,max=10"`
+	UseTariffData  bool                    `json:"useTariffData"`
+	UseWeatherData bool                    `json:"useWeatherData"`
+}
+
+// ListScenarioTemplatesRequest represents query parameters for listing scenario templates
+type ListScenarioTemplatesRequest struct {
+	Type OptimizationType `form:"type"`
+}
+
+// ApplyScenarioTemplateRequest represents a request to generate a scenario for a building from a
+// template. Fields that are set override the template.
+type ApplyScenarioTemplateRequest struct {
+	BuildingID     string    `json:"buildingId" binding:"required"`
+	Name           string    `json:"name"`
+	ScheduledStart time.Time `json:"scheduledStart"`
+	ForecastID     string    `json:"forecastId"`
+	Priority       int       `json:"priority" binding:"min=0,max=10"`
+
+	// Set when the scheduled start was given without a UTC offset
+	wallClockStart bool
+}
+
+// UnmarshalJSON also accepts scheduledStart without a UTC offset as a wall clock time in the
+// building's time zone
+func (r *ApplyScenarioTemplateRequest) UnmarshalJSON(data []byte) error {
+	type plain ApplyScenarioTemplateRequest
+	aux := struct {
+		*plain
+		ScheduledStart string `json:"scheduledStart"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if r.ScheduledStart, r.wallClockStart, err = parseRequestTime(aux.ScheduledStart); err != nil {
+		return fmt.Errorf("invalid scheduledStart: %w", err)
+	}
+	return nil
+}
+
+// GenerateRequest returns the optimization request that generates a scenario from the template
+func (r *ApplyScenarioTemplateRequest) GenerateRequest(templateID string) *OptimizationGenerateRequest {
+	return &OptimizationGenerateRequest{
+		BuildingID:     r.BuildingID,
+		TemplateID:     templateID,
+		Name:           r.Name,
+		ScheduledStart: r.ScheduledStart,
+		ForecastID:     r.ForecastID,
+		Priority:       r.Priority,
+		wallClockStart: r.wallClockStart,
+	}
+}
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TariffSource identifies where a resolved tariff came from
+type TariffSource string
+
+const (
+	TariffSourceLocal    TariffSource = "LOCAL"
+	TariffSourceExternal TariffSource = "EXTERNAL"
+)
+
+// TariffSchedule represents a locally managed tariff for a region
+type TariffSchedule struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Region           string             `bson:"region" json:"region"`
+	Name             string             `bson:"name" json:"name"`
+	Currency         string             `bson:"currency" json:"currency"`
+	BaseRate         float64            `bson:"base_rate" json:"baseRate"`
+	TimeOfUseWindows []TimeOfUseWindow  `bson:"time_of_use_windows,omitempty" json:"timeOfUseWindows,omitempty"`
+	SeasonalRates    []SeasonalRate     `bson:"seasonal_rates,omitempty" json:"seasonalRates,omitempty"`
+	DemandCharges    []DemandCharge     `bson:"demand_charges,omitempty" json:"demandCharges,omitempty"`
+	IsActive         bool               `bson:"is_active" json:"isActive"`
+	CreatedBy        string             `bson:"created_by" json:"createdBy"`
+	CreatedAt        time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// TimeOfUseWindow represents a time-of-use pricing window
+type TimeOfUseWindow struct {
+	Name       string  `bson:"name" json:"name"`
+	RatePerKWh float64 `bson:"rate_per_kwh" json:"ratePerKWh"`
+	StartHour  int     `bson:"start_hour" json:"startHour"`
+	EndHour    int     `bson:"end_hour" json:"endHour"`
+	DaysOfWeek []int   `bson:"days_of_week,omitempty" json:"daysOfWeek,omitempty"` // 0 = Sunday, empty = every day
+	IsPeak     bool    `bson:"is_peak" json:"isPeak"`
+}
+
+// SeasonalRate overrides the base rate and time-of-use windows for a range of months
+type SeasonalRate struct {
+	Name             string            `bson:"name" json:"name"`
+	StartMonth       int               `bson:"start_month" json:"startMonth"`
+	EndMonth         int               `bson:"end_month" json:"endMonth"`
+	BaseRate         float64           `bson:"base_rate" json:"baseRate"`
+	TimeOfUseWindows []TimeOfUseWindow `bson:"time_of_use_windows,omitempty" json:"timeOfUseWindows,omitempty"`
+}
+
+// DemandCharge represents a charge applied to the peak demand within a window
+type DemandCharge struct {
+	Name      string  `bson:"name" json:"name"`
+	RatePerKW float64 `bson:"rate_per_kw" json:"ratePerKW"`
+	StartHour int     `bson:"start_hour" json:"startHour"`
+	EndHour   int     `bson:"end_hour" json:"endHour"`
+}
+
+// Contains checks whether the window applies to the given time
+func (w *TimeOfUseWindow) Contains(t time.Time) bool {
+	if len(w.DaysOfWeek) > 0 {
+		matched := false
+		for _, day := range w.DaysOfWeek {
+			if int(t.Weekday()) == day {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return hourInRange(t.Hour(), w.StartHour, w.EndHour)
+}
+
+// Contains checks whether the seasonal rate applies to the given month
+func (s *SeasonalRate) Contains(month time.Month) bool {
+	m := int(month)
+	if s.StartMonth <= s.EndMonth {
+		return m >= s.StartMonth && m <= s.EndMonth
+	}
+	// Season wraps around the year end (e.g. November to February)
+	return m >= s.StartMonth || m <= s.EndMonth
+}
+
+// hourInRange checks whether an hour falls within [start, end), wrapping past midnight
+func hourInRange(hour, start, end int) bool {
+	if start == end {
+		return true
+	}
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// Contains checks whether the rate applies to the given hour of day
+func (r *TariffRate) Contains(hour int) bool {
+	return hourInRange(hour, r.StartHour, r.EndHour)
+}
+
+// Contains checks whether the demand charge applies to the given hour of day
+func (d *DemandCharge) Contains(hour int) bool {
+	return hourInRange(hour, d.StartHour, d.EndHour)
+}
+
+// Resolve converts the schedule into the tariff in effect at the given time
+func (ts *TariffSchedule) Resolve(at time.Time) *Tariff {
+	baseRate := ts.BaseRate
+	windows := ts.TimeOfUseWindows
+
+	for i := range ts.SeasonalRates {
+		season := &ts.SeasonalRates[i]
+		if season.Contains(at.Month()) {
+			if season.BaseRate > 0 {
+				baseRate = season.BaseRate
+			}
+			if len(season.TimeOfUseWindows) > 0 {
+				windows = season.TimeOfUseWindows
+			}
+			break
+		}
+	}
+
+	tariff := &Tariff{
+		Region:        ts.Region,
+		BaseRate:      baseRate,
+		CurrentRate:   baseRate,
+		PeakRate:      baseRate,
+		OffPeakRate:   baseRate,
+		Currency:      ts.Currency,
+		DemandCharges: ts.DemandCharges,
+		Source:        TariffSourceLocal,
+	}
+
+	peakSet, offPeakSet := false, false
+	for i := range windows {
+		window := &windows[i]
+		tariff.TimeOfUseRates = append(tariff.TimeOfUseRates, TariffRate{
+			Name:       window.Name,
+			RatePerKWh: window.RatePerKWh,
+			StartHour:  window.StartHour,
+			EndHour:    window.EndHour,
+			DaysOfWeek: window.DaysOfWeek,
+		})
+
+		if window.IsPeak {
+			if !peakSet || window.RatePerKWh > tariff.PeakRate {
+				tariff.PeakRate = window.RatePerKWh
+				peakSet = true
+			}
+		} else if !offPeakSet || window.RatePerKWh < tariff.OffPeakRate {
+			tariff.OffPeakRate = window.RatePerKWh
+			offPeakSet = true
+		}
+
+		if window.Contains(at) {
+			tariff.CurrentRate = window.RatePerKWh
+		}
+	}
+
+	return tariff
+}
+
+// TariffScheduleResponse represents a tariff schedule in API responses
+type TariffScheduleResponse struct {
+	ID               string            `json:"id"`
+	Region           string            `json:"region"`
+	Name             string            `json:"name"`
+	Currency         string            `json:"currency"`
+	BaseRate         float64           `json:"baseRate"`
+	TimeOfUseWindows []TimeOfUseWindow `json:"timeOfUseWindows,omitempty"`
+	SeasonalRates    []SeasonalRate    `json:"seasonalRates,omitempty"`
+	DemandCharges    []DemandCharge    `json:"demandCharges,omitempty"`
+	IsActive         bool              `json:"isActive"`
+	CreatedBy        string            `json:"createdBy"`
+	CreatedAt        time.Time         `json:"createdAt"`
+	UpdatedAt        time.Time         `json:"updatedAt"`
+}
+
+// ToResponse converts a TariffSchedule to TariffScheduleResponse
+func (ts *TariffSchedule) ToResponse() *TariffScheduleResponse {
+	return &TariffScheduleResponse{
+		ID:               ts.ID.Hex(),
+		Region:           ts.Region,
+		Name:             ts.Name,
+		Currency:         ts.Currency,
+		BaseRate:         ts.BaseRate,
+		TimeOfUseWindows: ts.TimeOfUseWindows,
+		SeasonalRates:    ts.SeasonalRates,
+		DemandCharges:    ts.DemandCharges,
+		IsActive:         ts.IsActive,
+		CreatedBy:        ts.CreatedBy,
+		CreatedAt:        ts.CreatedAt,
+		UpdatedAt:        ts.UpdatedAt,
+	}
+}
+
+// TariffCreateRequest represents the request to create a local tariff
+type TariffCreateRequest struct {
+	Region           string            `json:"region" binding:"required"`
+	Name             string            `json:"name"`
+	Currency         string            `json:"currency"`
+	BaseRate         float64           `json:"baseRate" binding:"required"`
+	TimeOfUseWindows []TimeOfUseWindow `json:"timeOfUseWindows"`
+	SeasonalRates    []SeasonalRate    `json:"seasonalRates"`
+	DemandCharges    []DemandCharge    `json:"demandCharges"`
+	IsActive         *bool             `json:"isActive"`
+}
+
+// TariffUpdateRequest represents the request to update a local tariff
+type TariffUpdateRequest struct {
+	Name             *string           `json:"name"`
+	Currency         *string           `json:"currency"`
+	BaseRate         *float64          `json:"baseRate"`
+	TimeOfUseWindows []TimeOfUseWindow `json:"timeOfUseWindows"`
+	SeasonalRates    []SeasonalRate    `json:"seasonalRates"`
+	DemandCharges    []DemandCharge    `json:"demandCharges"`
+	IsActive         *bool             `json:"isActive"`
+}
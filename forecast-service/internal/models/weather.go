@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// DegreeDaysRequest represents query parameters for degree day calculation.
+// BaseTemperature defaults to the configured balance point when omitted.
+type DegreeDaysRequest struct {
+	BuildingID      string    `form:"buildingId" binding:"required"`
+	From            time.Time `form:"from" binding:"required"`
+	To              time.Time `form:"to" binding:"required,gtfield=From,maxspan=From 9600h"`
+	BaseTemperature *float64  `form:"baseTemperature"`
+}
+
+// DailyDegreeDays holds the heating and cooling degree days of one day
+type DailyDegreeDays struct {
+	Date              string  `json:"date"`
+	MeanTemperature   float64 `json:"meanTemperature"`
+	HeatingDegreeDays float64 `json:"heatingDegreeDays"`
+	CoolingDegreeDays float64 `json:"coolingDegreeDays"`
+}
+
+// DegreeDays summarizes heating and cooling degree days of a building location over a period.
+// Days without weather observations are left out and counted in MissingDays.
+type DegreeDays struct {
+	BuildingID        string            `json:"buildingId"`
+	From              time.Time         `json:"from"`
+	To                time.Time         `json:"to"`
+	BaseTemperature   float64           `json:"baseTemperature"`
+	HeatingDegreeDays float64           `json:"heatingDegreeDays"`
+	CoolingDegreeDays float64           `json:"coolingDegreeDays"`
+	MissingDays       int               `json:"missingDays"`
+	Days              []DailyDegreeDays `json:"days"`
+}
+
+// WeatherForecastRequest represents query parameters for the weather forecast of a building
+// location. Hours defaults to 24.
+type WeatherForecastRequest struct {
+	BuildingID string `form:"buildingId" binding:"required"`
+	Hours      int    `form:"hours" binding:"omitempty,min=1,max=168"`
+}
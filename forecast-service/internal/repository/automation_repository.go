@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/tenant"
+)
+
+// AutomationRepository handles automation rule database operations
+type AutomationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewAutomationRepository creates a new automation rule repository
+func NewAutomationRepository(collection *mongo.Collection) *AutomationRepository {
+	return &AutomationRepository{collection: collection}
+}
+
+// Create inserts a new automation rule into the database
+func (r *AutomationRepository) Create(ctx context.Context, rule *models.AutomationRule) (*models.AutomationRule, error) {
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.ID = result.InsertedID.(primitive.ObjectID)
+	return rule, nil
+}
+
+// FindByID retrieves an automation rule by its ID
+func (r *AutomationRepository) FindByID(ctx context.Context, id string) (*models.AutomationRule, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid automation rule ID format")
+	}
+
+	var rule models.AutomationRule
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id")).Decode(&rule)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("automation rule not found")
+		}
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// FindByBuilding retrieves automation rules, optionally restricted to a building
+func (r *AutomationRepository) FindByBuilding(ctx context.Context, buildingID string) ([]*models.AutomationRule, error) {
+	filter := bson.M{}
+	if buildingID != "" {
+		filter["building_id"] = buildingID
+	}
+
+	return r.find(ctx, tenant.BuildingFilter(ctx, filter, "building_id"))
+}
+
+// FindEnabled retrieves all enabled automation rules
+func (r *AutomationRepository) FindEnabled(ctx context.Context) ([]*models.AutomationRule, error) {
+	return r.find(ctx, bson.M{"enabled": true})
+}
+
+func (r *AutomationRepository) find(ctx context.Context, filter bson.M) ([]*models.AutomationRule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "building_id", Value: 1}, {Key: "created_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rules []*models.AutomationRule
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// Update updates an existing automation rule
+func (r *AutomationRepository) Update(ctx context.Context, id string, updates bson.M) (*models.AutomationRule, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid automation rule ID format")
+	}
+
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"),
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var rule models.AutomationRule
+	if err := result.Decode(&rule); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("automation rule not found")
+		}
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// RecordEvaluation stores the outcome of a rule evaluation.
+// The trigger fields are only updated when a scenario was generated.
+func (r *AutomationRepository) RecordEvaluation(ctx context.Context, id primitive.ObjectID, evaluatedAt time.Time, triggeredFor, scenarioID string) error {
+	updates := bson.M{"last_evaluated_at": evaluatedAt}
+	if scenarioID != "" {
+		updates["last_triggered_for"] = triggeredFor
+		updates["last_scenario_id"] = scenarioID
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": updates})
+	return err
+}
+
+// Delete removes an automation rule from the database
+func (r *AutomationRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid automation rule ID format")
+	}
+
+	result, err := r.collection.DeleteOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"))
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("automation rule not found")
+	}
+
+	return nil
+}
+
+// DisableByCreator disables every enabled automation rule created by a user, returning how many were disabled
+func (r *AutomationRepository) DisableByCreator(ctx context.Context, createdBy string) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"created_by": createdBy, "enabled": true},
+		bson.M{"$set": bson.M{"enabled": false, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
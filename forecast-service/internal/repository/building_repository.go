@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/tenant"
+)
+
+// BuildingRepository handles building registry database operations
+type BuildingRepository struct {
+	collection *mongo.Collection
+}
+
+// NewBuildingRepository creates a new building repository
+func NewBuildingRepository(collection *mongo.Collection) *BuildingRepository {
+	return &BuildingRepository{collection: collection}
+}
+
+// FindByBuilding retrieves the registry entry of a building
+func (r *BuildingRepository) FindByBuilding(ctx context.Context, buildingID string) (*models.Building, error) {
+	var building models.Building
+	err := r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"building_id": buildingID}, "building_id")).Decode(&building)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("building not found")
+		}
+		return nil, err
+	}
+
+	return &building, nil
+}
+
+// FindAll retrieves every registered building ordered by building ID
+func (r *BuildingRepository) FindAll(ctx context.Context) ([]*models.Building, error) {
+	cursor, err := r.collection.Find(ctx, tenant.BuildingFilter(ctx, bson.M{}, "building_id"), options.Find().SetSort(bson.D{{Key: "building_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var buildings []*models.Building
+	if err := cursor.All(ctx, &buildings); err != nil {
+		return nil, err
+	}
+
+	return buildings, nil
+}
+
+// Upsert creates or replaces the registry entry of a building
+func (r *BuildingRepository) Upsert(ctx context.Context, building *models.Building) (*models.Building, error) {
+	now := time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"building_id": building.BuildingID},
+		bson.M{
+			"$set": bson.M{
+				"name":       building.Name,
+				"timezone":   building.TimeZone,
+				"updated_at": now,
+			},
+			"$setOnInsert": bson.M{
+				"created_by": building.CreatedBy,
+				"created_at": now,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	var updated models.Building
+	if err := result.Decode(&updated); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// DeleteByBuilding removes the registry entry of a building
+func (r *BuildingRepository) DeleteByBuilding(ctx context.Context, buildingID string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"building_id": buildingID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("building not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/tenant"
+)
+
+// CalendarRepository handles business calendar database operations
+type CalendarRepository struct {
+	collection *mongo.Collection
+}
+
+// NewCalendarRepository creates a new business calendar repository
+func NewCalendarRepository(collection *mongo.Collection) *CalendarRepository {
+	return &CalendarRepository{collection: collection}
+}
+
+// calendarFilter restricts a filter to the entries of the buildings of the organization ctx is
+// scoped to. With regional set, the regional entries shared by every organization match as well.
+func calendarFilter(ctx context.Context, filter bson.M, regional bool) bson.M {
+	if tenant.FromContext(ctx) == nil {
+		return filter
+	}
+
+	scopes := []bson.M{tenant.BuildingFilter(ctx, bson.M{}, "building_id")}
+	if regional {
+		scopes = append(scopes, bson.M{"building_id": bson.M{"$exists": false}})
+	}
+	return bson.M{"$and": []bson.M{filter, {"$or": scopes}}}
+}
+
+// Create inserts a new business calendar entry into the database
+func (r *CalendarRepository) Create(ctx context.Context, day *models.CalendarDay) (*models.CalendarDay, error) {
+	day.CreatedAt = time.Now()
+	day.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, day)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("calendar day already exists")
+		}
+		return nil, err
+	}
+
+	day.ID = result.InsertedID.(primitive.ObjectID)
+	return day, nil
+}
+
+// FindByID retrieves a business calendar entry by its ID
+func (r *CalendarRepository) FindByID(ctx context.Context, id string) (*models.CalendarDay, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid calendar day ID format")
+	}
+
+	var day models.CalendarDay
+	err = r.collection.FindOne(ctx, calendarFilter(ctx, bson.M{"_id": objectID}, true)).Decode(&day)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("calendar day not found")
+		}
+		return nil, err
+	}
+
+	return &day, nil
+}
+
+// Find retrieves the entries of a building and of a region between two dates (YYYY-MM-DD,
+// inclusive), ordered by date. Empty arguments are not filtered on.
+func (r *CalendarRepository) Find(ctx context.Context, buildingID, region, from, to string) ([]models.CalendarDay, error) {
+	filter := bson.M{}
+
+	var scopes []bson.M
+	if buildingID != "" {
+		scopes = append(scopes, bson.M{"building_id": buildingID})
+	}
+	if region != "" {
+		scopes = append(scopes, bson.M{"region": region, "building_id": bson.M{"$exists": false}})
+	}
+	if len(scopes) > 0 {
+		filter["$or"] = scopes
+	}
+
+	dates := bson.M{}
+	if from != "" {
+		dates["$gte"] = from
+	}
+	if to != "" {
+		dates["$lte"] = to
+	}
+	if len(dates) > 0 {
+		filter["date"] = dates
+	}
+
+	cursor, err := r.collection.Find(ctx, calendarFilter(ctx, filter, true), options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	days := []models.CalendarDay{}
+	if err := cursor.All(ctx, &days); err != nil {
+		return nil, err
+	}
+
+	return days, nil
+}
+
+// Replace overwrites a business calendar entry, keeping its creator and creation time
+func (r *CalendarRepository) Replace(ctx context.Context, id string, day *models.CalendarDay) (*models.CalendarDay, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid calendar day ID format")
+	}
+
+	set := bson.M{
+		"date":        day.Date,
+		"type":        day.Type,
+		"name":        day.Name,
+		"load_factor": day.LoadFactor,
+		"updated_at":  time.Now(),
+	}
+	unset := bson.M{}
+	for field, value := range map[string]interface{}{
+		"building_id": day.BuildingID,
+		"region":      day.Region,
+		"open_hour":   day.OpenHour,
+		"close_hour":  day.CloseHour,
+	} {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				unset[field] = ""
+				continue
+			}
+		case *int:
+			if v == nil {
+				unset[field] = ""
+				continue
+			}
+		}
+		set[field] = value
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		calendarFilter(ctx, bson.M{"_id": objectID}, false),
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var updated models.CalendarDay
+	if err := result.Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("calendar day not found")
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("calendar day already exists")
+		}
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// Delete removes a business calendar entry from the database
+func (r *CalendarRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid calendar day ID format")
+	}
+
+	result, err := r.collection.DeleteOne(ctx, calendarFilter(ctx, bson.M{"_id": objectID}, false))
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("calendar day not found")
+	}
+
+	return nil
+}
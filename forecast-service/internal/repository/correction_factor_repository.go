@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"forecast-service/internal/models"
+)
+
+// CorrectionFactorRepository handles optimization correction factor database operations
+type CorrectionFactorRepository struct {
+	collection *mongo.Collection
+}
+
+// NewCorrectionFactorRepository creates a new correction factor repository
+func NewCorrectionFactorRepository(collection *mongo.Collection) *CorrectionFactorRepository {
+	return &CorrectionFactorRepository{collection: collection}
+}
+
+// Record adds an observed expected and actual demand reduction to the factor of an action type
+// on a device type, or on a single device when deviceID is set, creating the factor if needed
+func (r *CorrectionFactorRepository) Record(ctx context.Context, actionType, deviceType, deviceID string, expectedKW, actualKW float64, scenarioID string) error {
+	filter := bson.M{
+		"action_type": actionType,
+		"device_type": deviceType,
+		"device_id":   deviceID,
+	}
+	update := bson.M{
+		"$inc": bson.M{
+			"expected_kw": expectedKW,
+			"actual_kw":   actualKW,
+			"samples":     1,
+		},
+		"$set": bson.M{
+			"last_scenario_id": scenarioID,
+			"updated_at":       time.Now(),
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// FindByActionTypes retrieves the factors of the given action types
+func (r *CorrectionFactorRepository) FindByActionTypes(ctx context.Context, actionTypes []string) ([]*models.CorrectionFactor, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"action_type": bson.M{"$in": actionTypes}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var factors []*models.CorrectionFactor
+	if err := cursor.All(ctx, &factors); err != nil {
+		return nil, err
+	}
+	return factors, nil
+}
+
+// FindAll retrieves factors matching the optional filters, device type factors before the
+// factors of single devices
+func (r *CorrectionFactorRepository) FindAll(ctx context.Context, query *models.CorrectionFactorQuery) ([]*models.CorrectionFactor, error) {
+	filter := bson.M{}
+	if query.ActionType != "" {
+		filter["action_type"] = query.ActionType
+	}
+	if query.DeviceType != "" {
+		filter["device_type"] = query.DeviceType
+	}
+	if query.DeviceID != "" {
+		filter["device_id"] = query.DeviceID
+	}
+
+	opts := options.Find().SetSort(bson.D{
+		{Key: "action_type", Value: 1},
+		{Key: "device_type", Value: 1},
+		{Key: "device_id", Value: 1},
+	})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var factors []*models.CorrectionFactor
+	if err := cursor.All(ctx, &factors); err != nil {
+		return nil, err
+	}
+	return factors, nil
+}
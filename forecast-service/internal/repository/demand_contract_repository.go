@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"forecast-service/internal/models"
+)
+
+// DemandContractRepository handles demand contract database operations
+type DemandContractRepository struct {
+	collection *mongo.Collection
+}
+
+// NewDemandContractRepository creates a new demand contract repository
+func NewDemandContractRepository(collection *mongo.Collection) *DemandContractRepository {
+	return &DemandContractRepository{collection: collection}
+}
+
+// FindByBuilding retrieves the demand contract of a building
+func (r *DemandContractRepository) FindByBuilding(ctx context.Context, buildingID string) (*models.DemandContract, error) {
+	var contract models.DemandContract
+	err := r.collection.FindOne(ctx, bson.M{"building_id": buildingID}).Decode(&contract)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("demand contract not found")
+		}
+		return nil, err
+	}
+
+	return &contract, nil
+}
+
+// Upsert creates or replaces the demand contract of a building
+func (r *DemandContractRepository) Upsert(ctx context.Context, contract *models.DemandContract) (*models.DemandContract, error) {
+	now := time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"building_id": contract.BuildingID},
+		bson.M{
+			"$set": bson.M{
+				"max_demand_kw":       contract.MaxDemandKW,
+				"penalty_rate_per_kw": contract.PenaltyRatePerKW,
+				"window_minutes":      contract.WindowMinutes,
+				"currency":            contract.Currency,
+				"updated_at":          now,
+			},
+			"$setOnInsert": bson.M{
+				"created_by": contract.CreatedBy,
+				"created_at": now,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	var updated models.DemandContract
+	if err := result.Decode(&updated); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// DeleteByBuilding removes the demand contract of a building
+func (r *DemandContractRepository) DeleteByBuilding(ctx context.Context, buildingID string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"building_id": buildingID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("demand contract not found")
+	}
+
+	return nil
+}
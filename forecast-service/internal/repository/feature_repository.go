@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"forecast-service/internal/models"
+)
+
+// FeatureRepository handles forecast input feature store database operations
+type FeatureRepository struct {
+	collection *mongo.Collection
+}
+
+// NewFeatureRepository creates a new feature repository
+func NewFeatureRepository(collection *mongo.Collection) *FeatureRepository {
+	return &FeatureRepository{collection: collection}
+}
+
+// Create inserts a new feature snapshot into the database
+func (r *FeatureRepository) Create(ctx context.Context, snapshot *models.FeatureSnapshot) (*models.FeatureSnapshot, error) {
+	snapshot.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot.ID = result.InsertedID.(primitive.ObjectID)
+	return snapshot, nil
+}
+
+// FindLatest retrieves the most recent valid snapshot of a feature fetched at or after since
+func (r *FeatureRepository) FindLatest(ctx context.Context, kind models.FeatureKind, key string, since time.Time) (*models.FeatureSnapshot, error) {
+	filter := bson.M{
+		"kind":        kind,
+		"key":         key,
+		"fetched_at":  bson.M{"$gte": since},
+		"invalidated": bson.M{"$ne": true},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "fetched_at", Value: -1}})
+
+	var snapshot models.FeatureSnapshot
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&snapshot)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("feature snapshot not found")
+		}
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// InvalidateKind stops every snapshot of a kind from being reused. Snapshots are kept so that
+// forecasts referring to them stay traceable.
+func (r *FeatureRepository) InvalidateKind(ctx context.Context, kind models.FeatureKind) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"kind": kind, "invalidated": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"invalidated": true}},
+	)
+	return err
+}
+
+// CountOlderThan counts feature snapshots created before the given time
+func (r *FeatureRepository) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+}
+
+// DeleteOlderThan removes feature snapshots created before the given time
+func (r *FeatureRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"forecast-service/internal/models"
+	"forecast-service/internal/tenant"
 )
 
 // ForecastRepository handles forecast database operations
@@ -39,13 +40,23 @@ func (r *ForecastRepository) Create(ctx context.Context, forecast *models.Foreca
 
 // FindByID retrieves a forecast by its ID
 func (r *ForecastRepository) FindByID(ctx context.Context, id string) (*models.Forecast, error) {
+	return r.FindByIDWithFields(ctx, id, nil)
+}
+
+// FindByIDWithFields retrieves a forecast by its ID, loading only the selected fields
+func (r *ForecastRepository) FindByIDWithFields(ctx context.Context, id string, fields *models.FieldSelection) (*models.Forecast, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid forecast ID format")
 	}
 
+	opts := options.FindOne()
+	if projection := fields.Projection(); projection != nil {
+		opts.SetProjection(projection)
+	}
+
 	var forecast models.Forecast
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&forecast)
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"), opts).Decode(&forecast)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("forecast not found")
@@ -56,11 +67,15 @@ func (r *ForecastRepository) FindByID(ctx context.Context, id string) (*models.F
 	return &forecast, nil
 }
 
-// FindLatestByBuilding retrieves the latest forecast for a building
+// FindLatestByBuilding retrieves the latest hourly forecast for a building
 func (r *ForecastRepository) FindLatestByBuilding(ctx context.Context, buildingID string, forecastType models.ForecastType) (*models.Forecast, error) {
 	filter := bson.M{
 		"building_id": buildingID,
 		"status":      models.ForecastStatusCompleted,
+		// Daily and weekly forecasts hold kWh totals and sub-hourly ones four or two values per
+		// hour, not the hourly load callers expect. Forecasts created before resolutions were
+		// stored are hourly.
+		"resolution": bson.M{"$in": []interface{}{models.ForecastResolutionHourly, "", nil}},
 	}
 
 	if forecastType != "" {
@@ -81,6 +96,64 @@ func (r *ForecastRepository) FindLatestByBuilding(ctx context.Context, buildingI
 	return &forecast, nil
 }
 
+// FindLatestByBuildings retrieves the latest completed hourly forecast of each building in a
+// single aggregation. Buildings without forecasts are left out.
+func (r *ForecastRepository) FindLatestByBuildings(ctx context.Context, buildingIDs []string, forecastType models.ForecastType) ([]*models.Forecast, error) {
+	match := bson.M{
+		"building_id": bson.M{"$in": buildingIDs},
+		"status":      models.ForecastStatusCompleted,
+		"resolution":  bson.M{"$in": []interface{}{models.ForecastResolutionHourly, "", nil}},
+	}
+	if forecastType != "" {
+		match["type"] = forecastType
+	}
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$sort": bson.M{"created_at": -1}},
+		{"$group": bson.M{"_id": "$building_id", "forecast": bson.M{"$first": "$$ROOT"}}},
+		{"$replaceRoot": bson.M{"newRoot": "$forecast"}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var forecasts []*models.Forecast
+	if err := cursor.All(ctx, &forecasts); err != nil {
+		return nil, err
+	}
+
+	return forecasts, nil
+}
+
+// FindLatestSubHourlyByBuilding retrieves the latest 15- or 30-minute forecast of a building that
+// still covers a time after the given one
+func (r *ForecastRepository) FindLatestSubHourlyByBuilding(ctx context.Context, buildingID string, forecastType models.ForecastType, after time.Time) (*models.Forecast, error) {
+	filter := bson.M{
+		"building_id": buildingID,
+		"device_id":   bson.M{"$in": []interface{}{"", nil}},
+		"type":        forecastType,
+		"status":      models.ForecastStatusCompleted,
+		"resolution":  bson.M{"$in": []models.ForecastResolution{models.ForecastResolutionQuarterHourly, models.ForecastResolutionHalfHourly}},
+		"end_time":    bson.M{"$gt": after},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var forecast models.Forecast
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&forecast)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("no forecasts found for this building")
+		}
+		return nil, err
+	}
+
+	return &forecast, nil
+}
+
 // FindByBuilding retrieves forecasts for a building with pagination
 func (r *ForecastRepository) FindByBuilding(ctx context.Context, buildingID string, page, limit int) ([]*models.Forecast, int64, error) {
 	if page < 1 {
@@ -91,7 +164,7 @@ func (r *ForecastRepository) FindByBuilding(ctx context.Context, buildingID stri
 	}
 
 	skip := int64((page - 1) * limit)
-	filter := bson.M{"building_id": buildingID}
+	filter := tenant.BuildingFilter(ctx, bson.M{"building_id": buildingID}, "building_id")
 
 	// Get total count
 	total, err := r.collection.CountDocuments(ctx, filter)
@@ -138,6 +211,39 @@ func (r *ForecastRepository) FindByDevice(ctx context.Context, deviceID string)
 	return forecasts, nil
 }
 
+// FindDeviceForecasts retrieves the completed device-level forecasts of a building of the given
+// type and resolution that overlap a time range, newest first
+func (r *ForecastRepository) FindDeviceForecasts(ctx context.Context, buildingID string, forecastType models.ForecastType, resolution models.ForecastResolution, from, to time.Time) ([]*models.Forecast, error) {
+	filter := bson.M{
+		"building_id": buildingID,
+		"device_id":   bson.M{"$exists": true, "$ne": ""},
+		"type":        forecastType,
+		"status":      models.ForecastStatusCompleted,
+		"start_time":  bson.M{"$lt": to},
+		"end_time":    bson.M{"$gt": from},
+	}
+	// Forecasts created before resolutions were stored are hourly
+	if resolution == "" || resolution == models.ForecastResolutionHourly {
+		filter["resolution"] = bson.M{"$in": []interface{}{models.ForecastResolutionHourly, "", nil}}
+	} else {
+		filter["resolution"] = resolution
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var forecasts []*models.Forecast
+	if err := cursor.All(ctx, &forecasts); err != nil {
+		return nil, err
+	}
+
+	return forecasts, nil
+}
+
 // Update updates an existing forecast
 func (r *ForecastRepository) Update(ctx context.Context, id string, updates bson.M) (*models.Forecast, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -149,7 +255,7 @@ func (r *ForecastRepository) Update(ctx context.Context, id string, updates bson
 
 	result := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"_id": objectID},
+		tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"),
 		bson.M{"$set": updates},
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	)
@@ -185,8 +291,8 @@ func (r *ForecastRepository) UpdateStatus(ctx context.Context, id string, status
 	return err
 }
 
-// UpdatePredictions updates the predictions for a forecast
-func (r *ForecastRepository) UpdatePredictions(ctx context.Context, id string, predictions []models.ForecastPrediction, accuracy *models.ForecastAccuracy) error {
+// UpdatePredictions updates the predictions and the model that produced them for a forecast
+func (r *ForecastRepository) UpdatePredictions(ctx context.Context, id string, predictions []models.ForecastPrediction, accuracy *models.ForecastAccuracy, modelUsed string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.New("invalid forecast ID format")
@@ -195,6 +301,7 @@ func (r *ForecastRepository) UpdatePredictions(ctx context.Context, id string, p
 	updates := bson.M{
 		"predictions": predictions,
 		"status":      models.ForecastStatusCompleted,
+		"model_used":  modelUsed,
 		"updated_at":  time.Now(),
 	}
 
@@ -213,7 +320,7 @@ func (r *ForecastRepository) Delete(ctx context.Context, id string) error {
 		return errors.New("invalid forecast ID format")
 	}
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	result, err := r.collection.DeleteOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"))
 	if err != nil {
 		return err
 	}
@@ -229,3 +336,56 @@ func (r *ForecastRepository) Delete(ctx context.Context, id string) error {
 func (r *ForecastRepository) CountByStatus(ctx context.Context, status models.ForecastStatus) (int64, error) {
 	return r.collection.CountDocuments(ctx, bson.M{"status": status})
 }
+
+// CountOlderThan counts forecasts created before the given time
+func (r *ForecastRepository) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+}
+
+// DeleteOlderThan removes forecasts created before the given time
+func (r *ForecastRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// FindAwaitingActuals retrieves completed forecasts that have started and whose actuals are not complete
+func (r *ForecastRepository) FindAwaitingActuals(ctx context.Context, now time.Time, limit int) ([]*models.Forecast, error) {
+	filter := bson.M{
+		"status":           models.ForecastStatusCompleted,
+		"start_time":       bson.M{"$lt": now},
+		"actuals.complete": bson.M{"$ne": true},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "start_time", Value: 1}}).SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var forecasts []*models.Forecast
+	if err := cursor.All(ctx, &forecasts); err != nil {
+		return nil, err
+	}
+
+	return forecasts, nil
+}
+
+// UpdateActuals stores the predictions with their backfilled actual values and the deviation summary
+func (r *ForecastRepository) UpdateActuals(ctx context.Context, id primitive.ObjectID, predictions []models.ForecastPrediction, actuals *models.ForecastActuals) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"predictions": predictions,
+		"actuals":     actuals,
+		"updated_at":  time.Now(),
+	}})
+	return err
+}
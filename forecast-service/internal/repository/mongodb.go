@@ -5,13 +5,24 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/description"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 
 	"forecast-service/internal/config"
+	"forecast-service/internal/models"
+	"forecast-service/internal/tracing"
+	"shared/jobs"
 )
 
 // MongoDB holds the database connection and collections
@@ -19,6 +30,7 @@ type MongoDB struct {
 	Client   *mongo.Client
 	Database *mongo.Database
 	config   *config.Config
+	monitor  *topologyMonitor
 }
 
 // Collections holds references to all MongoDB collections
@@ -28,6 +40,18 @@ type Collections struct {
 	OptimizationScenarios *mongo.Collection
 	Recommendations       *mongo.Collection
 	Devices               *mongo.Collection
+	Tariffs               *mongo.Collection
+	OccupancySchedules    *mongo.Collection
+	CalendarDays          *mongo.Collection
+	Buildings             *mongo.Collection
+	AutomationRules       *mongo.Collection
+	FeatureSnapshots      *mongo.Collection
+	Jobs                  *mongo.Collection
+	Settings              *mongo.Collection
+	SettingsHistory       *mongo.Collection
+	CorrectionFactors     *mongo.Collection
+	ScenarioTemplates     *mongo.Collection
+	DemandContracts       *mongo.Collection
 }
 
 // NewMongoDB creates a new MongoDB connection
@@ -35,12 +59,38 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoDB.Timeout)
 	defer cancel()
 
+	monitor := &topologyMonitor{}
+
 	// Create client options
 	clientOptions := options.Client().
 		ApplyURI(cfg.MongoDB.URI).
-		SetMaxPoolSize(100).
-		SetMinPoolSize(10).
-		SetMaxConnIdleTime(30 * time.Second)
+		SetMaxPoolSize(cfg.MongoDB.MaxPoolSize).
+		SetMinPoolSize(cfg.MongoDB.MinPoolSize).
+		SetMaxConnIdleTime(30 * time.Second).
+		SetServerSelectionTimeout(cfg.MongoDB.ServerSelectionTimeout).
+		SetRetryWrites(cfg.MongoDB.RetryWrites).
+		SetServerMonitor(&event.ServerMonitor{
+			TopologyDescriptionChanged: monitor.topologyChanged,
+		}).
+		SetMonitor(tracing.NewMongoMonitor())
+
+	if cfg.MongoDB.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(cfg.MongoDB.ReplicaSet)
+	}
+	if cfg.MongoDB.ReadPreference != "" {
+		readPref, err := parseReadPreference(cfg.MongoDB.ReadPreference)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetReadPreference(readPref)
+	}
+	if cfg.MongoDB.WriteConcern != "" {
+		writeConcern, err := parseWriteConcern(cfg.MongoDB.WriteConcern)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetWriteConcern(writeConcern)
+	}
 
 	// Connect to MongoDB
 	client, err := mongo.Connect(ctx, clientOptions)
@@ -59,9 +109,90 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 		Client:   client,
 		Database: client.Database(cfg.MongoDB.Database),
 		config:   cfg,
+		monitor:  monitor,
 	}, nil
 }
 
+// parseReadPreference parses a read preference mode such as "primary" or "secondaryPreferred"
+func parseReadPreference(value string) (*readpref.ReadPref, error) {
+	mode, err := readpref.ModeFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MongoDB read preference %q: %w", value, err)
+	}
+	return readpref.New(mode)
+}
+
+// parseWriteConcern accepts "majority" or the number of members that must acknowledge a write
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	if strings.EqualFold(value, "majority") {
+		return writeconcern.Majority(), nil
+	}
+	w, err := strconv.Atoi(value)
+	if err != nil || w < 0 {
+		return nil, fmt.Errorf("invalid MongoDB write concern %q: must be \"majority\" or a number", value)
+	}
+	return &writeconcern.WriteConcern{W: w}, nil
+}
+
+// topologyMonitor follows driver topology events to track the primary and any replica set
+// members that have become unreachable
+type topologyMonitor struct {
+	mu          sync.Mutex
+	primary     string
+	unavailable []string
+}
+
+// topologyChanged is called by the driver with the topology locked, so it must not run operations
+func (t *topologyMonitor) topologyChanged(e *event.TopologyDescriptionChangedEvent) {
+	var primary string
+	var unavailable []string
+	for _, server := range e.NewDescription.Servers {
+		switch {
+		case server.Kind == description.RSPrimary:
+			primary = server.Addr.String()
+		case server.Kind == description.RSGhost,
+			server.Kind == description.Unknown && server.LastError != nil:
+			// Members start as Unknown until their first heartbeat; only failed ones count
+			unavailable = append(unavailable, server.Addr.String())
+		}
+	}
+	sort.Strings(unavailable)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if primary != t.primary {
+		if primary == "" {
+			log.Printf("MongoDB primary %s is no longer available", t.primary)
+		} else {
+			log.Printf("MongoDB primary is now %s", primary)
+		}
+	}
+	if strings.Join(unavailable, ",") != strings.Join(t.unavailable, ",") {
+		if len(unavailable) > 0 {
+			log.Printf("MongoDB members unreachable: %s", strings.Join(unavailable, ", "))
+		} else {
+			log.Printf("All MongoDB members reachable")
+		}
+	}
+
+	t.primary = primary
+	t.unavailable = unavailable
+}
+
+// status reports unreachable members as a degraded state
+func (t *topologyMonitor) status() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.unavailable) == 0 {
+		return nil
+	}
+	return &models.DegradedError{
+		Reason: fmt.Sprintf("MongoDB members unreachable: %s", strings.Join(t.unavailable, ", ")),
+	}
+}
+
 // GetCollections returns all collection references
 func (m *MongoDB) GetCollections() *Collections {
 	return &Collections{
@@ -70,7 +201,33 @@ func (m *MongoDB) GetCollections() *Collections {
 		OptimizationScenarios: m.Database.Collection("optimization_scenarios"),
 		Recommendations:       m.Database.Collection("recommendations"),
 		Devices:               m.Database.Collection("devices"),
+		Tariffs:               m.Database.Collection("tariffs"),
+		OccupancySchedules:    m.Database.Collection("occupancy_schedules"),
+		CalendarDays:          m.Database.Collection("calendar_days"),
+		Buildings:             m.Database.Collection("buildings"),
+		AutomationRules:       m.Database.Collection("automation_rules"),
+		FeatureSnapshots:      m.Database.Collection("feature_snapshots"),
+		Jobs:                  m.Database.Collection("jobs"),
+		Settings:              m.Database.Collection("settings"),
+		SettingsHistory:       m.Database.Collection("settings_history"),
+		CorrectionFactors:     m.Database.Collection("correction_factors"),
+		ScenarioTemplates:     m.Database.Collection("scenario_templates"),
+		DemandContracts:       m.Database.Collection("demand_contracts"),
+	}
+}
+
+// Ping verifies the MongoDB primary is reachable
+func (m *MongoDB) Ping(ctx context.Context) error {
+	return m.Client.Ping(ctx, readpref.Primary())
+}
+
+// HealthCheck verifies the MongoDB primary is reachable and reports the connection as degraded
+// while other replica set members are unreachable
+func (m *MongoDB) HealthCheck(ctx context.Context) error {
+	if err := m.Ping(ctx); err != nil {
+		return err
 	}
+	return m.monitor.status()
 }
 
 // Close closes the MongoDB connection
@@ -97,6 +254,9 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: map[string]interface{}{"device_id": 1, "created_at": -1},
 		},
+		{
+			Keys: map[string]interface{}{"status": 1, "start_time": 1},
+		},
 	}
 	if _, err := collections.Forecasts.Indexes().CreateMany(ctx, forecastIndexes); err != nil {
 		return fmt.Errorf("failed to create forecast indexes: %w", err)
@@ -126,6 +286,15 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: map[string]interface{}{"scheduled_start": 1, "status": 1},
 		},
+		{
+			Keys: map[string]interface{}{"parent_scenario_id": 1},
+		},
+		{
+			Keys: map[string]interface{}{"name": "text", "description": "text"},
+			Options: options.Index().
+				SetName("scenario_search").
+				SetWeights(map[string]interface{}{"name": 10}),
+		},
 	}
 	if _, err := collections.OptimizationScenarios.Indexes().CreateMany(ctx, optimizationIndexes); err != nil {
 		return fmt.Errorf("failed to create optimization scenario indexes: %w", err)
@@ -158,6 +327,133 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		return fmt.Errorf("failed to create device indexes: %w", err)
 	}
 
+	// Tariffs collection indexes
+	tariffIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"region": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"is_active": 1},
+		},
+	}
+	if _, err := collections.Tariffs.Indexes().CreateMany(ctx, tariffIndexes); err != nil {
+		return fmt.Errorf("failed to create tariff indexes: %w", err)
+	}
+
+	// Occupancy schedules collection indexes
+	occupancyIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"building_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := collections.OccupancySchedules.Indexes().CreateMany(ctx, occupancyIndexes); err != nil {
+		return fmt.Errorf("failed to create occupancy schedule indexes: %w", err)
+	}
+
+	// Business calendar collection indexes; one entry per building or region and date
+	calendarIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"building_id": 1, "region": 1, "date": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"region": 1, "date": 1},
+		},
+	}
+	if _, err := collections.CalendarDays.Indexes().CreateMany(ctx, calendarIndexes); err != nil {
+		return fmt.Errorf("failed to create business calendar indexes: %w", err)
+	}
+
+	// Building registry collection indexes
+	buildingIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"building_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := collections.Buildings.Indexes().CreateMany(ctx, buildingIndexes); err != nil {
+		return fmt.Errorf("failed to create building indexes: %w", err)
+	}
+
+	// Demand contracts collection indexes
+	contractIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"building_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := collections.DemandContracts.Indexes().CreateMany(ctx, contractIndexes); err != nil {
+		return fmt.Errorf("failed to create demand contract indexes: %w", err)
+	}
+
+	// Automation rules collection indexes
+	automationIndexes := []mongo.IndexModel{
+		{Keys: map[string]interface{}{"building_id": 1}},
+		{Keys: map[string]interface{}{"enabled": 1}},
+	}
+	if _, err := collections.AutomationRules.Indexes().CreateMany(ctx, automationIndexes); err != nil {
+		return fmt.Errorf("failed to create automation rule indexes: %w", err)
+	}
+
+	// Scenario template names are unique per organization
+	templateIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "org_id", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := collections.ScenarioTemplates.Indexes().CreateMany(ctx, templateIndexes); err != nil {
+		return fmt.Errorf("failed to create scenario template indexes: %w", err)
+	}
+
+	// Feature snapshots collection indexes
+	featureIndexes := []mongo.IndexModel{
+		{Keys: map[string]interface{}{"kind": 1, "key": 1, "fetched_at": -1}},
+		{Keys: map[string]interface{}{"created_at": 1}},
+	}
+	if _, err := collections.FeatureSnapshots.Indexes().CreateMany(ctx, featureIndexes); err != nil {
+		return fmt.Errorf("failed to create feature snapshot indexes: %w", err)
+	}
+
+	// Job queue indexes: workers claim by type, status and run time; finished jobs expire
+	jobIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "type", Value: 1}, {Key: "status", Value: 1}, {Key: "run_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "finished_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(jobs.Retention.Seconds())),
+		},
+	}
+	if _, err := collections.Jobs.Indexes().CreateMany(ctx, jobIndexes); err != nil {
+		return fmt.Errorf("failed to create job indexes: %w", err)
+	}
+
+	// Settings history indexes: changes are listed newest first, overall and per setting
+	settingsHistoryIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "changed_at", Value: -1}}},
+		{Keys: bson.D{{Key: "changed_at", Value: -1}}},
+	}
+	if _, err := collections.SettingsHistory.Indexes().CreateMany(ctx, settingsHistoryIndexes); err != nil {
+		return fmt.Errorf("failed to create settings history indexes: %w", err)
+	}
+
+	// Correction factor indexes: one factor per action type, device type and device
+	correctionFactorIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "action_type", Value: 1}, {Key: "device_type", Value: 1}, {Key: "device_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := collections.CorrectionFactors.Indexes().CreateMany(ctx, correctionFactorIndexes); err != nil {
+		return fmt.Errorf("failed to create correction factor indexes: %w", err)
+	}
+
 	log.Println("MongoDB indexes created successfully")
 	return nil
 }
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/tenant"
+)
+
+// OccupancyRepository handles occupancy schedule database operations
+type OccupancyRepository struct {
+	collection *mongo.Collection
+}
+
+// NewOccupancyRepository creates a new occupancy repository
+func NewOccupancyRepository(collection *mongo.Collection) *OccupancyRepository {
+	return &OccupancyRepository{collection: collection}
+}
+
+// FindByBuilding retrieves the occupancy schedule for a building
+func (r *OccupancyRepository) FindByBuilding(ctx context.Context, buildingID string) (*models.OccupancySchedule, error) {
+	var schedule models.OccupancySchedule
+	err := r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"building_id": buildingID}, "building_id")).Decode(&schedule)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("occupancy schedule not found")
+		}
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// Upsert creates or replaces the occupancy schedule for a building
+func (r *OccupancyRepository) Upsert(ctx context.Context, schedule *models.OccupancySchedule) (*models.OccupancySchedule, error) {
+	now := time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"building_id": schedule.BuildingID},
+		bson.M{
+			"$set": bson.M{
+				"timezone":       schedule.TimeZone,
+				"region":         schedule.Region,
+				"business_hours": schedule.BusinessHours,
+				"holidays":       schedule.Holidays,
+				"live_occupancy": schedule.LiveOccupancy,
+				"updated_at":     now,
+			},
+			"$setOnInsert": bson.M{
+				"created_by": schedule.CreatedBy,
+				"created_at": now,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	var updated models.OccupancySchedule
+	if err := result.Decode(&updated); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// DeleteByBuilding removes the occupancy schedule for a building
+func (r *OccupancyRepository) DeleteByBuilding(ctx context.Context, buildingID string) error {
+	result, err := r.collection.DeleteOne(ctx, tenant.BuildingFilter(ctx, bson.M{"building_id": buildingID}, "building_id"))
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("occupancy schedule not found")
+	}
+
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"forecast-service/internal/models"
+	"forecast-service/internal/tenant"
 )
 
 // OptimizationRepository handles optimization scenario database operations
@@ -39,13 +40,23 @@ func (r *OptimizationRepository) Create(ctx context.Context, scenario *models.Op
 
 // FindByID retrieves an optimization scenario by its ID
 func (r *OptimizationRepository) FindByID(ctx context.Context, id string) (*models.OptimizationScenario, error) {
+	return r.FindByIDWithFields(ctx, id, nil)
+}
+
+// FindByIDWithFields retrieves an optimization scenario by its ID, loading only the selected fields
+func (r *OptimizationRepository) FindByIDWithFields(ctx context.Context, id string, fields *models.FieldSelection) (*models.OptimizationScenario, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid scenario ID format")
 	}
 
+	opts := options.FindOne()
+	if projection := fields.Projection(); projection != nil {
+		opts.SetProjection(projection)
+	}
+
 	var scenario models.OptimizationScenario
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&scenario)
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"), opts).Decode(&scenario)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("optimization scenario not found")
@@ -56,8 +67,9 @@ func (r *OptimizationRepository) FindByID(ctx context.Context, id string) (*mode
 	return &scenario, nil
 }
 
-// FindByBuilding retrieves optimization scenarios for a building
-func (r *OptimizationRepository) FindByBuilding(ctx context.Context, buildingID string, status models.OptimizationStatus, page, limit int) ([]*models.OptimizationScenario, int64, error) {
+// FindByBuilding retrieves optimization scenarios for a building. A field selection limits the
+// fields loaded.
+func (r *OptimizationRepository) FindByBuilding(ctx context.Context, buildingID string, status models.OptimizationStatus, page, limit int, fields *models.FieldSelection) ([]*models.OptimizationScenario, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -66,7 +78,7 @@ func (r *OptimizationRepository) FindByBuilding(ctx context.Context, buildingID
 	}
 
 	skip := int64((page - 1) * limit)
-	filter := bson.M{"building_id": buildingID}
+	filter := tenant.BuildingFilter(ctx, bson.M{"building_id": buildingID}, "building_id")
 
 	if status != "" {
 		filter["status"] = status
@@ -83,6 +95,9 @@ func (r *OptimizationRepository) FindByBuilding(ctx context.Context, buildingID
 		SetSkip(skip).
 		SetLimit(int64(limit)).
 		SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if projection := fields.Projection(); projection != nil {
+		findOptions.SetProjection(projection)
+	}
 
 	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
@@ -119,6 +134,107 @@ func (r *OptimizationRepository) FindPendingScenarios(ctx context.Context) ([]*m
 	return scenarios, nil
 }
 
+// FindActiveOverlapping retrieves approved or executing scenarios for a building that control
+// any of the given devices and whose schedule overlaps the window
+func (r *OptimizationRepository) FindActiveOverlapping(ctx context.Context, buildingID string, deviceIDs []string, start, end time.Time, excludeID primitive.ObjectID) ([]*models.OptimizationScenario, error) {
+	filter := bson.M{
+		"building_id": buildingID,
+		"status": bson.M{"$in": []models.OptimizationStatus{
+			models.OptimizationStatusApproved,
+			models.OptimizationStatusExecuting,
+		}},
+		"scheduled_start":   bson.M{"$lt": end},
+		"scheduled_end":     bson.M{"$gt": start},
+		"actions.device_id": bson.M{"$in": deviceIDs},
+	}
+	if !excludeID.IsZero() {
+		filter["_id"] = bson.M{"$ne": excludeID}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var scenarios []*models.OptimizationScenario
+	if err := cursor.All(ctx, &scenarios); err != nil {
+		return nil, err
+	}
+
+	return scenarios, nil
+}
+
+// FindCompletedInPeriod retrieves a building's completed scenarios whose schedule overlaps the
+// period, earliest first
+func (r *OptimizationRepository) FindCompletedInPeriod(ctx context.Context, buildingID string, start, end time.Time) ([]*models.OptimizationScenario, error) {
+	filter := tenant.BuildingFilter(ctx, bson.M{
+		"building_id":     buildingID,
+		"status":          models.OptimizationStatusCompleted,
+		"scheduled_start": bson.M{"$lt": end},
+		"scheduled_end":   bson.M{"$gt": start},
+	}, "building_id")
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "scheduled_start", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var scenarios []*models.OptimizationScenario
+	if err := cursor.All(ctx, &scenarios); err != nil {
+		return nil, err
+	}
+
+	return scenarios, nil
+}
+
+// Search retrieves up to limit scenarios matching the search, most relevant first.
+// A nil cursor starts from the best match.
+func (r *OptimizationRepository) Search(ctx context.Context, req *models.SearchScenariosRequest, after *models.SearchCursor, limit int) ([]*models.ScenarioSearchHit, error) {
+	filter := bson.M{}
+	if req.BuildingID != "" {
+		filter["building_id"] = req.BuildingID
+	}
+	if req.Status != "" {
+		filter["status"] = req.Status
+	}
+	if req.Type != "" {
+		filter["type"] = req.Type
+	}
+
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
+
+	cursor, err := r.collection.Aggregate(ctx, searchPipeline(req.Query, filter, after, limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hits []*models.ScenarioSearchHit
+	if err := cursor.All(ctx, &hits); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+// FindByParent retrieves the child scenarios of a portfolio scenario
+func (r *OptimizationRepository) FindByParent(ctx context.Context, parentID string) ([]*models.OptimizationScenario, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"parent_scenario_id": parentID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var scenarios []*models.OptimizationScenario
+	if err := cursor.All(ctx, &scenarios); err != nil {
+		return nil, err
+	}
+
+	return scenarios, nil
+}
+
 // Update updates an existing optimization scenario
 func (r *OptimizationRepository) Update(ctx context.Context, id string, updates bson.M) (*models.OptimizationScenario, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -130,7 +246,7 @@ func (r *OptimizationRepository) Update(ctx context.Context, id string, updates
 
 	result := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"_id": objectID},
+		tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"),
 		bson.M{"$set": updates},
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	)
@@ -242,7 +358,7 @@ func (r *OptimizationRepository) Delete(ctx context.Context, id string) error {
 		return errors.New("invalid scenario ID format")
 	}
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	result, err := r.collection.DeleteOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"))
 	if err != nil {
 		return err
 	}
@@ -262,3 +378,26 @@ func (r *OptimizationRepository) CountByStatus(ctx context.Context, buildingID s
 	}
 	return r.collection.CountDocuments(ctx, filter)
 }
+
+// WithdrawApprovals returns every approved scenario an approver has not yet seen executed to DRAFT,
+// so it runs only after someone else approves it. Returns how many approvals were withdrawn.
+func (r *OptimizationRepository) WithdrawApprovals(ctx context.Context, approverID, reason string) (int64, error) {
+	now := time.Now()
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"status": models.OptimizationStatusApproved, "approved_by": approverID},
+		bson.M{
+			"$set":   bson.M{"status": models.OptimizationStatusDraft, "updated_at": now},
+			"$unset": bson.M{"approved_by": "", "approved_at": ""},
+			"$push": bson.M{"execution_log": models.ExecutionLogEntry{
+				Timestamp: now,
+				Level:     "WARNING",
+				Message:   reason,
+			}},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"forecast-service/internal/models"
+	"forecast-service/internal/tenant"
 )
 
 // PeakLoadRepository handles peak load database operations
@@ -44,7 +45,7 @@ func (r *PeakLoadRepository) FindByID(ctx context.Context, id string) (*models.P
 	}
 
 	var peakLoad models.PeakLoad
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&peakLoad)
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id")).Decode(&peakLoad)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("peak load not found")
@@ -57,7 +58,7 @@ func (r *PeakLoadRepository) FindByID(ctx context.Context, id string) (*models.P
 
 // FindLatestByBuilding retrieves the latest peak load for a building
 func (r *PeakLoadRepository) FindLatestByBuilding(ctx context.Context, buildingID string) (*models.PeakLoad, error) {
-	filter := bson.M{"building_id": buildingID}
+	filter := tenant.BuildingFilter(ctx, bson.M{"building_id": buildingID}, "building_id")
 	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
 
 	var peakLoad models.PeakLoad
@@ -82,7 +83,7 @@ func (r *PeakLoadRepository) FindByBuilding(ctx context.Context, buildingID stri
 	}
 
 	skip := int64((page - 1) * limit)
-	filter := bson.M{"building_id": buildingID}
+	filter := tenant.BuildingFilter(ctx, bson.M{"building_id": buildingID}, "building_id")
 
 	// Get total count
 	total, err := r.collection.CountDocuments(ctx, filter)
@@ -158,7 +159,7 @@ func (r *PeakLoadRepository) Delete(ctx context.Context, id string) error {
 		return errors.New("invalid peak load ID format")
 	}
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	result, err := r.collection.DeleteOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"))
 	if err != nil {
 		return err
 	}
@@ -215,3 +216,23 @@ func (r *PeakLoadRepository) GetPeakLoadSummary(ctx context.Context, buildingID
 
 	return summary, nil
 }
+
+// CountOlderThan counts peak load records created before the given time
+func (r *PeakLoadRepository) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+}
+
+// DeleteOlderThan removes peak load records created before the given time
+func (r *PeakLoadRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"forecast-service/internal/models"
+	"forecast-service/internal/tenant"
 )
 
 // RecommendationRepository handles recommendation database operations
@@ -62,7 +63,7 @@ func (r *RecommendationRepository) FindByID(ctx context.Context, id string) (*mo
 	}
 
 	var rec models.Recommendation
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&rec)
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id")).Decode(&rec)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("recommendation not found")
@@ -152,7 +153,7 @@ func (r *RecommendationRepository) Delete(ctx context.Context, id string) error
 		return errors.New("invalid recommendation ID format")
 	}
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	result, err := r.collection.DeleteOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id"))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/tenant"
+)
+
+// ScenarioTemplateRepository handles scenario template database operations. Scoped requests
+// see their organization's templates and the shared ones, but change only their own.
+type ScenarioTemplateRepository struct {
+	collection *mongo.Collection
+}
+
+// NewScenarioTemplateRepository creates a new scenario template repository
+func NewScenarioTemplateRepository(collection *mongo.Collection) *ScenarioTemplateRepository {
+	return &ScenarioTemplateRepository{collection: collection}
+}
+
+// Create inserts a new scenario template into the database
+func (r *ScenarioTemplateRepository) Create(ctx context.Context, template *models.ScenarioTemplate) (*models.ScenarioTemplate, error) {
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = template.CreatedAt
+
+	result, err := r.collection.InsertOne(ctx, template)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("scenario template with this name already exists")
+		}
+		return nil, err
+	}
+
+	template.ID = result.InsertedID.(primitive.ObjectID)
+	return template, nil
+}
+
+// FindByID retrieves a scenario template by its ID
+func (r *ScenarioTemplateRepository) FindByID(ctx context.Context, id string) (*models.ScenarioTemplate, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid scenario template ID format")
+	}
+
+	var template models.ScenarioTemplate
+	err = r.collection.FindOne(ctx, tenant.OrgFilter(ctx, bson.M{"_id": objectID}, "org_id", true)).Decode(&template)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("scenario template not found")
+		}
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// FindAll retrieves scenario templates sorted by name, optionally of one optimization type
+func (r *ScenarioTemplateRepository) FindAll(ctx context.Context, optimizationType models.OptimizationType) ([]*models.ScenarioTemplate, error) {
+	filter := bson.M{}
+	if optimizationType != "" {
+		filter["type"] = optimizationType
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, tenant.OrgFilter(ctx, filter, "org_id", true), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []*models.ScenarioTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// Update updates an existing scenario template
+func (r *ScenarioTemplateRepository) Update(ctx context.Context, id string, updates bson.M) (*models.ScenarioTemplate, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid scenario template ID format")
+	}
+
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		tenant.OrgFilter(ctx, bson.M{"_id": objectID}, "org_id", false),
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var template models.ScenarioTemplate
+	if err := result.Decode(&template); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("scenario template not found")
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("scenario template with this name already exists")
+		}
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// Delete removes a scenario template from the database
+func (r *ScenarioTemplateRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid scenario template ID format")
+	}
+
+	result, err := r.collection.DeleteOne(ctx, tenant.OrgFilter(ctx, bson.M{"_id": objectID}, "org_id", false))
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("scenario template not found")
+	}
+
+	return nil
+}
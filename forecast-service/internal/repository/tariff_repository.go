@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"forecast-service/internal/models"
+)
+
+// TariffRepository handles local tariff schedule database operations
+type TariffRepository struct {
+	collection *mongo.Collection
+}
+
+// NewTariffRepository creates a new tariff repository
+func NewTariffRepository(collection *mongo.Collection) *TariffRepository {
+	return &TariffRepository{collection: collection}
+}
+
+// Create inserts a new tariff schedule into the database
+func (r *TariffRepository) Create(ctx context.Context, tariff *models.TariffSchedule) (*models.TariffSchedule, error) {
+	tariff.CreatedAt = time.Now()
+	tariff.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, tariff)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("tariff for this region already exists")
+		}
+		return nil, err
+	}
+
+	tariff.ID = result.InsertedID.(primitive.ObjectID)
+	return tariff, nil
+}
+
+// FindByID retrieves a tariff schedule by its ID
+func (r *TariffRepository) FindByID(ctx context.Context, id string) (*models.TariffSchedule, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid tariff ID format")
+	}
+
+	var tariff models.TariffSchedule
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&tariff)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("tariff not found")
+		}
+		return nil, err
+	}
+
+	return &tariff, nil
+}
+
+// FindActiveByRegion retrieves the active tariff schedule for a region
+func (r *TariffRepository) FindActiveByRegion(ctx context.Context, region string) (*models.TariffSchedule, error) {
+	var tariff models.TariffSchedule
+	err := r.collection.FindOne(ctx, bson.M{"region": region, "is_active": true}).Decode(&tariff)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("tariff not found")
+		}
+		return nil, err
+	}
+
+	return &tariff, nil
+}
+
+// FindAll retrieves tariff schedules with pagination
+func (r *TariffRepository) FindAll(ctx context.Context, region string, page, limit int) ([]*models.TariffSchedule, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	skip := int64((page - 1) * limit)
+	filter := bson.M{}
+
+	if region != "" {
+		filter["region"] = region
+	}
+
+	// Get total count
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Find tariffs with pagination
+	findOptions := options.Find().
+		SetSkip(skip).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "region", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var tariffs []*models.TariffSchedule
+	if err := cursor.All(ctx, &tariffs); err != nil {
+		return nil, 0, err
+	}
+
+	return tariffs, total, nil
+}
+
+// Update updates an existing tariff schedule
+func (r *TariffRepository) Update(ctx context.Context, id string, updates bson.M) (*models.TariffSchedule, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid tariff ID format")
+	}
+
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var tariff models.TariffSchedule
+	if err := result.Decode(&tariff); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("tariff not found")
+		}
+		return nil, err
+	}
+
+	return &tariff, nil
+}
+
+// Delete removes a tariff schedule from the database
+func (r *TariffRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid tariff ID format")
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("tariff not found")
+	}
+
+	return nil
+}
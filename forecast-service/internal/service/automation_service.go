@@ -0,0 +1,472 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"forecast-service/internal/integrations"
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+)
+
+// Default setpoints used when a rule does not specify them
+const (
+	defaultPreCoolTarget = 20.0
+	defaultPreCoolPeak   = 25.0
+	defaultPreHeatTarget = 23.0
+	defaultPreHeatPeak   = 18.0
+	maxAutomationLead    = 12
+)
+
+// AutomationService evaluates automation rules and generates pre-conditioning scenarios
+type AutomationService struct {
+	automationRepo      *repository.AutomationRepository
+	forecastRepo        *repository.ForecastRepository
+	externalClient      *integrations.ExternalClient
+	tariffService       *TariffService
+	optimizationService *OptimizationService
+}
+
+// NewAutomationService creates a new automation service
+func NewAutomationService(
+	automationRepo *repository.AutomationRepository,
+	forecastRepo *repository.ForecastRepository,
+	externalClient *integrations.ExternalClient,
+	tariffService *TariffService,
+	optimizationService *OptimizationService,
+) *AutomationService {
+	return &AutomationService{
+		automationRepo:      automationRepo,
+		forecastRepo:        forecastRepo,
+		externalClient:      externalClient,
+		tariffService:       tariffService,
+		optimizationService: optimizationService,
+	}
+}
+
+// CreateRule creates an automation rule for a building
+func (s *AutomationService) CreateRule(ctx context.Context, req *models.AutomationRuleRequest, userID string) (*models.AutomationRule, error) {
+	rule := &models.AutomationRule{CreatedBy: userID}
+	applyAutomationRuleRequest(rule, req)
+
+	if err := validateAutomationRule(rule); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	return s.automationRepo.Create(ctx, rule)
+}
+
+// GetRule retrieves an automation rule by ID
+func (s *AutomationService) GetRule(ctx context.Context, id string) (*models.AutomationRule, error) {
+	return s.automationRepo.FindByID(ctx, id)
+}
+
+// ListRules lists automation rules, optionally for a single building
+func (s *AutomationService) ListRules(ctx context.Context, buildingID string) ([]*models.AutomationRule, error) {
+	rules, err := s.automationRepo.FindByBuilding(ctx, buildingID)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []*models.AutomationRule{}
+	}
+	return rules, nil
+}
+
+// UpdateRule replaces the definition of an automation rule
+func (s *AutomationService) UpdateRule(ctx context.Context, id string, req *models.AutomationRuleRequest) (*models.AutomationRule, error) {
+	existing, err := s.automationRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := *existing
+	applyAutomationRuleRequest(&rule, req)
+	if req.Enabled == nil {
+		rule.Enabled = existing.Enabled
+	}
+
+	if err := validateAutomationRule(&rule); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	return s.automationRepo.Update(ctx, id, bson.M{
+		"building_id":        rule.BuildingID,
+		"name":               rule.Name,
+		"enabled":            rule.Enabled,
+		"action":             rule.Action,
+		"conditions":         rule.Conditions,
+		"peak_start_hour":    rule.PeakStartHour,
+		"peak_end_hour":      rule.PeakEndHour,
+		"lead_hours":         rule.LeadHours,
+		"target_temperature": rule.TargetTemperature,
+		"peak_temperature":   rule.PeakTemperature,
+	})
+}
+
+// SetRuleEnabled enables or disables an automation rule
+func (s *AutomationService) SetRuleEnabled(ctx context.Context, id string, enabled bool) (*models.AutomationRule, error) {
+	return s.automationRepo.Update(ctx, id, bson.M{"enabled": enabled})
+}
+
+// DeleteRule deletes an automation rule
+func (s *AutomationService) DeleteRule(ctx context.Context, id string) error {
+	return s.automationRepo.Delete(ctx, id)
+}
+
+// EvaluateRule evaluates a single rule on demand, generating a scenario if it matches.
+// Disabled rules are evaluated but never trigger.
+func (s *AutomationService) EvaluateRule(ctx context.Context, id, userID, authToken string) (*models.AutomationEvaluation, error) {
+	rule, err := s.automationRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluate(ctx, rule, time.Now(), userID, authToken), nil
+}
+
+// EvaluateAll evaluates every enabled rule and returns the outcomes
+func (s *AutomationService) EvaluateAll(ctx context.Context) ([]*models.AutomationEvaluation, error) {
+	rules, err := s.automationRepo.FindEnabled(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	evaluations := make([]*models.AutomationEvaluation, 0, len(rules))
+	for _, rule := range rules {
+		evaluations = append(evaluations, s.evaluate(ctx, rule, now, "automation", ""))
+	}
+	return evaluations, nil
+}
+
+// StartEvaluationWorker periodically evaluates enabled rules until the context is cancelled.
+// Evaluations are skipped while enabled reports false, so automation can be paused at runtime.
+func (s *AutomationService) StartEvaluationWorker(ctx context.Context, interval time.Duration, enabled func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !enabled() {
+				continue
+			}
+			evaluations, err := s.EvaluateAll(ctx)
+			if err != nil {
+				log.Printf("Failed to evaluate automation rules: %v", err)
+				continue
+			}
+			for _, evaluation := range evaluations {
+				if evaluation.Triggered {
+					log.Printf("Automation rule %s generated scenario %s for building %s", evaluation.RuleID, evaluation.ScenarioID, evaluation.BuildingID)
+				}
+			}
+		}
+	}
+}
+
+// evaluate checks a rule's conditions for its next peak window and triggers it when all match
+func (s *AutomationService) evaluate(ctx context.Context, rule *models.AutomationRule, now time.Time, userID, authToken string) *models.AutomationEvaluation {
+	peakStart, peakEnd := rule.NextPeakWindow(now)
+	evaluation := &models.AutomationEvaluation{
+		RuleID:      rule.ID.Hex(),
+		BuildingID:  rule.BuildingID,
+		PeakStart:   peakStart,
+		PeakEnd:     peakEnd,
+		EvaluatedAt: now,
+	}
+
+	metrics := &automationMetricSource{service: s, rule: rule, peakStart: peakStart, peakEnd: peakEnd, now: now, authToken: authToken}
+
+	evaluation.Matched = true
+	for _, condition := range rule.Conditions {
+		result := models.AutomationConditionResult{
+			Metric:    condition.Metric,
+			Operator:  condition.Operator,
+			Threshold: condition.Threshold,
+		}
+
+		actual, err := metrics.value(ctx, condition.Metric)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			actual = math.Round(actual*100) / 100
+			result.Actual = &actual
+			result.Matched = condition.Operator.Compare(actual, condition.Threshold)
+		}
+
+		if !result.Matched {
+			evaluation.Matched = false
+		}
+		evaluation.Conditions = append(evaluation.Conditions, result)
+	}
+
+	triggeredFor := peakStart.Format("2006-01-02")
+	switch {
+	case !evaluation.Matched:
+		evaluation.Reason = "conditions not met"
+	case !rule.Enabled:
+		evaluation.Reason = "rule is disabled"
+	case rule.LastTriggeredFor == triggeredFor:
+		evaluation.Reason = "scenario already generated for this peak"
+		evaluation.ScenarioID = rule.LastScenarioID
+	default:
+		scenario, err := s.optimizationService.GeneratePreConditioningScenario(ctx, &models.PreConditioningPlan{
+			BuildingID:        rule.BuildingID,
+			Action:            rule.Action,
+			PeakStart:         peakStart,
+			PeakEnd:           peakEnd,
+			LeadHours:         rule.LeadHours,
+			TargetTemperature: rule.TargetTemperature,
+			PeakTemperature:   rule.PeakTemperature,
+			RuleName:          rule.Name,
+		}, userID, authToken)
+		if err != nil {
+			evaluation.Reason = err.Error()
+			break
+		}
+		evaluation.Triggered = true
+		evaluation.ScenarioID = scenario.ID
+	}
+
+	scenarioID := ""
+	if evaluation.Triggered {
+		scenarioID = evaluation.ScenarioID
+	}
+	if err := s.automationRepo.RecordEvaluation(ctx, rule.ID, now, triggeredFor, scenarioID); err != nil {
+		log.Printf("Failed to record evaluation for automation rule %s: %v", rule.ID.Hex(), err)
+	}
+
+	return evaluation
+}
+
+// automationMetricSource resolves condition metrics for a rule's peak window, fetching each input once
+type automationMetricSource struct {
+	service   *AutomationService
+	rule      *models.AutomationRule
+	peakStart time.Time
+	peakEnd   time.Time
+	now       time.Time
+	authToken string
+
+	weather    []integrations.WeatherForecastPoint
+	weatherErr error
+	weatherSet bool
+	tariff     *models.Tariff
+	tariffErr  error
+	tariffSet  bool
+}
+
+// value returns the current value of a metric
+func (m *automationMetricSource) value(ctx context.Context, metric models.AutomationMetric) (float64, error) {
+	switch metric {
+	case models.AutomationMetricMaxTemperature, models.AutomationMetricMinTemperature:
+		points, err := m.weatherInWindow(ctx)
+		if err != nil {
+			return 0, err
+		}
+		value := points[0].Temperature
+		for _, point := range points[1:] {
+			if metric == models.AutomationMetricMaxTemperature {
+				value = math.Max(value, point.Temperature)
+			} else {
+				value = math.Min(value, point.Temperature)
+			}
+		}
+		return value, nil
+
+	case models.AutomationMetricTemperatureChange:
+		points, err := m.weatherInWindow(ctx)
+		if err != nil {
+			return 0, err
+		}
+		current, err := m.service.externalClient.GetCurrentWeather(ctx, m.rule.BuildingID, m.authToken)
+		if err != nil {
+			return 0, fmt.Errorf("current weather unavailable: %w", err)
+		}
+		highest := points[0].Temperature
+		for _, point := range points[1:] {
+			highest = math.Max(highest, point.Temperature)
+		}
+		return highest - current.Temperature, nil
+
+	case models.AutomationMetricTariffRate, models.AutomationMetricTariffPeakRatio:
+		tariff, err := m.loadTariff(ctx)
+		if err != nil {
+			return 0, err
+		}
+		rate := m.windowRate(tariff)
+		if metric == models.AutomationMetricTariffRate {
+			return rate, nil
+		}
+		if tariff.OffPeakRate <= 0 {
+			return 0, fmt.Errorf("tariff has no off-peak rate")
+		}
+		return rate / tariff.OffPeakRate, nil
+
+	case models.AutomationMetricForecastPeak:
+		forecast, err := latestDemandForecast(ctx, m.service.forecastRepo, m.rule.BuildingID)
+		if err != nil {
+			return 0, fmt.Errorf("no demand forecast available: %w", err)
+		}
+		found := false
+		peak := 0.0
+		for _, prediction := range forecast.Predictions {
+			if prediction.Timestamp.Before(m.peakStart) || !prediction.Timestamp.Before(m.peakEnd) {
+				continue
+			}
+			if !found || prediction.PredictedValue > peak {
+				peak = prediction.PredictedValue
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("latest forecast does not cover the peak window")
+		}
+		return peak, nil
+	}
+
+	return 0, fmt.Errorf("unsupported metric %s", metric)
+}
+
+// weatherInWindow returns the weather forecast points that fall within the peak window
+func (m *automationMetricSource) weatherInWindow(ctx context.Context) ([]integrations.WeatherForecastPoint, error) {
+	if !m.weatherSet {
+		hours := int(math.Ceil(m.peakEnd.Sub(m.now).Hours()))
+		m.weather, m.weatherErr = m.service.externalClient.GetWeatherForecast(ctx, m.rule.BuildingID, hours, m.authToken)
+		m.weatherSet = true
+	}
+	if m.weatherErr != nil {
+		return nil, fmt.Errorf("weather forecast unavailable: %w", m.weatherErr)
+	}
+
+	var points []integrations.WeatherForecastPoint
+	for _, point := range m.weather {
+		if !point.Timestamp.Before(m.peakStart) && point.Timestamp.Before(m.peakEnd) {
+			points = append(points, point)
+		}
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("weather forecast does not cover the peak window")
+	}
+	return points, nil
+}
+
+// loadTariff resolves the tariff once per evaluation
+func (m *automationMetricSource) loadTariff(ctx context.Context) (*models.Tariff, error) {
+	if !m.tariffSet {
+		m.tariff, m.tariffErr = m.service.tariffService.GetCurrentTariff(ctx, "default", m.authToken)
+		m.tariffSet = true
+	}
+	return m.tariff, m.tariffErr
+}
+
+// windowRate returns the highest time-of-use rate overlapping the peak window
+func (m *automationMetricSource) windowRate(tariff *models.Tariff) float64 {
+	if len(tariff.TimeOfUseRates) == 0 {
+		return tariff.PeakRate
+	}
+
+	rate := 0.0
+	for hour := m.rule.PeakStartHour; hour < m.rule.PeakEndHour; hour++ {
+		for i := range tariff.TimeOfUseRates {
+			if tariff.TimeOfUseRates[i].Contains(hour) && tariff.TimeOfUseRates[i].RatePerKWh > rate {
+				rate = tariff.TimeOfUseRates[i].RatePerKWh
+			}
+		}
+	}
+	if rate == 0 {
+		return tariff.CurrentRate
+	}
+	return rate
+}
+
+// applyAutomationRuleRequest copies a request onto a rule, filling in defaults
+func applyAutomationRuleRequest(rule *models.AutomationRule, req *models.AutomationRuleRequest) {
+	rule.BuildingID = req.BuildingID
+	rule.Name = req.Name
+	rule.Action = req.Action
+	rule.Conditions = req.Conditions
+	rule.Enabled = true
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+
+	rule.PeakStartHour = models.DefaultAutomationPeakStartHour
+	if req.PeakStartHour != nil {
+		rule.PeakStartHour = *req.PeakStartHour
+	}
+	rule.PeakEndHour = models.DefaultAutomationPeakEndHour
+	if req.PeakEndHour != nil {
+		rule.PeakEndHour = *req.PeakEndHour
+	}
+	rule.LeadHours = req.LeadHours
+	if rule.LeadHours == 0 {
+		rule.LeadHours = models.DefaultAutomationLeadHours
+	}
+
+	rule.TargetTemperature, rule.PeakTemperature = req.TargetTemperature, req.PeakTemperature
+	if rule.Action == models.AutomationActionPreHeat {
+		if rule.TargetTemperature == 0 {
+			rule.TargetTemperature = defaultPreHeatTarget
+		}
+		if rule.PeakTemperature == 0 {
+			rule.PeakTemperature = defaultPreHeatPeak
+		}
+	} else {
+		if rule.TargetTemperature == 0 {
+			rule.TargetTemperature = defaultPreCoolTarget
+		}
+		if rule.PeakTemperature == 0 {
+			rule.PeakTemperature = defaultPreCoolPeak
+		}
+	}
+
+	if rule.Name == "" {
+		rule.Name = fmt.Sprintf("%s %02d:00-%02d:00", rule.Action, rule.PeakStartHour, rule.PeakEndHour)
+	}
+}
+
+// validateAutomationRule validates the action, peak window and conditions of a rule
+func validateAutomationRule(rule *models.AutomationRule) error {
+	if rule.Action != models.AutomationActionPreCool && rule.Action != models.AutomationActionPreHeat {
+		return fmt.Errorf("action must be PRE_COOL or PRE_HEAT")
+	}
+	if !validHour(rule.PeakStartHour) || !validHour(rule.PeakEndHour) || rule.PeakStartHour >= rule.PeakEndHour {
+		return fmt.Errorf("peak window must satisfy 0 <= peakStartHour < peakEndHour <= 24")
+	}
+	if rule.LeadHours < 1 || rule.LeadHours > maxAutomationLead {
+		return fmt.Errorf("lead hours must be between 1 and %d", maxAutomationLead)
+	}
+	if rule.Action == models.AutomationActionPreCool && rule.TargetTemperature >= rule.PeakTemperature {
+		return fmt.Errorf("pre-cooling target temperature must be below the peak temperature")
+	}
+	if rule.Action == models.AutomationActionPreHeat && rule.TargetTemperature <= rule.PeakTemperature {
+		return fmt.Errorf("pre-heating target temperature must be above the peak temperature")
+	}
+	if len(rule.Conditions) == 0 {
+		return fmt.Errorf("at least one condition is required")
+	}
+	for _, condition := range rule.Conditions {
+		switch condition.Metric {
+		case models.AutomationMetricMaxTemperature, models.AutomationMetricMinTemperature,
+			models.AutomationMetricTemperatureChange, models.AutomationMetricTariffRate,
+			models.AutomationMetricTariffPeakRatio, models.AutomationMetricForecastPeak:
+		default:
+			return fmt.Errorf("unsupported condition metric %q", condition.Metric)
+		}
+		switch condition.Operator {
+		case models.AutomationOperatorGT, models.AutomationOperatorGTE, models.AutomationOperatorLT, models.AutomationOperatorLTE:
+		default:
+			return fmt.Errorf("unsupported condition operator %q", condition.Operator)
+		}
+	}
+	return nil
+}
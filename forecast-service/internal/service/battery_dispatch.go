@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"forecast-service/internal/models"
+)
+
+// Battery properties assumed when neither the device nor its type states them
+const (
+	defaultBatteryCapacityKWh  = 100.0
+	defaultBatteryMaxPowerKW   = 50.0
+	defaultBatteryMinSoC       = 10.0 // percent
+	defaultBatteryMaxSoC       = 95.0 // percent
+	defaultBatteryEfficiency   = 0.9  // round trip
+	batteryDispatchDefaultSpan = 24 * time.Hour
+)
+
+// Windows assumed when the tariff has no time-of-use rates but distinguishes peak and off-peak
+// rates, as hours of day in the building's time zone
+const (
+	defaultOffPeakStartHour = 0
+	defaultOffPeakEndHour   = 6
+	defaultPeakStartHour    = 17
+	defaultPeakEndHour      = 21
+)
+
+// dispatchWindow is a period in which a battery is charged or discharged
+type dispatchWindow struct {
+	start time.Time
+	end   time.Time
+	rate  float64
+}
+
+// hours returns the length of the window in hours
+func (w dispatchWindow) hours() float64 {
+	return w.end.Sub(w.start).Hours()
+}
+
+// isBatteryDevice reports whether a device type can be charged and discharged on command
+func (s *OptimizationService) isBatteryDevice(deviceType *models.DeviceType) bool {
+	return deviceType.SupportsCommand("CHARGE") && deviceType.SupportsCommand("DISCHARGE")
+}
+
+// generateBatteryDispatchActions charges the building's batteries in the cheapest hours of the
+// scenario and discharges them in the most expensive hours that follow, so stored energy
+// replaces grid imports at peak rates. Each battery charges up to its maximum state of charge
+// and discharges down to its minimum, spread evenly over the windows and capped by its power.
+func (s *OptimizationService) generateBatteryDispatchActions(
+	ctx context.Context,
+	req *models.OptimizationGenerateRequest,
+	devices []models.DeviceState,
+	tariff *models.Tariff,
+	authToken string,
+) ([]models.OptimizationAction, error) {
+	if tariff == nil {
+		return nil, fmt.Errorf("validation failed: battery dispatch needs a tariff, none is available")
+	}
+
+	location := s.occupancyService.Location(ctx, req.BuildingID)
+	charge, discharge, ok := batteryDispatchWindows(tariff, location, req.ScheduledStart, req.ScheduledEnd)
+	if !ok {
+		return nil, fmt.Errorf("validation failed: no peak window follows cheaper hours between the scheduled start and end")
+	}
+
+	excluded := make(map[string]bool, len(req.Constraints.ExcludeDevices))
+	for _, deviceID := range req.Constraints.ExcludeDevices {
+		excluded[deviceID] = true
+	}
+
+	var actions []models.OptimizationAction
+	for _, device := range devices {
+		if !device.Controllable || excluded[device.DeviceID] {
+			continue
+		}
+		deviceType := s.deviceTypes.Resolve(ctx, device, authToken)
+		if !s.isBatteryDevice(deviceType) {
+			continue
+		}
+
+		capacity := batteryProperty(device, deviceType, "capacityKWh", defaultBatteryCapacityKWh)
+		maxPower := batteryProperty(device, deviceType, "maxPowerKW", defaultBatteryMaxPowerKW)
+		minSoC := batteryProperty(device, deviceType, "minStateOfCharge", defaultBatteryMinSoC)
+		maxSoC := batteryProperty(device, deviceType, "maxStateOfCharge", defaultBatteryMaxSoC)
+		efficiency := batteryProperty(device, deviceType, "roundTripEfficiency", defaultBatteryEfficiency)
+		soc, reported := device.Parameters["stateOfCharge"].(float64)
+		if !reported {
+			soc = minSoC
+		}
+		soc = math.Min(math.Max(soc, minSoC), maxSoC)
+
+		// Energy in kWh: what fits until the maximum state of charge, then what is stored above the minimum
+		chargeKWh := math.Min(capacity*(maxSoC-soc)/100, maxPower*charge.hours())
+		dischargeKWh := math.Min(capacity*(soc-minSoC)/100+chargeKWh*efficiency, maxPower*discharge.hours())
+		if dischargeKWh <= 0 {
+			continue
+		}
+
+		if chargeKWh > 0 {
+			chargeKW := chargeKWh / charge.hours()
+			actions = append(actions, models.OptimizationAction{
+				ID:             uuid.New().String()[:8],
+				DeviceID:       device.DeviceID,
+				DeviceName:     "Device " + device.DeviceID,
+				DeviceType:     deviceType.Name,
+				ActionType:     "CHARGE",
+				CurrentValue:   fmt.Sprintf("%.0f%% charged", soc),
+				TargetValue:    fmt.Sprintf("%.1f kW", chargeKW),
+				ScheduledTime:  charge.start,
+				Duration:       int(charge.end.Sub(charge.start).Minutes()),
+				Status:         "PENDING",
+				ExpectedImpact: -chargeKW, // Charging draws extra power from the grid
+			})
+		}
+
+		dischargeKW := dischargeKWh / discharge.hours()
+		actions = append(actions, models.OptimizationAction{
+			ID:             uuid.New().String()[:8],
+			DeviceID:       device.DeviceID,
+			DeviceName:     "Device " + device.DeviceID,
+			DeviceType:     deviceType.Name,
+			ActionType:     "DISCHARGE",
+			CurrentValue:   fmt.Sprintf("%.0f%% charged", math.Min(soc+chargeKWh/capacity*100, maxSoC)),
+			TargetValue:    fmt.Sprintf("%.1f kW", dischargeKW),
+			ScheduledTime:  discharge.start,
+			Duration:       int(discharge.end.Sub(discharge.start).Minutes()),
+			Status:         "PENDING",
+			ExpectedImpact: dischargeKW,
+		})
+	}
+
+	return actions, nil
+}
+
+// batteryProperty reads a numeric battery property from the device's parameters, then from its
+// type's default constraints, falling back to the given default
+func batteryProperty(device models.DeviceState, deviceType *models.DeviceType, name string, fallback float64) float64 {
+	if value, ok := device.Parameters[name].(float64); ok && value > 0 {
+		return value
+	}
+	if value, ok := deviceType.Constraint(name); ok && value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// batteryDispatchWindows finds the discharge window, the first run of hours at the highest rate
+// between start and end that cheaper hours precede, and the charge window, the first run of
+// hours at the lowest rate before it. It fails when the rates do not differ or every peak starts
+// before any cheaper hour.
+func batteryDispatchWindows(tariff *models.Tariff, location *time.Location, start, end time.Time) (dispatchWindow, dispatchWindow, bool) {
+	var hours []dispatchWindow
+	for hour := start.Truncate(time.Hour); hour.Before(end); hour = hour.Add(time.Hour) {
+		window := dispatchWindow{start: hour, end: hour.Add(time.Hour), rate: hourlyRate(tariff, hour.In(location))}
+		if window.start.Before(start) {
+			window.start = start
+		}
+		if window.end.After(end) {
+			window.end = end
+		}
+		hours = append(hours, window)
+	}
+
+	highest := 0.0
+	for _, hour := range hours {
+		highest = math.Max(highest, hour.rate)
+	}
+
+	for i := 0; i < len(hours); i++ {
+		if hours[i].rate != highest {
+			continue
+		}
+		discharge, next := runAtRate(hours, i)
+
+		// Charge at the cheapest hours before the peak
+		charge, found := dispatchWindow{}, false
+		for j := 0; j < i; j++ {
+			if hours[j].rate < highest && (!found || hours[j].rate < charge.rate) {
+				charge, _ = runAtRate(hours[:i], j)
+				found = true
+			}
+		}
+		if found {
+			return charge, discharge, true
+		}
+		i = next - 1
+	}
+	return dispatchWindow{}, dispatchWindow{}, false
+}
+
+// runAtRate extends the hour at index i with the consecutive hours at the same rate, returning
+// the run and the index of the hour after it
+func runAtRate(hours []dispatchWindow, i int) (dispatchWindow, int) {
+	run := hours[i]
+	j := i + 1
+	for ; j < len(hours) && hours[j].rate == run.rate; j++ {
+		run.end = hours[j].end
+	}
+	return run, j
+}
+
+// hourlyRate returns the energy rate of the tariff in the hour starting at the given local time.
+// Without time-of-use rates, the default off-peak and peak windows get the tariff's off-peak and
+// peak rates.
+func hourlyRate(tariff *models.Tariff, local time.Time) float64 {
+	hour := local.Hour()
+
+	if len(tariff.TimeOfUseRates) == 0 {
+		switch {
+		case hour >= defaultOffPeakStartHour && hour < defaultOffPeakEndHour && tariff.OffPeakRate > 0:
+			return tariff.OffPeakRate
+		case hour >= defaultPeakStartHour && hour < defaultPeakEndHour && tariff.PeakRate > 0:
+			return tariff.PeakRate
+		}
+		return tariff.CurrentRate
+	}
+
+	rate, matched := 0.0, false
+	for i := range tariff.TimeOfUseRates {
+		tou := &tariff.TimeOfUseRates[i]
+		if !tou.Contains(hour) || !touAppliesOn(tou, local.Weekday()) {
+			continue
+		}
+		if !matched || tou.RatePerKWh > rate {
+			rate, matched = tou.RatePerKWh, true
+		}
+	}
+	if matched {
+		return rate
+	}
+	if tariff.BaseRate > 0 {
+		return tariff.BaseRate
+	}
+	return tariff.CurrentRate
+}
+
+// touAppliesOn checks whether a time-of-use rate applies on a weekday; rates without days apply every day
+func touAppliesOn(rate *models.TariffRate, weekday time.Weekday) bool {
+	if len(rate.DaysOfWeek) == 0 {
+		return true
+	}
+	for _, day := range rate.DaysOfWeek {
+		if day == int(weekday) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+)
+
+// BuildingService handles the building registry and the demand contracts of buildings
+type BuildingService struct {
+	buildingRepo *repository.BuildingRepository
+	contractRepo *repository.DemandContractRepository
+}
+
+// NewBuildingService creates a new building service
+func NewBuildingService(buildingRepo *repository.BuildingRepository, contractRepo *repository.DemandContractRepository) *BuildingService {
+	return &BuildingService{buildingRepo: buildingRepo, contractRepo: contractRepo}
+}
+
+// ListBuildings retrieves every registered building
+func (s *BuildingService) ListBuildings(ctx context.Context) ([]*models.BuildingResponse, error) {
+	buildings, err := s.buildingRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.BuildingResponse, len(buildings))
+	for i, building := range buildings {
+		responses[i] = building.ToResponse()
+	}
+	return responses, nil
+}
+
+// GetBuilding retrieves the registry entry of a building
+func (s *BuildingService) GetBuilding(ctx context.Context, buildingID string) (*models.BuildingResponse, error) {
+	building, err := s.buildingRepo.FindByBuilding(ctx, buildingID)
+	if err != nil {
+		return nil, err
+	}
+	return building.ToResponse(), nil
+}
+
+// SaveBuilding registers a building or replaces its entry
+func (s *BuildingService) SaveBuilding(ctx context.Context, buildingID string, req *models.BuildingRequest, userID string) (*models.BuildingResponse, error) {
+	if _, err := time.LoadLocation(req.TimeZone); err != nil || req.TimeZone == "Local" {
+		return nil, fmt.Errorf("validation failed: unknown time zone %q", req.TimeZone)
+	}
+
+	saved, err := s.buildingRepo.Upsert(ctx, &models.Building{
+		BuildingID: buildingID,
+		Name:       req.Name,
+		TimeZone:   req.TimeZone,
+		CreatedBy:  userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return saved.ToResponse(), nil
+}
+
+// DeleteBuilding removes a building from the registry. Its time zone falls back to the one of
+// its occupancy schedule, or UTC.
+func (s *BuildingService) DeleteBuilding(ctx context.Context, buildingID string) error {
+	return s.buildingRepo.DeleteByBuilding(ctx, buildingID)
+}
+
+// GetDemandContract retrieves the demand contract of a building
+func (s *BuildingService) GetDemandContract(ctx context.Context, buildingID string) (*models.DemandContract, error) {
+	return s.contractRepo.FindByBuilding(ctx, buildingID)
+}
+
+// SaveDemandContract sets the demand contract of a building, replacing any previous one. The
+// measurement window defaults to 15 minutes and the currency to USD.
+func (s *BuildingService) SaveDemandContract(ctx context.Context, buildingID string, req *models.DemandContractRequest, userID string) (*models.DemandContract, error) {
+	windowMinutes := req.WindowMinutes
+	if windowMinutes == 0 {
+		windowMinutes = models.DefaultDemandWindowMinutes
+	}
+	currency := req.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+
+	return s.contractRepo.Upsert(ctx, &models.DemandContract{
+		BuildingID:       buildingID,
+		MaxDemandKW:      req.MaxDemandKW,
+		PenaltyRatePerKW: req.PenaltyRatePerKW,
+		WindowMinutes:    windowMinutes,
+		Currency:         currency,
+		CreatedBy:        userID,
+	})
+}
+
+// DeleteDemandContract removes the demand contract of a building, so peak shaving no longer
+// targets a contracted limit
+func (s *BuildingService) DeleteDemandContract(ctx context.Context, buildingID string) error {
+	return s.contractRepo.DeleteByBuilding(ctx, buildingID)
+}
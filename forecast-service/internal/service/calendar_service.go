@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+	"forecast-service/internal/tenant"
+)
+
+// maxCalendarLoadFactor bounds how much a special event may scale the forecast load
+const maxCalendarLoadFactor = 5
+
+// CalendarService handles the business calendar of holidays, half-days and special events
+// consulted by occupancy schedules
+type CalendarService struct {
+	calendarRepo  *repository.CalendarRepository
+	occupancyRepo *repository.OccupancyRepository
+}
+
+// NewCalendarService creates a new business calendar service
+func NewCalendarService(calendarRepo *repository.CalendarRepository, occupancyRepo *repository.OccupancyRepository) *CalendarService {
+	return &CalendarService{
+		calendarRepo:  calendarRepo,
+		occupancyRepo: occupancyRepo,
+	}
+}
+
+// CreateDay adds a business calendar entry for a building or region
+func (s *CalendarService) CreateDay(ctx context.Context, req *models.CalendarDayRequest, userID string) (*models.CalendarDay, error) {
+	if err := checkCalendarAccess(ctx, req); err != nil {
+		return nil, err
+	}
+
+	day := calendarDayFromRequest(req)
+	day.CreatedBy = userID
+	if err := validateCalendarDay(day); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	return s.calendarRepo.Create(ctx, day)
+}
+
+// GetDay retrieves a business calendar entry by ID
+func (s *CalendarService) GetDay(ctx context.Context, id string) (*models.CalendarDay, error) {
+	return s.calendarRepo.FindByID(ctx, id)
+}
+
+// ListDays lists business calendar entries by date. Listing a building's entries includes those
+// of the region its occupancy schedule belongs to.
+func (s *CalendarService) ListDays(ctx context.Context, req *models.ListCalendarDaysRequest) ([]models.CalendarDay, error) {
+	if req.BuildingID != "" && !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
+	for _, date := range []string{req.From, req.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("validation failed: dates must use the YYYY-MM-DD format")
+		}
+	}
+
+	region := req.Region
+	if req.BuildingID != "" && region == "" {
+		if schedule, err := s.occupancyRepo.FindByBuilding(ctx, req.BuildingID); err == nil {
+			region = schedule.Region
+		}
+	}
+
+	return s.calendarRepo.Find(ctx, req.BuildingID, region, req.From, req.To)
+}
+
+// UpdateDay replaces a business calendar entry
+func (s *CalendarService) UpdateDay(ctx context.Context, id string, req *models.CalendarDayRequest) (*models.CalendarDay, error) {
+	if err := checkCalendarAccess(ctx, req); err != nil {
+		return nil, err
+	}
+
+	day := calendarDayFromRequest(req)
+	if err := validateCalendarDay(day); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	return s.calendarRepo.Replace(ctx, id, day)
+}
+
+// DeleteDay removes a business calendar entry
+func (s *CalendarService) DeleteDay(ctx context.Context, id string) error {
+	return s.calendarRepo.Delete(ctx, id)
+}
+
+// checkCalendarAccess checks that a request scoped to an organization only writes entries of its
+// own buildings. Regional entries apply to every organization's buildings in the region, so only
+// unscoped requests may write them.
+func checkCalendarAccess(ctx context.Context, req *models.CalendarDayRequest) error {
+	if tenant.FromContext(ctx) == nil {
+		return nil
+	}
+	if req.BuildingID == "" {
+		return fmt.Errorf("validation failed: regional calendar entries cannot be changed by an organization")
+	}
+	if !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
+	return nil
+}
+
+// calendarDayFromRequest builds a calendar entry from a request, applying type defaults
+func calendarDayFromRequest(req *models.CalendarDayRequest) *models.CalendarDay {
+	day := &models.CalendarDay{
+		BuildingID: req.BuildingID,
+		Region:     req.Region,
+		Date:       req.Date,
+		Type:       req.Type,
+		Name:       req.Name,
+		OpenHour:   req.OpenHour,
+		CloseHour:  req.CloseHour,
+		LoadFactor: req.LoadFactor,
+	}
+	if day.Type == models.CalendarDaySpecialEvent && day.LoadFactor == 0 {
+		day.LoadFactor = 1
+	}
+	return day
+}
+
+// validateCalendarDay validates the scope, date, type, hours and load factor of a calendar entry
+func validateCalendarDay(day *models.CalendarDay) error {
+	if (day.BuildingID == "") == (day.Region == "") {
+		return fmt.Errorf("exactly one of buildingId and region must be set")
+	}
+	if _, err := time.Parse("2006-01-02", day.Date); err != nil {
+		return fmt.Errorf("date must use the YYYY-MM-DD format")
+	}
+	if !day.Type.IsValid() {
+		return fmt.Errorf("invalid calendar day type: %s", day.Type)
+	}
+
+	for _, hour := range []*int{day.OpenHour, day.CloseHour} {
+		if hour != nil && !validHour(*hour) {
+			return fmt.Errorf("hours must be between 0 and 24")
+		}
+	}
+
+	switch day.Type {
+	case models.CalendarDayHoliday:
+		if day.OpenHour != nil || day.CloseHour != nil || day.LoadFactor != 0 {
+			return fmt.Errorf("holidays cannot set hours or a load factor")
+		}
+	case models.CalendarDayHalfDay:
+		if day.OpenHour != nil || day.LoadFactor != 0 {
+			return fmt.Errorf("half-days can only set a closing hour")
+		}
+	case models.CalendarDaySpecialEvent:
+		if (day.OpenHour == nil) != (day.CloseHour == nil) {
+			return fmt.Errorf("special events must set both an opening and a closing hour, or neither")
+		}
+		if day.OpenHour != nil && *day.OpenHour >= *day.CloseHour {
+			return fmt.Errorf("special events must open before they close")
+		}
+		if day.LoadFactor <= 0 || day.LoadFactor > maxCalendarLoadFactor {
+			return fmt.Errorf("load factor must be greater than 0 and at most %d", maxCalendarLoadFactor)
+		}
+	}
+
+	return nil
+}
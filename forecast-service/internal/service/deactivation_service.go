@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"forecast-service/internal/integrations"
+	"forecast-service/internal/repository"
+)
+
+// DeactivationService releases what users deactivated in the Security service own in this service
+type DeactivationService struct {
+	optimizationRepo *repository.OptimizationRepository
+	automationRepo   *repository.AutomationRepository
+	securityClient   *integrations.SecurityClient
+}
+
+// NewDeactivationService creates a new deactivation service
+func NewDeactivationService(
+	optimizationRepo *repository.OptimizationRepository,
+	automationRepo *repository.AutomationRepository,
+	securityClient *integrations.SecurityClient,
+) *DeactivationService {
+	return &DeactivationService{
+		optimizationRepo: optimizationRepo,
+		automationRepo:   automationRepo,
+		securityClient:   securityClient,
+	}
+}
+
+// ReleaseUser withdraws the approvals a deactivated user gave to scenarios that have not run yet
+// and pauses the automation rules they created, then records the result in the audit log.
+// Draft scenarios the user created stay available for others to review.
+func (s *DeactivationService) ReleaseUser(ctx context.Context, userID, actorID string) error {
+	details := map[string]interface{}{}
+	var failures []string
+
+	withdrawn, err := s.optimizationRepo.WithdrawApprovals(ctx, userID, "Approval withdrawn because the approver was deactivated")
+	if err != nil {
+		failures = append(failures, "scenario approvals: "+err.Error())
+	} else {
+		details["approvalsWithdrawn"] = withdrawn
+	}
+
+	disabled, err := s.automationRepo.DisableByCreator(ctx, userID)
+	if err != nil {
+		failures = append(failures, "automation rules: "+err.Error())
+	} else {
+		details["automationRulesPaused"] = disabled
+	}
+
+	status, errorMsg := "SUCCESS", ""
+	if len(failures) > 0 {
+		status, errorMsg = "FAILURE", strings.Join(failures, "; ")
+	}
+	s.securityClient.AuditLog(
+		ctx, actorID, "", "USER_DEACTIVATION_CASCADE", "user", userID,
+		status, errorMsg, "", "", "", "", details,
+	)
+
+	if len(failures) > 0 {
+		return errors.New(errorMsg)
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"forecast-service/internal/integrations"
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+)
+
+// DemandChargeService forecasts the demand charge of a building's billing period from its tariff,
+// observed load and latest demand forecast
+type DemandChargeService struct {
+	forecastRepo     *repository.ForecastRepository
+	optimizationRepo *repository.OptimizationRepository
+	tariffService    *TariffService
+	externalClient   *integrations.ExternalClient
+	occupancyService *OccupancyService
+}
+
+// NewDemandChargeService creates a new demand charge service
+func NewDemandChargeService(
+	forecastRepo *repository.ForecastRepository,
+	optimizationRepo *repository.OptimizationRepository,
+	tariffService *TariffService,
+	externalClient *integrations.ExternalClient,
+	occupancyService *OccupancyService,
+) *DemandChargeService {
+	return &DemandChargeService{
+		forecastRepo:     forecastRepo,
+		optimizationRepo: optimizationRepo,
+		tariffService:    tariffService,
+		externalClient:   externalClient,
+		occupancyService: occupancyService,
+	}
+}
+
+// intervalLoad is the average load of one interval and whether it was observed or forecast.
+// Observed intervals are hours; forecast intervals are as long as the forecast's resolution.
+type intervalLoad struct {
+	start    time.Time
+	length   time.Duration
+	kw       float64
+	upperKW  float64
+	forecast bool
+}
+
+// ForecastDemandCharge estimates the peak demand and demand charge of the current billing period,
+// the calendar month in the building's time zone, and compares it with the previous month. Each
+// demand charge of the tariff bills the highest interval load within its window at RatePerKW.
+// Observed load is hourly; hours still to come use a 15- or 30-minute forecast when one is
+// available, so intra-hour peaks are billed as the meter would, and the hourly forecast otherwise.
+// When a PEAK_SHAVING scenario is given, its action reductions are taken off the load to
+// estimate the demand charge it saves.
+func (s *DemandChargeService) ForecastDemandCharge(ctx context.Context, req *models.DemandChargeRequest, authToken string) (*models.DemandChargeForecast, error) {
+	region := req.Region
+	if region == "" {
+		region = "default"
+	}
+
+	tariff, err := s.tariffService.GetCurrentTariff(ctx, region, authToken)
+	if err != nil {
+		return nil, err
+	}
+	if len(tariff.DemandCharges) == 0 {
+		return nil, fmt.Errorf("validation failed: tariff for region %s has no demand charges", region)
+	}
+
+	var scenario *models.OptimizationScenario
+	if req.ScenarioID != "" {
+		scenario, err = s.optimizationRepo.FindByID(ctx, req.ScenarioID)
+		if err != nil {
+			return nil, err
+		}
+		if scenario.BuildingID != req.BuildingID {
+			return nil, fmt.Errorf("validation failed: scenario belongs to building %s", scenario.BuildingID)
+		}
+		if scenario.Type != models.OptimizationTypePeakShaving {
+			return nil, fmt.Errorf("validation failed: scenario is a %s scenario, not %s", scenario.Type, models.OptimizationTypePeakShaving)
+		}
+	}
+
+	loc := s.occupancyService.Location(ctx, req.BuildingID)
+	now := time.Now().In(loc)
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	previousStart := periodStart.AddDate(0, -1, 0)
+
+	history, err := s.externalClient.GetHistoricalConsumption(ctx, req.BuildingID, "", previousStart, now, "HOURLY", authToken)
+	if err != nil {
+		return nil, fmt.Errorf("consumption history unavailable: %w", err)
+	}
+	observed := make(map[int64]float64)
+	for _, point := range history.DataPoints {
+		// Hourly kWh equals the average kW of the hour
+		observed[point.Timestamp.Truncate(time.Hour).Unix()] += point.Value
+	}
+
+	result := &models.DemandChargeForecast{
+		BuildingID:  req.BuildingID,
+		Region:      region,
+		Currency:    tariff.Currency,
+		TimeZone:    loc.String(),
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		GeneratedAt: time.Now(),
+	}
+
+	predicted := make(map[int64]models.ForecastPrediction)
+	step := time.Hour
+	forecast, err := latestDemandForecast(ctx, s.forecastRepo, req.BuildingID)
+	if err != nil && err.Error() != "no forecasts found for this building" {
+		return nil, fmt.Errorf("failed to load demand forecast: %w", err)
+	}
+	if forecast != nil {
+		result.ForecastID = forecast.ID.Hex()
+		if forecast.Resolution.IsSubHourly() {
+			step = forecast.Resolution.Step()
+		}
+		for _, prediction := range forecast.Predictions {
+			predicted[prediction.Timestamp.Truncate(step).Unix()] = prediction
+		}
+	}
+	result.IntervalMinutes = int(step / time.Minute)
+
+	// Observed hours are used up to the current hour, the forecast for the hours after it
+	currentHour := now.Truncate(time.Hour)
+	var current []intervalLoad
+	for hour := periodStart; hour.Before(periodEnd); hour = hour.Add(time.Hour) {
+		if kw, ok := observed[hour.Unix()]; ok && hour.Before(currentHour) {
+			current = append(current, intervalLoad{start: hour, length: time.Hour, kw: kw, upperKW: kw})
+			result.ObservedHours++
+			continue
+		}
+		forecastHour := false
+		if !hour.Before(currentHour) {
+			for interval := hour; interval.Before(hour.Add(time.Hour)); interval = interval.Add(step) {
+				prediction, ok := predicted[interval.Unix()]
+				if !ok {
+					continue
+				}
+				upper := math.Max(prediction.UpperBound, prediction.PredictedValue)
+				current = append(current, intervalLoad{start: interval, length: step, kw: prediction.PredictedValue, upperKW: upper, forecast: true})
+				forecastHour = true
+			}
+		}
+		if forecastHour {
+			result.ForecastHours++
+			continue
+		}
+		result.MissingHours++
+	}
+
+	var previous []intervalLoad
+	for hour := previousStart; hour.Before(periodStart); hour = hour.Add(time.Hour) {
+		if kw, ok := observed[hour.Unix()]; ok {
+			previous = append(previous, intervalLoad{start: hour, length: time.Hour, kw: kw, upperKW: kw})
+		}
+	}
+
+	var previousTotal float64
+	hasPrevious := false
+	for _, charge := range tariff.DemandCharges {
+		estimate := models.DemandChargeEstimate{
+			Name:      charge.Name,
+			RatePerKW: charge.RatePerKW,
+			StartHour: charge.StartHour,
+			EndHour:   charge.EndHour,
+		}
+
+		if peak, ok := peakInWindow(current, charge, loc, false); ok {
+			peakAt := peak.start
+			estimate.PeakKW = roundKW(peak.kw)
+			estimate.PeakAt = &peakAt
+			estimate.PeakForecast = peak.forecast
+		}
+		if peak, ok := peakInWindow(current, charge, loc, true); ok {
+			estimate.UpperPeakKW = roundKW(peak.upperKW)
+		}
+		estimate.Charge = roundAmount(estimate.PeakKW * charge.RatePerKW)
+		estimate.UpperCharge = roundAmount(estimate.UpperPeakKW * charge.RatePerKW)
+
+		if peak, ok := peakInWindow(previous, charge, loc, false); ok {
+			previousPeak := roundKW(peak.kw)
+			previousCharge := roundAmount(previousPeak * charge.RatePerKW)
+			estimate.PreviousPeakKW = &previousPeak
+			estimate.PreviousCharge = &previousCharge
+			previousTotal += previousCharge
+			hasPrevious = true
+		}
+
+		result.EstimatedCharge += estimate.Charge
+		result.UpperEstimate += estimate.UpperCharge
+		result.Charges = append(result.Charges, estimate)
+	}
+	result.EstimatedCharge = roundAmount(result.EstimatedCharge)
+	result.UpperEstimate = roundAmount(result.UpperEstimate)
+
+	if hasPrevious {
+		comparison := &models.DemandChargeComparison{
+			PreviousCharge: roundAmount(previousTotal),
+			ChangeAmount:   roundAmount(result.EstimatedCharge - previousTotal),
+		}
+		if previousTotal > 0 {
+			percent := math.Round((result.EstimatedCharge-previousTotal)/previousTotal*1000) / 10
+			comparison.ChangePercent = &percent
+		}
+		result.PreviousPeriod = comparison
+	}
+
+	if scenario != nil {
+		result.ScenarioImpact = shavingImpact(scenario, current, tariff.DemandCharges, periodStart, periodEnd, loc)
+	}
+
+	return result, nil
+}
+
+// shavingImpact recalculates the demand charges with the load reductions of a scenario's actions
+// taken off the interval load. As in the scenario's cost comparison, an action reduces the load by
+// its expected impact for the part of each interval it runs.
+func shavingImpact(scenario *models.OptimizationScenario, load []intervalLoad, charges []models.DemandCharge, periodStart, periodEnd time.Time, loc *time.Location) *models.DemandChargeScenarioImpact {
+	impact := &models.DemandChargeScenarioImpact{
+		ScenarioID:   scenario.ID.Hex(),
+		ScenarioName: scenario.Name,
+		Status:       scenario.Status,
+	}
+
+	var actions []models.OptimizationAction
+	for _, action := range scenario.Actions {
+		actionEnd := action.ScheduledTime.Add(time.Duration(action.Duration) * time.Minute)
+		if action.Duration <= 0 || !actionEnd.After(periodStart) || !action.ScheduledTime.Before(periodEnd) {
+			impact.ActionsOutsidePeriod++
+			continue
+		}
+		actions = append(actions, action)
+	}
+
+	shaved := make([]intervalLoad, len(load))
+	for i, interval := range load {
+		intervalEnd := interval.start.Add(interval.length)
+		reduction := 0.0
+		for _, action := range actions {
+			overlapStart := action.ScheduledTime
+			overlapEnd := action.ScheduledTime.Add(time.Duration(action.Duration) * time.Minute)
+			if interval.start.After(overlapStart) {
+				overlapStart = interval.start
+			}
+			if intervalEnd.Before(overlapEnd) {
+				overlapEnd = intervalEnd
+			}
+			if overlapEnd.After(overlapStart) {
+				reduction += action.ExpectedImpact * float64(overlapEnd.Sub(overlapStart)) / float64(interval.length)
+			}
+		}
+		shaved[i] = interval
+		shaved[i].kw = math.Max(0, interval.kw-reduction)
+	}
+
+	for _, charge := range charges {
+		shaving := models.DemandChargeShaving{Name: charge.Name}
+		if peak, ok := peakInWindow(load, charge, loc, false); ok {
+			shaving.PeakKW = roundKW(peak.kw)
+		}
+		if peak, ok := peakInWindow(shaved, charge, loc, false); ok {
+			shaving.ShavedPeakKW = roundKW(peak.kw)
+		}
+		shaving.Charge = roundAmount(shaving.PeakKW * charge.RatePerKW)
+		shaving.ShavedCharge = roundAmount(shaving.ShavedPeakKW * charge.RatePerKW)
+		shaving.ChargeSavings = roundAmount(shaving.Charge - shaving.ShavedCharge)
+
+		impact.ChargeBefore += shaving.Charge
+		impact.ChargeAfter += shaving.ShavedCharge
+		impact.Charges = append(impact.Charges, shaving)
+	}
+	impact.ChargeBefore = roundAmount(impact.ChargeBefore)
+	impact.ChargeAfter = roundAmount(impact.ChargeAfter)
+	impact.ChargeSavings = roundAmount(impact.ChargeBefore - impact.ChargeAfter)
+
+	return impact
+}
+
+// peakInWindow returns the interval with the highest load, or upper bound load, within the window
+// of a demand charge. Intervals are matched against the window in the building's time zone.
+func peakInWindow(load []intervalLoad, charge models.DemandCharge, loc *time.Location, upper bool) (intervalLoad, bool) {
+	var peak intervalLoad
+	found := false
+	for _, interval := range load {
+		if !charge.Contains(interval.start.In(loc).Hour()) {
+			continue
+		}
+		value, peakValue := interval.kw, peak.kw
+		if upper {
+			value, peakValue = interval.upperKW, peak.upperKW
+		}
+		if !found || value > peakValue {
+			peak = interval
+			found = true
+		}
+	}
+	return peak, found
+}
+
+// roundKW rounds a load to three decimals
+func roundKW(kw float64) float64 {
+	return math.Round(kw*1000) / 1000
+}
+
+// roundAmount rounds a charge to two decimals
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"forecast-service/internal/integrations"
+	"forecast-service/internal/models"
+)
+
+// deviceTypeCatalogTTL is how long the IoT service's device type catalog is cached
+const deviceTypeCatalogTTL = 5 * time.Minute
+
+// defaultDeviceType is assumed for devices whose type cannot be resolved
+const defaultDeviceType = "EQUIPMENT"
+
+// builtinDeviceTypes mirrors the IoT service's built-in catalog entries and is used while
+// the catalog cannot be fetched
+var builtinDeviceTypes = map[string]*models.DeviceType{
+	"HVAC": {
+		Name:               "HVAC",
+		DisplayName:        "HVAC",
+		SupportedCommands:  []string{"TURN_ON", "TURN_OFF", "SET_TEMP", "SET_MODE", "REDUCE_POWER", "CURTAIL"},
+		TelemetryMetrics:   []string{"temperature", "humidity", "power", "energy"},
+		DefaultConstraints: map[string]interface{}{"minTemperature": 16.0, "maxTemperature": 30.0},
+	},
+	"LIGHTING": {
+		Name:               "LIGHTING",
+		DisplayName:        "Lighting",
+		SupportedCommands:  []string{"TURN_ON", "TURN_OFF", "SET_BRIGHTNESS", "REDUCE_POWER", "CURTAIL"},
+		TelemetryMetrics:   []string{"brightness", "power", "energy"},
+		DefaultConstraints: map[string]interface{}{"minBrightness": 10.0, "maxBrightness": 100.0},
+	},
+	"EQUIPMENT": {
+		Name:              "EQUIPMENT",
+		DisplayName:       "Equipment",
+		SupportedCommands: []string{"TURN_ON", "TURN_OFF", "REDUCE_POWER", "CURTAIL"},
+		TelemetryMetrics:  []string{"power", "energy"},
+	},
+	"SENSOR": {
+		Name:              "SENSOR",
+		DisplayName:       "Sensor",
+		SupportedCommands: []string{},
+		TelemetryMetrics:  []string{"temperature", "humidity", "co2", "occupancy"},
+	},
+	"SOLAR_INVERTER": {
+		Name:              "SOLAR_INVERTER",
+		DisplayName:       "Solar inverter",
+		SupportedCommands: []string{"TURN_ON", "TURN_OFF", "CURTAIL"},
+		TelemetryMetrics:  []string{"power", "generation", "gridImport", "gridExport"},
+	},
+	"BATTERY": {
+		Name:              "BATTERY",
+		DisplayName:       "Battery storage",
+		SupportedCommands: []string{"CHARGE", "DISCHARGE", "TURN_OFF"},
+		TelemetryMetrics:  []string{"power", "stateOfCharge", "batteryCharge", "batteryDischarge"},
+		DefaultConstraints: map[string]interface{}{
+			"capacityKWh":         100.0,
+			"maxPowerKW":          50.0,
+			"minStateOfCharge":    10.0,
+			"maxStateOfCharge":    95.0,
+			"roundTripEfficiency": 0.9,
+		},
+	},
+}
+
+// DeviceTypeCatalog resolves devices to their entry in the IoT service's device type catalog
+type DeviceTypeCatalog struct {
+	iotClient *integrations.IoTClient
+
+	mu        sync.Mutex
+	types     map[string]*models.DeviceType
+	fetchedAt time.Time
+}
+
+// NewDeviceTypeCatalog creates a new device type catalog
+func NewDeviceTypeCatalog(iotClient *integrations.IoTClient) *DeviceTypeCatalog {
+	return &DeviceTypeCatalog{iotClient: iotClient}
+}
+
+// Resolve returns the catalog entry for a device. The type comes from the device's registered
+// record, which the IoT service includes in device states; it is looked up when missing.
+// Devices of unknown type are treated as generic equipment.
+func (c *DeviceTypeCatalog) Resolve(ctx context.Context, device models.DeviceState, authToken string) *models.DeviceType {
+	name := device.Type
+	if name == "" {
+		if state, err := c.iotClient.GetDeviceState(ctx, device.DeviceID, authToken); err == nil {
+			name = state.Type
+		}
+	}
+
+	if deviceType, ok := c.lookup(ctx, name, authToken); ok {
+		return deviceType
+	}
+	return builtinDeviceTypes[defaultDeviceType]
+}
+
+// lookup finds a type by name, refreshing the cached catalog when it has expired
+func (c *DeviceTypeCatalog) lookup(ctx context.Context, name, authToken string) (*models.DeviceType, bool) {
+	if name == "" {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A failed fetch is not retried until the TTL passes, so an unreachable IoT service does not
+	// cost a request per device; the last catalog or the built-in types are used meanwhile
+	if time.Since(c.fetchedAt) > deviceTypeCatalogTTL {
+		c.fetchedAt = time.Now()
+		deviceTypes, err := c.iotClient.GetDeviceTypes(ctx, authToken)
+		if err != nil {
+			log.Printf("Failed to fetch device type catalog: %v", err)
+		} else {
+			c.types = make(map[string]*models.DeviceType, len(deviceTypes))
+			for i := range deviceTypes {
+				c.types[deviceTypes[i].Name] = &deviceTypes[i]
+			}
+		}
+	}
+
+	if deviceType, ok := c.types[name]; ok {
+		return deviceType, true
+	}
+	deviceType, ok := builtinDeviceTypes[name]
+	return deviceType, ok
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"forecast-service/internal/integrations"
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+)
+
+// FeatureStore serves forecast inputs from stored snapshots while they are fresh, fetching and
+// storing a new snapshot otherwise. Every input comes with its provenance.
+type FeatureStore struct {
+	featureRepo      *repository.FeatureRepository
+	externalClient   *integrations.ExternalClient
+	tariffService    *TariffService
+	weatherFreshness time.Duration
+	tariffFreshness  time.Duration
+}
+
+// NewFeatureStore creates a new feature store
+func NewFeatureStore(
+	featureRepo *repository.FeatureRepository,
+	externalClient *integrations.ExternalClient,
+	tariffService *TariffService,
+	weatherFreshness time.Duration,
+	tariffFreshness time.Duration,
+) *FeatureStore {
+	return &FeatureStore{
+		featureRepo:      featureRepo,
+		externalClient:   externalClient,
+		tariffService:    tariffService,
+		weatherFreshness: weatherFreshness,
+		tariffFreshness:  tariffFreshness,
+	}
+}
+
+// GetWeather returns the current weather at a building
+func (s *FeatureStore) GetWeather(ctx context.Context, buildingID, authToken string) (*models.Weather, *models.InputProvenance, error) {
+	now := time.Now()
+	if snapshot, ok := s.findFresh(ctx, models.FeatureKindWeather, buildingID, now.Add(-s.weatherFreshness)); ok {
+		provenance := snapshot.Provenance(true)
+		return snapshot.Weather, &provenance, nil
+	}
+
+	weather, err := s.externalClient.GetCurrentWeather(ctx, buildingID, authToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	snapshot := s.store(ctx, &models.FeatureSnapshot{
+		Kind:      models.FeatureKindWeather,
+		Key:       buildingID,
+		Source:    models.FeatureSourceWeatherAPI,
+		Weather:   weather,
+		FetchedAt: now,
+	})
+	provenance := snapshot.Provenance(false)
+	return weather, &provenance, nil
+}
+
+// GetTariff returns the tariff in effect for a region. The current rate depends on the hour, so
+// a snapshot is only reused within the hour it was resolved in.
+func (s *FeatureStore) GetTariff(ctx context.Context, region, authToken string) (*models.Tariff, *models.InputProvenance, error) {
+	now := time.Now()
+	since := now.Add(-s.tariffFreshness)
+	if hourStart := now.Truncate(time.Hour); hourStart.After(since) {
+		since = hourStart
+	}
+	if snapshot, ok := s.findFresh(ctx, models.FeatureKindTariff, region, since); ok {
+		provenance := snapshot.Provenance(true)
+		return snapshot.Tariff, &provenance, nil
+	}
+
+	tariff, err := s.tariffService.GetCurrentTariff(ctx, region, authToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	snapshot := s.store(ctx, &models.FeatureSnapshot{
+		Kind:      models.FeatureKindTariff,
+		Key:       region,
+		Source:    string(tariff.Source),
+		Tariff:    tariff,
+		FetchedAt: now,
+	})
+	provenance := snapshot.Provenance(false)
+	return tariff, &provenance, nil
+}
+
+// findFresh looks up a snapshot fetched at or after since. Store errors are treated as a miss.
+func (s *FeatureStore) findFresh(ctx context.Context, kind models.FeatureKind, key string, since time.Time) (*models.FeatureSnapshot, bool) {
+	snapshot, err := s.featureRepo.FindLatest(ctx, kind, key, since)
+	if err != nil {
+		if err.Error() != "feature snapshot not found" {
+			log.Printf("Failed to read %s feature for %s: %v", kind, key, err)
+		}
+		return nil, false
+	}
+	return snapshot, true
+}
+
+// store saves a snapshot. A failed save is logged; the fetched input is still used, with
+// provenance that has no snapshot to refer to.
+func (s *FeatureStore) store(ctx context.Context, snapshot *models.FeatureSnapshot) *models.FeatureSnapshot {
+	if _, err := s.featureRepo.Create(ctx, snapshot); err != nil {
+		log.Printf("Failed to store %s feature for %s: %v", snapshot.Kind, snapshot.Key, err)
+	}
+	return snapshot
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"forecast-service/internal/models"
+)
+
+// actualsBatchSize caps how many forecasts one backfill run updates
+const actualsBatchSize = 100
+
+// GetForecastWithActuals retrieves a forecast with the observed value next to each prediction
+// whose period has passed. Actuals are brought up to date first unless they are complete; when
+// consumption history cannot be fetched, the actuals stored so far are returned.
+func (s *ForecastService) GetForecastWithActuals(ctx context.Context, id, authToken string) (*models.ForecastWithActualsResponse, error) {
+	forecast, err := s.forecastRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if forecast.Status == models.ForecastStatusCompleted && (forecast.Actuals == nil || !forecast.Actuals.Complete) {
+		if err := s.backfillActuals(ctx, forecast, time.Now(), authToken); err != nil {
+			log.Printf("Failed to backfill actuals of forecast %s: %v", id, err)
+		}
+	}
+
+	return &models.ForecastWithActualsResponse{
+		ForecastResponse: s.localResponse(ctx, forecast),
+		Actuals:          forecast.Actuals,
+	}, nil
+}
+
+// BackfillActuals attaches actual values to the past predictions of every forecast still
+// awaiting them and returns how many forecasts were updated
+func (s *ForecastService) BackfillActuals(ctx context.Context) (int, error) {
+	now := time.Now()
+	forecasts, err := s.forecastRepo.FindAwaitingActuals(ctx, now, actualsBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, forecast := range forecasts {
+		if err := s.backfillActuals(ctx, forecast, now, ""); err != nil {
+			log.Printf("Failed to backfill actuals of forecast %s: %v", forecast.ID.Hex(), err)
+			continue
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// StartActualsWorker periodically backfills actuals into past predictions until the context is cancelled
+func (s *ForecastService) StartActualsWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			updated, err := s.BackfillActuals(ctx)
+			if err != nil {
+				log.Printf("Failed to backfill forecast actuals: %v", err)
+				continue
+			}
+			if updated > 0 {
+				log.Printf("Backfilled actuals of %d forecasts", updated)
+			}
+		}
+	}
+}
+
+// backfillActuals fetches consumption for the forecast's elapsed periods, attaches it to the
+// predictions and stores them with an updated deviation summary. The forecast is updated in place.
+// Actuals become complete once every prediction has one, or the grace period after the forecast
+// ended has passed.
+func (s *ForecastService) backfillActuals(ctx context.Context, forecast *models.Forecast, now time.Time, authToken string) error {
+	if !forecast.StartTime.Before(now) {
+		return nil
+	}
+
+	to := forecast.EndTime
+	if now.Before(to) {
+		to = now
+	}
+	history, err := s.externalClient.GetHistoricalConsumption(ctx, forecast.BuildingID, forecast.DeviceID, forecast.StartTime, to, "HOURLY", authToken)
+	if err != nil {
+		return fmt.Errorf("failed to get consumption history: %w", err)
+	}
+
+	predictions := attachActuals(forecast.Predictions, history.DataPoints, forecast.Resolution, forecast.Resolution.Step(), now)
+
+	actuals := summarizeActuals(predictions)
+	actuals.Quantiles, actuals.MeanPinballLoss = SummarizeQuantiles(predictions, forecast.InputParameters.Quantiles)
+	actuals.Complete = actuals.PredictionsCompared == actuals.PredictionsTotal ||
+		now.After(forecast.EndTime.Add(s.config.Forecast.ActualsGrace))
+	actuals.UpdatedAt = now
+
+	if err := s.forecastRepo.UpdateActuals(ctx, forecast.ID, predictions, actuals); err != nil {
+		return err
+	}
+	s.invalidateLatest(ctx, forecast.BuildingID, forecast.Type)
+	forecast.Predictions = predictions
+	forecast.Actuals = actuals
+	return nil
+}
+
+// attachActuals sets the actual value and deviation of every prediction whose period has ended
+// and has consumption data. Data points are hourly energy in kWh: hourly predictions (average
+// load in kW) take the mean of their hour, daily and weekly predictions (energy) the sum.
+// Sub-hourly predictions are compared with the mean of the hour they fall in, once it has ended.
+func attachActuals(predictions []models.ForecastPrediction, points []models.ConsumptionDataPoint, resolution models.ForecastResolution, step time.Duration, now time.Time) []models.ForecastPrediction {
+	result := make([]models.ForecastPrediction, len(predictions))
+	for i, prediction := range predictions {
+		result[i] = prediction
+
+		periodStart, periodEnd := prediction.Timestamp, prediction.Timestamp.Add(step)
+		if resolution.IsSubHourly() {
+			periodStart = prediction.Timestamp.Truncate(time.Hour)
+			periodEnd = periodStart.Add(time.Hour)
+		}
+		if periodEnd.After(now) {
+			continue
+		}
+
+		sum, count := 0.0, 0
+		for _, point := range points {
+			if !point.Timestamp.Before(periodStart) && point.Timestamp.Before(periodEnd) {
+				sum += point.Value
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+
+		actual := sum
+		if !resolution.IsLongHorizon() {
+			actual = sum / float64(count)
+		}
+		actual = math.Round(actual*100) / 100
+		deviation := math.Round((actual-prediction.PredictedValue)*100) / 100
+		result[i].ActualValue = &actual
+		result[i].Deviation = &deviation
+		if prediction.PredictedValue != 0 {
+			percent := math.Round(deviation/prediction.PredictedValue*1000) / 10
+			result[i].DeviationPercent = &percent
+		}
+	}
+	return result
+}
+
+// summarizeActuals computes deviation statistics over the predictions that have actual values
+func summarizeActuals(predictions []models.ForecastPrediction) *models.ForecastActuals {
+	actuals := &models.ForecastActuals{PredictionsTotal: len(predictions)}
+
+	var sumDeviation, sumAbsolute, sumPercent float64
+	percentCount, withinBounds := 0, 0
+	for _, prediction := range predictions {
+		if prediction.ActualValue == nil {
+			continue
+		}
+		actuals.PredictionsCompared++
+
+		deviation := *prediction.ActualValue - prediction.PredictedValue
+		sumDeviation += deviation
+		sumAbsolute += math.Abs(deviation)
+		if *prediction.ActualValue != 0 {
+			sumPercent += math.Abs(deviation / *prediction.ActualValue) * 100
+			percentCount++
+		}
+		if *prediction.ActualValue >= prediction.LowerBound && *prediction.ActualValue <= prediction.UpperBound {
+			withinBounds++
+		}
+	}
+
+	if actuals.PredictionsCompared == 0 {
+		return actuals
+	}
+	compared := float64(actuals.PredictionsCompared)
+	actuals.MeanDeviation = math.Round(sumDeviation/compared*100) / 100
+	actuals.MAE = math.Round(sumAbsolute/compared*100) / 100
+	if percentCount > 0 {
+		actuals.MAPE = math.Round(sumPercent/float64(percentCount)*100) / 100
+	}
+	actuals.WithinBoundsPercent = math.Round(float64(withinBounds)/compared*1000) / 10
+	return actuals
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"forecast-service/internal/integrations"
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+)
+
+// ForecastBreakdownService decomposes building forecasts into the contributions of their devices
+type ForecastBreakdownService struct {
+	forecastRepo     *repository.ForecastRepository
+	iotClient        *integrations.IoTClient
+	occupancyService *OccupancyService
+}
+
+// NewForecastBreakdownService creates a new forecast breakdown service
+func NewForecastBreakdownService(
+	forecastRepo *repository.ForecastRepository,
+	iotClient *integrations.IoTClient,
+	occupancyService *OccupancyService,
+) *ForecastBreakdownService {
+	return &ForecastBreakdownService{
+		forecastRepo:     forecastRepo,
+		iotClient:        iotClient,
+		occupancyService: occupancyService,
+	}
+}
+
+// GetBreakdown splits each prediction of a completed building forecast into the predictions of
+// the building's device-level forecasts for the same interval and a residual. Each device
+// contributes from its latest device forecast overlapping the building forecast, with the
+// prediction that starts within the interval since forecasts generated at different times are
+// not aligned to the same timestamps. Devices are grouped by type when the IoT service can list
+// the building's devices.
+func (s *ForecastBreakdownService) GetBreakdown(ctx context.Context, id, authToken string) (*models.ForecastBreakdown, error) {
+	forecast, err := s.forecastRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if forecast.DeviceID != "" {
+		return nil, fmt.Errorf("validation failed: forecast is for device %s, not a building", forecast.DeviceID)
+	}
+	if forecast.Status != models.ForecastStatusCompleted {
+		return nil, fmt.Errorf("validation failed: forecast is %s, not %s", forecast.Status, models.ForecastStatusCompleted)
+	}
+
+	deviceForecasts, err := s.forecastRepo.FindDeviceForecasts(ctx, forecast.BuildingID, forecast.Type, forecast.Resolution, forecast.StartTime, forecast.EndTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load device forecasts: %w", err)
+	}
+
+	deviceTypes, typesKnown := s.deviceTypes(ctx, forecast.BuildingID, authToken)
+	loc := s.occupancyService.Location(ctx, forecast.BuildingID)
+
+	resolution := forecast.Resolution
+	if resolution == "" {
+		resolution = models.ForecastResolutionHourly
+	}
+	result := &models.ForecastBreakdown{
+		ForecastID:  forecast.ID.Hex(),
+		BuildingID:  forecast.BuildingID,
+		Type:        forecast.Type,
+		Resolution:  resolution,
+		TimeZone:    loc.String(),
+		Devices:     []models.ForecastBreakdownDevice{},
+		Intervals:   make([]models.ForecastBreakdownInterval, 0, len(forecast.Predictions)),
+		TypesKnown:  typesKnown,
+		GeneratedAt: time.Now(),
+	}
+	if len(forecast.Predictions) == 0 {
+		return result, nil
+	}
+	result.Unit = forecast.Predictions[0].Unit
+
+	// Predictions are bucketed into the building forecast's intervals
+	base := forecast.Predictions[0].Timestamp
+	step := resolution.Step()
+	bucket := func(timestamp time.Time) (int64, bool) {
+		if timestamp.Before(base) {
+			return 0, false
+		}
+		return int64(timestamp.Sub(base) / step), true
+	}
+
+	// The results are sorted newest first, so the first forecast of each device is its latest
+	type deviceSeries struct {
+		info        models.ForecastBreakdownDevice
+		predictions map[int64]float64
+		coveredSum  float64 // Building total over the intervals the device covers
+	}
+	var devices []*deviceSeries
+	seen := make(map[string]bool)
+	for _, deviceForecast := range deviceForecasts {
+		if seen[deviceForecast.DeviceID] {
+			continue
+		}
+		seen[deviceForecast.DeviceID] = true
+
+		series := &deviceSeries{
+			info: models.ForecastBreakdownDevice{
+				DeviceID:     deviceForecast.DeviceID,
+				DeviceType:   deviceTypes[deviceForecast.DeviceID],
+				ForecastID:   deviceForecast.ID.Hex(),
+				ModelUsed:    deviceForecast.ModelUsed,
+				ForecastedAt: deviceForecast.CreatedAt.In(loc),
+			},
+			predictions: make(map[int64]float64, len(deviceForecast.Predictions)),
+		}
+		for _, prediction := range deviceForecast.Predictions {
+			if index, ok := bucket(prediction.Timestamp); ok {
+				if _, taken := series.predictions[index]; !taken {
+					series.predictions[index] = prediction.PredictedValue
+				}
+			}
+		}
+		devices = append(devices, series)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].info.DeviceID < devices[j].info.DeviceID })
+
+	for _, prediction := range forecast.Predictions {
+		index, _ := bucket(prediction.Timestamp)
+		total := prediction.PredictedValue
+		interval := models.ForecastBreakdownInterval{
+			Timestamp:     prediction.Timestamp.In(loc),
+			Total:         roundKW(total),
+			Contributions: []models.DeviceContribution{},
+		}
+
+		contributed := 0.0
+		byType := make(map[string]float64)
+		for _, series := range devices {
+			value, ok := series.predictions[index]
+			if !ok {
+				continue
+			}
+			contributed += value
+			series.info.IntervalsCovered++
+			series.info.Total += value
+			series.coveredSum += total
+
+			interval.Contributions = append(interval.Contributions, models.DeviceContribution{
+				DeviceID:     series.info.DeviceID,
+				DeviceType:   series.info.DeviceType,
+				Value:        roundKW(value),
+				SharePercent: sharePercent(value, total),
+			})
+			if typesKnown {
+				deviceType := series.info.DeviceType
+				if deviceType == "" {
+					deviceType = "UNKNOWN"
+				}
+				byType[deviceType] += value
+			}
+		}
+
+		for deviceType, value := range byType {
+			interval.ByType = append(interval.ByType, models.TypeContribution{
+				DeviceType:   deviceType,
+				Value:        roundKW(value),
+				SharePercent: sharePercent(value, total),
+			})
+		}
+		sort.Slice(interval.ByType, func(i, j int) bool { return interval.ByType[i].Value > interval.ByType[j].Value })
+
+		interval.Residual = roundKW(total - contributed)
+		interval.ResidualPercent = sharePercent(total-contributed, total)
+		result.Intervals = append(result.Intervals, interval)
+	}
+
+	for _, series := range devices {
+		if series.info.IntervalsCovered == 0 {
+			continue
+		}
+		series.info.SharePercent = sharePercent(series.info.Total, series.coveredSum)
+		series.info.Total = roundKW(series.info.Total)
+		result.Devices = append(result.Devices, series.info)
+	}
+
+	return result, nil
+}
+
+// deviceTypes maps the building's devices to their catalog types. It reports false when the
+// IoT service could not list them, in which case contributions are not grouped by type.
+func (s *ForecastBreakdownService) deviceTypes(ctx context.Context, buildingID, authToken string) (map[string]string, bool) {
+	states, err := s.iotClient.GetDevicesByBuilding(ctx, buildingID, authToken)
+	if err != nil {
+		log.Printf("Failed to load device types of building %s for forecast breakdown: %v", buildingID, err)
+		return map[string]string{}, false
+	}
+
+	types := make(map[string]string, len(states))
+	for _, state := range states {
+		types[state.DeviceID] = state.Type
+	}
+	return types, true
+}
+
+// sharePercent returns a value's share of a total in percent, rounded to one decimal, or nil
+// when the total is not positive
+func sharePercent(value, total float64) *float64 {
+	if total <= 0 {
+		return nil
+	}
+	percent := math.Round(value/total*1000) / 10
+	return &percent
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"forecast-service/internal/integrations"
+	"forecast-service/internal/models"
+	"shared/jobs"
+)
+
+// forecastGenerationJob is the job type generating submitted forecasts
+const forecastGenerationJob = "forecast_generation"
+
+// forecastJobPayload identifies a forecast awaiting asynchronous generation
+type forecastJobPayload struct {
+	ForecastID string `bson:"forecast_id"`
+	ModelType  string `bson:"model_type,omitempty"`
+}
+
+// processForecastJob generates a queued forecast and notifies its callback URL. A forecast that is
+// no longer processing, because an interrupted run finished it, is only notified.
+func (s *ForecastService) processForecastJob(ctx context.Context, job *jobs.Job) error {
+	var payload forecastJobPayload
+	if err := job.Decode(&payload); err != nil {
+		return jobs.Permanent(fmt.Errorf("invalid forecast job payload: %w", err))
+	}
+
+	forecast, err := s.forecastRepo.FindByID(ctx, payload.ForecastID)
+	if err != nil {
+		if err.Error() == "forecast not found" {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+
+	if forecast.Status == models.ForecastStatusProcessing {
+		// The submitter is authorized again on every run, so a forecast stops once they lose access
+		authToken, err := s.securityClient.DelegateToken(ctx, job.Identity.UserID, job.Identity.OrgID)
+		if errors.Is(err, integrations.ErrDelegationDenied) {
+			return jobs.Permanent(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		// runForecast records a generation failure on the forecast, so it is not retried
+		if err := s.runForecast(ctx, forecast, payload.ModelType, authToken); err != nil {
+			s.notifyForecastCallback(payload.ForecastID)
+			return jobs.Permanent(err)
+		}
+	}
+
+	s.notifyForecastCallback(payload.ForecastID)
+	return nil
+}
+
+// failForecastJob records a forecast job that failed for good, such as one that timed out, on its
+// forecast
+func (s *ForecastService) failForecastJob(ctx context.Context, job *jobs.Job, reason string) {
+	var payload forecastJobPayload
+	if err := job.Decode(&payload); err != nil {
+		return
+	}
+
+	forecast, err := s.forecastRepo.FindByID(ctx, payload.ForecastID)
+	if err != nil || forecast.Status != models.ForecastStatusProcessing {
+		return
+	}
+	if err := s.forecastRepo.UpdateStatus(ctx, payload.ForecastID, models.ForecastStatusFailed, reason); err != nil {
+		log.Printf("Failed to fail forecast %s: %v", payload.ForecastID, err)
+		return
+	}
+	s.notifyForecastCallback(payload.ForecastID)
+}
+
+// notifyForecastCallback sends the status of a finished forecast to its callback URL, if it has one
+func (s *ForecastService) notifyForecastCallback(forecastID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Forecast.CallbackTimeout)
+	defer cancel()
+
+	forecast, err := s.forecastRepo.FindByID(ctx, forecastID)
+	if err != nil {
+		log.Printf("Failed to load forecast %s for callback: %v", forecastID, err)
+		return
+	}
+	if forecast.CallbackURL == "" {
+		return
+	}
+	if err := s.callbackClient.Notify(ctx, forecast.CallbackURL, forecast.ToStatusResponse()); err != nil {
+		log.Printf("Failed to notify callback for forecast %s: %v", forecastID, err)
+	}
+}
+
+// GetForecastStatus reports the generation status of a forecast
+func (s *ForecastService) GetForecastStatus(ctx context.Context, id string) (*models.ForecastStatusResponse, error) {
+	forecast, err := s.forecastRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return forecast.ToStatusResponse(), nil
+}
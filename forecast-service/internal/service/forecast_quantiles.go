@@ -0,0 +1,166 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"forecast-service/internal/models"
+)
+
+// minQuantileResiduals is the number of backtest residuals from which their empirical
+// distribution, rather than a normal one, shapes the predicted quantiles
+const minQuantileResiduals = 48
+
+// residualModel is implemented by models that keep the one-step-ahead errors of their backtest
+type residualModel interface {
+	Residuals() []float64
+}
+
+// QuantileLabel returns the label a quantile is keyed by in predictions, e.g. "p90" for 0.9
+func QuantileLabel(quantile float64) string {
+	return "p" + strconv.FormatFloat(math.Round(quantile*1000)/10, 'f', -1, 64)
+}
+
+// normalizeQuantiles sorts quantiles ascending and drops duplicates
+func normalizeQuantiles(quantiles []float64) []float64 {
+	if len(quantiles) == 0 {
+		return nil
+	}
+	sorted := append([]float64(nil), quantiles...)
+	sort.Float64s(sorted)
+
+	normalized := sorted[:1]
+	for _, quantile := range sorted[1:] {
+		if QuantileLabel(quantile) != QuantileLabel(normalized[len(normalized)-1]) {
+			normalized = append(normalized, quantile)
+		}
+	}
+	return normalized
+}
+
+// normalQuantile returns the standard normal quantile function at p
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// quantileShapes returns, for each quantile, how many standard deviations it lies from the
+// predicted value. With enough backtest residuals they follow the residuals' empirical
+// distribution, so skewed errors such as unplanned load spikes widen the upper quantiles only;
+// otherwise errors are taken to be normal.
+func quantileShapes(quantiles, residuals []float64) []float64 {
+	shapes := make([]float64, len(quantiles))
+
+	var sumSquares float64
+	for _, residual := range residuals {
+		sumSquares += residual * residual
+	}
+	std := 0.0
+	if len(residuals) > 0 {
+		std = math.Sqrt(sumSquares / float64(len(residuals)))
+	}
+	if len(residuals) < minQuantileResiduals || std == 0 {
+		for i, quantile := range quantiles {
+			shapes[i] = normalQuantile(quantile)
+		}
+		return shapes
+	}
+
+	sorted := append([]float64(nil), residuals...)
+	sort.Float64s(sorted)
+	for i, quantile := range quantiles {
+		// Linear interpolation between the closest ranks
+		rank := quantile * float64(len(sorted)-1)
+		lower := int(math.Floor(rank))
+		upper := int(math.Ceil(rank))
+		value := sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+		shapes[i] = value / std
+	}
+	return shapes
+}
+
+// ApplyQuantiles adds the given quantiles, sorted ascending, to predictions that lack them. Each
+// prediction's spread is recovered from its upper bound and confidence level and shaped by the
+// backtest residuals, when there are any. Quantiles a model already predicted, such as those of
+// the external ML service, are kept; derived ones never fall below zero or a lower quantile.
+func ApplyQuantiles(predictions []models.ForecastPrediction, quantiles, residuals []float64) {
+	if len(quantiles) == 0 {
+		return
+	}
+	shapes := quantileShapes(quantiles, residuals)
+
+	for i := range predictions {
+		prediction := &predictions[i]
+		confidence := prediction.ConfidenceLevel
+		if confidence <= 0 || confidence >= 1 {
+			confidence = 0.95
+		}
+		sigma := math.Max(0, prediction.UpperBound-prediction.PredictedValue) / normalQuantile((1+confidence)/2)
+
+		values := make(map[string]float64, len(quantiles))
+		previous := 0.0
+		for j, quantile := range quantiles {
+			label := QuantileLabel(quantile)
+			value, predicted := prediction.Quantiles[label]
+			if !predicted {
+				value = math.Round(math.Max(previous, prediction.PredictedValue+sigma*shapes[j])*100) / 100
+			}
+			values[label] = value
+			previous = value
+		}
+		for label, value := range prediction.Quantiles {
+			if _, ok := values[label]; !ok {
+				values[label] = value
+			}
+		}
+		prediction.Quantiles = values
+	}
+}
+
+// PinballLoss returns the quantile (pinball) loss of predicting value at the given quantile when
+// actual was observed: under-prediction weighs quantile, over-prediction 1 - quantile
+func PinballLoss(actual, value, quantile float64) float64 {
+	if actual >= value {
+		return quantile * (actual - value)
+	}
+	return (1 - quantile) * (value - actual)
+}
+
+// SummarizeQuantiles scores the forecast's quantiles against the predictions that have actual
+// values, returning nil when none of them has a predicted quantile
+func SummarizeQuantiles(predictions []models.ForecastPrediction, quantiles []float64) ([]models.QuantileAccuracy, *float64) {
+	var scores []models.QuantileAccuracy
+	var totalLoss float64
+	for _, quantile := range quantiles {
+		label := QuantileLabel(quantile)
+		score := models.QuantileAccuracy{Quantile: quantile, Label: label}
+
+		var loss float64
+		covered := 0
+		for _, prediction := range predictions {
+			value, ok := prediction.Quantiles[label]
+			if prediction.ActualValue == nil || !ok {
+				continue
+			}
+			score.Compared++
+			loss += PinballLoss(*prediction.ActualValue, value, quantile)
+			if *prediction.ActualValue <= value {
+				covered++
+			}
+		}
+		if score.Compared == 0 {
+			continue
+		}
+
+		score.PinballLoss = math.Round(loss/float64(score.Compared)*1000) / 1000
+		score.Coverage = math.Round(float64(covered)/float64(score.Compared)*1000) / 10
+		totalLoss += score.PinballLoss
+		scores = append(scores, score)
+	}
+
+	if len(scores) == 0 {
+		return nil, nil
+	}
+	mean := math.Round(totalLoss/float64(len(scores))*1000) / 1000
+	return scores, &mean
+}
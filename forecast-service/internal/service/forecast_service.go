@@ -3,52 +3,178 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"math"
-	"math/rand"
+	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+
+	"forecast-service/internal/cache"
 	"forecast-service/internal/config"
+	"forecast-service/internal/events"
 	"forecast-service/internal/integrations"
 	"forecast-service/internal/models"
 	"forecast-service/internal/repository"
+	"forecast-service/internal/settings"
+	"forecast-service/internal/tenant"
+	"shared/jobs"
 )
 
 // ForecastService handles forecast business logic
 type ForecastService struct {
-	forecastRepo   *repository.ForecastRepository
-	peakLoadRepo   *repository.PeakLoadRepository
-	securityClient *integrations.SecurityClient
-	externalClient *integrations.ExternalClient
-	config         *config.Config
+	forecastRepo     *repository.ForecastRepository
+	peakLoadRepo     *repository.PeakLoadRepository
+	securityClient   *integrations.SecurityClient
+	externalClient   *integrations.ExternalClient
+	featureStore     *FeatureStore
+	occupancyService *OccupancyService
+	modelRegistry    *ForecastModelRegistry
+	eventBus         *events.Bus
+	callbackClient   *integrations.CallbackClient
+	config           *config.Config
+
+	// Queue of forecasts awaiting asynchronous generation
+	jobQueue *jobs.Queue
+
+	// Shared cache of the latest forecast per building and type; may be nil
+	cache *cache.Cache
+
+	// Horizon limits, peak threshold and cache age, adjustable at runtime
+	settings forecastSettings
+
+	// Forecast cache state: background refreshes in flight and explicit invalidations per building
+	cacheMu       sync.Mutex
+	refreshing    map[string]bool
+	invalidatedAt map[string]time.Time
+}
+
+// forecastSettings are the forecast tunables stored in the settings store. Their defaults come
+// from the environment configuration.
+type forecastSettings struct {
+	defaultHorizonHours      *settings.Int
+	maxHorizonHours          *settings.Int
+	longHorizonMaxHours      *settings.Int
+	peakLoadThresholdPercent *settings.Float
+	cacheMaxAge              *settings.Duration
 }
 
+// forecastRefreshTimeout bounds a background forecast regeneration
+const forecastRefreshTimeout = 2 * time.Minute
+
+// LatestForecastCacheKind is the cache kind of the latest completed forecasts, keyed by
+// building ID and forecast type
+const LatestForecastCacheKind = "latest_forecast"
+
 // NewForecastService creates a new forecast service
 func NewForecastService(
 	forecastRepo *repository.ForecastRepository,
 	peakLoadRepo *repository.PeakLoadRepository,
 	securityClient *integrations.SecurityClient,
 	externalClient *integrations.ExternalClient,
+	featureStore *FeatureStore,
+	occupancyService *OccupancyService,
+	modelRegistry *ForecastModelRegistry,
+	eventBus *events.Bus,
+	callbackClient *integrations.CallbackClient,
+	jobQueue *jobs.Queue,
+	forecastCache *cache.Cache,
+	settingsStore *settings.Store,
 	cfg *config.Config,
 ) *ForecastService {
-	return &ForecastService{
-		forecastRepo:   forecastRepo,
-		peakLoadRepo:   peakLoadRepo,
-		securityClient: securityClient,
-		externalClient: externalClient,
-		config:         cfg,
+	s := &ForecastService{
+		forecastRepo:     forecastRepo,
+		peakLoadRepo:     peakLoadRepo,
+		securityClient:   securityClient,
+		externalClient:   externalClient,
+		featureStore:     featureStore,
+		occupancyService: occupancyService,
+		modelRegistry:    modelRegistry,
+		eventBus:         eventBus,
+		callbackClient:   callbackClient,
+		config:           cfg,
+		jobQueue:         jobQueue,
+		cache:            forecastCache,
+		refreshing:       make(map[string]bool),
+		invalidatedAt:    make(map[string]time.Time),
+		settings: forecastSettings{
+			defaultHorizonHours: settingsStore.RegisterInt("forecast.default_horizon_hours",
+				"Horizon of forecasts requested without one", cfg.Forecast.DefaultHorizonHours, 1, 8*7*24),
+			maxHorizonHours: settingsStore.RegisterInt("forecast.max_horizon_hours",
+				"Longest horizon of hourly and shorter forecasts", cfg.Forecast.MaxHorizonHours, 1, 8*7*24),
+			longHorizonMaxHours: settingsStore.RegisterInt("forecast.long_horizon_max_hours",
+				"Longest horizon of daily and weekly forecasts; 0 disables them", cfg.Forecast.LongHorizonMaxHours, 0, 2*366*24),
+			peakLoadThresholdPercent: settingsStore.RegisterFloat("forecast.peak_load_threshold_percent",
+				"Share of the maximum predicted load that counts as a peak", cfg.Forecast.PeakLoadThresholdPercent, 1, 100),
+			cacheMaxAge: settingsStore.RegisterDuration("forecast.cache_max_age",
+				"How long a stored building forecast is served before it is regenerated", cfg.Forecast.CacheMaxAge, time.Minute, 7*24*time.Hour),
+		},
 	}
+	jobQueue.Register(forecastGenerationJob, s.processForecastJob, jobs.Options{
+		Timeout:          cfg.Forecast.JobTimeout,
+		RequiresIdentity: true,
+		OnFailed:         s.failForecastJob,
+	})
+	return s
 }
 
-// GenerateForecast generates an energy demand forecast
+// GenerateForecast generates an energy demand forecast and waits for the predictions
 func (s *ForecastService) GenerateForecast(ctx context.Context, req *models.ForecastGenerateRequest, userID, authToken string) (*models.ForecastResponse, error) {
+	forecast, err := s.createForecast(ctx, req, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.runForecast(ctx, forecast, req.ModelType, authToken); err != nil {
+		return nil, err
+	}
+
+	return s.localResponse(ctx, forecast), nil
+}
+
+// SubmitForecast creates a forecast in PROCESSING state and queues its generation on the job
+// queue, acting for userID in the organization of the request. The returned forecast has no
+// predictions yet; its status can be polled by ID.
+func (s *ForecastService) SubmitForecast(ctx context.Context, req *models.ForecastGenerateRequest, userID string) (*models.ForecastResponse, error) {
+	forecast, err := s.createForecast(ctx, req, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &forecastJobPayload{ForecastID: forecast.ID.Hex(), ModelType: req.ModelType}
+	identity := &jobs.Identity{UserID: userID}
+	if scope := tenant.FromContext(ctx); scope != nil {
+		identity.OrgID = scope.OrgID
+	}
+	if _, err := s.jobQueue.Enqueue(ctx, forecastGenerationJob, payload, identity); err != nil {
+		s.forecastRepo.UpdateStatus(ctx, forecast.ID.Hex(), models.ForecastStatusFailed, err.Error())
+		return nil, err
+	}
+
+	return s.localResponse(ctx, forecast), nil
+}
+
+// localResponse converts a forecast to its API response, with timestamps in the building's time zone
+func (s *ForecastService) localResponse(ctx context.Context, forecast *models.Forecast) *models.ForecastResponse {
+	return forecast.ToResponse().InLocation(s.occupancyService.Location(ctx, forecast.BuildingID))
+}
+
+// createForecast validates a request and stores the forecast record in PROCESSING state
+func (s *ForecastService) createForecast(ctx context.Context, req *models.ForecastGenerateRequest, userID string) (*models.Forecast, error) {
+	if !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
+
 	// Set defaults
-	horizonHours := req.HorizonHours
-	if horizonHours <= 0 {
-		horizonHours = s.config.Forecast.DefaultHorizonHours
+	resolution := req.Resolution
+	if resolution == "" {
+		resolution = models.ForecastResolutionHourly
 	}
-	if horizonHours > s.config.Forecast.MaxHorizonHours {
-		horizonHours = s.config.Forecast.MaxHorizonHours
+	horizonHours, err := s.forecastHorizon(req.HorizonHours, resolution)
+	if err != nil {
+		return nil, err
 	}
 
 	historicalDays := req.HistoricalDays
@@ -56,7 +182,29 @@ func (s *ForecastService) GenerateForecast(ctx context.Context, req *models.Fore
 		historicalDays = 30
 	}
 
+	quantiles := normalizeQuantiles(req.Quantiles)
+	if len(quantiles) == 0 {
+		quantiles = s.config.Forecast.Quantiles
+	}
+
+	modelUsed := models.ForecastModelStatistical
+	if req.ModelType != "" {
+		if _, err := s.modelRegistry.Get(req.ModelType); err != nil {
+			return nil, err
+		}
+		modelUsed = req.ModelType
+	}
+
 	startTime := time.Now()
+	if resolution.IsSubHourly() {
+		// Intervals line up with the quarter or half hours demand is metered on
+		startTime = startTime.Truncate(resolution.Step())
+	}
+	if resolution.IsLongHorizon() {
+		// Daily and weekly periods start at midnight in the building's time zone
+		local := startTime.In(s.occupancyService.Location(ctx, req.BuildingID))
+		startTime = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	}
 	endTime := startTime.Add(time.Duration(horizonHours) * time.Hour)
 
 	// Create forecast record in pending state
@@ -66,6 +214,7 @@ func (s *ForecastService) GenerateForecast(ctx context.Context, req *models.Fore
 		Type:         req.Type,
 		Status:       models.ForecastStatusProcessing,
 		HorizonHours: horizonHours,
+		Resolution:   resolution,
 		StartTime:    startTime,
 		EndTime:      endTime,
 		InputParameters: models.ForecastInputParams{
@@ -73,10 +222,12 @@ func (s *ForecastService) GenerateForecast(ctx context.Context, req *models.Fore
 			IncludeWeather:  req.IncludeWeather,
 			IncludeTariffs:  req.IncludeTariffs,
 			SeasonalFactors: true,
+			Quantiles:       quantiles,
 		},
-		ModelUsed: "STATISTICAL",
-		Metadata:  req.Metadata,
-		CreatedBy: userID,
+		ModelUsed:   modelUsed,
+		Metadata:    req.Metadata,
+		CreatedBy:   userID,
+		CallbackURL: req.CallbackURL,
 	}
 
 	createdForecast, err := s.forecastRepo.Create(ctx, forecast)
@@ -84,44 +235,120 @@ func (s *ForecastService) GenerateForecast(ctx context.Context, req *models.Fore
 		return nil, fmt.Errorf("failed to create forecast record: %w", err)
 	}
 
-	// Fetch external data if requested
-	if req.IncludeWeather {
-		weather, err := s.externalClient.GetCurrentWeather(ctx, req.BuildingID, authToken)
+	return createdForecast, nil
+}
+
+// runForecast fetches external inputs, generates the predictions of a stored forecast and marks
+// it completed, or failed when no model could produce predictions
+func (s *ForecastService) runForecast(ctx context.Context, createdForecast *models.Forecast, modelType, authToken string) error {
+	resolution := createdForecast.Resolution
+
+	// Fetch external data if requested, reusing fresh inputs from the feature store
+	inputs := &createdForecast.InputParameters
+	if inputs.IncludeWeather {
+		weather, provenance, err := s.featureStore.GetWeather(ctx, createdForecast.BuildingID, authToken)
 		if err == nil {
-			createdForecast.InputParameters.WeatherData = weather
+			inputs.WeatherData = weather
+			inputs.Provenance = append(inputs.Provenance, *provenance)
 		}
 	}
 
-	if req.IncludeTariffs {
+	if inputs.IncludeTariffs {
 		// Assume region is derived from building (simplified)
-		tariff, err := s.externalClient.GetCurrentTariff(ctx, "default", authToken)
+		tariff, provenance, err := s.featureStore.GetTariff(ctx, "default", authToken)
 		if err == nil {
-			createdForecast.InputParameters.TariffData = tariff
+			inputs.TariffData = tariff
+			inputs.Provenance = append(inputs.Provenance, *provenance)
+		}
+	}
+
+	// Record the inputs and their provenance so the forecast can be reproduced
+	if inputs.WeatherData != nil || inputs.TariffData != nil {
+		if _, err := s.forecastRepo.Update(ctx, createdForecast.ID.Hex(), bson.M{"input_parameters": inputs}); err != nil {
+			log.Printf("Failed to record inputs of forecast %s: %v", createdForecast.ID.Hex(), err)
 		}
 	}
 
 	// Generate predictions
-	predictions, accuracy, err := s.generatePredictions(ctx, createdForecast, authToken)
+	predictions, accuracy, modelUsed, err := s.generatePredictions(ctx, createdForecast, modelType, authToken)
 	if err != nil {
-		s.forecastRepo.UpdateStatus(ctx, createdForecast.ID.Hex(), models.ForecastStatusFailed, err.Error())
-		return nil, fmt.Errorf("failed to generate predictions: %w", err)
+		createdForecast.Status = models.ForecastStatusFailed
+		createdForecast.ErrorMessage = err.Error()
+		// Record the failure even when the request or job deadline has passed
+		s.forecastRepo.UpdateStatus(context.WithoutCancel(ctx), createdForecast.ID.Hex(), models.ForecastStatusFailed, err.Error())
+		return fmt.Errorf("failed to generate predictions: %w", err)
+	}
+	if resolution.IsLongHorizon() {
+		predictions = aggregatePredictions(predictions, createdForecast.StartTime, resolution)
+	}
+	if resolution.IsSubHourly() {
+		predictions = interpolatePredictions(predictions, resolution)
+	}
+	for i := range predictions {
+		predictions[i].Resolution = resolution
 	}
 
 	// Update forecast with predictions
-	if err := s.forecastRepo.UpdatePredictions(ctx, createdForecast.ID.Hex(), predictions, accuracy); err != nil {
-		return nil, fmt.Errorf("failed to update predictions: %w", err)
+	if err := s.forecastRepo.UpdatePredictions(ctx, createdForecast.ID.Hex(), predictions, accuracy, modelUsed); err != nil {
+		return fmt.Errorf("failed to update predictions: %w", err)
 	}
+	s.invalidateLatest(ctx, createdForecast.BuildingID, createdForecast.Type)
 
 	createdForecast.Predictions = predictions
 	createdForecast.Accuracy = accuracy
 	createdForecast.Status = models.ForecastStatusCompleted
+	createdForecast.ModelUsed = modelUsed
+
+	s.eventBus.Publish(ctx, events.ForecastCompleted, &events.ForecastCompletedData{
+		ForecastID:   createdForecast.ID.Hex(),
+		BuildingID:   createdForecast.BuildingID,
+		DeviceID:     createdForecast.DeviceID,
+		Type:         string(createdForecast.Type),
+		ModelUsed:    createdForecast.ModelUsed,
+		HorizonHours: createdForecast.HorizonHours,
+		Prediction:   predictionSummary(createdForecast),
+		CompletedAt:  time.Now(),
+	})
+
+	return nil
+}
+
+// forecastHorizon applies the default and limit to a requested horizon. Daily and weekly
+// forecasts have their own, longer limit and cover whole days or weeks.
+func (s *ForecastService) forecastHorizon(requested int, resolution models.ForecastResolution) (int, error) {
+	horizonHours := requested
+	if horizonHours <= 0 {
+		horizonHours = s.settings.defaultHorizonHours.Get()
+	}
+
+	if !resolution.IsLongHorizon() {
+		if maxHours := s.settings.maxHorizonHours.Get(); horizonHours > maxHours {
+			horizonHours = maxHours
+		}
+		return horizonHours, nil
+	}
 
-	return createdForecast.ToResponse(), nil
+	maxHours := s.settings.longHorizonMaxHours.Get()
+	if maxHours <= 0 {
+		return 0, errors.New("long-horizon forecasts are disabled")
+	}
+
+	step := int(resolution.Step() / time.Hour)
+	horizonHours = (horizonHours + step - 1) / step * step
+	if horizonHours > maxHours {
+		horizonHours = maxHours / step * step
+	}
+	if horizonHours < step {
+		return 0, fmt.Errorf("invalid forecast horizon: the long-horizon limit of %d hours is shorter than one %s period", maxHours, resolution)
+	}
+	return horizonHours, nil
 }
 
-// generatePredictions generates forecast predictions using available data
-func (s *ForecastService) generatePredictions(ctx context.Context, forecast *models.Forecast, authToken string) ([]models.ForecastPrediction, *models.ForecastAccuracy, error) {
-	// Try ML model first
+// generatePredictions generates forecast predictions with the requested model.
+// Without an explicit model the external ML model is tried first, falling back to the
+// statistical profile, or to synthetic predictions when there is no history.
+func (s *ForecastService) generatePredictions(ctx context.Context, forecast *models.Forecast, modelType, authToken string) ([]models.ForecastPrediction, *models.ForecastAccuracy, string, error) {
+	var history *models.HistoricalConsumption
 	historicalData, err := s.externalClient.GetHistoricalConsumption(
 		ctx,
 		forecast.BuildingID,
@@ -131,164 +358,267 @@ func (s *ForecastService) generatePredictions(ctx context.Context, forecast *mod
 		"HOURLY",
 		authToken,
 	)
+	if err == nil && len(historicalData.DataPoints) > 0 {
+		history = historicalData
+	}
 
-	var predictions []models.ForecastPrediction
-	var accuracy *models.ForecastAccuracy
+	modelInput := &ForecastModelInput{
+		BuildingID:   forecast.BuildingID,
+		DeviceID:     forecast.DeviceID,
+		StartTime:    forecast.StartTime,
+		HorizonHours: forecast.HorizonHours,
+		Resolution:   forecast.Resolution,
+		History:      history,
+		// Time-of-day patterns follow the building's occupancy schedule
+		Schedule:  s.occupancyService.GetSchedule(ctx, forecast.BuildingID),
+		Weather:   forecast.InputParameters.WeatherData,
+		Quantiles: forecast.InputParameters.Quantiles,
+		AuthToken: authToken,
+	}
 
-	if err == nil && len(historicalData.DataPoints) > 0 {
-		// Try ML prediction
-		mlRequest := &integrations.MLPredictionRequest{
-			BuildingID:     forecast.BuildingID,
-			DeviceID:       forecast.DeviceID,
-			HistoricalData: historicalData.DataPoints,
-			HorizonHours:   forecast.HorizonHours,
-			ModelType:      "PROPHET",
-		}
+	if modelType != "" {
+		predictions, accuracy, err := s.runModel(ctx, modelType, modelInput)
+		return predictions, accuracy, modelType, err
+	}
 
-		mlResp, err := s.externalClient.GetMLPrediction(ctx, mlRequest, authToken)
-		if err == nil && mlResp.Success {
-			return mlResp.Predictions, mlResp.Accuracy, nil
-		}
+	chain := []string{models.ForecastModelSynthetic}
+	if modelInput.History != nil {
+		chain = []string{models.ForecastModelExternalML, models.ForecastModelStatistical}
+	}
 
-		// Fall back to statistical prediction using historical data
-		predictions = s.generateStatisticalPredictions(forecast, historicalData)
-		accuracy = &models.ForecastAccuracy{
-			MAE:   15.5,
-			RMSE:  20.3,
-			MAPE:  8.2,
-			Score: 78.0,
-		}
-	} else {
-		// Generate synthetic predictions for demo purposes
-		predictions = s.generateSyntheticPredictions(forecast)
-		accuracy = &models.ForecastAccuracy{
-			MAE:   25.0,
-			RMSE:  32.0,
-			MAPE:  12.0,
-			Score: 65.0,
+	var lastErr error
+	for _, name := range chain {
+		predictions, accuracy, err := s.runModel(ctx, name, modelInput)
+		if err == nil {
+			return predictions, accuracy, name, nil
 		}
+		lastErr = err
 	}
 
-	return predictions, accuracy, nil
+	return nil, nil, "", lastErr
 }
 
-// generateStatisticalPredictions generates predictions using statistical methods
-func (s *ForecastService) generateStatisticalPredictions(forecast *models.Forecast, historical *models.HistoricalConsumption) []models.ForecastPrediction {
-	predictions := make([]models.ForecastPrediction, 0, forecast.HorizonHours)
-
-	// Calculate baseline from historical data
-	baseline := historical.Summary.AverageKW
-	variance := (historical.Summary.PeakKW - historical.Summary.MinKW) / 4
+// runModel trains the named model and predicts the forecast horizon, including the quantiles
+// the model did not predict itself
+func (s *ForecastService) runModel(ctx context.Context, name string, input *ForecastModelInput) ([]models.ForecastPrediction, *models.ForecastAccuracy, error) {
+	model, err := s.modelRegistry.Get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := model.Train(ctx, input); err != nil {
+		return nil, nil, err
+	}
+	predictions, accuracy, err := model.Predict(ctx, input)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	currentTime := forecast.StartTime
+	var residuals []float64
+	if withResiduals, ok := model.(residualModel); ok {
+		residuals = withResiduals.Residuals()
+	}
+	ApplyQuantiles(predictions, input.Quantiles, residuals)
+	return predictions, accuracy, nil
+}
 
-	for i := 0; i < forecast.HorizonHours; i++ {
-		hour := currentTime.Hour()
+// ListModels returns the forecasting models available for selection
+func (s *ForecastService) ListModels() []models.ForecastModelInfo {
+	return s.modelRegistry.List()
+}
 
-		// Apply time-of-day pattern
-		var factor float64
-		switch {
-		case hour >= 6 && hour < 9:
-			factor = 1.2 // Morning ramp-up
-		case hour >= 9 && hour < 17:
-			factor = 1.4 // Business hours peak
-		case hour >= 17 && hour < 20:
-			factor = 1.1 // Evening
-		default:
-			factor = 0.6 // Night
+// GetLatestForecast returns the latest forecast for a building from the cache when it is still fresh.
+// A stale forecast is returned as-is while a replacement is generated in the background; a missing
+// forecast, or forceRefresh, generates one synchronously.
+func (s *ForecastService) GetLatestForecast(ctx context.Context, buildingID string, forecastType models.ForecastType, forceRefresh bool, userID, authToken string) (*models.ForecastResponse, error) {
+	maxAge := s.settings.cacheMaxAge.Get()
+
+	var cached *models.Forecast
+	if !forceRefresh {
+		forecast, err := s.findLatest(ctx, buildingID, forecastType)
+		if err != nil && err.Error() != "no forecasts found for this building" {
+			return nil, err
 		}
+		cached = forecast
+	}
 
-		// Apply day-of-week factor
-		if currentTime.Weekday() == time.Saturday || currentTime.Weekday() == time.Sunday {
-			factor *= 0.7
+	if cached == nil {
+		req := &models.ForecastGenerateRequest{BuildingID: buildingID, Type: forecastType}
+		response, err := s.GenerateForecast(ctx, req, userID, authToken)
+		if err != nil {
+			return nil, err
 		}
-
-		// Apply weather factor if available
-		if forecast.InputParameters.WeatherData != nil {
-			temp := forecast.InputParameters.WeatherData.Temperature
-			if temp > 25 || temp < 10 {
-				factor *= 1.15 // Increased HVAC usage
-			}
+		response.Freshness = &models.ForecastFreshness{
+			Source:        models.ForecastSourceGenerated,
+			GeneratedAt:   response.CreatedAt,
+			MaxAgeSeconds: int64(maxAge.Seconds()),
 		}
+		return response, nil
+	}
 
-		predictedValue := baseline * factor
-		uncertaintyMargin := variance * (1 + float64(i)/float64(forecast.HorizonHours)*0.5)
+	response := cached.ToResponse()
+	freshness := &models.ForecastFreshness{
+		Source:        models.ForecastSourceCache,
+		GeneratedAt:   cached.CreatedAt,
+		AgeSeconds:    int64(time.Since(cached.CreatedAt).Seconds()),
+		MaxAgeSeconds: int64(maxAge.Seconds()),
+		StaleReason:   s.staleReason(ctx, cached, maxAge),
+	}
+	freshness.Stale = freshness.StaleReason != ""
+	if freshness.Stale {
+		freshness.Refreshing = s.refreshInBackground(cached, userID, authToken)
+	}
+	response.Freshness = freshness
 
-		predictions = append(predictions, models.ForecastPrediction{
-			Timestamp:       currentTime,
-			PredictedValue:  math.Round(predictedValue*100) / 100,
-			LowerBound:      math.Round((predictedValue-uncertaintyMargin)*100) / 100,
-			UpperBound:      math.Round((predictedValue+uncertaintyMargin)*100) / 100,
-			ConfidenceLevel: 0.95 - float64(i)*0.01,
-			Unit:            "kW",
-		})
+	return response.InLocation(s.occupancyService.Location(ctx, buildingID)), nil
+}
 
-		currentTime = currentTime.Add(time.Hour)
+// GetStoredForecast returns the latest completed forecast of a building as stored, without
+// refreshing it when stale
+func (s *ForecastService) GetStoredForecast(ctx context.Context, buildingID string, forecastType models.ForecastType) (*models.ForecastResponse, error) {
+	forecast, err := s.findLatest(ctx, buildingID, forecastType)
+	if err != nil {
+		return nil, err
 	}
 
-	return predictions
+	return forecast.ToResponse(), nil
 }
 
-// generateSyntheticPredictions generates synthetic predictions for demo
-func (s *ForecastService) generateSyntheticPredictions(forecast *models.Forecast) []models.ForecastPrediction {
-	predictions := make([]models.ForecastPrediction, 0, forecast.HorizonHours)
+// GetStoredForecasts retrieves the latest stored forecast of each building without triggering
+// refreshes. Cached forecasts are used as is and the rest are loaded in a single query.
+// Buildings without forecasts are left out.
+func (s *ForecastService) GetStoredForecasts(ctx context.Context, buildingIDs []string, forecastType models.ForecastType) ([]*models.ForecastResponse, error) {
+	responses := make([]*models.ForecastResponse, 0, len(buildingIDs))
+	var missing []string
+	for _, buildingID := range buildingIDs {
+		if forecast, ok := cache.Get[*models.Forecast](ctx, s.cache, LatestForecastCacheKind, latestForecastKey(buildingID, forecastType)); ok {
+			responses = append(responses, forecast.ToResponse())
+			continue
+		}
+		missing = append(missing, buildingID)
+	}
+	if len(missing) == 0 {
+		return responses, nil
+	}
 
-	baseLoad := 50.0 + rand.Float64()*50 // Random base between 50-100 kW
-	currentTime := forecast.StartTime
+	forecasts, err := s.forecastRepo.FindLatestByBuildings(ctx, missing, forecastType)
+	if err != nil {
+		return nil, err
+	}
+	for _, forecast := range forecasts {
+		cache.Set(ctx, s.cache, LatestForecastCacheKind, latestForecastKey(forecast.BuildingID, forecastType), forecast)
+		responses = append(responses, forecast.ToResponse())
+	}
+
+	return responses, nil
+}
+
+// InvalidateCache marks every cached forecast of a building as stale
+func (s *ForecastService) InvalidateCache(buildingID string) {
+	s.cacheMu.Lock()
+	s.invalidatedAt[buildingID] = time.Now()
+	s.cacheMu.Unlock()
+
+	s.invalidateLatest(context.Background(), buildingID, "")
+}
 
-	for i := 0; i < forecast.HorizonHours; i++ {
-		hour := currentTime.Hour()
+// findLatest returns the latest completed hourly forecast of a building, from the shared cache
+// when present. Buildings without forecasts are not cached.
+func (s *ForecastService) findLatest(ctx context.Context, buildingID string, forecastType models.ForecastType) (*models.Forecast, error) {
+	return cache.GetOrLoad(ctx, s.cache, LatestForecastCacheKind, latestForecastKey(buildingID, forecastType), func() (*models.Forecast, error) {
+		return s.forecastRepo.FindLatestByBuilding(ctx, buildingID, forecastType)
+	})
+}
 
-		// Time-of-day pattern
-		var factor float64
-		switch {
-		case hour >= 6 && hour < 9:
-			factor = 1.3
-		case hour >= 9 && hour < 17:
-			factor = 1.5
-		case hour >= 17 && hour < 21:
-			factor = 1.2
-		default:
-			factor = 0.5
+// invalidateLatest drops the cached latest forecasts a changed forecast may be, the one of its
+// type and the one of any type. An empty type drops those of every type.
+func (s *ForecastService) invalidateLatest(ctx context.Context, buildingID string, forecastType models.ForecastType) {
+	keys := []string{latestForecastKey(buildingID, "")}
+	if forecastType != "" {
+		keys = append(keys, latestForecastKey(buildingID, forecastType))
+	} else {
+		for _, t := range []models.ForecastType{models.ForecastTypeDemand, models.ForecastTypeConsumption, models.ForecastTypeLoad} {
+			keys = append(keys, latestForecastKey(buildingID, t))
 		}
+	}
+	s.cache.Invalidate(ctx, LatestForecastCacheKind, keys...)
+}
 
-		// Add some randomness
-		noise := (rand.Float64() - 0.5) * 10
+// latestForecastKey is the cache key of the latest forecast of a building and type
+func latestForecastKey(buildingID string, forecastType models.ForecastType) string {
+	return buildingID + ":" + string(forecastType)
+}
 
-		predictedValue := baseLoad*factor + noise
-		margin := predictedValue * 0.15
+// staleReason reports why a cached forecast should be regenerated, or "" when it is fresh
+func (s *ForecastService) staleReason(ctx context.Context, forecast *models.Forecast, maxAge time.Duration) string {
+	s.cacheMu.Lock()
+	invalidatedAt, invalidated := s.invalidatedAt[forecast.BuildingID]
+	s.cacheMu.Unlock()
 
-		predictions = append(predictions, models.ForecastPrediction{
-			Timestamp:       currentTime,
-			PredictedValue:  math.Round(predictedValue*100) / 100,
-			LowerBound:      math.Round((predictedValue-margin)*100) / 100,
-			UpperBound:      math.Round((predictedValue+margin)*100) / 100,
-			ConfidenceLevel: 0.90,
-			Unit:            "kW",
-		})
+	switch {
+	case invalidated && forecast.CreatedAt.Before(invalidatedAt):
+		return models.ForecastStaleInvalidated
+	case time.Since(forecast.CreatedAt) > maxAge:
+		return models.ForecastStaleExpired
+	}
 
-		currentTime = currentTime.Add(time.Hour)
+	// Forecasts factor in occupancy, so a schedule or calendar edited after generation invalidates them
+	schedule := s.occupancyService.GetSchedule(ctx, forecast.BuildingID)
+	if changed := schedule.LastChanged(); !changed.IsZero() && forecast.CreatedAt.Before(changed) {
+		return models.ForecastStaleScheduleChanged
 	}
 
-	return predictions
+	return ""
 }
 
-// GetLatestForecast retrieves the latest forecast for a building
-func (s *ForecastService) GetLatestForecast(ctx context.Context, buildingID string, forecastType models.ForecastType) (*models.ForecastResponse, error) {
-	forecast, err := s.forecastRepo.FindLatestByBuilding(ctx, buildingID, forecastType)
-	if err != nil {
-		return nil, err
-	}
-	return forecast.ToResponse(), nil
+// refreshInBackground regenerates a stale forecast with its original parameters unless a refresh
+// for the same building and type is already running. It reports whether a refresh is in progress.
+func (s *ForecastService) refreshInBackground(stale *models.Forecast, userID, authToken string) bool {
+	key := stale.BuildingID + "|" + string(stale.Type)
+
+	s.cacheMu.Lock()
+	if s.refreshing[key] {
+		s.cacheMu.Unlock()
+		return true
+	}
+	s.refreshing[key] = true
+	s.cacheMu.Unlock()
+
+	req := &models.ForecastGenerateRequest{
+		BuildingID:     stale.BuildingID,
+		Type:           stale.Type,
+		HorizonHours:   stale.HorizonHours,
+		Resolution:     stale.Resolution,
+		IncludeWeather: stale.InputParameters.IncludeWeather,
+		IncludeTariffs: stale.InputParameters.IncludeTariffs,
+		HistoricalDays: stale.InputParameters.HistoricalDays,
+		Metadata:       stale.Metadata,
+	}
+
+	go func() {
+		defer func() {
+			s.cacheMu.Lock()
+			delete(s.refreshing, key)
+			s.cacheMu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), forecastRefreshTimeout)
+		defer cancel()
+
+		if _, err := s.GenerateForecast(ctx, req, userID, authToken); err != nil {
+			log.Printf("Background forecast refresh failed for building %s: %v", stale.BuildingID, err)
+		}
+	}()
+
+	return true
 }
 
 // GetForecastByID retrieves a forecast by ID
-func (s *ForecastService) GetForecastByID(ctx context.Context, id string) (*models.ForecastResponse, error) {
-	forecast, err := s.forecastRepo.FindByID(ctx, id)
+func (s *ForecastService) GetForecastByID(ctx context.Context, id string, fields *models.FieldSelection) (*models.ForecastResponse, error) {
+	forecast, err := s.forecastRepo.FindByIDWithFields(ctx, id, fields)
 	if err != nil {
 		return nil, err
 	}
-	return forecast.ToResponse(), nil
+	return s.localResponse(ctx, forecast), nil
 }
 
 // GetDevicePrediction retrieves predicted consumption for a device
@@ -311,13 +641,28 @@ func (s *ForecastService) GetDevicePrediction(ctx context.Context, deviceID, aut
 		return nil, fmt.Errorf("no forecast found for device %s", deviceID)
 	}
 
-	// Calculate trend
+	trend, trendPercentage := predictionTrend(latestForecast.Predictions)
+
+	return &models.DevicePrediction{
+		DeviceID:           deviceID,
+		DeviceName:         "Device " + deviceID,
+		DeviceType:         "UNKNOWN",
+		CurrentConsumption: latestForecast.Predictions[0].PredictedValue,
+		PredictedValues:    latestForecast.Predictions,
+		Trend:              trend,
+		TrendPercentage:    trendPercentage,
+	}, nil
+}
+
+// predictionTrend classifies the change between the first and last predicted values as
+// INCREASING or DECREASING when it exceeds 5%, and STABLE otherwise
+func predictionTrend(predictions []models.ForecastPrediction) (string, float64) {
 	trend := "STABLE"
 	trendPercentage := 0.0
 
-	if len(latestForecast.Predictions) >= 2 {
-		first := latestForecast.Predictions[0].PredictedValue
-		last := latestForecast.Predictions[len(latestForecast.Predictions)-1].PredictedValue
+	if len(predictions) >= 2 {
+		first := predictions[0].PredictedValue
+		last := predictions[len(predictions)-1].PredictedValue
 
 		if first > 0 {
 			trendPercentage = ((last - first) / first) * 100
@@ -330,22 +675,44 @@ func (s *ForecastService) GetDevicePrediction(ctx context.Context, deviceID, aut
 		}
 	}
 
-	return &models.DevicePrediction{
-		DeviceID:           deviceID,
-		DeviceName:         "Device " + deviceID,
-		DeviceType:         "UNKNOWN",
-		CurrentConsumption: latestForecast.Predictions[0].PredictedValue,
-		PredictedValues:    latestForecast.Predictions,
+	return trend, math.Round(trendPercentage*100) / 100
+}
+
+// predictionSummary summarizes the predictions of a device forecast for the ForecastCompleted
+// event. Building forecasts and forecasts without predictions have no summary.
+func predictionSummary(forecast *models.Forecast) *events.PredictionSummary {
+	if forecast.DeviceID == "" || len(forecast.Predictions) == 0 {
+		return nil
+	}
+
+	trend, trendPercentage := predictionTrend(forecast.Predictions)
+	summary := &events.PredictionSummary{
+		CurrentConsumption: forecast.Predictions[0].PredictedValue,
 		Trend:              trend,
-		TrendPercentage:    math.Round(trendPercentage*100) / 100,
-	}, nil
+		TrendPercentage:    trendPercentage,
+		Values:             make([]events.PredictedValue, 0, len(forecast.Predictions)),
+	}
+	for i, pred := range forecast.Predictions {
+		if i == 0 || pred.PredictedValue > summary.PeakValue {
+			summary.PeakValue = pred.PredictedValue
+			summary.PeakAt = pred.Timestamp
+		}
+		summary.Values = append(summary.Values, events.PredictedValue{
+			Timestamp:      pred.Timestamp,
+			PredictedValue: pred.PredictedValue,
+			LowerBound:     pred.LowerBound,
+			UpperBound:     pred.UpperBound,
+			Unit:           pred.Unit,
+		})
+	}
+	return summary
 }
 
 // GeneratePeakLoad generates peak load predictions
 func (s *ForecastService) GeneratePeakLoad(ctx context.Context, req *models.PeakLoadRequest, userID, authToken string) (*models.PeakLoadResponse, error) {
 	// Set defaults
 	if req.ThresholdPercent <= 0 {
-		req.ThresholdPercent = s.config.Forecast.PeakLoadThresholdPercent
+		req.ThresholdPercent = s.settings.peakLoadThresholdPercent.Get()
 	}
 
 	if req.AnalysisFromDate.IsZero() {
@@ -357,7 +724,7 @@ func (s *ForecastService) GeneratePeakLoad(ctx context.Context, req *models.Peak
 	}
 
 	// Get or generate forecast
-	forecast, err := s.forecastRepo.FindLatestByBuilding(ctx, req.BuildingID, models.ForecastTypeDemand)
+	forecast, err := latestDemandForecast(ctx, s.forecastRepo, req.BuildingID)
 	if err != nil {
 		// Generate a new forecast
 		forecastReq := &models.ForecastGenerateRequest{
@@ -383,12 +750,20 @@ func (s *ForecastService) GeneratePeakLoad(ctx context.Context, req *models.Peak
 	baseline := totalValue / float64(len(forecast.Predictions))
 	threshold := baseline * (1 + req.ThresholdPercent/100)
 
+	// Conservative planning compares the p90 quantile with the threshold
+	basis := models.PeakLoadBasisExpected
+	predictions := forecast.Predictions
+	if req.Conservative {
+		basis = models.PeakLoadBasisP90
+		predictions = p90Predictions(forecast.Predictions)
+	}
+
 	// Identify peak periods
-	peaks := s.identifyPeakPeriods(forecast.Predictions, baseline, threshold)
+	peaks := s.identifyPeakPeriods(predictions, forecast.Resolution.Step(), baseline, threshold)
 
 	// Find max predicted load
 	var maxLoad float64
-	for _, pred := range forecast.Predictions {
+	for _, pred := range predictions {
 		if pred.PredictedValue > maxLoad {
 			maxLoad = pred.PredictedValue
 		}
@@ -412,6 +787,7 @@ func (s *ForecastService) GeneratePeakLoad(ctx context.Context, req *models.Peak
 		BaselineLoad:     math.Round(baseline*100) / 100,
 		MaxPredictedLoad: math.Round(maxLoad*100) / 100,
 		ThresholdPercent: req.ThresholdPercent,
+		Basis:            basis,
 		AnalysisPeriod: models.AnalysisPeriod{
 			From: req.AnalysisFromDate,
 			To:   req.AnalysisToDate,
@@ -429,8 +805,37 @@ func (s *ForecastService) GeneratePeakLoad(ctx context.Context, req *models.Peak
 	return createdPeakLoad.ToResponse(), nil
 }
 
-// identifyPeakPeriods identifies periods of peak load from predictions
-func (s *ForecastService) identifyPeakPeriods(predictions []models.ForecastPrediction, baseline, threshold float64) []models.PeakPeriod {
+// latestDemandForecast returns the latest demand forecast of a building, preferring a 15- or
+// 30-minute forecast that has not ended over the latest hourly one, since short peaks that set
+// demand charges average out over an hour
+func latestDemandForecast(ctx context.Context, forecastRepo *repository.ForecastRepository, buildingID string) (*models.Forecast, error) {
+	forecast, err := forecastRepo.FindLatestSubHourlyByBuilding(ctx, buildingID, models.ForecastTypeDemand, time.Now())
+	if err == nil {
+		return forecast, nil
+	}
+	return forecastRepo.FindLatestByBuilding(ctx, buildingID, models.ForecastTypeDemand)
+}
+
+// p90Predictions returns copies of the predictions whose predicted value is their p90 quantile.
+// Predictions without one, such as those of forecasts generated before quantiles or with other
+// quantiles, fall back to their upper bound.
+func p90Predictions(predictions []models.ForecastPrediction) []models.ForecastPrediction {
+	label := QuantileLabel(0.9)
+	conservative := make([]models.ForecastPrediction, len(predictions))
+	for i, prediction := range predictions {
+		if value, ok := prediction.Quantiles[label]; ok {
+			prediction.PredictedValue = value
+		} else {
+			prediction.PredictedValue = prediction.UpperBound
+		}
+		conservative[i] = prediction
+	}
+	return conservative
+}
+
+// identifyPeakPeriods identifies periods of peak load from predictions covering step each.
+// The expected load of a peak is its energy, the sum of the kW predictions times their step.
+func (s *ForecastService) identifyPeakPeriods(predictions []models.ForecastPrediction, step time.Duration, baseline, threshold float64) []models.PeakPeriod {
 	var peaks []models.PeakPeriod
 	var currentPeak *models.PeakPeriod
 
@@ -440,13 +845,13 @@ func (s *ForecastService) identifyPeakPeriods(predictions []models.ForecastPredi
 				currentPeak = &models.PeakPeriod{
 					StartTime:    pred.Timestamp,
 					PeakValue:    pred.PredictedValue,
-					ExpectedLoad: pred.PredictedValue,
+					ExpectedLoad: pred.PredictedValue * step.Hours(),
 				}
 			} else {
 				if pred.PredictedValue > currentPeak.PeakValue {
 					currentPeak.PeakValue = pred.PredictedValue
 				}
-				currentPeak.ExpectedLoad += pred.PredictedValue
+				currentPeak.ExpectedLoad += pred.PredictedValue * step.Hours()
 			}
 		} else if currentPeak != nil {
 			// End of peak period
@@ -462,7 +867,7 @@ func (s *ForecastService) identifyPeakPeriods(predictions []models.ForecastPredi
 
 	// Handle case where peak extends to end
 	if currentPeak != nil && len(predictions) > 0 {
-		currentPeak.EndTime = predictions[len(predictions)-1].Timestamp.Add(time.Hour)
+		currentPeak.EndTime = predictions[len(predictions)-1].Timestamp.Add(step)
 		currentPeak.PercentAboveBase = ((currentPeak.PeakValue - baseline) / baseline) * 100
 		currentPeak.Severity = s.calculateSeverity(currentPeak.PercentAboveBase)
 		currentPeak.Confidence = 0.85
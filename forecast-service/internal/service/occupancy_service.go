@@ -0,0 +1,340 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"forecast-service/internal/integrations"
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+	"forecast-service/internal/tenant"
+)
+
+// liveOccupancyWindow bounds how far from now live sensor readings are considered representative
+const liveOccupancyWindow = 15 * time.Minute
+
+// OccupancyService handles building occupancy schedules and occupancy resolution
+type OccupancyService struct {
+	occupancyRepo *repository.OccupancyRepository
+	calendarRepo  *repository.CalendarRepository
+	buildingRepo  *repository.BuildingRepository
+	iotClient     *integrations.IoTClient
+}
+
+// NewOccupancyService creates a new occupancy service
+func NewOccupancyService(
+	occupancyRepo *repository.OccupancyRepository,
+	calendarRepo *repository.CalendarRepository,
+	buildingRepo *repository.BuildingRepository,
+	iotClient *integrations.IoTClient,
+) *OccupancyService {
+	return &OccupancyService{
+		occupancyRepo: occupancyRepo,
+		calendarRepo:  calendarRepo,
+		buildingRepo:  buildingRepo,
+		iotClient:     iotClient,
+	}
+}
+
+// GetSchedule retrieves the effective occupancy schedule for a building, with the business
+// calendar of the building and its region. Buildings without a stored schedule fall back to
+// the default business hours. The time zone of the building registry takes precedence over
+// the schedule's own.
+func (s *OccupancyService) GetSchedule(ctx context.Context, buildingID string) *models.OccupancySchedule {
+	schedule, err := s.occupancyRepo.FindByBuilding(ctx, buildingID)
+	if err != nil {
+		if err.Error() != "occupancy schedule not found" {
+			log.Printf("Failed to load occupancy schedule for building %s: %v", buildingID, err)
+		}
+		schedule = models.DefaultOccupancySchedule(buildingID)
+	}
+	if timeZone := s.registeredTimeZone(ctx, buildingID); timeZone != "" {
+		schedule.TimeZone = timeZone
+	}
+
+	calendar, err := s.calendarRepo.Find(ctx, buildingID, schedule.Region, "", "")
+	if err != nil {
+		log.Printf("Failed to load business calendar for building %s: %v", buildingID, err)
+	}
+	schedule.Calendar = calendar
+	return schedule
+}
+
+// Location returns the time zone of a building: the one in the building registry, else the
+// occupancy schedule's, else UTC
+func (s *OccupancyService) Location(ctx context.Context, buildingID string) *time.Location {
+	if timeZone := s.registeredTimeZone(ctx, buildingID); timeZone != "" {
+		return models.LoadTimeZone(timeZone)
+	}
+	schedule, err := s.occupancyRepo.FindByBuilding(ctx, buildingID)
+	if err != nil {
+		return time.UTC
+	}
+	return schedule.Location()
+}
+
+// registeredTimeZone returns the time zone of a building in the building registry, or "" when
+// the building is not registered
+func (s *OccupancyService) registeredTimeZone(ctx context.Context, buildingID string) string {
+	building, err := s.buildingRepo.FindByBuilding(ctx, buildingID)
+	if err != nil {
+		if err.Error() != "building not found" {
+			log.Printf("Failed to load building %s: %v", buildingID, err)
+		}
+		return ""
+	}
+	return building.TimeZone
+}
+
+// SaveSchedule creates or replaces the occupancy schedule for a building
+func (s *OccupancyService) SaveSchedule(ctx context.Context, buildingID string, req *models.OccupancyScheduleRequest, userID string) (*models.OccupancyScheduleResponse, error) {
+	if !tenant.AllowsBuilding(ctx, buildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", buildingID)
+	}
+
+	schedule := &models.OccupancySchedule{
+		BuildingID:    buildingID,
+		TimeZone:      req.TimeZone,
+		Region:        req.Region,
+		BusinessHours: req.BusinessHours,
+		Holidays:      req.Holidays,
+		LiveOccupancy: req.LiveOccupancy,
+		CreatedBy:     userID,
+	}
+
+	for i := range schedule.Holidays {
+		if schedule.Holidays[i].Source == "" {
+			schedule.Holidays[i].Source = models.HolidaySourceManual
+		}
+	}
+	if schedule.LiveOccupancy != nil && schedule.LiveOccupancy.Parameter == "" {
+		schedule.LiveOccupancy.Parameter = "occupancy"
+	}
+
+	if err := validateOccupancySchedule(schedule); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	saved, err := s.occupancyRepo.Upsert(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	return saved.ToResponse(), nil
+}
+
+// DeleteSchedule removes a building's occupancy schedule, reverting it to the default
+func (s *OccupancyService) DeleteSchedule(ctx context.Context, buildingID string) error {
+	if !tenant.AllowsBuilding(ctx, buildingID) {
+		return fmt.Errorf("validation failed: building %s does not belong to your organization", buildingID)
+	}
+	return s.occupancyRepo.DeleteByBuilding(ctx, buildingID)
+}
+
+// ImportHolidays merges holidays from an iCalendar (.ics) document into a building's schedule.
+// Dates already present in the schedule are skipped.
+func (s *OccupancyService) ImportHolidays(ctx context.Context, buildingID, calendar, userID string) (*models.HolidayImportResult, error) {
+	if !tenant.AllowsBuilding(ctx, buildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", buildingID)
+	}
+
+	imported, err := parseICalendarHolidays(calendar)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	schedule := s.GetSchedule(ctx, buildingID)
+	if schedule.ID.IsZero() {
+		schedule.CreatedBy = userID
+	}
+
+	existing := make(map[string]bool, len(schedule.Holidays))
+	for _, holiday := range schedule.Holidays {
+		existing[holiday.Date] = true
+	}
+
+	result := &models.HolidayImportResult{BuildingID: buildingID}
+	for _, holiday := range imported {
+		if existing[holiday.Date] {
+			result.Skipped++
+			continue
+		}
+		existing[holiday.Date] = true
+		schedule.Holidays = append(schedule.Holidays, holiday)
+		result.Imported++
+	}
+
+	if result.Imported > 0 {
+		if _, err := s.occupancyRepo.Upsert(ctx, schedule); err != nil {
+			return nil, err
+		}
+	}
+	result.Total = len(schedule.Holidays)
+
+	return result, nil
+}
+
+// GetOccupancyStatus resolves whether a building is occupied at the given time.
+// Live occupancy sensors are consulted when configured and the time is close to now;
+// otherwise the schedule (or the default business hours) decides.
+func (s *OccupancyService) GetOccupancyStatus(ctx context.Context, buildingID string, at time.Time, authToken string) *models.OccupancyStatus {
+	schedule := s.GetSchedule(ctx, buildingID)
+
+	status := &models.OccupancyStatus{
+		BuildingID: buildingID,
+		Timestamp:  at,
+		Period:     schedule.PeriodAt(at),
+		Source:     models.OccupancySourceSchedule,
+	}
+	if schedule.ID.IsZero() {
+		status.Source = models.OccupancySourceDefault
+	}
+	status.Occupied = status.Period == models.OccupancyPeriodOccupied
+	if holiday := schedule.HolidayAt(at); holiday != nil {
+		status.Holiday = holiday.Name
+	}
+
+	if live := schedule.LiveOccupancy; live != nil && live.Enabled && math.Abs(time.Since(at).Seconds()) <= liveOccupancyWindow.Seconds() {
+		if occupied, ok := s.readLiveOccupancy(ctx, live, authToken); ok {
+			status.Occupied = occupied
+			status.Source = models.OccupancySourceLive
+		}
+	}
+
+	return status
+}
+
+// readLiveOccupancy reads occupancy sensors; ok is false when no sensor could be read
+func (s *OccupancyService) readLiveOccupancy(ctx context.Context, live *models.LiveOccupancyConfig, authToken string) (occupied bool, ok bool) {
+	for _, deviceID := range live.SensorDeviceIDs {
+		state, err := s.iotClient.GetDeviceState(ctx, deviceID, authToken)
+		if err != nil {
+			log.Printf("Failed to read occupancy sensor %s: %v", deviceID, err)
+			continue
+		}
+
+		value, found := state.Parameters[live.Parameter]
+		if !found {
+			continue
+		}
+		ok = true
+
+		switch v := value.(type) {
+		case bool:
+			if v {
+				return true, true
+			}
+		case float64:
+			if v >= live.Threshold && v > 0 {
+				return true, true
+			}
+		}
+	}
+
+	return false, ok
+}
+
+// validateOccupancySchedule validates business hours, holidays, time zone and sensors
+func validateOccupancySchedule(schedule *models.OccupancySchedule) error {
+	if schedule.TimeZone != "" {
+		if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+			return fmt.Errorf("unknown time zone %q", schedule.TimeZone)
+		}
+	}
+
+	seenDays := make(map[int]bool)
+	for _, hours := range schedule.BusinessHours {
+		if hours.DayOfWeek < 0 || hours.DayOfWeek > 6 {
+			return fmt.Errorf("business hours must use days between 0 (Sunday) and 6")
+		}
+		if seenDays[hours.DayOfWeek] {
+			return fmt.Errorf("business hours for day %d are defined more than once", hours.DayOfWeek)
+		}
+		seenDays[hours.DayOfWeek] = true
+		if !validHour(hours.OpenHour) || !validHour(hours.CloseHour) || hours.OpenHour >= hours.CloseHour {
+			return fmt.Errorf("business hours for day %d must open before they close, between 0 and 24", hours.DayOfWeek)
+		}
+	}
+
+	for _, holiday := range schedule.Holidays {
+		if _, err := time.Parse("2006-01-02", holiday.Date); err != nil {
+			return fmt.Errorf("holiday %q must use the YYYY-MM-DD date format", holiday.Name)
+		}
+	}
+
+	if live := schedule.LiveOccupancy; live != nil && live.Enabled && len(live.SensorDeviceIDs) == 0 {
+		return fmt.Errorf("live occupancy requires at least one sensor device")
+	}
+
+	return nil
+}
+
+// parseICalendarHolidays extracts all-day events from an iCalendar document as holidays
+func parseICalendarHolidays(calendar string) ([]models.Holiday, error) {
+	// Unfold continuation lines (RFC 5545 section 3.1)
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(calendar))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var holidays []models.Holiday
+	var current *models.Holiday
+	sawCalendar := false
+
+	for _, line := range lines {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		// Strip property parameters such as DTSTART;VALUE=DATE
+		property := strings.ToUpper(strings.SplitN(name, ";", 2)[0])
+
+		switch {
+		case property == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			sawCalendar = true
+		case property == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = &models.Holiday{Source: models.HolidaySourceCalendar}
+		case property == "END" && strings.EqualFold(value, "VEVENT"):
+			if current != nil && current.Date != "" {
+				if current.Name == "" {
+					current.Name = "Holiday"
+				}
+				holidays = append(holidays, *current)
+			}
+			current = nil
+		case current == nil:
+			continue
+		case property == "DTSTART":
+			if len(value) < 8 {
+				return nil, fmt.Errorf("invalid DTSTART value %q", value)
+			}
+			date, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTSTART value %q", value)
+			}
+			current.Date = date.Format("2006-01-02")
+		case property == "SUMMARY":
+			current.Name = strings.TrimSpace(strings.ReplaceAll(value, "\\,", ","))
+		}
+	}
+
+	if !sawCalendar {
+		return nil, fmt.Errorf("document is not an iCalendar file")
+	}
+
+	return holidays, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"forecast-service/internal/models"
+)
+
+// capabilityRange names the device type constraints bounding the target value of an action type.
+// An empty name leaves that side unbounded.
+type capabilityRange struct {
+	unit   string
+	format string
+	min    string
+	max    string
+}
+
+// capabilityRanges maps action types to the catalog constraints their targets must respect
+var capabilityRanges = map[string]capabilityRange{
+	"SET_TEMP":       {unit: "°C", format: "%.1f°C", min: "minTemperature", max: "maxTemperature"},
+	"SET_BRIGHTNESS": {unit: "%", format: "%.0f%%", min: "minBrightness", max: "maxBrightness"},
+	"CHARGE":         {unit: "kW", format: "%.1f kW", max: "maxPowerKW"},
+	"DISCHARGE":      {unit: "kW", format: "%.1f kW", max: "maxPowerKW"},
+}
+
+// CapabilityError is returned when generated actions ask more of their devices than the device
+// type catalog allows
+type CapabilityError struct {
+	Violations []models.CapabilityViolation
+}
+
+func (e *CapabilityError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// FieldErrors describes the violations in the form of request validation errors, with the
+// capability as the rule and its limit as the parameter
+func (e *CapabilityError) FieldErrors() []models.FieldError {
+	fields := make([]models.FieldError, len(e.Violations))
+	for i, violation := range e.Violations {
+		fields[i] = models.FieldError{
+			Field:   violation.Field,
+			Rule:    violation.Capability,
+			Message: violation.Message,
+		}
+		if violation.Capability != "supportedCommands" {
+			fields[i].Param = strconv.FormatFloat(violation.Limit, 'f', -1, 64)
+		}
+	}
+	return fields
+}
+
+// enforceCapabilities checks generated actions against their devices' capabilities. Violations
+// that were clamped are returned as warnings; any other violation fails with a CapabilityError.
+func (s *OptimizationService) enforceCapabilities(ctx context.Context, actions []models.OptimizationAction, authToken string) ([]models.CapabilityViolation, error) {
+	violations := s.checkCapabilities(ctx, actions, s.clampToCapabilities.Get(), authToken)
+	if blocking := blockingViolations(violations); len(blocking) > 0 {
+		return nil, &CapabilityError{Violations: blocking}
+	}
+	return violations, nil
+}
+
+// checkCapabilities validates actions against the device type catalog: the device must support
+// the command, and the target must lie within the type's capability range, e.g. no setpoint
+// below the unit's minimum temperature. With clamp set, out-of-range targets are moved to the
+// limit in place and reported as clamped. Targets that are not numbers are left to the IoT service.
+func (s *OptimizationService) checkCapabilities(ctx context.Context, actions []models.OptimizationAction, clamp bool, authToken string) []models.CapabilityViolation {
+	var violations []models.CapabilityViolation
+
+	for i := range actions {
+		action := &actions[i]
+		deviceType := s.deviceTypes.Resolve(ctx, models.DeviceState{DeviceID: action.DeviceID, Type: action.DeviceType}, authToken)
+		violation := models.CapabilityViolation{
+			Field:      fmt.Sprintf("actions[%d].targetValue", i),
+			ActionID:   action.ID,
+			DeviceID:   action.DeviceID,
+			DeviceType: deviceType.Name,
+			ActionType: action.ActionType,
+		}
+
+		if !deviceType.SupportsCommand(action.ActionType) {
+			violation.Field = fmt.Sprintf("actions[%d].actionType", i)
+			violation.Capability = "supportedCommands"
+			violation.Message = fmt.Sprintf("device %s is a %s, which does not support %s", action.DeviceID, deviceType.Name, action.ActionType)
+			violations = append(violations, violation)
+			continue
+		}
+
+		limits, ok := capabilityRanges[action.ActionType]
+		if !ok {
+			continue
+		}
+		target, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(action.TargetValue, limits.unit)), 64)
+		if err != nil {
+			continue
+		}
+
+		if minimum, ok := deviceType.Constraint(limits.min); ok && target < minimum {
+			violation.Capability, violation.Limit = limits.min, minimum
+			violation.Message = fmt.Sprintf("target %s of device %s is below its minimum of "+limits.format, action.TargetValue, action.DeviceID, minimum)
+		} else if maximum, ok := deviceType.Constraint(limits.max); ok && target > maximum {
+			violation.Capability, violation.Limit = limits.max, maximum
+			violation.Message = fmt.Sprintf("target %s of device %s is above its maximum of "+limits.format, action.TargetValue, action.DeviceID, maximum)
+		} else {
+			continue
+		}
+
+		violation.Requested = target
+		if clamp {
+			action.TargetValue = fmt.Sprintf(limits.format, violation.Limit)
+			violation.Clamped = true
+			violation.Message += "; clamped to the limit"
+		}
+		violations = append(violations, violation)
+	}
+
+	return violations
+}
+
+// blockingViolations returns the violations that were not resolved by clamping
+func blockingViolations(violations []models.CapabilityViolation) []models.CapabilityViolation {
+	var blocking []models.CapabilityViolation
+	for _, violation := range violations {
+		if !violation.Clamped {
+			blocking = append(blocking, violation)
+		}
+	}
+	return blocking
+}
+
+// saveClampedActions stores actions clamped at approval time and logs a warning for each
+func (s *OptimizationService) saveClampedActions(ctx context.Context, scenario *models.OptimizationScenario, clamped []models.CapabilityViolation) error {
+	scenario.CapabilityWarnings = append(scenario.CapabilityWarnings, clamped...)
+	if _, err := s.optimizationRepo.Update(ctx, scenario.ID.Hex(), bson.M{
+		"actions":             scenario.Actions,
+		"capability_warnings": scenario.CapabilityWarnings,
+	}); err != nil {
+		return fmt.Errorf("failed to save clamped actions: %w", err)
+	}
+
+	for _, violation := range clamped {
+		s.optimizationRepo.AddExecutionLog(ctx, scenario.ID.Hex(), models.ExecutionLogEntry{
+			Level:    "WARNING",
+			Message:  violation.Message,
+			ActionID: violation.ActionID,
+		})
+	}
+	return nil
+}
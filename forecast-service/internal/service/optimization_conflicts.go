@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"forecast-service/internal/models"
+)
+
+// GetScenarioConflicts lists the approved or executing scenarios that control the same
+// devices as the scenario in an overlapping time window
+func (s *OptimizationService) GetScenarioConflicts(ctx context.Context, scenarioID string) (*models.ScenarioConflictsResponse, error) {
+	scenario, err := s.optimizationRepo.FindByID(ctx, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts, _, err := s.detectConflicts(ctx, scenario)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check scenario conflicts: %w", err)
+	}
+	if conflicts == nil {
+		conflicts = []models.ScenarioConflict{}
+	}
+
+	return &models.ScenarioConflictsResponse{
+		ScenarioID:   scenarioID,
+		HasConflicts: len(conflicts) > 0,
+		Conflicts:    conflicts,
+	}, nil
+}
+
+// detectConflicts compares the scenario's actions with those of active scenarios for the same
+// building. It returns one conflict per clashing scenario and the IDs of the scenario's own
+// actions that clash.
+func (s *OptimizationService) detectConflicts(ctx context.Context, scenario *models.OptimizationScenario) ([]models.ScenarioConflict, map[string]bool, error) {
+	var deviceIDs []string
+	seen := make(map[string]bool)
+	for _, action := range scenario.Actions {
+		if action.DeviceID != "" && !seen[action.DeviceID] {
+			seen[action.DeviceID] = true
+			deviceIDs = append(deviceIDs, action.DeviceID)
+		}
+	}
+	if len(deviceIDs) == 0 {
+		return nil, nil, nil
+	}
+
+	start, end := scenarioWindow(scenario)
+	candidates, err := s.optimizationRepo.FindActiveOverlapping(ctx, scenario.BuildingID, deviceIDs, start, end, scenario.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var conflicts []models.ScenarioConflict
+	conflictingActions := make(map[string]bool)
+	for _, other := range candidates {
+		devices := make(map[string]bool)
+		var overlapStart, overlapEnd time.Time
+
+		for _, action := range scenario.Actions {
+			actionStart, actionEnd := actionWindow(scenario, action)
+			for _, otherAction := range other.Actions {
+				if otherAction.DeviceID != action.DeviceID {
+					continue
+				}
+				otherStart, otherEnd := actionWindow(other, otherAction)
+				if !actionStart.Before(otherEnd) || !otherStart.Before(actionEnd) {
+					continue
+				}
+
+				devices[action.DeviceID] = true
+				conflictingActions[action.ID] = true
+
+				from, to := maxTime(actionStart, otherStart), minTime(actionEnd, otherEnd)
+				if overlapStart.IsZero() || from.Before(overlapStart) {
+					overlapStart = from
+				}
+				if to.After(overlapEnd) {
+					overlapEnd = to
+				}
+			}
+		}
+
+		if len(devices) == 0 {
+			continue
+		}
+
+		conflict := models.ScenarioConflict{
+			ScenarioID:   other.ID.Hex(),
+			ScenarioName: other.Name,
+			Status:       other.Status,
+			Priority:     other.Priority,
+			OverlapStart: overlapStart,
+			OverlapEnd:   overlapEnd,
+		}
+		for deviceID := range devices {
+			conflict.DeviceIDs = append(conflict.DeviceIDs, deviceID)
+		}
+		sort.Strings(conflict.DeviceIDs)
+		conflicts = append(conflicts, conflict)
+	}
+
+	return conflicts, conflictingActions, nil
+}
+
+// resolveConflicts applies the requested resolution before a scenario is approved. Conflicts
+// that could not be resolved are returned; the scenario must not be approved if any remain.
+func (s *OptimizationService) resolveConflicts(
+	ctx context.Context,
+	scenario *models.OptimizationScenario,
+	conflicts []models.ScenarioConflict,
+	conflictingActions map[string]bool,
+	resolution models.ConflictResolution,
+	response *models.SendToIoTResponse,
+) ([]models.ScenarioConflict, error) {
+	switch resolution {
+	case models.ConflictResolutionPriority:
+		// Only approved scenarios can be superseded; executing ones already sent commands
+		var unresolved []models.ScenarioConflict
+		for _, conflict := range conflicts {
+			if conflict.Status != models.OptimizationStatusApproved || conflict.Priority >= scenario.Priority {
+				unresolved = append(unresolved, conflict)
+			}
+		}
+		if len(unresolved) > 0 {
+			return unresolved, nil
+		}
+
+		for _, conflict := range conflicts {
+			message := fmt.Sprintf("Superseded by higher-priority scenario %s", scenario.ID.Hex())
+			if err := s.optimizationRepo.UpdateStatus(ctx, conflict.ScenarioID, models.OptimizationStatusCancelled, message); err != nil {
+				return nil, fmt.Errorf("failed to cancel scenario %s: %w", conflict.ScenarioID, err)
+			}
+			s.optimizationRepo.AddExecutionLog(ctx, conflict.ScenarioID, models.ExecutionLogEntry{
+				Level:   "WARNING",
+				Message: message,
+			})
+			response.CancelledScenarios = append(response.CancelledScenarios, conflict.ScenarioID)
+		}
+		return nil, nil
+
+	case models.ConflictResolutionMerge:
+		var actions []models.OptimizationAction
+		for _, action := range scenario.Actions {
+			if !conflictingActions[action.ID] {
+				actions = append(actions, action)
+			}
+		}
+		if len(actions) == 0 {
+			return conflicts, nil
+		}
+
+		dropped := len(scenario.Actions) - len(actions)
+		savings := s.calculateExpectedSavings(ctx, actions, scenario.TariffData, scenario.BaselineLoadKW)
+		if _, err := s.optimizationRepo.Update(ctx, scenario.ID.Hex(), bson.M{
+			"actions":          actions,
+			"expected_savings": savings,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to merge scenario actions: %w", err)
+		}
+		s.optimizationRepo.AddExecutionLog(ctx, scenario.ID.Hex(), models.ExecutionLogEntry{
+			Level:   "WARNING",
+			Message: fmt.Sprintf("Dropped %d actions that conflict with %d active scenarios", dropped, len(conflicts)),
+		})
+
+		scenario.Actions = actions
+		scenario.ExpectedSavings = savings
+		response.ActionsDropped = dropped
+		return nil, nil
+
+	default:
+		return conflicts, nil
+	}
+}
+
+// scenarioWindow returns the period covered by a scenario's schedule and all of its actions
+func scenarioWindow(scenario *models.OptimizationScenario) (time.Time, time.Time) {
+	start, end := scenario.ScheduledStart, scenario.ScheduledEnd
+	for _, action := range scenario.Actions {
+		actionStart, actionEnd := actionWindow(scenario, action)
+		if start.IsZero() || actionStart.Before(start) {
+			start = actionStart
+		}
+		if actionEnd.After(end) {
+			end = actionEnd
+		}
+	}
+	return start, end
+}
+
+// actionWindow returns the period during which an action controls its device. Actions without
+// a schedule or duration fall back to the scenario's schedule.
+func actionWindow(scenario *models.OptimizationScenario, action models.OptimizationAction) (time.Time, time.Time) {
+	start := action.ScheduledTime
+	if start.IsZero() {
+		start = scenario.ScheduledStart
+	}
+	if action.Duration <= 0 {
+		return start, maxTime(start, scenario.ScheduledEnd)
+	}
+	return start, start.Add(time.Duration(action.Duration) * time.Minute)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
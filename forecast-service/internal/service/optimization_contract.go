@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"forecast-service/internal/models"
+)
+
+// maxContractShedShare is the largest share of a device's current power peak shaving sheds
+const maxContractShedShare = 0.3
+
+// DemandWindow is the projected average demand from Start over Length, one measurement window of
+// a demand contract or a longer prediction
+type DemandWindow struct {
+	Start  time.Time
+	Length time.Duration
+	KW     float64
+}
+
+// ContractShedding is the load a device sheds to keep a building under its contracted demand
+type ContractShedding struct {
+	Device      models.DeviceState
+	DeviceType  *models.DeviceType
+	ReductionKW float64
+}
+
+// demandContract returns the demand contract of a building, or nil when it has none
+func (s *OptimizationService) demandContract(ctx context.Context, buildingID string) *models.DemandContract {
+	contract, err := s.contractRepo.FindByBuilding(ctx, buildingID)
+	if err != nil {
+		if err.Error() != "demand contract not found" {
+			log.Printf("Failed to load demand contract of building %s: %v", buildingID, err)
+		}
+		return nil
+	}
+	return contract
+}
+
+// generateContractShavingActions sheds just enough load to keep the building's demand under its
+// contracted maximum. Demand is projected from the scenario's forecast, or the building's latest
+// demand forecast, averaged over the contract's measurement windows; without one the current load
+// is assumed to last the whole scenario. The excess of the highest window is shed by the largest
+// devices first, each reducing its power by at most 30% from the first window above the limit to
+// the end of the last. Also returns the projected peak, before the reductions are applied.
+func (s *OptimizationService) generateContractShavingActions(
+	ctx context.Context,
+	contract *models.DemandContract,
+	devices []models.DeviceState,
+	forecast *models.Forecast,
+	occupancy *models.OccupancyStatus,
+	req *models.OptimizationGenerateRequest,
+	authToken string,
+) ([]models.OptimizationAction, *models.ContractPenaltySavings) {
+	if forecast == nil {
+		forecast, _ = latestDemandForecast(ctx, s.forecastRepo, req.BuildingID)
+	}
+
+	window := contract.Window()
+	var windows []DemandWindow
+	if forecast != nil && !forecast.Resolution.IsLongHorizon() {
+		windows = ContractDemandWindows(forecast.Predictions, forecast.Resolution.Step(), window, req.ScheduledStart, req.ScheduledEnd)
+	}
+	projected := len(windows) > 0
+
+	peak, start, end, over := ContractExcessSpan(windows, contract.MaxDemandKW)
+	if !projected {
+		peak = DemandWindow{Start: req.ScheduledStart, KW: totalCurrentPower(devices)}
+		start, end, over = req.ScheduledStart, req.ScheduledEnd, peak.KW > contract.MaxDemandKW
+	}
+
+	peakAt := peak.Start
+	penalty := &models.ContractPenaltySavings{
+		MaxDemandKW:     contract.MaxDemandKW,
+		WindowMinutes:   int(window / time.Minute),
+		ProjectedPeakKW: roundKW(peak.KW),
+		PeakAt:          &peakAt,
+		Currency:        contract.Currency,
+		Forecast:        projected,
+	}
+	if !over {
+		return nil, penalty
+	}
+
+	// Devices that may shed load, as for any other peak shaving scenario
+	occupied := occupancy == nil || occupancy.Occupied
+	var candidates []ContractShedding
+	for _, candidate := range s.optimizableDevices(ctx, devices, req.Constraints, occupied, authToken) {
+		if candidate.DeviceType.SupportsCommand("REDUCE_POWER") && candidate.Device.CurrentPower > 0 {
+			candidates = append(candidates, ContractShedding{Device: candidate.Device, DeviceType: candidate.DeviceType})
+		}
+	}
+
+	duration := int(math.Ceil(end.Sub(start).Minutes()))
+	var actions []models.OptimizationAction
+	for _, shedding := range AllocateContractShedding(candidates, peak.KW-contract.MaxDemandKW) {
+		device := shedding.Device
+		actions = append(actions, models.OptimizationAction{
+			ID:             uuid.New().String()[:8],
+			DeviceID:       device.DeviceID,
+			DeviceName:     "Device " + device.DeviceID,
+			DeviceType:     shedding.DeviceType.Name,
+			ActionType:     "REDUCE_POWER",
+			CurrentValue:   fmt.Sprintf("%.1f kW", device.CurrentPower),
+			TargetValue:    fmt.Sprintf("%.1f kW", device.CurrentPower-shedding.ReductionKW),
+			ScheduledTime:  start,
+			Duration:       duration,
+			Status:         "PENDING",
+			ExpectedImpact: roundKW(shedding.ReductionKW),
+		})
+	}
+
+	return actions, penalty
+}
+
+// ContractDemandWindows averages load predictions, each covering step, over the measurement
+// windows of a demand contract between from and to. Predictions longer than the window are their
+// own window, since their average load holds throughout them.
+func ContractDemandWindows(predictions []models.ForecastPrediction, step, window time.Duration, from, to time.Time) []DemandWindow {
+	sums := make(map[int64]float64)
+	counts := make(map[int64]int)
+	var starts []time.Time
+	for _, prediction := range predictions {
+		if prediction.Timestamp.Before(from) || !prediction.Timestamp.Before(to) {
+			continue
+		}
+		start := prediction.Timestamp
+		if step < window {
+			start = start.Truncate(window)
+		}
+		if counts[start.Unix()] == 0 {
+			starts = append(starts, start)
+		}
+		sums[start.Unix()] += prediction.PredictedValue
+		counts[start.Unix()]++
+	}
+
+	length := window
+	if step > window {
+		length = step
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	windows := make([]DemandWindow, len(starts))
+	for i, start := range starts {
+		windows[i] = DemandWindow{Start: start, Length: length, KW: sums[start.Unix()] / float64(counts[start.Unix()])}
+	}
+	return windows
+}
+
+// ContractExcessSpan returns the highest demand window and the span from the start of the first
+// window above the contracted limit to the end of the last. over is false when no window exceeds
+// the limit.
+func ContractExcessSpan(windows []DemandWindow, limitKW float64) (peak DemandWindow, start, end time.Time, over bool) {
+	for i, w := range windows {
+		if i == 0 || w.KW > peak.KW {
+			peak = w
+		}
+		if w.KW <= limitKW {
+			continue
+		}
+		if !over {
+			start = w.Start
+			over = true
+		}
+		end = w.Start.Add(w.Length)
+	}
+	return peak, start, end, over
+}
+
+// AllocateContractShedding spreads the load to shed over devices, largest current power first,
+// each shedding at most 30% of its power. Devices are only used while load is left to shed, so
+// the last one may shed less than it could; when every device is used the excess is not covered.
+func AllocateContractShedding(candidates []ContractShedding, excessKW float64) []ContractShedding {
+	sorted := make([]ContractShedding, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Device.CurrentPower > sorted[j].Device.CurrentPower
+	})
+
+	var allocated []ContractShedding
+	remaining := excessKW
+	for _, candidate := range sorted {
+		if remaining <= 1e-6 {
+			break
+		}
+		candidate.ReductionKW = math.Min(candidate.Device.CurrentPower*maxContractShedShare, remaining)
+		remaining -= candidate.ReductionKW
+		allocated = append(allocated, candidate)
+	}
+	return allocated
+}
+
+// ContractPenalty completes the projected penalty of a scenario with the effect of its actions.
+// The peak is lowered by the reductions of the actions running when it is projected.
+func ContractPenalty(contract *models.DemandContract, penalty *models.ContractPenaltySavings, actions []models.OptimizationAction) *models.ContractPenaltySavings {
+	reduction := 0.0
+	for _, action := range actions {
+		actionEnd := action.ScheduledTime.Add(time.Duration(action.Duration) * time.Minute)
+		if penalty.PeakAt == nil || (!penalty.PeakAt.Before(action.ScheduledTime) && penalty.PeakAt.Before(actionEnd)) {
+			reduction += action.ExpectedImpact
+		}
+	}
+
+	result := *penalty
+	result.ShavedPeakKW = roundKW(math.Max(0, penalty.ProjectedPeakKW-reduction))
+	result.ExcessKW = roundKW(math.Max(0, penalty.ProjectedPeakKW-contract.MaxDemandKW))
+	result.RemainingExcess = roundKW(math.Max(0, result.ShavedPeakKW-contract.MaxDemandKW))
+	result.PenaltyBefore = roundAmount(contract.Penalty(penalty.ProjectedPeakKW))
+	result.PenaltyAfter = roundAmount(contract.Penalty(result.ShavedPeakKW))
+	result.PenaltyAvoided = roundAmount(result.PenaltyBefore - result.PenaltyAfter)
+	return &result
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"forecast-service/internal/models"
+)
+
+// ExportScenario converts a scenario to the portable format used to reuse it in other buildings
+func (s *OptimizationService) ExportScenario(ctx context.Context, scenarioID string) (*models.ScenarioExport, error) {
+	scenario, err := s.optimizationRepo.FindByID(ctx, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	if scenario.Type == models.OptimizationTypePortfolio {
+		return nil, fmt.Errorf("validation failed: portfolio scenarios cannot be exported, export their building scenarios instead")
+	}
+
+	export := &models.ScenarioExport{
+		FormatVersion:    models.ScenarioExportFormatVersion,
+		Name:             scenario.Name,
+		Description:      scenario.Description,
+		Type:             scenario.Type,
+		Priority:         scenario.Priority,
+		DurationMinutes:  int(scenario.ScheduledEnd.Sub(scenario.ScheduledStart).Minutes()),
+		SourceBuildingID: scenario.BuildingID,
+		Constraints:      scenario.Constraints,
+		Actions:          make([]models.ScenarioExportAction, 0, len(scenario.Actions)),
+		ExpectedSavings:  scenario.ExpectedSavings,
+		ExportedAt:       time.Now(),
+	}
+	for _, action := range scenario.Actions {
+		export.Actions = append(export.Actions, models.ScenarioExportAction{
+			DeviceID:       action.DeviceID,
+			DeviceName:     action.DeviceName,
+			DeviceType:     action.DeviceType,
+			ActionType:     action.ActionType,
+			TargetValue:    action.TargetValue,
+			OffsetMinutes:  int(action.ScheduledTime.Sub(scenario.ScheduledStart).Minutes()),
+			Duration:       action.Duration,
+			ExpectedImpact: action.ExpectedImpact,
+		})
+	}
+
+	return export, nil
+}
+
+// ImportScenario creates a draft scenario in the target building from an exported scenario.
+// Device IDs are re-mapped through the request's mapping table, and the actions and constraints
+// are validated against the building's devices before anything is stored.
+func (s *OptimizationService) ImportScenario(ctx context.Context, req *models.ScenarioImportRequest, userID, authToken string) (*models.OptimizationScenarioResponse, error) {
+	export := req.Scenario
+	if export.FormatVersion != models.ScenarioExportFormatVersion {
+		return nil, fmt.Errorf("validation failed: unsupported scenario format version %d", export.FormatVersion)
+	}
+	if export.Type == models.OptimizationTypePortfolio {
+		return nil, fmt.Errorf("validation failed: portfolio scenarios cannot be imported")
+	}
+
+	start := req.ScheduledStart
+	if start.IsZero() {
+		start = time.Now().Add(time.Hour)
+	}
+	duration := time.Duration(export.DurationMinutes) * time.Minute
+	if duration <= 0 {
+		duration = 8 * time.Hour
+	}
+	name := req.Name
+	if name == "" {
+		name = export.Name
+	}
+	priority := export.Priority
+	if priority <= 0 {
+		priority = 5
+	}
+
+	mapDevice := func(deviceID string) string {
+		if mapped, ok := req.DeviceMapping[deviceID]; ok && mapped != "" {
+			return mapped
+		}
+		return deviceID
+	}
+
+	constraints := export.Constraints
+	constraints.ExcludeDevices = make([]string, 0, len(export.Constraints.ExcludeDevices))
+	for _, deviceID := range export.Constraints.ExcludeDevices {
+		constraints.ExcludeDevices = append(constraints.ExcludeDevices, mapDevice(deviceID))
+	}
+
+	actions := make([]models.OptimizationAction, 0, len(export.Actions))
+	for _, action := range export.Actions {
+		actions = append(actions, models.OptimizationAction{
+			ID:             uuid.New().String()[:8],
+			DeviceID:       mapDevice(action.DeviceID),
+			DeviceName:     action.DeviceName,
+			DeviceType:     action.DeviceType,
+			ActionType:     action.ActionType,
+			TargetValue:    action.TargetValue,
+			ScheduledTime:  start.Add(time.Duration(action.OffsetMinutes) * time.Minute),
+			Duration:       action.Duration,
+			Status:         "PENDING",
+			ExpectedImpact: action.ExpectedImpact,
+		})
+	}
+
+	devices, err := s.iotClient.GetDevicesByBuilding(ctx, req.BuildingID, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devices of the target building: %w", err)
+	}
+
+	schedule := s.occupancyService.GetSchedule(ctx, req.BuildingID)
+	if problems := s.validateImportedScenario(ctx, req.BuildingID, actions, constraints, devices, schedule, start, authToken); len(problems) > 0 {
+		return nil, fmt.Errorf("validation failed: %s", strings.Join(problems, "; "))
+	}
+
+	for i := range actions {
+		if actions[i].DeviceName == "" {
+			actions[i].DeviceName = "Device " + actions[i].DeviceID
+		}
+	}
+
+	tariffData, _ := s.tariffService.GetCurrentTariff(ctx, "default", authToken)
+	baselineKW := totalCurrentPower(devices)
+	savings := s.calculateExpectedSavings(ctx, actions, tariffData, baselineKW)
+
+	description := export.Description
+	if description == "" {
+		description = s.generateScenarioDescription(export.Type, actions, savings)
+	}
+
+	scenario := &models.OptimizationScenario{
+		BuildingID:      req.BuildingID,
+		Name:            name,
+		Description:     description,
+		Type:            export.Type,
+		Status:          models.OptimizationStatusDraft,
+		ScheduledStart:  start,
+		ScheduledEnd:    start.Add(duration),
+		Actions:         actions,
+		ExpectedSavings: savings,
+		Constraints:     constraints,
+		Priority:        priority,
+		TariffData:      tariffData,
+		BaselineLoadKW:  baselineKW,
+		CreatedBy:       userID,
+	}
+
+	createdScenario, err := s.optimizationRepo.Create(ctx, scenario)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scenario: %w", err)
+	}
+
+	return s.responseWithConflicts(ctx, createdScenario), nil
+}
+
+// validateImportedScenario checks re-mapped actions and constraints against the devices of the
+// target building and returns every problem found
+func (s *OptimizationService) validateImportedScenario(
+	ctx context.Context,
+	buildingID string,
+	actions []models.OptimizationAction,
+	constraints models.OptimizationConstraints,
+	devices []models.DeviceState,
+	schedule *models.OccupancySchedule,
+	start time.Time,
+	authToken string,
+) []string {
+	var problems []string
+
+	if constraints.MinTemperature != nil && constraints.MaxTemperature != nil && *constraints.MinTemperature > *constraints.MaxTemperature {
+		problems = append(problems, "minimum temperature is above maximum temperature")
+	}
+	for _, window := range constraints.TimeWindows {
+		if !validClock(window.StartTime) || !validClock(window.EndTime) {
+			problems = append(problems, fmt.Sprintf("time window %s-%s must use the HH:MM format", window.StartTime, window.EndTime))
+		}
+	}
+	if len(problems) == 0 && len(constraints.TimeWindows) > 0 && !timeWindowsAllow(constraints.TimeWindows, schedule, start) {
+		problems = append(problems, "scheduled start is outside the scenario's time windows")
+	}
+
+	byID := make(map[string]models.DeviceState, len(devices))
+	for _, device := range devices {
+		byID[device.DeviceID] = device
+	}
+	excluded := make(map[string]bool, len(constraints.ExcludeDevices))
+	for _, deviceID := range constraints.ExcludeDevices {
+		excluded[deviceID] = true
+	}
+
+	for _, action := range actions {
+		device, ok := byID[action.DeviceID]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("device %s is not in building %s; map it to a device of the building", action.DeviceID, buildingID))
+			continue
+		}
+		if !device.Controllable {
+			problems = append(problems, fmt.Sprintf("device %s is not controllable", action.DeviceID))
+		}
+		if excluded[action.DeviceID] {
+			problems = append(problems, fmt.Sprintf("device %s is excluded by the scenario's constraints", action.DeviceID))
+		}
+		if action.DeviceType != "" {
+			if deviceType := s.deviceTypes.Resolve(ctx, device, authToken); deviceType.Name != action.DeviceType {
+				problems = append(problems, fmt.Sprintf("device %s is a %s, not a %s", action.DeviceID, deviceType.Name, action.DeviceType))
+			}
+		}
+		if action.ActionType == "SET_TEMP" {
+			setpoint, err := strconv.ParseFloat(strings.TrimSuffix(action.TargetValue, "°C"), 64)
+			switch {
+			case err != nil:
+				problems = append(problems, fmt.Sprintf("target temperature %q of device %s is not a number", action.TargetValue, action.DeviceID))
+			case constraints.MinTemperature != nil && setpoint < *constraints.MinTemperature,
+				constraints.MaxTemperature != nil && setpoint > *constraints.MaxTemperature:
+				problems = append(problems, fmt.Sprintf("target temperature %s of device %s is outside the temperature constraints", action.TargetValue, action.DeviceID))
+			}
+		}
+	}
+
+	return problems
+}
+
+// validClock checks that a time of day uses the HH:MM format
+func validClock(clock string) bool {
+	_, err := time.Parse("15:04", clock)
+	return err == nil
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+)
+
+const (
+	// minCorrectionSamples is the number of verified scenarios a correction factor needs before
+	// it scales the expected savings of new scenarios
+	minCorrectionSamples = 3
+
+	// maxRealizationRatio caps the share of its expected reduction credited to an action from a
+	// single verification, so one outlying report cannot dominate a factor
+	maxRealizationRatio = 2.0
+)
+
+// OptimizationFeedbackService learns from verified savings how much of the expected demand
+// reduction each action type delivers per device type and device, and corrects the expected
+// savings of new scenarios with what it learned
+type OptimizationFeedbackService struct {
+	factorRepo       *repository.CorrectionFactorRepository
+	optimizationRepo *repository.OptimizationRepository
+}
+
+// NewOptimizationFeedbackService creates a new optimization feedback service
+func NewOptimizationFeedbackService(factorRepo *repository.CorrectionFactorRepository, optimizationRepo *repository.OptimizationRepository) *OptimizationFeedbackService {
+	return &OptimizationFeedbackService{
+		factorRepo:       factorRepo,
+		optimizationRepo: optimizationRepo,
+	}
+}
+
+// ReconcileScenario stores verified savings as the actual savings of a scenario. Option A
+// verifications also update the correction factors: the actions change device settings until they
+// are changed again, so the measured drop in the mean demand of the changed devices is compared
+// with the reduction their actions were expected to deliver. The verification cannot tell the
+// actions apart, so each is credited with the same share of its expected reduction. A scenario
+// updates the factors only once.
+func (s *OptimizationFeedbackService) ReconcileScenario(ctx context.Context, verified *models.VerifiedSavings) error {
+	scenario, err := s.optimizationRepo.FindByID(ctx, verified.ScenarioID)
+	if err != nil {
+		return err
+	}
+
+	updates := bson.M{
+		"actual_savings": &models.Savings{
+			EnergyKWh:        verified.SavingsKWh,
+			Currency:         scenario.ExpectedSavings.Currency,
+			CO2ReductionKg:   math.Round(verified.SavingsKWh*0.4*100) / 100,
+			PercentReduction: verified.SavingsPercent,
+		},
+	}
+
+	if verified.Option == "A" && scenario.Realization == nil {
+		realization, actions := s.learn(ctx, scenario, verified)
+		if realization != nil {
+			updates["realization"] = realization
+			updates["actions"] = actions
+		}
+	}
+
+	_, err = s.optimizationRepo.Update(ctx, verified.ScenarioID, updates)
+	return err
+}
+
+// learn records the realization of a scenario's actions on the verified devices in the factors of
+// their action types and returns it with the actions' actual impacts set. It returns nil when
+// the actions were not expected to reduce demand.
+func (s *OptimizationFeedbackService) learn(ctx context.Context, scenario *models.OptimizationScenario, verified *models.VerifiedSavings) (*models.SavingsRealization, []models.OptimizationAction) {
+	devices := make(map[string]bool, len(verified.DeviceIDs))
+	for _, deviceID := range verified.DeviceIDs {
+		devices[deviceID] = true
+	}
+
+	expected := 0.0
+	for _, action := range scenario.Actions {
+		if devices[action.DeviceID] {
+			expected += action.ExpectedImpact
+		}
+	}
+	if expected <= 0 {
+		return nil, nil
+	}
+
+	actual := verified.BaselineMeanKWh - verified.ReportingMeanKWh
+	ratio := math.Max(0, math.Min(actual/expected, maxRealizationRatio))
+
+	actions := make([]models.OptimizationAction, len(scenario.Actions))
+	copy(actions, scenario.Actions)
+	for i := range actions {
+		action := &actions[i]
+		if !devices[action.DeviceID] || action.ExpectedImpact <= 0 {
+			continue
+		}
+
+		impact := roundKW(action.ExpectedImpact * ratio)
+		action.ActualImpact = &impact
+
+		for _, deviceID := range []string{"", action.DeviceID} {
+			if err := s.factorRepo.Record(ctx, action.ActionType, action.DeviceType, deviceID, action.ExpectedImpact, action.ExpectedImpact*ratio, verified.ScenarioID); err != nil {
+				log.Printf("Failed to record correction factor of %s on %s: %v", action.ActionType, action.DeviceType, err)
+			}
+		}
+	}
+
+	return &models.SavingsRealization{
+		ReportID:     verified.ReportID,
+		ExpectedKW:   roundKW(expected),
+		ActualKW:     roundKW(actual),
+		Ratio:        math.Round(ratio*10000) / 10000,
+		ReconciledAt: time.Now(),
+	}, actions
+}
+
+// Correct returns the actions with their expected impacts scaled by the learned correction
+// factors. The factor of the action type on the device is used when it has enough samples, else
+// the factor on the device type; actions without either are left as estimated. The actions are
+// returned unchanged when the factors cannot be read.
+func (s *OptimizationFeedbackService) Correct(ctx context.Context, actions []models.OptimizationAction) []models.OptimizationAction {
+	var actionTypes []string
+	seen := make(map[string]bool)
+	for _, action := range actions {
+		if !seen[action.ActionType] {
+			seen[action.ActionType] = true
+			actionTypes = append(actionTypes, action.ActionType)
+		}
+	}
+	if len(actionTypes) == 0 {
+		return actions
+	}
+
+	factors, err := s.factorRepo.FindByActionTypes(ctx, actionTypes)
+	if err != nil {
+		log.Printf("Failed to read correction factors, using uncorrected savings: %v", err)
+		return actions
+	}
+	byKey := make(map[string]*models.CorrectionFactor, len(factors))
+	for _, factor := range factors {
+		if factor.Samples >= minCorrectionSamples {
+			byKey[correctionKey(factor.ActionType, factor.DeviceType, factor.DeviceID)] = factor
+		}
+	}
+	if len(byKey) == 0 {
+		return actions
+	}
+
+	corrected := make([]models.OptimizationAction, len(actions))
+	copy(corrected, actions)
+	for i := range corrected {
+		action := &corrected[i]
+		if action.ExpectedImpact <= 0 {
+			continue
+		}
+		factor, ok := byKey[correctionKey(action.ActionType, action.DeviceType, action.DeviceID)]
+		if !ok {
+			factor, ok = byKey[correctionKey(action.ActionType, action.DeviceType, "")]
+		}
+		if ok {
+			action.ExpectedImpact *= factor.Factor()
+		}
+	}
+	return corrected
+}
+
+// ListFactors retrieves the learned correction factors matching the query
+func (s *OptimizationFeedbackService) ListFactors(ctx context.Context, query *models.CorrectionFactorQuery) ([]*models.CorrectionFactorResponse, error) {
+	factors, err := s.factorRepo.FindAll(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read correction factors: %w", err)
+	}
+
+	responses := make([]*models.CorrectionFactorResponse, len(factors))
+	for i, factor := range factors {
+		responses[i] = &models.CorrectionFactorResponse{
+			ActionType:     factor.ActionType,
+			DeviceType:     factor.DeviceType,
+			DeviceID:       factor.DeviceID,
+			Factor:         math.Round(factor.Factor()*10000) / 10000,
+			ExpectedKW:     roundKW(factor.ExpectedKW),
+			ActualKW:       roundKW(factor.ActualKW),
+			Samples:        factor.Samples,
+			Applied:        factor.Samples >= minCorrectionSamples,
+			LastScenarioID: factor.LastScenarioID,
+			UpdatedAt:      factor.UpdatedAt,
+		}
+	}
+	return responses, nil
+}
+
+// correctionKey identifies the factor of an action type on a device type, or on a device
+func correctionKey(actionType, deviceType, deviceID string) string {
+	return actionType + "|" + deviceType + "|" + deviceID
+}
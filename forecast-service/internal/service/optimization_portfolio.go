@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"forecast-service/internal/models"
+)
+
+// Sources of the load a portfolio target is distributed by
+const (
+	portfolioLoadForecast = "FORECAST"
+	portfolioLoadCurrent  = "CURRENT"
+	portfolioLoadNone     = "NONE"
+)
+
+// GeneratePortfolio generates a PORTFOLIO scenario that spreads a curtailment target across several
+// buildings, e.g. a utility demand response event across a campus. Each building gets a draft child
+// scenario sized to a share of the target proportional to its forecast load; what a building cannot
+// shed is handed to the others.
+func (s *OptimizationService) GeneratePortfolio(ctx context.Context, req *models.PortfolioGenerateRequest, userID, authToken string) (*models.OptimizationScenarioResponse, error) {
+	buildingIDs := uniqueStrings(req.BuildingIDs)
+	if len(buildingIDs) < 2 {
+		return nil, fmt.Errorf("a portfolio needs at least two buildings")
+	}
+	childType := req.Type
+	if childType == "" {
+		childType = models.OptimizationTypeDemandResponse
+	}
+	if childType == models.OptimizationTypePortfolio {
+		return nil, fmt.Errorf("portfolio scenarios cannot contain portfolio scenarios")
+	}
+	if req.ScheduledStart.IsZero() {
+		req.ScheduledStart = time.Now().Add(time.Hour)
+	}
+	if req.ScheduledEnd.IsZero() {
+		req.ScheduledEnd = req.ScheduledStart.Add(8 * time.Hour)
+	}
+	if req.Priority <= 0 {
+		req.Priority = 5
+	}
+	name := req.Name
+	if name == "" {
+		name = fmt.Sprintf("Portfolio %s - %s", childType, req.ScheduledStart.Format("2006-01-02 15:04"))
+	}
+
+	// Build every building's candidate scenario first; the actions it could take bound its share
+	children := make([]*models.OptimizationScenario, len(buildingIDs))
+	allocations := make([]models.PortfolioAllocation, len(buildingIDs))
+	loads := make([]float64, len(buildingIDs))
+	capacities := make([]float64, len(buildingIDs))
+	for i, buildingID := range buildingIDs {
+		child, err := s.buildScenario(ctx, &models.OptimizationGenerateRequest{
+			BuildingID:     buildingID,
+			Name:           fmt.Sprintf("%s - %s", name, buildingID),
+			Type:           childType,
+			ScheduledStart: req.ScheduledStart,
+			ScheduledEnd:   req.ScheduledEnd,
+			UseTariffData:  req.UseTariffData,
+			Constraints:    req.Constraints,
+			Priority:       req.Priority,
+		}, userID, authToken, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate scenario for building %s: %w", buildingID, err)
+		}
+		children[i] = child
+
+		load, source := s.forecastLoad(ctx, buildingID, req.ScheduledStart, req.ScheduledEnd, child.BaselineLoadKW)
+		loads[i] = load
+		for _, action := range child.Actions {
+			capacities[i] += action.ExpectedImpact
+		}
+		allocations[i] = models.PortfolioAllocation{
+			BuildingID:     buildingID,
+			ForecastLoadKW: math.Round(load*100) / 100,
+			LoadSource:     source,
+			Status:         models.OptimizationStatusDraft,
+		}
+	}
+
+	totalLoad := 0.0
+	for _, load := range loads {
+		totalLoad += load
+	}
+	targets := allocateTarget(req.TargetReductionKW, loads, capacities)
+
+	portfolio := &models.Portfolio{
+		BuildingIDs:       buildingIDs,
+		ChildType:         childType,
+		TargetReductionKW: req.TargetReductionKW,
+		Allocations:       allocations,
+	}
+	parent := &models.OptimizationScenario{
+		Name:           name,
+		Type:           models.OptimizationTypePortfolio,
+		Status:         models.OptimizationStatusDraft,
+		ScheduledStart: req.ScheduledStart,
+		ScheduledEnd:   req.ScheduledEnd,
+		Actions:        []models.OptimizationAction{},
+		Constraints:    req.Constraints,
+		Priority:       req.Priority,
+		Portfolio:      portfolio,
+		CreatedBy:      userID,
+	}
+	createdParent, err := s.optimizationRepo.Create(ctx, parent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scenario: %w", err)
+	}
+	parentID := createdParent.ID.Hex()
+
+	var savings []models.Savings
+	for i, child := range children {
+		child.Actions = selectActionsForTarget(child.Actions, targets[i])
+		child.ExpectedSavings = s.calculateExpectedSavings(ctx, child.Actions, child.TariffData, child.BaselineLoadKW)
+		child.Description = s.generateScenarioDescription(child.Type, child.Actions, child.ExpectedSavings)
+		child.ParentScenarioID = parentID
+
+		createdChild, err := s.optimizationRepo.Create(ctx, child)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scenario for building %s: %w", child.BuildingID, err)
+		}
+
+		expected := 0.0
+		for _, action := range createdChild.Actions {
+			expected += action.ExpectedImpact
+		}
+		portfolio.Allocations[i].ScenarioID = createdChild.ID.Hex()
+		portfolio.Allocations[i].TargetReductionKW = math.Round(targets[i]*100) / 100
+		portfolio.Allocations[i].ExpectedReductionKW = math.Round(expected*100) / 100
+		if totalLoad > 0 {
+			portfolio.Allocations[i].Share = math.Round(loads[i]/totalLoad*1000) / 1000
+		} else {
+			portfolio.Allocations[i].Share = math.Round(1/float64(len(children))*1000) / 1000
+		}
+		portfolio.ExpectedReductionKW += expected
+		savings = append(savings, createdChild.ExpectedSavings)
+	}
+	portfolio.ExpectedReductionKW = math.Round(portfolio.ExpectedReductionKW*100) / 100
+	portfolio.ShortfallKW = math.Round(math.Max(req.TargetReductionKW-portfolio.ExpectedReductionKW, 0)*100) / 100
+
+	expectedSavings := sumSavings(savings)
+	if totalLoad > 0 {
+		expectedSavings.PercentReduction = math.Round(portfolio.ExpectedReductionKW/totalLoad*1000) / 10
+	}
+	description := fmt.Sprintf("Curtail %.1f kW across %d buildings in proportion to their forecast load (%.1f kW expected).",
+		req.TargetReductionKW, len(buildingIDs), portfolio.ExpectedReductionKW)
+	if portfolio.ShortfallKW > 0 {
+		description += fmt.Sprintf(" The buildings cannot shed the remaining %.1f kW.", portfolio.ShortfallKW)
+	}
+
+	updated, err := s.optimizationRepo.Update(ctx, parentID, bson.M{
+		"portfolio":        portfolio,
+		"expected_savings": expectedSavings,
+		"description":      description,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update scenario: %w", err)
+	}
+	return updated.ToResponse(), nil
+}
+
+// forecastLoad returns a building's average forecast load over a period in kW, from its latest
+// hourly forecast. Without predictions for the period, the current load of its devices stands in.
+func (s *OptimizationService) forecastLoad(ctx context.Context, buildingID string, start, end time.Time, currentKW float64) (float64, string) {
+	if forecast, err := s.forecastRepo.FindLatestByBuilding(ctx, buildingID, ""); err == nil {
+		sum, count := 0.0, 0
+		for _, prediction := range forecast.Predictions {
+			if !prediction.Timestamp.Before(start) && prediction.Timestamp.Before(end) {
+				sum += prediction.PredictedValue
+				count++
+			}
+		}
+		if count > 0 {
+			return sum / float64(count), portfolioLoadForecast
+		}
+	}
+	if currentKW > 0 {
+		return currentKW, portfolioLoadCurrent
+	}
+	return 0, portfolioLoadNone
+}
+
+// allocateTarget splits a target across buildings in proportion to their load, or equally when no
+// load is known. A building's share is capped at its capacity and the excess is split the same
+// way among the buildings with capacity left.
+func allocateTarget(target float64, loads, capacities []float64) []float64 {
+	allocated := make([]float64, len(loads))
+	open := make([]int, 0, len(loads))
+	for i := range loads {
+		if capacities[i] > 0 {
+			open = append(open, i)
+		}
+	}
+
+	remaining := target
+	for remaining > 1e-6 && len(open) > 0 {
+		totalLoad := 0.0
+		for _, i := range open {
+			totalLoad += loads[i]
+		}
+
+		distributed := 0.0
+		stillOpen := open[:0]
+		for _, i := range open {
+			share := 1 / float64(len(open))
+			if totalLoad > 0 {
+				share = loads[i] / totalLoad
+			}
+			amount := math.Min(remaining*share, capacities[i]-allocated[i])
+			allocated[i] += amount
+			distributed += amount
+			if capacities[i]-allocated[i] > 1e-6 {
+				stillOpen = append(stillOpen, i)
+			}
+		}
+		open = stillOpen
+		remaining -= distributed
+		if distributed <= 1e-6 {
+			break
+		}
+	}
+
+	// Buildings without capacity still get their nominal share, so the allocation shows the gap
+	for i := range allocated {
+		if capacities[i] > 0 {
+			continue
+		}
+		totalLoad := 0.0
+		for _, load := range loads {
+			totalLoad += load
+		}
+		if totalLoad > 0 {
+			allocated[i] = target * loads[i] / totalLoad
+		}
+	}
+	return allocated
+}
+
+// selectActionsForTarget keeps the largest actions until their expected impact reaches the target
+func selectActionsForTarget(actions []models.OptimizationAction, targetKW float64) []models.OptimizationAction {
+	sorted := append([]models.OptimizationAction(nil), actions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ExpectedImpact > sorted[j].ExpectedImpact })
+
+	selected := []models.OptimizationAction{}
+	reduction := 0.0
+	for _, action := range sorted {
+		if reduction >= targetKW-1e-6 {
+			break
+		}
+		selected = append(selected, action)
+		reduction += action.ExpectedImpact
+	}
+	return selected
+}
+
+// sumSavings adds up the expected savings of several scenarios
+func sumSavings(savings []models.Savings) models.Savings {
+	var total models.Savings
+	for _, s := range savings {
+		total.EnergyKWh += s.EnergyKWh
+		total.CostAmount += s.CostAmount
+		total.CO2ReductionKg += s.CO2ReductionKg
+		if total.Currency == "" {
+			total.Currency = s.Currency
+		}
+	}
+	total.EnergyKWh = math.Round(total.EnergyKWh*100) / 100
+	total.CostAmount = math.Round(total.CostAmount*100) / 100
+	total.CO2ReductionKg = math.Round(total.CO2ReductionKg*100) / 100
+	return total
+}
+
+// refreshPortfolio updates the child statuses of a portfolio scenario and derives its own status
+// from them. The updated status is stored when it changed.
+func (s *OptimizationService) refreshPortfolio(ctx context.Context, parent *models.OptimizationScenario) {
+	children, err := s.optimizationRepo.FindByParent(ctx, parent.ID.Hex())
+	if err != nil {
+		log.Printf("Failed to load child scenarios of portfolio %s: %v", parent.ID.Hex(), err)
+		return
+	}
+
+	statuses := make(map[string]models.OptimizationStatus, len(children))
+	for _, child := range children {
+		statuses[child.ID.Hex()] = child.Status
+	}
+	for i := range parent.Portfolio.Allocations {
+		if status, ok := statuses[parent.Portfolio.Allocations[i].ScenarioID]; ok {
+			parent.Portfolio.Allocations[i].Status = status
+		}
+	}
+
+	status, errorMsg := portfolioStatus(children, parent.Status)
+	if status == parent.Status && errorMsg == parent.ErrorMessage {
+		return
+	}
+	parent.Status = status
+	parent.ErrorMessage = errorMsg
+	if err := s.optimizationRepo.UpdateStatus(ctx, parent.ID.Hex(), status, errorMsg); err != nil {
+		log.Printf("Failed to update status of portfolio %s: %v", parent.ID.Hex(), err)
+	}
+}
+
+// portfolioStatus derives a portfolio's status from its child scenarios. It is executing while any
+// child executes and finished once all are; while children await approval or sending it keeps its
+// current status.
+func portfolioStatus(children []*models.OptimizationScenario, current models.OptimizationStatus) (models.OptimizationStatus, string) {
+	counts := make(map[models.OptimizationStatus]int)
+	for _, child := range children {
+		counts[child.Status]++
+	}
+	finished := counts[models.OptimizationStatusCompleted] + counts[models.OptimizationStatusFailed] + counts[models.OptimizationStatusCancelled]
+
+	switch {
+	case len(children) == 0:
+		return current, ""
+	case counts[models.OptimizationStatusExecuting] > 0:
+		return models.OptimizationStatusExecuting, ""
+	case finished < len(children):
+		return current, ""
+	case counts[models.OptimizationStatusCompleted] > 0 && counts[models.OptimizationStatusFailed] > 0:
+		return models.OptimizationStatusCompleted, fmt.Sprintf("%d of %d building scenarios failed", counts[models.OptimizationStatusFailed], len(children))
+	case counts[models.OptimizationStatusCompleted] > 0:
+		return models.OptimizationStatusCompleted, ""
+	case counts[models.OptimizationStatusFailed] > 0:
+		return models.OptimizationStatusFailed, "all building scenarios failed"
+	}
+	return models.OptimizationStatusCancelled, ""
+}
+
+// sendPortfolioToIoT sends every child scenario of a portfolio that awaits execution to the IoT
+// service and combines their results. The portfolio executes once any child was sent.
+func (s *OptimizationService) sendPortfolioToIoT(ctx context.Context, parent *models.OptimizationScenario, req *models.SendToIoTRequest, userID, authToken string) (*models.SendToIoTResponse, error) {
+	children, err := s.optimizationRepo.FindByParent(ctx, parent.ID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load child scenarios: %w", err)
+	}
+
+	response := &models.SendToIoTResponse{ScenarioID: req.ScenarioID}
+	var executionIDs []string
+	sent := 0
+	for _, child := range children {
+		if child.Status != models.OptimizationStatusDraft && child.Status != models.OptimizationStatusApproved {
+			continue
+		}
+
+		childReq := *req
+		childReq.ScenarioID = child.ID.Hex()
+		childResp, err := s.SendToIoT(ctx, &childReq, userID, authToken)
+		if err != nil {
+			response.Errors = append(response.Errors, fmt.Sprintf("building %s: %v", child.BuildingID, err))
+			continue
+		}
+
+		response.ActionsQueued += childResp.ActionsQueued
+		response.ActionsSkipped += childResp.ActionsSkipped
+		response.ActionsDropped += childResp.ActionsDropped
+		response.Conflicts = append(response.Conflicts, childResp.Conflicts...)
+		response.CancelledScenarios = append(response.CancelledScenarios, childResp.CancelledScenarios...)
+		if childResp.Preview != nil {
+			if response.Preview == nil {
+				response.Preview = &models.ScenarioPreview{Actions: []models.PreviewAction{}}
+			}
+			response.Preview.Add(childResp.Preview)
+		}
+		for _, msg := range childResp.Errors {
+			response.Errors = append(response.Errors, fmt.Sprintf("building %s: %s", child.BuildingID, msg))
+		}
+		if childResp.Success {
+			sent++
+			if childResp.ExecutionID != "" {
+				executionIDs = append(executionIDs, childResp.ExecutionID)
+			}
+		}
+	}
+
+	response.Success = sent > 0
+	response.ExecutionID = strings.Join(executionIDs, ",")
+	if sent > 0 && !req.DryRun {
+		s.optimizationRepo.UpdateStatus(ctx, req.ScenarioID, models.OptimizationStatusExecuting, "")
+		s.optimizationRepo.AddExecutionLog(ctx, req.ScenarioID, models.ExecutionLogEntry{
+			Level:   "INFO",
+			Message: fmt.Sprintf("Sent %d of %d building scenarios to IoT service", sent, len(children)),
+		})
+	}
+	return response, nil
+}
+
+// uniqueStrings returns the non-empty values in their original order without duplicates
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	return unique
+}
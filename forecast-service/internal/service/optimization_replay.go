@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/tenant"
+)
+
+// maxReplayDays is the longest period a strategy can be replayed over
+const maxReplayDays = 31
+
+// ReplayOptimization simulates what an optimization strategy would have done in a building over a
+// past period and compares it with what actually happened. Device states are reconstructed from
+// each device's recorded hourly consumption and the strategy is re-planned every hour with the
+// rules used to generate scenarios, the occupancy schedule, time windows and learned correction
+// factors. The actual and simulated load are priced with the tariff in effect in each billing
+// month, and the savings are compared with those of the scenarios executed in the period.
+func (s *OptimizationService) ReplayOptimization(ctx context.Context, req *models.OptimizationReplayRequest, authToken string) (*models.OptimizationReplay, error) {
+	if !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
+
+	from := req.From.Truncate(time.Hour)
+	to := req.To.Truncate(time.Hour)
+	if !to.After(from) {
+		return nil, fmt.Errorf("validation failed: to must be at least an hour after from")
+	}
+	if to.After(time.Now()) {
+		return nil, fmt.Errorf("validation failed: the replay period must have ended")
+	}
+	if to.Sub(from) > maxReplayDays*24*time.Hour {
+		return nil, fmt.Errorf("validation failed: the replay period may be at most %d days", maxReplayDays)
+	}
+	region := req.Region
+	if region == "" {
+		region = "default"
+	}
+
+	devices, err := s.iotClient.GetDevicesByBuilding(ctx, req.BuildingID, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devices: %w", err)
+	}
+	if len(req.DeviceTags) > 0 {
+		devices = devicesWithTags(devices, req.DeviceTags)
+	}
+
+	history, err := s.externalClient.GetHistoricalConsumption(ctx, req.BuildingID, "", from, to, "HOURLY", authToken)
+	if err != nil {
+		return nil, fmt.Errorf("consumption history unavailable: %w", err)
+	}
+	observed := hourlyLoad(history.DataPoints)
+
+	loc := s.occupancyService.Location(ctx, req.BuildingID)
+	schedule := s.occupancyService.GetSchedule(ctx, req.BuildingID)
+
+	// Hours the strategy may act in, and whether the building was scheduled to be occupied
+	occupiedAt := make(map[int64]bool)
+	var hours []time.Time
+	for hour := from; hour.Before(to); hour = hour.Add(time.Hour) {
+		if len(req.Constraints.TimeWindows) > 0 && !timeWindowsAllow(req.Constraints.TimeWindows, schedule, hour) {
+			continue
+		}
+		hours = append(hours, hour)
+		occupiedAt[hour.Unix()] = schedule.IsOccupied(hour)
+	}
+
+	result := &models.OptimizationReplay{
+		BuildingID:        req.BuildingID,
+		Type:              req.Type,
+		Region:            region,
+		TimeZone:          loc.String(),
+		From:              from,
+		To:                to,
+		Hours:             int(to.Sub(from).Hours()),
+		Devices:           []models.ReplayDevice{},
+		ExecutedScenarios: []models.ReplayExecutedScenario{},
+		GeneratedAt:       time.Now(),
+	}
+
+	// Occupancy changes from hour to hour, so candidates are taken as if the building were empty
+	// and occupant-facing devices are skipped in occupied hours below
+	var actions []models.OptimizationAction
+	deviceLoads := make(map[string]map[int64]float64)
+	replayed := make(map[string]*models.ReplayDevice)
+	var order []string
+	for _, candidate := range s.optimizableDevices(ctx, devices, req.Constraints, false, authToken) {
+		deviceID := candidate.Device.DeviceID
+		deviceHistory, err := s.externalClient.GetHistoricalConsumption(ctx, req.BuildingID, deviceID, from, to, "HOURLY", authToken)
+		if err != nil || len(deviceHistory.DataPoints) == 0 {
+			result.DevicesWithoutHistory = append(result.DevicesWithoutHistory, deviceID)
+			continue
+		}
+		load := hourlyLoad(deviceHistory.DataPoints)
+		deviceLoads[deviceID] = load
+
+		device := &models.ReplayDevice{DeviceID: deviceID, DeviceType: candidate.DeviceType.Name}
+		for _, kwh := range load {
+			device.ActualKWh += kwh
+		}
+		replayed[deviceID] = device
+		order = append(order, deviceID)
+
+		occupantFacing := s.isHVACDevice(candidate.DeviceType) || s.isLightingDevice(candidate.DeviceType)
+		for _, hour := range hours {
+			kw, ok := load[hour.Unix()]
+			if !ok {
+				continue
+			}
+			occupied := occupiedAt[hour.Unix()]
+			if req.Constraints.OccupancyRequired && occupied && occupantFacing {
+				continue
+			}
+
+			// Hourly kWh equals the average kW of the hour
+			state := candidate.Device
+			state.CurrentPower = kw
+			action := s.createActionForDevice(req.Type, state, candidate.DeviceType, nil, occupied, req.Constraints, hour)
+			if action == nil {
+				continue
+			}
+			// The strategy is re-planned every hour, so each action lasts the hour
+			action.Duration = 60
+			actions = append(actions, *action)
+		}
+	}
+
+	savings := make(map[int64]float64)
+	for _, action := range s.feedbackService.Correct(ctx, actions) {
+		// A device cannot save more than it used
+		saved := math.Min(action.ExpectedImpact, deviceLoads[action.DeviceID][action.ScheduledTime.Unix()])
+		if saved <= 0 {
+			continue
+		}
+		savings[action.ScheduledTime.Unix()] += saved
+
+		device := replayed[action.DeviceID]
+		device.ActionType = action.ActionType
+		device.SavedKWh += saved
+		device.ActiveHours++
+	}
+	for _, deviceID := range order {
+		device := replayed[deviceID]
+		device.ActualKWh = roundAmount(device.ActualKWh)
+		device.SavedKWh = roundAmount(device.SavedKWh)
+		result.Devices = append(result.Devices, *device)
+	}
+	sort.SliceStable(result.Devices, func(i, j int) bool {
+		return result.Devices[i].SavedKWh > result.Devices[j].SavedKWh
+	})
+
+	actual, simulated := ReplayProfiles(from, to, observed, savings)
+	result.ObservedHours = len(actual)
+	result.Hourly = make([]models.ReplayHour, len(actual))
+	for i := range actual {
+		result.ActualKWh += actual[i].EnergyKWh
+		result.SimulatedKWh += simulated[i].EnergyKWh
+		result.Hourly[i] = models.ReplayHour{
+			Start:        actual[i].Start,
+			ActualKWh:    roundKW(actual[i].EnergyKWh),
+			SimulatedKWh: roundKW(simulated[i].EnergyKWh),
+		}
+	}
+
+	costSaved := s.priceReplay(ctx, result, loc, actual, simulated, authToken)
+	result.Simulated = replaySavings(result.ActualKWh-result.SimulatedKWh, costSaved, result.ActualKWh)
+	result.ActualKWh = roundAmount(result.ActualKWh)
+	result.SimulatedKWh = roundAmount(result.SimulatedKWh)
+
+	// What the scenarios that actually ran saved
+	executed, err := s.optimizationRepo.FindCompletedInPeriod(ctx, req.BuildingID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executed scenarios: %w", err)
+	}
+	var realizedKWh, realizedCost float64
+	for _, scenario := range executed {
+		scenarioSavings, verified := scenario.ExpectedSavings, false
+		if scenario.ActualSavings != nil {
+			scenarioSavings, verified = *scenario.ActualSavings, true
+		}
+		realizedKWh += scenarioSavings.EnergyKWh
+		realizedCost += scenarioSavings.CostAmount
+		result.ExecutedScenarios = append(result.ExecutedScenarios, models.ReplayExecutedScenario{
+			ScenarioID:     scenario.ID.Hex(),
+			Name:           scenario.Name,
+			Type:           scenario.Type,
+			ScheduledStart: scenario.ScheduledStart,
+			ScheduledEnd:   scenario.ScheduledEnd,
+			EnergyKWh:      scenarioSavings.EnergyKWh,
+			CostAmount:     scenarioSavings.CostAmount,
+			Verified:       verified,
+		})
+	}
+	result.Realized = replaySavings(realizedKWh, realizedCost, result.ActualKWh)
+	result.Missed = replaySavings(result.Simulated.EnergyKWh-result.Realized.EnergyKWh, result.Simulated.CostAmount-result.Realized.CostAmount, result.ActualKWh)
+
+	return result, nil
+}
+
+// priceReplay prices the savings of a replay with the tariff in effect in each billing month, the
+// calendar month in the building's time zone, so seasonal rates and monthly demand charges apply
+// as they were billed. Months the Analytics service cost engine cannot price are priced at the
+// tariff's hourly energy rates, and the replay is then marked as priced by energy rate.
+func (s *OptimizationService) priceReplay(ctx context.Context, result *models.OptimizationReplay, loc *time.Location, actual, simulated []models.LoadInterval, authToken string) float64 {
+	result.PricedBy = models.ReplayPricedByCostEngine
+	total := 0.0
+
+	for start := 0; start < len(actual); {
+		local := actual[start].Start.In(loc)
+		end := start
+		for end < len(actual) {
+			next := actual[end].Start.In(loc)
+			if next.Year() != local.Year() || next.Month() != local.Month() {
+				break
+			}
+			end++
+		}
+
+		tariff, err := s.tariffService.GetTariffAt(ctx, result.Region, local, authToken)
+		if err != nil {
+			log.Printf("Failed to get tariff for replay of building %s, using the default rate: %v", result.BuildingID, err)
+			tariff = &models.Tariff{
+				Region:      result.Region,
+				BaseRate:    defaultEnergyRate,
+				CurrentRate: defaultEnergyRate,
+				PeakRate:    defaultEnergyRate,
+				OffPeakRate: defaultEnergyRate,
+				Currency:    "USD",
+			}
+		}
+		if result.Currency == "" {
+			result.Currency = tariff.Currency
+			result.TariffSource = tariff.Source
+		}
+
+		comparison, err := s.analyticsClient.CompareCosts(ctx, &models.CostComparisonRequest{
+			Tariff:    tariff,
+			Baseline:  actual[start:end],
+			Optimized: simulated[start:end],
+		})
+		if err != nil {
+			log.Printf("Failed to price replay with the analytics cost engine, using energy rates: %v", err)
+			result.PricedBy = models.ReplayPricedByEnergyRate
+			total += ReplayEnergyCost(tariff, loc, actual[start:end], simulated[start:end])
+		} else {
+			total += comparison.TotalSavings
+		}
+
+		start = end
+	}
+
+	return total
+}
+
+// ReplayProfiles builds the hourly actual and simulated load profiles of a replay from the
+// building's recorded consumption and the strategy's savings per hour, both keyed by the Unix
+// time of the hour. Hours without recorded consumption are left out, and savings never take an
+// hour's consumption below zero.
+func ReplayProfiles(from, to time.Time, observed, savings map[int64]float64) ([]models.LoadInterval, []models.LoadInterval) {
+	var actual, simulated []models.LoadInterval
+	for hour := from; hour.Before(to); hour = hour.Add(time.Hour) {
+		kwh, ok := observed[hour.Unix()]
+		if !ok {
+			continue
+		}
+		actual = append(actual, models.LoadInterval{Start: hour, Minutes: 60, EnergyKWh: kwh})
+		simulated = append(simulated, models.LoadInterval{Start: hour, Minutes: 60, EnergyKWh: math.Max(kwh-savings[hour.Unix()], 0)})
+	}
+	return actual, simulated
+}
+
+// ReplayEnergyCost prices the energy a replay saved at the tariff's rate in each hour, in the
+// building's time zone, without demand charges
+func ReplayEnergyCost(tariff *models.Tariff, loc *time.Location, actual, simulated []models.LoadInterval) float64 {
+	total := 0.0
+	for i := range actual {
+		total += (actual[i].EnergyKWh - simulated[i].EnergyKWh) * hourlyRate(tariff, actual[i].Start.In(loc))
+	}
+	return total
+}
+
+// hourlyLoad sums consumption data points by the hour they fall in, keyed by the hour's Unix time
+func hourlyLoad(points []models.ConsumptionDataPoint) map[int64]float64 {
+	load := make(map[int64]float64, len(points))
+	for _, point := range points {
+		load[point.Timestamp.Truncate(time.Hour).Unix()] += point.Value
+	}
+	return load
+}
+
+// replaySavings rounds the energy and cost saved over a replay and relates them to the building's
+// consumption over the period
+func replaySavings(energyKWh, costAmount, consumedKWh float64) models.ReplaySavings {
+	savings := models.ReplaySavings{
+		EnergyKWh:      roundAmount(energyKWh),
+		CostAmount:     roundAmount(costAmount),
+		CO2ReductionKg: roundAmount(energyKWh * 0.4), // Approximate kg CO2 per kWh
+	}
+	if consumedKWh > 0 {
+		savings.PercentReduction = roundAmount(energyKWh / consumedKWh * 100)
+	}
+	return savings
+}
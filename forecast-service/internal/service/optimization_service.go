@@ -3,14 +3,20 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
+	"forecast-service/internal/config"
 	"forecast-service/internal/integrations"
 	"forecast-service/internal/models"
 	"forecast-service/internal/repository"
+	"forecast-service/internal/settings"
+	"forecast-service/internal/tenant"
+	"forecast-service/internal/tracing"
 )
 
 // OptimizationService handles optimization scenario business logic
@@ -21,6 +27,17 @@ type OptimizationService struct {
 	iotClient          *integrations.IoTClient
 	externalClient     *integrations.ExternalClient
 	securityClient     *integrations.SecurityClient
+	analyticsClient    *integrations.AnalyticsClient
+	tariffService      *TariffService
+	occupancyService   *OccupancyService
+	feedbackService    *OptimizationFeedbackService
+	templateRepo       *repository.ScenarioTemplateRepository
+	contractRepo       *repository.DemandContractRepository
+	deviceTypes        *DeviceTypeCatalog
+
+	// clampToCapabilities moves action targets outside a device's capabilities to the limit
+	// instead of rejecting the scenario
+	clampToCapabilities *settings.Bool
 }
 
 // NewOptimizationService creates a new optimization service
@@ -31,6 +48,14 @@ func NewOptimizationService(
 	iotClient *integrations.IoTClient,
 	externalClient *integrations.ExternalClient,
 	securityClient *integrations.SecurityClient,
+	analyticsClient *integrations.AnalyticsClient,
+	tariffService *TariffService,
+	occupancyService *OccupancyService,
+	feedbackService *OptimizationFeedbackService,
+	templateRepo *repository.ScenarioTemplateRepository,
+	contractRepo *repository.DemandContractRepository,
+	settingsStore *settings.Store,
+	cfg *config.Config,
 ) *OptimizationService {
 	return &OptimizationService{
 		optimizationRepo:   optimizationRepo,
@@ -39,22 +64,77 @@ func NewOptimizationService(
 		iotClient:          iotClient,
 		externalClient:     externalClient,
 		securityClient:     securityClient,
+		analyticsClient:    analyticsClient,
+		tariffService:      tariffService,
+		occupancyService:   occupancyService,
+		feedbackService:    feedbackService,
+		templateRepo:       templateRepo,
+		contractRepo:       contractRepo,
+		deviceTypes:        NewDeviceTypeCatalog(iotClient),
+		clampToCapabilities: settingsStore.RegisterBool("optimization.clamp_to_capabilities",
+			"Clamp action targets outside a device's capabilities instead of rejecting the scenario", cfg.Optimization.ClampToCapabilities),
 	}
 }
 
 // GenerateOptimization generates an optimization scenario
 func (s *OptimizationService) GenerateOptimization(ctx context.Context, req *models.OptimizationGenerateRequest, userID, authToken string) (*models.OptimizationScenarioResponse, error) {
+	scenario, err := s.buildScenario(ctx, req, userID, authToken, true)
+	if err != nil {
+		return nil, err
+	}
+
+	createdScenario, err := s.optimizationRepo.Create(ctx, scenario)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scenario: %w", err)
+	}
+
+	return s.responseWithConflicts(ctx, createdScenario), nil
+}
+
+// buildScenario generates the actions and expected savings of a draft scenario without storing it.
+// With useContract, a PEAK_SHAVING scenario targets the building's contracted maximum demand.
+func (s *OptimizationService) buildScenario(ctx context.Context, req *models.OptimizationGenerateRequest, userID, authToken string, useContract bool) (*models.OptimizationScenario, error) {
+	if !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
+
+	// Scheduled times without a UTC offset are wall clock times of the building
+	if req.HasWallClockTimes() {
+		req.ApplyTimeZone(s.occupancyService.Location(ctx, req.BuildingID))
+	}
+
+	// A template fills in what the request leaves unset, including when the scenario runs
+	if req.TemplateID != "" {
+		if err := s.applyTemplate(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	if req.Type == "" {
+		return nil, fmt.Errorf("validation failed: type is required unless a template is given")
+	}
+
 	// Set defaults
 	if req.ScheduledStart.IsZero() {
 		req.ScheduledStart = time.Now().Add(time.Hour)
 	}
 	if req.ScheduledEnd.IsZero() {
 		req.ScheduledEnd = req.ScheduledStart.Add(8 * time.Hour)
+		if req.Type == models.OptimizationTypeBatteryDispatch {
+			req.ScheduledEnd = req.ScheduledStart.Add(batteryDispatchDefaultSpan)
+		}
 	}
 	if req.Priority <= 0 {
 		req.Priority = 5
 	}
 
+	// Time windows are evaluated against the business calendar, so holidays count as weekend days
+	if len(req.Constraints.TimeWindows) > 0 {
+		schedule := s.occupancyService.GetSchedule(ctx, req.BuildingID)
+		if !timeWindowsAllow(req.Constraints.TimeWindows, schedule, req.ScheduledStart) {
+			return nil, fmt.Errorf("validation failed: scheduled start is outside the scenario's time windows")
+		}
+	}
+
 	// Generate scenario name if not provided
 	name := req.Name
 	if name == "" {
@@ -77,11 +157,14 @@ func (s *OptimizationService) GenerateOptimization(ctx context.Context, req *mod
 		// Continue without device states, use simulated data
 		devices = s.generateSimulatedDevices(req.BuildingID)
 	}
+	if len(req.DeviceTags) > 0 {
+		devices = devicesWithTags(devices, req.DeviceTags)
+	}
 
-	// Fetch tariff data if requested
+	// Fetch tariff data if requested; battery dispatch is driven by the tariff's rates
 	var tariffData *models.Tariff
-	if req.UseTariffData {
-		tariffData, _ = s.externalClient.GetCurrentTariff(ctx, "default", authToken)
+	if req.UseTariffData || req.Type == models.OptimizationTypeBatteryDispatch {
+		tariffData, _ = s.tariffService.GetCurrentTariff(ctx, "default", authToken)
 	}
 
 	// Fetch weather data if requested
@@ -90,11 +173,41 @@ func (s *OptimizationService) GenerateOptimization(ctx context.Context, req *mod
 		weatherData, _ = s.externalClient.GetCurrentWeather(ctx, req.BuildingID, authToken)
 	}
 
+	// Resolve occupancy at the scheduled start (schedule, or live sensors when starting now)
+	occupancy := s.occupancyService.GetOccupancyStatus(ctx, req.BuildingID, req.ScheduledStart, authToken)
+
+	// Peak shaving keeps buildings with a demand contract under their contracted maximum
+	var contract *models.DemandContract
+	if useContract && req.Type == models.OptimizationTypePeakShaving {
+		contract = s.demandContract(ctx, req.BuildingID)
+	}
+
 	// Generate optimization actions based on type
-	actions := s.generateOptimizationActions(req.Type, devices, forecast, tariffData, req.Constraints, req.ScheduledStart)
+	var actions []models.OptimizationAction
+	var contractPenalty *models.ContractPenaltySavings
+	if req.Type == models.OptimizationTypeBatteryDispatch {
+		actions, err = s.generateBatteryDispatchActions(ctx, req, devices, tariffData, authToken)
+		if err != nil {
+			return nil, err
+		}
+	} else if contract != nil {
+		actions, contractPenalty = s.generateContractShavingActions(ctx, contract, devices, forecast, occupancy, req, authToken)
+	} else {
+		actions = s.generateOptimizationActions(ctx, req.Type, devices, forecast, tariffData, occupancy, req.Constraints, req.ScheduledStart, authToken)
+	}
 
-	// Calculate expected savings
-	expectedSavings := s.calculateExpectedSavings(actions, tariffData)
+	// Targets beyond what a device supports are rejected, or clamped to the limit when configured
+	capabilityWarnings, err := s.enforceCapabilities(ctx, actions, authToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate expected savings against the building's current load
+	baselineKW := totalCurrentPower(devices)
+	expectedSavings := s.calculateExpectedSavings(ctx, actions, tariffData, baselineKW)
+	if contractPenalty != nil {
+		expectedSavings.ContractPenalty = ContractPenalty(contract, contractPenalty, actions)
+	}
 
 	// Generate description
 	description := s.generateScenarioDescription(req.Type, actions, expectedSavings)
@@ -113,7 +226,117 @@ func (s *OptimizationService) GenerateOptimization(ctx context.Context, req *mod
 		Constraints:     req.Constraints,
 		Priority:        req.Priority,
 		TariffData:      tariffData,
+		BaselineLoadKW:  baselineKW,
 		WeatherData:     weatherData,
+		TemplateID:      req.TemplateID,
+		CreatedBy:       userID,
+		CapabilityWarnings: capabilityWarnings,
+	}
+
+	return scenario, nil
+}
+
+// GeneratePreConditioningScenario creates an EFFICIENCY scenario that pre-cools or pre-heats HVAC
+// ahead of a peak window and relaxes the setpoint while the peak lasts, shifting load off-peak
+func (s *OptimizationService) GeneratePreConditioningScenario(ctx context.Context, plan *models.PreConditioningPlan, userID, authToken string) (*models.OptimizationScenarioResponse, error) {
+	devices, err := s.iotClient.GetDevicesByBuilding(ctx, plan.BuildingID, authToken)
+	if err != nil {
+		devices = s.generateSimulatedDevices(plan.BuildingID)
+	}
+
+	tariffData, _ := s.tariffService.GetCurrentTariff(ctx, "default", authToken)
+
+	preStart := plan.PeakStart.Add(-time.Duration(plan.LeadHours) * time.Hour)
+	peakHours := plan.PeakEnd.Sub(plan.PeakStart).Hours()
+
+	var actions []models.OptimizationAction
+	var shiftedKWh, peakLoadKWh float64
+	for _, device := range devices {
+		if !device.Controllable {
+			continue
+		}
+		deviceType := s.deviceTypes.Resolve(ctx, device, authToken)
+		if !s.isHVACDevice(deviceType) {
+			continue
+		}
+
+		// Pre-conditioning draws extra power before the peak so less is needed during it
+		extraPower := device.CurrentPower * 0.15
+		peakReduction := device.CurrentPower * 0.3
+
+		actions = append(actions,
+			models.OptimizationAction{
+				ID:             uuid.New().String()[:8],
+				DeviceID:       device.DeviceID,
+				DeviceName:     "Device " + device.DeviceID,
+				DeviceType:     deviceType.Name,
+				ActionType:     "SET_TEMP",
+				CurrentValue:   "22°C",
+				TargetValue:    fmt.Sprintf("%.1f°C", s.clampSetpoint(deviceType, plan.TargetTemperature)),
+				ScheduledTime:  preStart,
+				Duration:       plan.LeadHours * 60,
+				Status:         "PENDING",
+				ExpectedImpact: -extraPower,
+			},
+			models.OptimizationAction{
+				ID:             uuid.New().String()[:8],
+				DeviceID:       device.DeviceID,
+				DeviceName:     "Device " + device.DeviceID,
+				DeviceType:     deviceType.Name,
+				ActionType:     "SET_TEMP",
+				CurrentValue:   fmt.Sprintf("%.1f°C", s.clampSetpoint(deviceType, plan.TargetTemperature)),
+				TargetValue:    fmt.Sprintf("%.1f°C", s.clampSetpoint(deviceType, plan.PeakTemperature)),
+				ScheduledTime:  plan.PeakStart,
+				Duration:       int(plan.PeakEnd.Sub(plan.PeakStart).Minutes()),
+				Status:         "PENDING",
+				ExpectedImpact: peakReduction,
+			},
+		)
+
+		shiftedKWh += peakReduction * peakHours
+		peakLoadKWh += device.CurrentPower * peakHours
+	}
+
+	// Savings come from the price difference between the peak and the pre-conditioning period
+	// and from the lower demand during the peak
+	baselineKW := totalCurrentPower(devices)
+	savings := s.calculateExpectedSavings(ctx, actions, tariffData, baselineKW)
+	savings.PercentReduction = 0
+	if peakLoadKWh > 0 {
+		savings.PercentReduction = math.Round(shiftedKWh/peakLoadKWh*1000) / 10
+	}
+
+	verb := "Pre-cooling"
+	if plan.Action == models.AutomationActionPreHeat {
+		verb = "Pre-heating"
+	}
+	name := fmt.Sprintf("%s before %s peak", verb, plan.PeakStart.Format("2006-01-02 15:04"))
+	if plan.RuleName != "" {
+		name = fmt.Sprintf("%s (%s)", name, plan.RuleName)
+	}
+
+	scenario := &models.OptimizationScenario{
+		BuildingID: plan.BuildingID,
+		Name:       name,
+		Description: fmt.Sprintf(
+			"%s HVAC to %.1f°C from %s, relaxing to %.1f°C during the %s-%s peak. %s",
+			verb,
+			plan.TargetTemperature,
+			preStart.Format("15:04"),
+			plan.PeakTemperature,
+			plan.PeakStart.Format("15:04"),
+			plan.PeakEnd.Format("15:04"),
+			s.generateScenarioDescription(models.OptimizationTypeEfficiency, actions, savings),
+		),
+		Type:            models.OptimizationTypeEfficiency,
+		Status:          models.OptimizationStatusDraft,
+		ScheduledStart:  preStart,
+		ScheduledEnd:    plan.PeakEnd,
+		Actions:         actions,
+		ExpectedSavings: savings,
+		Priority:        5,
+		TariffData:      tariffData,
+		BaselineLoadKW:  baselineKW,
 		CreatedBy:       userID,
 	}
 
@@ -122,31 +345,78 @@ func (s *OptimizationService) GenerateOptimization(ctx context.Context, req *mod
 		return nil, fmt.Errorf("failed to create scenario: %w", err)
 	}
 
-	return createdScenario.ToResponse(), nil
+	return s.responseWithConflicts(ctx, createdScenario), nil
+}
+
+// responseWithConflicts converts a new scenario to a response listing the active scenarios it
+// would clash with, so they can be resolved before approval
+func (s *OptimizationService) responseWithConflicts(ctx context.Context, scenario *models.OptimizationScenario) *models.OptimizationScenarioResponse {
+	response := scenario.ToResponse().InLocation(s.occupancyService.Location(ctx, scenario.BuildingID))
+
+	conflicts, _, err := s.detectConflicts(ctx, scenario)
+	if err != nil {
+		log.Printf("Failed to check conflicts for scenario %s: %v", scenario.ID.Hex(), err)
+		return response
+	}
+	response.Conflicts = conflicts
+	return response
 }
 
 // generateSimulatedDevices creates simulated device states for demo
 func (s *OptimizationService) generateSimulatedDevices(buildingID string) []models.DeviceState {
 	return []models.DeviceState{
-		{DeviceID: "hvac-1", Status: "ONLINE", CurrentPower: 25.5, CurrentState: "ON", Controllable: true},
-		{DeviceID: "hvac-2", Status: "ONLINE", CurrentPower: 22.0, CurrentState: "ON", Controllable: true},
-		{DeviceID: "lighting-1", Status: "ONLINE", CurrentPower: 5.2, CurrentState: "ON", Controllable: true},
-		{DeviceID: "lighting-2", Status: "ONLINE", CurrentPower: 4.8, CurrentState: "ON", Controllable: true},
-		{DeviceID: "equipment-1", Status: "ONLINE", CurrentPower: 15.0, CurrentState: "ON", Controllable: false},
+		{DeviceID: "hvac-1", Type: "HVAC", Status: "ONLINE", CurrentPower: 25.5, CurrentState: "ON", Controllable: true},
+		{DeviceID: "hvac-2", Type: "HVAC", Status: "ONLINE", CurrentPower: 22.0, CurrentState: "ON", Controllable: true},
+		{DeviceID: "lighting-1", Type: "LIGHTING", Status: "ONLINE", CurrentPower: 5.2, CurrentState: "ON", Controllable: true},
+		{DeviceID: "lighting-2", Type: "LIGHTING", Status: "ONLINE", CurrentPower: 4.8, CurrentState: "ON", Controllable: true},
+		{DeviceID: "equipment-1", Type: "EQUIPMENT", Status: "ONLINE", CurrentPower: 15.0, CurrentState: "ON", Controllable: false},
 	}
 }
 
 // generateOptimizationActions generates optimization actions based on type
 func (s *OptimizationService) generateOptimizationActions(
+	ctx context.Context,
 	optType models.OptimizationType,
 	devices []models.DeviceState,
 	forecast *models.Forecast,
 	tariff *models.Tariff,
+	occupancy *models.OccupancyStatus,
 	constraints models.OptimizationConstraints,
 	startTime time.Time,
+	authToken string,
 ) []models.OptimizationAction {
+	// Without occupancy information assume the building is in use
+	occupied := occupancy == nil || occupancy.Occupied
+
 	var actions []models.OptimizationAction
 
+	for _, candidate := range s.optimizableDevices(ctx, devices, constraints, occupied, authToken) {
+		action := s.createActionForDevice(optType, candidate.Device, candidate.DeviceType, tariff, occupied, constraints, startTime)
+		if action != nil {
+			actions = append(actions, *action)
+		}
+	}
+
+	return actions
+}
+
+// optimizableDevice is a device a scenario may act on, with its resolved type
+type optimizableDevice struct {
+	Device     models.DeviceState
+	DeviceType *models.DeviceType
+}
+
+// optimizableDevices returns the controllable devices the constraints do not exclude. Occupant-
+// facing devices are left alone while the building is occupied if the constraints require it.
+func (s *OptimizationService) optimizableDevices(
+	ctx context.Context,
+	devices []models.DeviceState,
+	constraints models.OptimizationConstraints,
+	occupied bool,
+	authToken string,
+) []optimizableDevice {
+	var candidates []optimizableDevice
+
 	for _, device := range devices {
 		if !device.Controllable {
 			continue
@@ -164,20 +434,26 @@ func (s *OptimizationService) generateOptimizationActions(
 			continue
 		}
 
-		action := s.createActionForDevice(optType, device, tariff, constraints, startTime)
-		if action != nil {
-			actions = append(actions, *action)
+		deviceType := s.deviceTypes.Resolve(ctx, device, authToken)
+
+		// Occupant-facing devices are left alone while the building is occupied
+		if constraints.OccupancyRequired && occupied && (s.isHVACDevice(deviceType) || s.isLightingDevice(deviceType)) {
+			continue
 		}
+
+		candidates = append(candidates, optimizableDevice{Device: device, DeviceType: deviceType})
 	}
 
-	return actions
+	return candidates
 }
 
 // createActionForDevice creates an optimization action for a specific device
 func (s *OptimizationService) createActionForDevice(
 	optType models.OptimizationType,
 	device models.DeviceState,
+	deviceType *models.DeviceType,
 	tariff *models.Tariff,
+	occupied bool,
 	constraints models.OptimizationConstraints,
 	startTime time.Time,
 ) *models.OptimizationAction {
@@ -185,13 +461,13 @@ func (s *OptimizationService) createActionForDevice(
 
 	switch optType {
 	case models.OptimizationTypeCostReduction:
-		if device.CurrentPower > 10 {
+		if device.CurrentPower > 10 && deviceType.SupportsCommand("REDUCE_POWER") {
 			reduction := device.CurrentPower * 0.15
 			return &models.OptimizationAction{
 				ID:             actionID,
 				DeviceID:       device.DeviceID,
 				DeviceName:     "Device " + device.DeviceID,
-				DeviceType:     s.inferDeviceType(device.DeviceID),
+				DeviceType:     deviceType.Name,
 				ActionType:     "REDUCE_POWER",
 				CurrentValue:   fmt.Sprintf("%.1f kW", device.CurrentPower),
 				TargetValue:    fmt.Sprintf("%.1f kW", device.CurrentPower-reduction),
@@ -203,12 +479,12 @@ func (s *OptimizationService) createActionForDevice(
 		}
 
 	case models.OptimizationTypePeakShaving:
-		if device.CurrentPower > 15 {
+		if device.CurrentPower > 15 && deviceType.SupportsCommand("REDUCE_POWER") {
 			return &models.OptimizationAction{
 				ID:             actionID,
 				DeviceID:       device.DeviceID,
 				DeviceName:     "Device " + device.DeviceID,
-				DeviceType:     s.inferDeviceType(device.DeviceID),
+				DeviceType:     deviceType.Name,
 				ActionType:     "REDUCE_POWER",
 				CurrentValue:   fmt.Sprintf("%.1f kW", device.CurrentPower),
 				TargetValue:    fmt.Sprintf("%.1f kW", device.CurrentPower*0.7),
@@ -220,15 +496,16 @@ func (s *OptimizationService) createActionForDevice(
 		}
 
 	case models.OptimizationTypeEfficiency:
-		if s.isHVACDevice(device.DeviceID) && !constraints.PreserveComfort {
+		// Comfort only needs preserving while someone is in the building
+		if s.isHVACDevice(deviceType) && (!constraints.PreserveComfort || !occupied) {
 			return &models.OptimizationAction{
 				ID:             actionID,
 				DeviceID:       device.DeviceID,
 				DeviceName:     "Device " + device.DeviceID,
-				DeviceType:     "HVAC",
+				DeviceType:     deviceType.Name,
 				ActionType:     "SET_TEMP",
 				CurrentValue:   "22°C",
-				TargetValue:    "24°C",
+				TargetValue:    fmt.Sprintf("%.0f°C", s.clampSetpoint(deviceType, 24)),
 				ScheduledTime:  startTime,
 				Duration:       120,
 				Status:         "PENDING",
@@ -237,11 +514,14 @@ func (s *OptimizationService) createActionForDevice(
 		}
 
 	case models.OptimizationTypeDemandResponse:
+		if !deviceType.SupportsCommand("CURTAIL") {
+			return nil
+		}
 		return &models.OptimizationAction{
 			ID:             actionID,
 			DeviceID:       device.DeviceID,
 			DeviceName:     "Device " + device.DeviceID,
-			DeviceType:     s.inferDeviceType(device.DeviceID),
+			DeviceType:     deviceType.Name,
 			ActionType:     "CURTAIL",
 			CurrentValue:   fmt.Sprintf("%.1f kW", device.CurrentPower),
 			TargetValue:    fmt.Sprintf("%.1f kW", device.CurrentPower*0.5),
@@ -255,55 +535,204 @@ func (s *OptimizationService) createActionForDevice(
 	return nil
 }
 
-// inferDeviceType infers device type from ID
-func (s *OptimizationService) inferDeviceType(deviceID string) string {
-	if s.isHVACDevice(deviceID) {
-		return "HVAC"
+// isHVACDevice reports whether a device type controls temperature
+func (s *OptimizationService) isHVACDevice(deviceType *models.DeviceType) bool {
+	return deviceType.SupportsCommand("SET_TEMP")
+}
+
+// isLightingDevice reports whether a device type controls light levels
+func (s *OptimizationService) isLightingDevice(deviceType *models.DeviceType) bool {
+	return deviceType.SupportsCommand("SET_BRIGHTNESS")
+}
+
+// timeWindowsAllow checks whether t falls in one of the time windows, in the building's time zone.
+// Windows without days apply every day. On a holiday only windows listing Holiday, Saturday or
+// Sunday apply, so a public holiday is treated like a weekend.
+func timeWindowsAllow(windows []models.TimeWindow, schedule *models.OccupancySchedule, t time.Time) bool {
+	days := calendarDayNames(schedule, t)
+	clock := t.In(schedule.Location()).Format("15:04")
+
+	for _, window := range windows {
+		if !windowIncludesDay(window.DaysOfWeek, days) {
+			continue
+		}
+		if window.StartTime <= window.EndTime {
+			if clock >= window.StartTime && clock < window.EndTime {
+				return true
+			}
+		} else if clock >= window.StartTime || clock < window.EndTime {
+			// Window spans midnight
+			return true
+		}
 	}
-	if s.isLightingDevice(deviceID) {
-		return "LIGHTING"
+	return false
+}
+
+// calendarDayNames returns the day names a time falls on in the building's time zone: its
+// weekday, or Holiday and the weekend days on holidays of the business calendar
+func calendarDayNames(schedule *models.OccupancySchedule, t time.Time) []string {
+	if schedule.HolidayAt(t) != nil {
+		return []string{"Holiday", time.Saturday.String(), time.Sunday.String()}
 	}
-	return "EQUIPMENT"
+	return []string{t.In(schedule.Location()).Weekday().String()}
 }
 
-func (s *OptimizationService) isHVACDevice(deviceID string) bool {
-	return len(deviceID) > 4 && deviceID[:4] == "hvac"
+// windowIncludesDay checks whether a time window's days include any of the given day names
+func windowIncludesDay(windowDays, days []string) bool {
+	if len(windowDays) == 0 {
+		return true
+	}
+	for _, windowDay := range windowDays {
+		for _, day := range days {
+			if strings.EqualFold(windowDay, day) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-func (s *OptimizationService) isLightingDevice(deviceID string) bool {
-	return len(deviceID) > 8 && deviceID[:8] == "lighting"
+// clampSetpoint keeps a temperature setpoint within the device type's default constraints
+func (s *OptimizationService) clampSetpoint(deviceType *models.DeviceType, setpoint float64) float64 {
+	if minTemp, ok := deviceType.Constraint("minTemperature"); ok && setpoint < minTemp {
+		return minTemp
+	}
+	if maxTemp, ok := deviceType.Constraint("maxTemperature"); ok && setpoint > maxTemp {
+		return maxTemp
+	}
+	return setpoint
 }
 
-// calculateExpectedSavings calculates expected savings from actions
-func (s *OptimizationService) calculateExpectedSavings(actions []models.OptimizationAction, tariff *models.Tariff) models.Savings {
+// calculateExpectedSavings calculates expected savings from actions. The cost is priced by the
+// Analytics service cost engine, with time-of-use rates and demand charges, so that savings match
+// the costs analytics reports; when it cannot be reached the current rate is applied instead.
+// Expected impacts are first corrected by how much of them similar actions delivered before.
+func (s *OptimizationService) calculateExpectedSavings(ctx context.Context, actions []models.OptimizationAction, tariff *models.Tariff, baselineKW float64) models.Savings {
+	var uncorrectedKWh float64
+	for _, action := range actions {
+		uncorrectedKWh += action.ExpectedImpact * (float64(action.Duration) / 60)
+	}
+
+	actions = s.feedbackService.Correct(ctx, actions)
 	var totalEnergyKWh float64
 	for _, action := range actions {
 		energySaved := action.ExpectedImpact * (float64(action.Duration) / 60)
 		totalEnergyKWh += energySaved
 	}
 
-	rate := 0.15 // Default rate
-	currency := "USD"
-	if tariff != nil {
-		rate = tariff.CurrentRate
-		currency = tariff.Currency
+	if tariff == nil {
+		tariff = &models.Tariff{
+			Region:      "default",
+			BaseRate:    defaultEnergyRate,
+			CurrentRate: defaultEnergyRate,
+			PeakRate:    defaultEnergyRate,
+			OffPeakRate: defaultEnergyRate,
+			Currency:    "USD",
+		}
 	}
+	rate := tariff.CurrentRate
+	currency := tariff.Currency
 
 	costSaved := totalEnergyKWh * rate
+	if baseline, optimized := loadProfiles(actions, baselineKW); len(baseline) > 0 {
+		comparison, err := s.analyticsClient.CompareCosts(ctx, &models.CostComparisonRequest{
+			Tariff:    tariff,
+			Baseline:  baseline,
+			Optimized: optimized,
+		})
+		if err != nil {
+			log.Printf("Failed to price savings with the analytics cost engine, using the current rate: %v", err)
+		} else {
+			costSaved = comparison.TotalSavings
+			if comparison.Currency != "" {
+				currency = comparison.Currency
+			}
+		}
+	}
+
 	co2Reduction := totalEnergyKWh * 0.4 // Approximate kg CO2 per kWh
 
-	return models.Savings{
+	savings := models.Savings{
 		EnergyKWh:        math.Round(totalEnergyKWh*100) / 100,
 		CostAmount:       math.Round(costSaved*100) / 100,
 		Currency:         currency,
 		CO2ReductionKg:   math.Round(co2Reduction*100) / 100,
 		PercentReduction: 12.5, // Estimated
 	}
+	if uncorrected := math.Round(uncorrectedKWh*100) / 100; uncorrected != savings.EnergyKWh {
+		savings.UncorrectedEnergyKWh = uncorrected
+	}
+	return savings
+}
+
+// defaultEnergyRate is the flat rate per kWh assumed when no tariff is available
+const defaultEnergyRate = 0.15
+
+// totalCurrentPower sums the current power draw of devices, in kW
+func totalCurrentPower(devices []models.DeviceState) float64 {
+	total := 0.0
+	for _, device := range devices {
+		total += device.CurrentPower
+	}
+	return total
+}
+
+// loadProfiles builds hourly baseline and optimized load profiles over the span of the actions.
+// The baseline is a flat load of baselineKW, raised where needed so no action takes the load below
+// zero; each action changes the optimized load by its expected impact for as long as it runs.
+func loadProfiles(actions []models.OptimizationAction, baselineKW float64) ([]models.LoadInterval, []models.LoadInterval) {
+	var start, end time.Time
+	for _, action := range actions {
+		if action.Duration <= 0 {
+			continue
+		}
+		actionEnd := action.ScheduledTime.Add(time.Duration(action.Duration) * time.Minute)
+		if start.IsZero() || action.ScheduledTime.Before(start) {
+			start = action.ScheduledTime
+		}
+		if actionEnd.After(end) {
+			end = actionEnd
+		}
+	}
+	if start.IsZero() {
+		return nil, nil
+	}
+	start = start.Truncate(time.Hour)
+
+	var reductions []float64
+	for hour := start; hour.Before(end); hour = hour.Add(time.Hour) {
+		hourEnd := hour.Add(time.Hour)
+		reduction := 0.0
+		for _, action := range actions {
+			actionEnd := action.ScheduledTime.Add(time.Duration(action.Duration) * time.Minute)
+			overlapStart, overlapEnd := action.ScheduledTime, actionEnd
+			if hour.After(overlapStart) {
+				overlapStart = hour
+			}
+			if hourEnd.Before(overlapEnd) {
+				overlapEnd = hourEnd
+			}
+			if overlapEnd.After(overlapStart) {
+				reduction += action.ExpectedImpact * overlapEnd.Sub(overlapStart).Hours()
+			}
+		}
+		reductions = append(reductions, reduction)
+		baselineKW = math.Max(baselineKW, reduction)
+	}
+
+	baseline := make([]models.LoadInterval, len(reductions))
+	optimized := make([]models.LoadInterval, len(reductions))
+	for i, reduction := range reductions {
+		hour := start.Add(time.Duration(i) * time.Hour)
+		baseline[i] = models.LoadInterval{Start: hour, Minutes: 60, EnergyKWh: baselineKW}
+		optimized[i] = models.LoadInterval{Start: hour, Minutes: 60, EnergyKWh: baselineKW - reduction}
+	}
+	return baseline, optimized
 }
 
 // generateScenarioDescription generates a description for the scenario
 func (s *OptimizationService) generateScenarioDescription(optType models.OptimizationType, actions []models.OptimizationAction, savings models.Savings) string {
-	return fmt.Sprintf(
+	description := fmt.Sprintf(
 		"%s optimization scenario with %d actions. Expected savings: %.1f kWh (%.2f %s), CO2 reduction: %.1f kg",
 		optType,
 		len(actions),
@@ -312,15 +741,93 @@ func (s *OptimizationService) generateScenarioDescription(optType models.Optimiz
 		savings.Currency,
 		savings.CO2ReductionKg,
 	)
+
+	if penalty := savings.ContractPenalty; penalty != nil {
+		if penalty.ExcessKW == 0 {
+			description += fmt.Sprintf(". Projected peak of %.1f kW stays under the contracted %.1f kW", penalty.ProjectedPeakKW, penalty.MaxDemandKW)
+		} else {
+			description += fmt.Sprintf(". Projected peak of %.1f kW shaved to %.1f kW against the contracted %.1f kW, avoiding a %.2f %s penalty",
+				penalty.ProjectedPeakKW, penalty.ShavedPeakKW, penalty.MaxDemandKW, penalty.PenaltyAvoided, penalty.Currency)
+		}
+	}
+	return description
 }
 
 // GetScenario retrieves an optimization scenario by ID
-func (s *OptimizationService) GetScenario(ctx context.Context, scenarioID string) (*models.OptimizationScenarioResponse, error) {
-	scenario, err := s.optimizationRepo.FindByID(ctx, scenarioID)
+func (s *OptimizationService) GetScenario(ctx context.Context, scenarioID string, fields *models.FieldSelection) (*models.OptimizationScenarioResponse, error) {
+	scenario, err := s.optimizationRepo.FindByIDWithFields(ctx, scenarioID, fields)
+	if err != nil {
+		return nil, err
+	}
+	// A portfolio's status is derived from its children, which needs the whole document
+	if fields == nil && scenario.Type == models.OptimizationTypePortfolio && scenario.Portfolio != nil {
+		s.refreshPortfolio(ctx, scenario)
+	}
+	return scenario.ToResponse().InLocation(s.occupancyService.Location(ctx, scenario.BuildingID)), nil
+}
+
+// ListScenarios lists a building's optimization scenarios, newest first
+func (s *OptimizationService) ListScenarios(ctx context.Context, buildingID string, status models.OptimizationStatus, page, limit int, fields *models.FieldSelection) ([]*models.OptimizationScenarioResponse, int64, error) {
+	scenarios, total, err := s.optimizationRepo.FindByBuilding(ctx, buildingID, status, page, limit, fields)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	loc := s.occupancyService.Location(ctx, buildingID)
+	responses := make([]*models.OptimizationScenarioResponse, len(scenarios))
+	for i, scenario := range scenarios {
+		responses[i] = scenario.ToResponse().InLocation(loc)
+	}
+
+	return responses, total, nil
+}
+
+// SearchScenarios searches optimization scenarios by free text and filters, most relevant first.
+// Results are paged with the cursor returned by the previous page.
+func (s *OptimizationService) SearchScenarios(ctx context.Context, req *models.SearchScenariosRequest) (*models.SearchResponse, error) {
+	var after *models.SearchCursor
+	if req.Cursor != "" {
+		cursor, err := models.DecodeSearchCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = cursor
+	}
+	limit := req.Limit
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	// One extra hit tells whether another page follows
+	hits, err := s.optimizationRepo.Search(ctx, req, after, limit+1)
 	if err != nil {
 		return nil, err
 	}
-	return scenario.ToResponse(), nil
+
+	response := &models.SearchResponse{Results: []*models.SearchResult{}}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[len(hits)-1]
+		response.NextCursor = models.SearchCursor{Score: last.Score, ID: last.ID}.Encode()
+	}
+	locations := make(map[string]*time.Location)
+	for _, hit := range hits {
+		loc, ok := locations[hit.BuildingID]
+		if !ok {
+			loc = s.occupancyService.Location(ctx, hit.BuildingID)
+			locations[hit.BuildingID] = loc
+		}
+		response.Results = append(response.Results, &models.SearchResult{
+			Type:       models.SearchResultScenario,
+			ID:         hit.ID.Hex(),
+			Title:      hit.Name,
+			BuildingID: hit.BuildingID,
+			Score:      hit.Score,
+			Data:       hit.OptimizationScenario.ToResponse().InLocation(loc),
+		})
+	}
+
+	return response, nil
 }
 
 // GetRecommendations retrieves energy-saving recommendations for a building
@@ -454,14 +961,60 @@ func (s *OptimizationService) SendToIoT(ctx context.Context, req *models.SendToI
 	if err != nil {
 		return nil, err
 	}
+	tracing.SetScenario(ctx, req.ScenarioID, scenario.BuildingID)
 
 	// Check status
 	if scenario.Status != models.OptimizationStatusApproved && scenario.Status != models.OptimizationStatusDraft {
 		return nil, fmt.Errorf("scenario must be approved or draft to send to IoT")
 	}
+	if scenario.Type == models.OptimizationTypePortfolio {
+		return s.sendPortfolioToIoT(ctx, scenario, req, userID, authToken)
+	}
+
+	response := &models.SendToIoTResponse{ScenarioID: req.ScenarioID}
+
+	resolution := req.ConflictResolution
+	if resolution == "" {
+		resolution = models.ConflictResolutionReject
+	}
+	if !resolution.IsValid() {
+		return nil, fmt.Errorf("invalid conflict resolution: %s", resolution)
+	}
 
-	// Approve if draft
-	if scenario.Status == models.OptimizationStatusDraft {
+	// Another scenario controlling the same devices at the same time would issue contradictory commands
+	conflicts, conflictingActions, err := s.detectConflicts(ctx, scenario)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check scenario conflicts: %w", err)
+	}
+	response.Conflicts = conflicts
+	if len(conflicts) > 0 && !req.DryRun {
+		unresolved, err := s.resolveConflicts(ctx, scenario, conflicts, conflictingActions, resolution, response)
+		if err != nil {
+			return nil, err
+		}
+		if len(unresolved) > 0 {
+			response.Conflicts = unresolved
+			response.Errors = []string{fmt.Sprintf("scenario conflicts with %d approved or executing scenarios", len(unresolved))}
+			return response, nil
+		}
+	}
+
+	// The device catalog may have changed since the scenario was generated or imported, so the
+	// actions are checked again before anything is approved or sent
+	violations := s.checkCapabilities(ctx, scenario.Actions, s.clampToCapabilities.Get(), authToken)
+	response.CapabilityViolations = violations
+	if blocking := blockingViolations(violations); len(blocking) > 0 {
+		response.Errors = append(response.Errors, fmt.Sprintf("%d actions exceed their device's capabilities", len(blocking)))
+		return response, nil
+	}
+	if len(violations) > 0 && !req.DryRun {
+		if err := s.saveClampedActions(ctx, scenario, violations); err != nil {
+			return nil, err
+		}
+	}
+
+	// Approve if draft. A dry run only reports conflicts, so it must not approve a conflicting draft.
+	if scenario.Status == models.OptimizationStatusDraft && (!req.DryRun || len(conflicts) == 0) {
 		if err := s.optimizationRepo.ApproveScenario(ctx, req.ScenarioID, userID); err != nil {
 			return nil, fmt.Errorf("failed to approve scenario: %w", err)
 		}
@@ -483,14 +1036,13 @@ func (s *OptimizationService) SendToIoT(ctx context.Context, req *models.SendToI
 		})
 	}
 
-	return &models.SendToIoTResponse{
-		Success:       iotResp.Success,
-		ScenarioID:    req.ScenarioID,
-		ActionsQueued: iotResp.ActionsQueued,
-		ActionsSkipped: iotResp.ActionsSkipped,
-		Errors:        iotResp.Errors,
-		ExecutionID:   iotResp.ExecutionID,
-	}, nil
+	response.Success = iotResp.Success
+	response.ActionsQueued = iotResp.ActionsQueued
+	response.ActionsSkipped = iotResp.ActionsSkipped
+	response.Errors = iotResp.Errors
+	response.ExecutionID = iotResp.ExecutionID
+	response.Preview = iotResp.Preview
+	return response, nil
 }
 
 // GetDeviceOptimization retrieves optimization recommendations for a device
@@ -513,10 +1065,10 @@ func (s *OptimizationService) GetDeviceOptimization(ctx context.Context, deviceI
 	scheduledActions := []models.ScheduledAction{}
 	potentialSavings := 0.0
 
-	deviceType := s.inferDeviceType(deviceID)
+	deviceType := s.deviceTypes.Resolve(ctx, *deviceState, authToken)
 
-	switch deviceType {
-	case "HVAC":
+	switch {
+	case s.isHVACDevice(deviceType):
 		recommendations = append(recommendations,
 			"Consider increasing setpoint by 1-2°C during peak hours",
 			"Enable pre-cooling before peak tariff periods",
@@ -530,7 +1082,7 @@ func (s *OptimizationService) GetDeviceOptimization(ctx context.Context, deviceI
 			Reason:      "Peak tariff period approaching",
 		})
 
-	case "LIGHTING":
+	case s.isLightingDevice(deviceType):
 		recommendations = append(recommendations,
 			"Enable daylight harvesting during daytime hours",
 			"Reduce illumination levels in unoccupied areas",
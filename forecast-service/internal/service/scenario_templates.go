@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+	"forecast-service/internal/tenant"
+)
+
+// templateRunHorizon is how far ahead the next run of a template schedule is searched, so
+// holiday-only schedules find the next holiday of the year
+const templateRunHorizon = 366
+
+// ScenarioTemplateService manages reusable optimization scenario templates
+type ScenarioTemplateService struct {
+	templateRepo *repository.ScenarioTemplateRepository
+}
+
+// NewScenarioTemplateService creates a new scenario template service
+func NewScenarioTemplateService(templateRepo *repository.ScenarioTemplateRepository) *ScenarioTemplateService {
+	return &ScenarioTemplateService{templateRepo: templateRepo}
+}
+
+// CreateTemplate creates a scenario template for the caller's organization, or a shared one
+// when the caller is not scoped to an organization
+func (s *ScenarioTemplateService) CreateTemplate(ctx context.Context, req *models.ScenarioTemplateRequest, userID string) (*models.ScenarioTemplate, error) {
+	if err := validateScenarioTemplate(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	template := &models.ScenarioTemplate{OrgID: tenant.OrgID(ctx), CreatedBy: userID}
+	applyScenarioTemplateRequest(template, req)
+	return s.templateRepo.Create(ctx, template)
+}
+
+// GetTemplate retrieves a scenario template by ID
+func (s *ScenarioTemplateService) GetTemplate(ctx context.Context, id string) (*models.ScenarioTemplate, error) {
+	return s.templateRepo.FindByID(ctx, id)
+}
+
+// ListTemplates lists scenario templates, optionally of one optimization type
+func (s *ScenarioTemplateService) ListTemplates(ctx context.Context, req *models.ListScenarioTemplatesRequest) ([]*models.ScenarioTemplate, error) {
+	return s.templateRepo.FindAll(ctx, req.Type)
+}
+
+// UpdateTemplate replaces the definition of a scenario template
+func (s *ScenarioTemplateService) UpdateTemplate(ctx context.Context, id string, req *models.ScenarioTemplateRequest, userID string) (*models.ScenarioTemplate, error) {
+	if err := validateScenarioTemplate(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	var template models.ScenarioTemplate
+	applyScenarioTemplateRequest(&template, req)
+	return s.templateRepo.Update(ctx, id, bson.M{
+		"name":             template.Name,
+		"description":      template.Description,
+		"type":             template.Type,
+		"constraints":      template.Constraints,
+		"device_tags":      template.DeviceTags,
+		"schedule":         template.Schedule,
+		"priority":         template.Priority,
+		"use_tariff_data":  template.UseTariffData,
+		"use_weather_data": template.UseWeatherData,
+		"updated_by":       userID,
+	})
+}
+
+// DeleteTemplate deletes a scenario template. Scenarios generated from it are kept.
+func (s *ScenarioTemplateService) DeleteTemplate(ctx context.Context, id string) error {
+	return s.templateRepo.Delete(ctx, id)
+}
+
+// applyScenarioTemplateRequest copies the fields of a request to a template
+func applyScenarioTemplateRequest(template *models.ScenarioTemplate, req *models.ScenarioTemplateRequest) {
+	template.Name = strings.TrimSpace(req.Name)
+	template.Description = req.Description
+	template.Type = req.Type
+	template.Constraints = req.Constraints
+	template.DeviceTags = req.DeviceTags
+	template.Schedule = req.Schedule
+	template.Priority = req.Priority
+	template.UseTariffData = req.UseTariffData
+	template.UseWeatherData = req.UseWeatherData
+}
+
+// validateScenarioTemplate checks the constraints and schedule of a template request
+func validateScenarioTemplate(req *models.ScenarioTemplateRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return errors.New("name must not be blank")
+	}
+
+	constraints := req.Constraints
+	if constraints.MinTemperature != nil && constraints.MaxTemperature != nil && *constraints.MinTemperature > *constraints.MaxTemperature {
+		return errors.New("minimum temperature is above maximum temperature")
+	}
+	for _, window := range constraints.TimeWindows {
+		if !validClock(window.StartTime) || !validClock(window.EndTime) {
+			return fmt.Errorf("time window %s-%s must use the HH:MM format", window.StartTime, window.EndTime)
+		}
+	}
+
+	for _, tag := range req.DeviceTags {
+		if strings.TrimSpace(tag) == "" {
+			return errors.New("device tags must not be blank")
+		}
+	}
+
+	if req.Schedule != nil {
+		if !validClock(req.Schedule.StartTime) {
+			return fmt.Errorf("schedule start time %s must use the HH:MM format", req.Schedule.StartTime)
+		}
+		for _, day := range req.Schedule.DaysOfWeek {
+			if !validScheduleDay(day) {
+				return fmt.Errorf("schedule day %s must be a weekday name or Holiday", day)
+			}
+		}
+	}
+	return nil
+}
+
+// validScheduleDay checks that a day of a template schedule is a weekday name or Holiday
+func validScheduleDay(day string) bool {
+	if strings.EqualFold(day, "Holiday") {
+		return true
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(day, weekday.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextTemplateRun returns the first start of a template schedule after a time, in the building's
+// time zone. Holidays of the business calendar count as Holiday and as weekend days, as they do
+// for the time windows of constraints. It reports false when no day within a year matches.
+func NextTemplateRun(pattern *models.TemplateSchedule, schedule *models.OccupancySchedule, after time.Time) (time.Time, bool) {
+	clock, err := time.Parse("15:04", pattern.StartTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := after.In(schedule.Location())
+	for day := 0; day <= templateRunHorizon; day++ {
+		start := time.Date(local.Year(), local.Month(), local.Day()+day, clock.Hour(), clock.Minute(), 0, 0, local.Location())
+		if !start.After(after) {
+			continue
+		}
+		if windowIncludesDay(pattern.DaysOfWeek, calendarDayNames(schedule, start)) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// applyTemplate fills in the fields of a generate request that were not set from its scenario
+// template: type, constraints, device tags, priority, data sources and, from the template's
+// schedule, the scheduled start and end
+func (s *OptimizationService) applyTemplate(ctx context.Context, req *models.OptimizationGenerateRequest) error {
+	template, err := s.templateRepo.FindByID(ctx, req.TemplateID)
+	if err != nil {
+		return err
+	}
+
+	if req.Type == "" {
+		req.Type = template.Type
+	} else if req.Type != template.Type {
+		return fmt.Errorf("validation failed: type %s does not match the %s type of template %s", req.Type, template.Type, template.Name)
+	}
+
+	req.Constraints = mergeConstraints(req.Constraints, template.Constraints)
+	if len(req.DeviceTags) == 0 {
+		req.DeviceTags = template.DeviceTags
+	}
+	if req.Priority <= 0 {
+		req.Priority = template.Priority
+	}
+	req.UseTariffData = req.UseTariffData || template.UseTariffData
+	req.UseWeatherData = req.UseWeatherData || template.UseWeatherData
+
+	if template.Schedule != nil {
+		if req.ScheduledStart.IsZero() {
+			start, ok := NextTemplateRun(template.Schedule, s.occupancyService.GetSchedule(ctx, req.BuildingID), time.Now())
+			if !ok {
+				return fmt.Errorf("validation failed: the schedule of template %s has no run within a year", template.Name)
+			}
+			req.ScheduledStart = start
+		}
+		if req.ScheduledEnd.IsZero() {
+			req.ScheduledEnd = req.ScheduledStart.Add(time.Duration(template.Schedule.DurationMinutes) * time.Minute)
+		}
+	}
+
+	if req.Name == "" {
+		req.Name = fmt.Sprintf("%s - %s", template.Name, req.ScheduledStart.In(s.occupancyService.Location(ctx, req.BuildingID)).Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// mergeConstraints fills the constraints a request left unset from a template's. Flags are
+// enabled when either enables them, and excluded devices of both are excluded.
+func mergeConstraints(requested, template models.OptimizationConstraints) models.OptimizationConstraints {
+	merged := requested
+	if merged.MinTemperature == nil {
+		merged.MinTemperature = template.MinTemperature
+	}
+	if merged.MaxTemperature == nil {
+		merged.MaxTemperature = template.MaxTemperature
+	}
+	if merged.MinLightLevel == nil {
+		merged.MinLightLevel = template.MinLightLevel
+	}
+	if merged.MaxPeakReduction == nil {
+		merged.MaxPeakReduction = template.MaxPeakReduction
+	}
+	merged.OccupancyRequired = merged.OccupancyRequired || template.OccupancyRequired
+	merged.PreserveComfort = merged.PreserveComfort || template.PreserveComfort
+	if len(merged.TimeWindows) == 0 {
+		merged.TimeWindows = template.TimeWindows
+	}
+
+	excluded := make(map[string]bool, len(merged.ExcludeDevices))
+	for _, deviceID := range merged.ExcludeDevices {
+		excluded[deviceID] = true
+	}
+	for _, deviceID := range template.ExcludeDevices {
+		if !excluded[deviceID] {
+			merged.ExcludeDevices = append(merged.ExcludeDevices, deviceID)
+			excluded[deviceID] = true
+		}
+	}
+	return merged
+}
+
+// devicesWithTags returns the devices that carry at least one of the tags
+func devicesWithTags(devices []models.DeviceState, tags []string) []models.DeviceState {
+	matched := make([]models.DeviceState, 0, len(devices))
+	for _, device := range devices {
+		if hasAnyTag(device.Tags, tags) {
+			matched = append(matched, device)
+		}
+	}
+	return matched
+}
+
+// hasAnyTag reports whether any of a device's tags is one of the wanted tags, ignoring case
+func hasAnyTag(deviceTags, wanted []string) bool {
+	for _, tag := range deviceTags {
+		for _, want := range wanted {
+			if strings.EqualFold(tag, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
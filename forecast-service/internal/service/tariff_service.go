@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"forecast-service/internal/integrations"
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+)
+
+// TariffService handles local tariff management and tariff resolution
+type TariffService struct {
+	tariffRepo     *repository.TariffRepository
+	featureRepo    *repository.FeatureRepository
+	externalClient *integrations.ExternalClient
+}
+
+// NewTariffService creates a new tariff service
+func NewTariffService(
+	tariffRepo *repository.TariffRepository,
+	featureRepo *repository.FeatureRepository,
+	externalClient *integrations.ExternalClient,
+) *TariffService {
+	return &TariffService{
+		tariffRepo:     tariffRepo,
+		featureRepo:    featureRepo,
+		externalClient: externalClient,
+	}
+}
+
+// CreateTariff creates a local tariff schedule for a region
+func (s *TariffService) CreateTariff(ctx context.Context, req *models.TariffCreateRequest, userID string) (*models.TariffScheduleResponse, error) {
+	if err := validateTariffDefinition(req.BaseRate, req.TimeOfUseWindows, req.SeasonalRates, req.DemandCharges); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	tariff := &models.TariffSchedule{
+		Region:           req.Region,
+		Name:             req.Name,
+		Currency:         currency,
+		BaseRate:         req.BaseRate,
+		TimeOfUseWindows: req.TimeOfUseWindows,
+		SeasonalRates:    req.SeasonalRates,
+		DemandCharges:    req.DemandCharges,
+		IsActive:         isActive,
+		CreatedBy:        userID,
+	}
+
+	createdTariff, err := s.tariffRepo.Create(ctx, tariff)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateStoredTariffs(ctx)
+
+	return createdTariff.ToResponse(), nil
+}
+
+// GetTariff retrieves a local tariff schedule by ID
+func (s *TariffService) GetTariff(ctx context.Context, id string) (*models.TariffScheduleResponse, error) {
+	tariff, err := s.tariffRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return tariff.ToResponse(), nil
+}
+
+// ListTariffs lists local tariff schedules
+func (s *TariffService) ListTariffs(ctx context.Context, region string, page, limit int) ([]*models.TariffScheduleResponse, int64, error) {
+	tariffs, total, err := s.tariffRepo.FindAll(ctx, region, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]*models.TariffScheduleResponse, len(tariffs))
+	for i, tariff := range tariffs {
+		responses[i] = tariff.ToResponse()
+	}
+
+	return responses, total, nil
+}
+
+// UpdateTariff updates a local tariff schedule
+func (s *TariffService) UpdateTariff(ctx context.Context, id string, req *models.TariffUpdateRequest) (*models.TariffScheduleResponse, error) {
+	existing, err := s.tariffRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the merged definition so partial updates cannot leave it inconsistent
+	baseRate := existing.BaseRate
+	if req.BaseRate != nil {
+		baseRate = *req.BaseRate
+	}
+	windows := existing.TimeOfUseWindows
+	if req.TimeOfUseWindows != nil {
+		windows = req.TimeOfUseWindows
+	}
+	seasons := existing.SeasonalRates
+	if req.SeasonalRates != nil {
+		seasons = req.SeasonalRates
+	}
+	charges := existing.DemandCharges
+	if req.DemandCharges != nil {
+		charges = req.DemandCharges
+	}
+	if err := validateTariffDefinition(baseRate, windows, seasons, charges); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	updates := bson.M{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Currency != nil {
+		updates["currency"] = *req.Currency
+	}
+	if req.BaseRate != nil {
+		updates["base_rate"] = *req.BaseRate
+	}
+	if req.TimeOfUseWindows != nil {
+		updates["time_of_use_windows"] = req.TimeOfUseWindows
+	}
+	if req.SeasonalRates != nil {
+		updates["seasonal_rates"] = req.SeasonalRates
+	}
+	if req.DemandCharges != nil {
+		updates["demand_charges"] = req.DemandCharges
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	updatedTariff, err := s.tariffRepo.Update(ctx, id, updates)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateStoredTariffs(ctx)
+
+	return updatedTariff.ToResponse(), nil
+}
+
+// DeleteTariff deletes a local tariff schedule
+func (s *TariffService) DeleteTariff(ctx context.Context, id string) error {
+	if err := s.tariffRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.invalidateStoredTariffs(ctx)
+	return nil
+}
+
+// invalidateStoredTariffs stops forecasts from reusing tariffs resolved before a local schedule changed
+func (s *TariffService) invalidateStoredTariffs(ctx context.Context) {
+	if err := s.featureRepo.InvalidateKind(ctx, models.FeatureKindTariff); err != nil {
+		log.Printf("Failed to invalidate stored tariffs: %v", err)
+	}
+}
+
+// GetCurrentTariff resolves the tariff in effect for a region.
+// Locally managed tariffs take precedence; the external tariff API is used as a fallback.
+func (s *TariffService) GetCurrentTariff(ctx context.Context, region string, authToken string) (*models.Tariff, error) {
+	return s.GetTariffAt(ctx, region, time.Now(), authToken)
+}
+
+// GetTariffAt resolves the tariff of a region in effect at a past or future time: the active local
+// schedule's seasonal and time-of-use rates at that time. Only the external tariff API's current
+// tariff is available as a fallback.
+func (s *TariffService) GetTariffAt(ctx context.Context, region string, at time.Time, authToken string) (*models.Tariff, error) {
+	localTariff, err := s.tariffRepo.FindActiveByRegion(ctx, region)
+	if err == nil {
+		return localTariff.Resolve(at), nil
+	}
+	if err.Error() != "tariff not found" {
+		log.Printf("Failed to load local tariff for region %s: %v", region, err)
+	}
+
+	tariff, err := s.externalClient.GetCurrentTariff(ctx, region, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("no tariff available for region %s: %w", region, err)
+	}
+	tariff.Source = models.TariffSourceExternal
+
+	return tariff, nil
+}
+
+// validateTariffDefinition validates rates, windows, seasons and demand charges
+func validateTariffDefinition(baseRate float64, windows []models.TimeOfUseWindow, seasons []models.SeasonalRate, charges []models.DemandCharge) error {
+	if baseRate < 0 {
+		return fmt.Errorf("base rate must not be negative")
+	}
+	if err := validateTimeOfUseWindows(windows); err != nil {
+		return err
+	}
+	for _, season := range seasons {
+		if season.StartMonth < 1 || season.StartMonth > 12 || season.EndMonth < 1 || season.EndMonth > 12 {
+			return fmt.Errorf("seasonal rate %q must use months between 1 and 12", season.Name)
+		}
+		if season.BaseRate < 0 {
+			return fmt.Errorf("seasonal rate %q must not have a negative base rate", season.Name)
+		}
+		if err := validateTimeOfUseWindows(season.TimeOfUseWindows); err != nil {
+			return err
+		}
+	}
+	for _, charge := range charges {
+		if charge.RatePerKW < 0 {
+			return fmt.Errorf("demand charge %q must not have a negative rate", charge.Name)
+		}
+		if !validHour(charge.StartHour) || !validHour(charge.EndHour) {
+			return fmt.Errorf("demand charge %q must use hours between 0 and 24", charge.Name)
+		}
+	}
+	return nil
+}
+
+// validateTimeOfUseWindows validates a set of time-of-use windows
+func validateTimeOfUseWindows(windows []models.TimeOfUseWindow) error {
+	for _, window := range windows {
+		if window.RatePerKWh < 0 {
+			return fmt.Errorf("time-of-use window %q must not have a negative rate", window.Name)
+		}
+		if !validHour(window.StartHour) || !validHour(window.EndHour) {
+			return fmt.Errorf("time-of-use window %q must use hours between 0 and 24", window.Name)
+		}
+		for _, day := range window.DaysOfWeek {
+			if day < 0 || day > 6 {
+				return fmt.Errorf("time-of-use window %q must use days between 0 (Sunday) and 6", window.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validHour checks that an hour boundary is within a day
+func validHour(hour int) bool {
+	return hour >= 0 && hour <= 24
+}
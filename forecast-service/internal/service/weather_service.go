@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"forecast-service/internal/integrations"
+	"forecast-service/internal/models"
+)
+
+// maxDegreeDayRange bounds the period of a single degree day calculation
+const maxDegreeDayRange = 400 * 24 * time.Hour
+
+// maxWeatherForecastHours bounds the horizon of a weather forecast request
+const maxWeatherForecastHours = 168
+
+// WeatherService derives weather statistics from the external weather API
+type WeatherService struct {
+	externalClient  *integrations.ExternalClient
+	baseTemperature float64
+}
+
+// NewWeatherService creates a new weather service.
+// baseTemperature is the balance point in °C below which heating and above which cooling is needed.
+func NewWeatherService(externalClient *integrations.ExternalClient, baseTemperature float64) *WeatherService {
+	return &WeatherService{
+		externalClient:  externalClient,
+		baseTemperature: baseTemperature,
+	}
+}
+
+// GetDegreeDays calculates heating and cooling degree days from observed daily mean temperatures.
+// Days are UTC calendar days; a day with mean temperature T contributes max(0, base-T) heating
+// and max(0, T-base) cooling degree days.
+func (s *WeatherService) GetDegreeDays(ctx context.Context, req *models.DegreeDaysRequest, authToken string) (*models.DegreeDays, error) {
+	if !req.To.After(req.From) {
+		return nil, errors.New("to must be after from")
+	}
+	if req.To.Sub(req.From) > maxDegreeDayRange {
+		return nil, errors.New("degree day range must not exceed 400 days")
+	}
+
+	base := s.baseTemperature
+	if req.BaseTemperature != nil {
+		base = *req.BaseTemperature
+	}
+
+	observations, err := s.externalClient.GetWeatherHistory(ctx, req.BuildingID, req.From, req.To, authToken)
+	if err != nil {
+		return nil, err
+	}
+
+	type dailyTotal struct {
+		sum   float64
+		count int
+	}
+	totals := make(map[string]*dailyTotal)
+	for _, observation := range observations {
+		if observation.Timestamp.Before(req.From) || !observation.Timestamp.Before(req.To) {
+			continue
+		}
+		date := observation.Timestamp.UTC().Format("2006-01-02")
+		if totals[date] == nil {
+			totals[date] = &dailyTotal{}
+		}
+		totals[date].sum += observation.Temperature
+		totals[date].count++
+	}
+
+	result := &models.DegreeDays{
+		BuildingID:      req.BuildingID,
+		From:            req.From,
+		To:              req.To,
+		BaseTemperature: base,
+		Days:            []models.DailyDegreeDays{},
+	}
+
+	start := req.From.UTC().Truncate(24 * time.Hour)
+	for day := start; day.Before(req.To); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		total, ok := totals[date]
+		if !ok {
+			result.MissingDays++
+			continue
+		}
+
+		mean := total.sum / float64(total.count)
+		daily := models.DailyDegreeDays{
+			Date:              date,
+			MeanTemperature:   roundDegrees(mean),
+			HeatingDegreeDays: roundDegrees(math.Max(0, base-mean)),
+			CoolingDegreeDays: roundDegrees(math.Max(0, mean-base)),
+		}
+		result.HeatingDegreeDays += daily.HeatingDegreeDays
+		result.CoolingDegreeDays += daily.CoolingDegreeDays
+		result.Days = append(result.Days, daily)
+	}
+
+	result.HeatingDegreeDays = roundDegrees(result.HeatingDegreeDays)
+	result.CoolingDegreeDays = roundDegrees(result.CoolingDegreeDays)
+
+	return result, nil
+}
+
+// GetWeatherForecast returns the hourly weather forecast of a building location. It is used by
+// background jobs of other services, so it calls the weather API without a user token.
+func (s *WeatherService) GetWeatherForecast(ctx context.Context, req *models.WeatherForecastRequest) ([]integrations.WeatherForecastPoint, error) {
+	hours := req.Hours
+	if hours == 0 {
+		hours = 24
+	}
+	if hours < 1 || hours > maxWeatherForecastHours {
+		return nil, errors.New("hours must be between 1 and 168")
+	}
+
+	points, err := s.externalClient.GetWeatherForecast(ctx, req.BuildingID, hours, "")
+	if err != nil {
+		return nil, err
+	}
+	if points == nil {
+		points = []integrations.WeatherForecastPoint{}
+	}
+	return points, nil
+}
+
+// roundDegrees rounds a temperature or degree day value to two decimals
+func roundDegrees(value float64) float64 {
+	return math.Round(value*100) / 100
+}
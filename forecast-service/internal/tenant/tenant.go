@@ -0,0 +1,72 @@
+// Package tenant carries the organization a request is scoped to and restricts repository
+// queries to the buildings it owns. Requests without a scope, such as those of super admins,
+// users outside any organization, internal services and background jobs, are not restricted.
+package tenant
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"forecast-service/internal/models"
+)
+
+type contextKey struct{}
+
+// WithScope returns a copy of ctx carrying the tenant scope of a request
+func WithScope(ctx context.Context, scope *models.TenantScope) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope)
+}
+
+// FromContext returns the tenant scope carried by ctx, or nil when the request is not scoped
+func FromContext(ctx context.Context) *models.TenantScope {
+	scope, _ := ctx.Value(contextKey{}).(*models.TenantScope)
+	return scope
+}
+
+// BuildingFilter restricts a filter to the documents whose field holds a building of the
+// organization ctx is scoped to. A condition the filter already has on the field is kept.
+func BuildingFilter(ctx context.Context, filter bson.M, field string) bson.M {
+	scope := FromContext(ctx)
+	if scope == nil {
+		return filter
+	}
+
+	// An organization without buildings matches nothing rather than failing the query
+	restriction := bson.M{field: bson.M{"$in": append([]string{}, scope.BuildingIDs...)}}
+	if _, ok := filter[field]; ok {
+		return bson.M{"$and": []bson.M{filter, restriction}}
+	}
+	filter[field] = restriction[field]
+	return filter
+}
+
+// AllowsBuilding reports whether ctx may access the data of a building
+func AllowsBuilding(ctx context.Context, buildingID string) bool {
+	scope := FromContext(ctx)
+	return scope == nil || scope.AllowsBuilding(buildingID)
+}
+
+// OrgFilter restricts a filter to the documents whose field holds the organization ctx is
+// scoped to. With shared set, documents that belong to no organization match as well.
+func OrgFilter(ctx context.Context, filter bson.M, field string, shared bool) bson.M {
+	scope := FromContext(ctx)
+	if scope == nil {
+		return filter
+	}
+
+	orgs := []string{scope.OrgID}
+	if shared {
+		orgs = append(orgs, "")
+	}
+	filter[field] = bson.M{"$in": orgs}
+	return filter
+}
+
+// OrgID returns the organization ctx is scoped to, or "" when the request is not scoped
+func OrgID(ctx context.Context) string {
+	if scope := FromContext(ctx); scope != nil {
+		return scope.OrgID
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewMongoMonitor returns a command monitor tracing the MongoDB commands of traced work. Commands
+// run outside a trace, like the polling of the job queue, are not traced.
+func NewMongoMonitor() *event.CommandMonitor {
+	var spans sync.Map // request ID -> trace.Span
+
+	end := func(requestID int64, err error) {
+		value, ok := spans.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		RecordError(span, err)
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				return
+			}
+			attrs := []attribute.KeyValue{
+				attribute.String("db.system", "mongodb"),
+				attribute.String("db.namespace", evt.DatabaseName),
+				attribute.String("db.operation.name", evt.CommandName),
+			}
+			name := evt.CommandName
+			// The first element of a command names the collection it runs on
+			if element, err := evt.Command.IndexErr(0); err == nil {
+				if collection, ok := element.Value().StringValueOK(); ok {
+					attrs = append(attrs, attribute.String("db.collection.name", collection))
+					name += " " + collection
+				}
+			}
+			_, span := Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			spans.Store(evt.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			end(evt.RequestID, nil)
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			end(evt.RequestID, errors.New(evt.Failure))
+		},
+	}
+}
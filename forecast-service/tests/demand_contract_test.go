@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractDemandWindows(t *testing.T) {
+	start := time.Date(2024, 7, 1, 14, 0, 0, 0, time.UTC)
+	var predictions []models.ForecastPrediction
+	for i, kw := range []float64{80, 100, 120, 140, 90, 90} {
+		predictions = append(predictions, models.ForecastPrediction{Timestamp: start.Add(time.Duration(i) * 15 * time.Minute), PredictedValue: kw})
+	}
+
+	t.Run("quarter hours averaged over 30 minute windows", func(t *testing.T) {
+		windows := service.ContractDemandWindows(predictions, 15*time.Minute, 30*time.Minute, start, start.Add(2*time.Hour))
+		require.Len(t, windows, 3)
+		assert.Equal(t, 90.0, windows[0].KW)
+		assert.Equal(t, 130.0, windows[1].KW)
+		assert.True(t, windows[1].Start.Equal(start.Add(30*time.Minute)))
+		assert.Equal(t, 30*time.Minute, windows[1].Length)
+	})
+
+	t.Run("only predictions within the scenario", func(t *testing.T) {
+		windows := service.ContractDemandWindows(predictions, 15*time.Minute, 15*time.Minute, start.Add(30*time.Minute), start.Add(time.Hour))
+		require.Len(t, windows, 2)
+		assert.Equal(t, 120.0, windows[0].KW)
+	})
+
+	t.Run("hourly predictions are their own window", func(t *testing.T) {
+		hourly := []models.ForecastPrediction{{Timestamp: start, PredictedValue: 110}}
+		windows := service.ContractDemandWindows(hourly, time.Hour, 15*time.Minute, start, start.Add(time.Hour))
+		require.Len(t, windows, 1)
+		assert.Equal(t, time.Hour, windows[0].Length)
+	})
+}
+
+func TestContractExcessSpan(t *testing.T) {
+	start := time.Date(2024, 7, 1, 14, 0, 0, 0, time.UTC)
+	window := 15 * time.Minute
+	windows := []service.DemandWindow{
+		{Start: start, Length: window, KW: 90},
+		{Start: start.Add(15 * time.Minute), Length: window, KW: 110},
+		{Start: start.Add(30 * time.Minute), Length: window, KW: 95},
+		{Start: start.Add(45 * time.Minute), Length: window, KW: 125},
+		{Start: start.Add(60 * time.Minute), Length: window, KW: 80},
+	}
+
+	peak, from, to, over := service.ContractExcessSpan(windows, 100)
+	require.True(t, over)
+	assert.Equal(t, 125.0, peak.KW)
+	assert.True(t, from.Equal(start.Add(15*time.Minute)))
+	assert.True(t, to.Equal(start.Add(60*time.Minute)), "the span ends with the last window above the limit")
+
+	_, _, _, over = service.ContractExcessSpan(windows, 130)
+	assert.False(t, over)
+}
+
+func TestAllocateContractShedding(t *testing.T) {
+	candidates := []service.ContractShedding{
+		{Device: models.DeviceState{DeviceID: "small", CurrentPower: 10}},
+		{Device: models.DeviceState{DeviceID: "large", CurrentPower: 50}},
+		{Device: models.DeviceState{DeviceID: "medium", CurrentPower: 30}},
+	}
+
+	t.Run("largest devices first, only as much as needed", func(t *testing.T) {
+		shedding := service.AllocateContractShedding(candidates, 20)
+		require.Len(t, shedding, 2)
+		assert.Equal(t, "large", shedding[0].Device.DeviceID)
+		assert.InDelta(t, 15.0, shedding[0].ReductionKW, 1e-9)
+		assert.Equal(t, "medium", shedding[1].Device.DeviceID)
+		assert.InDelta(t, 5.0, shedding[1].ReductionKW, 1e-9)
+	})
+
+	t.Run("excess beyond what devices can shed", func(t *testing.T) {
+		shedding := service.AllocateContractShedding(candidates, 100)
+		require.Len(t, shedding, 3)
+		total := 0.0
+		for _, s := range shedding {
+			total += s.ReductionKW
+		}
+		assert.InDelta(t, 27.0, total, 1e-9)
+	})
+}
+
+func TestContractPenalty(t *testing.T) {
+	contract := &models.DemandContract{MaxDemandKW: 100, PenaltyRatePerKW: 12.5, WindowMinutes: 15, Currency: "EUR"}
+	peakAt := time.Date(2024, 7, 1, 14, 45, 0, 0, time.UTC)
+	projected := &models.ContractPenaltySavings{MaxDemandKW: 100, ProjectedPeakKW: 125, PeakAt: &peakAt, Currency: "EUR"}
+
+	t.Run("actions running at the peak avoid the penalty", func(t *testing.T) {
+		actions := []models.OptimizationAction{
+			{ScheduledTime: peakAt.Add(-30 * time.Minute), Duration: 60, ExpectedImpact: 15},
+			{ScheduledTime: peakAt.Add(-30 * time.Minute), Duration: 60, ExpectedImpact: 6},
+			{ScheduledTime: peakAt.Add(time.Hour), Duration: 60, ExpectedImpact: 50},
+		}
+		penalty := service.ContractPenalty(contract, projected, actions)
+		assert.Equal(t, 104.0, penalty.ShavedPeakKW)
+		assert.Equal(t, 25.0, penalty.ExcessKW)
+		assert.Equal(t, 4.0, penalty.RemainingExcess)
+		assert.Equal(t, 312.5, penalty.PenaltyBefore)
+		assert.Equal(t, 50.0, penalty.PenaltyAfter)
+		assert.Equal(t, 262.5, penalty.PenaltyAvoided)
+	})
+
+	t.Run("under the limit there is no penalty", func(t *testing.T) {
+		under := &models.ContractPenaltySavings{MaxDemandKW: 100, ProjectedPeakKW: 80, PeakAt: &peakAt}
+		penalty := service.ContractPenalty(contract, under, nil)
+		assert.Zero(t, penalty.ExcessKW)
+		assert.Zero(t, penalty.PenaltyAvoided)
+	})
+}
@@ -5,11 +5,14 @@ import (
 	"testing"
 	"time"
 
+	"forecast-service/internal/cache"
 	"forecast-service/internal/config"
 	"forecast-service/internal/integrations"
 	"forecast-service/internal/models"
 	"forecast-service/internal/repository"
 	"forecast-service/internal/service"
+	"forecast-service/internal/settings"
+	"shared/jobs"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -57,12 +60,30 @@ func TestGenerateForecast(t *testing.T) {
 	peakLoadRepo := repository.NewPeakLoadRepository(db.Collection("peak_loads"))
 	securityClient := integrations.NewSecurityClient(cfg)
 	externalClient := integrations.NewExternalClient(cfg)
+	featureRepo := repository.NewFeatureRepository(db.Collection("feature_snapshots"))
+	tariffService := service.NewTariffService(repository.NewTariffRepository(db.Collection("tariffs")), featureRepo, externalClient)
+	occupancyService := service.NewOccupancyService(
+		repository.NewOccupancyRepository(db.Collection("occupancy_schedules")),
+		repository.NewCalendarRepository(db.Collection("calendar_days")),
+		repository.NewBuildingRepository(db.Collection("buildings")),
+		integrations.NewIoTClient(cfg),
+	)
+
+	featureStore := service.NewFeatureStore(featureRepo, externalClient, tariffService, time.Hour, time.Hour)
 
 	forecastService := service.NewForecastService(
 		forecastRepo,
 		peakLoadRepo,
 		securityClient,
 		externalClient,
+		featureStore,
+		occupancyService,
+		service.NewDefaultForecastModelRegistry(externalClient),
+		nil,
+		integrations.NewCallbackClient(cfg),
+		jobs.NewQueue(db.Collection("jobs"), "forecast-service", "job-secret", time.Second),
+		cache.New(cache.Config{Prefix: "forecast-service"}),
+		settings.NewStore(db.Collection("settings"), db.Collection("settings_history")),
 		cfg,
 	)
 
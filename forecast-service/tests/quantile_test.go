@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuantileLabel(t *testing.T) {
+	assert.Equal(t, "p10", service.QuantileLabel(0.1))
+	assert.Equal(t, "p50", service.QuantileLabel(0.5))
+	assert.Equal(t, "p90", service.QuantileLabel(0.9))
+	assert.Equal(t, "p97.5", service.QuantileLabel(0.975))
+	assert.Equal(t, "p7", service.QuantileLabel(0.07))
+}
+
+func TestPinballLoss(t *testing.T) {
+	// Under-prediction weighs the quantile, over-prediction its complement
+	assert.InDelta(t, 18.0, service.PinballLoss(120, 100, 0.9), 1e-9)
+	assert.InDelta(t, 2.0, service.PinballLoss(80, 100, 0.9), 1e-9)
+	assert.InDelta(t, 10.0, service.PinballLoss(120, 100, 0.5), 1e-9)
+	assert.Zero(t, service.PinballLoss(100, 100, 0.1))
+}
+
+func TestApplyQuantiles(t *testing.T) {
+	quantiles := []float64{0.1, 0.5, 0.9}
+	newPrediction := func(value, upper float64) models.ForecastPrediction {
+		return models.ForecastPrediction{
+			Timestamp:       time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC),
+			PredictedValue:  value,
+			LowerBound:      2*value - upper,
+			UpperBound:      upper,
+			ConfidenceLevel: 0.95,
+			Unit:            "kW",
+		}
+	}
+
+	t.Run("normal errors without residuals", func(t *testing.T) {
+		// A 95% margin of 19.6 kW is a standard deviation of 10 kW
+		predictions := []models.ForecastPrediction{newPrediction(100, 119.6)}
+		service.ApplyQuantiles(predictions, quantiles, nil)
+
+		require.Len(t, predictions[0].Quantiles, 3)
+		assert.InDelta(t, 87.18, predictions[0].Quantiles["p10"], 0.01)
+		assert.InDelta(t, 100.0, predictions[0].Quantiles["p50"], 0.01)
+		assert.InDelta(t, 112.82, predictions[0].Quantiles["p90"], 0.01)
+	})
+
+	t.Run("skewed residuals widen the upper quantiles", func(t *testing.T) {
+		// Mostly small over-predictions with occasional large spikes
+		residuals := make([]float64, 0, 48)
+		for i := 0; i < 40; i++ {
+			residuals = append(residuals, -4+0.1*float64(i))
+		}
+		for i := 0; i < 8; i++ {
+			residuals = append(residuals, 10)
+		}
+
+		predictions := []models.ForecastPrediction{newPrediction(100, 119.6)}
+		service.ApplyQuantiles(predictions, quantiles, residuals)
+
+		q := predictions[0].Quantiles
+		assert.Less(t, q["p10"], q["p50"])
+		assert.Greater(t, q["p90"]-q["p50"], q["p50"]-q["p10"])
+	})
+
+	t.Run("quantiles from the model are kept", func(t *testing.T) {
+		prediction := newPrediction(100, 119.6)
+		prediction.Quantiles = map[string]float64{"p90": 130}
+		predictions := []models.ForecastPrediction{prediction}
+		service.ApplyQuantiles(predictions, quantiles, nil)
+
+		assert.Equal(t, 130.0, predictions[0].Quantiles["p90"])
+		assert.InDelta(t, 87.18, predictions[0].Quantiles["p10"], 0.01)
+	})
+
+	t.Run("quantiles are never negative", func(t *testing.T) {
+		predictions := []models.ForecastPrediction{newPrediction(1, 40)}
+		service.ApplyQuantiles(predictions, quantiles, nil)
+
+		assert.Zero(t, predictions[0].Quantiles["p10"])
+		assert.GreaterOrEqual(t, predictions[0].Quantiles["p50"], predictions[0].Quantiles["p10"])
+	})
+}
+
+func TestSummarizeQuantiles(t *testing.T) {
+	actual := func(value float64) *float64 { return &value }
+	predictions := []models.ForecastPrediction{
+		{PredictedValue: 100, ActualValue: actual(95), Quantiles: map[string]float64{"p10": 90, "p90": 110}},
+		{PredictedValue: 100, ActualValue: actual(115), Quantiles: map[string]float64{"p10": 90, "p90": 110}},
+		{PredictedValue: 100, ActualValue: actual(85), Quantiles: map[string]float64{"p10": 90, "p90": 110}},
+		// Not yet observed
+		{PredictedValue: 100, Quantiles: map[string]float64{"p10": 90, "p90": 110}},
+	}
+
+	scores, mean := service.SummarizeQuantiles(predictions, []float64{0.1, 0.9})
+	require.Len(t, scores, 2)
+	require.NotNil(t, mean)
+
+	assert.Equal(t, "p10", scores[0].Label)
+	assert.Equal(t, 3, scores[0].Compared)
+	// (0.1×5 + 0.1×25 + 0.9×5) / 3
+	assert.InDelta(t, 2.5, scores[0].PinballLoss, 0.001)
+	assert.InDelta(t, 33.3, scores[0].Coverage, 0.1)
+
+	assert.Equal(t, "p90", scores[1].Label)
+	// (0.1×15 + 0.9×5 + 0.1×25) / 3
+	assert.InDelta(t, 2.833, scores[1].PinballLoss, 0.001)
+	assert.InDelta(t, 66.7, scores[1].Coverage, 0.1)
+	assert.InDelta(t, 2.667, *mean, 0.001)
+
+	t.Run("forecasts without quantiles are not scored", func(t *testing.T) {
+		scores, mean := service.SummarizeQuantiles([]models.ForecastPrediction{{PredictedValue: 100, ActualValue: actual(95)}}, []float64{0.9})
+		assert.Nil(t, scores)
+		assert.Nil(t, mean)
+	})
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayProfiles(t *testing.T) {
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(4 * time.Hour)
+	hour := func(i int) int64 { return from.Add(time.Duration(i) * time.Hour).Unix() }
+
+	observed := map[int64]float64{hour(0): 50, hour(1): 60, hour(3): 10}
+	savings := map[int64]float64{hour(1): 15, hour(2): 20, hour(3): 25}
+
+	actual, simulated := service.ReplayProfiles(from, to, observed, savings)
+
+	// The hour without recorded consumption is left out, with its savings
+	require.Len(t, actual, 3)
+	require.Len(t, simulated, 3)
+	assert.Equal(t, from, actual[0].Start)
+	assert.Equal(t, 60, actual[0].Minutes)
+	assert.Equal(t, 50.0, simulated[0].EnergyKWh)
+	assert.Equal(t, 60.0, actual[1].EnergyKWh)
+	assert.Equal(t, 45.0, simulated[1].EnergyKWh)
+	// Savings never take an hour below zero
+	assert.Equal(t, from.Add(3*time.Hour), simulated[2].Start)
+	assert.Zero(t, simulated[2].EnergyKWh)
+}
+
+func TestReplayEnergyCost(t *testing.T) {
+	tariff := &models.Tariff{
+		BaseRate:    0.10,
+		CurrentRate: 0.10,
+		TimeOfUseRates: []models.TariffRate{
+			{Name: "Peak", RatePerKWh: 0.30, StartHour: 17, EndHour: 21},
+		},
+	}
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	// 15:00 UTC is 17:00 local, in the peak window; 10:00 UTC is off-peak
+	actual := []models.LoadInterval{
+		{Start: time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC), Minutes: 60, EnergyKWh: 40},
+		{Start: time.Date(2024, 7, 1, 15, 0, 0, 0, time.UTC), Minutes: 60, EnergyKWh: 40},
+	}
+	simulated := []models.LoadInterval{
+		{Start: actual[0].Start, Minutes: 60, EnergyKWh: 30},
+		{Start: actual[1].Start, Minutes: 60, EnergyKWh: 30},
+	}
+
+	// 10 kWh at 0.10 plus 10 kWh at 0.30
+	assert.InDelta(t, 4.0, service.ReplayEnergyCost(tariff, loc, actual, simulated), 1e-9)
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/repository"
+	"forecast-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestRetentionPurge tests that retention purges only forecasts created before the cutoff, that
+// a dry run only counts them and that a failing policy does not stop the others
+func TestRetentionPurge(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	forecasts := db.Collection("forecasts")
+	forecastRepo := repository.NewForecastRepository(forecasts)
+
+	// Insert directly, as the repository stamps the current time
+	old := time.Now().AddDate(0, 0, -400)
+	recent := time.Now().AddDate(0, 0, -30)
+	for _, createdAt := range []time.Time{old, old, recent} {
+		_, err := forecasts.InsertOne(ctx, &models.Forecast{
+			BuildingID: "building-1",
+			Type:       models.ForecastTypeDemand,
+			Status:     models.ForecastStatusCompleted,
+			CreatedAt:  createdAt,
+		})
+		require.NoError(t, err)
+	}
+
+	failing := func(ctx context.Context, before time.Time) (int64, error) {
+		return 0, errors.New("collection unavailable")
+	}
+
+	retention := service.NewRetentionService(true, time.Hour)
+	retention.Register("peak_loads", 90*24*time.Hour, failing, failing)
+	retention.Register("forecasts", 365*24*time.Hour, forecastRepo.CountOlderThan, forecastRepo.DeleteOlderThan)
+
+	status := retention.Run(ctx)
+	require.Len(t, status.Collections, 2)
+	assert.Equal(t, "collection unavailable", status.Collections[0].LastError)
+	assert.Equal(t, int64(2), status.Collections[1].LastAffected)
+	assert.Zero(t, status.Collections[1].TotalPurged)
+
+	total, err := forecasts.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total, "a dry run deletes nothing")
+
+	retention.SetDryRun(false)
+	status = retention.Run(ctx)
+	assert.Equal(t, int64(2), status.Collections[1].TotalPurged)
+	assert.Empty(t, status.Collections[1].LastError)
+
+	total, err = forecasts.CountDocuments(ctx, bson.M{"created_at": bson.M{"$lt": time.Now().AddDate(0, 0, -365)}})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	total, err = forecasts.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
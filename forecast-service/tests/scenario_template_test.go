@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextTemplateRun(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	schedule := &models.OccupancySchedule{BuildingID: "building-12", TimeZone: "Europe/Berlin"}
+	weekdays := &models.TemplateSchedule{
+		StartTime:       "14:00",
+		DurationMinutes: 240,
+		DaysOfWeek:      []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"},
+	}
+
+	t.Run("later the same day", func(t *testing.T) {
+		start, ok := service.NextTemplateRun(weekdays, schedule, time.Date(2024, 7, 5, 9, 0, 0, 0, berlin))
+		require.True(t, ok)
+		assert.True(t, start.Equal(time.Date(2024, 7, 5, 14, 0, 0, 0, berlin)))
+	})
+
+	t.Run("skips the weekend", func(t *testing.T) {
+		// Friday afternoon, after the start time
+		start, ok := service.NextTemplateRun(weekdays, schedule, time.Date(2024, 7, 5, 15, 0, 0, 0, berlin))
+		require.True(t, ok)
+		assert.True(t, start.Equal(time.Date(2024, 7, 8, 14, 0, 0, 0, berlin)))
+	})
+
+	t.Run("start time is in the building's time zone", func(t *testing.T) {
+		start, ok := service.NextTemplateRun(weekdays, schedule, time.Date(2024, 7, 5, 11, 30, 0, 0, time.UTC))
+		require.True(t, ok)
+		assert.True(t, start.Equal(time.Date(2024, 7, 5, 12, 0, 0, 0, time.UTC)), "14:00 in Berlin is 12:00 UTC in summer, got %s", start)
+	})
+
+	t.Run("holidays count as weekend days", func(t *testing.T) {
+		holidaySchedule := &models.OccupancySchedule{
+			BuildingID: "building-12",
+			TimeZone:   "Europe/Berlin",
+			Holidays:   []models.Holiday{{Date: "2024-07-08", Name: "Company Day", Source: models.HolidaySourceManual}},
+		}
+		start, ok := service.NextTemplateRun(weekdays, holidaySchedule, time.Date(2024, 7, 5, 15, 0, 0, 0, berlin))
+		require.True(t, ok)
+		assert.True(t, start.Equal(time.Date(2024, 7, 9, 14, 0, 0, 0, berlin)))
+
+		holidaysOnly := &models.TemplateSchedule{StartTime: "08:00", DurationMinutes: 60, DaysOfWeek: []string{"Holiday"}}
+		start, ok = service.NextTemplateRun(holidaysOnly, holidaySchedule, time.Date(2024, 7, 1, 0, 0, 0, 0, berlin))
+		require.True(t, ok)
+		assert.True(t, start.Equal(time.Date(2024, 7, 8, 8, 0, 0, 0, berlin)))
+	})
+
+	t.Run("no days means every day", func(t *testing.T) {
+		daily := &models.TemplateSchedule{StartTime: "06:30", DurationMinutes: 60}
+		start, ok := service.NextTemplateRun(daily, schedule, time.Date(2024, 7, 6, 7, 0, 0, 0, berlin))
+		require.True(t, ok)
+		assert.True(t, start.Equal(time.Date(2024, 7, 7, 6, 30, 0, 0, berlin)))
+	})
+
+	t.Run("no matching day", func(t *testing.T) {
+		holidaysOnly := &models.TemplateSchedule{StartTime: "08:00", DurationMinutes: 60, DaysOfWeek: []string{"Holiday"}}
+		_, ok := service.NextTemplateRun(holidaysOnly, schedule, time.Date(2024, 7, 1, 0, 0, 0, 0, berlin))
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"forecast-service/internal/models"
+	"forecast-service/internal/service"
+	"forecast-service/internal/tenant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOccupancyTenantIsolation tests that an organization cannot change the occupancy schedule
+// or business calendar of another organization's building. The checks fail before any
+// repository is used.
+func TestOccupancyTenantIsolation(t *testing.T) {
+	ctx := tenant.WithScope(context.Background(), &models.TenantScope{OrgID: "org-a", BuildingIDs: []string{"building-a"}})
+	occupancy := service.NewOccupancyService(nil, nil, nil, nil)
+	calendar := service.NewCalendarService(nil, nil)
+
+	_, err := occupancy.SaveSchedule(ctx, "building-b", &models.OccupancyScheduleRequest{}, "user-1")
+	assert.ErrorContains(t, err, "does not belong to your organization")
+
+	err = occupancy.DeleteSchedule(ctx, "building-b")
+	assert.ErrorContains(t, err, "does not belong to your organization")
+
+	_, err = occupancy.ImportHolidays(ctx, "building-b", "BEGIN:VCALENDAR\nEND:VCALENDAR", "user-1")
+	assert.ErrorContains(t, err, "does not belong to your organization")
+
+	day := &models.CalendarDayRequest{BuildingID: "building-b", Date: "2025-12-24", Type: models.CalendarDayHoliday, Name: "Christmas Eve"}
+	_, err = calendar.CreateDay(ctx, day, "user-1")
+	assert.ErrorContains(t, err, "does not belong to your organization")
+
+	_, err = calendar.UpdateDay(ctx, "64b000000000000000000000", day)
+	assert.ErrorContains(t, err, "does not belong to your organization")
+
+	_, err = calendar.ListDays(ctx, &models.ListCalendarDaysRequest{BuildingID: "building-b"})
+	assert.ErrorContains(t, err, "does not belong to your organization")
+
+	// Regional entries apply to every organization in the region
+	regional := &models.CalendarDayRequest{Region: "DE", Date: "2025-12-24", Type: models.CalendarDayHoliday, Name: "Christmas Eve"}
+	_, err = calendar.CreateDay(ctx, regional, "user-1")
+	assert.ErrorContains(t, err, "regional calendar entries")
+}
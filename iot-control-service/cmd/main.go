@@ -13,7 +13,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"iot-control-service/internal/cache"
 	"iot-control-service/internal/config"
+	"iot-control-service/internal/events"
 	"iot-control-service/internal/handlers"
 	"iot-control-service/internal/integrations"
 	"iot-control-service/internal/middleware"
@@ -21,15 +23,27 @@ import (
 	"iot-control-service/internal/mqtt"
 	"iot-control-service/internal/repository"
 	"iot-control-service/internal/service"
+	"iot-control-service/internal/settings"
+	"iot-control-service/internal/tracing"
+	"shared/jobs"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
+	// Set up tracing before anything opens connections, so they are instrumented
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
 	// Connect to MongoDB
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -59,56 +73,203 @@ func main() {
 	telemetryRepo := repository.NewTelemetryRepository(collections.Telemetry)
 	commandRepo := repository.NewCommandRepository(collections.DeviceCommands)
 	optimizationRepo := repository.NewOptimizationRepository(collections.OptimizationScenarios)
+	scheduleRepo := repository.NewScheduledCommandRepository(collections.ScheduledCommands)
+	deviceTypeRepo := repository.NewDeviceTypeRepository(collections.DeviceTypes)
+	provisioningRepo := repository.NewProvisioningRepository(collections.ProvisioningTokens)
+	weatherRuleRepo := repository.NewWeatherRuleRepository(collections.WeatherRules)
+	locationRepo := repository.NewLocationRepository(collections.Locations)
+	weatherRuleExecutionRepo := repository.NewWeatherRuleExecutionRepository(collections.WeatherRuleExecutions)
+	gatewayMappingRepo := repository.NewGatewayMappingRepository(collections.GatewayMappings)
 
 	// Initialize external integrations
 	securityClient := integrations.NewSecurityClient(cfg)
 	// Integration: ForecastClient enables fetching device predictions for optimization timing
 	forecastClient := integrations.NewForecastClient(cfg)
+	// Integration: cron schedules run in the time zones of the Forecast service's building registry
+	buildingTimeZones := integrations.NewBuildingTimeZones(forecastClient)
+	// Integration: predictions pushed by the Forecast service are cached for scenario execution
+	predictionCache := integrations.NewPredictionCache(cfg.Forecast.PredictionCacheTTL)
 	// Integration: AnalyticsClient enables checking anomalies before applying optimizations
 	analyticsClient := integrations.NewAnalyticsClient(cfg)
 
 	// Initialize MQTT client
+	topicAuthorizer := mqtt.NewTopicAuthorizer(deviceRepo, cfg.MQTT.AllowLegacyTopics)
 	mqttClient, err := mqtt.NewClient(cfg)
 	if err != nil {
 		log.Printf("Warning: Failed to connect to MQTT broker: %v", err)
 	} else {
 		defer mqttClient.Disconnect()
-		// Subscribe to MQTT telemetry and acks
-		setupMQTTSubscriptions(mqttClient, telemetryRepo, deviceRepo, commandRepo)
+		// Only accept device messages published on the device's own building topic
+		mqttClient.SetAuthorizer(topicAuthorizer)
+	}
+
+	// Connect to the inter-service event bus
+	var eventBus *events.Bus
+	if cfg.Events.Enabled {
+		eventBus = events.NewBus(cfg, "iot-control-service")
+		defer eventBus.Close()
 	}
 
+	// Runtime settings: stored overrides are loaded once every tunable is registered
+	settingsStore := settings.NewStore(collections.Settings, collections.SettingsHistory)
+
+	// Persistent job queue shared by asynchronous work
+	jobQueue := jobs.NewQueue(collections.Jobs, "iot-control-service", cfg.Auth.ServiceSecret, cfg.Jobs.PollInterval)
+
+	// Optional Redis cache for hot reads; without an address every lookup misses
+	hotCache := cache.New(cache.Config{
+		Addr:     cfg.Cache.RedisAddr,
+		Password: cfg.Cache.RedisPassword,
+		DB:       cfg.Cache.RedisDB,
+		Prefix:   "iot-control-service",
+		Timeout:  cfg.Cache.Timeout,
+		TTLs:     map[string]time.Duration{service.DeviceStateCacheKind: cfg.Cache.DeviceStateTTL},
+	})
+	defer hotCache.Close()
+
 	// Initialize services
-	deviceService := service.NewDeviceService(deviceRepo)
-	telemetryService := service.NewTelemetryService(telemetryRepo, deviceRepo)
-	controlService := service.NewControlService(commandRepo, deviceRepo, mqttClient, cfg.IoT.CommandTimeout)
+	deviceService := service.NewDeviceService(deviceRepo, deviceTypeRepo, hotCache)
+	deviceService.SetTopicAuthorizer(topicAuthorizer)
+	deviceTypeService := service.NewDeviceTypeService(deviceTypeRepo, deviceRepo)
+	if err := deviceTypeService.InitializeDefaultTypes(ctx); err != nil {
+		log.Printf("Warning: Failed to initialize default device types: %v", err)
+	}
+	telemetryService := service.NewTelemetryService(telemetryRepo, deviceRepo, hotCache, eventBus)
+	controlService := service.NewControlService(commandRepo, deviceRepo, deviceTypeRepo, telemetryRepo, optimizationRepo, mqttClient, eventBus, cfg.IoT.CommandTimeout, cfg.IoT.CommandRetries, cfg.IoT.CommandTTL, cfg.IoT.CommandApproval)
+	scheduleService := service.NewScheduleService(scheduleRepo, deviceRepo, controlService, buildingTimeZones)
 	// Integration: OptimizationService now uses ForecastClient and AnalyticsClient
 	// to fetch predictions and check anomalies before executing optimization scenarios
-	optimizationService := service.NewOptimizationService(optimizationRepo, commandRepo, deviceRepo, forecastClient, analyticsClient)
-	stateService := service.NewStateService(deviceRepo, telemetryRepo)
+	optimizationService := service.NewOptimizationService(optimizationRepo, commandRepo, deviceRepo, deviceTypeRepo, telemetryRepo, forecastClient, predictionCache, analyticsClient, eventBus, jobQueue)
+	stateService := service.NewStateService(deviceRepo, telemetryRepo, hotCache)
+	provisioningService := service.NewProvisioningService(provisioningRepo, deviceRepo, deviceService, cfg.IoT.ProvisioningTTL)
+	weatherRuleService := service.NewWeatherRuleService(weatherRuleRepo, weatherRuleExecutionRepo, deviceRepo, optimizationRepo, controlService, forecastClient, buildingTimeZones)
+	gatewayService := service.NewGatewayService(gatewayMappingRepo, deviceRepo, telemetryService)
+	locationService := service.NewLocationService(locationRepo, deviceRepo)
+
+	// Subscribe to MQTT telemetry and acks
+	if mqttClient != nil {
+		setupMQTTSubscriptions(mqttClient, telemetryService, controlService, provisioningService)
+	}
+
+	// Start background workers
+	workerCtx, workerCancel := context.WithCancel(context.Background())
+	defer workerCancel()
+	go jobQueue.Start(workerCtx, cfg.Jobs.Workers)
+	go controlService.StartExpiryWorker(workerCtx, cfg.IoT.CommandExpiryCheck)
+	go controlService.StartTimeoutWorker(workerCtx, cfg.IoT.CommandTimeoutCheck)
+	go scheduleService.StartSchedulerWorker(workerCtx, cfg.IoT.ScheduleCheck)
+	go deviceService.StartPurgeWorker(workerCtx, cfg.IoT.DeletedPurgeCheck, cfg.IoT.DeletedRetention)
+	go weatherRuleService.StartWeatherRuleWorker(workerCtx, cfg.IoT.WeatherRuleCheck)
+	go optimizationService.StartComfortGuardrailWorker(workerCtx, cfg.IoT.ComfortCheck)
+
+	// Run virtual devices for demos and testing without hardware
+	if cfg.Simulator.Enabled {
+		simulatorService := service.NewSimulatorService(deviceRepo, mqttClient, cfg.Simulator)
+		if err := simulatorService.RegisterDevices(ctx); err != nil {
+			log.Printf("Warning: Failed to start device simulator: %v", err)
+		} else {
+			go simulatorService.StartSimulatorWorker(workerCtx, cfg.Simulator.TelemetryInterval)
+		}
+	}
+
+	// Initialize data retention
+	retentionService := service.NewRetentionService(cfg.Retention.DryRun, cfg.Retention.Interval)
+	retentionService.Register("telemetry", cfg.Retention.Telemetry, telemetryRepo.CountOlderThan, telemetryRepo.DeleteOlderThan)
+	retentionService.Register("device_commands", cfg.Retention.Commands, commandRepo.CountOlderThan, commandRepo.DeleteOlderThan)
+	retentionDryRun := settingsStore.RegisterBool("retention.dry_run",
+		"Only count the records retention would purge", cfg.Retention.DryRun)
+	retentionDryRun.OnChange(func() { retentionService.SetDryRun(retentionDryRun.Get()) })
+	for collection, retention := range map[string]time.Duration{
+		"telemetry":       cfg.Retention.Telemetry,
+		"device_commands": cfg.Retention.Commands,
+	} {
+		collection := collection
+		days := settingsStore.RegisterInt("retention."+collection+"_days",
+			"Days "+collection+" records are kept; 0 disables purging", int(retention.Hours()/24), 0, 3650)
+		days.OnChange(func() { retentionService.SetRetention(collection, time.Duration(days.Get())*24*time.Hour) })
+	}
+
+	// Apply stored settings, then pick up changes made by other instances
+	if err := settingsStore.Load(ctx); err != nil {
+		log.Printf("Warning: Failed to load settings, using defaults: %v", err)
+	}
+	go settingsStore.Start(workerCtx, cfg.Settings.ReloadInterval)
+
+	if cfg.Retention.Enabled {
+		go retentionService.StartWorker(workerCtx)
+	}
+
+	// Initialize health checks
+	healthService := service.NewHealthService("iot-control-service")
+	healthService.Register("mongodb", true, mongoDB.HealthCheck)
+	healthService.Register("mqtt", true, mqttClient.HealthCheck)
+	healthService.Register("security_service", false, securityClient.HealthCheck)
+	healthService.Register("forecast_service", false, forecastClient.HealthCheck)
+	healthService.Register("analytics_service", false, analyticsClient.HealthCheck)
+	if eventBus != nil {
+		healthService.Register("event_bus", false, eventBus.HealthCheck)
+	}
+	if hotCache.Enabled() {
+		healthService.Register("redis", false, hotCache.HealthCheck)
+	}
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(securityClient)
+	authMiddleware := middleware.NewAuthMiddleware(securityClient, cfg.Auth)
+
+	// Consume events published by other services
+	if eventBus != nil {
+		deactivationService := service.NewDeactivationService(scheduleRepo, securityClient)
+		if err := events.NewSubscriber(eventBus, authMiddleware.Permissions(), deactivationService, predictionCache).Start(); err != nil {
+			log.Printf("Warning: Failed to subscribe to events: %v", err)
+		}
+	}
 
 	// Initialize handlers
 	deviceHandler := handlers.NewDeviceHandler(deviceService, securityClient)
+	deviceTypeHandler := handlers.NewDeviceTypeHandler(deviceTypeService, securityClient)
 	telemetryHandler := handlers.NewTelemetryHandler(telemetryService, securityClient)
-	controlHandler := handlers.NewControlHandler(controlService, securityClient)
+	controlHandler := handlers.NewControlHandler(controlService, scheduleService, securityClient)
 	optimizationHandler := handlers.NewOptimizationHandler(optimizationService, securityClient)
 	stateHandler := handlers.NewStateHandler(stateService)
+	healthHandler := handlers.NewHealthHandler(healthService, mqttClient, hotCache)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
+	authEventsHandler := handlers.NewAuthEventsHandler(authMiddleware.Permissions())
+	provisioningHandler := handlers.NewProvisioningHandler(provisioningService, securityClient)
+	weatherRuleHandler := handlers.NewWeatherRuleHandler(weatherRuleService, securityClient)
+	locationHandler := handlers.NewLocationHandler(locationService, securityClient)
+	gatewayHandler := handlers.NewGatewayHandler(gatewayService, securityClient)
+	jobHandler := handlers.NewJobHandler(jobQueue)
+	settingsHandler := handlers.NewSettingsHandler(settingsStore, securityClient)
 
 	// Create router
 	router := handlers.NewRouter(
 		deviceHandler,
+		deviceTypeHandler,
 		telemetryHandler,
 		controlHandler,
 		optimizationHandler,
 		stateHandler,
+		healthHandler,
+		retentionHandler,
+		authEventsHandler,
+		provisioningHandler,
+		weatherRuleHandler,
+		gatewayHandler,
+		jobHandler,
+		settingsHandler,
+		locationHandler,
 		authMiddleware,
 	)
 
+	// Meter API calls and monitored devices for billing
+	if eventBus != nil {
+		router.UsageMeter = middleware.NewUsageMeter()
+		go router.UsageMeter.StartReporter(workerCtx, cfg.Events.UsageReportInterval, deviceService.UsageReporter(eventBus))
+	}
+
 	// Create Gin engine and setup routes
 	engine := gin.New()
-	router.SetupRoutes(engine)
+	router.SetupRoutes(engine, cfg.HTTP)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -141,48 +302,56 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Flush the spans still buffered for export
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+
 	log.Println("Server exited properly")
 }
 
-// setupMQTTSubscriptions sets up MQTT subscriptions for telemetry and command acks
+// setupMQTTSubscriptions sets up MQTT subscriptions for telemetry, command acks and device claims
 func setupMQTTSubscriptions(
 	mqttClient *mqtt.Client,
-	telemetryRepo *repository.TelemetryRepository,
-	deviceRepo *repository.DeviceRepository,
-	commandRepo *repository.CommandRepository,
+	telemetryService *service.TelemetryService,
+	controlService *service.ControlService,
+	provisioningService *service.ProvisioningService,
 ) {
 	// Subscribe to all telemetry
-	mqttClient.SubscribeToAllTelemetry(func(deviceID string, telemetry *models.Telemetry) {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	mqttClient.SubscribeToAllTelemetry(func(ctx context.Context, deviceID string, telemetry *models.Telemetry) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
-		telemetry.Source = "MQTT"
-		_, err := telemetryRepo.Create(ctx, telemetry)
-		if err != nil {
+		if err := telemetryService.IngestDeviceTelemetry(ctx, telemetry, "MQTT"); err != nil {
 			log.Printf("Failed to save MQTT telemetry: %v", err)
-			return
 		}
-
-		// Update device last seen
-		deviceRepo.UpdateLastSeen(ctx, deviceID)
 	})
 
 	// Subscribe to all command acks
-	mqttClient.SubscribeToAllAcks(func(deviceID string, ack *models.CommandAck) {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	mqttClient.SubscribeToAllAcks(func(ctx context.Context, deviceID string, ack *models.CommandAck) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
-		_, err := commandRepo.FindByCommandID(ctx, ack.CommandID)
-		if err != nil {
-			log.Printf("Command not found for ack: %s", ack.CommandID)
-			return
+		if err := controlService.ProcessCommandAck(ctx, ack); err != nil {
+			log.Printf("Ignoring ack %s: %v", ack.CommandID, err)
 		}
+	})
 
-		status := models.CommandStatusApplied
-		if ack.Status == "FAILED" {
-			status = models.CommandStatusFailed
-		}
+	// Subscribe to claims of new devices and reply on each device's result topic
+	mqttClient.SubscribeToProvisioningClaims(func(req *models.ClaimDeviceRequest) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
 
-		commandRepo.UpdateStatus(ctx, ack.CommandID, status, ack.ErrorMsg)
+		result := &models.ProvisioningClaimResult{Success: true}
+		response, err := provisioningService.ClaimDevice(ctx, req)
+		if err != nil {
+			log.Printf("Rejected MQTT claim of device %s: %v", req.DeviceID, err)
+			result = &models.ProvisioningClaimResult{Error: err.Error()}
+		} else {
+			result.Result = response
+		}
+		if err := mqttClient.PublishProvisioningResult(req.DeviceID, result); err != nil {
+			log.Printf("Failed to publish claim result to device %s: %v", req.DeviceID, err)
+		}
 	})
 }
@@ -5,9 +5,12 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+
+	"shared/signing"
 )
 
 // Config holds all application configuration
@@ -15,12 +18,21 @@ type Config struct {
 	Server    ServerConfig
 	MongoDB   MongoDBConfig
 	Security  SecurityServiceConfig
+	Auth      AuthConfig
 	Forecast  ForecastServiceConfig
 	Analytics AnalyticsServiceConfig
 	Storage   StorageServiceConfig
 	MQTT      MQTTConfig
 	IoT       IoTConfig
+	Simulator SimulatorConfig
+	Retention RetentionConfig
+	Settings  SettingsConfig
+	Events    EventsConfig
+	Jobs      JobsConfig
+	Cache     CacheConfig
 	Logging   LoggingConfig
+	Tracing   TracingConfig
+	HTTP      HTTPConfig
 }
 
 // StorageServiceConfig holds Storage service integration settings
@@ -41,6 +53,14 @@ type MongoDBConfig struct {
 	URI      string
 	Database string
 	Timeout  time.Duration
+	// ReplicaSet, ReadPreference and WriteConcern are left to the URI and driver defaults when empty
+	ReplicaSet             string
+	ReadPreference         string
+	WriteConcern           string
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	ServerSelectionTimeout time.Duration
+	RetryWrites            bool
 }
 
 // SecurityServiceConfig holds Security service integration settings
@@ -49,10 +69,32 @@ type SecurityServiceConfig struct {
 	Timeout time.Duration
 }
 
+// AuthConfig holds token validation settings.
+// With local validation enabled, tokens are verified against the Security service signing key
+// instead of a round trip per request; JWTSecret pins the key instead of fetching it.
+type AuthConfig struct {
+	LocalValidation    bool
+	JWTSecret          string
+	SigningKeyRefresh  time.Duration
+	PermissionCacheTTL time.Duration
+
+	// ServiceKey is the shared secret internal requests are signed with. ServiceSecret overrides
+	// it for requests this service sends, and ServiceSecrets for requests from the named services.
+	// Requests from services without their own secret are only verified with ServiceKey when
+	// SharedSecretDevMode is set, since any service could otherwise sign as another.
+	ServiceKey          string
+	ServiceSecret       string
+	ServiceSecrets      map[string]string
+	SharedSecretDevMode bool
+	SignatureWindow     time.Duration
+	AcceptServiceKey    bool // also accept the unsigned X-Service-Key header during migration
+}
+
 // ForecastServiceConfig holds Forecast service integration settings
 type ForecastServiceConfig struct {
-	URL     string
-	Timeout time.Duration
+	URL                string
+	Timeout            time.Duration
+	PredictionCacheTTL time.Duration // how long predictions pushed over the event bus are used
 }
 
 // AnalyticsServiceConfig holds Analytics service integration settings
@@ -69,13 +111,83 @@ type MQTTConfig struct {
 	Password string
 	ClientID string
 	QoS      byte
+	// AllowLegacyTopics accepts flat mqtt/iot/{deviceId}/... topics from devices assigned
+	// to a building while they migrate to building-scoped topics
+	AllowLegacyTopics bool
 }
 
 // IoTConfig holds IoT-specific settings
 type IoTConfig struct {
 	TelemetryBatchSize  int
 	CommandTimeout      time.Duration
+	CommandTimeoutCheck time.Duration
+	CommandRetries      int // times a timed-out command is sent again; 0 disables retries
+	CommandTTL          time.Duration
+	CommandExpiryCheck  time.Duration
+	CommandApproval     time.Duration // how long a high-impact command waits for approval
+	ScheduleCheck       time.Duration
+	DeletedRetention    time.Duration
+	DeletedPurgeCheck   time.Duration
 	StateUpdateInterval time.Duration
+	ProvisioningTTL     time.Duration // default validity of device provisioning tokens
+	WeatherRuleCheck    time.Duration
+	ComfortCheck        time.Duration // how often completed scenarios are checked by the comfort guardrail
+}
+
+// SimulatorConfig holds settings of the virtual device simulator used for demos and testing.
+// When enabled, simulated devices are registered in BuildingID and behave like real devices on MQTT.
+type SimulatorConfig struct {
+	Enabled           bool
+	BuildingID        string
+	DeviceCount       int
+	TelemetryInterval time.Duration
+	AckDelay          time.Duration
+	FailurePercent    int // share of commands the simulated devices reject
+}
+
+// RetentionConfig holds per-collection data retention settings.
+// A retention of zero days disables purging for that collection.
+type RetentionConfig struct {
+	Enabled   bool
+	DryRun    bool
+	Interval  time.Duration
+	Telemetry time.Duration
+	Commands  time.Duration
+}
+
+// SettingsConfig holds runtime settings store settings
+type SettingsConfig struct {
+	ReloadInterval time.Duration // How often settings changed by other instances are picked up
+}
+
+// EventsConfig holds inter-service event bus settings.
+// Events are exchanged through the MQTT broker shared by all services.
+type EventsConfig struct {
+	Enabled  bool
+	Broker   string
+	Port     int
+	Username string
+	Password string
+	ClientID string
+	QoS      byte
+	// UsageReportInterval is how often the metered API usage is published for billing
+	UsageReportInterval time.Duration
+}
+
+// JobsConfig holds settings of the persistent job queue that runs asynchronous work
+type JobsConfig struct {
+	Workers      int
+	PollInterval time.Duration // how often idle workers check for due jobs
+}
+
+// CacheConfig holds settings of the optional Redis cache for hot reads.
+// An empty address disables caching.
+type CacheConfig struct {
+	RedisAddr      string
+	RedisPassword  string
+	RedisDB        int
+	Timeout        time.Duration
+	DeviceStateTTL time.Duration
 }
 
 // LoggingConfig holds logging configuration
@@ -84,6 +196,15 @@ type LoggingConfig struct {
 	Format string
 }
 
+// TracingConfig holds OpenTelemetry tracing settings. Spans are exported over OTLP/HTTP.
+type TracingConfig struct {
+	Enabled     bool
+	Endpoint    string  // collector host:port, or a full URL of its traces endpoint
+	Insecure    bool    // export over plain HTTP
+	ServiceName string  // service.name of the exported spans
+	SampleRatio float64 // share of traces started by this service that are recorded
+}
+
 // Load reads configuration from environment variables
 func Load() *Config {
 	// Load .env file if it exists
@@ -91,24 +212,46 @@ func Load() *Config {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	mode := getEnv("GIN_MODE", "debug")
+
 	return &Config{
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8083"),
 			Host: getEnv("SERVER_HOST", "0.0.0.0"),
-			Mode: getEnv("GIN_MODE", "debug"),
+			Mode: mode,
 		},
 		MongoDB: MongoDBConfig{
-			URI:      getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-			Database: getEnv("MONGODB_DATABASE", "iot_control_service"),
-			Timeout:  time.Duration(getEnvAsInt("MONGODB_TIMEOUT", 10)) * time.Second,
+			URI:                    getEnv("MONGODB_URI", "mongodb://localhost:27017"),
+			Database:               getEnv("MONGODB_DATABASE", "iot_control_service"),
+			Timeout:                time.Duration(getEnvAsInt("MONGODB_TIMEOUT", 10)) * time.Second,
+			ReplicaSet:             getEnv("MONGODB_REPLICA_SET", ""),
+			ReadPreference:         getEnv("MONGODB_READ_PREFERENCE", ""),
+			WriteConcern:           getEnv("MONGODB_WRITE_CONCERN", ""),
+			MaxPoolSize:            uint64(getEnvAsInt("MONGODB_MAX_POOL_SIZE", 100)),
+			MinPoolSize:            uint64(getEnvAsInt("MONGODB_MIN_POOL_SIZE", 10)),
+			ServerSelectionTimeout: time.Duration(getEnvAsInt("MONGODB_SERVER_SELECTION_TIMEOUT", 30)) * time.Second,
+			RetryWrites:            getEnvAsBool("MONGODB_RETRY_WRITES", true),
 		},
 		Security: SecurityServiceConfig{
 			URL:     getEnv("SECURITY_SERVICE_URL", "http://localhost:8080"),
 			Timeout: time.Duration(getEnvAsInt("SECURITY_SERVICE_TIMEOUT", 10)) * time.Second,
 		},
+		Auth: AuthConfig{
+			LocalValidation:     getEnv("AUTH_LOCAL_VALIDATION", "false") == "true",
+			JWTSecret:           getEnv("AUTH_JWT_SECRET", ""),
+			SigningKeyRefresh:   time.Duration(getEnvAsInt("AUTH_SIGNING_KEY_REFRESH_MINUTES", 60)) * time.Minute,
+			PermissionCacheTTL:  time.Duration(getEnvAsInt("AUTH_PERMISSION_CACHE_TTL_SECONDS", 30)) * time.Second,
+			ServiceKey:          getEnv("INTERNAL_SERVICE_KEY", ""),
+			ServiceSecret:       getEnv("INTERNAL_SERVICE_SECRET", getEnv("INTERNAL_SERVICE_KEY", "")),
+			ServiceSecrets:      signing.ParseSecrets(getEnv("INTERNAL_SERVICE_SECRETS", "")),
+			SharedSecretDevMode: getEnvAsBool("INTERNAL_SHARED_SECRET_DEV_MODE", false),
+			SignatureWindow:     time.Duration(getEnvAsInt("INTERNAL_SIGNATURE_WINDOW_SECONDS", 300)) * time.Second,
+			AcceptServiceKey:    getEnvAsBool("INTERNAL_ACCEPT_SERVICE_KEY", false),
+		},
 		Forecast: ForecastServiceConfig{
-			URL:     getEnv("FORECAST_SERVICE_URL", "http://localhost:8082"),
-			Timeout: time.Duration(getEnvAsInt("FORECAST_SERVICE_TIMEOUT", 10)) * time.Second,
+			URL:                getEnv("FORECAST_SERVICE_URL", "http://localhost:8082"),
+			Timeout:            time.Duration(getEnvAsInt("FORECAST_SERVICE_TIMEOUT", 10)) * time.Second,
+			PredictionCacheTTL: time.Duration(getEnvAsInt("FORECAST_PREDICTION_CACHE_TTL_MINUTES", 360)) * time.Minute,
 		},
 		Analytics: AnalyticsServiceConfig{
 			URL:     getEnv("ANALYTICS_SERVICE_URL", "http://localhost:8084"),
@@ -119,25 +262,91 @@ func Load() *Config {
 			Timeout: time.Duration(getEnvAsInt("STORAGE_SERVICE_TIMEOUT", 10)) * time.Second,
 		},
 		MQTT: MQTTConfig{
-			Broker:   getEnv("MQTT_BROKER", "localhost"),
-			Port:     getEnvAsInt("MQTT_PORT", 1883),
-			Username: getEnv("MQTT_USERNAME", ""),
-			Password: getEnv("MQTT_PASSWORD", ""),
-			ClientID: getEnv("MQTT_CLIENT_ID", "iot-control-service"),
-			QoS:      byte(getEnvAsInt("MQTT_QOS", 1)),
+			Broker:            getEnv("MQTT_BROKER", "localhost"),
+			Port:              getEnvAsInt("MQTT_PORT", 1883),
+			Username:          getEnv("MQTT_USERNAME", ""),
+			Password:          getEnv("MQTT_PASSWORD", ""),
+			ClientID:          getEnv("MQTT_CLIENT_ID", "iot-control-service"),
+			QoS:               byte(getEnvAsInt("MQTT_QOS", 1)),
+			AllowLegacyTopics: getEnv("MQTT_ALLOW_LEGACY_TOPICS", "false") == "true",
 		},
 		IoT: IoTConfig{
 			TelemetryBatchSize:  getEnvAsInt("IOT_TELEMETRY_BATCH_SIZE", 100),
 			CommandTimeout:      time.Duration(getEnvAsInt("IOT_COMMAND_TIMEOUT", 30)) * time.Second,
+			CommandTimeoutCheck: time.Duration(getEnvAsInt("IOT_COMMAND_TIMEOUT_CHECK_INTERVAL", 10)) * time.Second,
+			CommandRetries:      getEnvAsInt("IOT_COMMAND_TIMEOUT_RETRIES", 0),
+			CommandTTL:          time.Duration(getEnvAsInt("IOT_COMMAND_TTL", 900)) * time.Second,
+			CommandExpiryCheck:  time.Duration(getEnvAsInt("IOT_COMMAND_EXPIRY_CHECK_INTERVAL", 30)) * time.Second,
+			CommandApproval:     time.Duration(getEnvAsInt("IOT_COMMAND_APPROVAL_TIMEOUT", 3600)) * time.Second,
+			ScheduleCheck:       time.Duration(getEnvAsInt("IOT_SCHEDULE_CHECK_INTERVAL", 15)) * time.Second,
+			DeletedRetention:    time.Duration(getEnvAsInt("SOFT_DELETE_RETENTION_DAYS", 30)) * 24 * time.Hour,
+			DeletedPurgeCheck:   time.Duration(getEnvAsInt("SOFT_DELETE_PURGE_INTERVAL_HOURS", 24)) * time.Hour,
 			StateUpdateInterval: time.Duration(getEnvAsInt("IOT_STATE_UPDATE_INTERVAL", 5)) * time.Second,
+			ProvisioningTTL:     time.Duration(getEnvAsInt("IOT_PROVISIONING_TOKEN_TTL_HOURS", 24)) * time.Hour,
+			WeatherRuleCheck:    time.Duration(getEnvAsInt("IOT_WEATHER_RULE_CHECK_INTERVAL", 60)) * time.Second,
+			ComfortCheck:        time.Duration(getEnvAsInt("IOT_COMFORT_GUARDRAIL_CHECK_INTERVAL", 60)) * time.Second,
+		},
+		Simulator: SimulatorConfig{
+			Enabled:           getEnvAsBool("SIMULATOR_ENABLED", false),
+			BuildingID:        getEnv("SIMULATOR_BUILDING_ID", "sim-building"),
+			DeviceCount:       getEnvAsInt("SIMULATOR_DEVICE_COUNT", 8),
+			TelemetryInterval: time.Duration(getEnvAsInt("SIMULATOR_TELEMETRY_INTERVAL_SECONDS", 30)) * time.Second,
+			AckDelay:          time.Duration(getEnvAsInt("SIMULATOR_ACK_DELAY_MS", 500)) * time.Millisecond,
+			FailurePercent:    getEnvAsInt("SIMULATOR_FAILURE_PERCENT", 0),
+		},
+		Retention: RetentionConfig{
+			Enabled:   getEnv("RETENTION_ENABLED", "true") == "true",
+			DryRun:    getEnv("RETENTION_DRY_RUN", "false") == "true",
+			Interval:  time.Duration(getEnvAsInt("RETENTION_INTERVAL_HOURS", 24)) * time.Hour,
+			Telemetry: time.Duration(getEnvAsInt("RETENTION_TELEMETRY_DAYS", 30)) * 24 * time.Hour,
+			Commands:  time.Duration(getEnvAsInt("RETENTION_COMMAND_DAYS", 90)) * 24 * time.Hour,
+		},
+		Settings: SettingsConfig{
+			ReloadInterval: time.Duration(getEnvAsInt("SETTINGS_RELOAD_INTERVAL_SECONDS", 30)) * time.Second,
+		},
+		Events: EventsConfig{
+			Enabled:  getEnv("EVENTS_ENABLED", "false") == "true",
+			Broker:   getEnv("EVENT_BUS_BROKER", "localhost"),
+			Port:     getEnvAsInt("EVENT_BUS_PORT", 1883),
+			Username: getEnv("EVENT_BUS_USERNAME", ""),
+			Password: getEnv("EVENT_BUS_PASSWORD", ""),
+			ClientID: getEnv("EVENT_BUS_CLIENT_ID", "iot-control-service-events"),
+			QoS:      byte(getEnvAsInt("EVENT_BUS_QOS", 1)),
+
+			UsageReportInterval: time.Duration(getEnvAsInt("EVENT_BUS_USAGE_REPORT_INTERVAL_SECONDS", 60)) * time.Second,
+		},
+		Jobs: JobsConfig{
+			Workers:      getEnvAsInt("JOB_WORKERS", 4),
+			PollInterval: time.Duration(getEnvAsInt("JOB_POLL_INTERVAL_MS", 1000)) * time.Millisecond,
+		},
+		Cache: CacheConfig{
+			RedisAddr:      getEnv("REDIS_ADDR", ""),
+			RedisPassword:  getEnv("REDIS_PASSWORD", ""),
+			RedisDB:        getEnvAsInt("REDIS_DB", 0),
+			Timeout:        time.Duration(getEnvAsInt("REDIS_TIMEOUT_MS", 500)) * time.Millisecond,
+			DeviceStateTTL: time.Duration(getEnvAsInt("CACHE_DEVICE_STATE_TTL_SECONDS", 30)) * time.Second,
 		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "debug"),
 			Format: getEnv("LOG_FORMAT", "json"),
 		},
+		Tracing: TracingConfig{
+			Enabled:     getEnvAsBool("TRACING_ENABLED", false),
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
+			Insecure:    getEnvAsBool("OTEL_EXPORTER_OTLP_INSECURE", true),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "iot-control-service"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1.0),
+		},
+		HTTP: loadHTTPConfig(mode),
 	}
 }
 
+// Validate checks settings that would make the service unsafe or unusable, so startup fails
+// instead of serving with them
+func (c *Config) Validate() error {
+	return c.HTTP.Validate(c.Server.Mode)
+}
+
 // getEnv retrieves an environment variable with a default fallback
 func getEnv(key, defaultVal string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -146,6 +355,17 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+// getEnvAsList retrieves a comma-separated environment variable as a list, skipping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getEnvAsInt retrieves an environment variable as an integer
 func getEnvAsInt(key string, defaultVal int) int {
 	if value, exists := os.LookupEnv(key); exists {
@@ -155,3 +375,22 @@ func getEnvAsInt(key string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+func getEnvAsBool(key string, defaultVal bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultVal
+}
+
+// getEnvAsFloat retrieves an environment variable as a float
+func getEnvAsFloat(key string, defaultVal float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultVal
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// HTTPConfig holds the CORS and security header policies applied to every response
+type HTTPConfig struct {
+	CORS    CORSConfig
+	Headers SecurityHeadersConfig
+}
+
+// CORSConfig holds the cross-origin policy. An empty AllowedOrigins list allows no cross-origin
+// requests; "*" allows any origin and is rejected in release mode.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// SecurityHeadersConfig holds the security headers policy. Empty values omit their header, and
+// a zero HSTSMaxAge omits Strict-Transport-Security.
+type SecurityHeadersConfig struct {
+	ContentSecurityPolicy string
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	FrameOptions          string
+	ReferrerPolicy        string
+}
+
+// loadHTTPConfig reads the HTTP policies. Release mode defaults to a strict policy that allows
+// no cross-origin requests and enables HSTS; other modes default to a relaxed one for local
+// development.
+func loadHTTPConfig(mode string) HTTPConfig {
+	release := mode == "release"
+
+	origins := []string{"*"}
+	maxAge, hstsMaxAge := 86400, 0
+	csp := "default-src 'self'"
+	if release {
+		origins = nil
+		maxAge, hstsMaxAge = 600, 31536000
+		csp = "default-src 'none'; frame-ancestors 'none'"
+	}
+
+	return HTTPConfig{
+		CORS: CORSConfig{
+			AllowedOrigins:   getEnvAsListOr("CORS_ALLOWED_ORIGINS", origins),
+			AllowedMethods:   getEnvAsListOr("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+			AllowedHeaders:   getEnvAsListOr("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID", "Idempotency-Key"}),
+			ExposedHeaders:   getEnvAsListOr("CORS_EXPOSED_HEADERS", []string{"X-Request-ID"}),
+			AllowCredentials: getEnvAsBool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           time.Duration(getEnvAsInt("CORS_MAX_AGE_SECONDS", maxAge)) * time.Second,
+		},
+		Headers: SecurityHeadersConfig{
+			ContentSecurityPolicy: getEnv("SECURITY_CSP", csp),
+			HSTSMaxAge:            time.Duration(getEnvAsInt("SECURITY_HSTS_MAX_AGE_SECONDS", hstsMaxAge)) * time.Second,
+			HSTSIncludeSubdomains: getEnvAsBool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", true),
+			HSTSPreload:           getEnvAsBool("SECURITY_HSTS_PRELOAD", false),
+			FrameOptions:          getEnv("SECURITY_FRAME_OPTIONS", "DENY"),
+			ReferrerPolicy:        getEnv("SECURITY_REFERRER_POLICY", "strict-origin-when-cross-origin"),
+		},
+	}
+}
+
+// Validate checks the HTTP policies for the given Gin mode
+func (h HTTPConfig) Validate(mode string) error {
+	for _, origin := range h.CORS.AllowedOrigins {
+		if origin == "*" {
+			if mode == "release" {
+				return fmt.Errorf("CORS_ALLOWED_ORIGINS must list explicit origins in release mode, not *")
+			}
+			if h.CORS.AllowCredentials {
+				return fmt.Errorf("CORS_ALLOW_CREDENTIALS cannot be combined with the * origin")
+			}
+			continue
+		}
+		if strings.Contains(origin, "*") {
+			return fmt.Errorf("CORS origin %q: wildcard origins are not supported", origin)
+		}
+		parsed, err := url.Parse(origin)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") {
+			return fmt.Errorf("CORS origin %q must be a scheme and host such as https://app.example.com", origin)
+		}
+	}
+	for _, method := range h.CORS.AllowedMethods {
+		if method != strings.ToUpper(method) || strings.ContainsAny(method, " ,") {
+			return fmt.Errorf("CORS method %q must be an upper-case HTTP method", method)
+		}
+	}
+	if h.CORS.MaxAge < 0 {
+		return fmt.Errorf("CORS_MAX_AGE_SECONDS must not be negative")
+	}
+
+	if h.Headers.HSTSMaxAge < 0 {
+		return fmt.Errorf("SECURITY_HSTS_MAX_AGE_SECONDS must not be negative")
+	}
+	if h.Headers.HSTSPreload && (!h.Headers.HSTSIncludeSubdomains || h.Headers.HSTSMaxAge < 365*24*time.Hour) {
+		return fmt.Errorf("SECURITY_HSTS_PRELOAD requires SECURITY_HSTS_INCLUDE_SUBDOMAINS and a max age of at least one year")
+	}
+	switch h.Headers.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf("SECURITY_FRAME_OPTIONS must be DENY, SAMEORIGIN or empty, not %q", h.Headers.FrameOptions)
+	}
+	return nil
+}
+
+// getEnvAsListOr retrieves a comma-separated environment variable as a list, or the default when
+// it is not set. Setting it to an empty value yields an empty list.
+func getEnvAsListOr(key string, defaultVal []string) []string {
+	if _, exists := os.LookupEnv(key); !exists {
+		return defaultVal
+	}
+	return getEnvAsList(key)
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"log"
+)
+
+// PermissionInvalidator drops cached token validations for a user
+type PermissionInvalidator interface {
+	InvalidateUser(userID string)
+}
+
+// UserReleaser releases the resources a deactivated user owns
+type UserReleaser interface {
+	ReleaseUser(ctx context.Context, userID, actorID string) error
+}
+
+// ForecastCache keeps the latest predictions pushed by the Forecast service
+type ForecastCache interface {
+	UpdateForecast(data *ForecastCompletedData)
+}
+
+// Subscriber reacts to domain events published by other services
+type Subscriber struct {
+	bus         *Bus
+	permissions PermissionInvalidator
+	users       UserReleaser
+	forecasts   ForecastCache
+}
+
+// NewSubscriber creates the IoT service event subscriber
+func NewSubscriber(bus *Bus, permissions PermissionInvalidator, users UserReleaser, forecasts ForecastCache) *Subscriber {
+	return &Subscriber{
+		bus:         bus,
+		permissions: permissions,
+		users:       users,
+		forecasts:   forecasts,
+	}
+}
+
+// Start registers handlers for the events this service consumes
+func (s *Subscriber) Start() error {
+	if err := s.bus.Subscribe(ForecastCompleted, s.onForecastCompleted); err != nil {
+		return err
+	}
+	return s.bus.Subscribe(UserDeactivated, s.onUserDeactivated)
+}
+
+// onForecastCompleted caches the prediction of a completed device forecast for scenario execution
+func (s *Subscriber) onForecastCompleted(ctx context.Context, event *Event) error {
+	var data ForecastCompletedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	s.forecasts.UpdateForecast(&data)
+	return nil
+}
+
+// onUserDeactivated stops accepting cached or locally validated tokens of a deactivated user
+// and pauses the scheduled commands they own
+func (s *Subscriber) onUserDeactivated(ctx context.Context, event *Event) error {
+	var data UserDeactivatedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	s.permissions.InvalidateUser(data.UserID)
+	log.Printf("Invalidated cached permissions of deactivated user %s", data.UserID)
+
+	return s.users.ReleaseUser(ctx, data.UserID, data.DeactivatedBy)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -13,27 +14,34 @@ import (
 
 // ControlHandler handles device control-related requests
 type ControlHandler struct {
-	controlService *service.ControlService
-	securityClient interface {
+	controlService  *service.ControlService
+	scheduleService *service.ScheduleService
+	securityClient  interface {
 		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+		CheckPermission(ctx context.Context, userID, resource, action string) (bool, error)
 	}
 }
 
 // NewControlHandler creates a new control handler
 func NewControlHandler(
 	controlService *service.ControlService,
+	scheduleService *service.ScheduleService,
 	securityClient interface {
 		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+		CheckPermission(ctx context.Context, userID, resource, action string) (bool, error)
 	},
 ) *ControlHandler {
 	return &ControlHandler{
-		controlService: controlService,
-		securityClient: securityClient,
+		controlService:  controlService,
+		scheduleService: scheduleService,
+		securityClient:  securityClient,
 	}
 }
 
 // SendCommand handles command sending
 // POST /iot/device-control/{deviceId}/command
+// An optional Idempotency-Key header makes retries return the original command.
+// A scheduledAt time or cron expression in the body schedules the command instead.
 func (h *ControlHandler) SendCommand(c *gin.Context) {
 	deviceID := c.Param("deviceId")
 	if deviceID == "" {
@@ -47,10 +55,16 @@ func (h *ControlHandler) SendCommand(c *gin.Context) {
 
 	var req models.SendCommandRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if len(idempotencyKey) > 255 {
 		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
 			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
+			"Idempotency-Key header must not exceed 255 characters",
+			"",
 		))
 		return
 	}
@@ -59,12 +73,40 @@ func (h *ControlHandler) SendCommand(c *gin.Context) {
 	ipAddress := middleware.GetClientIP(c)
 	userAgent := middleware.GetUserAgent(c)
 
-	response, err := h.controlService.SendCommand(c.Request.Context(), deviceID, &req, userID)
+	if req.OverrideRateLimit {
+		if req.IsScheduled() {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				"overrideRateLimit applies to immediate commands only",
+				"",
+			))
+			return
+		}
+		if !middleware.HasRole(c, "admin") {
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				"Overriding the command rate limit requires the admin role",
+				"",
+			))
+			return
+		}
+	}
+
+	if req.IsScheduled() {
+		h.scheduleCommand(c, deviceID, &req, userID, ipAddress, userAgent)
+		return
+	}
+
+	details := map[string]interface{}{"deviceId": deviceID, "command": req.Command}
+	if req.OverrideRateLimit {
+		details["overrideRateLimit"] = true
+	}
+
+	response, replayed, err := h.controlService.SendCommand(c.Request.Context(), deviceID, &req, userID, idempotencyKey)
 	if err != nil {
 		h.securityClient.AuditLog(
 			c.Request.Context(), userID, "", "SEND_COMMAND", "command", "",
-			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
-			map[string]interface{}{"deviceId": deviceID, "command": req.Command},
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
 		)
 		// Check if device not found error
 		if strings.Contains(err.Error(), "device not found") {
@@ -75,6 +117,30 @@ func (h *ControlHandler) SendCommand(c *gin.Context) {
 			))
 			return
 		}
+		if strings.Contains(err.Error(), "idempotency key") {
+			c.JSON(http.StatusConflict, models.NewErrorResponse(
+				models.ErrCodeConflict,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		if strings.HasPrefix(err.Error(), "rate limit exceeded") {
+			c.JSON(http.StatusTooManyRequests, models.NewErrorResponse(
+				models.ErrCodeRateLimited,
+				err.Error(),
+				"",
+			))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			models.ErrCodeCommandFailed,
 			err.Error(),
@@ -83,14 +149,169 @@ func (h *ControlHandler) SendCommand(c *gin.Context) {
 		return
 	}
 
+	if replayed {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Command already accepted"))
+		return
+	}
+
+	if response.BuildingID != "" {
+		details["buildingId"] = response.BuildingID
+	}
 	h.securityClient.AuditLog(
 		c.Request.Context(), userID, "", "SEND_COMMAND", "command", response.CommandID,
-		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
-		map[string]interface{}{"deviceId": deviceID, "command": req.Command},
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
 	)
+	if response.Status == string(models.CommandStatusPendingApproval) {
+		c.JSON(http.StatusAccepted, models.NewSuccessResponse(response, "Command awaiting approval"))
+		return
+	}
 	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Command sent successfully"))
 }
 
+// ApproveCommand handles approving a high-impact command, which publishes it to the device
+// POST /iot/commands/{commandId}/approve
+func (h *ControlHandler) ApproveCommand(c *gin.Context) {
+	h.reviewCommand(c, "APPROVE_COMMAND", "Command approved and sent", h.controlService.ApproveCommand)
+}
+
+// RejectCommand handles rejecting a high-impact command so it is never sent
+// POST /iot/commands/{commandId}/reject
+func (h *ControlHandler) RejectCommand(c *gin.Context) {
+	h.reviewCommand(c, "REJECT_COMMAND", "Command rejected", h.controlService.RejectCommand)
+}
+
+// ListPendingApproval handles listing the commands awaiting approval
+// GET /iot/commands/pending-approval
+func (h *ControlHandler) ListPendingApproval(c *gin.Context) {
+	var req models.ListPendingApprovalRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	responses, total, err := h.controlService.ListPendingApproval(c.Request.Context(), req.Page, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"commands": responses,
+		"total":    total,
+		"page":     req.Page,
+		"limit":    req.Limit,
+	}, ""))
+}
+
+// reviewCommand approves or rejects a command awaiting approval and audits it. Reviewers need
+// the commands:approve permission unless they are admins.
+func (h *ControlHandler) reviewCommand(
+	c *gin.Context,
+	action, message string,
+	review func(ctx context.Context, commandID, reviewerID, comment string) (*models.CommandResponse, error),
+) {
+	commandID := c.Param("commandId")
+	userID := middleware.GetUserID(c)
+
+	var req models.ReviewCommandRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondValidationError(c, "Invalid request body", err)
+			return
+		}
+	}
+
+	allowed := middleware.HasRole(c, "admin")
+	if !allowed {
+		var err error
+		allowed, err = h.securityClient.CheckPermission(c.Request.Context(), userID, "commands", "approve")
+		if err != nil {
+			c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+				models.ErrCodeExternalAPIError,
+				"Failed to check permissions",
+				err.Error(),
+			))
+			return
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(
+			models.ErrCodeForbidden,
+			"Reviewing commands requires the commands:approve permission",
+			"",
+		))
+		return
+	}
+
+	details := map[string]interface{}{}
+	if req.Comment != "" {
+		details["comment"] = req.Comment
+	}
+
+	response, err := review(c.Request.Context(), commandID, userID, req.Comment)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", action, "command", commandID,
+			"FAILURE", err.Error(), middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method, details,
+		)
+		switch {
+		case err.Error() == "command not found":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				err.Error(),
+				"",
+			))
+		case err.Error() == "command is not awaiting approval":
+			c.JSON(http.StatusConflict, models.NewErrorResponse(
+				models.ErrCodeConflict,
+				err.Error(),
+				"",
+			))
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				err.Error(),
+				"",
+			))
+		case strings.Contains(err.Error(), "device not found"):
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeDeviceNotFound,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeCommandFailed,
+				err.Error(),
+				"",
+			))
+		}
+		return
+	}
+
+	details["deviceId"] = response.DeviceID
+	details["command"] = response.Command
+	if response.BuildingID != "" {
+		details["buildingId"] = response.BuildingID
+	}
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", action, "command", commandID,
+		"SUCCESS", "", middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, message))
+}
+
 // ListCommands handles command listing
 // GET /iot/device-control/{deviceId}/commands
 func (h *ControlHandler) ListCommands(c *gin.Context) {
@@ -106,11 +327,7 @@ func (h *ControlHandler) ListCommands(c *gin.Context) {
 
 	var req models.ListCommandsRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid query parameters",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid query parameters", err)
 		return
 	}
 
@@ -144,3 +361,203 @@ func (h *ControlHandler) ListCommands(c *gin.Context) {
 		"limit":    req.Limit,
 	}, ""))
 }
+
+// GetCommandHistory handles the command audit view, correlating commands with resulting state changes
+// GET /iot/devices/{deviceId}/command-history
+func (h *ControlHandler) GetCommandHistory(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	var req models.CommandHistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	entries, total, err := h.controlService.GetCommandHistory(c.Request.Context(), deviceID, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "device not found") {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeDeviceNotFound,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	flagged := 0
+	for _, entry := range entries {
+		if entry.Flagged {
+			flagged++
+		}
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"history": entries,
+		"flagged": flagged,
+		"total":   total,
+		"page":    req.Page,
+		"limit":   req.Limit,
+	}, ""))
+}
+
+// scheduleCommand handles a command request with a scheduledAt time or cron expression
+func (h *ControlHandler) scheduleCommand(c *gin.Context, deviceID string, req *models.SendCommandRequest, userID, ipAddress, userAgent string) {
+	details := map[string]interface{}{"deviceId": deviceID, "command": req.Command}
+	if req.ScheduledAt != nil {
+		details["scheduledAt"] = req.ScheduledAt
+	}
+	if req.Cron != "" {
+		details["cron"] = req.Cron
+	}
+
+	schedule, err := h.scheduleService.ScheduleCommand(c.Request.Context(), deviceID, req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "SCHEDULE_COMMAND", "scheduled_command", "",
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+		)
+		switch {
+		case strings.Contains(err.Error(), "device not found"):
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeDeviceNotFound,
+				err.Error(),
+				"",
+			))
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				err.Error(),
+				"",
+			))
+		}
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "SCHEDULE_COMMAND", "scheduled_command", schedule.ScheduleID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(schedule, "Command scheduled successfully"))
+}
+
+// ListScheduledCommands handles scheduled command listing
+// GET /iot/device-control/{deviceId}/scheduled
+func (h *ControlHandler) ListScheduledCommands(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	var req models.ListScheduledCommandsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	schedules, total, err := h.scheduleService.ListScheduledCommands(c.Request.Context(), deviceID, req.Status, req.Page, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"scheduledCommands": schedules,
+		"total":             total,
+		"page":              req.Page,
+		"limit":             req.Limit,
+	}, ""))
+}
+
+// PauseScheduledCommand handles pausing a scheduled command
+// POST /iot/device-control/{deviceId}/scheduled/{scheduleId}/pause
+func (h *ControlHandler) PauseScheduledCommand(c *gin.Context) {
+	h.changeSchedule(c, "PAUSE_SCHEDULED_COMMAND", "Scheduled command paused", h.scheduleService.PauseScheduledCommand)
+}
+
+// ResumeScheduledCommand handles resuming a paused scheduled command
+// POST /iot/device-control/{deviceId}/scheduled/{scheduleId}/resume
+func (h *ControlHandler) ResumeScheduledCommand(c *gin.Context) {
+	h.changeSchedule(c, "RESUME_SCHEDULED_COMMAND", "Scheduled command resumed", h.scheduleService.ResumeScheduledCommand)
+}
+
+// CancelScheduledCommand handles cancelling a pending scheduled command
+// DELETE /iot/device-control/{deviceId}/scheduled/{scheduleId}
+func (h *ControlHandler) CancelScheduledCommand(c *gin.Context) {
+	h.changeSchedule(c, "CANCEL_SCHEDULED_COMMAND", "Scheduled command cancelled", h.scheduleService.CancelScheduledCommand)
+}
+
+// changeSchedule applies a status change to a scheduled command and audits it
+func (h *ControlHandler) changeSchedule(
+	c *gin.Context,
+	action, message string,
+	change func(ctx context.Context, deviceID, scheduleID string) (*models.ScheduledCommand, error),
+) {
+	deviceID := c.Param("deviceId")
+	scheduleID := c.Param("scheduleId")
+	userID := middleware.GetUserID(c)
+	details := map[string]interface{}{"deviceId": deviceID}
+
+	schedule, err := change(c.Request.Context(), deviceID, scheduleID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", action, "scheduled_command", scheduleID,
+			"FAILURE", err.Error(), middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method, details,
+		)
+		switch err.Error() {
+		case "scheduled command not found":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				err.Error(),
+				"",
+			))
+		case "scheduled command is not active", "scheduled command is not paused",
+			"scheduled command has already finished", "scheduled command was modified concurrently":
+			c.JSON(http.StatusConflict, models.NewErrorResponse(
+				models.ErrCodeConflict,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				err.Error(),
+				"",
+			))
+		}
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", action, "scheduled_command", scheduleID,
+		"SUCCESS", "", middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(schedule, message))
+}
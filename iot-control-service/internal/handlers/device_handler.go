@@ -2,6 +2,8 @@
 package handlers
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"strings"
 
@@ -17,6 +19,7 @@ type DeviceHandler struct {
 	deviceService  *service.DeviceService
 	securityClient interface {
 		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+		CheckPermission(ctx context.Context, userID, resource, action string) (bool, error)
 	}
 }
 
@@ -25,6 +28,7 @@ func NewDeviceHandler(
 	deviceService *service.DeviceService,
 	securityClient interface {
 		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+		CheckPermission(ctx context.Context, userID, resource, action string) (bool, error)
 	},
 ) *DeviceHandler {
 	return &DeviceHandler{
@@ -38,11 +42,7 @@ func NewDeviceHandler(
 func (h *DeviceHandler) RegisterDevice(c *gin.Context) {
 	var req models.RegisterDeviceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -66,6 +66,14 @@ func (h *DeviceHandler) RegisterDevice(c *gin.Context) {
 			))
 			return
 		}
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			models.ErrCodeInternalError,
 			err.Error(),
@@ -83,7 +91,7 @@ func (h *DeviceHandler) RegisterDevice(c *gin.Context) {
 }
 
 // GetDevice handles device retrieval
-// GET /iot/devices/{deviceId}
+// GET /iot/devices/{deviceId}?fields=
 func (h *DeviceHandler) GetDevice(c *gin.Context) {
 	deviceID := c.Param("deviceId")
 	if deviceID == "" {
@@ -95,7 +103,17 @@ func (h *DeviceHandler) GetDevice(c *gin.Context) {
 		return
 	}
 
-	response, err := h.deviceService.GetDevice(c.Request.Context(), deviceID)
+	fields, err := models.ParseFieldSelection(c.Query("fields"), models.Device{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	response, err := h.deviceService.GetDevice(c.Request.Context(), deviceID, fields)
 	if err != nil {
 		if err.Error() == "device not found" {
 			c.JSON(http.StatusNotFound, models.NewErrorResponse(
@@ -113,19 +131,25 @@ func (h *DeviceHandler) GetDevice(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+	data, err := fields.Apply(response)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(data, ""))
 }
 
 // ListDevices handles device listing
-// GET /iot/devices
+// GET /iot/devices?fields=
 func (h *DeviceHandler) ListDevices(c *gin.Context) {
 	var req models.ListDevicesRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid query parameters",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid query parameters", err)
 		return
 	}
 
@@ -136,6 +160,16 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 		req.Limit = 20
 	}
 
+	fields, err := models.ParseFieldSelection(req.Fields, models.Device{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
 	responses, total, err := h.deviceService.ListDevices(
 		c.Request.Context(),
 		req.BuildingID,
@@ -143,6 +177,7 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 		req.Status,
 		req.Page,
 		req.Limit,
+		fields,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
@@ -153,6 +188,227 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 		return
 	}
 
+	devices, err := fields.Apply(responses)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"devices": devices,
+		"total":   total,
+		"page":    req.Page,
+		"limit":   req.Limit,
+	}, ""))
+}
+
+// SearchDevices handles searching devices by free text and filters with cursor pagination.
+// Callers without read access to buildings receive no results.
+// GET /search
+func (h *DeviceHandler) SearchDevices(c *gin.Context) {
+	var req models.SearchDevicesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	allowed := middleware.HasRole(c, "admin")
+	if !allowed {
+		var err error
+		allowed, err = h.securityClient.CheckPermission(c.Request.Context(), middleware.GetUserID(c), "buildings", "read")
+		if err != nil {
+			c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+				models.ErrCodeExternalAPIError,
+				"Failed to check permissions",
+				err.Error(),
+			))
+			return
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(&models.SearchResponse{Results: []*models.SearchResult{}}, ""))
+		return
+	}
+
+	response, err := h.deviceService.SearchDevices(c.Request.Context(), &req)
+	if err != nil {
+		if err.Error() == "invalid cursor" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				"Invalid cursor",
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// SetCalibration handles replacing a device's metric calibration factors
+// PUT /iot/devices/{deviceId}/calibration
+func (h *DeviceHandler) SetCalibration(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	var req models.SetCalibrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.deviceService.SetCalibration(c.Request.Context(), deviceID, &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "SET_DEVICE_CALIBRATION", "device", deviceID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		if err.Error() == "device not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeDeviceNotFound,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "SET_DEVICE_CALIBRATION", "device", deviceID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"metrics": len(req.Metrics)},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Device calibration updated successfully"))
+}
+
+// DeleteDevice handles soft deleting a device
+// DELETE /iot/devices/{deviceId}
+func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.deviceService.DeleteDevice(c.Request.Context(), deviceID, userID); err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "DELETE_DEVICE", "device", deviceID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		if err.Error() == "device not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeDeviceNotFound,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "DELETE_DEVICE", "device", deviceID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Device deleted successfully"))
+}
+
+// RestoreDevice handles restoring a soft-deleted device
+// POST /iot/devices/{deviceId}/restore
+func (h *DeviceHandler) RestoreDevice(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.deviceService.RestoreDevice(c.Request.Context(), deviceID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "RESTORE_DEVICE", "device", deviceID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		if err.Error() == "deleted device not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeDeviceNotFound,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "RESTORE_DEVICE", "device", deviceID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Device restored successfully"))
+}
+
+// ListDeletedDevices handles listing soft-deleted devices awaiting purge
+// GET /iot/devices/deleted
+func (h *DeviceHandler) ListDeletedDevices(c *gin.Context) {
+	var req models.ListDevicesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	responses, total, err := h.deviceService.ListDeletedDevices(c.Request.Context(), req.BuildingID, req.Page, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
 		"devices": responses,
 		"total":   total,
@@ -160,3 +416,65 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 		"limit":   req.Limit,
 	}, ""))
 }
+
+// maxDeviceImportSize caps the size of a CSV device import
+const maxDeviceImportSize = 5 << 20
+
+// ImportDevices handles creating or updating devices in bulk from a CSV document or a JSON list
+// POST /iot/devices/import
+func (h *DeviceHandler) ImportDevices(c *gin.Context) {
+	var rows []models.DeviceImportRow
+	if c.ContentType() == "text/csv" {
+		parsed, err := service.ParseDeviceImportCSV(io.LimitReader(c.Request.Body, maxDeviceImportSize))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		rows = parsed
+	} else {
+		var req models.ImportDevicesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondValidationError(c, "Invalid request body", err)
+			return
+		}
+		rows = req.Devices
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	result, err := h.deviceService.ImportDevices(c.Request.Context(), rows, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "IMPORT_DEVICES", "device", "",
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			map[string]interface{}{"rows": len(rows)},
+		)
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "IMPORT_DEVICES", "device", "",
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"created": result.Created, "updated": result.Updated, "unchanged": result.Unchanged, "failed": result.Failed},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result, "Devices imported successfully"))
+}
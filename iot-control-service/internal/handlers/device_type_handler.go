@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"iot-control-service/internal/middleware"
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/service"
+)
+
+// DeviceTypeHandler handles device type catalog requests
+type DeviceTypeHandler struct {
+	deviceTypeService *service.DeviceTypeService
+	securityClient    interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewDeviceTypeHandler creates a new device type handler
+func NewDeviceTypeHandler(
+	deviceTypeService *service.DeviceTypeService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *DeviceTypeHandler {
+	return &DeviceTypeHandler{
+		deviceTypeService: deviceTypeService,
+		securityClient:    securityClient,
+	}
+}
+
+// ListDeviceTypes handles listing the device type catalog
+// GET /iot/device-types
+func (h *DeviceTypeHandler) ListDeviceTypes(c *gin.Context) {
+	response, err := h.deviceTypeService.ListDeviceTypes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetDeviceType handles retrieving a device type
+// GET /iot/device-types/{name}
+func (h *DeviceTypeHandler) GetDeviceType(c *gin.Context) {
+	response, err := h.deviceTypeService.GetDeviceType(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// CreateDeviceType handles adding a device type to the catalog
+// POST /iot/device-types
+func (h *DeviceTypeHandler) CreateDeviceType(c *gin.Context) {
+	var req models.CreateDeviceTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.deviceTypeService.CreateDeviceType(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "CREATE_DEVICE_TYPE", "device_type", req.Name,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "CREATE_DEVICE_TYPE", "device_type", response.Name,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Device type created successfully"))
+}
+
+// UpdateDeviceType handles updating a device type
+// PUT /iot/device-types/{name}
+func (h *DeviceTypeHandler) UpdateDeviceType(c *gin.Context) {
+	name := c.Param("name")
+
+	var req models.UpdateDeviceTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.deviceTypeService.UpdateDeviceType(c.Request.Context(), name, &req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "UPDATE_DEVICE_TYPE", "device_type", name,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "UPDATE_DEVICE_TYPE", "device_type", response.Name,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Device type updated successfully"))
+}
+
+// DeleteDeviceType handles removing a device type from the catalog
+// DELETE /iot/device-types/{name}
+func (h *DeviceTypeHandler) DeleteDeviceType(c *gin.Context) {
+	name := c.Param("name")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.deviceTypeService.DeleteDeviceType(c.Request.Context(), name); err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "DELETE_DEVICE_TYPE", "device_type", name,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "DELETE_DEVICE_TYPE", "device_type", name,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Device type deleted successfully"))
+}
+
+// respondError maps device type service errors to HTTP responses
+func (h *DeviceTypeHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "device type not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeNotFound,
+			err.Error(),
+			"",
+		))
+	case err.Error() == "device type already exists",
+		err.Error() == "cannot delete system device type",
+		strings.HasPrefix(err.Error(), "device type is in use"):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(
+			models.ErrCodeConflict,
+			err.Error(),
+			"",
+		))
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+	}
+}
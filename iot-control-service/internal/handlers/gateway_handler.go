@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"iot-control-service/internal/middleware"
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/service"
+)
+
+// GatewayHandler handles Modbus and BACnet gateway requests
+type GatewayHandler struct {
+	gatewayService *service.GatewayService
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewGatewayHandler creates a new gateway handler
+func NewGatewayHandler(
+	gatewayService *service.GatewayService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *GatewayHandler {
+	return &GatewayHandler{
+		gatewayService: gatewayService,
+		securityClient: securityClient,
+	}
+}
+
+// IngestReadings handles a batch of register readings posted by a gateway
+// POST /iot/gateways/readings
+func (h *GatewayHandler) IngestReadings(c *gin.Context) {
+	var req models.GatewayReadingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"readings": len(req.Readings)}
+
+	result, err := h.gatewayService.IngestReadings(c.Request.Context(), &req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "INGEST_GATEWAY_READINGS", "telemetry", req.GatewayID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	details["accepted"] = result.ReadingsAccepted
+	details["rejected"] = result.ReadingsRejected
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "INGEST_GATEWAY_READINGS", "telemetry", req.GatewayID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result, "Gateway readings processed"))
+}
+
+// SaveMapping handles creating or replacing the register mapping of a device
+// PUT /iot/gateways/mappings/{deviceId}
+func (h *GatewayHandler) SaveMapping(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	var req models.GatewayMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"protocol": req.Protocol, "registers": len(req.Registers)}
+
+	mapping, err := h.gatewayService.SaveMapping(c.Request.Context(), deviceID, &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "SAVE_GATEWAY_MAPPING", "gateway_mapping", deviceID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "SAVE_GATEWAY_MAPPING", "gateway_mapping", deviceID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(mapping, "Gateway mapping saved successfully"))
+}
+
+// ListMappings handles listing gateway mappings
+// GET /iot/gateways/mappings?buildingId=&page=&limit=
+func (h *GatewayHandler) ListMappings(c *gin.Context) {
+	var req models.ListGatewayMappingsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	mappings, total, err := h.gatewayService.ListMappings(c.Request.Context(), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"mappings": mappings,
+		"total":    total,
+		"page":     req.Page,
+		"limit":    req.Limit,
+	}, ""))
+}
+
+// GetMapping handles retrieving the register mapping of a device
+// GET /iot/gateways/mappings/{deviceId}
+func (h *GatewayHandler) GetMapping(c *gin.Context) {
+	mapping, err := h.gatewayService.GetMapping(c.Request.Context(), c.Param("deviceId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(mapping, ""))
+}
+
+// DeleteMapping handles deleting the register mapping of a device
+// DELETE /iot/gateways/mappings/{deviceId}
+func (h *GatewayHandler) DeleteMapping(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.gatewayService.DeleteMapping(c.Request.Context(), deviceID); err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "DELETE_GATEWAY_MAPPING", "gateway_mapping", deviceID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "DELETE_GATEWAY_MAPPING", "gateway_mapping", deviceID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Gateway mapping deleted successfully"))
+}
+
+// respondError maps gateway service errors to HTTP responses
+func (h *GatewayHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "device") && strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeDeviceNotFound,
+			err.Error(),
+			"",
+		))
+	case err.Error() == "gateway mapping not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeNotFound,
+			err.Error(),
+			"",
+		))
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"iot-control-service/internal/cache"
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/service"
+)
+
+// MQTTStatsSource reports the MQTT connection counters
+type MQTTStatsSource interface {
+	Stats() models.MQTTStats
+}
+
+// CacheStatsSource reports the cache hits and misses per kind of object
+type CacheStatsSource interface {
+	Stats() cache.Stats
+}
+
+// HealthHandler handles health check requests
+type HealthHandler struct {
+	healthService *service.HealthService
+	mqtt          MQTTStatsSource
+	cache         CacheStatsSource
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(healthService *service.HealthService, mqtt MQTTStatsSource, cache CacheStatsSource) *HealthHandler {
+	return &HealthHandler{healthService: healthService, mqtt: mqtt, cache: cache}
+}
+
+// Health reports that the service process is up
+// GET /health
+func (h *HealthHandler) Health(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  models.HealthStatusHealthy,
+		"service": "iot-control-service",
+	})
+}
+
+// DeepHealth checks the service's dependencies and reports a component map with latencies.
+// Responds with 503 when a critical dependency is unavailable so it can back readiness probes.
+// GET /health/deep
+func (h *HealthHandler) DeepHealth(c *gin.Context) {
+	health := h.healthService.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if health.Status == models.HealthStatusUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, health)
+}
+
+// GetMQTTStats reports broker reconnects and the subscriptions restored after them
+// GET /iot/admin/mqtt
+func (h *HealthHandler) GetMQTTStats(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.mqtt.Stats(), ""))
+}
+
+// GetCacheStats reports whether the Redis cache is enabled and its hits and misses per kind
+// GET /iot/admin/cache
+func (h *HealthHandler) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.cache.Stats(), ""))
+}
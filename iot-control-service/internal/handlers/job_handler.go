@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"iot-control-service/internal/models"
+	"shared/jobs"
+)
+
+// JobHandler handles job queue administration requests
+type JobHandler struct {
+	queue *jobs.Queue
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(queue *jobs.Queue) *JobHandler {
+	return &JobHandler{queue: queue}
+}
+
+// ListJobs handles listing queued and finished jobs
+// GET /iot/admin/jobs?status=&type=&page=&limit=
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	list, total, err := h.queue.List(c.Request.Context(), strings.ToUpper(c.Query("status")), c.Query("type"), page, limit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"jobs":  list,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}, ""))
+}
+
+// GetStats handles counting jobs by type and status
+// GET /iot/admin/jobs/stats
+func (h *JobHandler) GetStats(c *gin.Context) {
+	stats, err := h.queue.Stats(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(stats, ""))
+}
+
+// GetJob handles retrieving a job
+// GET /iot/admin/jobs/{jobId}
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, err := h.queue.Get(c.Request.Context(), c.Param("jobId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(job, ""))
+}
+
+// RetryJob handles queueing a failed or cancelled job again
+// POST /iot/admin/jobs/{jobId}/retry
+func (h *JobHandler) RetryJob(c *gin.Context) {
+	job, err := h.queue.Retry(c.Request.Context(), c.Param("jobId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(job, "Job queued for retry"))
+}
+
+// CancelJob handles cancelling a pending job
+// POST /iot/admin/jobs/{jobId}/cancel
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	job, err := h.queue.Cancel(c.Request.Context(), c.Param("jobId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(job, "Job cancelled"))
+}
+
+// respondError maps job queue errors to HTTP responses
+func (h *JobHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "job not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeNotFound,
+			err.Error(),
+			"",
+		))
+	case err.Error() == "invalid job ID format":
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+	case strings.HasPrefix(err.Error(), "only "), errors.Is(err, jobs.ErrInvalidIdentity):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(
+			models.ErrCodeConflict,
+			err.Error(),
+			"",
+		))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+	}
+}
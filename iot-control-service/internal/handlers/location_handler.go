@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"iot-control-service/internal/middleware"
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/service"
+)
+
+// LocationHandler handles location hierarchy, device assignment and floor plan requests
+type LocationHandler struct {
+	locationService *service.LocationService
+	securityClient  interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewLocationHandler creates a new location handler
+func NewLocationHandler(
+	locationService *service.LocationService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *LocationHandler {
+	return &LocationHandler{
+		locationService: locationService,
+		securityClient:  securityClient,
+	}
+}
+
+// CreateLocation handles adding a location to the hierarchy
+// POST /iot/locations
+func (h *LocationHandler) CreateLocation(c *gin.Context) {
+	var req models.CreateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"level": req.Level, "parentId": req.ParentID}
+
+	location, err := h.locationService.CreateLocation(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "CREATE_LOCATION", "location", req.LocationID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "CREATE_LOCATION", "location", location.LocationID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(location, "Location created successfully"))
+}
+
+// ListLocations handles listing locations
+// GET /iot/locations?buildingId=&parentId=&level=
+func (h *LocationHandler) ListLocations(c *gin.Context) {
+	var req models.ListLocationsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	locations, err := h.locationService.ListLocations(c.Request.Context(), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"locations": locations,
+		"total":     len(locations),
+	}, ""))
+}
+
+// GetLocation handles retrieving a location with its children and device count
+// GET /iot/locations/{locationId}
+func (h *LocationHandler) GetLocation(c *gin.Context) {
+	location, err := h.locationService.GetLocation(c.Request.Context(), c.Param("locationId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(location, ""))
+}
+
+// UpdateLocation handles renaming a location or changing its area or shape
+// PUT /iot/locations/{locationId}
+func (h *LocationHandler) UpdateLocation(c *gin.Context) {
+	locationID := c.Param("locationId")
+
+	var req models.UpdateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	location, err := h.locationService.UpdateLocation(c.Request.Context(), locationID, &req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "UPDATE_LOCATION", "location", locationID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "UPDATE_LOCATION", "location", locationID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(location, "Location updated successfully"))
+}
+
+// DeleteLocation handles removing a location without children or devices
+// DELETE /iot/locations/{locationId}
+func (h *LocationHandler) DeleteLocation(c *gin.Context) {
+	locationID := c.Param("locationId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.locationService.DeleteLocation(c.Request.Context(), locationID); err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "DELETE_LOCATION", "location", locationID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "DELETE_LOCATION", "location", locationID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Location deleted successfully"))
+}
+
+// ListLocationDevices handles listing the devices assigned to a location or any location below it
+// GET /iot/locations/{locationId}/devices
+func (h *LocationHandler) ListLocationDevices(c *gin.Context) {
+	devices, err := h.locationService.ListLocationDevices(c.Request.Context(), c.Param("locationId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"devices": devices,
+		"total":   len(devices),
+	}, ""))
+}
+
+// AssignDevices handles assigning devices to a floor, zone or room
+// POST /iot/locations/{locationId}/devices
+func (h *LocationHandler) AssignDevices(c *gin.Context) {
+	locationID := c.Param("locationId")
+
+	var req models.AssignDevicesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	result, err := h.locationService.AssignDevices(c.Request.Context(), locationID, &req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "ASSIGN_DEVICES", "location", locationID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			map[string]interface{}{"devices": len(req.DeviceIDs)},
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "ASSIGN_DEVICES", "location", locationID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"assigned": result.Assigned, "failed": len(result.Failed)},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result, "Devices assigned"))
+}
+
+// UnassignDevice handles removing a device from the location it is assigned to
+// DELETE /iot/locations/{locationId}/devices/{deviceId}
+func (h *LocationHandler) UnassignDevice(c *gin.Context) {
+	locationID := c.Param("locationId")
+	deviceID := c.Param("deviceId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"deviceId": deviceID}
+
+	if err := h.locationService.UnassignDevice(c.Request.Context(), locationID, deviceID); err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "UNASSIGN_DEVICE", "location", locationID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "UNASSIGN_DEVICE", "location", locationID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Device unassigned"))
+}
+
+// GetFloorPlan handles retrieving a floor plan with its zones, rooms and devices
+// GET /iot/locations/{locationId}/floor-plan
+func (h *LocationHandler) GetFloorPlan(c *gin.Context) {
+	view, err := h.locationService.GetFloorPlan(c.Request.Context(), c.Param("locationId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(view, ""))
+}
+
+// UploadFloorPlan handles uploading a floor plan and the zones drawn on it
+// PUT /iot/locations/{locationId}/floor-plan
+func (h *LocationHandler) UploadFloorPlan(c *gin.Context) {
+	floorID := c.Param("locationId")
+
+	var req models.FloorPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"zones": len(req.Zones)}
+
+	view, err := h.locationService.UploadFloorPlan(c.Request.Context(), floorID, &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "UPLOAD_FLOOR_PLAN", "location", floorID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "UPLOAD_FLOOR_PLAN", "location", floorID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(view, "Floor plan uploaded successfully"))
+}
+
+// respondError maps location service errors to HTTP responses
+func (h *LocationHandler) respondError(c *gin.Context, err error) {
+	switch {
+	// validation errors may name a parent location that was not found
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+	case strings.HasSuffix(err.Error(), "not found") || strings.Contains(err.Error(), "is not assigned to location"):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeNotFound,
+			err.Error(),
+			"",
+		))
+	case strings.Contains(err.Error(), "already exists"):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(
+			models.ErrCodeConflict,
+			err.Error(),
+			"",
+		))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+	}
+}
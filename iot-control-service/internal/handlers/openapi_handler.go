@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"iot-control-service/internal/cache"
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/openapi"
+	"iot-control-service/internal/settings"
+	"iot-control-service/internal/validation"
+)
+
+// operations describes the query parameters, request bodies and response data of the handlers
+// for the OpenAPI document. Routes of other handlers are listed without schemas.
+var operations = map[string]openapi.Operation{
+	"AuthEventsHandler.RoleChanged":             {Body: models.RoleChangeEvent{}},
+	"ControlHandler.SendCommand":                {Body: models.SendCommandRequest{}, Response: models.CommandResponse{}},
+	"ControlHandler.ListPendingApproval":        {Query: models.ListPendingApprovalRequest{}},
+	"ControlHandler.ListCommands":               {Query: models.ListCommandsRequest{}},
+	"ControlHandler.GetCommandHistory":          {Query: models.CommandHistoryRequest{}},
+	"ControlHandler.ListScheduledCommands":      {Query: models.ListScheduledCommandsRequest{}},
+	"DeviceHandler.RegisterDevice":              {Body: models.RegisterDeviceRequest{}, Response: models.DeviceResponse{}},
+	"DeviceHandler.ListDevices":                 {Query: models.ListDevicesRequest{}},
+	"DeviceHandler.SearchDevices":               {Query: models.SearchDevicesRequest{}, Response: models.SearchResponse{}},
+	"DeviceHandler.SetCalibration":              {Body: models.SetCalibrationRequest{}, Response: models.DeviceResponse{}},
+	"DeviceHandler.RestoreDevice":               {Response: models.DeviceResponse{}},
+	"DeviceHandler.ListDeletedDevices":          {Query: models.ListDevicesRequest{}},
+	"DeviceHandler.ImportDevices":               {Body: models.ImportDevicesRequest{}, Response: models.DeviceImportResult{}},
+	"DeviceTypeHandler.ListDeviceTypes":         {Response: []*models.DeviceTypeResponse{}},
+	"DeviceTypeHandler.GetDeviceType":           {Response: models.DeviceTypeResponse{}},
+	"DeviceTypeHandler.CreateDeviceType":        {Body: models.CreateDeviceTypeRequest{}, Response: models.DeviceTypeResponse{}},
+	"DeviceTypeHandler.UpdateDeviceType":        {Body: models.UpdateDeviceTypeRequest{}, Response: models.DeviceTypeResponse{}},
+	"GatewayHandler.IngestReadings":             {Body: models.GatewayReadingsRequest{}, Response: models.GatewayIngestResult{}},
+	"GatewayHandler.SaveMapping":                {Body: models.GatewayMappingRequest{}, Response: models.GatewayMapping{}},
+	"GatewayHandler.ListMappings":               {Query: models.ListGatewayMappingsRequest{}},
+	"GatewayHandler.GetMapping":                 {Response: models.GatewayMapping{}},
+	"HealthHandler.GetMQTTStats":                {Response: models.MQTTStats{}},
+	"HealthHandler.GetCacheStats":               {Response: cache.Stats{}},
+	"LocationHandler.CreateLocation":            {Body: models.CreateLocationRequest{}, Response: models.Location{}},
+	"LocationHandler.ListLocations":             {Query: models.ListLocationsRequest{}},
+	"LocationHandler.GetLocation":               {Response: models.LocationDetail{}},
+	"LocationHandler.UpdateLocation":            {Body: models.UpdateLocationRequest{}, Response: models.Location{}},
+	"LocationHandler.AssignDevices":             {Body: models.AssignDevicesRequest{}, Response: models.AssignDevicesResult{}},
+	"LocationHandler.GetFloorPlan":              {Response: models.FloorPlanView{}},
+	"LocationHandler.UploadFloorPlan":           {Body: models.FloorPlanRequest{}, Response: models.FloorPlanView{}},
+	"OptimizationHandler.ApplyOptimization":     {Body: models.ApplyOptimizationRequest{}, Response: models.OptimizationScenarioResponse{}},
+	"OptimizationHandler.GetOptimizationStatus": {Response: models.OptimizationScenarioResponse{}},
+	"OptimizationHandler.ResumeOptimization":    {Response: models.OptimizationScenarioResponse{}},
+	"ProvisioningHandler.CreateToken":           {Body: models.CreateProvisioningTokenRequest{}, Response: models.CreateProvisioningTokenResponse{}},
+	"ProvisioningHandler.RevokeToken":           {Response: models.ProvisioningToken{}},
+	"ProvisioningHandler.ClaimDevice":           {Body: models.ClaimDeviceRequest{}, Response: models.ClaimDeviceResponse{}},
+	"StateHandler.GetLiveState":                 {Response: models.LiveStateResponse{}},
+	"StateHandler.GetDeviceState":               {Response: models.DeviceState{}},
+	"TelemetryHandler.IngestTelemetry":          {Body: models.TelemetryIngestRequest{}, Response: models.TelemetryResponse{}},
+	"TelemetryHandler.IngestBulkTelemetry":      {Body: models.BulkTelemetryIngestRequest{}, Response: []*models.TelemetryResponse{}},
+	"TelemetryHandler.GetTelemetryHistory":      {Query: models.TelemetryHistoryRequest{}},
+	"TelemetryHandler.QueryTelemetry":           {Query: models.TelemetryQueryRequest{}, Response: models.TelemetryQueryResponse{}},
+	"TelemetryHandler.ExportTelemetry":          {Query: models.TelemetryExportRequest{}},
+	"WeatherRuleHandler.CreateRule":             {Body: models.WeatherRuleRequest{}, Response: models.WeatherRule{}},
+	"WeatherRuleHandler.ListRules":              {Query: models.ListWeatherRulesRequest{}},
+	"WeatherRuleHandler.GetRule":                {Response: models.WeatherRule{}},
+	"WeatherRuleHandler.UpdateRule":             {Body: models.WeatherRuleRequest{}, Response: models.WeatherRule{}},
+	"WeatherRuleHandler.EvaluateRule":           {Response: models.WeatherRuleExecution{}},
+	"SettingsHandler.UpdateSetting":             {Body: settings.UpdateRequest{}},
+}
+
+// OpenAPI serves the OpenAPI 3 document of the service. It is built on the first request, once
+// every route is registered on the engine.
+// GET /openapi.json
+func OpenAPI(engine *gin.Engine) gin.HandlerFunc {
+	var once sync.Once
+	var document openapi.Document
+	return func(c *gin.Context) {
+		once.Do(func() {
+			document = openapi.Build(openapi.Info{
+				Title:       "IoT Control Service API",
+				Version:     "1.0.0",
+				Description: "Device registry, telemetry ingestion and device control",
+			}, engine.Routes(), operations)
+		})
+		c.JSON(http.StatusOK, document)
+	}
+}
+
+// respondValidationError responds to a request that failed binding, listing the fields that failed
+func respondValidationError(c *gin.Context, message string, err error) {
+	c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(message, err.Error(), validation.FieldErrors(err)))
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -37,11 +38,7 @@ func NewOptimizationHandler(
 func (h *OptimizationHandler) ApplyOptimization(c *gin.Context) {
 	var req models.ApplyOptimizationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -49,6 +46,11 @@ func (h *OptimizationHandler) ApplyOptimization(c *gin.Context) {
 	ipAddress := middleware.GetClientIP(c)
 	userAgent := middleware.GetUserAgent(c)
 
+	if req.DryRun {
+		h.dryRunOptimization(c, &req, userID, ipAddress, userAgent)
+		return
+	}
+
 	response, err := h.optimizationService.ApplyOptimization(c.Request.Context(), &req, userID)
 	if err != nil {
 		h.securityClient.AuditLog(
@@ -72,6 +74,31 @@ func (h *OptimizationHandler) ApplyOptimization(c *gin.Context) {
 	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Optimization scenario applied successfully"))
 }
 
+// dryRunOptimization handles previewing an optimization scenario without sending any command
+func (h *OptimizationHandler) dryRunOptimization(c *gin.Context, req *models.ApplyOptimizationRequest, userID, ipAddress, userAgent string) {
+	report, err := h.optimizationService.DryRunOptimization(c.Request.Context(), req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "DRY_RUN_OPTIMIZATION", "optimization", req.ScenarioID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+			map[string]interface{}{"buildingId": req.BuildingID},
+		)
+		status, code := http.StatusInternalServerError, models.ErrCodeOptimizationFailed
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			status, code = http.StatusBadRequest, models.ErrCodeValidationFailed
+		}
+		c.JSON(status, models.NewErrorResponse(code, err.Error(), ""))
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "DRY_RUN_OPTIMIZATION", "optimization", req.ScenarioID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"buildingId": req.BuildingID, "wouldSucceed": report.Summary.WouldSucceed, "conflicts": report.Summary.Conflicts},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(report, "Dry run completed; no commands were sent"))
+}
+
 // GetOptimizationStatus handles optimization status retrieval
 // GET /iot/optimization/status/{scenarioId}
 func (h *OptimizationHandler) GetOptimizationStatus(c *gin.Context) {
@@ -105,3 +132,36 @@ func (h *OptimizationHandler) GetOptimizationStatus(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
 }
+
+// ResumeOptimization handles executing the failed and pending actions of a finished scenario again
+// POST /iot/optimization/{scenarioId}/resume
+func (h *OptimizationHandler) ResumeOptimization(c *gin.Context) {
+	scenarioID := c.Param("scenarioId")
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.optimizationService.ResumeScenario(c.Request.Context(), scenarioID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "RESUME_OPTIMIZATION", "optimization", scenarioID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		switch {
+		case err.Error() == "scenario not found":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeOptimizationFailed, err.Error(), ""))
+		}
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "RESUME_OPTIMIZATION", "optimization", scenarioID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"buildingId": response.BuildingID},
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Optimization scenario resumed"))
+}
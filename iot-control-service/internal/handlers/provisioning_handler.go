@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"iot-control-service/internal/middleware"
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/service"
+)
+
+// ProvisioningHandler handles provisioning token and device claim requests
+type ProvisioningHandler struct {
+	provisioningService *service.ProvisioningService
+	securityClient      interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewProvisioningHandler creates a new provisioning handler
+func NewProvisioningHandler(
+	provisioningService *service.ProvisioningService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *ProvisioningHandler {
+	return &ProvisioningHandler{
+		provisioningService: provisioningService,
+		securityClient:      securityClient,
+	}
+}
+
+// CreateToken handles generating a one-time claim token for a building
+// POST /iot/provisioning/tokens
+func (h *ProvisioningHandler) CreateToken(c *gin.Context) {
+	var req models.CreateProvisioningTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"buildingId": req.BuildingID, "deviceType": req.DeviceType}
+
+	response, err := h.provisioningService.CreateToken(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "CREATE_PROVISIONING_TOKEN", "provisioning_token", "",
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "CREATE_PROVISIONING_TOKEN", "provisioning_token", response.ProvisioningToken.ID.Hex(),
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Provisioning token created; it will not be shown again"))
+}
+
+// ListTokens handles listing outstanding claim tokens
+// GET /iot/provisioning/tokens?buildingId=
+func (h *ProvisioningHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.provisioningService.ListOutstandingTokens(c.Request.Context(), c.Query("buildingId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"tokens": tokens,
+		"total":  len(tokens),
+	}, ""))
+}
+
+// RevokeToken handles invalidating an outstanding claim token
+// DELETE /iot/provisioning/tokens/{tokenId}
+func (h *ProvisioningHandler) RevokeToken(c *gin.Context) {
+	tokenID := c.Param("tokenId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.provisioningService.RevokeToken(c.Request.Context(), tokenID, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "REVOKE_PROVISIONING_TOKEN", "provisioning_token", tokenID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "REVOKE_PROVISIONING_TOKEN", "provisioning_token", tokenID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Provisioning token revoked successfully"))
+}
+
+// ClaimDevice handles a new device registering itself with a claim token. The token
+// authenticates the request, so no user token is needed.
+// POST /iot/provisioning/claim
+func (h *ProvisioningHandler) ClaimDevice(c *gin.Context) {
+	var req models.ClaimDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"transport": "HTTP", "type": req.Type}
+
+	response, err := h.provisioningService.ClaimDevice(c.Request.Context(), &req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), "", "", "CLAIM_DEVICE", "device", req.DeviceID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), "", "", "CLAIM_DEVICE", "device", req.DeviceID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Device registered successfully"))
+}
+
+// respondError maps provisioning service errors to HTTP responses
+func (h *ProvisioningHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "provisioning token not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeNotFound,
+			err.Error(),
+			"",
+		))
+	case err.Error() == "provisioning token is invalid, expired or already used":
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			models.ErrCodeTokenInvalid,
+			err.Error(),
+			"",
+		))
+	case strings.Contains(err.Error(), "already exists"):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(
+			models.ErrCodeDeviceExists,
+			err.Error(),
+			"",
+		))
+	case err.Error() == "invalid provisioning token ID format",
+		strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+	}
+}
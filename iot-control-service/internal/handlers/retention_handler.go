@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/service"
+)
+
+// RetentionHandler handles data retention requests
+type RetentionHandler struct {
+	retentionService *service.RetentionService
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(retentionService *service.RetentionService) *RetentionHandler {
+	return &RetentionHandler{retentionService: retentionService}
+}
+
+// GetStatus reports retention policies and purged record counts
+// GET /iot/admin/retention
+func (h *RetentionHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.retentionService.Status(), ""))
+}
+
+// RunNow applies retention policies immediately
+// POST /iot/admin/retention/run
+func (h *RetentionHandler) RunNow(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.retentionService.Run(c.Request.Context()), "Retention run completed"))
+}
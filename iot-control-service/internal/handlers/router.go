@@ -3,63 +3,120 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 
+	"iot-control-service/internal/config"
 	"iot-control-service/internal/middleware"
+	"iot-control-service/internal/validation"
 )
 
 // Router holds all handler dependencies
 type Router struct {
 	DeviceHandler       *DeviceHandler
+	DeviceTypeHandler   *DeviceTypeHandler
 	TelemetryHandler    *TelemetryHandler
 	ControlHandler      *ControlHandler
 	OptimizationHandler *OptimizationHandler
 	StateHandler        *StateHandler
+	HealthHandler       *HealthHandler
+	RetentionHandler    *RetentionHandler
+	AuthEventsHandler   *AuthEventsHandler
+	ProvisioningHandler *ProvisioningHandler
+	WeatherRuleHandler  *WeatherRuleHandler
+	GatewayHandler      *GatewayHandler
+	JobHandler          *JobHandler
+	SettingsHandler     *SettingsHandler
+	LocationHandler     *LocationHandler
 	AuthMiddleware      *middleware.AuthMiddleware
+
+	// UsageMeter meters API calls for billing when set
+	UsageMeter *middleware.UsageMeter
 }
 
 // NewRouter creates a new router with all handlers
 func NewRouter(
 	deviceHandler *DeviceHandler,
+	deviceTypeHandler *DeviceTypeHandler,
 	telemetryHandler *TelemetryHandler,
 	controlHandler *ControlHandler,
 	optimizationHandler *OptimizationHandler,
 	stateHandler *StateHandler,
+	healthHandler *HealthHandler,
+	retentionHandler *RetentionHandler,
+	authEventsHandler *AuthEventsHandler,
+	provisioningHandler *ProvisioningHandler,
+	weatherRuleHandler *WeatherRuleHandler,
+	gatewayHandler *GatewayHandler,
+	jobHandler *JobHandler,
+	settingsHandler *SettingsHandler,
+	locationHandler *LocationHandler,
 	authMiddleware *middleware.AuthMiddleware,
 ) *Router {
 	return &Router{
 		DeviceHandler:       deviceHandler,
+		DeviceTypeHandler:   deviceTypeHandler,
 		TelemetryHandler:    telemetryHandler,
 		ControlHandler:      controlHandler,
 		OptimizationHandler: optimizationHandler,
 		StateHandler:        stateHandler,
+		HealthHandler:       healthHandler,
+		RetentionHandler:    retentionHandler,
+		AuthEventsHandler:   authEventsHandler,
+		ProvisioningHandler: provisioningHandler,
+		WeatherRuleHandler:  weatherRuleHandler,
+		GatewayHandler:      gatewayHandler,
+		JobHandler:          jobHandler,
+		SettingsHandler:     settingsHandler,
+		LocationHandler:     locationHandler,
 		AuthMiddleware:      authMiddleware,
 	}
 }
 
-// SetupRoutes configures all API routes
-func (r *Router) SetupRoutes(engine *gin.Engine) {
+// SetupRoutes configures all API routes, applying the given CORS and security header policies
+func (r *Router) SetupRoutes(engine *gin.Engine, policy config.HTTPConfig) {
+	// Register the custom binding rules and report validation errors by JSON field name
+	validation.Register()
+
 	// Apply common middleware
 	engine.Use(middleware.Recovery())
+	engine.Use(middleware.Tracing())
 	engine.Use(middleware.RequestID())
-	engine.Use(middleware.CORS())
-	engine.Use(middleware.SecurityHeaders())
+	engine.Use(middleware.Localization())
+	engine.Use(middleware.CORS(policy.CORS))
+	engine.Use(middleware.SecurityHeaders(policy.Headers))
 	engine.Use(middleware.RequestLogger())
+	if r.UsageMeter != nil {
+		engine.Use(r.UsageMeter.Middleware())
+	}
+
+	// Health check endpoints
+	engine.GET("/health", r.HealthHandler.Health)
+	engine.GET("/health/deep", r.HealthHandler.DeepHealth)
 
-	// Health check endpoint
-	engine.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "healthy",
-			"service": "iot-control-service",
-		})
-	})
+	// OpenAPI document of the routes, for generating clients
+	engine.GET("/openapi.json", OpenAPI(engine))
+
+	// Internal notifications from other services
+	internal := engine.Group("/internal")
+	internal.Use(r.AuthMiddleware.RequireServiceKey())
+	{
+		internal.POST("/auth/role-changed", r.AuthEventsHandler.RoleChanged)
+		// Device listing for kiosk dashboards, which carry no user token
+		internal.GET("/devices", r.DeviceHandler.ListDevices)
+	}
 
 	// API v1 routes
 	api := engine.Group("/api/v1")
 	{
 		r.setupTelemetryRoutes(api)
 		r.setupDeviceRoutes(api)
+		r.setupDeviceTypeRoutes(api)
+		r.setupProvisioningRoutes(api)
 		r.setupControlRoutes(api)
 		r.setupOptimizationRoutes(api)
+		r.setupWeatherRuleRoutes(api)
+		r.setupLocationRoutes(api)
+		r.setupGatewayRoutes(api)
 		r.setupStateRoutes(api)
+		r.setupAdminRoutes(api)
 	}
 
 	// Legacy routes (without /api/v1 prefix for backward compatibility)
@@ -74,6 +131,8 @@ func (r *Router) setupTelemetryRoutes(rg *gin.RouterGroup) {
 		telemetry.POST("", r.TelemetryHandler.IngestTelemetry)
 		telemetry.POST("/bulk", r.TelemetryHandler.IngestBulkTelemetry)
 		telemetry.GET("/history", r.TelemetryHandler.GetTelemetryHistory)
+		telemetry.GET("/query", r.TelemetryHandler.QueryTelemetry)
+		telemetry.GET("/export", r.TelemetryHandler.ExportTelemetry)
 	}
 }
 
@@ -85,6 +144,38 @@ func (r *Router) setupDeviceRoutes(rg *gin.RouterGroup) {
 		devices.GET("", r.DeviceHandler.ListDevices)
 		devices.GET("/:deviceId", r.DeviceHandler.GetDevice)
 		devices.POST("/register", r.DeviceHandler.RegisterDevice)
+		devices.POST("/import", r.AuthMiddleware.RequireAdmin(), r.DeviceHandler.ImportDevices)
+		devices.GET("/deleted", r.AuthMiddleware.RequireAdmin(), r.DeviceHandler.ListDeletedDevices)
+		devices.DELETE("/:deviceId", r.AuthMiddleware.RequireAdmin(), r.DeviceHandler.DeleteDevice)
+		devices.POST("/:deviceId/restore", r.AuthMiddleware.RequireAdmin(), r.DeviceHandler.RestoreDevice)
+		devices.PUT("/:deviceId/calibration", r.AuthMiddleware.RequireAdmin(), r.DeviceHandler.SetCalibration)
+		devices.GET("/:deviceId/command-history", r.ControlHandler.GetCommandHistory)
+	}
+	rg.GET("/iot/search", r.AuthMiddleware.RequireAuth(), r.DeviceHandler.SearchDevices)
+}
+
+// setupDeviceTypeRoutes configures device type catalog routes
+func (r *Router) setupDeviceTypeRoutes(rg *gin.RouterGroup) {
+	deviceTypes := rg.Group("/iot/device-types")
+	deviceTypes.Use(r.AuthMiddleware.RequireAuth())
+	{
+		deviceTypes.GET("", r.DeviceTypeHandler.ListDeviceTypes)
+		deviceTypes.GET("/:name", r.DeviceTypeHandler.GetDeviceType)
+		deviceTypes.POST("", r.AuthMiddleware.RequireAdmin(), r.DeviceTypeHandler.CreateDeviceType)
+		deviceTypes.PUT("/:name", r.AuthMiddleware.RequireAdmin(), r.DeviceTypeHandler.UpdateDeviceType)
+		deviceTypes.DELETE("/:name", r.AuthMiddleware.RequireAdmin(), r.DeviceTypeHandler.DeleteDeviceType)
+	}
+}
+
+// setupProvisioningRoutes configures device provisioning routes. Claims are authenticated by
+// the provisioning token instead of a user token.
+func (r *Router) setupProvisioningRoutes(rg *gin.RouterGroup) {
+	provisioning := rg.Group("/iot/provisioning")
+	{
+		provisioning.POST("/claim", r.ProvisioningHandler.ClaimDevice)
+		provisioning.POST("/tokens", r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin(), r.ProvisioningHandler.CreateToken)
+		provisioning.GET("/tokens", r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin(), r.ProvisioningHandler.ListTokens)
+		provisioning.DELETE("/tokens/:tokenId", r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin(), r.ProvisioningHandler.RevokeToken)
 	}
 }
 
@@ -95,6 +186,18 @@ func (r *Router) setupControlRoutes(rg *gin.RouterGroup) {
 	{
 		control.POST("/:deviceId/command", r.ControlHandler.SendCommand)
 		control.GET("/:deviceId/commands", r.ControlHandler.ListCommands)
+		control.GET("/:deviceId/scheduled", r.ControlHandler.ListScheduledCommands)
+		control.POST("/:deviceId/scheduled/:scheduleId/pause", r.ControlHandler.PauseScheduledCommand)
+		control.POST("/:deviceId/scheduled/:scheduleId/resume", r.ControlHandler.ResumeScheduledCommand)
+		control.DELETE("/:deviceId/scheduled/:scheduleId", r.ControlHandler.CancelScheduledCommand)
+	}
+
+	commands := rg.Group("/iot/commands")
+	commands.Use(r.AuthMiddleware.RequireAuth())
+	{
+		commands.GET("/pending-approval", r.ControlHandler.ListPendingApproval)
+		commands.POST("/:commandId/approve", r.ControlHandler.ApproveCommand)
+		commands.POST("/:commandId/reject", r.ControlHandler.RejectCommand)
 	}
 }
 
@@ -108,6 +211,54 @@ func (r *Router) setupOptimizationRoutes(rg *gin.RouterGroup) {
 		// Legacy endpoint for backward compatibility
 		optimization.POST("/apply", r.OptimizationHandler.ApplyOptimization)
 		optimization.GET("/status/:scenarioId", r.OptimizationHandler.GetOptimizationStatus)
+		optimization.POST("/:scenarioId/resume", r.OptimizationHandler.ResumeOptimization)
+	}
+}
+
+// setupWeatherRuleRoutes configures weather-dependent control rule routes
+func (r *Router) setupWeatherRuleRoutes(rg *gin.RouterGroup) {
+	weatherRules := rg.Group("/iot/weather-rules")
+	weatherRules.Use(r.AuthMiddleware.RequireAuth())
+	{
+		weatherRules.GET("", r.WeatherRuleHandler.ListRules)
+		weatherRules.GET("/:ruleId", r.WeatherRuleHandler.GetRule)
+		weatherRules.GET("/:ruleId/executions", r.WeatherRuleHandler.ListExecutions)
+		weatherRules.POST("", r.AuthMiddleware.RequireAdmin(), r.WeatherRuleHandler.CreateRule)
+		weatherRules.PUT("/:ruleId", r.AuthMiddleware.RequireAdmin(), r.WeatherRuleHandler.UpdateRule)
+		weatherRules.DELETE("/:ruleId", r.AuthMiddleware.RequireAdmin(), r.WeatherRuleHandler.DeleteRule)
+		weatherRules.POST("/:ruleId/evaluate", r.AuthMiddleware.RequireAdmin(), r.WeatherRuleHandler.EvaluateRule)
+	}
+}
+
+// setupLocationRoutes configures location hierarchy, device assignment and floor plan routes
+func (r *Router) setupLocationRoutes(rg *gin.RouterGroup) {
+	locations := rg.Group("/iot/locations")
+	locations.Use(r.AuthMiddleware.RequireAuth())
+	{
+		locations.GET("", r.LocationHandler.ListLocations)
+		locations.GET("/:locationId", r.LocationHandler.GetLocation)
+		locations.GET("/:locationId/devices", r.LocationHandler.ListLocationDevices)
+		locations.GET("/:locationId/floor-plan", r.LocationHandler.GetFloorPlan)
+		locations.POST("", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.CreateLocation)
+		locations.PUT("/:locationId", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.UpdateLocation)
+		locations.DELETE("/:locationId", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.DeleteLocation)
+		locations.POST("/:locationId/devices", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.AssignDevices)
+		locations.DELETE("/:locationId/devices/:deviceId", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.UnassignDevice)
+		locations.PUT("/:locationId/floor-plan", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.UploadFloorPlan)
+	}
+}
+
+// setupGatewayRoutes configures Modbus and BACnet gateway routes. Gateways post readings with
+// their own credentials; register mappings are managed by administrators.
+func (r *Router) setupGatewayRoutes(rg *gin.RouterGroup) {
+	gateways := rg.Group("/iot/gateways")
+	gateways.Use(r.AuthMiddleware.RequireAuth())
+	{
+		gateways.POST("/readings", r.GatewayHandler.IngestReadings)
+		gateways.GET("/mappings", r.GatewayHandler.ListMappings)
+		gateways.GET("/mappings/:deviceId", r.GatewayHandler.GetMapping)
+		gateways.PUT("/mappings/:deviceId", r.AuthMiddleware.RequireAdmin(), r.GatewayHandler.SaveMapping)
+		gateways.DELETE("/mappings/:deviceId", r.AuthMiddleware.RequireAdmin(), r.GatewayHandler.DeleteMapping)
 	}
 }
 
@@ -121,6 +272,29 @@ func (r *Router) setupStateRoutes(rg *gin.RouterGroup) {
 	}
 }
 
+// setupAdminRoutes configures administrative maintenance routes
+func (r *Router) setupAdminRoutes(rg *gin.RouterGroup) {
+	admin := rg.Group("/iot/admin")
+	admin.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		admin.GET("/retention", r.RetentionHandler.GetStatus)
+		admin.POST("/retention/run", r.RetentionHandler.RunNow)
+		admin.GET("/mqtt", r.HealthHandler.GetMQTTStats)
+		admin.GET("/cache", r.HealthHandler.GetCacheStats)
+		admin.GET("/jobs", r.JobHandler.ListJobs)
+		admin.GET("/jobs/stats", r.JobHandler.GetStats)
+		admin.GET("/jobs/:jobId", r.JobHandler.GetJob)
+		admin.POST("/jobs/:jobId/retry", r.JobHandler.RetryJob)
+		admin.POST("/jobs/:jobId/cancel", r.JobHandler.CancelJob)
+		admin.GET("/settings", r.SettingsHandler.ListSettings)
+		admin.GET("/settings/history", r.SettingsHandler.GetHistory)
+		admin.GET("/settings/:key", r.SettingsHandler.GetSetting)
+		admin.PUT("/settings/:key", r.SettingsHandler.UpdateSetting)
+		admin.DELETE("/settings/:key", r.SettingsHandler.ResetSetting)
+		admin.GET("/settings/:key/history", r.SettingsHandler.GetHistory)
+	}
+}
+
 // setupLegacyRoutes configures legacy routes without /api/v1 prefix
 func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 	// Telemetry routes
@@ -130,6 +304,8 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 		telemetry.POST("", r.TelemetryHandler.IngestTelemetry)
 		telemetry.POST("/bulk", r.TelemetryHandler.IngestBulkTelemetry)
 		telemetry.GET("/history", r.TelemetryHandler.GetTelemetryHistory)
+		telemetry.GET("/query", r.TelemetryHandler.QueryTelemetry)
+		telemetry.GET("/export", r.TelemetryHandler.ExportTelemetry)
 	}
 
 	// Device routes
@@ -139,6 +315,33 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 		devices.GET("", r.DeviceHandler.ListDevices)
 		devices.GET("/:deviceId", r.DeviceHandler.GetDevice)
 		devices.POST("/register", r.DeviceHandler.RegisterDevice)
+		devices.POST("/import", r.AuthMiddleware.RequireAdmin(), r.DeviceHandler.ImportDevices)
+		devices.GET("/deleted", r.AuthMiddleware.RequireAdmin(), r.DeviceHandler.ListDeletedDevices)
+		devices.DELETE("/:deviceId", r.AuthMiddleware.RequireAdmin(), r.DeviceHandler.DeleteDevice)
+		devices.POST("/:deviceId/restore", r.AuthMiddleware.RequireAdmin(), r.DeviceHandler.RestoreDevice)
+		devices.PUT("/:deviceId/calibration", r.AuthMiddleware.RequireAdmin(), r.DeviceHandler.SetCalibration)
+		devices.GET("/:deviceId/command-history", r.ControlHandler.GetCommandHistory)
+	}
+	engine.GET("/iot/search", r.AuthMiddleware.RequireAuth(), r.DeviceHandler.SearchDevices)
+
+	// Device type routes
+	deviceTypes := engine.Group("/iot/device-types")
+	deviceTypes.Use(r.AuthMiddleware.RequireAuth())
+	{
+		deviceTypes.GET("", r.DeviceTypeHandler.ListDeviceTypes)
+		deviceTypes.GET("/:name", r.DeviceTypeHandler.GetDeviceType)
+		deviceTypes.POST("", r.AuthMiddleware.RequireAdmin(), r.DeviceTypeHandler.CreateDeviceType)
+		deviceTypes.PUT("/:name", r.AuthMiddleware.RequireAdmin(), r.DeviceTypeHandler.UpdateDeviceType)
+		deviceTypes.DELETE("/:name", r.AuthMiddleware.RequireAdmin(), r.DeviceTypeHandler.DeleteDeviceType)
+	}
+
+	// Provisioning routes
+	provisioning := engine.Group("/iot/provisioning")
+	{
+		provisioning.POST("/claim", r.ProvisioningHandler.ClaimDevice)
+		provisioning.POST("/tokens", r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin(), r.ProvisioningHandler.CreateToken)
+		provisioning.GET("/tokens", r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin(), r.ProvisioningHandler.ListTokens)
+		provisioning.DELETE("/tokens/:tokenId", r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin(), r.ProvisioningHandler.RevokeToken)
 	}
 
 	// Control routes
@@ -147,6 +350,19 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 	{
 		control.POST("/:deviceId/command", r.ControlHandler.SendCommand)
 		control.GET("/:deviceId/commands", r.ControlHandler.ListCommands)
+		control.GET("/:deviceId/scheduled", r.ControlHandler.ListScheduledCommands)
+		control.POST("/:deviceId/scheduled/:scheduleId/pause", r.ControlHandler.PauseScheduledCommand)
+		control.POST("/:deviceId/scheduled/:scheduleId/resume", r.ControlHandler.ResumeScheduledCommand)
+		control.DELETE("/:deviceId/scheduled/:scheduleId", r.ControlHandler.CancelScheduledCommand)
+	}
+
+	// Command approval routes
+	commands := engine.Group("/iot/commands")
+	commands.Use(r.AuthMiddleware.RequireAuth())
+	{
+		commands.GET("/pending-approval", r.ControlHandler.ListPendingApproval)
+		commands.POST("/:commandId/approve", r.ControlHandler.ApproveCommand)
+		commands.POST("/:commandId/reject", r.ControlHandler.RejectCommand)
 	}
 
 	// Optimization routes
@@ -158,6 +374,47 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 		// Legacy endpoint for backward compatibility
 		optimization.POST("/apply", r.OptimizationHandler.ApplyOptimization)
 		optimization.GET("/status/:scenarioId", r.OptimizationHandler.GetOptimizationStatus)
+		optimization.POST("/:scenarioId/resume", r.OptimizationHandler.ResumeOptimization)
+	}
+
+	// Weather rule routes
+	weatherRules := engine.Group("/iot/weather-rules")
+	weatherRules.Use(r.AuthMiddleware.RequireAuth())
+	{
+		weatherRules.GET("", r.WeatherRuleHandler.ListRules)
+		weatherRules.GET("/:ruleId", r.WeatherRuleHandler.GetRule)
+		weatherRules.GET("/:ruleId/executions", r.WeatherRuleHandler.ListExecutions)
+		weatherRules.POST("", r.AuthMiddleware.RequireAdmin(), r.WeatherRuleHandler.CreateRule)
+		weatherRules.PUT("/:ruleId", r.AuthMiddleware.RequireAdmin(), r.WeatherRuleHandler.UpdateRule)
+		weatherRules.DELETE("/:ruleId", r.AuthMiddleware.RequireAdmin(), r.WeatherRuleHandler.DeleteRule)
+		weatherRules.POST("/:ruleId/evaluate", r.AuthMiddleware.RequireAdmin(), r.WeatherRuleHandler.EvaluateRule)
+	}
+
+	// Location routes
+	locations := engine.Group("/iot/locations")
+	locations.Use(r.AuthMiddleware.RequireAuth())
+	{
+		locations.GET("", r.LocationHandler.ListLocations)
+		locations.GET("/:locationId", r.LocationHandler.GetLocation)
+		locations.GET("/:locationId/devices", r.LocationHandler.ListLocationDevices)
+		locations.GET("/:locationId/floor-plan", r.LocationHandler.GetFloorPlan)
+		locations.POST("", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.CreateLocation)
+		locations.PUT("/:locationId", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.UpdateLocation)
+		locations.DELETE("/:locationId", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.DeleteLocation)
+		locations.POST("/:locationId/devices", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.AssignDevices)
+		locations.DELETE("/:locationId/devices/:deviceId", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.UnassignDevice)
+		locations.PUT("/:locationId/floor-plan", r.AuthMiddleware.RequireAdmin(), r.LocationHandler.UploadFloorPlan)
+	}
+
+	// Gateway routes
+	gateways := engine.Group("/iot/gateways")
+	gateways.Use(r.AuthMiddleware.RequireAuth())
+	{
+		gateways.POST("/readings", r.GatewayHandler.IngestReadings)
+		gateways.GET("/mappings", r.GatewayHandler.ListMappings)
+		gateways.GET("/mappings/:deviceId", r.GatewayHandler.GetMapping)
+		gateways.PUT("/mappings/:deviceId", r.AuthMiddleware.RequireAdmin(), r.GatewayHandler.SaveMapping)
+		gateways.DELETE("/mappings/:deviceId", r.AuthMiddleware.RequireAdmin(), r.GatewayHandler.DeleteMapping)
 	}
 
 	// State routes
@@ -167,4 +424,25 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 		state.GET("/live", r.StateHandler.GetLiveState)
 		state.GET("/:deviceId", r.StateHandler.GetDeviceState)
 	}
+
+	// Admin routes
+	admin := engine.Group("/iot/admin")
+	admin.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		admin.GET("/retention", r.RetentionHandler.GetStatus)
+		admin.POST("/retention/run", r.RetentionHandler.RunNow)
+		admin.GET("/mqtt", r.HealthHandler.GetMQTTStats)
+		admin.GET("/cache", r.HealthHandler.GetCacheStats)
+		admin.GET("/jobs", r.JobHandler.ListJobs)
+		admin.GET("/jobs/stats", r.JobHandler.GetStats)
+		admin.GET("/jobs/:jobId", r.JobHandler.GetJob)
+		admin.POST("/jobs/:jobId/retry", r.JobHandler.RetryJob)
+		admin.POST("/jobs/:jobId/cancel", r.JobHandler.CancelJob)
+		admin.GET("/settings", r.SettingsHandler.ListSettings)
+		admin.GET("/settings/history", r.SettingsHandler.GetHistory)
+		admin.GET("/settings/:key", r.SettingsHandler.GetSetting)
+		admin.PUT("/settings/:key", r.SettingsHandler.UpdateSetting)
+		admin.DELETE("/settings/:key", r.SettingsHandler.ResetSetting)
+		admin.GET("/settings/:key/history", r.SettingsHandler.GetHistory)
+	}
 }
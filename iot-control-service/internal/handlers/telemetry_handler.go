@@ -1,7 +1,15 @@
 package handlers
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +20,13 @@ import (
 	"iot-control-service/internal/service"
 )
 
+const (
+	// exportFlushEvery is the number of rows written between flushes during an export
+	exportFlushEvery = 1000
+	// exportWriteTimeout bounds how long a single flush may take before the export is aborted
+	exportWriteTimeout = 30 * time.Second
+)
+
 // TelemetryHandler handles telemetry-related requests
 type TelemetryHandler struct {
 	telemetryService *service.TelemetryService
@@ -38,11 +53,7 @@ func NewTelemetryHandler(
 func (h *TelemetryHandler) IngestTelemetry(c *gin.Context) {
 	var req models.TelemetryIngestRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -66,6 +77,14 @@ func (h *TelemetryHandler) IngestTelemetry(c *gin.Context) {
 			))
 			return
 		}
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			models.ErrCodeInternalError,
 			err.Error(),
@@ -87,11 +106,7 @@ func (h *TelemetryHandler) IngestTelemetry(c *gin.Context) {
 func (h *TelemetryHandler) IngestBulkTelemetry(c *gin.Context) {
 	var req models.BulkTelemetryIngestRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -106,6 +121,14 @@ func (h *TelemetryHandler) IngestBulkTelemetry(c *gin.Context) {
 			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method,
 			map[string]interface{}{"count": len(req.Telemetry)},
 		)
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			models.ErrCodeInternalError,
 			err.Error(),
@@ -127,11 +150,7 @@ func (h *TelemetryHandler) IngestBulkTelemetry(c *gin.Context) {
 func (h *TelemetryHandler) GetTelemetryHistory(c *gin.Context) {
 	var req models.TelemetryHistoryRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid query parameters",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid query parameters", err)
 		return
 	}
 
@@ -181,3 +200,185 @@ func (h *TelemetryHandler) GetTelemetryHistory(c *gin.Context) {
 		"limit":     req.Limit,
 	}, ""))
 }
+
+// QueryTelemetry handles aggregated telemetry queries
+// GET /iot/telemetry/query?metrics=power,energy&agg=avg&groupBy=device,hour&buildingId=
+func (h *TelemetryHandler) QueryTelemetry(c *gin.Context) {
+	var req models.TelemetryQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	response, err := h.telemetryService.QueryTelemetry(c.Request.Context(), &req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// ExportTelemetry streams a device's telemetry history as CSV, optionally gzip-compressed.
+// Long ranges are exported as averaged time buckets unless a resolution is given.
+// GET /iot/telemetry/export?deviceId=&from=&to=&format=csv&resolution=auto|raw|minute|hour|day&metrics=&gzip=true
+func (h *TelemetryHandler) ExportTelemetry(c *gin.Context) {
+	var req models.TelemetryExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	export, err := h.telemetryService.PrepareExport(c.Request.Context(), &req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+		return
+	}
+
+	filename := fmt.Sprintf("telemetry-%s-%s.csv", export.DeviceID, time.Now().UTC().Format("20060102T150405Z"))
+	var out io.Writer = c.Writer
+	var gzipWriter *gzip.Writer
+	if req.Gzip {
+		filename += ".gz"
+		c.Header("Content-Type", "application/gzip")
+		gzipWriter = gzip.NewWriter(c.Writer)
+		out = gzipWriter
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Telemetry-Resolution", string(export.Resolution))
+	c.Status(http.StatusOK)
+
+	// Exports can outlive the server write timeout; keep extending it while rows flow
+	controller := http.NewResponseController(c.Writer)
+	extendDeadline := func() {
+		_ = controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	}
+	extendDeadline()
+
+	csvWriter := csv.NewWriter(out)
+	flush := func() error {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+		if gzipWriter != nil {
+			if err := gzipWriter.Flush(); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		extendDeadline()
+		return nil
+	}
+
+	rows := 0
+	err = csvWriter.Write(telemetryCSVHeader(export))
+	if err == nil {
+		err = h.telemetryService.StreamExport(c.Request.Context(), export, func(row *models.TelemetryExportRow) error {
+			if err := csvWriter.Write(telemetryCSVRecord(export, row)); err != nil {
+				return err
+			}
+			rows++
+			if rows%exportFlushEvery == 0 {
+				return flush()
+			}
+			return nil
+		})
+	}
+	if flushErr := flush(); err == nil {
+		err = flushErr
+	}
+	if gzipWriter != nil {
+		if closeErr := gzipWriter.Close(); err == nil {
+			err = closeErr
+		}
+		c.Writer.Flush()
+	}
+
+	// Headers are already sent, so failures can only be logged
+	status, errMsg := "SUCCESS", ""
+	if err != nil {
+		status, errMsg = "FAILURE", err.Error()
+		log.Printf("Telemetry export of device %s aborted after %d rows: %v", export.DeviceID, rows, err)
+	}
+
+	h.securityClient.AuditLog(
+		context.Background(), middleware.GetUserID(c), "", "EXPORT_TELEMETRY", "telemetry", export.DeviceID,
+		status, errMsg, middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method,
+		map[string]interface{}{"resolution": string(export.Resolution), "rows": rows, "gzip": req.Gzip},
+	)
+}
+
+// telemetryCSVHeader returns the column layout of a telemetry export: the timestamp (the start
+// of the bucket when downsampled), the device, the bucket's sample count and one column per metric
+func telemetryCSVHeader(export *models.TelemetryExport) []string {
+	header := []string{"timestamp", "deviceId"}
+	if export.Downsampled() {
+		header = append(header, "samples")
+	}
+	return append(header, export.Metrics...)
+}
+
+// telemetryCSVRecord converts an export row into a CSV row matching telemetryCSVHeader.
+// Metrics missing from the row are left empty.
+func telemetryCSVRecord(export *models.TelemetryExport, row *models.TelemetryExportRow) []string {
+	record := make([]string, 0, len(export.Metrics)+3)
+	record = append(record, row.Timestamp.UTC().Format(time.RFC3339Nano), export.DeviceID)
+	if export.Downsampled() {
+		record = append(record, strconv.FormatInt(row.Samples, 10))
+	}
+	for _, metric := range export.Metrics {
+		record = append(record, telemetryCSVValue(row.Metrics[metric]))
+	}
+	return record
+}
+
+// telemetryCSVValue formats a metric value for CSV; structured values are encoded as JSON
+func telemetryCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"iot-control-service/internal/middleware"
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/service"
+)
+
+// WeatherRuleHandler handles weather-dependent device control rule requests
+type WeatherRuleHandler struct {
+	weatherRuleService *service.WeatherRuleService
+	securityClient     interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	}
+}
+
+// NewWeatherRuleHandler creates a new weather rule handler
+func NewWeatherRuleHandler(
+	weatherRuleService *service.WeatherRuleService,
+	securityClient interface {
+		AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{})
+	},
+) *WeatherRuleHandler {
+	return &WeatherRuleHandler{
+		weatherRuleService: weatherRuleService,
+		securityClient:     securityClient,
+	}
+}
+
+// CreateRule handles creating a weather rule
+// POST /iot/weather-rules
+func (h *WeatherRuleHandler) CreateRule(c *gin.Context) {
+	var req models.WeatherRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"buildingId": req.BuildingID, "cron": req.Cron}
+
+	rule, err := h.weatherRuleService.CreateRule(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "CREATE_WEATHER_RULE", "weather_rule", "",
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "CREATE_WEATHER_RULE", "weather_rule", rule.RuleID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(rule, "Weather rule created successfully"))
+}
+
+// ListRules handles listing weather rules
+// GET /iot/weather-rules?buildingId=&page=&limit=
+func (h *WeatherRuleHandler) ListRules(c *gin.Context) {
+	var req models.ListWeatherRulesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	rules, total, err := h.weatherRuleService.ListRules(c.Request.Context(), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"rules": rules,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	}, ""))
+}
+
+// GetRule handles retrieving a weather rule
+// GET /iot/weather-rules/{ruleId}
+func (h *WeatherRuleHandler) GetRule(c *gin.Context) {
+	rule, err := h.weatherRuleService.GetRule(c.Request.Context(), c.Param("ruleId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(rule, ""))
+}
+
+// UpdateRule handles replacing a weather rule
+// PUT /iot/weather-rules/{ruleId}
+func (h *WeatherRuleHandler) UpdateRule(c *gin.Context) {
+	ruleID := c.Param("ruleId")
+
+	var req models.WeatherRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	rule, err := h.weatherRuleService.UpdateRule(c.Request.Context(), ruleID, &req)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "UPDATE_WEATHER_RULE", "weather_rule", ruleID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "UPDATE_WEATHER_RULE", "weather_rule", ruleID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(rule, "Weather rule updated successfully"))
+}
+
+// DeleteRule handles deleting a weather rule and its execution log
+// DELETE /iot/weather-rules/{ruleId}
+func (h *WeatherRuleHandler) DeleteRule(c *gin.Context) {
+	ruleID := c.Param("ruleId")
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	if err := h.weatherRuleService.DeleteRule(c.Request.Context(), ruleID); err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "DELETE_WEATHER_RULE", "weather_rule", ruleID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "DELETE_WEATHER_RULE", "weather_rule", ruleID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, nil,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Weather rule deleted successfully"))
+}
+
+// ListExecutions handles listing the execution log of a weather rule
+// GET /iot/weather-rules/{ruleId}/executions?page=&limit=
+func (h *WeatherRuleHandler) ListExecutions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	executions, total, err := h.weatherRuleService.ListExecutions(c.Request.Context(), c.Param("ruleId"), page, limit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"executions": executions,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+	}, ""))
+}
+
+// EvaluateRule handles evaluating a weather rule immediately. With dryRun=true the outcome is
+// logged but no commands are sent.
+// POST /iot/weather-rules/{ruleId}/evaluate?dryRun=
+func (h *WeatherRuleHandler) EvaluateRule(c *gin.Context) {
+	ruleID := c.Param("ruleId")
+	dryRun := c.Query("dryRun") == "true"
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+	details := map[string]interface{}{"dryRun": dryRun}
+
+	execution, err := h.weatherRuleService.EvaluateNow(c.Request.Context(), ruleID, dryRun)
+	if err != nil {
+		h.securityClient.AuditLog(
+			c.Request.Context(), userID, "", "EVALUATE_WEATHER_RULE", "weather_rule", ruleID,
+			"FAILURE", err.Error(), ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+		)
+		h.respondError(c, err)
+		return
+	}
+
+	h.securityClient.AuditLog(
+		c.Request.Context(), userID, "", "EVALUATE_WEATHER_RULE", "weather_rule", ruleID,
+		"SUCCESS", "", ipAddress, userAgent, c.Request.URL.Path, c.Request.Method, details,
+	)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(execution, "Weather rule evaluated"))
+}
+
+// respondError maps weather rule service errors to HTTP responses
+func (h *WeatherRuleHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "weather rule not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeNotFound,
+			err.Error(),
+			"",
+		))
+	case strings.Contains(err.Error(), "already exists"):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(
+			models.ErrCodeConflict,
+			err.Error(),
+			"",
+		))
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			err.Error(),
+			"",
+		))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			err.Error(),
+			"",
+		))
+	}
+}
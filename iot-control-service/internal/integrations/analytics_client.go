@@ -8,6 +8,7 @@ import (
 
 	"iot-control-service/internal/config"
 	"iot-control-service/internal/models"
+	"iot-control-service/internal/tracing"
 )
 
 // AnalyticsClient handles communication with the Analytics service
@@ -20,12 +21,18 @@ type AnalyticsClient struct {
 func NewAnalyticsClient(cfg *config.Config) *AnalyticsClient {
 	return &AnalyticsClient{
 		httpClient: &http.Client{
-			Timeout: cfg.Analytics.Timeout,
+			Timeout:   cfg.Analytics.Timeout,
+			Transport: tracing.NewTransport(http.DefaultTransport),
 		},
 		baseURL: cfg.Analytics.URL,
 	}
 }
 
+// HealthCheck checks if the analytics service is reachable
+func (c *AnalyticsClient) HealthCheck(ctx context.Context) error {
+	return checkServiceHealth(ctx, c.httpClient, c.baseURL+"/health")
+}
+
 // GetAnomalies retrieves anomaly detection results
 func (c *AnalyticsClient) GetAnomalies(ctx context.Context, deviceID string, authToken string) (interface{}, error) {
 	url := fmt.Sprintf("%s/analytics/anomalies?deviceId=%s", c.baseURL, deviceID)
@@ -0,0 +1,71 @@
+package integrations
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// buildingTimeZoneTTL is how long a building's time zone is used before it is looked up again
+const buildingTimeZoneTTL = 15 * time.Minute
+
+// BuildingTimeZoneSource looks up the IANA time zone name of a building
+type BuildingTimeZoneSource interface {
+	GetBuildingTimeZone(ctx context.Context, buildingID string) (string, error)
+}
+
+// BuildingTimeZones caches the time zones of buildings from the Forecast service's building
+// registry. Buildings without a registered time zone are in UTC. When a lookup fails, the last
+// known time zone is used until the registry can be reached again.
+type BuildingTimeZones struct {
+	source BuildingTimeZoneSource
+
+	mu      sync.Mutex
+	entries map[string]cachedTimeZone
+}
+
+type cachedTimeZone struct {
+	loc       *time.Location
+	expiresAt time.Time
+}
+
+// NewBuildingTimeZones creates a building time zone cache
+func NewBuildingTimeZones(source BuildingTimeZoneSource) *BuildingTimeZones {
+	return &BuildingTimeZones{
+		source:  source,
+		entries: make(map[string]cachedTimeZone),
+	}
+}
+
+// Location returns the time zone of a building
+func (z *BuildingTimeZones) Location(ctx context.Context, buildingID string) (*time.Location, error) {
+	z.mu.Lock()
+	entry, ok := z.entries[buildingID]
+	z.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.loc, nil
+	}
+
+	name, err := z.source.GetBuildingTimeZone(ctx, buildingID)
+	if err != nil {
+		if ok {
+			log.Printf("Failed to refresh time zone of building %s, using %s: %v", buildingID, entry.loc, err)
+			return entry.loc, nil
+		}
+		return nil, err
+	}
+
+	loc := time.UTC
+	if name != "" {
+		if loc, err = time.LoadLocation(name); err != nil {
+			log.Printf("Building %s has an unknown time zone %q, using UTC", buildingID, name)
+			loc = time.UTC
+		}
+	}
+
+	z.mu.Lock()
+	z.entries[buildingID] = cachedTimeZone{loc: loc, expiresAt: time.Now().Add(buildingTimeZoneTTL)}
+	z.mu.Unlock()
+	return loc, nil
+}
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"iot-control-service/internal/config"
 	"iot-control-service/internal/models"
+	"iot-control-service/internal/tracing"
+	"shared/signing"
 )
 
 // ForecastClient handles communication with the Forecast & Optimization service
@@ -19,13 +22,16 @@ type ForecastClient struct {
 // NewForecastClient creates a new forecast client
 func NewForecastClient(cfg *config.Config) *ForecastClient {
 	return &ForecastClient{
-		httpClient: &http.Client{
-			Timeout: cfg.Forecast.Timeout,
-		},
-		baseURL: cfg.Forecast.URL,
+		httpClient: tracing.WrapClient(signing.NewClient(cfg.Forecast.Timeout, ServiceName, cfg.Auth.ServiceSecret)),
+		baseURL:    cfg.Forecast.URL,
 	}
 }
 
+// HealthCheck checks if the forecast service is reachable
+func (c *ForecastClient) HealthCheck(ctx context.Context) error {
+	return checkServiceHealth(ctx, c.httpClient, c.baseURL+"/health")
+}
+
 // GetDevicePrediction retrieves predicted consumption for a device
 func (c *ForecastClient) GetDevicePrediction(ctx context.Context, deviceID, authToken string) (*models.DevicePrediction, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/forecast/prediction/"+deviceID, nil)
@@ -94,3 +100,75 @@ func (c *ForecastClient) GetDeviceOptimization(ctx context.Context, deviceID, au
 
 	return apiResp.Data, nil
 }
+
+// GetWeatherForecast retrieves the hourly weather forecast of a building location for the next
+// hours. It authenticates with the service key, so background workers can use it.
+func (c *ForecastClient) GetWeatherForecast(ctx context.Context, buildingID string, hours int) ([]models.WeatherPoint, error) {
+	reqURL := fmt.Sprintf("%s/internal/weather/forecast?buildingId=%s&hours=%d", c.baseURL, url.QueryEscape(buildingID), hours)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("forecast service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp struct {
+		Success bool                  `json:"success"`
+		Data    []models.WeatherPoint `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !apiResp.Success {
+		return nil, fmt.Errorf("forecast service returned an error")
+	}
+
+	return apiResp.Data, nil
+}
+
+// GetBuildingTimeZone retrieves the IANA time zone name of a building from the Forecast service's
+// building registry. Buildings missing from the registry have no time zone and yield an empty
+// name. It authenticates with the service key, so background workers can use it.
+func (c *ForecastClient) GetBuildingTimeZone(ctx context.Context, buildingID string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/internal/buildings/"+url.PathEscape(buildingID), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("forecast service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			TimeZone string `json:"timezone"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !apiResp.Success {
+		return "", fmt.Errorf("forecast service returned an error")
+	}
+
+	return apiResp.Data.TimeZone, nil
+}
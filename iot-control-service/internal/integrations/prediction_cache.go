@@ -0,0 +1,109 @@
+package integrations
+
+import (
+	"sync"
+	"time"
+
+	"iot-control-service/internal/events"
+	"iot-control-service/internal/models"
+)
+
+// PredictionCache keeps the latest device prediction pushed by the Forecast service, so scenarios
+// can be prepared without a synchronous prediction request per device. A prediction is dropped
+// once it is older than the TTL or its last predicted value has passed.
+type PredictionCache struct {
+	ttl time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]cachedPrediction
+}
+
+type cachedPrediction struct {
+	forecastID  string
+	prediction  *models.DevicePrediction
+	completedAt time.Time
+	expiresAt   time.Time
+}
+
+// NewPredictionCache creates a prediction cache. A zero TTL disables caching.
+func NewPredictionCache(ttl time.Duration) *PredictionCache {
+	return &PredictionCache{
+		ttl:     ttl,
+		entries: make(map[string]cachedPrediction),
+	}
+}
+
+// UpdateForecast caches the prediction of a completed device forecast. Building forecasts and
+// forecasts older than the cached one are ignored.
+func (c *PredictionCache) UpdateForecast(data *events.ForecastCompletedData) {
+	if c == nil || c.ttl <= 0 || data.DeviceID == "" || data.Prediction == nil || len(data.Prediction.Values) == 0 {
+		return
+	}
+
+	expiresAt := data.CompletedAt.Add(c.ttl)
+	if last := data.Prediction.Values[len(data.Prediction.Values)-1].Timestamp; last.Before(expiresAt) {
+		expiresAt = last
+	}
+	if time.Now().After(expiresAt) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existing, ok := c.entries[data.DeviceID]; ok && existing.completedAt.After(data.CompletedAt) {
+		return
+	}
+	c.entries[data.DeviceID] = cachedPrediction{
+		forecastID:  data.ForecastID,
+		prediction:  devicePrediction(data.DeviceID, data.Prediction),
+		completedAt: data.CompletedAt,
+		expiresAt:   expiresAt,
+	}
+}
+
+// Get returns the cached prediction of a device
+func (c *PredictionCache) Get(deviceID string) (*models.DevicePrediction, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[deviceID]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		c.mu.Lock()
+		if current, ok := c.entries[deviceID]; ok && current.forecastID == entry.forecastID {
+			delete(c.entries, deviceID)
+		}
+		c.mu.Unlock()
+		return nil, false
+	}
+	return entry.prediction, true
+}
+
+// devicePrediction converts a pushed prediction summary to the prediction the Forecast service
+// returns for a device
+func devicePrediction(deviceID string, summary *events.PredictionSummary) *models.DevicePrediction {
+	values := make([]models.ForecastPrediction, len(summary.Values))
+	for i, value := range summary.Values {
+		values[i] = models.ForecastPrediction{
+			Timestamp:      value.Timestamp,
+			PredictedValue: value.PredictedValue,
+			LowerBound:     value.LowerBound,
+			UpperBound:     value.UpperBound,
+			Unit:           value.Unit,
+		}
+	}
+
+	return &models.DevicePrediction{
+		DeviceID:           deviceID,
+		CurrentConsumption: summary.CurrentConsumption,
+		PredictedValues:    values,
+		Trend:              summary.Trend,
+		TrendPercentage:    summary.TrendPercentage,
+	}
+}
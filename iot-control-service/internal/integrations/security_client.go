@@ -11,6 +11,8 @@ import (
 
 	"iot-control-service/internal/config"
 	"iot-control-service/internal/models"
+	"iot-control-service/internal/tracing"
+	"shared/signing"
 )
 
 // SecurityClient handles communication with the Security & External Integration service
@@ -19,16 +21,26 @@ type SecurityClient struct {
 	baseURL    string
 }
 
+// ServiceKeyHeader carries the shared service key, which internal endpoints accept in place of
+// a request signature only while INTERNAL_ACCEPT_SERVICE_KEY is enabled
+const ServiceKeyHeader = "X-Service-Key"
+
+// ServiceName identifies this service in signed internal requests
+const ServiceName = "iot-control-service"
+
 // NewSecurityClient creates a new security client
 func NewSecurityClient(cfg *config.Config) *SecurityClient {
 	return &SecurityClient{
-		httpClient: &http.Client{
-			Timeout: cfg.Security.Timeout,
-		},
-		baseURL: cfg.Security.URL,
+		httpClient: tracing.WrapClient(signing.NewClient(cfg.Security.Timeout, ServiceName, cfg.Auth.ServiceSecret)),
+		baseURL:    cfg.Security.URL,
 	}
 }
 
+// HealthCheck checks if the security service is reachable
+func (c *SecurityClient) HealthCheck(ctx context.Context) error {
+	return checkServiceHealth(ctx, c.httpClient, c.baseURL+"/health")
+}
+
 // ValidateToken validates a JWT token with the security service
 func (c *SecurityClient) ValidateToken(ctx context.Context, token string) (*models.TokenValidationResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/auth/validate-token", nil)
@@ -52,6 +64,38 @@ func (c *SecurityClient) ValidateToken(ctx context.Context, token string) (*mode
 	return &result, nil
 }
 
+// GetSigningKey fetches the access token signing key used for local token validation
+func (c *SecurityClient) GetSigningKey(ctx context.Context) (*models.SigningKeyResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/auth/signing-key", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("security service returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp struct {
+		Success bool                      `json:"success"`
+		Data    models.SigningKeyResponse `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.Success || apiResp.Data.Key == "" {
+		return nil, fmt.Errorf("security service returned no signing key")
+	}
+
+	return &apiResp.Data, nil
+}
+
 // LogAuditEvent sends an audit log entry to the security service
 func (c *SecurityClient) LogAuditEvent(ctx context.Context, req *models.AuditLogRequest) error {
 	jsonData, err := json.Marshal(req)
@@ -79,6 +123,48 @@ func (c *SecurityClient) LogAuditEvent(ctx context.Context, req *models.AuditLog
 	return nil
 }
 
+// CheckPermission checks if a user has permission for a specific action
+func (c *SecurityClient) CheckPermission(ctx context.Context, userID, resource, action string) (bool, error) {
+	payload := map[string]string{
+		"userId":   userID,
+		"resource": resource,
+		"action":   action,
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/auth/check-permissions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("security service returned status: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Allowed bool   `json:"allowed"`
+		Reason  string `json:"reason,omitempty"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Allowed, nil
+}
+
 // AuditLog is a convenience method to log audit events
 func (c *SecurityClient) AuditLog(ctx interface{}, userID, username, action, resource, resourceID, status, errorMsg, ipAddress, userAgent, requestPath, method string, details map[string]interface{}) {
 	req := &models.AuditLogRequest{
@@ -12,6 +12,7 @@ import (
 
 	"iot-control-service/internal/config"
 	"iot-control-service/internal/models"
+	"iot-control-service/internal/tracing"
 )
 
 // StorageClient handles communication with the external Storage service
@@ -28,12 +29,18 @@ type StorageClient struct {
 func NewStorageClient(cfg *config.Config) *StorageClient {
 	return &StorageClient{
 		httpClient: &http.Client{
-			Timeout: cfg.Storage.Timeout,
+			Timeout:   cfg.Storage.Timeout,
+			Transport: tracing.NewTransport(http.DefaultTransport),
 		},
 		baseURL: cfg.Storage.URL,
 	}
 }
 
+// HealthCheck checks if the storage service is reachable
+func (c *StorageClient) HealthCheck(ctx context.Context) error {
+	return checkServiceHealth(ctx, c.httpClient, c.baseURL+"/health")
+}
+
 // SaveTelemetry saves telemetry data to the storage service
 // POST /storage/telemetry/save
 func (c *StorageClient) SaveTelemetry(ctx context.Context, telemetry *models.Telemetry, authToken string) error {
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"bytes"
+	"encoding/json"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -16,18 +18,46 @@ const (
 	CommandStatusFailed    CommandStatus = "FAILED"
 	CommandStatusCancelled CommandStatus = "CANCELLED"
 	CommandStatusTimeout   CommandStatus = "TIMEOUT"
+	CommandStatusExpired   CommandStatus = "EXPIRED"
+
+	// High-impact commands wait in PENDING_APPROVAL until another user approves or rejects them
+	CommandStatusPendingApproval CommandStatus = "PENDING_APPROVAL"
+	CommandStatusRejected        CommandStatus = "REJECTED"
 )
 
+// AckableCommandStatuses are the statuses in which a device acknowledgment is recorded. Commands
+// that timed out may still be acknowledged late; expired commands may not.
+var AckableCommandStatuses = []CommandStatus{CommandStatusPending, CommandStatusSent, CommandStatusTimeout}
+
+// FinishedCommandStatuses are the statuses in which a command can no longer change, so it may be
+// purged by retention. Timed out commands are kept, as a late acknowledgment may still apply them.
+var FinishedCommandStatuses = []CommandStatus{
+	CommandStatusApplied,
+	CommandStatusFailed,
+	CommandStatusCancelled,
+	CommandStatusExpired,
+	CommandStatusRejected,
+}
+
 // DeviceCommand represents a command sent to a device
 type DeviceCommand struct {
 	ID          primitive.ObjectID          `bson:"_id,omitempty" json:"id"`
 	CommandID   string                      `bson:"command_id" json:"commandId"`
 	DeviceID    string                      `bson:"device_id" json:"deviceId"`
+	BuildingID  string                      `bson:"building_id,omitempty" json:"buildingId,omitempty"` // Building of the device when the command was issued
 	Command     string                      `bson:"command" json:"command"`
 	Params      map[string]interface{}      `bson:"params" json:"params"`
 	Status      CommandStatus               `bson:"status" json:"status"`
 	IssuedBy    string                      `bson:"issued_by" json:"issuedBy"`
+	IdempotencyKey string                   `bson:"idempotency_key,omitempty" json:"idempotencyKey,omitempty"`
 	ErrorMsg    string                      `bson:"error_msg,omitempty" json:"errorMsg,omitempty"`
+	RetryOf     string                      `bson:"retry_of,omitempty" json:"retryOf,omitempty"`       // Command that timed out and was sent again as this one
+	RetryCount  int                         `bson:"retry_count,omitempty" json:"retryCount,omitempty"` // Number of times the original command was sent again
+	ExpiresAt   *time.Time                  `bson:"expires_at,omitempty" json:"expiresAt,omitempty"` // Devices must discard the command after this time
+	TTLSeconds  int                         `bson:"ttl_seconds,omitempty" json:"ttlSeconds,omitempty"` // Delivery window applied once a command awaiting approval is approved
+	ReviewedBy  string                      `bson:"reviewed_by,omitempty" json:"reviewedBy,omitempty"` // User who approved or rejected the command
+	ReviewedAt  *time.Time                  `bson:"reviewed_at,omitempty" json:"reviewedAt,omitempty"`
+	ReviewComment string                    `bson:"review_comment,omitempty" json:"reviewComment,omitempty"`
 	SentAt      *time.Time                  `bson:"sent_at,omitempty" json:"sentAt,omitempty"`
 	AppliedAt   *time.Time                  `bson:"applied_at,omitempty" json:"appliedAt,omitempty"`
 	CreatedAt   time.Time                   `bson:"created_at" json:"createdAt"`
@@ -39,11 +69,19 @@ type CommandResponse struct {
 	ID        string                 `json:"id"`
 	CommandID string                 `json:"commandId"`
 	DeviceID  string                 `json:"deviceId"`
+	BuildingID string                `json:"buildingId,omitempty"`
 	Command   string                 `json:"command"`
 	Params    map[string]interface{} `json:"params"`
 	Status    string                 `json:"status"`
 	IssuedBy  string                 `json:"issuedBy"`
+	IdempotencyKey string            `json:"idempotencyKey,omitempty"`
 	ErrorMsg  string                 `json:"errorMsg,omitempty"`
+	RetryOf   string                 `json:"retryOf,omitempty"`
+	RetryCount int                   `json:"retryCount,omitempty"`
+	ExpiresAt *time.Time             `json:"expiresAt,omitempty"`
+	ReviewedBy string                `json:"reviewedBy,omitempty"`
+	ReviewedAt *time.Time            `json:"reviewedAt,omitempty"`
+	ReviewComment string             `json:"reviewComment,omitempty"`
 	SentAt    *time.Time             `json:"sentAt,omitempty"`
 	AppliedAt *time.Time             `json:"appliedAt,omitempty"`
 	CreatedAt time.Time               `json:"createdAt"`
@@ -56,11 +94,19 @@ func (c *DeviceCommand) ToResponse() *CommandResponse {
 		ID:        c.ID.Hex(),
 		CommandID: c.CommandID,
 		DeviceID:  c.DeviceID,
+		BuildingID: c.BuildingID,
 		Command:   c.Command,
 		Params:    c.Params,
 		Status:    string(c.Status),
 		IssuedBy:  c.IssuedBy,
+		IdempotencyKey: c.IdempotencyKey,
 		ErrorMsg:  c.ErrorMsg,
+		RetryOf:   c.RetryOf,
+		RetryCount: c.RetryCount,
+		ExpiresAt: c.ExpiresAt,
+		ReviewedBy: c.ReviewedBy,
+		ReviewedAt: c.ReviewedAt,
+		ReviewComment: c.ReviewComment,
 		SentAt:    c.SentAt,
 		AppliedAt: c.AppliedAt,
 		CreatedAt: c.CreatedAt,
@@ -68,10 +114,32 @@ func (c *DeviceCommand) ToResponse() *CommandResponse {
 	}
 }
 
-// SendCommandRequest represents a request to send a command
+// SendCommandRequest represents a request to send a command.
+// Setting ScheduledAt or Cron schedules the command instead of sending it immediately.
 type SendCommandRequest struct {
-	Command string                 `json:"command" binding:"required"`
-	Params  map[string]interface{} `json:"params"`
+	Command     string                 `json:"command" binding:"required"`
+	Params      map[string]interface{} `json:"params"`
+	TTLSeconds  int                    `json:"ttlSeconds"`  // Optional; defaults to the configured command TTL
+	ScheduledAt *time.Time             `json:"scheduledAt"` // Optional; run once at this time
+	Cron        string                 `json:"cron"`        // Optional; five-field cron expression in UTC
+	// OverrideRateLimit sends an immediate command despite the device type's rate limit; admin only
+	OverrideRateLimit bool `json:"overrideRateLimit"`
+}
+
+// IsScheduled reports whether the request asks for a scheduled command
+func (r *SendCommandRequest) IsScheduled() bool {
+	return r.ScheduledAt != nil || r.Cron != ""
+}
+
+// ListPendingApprovalRequest represents query parameters for listing commands awaiting approval
+type ListPendingApprovalRequest struct {
+	Page  int `form:"page"`
+	Limit int `form:"limit"`
+}
+
+// ReviewCommandRequest represents the optional body of a command approval or rejection
+type ReviewCommandRequest struct {
+	Comment string `json:"comment"`
 }
 
 // ListCommandsRequest represents query parameters for listing commands
@@ -89,3 +157,155 @@ type CommandAck struct {
 	ErrorMsg  string    `json:"errorMsg,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
 }
+
+// IsExpired checks whether the command's delivery window has passed
+func (c *DeviceCommand) IsExpired(now time.Time) bool {
+	return c.ExpiresAt != nil && !now.Before(*c.ExpiresAt)
+}
+
+// MatchesRequest reports whether the command was issued for the same command and parameters as
+// a request. Stored parameters are decoded with BSON document, array and integer types, so both
+// sets are compared as JSON.
+func (c *DeviceCommand) MatchesRequest(req *SendCommandRequest) bool {
+	if c.Command != req.Command {
+		return false
+	}
+	if len(c.Params) == 0 && len(req.Params) == 0 {
+		return true
+	}
+	stored, err := json.Marshal(plainParam(c.Params))
+	if err != nil {
+		return false
+	}
+	requested, err := json.Marshal(plainParam(req.Params))
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(stored, requested)
+}
+
+// plainParam converts BSON documents and arrays in a parameter value to maps and slices
+func plainParam(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.D:
+		m := make(map[string]interface{}, len(v))
+		for _, e := range v {
+			m[e.Key] = plainParam(e.Value)
+		}
+		return m
+	case primitive.M:
+		return plainParam(map[string]interface{}(v))
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = plainParam(item)
+		}
+		return m
+	case primitive.A:
+		return plainParam([]interface{}(v))
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = plainParam(item)
+		}
+		return items
+	default:
+		return value
+	}
+}
+
+// CommandVerification represents whether a command's requested change was observed in telemetry
+type CommandVerification string
+
+const (
+	CommandVerificationMaterialized    CommandVerification = "MATERIALIZED"
+	CommandVerificationNotMaterialized CommandVerification = "NOT_MATERIALIZED"
+	CommandVerificationPending         CommandVerification = "PENDING"       // observation window still open
+	CommandVerificationUnverifiable    CommandVerification = "UNVERIFIABLE"  // no telemetry or no comparable params
+	CommandVerificationNotDelivered    CommandVerification = "NOT_DELIVERED" // failed, expired or cancelled
+)
+
+// TelemetrySnapshot represents the device metrics reported at a point in time
+type TelemetrySnapshot struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Metrics   map[string]interface{} `json:"metrics"`
+}
+
+// MetricChange represents a metric whose value differs between two snapshots
+type MetricChange struct {
+	Metric string      `json:"metric"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// ParamCheck compares a requested command parameter with the value observed afterwards
+type ParamCheck struct {
+	Param     string      `json:"param"`
+	Requested interface{} `json:"requested"`
+	Observed  interface{} `json:"observed"`
+	Matched   bool        `json:"matched"`
+}
+
+// CommandHistoryEntry correlates a command with the telemetry before and after it
+type CommandHistoryEntry struct {
+	Command      *CommandResponse    `json:"command"`
+	Before       *TelemetrySnapshot  `json:"before,omitempty"`
+	After        *TelemetrySnapshot  `json:"after,omitempty"`
+	Diff         []MetricChange      `json:"diff"`
+	ParamChecks  []ParamCheck        `json:"paramChecks"`
+	Verification CommandVerification `json:"verification"`
+	Flagged      bool                `json:"flagged"` // requested change never materialized
+}
+
+// CommandHistoryRequest represents query parameters for the command audit view
+type CommandHistoryRequest struct {
+	Page          int     `form:"page"`
+	Limit         int     `form:"limit"`
+	WindowMinutes int     `form:"windowMinutes"` // how long after a command to look for the resulting state
+	Tolerance     float64 `form:"tolerance"`     // allowed absolute difference for numeric params
+}
+
+// ScheduleStatus represents the status of a scheduled command
+type ScheduleStatus string
+
+const (
+	ScheduleStatusActive    ScheduleStatus = "ACTIVE"
+	ScheduleStatusPaused    ScheduleStatus = "PAUSED"
+	ScheduleStatusCompleted ScheduleStatus = "COMPLETED"
+	ScheduleStatusCancelled ScheduleStatus = "CANCELLED"
+)
+
+// ScheduledCommand represents a command dispatched to a device at a future time, once or on a
+// recurring cron schedule evaluated in the local time of the device's building
+type ScheduledCommand struct {
+	ID            primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ScheduleID    string                 `bson:"schedule_id" json:"scheduleId"`
+	DeviceID      string                 `bson:"device_id" json:"deviceId"`
+	BuildingID    string                 `bson:"building_id,omitempty" json:"buildingId,omitempty"`
+	Command       string                 `bson:"command" json:"command"`
+	Params        map[string]interface{} `bson:"params" json:"params"`
+	TTLSeconds    int                    `bson:"ttl_seconds,omitempty" json:"ttlSeconds,omitempty"`
+	ScheduledAt   *time.Time             `bson:"scheduled_at,omitempty" json:"scheduledAt,omitempty"`
+	Cron          string                 `bson:"cron,omitempty" json:"cron,omitempty"`
+	Status        ScheduleStatus         `bson:"status" json:"status"`
+	NextRunAt     *time.Time             `bson:"next_run_at,omitempty" json:"nextRunAt,omitempty"`
+	LastRunAt     *time.Time             `bson:"last_run_at,omitempty" json:"lastRunAt,omitempty"`
+	LastCommandID string                 `bson:"last_command_id,omitempty" json:"lastCommandId,omitempty"`
+	LastError     string                 `bson:"last_error,omitempty" json:"lastError,omitempty"`
+	RunCount      int                    `bson:"run_count" json:"runCount"`
+	CreatedBy     string                 `bson:"created_by" json:"createdBy"`
+	CreatedAt     time.Time              `bson:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time              `bson:"updated_at" json:"updatedAt"`
+}
+
+// IsRecurring reports whether the command runs on a cron schedule
+func (s *ScheduledCommand) IsRecurring() bool {
+	return s.Cron != ""
+}
+
+// ListScheduledCommandsRequest represents query parameters for listing scheduled commands
+type ListScheduledCommandsRequest struct {
+	Status string `form:"status"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
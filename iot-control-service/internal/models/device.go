@@ -19,18 +19,25 @@ const (
 
 // Device represents a device in the system
 type Device struct {
-	ID           primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
-	DeviceID     string                 `bson:"device_id" json:"deviceId"`
-	Type         string                 `bson:"type" json:"type"`
-	Model        string                 `bson:"model" json:"model"`
-	Location     DeviceLocation         `bson:"location" json:"location"`
-	Capabilities []string               `bson:"capabilities" json:"capabilities"`
-	Status       DeviceStatus           `bson:"status" json:"status"`
-	LastSeen     time.Time              `bson:"last_seen" json:"lastSeen"`
-	Metadata     map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
-	CreatedAt    time.Time              `bson:"created_at" json:"createdAt"`
-	UpdatedAt    time.Time              `bson:"updated_at" json:"updatedAt"`
-	CreatedBy    string                 `bson:"created_by" json:"createdBy"`
+	ID           primitive.ObjectID           `bson:"_id,omitempty" json:"id"`
+	DeviceID     string                       `bson:"device_id" json:"deviceId"`
+	Name         string                       `bson:"name,omitempty" json:"name,omitempty"`
+	Type         string                       `bson:"type" json:"type"`
+	Model        string                       `bson:"model" json:"model"`
+	Location     DeviceLocation               `bson:"location" json:"location"`
+	Capabilities []string                     `bson:"capabilities" json:"capabilities"`
+	Tags         []string                     `bson:"tags,omitempty" json:"tags,omitempty"`
+	Status       DeviceStatus                 `bson:"status" json:"status"`
+	LastSeen     time.Time                    `bson:"last_seen" json:"lastSeen"`
+	Metadata     map[string]interface{}       `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Calibration  map[string]MetricCalibration `bson:"calibration,omitempty" json:"calibration,omitempty"`
+	CreatedAt    time.Time                    `bson:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time                    `bson:"updated_at" json:"updatedAt"`
+	CreatedBy    string                       `bson:"created_by" json:"createdBy"`
+	DeletedAt    *time.Time                   `bson:"deleted_at,omitempty" json:"deletedAt,omitempty"`
+	DeletedBy    string                       `bson:"deleted_by,omitempty" json:"deletedBy,omitempty"`
+	// CredentialHash is the hash of the secret issued to a device that claimed a provisioning token
+	CredentialHash string `bson:"credential_hash,omitempty" json:"-"`
 }
 
 // DeviceLocation represents device location information
@@ -40,6 +47,19 @@ type DeviceLocation struct {
 	Room       string  `bson:"room,omitempty" json:"room,omitempty"`
 	Latitude   float64 `bson:"latitude,omitempty" json:"latitude,omitempty"`
 	Longitude  float64 `bson:"longitude,omitempty" json:"longitude,omitempty"`
+	// Hierarchy places the device in the location hierarchy; it is set through the locations API
+	Hierarchy *DeviceHierarchy `bson:"hierarchy,omitempty" json:"hierarchy,omitempty"`
+}
+
+// DeviceHierarchy is where a device sits in the location hierarchy. LocationID is the floor, zone
+// or room the device was assigned to; the levels above it are filled in and those below are empty.
+type DeviceHierarchy struct {
+	LocationID string   `bson:"location_id" json:"locationId"`
+	SiteID     string   `bson:"site_id,omitempty" json:"siteId,omitempty"`
+	FloorID    string   `bson:"floor_id,omitempty" json:"floorId,omitempty"`
+	ZoneID     string   `bson:"zone_id,omitempty" json:"zoneId,omitempty"`
+	RoomID     string   `bson:"room_id,omitempty" json:"roomId,omitempty"`
+	Path       []string `bson:"path" json:"path"` // location IDs from the top of the hierarchy down to LocationID
 }
 
 // UnmarshalJSON allows DeviceLocation to be unmarshaled from either a string or an object
@@ -64,17 +84,24 @@ func (dl *DeviceLocation) UnmarshalJSON(data []byte) error {
 
 // DeviceResponse represents device data in API responses
 type DeviceResponse struct {
-	ID           string                 `json:"id"`
-	DeviceID     string                 `json:"deviceId"`
-	Type         string                 `json:"type"`
-	Model        string                 `json:"model"`
-	Location     DeviceLocation         `json:"location"`
-	Capabilities []string               `json:"capabilities"`
-	Status       string                 `json:"status"`
-	LastSeen     time.Time              `json:"lastSeen"`
-	Metadata     map[string]interface{} `json:"metadata,omitempty"`
-	CreatedAt    time.Time              `json:"createdAt"`
-	UpdatedAt    time.Time              `json:"updatedAt"`
+	ID           string                       `json:"id"`
+	DeviceID     string                       `json:"deviceId"`
+	Name         string                       `json:"name,omitempty"`
+	Type         string                       `json:"type"`
+	Model        string                       `json:"model"`
+	Location     DeviceLocation               `json:"location"`
+	Capabilities []string                     `json:"capabilities"`
+	Tags         []string                     `json:"tags,omitempty"`
+	Status       string                       `json:"status"`
+	LastSeen     time.Time                    `json:"lastSeen"`
+	Metadata     map[string]interface{}       `json:"metadata,omitempty"`
+	Calibration  map[string]MetricCalibration `json:"calibration,omitempty"`
+	CreatedAt    time.Time                    `json:"createdAt"`
+	UpdatedAt    time.Time                    `json:"updatedAt"`
+	DeletedAt    *time.Time                   `json:"deletedAt,omitempty"`
+	DeletedBy    string                       `json:"deletedBy,omitempty"`
+	// TypeInfo is the device's catalog entry, giving clients its commands, metrics and icon
+	TypeInfo *DeviceTypeResponse `json:"typeInfo,omitempty"`
 }
 
 // ToResponse converts a Device to DeviceResponse
@@ -82,18 +109,48 @@ func (d *Device) ToResponse() *DeviceResponse {
 	return &DeviceResponse{
 		ID:           d.ID.Hex(),
 		DeviceID:     d.DeviceID,
+		Name:         d.Name,
 		Type:         d.Type,
 		Model:        d.Model,
 		Location:     d.Location,
 		Capabilities: d.Capabilities,
+		Tags:         d.Tags,
 		Status:       string(d.Status),
 		LastSeen:     d.LastSeen,
 		Metadata:     d.Metadata,
+		Calibration:  d.Calibration,
 		CreatedAt:    d.CreatedAt,
 		UpdatedAt:    d.UpdatedAt,
+		DeletedAt:    d.DeletedAt,
+		DeletedBy:    d.DeletedBy,
 	}
 }
 
+// MetricCalibration holds the linear correction applied to a metric reported by a device
+type MetricCalibration struct {
+	Scale     float64   `bson:"scale" json:"scale"`
+	Offset    float64   `bson:"offset" json:"offset"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updatedAt"`
+	UpdatedBy string    `bson:"updated_by" json:"updatedBy"`
+}
+
+// Apply returns the calibrated value of a raw reading
+func (c MetricCalibration) Apply(raw float64) float64 {
+	return raw*c.Scale + c.Offset
+}
+
+// CalibrationFactor represents the scale and offset to apply to one metric
+type CalibrationFactor struct {
+	Scale  float64 `json:"scale"`
+	Offset float64 `json:"offset"`
+}
+
+// SetCalibrationRequest represents a request to replace a device's calibration factors.
+// Metrics left out of the request are no longer calibrated.
+type SetCalibrationRequest struct {
+	Metrics map[string]CalibrationFactor `json:"metrics" binding:"required"`
+}
+
 // RegisterDeviceRequest represents a request to register a device
 type RegisterDeviceRequest struct {
 	DeviceID     string                 `json:"deviceId" binding:"required"`
@@ -103,6 +160,7 @@ type RegisterDeviceRequest struct {
 	BuildingID   string                 `json:"buildingId"`
 	Location     DeviceLocation         `json:"location"`
 	Capabilities []string               `json:"capabilities"`
+	Tags         []string               `json:"tags,omitempty"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -121,6 +179,7 @@ type ListDevicesRequest struct {
 	Status     string `form:"status"`
 	Page       int    `form:"page"`
 	Limit      int    `form:"limit"`
+	Fields     string `form:"fields"` // comma-separated fields to return, e.g. deviceId,status
 }
 
 // DevicePrediction represents forecast prediction data for a device
@@ -143,3 +202,50 @@ type ForecastPrediction struct {
 	ConfidenceLevel float64   `json:"confidenceLevel"`
 	Unit            string    `json:"unit"`
 }
+
+// DeviceImportAction is what an import did with one row
+type DeviceImportAction string
+
+const (
+	DeviceImportCreated   DeviceImportAction = "CREATED"
+	DeviceImportUpdated   DeviceImportAction = "UPDATED"
+	DeviceImportUnchanged DeviceImportAction = "UNCHANGED"
+	DeviceImportFailed    DeviceImportAction = "FAILED"
+)
+
+// DeviceImportRow is one device of a bulk import. Empty optional fields keep the current value of an
+// existing device.
+type DeviceImportRow struct {
+	Row        int      `json:"-"`
+	DeviceID   string   `json:"deviceId"`
+	Type       string   `json:"type"`
+	Model      string   `json:"model,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	BuildingID string   `json:"buildingId"`
+	Floor      string   `json:"floor,omitempty"`
+	Room       string   `json:"room,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// ImportDevicesRequest represents a JSON bulk device import
+type ImportDevicesRequest struct {
+	Devices []DeviceImportRow `json:"devices" binding:"required"`
+}
+
+// DeviceImportRowResult reports the outcome of one import row
+type DeviceImportRowResult struct {
+	Row      int                `json:"row"`
+	DeviceID string             `json:"deviceId,omitempty"`
+	Action   DeviceImportAction `json:"action"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// DeviceImportResult summarizes a bulk device import
+type DeviceImportResult struct {
+	Total     int                     `json:"total"`
+	Created   int                     `json:"created"`
+	Updated   int                     `json:"updated"`
+	Unchanged int                     `json:"unchanged"`
+	Failed    int                     `json:"failed"`
+	Rows      []DeviceImportRowResult `json:"rows"`
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeviceType is a catalog entry describing a kind of device: the commands it accepts, the
+// telemetry it reports and hints for presenting it in the UI
+type DeviceType struct {
+	ID                 primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Name               string                 `bson:"name" json:"name"` // e.g. HVAC, LIGHTING
+	DisplayName        string                 `bson:"display_name" json:"displayName"`
+	Description        string                 `bson:"description,omitempty" json:"description,omitempty"`
+	SupportedCommands  []string               `bson:"supported_commands" json:"supportedCommands"`
+	TelemetryMetrics   []string               `bson:"telemetry_metrics" json:"telemetryMetrics"`
+	Icon               string                 `bson:"icon,omitempty" json:"icon,omitempty"`
+	DefaultConstraints map[string]interface{} `bson:"default_constraints,omitempty" json:"defaultConstraints,omitempty"`
+	RateLimit          *CommandRateLimit      `bson:"rate_limit,omitempty" json:"rateLimit,omitempty"`
+	HighImpactCommands []string               `bson:"high_impact_commands,omitempty" json:"highImpactCommands,omitempty"` // Commands that need a second user's approval before they are sent
+	IsSystem           bool                   `bson:"is_system" json:"isSystem"`
+	CreatedAt          time.Time              `bson:"created_at" json:"createdAt"`
+	UpdatedAt          time.Time              `bson:"updated_at" json:"updatedAt"`
+	CreatedBy          string                 `bson:"created_by,omitempty" json:"createdBy,omitempty"`
+}
+
+// StateChangeCommands switch a device on, off or between modes. Devices such as HVAC compressors
+// are damaged by switching too often, so their type can require a minimum interval between them.
+var StateChangeCommands = []string{"TURN_ON", "TURN_OFF", "SET_MODE"}
+
+// IsStateChangeCommand reports whether a command switches a device's state
+func IsStateChangeCommand(command string) bool {
+	for _, stateChange := range StateChangeCommands {
+		if stateChange == command {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandRateLimit protects devices of a type from rapid repeated commands. Zero values disable
+// the corresponding limit.
+type CommandRateLimit struct {
+	MaxCommands           int `bson:"max_commands,omitempty" json:"maxCommands,omitempty"`                       // Commands accepted per window
+	WindowSeconds         int `bson:"window_seconds,omitempty" json:"windowSeconds,omitempty"`                   // Length of the window MaxCommands applies to
+	MinStateChangeSeconds int `bson:"min_state_change_seconds,omitempty" json:"minStateChangeSeconds,omitempty"` // Minimum time between state change commands
+}
+
+// SupportsCommand reports whether devices of this type accept a command
+func (t *DeviceType) SupportsCommand(command string) bool {
+	for _, supported := range t.SupportedCommands {
+		if supported == command {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresApproval reports whether a command is high-impact for devices of this type and must be
+// approved by another user before it is sent
+func (t *DeviceType) RequiresApproval(command string) bool {
+	for _, highImpact := range t.HighImpactCommands {
+		if highImpact == command {
+			return true
+		}
+	}
+	return false
+}
+
+// DeviceTypeResponse represents a device type in API responses
+type DeviceTypeResponse struct {
+	ID                 string                 `json:"id"`
+	Name               string                 `json:"name"`
+	DisplayName        string                 `json:"displayName"`
+	Description        string                 `json:"description,omitempty"`
+	SupportedCommands  []string               `json:"supportedCommands"`
+	TelemetryMetrics   []string               `json:"telemetryMetrics"`
+	Icon               string                 `json:"icon,omitempty"`
+	DefaultConstraints map[string]interface{} `json:"defaultConstraints,omitempty"`
+	RateLimit          *CommandRateLimit      `json:"rateLimit,omitempty"`
+	HighImpactCommands []string               `json:"highImpactCommands,omitempty"`
+	IsSystem           bool                   `json:"isSystem"`
+	CreatedAt          time.Time              `json:"createdAt"`
+	UpdatedAt          time.Time              `json:"updatedAt"`
+}
+
+// ToResponse converts a DeviceType to DeviceTypeResponse
+func (t *DeviceType) ToResponse() *DeviceTypeResponse {
+	return &DeviceTypeResponse{
+		ID:                 t.ID.Hex(),
+		Name:               t.Name,
+		DisplayName:        t.DisplayName,
+		Description:        t.Description,
+		SupportedCommands:  t.SupportedCommands,
+		TelemetryMetrics:   t.TelemetryMetrics,
+		Icon:               t.Icon,
+		DefaultConstraints: t.DefaultConstraints,
+		RateLimit:          t.RateLimit,
+		HighImpactCommands: t.HighImpactCommands,
+		IsSystem:           t.IsSystem,
+		CreatedAt:          t.CreatedAt,
+		UpdatedAt:          t.UpdatedAt,
+	}
+}
+
+// CreateDeviceTypeRequest represents a request to add a device type to the catalog
+type CreateDeviceTypeRequest struct {
+	Name               string                 `json:"name" binding:"required"`
+	DisplayName        string                 `json:"displayName"`
+	Description        string                 `json:"description"`
+	SupportedCommands  []string               `json:"supportedCommands"`
+	TelemetryMetrics   []string               `json:"telemetryMetrics"`
+	Icon               string                 `json:"icon"`
+	DefaultConstraints map[string]interface{} `json:"defaultConstraints"`
+	RateLimit          *CommandRateLimit      `json:"rateLimit"`
+	HighImpactCommands []string               `json:"highImpactCommands"`
+}
+
+// UpdateDeviceTypeRequest represents a request to update a device type. Omitted fields are left unchanged.
+type UpdateDeviceTypeRequest struct {
+	DisplayName        *string                `json:"displayName"`
+	Description        *string                `json:"description"`
+	SupportedCommands  []string               `json:"supportedCommands"`
+	TelemetryMetrics   []string               `json:"telemetryMetrics"`
+	Icon               *string                `json:"icon"`
+	DefaultConstraints map[string]interface{} `json:"defaultConstraints"`
+	RateLimit          *CommandRateLimit      `json:"rateLimit"`          // An empty object removes the rate limit
+	HighImpactCommands []string               `json:"highImpactCommands"` // An empty list removes the approval requirement
+}
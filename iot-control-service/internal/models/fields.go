@@ -0,0 +1,171 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FieldSelection is the set of fields a client asked for with the fields query parameter, e.g.
+// fields=deviceId,status,location.buildingId. The fields are projected in the database query, and
+// the response is trimmed to them so unselected fields are not sent as empty values. The id is
+// always returned.
+type FieldSelection struct {
+	paths      []string
+	projection bson.M
+}
+
+// ParseFieldSelection parses a comma-separated fields parameter against the JSON field names of a
+// stored document, such as Device{}. Nested fields are selected with dots. Returns nil when no
+// fields are requested, which selects every field.
+func ParseFieldSelection(param string, document interface{}) (*FieldSelection, error) {
+	if strings.TrimSpace(param) == "" {
+		return nil, nil
+	}
+
+	names := make(map[string]string)
+	collectFieldNames(reflect.TypeOf(document), "", "", names, 0)
+
+	selected := make(map[string]string)
+	for _, raw := range strings.Split(param, ",") {
+		path := strings.TrimSpace(raw)
+		if path == "" {
+			continue
+		}
+		bsonPath, ok := names[path]
+		if !ok {
+			return nil, fmt.Errorf("validation failed: unknown field %q", path)
+		}
+		selected[path] = bsonPath
+	}
+	if len(selected) == 0 {
+		return nil, nil
+	}
+
+	selection := &FieldSelection{projection: bson.M{}}
+	for path, bsonPath := range selected {
+		// A selected parent already includes its children, and MongoDB rejects both in one projection
+		if coveredBySelectedParent(path, selected) {
+			continue
+		}
+		selection.paths = append(selection.paths, path)
+		selection.projection[bsonPath] = 1
+	}
+	sort.Strings(selection.paths)
+	return selection, nil
+}
+
+// Projection returns the MongoDB projection of the selection, or nil to return whole documents
+func (s *FieldSelection) Projection() bson.M {
+	if s == nil {
+		return nil
+	}
+	return s.projection
+}
+
+// Apply trims a response, or a list of responses, to the selected fields. A nil selection returns
+// the response unchanged.
+func (s *FieldSelection) Apply(response interface{}) (interface{}, error) {
+	if s == nil {
+		return response, nil
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	tree := fieldTree{"id": nil}
+	for _, path := range s.paths {
+		tree.add(strings.Split(path, "."))
+	}
+	return tree.trim(decoded), nil
+}
+
+// fieldTree holds selected JSON paths by level; a nil subtree keeps the whole value
+type fieldTree map[string]fieldTree
+
+func (t fieldTree) add(parts []string) {
+	child, exists := t[parts[0]]
+	if exists && child == nil {
+		return
+	}
+	if len(parts) == 1 {
+		t[parts[0]] = nil
+		return
+	}
+	if child == nil {
+		child = fieldTree{}
+		t[parts[0]] = child
+	}
+	child.add(parts[1:])
+}
+
+func (t fieldTree) trim(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		trimmed := make(map[string]interface{}, len(t))
+		for key, subtree := range t {
+			field, ok := v[key]
+			if !ok {
+				continue
+			}
+			if subtree == nil {
+				trimmed[key] = field
+			} else {
+				trimmed[key] = subtree.trim(field)
+			}
+		}
+		return trimmed
+	case []interface{}:
+		trimmed := make([]interface{}, len(v))
+		for i, item := range v {
+			trimmed[i] = t.trim(item)
+		}
+		return trimmed
+	}
+	return value
+}
+
+// collectFieldNames maps the JSON paths of a document type to their BSON paths, descending into
+// nested documents and arrays of documents
+func collectFieldNames(t reflect.Type, jsonPrefix, bsonPrefix string, names map[string]string, depth int) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) || depth > 3 {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		bsonName := strings.Split(field.Tag.Get("bson"), ",")[0]
+		if jsonName == "" || jsonName == "-" || bsonName == "" || bsonName == "-" {
+			continue
+		}
+		jsonPath := jsonPrefix + jsonName
+		bsonPath := bsonPrefix + bsonName
+		names[jsonPath] = bsonPath
+		collectFieldNames(field.Type, jsonPath+".", bsonPath+".", names, depth+1)
+	}
+}
+
+// coveredBySelectedParent reports whether a parent of the path is also selected
+func coveredBySelectedParent(path string, selected map[string]string) bool {
+	for i := strings.LastIndex(path, "."); i > 0; i = strings.LastIndex(path[:i], ".") {
+		if _, ok := selected[path[:i]]; ok {
+			return true
+		}
+	}
+	return false
+}
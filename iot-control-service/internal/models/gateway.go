@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GatewayProtocol is the fieldbus protocol a gateway reads a device over
+type GatewayProtocol string
+
+const (
+	GatewayProtocolModbus GatewayProtocol = "MODBUS"
+	GatewayProtocolBACnet GatewayProtocol = "BACNET"
+)
+
+// RegisterDataType is how the raw 16-bit words of a Modbus register are interpreted
+type RegisterDataType string
+
+const (
+	RegisterUint16  RegisterDataType = "UINT16"
+	RegisterInt16   RegisterDataType = "INT16"
+	RegisterUint32  RegisterDataType = "UINT32"
+	RegisterInt32   RegisterDataType = "INT32"
+	RegisterFloat32 RegisterDataType = "FLOAT32"
+)
+
+// RegisterWordOrder is the order of the words of a 32-bit Modbus value
+type RegisterWordOrder string
+
+const (
+	// RegisterHighWordFirst sends the most significant word first (big-endian, the Modbus default)
+	RegisterHighWordFirst RegisterWordOrder = "HIGH_FIRST"
+	// RegisterLowWordFirst sends the least significant word first, as many meters do
+	RegisterLowWordFirst RegisterWordOrder = "LOW_FIRST"
+)
+
+// Telemetry source of readings ingested from fieldbus gateways
+const TelemetrySourceGateway = "GATEWAY"
+
+// RegisterMapping maps a gateway point, a Modbus register or a BACnet object, to a telemetry
+// metric. The decoded value is multiplied by Scale, Offset is added, and the result is converted
+// from Unit to TargetUnit when both are set. A Scale of 0 is treated as 1.
+type RegisterMapping struct {
+	Address    string            `bson:"address" json:"address" binding:"required"` // e.g. "40001" or "analogInput:3"
+	Metric     string            `bson:"metric" json:"metric" binding:"required"`
+	DataType   RegisterDataType  `bson:"data_type,omitempty" json:"dataType,omitempty"`   // Modbus only; defaults to UINT16
+	WordOrder  RegisterWordOrder `bson:"word_order,omitempty" json:"wordOrder,omitempty"` // Modbus 32-bit types; defaults to HIGH_FIRST
+	Scale      float64           `bson:"scale,omitempty" json:"scale,omitempty"`
+	Offset     float64           `bson:"offset,omitempty" json:"offset,omitempty"`
+	Unit       string            `bson:"unit,omitempty" json:"unit,omitempty"`
+	TargetUnit string            `bson:"target_unit,omitempty" json:"targetUnit,omitempty"`
+}
+
+// GatewayMapping holds how the points a gateway reads from a device become its telemetry
+type GatewayMapping struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	DeviceID   string             `bson:"device_id" json:"deviceId"`
+	BuildingID string             `bson:"building_id" json:"buildingId"`
+	Protocol   GatewayProtocol    `bson:"protocol" json:"protocol"`
+	Registers  []RegisterMapping  `bson:"registers" json:"registers"`
+	UpdatedBy  string             `bson:"updated_by" json:"updatedBy"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// GatewayMappingRequest represents a request to create or replace the mapping of a device
+type GatewayMappingRequest struct {
+	Protocol  GatewayProtocol   `json:"protocol" binding:"required,oneof=MODBUS BACNET"`
+	Registers []RegisterMapping `json:"registers" binding:"required,min=1,max=200,dive"`
+}
+
+// ListGatewayMappingsRequest represents query parameters for listing gateway mappings
+type ListGatewayMappingsRequest struct {
+	BuildingID string `form:"buildingId"`
+	Page       int    `form:"page"`
+	Limit      int    `form:"limit"`
+}
+
+// GatewayPointValue is a value a gateway read from a point: a present value, or for Modbus the
+// raw register words, which are decoded with the mapping's data type
+type GatewayPointValue struct {
+	Address string   `json:"address" binding:"required"`
+	Value   *float64 `json:"value,omitempty"`
+	Words   []uint16 `json:"words,omitempty" binding:"omitempty,max=2"`
+}
+
+// GatewayReading is the set of points a gateway read from one device at one time
+type GatewayReading struct {
+	DeviceID  string              `json:"deviceId" binding:"required"`
+	Timestamp time.Time           `json:"timestamp"`
+	Points    []GatewayPointValue `json:"points" binding:"required,min=1,dive"`
+}
+
+// GatewayReadingsRequest represents a batch of readings posted by a gateway
+type GatewayReadingsRequest struct {
+	GatewayID string           `json:"gatewayId" binding:"required"`
+	Readings  []GatewayReading `json:"readings" binding:"required,min=1,max=1000,dive"`
+}
+
+// GatewayPointError explains why a reading or one of its points was not stored
+type GatewayPointError struct {
+	Reading  int    `json:"reading"` // index in the request
+	DeviceID string `json:"deviceId"`
+	Address  string `json:"address,omitempty"` // empty when the whole reading was rejected
+	Message  string `json:"message"`
+}
+
+// GatewayIngestResult reports what was stored from a batch of gateway readings
+type GatewayIngestResult struct {
+	GatewayID        string              `json:"gatewayId"`
+	ReadingsAccepted int                 `json:"readingsAccepted"`
+	ReadingsRejected int                 `json:"readingsRejected"`
+	MetricsStored    int                 `json:"metricsStored"`
+	Errors           []GatewayPointError `json:"errors"`
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LocationLevel is a level of the location hierarchy: site → building → floor → zone → room
+type LocationLevel string
+
+const (
+	LocationLevelSite     LocationLevel = "SITE"
+	LocationLevelBuilding LocationLevel = "BUILDING"
+	LocationLevelFloor    LocationLevel = "FLOOR"
+	LocationLevelZone     LocationLevel = "ZONE"
+	LocationLevelRoom     LocationLevel = "ROOM"
+)
+
+// ParentLevels returns the levels a location of this level can be placed under. Buildings may
+// stand alone and rooms may sit directly on a floor when it is not divided into zones.
+func (l LocationLevel) ParentLevels() []LocationLevel {
+	switch l {
+	case LocationLevelBuilding:
+		return []LocationLevel{LocationLevelSite}
+	case LocationLevelFloor:
+		return []LocationLevel{LocationLevelBuilding}
+	case LocationLevelZone:
+		return []LocationLevel{LocationLevelFloor}
+	case LocationLevelRoom:
+		return []LocationLevel{LocationLevelZone, LocationLevelFloor}
+	}
+	return nil
+}
+
+// FloorPlanPoint is a point on a floor plan, in the units of the plan's width and height
+type FloorPlanPoint struct {
+	X float64 `bson:"x" json:"x" binding:"min=0"`
+	Y float64 `bson:"y" json:"y" binding:"min=0"`
+}
+
+// FloorPlan is the drawing of a floor that the shapes of its zones and rooms are placed on
+type FloorPlan struct {
+	ImageURL   string    `bson:"image_url,omitempty" json:"imageUrl,omitempty"`
+	Width      float64   `bson:"width" json:"width"`
+	Height     float64   `bson:"height" json:"height"`
+	UploadedBy string    `bson:"uploaded_by" json:"uploadedBy"`
+	UploadedAt time.Time `bson:"uploaded_at" json:"uploadedAt"`
+}
+
+// Location is a node of the location hierarchy. A building location's ID is the building ID
+// devices carry; every location below it keeps that building ID. Sites group buildings and
+// belong to the organization that created them.
+type Location struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LocationID string             `bson:"location_id" json:"locationId"`
+	Level      LocationLevel      `bson:"level" json:"level"`
+	Name       string             `bson:"name" json:"name"`
+	ParentID   string             `bson:"parent_id,omitempty" json:"parentId,omitempty"`
+	BuildingID string             `bson:"building_id,omitempty" json:"buildingId,omitempty"`
+	OrgID      string             `bson:"org_id,omitempty" json:"orgId,omitempty"`
+	// Path lists the IDs of the location's ancestors, from the top of the hierarchy down
+	Path      []string         `bson:"path" json:"path"`
+	Area      float64          `bson:"area,omitempty" json:"area,omitempty"`            // m²
+	Shape     []FloorPlanPoint `bson:"shape,omitempty" json:"shape,omitempty"`          // outline on the floor plan, for zones and rooms
+	FloorPlan *FloorPlan       `bson:"floor_plan,omitempty" json:"floorPlan,omitempty"` // floors only
+	CreatedBy string           `bson:"created_by" json:"createdBy"`
+	CreatedAt time.Time        `bson:"created_at" json:"createdAt"`
+	UpdatedAt time.Time        `bson:"updated_at" json:"updatedAt"`
+}
+
+// CreateLocationRequest represents a request to add a location to the hierarchy. A building's
+// locationId is its building ID; other locations get a generated ID when none is given.
+type CreateLocationRequest struct {
+	LocationID string           `json:"locationId" binding:"omitempty,max=64"`
+	Level      LocationLevel    `json:"level" binding:"required,oneof=SITE BUILDING FLOOR ZONE ROOM"`
+	Name       string           `json:"name" binding:"required,max=100"`
+	ParentID   string           `json:"parentId"`
+	Area       float64          `json:"area" binding:"min=0"`
+	Shape      []FloorPlanPoint `json:"shape" binding:"omitempty,min=3,dive"`
+}
+
+// UpdateLocationRequest represents a request to rename a location or change its area or shape.
+// A location keeps its place in the hierarchy.
+type UpdateLocationRequest struct {
+	Name  string           `json:"name" binding:"required,max=100"`
+	Area  float64          `json:"area" binding:"min=0"`
+	Shape []FloorPlanPoint `json:"shape" binding:"omitempty,min=3,dive"`
+}
+
+// ListLocationsRequest represents query parameters for listing locations
+type ListLocationsRequest struct {
+	BuildingID string `form:"buildingId"`
+	ParentID   string `form:"parentId"`
+	Level      string `form:"level" binding:"omitempty,oneof=SITE BUILDING FLOOR ZONE ROOM"`
+}
+
+// LocationDetail is a location with its direct children and the number of devices assigned to it
+// or to any location below it
+type LocationDetail struct {
+	*Location
+	Children    []*Location `json:"children"`
+	DeviceCount int64       `json:"deviceCount"`
+}
+
+// AssignDevicesRequest represents a request to assign devices to a floor, zone or room
+type AssignDevicesRequest struct {
+	DeviceIDs []string `json:"deviceIds" binding:"required,min=1,max=500"`
+}
+
+// AssignDevicesResult reports which devices were assigned to a location
+type AssignDevicesResult struct {
+	LocationID string              `json:"locationId"`
+	Assigned   []string            `json:"assigned"`
+	Failed     []DeviceAssignError `json:"failed,omitempty"`
+}
+
+// DeviceAssignError explains why a device could not be assigned
+type DeviceAssignError struct {
+	DeviceID string `json:"deviceId"`
+	Error    string `json:"error"`
+}
+
+// FloorPlanZone is a zone drawn on an uploaded floor plan. A zone with a locationId updates that
+// zone of the floor; one without is created.
+type FloorPlanZone struct {
+	LocationID string           `json:"locationId"`
+	Name       string           `json:"name" binding:"required,max=100"`
+	Area       float64          `json:"area" binding:"min=0"`
+	Shape      []FloorPlanPoint `json:"shape" binding:"required,min=3,dive"`
+}
+
+// FloorPlanRequest represents an upload of a floor plan and the zones drawn on it. Zones of the
+// floor left out of the upload are kept.
+type FloorPlanRequest struct {
+	ImageURL string          `json:"imageUrl" binding:"omitempty,url,max=500"`
+	Width    float64         `json:"width" binding:"required,gt=0"`
+	Height   float64         `json:"height" binding:"required,gt=0"`
+	Zones    []FloorPlanZone `json:"zones" binding:"max=200,dive"`
+}
+
+// FloorPlanDevice is a device shown on a floor plan
+type FloorPlanDevice struct {
+	DeviceID string `json:"deviceId"`
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+}
+
+// FloorPlanArea is a zone or room of a floor plan with its devices. Devices in a room are listed
+// under the room rather than its zone.
+type FloorPlanArea struct {
+	*Location
+	Devices       []FloorPlanDevice `json:"devices"`
+	OnlineDevices int               `json:"onlineDevices"`
+}
+
+// FloorPlanView is a floor with its plan, the zones and rooms drawn on it and their devices, as
+// shown on the dashboard. Devices assigned to the floor itself are listed as unzoned.
+type FloorPlanView struct {
+	Floor   *Location         `json:"floor"`
+	Areas   []*FloorPlanArea  `json:"areas"`
+	Unzoned []FloorPlanDevice `json:"unzoned"`
+}
@@ -15,73 +15,274 @@ const (
 	OptimizationStatusCompleted OptimizationExecutionStatus = "COMPLETED"
 	OptimizationStatusFailed    OptimizationExecutionStatus = "FAILED"
 	OptimizationStatusCancelled OptimizationExecutionStatus = "CANCELLED"
+	OptimizationStatusPaused    OptimizationExecutionStatus = "PAUSED" // Stopped by the comfort guardrail; can be resumed
 )
 
 // OptimizationScenario represents an optimization scenario
 type OptimizationScenario struct {
-	ID              primitive.ObjectID          `bson:"_id,omitempty" json:"id"`
-	ScenarioID      string                      `bson:"scenario_id" json:"scenarioId"`
-	ForecastID      string                      `bson:"forecast_id,omitempty" json:"forecastId,omitempty"`
-	BuildingID      string                      `bson:"building_id" json:"buildingId"`
-	Actions         []OptimizationAction        `bson:"actions" json:"actions"`
-	ExecutionStatus OptimizationExecutionStatus `bson:"execution_status" json:"executionStatus"`
-	Progress        float64                     `bson:"progress" json:"progress"` // 0.0 to 1.0
-	StartedAt       *time.Time                  `bson:"started_at,omitempty" json:"startedAt,omitempty"`
-	CompletedAt     *time.Time                  `bson:"completed_at,omitempty" json:"completedAt,omitempty"`
-	ErrorMsg        string                      `bson:"error_msg,omitempty" json:"errorMsg,omitempty"`
-	CreatedBy       string                      `bson:"created_by" json:"createdBy"`
-	CreatedAt       time.Time                   `bson:"created_at" json:"createdAt"`
-	UpdatedAt       time.Time                   `bson:"updated_at" json:"updatedAt"`
+	ID               primitive.ObjectID          `bson:"_id,omitempty" json:"id"`
+	ScenarioID       string                      `bson:"scenario_id" json:"scenarioId"`
+	SourceScenarioID string                      `bson:"source_scenario_id,omitempty" json:"sourceScenarioId,omitempty"` // Forecast service scenario that requested the execution
+	ScenarioType     string                      `bson:"scenario_type,omitempty" json:"scenarioType,omitempty"`          // Optimization type of the source scenario, e.g. DEMAND_RESPONSE
+	ForecastID       string                      `bson:"forecast_id,omitempty" json:"forecastId,omitempty"`
+	BuildingID       string                      `bson:"building_id" json:"buildingId"`
+	Actions          []OptimizationAction        `bson:"actions" json:"actions"`
+	ExecutionStatus  OptimizationExecutionStatus `bson:"execution_status" json:"executionStatus"`
+	Policy           ExecutionPolicy             `bson:"policy" json:"policy"`
+	Comfort          *ComfortBand                `bson:"comfort,omitempty" json:"comfort,omitempty"`
+	Interventions    []GuardrailIntervention     `bson:"guardrail_interventions,omitempty" json:"guardrailInterventions,omitempty"`
+	Progress         float64                     `bson:"progress" json:"progress"` // 0.0 to 1.0
+	StartedAt        *time.Time                  `bson:"started_at,omitempty" json:"startedAt,omitempty"`
+	CompletedAt      *time.Time                  `bson:"completed_at,omitempty" json:"completedAt,omitempty"`
+	ErrorMsg         string                      `bson:"error_msg,omitempty" json:"errorMsg,omitempty"`
+	CreatedBy        string                      `bson:"created_by" json:"createdBy"`
+	CreatedAt        time.Time                   `bson:"created_at" json:"createdAt"`
+	UpdatedAt        time.Time                   `bson:"updated_at" json:"updatedAt"`
 }
 
-// OptimizationAction represents a single action in an optimization scenario
+// Statuses of a scenario action. Actions that timed out take the command's TIMEOUT status.
+const (
+	ActionStatusPending    = "PENDING"
+	ActionStatusSent       = "SENT"
+	ActionStatusApplied    = "APPLIED"
+	ActionStatusFailed     = "FAILED"
+	ActionStatusRolledBack = "ROLLED_BACK"
+)
+
+// OptimizationAction represents a single action in an optimization scenario. Retry overrides the
+// scenario's retry policy for the action. RollbackCommand and RollbackParams undo the action when
+// the scenario is aborted; without them TURN_ON and TURN_OFF are undone by the opposite command and
+// other commands by sending them again with the values the device reported before the action.
+// An action with a LocationID instead of a DeviceID targets every device assigned to that location
+// or below it, optionally only those of DeviceType; it is expanded into one action per device, which
+// keeps the LocationID, when the scenario is created.
 type OptimizationAction struct {
-	DeviceID  string                 `bson:"device_id" json:"deviceId"`
-	Command   string                 `bson:"command" json:"command"`
-	Params    map[string]interface{} `bson:"params" json:"params"`
-	Priority  int                    `bson:"priority" json:"priority"`
-	Status    string                 `bson:"status" json:"status"` // "PENDING", "SENT", "APPLIED", "FAILED"
-	CommandID string                 `bson:"command_id,omitempty" json:"commandId,omitempty"`
+	DeviceID          string                 `bson:"device_id" json:"deviceId"`
+	LocationID        string                 `bson:"location_id,omitempty" json:"locationId,omitempty"`
+	DeviceType        string                 `bson:"device_type,omitempty" json:"deviceType,omitempty"`
+	Command           string                 `bson:"command" json:"command"`
+	Params            map[string]interface{} `bson:"params" json:"params"`
+	Priority          int                    `bson:"priority" json:"priority"`
+	Status            string                 `bson:"status" json:"status"` // "PENDING", "SENT", "APPLIED", "FAILED", "TIMEOUT", "ROLLED_BACK"
+	CommandID         string                 `bson:"command_id,omitempty" json:"commandId,omitempty"`
+	Retry             *RetryPolicy           `bson:"retry,omitempty" json:"retry,omitempty"`
+	RollbackCommand   string                 `bson:"rollback_command,omitempty" json:"rollbackCommand,omitempty"`
+	RollbackParams    map[string]interface{} `bson:"rollback_params,omitempty" json:"rollbackParams,omitempty"`
+	Attempts          int                    `bson:"attempts,omitempty" json:"attempts,omitempty"`
+	LastError         string                 `bson:"last_error,omitempty" json:"lastError,omitempty"`
+	PreviousState     map[string]interface{} `bson:"previous_state,omitempty" json:"previousState,omitempty"` // Reported values of the metrics the action changes, before it was sent
+	RollbackCommandID string                 `bson:"rollback_command_id,omitempty" json:"rollbackCommandId,omitempty"`
+}
+
+// RetryPolicy controls how often a failed action is sent again. The wait before each retry starts
+// at BackoffSeconds and doubles with every further attempt.
+type RetryPolicy struct {
+	MaxAttempts    int `bson:"max_attempts" json:"maxAttempts"`
+	BackoffSeconds int `bson:"backoff_seconds" json:"backoffSeconds"`
+}
+
+// ExecutionPolicy controls how a scenario handles failed actions. Once the share of failed actions
+// exceeds FailureThresholdPercent, the execution is aborted and the actions already carried out are
+// rolled back; zero disables the threshold.
+type ExecutionPolicy struct {
+	Retry                   RetryPolicy `bson:"retry" json:"retry"`
+	FailureThresholdPercent float64     `bson:"failure_threshold_percent,omitempty" json:"failureThresholdPercent,omitempty"`
+}
+
+// ComfortBand is the indoor temperature range, in °C, a scenario must keep. While the scenario
+// executes and for MonitorMinutes after it completes, the temperature its devices report is
+// watched; once a device leaves the band by more than ToleranceCelsius, the scenario's actions on
+// it are rolled back and a running execution is paused.
+type ComfortBand struct {
+	MinTemperature   *float64 `bson:"min_temperature,omitempty" json:"minTemperature,omitempty"`
+	MaxTemperature   *float64 `bson:"max_temperature,omitempty" json:"maxTemperature,omitempty"`
+	ToleranceCelsius float64  `bson:"tolerance_celsius" json:"toleranceCelsius"`
+	MonitorMinutes   int      `bson:"monitor_minutes" json:"monitorMinutes"`
+}
+
+// Sides of a comfort band crossed by a device's temperature
+const (
+	ComfortBoundMin = "MIN"
+	ComfortBoundMax = "MAX"
+)
+
+// GuardrailIntervention records the comfort guardrail stepping in on a scenario: the device whose
+// temperature left the band, the scenario actions rolled back and whether execution was paused
+type GuardrailIntervention struct {
+	DeviceID             string    `bson:"device_id" json:"deviceId"`
+	Temperature          float64   `bson:"temperature" json:"temperature"`
+	Bound                string    `bson:"bound" json:"bound"`
+	Limit                float64   `bson:"limit" json:"limit"`
+	ReadingAt            time.Time `bson:"reading_at" json:"readingAt"`
+	ActionsRolledBack    int       `bson:"actions_rolled_back" json:"actionsRolledBack"`
+	ActionsNotRolledBack int       `bson:"actions_not_rolled_back,omitempty" json:"actionsNotRolledBack,omitempty"`
+	Paused               bool      `bson:"paused" json:"paused"`
+	Message              string    `bson:"message" json:"message"`
+	TriggeredAt          time.Time `bson:"triggered_at" json:"triggeredAt"`
 }
 
 // OptimizationScenarioResponse represents optimization scenario data in API responses
 type OptimizationScenarioResponse struct {
-	ID              string                 `json:"id"`
-	ScenarioID      string                 `json:"scenarioId"`
-	ForecastID      string                 `json:"forecastId,omitempty"`
-	BuildingID      string                 `json:"buildingId"`
-	Actions         []OptimizationAction   `json:"actions"`
-	ExecutionStatus string                 `json:"executionStatus"`
-	Progress        float64                `json:"progress"`
-	StartedAt       *time.Time             `json:"startedAt,omitempty"`
-	CompletedAt     *time.Time             `json:"completedAt,omitempty"`
-	ErrorMsg        string                 `json:"errorMsg,omitempty"`
-	CreatedAt       time.Time              `json:"createdAt"`
-	UpdatedAt       time.Time              `json:"updatedAt"`
+	ID               string                  `json:"id"`
+	ScenarioID       string                  `json:"scenarioId"`
+	SourceScenarioID string                  `json:"sourceScenarioId,omitempty"`
+	ScenarioType     string                  `json:"scenarioType,omitempty"`
+	ForecastID       string                  `json:"forecastId,omitempty"`
+	BuildingID       string                  `json:"buildingId"`
+	Actions          []OptimizationAction    `json:"actions"`
+	ExecutionStatus  string                  `json:"executionStatus"`
+	Policy           ExecutionPolicy         `json:"policy"`
+	Comfort          *ComfortBand            `json:"comfort,omitempty"`
+	Interventions    []GuardrailIntervention `json:"guardrailInterventions,omitempty"`
+	Locations        []LocationActionSummary `json:"locations,omitempty"`
+	Progress         float64                 `json:"progress"`
+	StartedAt        *time.Time              `json:"startedAt,omitempty"`
+	CompletedAt      *time.Time              `json:"completedAt,omitempty"`
+	ErrorMsg         string                  `json:"errorMsg,omitempty"`
+	CreatedAt        time.Time               `json:"createdAt"`
+	UpdatedAt        time.Time               `json:"updatedAt"`
 }
 
 // ToResponse converts an OptimizationScenario to OptimizationScenarioResponse
 func (o *OptimizationScenario) ToResponse() *OptimizationScenarioResponse {
 	return &OptimizationScenarioResponse{
-		ID:              o.ID.Hex(),
-		ScenarioID:      o.ScenarioID,
-		ForecastID:      o.ForecastID,
-		BuildingID:      o.BuildingID,
-		Actions:         o.Actions,
-		ExecutionStatus: string(o.ExecutionStatus),
-		Progress:        o.Progress,
-		StartedAt:       o.StartedAt,
-		CompletedAt:     o.CompletedAt,
-		ErrorMsg:        o.ErrorMsg,
-		CreatedAt:       o.CreatedAt,
-		UpdatedAt:       o.UpdatedAt,
+		ID:               o.ID.Hex(),
+		ScenarioID:       o.ScenarioID,
+		SourceScenarioID: o.SourceScenarioID,
+		ScenarioType:     o.ScenarioType,
+		ForecastID:       o.ForecastID,
+		BuildingID:       o.BuildingID,
+		Actions:          o.Actions,
+		ExecutionStatus:  string(o.ExecutionStatus),
+		Policy:           o.Policy,
+		Comfort:          o.Comfort,
+		Interventions:    o.Interventions,
+		Locations:        SummarizeLocationActions(o.Actions),
+		Progress:         o.Progress,
+		StartedAt:        o.StartedAt,
+		CompletedAt:      o.CompletedAt,
+		ErrorMsg:         o.ErrorMsg,
+		CreatedAt:        o.CreatedAt,
+		UpdatedAt:        o.UpdatedAt,
 	}
 }
 
+// LocationActionSummary counts the actions of a scenario that targeted a location by their status.
+// Failed includes actions that timed out.
+type LocationActionSummary struct {
+	LocationID string `json:"locationId"`
+	Actions    int    `json:"actions"`
+	Applied    int    `json:"applied"`
+	Failed     int    `json:"failed"`
+	RolledBack int    `json:"rolledBack"`
+	Pending    int    `json:"pending"`
+}
+
+// SummarizeLocationActions summarizes the actions that targeted a location, in the order the
+// locations first appear
+func SummarizeLocationActions(actions []OptimizationAction) []LocationActionSummary {
+	var summaries []LocationActionSummary
+	index := make(map[string]int)
+	for _, action := range actions {
+		if action.LocationID == "" {
+			continue
+		}
+		i, ok := index[action.LocationID]
+		if !ok {
+			i = len(summaries)
+			index[action.LocationID] = i
+			summaries = append(summaries, LocationActionSummary{LocationID: action.LocationID})
+		}
+		summary := &summaries[i]
+		summary.Actions++
+		switch action.Status {
+		case ActionStatusApplied:
+			summary.Applied++
+		case ActionStatusRolledBack:
+			summary.RolledBack++
+		case ActionStatusPending, ActionStatusSent, "":
+			summary.Pending++
+		default:
+			summary.Failed++
+		}
+	}
+	return summaries
+}
+
 // ApplyOptimizationRequest represents a request to apply an optimization scenario
 type ApplyOptimizationRequest struct {
-	ScenarioID string                 `json:"scenarioId" binding:"required"`
-	ForecastID string                 `json:"forecastId,omitempty"`
-	BuildingID string                 `json:"buildingId" binding:"required"`
-	Actions    []OptimizationAction    `json:"actions" binding:"required"`
+	ScenarioID   string               `json:"scenarioId" binding:"required"`
+	ScenarioType string               `json:"scenarioType,omitempty"`
+	ForecastID   string               `json:"forecastId,omitempty"`
+	BuildingID   string               `json:"buildingId" binding:"required"`
+	Actions      []OptimizationAction `json:"actions" binding:"required"`
+	// Policy sets the retries of failed actions and the failure threshold; actions are tried once
+	// and never aborted by default
+	Policy *ExecutionPolicy `json:"policy,omitempty"`
+	// Comfort enables the comfort guardrail for the scenario
+	Comfort *ComfortBand `json:"comfort,omitempty"`
+	// DryRun previews the scenario against the current device state without creating it or
+	// sending any command
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// DryRunOutcome is the predicted outcome of a scenario action in a dry run
+type DryRunOutcome string
+
+const (
+	// DryRunOutcomeSucceed means the command would be sent to the device
+	DryRunOutcomeSucceed DryRunOutcome = "SUCCEED"
+	// DryRunOutcomeSkip means the device is offline or excluded from the scenario's building
+	DryRunOutcomeSkip DryRunOutcome = "SKIP"
+	// DryRunOutcomeConflict means the device is under maintenance or controlled by another
+	// pending or running scenario
+	DryRunOutcomeConflict DryRunOutcome = "CONFLICT"
+	// DryRunOutcomeFail means the action cannot be carried out: the device is unknown or does
+	// not support the command
+	DryRunOutcomeFail DryRunOutcome = "FAIL"
+)
+
+// ScenarioDryRun is the preview of an optimization scenario. Nothing is persisted and no
+// command is sent.
+type ScenarioDryRun struct {
+	SourceScenarioID string         `json:"sourceScenarioId"`
+	BuildingID       string         `json:"buildingId"`
+	Actions          []DryRunAction `json:"actions"`
+	Summary          DryRunSummary  `json:"summary"`
+	GeneratedAt      time.Time      `json:"generatedAt"`
+}
+
+// DryRunAction is the predicted outcome of a single scenario action. Changes estimates how far
+// the action moves the device's reported metrics.
+type DryRunAction struct {
+	DeviceID     string                 `json:"deviceId"`
+	LocationID   string                 `json:"locationId,omitempty"`
+	Command      string                 `json:"command"`
+	Params       map[string]interface{} `json:"params,omitempty"`
+	Outcome      DryRunOutcome          `json:"outcome"`
+	Reason       string                 `json:"reason,omitempty"`
+	DeviceStatus string                 `json:"deviceStatus,omitempty"`
+	Conflicts    []string               `json:"conflictingScenarios,omitempty"`
+	Changes      []StateChange          `json:"changes,omitempty"`
+}
+
+// StateChange is the estimated change of a device metric. Current is nil when the device has
+// not reported the metric yet.
+type StateChange struct {
+	Metric       string   `json:"metric"`
+	Current      *float64 `json:"current,omitempty"`
+	Target       float64  `json:"target"`
+	Delta        *float64 `json:"delta,omitempty"`
+	DeltaPercent *float64 `json:"deltaPercent,omitempty"`
+}
+
+// DryRunSummary counts the dry run outcomes. PowerChangeKW is the estimated total change of
+// the devices' power draw, negative for a reduction.
+type DryRunSummary struct {
+	TotalActions  int     `json:"totalActions"`
+	WouldSucceed  int     `json:"wouldSucceed"`
+	Skipped       int     `json:"skipped"`
+	Conflicts     int     `json:"conflicts"`
+	Failed        int     `json:"failed"`
+	PowerChangeKW float64 `json:"powerChangeKw"`
 }
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProvisioningTokenStatus represents the status of a provisioning token
+type ProvisioningTokenStatus string
+
+const (
+	ProvisioningTokenActive  ProvisioningTokenStatus = "ACTIVE"
+	ProvisioningTokenClaimed ProvisioningTokenStatus = "CLAIMED"
+	ProvisioningTokenRevoked ProvisioningTokenStatus = "REVOKED"
+)
+
+// ProvisioningToken is a one-time claim token that lets a new device register itself in a
+// building. Only a hash of the token is stored; the token itself is shown once on creation.
+type ProvisioningToken struct {
+	ID              primitive.ObjectID      `bson:"_id,omitempty" json:"id"`
+	TokenHash       string                  `bson:"token_hash" json:"-"`
+	TokenPrefix     string                  `bson:"token_prefix" json:"tokenPrefix"`
+	BuildingID      string                  `bson:"building_id" json:"buildingId"`
+	DeviceType      string                  `bson:"device_type,omitempty" json:"deviceType,omitempty"`
+	Description     string                  `bson:"description,omitempty" json:"description,omitempty"`
+	Status          ProvisioningTokenStatus `bson:"status" json:"status"`
+	ExpiresAt       time.Time               `bson:"expires_at" json:"expiresAt"`
+	CreatedAt       time.Time               `bson:"created_at" json:"createdAt"`
+	CreatedBy       string                  `bson:"created_by" json:"createdBy"`
+	ClaimedAt       *time.Time              `bson:"claimed_at,omitempty" json:"claimedAt,omitempty"`
+	ClaimedDeviceID string                  `bson:"claimed_device_id,omitempty" json:"claimedDeviceId,omitempty"`
+	RevokedAt       *time.Time              `bson:"revoked_at,omitempty" json:"revokedAt,omitempty"`
+	RevokedBy       string                  `bson:"revoked_by,omitempty" json:"revokedBy,omitempty"`
+}
+
+// CreateProvisioningTokenRequest represents an admin request for a claim token.
+// DeviceType optionally restricts the token to one device type.
+type CreateProvisioningTokenRequest struct {
+	BuildingID     string `json:"buildingId" binding:"required"`
+	DeviceType     string `json:"deviceType"`
+	Description    string `json:"description"`
+	ExpiresInHours int    `json:"expiresInHours"`
+}
+
+// CreateProvisioningTokenResponse carries the claim token, which cannot be retrieved again
+type CreateProvisioningTokenResponse struct {
+	Token             string             `json:"token"`
+	ProvisioningToken *ProvisioningToken `json:"provisioningToken"`
+}
+
+// ClaimDeviceRequest is presented by a new device over HTTP or MQTT to register itself.
+// The building comes from the claim token.
+type ClaimDeviceRequest struct {
+	Token        string                 `json:"token" binding:"required"`
+	DeviceID     string                 `json:"deviceId" binding:"required"`
+	Type         string                 `json:"type" binding:"required"`
+	Model        string                 `json:"model"`
+	Name         string                 `json:"name"`
+	Location     DeviceLocation         `json:"location"`
+	Capabilities []string               `json:"capabilities"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ClaimDeviceResponse carries the registered device and its credentials. The device secret is
+// shown once; the device uses it to authenticate from then on.
+type ClaimDeviceResponse struct {
+	Device       *DeviceResponse   `json:"device"`
+	DeviceSecret string            `json:"deviceSecret"`
+	Topics       map[string]string `json:"topics"`
+}
+
+// ProvisioningClaimResult is published to a device on its provisioning result topic after an
+// MQTT claim
+type ProvisioningClaimResult struct {
+	Success bool                 `json:"success"`
+	Error   string               `json:"error,omitempty"`
+	Result  *ClaimDeviceResponse `json:"result,omitempty"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Search result types
+const (
+	SearchResultDevice = "DEVICE"
+)
+
+// SearchDevicesRequest represents query parameters for searching devices. Query is matched
+// against the name, ID, type, model, location and tags of a device; the filters must all match.
+type SearchDevicesRequest struct {
+	Query      string `form:"q"`
+	BuildingID string `form:"buildingId"`
+	Floor      string `form:"floor"`
+	Room       string `form:"room"`
+	Tags       string `form:"tags"`
+	Type       string `form:"type"`
+	Status     string `form:"status"`
+	Cursor     string `form:"cursor"`
+	Limit      int    `form:"limit"`
+}
+
+// SearchResult represents one search hit
+type SearchResult struct {
+	Type       string      `json:"type"`
+	ID         string      `json:"id"`
+	Title      string      `json:"title"`
+	BuildingID string      `json:"buildingId,omitempty"`
+	Score      float64     `json:"score,omitempty"`
+	Data       interface{} `json:"data"`
+}
+
+// SearchResponse represents a page of search results
+type SearchResponse struct {
+	Results    []*SearchResult `json:"results"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+// DeviceSearchHit is a device matched by a search with its text relevance
+type DeviceSearchHit struct {
+	Device `bson:",inline"`
+	Score  float64 `bson:"search_score"`
+}
+
+// SearchCursor identifies a position in search results ordered by relevance and ID (newest first)
+type SearchCursor struct {
+	Score float64
+	ID    primitive.ObjectID
+}
+
+// Encode serializes the cursor into an opaque URL-safe token
+func (c SearchCursor) Encode() string {
+	raw := strconv.FormatFloat(c.Score, 'g', -1, 64) + ":" + c.ID.Hex()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeSearchCursor parses a cursor token produced by SearchCursor.Encode
+func DecodeSearchCursor(token string) (*SearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid cursor")
+	}
+
+	score, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	id, err := primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &SearchCursor{Score: score, ID: id}, nil
+}
@@ -7,6 +7,7 @@ import (
 // DeviceState represents the current state of a device
 type DeviceState struct {
 	DeviceID   string                 `json:"deviceId"`
+	Type       string                 `json:"type"`
 	Status     string                 `json:"status"`
 	LastSeen   time.Time              `json:"lastSeen"`
 	Metrics    map[string]interface{} `json:"metrics"`
@@ -6,33 +6,55 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Telemetry represents a telemetry data point
+// Telemetry represents a telemetry data point.
+// Metrics holds calibrated values; RawMetrics keeps the original readings of calibrated metrics.
 type Telemetry struct {
-	ID        primitive.ObjectID          `bson:"_id,omitempty" json:"id"`
-	DeviceID  string                      `bson:"device_id" json:"deviceId"`
-	Timestamp time.Time                   `bson:"timestamp" json:"timestamp"`
-	Metrics   map[string]interface{}      `bson:"metrics" json:"metrics"`
-	Source    string                      `bson:"source" json:"source"` // "HTTP" or "MQTT"
-	CreatedAt time.Time                   `bson:"created_at" json:"createdAt"`
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	DeviceID   string                 `bson:"device_id" json:"deviceId"`
+	Timestamp  time.Time              `bson:"timestamp" json:"timestamp"`
+	Metrics    map[string]interface{} `bson:"metrics" json:"metrics"`
+	RawMetrics map[string]interface{} `bson:"raw_metrics,omitempty" json:"rawMetrics,omitempty"`
+	Source     string                 `bson:"source" json:"source"` // "HTTP", "MQTT" or "GATEWAY"
+	CreatedAt  time.Time              `bson:"created_at" json:"createdAt"`
 }
 
+// Energy flow metrics of sites with on-site generation and storage, in kWh per reading.
+// Directional metrics are never negative; bidirectional meters may instead report the signed
+// grid or battery metric, which is split into its directional pair on ingestion.
+const (
+	MetricGeneration       = "generation"       // Produced by on-site generation such as PV
+	MetricGridImport       = "gridImport"       // Drawn from the grid
+	MetricGridExport       = "gridExport"       // Fed back into the grid
+	MetricBatteryCharge    = "batteryCharge"    // Stored into batteries
+	MetricBatteryDischarge = "batteryDischarge" // Released from batteries
+	MetricStateOfCharge    = "stateOfCharge"    // Battery charge level, percent of capacity
+
+	MetricGridNet    = "gridNet"    // Signed grid flow: positive imports, negative exports
+	MetricBatteryNet = "batteryNet" // Signed battery flow: positive charges, negative discharges
+)
+
+// EnergyFlowMetrics lists the directional energy flow metrics
+var EnergyFlowMetrics = []string{MetricGeneration, MetricGridImport, MetricGridExport, MetricBatteryCharge, MetricBatteryDischarge}
+
 // TelemetryResponse represents telemetry data in API responses
 type TelemetryResponse struct {
-	ID        string                 `json:"id"`
-	DeviceID  string                 `json:"deviceId"`
-	Timestamp time.Time              `json:"timestamp"`
-	Metrics   map[string]interface{} `json:"metrics"`
-	Source    string                 `json:"source"`
+	ID         string                 `json:"id"`
+	DeviceID   string                 `json:"deviceId"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Metrics    map[string]interface{} `json:"metrics"`
+	RawMetrics map[string]interface{} `json:"rawMetrics,omitempty"`
+	Source     string                 `json:"source"`
 }
 
 // ToResponse converts a Telemetry to TelemetryResponse
 func (t *Telemetry) ToResponse() *TelemetryResponse {
 	return &TelemetryResponse{
-		ID:        t.ID.Hex(),
-		DeviceID:  t.DeviceID,
-		Timestamp: t.Timestamp,
-		Metrics:   t.Metrics,
-		Source:    t.Source,
+		ID:         t.ID.Hex(),
+		DeviceID:   t.DeviceID,
+		Timestamp:  t.Timestamp,
+		Metrics:    t.Metrics,
+		RawMetrics: t.RawMetrics,
+		Source:     t.Source,
 	}
 }
 
@@ -52,7 +74,151 @@ type BulkTelemetryIngestRequest struct {
 type TelemetryHistoryRequest struct {
 	DeviceID string    `form:"deviceId" binding:"required"`
 	From     time.Time `form:"from"`
-	To       time.Time `form:"to"`
+	To       time.Time `form:"to" binding:"omitempty,gtfield=From"`
 	Page     int       `form:"page"`
 	Limit    int       `form:"limit"`
 }
+
+// TelemetryAggregation represents an aggregation function applied to a metric
+type TelemetryAggregation string
+
+const (
+	TelemetryAggregationSum        TelemetryAggregation = "sum"
+	TelemetryAggregationAvg        TelemetryAggregation = "avg"
+	TelemetryAggregationMin        TelemetryAggregation = "min"
+	TelemetryAggregationMax        TelemetryAggregation = "max"
+	TelemetryAggregationCount      TelemetryAggregation = "count"
+	TelemetryAggregationPercentile TelemetryAggregation = "percentile"
+)
+
+// TelemetryGroupBy represents a dimension telemetry can be grouped by
+type TelemetryGroupBy string
+
+const (
+	TelemetryGroupByDevice   TelemetryGroupBy = "device"
+	TelemetryGroupByBuilding TelemetryGroupBy = "building"
+	TelemetryGroupByHour     TelemetryGroupBy = "hour"
+	TelemetryGroupByDay      TelemetryGroupBy = "day"
+	// Location levels group readings by the floor, zone or room their device is assigned to
+	TelemetryGroupByFloor TelemetryGroupBy = "floor"
+	TelemetryGroupByZone  TelemetryGroupBy = "zone"
+	TelemetryGroupByRoom  TelemetryGroupBy = "room"
+)
+
+// TelemetryQueryRequest represents query parameters for aggregated telemetry.
+// List parameters (metrics, groupBy, deviceId) are comma-separated. LocationID limits the query
+// to the devices assigned to a location of the hierarchy or to any location below it.
+type TelemetryQueryRequest struct {
+	Metrics     string    `form:"metrics" binding:"required"`
+	Aggregation string    `form:"agg"`
+	Percentile  float64   `form:"percentile"`
+	GroupBy     string    `form:"groupBy"`
+	DeviceIDs   string    `form:"deviceId"`
+	BuildingID  string    `form:"buildingId"`
+	LocationID  string    `form:"locationId"`
+	From        time.Time `form:"from"`
+	To          time.Time `form:"to" binding:"omitempty,gtfield=From"`
+}
+
+// TelemetryQuery is a validated aggregation query over telemetry
+type TelemetryQuery struct {
+	Metrics     []string
+	Aggregation TelemetryAggregation
+	Percentile  float64 // 0-100, only used by the percentile aggregation
+	GroupBy     []TelemetryGroupBy
+	DeviceIDs   []string
+	BuildingID  string
+	LocationID  string
+	From        time.Time
+	To          time.Time
+}
+
+// GroupsByLocation checks whether the query groups by a level of the location hierarchy
+func (q *TelemetryQuery) GroupsByLocation() bool {
+	return q.HasGroup(TelemetryGroupByFloor) || q.HasGroup(TelemetryGroupByZone) || q.HasGroup(TelemetryGroupByRoom)
+}
+
+// HasGroup checks whether the query groups by the given dimension
+func (q *TelemetryQuery) HasGroup(group TelemetryGroupBy) bool {
+	for _, g := range q.GroupBy {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// TelemetryBucket represents one aggregated group of telemetry.
+// Fields for dimensions that were not grouped by are omitted; readings of devices that are not
+// assigned to a location level grouped by have an empty ID for it.
+type TelemetryBucket struct {
+	DeviceID   string             `json:"deviceId,omitempty"`
+	BuildingID string             `json:"buildingId,omitempty"`
+	FloorID    string             `json:"floorId,omitempty"`
+	ZoneID     string             `json:"zoneId,omitempty"`
+	RoomID     string             `json:"roomId,omitempty"`
+	Period     *time.Time         `json:"period,omitempty"`
+	Values     map[string]float64 `json:"values"`
+	Samples    int64              `json:"samples"`
+}
+
+// TelemetryQueryResponse represents the result of an aggregated telemetry query
+type TelemetryQueryResponse struct {
+	Metrics     []string             `json:"metrics"`
+	Aggregation TelemetryAggregation `json:"aggregation"`
+	Percentile  float64              `json:"percentile,omitempty"`
+	GroupBy     []TelemetryGroupBy   `json:"groupBy"`
+	BuildingID  string               `json:"buildingId,omitempty"`
+	LocationID  string               `json:"locationId,omitempty"`
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Buckets     []*TelemetryBucket   `json:"buckets"`
+}
+
+// TelemetryExportResolution is the time resolution of a telemetry export
+type TelemetryExportResolution string
+
+const (
+	TelemetryResolutionAuto   TelemetryExportResolution = "auto"
+	TelemetryResolutionRaw    TelemetryExportResolution = "raw"
+	TelemetryResolutionMinute TelemetryExportResolution = "minute"
+	TelemetryResolutionHour   TelemetryExportResolution = "hour"
+	TelemetryResolutionDay    TelemetryExportResolution = "day"
+)
+
+// TelemetryExportFormatCSV is the only supported telemetry export format
+const TelemetryExportFormatCSV = "csv"
+
+// TelemetryExportRequest represents query parameters for a telemetry export.
+// Metrics is comma-separated; when empty, every metric the device reported in the range is exported.
+type TelemetryExportRequest struct {
+	DeviceID   string    `form:"deviceId" binding:"required"`
+	From       time.Time `form:"from"`
+	To         time.Time `form:"to" binding:"omitempty,gtfield=From"`
+	Format     string    `form:"format"`
+	Resolution string    `form:"resolution"`
+	Metrics    string    `form:"metrics"`
+	Gzip       bool      `form:"gzip"`
+}
+
+// TelemetryExport is a validated telemetry export with its resolution resolved
+type TelemetryExport struct {
+	DeviceID   string
+	From       time.Time
+	To         time.Time
+	Resolution TelemetryExportResolution
+	Metrics    []string
+}
+
+// Downsampled reports whether the export averages readings into time buckets
+func (e *TelemetryExport) Downsampled() bool {
+	return e.Resolution != TelemetryResolutionRaw
+}
+
+// TelemetryExportRow is one exported reading, or one time bucket of a downsampled export.
+// Samples is the number of readings averaged into a bucket and is zero for raw readings.
+type TelemetryExportRow struct {
+	Timestamp time.Time
+	Metrics   map[string]interface{}
+	Samples   int64
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WeatherMetric is a weather forecast value a rule condition compares
+type WeatherMetric string
+
+const (
+	WeatherMetricTemperature WeatherMetric = "temperature" // °C
+	WeatherMetricHumidity    WeatherMetric = "humidity"    // %
+	WeatherMetricCloudCover  WeatherMetric = "cloudCover"  // %
+	WeatherMetricWindSpeed   WeatherMetric = "windSpeed"   // m/s
+)
+
+// WeatherOperator compares a forecast value with a rule threshold
+type WeatherOperator string
+
+const (
+	WeatherOperatorGT  WeatherOperator = "GT"
+	WeatherOperatorGTE WeatherOperator = "GTE"
+	WeatherOperatorLT  WeatherOperator = "LT"
+	WeatherOperatorLTE WeatherOperator = "LTE"
+)
+
+// WeatherConflictPolicy decides what a rule does with a device that an optimization scenario is
+// currently controlling
+type WeatherConflictPolicy string
+
+const (
+	// WeatherConflictSkip leaves the device to the optimization scenario
+	WeatherConflictSkip WeatherConflictPolicy = "SKIP"
+	// WeatherConflictOverride sends the rule's command anyway
+	WeatherConflictOverride WeatherConflictPolicy = "OVERRIDE"
+)
+
+// Weather rule action outcomes
+const (
+	WeatherActionSent    = "SENT"
+	WeatherActionFailed  = "FAILED"
+	WeatherActionSkipped = "SKIPPED"
+)
+
+// Weather rule evaluation triggers
+const (
+	WeatherRuleTriggerSchedule = "SCHEDULE"
+	WeatherRuleTriggerManual   = "MANUAL"
+)
+
+// WeatherCondition is met when the forecast value of the metric, at any hour from the rule's
+// run time through LookaheadHours after it, compares true with the threshold. For example,
+// temperature GT 30 with a lookahead of 4 pre-cools at 13:00 when the afternoon will be hot.
+type WeatherCondition struct {
+	Metric         WeatherMetric   `bson:"metric" json:"metric" binding:"required"`
+	Operator       WeatherOperator `bson:"operator" json:"operator" binding:"required"`
+	Threshold      float64         `bson:"threshold" json:"threshold"`
+	LookaheadHours int             `bson:"lookahead_hours" json:"lookaheadHours"` // 0 checks only the run hour
+}
+
+// Window returns the hours of the forecast the condition looks at for a run: [from, to), from
+// the start of the run's hour through LookaheadHours after it
+func (c WeatherCondition) Window(runAt time.Time) (from, to time.Time) {
+	from = runAt.Truncate(time.Hour)
+	return from, from.Add(time.Duration(c.LookaheadHours+1) * time.Hour)
+}
+
+// Observe returns the forecast value of the condition's metric within the window of a run that
+// decides the condition: the highest for GT and GTE, the lowest for LT and LTE. ok is false when
+// the forecast has no point in the window.
+func (c WeatherCondition) Observe(points []WeatherPoint, runAt time.Time) (value float64, at time.Time, ok bool) {
+	from, to := c.Window(runAt)
+	higherWins := c.Operator == WeatherOperatorGT || c.Operator == WeatherOperatorGTE
+	for _, point := range points {
+		if point.Timestamp.Before(from) || !point.Timestamp.Before(to) {
+			continue
+		}
+		v := point.Value(c.Metric)
+		if !ok || (higherWins && v > value) || (!higherWins && v < value) {
+			value, at, ok = v, point.Timestamp, true
+		}
+	}
+	return value, at, ok
+}
+
+// Met compares an observed value with the condition's threshold
+func (c WeatherCondition) Met(value float64) bool {
+	switch c.Operator {
+	case WeatherOperatorGT:
+		return value > c.Threshold
+	case WeatherOperatorGTE:
+		return value >= c.Threshold
+	case WeatherOperatorLT:
+		return value < c.Threshold
+	case WeatherOperatorLTE:
+		return value <= c.Threshold
+	}
+	return false
+}
+
+// WeatherRuleAction is a command sent to a device when a rule's condition is met
+type WeatherRuleAction struct {
+	DeviceID   string                 `bson:"device_id" json:"deviceId" binding:"required"`
+	Command    string                 `bson:"command" json:"command" binding:"required"`
+	Params     map[string]interface{} `bson:"params,omitempty" json:"params,omitempty"`
+	TTLSeconds int                    `bson:"ttl_seconds,omitempty" json:"ttlSeconds,omitempty"`
+}
+
+// WeatherRule maps a weather condition to device commands, evaluated on a cron schedule in the
+// building's local time
+type WeatherRule struct {
+	ID              primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	RuleID          string                `bson:"rule_id" json:"ruleId"`
+	Name            string                `bson:"name" json:"name"`
+	Description     string                `bson:"description,omitempty" json:"description,omitempty"`
+	BuildingID      string                `bson:"building_id" json:"buildingId"`
+	Condition       WeatherCondition      `bson:"condition" json:"condition"`
+	Cron            string                `bson:"cron" json:"cron"`
+	Actions         []WeatherRuleAction   `bson:"actions" json:"actions"`
+	ConflictPolicy  WeatherConflictPolicy `bson:"conflict_policy" json:"conflictPolicy"`
+	Enabled         bool                  `bson:"enabled" json:"enabled"`
+	NextRunAt       *time.Time            `bson:"next_run_at,omitempty" json:"nextRunAt,omitempty"`
+	LastEvaluatedAt *time.Time            `bson:"last_evaluated_at,omitempty" json:"lastEvaluatedAt,omitempty"`
+	LastTriggeredAt *time.Time            `bson:"last_triggered_at,omitempty" json:"lastTriggeredAt,omitempty"`
+	CreatedBy       string                `bson:"created_by" json:"createdBy"`
+	CreatedAt       time.Time             `bson:"created_at" json:"createdAt"`
+	UpdatedAt       time.Time             `bson:"updated_at" json:"updatedAt"`
+}
+
+// WeatherRuleRequest represents a request to create or replace a weather rule.
+// ConflictPolicy defaults to SKIP and Enabled to true.
+type WeatherRuleRequest struct {
+	Name           string                `json:"name" binding:"required"`
+	Description    string                `json:"description"`
+	BuildingID     string                `json:"buildingId" binding:"required"`
+	Condition      WeatherCondition      `json:"condition"`
+	Cron           string                `json:"cron" binding:"required"`
+	Actions        []WeatherRuleAction   `json:"actions" binding:"required,min=1,dive"`
+	ConflictPolicy WeatherConflictPolicy `json:"conflictPolicy"`
+	Enabled        *bool                 `json:"enabled"`
+}
+
+// ListWeatherRulesRequest represents query parameters for listing weather rules
+type ListWeatherRulesRequest struct {
+	BuildingID string `form:"buildingId"`
+	Page       int    `form:"page"`
+	Limit      int    `form:"limit"`
+}
+
+// WeatherPoint is one hour of a building location's weather forecast
+type WeatherPoint struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Temperature float64   `json:"temperature"`
+	Humidity    float64   `json:"humidity"`
+	CloudCover  float64   `json:"cloudCover"`
+	WindSpeed   float64   `json:"windSpeed"`
+	Condition   string    `json:"condition"`
+}
+
+// Value returns the forecast value of a metric
+func (p WeatherPoint) Value(metric WeatherMetric) float64 {
+	switch metric {
+	case WeatherMetricHumidity:
+		return p.Humidity
+	case WeatherMetricCloudCover:
+		return p.CloudCover
+	case WeatherMetricWindSpeed:
+		return p.WindSpeed
+	}
+	return p.Temperature
+}
+
+// WeatherRuleActionResult records what happened to one action of a triggered rule
+type WeatherRuleActionResult struct {
+	DeviceID   string `bson:"device_id" json:"deviceId"`
+	Command    string `bson:"command" json:"command"`
+	Status     string `bson:"status" json:"status"` // SENT, FAILED or SKIPPED
+	CommandID  string `bson:"command_id,omitempty" json:"commandId,omitempty"`
+	ScenarioID string `bson:"scenario_id,omitempty" json:"scenarioId,omitempty"` // Conflicting optimization scenario
+	Reason     string `bson:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// WeatherRuleExecution is the log entry of one evaluation of a weather rule
+type WeatherRuleExecution struct {
+	ID            primitive.ObjectID        `bson:"_id,omitempty" json:"id"`
+	RuleID        string                    `bson:"rule_id" json:"ruleId"`
+	BuildingID    string                    `bson:"building_id" json:"buildingId"`
+	Trigger       string                    `bson:"trigger" json:"trigger"` // SCHEDULE or MANUAL
+	DryRun        bool                      `bson:"dry_run,omitempty" json:"dryRun,omitempty"`
+	RunAt         time.Time                 `bson:"run_at" json:"runAt"`
+	Condition     WeatherCondition          `bson:"condition" json:"condition"`
+	ObservedValue *float64                  `bson:"observed_value,omitempty" json:"observedValue,omitempty"`
+	ObservedAt    *time.Time                `bson:"observed_at,omitempty" json:"observedAt,omitempty"`
+	Triggered     bool                      `bson:"triggered" json:"triggered"`
+	Actions       []WeatherRuleActionResult `bson:"actions,omitempty" json:"actions,omitempty"`
+	Error         string                    `bson:"error,omitempty" json:"error,omitempty"`
+	EvaluatedAt   time.Time                 `bson:"evaluated_at" json:"evaluatedAt"`
+}
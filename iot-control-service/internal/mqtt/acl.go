@@ -0,0 +1,111 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"iot-control-service/internal/models"
+)
+
+// deviceCacheTTL bounds how long a device's building assignment is trusted without a lookup.
+// Device changes made through this instance evict the entry right away; the TTL bounds how long
+// another instance may use the old building.
+const deviceCacheTTL = time.Minute
+
+// DeviceLookup resolves registered devices
+type DeviceLookup interface {
+	FindByDeviceID(ctx context.Context, deviceID string) (*models.Device, error)
+}
+
+// TopicAuthorizer checks that inbound messages are published on the topic of a registered
+// device in the device's own building, so a device credential cannot publish as a device
+// of another building.
+type TopicAuthorizer struct {
+	devices     DeviceLookup
+	allowLegacy bool
+
+	mu    sync.Mutex
+	cache map[string]cachedBuilding
+}
+
+type cachedBuilding struct {
+	buildingID string
+	expiresAt  time.Time
+}
+
+// NewTopicAuthorizer creates a topic authorizer.
+// When allowLegacy is false, legacy flat topics are only accepted for devices without a building.
+func NewTopicAuthorizer(devices DeviceLookup, allowLegacy bool) *TopicAuthorizer {
+	return &TopicAuthorizer{
+		devices:     devices,
+		allowLegacy: allowLegacy,
+		cache:       make(map[string]cachedBuilding),
+	}
+}
+
+// Authorize verifies a parsed inbound topic against the device registry
+func (a *TopicAuthorizer) Authorize(ctx context.Context, topic Topic) error {
+	buildingID, err := a.deviceBuilding(ctx, topic.DeviceID, false)
+	if err != nil {
+		return err
+	}
+	// A device moved since its building was cached is checked against the registry
+	if !topic.IsLegacy() && topic.BuildingID != buildingID {
+		if buildingID, err = a.deviceBuilding(ctx, topic.DeviceID, true); err != nil {
+			return err
+		}
+	}
+
+	if topic.IsLegacy() {
+		if buildingID != "" && !a.allowLegacy {
+			return fmt.Errorf("device %s belongs to building %s and must publish on building-scoped topics", topic.DeviceID, buildingID)
+		}
+		return nil
+	}
+
+	if topic.BuildingID != buildingID {
+		return fmt.Errorf("device %s is not registered in building %s", topic.DeviceID, topic.BuildingID)
+	}
+	return nil
+}
+
+// Forget drops the cached building of a device, so its next message is checked against the
+// registry. It is called when a device is updated, deleted or restored.
+func (a *TopicAuthorizer) Forget(deviceID string) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	delete(a.cache, deviceID)
+	a.mu.Unlock()
+}
+
+// deviceBuilding returns the building a device is registered in, using a short-lived cache
+// unless refresh is set
+func (a *TopicAuthorizer) deviceBuilding(ctx context.Context, deviceID string, refresh bool) (string, error) {
+	now := time.Now()
+
+	if !refresh {
+		a.mu.Lock()
+		cached, ok := a.cache[deviceID]
+		a.mu.Unlock()
+		if ok && now.Before(cached.expiresAt) {
+			return cached.buildingID, nil
+		}
+	}
+
+	device, err := a.devices.FindByDeviceID(ctx, deviceID)
+	if err != nil {
+		a.Forget(deviceID)
+		return "", fmt.Errorf("unknown device %s: %w", deviceID, err)
+	}
+
+	a.mu.Lock()
+	a.cache[deviceID] = cachedBuilding{buildingID: device.Location.BuildingID, expiresAt: now.Add(deviceCacheTTL)}
+	a.mu.Unlock()
+
+	return device.Location.BuildingID, nil
+}
@@ -1,20 +1,34 @@
 package mqtt
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
+	"sync"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"iot-control-service/internal/config"
 	"iot-control-service/internal/models"
+	"iot-control-service/internal/tracing"
 )
 
-// Client wraps the MQTT client
+// Client wraps the MQTT client.
+// Every subscription is kept in a registry and restored whenever the connection is
+// re-established, since a clean session loses them when the broker restarts.
 type Client struct {
-	client mqtt.Client
-	config *config.Config
+	client     mqtt.Client
+	config     *config.Config
+	authorizer *TopicAuthorizer
+
+	mu            sync.Mutex
+	subscriptions map[string]mqtt.MessageHandler
+	stats         models.MQTTStats
 }
 
 // NewClient creates a new MQTT client
@@ -36,102 +50,214 @@ func NewClient(cfg *config.Config) (*Client, error) {
 		opts.SetPassword(cfg.MQTT.Password)
 	}
 
+	c := &Client{
+		config:        cfg,
+		subscriptions: make(map[string]mqtt.MessageHandler),
+	}
+
 	opts.SetOnConnectHandler(func(client mqtt.Client) {
 		log.Println("MQTT client connected")
+		c.onConnect()
 	})
 
 	opts.SetConnectionLostHandler(func(client mqtt.Client, err error) {
 		log.Printf("MQTT connection lost: %v", err)
+		c.mu.Lock()
+		c.stats.ConnectionLosses++
+		now := time.Now()
+		c.stats.LastDisconnectedAt = &now
+		c.mu.Unlock()
 	})
 
-	client := mqtt.NewClient(opts)
-	token := client.Connect()
+	c.client = mqtt.NewClient(opts)
+	token := c.client.Connect()
 	if token.Wait() && token.Error() != nil {
 		return nil, fmt.Errorf("failed to connect to MQTT broker: %w", token.Error())
 	}
 
-	return &Client{
-		client: client,
-		config: cfg,
-	}, nil
+	return c, nil
+}
+
+// SetAuthorizer enables topic authorization for inbound device messages
+func (c *Client) SetAuthorizer(authorizer *TopicAuthorizer) {
+	c.authorizer = authorizer
 }
 
 // PublishTelemetry publishes telemetry data to MQTT
-func (c *Client) PublishTelemetry(deviceID string, telemetry *models.Telemetry) error {
-	topic := fmt.Sprintf("mqtt/iot/%s/telemetry", deviceID)
-	return c.publish(topic, telemetry)
+func (c *Client) PublishTelemetry(buildingID, deviceID string, telemetry *models.Telemetry) error {
+	return c.publish(context.Background(), DeviceTopic(buildingID, deviceID, TopicKindTelemetry), telemetry)
+}
+
+// PublishCommand publishes a command to a device on its building-scoped topic.
+// Expired commands are never published; the payload carries expiresAt so devices
+// can also discard commands that reach them late. Publishing is traced as part of ctx's trace.
+func (c *Client) PublishCommand(ctx context.Context, buildingID, deviceID string, command *models.DeviceCommand) error {
+	if command.IsExpired(time.Now()) {
+		return fmt.Errorf("command %s has expired", command.CommandID)
+	}
+	return c.publish(ctx, DeviceTopic(buildingID, deviceID, TopicKindCommand), command)
 }
 
-// PublishCommand publishes a command to a device
-func (c *Client) PublishCommand(deviceID string, command *models.DeviceCommand) error {
-	topic := fmt.Sprintf("mqtt/iot/%s/command", deviceID)
-	return c.publish(topic, command)
+// PublishAck publishes a command acknowledgment on a device's building-scoped topic, as a device would
+func (c *Client) PublishAck(buildingID, deviceID string, ack *models.CommandAck) error {
+	return c.publish(context.Background(), DeviceTopic(buildingID, deviceID, TopicKindAck), ack)
 }
 
 // PublishBroadcast publishes a broadcast message to all devices
 func (c *Client) PublishBroadcast(message map[string]interface{}) error {
-	topic := "mqtt/iot/broadcast/announcement"
-	return c.publish(topic, message)
+	topic := TopicPrefix + "/broadcast/announcement"
+	return c.publish(context.Background(), topic, message)
 }
 
 // SubscribeToTelemetry subscribes to telemetry from a device
-func (c *Client) SubscribeToTelemetry(deviceID string, handler func(*models.Telemetry)) error {
-	topic := fmt.Sprintf("mqtt/iot/%s/telemetry", deviceID)
-	return c.subscribe(topic, func(topic string, payload []byte) {
-		var telemetry models.Telemetry
-		if err := json.Unmarshal(payload, &telemetry); err != nil {
-			log.Printf("Failed to unmarshal telemetry: %v", err)
+func (c *Client) SubscribeToTelemetry(buildingID, deviceID string, handler func(*models.Telemetry)) error {
+	return c.subscribeDevice(DeviceTopic(buildingID, deviceID, TopicKindTelemetry), func(ctx context.Context, topic Topic, payload []byte) {
+		if telemetry, ok := decodeTelemetry(topic, payload); ok {
+			handler(telemetry)
+		}
+	})
+}
+
+// SubscribeToCommands subscribes to commands sent to a device, as the device would
+func (c *Client) SubscribeToCommands(buildingID, deviceID string, handler func(*models.DeviceCommand)) error {
+	return c.subscribe(DeviceTopic(buildingID, deviceID, TopicKindCommand), func(topicName string, payload []byte) {
+		var command models.DeviceCommand
+		if err := json.Unmarshal(payload, &command); err != nil {
+			log.Printf("Failed to unmarshal command: %v", err)
 			return
 		}
-		handler(&telemetry)
+		handler(&command)
 	})
 }
 
 // SubscribeToAck subscribes to command acknowledgments from a device
-func (c *Client) SubscribeToAck(deviceID string, handler func(*models.CommandAck)) error {
-	topic := fmt.Sprintf("mqtt/iot/%s/ack", deviceID)
-	return c.subscribe(topic, func(topic string, payload []byte) {
-		var ack models.CommandAck
-		if err := json.Unmarshal(payload, &ack); err != nil {
-			log.Printf("Failed to unmarshal ack: %v", err)
-			return
+func (c *Client) SubscribeToAck(buildingID, deviceID string, handler func(*models.CommandAck)) error {
+	return c.subscribeDevice(DeviceTopic(buildingID, deviceID, TopicKindAck), func(ctx context.Context, topic Topic, payload []byte) {
+		if ack, ok := decodeAck(topic, payload); ok {
+			handler(ack)
 		}
-		handler(&ack)
 	})
 }
 
-// SubscribeToAllTelemetry subscribes to telemetry from all devices
-func (c *Client) SubscribeToAllTelemetry(handler func(string, *models.Telemetry)) error {
-	topic := "mqtt/iot/+/telemetry"
-	return c.subscribe(topic, func(topic string, payload []byte) {
-		var telemetry models.Telemetry
-		if err := json.Unmarshal(payload, &telemetry); err != nil {
-			log.Printf("Failed to unmarshal telemetry: %v", err)
+// SubscribeToAllTelemetry subscribes to telemetry from all devices in all buildings,
+// including devices still publishing on legacy flat topics. The handler runs in the message's span.
+func (c *Client) SubscribeToAllTelemetry(handler func(context.Context, string, *models.Telemetry)) error {
+	onMessage := func(ctx context.Context, topic Topic, payload []byte) {
+		if telemetry, ok := decodeTelemetry(topic, payload); ok {
+			handler(ctx, topic.DeviceID, telemetry)
+		}
+	}
+	if err := c.subscribeDevice(BuildingWildcard(TopicKindTelemetry), onMessage); err != nil {
+		return err
+	}
+	return c.subscribeDevice(LegacyWildcard(TopicKindTelemetry), onMessage)
+}
+
+// SubscribeToAllAcks subscribes to acknowledgments from all devices in all buildings,
+// including devices still publishing on legacy flat topics. The handler runs in the message's span.
+func (c *Client) SubscribeToAllAcks(handler func(context.Context, string, *models.CommandAck)) error {
+	onMessage := func(ctx context.Context, topic Topic, payload []byte) {
+		if ack, ok := decodeAck(topic, payload); ok {
+			handler(ctx, topic.DeviceID, ack)
+		}
+	}
+	if err := c.subscribeDevice(BuildingWildcard(TopicKindAck), onMessage); err != nil {
+		return err
+	}
+	return c.subscribeDevice(LegacyWildcard(TopicKindAck), onMessage)
+}
+
+// SubscribeToProvisioningClaims subscribes to claim requests of devices that are not registered
+// yet. Claims are not authorized against the device ACL; the provisioning token is the credential.
+func (c *Client) SubscribeToProvisioningClaims(handler func(*models.ClaimDeviceRequest)) error {
+	return c.subscribe(ProvisioningClaimTopic, func(topic string, payload []byte) {
+		var req models.ClaimDeviceRequest
+		if err := json.Unmarshal(payload, &req); err != nil || req.DeviceID == "" {
+			log.Printf("Ignoring malformed provisioning claim on %s", topic)
 			return
 		}
-		// Extract device ID from topic: mqtt/iot/{deviceId}/telemetry
-		deviceID := extractDeviceIDFromTopic(topic)
-		handler(deviceID, &telemetry)
+		handler(&req)
 	})
 }
 
-// SubscribeToAllAcks subscribes to acknowledgments from all devices
-func (c *Client) SubscribeToAllAcks(handler func(string, *models.CommandAck)) error {
-	topic := "mqtt/iot/+/ack"
-	return c.subscribe(topic, func(topic string, payload []byte) {
-		var ack models.CommandAck
-		if err := json.Unmarshal(payload, &ack); err != nil {
-			log.Printf("Failed to unmarshal ack: %v", err)
+// PublishProvisioningResult publishes the outcome of a claim to the claiming device
+func (c *Client) PublishProvisioningResult(deviceID string, result *models.ProvisioningClaimResult) error {
+	return c.publish(context.Background(), ProvisioningResultTopic(deviceID), result)
+}
+
+// decodeTelemetry decodes a telemetry payload, binding it to the device named in the topic
+func decodeTelemetry(topic Topic, payload []byte) (*models.Telemetry, bool) {
+	var telemetry models.Telemetry
+	if err := json.Unmarshal(payload, &telemetry); err != nil {
+		log.Printf("Failed to unmarshal telemetry: %v", err)
+		return nil, false
+	}
+	if telemetry.DeviceID != "" && telemetry.DeviceID != topic.DeviceID {
+		log.Printf("Rejected telemetry for device %s published on topic of device %s", telemetry.DeviceID, topic.DeviceID)
+		return nil, false
+	}
+	telemetry.DeviceID = topic.DeviceID
+	return &telemetry, true
+}
+
+// decodeAck decodes a command ack payload, binding it to the device named in the topic
+func decodeAck(topic Topic, payload []byte) (*models.CommandAck, bool) {
+	var ack models.CommandAck
+	if err := json.Unmarshal(payload, &ack); err != nil {
+		log.Printf("Failed to unmarshal ack: %v", err)
+		return nil, false
+	}
+	if ack.DeviceID != "" && ack.DeviceID != topic.DeviceID {
+		log.Printf("Rejected ack for device %s published on topic of device %s", ack.DeviceID, topic.DeviceID)
+		return nil, false
+	}
+	ack.DeviceID = topic.DeviceID
+	return &ack, true
+}
+
+// subscribeDevice subscribes to device topics, parsing and authorizing each message before handling
+// it. Devices cannot carry trace context, so each message starts a trace of its own.
+func (c *Client) subscribeDevice(filter string, handler func(context.Context, Topic, []byte)) error {
+	return c.subscribe(filter, func(topicName string, payload []byte) {
+		topic, err := ParseTopic(topicName)
+		if err != nil {
+			log.Printf("Ignoring message on unexpected topic: %v", err)
 			return
 		}
-		// Extract device ID from topic: mqtt/iot/{deviceId}/ack
-		deviceID := extractDeviceIDFromTopic(topic)
-		handler(deviceID, &ack)
+
+		ctx, span := tracing.Start(context.Background(), "receive "+topic.Kind,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(messagingAttributes(topicName, topic.BuildingID, topic.DeviceID)...),
+		)
+		defer span.End()
+
+		if c.authorizer != nil {
+			authCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			err := c.authorizer.Authorize(authCtx, topic)
+			cancel()
+			if err != nil {
+				log.Printf("Rejected MQTT message on %s: %v", topicName, err)
+				tracing.RecordError(span, err)
+				return
+			}
+		}
+
+		handler(ctx, topic, payload)
 	})
 }
 
-// publish publishes a message to a topic
-func (c *Client) publish(topic string, payload interface{}) error {
+// publish publishes a message to a topic, in a producer span when ctx is traced
+func (c *Client) publish(ctx context.Context, topic string, payload interface{}) error {
+	name, buildingID, deviceID := "publish "+topic, "", ""
+	if parsed, err := ParseTopic(topic); err == nil {
+		name, buildingID, deviceID = "publish "+parsed.Kind, parsed.BuildingID, parsed.DeviceID
+	}
+	_, span := tracing.StartChild(ctx, name,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messagingAttributes(topic, buildingID, deviceID)...),
+	)
+	defer span.End()
+
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
@@ -139,18 +265,34 @@ func (c *Client) publish(topic string, payload interface{}) error {
 
 	token := c.client.Publish(topic, c.config.MQTT.QoS, false, data)
 	if token.Wait() && token.Error() != nil {
+		tracing.RecordError(span, token.Error())
 		return fmt.Errorf("failed to publish to %s: %w", topic, token.Error())
 	}
 
 	return nil
 }
 
-// subscribe subscribes to a topic with a handler
+// messagingAttributes returns the span attributes of a message on a device topic
+func messagingAttributes(topic, buildingID, deviceID string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "mqtt"),
+		attribute.String("messaging.destination.name", topic),
+	}
+	return append(attrs, tracing.EntityAttributes(buildingID, deviceID)...)
+}
+
+// subscribe subscribes to a topic with a handler and registers it for resubscription after reconnects.
+// The subscription is registered even when subscribing fails, so it is retried on the next connect.
 func (c *Client) subscribe(topic string, handler func(string, []byte)) error {
-	token := c.client.Subscribe(topic, c.config.MQTT.QoS, func(client mqtt.Client, msg mqtt.Message) {
+	callback := func(client mqtt.Client, msg mqtt.Message) {
 		handler(msg.Topic(), msg.Payload())
-	})
+	}
+
+	c.mu.Lock()
+	c.subscriptions[topic] = callback
+	c.mu.Unlock()
 
+	token := c.client.Subscribe(topic, c.config.MQTT.QoS, callback)
 	if token.Wait() && token.Error() != nil {
 		return fmt.Errorf("failed to subscribe to %s: %w", topic, token.Error())
 	}
@@ -159,34 +301,62 @@ func (c *Client) subscribe(topic string, handler func(string, []byte)) error {
 	return nil
 }
 
-// extractDeviceIDFromTopic extracts device ID from MQTT topic
-func extractDeviceIDFromTopic(topic string) string {
-	// Topic format: mqtt/iot/{deviceId}/telemetry or mqtt/iot/{deviceId}/ack
-	parts := splitTopic(topic)
-	if len(parts) >= 3 {
-		return parts[2]
+// onConnect counts the connection and restores all registered subscriptions.
+// Paho runs the handler in its own goroutine, so waiting on the subscribe token is safe here.
+func (c *Client) onConnect() {
+	c.mu.Lock()
+	c.stats.Connects++
+	if c.stats.Connects > 1 {
+		c.stats.Reconnects++
 	}
-	return ""
-}
+	now := time.Now()
+	c.stats.LastConnectedAt = &now
+	handlers := make(map[string]mqtt.MessageHandler, len(c.subscriptions))
+	for topic, handler := range c.subscriptions {
+		handlers[topic] = handler
+	}
+	c.mu.Unlock()
 
-// splitTopic splits a topic string by '/'
-func splitTopic(topic string) []string {
-	var parts []string
-	var current string
-	for _, char := range topic {
-		if char == '/' {
-			if current != "" {
-				parts = append(parts, current)
-				current = ""
-			}
-		} else {
-			current += string(char)
+	restored := 0
+	for topic, handler := range handlers {
+		token := c.client.Subscribe(topic, c.config.MQTT.QoS, handler)
+		if token.Wait() && token.Error() != nil {
+			log.Printf("Failed to restore subscription to %s: %v", topic, token.Error())
+			c.mu.Lock()
+			c.stats.ResubscribeFailures++
+			c.stats.LastError = token.Error().Error()
+			c.mu.Unlock()
+			continue
 		}
+		restored++
 	}
-	if current != "" {
-		parts = append(parts, current)
+
+	if restored > 0 {
+		c.mu.Lock()
+		c.stats.Resubscriptions += restored
+		c.mu.Unlock()
+		log.Printf("Restored %d of %d MQTT subscriptions", restored, len(handlers))
 	}
-	return parts
+}
+
+// Stats reports connection and resubscription counters.
+// It is safe to call on a nil client, which means the initial connection failed.
+func (c *Client) Stats() models.MQTTStats {
+	if c == nil {
+		return models.MQTTStats{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Connected = c.client.IsConnectionOpen()
+	stats.Subscriptions = make([]string, 0, len(c.subscriptions))
+	for topic := range c.subscriptions {
+		stats.Subscriptions = append(stats.Subscriptions, topic)
+	}
+	sort.Strings(stats.Subscriptions)
+	return stats
 }
 
 // Disconnect disconnects from the MQTT broker
@@ -199,3 +369,15 @@ func (c *Client) Disconnect() {
 func (c *Client) IsConnected() bool {
 	return c.client.IsConnected()
 }
+
+// HealthCheck reports an error when the broker connection is not open.
+// It is safe to call on a nil client, which means the initial connection failed.
+func (c *Client) HealthCheck(ctx context.Context) error {
+	if c == nil {
+		return fmt.Errorf("MQTT client is not initialized")
+	}
+	if !c.client.IsConnectionOpen() {
+		return fmt.Errorf("not connected to MQTT broker %s:%d", c.config.MQTT.Broker, c.config.MQTT.Port)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package mqtt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TopicPrefix is the root of all IoT topics
+const TopicPrefix = "mqtt/iot"
+
+// Message kinds carried on device topics
+const (
+	TopicKindTelemetry = "telemetry"
+	TopicKindCommand   = "command"
+	TopicKindAck       = "ack"
+)
+
+// ProvisioningClaimTopic is where new devices publish claim requests with a provisioning token
+const ProvisioningClaimTopic = TopicPrefix + "/provisioning/claim"
+
+// ProvisioningResultTopic returns the topic a claiming device subscribes to for the outcome of
+// its claim: mqtt/iot/provisioning/{deviceId}/result
+func ProvisioningResultTopic(deviceID string) string {
+	return fmt.Sprintf("%s/provisioning/%s/result", TopicPrefix, deviceID)
+}
+
+// Topic identifies the building, device and message kind of a device topic.
+// Legacy topics (mqtt/iot/{deviceId}/{kind}) have no building.
+type Topic struct {
+	BuildingID string
+	DeviceID   string
+	Kind       string
+}
+
+// IsLegacy reports whether the topic uses the flat pre-building namespace
+func (t Topic) IsLegacy() bool {
+	return t.BuildingID == ""
+}
+
+// DeviceTopic builds the topic for a device: mqtt/iot/{buildingId}/{deviceId}/{kind}.
+// Devices without a building fall back to the legacy flat namespace.
+func DeviceTopic(buildingID, deviceID, kind string) string {
+	if buildingID == "" {
+		return fmt.Sprintf("%s/%s/%s", TopicPrefix, deviceID, kind)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", TopicPrefix, buildingID, deviceID, kind)
+}
+
+// BuildingWildcard returns the subscription filter for a message kind across all buildings
+func BuildingWildcard(kind string) string {
+	return fmt.Sprintf("%s/+/+/%s", TopicPrefix, kind)
+}
+
+// LegacyWildcard returns the subscription filter for a message kind in the flat namespace
+func LegacyWildcard(kind string) string {
+	return fmt.Sprintf("%s/+/%s", TopicPrefix, kind)
+}
+
+// ParseTopic parses a building-scoped or legacy device topic
+func ParseTopic(topic string) (Topic, error) {
+	rest := strings.TrimPrefix(topic, TopicPrefix+"/")
+	if rest == topic {
+		return Topic{}, fmt.Errorf("topic %q is outside %s", topic, TopicPrefix)
+	}
+
+	parts := strings.Split(rest, "/")
+	for _, part := range parts {
+		if part == "" || part == "+" || part == "#" {
+			return Topic{}, fmt.Errorf("topic %q has an empty or wildcard level", topic)
+		}
+	}
+
+	switch len(parts) {
+	case 2:
+		return Topic{DeviceID: parts[0], Kind: parts[1]}, nil
+	case 3:
+		return Topic{BuildingID: parts[0], DeviceID: parts[1], Kind: parts[2]}, nil
+	}
+	return Topic{}, fmt.Errorf("topic %q does not match %s/{buildingId}/{deviceId}/{kind}", topic, TopicPrefix)
+}
@@ -72,6 +72,19 @@ func (r *CommandRepository) FindByCommandID(ctx context.Context, commandID strin
 	return &command, nil
 }
 
+// FindByIdempotencyKey retrieves a command previously submitted for a device with the given idempotency key
+func (r *CommandRepository) FindByIdempotencyKey(ctx context.Context, deviceID, idempotencyKey string) (*models.DeviceCommand, error) {
+	var command models.DeviceCommand
+	err := r.collection.FindOne(ctx, bson.M{"device_id": deviceID, "idempotency_key": idempotencyKey}).Decode(&command)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("command not found")
+		}
+		return nil, err
+	}
+	return &command, nil
+}
+
 // FindByDeviceID retrieves commands for a device
 func (r *CommandRepository) FindByDeviceID(ctx context.Context, deviceID string, status string, page, limit int) ([]*models.DeviceCommand, int64, error) {
 	if page < 1 {
@@ -114,6 +127,40 @@ func (r *CommandRepository) FindByDeviceID(ctx context.Context, deviceID string,
 	return commands, total, nil
 }
 
+// CountSince counts the commands issued to a device since a time
+func (r *CommandRepository) CountSince(ctx context.Context, deviceID string, since time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"device_id":  deviceID,
+		"created_at": bson.M{"$gte": since},
+	})
+}
+
+// FindLatestSince retrieves the most recent of the given commands issued to a device since a time,
+// ignoring commands that failed, expired or were cancelled and so never took effect
+func (r *CommandRepository) FindLatestSince(ctx context.Context, deviceID string, commands []string, since time.Time) (*models.DeviceCommand, error) {
+	filter := bson.M{
+		"device_id":  deviceID,
+		"command":    bson.M{"$in": commands},
+		"created_at": bson.M{"$gte": since},
+		"status": bson.M{"$nin": []models.CommandStatus{
+			models.CommandStatusFailed,
+			models.CommandStatusExpired,
+			models.CommandStatusCancelled,
+			models.CommandStatusRejected,
+		}},
+	}
+
+	var command models.DeviceCommand
+	err := r.collection.FindOne(ctx, filter, options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})).Decode(&command)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("command not found")
+		}
+		return nil, err
+	}
+	return &command, nil
+}
+
 // Update updates a command
 func (r *CommandRepository) Update(ctx context.Context, id string, updates bson.M) (*models.DeviceCommand, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -169,3 +216,229 @@ func (r *CommandRepository) UpdateStatus(ctx context.Context, commandID string,
 	)
 	return err
 }
+
+// Acknowledge records a device's acknowledgment of a command as APPLIED or FAILED. It reports
+// false, changing nothing, when the command no longer awaits an acknowledgment, e.g. because it
+// expired or was already acknowledged.
+func (r *CommandRepository) Acknowledge(ctx context.Context, commandID string, status models.CommandStatus, errorMsg string) (bool, error) {
+	now := time.Now()
+	updates := bson.M{
+		"status":     status,
+		"updated_at": now,
+	}
+	if status == models.CommandStatusApplied {
+		updates["applied_at"] = now
+	}
+	if errorMsg != "" {
+		updates["error_msg"] = errorMsg
+	}
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"command_id": commandID,
+			"status":     bson.M{"$in": models.AckableCommandStatuses},
+		},
+		bson.M{"$set": updates},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// ExpireStale marks un-acknowledged commands, and commands still awaiting approval, whose TTL
+// has passed as expired
+func (r *CommandRepository) ExpireStale(ctx context.Context, now time.Time) (int64, error) {
+	awaiting, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{
+			"status":     models.CommandStatusPendingApproval,
+			"expires_at": bson.M{"$lte": now},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":     models.CommandStatusExpired,
+				"error_msg":  "command expired awaiting approval",
+				"updated_at": now,
+			},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{
+			"status":     bson.M{"$in": []models.CommandStatus{models.CommandStatusPending, models.CommandStatusSent}},
+			"expires_at": bson.M{"$lte": now},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":     models.CommandStatusExpired,
+				"error_msg":  "command expired before acknowledgment",
+				"updated_at": now,
+			},
+		},
+	)
+	if err != nil {
+		return awaiting.ModifiedCount, err
+	}
+	return awaiting.ModifiedCount + result.ModifiedCount, nil
+}
+
+// FindPendingApproval retrieves commands awaiting approval that have not expired, oldest first.
+// Non-nil device IDs limit the commands to those devices.
+func (r *CommandRepository) FindPendingApproval(ctx context.Context, now time.Time, deviceIDs []string, page, limit int) ([]*models.DeviceCommand, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{
+		"status":     models.CommandStatusPendingApproval,
+		"expires_at": bson.M{"$gt": now},
+	}
+	if deviceIDs != nil {
+		filter["device_id"] = bson.M{"$in": deviceIDs}
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var commands []*models.DeviceCommand
+	if err := cursor.All(ctx, &commands); err != nil {
+		return nil, 0, err
+	}
+	return commands, total, nil
+}
+
+// Review records the approval or rejection of a command still awaiting approval and not yet
+// expired. Approved commands move straight to SENT with their delivery expiry, so the timeout
+// worker measures the acknowledgment timeout from the approval. It returns the updated command,
+// or an error when the command is no longer awaiting approval.
+func (r *CommandRepository) Review(ctx context.Context, commandID string, status models.CommandStatus, reviewerID, comment string, expiresAt *time.Time, now time.Time) (*models.DeviceCommand, error) {
+	updates := bson.M{
+		"status":      status,
+		"reviewed_by": reviewerID,
+		"reviewed_at": now,
+		"updated_at":  now,
+	}
+	if comment != "" {
+		updates["review_comment"] = comment
+	}
+	if status == models.CommandStatusSent {
+		updates["sent_at"] = now
+	}
+	if expiresAt != nil {
+		updates["expires_at"] = *expiresAt
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"command_id": commandID,
+			"status":     models.CommandStatusPendingApproval,
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var command models.DeviceCommand
+	if err := result.Decode(&command); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("command is not awaiting approval")
+		}
+		return nil, err
+	}
+	return &command, nil
+}
+
+// FindUnacknowledged retrieves commands still awaiting an acknowledgment that were sent, or
+// created if they were never sent, before the cutoff
+func (r *CommandRepository) FindUnacknowledged(ctx context.Context, before time.Time, limit int64) ([]*models.DeviceCommand, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": models.CommandStatusSent, "sent_at": bson.M{"$lt": before}},
+			{"status": models.CommandStatusPending, "created_at": bson.M{"$lt": before}},
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var commands []*models.DeviceCommand
+	if err := cursor.All(ctx, &commands); err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// MarkTimedOut moves a command still awaiting an acknowledgment to TIMEOUT. It reports false
+// when the command was acknowledged or expired in the meantime.
+func (r *CommandRepository) MarkTimedOut(ctx context.Context, commandID string, now time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"command_id": commandID,
+			"status":     bson.M{"$in": []models.CommandStatus{models.CommandStatusPending, models.CommandStatusSent}},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":     models.CommandStatusTimeout,
+				"error_msg":  "no acknowledgment received before the command timeout",
+				"updated_at": now,
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// CountOlderThan counts finished commands created before the given time
+func (r *CommandRepository) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, finishedBefore(before))
+}
+
+// DeleteOlderThan removes finished commands created before the given time. Commands still
+// awaiting approval, delivery or an acknowledgment are kept however old they are.
+func (r *CommandRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, finishedBefore(before))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// finishedBefore matches finished commands created before the given time
+func finishedBefore(before time.Time) bson.M {
+	return bson.M{
+		"created_at": bson.M{"$lt": before},
+		"status":     bson.M{"$in": models.FinishedCommandStatuses},
+	}
+}
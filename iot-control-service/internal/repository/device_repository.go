@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"iot-control-service/internal/models"
+	"iot-control-service/internal/tenant"
 )
 
 // DeviceRepository handles device database operations
@@ -23,6 +24,17 @@ func NewDeviceRepository(collection *mongo.Collection) *DeviceRepository {
 	return &DeviceRepository{collection: collection}
 }
 
+// notDeleted restricts a filter to devices that have not been soft deleted
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	return filter
+}
+
+// inScope restricts a filter to the devices of the buildings the request is scoped to
+func inScope(ctx context.Context, filter bson.M) bson.M {
+	return tenant.BuildingFilter(ctx, filter, "location.building_id")
+}
+
 // Create inserts a new device into the database
 func (r *DeviceRepository) Create(ctx context.Context, device *models.Device) (*models.Device, error) {
 	device.CreatedAt = time.Now()
@@ -48,7 +60,7 @@ func (r *DeviceRepository) FindByID(ctx context.Context, id string) (*models.Dev
 	}
 
 	var device models.Device
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&device)
+	err = r.collection.FindOne(ctx, inScope(ctx, notDeleted(bson.M{"_id": objectID}))).Decode(&device)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("device not found")
@@ -61,8 +73,18 @@ func (r *DeviceRepository) FindByID(ctx context.Context, id string) (*models.Dev
 
 // FindByDeviceID retrieves a device by its device_id field
 func (r *DeviceRepository) FindByDeviceID(ctx context.Context, deviceID string) (*models.Device, error) {
+	return r.FindByDeviceIDWithFields(ctx, deviceID, nil)
+}
+
+// FindByDeviceIDWithFields retrieves a device by its device_id field, loading only the selected fields
+func (r *DeviceRepository) FindByDeviceIDWithFields(ctx context.Context, deviceID string, fields *models.FieldSelection) (*models.Device, error) {
+	opts := options.FindOne()
+	if projection := fields.Projection(); projection != nil {
+		opts.SetProjection(projection)
+	}
+
 	var device models.Device
-	err := r.collection.FindOne(ctx, bson.M{"device_id": deviceID}).Decode(&device)
+	err := r.collection.FindOne(ctx, inScope(ctx, notDeleted(bson.M{"device_id": deviceID})), opts).Decode(&device)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("device not found")
@@ -72,8 +94,8 @@ func (r *DeviceRepository) FindByDeviceID(ctx context.Context, deviceID string)
 	return &device, nil
 }
 
-// FindAll retrieves devices with filters and pagination
-func (r *DeviceRepository) FindAll(ctx context.Context, buildingID, deviceType, status string, page, limit int) ([]*models.Device, int64, error) {
+// FindAll retrieves devices with filters and pagination. A field selection limits the fields loaded.
+func (r *DeviceRepository) FindAll(ctx context.Context, buildingID, deviceType, status string, page, limit int, fields *models.FieldSelection) ([]*models.Device, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -82,7 +104,7 @@ func (r *DeviceRepository) FindAll(ctx context.Context, buildingID, deviceType,
 	}
 
 	skip := int64((page - 1) * limit)
-	filter := bson.M{}
+	filter := notDeleted(bson.M{})
 
 	if buildingID != "" {
 		filter["location.building_id"] = buildingID
@@ -93,6 +115,7 @@ func (r *DeviceRepository) FindAll(ctx context.Context, buildingID, deviceType,
 	if status != "" {
 		filter["status"] = status
 	}
+	filter = inScope(ctx, filter)
 
 	// Get total count
 	total, err := r.collection.CountDocuments(ctx, filter)
@@ -105,6 +128,9 @@ func (r *DeviceRepository) FindAll(ctx context.Context, buildingID, deviceType,
 		SetSkip(skip).
 		SetLimit(int64(limit)).
 		SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if projection := fields.Projection(); projection != nil {
+		findOptions.SetProjection(projection)
+	}
 
 	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
@@ -120,6 +146,167 @@ func (r *DeviceRepository) FindAll(ctx context.Context, buildingID, deviceType,
 	return devices, total, nil
 }
 
+// Search retrieves up to limit devices matching the search, most relevant first.
+// A nil cursor starts from the best match.
+func (r *DeviceRepository) Search(ctx context.Context, req *models.SearchDevicesRequest, tags []string, after *models.SearchCursor, limit int) ([]*models.DeviceSearchHit, error) {
+	filter := notDeleted(bson.M{})
+	if req.BuildingID != "" {
+		filter["location.building_id"] = req.BuildingID
+	}
+	if req.Floor != "" {
+		filter["location.floor"] = req.Floor
+	}
+	if req.Room != "" {
+		filter["location.room"] = req.Room
+	}
+	if len(tags) > 0 {
+		filter["tags"] = bson.M{"$all": tags}
+	}
+	if req.Type != "" {
+		filter["type"] = req.Type
+	}
+	if req.Status != "" {
+		filter["status"] = req.Status
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, searchPipeline(req.Query, inScope(ctx, filter), after, limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hits []*models.DeviceSearchHit
+	if err := cursor.All(ctx, &hits); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+// CountByType counts the devices of a type, including soft-deleted ones that may still be restored
+func (r *DeviceRepository) CountByType(ctx context.Context, deviceType string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"type": deviceType})
+}
+
+// CountByBuilding counts the devices that are not deleted in each building, keyed by building ID
+func (r *DeviceRepository) CountByBuilding(ctx context.Context) (map[string]int64, error) {
+	pipeline := []bson.M{
+		{"$match": notDeleted(bson.M{})},
+		{"$group": bson.M{"_id": "$location.building_id", "count": bson.M{"$sum": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[string]int64)
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID    string `bson:"_id"`
+			Count int64  `bson:"count"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		counts[doc.ID] = doc.Count
+	}
+
+	return counts, cursor.Err()
+}
+
+// maxLocationDevices caps the number of devices loaded for one location
+const maxLocationDevices = 5000
+
+// FindByLocation retrieves the devices assigned to a location or to any location below it
+func (r *DeviceRepository) FindByLocation(ctx context.Context, locationID string) ([]*models.Device, error) {
+	findOptions := options.Find().
+		SetLimit(maxLocationDevices).
+		SetSort(bson.D{{Key: "device_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, inScope(ctx, notDeleted(bson.M{"location.hierarchy.path": locationID})), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	devices := []*models.Device{}
+	if err := cursor.All(ctx, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// CountByLocation counts the devices assigned to a location or to any location below it
+func (r *DeviceRepository) CountByLocation(ctx context.Context, locationID string) (int64, error) {
+	return r.collection.CountDocuments(ctx, inScope(ctx, notDeleted(bson.M{"location.hierarchy.path": locationID})))
+}
+
+// ClearHierarchy removes every device, including soft-deleted ones, from a location of the hierarchy
+func (r *DeviceRepository) ClearHierarchy(ctx context.Context, locationID string) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"location.hierarchy.path": locationID},
+		bson.M{"$unset": bson.M{"location.hierarchy": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	return err
+}
+
+// SetHierarchy places a device in the location hierarchy, or removes it when hierarchy is nil.
+// The device's floor and room names follow the locations it is assigned to.
+func (r *DeviceRepository) SetHierarchy(ctx context.Context, deviceID string, hierarchy *models.DeviceHierarchy, floor, room string) (*models.Device, error) {
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+	if hierarchy != nil {
+		set["location.hierarchy"] = hierarchy
+	} else {
+		unset["location.hierarchy"] = ""
+	}
+	if floor != "" {
+		set["location.floor"] = floor
+	}
+	if room != "" {
+		set["location.room"] = room
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		inScope(ctx, notDeleted(bson.M{"device_id": deviceID})),
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var device models.Device
+	if err := result.Decode(&device); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("device not found")
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+// FindDeviceIDs retrieves the device_id of every device the request is scoped to
+func (r *DeviceRepository) FindDeviceIDs(ctx context.Context) ([]string, error) {
+	values, err := r.collection.Distinct(ctx, "device_id", inScope(ctx, notDeleted(bson.M{})))
+	if err != nil {
+		return nil, err
+	}
+
+	deviceIDs := make([]string, 0, len(values))
+	for _, value := range values {
+		if deviceID, ok := value.(string); ok {
+			deviceIDs = append(deviceIDs, deviceID)
+		}
+	}
+	return deviceIDs, nil
+}
+
 // Update updates an existing device
 func (r *DeviceRepository) Update(ctx context.Context, id string, updates bson.M) (*models.Device, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -131,7 +318,7 @@ func (r *DeviceRepository) Update(ctx context.Context, id string, updates bson.M
 
 	result := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"_id": objectID},
+		inScope(ctx, notDeleted(bson.M{"_id": objectID})),
 		bson.M{"$set": updates},
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	)
@@ -152,7 +339,7 @@ func (r *DeviceRepository) UpdateLastSeen(ctx context.Context, deviceID string)
 	now := time.Now()
 	_, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"device_id": deviceID},
+		notDeleted(bson.M{"device_id": deviceID}),
 		bson.M{
 			"$set": bson.M{
 				"last_seen": now,
@@ -168,7 +355,7 @@ func (r *DeviceRepository) UpdateLastSeen(ctx context.Context, deviceID string)
 func (r *DeviceRepository) UpdateStatus(ctx context.Context, deviceID string, status models.DeviceStatus) error {
 	_, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"device_id": deviceID},
+		notDeleted(bson.M{"device_id": deviceID}),
 		bson.M{
 			"$set": bson.M{
 				"status":     status,
@@ -179,21 +366,108 @@ func (r *DeviceRepository) UpdateStatus(ctx context.Context, deviceID string, st
 	return err
 }
 
-// Delete removes a device from the database
-func (r *DeviceRepository) Delete(ctx context.Context, id string) error {
+// Delete soft deletes a device; its telemetry and command history stay resolvable until it is purged
+func (r *DeviceRepository) Delete(ctx context.Context, id, deletedBy string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.New("invalid device ID format")
 	}
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	now := time.Now()
+	result, err := r.collection.UpdateOne(
+		ctx,
+		inScope(ctx, notDeleted(bson.M{"_id": objectID})),
+		bson.M{"$set": bson.M{
+			"deleted_at": now,
+			"deleted_by": deletedBy,
+			"status":     models.DeviceStatusOffline,
+			"updated_at": now,
+		}},
+	)
 	if err != nil {
 		return err
 	}
 
-	if result.DeletedCount == 0 {
+	if result.MatchedCount == 0 {
 		return errors.New("device not found")
 	}
 
 	return nil
 }
+
+// FindDeleted retrieves soft-deleted devices with pagination, most recently deleted first
+func (r *DeviceRepository) FindDeleted(ctx context.Context, buildingID string, page, limit int) ([]*models.Device, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{"deleted_at": bson.M{"$ne": nil}}
+	if buildingID != "" {
+		filter["location.building_id"] = buildingID
+	}
+	filter = inScope(ctx, filter)
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "deleted_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var devices []*models.Device
+	if err := cursor.All(ctx, &devices); err != nil {
+		return nil, 0, err
+	}
+
+	return devices, total, nil
+}
+
+// Restore reverses a soft delete by device_id
+func (r *DeviceRepository) Restore(ctx context.Context, deviceID string) (*models.Device, error) {
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		inScope(ctx, bson.M{"device_id": deviceID, "deleted_at": bson.M{"$ne": nil}}),
+		bson.M{
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var device models.Device
+	if err := result.Decode(&device); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("deleted device not found")
+		}
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+// PurgeDeletedBefore permanently removes devices soft deleted before the given time
+func (r *DeviceRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// PurgeByDeviceID permanently removes a device, used to undo a registration that could not be completed
+func (r *DeviceRepository) PurgeByDeviceID(ctx context.Context, deviceID string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"device_id": deviceID})
+	return err
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"iot-control-service/internal/models"
+)
+
+// DeviceTypeRepository handles device type catalog database operations
+type DeviceTypeRepository struct {
+	collection *mongo.Collection
+}
+
+// NewDeviceTypeRepository creates a new device type repository
+func NewDeviceTypeRepository(collection *mongo.Collection) *DeviceTypeRepository {
+	return &DeviceTypeRepository{collection: collection}
+}
+
+// Create inserts a new device type
+func (r *DeviceTypeRepository) Create(ctx context.Context, deviceType *models.DeviceType) (*models.DeviceType, error) {
+	deviceType.CreatedAt = time.Now()
+	deviceType.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, deviceType)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("device type already exists")
+		}
+		return nil, err
+	}
+
+	deviceType.ID = result.InsertedID.(primitive.ObjectID)
+	return deviceType, nil
+}
+
+// FindByName retrieves a device type by its name
+func (r *DeviceTypeRepository) FindByName(ctx context.Context, name string) (*models.DeviceType, error) {
+	var deviceType models.DeviceType
+	err := r.collection.FindOne(ctx, bson.M{"name": name}).Decode(&deviceType)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("device type not found")
+		}
+		return nil, err
+	}
+	return &deviceType, nil
+}
+
+// FindAll retrieves every device type ordered by name
+func (r *DeviceTypeRepository) FindAll(ctx context.Context) ([]*models.DeviceType, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deviceTypes []*models.DeviceType
+	if err := cursor.All(ctx, &deviceTypes); err != nil {
+		return nil, err
+	}
+	return deviceTypes, nil
+}
+
+// Update applies updates to a device type and returns the updated document
+func (r *DeviceTypeRepository) Update(ctx context.Context, name string, updates bson.M) (*models.DeviceType, error) {
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"name": name},
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var deviceType models.DeviceType
+	if err := result.Decode(&deviceType); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("device type not found")
+		}
+		return nil, err
+	}
+	return &deviceType, nil
+}
+
+// Delete removes a device type
+func (r *DeviceTypeRepository) Delete(ctx context.Context, name string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("device type not found")
+	}
+	return nil
+}
+
+// ExistsByName checks if a device type exists
+func (r *DeviceTypeRepository) ExistsByName(ctx context.Context, name string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"name": name})
+	return count > 0, err
+}
+
+// InitializeDefaultTypes creates the built-in device types if they don't exist
+func (r *DeviceTypeRepository) InitializeDefaultTypes(ctx context.Context) error {
+	defaultTypes := []models.DeviceType{
+		{
+			Name:              "HVAC",
+			DisplayName:       "HVAC",
+			Description:       "Heating, ventilation and air conditioning units",
+			SupportedCommands: []string{"TURN_ON", "TURN_OFF", "SET_TEMP", "SET_MODE", "REDUCE_POWER", "CURTAIL"},
+			TelemetryMetrics:  []string{"temperature", "humidity", "power", "energy"},
+			Icon:              "thermostat",
+			DefaultConstraints: map[string]interface{}{
+				"minTemperature": 16.0,
+				"maxTemperature": 30.0,
+			},
+			// Short-cycling damages compressors
+			RateLimit: &models.CommandRateLimit{
+				MaxCommands:           10,
+				WindowSeconds:         60,
+				MinStateChangeSeconds: 300,
+			},
+			IsSystem: true,
+		},
+		{
+			Name:              "LIGHTING",
+			DisplayName:       "Lighting",
+			Description:       "Lighting circuits and fixtures",
+			SupportedCommands: []string{"TURN_ON", "TURN_OFF", "SET_BRIGHTNESS", "REDUCE_POWER", "CURTAIL"},
+			TelemetryMetrics:  []string{"brightness", "power", "energy"},
+			Icon:              "lightbulb",
+			DefaultConstraints: map[string]interface{}{
+				"minBrightness": 10.0,
+				"maxBrightness": 100.0,
+			},
+			IsSystem: true,
+		},
+		{
+			Name:              "EQUIPMENT",
+			DisplayName:       "Equipment",
+			Description:       "Other controllable electrical equipment",
+			SupportedCommands: []string{"TURN_ON", "TURN_OFF", "REDUCE_POWER", "CURTAIL"},
+			TelemetryMetrics:  []string{"power", "energy"},
+			Icon:              "plug",
+			IsSystem:          true,
+		},
+		{
+			Name:              "SENSOR",
+			DisplayName:       "Sensor",
+			Description:       "Read-only environmental and occupancy sensors",
+			SupportedCommands: []string{},
+			TelemetryMetrics:  []string{"temperature", "humidity", "co2", "occupancy"},
+			Icon:              "sensor",
+			IsSystem:          true,
+		},
+		{
+			Name:              "SOLAR_INVERTER",
+			DisplayName:       "Solar inverter",
+			Description:       "PV inverters metering generation and grid export",
+			SupportedCommands: []string{"TURN_ON", "TURN_OFF", "CURTAIL"},
+			TelemetryMetrics:  []string{"power", models.MetricGeneration, models.MetricGridImport, models.MetricGridExport},
+			Icon:              "solar",
+			IsSystem:          true,
+		},
+		{
+			Name:              "BATTERY",
+			DisplayName:       "Battery storage",
+			Description:       "Stationary batteries that can be charged and discharged on command",
+			SupportedCommands: []string{"CHARGE", "DISCHARGE", "TURN_OFF"},
+			TelemetryMetrics:  []string{"power", models.MetricStateOfCharge, models.MetricBatteryCharge, models.MetricBatteryDischarge},
+			Icon:              "battery",
+			DefaultConstraints: map[string]interface{}{
+				"capacityKWh":         100.0,
+				"maxPowerKW":          50.0,
+				"minStateOfCharge":    10.0,
+				"maxStateOfCharge":    95.0,
+				"roundTripEfficiency": 0.9,
+			},
+			IsSystem: true,
+		},
+	}
+
+	for _, deviceType := range defaultTypes {
+		exists, err := r.ExistsByName(ctx, deviceType.Name)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			deviceType.CreatedAt = time.Now()
+			deviceType.UpdatedAt = time.Now()
+			if _, err := r.collection.InsertOne(ctx, deviceType); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/tenant"
+)
+
+// GatewayMappingRepository handles gateway register mapping database operations
+type GatewayMappingRepository struct {
+	collection *mongo.Collection
+}
+
+// NewGatewayMappingRepository creates a new gateway mapping repository
+func NewGatewayMappingRepository(collection *mongo.Collection) *GatewayMappingRepository {
+	return &GatewayMappingRepository{collection: collection}
+}
+
+// Upsert creates or replaces the mapping of a device and returns the stored document
+func (r *GatewayMappingRepository) Upsert(ctx context.Context, mapping *models.GatewayMapping) (*models.GatewayMapping, error) {
+	now := time.Now()
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"device_id": mapping.DeviceID},
+		bson.M{
+			"$set": bson.M{
+				"building_id": mapping.BuildingID,
+				"protocol":    mapping.Protocol,
+				"registers":   mapping.Registers,
+				"updated_by":  mapping.UpdatedBy,
+				"updated_at":  now,
+			},
+			"$setOnInsert": bson.M{"created_at": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	var stored models.GatewayMapping
+	if err := result.Decode(&stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// FindByDeviceID retrieves the mapping of a device
+func (r *GatewayMappingRepository) FindByDeviceID(ctx context.Context, deviceID string) (*models.GatewayMapping, error) {
+	var mapping models.GatewayMapping
+	err := r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"device_id": deviceID}, "building_id")).Decode(&mapping)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("gateway mapping not found")
+		}
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+// FindByDeviceIDs retrieves the mappings of several devices keyed by device ID. Devices without
+// a mapping are missing from the result.
+func (r *GatewayMappingRepository) FindByDeviceIDs(ctx context.Context, deviceIDs []string) (map[string]*models.GatewayMapping, error) {
+	cursor, err := r.collection.Find(ctx, tenant.BuildingFilter(ctx, bson.M{"device_id": bson.M{"$in": deviceIDs}}, "building_id"))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	mappings := make(map[string]*models.GatewayMapping)
+	for cursor.Next(ctx) {
+		var mapping models.GatewayMapping
+		if err := cursor.Decode(&mapping); err != nil {
+			return nil, err
+		}
+		mappings[mapping.DeviceID] = &mapping
+	}
+	return mappings, cursor.Err()
+}
+
+// FindAll retrieves mappings, optionally of one building, with pagination
+func (r *GatewayMappingRepository) FindAll(ctx context.Context, buildingID string, page, limit int) ([]*models.GatewayMapping, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{}
+	if buildingID != "" {
+		filter["building_id"] = buildingID
+	}
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "device_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	mappings := []*models.GatewayMapping{}
+	if err := cursor.All(ctx, &mappings); err != nil {
+		return nil, 0, err
+	}
+	return mappings, total, nil
+}
+
+// Delete removes the mapping of a device
+func (r *GatewayMappingRepository) Delete(ctx context.Context, deviceID string) error {
+	result, err := r.collection.DeleteOne(ctx, tenant.BuildingFilter(ctx, bson.M{"device_id": deviceID}, "building_id"))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("gateway mapping not found")
+	}
+	return nil
+}
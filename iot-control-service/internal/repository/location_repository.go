@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/tenant"
+)
+
+// maxLocations caps the number of locations a listing returns
+const maxLocations = 1000
+
+// LocationRepository handles location hierarchy database operations. Requests scoped to an
+// organization only see the locations of its buildings and its own sites.
+type LocationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewLocationRepository creates a new location repository
+func NewLocationRepository(collection *mongo.Collection) *LocationRepository {
+	return &LocationRepository{collection: collection}
+}
+
+// locationScope restricts a filter to the locations the organization ctx is scoped to may see
+func locationScope(ctx context.Context, filter bson.M) bson.M {
+	scope := tenant.FromContext(ctx)
+	if scope == nil {
+		return filter
+	}
+	return bson.M{"$and": []bson.M{filter, {"$or": []bson.M{
+		{"building_id": bson.M{"$in": append([]string{}, scope.BuildingIDs...)}},
+		{"level": models.LocationLevelSite, "org_id": scope.OrgID},
+	}}}}
+}
+
+// Create inserts a new location
+func (r *LocationRepository) Create(ctx context.Context, location *models.Location) (*models.Location, error) {
+	location.CreatedAt = time.Now()
+	location.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, location)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("location with this ID already exists")
+		}
+		return nil, err
+	}
+
+	location.ID = result.InsertedID.(primitive.ObjectID)
+	return location, nil
+}
+
+// FindByLocationID retrieves a location by its location_id field
+func (r *LocationRepository) FindByLocationID(ctx context.Context, locationID string) (*models.Location, error) {
+	var location models.Location
+	err := r.collection.FindOne(ctx, locationScope(ctx, bson.M{"location_id": locationID})).Decode(&location)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("location not found")
+		}
+		return nil, err
+	}
+	return &location, nil
+}
+
+// FindAll retrieves locations, optionally of one building, under one parent or of one level,
+// ordered by name
+func (r *LocationRepository) FindAll(ctx context.Context, buildingID, parentID string, level models.LocationLevel) ([]*models.Location, error) {
+	filter := bson.M{}
+	if buildingID != "" {
+		filter["building_id"] = buildingID
+	}
+	if parentID != "" {
+		filter["parent_id"] = parentID
+	}
+	if level != "" {
+		filter["level"] = level
+	}
+
+	findOptions := options.Find().
+		SetLimit(maxLocations).
+		SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, locationScope(ctx, filter), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	locations := []*models.Location{}
+	if err := cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// FindByLocationIDs retrieves the locations with the given IDs, keyed by location ID
+func (r *LocationRepository) FindByLocationIDs(ctx context.Context, locationIDs []string) (map[string]*models.Location, error) {
+	cursor, err := r.collection.Find(ctx, locationScope(ctx, bson.M{"location_id": bson.M{"$in": locationIDs}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var locations []*models.Location
+	if err := cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*models.Location, len(locations))
+	for _, location := range locations {
+		byID[location.LocationID] = location
+	}
+	return byID, nil
+}
+
+// CountChildren counts the locations directly below a location
+func (r *LocationRepository) CountChildren(ctx context.Context, locationID string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"parent_id": locationID})
+}
+
+// Update sets fields of a location and returns the updated document
+func (r *LocationRepository) Update(ctx context.Context, locationID string, updates bson.M) (*models.Location, error) {
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		locationScope(ctx, bson.M{"location_id": locationID}),
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var location models.Location
+	if err := result.Decode(&location); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("location not found")
+		}
+		return nil, err
+	}
+	return &location, nil
+}
+
+// Delete removes a location
+func (r *LocationRepository) Delete(ctx context.Context, locationID string) error {
+	result, err := r.collection.DeleteOne(ctx, locationScope(ctx, bson.M{"location_id": locationID}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("location not found")
+	}
+	return nil
+}
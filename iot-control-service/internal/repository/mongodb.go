@@ -5,13 +5,24 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/description"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 
 	"iot-control-service/internal/config"
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/tracing"
+	"shared/jobs"
 )
 
 // MongoDB holds the database connection and collections
@@ -19,6 +30,7 @@ type MongoDB struct {
 	Client   *mongo.Client
 	Database *mongo.Database
 	config   *config.Config
+	monitor  *topologyMonitor
 }
 
 // Collections holds references to all MongoDB collections
@@ -27,6 +39,16 @@ type Collections struct {
 	Telemetry             *mongo.Collection
 	DeviceCommands        *mongo.Collection
 	OptimizationScenarios *mongo.Collection
+	ScheduledCommands     *mongo.Collection
+	DeviceTypes           *mongo.Collection
+	ProvisioningTokens    *mongo.Collection
+	WeatherRules          *mongo.Collection
+	WeatherRuleExecutions *mongo.Collection
+	GatewayMappings       *mongo.Collection
+	Jobs                  *mongo.Collection
+	Settings              *mongo.Collection
+	SettingsHistory       *mongo.Collection
+	Locations             *mongo.Collection
 }
 
 // NewMongoDB creates a new MongoDB connection
@@ -34,12 +56,38 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoDB.Timeout)
 	defer cancel()
 
+	monitor := &topologyMonitor{}
+
 	// Create client options
 	clientOptions := options.Client().
 		ApplyURI(cfg.MongoDB.URI).
-		SetMaxPoolSize(100).
-		SetMinPoolSize(10).
-		SetMaxConnIdleTime(30 * time.Second)
+		SetMaxPoolSize(cfg.MongoDB.MaxPoolSize).
+		SetMinPoolSize(cfg.MongoDB.MinPoolSize).
+		SetMaxConnIdleTime(30 * time.Second).
+		SetServerSelectionTimeout(cfg.MongoDB.ServerSelectionTimeout).
+		SetRetryWrites(cfg.MongoDB.RetryWrites).
+		SetServerMonitor(&event.ServerMonitor{
+			TopologyDescriptionChanged: monitor.topologyChanged,
+		}).
+		SetMonitor(tracing.NewMongoMonitor())
+
+	if cfg.MongoDB.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(cfg.MongoDB.ReplicaSet)
+	}
+	if cfg.MongoDB.ReadPreference != "" {
+		readPref, err := parseReadPreference(cfg.MongoDB.ReadPreference)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetReadPreference(readPref)
+	}
+	if cfg.MongoDB.WriteConcern != "" {
+		writeConcern, err := parseWriteConcern(cfg.MongoDB.WriteConcern)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetWriteConcern(writeConcern)
+	}
 
 	// Connect to MongoDB
 	client, err := mongo.Connect(ctx, clientOptions)
@@ -58,9 +106,90 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 		Client:   client,
 		Database: client.Database(cfg.MongoDB.Database),
 		config:   cfg,
+		monitor:  monitor,
 	}, nil
 }
 
+// parseReadPreference parses a read preference mode such as "primary" or "secondaryPreferred"
+func parseReadPreference(value string) (*readpref.ReadPref, error) {
+	mode, err := readpref.ModeFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MongoDB read preference %q: %w", value, err)
+	}
+	return readpref.New(mode)
+}
+
+// parseWriteConcern accepts "majority" or the number of members that must acknowledge a write
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	if strings.EqualFold(value, "majority") {
+		return writeconcern.Majority(), nil
+	}
+	w, err := strconv.Atoi(value)
+	if err != nil || w < 0 {
+		return nil, fmt.Errorf("invalid MongoDB write concern %q: must be \"majority\" or a number", value)
+	}
+	return &writeconcern.WriteConcern{W: w}, nil
+}
+
+// topologyMonitor follows driver topology events to track the primary and any replica set
+// members that have become unreachable
+type topologyMonitor struct {
+	mu          sync.Mutex
+	primary     string
+	unavailable []string
+}
+
+// topologyChanged is called by the driver with the topology locked, so it must not run operations
+func (t *topologyMonitor) topologyChanged(e *event.TopologyDescriptionChangedEvent) {
+	var primary string
+	var unavailable []string
+	for _, server := range e.NewDescription.Servers {
+		switch {
+		case server.Kind == description.RSPrimary:
+			primary = server.Addr.String()
+		case server.Kind == description.RSGhost,
+			server.Kind == description.Unknown && server.LastError != nil:
+			// Members start as Unknown until their first heartbeat; only failed ones count
+			unavailable = append(unavailable, server.Addr.String())
+		}
+	}
+	sort.Strings(unavailable)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if primary != t.primary {
+		if primary == "" {
+			log.Printf("MongoDB primary %s is no longer available", t.primary)
+		} else {
+			log.Printf("MongoDB primary is now %s", primary)
+		}
+	}
+	if strings.Join(unavailable, ",") != strings.Join(t.unavailable, ",") {
+		if len(unavailable) > 0 {
+			log.Printf("MongoDB members unreachable: %s", strings.Join(unavailable, ", "))
+		} else {
+			log.Printf("All MongoDB members reachable")
+		}
+	}
+
+	t.primary = primary
+	t.unavailable = unavailable
+}
+
+// status reports unreachable members as a degraded state
+func (t *topologyMonitor) status() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.unavailable) == 0 {
+		return nil
+	}
+	return &models.DegradedError{
+		Reason: fmt.Sprintf("MongoDB members unreachable: %s", strings.Join(t.unavailable, ", ")),
+	}
+}
+
 // GetCollections returns all collection references
 func (m *MongoDB) GetCollections() *Collections {
 	return &Collections{
@@ -68,7 +197,31 @@ func (m *MongoDB) GetCollections() *Collections {
 		Telemetry:             m.Database.Collection("telemetry"),
 		DeviceCommands:       m.Database.Collection("device_commands"),
 		OptimizationScenarios: m.Database.Collection("optimization_scenarios"),
+		ScheduledCommands:     m.Database.Collection("scheduled_commands"),
+		DeviceTypes:           m.Database.Collection("device_types"),
+		ProvisioningTokens:    m.Database.Collection("provisioning_tokens"),
+		WeatherRules:          m.Database.Collection("weather_rules"),
+		WeatherRuleExecutions: m.Database.Collection("weather_rule_executions"),
+		GatewayMappings:       m.Database.Collection("gateway_mappings"),
+		Jobs:                  m.Database.Collection("jobs"),
+		Settings:              m.Database.Collection("settings"),
+		SettingsHistory:       m.Database.Collection("settings_history"),
+		Locations:             m.Database.Collection("locations"),
+	}
+}
+
+// Ping verifies the MongoDB primary is reachable
+func (m *MongoDB) Ping(ctx context.Context) error {
+	return m.Client.Ping(ctx, readpref.Primary())
+}
+
+// HealthCheck verifies the MongoDB primary is reachable and reports the connection as degraded
+// while other replica set members are unreachable
+func (m *MongoDB) HealthCheck(ctx context.Context) error {
+	if err := m.Ping(ctx); err != nil {
+		return err
 	}
+	return m.monitor.status()
 }
 
 // Close closes the MongoDB connection
@@ -99,6 +252,20 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: map[string]interface{}{"type": 1},
 		},
+		{
+			// Devices are rolled up by any location of the hierarchy above them
+			Keys: map[string]interface{}{"location.hierarchy.path": 1},
+		},
+		{
+			// Device search matches names, IDs and tags ahead of the type, model and location
+			Keys: map[string]interface{}{
+				"name": "text", "device_id": "text", "tags": "text", "type": "text", "model": "text",
+				"location.floor": "text", "location.room": "text",
+			},
+			Options: options.Index().
+				SetName("device_search").
+				SetWeights(map[string]interface{}{"name": 10, "device_id": 5, "tags": 5}),
+		},
 	}
 	if _, err := collections.Devices.Indexes().CreateMany(ctx, deviceIndexes); err != nil {
 		return fmt.Errorf("failed to create device indexes: %w", err)
@@ -133,6 +300,18 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: map[string]interface{}{"status": 1, "created_at": -1},
 		},
+		{
+			Keys: map[string]interface{}{"device_id": 1, "idempotency_key": 1},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(map[string]interface{}{"idempotency_key": map[string]interface{}{"$type": "string"}}),
+		},
+		{
+			Keys: map[string]interface{}{"status": 1, "expires_at": 1},
+		},
+		{
+			Keys: map[string]interface{}{"status": 1, "sent_at": 1},
+		},
 	}
 	if _, err := collections.DeviceCommands.Indexes().CreateMany(ctx, commandIndexes); err != nil {
 		return fmt.Errorf("failed to create device command indexes: %w", err)
@@ -150,11 +329,139 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: map[string]interface{}{"execution_status": 1, "created_at": -1},
 		},
+		{
+			Keys: map[string]interface{}{"actions.command_id": 1},
+		},
+		{
+			Keys: map[string]interface{}{"actions.device_id": 1, "execution_status": 1},
+		},
 	}
 	if _, err := collections.OptimizationScenarios.Indexes().CreateMany(ctx, optimizationIndexes); err != nil {
 		return fmt.Errorf("failed to create optimization scenario indexes: %w", err)
 	}
 
+	// Scheduled commands collection indexes
+	scheduleIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"schedule_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"device_id": 1, "created_at": -1},
+		},
+		{
+			Keys: map[string]interface{}{"status": 1, "next_run_at": 1},
+		},
+	}
+	if _, err := collections.ScheduledCommands.Indexes().CreateMany(ctx, scheduleIndexes); err != nil {
+		return fmt.Errorf("failed to create scheduled command indexes: %w", err)
+	}
+
+	// Device types collection indexes
+	deviceTypeIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"name": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := collections.DeviceTypes.Indexes().CreateMany(ctx, deviceTypeIndexes); err != nil {
+		return fmt.Errorf("failed to create device type indexes: %w", err)
+	}
+
+	// Provisioning tokens collection indexes
+	provisioningIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"token_hash": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"status": 1, "expires_at": 1},
+		},
+	}
+	if _, err := collections.ProvisioningTokens.Indexes().CreateMany(ctx, provisioningIndexes); err != nil {
+		return fmt.Errorf("failed to create provisioning token indexes: %w", err)
+	}
+
+	// Weather rules collection indexes
+	weatherRuleIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"rule_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"enabled": 1, "next_run_at": 1},
+		},
+		{
+			Keys: map[string]interface{}{"building_id": 1, "created_at": -1},
+		},
+	}
+	if _, err := collections.WeatherRules.Indexes().CreateMany(ctx, weatherRuleIndexes); err != nil {
+		return fmt.Errorf("failed to create weather rule indexes: %w", err)
+	}
+
+	// Weather rule executions collection indexes
+	weatherRuleExecutionIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{"rule_id": 1, "evaluated_at": -1},
+		},
+	}
+	if _, err := collections.WeatherRuleExecutions.Indexes().CreateMany(ctx, weatherRuleExecutionIndexes); err != nil {
+		return fmt.Errorf("failed to create weather rule execution indexes: %w", err)
+	}
+
+	// Gateway mappings collection indexes
+	gatewayMappingIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"device_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "building_id", Value: 1}, {Key: "device_id", Value: 1}},
+		},
+	}
+	if _, err := collections.GatewayMappings.Indexes().CreateMany(ctx, gatewayMappingIndexes); err != nil {
+		return fmt.Errorf("failed to create gateway mapping indexes: %w", err)
+	}
+
+	// Job queue indexes: workers claim by type, status and run time; finished jobs expire
+	jobIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "type", Value: 1}, {Key: "status", Value: 1}, {Key: "run_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "finished_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(jobs.Retention.Seconds())),
+		},
+	}
+	if _, err := collections.Jobs.Indexes().CreateMany(ctx, jobIndexes); err != nil {
+		return fmt.Errorf("failed to create job indexes: %w", err)
+	}
+
+	// Settings history indexes: changes are listed newest first, overall and per setting
+	settingsHistoryIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "changed_at", Value: -1}}},
+		{Keys: bson.D{{Key: "changed_at", Value: -1}}},
+	}
+	if _, err := collections.SettingsHistory.Indexes().CreateMany(ctx, settingsHistoryIndexes); err != nil {
+		return fmt.Errorf("failed to create settings history indexes: %w", err)
+	}
+
+	// Location hierarchy indexes: locations are listed per building and per parent
+	locationIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "location_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "building_id", Value: 1}, {Key: "level", Value: 1}}},
+		{Keys: bson.D{{Key: "parent_id", Value: 1}}},
+	}
+	if _, err := collections.Locations.Indexes().CreateMany(ctx, locationIndexes); err != nil {
+		return fmt.Errorf("failed to create location indexes: %w", err)
+	}
+
 	log.Println("MongoDB indexes created successfully")
 	return nil
 }
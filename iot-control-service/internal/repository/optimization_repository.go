@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -72,6 +73,27 @@ func (r *OptimizationRepository) FindByScenarioID(ctx context.Context, scenarioI
 	return &scenario, nil
 }
 
+// FindActiveByDevice retrieves the pending or running scenarios with an action for a device
+func (r *OptimizationRepository) FindActiveByDevice(ctx context.Context, deviceID string) ([]*models.OptimizationScenario, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"actions.device_id": deviceID,
+		"execution_status": bson.M{"$in": []models.OptimizationExecutionStatus{
+			models.OptimizationStatusPending,
+			models.OptimizationStatusRunning,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var scenarios []*models.OptimizationScenario
+	if err := cursor.All(ctx, &scenarios); err != nil {
+		return nil, err
+	}
+	return scenarios, nil
+}
+
 // Update updates a scenario
 func (r *OptimizationRepository) Update(ctx context.Context, id string, updates bson.M) (*models.OptimizationScenario, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -143,3 +165,105 @@ func (r *OptimizationRepository) UpdateActionStatus(ctx context.Context, scenari
 	)
 	return err
 }
+
+// UpdateActionStatusByCommandID updates the status and command of the action that sent a command
+// and returns the updated scenario
+func (r *OptimizationRepository) UpdateActionStatusByCommandID(ctx context.Context, commandID, status, newCommandID string) (*models.OptimizationScenario, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var scenario models.OptimizationScenario
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"actions.command_id": commandID},
+		bson.M{
+			"$set": bson.M{
+				"actions.$.status":     status,
+				"actions.$.command_id": newCommandID,
+				"updated_at":           time.Now(),
+			},
+		},
+		opts,
+	).Decode(&scenario)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("optimization scenario not found")
+		}
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+// FindComfortMonitored retrieves the completed scenarios with a comfort band that completed after
+// a time, whose devices the comfort guardrail may still be watching
+func (r *OptimizationRepository) FindComfortMonitored(ctx context.Context, completedAfter time.Time) ([]*models.OptimizationScenario, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"execution_status": models.OptimizationStatusCompleted,
+		"comfort":          bson.M{"$exists": true},
+		"completed_at":     bson.M{"$gte": completedAfter},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var scenarios []*models.OptimizationScenario
+	if err := cursor.All(ctx, &scenarios); err != nil {
+		return nil, err
+	}
+	return scenarios, nil
+}
+
+// AddGuardrailIntervention appends a comfort guardrail intervention to a scenario
+func (r *OptimizationRepository) AddGuardrailIntervention(ctx context.Context, scenarioID string, intervention models.GuardrailIntervention) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"scenario_id": scenarioID},
+		bson.M{
+			"$push": bson.M{"guardrail_interventions": intervention},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+// UpdateAction sets fields of the action at the given position in a scenario
+func (r *OptimizationRepository) UpdateAction(ctx context.Context, scenarioID string, index int, updates bson.M) error {
+	set := bson.M{"updated_at": time.Now()}
+	for field, value := range updates {
+		set[fmt.Sprintf("actions.%d.%s", index, field)] = value
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"scenario_id": scenarioID}, bson.M{"$set": set})
+	return err
+}
+
+// Resume replaces the actions of a finished scenario and queues it for execution again. It
+// returns the updated scenario, or an error when the scenario is no longer finished.
+func (r *OptimizationRepository) Resume(ctx context.Context, scenarioID string, actions []models.OptimizationAction) (*models.OptimizationScenario, error) {
+	filter := bson.M{
+		"scenario_id": scenarioID,
+		"execution_status": bson.M{"$in": []models.OptimizationExecutionStatus{
+			models.OptimizationStatusCompleted,
+			models.OptimizationStatusFailed,
+			models.OptimizationStatusPaused,
+		}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"actions":          actions,
+			"execution_status": models.OptimizationStatusPending,
+			"updated_at":       time.Now(),
+		},
+		"$unset": bson.M{"error_msg": "", "completed_at": ""},
+	}
+
+	var scenario models.OptimizationScenario
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&scenario)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("validation failed: scenario is already being executed")
+		}
+		return nil, err
+	}
+	return &scenario, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/tenant"
+)
+
+// ProvisioningRepository handles provisioning token database operations
+type ProvisioningRepository struct {
+	collection *mongo.Collection
+}
+
+// NewProvisioningRepository creates a new provisioning token repository
+func NewProvisioningRepository(collection *mongo.Collection) *ProvisioningRepository {
+	return &ProvisioningRepository{collection: collection}
+}
+
+// Create inserts a new provisioning token
+func (r *ProvisioningRepository) Create(ctx context.Context, token *models.ProvisioningToken) (*models.ProvisioningToken, error) {
+	token.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	token.ID = result.InsertedID.(primitive.ObjectID)
+	return token, nil
+}
+
+// FindOutstanding retrieves active, unexpired tokens, newest first. An empty building ID
+// matches every building.
+func (r *ProvisioningRepository) FindOutstanding(ctx context.Context, buildingID string) ([]*models.ProvisioningToken, error) {
+	filter := bson.M{
+		"status":     models.ProvisioningTokenActive,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	if buildingID != "" {
+		filter["building_id"] = buildingID
+	}
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []*models.ProvisioningToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Reserve atomically marks an active, unexpired token as claimed by a device so that it
+// cannot be used twice
+func (r *ProvisioningRepository) Reserve(ctx context.Context, tokenHash, deviceID string) (*models.ProvisioningToken, error) {
+	now := time.Now()
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"token_hash": tokenHash,
+			"status":     models.ProvisioningTokenActive,
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{
+			"status":            models.ProvisioningTokenClaimed,
+			"claimed_at":        now,
+			"claimed_device_id": deviceID,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var token models.ProvisioningToken
+	if err := result.Decode(&token); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("provisioning token is invalid, expired or already used")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Release returns a reserved token to the active state after a claim failed
+func (r *ProvisioningRepository) Release(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": models.ProvisioningTokenClaimed},
+		bson.M{
+			"$set":   bson.M{"status": models.ProvisioningTokenActive},
+			"$unset": bson.M{"claimed_at": "", "claimed_device_id": ""},
+		},
+	)
+	return err
+}
+
+// Revoke invalidates an active token
+func (r *ProvisioningRepository) Revoke(ctx context.Context, id, revokedBy string) (*models.ProvisioningToken, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid provisioning token ID format")
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		tenant.BuildingFilter(ctx, bson.M{"_id": objectID, "status": models.ProvisioningTokenActive}, "building_id"),
+		bson.M{"$set": bson.M{
+			"status":     models.ProvisioningTokenRevoked,
+			"revoked_at": time.Now(),
+			"revoked_by": revokedBy,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var token models.ProvisioningToken
+	if err := result.Decode(&token); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("provisioning token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"iot-control-service/internal/models"
+)
+
+// ScheduledCommandRepository handles scheduled command database operations
+type ScheduledCommandRepository struct {
+	collection *mongo.Collection
+}
+
+// NewScheduledCommandRepository creates a new scheduled command repository
+func NewScheduledCommandRepository(collection *mongo.Collection) *ScheduledCommandRepository {
+	return &ScheduledCommandRepository{collection: collection}
+}
+
+// Create inserts a new scheduled command
+func (r *ScheduledCommandRepository) Create(ctx context.Context, schedule *models.ScheduledCommand) (*models.ScheduledCommand, error) {
+	schedule.CreatedAt = time.Now()
+	schedule.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, schedule)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("scheduled command with this ID already exists")
+		}
+		return nil, err
+	}
+
+	schedule.ID = result.InsertedID.(primitive.ObjectID)
+	return schedule, nil
+}
+
+// FindByScheduleID retrieves a scheduled command by its schedule_id field
+func (r *ScheduledCommandRepository) FindByScheduleID(ctx context.Context, scheduleID string) (*models.ScheduledCommand, error) {
+	var schedule models.ScheduledCommand
+	err := r.collection.FindOne(ctx, bson.M{"schedule_id": scheduleID}).Decode(&schedule)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("scheduled command not found")
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// FindByDeviceID retrieves scheduled commands for a device
+func (r *ScheduledCommandRepository) FindByDeviceID(ctx context.Context, deviceID string, status string, page, limit int) ([]*models.ScheduledCommand, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	skip := int64((page - 1) * limit)
+	filter := bson.M{"device_id": deviceID}
+
+	if status != "" {
+		filter["status"] = status
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(skip).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var schedules []*models.ScheduledCommand
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, 0, err
+	}
+
+	return schedules, total, nil
+}
+
+// FindDue retrieves active scheduled commands whose next run is at or before now
+func (r *ScheduledCommandRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]*models.ScheduledCommand, error) {
+	findOptions := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "next_run_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"status":      models.ScheduleStatusActive,
+		"next_run_at": bson.M{"$lte": now},
+	}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var schedules []*models.ScheduledCommand
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// ClaimRun advances a due scheduled command to its next run, or completes it when next is nil.
+// The update only applies while the schedule is still active and due at runAt, so concurrent
+// schedulers dispatch each run once; the returned bool reports whether this caller won.
+func (r *ScheduledCommandRepository) ClaimRun(ctx context.Context, scheduleID string, runAt time.Time, next *time.Time) (bool, error) {
+	set := bson.M{"updated_at": time.Now()}
+	update := bson.M{"$set": set}
+	if next != nil {
+		set["next_run_at"] = *next
+	} else {
+		set["status"] = models.ScheduleStatusCompleted
+		update["$unset"] = bson.M{"next_run_at": ""}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{
+		"schedule_id": scheduleID,
+		"status":      models.ScheduleStatusActive,
+		"next_run_at": runAt,
+	}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// RecordRun stores the outcome of a dispatched run
+func (r *ScheduledCommandRepository) RecordRun(ctx context.Context, scheduleID, commandID, errorMsg string, ranAt time.Time) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"schedule_id": scheduleID},
+		bson.M{
+			"$set": bson.M{
+				"last_run_at":     ranAt,
+				"last_command_id": commandID,
+				"last_error":      errorMsg,
+				"updated_at":      time.Now(),
+			},
+			"$inc": bson.M{"run_count": 1},
+		},
+	)
+	return err
+}
+
+// TransitionStatus moves a scheduled command from one of the given statuses to a new status.
+// A nil nextRunAt clears the next run. It returns "scheduled command not found" when no
+// schedule in an allowed status matched.
+func (r *ScheduledCommandRepository) TransitionStatus(ctx context.Context, scheduleID string, from []models.ScheduleStatus, to models.ScheduleStatus, nextRunAt *time.Time) (*models.ScheduledCommand, error) {
+	set := bson.M{
+		"status":     to,
+		"updated_at": time.Now(),
+	}
+	update := bson.M{"$set": set}
+	if nextRunAt != nil {
+		set["next_run_at"] = *nextRunAt
+	} else {
+		update["$unset"] = bson.M{"next_run_at": ""}
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"schedule_id": scheduleID, "status": bson.M{"$in": from}},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var schedule models.ScheduledCommand
+	if err := result.Decode(&schedule); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("scheduled command not found")
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// PauseByCreator pauses every active scheduled command created by a user, returning how many were paused
+func (r *ScheduledCommandRepository) PauseByCreator(ctx context.Context, createdBy string) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"created_by": createdBy, "status": models.ScheduleStatusActive},
+		bson.M{"$set": bson.M{
+			"status":     models.ScheduleStatusPaused,
+			"updated_at": time.Now(),
+		}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
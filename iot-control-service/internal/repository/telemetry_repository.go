@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"iot-control-service/internal/models"
+	"iot-control-service/internal/tenant"
 )
 
 // TelemetryRepository handles telemetry database operations
@@ -125,6 +127,41 @@ func (r *TelemetryRepository) FindLatestByDevice(ctx context.Context, deviceID s
 	return &telemetry, nil
 }
 
+// FindSnapshotBefore retrieves the latest telemetry for a device at or before a time, within a lookback window
+func (r *TelemetryRepository) FindSnapshotBefore(ctx context.Context, deviceID string, at time.Time, lookback time.Duration) (*models.Telemetry, error) {
+	filter := bson.M{
+		"device_id": deviceID,
+		"timestamp": bson.M{"$lte": at, "$gte": at.Add(-lookback)},
+	}
+	return r.findOne(ctx, filter, -1)
+}
+
+// FindSnapshotAfter retrieves the latest telemetry for a device after a time and up to the end of a window,
+// i.e. the settled state the device reported once a change had time to take effect
+func (r *TelemetryRepository) FindSnapshotAfter(ctx context.Context, deviceID string, after time.Time, window time.Duration) (*models.Telemetry, error) {
+	filter := bson.M{
+		"device_id": deviceID,
+		"timestamp": bson.M{"$gt": after, "$lte": after.Add(window)},
+	}
+	return r.findOne(ctx, filter, -1)
+}
+
+// findOne retrieves a single telemetry record sorted by timestamp
+func (r *TelemetryRepository) findOne(ctx context.Context, filter bson.M, order int) (*models.Telemetry, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: order}})
+
+	var telemetry models.Telemetry
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&telemetry)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("no telemetry found for device")
+		}
+		return nil, err
+	}
+
+	return &telemetry, nil
+}
+
 // FindLatestMetricsByDevice retrieves latest metrics for multiple devices
 func (r *TelemetryRepository) FindLatestMetricsByDevice(ctx context.Context, deviceIDs []string) (map[string]*models.Telemetry, error) {
 	if len(deviceIDs) == 0 {
@@ -162,3 +199,295 @@ func (r *TelemetryRepository) FindLatestMetricsByDevice(ctx context.Context, dev
 
 	return result, nil
 }
+
+// MetricNames lists the metrics a device reported between from and to, in name order
+func (r *TelemetryRepository) MetricNames(ctx context.Context, deviceID string, from, to time.Time) ([]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"device_id": deviceID, "timestamp": bson.M{"$gte": from, "$lte": to}}}},
+		{{Key: "$project", Value: bson.M{"metrics": bson.M{"$objectToArray": "$metrics"}}}},
+		{{Key: "$unwind", Value: "$metrics"}},
+		{{Key: "$group", Value: bson.M{"_id": "$metrics.k"}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	names := []string{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID string `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		names = append(names, doc.ID)
+	}
+
+	return names, cursor.Err()
+}
+
+// StreamExport iterates over a device's telemetry in timestamp order without loading it into
+// memory, calling fn for each row. Downsampled exports average each metric per time bucket in
+// MongoDB. The cursor fetches the next batch only once fn has consumed the current one, so a
+// slow consumer slows down the reads. Iteration stops at the first error.
+func (r *TelemetryRepository) StreamExport(ctx context.Context, export *models.TelemetryExport, batchSize int32, fn func(*models.TelemetryExportRow) error) error {
+	match := bson.M{"device_id": export.DeviceID, "timestamp": bson.M{"$gte": export.From, "$lte": export.To}}
+
+	if !export.Downsampled() {
+		findOptions := options.Find().
+			SetBatchSize(batchSize).
+			SetSort(bson.D{{Key: "timestamp", Value: 1}}).
+			SetProjection(bson.M{"timestamp": 1, "metrics": 1})
+
+		cursor, err := r.collection.Find(ctx, match, findOptions)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var telemetry models.Telemetry
+			if err := cursor.Decode(&telemetry); err != nil {
+				return err
+			}
+			if err := fn(&models.TelemetryExportRow{Timestamp: telemetry.Timestamp, Metrics: telemetry.Metrics}); err != nil {
+				return err
+			}
+		}
+		return cursor.Err()
+	}
+
+	group := bson.M{
+		"_id":     bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": string(export.Resolution)}},
+		"samples": bson.M{"$sum": 1},
+	}
+	for i, metric := range export.Metrics {
+		group[fmt.Sprintf("v%d", i)] = bson.M{"$avg": "$metrics." + metric}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: group}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true).SetBatchSize(batchSize))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			Period  time.Time `bson:"_id"`
+			Samples int64     `bson:"samples"`
+			Rest    bson.M    `bson:",inline"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		row := &models.TelemetryExportRow{
+			Timestamp: doc.Period,
+			Metrics:   make(map[string]interface{}, len(export.Metrics)),
+			Samples:   doc.Samples,
+		}
+		for i, metric := range export.Metrics {
+			if value, ok := numericValue(doc.Rest[fmt.Sprintf("v%d", i)]); ok {
+				row.Metrics[metric] = value
+			}
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// maxTelemetryBuckets caps the number of groups returned by an aggregation query
+const maxTelemetryBuckets = 10000
+
+// Aggregate runs an aggregation query over telemetry, grouping and reducing metrics in MongoDB.
+// Building and location filters, building and location grouping and tenant scopes join each
+// reading with its device's location.
+func (r *TelemetryRepository) Aggregate(ctx context.Context, query *models.TelemetryQuery) ([]*models.TelemetryBucket, error) {
+	match := bson.M{"timestamp": bson.M{"$gte": query.From, "$lte": query.To}}
+	if len(query.DeviceIDs) > 0 {
+		match["device_id"] = bson.M{"$in": query.DeviceIDs}
+	}
+	metricFilters := make(bson.A, len(query.Metrics))
+	for i, metric := range query.Metrics {
+		metricFilters[i] = bson.M{"metrics." + metric: bson.M{"$type": "number"}}
+	}
+	match["$or"] = metricFilters
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+
+	// Requests scoped to an organization only see the readings of its buildings' devices
+	scoped := tenant.FromContext(ctx) != nil
+	if query.BuildingID != "" || query.LocationID != "" || query.HasGroup(models.TelemetryGroupByBuilding) || query.GroupsByLocation() || scoped {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$lookup", Value: bson.M{
+				"from":         "devices",
+				"localField":   "device_id",
+				"foreignField": "device_id",
+				"as":           "device",
+			}}},
+			bson.D{{Key: "$unwind", Value: "$device"}},
+		)
+		buildingMatch := bson.M{}
+		if query.BuildingID != "" {
+			buildingMatch["device.location.building_id"] = query.BuildingID
+		}
+		if query.LocationID != "" {
+			buildingMatch["device.location.hierarchy.path"] = query.LocationID
+		}
+		buildingMatch = tenant.BuildingFilter(ctx, buildingMatch, "device.location.building_id")
+		if len(buildingMatch) > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$match", Value: buildingMatch}})
+		}
+	}
+
+	groupID := bson.M{}
+	if query.HasGroup(models.TelemetryGroupByDevice) {
+		groupID["device"] = "$device_id"
+	}
+	if query.HasGroup(models.TelemetryGroupByBuilding) {
+		groupID["building"] = "$device.location.building_id"
+	}
+	if query.HasGroup(models.TelemetryGroupByFloor) {
+		groupID["floor"] = "$device.location.hierarchy.floor_id"
+	}
+	if query.HasGroup(models.TelemetryGroupByZone) {
+		groupID["zone"] = "$device.location.hierarchy.zone_id"
+	}
+	if query.HasGroup(models.TelemetryGroupByRoom) {
+		groupID["room"] = "$device.location.hierarchy.room_id"
+	}
+	if query.HasGroup(models.TelemetryGroupByHour) {
+		groupID["period"] = bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": "hour"}}
+	} else if query.HasGroup(models.TelemetryGroupByDay) {
+		groupID["period"] = bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": "day"}}
+	}
+
+	group := bson.M{"_id": groupID, "samples": bson.M{"$sum": 1}}
+	for i, metric := range query.Metrics {
+		group[fmt.Sprintf("v%d", i)] = aggregationExpression(query, "$metrics."+metric)
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: group}},
+		bson.D{{Key: "$sort", Value: bson.D{
+			{Key: "_id.period", Value: 1}, {Key: "_id.building", Value: 1}, {Key: "_id.floor", Value: 1},
+			{Key: "_id.zone", Value: 1}, {Key: "_id.room", Value: 1}, {Key: "_id.device", Value: 1},
+		}}},
+		bson.D{{Key: "$limit", Value: maxTelemetryBuckets}},
+	)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	buckets := []*models.TelemetryBucket{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID struct {
+				Device   string     `bson:"device"`
+				Building string     `bson:"building"`
+				Floor    string     `bson:"floor"`
+				Zone     string     `bson:"zone"`
+				Room     string     `bson:"room"`
+				Period   *time.Time `bson:"period"`
+			} `bson:"_id"`
+			Samples int64  `bson:"samples"`
+			Rest    bson.M `bson:",inline"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+
+		bucket := &models.TelemetryBucket{
+			DeviceID:   doc.ID.Device,
+			BuildingID: doc.ID.Building,
+			FloorID:    doc.ID.Floor,
+			ZoneID:     doc.ID.Zone,
+			RoomID:     doc.ID.Room,
+			Period:     doc.ID.Period,
+			Values:     make(map[string]float64, len(query.Metrics)),
+			Samples:    doc.Samples,
+		}
+		for i, metric := range query.Metrics {
+			if value, ok := numericValue(doc.Rest[fmt.Sprintf("v%d", i)]); ok {
+				bucket.Values[metric] = value
+			}
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, cursor.Err()
+}
+
+// aggregationExpression builds the $group accumulator for a metric field
+func aggregationExpression(query *models.TelemetryQuery, field string) bson.M {
+	switch query.Aggregation {
+	case models.TelemetryAggregationSum:
+		return bson.M{"$sum": field}
+	case models.TelemetryAggregationMin:
+		return bson.M{"$min": field}
+	case models.TelemetryAggregationMax:
+		return bson.M{"$max": field}
+	case models.TelemetryAggregationCount:
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$isNumber": field}, 1, 0}}}
+	case models.TelemetryAggregationPercentile:
+		return bson.M{"$percentile": bson.M{
+			"input":  field,
+			"p":      bson.A{query.Percentile / 100},
+			"method": "approximate",
+		}}
+	}
+	return bson.M{"$avg": field}
+}
+
+// numericValue converts an aggregated value to a float, unwrapping single-element percentile arrays
+func numericValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case bson.A:
+		if len(v) == 1 {
+			return numericValue(v[0])
+		}
+	}
+	return 0, false
+}
+
+// CountOlderThan counts telemetry records recorded before the given time
+func (r *TelemetryRepository) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"timestamp": bson.M{"$lt": before},
+	})
+}
+
+// DeleteOlderThan removes telemetry records recorded before the given time
+func (r *TelemetryRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{
+		"timestamp": bson.M{"$lt": before},
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
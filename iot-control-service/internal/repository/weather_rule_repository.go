@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/tenant"
+)
+
+// WeatherRuleRepository handles weather rule database operations
+type WeatherRuleRepository struct {
+	collection *mongo.Collection
+}
+
+// NewWeatherRuleRepository creates a new weather rule repository
+func NewWeatherRuleRepository(collection *mongo.Collection) *WeatherRuleRepository {
+	return &WeatherRuleRepository{collection: collection}
+}
+
+// Create inserts a new weather rule
+func (r *WeatherRuleRepository) Create(ctx context.Context, rule *models.WeatherRule) (*models.WeatherRule, error) {
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, rule)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("weather rule with this ID already exists")
+		}
+		return nil, err
+	}
+
+	rule.ID = result.InsertedID.(primitive.ObjectID)
+	return rule, nil
+}
+
+// FindByRuleID retrieves a weather rule by its rule_id field
+func (r *WeatherRuleRepository) FindByRuleID(ctx context.Context, ruleID string) (*models.WeatherRule, error) {
+	var rule models.WeatherRule
+	err := r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"rule_id": ruleID}, "building_id")).Decode(&rule)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("weather rule not found")
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// FindAll retrieves weather rules, optionally of one building, with pagination
+func (r *WeatherRuleRepository) FindAll(ctx context.Context, buildingID string, page, limit int) ([]*models.WeatherRule, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{}
+	if buildingID != "" {
+		filter["building_id"] = buildingID
+	}
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	rules := []*models.WeatherRule{}
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, 0, err
+	}
+	return rules, total, nil
+}
+
+// Replace stores the editable fields of a weather rule and returns the updated document
+func (r *WeatherRuleRepository) Replace(ctx context.Context, rule *models.WeatherRule) (*models.WeatherRule, error) {
+	set := bson.M{
+		"name":            rule.Name,
+		"description":     rule.Description,
+		"building_id":     rule.BuildingID,
+		"condition":       rule.Condition,
+		"cron":            rule.Cron,
+		"actions":         rule.Actions,
+		"conflict_policy": rule.ConflictPolicy,
+		"enabled":         rule.Enabled,
+		"updated_at":      time.Now(),
+	}
+	update := bson.M{"$set": set}
+	if rule.NextRunAt != nil {
+		set["next_run_at"] = *rule.NextRunAt
+	} else {
+		update["$unset"] = bson.M{"next_run_at": ""}
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		tenant.BuildingFilter(ctx, bson.M{"rule_id": rule.RuleID}, "building_id"),
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var updated models.WeatherRule
+	if err := result.Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("weather rule not found")
+		}
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Delete removes a weather rule
+func (r *WeatherRuleRepository) Delete(ctx context.Context, ruleID string) error {
+	result, err := r.collection.DeleteOne(ctx, tenant.BuildingFilter(ctx, bson.M{"rule_id": ruleID}, "building_id"))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("weather rule not found")
+	}
+	return nil
+}
+
+// FindDue retrieves enabled rules whose next evaluation is at or before now, earliest first
+func (r *WeatherRuleRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]*models.WeatherRule, error) {
+	findOptions := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "next_run_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"enabled":     true,
+		"next_run_at": bson.M{"$lte": now},
+	}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rules []*models.WeatherRule
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ClaimRun advances a due rule to its next evaluation. The update only applies while the rule
+// is still enabled and due at runAt, so concurrent workers evaluate each run once; the returned
+// bool reports whether this caller won.
+func (r *WeatherRuleRepository) ClaimRun(ctx context.Context, ruleID string, runAt, next time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx, bson.M{
+		"rule_id":     ruleID,
+		"enabled":     true,
+		"next_run_at": runAt,
+	}, bson.M{"$set": bson.M{"next_run_at": next, "updated_at": time.Now()}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// RecordEvaluation stores when a rule was last evaluated and, if its condition was met, triggered
+func (r *WeatherRuleRepository) RecordEvaluation(ctx context.Context, ruleID string, evaluatedAt time.Time, triggered bool) error {
+	set := bson.M{"last_evaluated_at": evaluatedAt}
+	if triggered {
+		set["last_triggered_at"] = evaluatedAt
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"rule_id": ruleID}, bson.M{"$set": set})
+	return err
+}
+
+// WeatherRuleExecutionRepository handles the execution log of weather rules
+type WeatherRuleExecutionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewWeatherRuleExecutionRepository creates a new weather rule execution repository
+func NewWeatherRuleExecutionRepository(collection *mongo.Collection) *WeatherRuleExecutionRepository {
+	return &WeatherRuleExecutionRepository{collection: collection}
+}
+
+// Create inserts an execution log entry
+func (r *WeatherRuleExecutionRepository) Create(ctx context.Context, execution *models.WeatherRuleExecution) (*models.WeatherRuleExecution, error) {
+	result, err := r.collection.InsertOne(ctx, execution)
+	if err != nil {
+		return nil, err
+	}
+
+	execution.ID = result.InsertedID.(primitive.ObjectID)
+	return execution, nil
+}
+
+// FindByRuleID retrieves the executions of a rule, newest first, with pagination
+func (r *WeatherRuleExecutionRepository) FindByRuleID(ctx context.Context, ruleID string, page, limit int) ([]*models.WeatherRuleExecution, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{"rule_id": ruleID}
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "evaluated_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	executions := []*models.WeatherRuleExecution{}
+	if err := cursor.All(ctx, &executions); err != nil {
+		return nil, 0, err
+	}
+	return executions, total, nil
+}
+
+// DeleteByRuleID removes the execution log of a deleted rule
+func (r *WeatherRuleExecutionRepository) DeleteByRuleID(ctx context.Context, ruleID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"rule_id": ruleID})
+	return err
+}
@@ -3,84 +3,193 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
+	"math"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 
+	"iot-control-service/internal/events"
 	"iot-control-service/internal/models"
 	"iot-control-service/internal/mqtt"
 	"iot-control-service/internal/repository"
+	"iot-control-service/internal/tenant"
 )
 
 // ControlService handles device control business logic
 type ControlService struct {
-	commandRepo *repository.CommandRepository
-	deviceRepo  *repository.DeviceRepository
-	mqttClient  *mqtt.Client
-	config      interface {
+	commandRepo      *repository.CommandRepository
+	deviceRepo       *repository.DeviceRepository
+	deviceTypeRepo   *repository.DeviceTypeRepository
+	telemetryRepo    *repository.TelemetryRepository
+	optimizationRepo *repository.OptimizationRepository
+	mqttClient       *mqtt.Client
+	eventBus         *events.Bus
+	config           interface {
 		GetCommandTimeout() time.Duration
+		GetCommandRetries() int
+		GetCommandTTL() time.Duration
+		GetApprovalTimeout() time.Duration
 	}
 }
 
+// maxCommandTTL caps how long a command may remain deliverable
+const maxCommandTTL = 24 * time.Hour
+
+// commandTimeoutBatchSize caps how many commands one timeout run moves to TIMEOUT
+const commandTimeoutBatchSize = 500
+
+// Defaults for correlating commands with telemetry in the command history
+const (
+	defaultHistoryWindow    = 15 * time.Minute
+	maxHistoryWindow        = 24 * time.Hour
+	historyLookback         = time.Hour
+	defaultHistoryTolerance = 0.5
+)
+
 // NewControlService creates a new control service
 func NewControlService(
 	commandRepo *repository.CommandRepository,
 	deviceRepo *repository.DeviceRepository,
+	deviceTypeRepo *repository.DeviceTypeRepository,
+	telemetryRepo *repository.TelemetryRepository,
+	optimizationRepo *repository.OptimizationRepository,
 	mqttClient *mqtt.Client,
+	eventBus *events.Bus,
 	commandTimeout time.Duration,
+	commandRetries int,
+	commandTTL time.Duration,
+	approvalTimeout time.Duration,
 ) *ControlService {
 	return &ControlService{
-		commandRepo: commandRepo,
-		deviceRepo:  deviceRepo,
-		mqttClient:  mqttClient,
-		config:      &configWrapper{timeout: commandTimeout},
+		commandRepo:      commandRepo,
+		deviceRepo:       deviceRepo,
+		deviceTypeRepo:   deviceTypeRepo,
+		telemetryRepo:    telemetryRepo,
+		optimizationRepo: optimizationRepo,
+		mqttClient:       mqttClient,
+		eventBus:         eventBus,
+		config:           &configWrapper{timeout: commandTimeout, retries: commandRetries, ttl: commandTTL, approval: approvalTimeout},
 	}
 }
 
 type configWrapper struct {
-	timeout time.Duration
+	timeout  time.Duration
+	retries  int
+	ttl      time.Duration
+	approval time.Duration
 }
 
 func (c *configWrapper) GetCommandTimeout() time.Duration {
 	return c.timeout
 }
 
-// SendCommand sends a command to a device
-func (s *ControlService) SendCommand(ctx context.Context, deviceID string, req *models.SendCommandRequest, userID string) (*models.CommandResponse, error) {
+func (c *configWrapper) GetCommandRetries() int {
+	return c.retries
+}
+
+func (c *configWrapper) GetCommandTTL() time.Duration {
+	return c.ttl
+}
+
+func (c *configWrapper) GetApprovalTimeout() time.Duration {
+	return c.approval
+}
+
+// SendCommand sends a command to a device.
+// When an idempotency key is supplied, a retry with the same key returns the original
+// command instead of creating a duplicate; the returned bool reports such a replay.
+// Commands the device type marks as high-impact are not sent but wait in PENDING_APPROVAL
+// until another user approves them.
+func (s *ControlService) SendCommand(ctx context.Context, deviceID string, req *models.SendCommandRequest, userID, idempotencyKey string) (*models.CommandResponse, bool, error) {
 	// Validate device exists
-	_, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
+	device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
 	if err != nil {
-		return nil, fmt.Errorf("device not found: %w", err)
+		return nil, false, fmt.Errorf("device not found: %w", err)
 	}
 
 	// Validate command
 	if err := s.validateCommand(req); err != nil {
-		return nil, fmt.Errorf("validation failed: %w", err)
+		return nil, false, fmt.Errorf("validation failed: %w", err)
+	}
+
+	// Return the original command for retried requests
+	if idempotencyKey != "" {
+		existing, err := s.commandRepo.FindByIdempotencyKey(ctx, deviceID, idempotencyKey)
+		if err == nil {
+			return s.replayCommand(existing, req)
+		}
+	}
+
+	deviceType, err := s.deviceTypeRepo.FindByName(ctx, device.Type)
+	if err != nil {
+		if err.Error() != "device type not found" {
+			return nil, false, fmt.Errorf("failed to load device type: %w", err)
+		}
+	}
+
+	// Protect the device from rapid repeated commands unless an admin overrides the limit
+	if !req.OverrideRateLimit {
+		if err := s.checkRateLimit(ctx, device, deviceType, req.Command); err != nil {
+			// The command counted against the limit may be a concurrent retry's
+			if response, replayed, replayErr := s.concurrentReplay(ctx, deviceID, idempotencyKey, req); response != nil || replayErr != nil {
+				return response, replayed, replayErr
+			}
+			return nil, false, err
+		}
 	}
 
 	// Generate command ID
 	commandID := uuid.New().String()
 
+	ttl := s.config.GetCommandTTL()
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	expiresAt := time.Now().Add(ttl)
+
 	// Create command record
 	command := &models.DeviceCommand{
-		CommandID: commandID,
-		DeviceID:  deviceID,
-		Command:   req.Command,
-		Params:    req.Params,
-		Status:    models.CommandStatusPending,
-		IssuedBy:  userID,
+		CommandID:      commandID,
+		DeviceID:       deviceID,
+		BuildingID:     device.Location.BuildingID,
+		Command:        req.Command,
+		Params:         req.Params,
+		Status:         models.CommandStatusPending,
+		IssuedBy:       userID,
+		IdempotencyKey: idempotencyKey,
+		ExpiresAt:      &expiresAt,
+	}
+
+	// High-impact commands wait for approval; their delivery TTL starts once approved
+	requiresApproval := deviceType != nil && deviceType.RequiresApproval(req.Command)
+	if requiresApproval {
+		approvalExpiresAt := time.Now().Add(s.config.GetApprovalTimeout())
+		command.Status = models.CommandStatusPendingApproval
+		command.TTLSeconds = int(ttl / time.Second)
+		command.ExpiresAt = &approvalExpiresAt
 	}
 
 	createdCommand, err := s.commandRepo.Create(ctx, command)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create command: %w", err)
+		// A concurrent retry with the same key may have won the insert
+		if response, replayed, replayErr := s.concurrentReplay(ctx, deviceID, idempotencyKey, req); response != nil || replayErr != nil {
+			return response, replayed, replayErr
+		}
+		return nil, false, fmt.Errorf("failed to create command: %w", err)
+	}
+
+	if requiresApproval {
+		s.publishApprovalRequested(ctx, device, createdCommand)
+		return createdCommand.ToResponse(), false, nil
 	}
 
 	// Publish command to MQTT
-	if err := s.mqttClient.PublishCommand(deviceID, createdCommand); err != nil {
+	if err := s.mqttClient.PublishCommand(ctx, device.Location.BuildingID, deviceID, createdCommand); err != nil {
 		// Update command status to failed
 		s.commandRepo.UpdateStatus(ctx, commandID, models.CommandStatusFailed, fmt.Sprintf("MQTT publish failed: %v", err))
-		return nil, fmt.Errorf("failed to publish command: %w", err)
+		return nil, false, fmt.Errorf("failed to publish command: %w", err)
 	}
 
 	// Update command status to sent
@@ -89,10 +198,180 @@ func (s *ControlService) SendCommand(ctx context.Context, deviceID string, req *
 	// Refresh command from DB
 	updatedCommand, err := s.commandRepo.FindByCommandID(ctx, commandID)
 	if err != nil {
-		return createdCommand.ToResponse(), nil
+		return createdCommand.ToResponse(), false, nil
+	}
+
+	return updatedCommand.ToResponse(), false, nil
+}
+
+// replayCommand returns a previously accepted command for a retried request. A key reused for
+// another command, or the same command with other parameters, is a conflict.
+func (s *ControlService) replayCommand(existing *models.DeviceCommand, req *models.SendCommandRequest) (*models.CommandResponse, bool, error) {
+	if !existing.MatchesRequest(req) {
+		return nil, false, fmt.Errorf("idempotency key already used for a different command")
+	}
+	return existing.ToResponse(), true, nil
+}
+
+// concurrentReplay replays the command a concurrent request with the same idempotency key
+// created after this request looked for it. Both the response and the error are nil when there
+// is no such command.
+func (s *ControlService) concurrentReplay(ctx context.Context, deviceID, idempotencyKey string, req *models.SendCommandRequest) (*models.CommandResponse, bool, error) {
+	if idempotencyKey == "" {
+		return nil, false, nil
+	}
+	existing, err := s.commandRepo.FindByIdempotencyKey(ctx, deviceID, idempotencyKey)
+	if err != nil {
+		return nil, false, nil
+	}
+	return s.replayCommand(existing, req)
+}
+
+// checkRateLimit enforces the command rate limit of the device's type: at most MaxCommands
+// commands per window, and a minimum interval between state change commands. Devices of types
+// missing from the catalog are not limited.
+func (s *ControlService) checkRateLimit(ctx context.Context, device *models.Device, deviceType *models.DeviceType, command string) error {
+	if deviceType == nil {
+		return nil
+	}
+	limit := deviceType.RateLimit
+	if limit == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if limit.MaxCommands > 0 && limit.WindowSeconds > 0 {
+		window := time.Duration(limit.WindowSeconds) * time.Second
+		count, err := s.commandRepo.CountSince(ctx, device.DeviceID, now.Add(-window))
+		if err != nil {
+			return fmt.Errorf("failed to check command rate limit: %w", err)
+		}
+		if count >= int64(limit.MaxCommands) {
+			return fmt.Errorf("rate limit exceeded: %s devices accept at most %d commands per %d seconds",
+				deviceType.Name, limit.MaxCommands, limit.WindowSeconds)
+		}
+	}
+
+	if limit.MinStateChangeSeconds > 0 && models.IsStateChangeCommand(command) {
+		interval := time.Duration(limit.MinStateChangeSeconds) * time.Second
+		last, err := s.commandRepo.FindLatestSince(ctx, device.DeviceID, models.StateChangeCommands, now.Add(-interval))
+		if err == nil {
+			retryIn := int(math.Ceil(last.CreatedAt.Add(interval).Sub(now).Seconds()))
+			return fmt.Errorf("rate limit exceeded: %s devices must wait %d seconds between state changes; retry in %d seconds",
+				deviceType.Name, limit.MinStateChangeSeconds, retryIn)
+		}
+		if err.Error() != "command not found" {
+			return fmt.Errorf("failed to check command rate limit: %w", err)
+		}
 	}
 
-	return updatedCommand.ToResponse(), nil
+	return nil
+}
+
+// publishApprovalRequested announces a command awaiting approval so approvers are notified
+func (s *ControlService) publishApprovalRequested(ctx context.Context, device *models.Device, command *models.DeviceCommand) {
+	if s.eventBus == nil {
+		return
+	}
+
+	s.eventBus.Publish(ctx, events.CommandApprovalRequested, &events.CommandApprovalRequestedData{
+		CommandID:   command.CommandID,
+		DeviceID:    command.DeviceID,
+		BuildingID:  device.Location.BuildingID,
+		DeviceType:  device.Type,
+		Command:     command.Command,
+		Params:      command.Params,
+		RequestedBy: command.IssuedBy,
+		RequestedAt: command.CreatedAt,
+		ExpiresAt:   *command.ExpiresAt,
+	})
+}
+
+// ApproveCommand approves a command awaiting approval and publishes it to the device. The user
+// who issued the command cannot approve it. Its delivery TTL starts at the approval.
+func (s *ControlService) ApproveCommand(ctx context.Context, commandID, approverID, comment string) (*models.CommandResponse, error) {
+	command, err := s.loadForReview(ctx, commandID, approverID)
+	if err != nil {
+		return nil, err
+	}
+
+	device, err := s.deviceRepo.FindByDeviceID(ctx, command.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("device not found: %w", err)
+	}
+
+	ttl := time.Duration(command.TTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = s.config.GetCommandTTL()
+	}
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	approved, err := s.commandRepo.Review(ctx, commandID, models.CommandStatusSent, approverID, comment, &expiresAt, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mqttClient.PublishCommand(ctx, device.Location.BuildingID, approved.DeviceID, approved); err != nil {
+		s.commandRepo.UpdateStatus(ctx, commandID, models.CommandStatusFailed, fmt.Sprintf("MQTT publish failed: %v", err))
+		return nil, fmt.Errorf("failed to publish command: %w", err)
+	}
+
+	return approved.ToResponse(), nil
+}
+
+// RejectCommand rejects a command awaiting approval so it is never sent
+func (s *ControlService) RejectCommand(ctx context.Context, commandID, reviewerID, comment string) (*models.CommandResponse, error) {
+	if _, err := s.loadForReview(ctx, commandID, reviewerID); err != nil {
+		return nil, err
+	}
+
+	rejected, err := s.commandRepo.Review(ctx, commandID, models.CommandStatusRejected, reviewerID, comment, nil, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return rejected.ToResponse(), nil
+}
+
+// ListPendingApproval lists the commands awaiting approval that have not expired, limited to
+// the devices of the caller's organization
+func (s *ControlService) ListPendingApproval(ctx context.Context, page, limit int) ([]*models.CommandResponse, int64, error) {
+	var deviceIDs []string
+	if tenant.FromContext(ctx) != nil {
+		var err error
+		if deviceIDs, err = s.deviceRepo.FindDeviceIDs(ctx); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	commands, total, err := s.commandRepo.FindPendingApproval(ctx, time.Now(), deviceIDs, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]*models.CommandResponse, len(commands))
+	for i, command := range commands {
+		responses[i] = command.ToResponse()
+	}
+	return responses, total, nil
+}
+
+// loadForReview loads a command a user is about to approve or reject
+func (s *ControlService) loadForReview(ctx context.Context, commandID, reviewerID string) (*models.DeviceCommand, error) {
+	command, err := s.commandRepo.FindByCommandID(ctx, commandID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDeviceAccess(ctx, s.deviceRepo, command.DeviceID); err != nil {
+		return nil, fmt.Errorf("command not found")
+	}
+	if command.Status != models.CommandStatusPendingApproval || command.IsExpired(time.Now()) {
+		return nil, fmt.Errorf("command is not awaiting approval")
+	}
+	if command.IssuedBy != "" && command.IssuedBy == reviewerID {
+		return nil, fmt.Errorf("validation failed: a command cannot be reviewed by the user who issued it")
+	}
+	return command, nil
 }
 
 // GetCommand retrieves a command by ID
@@ -101,11 +380,18 @@ func (s *ControlService) GetCommand(ctx context.Context, commandID string) (*mod
 	if err != nil {
 		return nil, err
 	}
+	if err := checkDeviceAccess(ctx, s.deviceRepo, command.DeviceID); err != nil {
+		return nil, fmt.Errorf("command not found")
+	}
 	return command.ToResponse(), nil
 }
 
 // ListCommands lists commands for a device
 func (s *ControlService) ListCommands(ctx context.Context, deviceID string, status string, page, limit int) ([]*models.CommandResponse, int64, error) {
+	if err := checkDeviceAccess(ctx, s.deviceRepo, deviceID); err != nil {
+		return nil, 0, err
+	}
+
 	commands, total, err := s.commandRepo.FindByDeviceID(ctx, deviceID, status, page, limit)
 	if err != nil {
 		return nil, 0, err
@@ -119,19 +405,361 @@ func (s *ControlService) ListCommands(ctx context.Context, deviceID string, stat
 	return responses, total, nil
 }
 
+// GetCommandHistory lists a device's commands joined with the telemetry reported before and after each,
+// flagging commands whose requested parameters were never reflected in the device's state
+func (s *ControlService) GetCommandHistory(ctx context.Context, deviceID string, req *models.CommandHistoryRequest) ([]*models.CommandHistoryEntry, int64, error) {
+	if _, err := s.deviceRepo.FindByDeviceID(ctx, deviceID); err != nil {
+		return nil, 0, fmt.Errorf("device not found: %w", err)
+	}
+
+	window := defaultHistoryWindow
+	if req.WindowMinutes > 0 {
+		window = time.Duration(req.WindowMinutes) * time.Minute
+	}
+	if window > maxHistoryWindow {
+		window = maxHistoryWindow
+	}
+	tolerance := defaultHistoryTolerance
+	if req.Tolerance > 0 {
+		tolerance = req.Tolerance
+	}
+
+	commands, total, err := s.commandRepo.FindByDeviceID(ctx, deviceID, "", req.Page, req.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	entries := make([]*models.CommandHistoryEntry, len(commands))
+	for i, command := range commands {
+		entry := &models.CommandHistoryEntry{
+			Command:     command.ToResponse(),
+			Diff:        []models.MetricChange{},
+			ParamChecks: []models.ParamCheck{},
+		}
+
+		if before, err := s.telemetryRepo.FindSnapshotBefore(ctx, deviceID, command.CreatedAt, historyLookback); err == nil {
+			entry.Before = &models.TelemetrySnapshot{Timestamp: before.Timestamp, Metrics: before.Metrics}
+		}
+
+		effectiveAt := command.CreatedAt
+		if command.AppliedAt != nil {
+			effectiveAt = *command.AppliedAt
+		} else if command.SentAt != nil {
+			effectiveAt = *command.SentAt
+		}
+		if after, err := s.telemetryRepo.FindSnapshotAfter(ctx, deviceID, effectiveAt, window); err == nil {
+			entry.After = &models.TelemetrySnapshot{Timestamp: after.Timestamp, Metrics: after.Metrics}
+		}
+
+		if entry.Before != nil && entry.After != nil {
+			entry.Diff = diffMetrics(entry.Before.Metrics, entry.After.Metrics)
+		}
+
+		switch command.Status {
+		case models.CommandStatusFailed, models.CommandStatusExpired, models.CommandStatusCancelled:
+			entry.Verification = models.CommandVerificationNotDelivered
+		default:
+			entry.Verification = verifyCommand(entry, command, tolerance, now.Before(effectiveAt.Add(window)))
+		}
+		entry.Flagged = entry.Verification == models.CommandVerificationNotMaterialized
+
+		entries[i] = entry
+	}
+
+	return entries, total, nil
+}
+
+// verifyCommand checks the command's parameters against the state observed after it
+func verifyCommand(entry *models.CommandHistoryEntry, command *models.DeviceCommand, tolerance float64, windowOpen bool) models.CommandVerification {
+	if entry.After == nil {
+		if windowOpen {
+			return models.CommandVerificationPending
+		}
+		return models.CommandVerificationUnverifiable
+	}
+
+	allMatched := true
+	for param, requested := range command.Params {
+		observed, ok := entry.After.Metrics[param]
+		if !ok {
+			continue
+		}
+		check := models.ParamCheck{
+			Param:     param,
+			Requested: requested,
+			Observed:  observed,
+			Matched:   valuesMatch(requested, observed, tolerance),
+		}
+		if !check.Matched {
+			allMatched = false
+		}
+		entry.ParamChecks = append(entry.ParamChecks, check)
+	}
+
+	switch {
+	case len(entry.ParamChecks) == 0:
+		return models.CommandVerificationUnverifiable
+	case allMatched:
+		return models.CommandVerificationMaterialized
+	case windowOpen:
+		return models.CommandVerificationPending
+	}
+	return models.CommandVerificationNotMaterialized
+}
+
+// diffMetrics lists metrics that were added, removed or changed between two snapshots
+func diffMetrics(before, after map[string]interface{}) []models.MetricChange {
+	changes := []models.MetricChange{}
+	for metric, afterValue := range after {
+		beforeValue, ok := before[metric]
+		if !ok || !valuesMatch(beforeValue, afterValue, 0) {
+			changes = append(changes, models.MetricChange{Metric: metric, Before: beforeValue, After: afterValue})
+		}
+	}
+	for metric, beforeValue := range before {
+		if _, ok := after[metric]; !ok {
+			changes = append(changes, models.MetricChange{Metric: metric, Before: beforeValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Metric < changes[j].Metric })
+	return changes
+}
+
+// valuesMatch compares two telemetry values, numerically within a tolerance when both are numbers
+func valuesMatch(a, b interface{}, tolerance float64) bool {
+	af, aNumeric := toFloat(a)
+	bf, bNumeric := toFloat(b)
+	if aNumeric && bNumeric {
+		return math.Abs(af-bf) <= tolerance
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// toFloat converts numeric telemetry values decoded from JSON or BSON to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
 // ProcessCommandAck processes a command acknowledgment from a device
 func (s *ControlService) ProcessCommandAck(ctx context.Context, ack *models.CommandAck) error {
-	_, err := s.commandRepo.FindByCommandID(ctx, ack.CommandID)
+	command, err := s.commandRepo.FindByCommandID(ctx, ack.CommandID)
 	if err != nil {
 		return fmt.Errorf("command not found: %w", err)
 	}
 
+	// Late acks must not revive commands that already expired
+	if command.Status == models.CommandStatusExpired {
+		return fmt.Errorf("command %s has expired", ack.CommandID)
+	}
+	// Commands that were never approved were never sent
+	if command.Status == models.CommandStatusPendingApproval || command.Status == models.CommandStatusRejected {
+		return fmt.Errorf("command %s was not sent", ack.CommandID)
+	}
+
 	status := models.CommandStatusApplied
 	if ack.Status == "FAILED" {
 		status = models.CommandStatusFailed
 	}
 
-	return s.commandRepo.UpdateStatus(ctx, ack.CommandID, status, ack.ErrorMsg)
+	// The command may have expired since it was read; the update only applies while it still
+	// awaits an acknowledgment
+	acknowledged, err := s.commandRepo.Acknowledge(ctx, ack.CommandID, status, ack.ErrorMsg)
+	if err != nil {
+		return err
+	}
+	if !acknowledged {
+		return fmt.Errorf("command %s is no longer awaiting an acknowledgment", ack.CommandID)
+	}
+
+	if status == models.CommandStatusApplied {
+		s.publishCommandApplied(ctx, command)
+	}
+	return nil
+}
+
+// publishCommandApplied announces an applied command to other services
+func (s *ControlService) publishCommandApplied(ctx context.Context, command *models.DeviceCommand) {
+	if s.eventBus == nil {
+		return
+	}
+
+	data := &events.CommandAppliedData{
+		CommandID: command.CommandID,
+		DeviceID:  command.DeviceID,
+		Command:   command.Command,
+		Params:    command.Params,
+		IssuedBy:  command.IssuedBy,
+		AppliedAt: time.Now(),
+	}
+	if device, err := s.deviceRepo.FindByDeviceID(ctx, command.DeviceID); err == nil {
+		data.BuildingID = device.Location.BuildingID
+	}
+
+	s.eventBus.Publish(ctx, events.CommandApplied, data)
+}
+
+// ExpireStaleCommands marks un-acknowledged commands past their TTL as expired
+func (s *ControlService) ExpireStaleCommands(ctx context.Context) (int64, error) {
+	return s.commandRepo.ExpireStale(ctx, time.Now())
+}
+
+// StartExpiryWorker periodically expires stale commands until the context is cancelled
+func (s *ControlService) StartExpiryWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.ExpireStaleCommands(ctx)
+			if err != nil {
+				log.Printf("Failed to expire stale commands: %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("Expired %d stale commands", expired)
+			}
+		}
+	}
+}
+
+// TimeOutCommands moves commands that devices did not acknowledge within the command timeout
+// to TIMEOUT and returns how many were timed out
+func (s *ControlService) TimeOutCommands(ctx context.Context) (int, error) {
+	now := time.Now()
+	commands, err := s.commandRepo.FindUnacknowledged(ctx, now.Add(-s.config.GetCommandTimeout()), commandTimeoutBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	timedOut := 0
+	for _, command := range commands {
+		ok, err := s.timeOutCommand(ctx, command, now)
+		if err != nil {
+			log.Printf("Failed to time out command %s: %v", command.CommandID, err)
+			continue
+		}
+		if ok {
+			timedOut++
+		}
+	}
+	return timedOut, nil
+}
+
+// StartTimeoutWorker periodically times out unacknowledged commands until the context is cancelled
+func (s *ControlService) StartTimeoutWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			timedOut, err := s.TimeOutCommands(ctx)
+			if err != nil {
+				log.Printf("Failed to time out commands: %v", err)
+				continue
+			}
+			if timedOut > 0 {
+				log.Printf("Timed out %d unacknowledged commands", timedOut)
+			}
+		}
+	}
+}
+
+// timeOutCommand moves a command to TIMEOUT, sends it again while retries remain and it has not
+// expired, and records the outcome on the optimization scenario action that sent it. It reports
+// false when the command was acknowledged or expired in the meantime.
+func (s *ControlService) timeOutCommand(ctx context.Context, command *models.DeviceCommand, now time.Time) (bool, error) {
+	ok, err := s.commandRepo.MarkTimedOut(ctx, command.CommandID, now)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	data := &events.CommandTimedOutData{
+		CommandID:  command.CommandID,
+		DeviceID:   command.DeviceID,
+		BuildingID: command.BuildingID,
+		Command:    command.Command,
+		IssuedBy:   command.IssuedBy,
+		TimedOutAt: now,
+	}
+
+	actionStatus, actionCommandID := string(models.CommandStatusTimeout), command.CommandID
+	if command.RetryCount < s.config.GetCommandRetries() && !command.IsExpired(now) {
+		retry, err := s.retryCommand(ctx, command)
+		if err != nil {
+			log.Printf("Failed to retry command %s: %v", command.CommandID, err)
+		} else {
+			data.RetryCommandID = retry.CommandID
+			actionStatus, actionCommandID = string(models.CommandStatusSent), retry.CommandID
+		}
+	}
+
+	scenario, err := s.optimizationRepo.UpdateActionStatusByCommandID(ctx, command.CommandID, actionStatus, actionCommandID)
+	if err == nil {
+		data.ScenarioID = scenario.ScenarioID
+		data.SourceScenarioID = scenario.SourceScenarioID
+		data.BuildingID = scenario.BuildingID
+	} else if err.Error() != "optimization scenario not found" {
+		log.Printf("Failed to record timeout of command %s on its scenario: %v", command.CommandID, err)
+	}
+
+	if s.eventBus != nil {
+		if data.BuildingID == "" {
+			if device, err := s.deviceRepo.FindByDeviceID(ctx, command.DeviceID); err == nil {
+				data.BuildingID = device.Location.BuildingID
+			}
+		}
+		s.eventBus.Publish(ctx, events.CommandTimedOut, data)
+	}
+	return true, nil
+}
+
+// retryCommand sends a timed-out command again as a new command that keeps its expiry
+func (s *ControlService) retryCommand(ctx context.Context, command *models.DeviceCommand) (*models.DeviceCommand, error) {
+	device, err := s.deviceRepo.FindByDeviceID(ctx, command.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("device not found: %w", err)
+	}
+
+	retry, err := s.commandRepo.Create(ctx, &models.DeviceCommand{
+		CommandID:  uuid.New().String(),
+		DeviceID:   command.DeviceID,
+		Command:    command.Command,
+		Params:     command.Params,
+		Status:     models.CommandStatusPending,
+		IssuedBy:   command.IssuedBy,
+		RetryOf:    command.CommandID,
+		RetryCount: command.RetryCount + 1,
+		ExpiresAt:  command.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create command: %w", err)
+	}
+
+	if err := s.mqttClient.PublishCommand(ctx, device.Location.BuildingID, retry.DeviceID, retry); err != nil {
+		s.commandRepo.UpdateStatus(ctx, retry.CommandID, models.CommandStatusFailed, fmt.Sprintf("MQTT publish failed: %v", err))
+		return nil, fmt.Errorf("failed to publish command: %w", err)
+	}
+	s.commandRepo.UpdateStatus(ctx, retry.CommandID, models.CommandStatusSent, "")
+	return retry, nil
 }
 
 // validateCommand validates a command request
@@ -139,5 +767,11 @@ func (s *ControlService) validateCommand(req *models.SendCommandRequest) error {
 	if req.Command == "" {
 		return fmt.Errorf("command is required")
 	}
+	if req.TTLSeconds < 0 {
+		return fmt.Errorf("ttlSeconds must not be negative")
+	}
+	if time.Duration(req.TTLSeconds)*time.Second > maxCommandTTL {
+		return fmt.Errorf("ttlSeconds must not exceed %d", int(maxCommandTTL.Seconds()))
+	}
 	return nil
 }
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed five-field cron expression (minute hour day-of-month month day-of-week).
+// Fields accept "*", single values, ranges ("1-5"), lists ("1,15") and steps ("*/10", "0-30/5").
+type CronSchedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	// Like standard cron, a day matches either field when both day fields are restricted
+	daysRestricted     bool
+	weekdaysRestricted bool
+}
+
+// BuildingLocator provides the time zone of a building, in which its cron schedules are evaluated
+type BuildingLocator interface {
+	Location(ctx context.Context, buildingID string) (*time.Location, error)
+}
+
+// buildingLocation returns the time zone of a building, or UTC without a locator
+func buildingLocation(ctx context.Context, locator BuildingLocator, buildingID string) (*time.Location, error) {
+	if locator == nil || buildingID == "" {
+		return time.UTC, nil
+	}
+	loc, err := locator.Location(ctx, buildingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time zone of building %s: %w", buildingID, err)
+	}
+	return loc, nil
+}
+
+// cronSearchLimit bounds how far ahead the next run is searched for, e.g. for "0 0 31 2 *"
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// ParseCron parses a five-field cron expression
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields, got %d", len(fields))
+	}
+
+	schedule := &CronSchedule{}
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if schedule.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day-of-month field: %w", err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if schedule.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day-of-week field: %w", err)
+	}
+	// Sunday may be written as 0 or 7
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	schedule.daysRestricted = fields[2] != "*"
+	schedule.weekdaysRestricted = fields[4] != "*"
+
+	return schedule, nil
+}
+
+// parseCronField parses one cron field into a bit set of allowed values
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = s
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			l, err1 := strconv.Atoi(bounds[0])
+			h, err2 := strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+			low, high = l, h
+		default:
+			v, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rangePart)
+			}
+			low, high = v, v
+			if strings.Contains(part, "/") {
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first matching minute strictly after the given time, with the fields matched
+// against the wall clock in loc (UTC when nil). A zero time means the expression never matches
+// within the search limit.
+//
+// On daylight saving time changes, wall clock minutes skipped when clocks go forward run at the
+// same offset into the new hour (02:30 becomes 03:30), and minutes repeated when clocks go back
+// run once, at their first occurrence.
+func (c *CronSchedule) Next(after time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	// Wall clock readings are stepped through in UTC, where every minute exists exactly once
+	wall := after.In(loc)
+	t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		if c.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if c.hours&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if c.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		run := wallClockTime(t, loc)
+		// A minute skipped by a clock change can land on or before a run that already happened
+		if !run.After(after) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return run
+	}
+	return time.Time{}
+}
+
+// matchesDay checks the day-of-month and day-of-week fields
+func (c *CronSchedule) matchesDay(t time.Time) bool {
+	dayMatch := c.days&(1<<uint(t.Day())) != 0
+	weekdayMatch := c.weekdays&(1<<uint(t.Weekday())) != 0
+	if c.daysRestricted && c.weekdaysRestricted {
+		return dayMatch || weekdayMatch
+	}
+	return dayMatch && weekdayMatch
+}
+
+// wallClockTime returns the instant at which the wall clock in loc shows the reading of wall,
+// which is given in UTC. A reading repeated when clocks go back is its first occurrence, and a
+// reading skipped when clocks go forward is shifted forward by the length of the gap. time.Date
+// leaves both choices unspecified.
+func wallClockTime(wall time.Time, loc *time.Location) time.Time {
+	_, offsetBefore := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, offsetAfter := wall.Add(24 * time.Hour).In(loc).Zone()
+	before := wall.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
+	after := wall.Add(-time.Duration(offsetAfter) * time.Second).In(loc)
+
+	beforeValid := sameWallClock(before, wall)
+	afterValid := sameWallClock(after, wall)
+	switch {
+	case beforeValid && afterValid:
+		if after.Before(before) {
+			return after
+		}
+		return before
+	case afterValid:
+		return after
+	default:
+		// Either the reading exists at the earlier offset, or it was skipped and the earlier
+		// offset carries it past the gap
+		return before
+	}
+}
+
+// sameWallClock reports whether t shows the date, hour and minute of wall
+func sameWallClock(t, wall time.Time) bool {
+	return t.Year() == wall.Year() && t.Month() == wall.Month() && t.Day() == wall.Day() &&
+		t.Hour() == wall.Hour() && t.Minute() == wall.Minute()
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+
+	"iot-control-service/internal/integrations"
+	"iot-control-service/internal/repository"
+)
+
+// DeactivationService releases what users deactivated in the Security service own in this service
+type DeactivationService struct {
+	scheduleRepo   *repository.ScheduledCommandRepository
+	securityClient *integrations.SecurityClient
+}
+
+// NewDeactivationService creates a new deactivation service
+func NewDeactivationService(scheduleRepo *repository.ScheduledCommandRepository, securityClient *integrations.SecurityClient) *DeactivationService {
+	return &DeactivationService{
+		scheduleRepo:   scheduleRepo,
+		securityClient: securityClient,
+	}
+}
+
+// ReleaseUser pauses the scheduled commands of a deactivated user, which would otherwise keep
+// sending commands on their behalf, and records the result in the audit log. Paused commands
+// can be reviewed and resumed by an operator.
+func (s *DeactivationService) ReleaseUser(ctx context.Context, userID, actorID string) error {
+	paused, err := s.scheduleRepo.PauseByCreator(ctx, userID)
+
+	status, errorMsg := "SUCCESS", ""
+	if err != nil {
+		status, errorMsg = "FAILURE", err.Error()
+	}
+	s.securityClient.AuditLog(
+		ctx, actorID, "", "USER_DEACTIVATION_CASCADE", "user", userID,
+		status, errorMsg, "", "", "", "",
+		map[string]interface{}{"scheduledCommandsPaused": paused},
+	)
+
+	return err
+}
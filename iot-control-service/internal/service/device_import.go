@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/tenant"
+)
+
+// MaxDeviceImportRows caps the number of devices in one import
+const MaxDeviceImportRows = 5000
+
+// deviceImportColumns maps the accepted CSV header names, as exported by common BMS tools, to
+// import fields. Headers are matched case-insensitively with spaces, dashes and underscores removed.
+var deviceImportColumns = map[string]string{
+	"deviceid":   "deviceId",
+	"id":         "deviceId",
+	"pointid":    "deviceId",
+	"type":       "type",
+	"devicetype": "type",
+	"model":      "model",
+	"name":       "name",
+	"devicename": "name",
+	"buildingid": "buildingId",
+	"building":   "buildingId",
+	"floor":      "floor",
+	"level":      "floor",
+	"room":       "room",
+	"zone":       "room",
+	"tags":       "tags",
+}
+
+// ParseDeviceImportCSV reads the rows of a device import from a CSV document with a header line.
+// Tags are separated by semicolons or pipes. Rows are numbered by their line in the file.
+func ParseDeviceImportCSV(r io.Reader) ([]models.DeviceImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("validation failed: CSV document is empty")
+		}
+		return nil, fmt.Errorf("validation failed: invalid CSV header: %w", err)
+	}
+
+	fields := make([]string, len(header))
+	hasDeviceID := false
+	for i, name := range header {
+		key := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+		key = strings.TrimPrefix(key, "\ufeff")
+		fields[i] = deviceImportColumns[key]
+		hasDeviceID = hasDeviceID || fields[i] == "deviceId"
+	}
+	if !hasDeviceID {
+		return nil, fmt.Errorf("validation failed: CSV header has no device ID column")
+	}
+
+	var rows []models.DeviceImportRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("validation failed: invalid CSV at line %d: %w", line, err)
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		row := models.DeviceImportRow{Row: line}
+		for i, value := range record {
+			if i >= len(fields) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch fields[i] {
+			case "deviceId":
+				row.DeviceID = value
+			case "type":
+				row.Type = value
+			case "model":
+				row.Model = value
+			case "name":
+				row.Name = value
+			case "buildingId":
+				row.BuildingID = value
+			case "floor":
+				row.Floor = value
+			case "room":
+				row.Room = value
+			case "tags":
+				row.Tags = splitImportTags(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ImportDevices creates or updates the devices of an import. Every row is validated on its own, so
+// a bad row is reported and skipped without failing the rest. Devices that already exist are
+// updated with the non-empty fields of their row.
+func (s *DeviceService) ImportDevices(ctx context.Context, rows []models.DeviceImportRow, userID string) (*models.DeviceImportResult, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("validation failed: import contains no devices")
+	}
+	if len(rows) > MaxDeviceImportRows {
+		return nil, fmt.Errorf("validation failed: import contains %d devices, at most %d are allowed", len(rows), MaxDeviceImportRows)
+	}
+
+	result := &models.DeviceImportResult{
+		Total: len(rows),
+		Rows:  make([]models.DeviceImportRowResult, 0, len(rows)),
+	}
+	deviceTypes := make(map[string]*models.DeviceType)
+	seen := make(map[string]int)
+
+	for i, row := range rows {
+		if row.Row == 0 {
+			row.Row = i + 1
+		}
+		rowResult := models.DeviceImportRowResult{Row: row.Row, DeviceID: row.DeviceID}
+
+		action, err := s.importDevice(ctx, row, userID, deviceTypes, seen)
+		if err != nil {
+			rowResult.Action, rowResult.Error = models.DeviceImportFailed, err.Error()
+		} else {
+			rowResult.Action = action
+			seen[row.DeviceID] = row.Row
+		}
+
+		switch rowResult.Action {
+		case models.DeviceImportCreated:
+			result.Created++
+		case models.DeviceImportUpdated:
+			result.Updated++
+		case models.DeviceImportUnchanged:
+			result.Unchanged++
+		default:
+			result.Failed++
+		}
+		result.Rows = append(result.Rows, rowResult)
+	}
+
+	return result, nil
+}
+
+// importDevice validates and upserts a single import row. Device types are cached across the rows.
+func (s *DeviceService) importDevice(ctx context.Context, row models.DeviceImportRow, userID string, deviceTypes map[string]*models.DeviceType, seen map[string]int) (models.DeviceImportAction, error) {
+	switch {
+	case row.DeviceID == "":
+		return "", fmt.Errorf("device ID is required")
+	case row.Type == "":
+		return "", fmt.Errorf("device type is required")
+	case row.BuildingID == "":
+		return "", fmt.Errorf("building ID is required")
+	case !tenant.AllowsBuilding(ctx, row.BuildingID):
+		return "", fmt.Errorf("building %s does not belong to your organization", row.BuildingID)
+	}
+	if first, ok := seen[row.DeviceID]; ok {
+		return "", fmt.Errorf("duplicate of row %d", first)
+	}
+
+	typeName := NormalizeDeviceTypeName(row.Type)
+	deviceType, ok := deviceTypes[typeName]
+	if !ok {
+		var err error
+		deviceType, err = s.deviceTypeRepo.FindByName(ctx, typeName)
+		if err != nil && err.Error() != "device type not found" {
+			return "", fmt.Errorf("failed to load device type: %w", err)
+		}
+		deviceTypes[typeName] = deviceType
+	}
+	if deviceType == nil {
+		return "", fmt.Errorf("unknown device type %s", row.Type)
+	}
+
+	existing, err := s.deviceRepo.FindByDeviceID(ctx, row.DeviceID)
+	if err != nil && err.Error() != "device not found" {
+		return "", fmt.Errorf("failed to load device: %w", err)
+	}
+
+	if existing == nil {
+		device := &models.Device{
+			DeviceID: row.DeviceID,
+			Name:     row.Name,
+			Type:     deviceType.Name,
+			Model:    row.Model,
+			Location: models.DeviceLocation{
+				BuildingID: row.BuildingID,
+				Floor:      row.Floor,
+				Room:       row.Room,
+			},
+			Capabilities: deviceType.SupportedCommands,
+			Tags:         row.Tags,
+			Status:       models.DeviceStatusOffline,
+			CreatedBy:    userID,
+		}
+		if _, err := s.deviceRepo.Create(ctx, device); err != nil {
+			if strings.Contains(err.Error(), "already exists") {
+				return "", fmt.Errorf("device %s was deleted; restore it before importing it again", row.DeviceID)
+			}
+			return "", fmt.Errorf("failed to create device: %w", err)
+		}
+		return models.DeviceImportCreated, nil
+	}
+
+	updates := importUpdates(existing, row, deviceType)
+	if len(updates) == 0 {
+		return models.DeviceImportUnchanged, nil
+	}
+	if _, err := s.deviceRepo.Update(ctx, existing.ID.Hex(), updates); err != nil {
+		return "", fmt.Errorf("failed to update device: %w", err)
+	}
+	s.deviceChanged(ctx, row.DeviceID)
+	return models.DeviceImportUpdated, nil
+}
+
+// importUpdates returns the fields of an existing device that an import row changes. A change of
+// type resets the capabilities to those of the new type.
+func importUpdates(device *models.Device, row models.DeviceImportRow, deviceType *models.DeviceType) bson.M {
+	updates := bson.M{}
+	if device.Type != deviceType.Name {
+		updates["type"] = deviceType.Name
+		updates["capabilities"] = deviceType.SupportedCommands
+	}
+	if device.Location.BuildingID != row.BuildingID {
+		updates["location.building_id"] = row.BuildingID
+	}
+	setIfChanged := func(field, current, value string) {
+		if value != "" && value != current {
+			updates[field] = value
+		}
+	}
+	setIfChanged("model", device.Model, row.Model)
+	setIfChanged("name", device.Name, row.Name)
+	setIfChanged("location.floor", device.Location.Floor, row.Floor)
+	setIfChanged("location.room", device.Location.Room, row.Room)
+	if len(row.Tags) > 0 && strings.Join(row.Tags, "\x00") != strings.Join(device.Tags, "\x00") {
+		updates["tags"] = row.Tags
+	}
+	return updates
+}
+
+// splitImportTags splits a CSV tag cell on semicolons or pipes
+func splitImportTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '|' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// isBlankRecord reports whether every cell of a CSV record is empty
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -4,24 +4,42 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"iot-control-service/internal/cache"
+	"iot-control-service/internal/events"
 	"iot-control-service/internal/models"
+	"iot-control-service/internal/mqtt"
 	"iot-control-service/internal/repository"
+	"iot-control-service/internal/tenant"
 )
 
 // DeviceService handles device business logic
 type DeviceService struct {
-	deviceRepo *repository.DeviceRepository
+	deviceRepo     *repository.DeviceRepository
+	deviceTypeRepo *repository.DeviceTypeRepository
+	cache          *cache.Cache
+	topics         *mqtt.TopicAuthorizer
 }
 
-// NewDeviceService creates a new device service
-func NewDeviceService(deviceRepo *repository.DeviceRepository) *DeviceService {
+// NewDeviceService creates a new device service. The cache holds device states, which are
+// invalidated when a device changes; it may be nil.
+func NewDeviceService(deviceRepo *repository.DeviceRepository, deviceTypeRepo *repository.DeviceTypeRepository, stateCache *cache.Cache) *DeviceService {
 	return &DeviceService{
-		deviceRepo: deviceRepo,
+		deviceRepo:     deviceRepo,
+		deviceTypeRepo: deviceTypeRepo,
+		cache:          stateCache,
 	}
 }
 
+// SetTopicAuthorizer sets the MQTT topic authorizer whose cached device buildings are evicted
+// when a device is updated, deleted or restored
+func (s *DeviceService) SetTopicAuthorizer(topics *mqtt.TopicAuthorizer) {
+	s.topics = topics
+}
+
 // RegisterDevice registers a new device
 func (s *DeviceService) RegisterDevice(ctx context.Context, req *models.RegisterDeviceRequest, userID string) (*models.DeviceResponse, error) {
 	// Validate request
@@ -29,8 +47,21 @@ func (s *DeviceService) RegisterDevice(ctx context.Context, req *models.Register
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
+	// The type must be in the catalog; its supported commands are the default capabilities
+	deviceType, err := s.deviceTypeRepo.FindByName(ctx, NormalizeDeviceTypeName(req.Type))
+	if err != nil {
+		if err.Error() == "device type not found" {
+			return nil, fmt.Errorf("validation failed: unknown device type %s", req.Type)
+		}
+		return nil, err
+	}
+	capabilities := req.Capabilities
+	if len(capabilities) == 0 {
+		capabilities = deviceType.SupportedCommands
+	}
+
 	// Check if device already exists
-	_, err := s.deviceRepo.FindByDeviceID(ctx, req.DeviceID)
+	_, err = s.deviceRepo.FindByDeviceID(ctx, req.DeviceID)
 	if err == nil {
 		return nil, fmt.Errorf("device with ID %s already exists", req.DeviceID)
 	}
@@ -40,14 +71,20 @@ func (s *DeviceService) RegisterDevice(ctx context.Context, req *models.Register
 	if location.BuildingID == "" && req.BuildingID != "" {
 		location.BuildingID = req.BuildingID
 	}
+	location.Hierarchy = nil // devices are placed in the hierarchy through the locations API
+	if !tenant.AllowsBuilding(ctx, location.BuildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", location.BuildingID)
+	}
 
 	// Create device
 	device := &models.Device{
 		DeviceID:     req.DeviceID,
-		Type:         req.Type,
+		Name:         req.Name,
+		Type:         deviceType.Name,
 		Model:        req.Model,
 		Location:     location,
-		Capabilities: req.Capabilities,
+		Capabilities: capabilities,
+		Tags:         req.Tags,
 		Status:       models.DeviceStatusOffline,
 		LastSeen:     time.Time{},
 		Metadata:     req.Metadata,
@@ -59,33 +96,107 @@ func (s *DeviceService) RegisterDevice(ctx context.Context, req *models.Register
 		return nil, fmt.Errorf("failed to create device: %w", err)
 	}
 
-	return createdDevice.ToResponse(), nil
+	response := createdDevice.ToResponse()
+	response.TypeInfo = deviceType.ToResponse()
+	return response, nil
 }
 
-// GetDevice retrieves a device by ID
-func (s *DeviceService) GetDevice(ctx context.Context, deviceID string) (*models.DeviceResponse, error) {
-	device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
+// GetDevice retrieves a device by ID, loading only the selected fields when a selection is given
+func (s *DeviceService) GetDevice(ctx context.Context, deviceID string, fields *models.FieldSelection) (*models.DeviceResponse, error) {
+	device, err := s.deviceRepo.FindByDeviceIDWithFields(ctx, deviceID, fields)
 	if err != nil {
 		return nil, err
 	}
-	return device.ToResponse(), nil
+
+	response := device.ToResponse()
+	if deviceType, err := s.deviceTypeRepo.FindByName(ctx, device.Type); err == nil {
+		response.TypeInfo = deviceType.ToResponse()
+	}
+	return response, nil
 }
 
 // ListDevices lists devices with filters
-func (s *DeviceService) ListDevices(ctx context.Context, buildingID, deviceType, status string, page, limit int) ([]*models.DeviceResponse, int64, error) {
-	devices, total, err := s.deviceRepo.FindAll(ctx, buildingID, deviceType, status, page, limit)
+func (s *DeviceService) ListDevices(ctx context.Context, buildingID, deviceType, status string, page, limit int, fields *models.FieldSelection) ([]*models.DeviceResponse, int64, error) {
+	if deviceType != "" {
+		deviceType = NormalizeDeviceTypeName(deviceType)
+	}
+	devices, total, err := s.deviceRepo.FindAll(ctx, buildingID, deviceType, status, page, limit, fields)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	// The catalog is small, so load it once rather than per device
+	typeInfo := make(map[string]*models.DeviceTypeResponse)
+	if deviceTypes, err := s.deviceTypeRepo.FindAll(ctx); err == nil {
+		for _, t := range deviceTypes {
+			typeInfo[t.Name] = t.ToResponse()
+		}
+	}
+
 	responses := make([]*models.DeviceResponse, len(devices))
 	for i, device := range devices {
 		responses[i] = device.ToResponse()
+		responses[i].TypeInfo = typeInfo[device.Type]
 	}
 
 	return responses, total, nil
 }
 
+// SearchDevices searches devices by free text and filters, most relevant first. Results are
+// paged with the cursor returned by the previous page.
+func (s *DeviceService) SearchDevices(ctx context.Context, req *models.SearchDevicesRequest) (*models.SearchResponse, error) {
+	var after *models.SearchCursor
+	if req.Cursor != "" {
+		cursor, err := models.DecodeSearchCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = cursor
+	}
+	limit := req.Limit
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if req.Type != "" {
+		req.Type = NormalizeDeviceTypeName(req.Type)
+	}
+	var tags []string
+	for _, tag := range strings.Split(req.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	// One extra hit tells whether another page follows
+	hits, err := s.deviceRepo.Search(ctx, req, tags, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.SearchResponse{Results: []*models.SearchResult{}}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[len(hits)-1]
+		response.NextCursor = models.SearchCursor{Score: last.Score, ID: last.ID}.Encode()
+	}
+	for _, hit := range hits {
+		title := hit.Name
+		if title == "" {
+			title = hit.DeviceID
+		}
+		response.Results = append(response.Results, &models.SearchResult{
+			Type:       models.SearchResultDevice,
+			ID:         hit.DeviceID,
+			Title:      title,
+			BuildingID: hit.Location.BuildingID,
+			Score:      hit.Score,
+			Data:       hit.Device.ToResponse(),
+		})
+	}
+
+	return response, nil
+}
+
 // UpdateDevice updates a device
 func (s *DeviceService) UpdateDevice(ctx context.Context, deviceID string, updates map[string]interface{}) (*models.DeviceResponse, error) {
 	// Find device first to get MongoDB ID
@@ -98,23 +209,165 @@ func (s *DeviceService) UpdateDevice(ctx context.Context, deviceID string, updat
 	if err != nil {
 		return nil, err
 	}
+	s.deviceChanged(ctx, deviceID)
+
+	return updatedDevice.ToResponse(), nil
+}
+
+// SetCalibration replaces the calibration factors applied to a device's metrics at ingestion.
+// Factors that are unchanged keep their original update time.
+func (s *DeviceService) SetCalibration(ctx context.Context, deviceID string, req *models.SetCalibrationRequest, userID string) (*models.DeviceResponse, error) {
+	device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	calibration := make(map[string]models.MetricCalibration, len(req.Metrics))
+	for metric, factor := range req.Metrics {
+		if !metricNamePattern.MatchString(metric) {
+			return nil, fmt.Errorf("validation failed: invalid metric name %q", metric)
+		}
+		if factor.Scale == 0 {
+			return nil, fmt.Errorf("validation failed: scale for metric %s must be non-zero", metric)
+		}
+
+		if existing, ok := device.Calibration[metric]; ok && existing.Scale == factor.Scale && existing.Offset == factor.Offset {
+			calibration[metric] = existing
+			continue
+		}
+		calibration[metric] = models.MetricCalibration{
+			Scale:     factor.Scale,
+			Offset:    factor.Offset,
+			UpdatedAt: now,
+			UpdatedBy: userID,
+		}
+	}
+
+	updatedDevice, err := s.deviceRepo.Update(ctx, device.ID.Hex(), map[string]interface{}{"calibration": calibration})
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Invalidate(ctx, DeviceStateCacheKind, deviceID)
 
 	return updatedDevice.ToResponse(), nil
 }
 
-// DeleteDevice deletes a device
-func (s *DeviceService) DeleteDevice(ctx context.Context, deviceID string) error {
+// DeleteDevice soft deletes a device
+func (s *DeviceService) DeleteDevice(ctx context.Context, deviceID, userID string) error {
 	device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
 	if err != nil {
 		return err
 	}
 
-	return s.deviceRepo.Delete(ctx, device.ID.Hex())
+	if err := s.deviceRepo.Delete(ctx, device.ID.Hex(), userID); err != nil {
+		return err
+	}
+	s.deviceChanged(ctx, deviceID)
+	return nil
+}
+
+// RestoreDevice restores a soft-deleted device
+func (s *DeviceService) RestoreDevice(ctx context.Context, deviceID string) (*models.DeviceResponse, error) {
+	device, err := s.deviceRepo.Restore(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	s.deviceChanged(ctx, deviceID)
+	return device.ToResponse(), nil
+}
+
+// deviceChanged drops the cached state and building of a device that was updated, deleted or restored
+func (s *DeviceService) deviceChanged(ctx context.Context, deviceID string) {
+	s.cache.Invalidate(ctx, DeviceStateCacheKind, deviceID)
+	s.topics.Forget(deviceID)
+}
+
+// ListDeletedDevices lists soft-deleted devices awaiting purge
+func (s *DeviceService) ListDeletedDevices(ctx context.Context, buildingID string, page, limit int) ([]*models.DeviceResponse, int64, error) {
+	devices, total, err := s.deviceRepo.FindDeleted(ctx, buildingID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]*models.DeviceResponse, len(devices))
+	for i, device := range devices {
+		responses[i] = device.ToResponse()
+	}
+
+	return responses, total, nil
+}
+
+// PurgeDeletedDevices permanently removes devices soft deleted longer than the retention period
+func (s *DeviceService) PurgeDeletedDevices(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.deviceRepo.PurgeDeletedBefore(ctx, time.Now().Add(-retention))
+}
+
+// StartPurgeWorker periodically purges soft-deleted devices past retention until the context is cancelled
+func (s *DeviceService) StartPurgeWorker(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeDeletedDevices(ctx, retention)
+			if err != nil {
+				log.Printf("Failed to purge deleted devices: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted devices", purged)
+			}
+		}
+	}
+}
+
+// UsageReporter returns the reporter of the service's usage meter. It publishes the metered API
+// calls together with the number of devices monitored in each building, which the Security
+// service meters for billing.
+func (s *DeviceService) UsageReporter(eventBus *events.Bus) func(ctx context.Context, apiCalls []events.UsageCount) {
+	return func(ctx context.Context, apiCalls []events.UsageCount) {
+		counts, err := s.deviceRepo.CountByBuilding(ctx)
+		if err != nil {
+			log.Printf("Failed to count monitored devices for usage report: %v", err)
+		}
+
+		devices := make([]events.UsageCount, 0, len(counts))
+		for buildingID, count := range counts {
+			if buildingID != "" {
+				devices = append(devices, events.UsageCount{BuildingID: buildingID, Count: count})
+			}
+		}
+
+		eventBus.Publish(ctx, events.UsageReported, &events.UsageReportedData{
+			Service:    "iot-control-service",
+			APICalls:   apiCalls,
+			Devices:    devices,
+			ReportedAt: time.Now(),
+		})
+	}
 }
 
 // UpdateDeviceLastSeen updates the last seen timestamp for a device
 func (s *DeviceService) UpdateDeviceLastSeen(ctx context.Context, deviceID string) error {
-	return s.deviceRepo.UpdateLastSeen(ctx, deviceID)
+	if err := s.deviceRepo.UpdateLastSeen(ctx, deviceID); err != nil {
+		return err
+	}
+	s.cache.Invalidate(ctx, DeviceStateCacheKind, deviceID)
+	return nil
+}
+
+// checkDeviceAccess ensures a device exists in the buildings the request is scoped to, so data
+// keyed only by device ID is not read across organizations
+func checkDeviceAccess(ctx context.Context, deviceRepo *repository.DeviceRepository, deviceID string) error {
+	if tenant.FromContext(ctx) == nil {
+		return nil
+	}
+	_, err := deviceRepo.FindByDeviceID(ctx, deviceID)
+	return err
 }
 
 // validateRegisterDevice validates device registration request
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/repository"
+)
+
+// deviceTypeNamePattern restricts catalog names to upper-case identifiers such as HVAC or HEAT_PUMP
+var deviceTypeNamePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,31}$`)
+
+// DeviceTypeService manages the device type catalog
+type DeviceTypeService struct {
+	deviceTypeRepo *repository.DeviceTypeRepository
+	deviceRepo     *repository.DeviceRepository
+}
+
+// NewDeviceTypeService creates a new device type service
+func NewDeviceTypeService(deviceTypeRepo *repository.DeviceTypeRepository, deviceRepo *repository.DeviceRepository) *DeviceTypeService {
+	return &DeviceTypeService{
+		deviceTypeRepo: deviceTypeRepo,
+		deviceRepo:     deviceRepo,
+	}
+}
+
+// InitializeDefaultTypes seeds the catalog with the built-in device types
+func (s *DeviceTypeService) InitializeDefaultTypes(ctx context.Context) error {
+	return s.deviceTypeRepo.InitializeDefaultTypes(ctx)
+}
+
+// NormalizeDeviceTypeName returns the catalog form of a device type name
+func NormalizeDeviceTypeName(name string) string {
+	return strings.ToUpper(strings.TrimSpace(name))
+}
+
+// CreateDeviceType adds a device type to the catalog
+func (s *DeviceTypeService) CreateDeviceType(ctx context.Context, req *models.CreateDeviceTypeRequest, userID string) (*models.DeviceTypeResponse, error) {
+	name := NormalizeDeviceTypeName(req.Name)
+	if !deviceTypeNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("validation failed: invalid device type name %q", req.Name)
+	}
+	if err := validateDeviceTypeLists(req.SupportedCommands, req.TelemetryMetrics); err != nil {
+		return nil, err
+	}
+	if err := validateRateLimit(req.RateLimit); err != nil {
+		return nil, err
+	}
+	if err := validateHighImpactCommands(req.HighImpactCommands, req.SupportedCommands); err != nil {
+		return nil, err
+	}
+
+	displayName := req.DisplayName
+	if displayName == "" {
+		displayName = name
+	}
+
+	deviceType := &models.DeviceType{
+		Name:               name,
+		DisplayName:        displayName,
+		Description:        req.Description,
+		SupportedCommands:  normalizeCommands(req.SupportedCommands),
+		TelemetryMetrics:   req.TelemetryMetrics,
+		Icon:               req.Icon,
+		DefaultConstraints: req.DefaultConstraints,
+		RateLimit:          normalizeRateLimit(req.RateLimit),
+		HighImpactCommands: normalizeCommands(req.HighImpactCommands),
+		CreatedBy:          userID,
+	}
+	if deviceType.TelemetryMetrics == nil {
+		deviceType.TelemetryMetrics = []string{}
+	}
+
+	created, err := s.deviceTypeRepo.Create(ctx, deviceType)
+	if err != nil {
+		return nil, err
+	}
+	return created.ToResponse(), nil
+}
+
+// GetDeviceType retrieves a device type by name
+func (s *DeviceTypeService) GetDeviceType(ctx context.Context, name string) (*models.DeviceTypeResponse, error) {
+	deviceType, err := s.deviceTypeRepo.FindByName(ctx, NormalizeDeviceTypeName(name))
+	if err != nil {
+		return nil, err
+	}
+	return deviceType.ToResponse(), nil
+}
+
+// ListDeviceTypes lists the device type catalog
+func (s *DeviceTypeService) ListDeviceTypes(ctx context.Context) ([]*models.DeviceTypeResponse, error) {
+	deviceTypes, err := s.deviceTypeRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.DeviceTypeResponse, len(deviceTypes))
+	for i, deviceType := range deviceTypes {
+		responses[i] = deviceType.ToResponse()
+	}
+	return responses, nil
+}
+
+// UpdateDeviceType updates a device type's commands, metrics and UI hints. The name cannot change
+// because registered devices refer to it.
+func (s *DeviceTypeService) UpdateDeviceType(ctx context.Context, name string, req *models.UpdateDeviceTypeRequest) (*models.DeviceTypeResponse, error) {
+	if err := validateDeviceTypeLists(req.SupportedCommands, req.TelemetryMetrics); err != nil {
+		return nil, err
+	}
+	if err := validateRateLimit(req.RateLimit); err != nil {
+		return nil, err
+	}
+	if req.HighImpactCommands != nil || req.SupportedCommands != nil {
+		// High-impact commands must stay a subset of the supported commands after the update
+		current, err := s.deviceTypeRepo.FindByName(ctx, NormalizeDeviceTypeName(name))
+		if err != nil {
+			return nil, err
+		}
+		highImpact, supported := req.HighImpactCommands, req.SupportedCommands
+		if highImpact == nil {
+			highImpact = current.HighImpactCommands
+		}
+		if supported == nil {
+			supported = current.SupportedCommands
+		}
+		if err := validateHighImpactCommands(highImpact, supported); err != nil {
+			return nil, err
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if req.DisplayName != nil {
+		updates["display_name"] = *req.DisplayName
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.SupportedCommands != nil {
+		updates["supported_commands"] = normalizeCommands(req.SupportedCommands)
+	}
+	if req.TelemetryMetrics != nil {
+		updates["telemetry_metrics"] = req.TelemetryMetrics
+	}
+	if req.Icon != nil {
+		updates["icon"] = *req.Icon
+	}
+	if req.DefaultConstraints != nil {
+		updates["default_constraints"] = req.DefaultConstraints
+	}
+	if req.RateLimit != nil {
+		updates["rate_limit"] = normalizeRateLimit(req.RateLimit)
+	}
+	if req.HighImpactCommands != nil {
+		updates["high_impact_commands"] = normalizeCommands(req.HighImpactCommands)
+	}
+
+	deviceType, err := s.deviceTypeRepo.Update(ctx, NormalizeDeviceTypeName(name), updates)
+	if err != nil {
+		return nil, err
+	}
+	return deviceType.ToResponse(), nil
+}
+
+// DeleteDeviceType removes a device type that no device is registered with. Built-in types cannot be deleted.
+func (s *DeviceTypeService) DeleteDeviceType(ctx context.Context, name string) error {
+	deviceType, err := s.deviceTypeRepo.FindByName(ctx, NormalizeDeviceTypeName(name))
+	if err != nil {
+		return err
+	}
+	if deviceType.IsSystem {
+		return fmt.Errorf("cannot delete system device type")
+	}
+
+	count, err := s.deviceRepo.CountByType(ctx, deviceType.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("device type is in use by %d devices", count)
+	}
+
+	return s.deviceTypeRepo.Delete(ctx, deviceType.Name)
+}
+
+// validateDeviceTypeLists checks the command and metric names of a catalog entry
+func validateDeviceTypeLists(commands, metrics []string) error {
+	for _, command := range commands {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("validation failed: supported commands must not be empty")
+		}
+	}
+	for _, metric := range metrics {
+		if !metricNamePattern.MatchString(metric) {
+			return fmt.Errorf("validation failed: invalid metric name %q", metric)
+		}
+	}
+	return nil
+}
+
+// validateRateLimit checks a command rate limit. A command limit needs both a count and a window.
+func validateRateLimit(limit *models.CommandRateLimit) error {
+	if limit == nil {
+		return nil
+	}
+	if limit.MaxCommands < 0 || limit.WindowSeconds < 0 || limit.MinStateChangeSeconds < 0 {
+		return fmt.Errorf("validation failed: rate limit values must not be negative")
+	}
+	if (limit.MaxCommands > 0) != (limit.WindowSeconds > 0) {
+		return fmt.Errorf("validation failed: maxCommands and windowSeconds must be set together")
+	}
+	return nil
+}
+
+// validateHighImpactCommands checks that every high-impact command is a supported command
+func validateHighImpactCommands(highImpact, supported []string) error {
+	supportedSet := make(map[string]bool, len(supported))
+	for _, command := range normalizeCommands(supported) {
+		supportedSet[command] = true
+	}
+	for _, command := range normalizeCommands(highImpact) {
+		if !supportedSet[command] {
+			return fmt.Errorf("validation failed: high-impact command %q is not a supported command", command)
+		}
+	}
+	return nil
+}
+
+// normalizeRateLimit drops a rate limit that limits nothing
+func normalizeRateLimit(limit *models.CommandRateLimit) *models.CommandRateLimit {
+	if limit == nil || (limit.MaxCommands == 0 && limit.MinStateChangeSeconds == 0) {
+		return nil
+	}
+	return limit
+}
+
+// normalizeCommands upper-cases command names so they match the commands devices are sent
+func normalizeCommands(commands []string) []string {
+	normalized := make([]string, len(commands))
+	for i, command := range commands {
+		normalized[i] = strings.ToUpper(strings.TrimSpace(command))
+	}
+	return normalized
+}
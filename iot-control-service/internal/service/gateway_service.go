@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/repository"
+)
+
+// GatewayDecoder turns a value a gateway read from a point into a number. Decoders are
+// registered per protocol, so further fieldbus protocols plug into ingestion without changing it.
+type GatewayDecoder interface {
+	// ValidateMapping rejects register mappings the decoder cannot decode
+	ValidateMapping(register models.RegisterMapping) error
+	// Decode returns the number a point value represents, before scaling and unit conversion
+	Decode(register models.RegisterMapping, value models.GatewayPointValue) (float64, error)
+}
+
+// gatewayDecoders are the decoders of the supported gateway protocols
+var gatewayDecoders = map[models.GatewayProtocol]GatewayDecoder{
+	models.GatewayProtocolModbus: ModbusDecoder{},
+	models.GatewayProtocolBACnet: BACnetDecoder{},
+}
+
+// ModbusDecoder decodes Modbus register values. Gateways send either the decoded value or the
+// raw 16-bit words, which are interpreted with the mapping's data type and word order.
+type ModbusDecoder struct{}
+
+// ValidateMapping checks the data type and word order of a Modbus register mapping
+func (ModbusDecoder) ValidateMapping(register models.RegisterMapping) error {
+	switch register.DataType {
+	case "", models.RegisterUint16, models.RegisterInt16, models.RegisterUint32, models.RegisterInt32, models.RegisterFloat32:
+	default:
+		return fmt.Errorf("unknown data type %s", register.DataType)
+	}
+	switch register.WordOrder {
+	case "", models.RegisterHighWordFirst, models.RegisterLowWordFirst:
+	default:
+		return fmt.Errorf("unknown word order %s", register.WordOrder)
+	}
+	return nil
+}
+
+// Decode returns the value of a Modbus register
+func (ModbusDecoder) Decode(register models.RegisterMapping, value models.GatewayPointValue) (float64, error) {
+	if len(value.Words) == 0 {
+		if value.Value == nil {
+			return 0, errors.New("neither value nor words given")
+		}
+		return *value.Value, nil
+	}
+
+	dataType := register.DataType
+	if dataType == "" {
+		dataType = models.RegisterUint16
+	}
+
+	switch dataType {
+	case models.RegisterUint16, models.RegisterInt16:
+		if len(value.Words) != 1 {
+			return 0, fmt.Errorf("%s takes 1 word, got %d", dataType, len(value.Words))
+		}
+		if dataType == models.RegisterInt16 {
+			return float64(int16(value.Words[0])), nil
+		}
+		return float64(value.Words[0]), nil
+	}
+
+	if len(value.Words) != 2 {
+		return 0, fmt.Errorf("%s takes 2 words, got %d", dataType, len(value.Words))
+	}
+	high, low := value.Words[0], value.Words[1]
+	if register.WordOrder == models.RegisterLowWordFirst {
+		high, low = low, high
+	}
+	bits := uint32(high)<<16 | uint32(low)
+
+	switch dataType {
+	case models.RegisterInt32:
+		return float64(int32(bits)), nil
+	case models.RegisterFloat32:
+		decoded := float64(math.Float32frombits(bits))
+		if math.IsNaN(decoded) || math.IsInf(decoded, 0) {
+			return 0, errors.New("words are not a finite float")
+		}
+		return decoded, nil
+	}
+	return float64(bits), nil
+}
+
+// BACnetDecoder decodes BACnet object values, which gateways read as present values
+type BACnetDecoder struct{}
+
+// ValidateMapping rejects Modbus register settings on BACnet objects
+func (BACnetDecoder) ValidateMapping(register models.RegisterMapping) error {
+	if register.DataType != "" || register.WordOrder != "" {
+		return errors.New("data type and word order apply to Modbus registers only")
+	}
+	return nil
+}
+
+// Decode returns the present value of a BACnet object
+func (BACnetDecoder) Decode(register models.RegisterMapping, value models.GatewayPointValue) (float64, error) {
+	if len(value.Words) > 0 {
+		return 0, errors.New("BACnet objects take a value, not words")
+	}
+	if value.Value == nil {
+		return 0, errors.New("no value given")
+	}
+	return *value.Value, nil
+}
+
+// GatewayService maps readings of Modbus and BACnet gateways to telemetry
+type GatewayService struct {
+	mappingRepo      *repository.GatewayMappingRepository
+	deviceRepo       *repository.DeviceRepository
+	telemetryService *TelemetryService
+}
+
+// NewGatewayService creates a new gateway service
+func NewGatewayService(
+	mappingRepo *repository.GatewayMappingRepository,
+	deviceRepo *repository.DeviceRepository,
+	telemetryService *TelemetryService,
+) *GatewayService {
+	return &GatewayService{
+		mappingRepo:      mappingRepo,
+		deviceRepo:       deviceRepo,
+		telemetryService: telemetryService,
+	}
+}
+
+// SaveMapping creates or replaces how the points of a device map to its metrics
+func (s *GatewayService) SaveMapping(ctx context.Context, deviceID string, req *models.GatewayMappingRequest, userID string) (*models.GatewayMapping, error) {
+	device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateGatewayMapping(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	return s.mappingRepo.Upsert(ctx, &models.GatewayMapping{
+		DeviceID:   device.DeviceID,
+		BuildingID: device.Location.BuildingID,
+		Protocol:   req.Protocol,
+		Registers:  req.Registers,
+		UpdatedBy:  userID,
+	})
+}
+
+// GetMapping retrieves the mapping of a device
+func (s *GatewayService) GetMapping(ctx context.Context, deviceID string) (*models.GatewayMapping, error) {
+	return s.mappingRepo.FindByDeviceID(ctx, deviceID)
+}
+
+// ListMappings lists mappings, optionally of one building
+func (s *GatewayService) ListMappings(ctx context.Context, req *models.ListGatewayMappingsRequest) ([]*models.GatewayMapping, int64, error) {
+	return s.mappingRepo.FindAll(ctx, req.BuildingID, req.Page, req.Limit)
+}
+
+// DeleteMapping removes the mapping of a device; its gateway readings are rejected afterwards
+func (s *GatewayService) DeleteMapping(ctx context.Context, deviceID string) error {
+	return s.mappingRepo.Delete(ctx, deviceID)
+}
+
+// IngestReadings maps a batch of gateway readings to telemetry and stores it. Points without a
+// mapping or that fail to decode are skipped, and readings of devices without a mapping or with
+// no usable point are rejected; both are reported in the result instead of failing the batch.
+func (s *GatewayService) IngestReadings(ctx context.Context, req *models.GatewayReadingsRequest) (*models.GatewayIngestResult, error) {
+	deviceIDs := make([]string, 0, len(req.Readings))
+	for _, reading := range req.Readings {
+		deviceIDs = append(deviceIDs, reading.DeviceID)
+	}
+	mappings, err := s.mappingRepo.FindByDeviceIDs(ctx, deviceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load gateway mappings: %w", err)
+	}
+
+	result := &models.GatewayIngestResult{GatewayID: req.GatewayID, Errors: []models.GatewayPointError{}}
+	bulk := &models.BulkTelemetryIngestRequest{}
+	now := time.Now()
+
+	for i, reading := range req.Readings {
+		mapping, ok := mappings[reading.DeviceID]
+		if !ok {
+			result.ReadingsRejected++
+			result.Errors = append(result.Errors, models.GatewayPointError{
+				Reading: i, DeviceID: reading.DeviceID, Message: "device has no gateway mapping",
+			})
+			continue
+		}
+
+		metrics, pointErrors := mapGatewayReading(mapping, reading)
+		for _, pointError := range pointErrors {
+			pointError.Reading = i
+			result.Errors = append(result.Errors, pointError)
+		}
+		if len(metrics) == 0 {
+			result.ReadingsRejected++
+			result.Errors = append(result.Errors, models.GatewayPointError{
+				Reading: i, DeviceID: reading.DeviceID, Message: "no point of the reading could be mapped",
+			})
+			continue
+		}
+
+		timestamp := reading.Timestamp
+		if timestamp.IsZero() {
+			timestamp = now
+		}
+		bulk.Telemetry = append(bulk.Telemetry, models.TelemetryIngestRequest{
+			DeviceID:  reading.DeviceID,
+			Timestamp: timestamp,
+			Metrics:   metrics,
+		})
+		result.ReadingsAccepted++
+		result.MetricsStored += len(metrics)
+	}
+
+	if len(bulk.Telemetry) > 0 {
+		if _, err := s.telemetryService.IngestBulkTelemetry(ctx, bulk, models.TelemetrySourceGateway); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// mapGatewayReading decodes, scales and converts the points of a reading to metrics
+func mapGatewayReading(mapping *models.GatewayMapping, reading models.GatewayReading) (map[string]interface{}, []models.GatewayPointError) {
+	decoder := gatewayDecoders[mapping.Protocol]
+	registers := make(map[string]models.RegisterMapping, len(mapping.Registers))
+	for _, register := range mapping.Registers {
+		registers[register.Address] = register
+	}
+
+	metrics := make(map[string]interface{})
+	var pointErrors []models.GatewayPointError
+	for _, point := range reading.Points {
+		register, ok := registers[point.Address]
+		if !ok {
+			pointErrors = append(pointErrors, models.GatewayPointError{
+				DeviceID: reading.DeviceID, Address: point.Address, Message: "point is not mapped",
+			})
+			continue
+		}
+
+		value, err := MapGatewayPoint(decoder, register, point)
+		if err != nil {
+			pointErrors = append(pointErrors, models.GatewayPointError{
+				DeviceID: reading.DeviceID, Address: point.Address, Message: err.Error(),
+			})
+			continue
+		}
+		metrics[register.Metric] = value
+	}
+	return metrics, pointErrors
+}
+
+// MapGatewayPoint decodes a point value with a protocol's decoder, applies the mapping's scale
+// and offset and converts the result to the mapping's target unit
+func MapGatewayPoint(decoder GatewayDecoder, register models.RegisterMapping, point models.GatewayPointValue) (float64, error) {
+	value, err := decoder.Decode(register, point)
+	if err != nil {
+		return 0, err
+	}
+
+	scale := register.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	value = value*scale + register.Offset
+
+	if register.Unit != "" && register.TargetUnit != "" {
+		if value, err = ConvertUnit(value, register.Unit, register.TargetUnit); err != nil {
+			return 0, err
+		}
+	}
+	return value, nil
+}
+
+// validateGatewayMapping checks that every point maps to a distinct, valid metric and can be
+// decoded and converted
+func validateGatewayMapping(req *models.GatewayMappingRequest) error {
+	decoder, ok := gatewayDecoders[req.Protocol]
+	if !ok {
+		return fmt.Errorf("unsupported protocol %s", req.Protocol)
+	}
+
+	addresses := make(map[string]bool, len(req.Registers))
+	metrics := make(map[string]bool, len(req.Registers))
+	for _, register := range req.Registers {
+		if addresses[register.Address] {
+			return fmt.Errorf("address %s is mapped more than once", register.Address)
+		}
+		addresses[register.Address] = true
+
+		if !metricNamePattern.MatchString(register.Metric) {
+			return fmt.Errorf("invalid metric name %q", register.Metric)
+		}
+		if metrics[register.Metric] {
+			return fmt.Errorf("metric %s is mapped more than once", register.Metric)
+		}
+		metrics[register.Metric] = true
+
+		if err := decoder.ValidateMapping(register); err != nil {
+			return fmt.Errorf("address %s: %w", register.Address, err)
+		}
+		if (register.Unit == "") != (register.TargetUnit == "") {
+			return fmt.Errorf("address %s: unit and targetUnit must be set together", register.Address)
+		}
+		if register.Unit != "" {
+			if _, err := ConvertUnit(0, register.Unit, register.TargetUnit); err != nil {
+				return fmt.Errorf("address %s: %w", register.Address, err)
+			}
+		}
+	}
+	return nil
+}
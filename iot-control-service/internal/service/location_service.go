@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/repository"
+	"iot-control-service/internal/tenant"
+)
+
+// locationIDPattern matches the location IDs clients may choose
+var locationIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]{0,63}$`)
+
+// LocationService handles the location hierarchy and the devices assigned to it
+type LocationService struct {
+	locationRepo *repository.LocationRepository
+	deviceRepo   *repository.DeviceRepository
+}
+
+// NewLocationService creates a new location service
+func NewLocationService(locationRepo *repository.LocationRepository, deviceRepo *repository.DeviceRepository) *LocationService {
+	return &LocationService{
+		locationRepo: locationRepo,
+		deviceRepo:   deviceRepo,
+	}
+}
+
+// CreateLocation adds a location to the hierarchy under its parent. Sites and buildings may be
+// created without a parent; every other level needs one of the level above.
+func (s *LocationService) CreateLocation(ctx context.Context, req *models.CreateLocationRequest, userID string) (*models.Location, error) {
+	location := &models.Location{
+		LocationID: req.LocationID,
+		Level:      req.Level,
+		Name:       req.Name,
+		ParentID:   req.ParentID,
+		Area:       req.Area,
+		Shape:      req.Shape,
+		Path:       []string{},
+		CreatedBy:  userID,
+	}
+	if scope := tenant.FromContext(ctx); scope != nil {
+		location.OrgID = scope.OrgID
+	}
+
+	if len(req.Shape) > 0 && req.Level != models.LocationLevelZone && req.Level != models.LocationLevelRoom {
+		return nil, fmt.Errorf("validation failed: only zones and rooms have a shape")
+	}
+
+	if req.ParentID == "" {
+		if req.Level != models.LocationLevelSite && req.Level != models.LocationLevelBuilding {
+			return nil, fmt.Errorf("validation failed: a %s location needs a parent", req.Level)
+		}
+	} else {
+		parent, err := s.locationRepo.FindByLocationID(ctx, req.ParentID)
+		if err != nil {
+			if err.Error() == "location not found" {
+				return nil, fmt.Errorf("validation failed: parent location %s not found", req.ParentID)
+			}
+			return nil, err
+		}
+		if !levelIn(parent.Level, req.Level.ParentLevels()) {
+			return nil, fmt.Errorf("validation failed: a %s location cannot be placed under a %s", req.Level, parent.Level)
+		}
+		location.Path = append(append([]string{}, parent.Path...), parent.LocationID)
+		location.BuildingID = parent.BuildingID
+	}
+
+	if req.Level == models.LocationLevelBuilding {
+		if req.LocationID == "" {
+			return nil, fmt.Errorf("validation failed: the locationId of a building is its building ID")
+		}
+		if !tenant.AllowsBuilding(ctx, req.LocationID) {
+			return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", req.LocationID)
+		}
+		location.BuildingID = req.LocationID
+	}
+	if location.LocationID == "" {
+		location.LocationID = uuid.New().String()
+	} else if !locationIDPattern.MatchString(location.LocationID) {
+		return nil, fmt.Errorf("validation failed: invalid locationId %q", location.LocationID)
+	}
+
+	return s.locationRepo.Create(ctx, location)
+}
+
+// GetLocation retrieves a location with its direct children and the number of devices in it
+func (s *LocationService) GetLocation(ctx context.Context, locationID string) (*models.LocationDetail, error) {
+	location, err := s.locationRepo.FindByLocationID(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := s.locationRepo.FindAll(ctx, "", locationID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list child locations: %w", err)
+	}
+	count, err := s.deviceRepo.CountByLocation(ctx, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count devices: %w", err)
+	}
+
+	return &models.LocationDetail{Location: location, Children: children, DeviceCount: count}, nil
+}
+
+// ListLocations lists locations, optionally of one building, under one parent or of one level
+func (s *LocationService) ListLocations(ctx context.Context, req *models.ListLocationsRequest) ([]*models.Location, error) {
+	return s.locationRepo.FindAll(ctx, req.BuildingID, req.ParentID, models.LocationLevel(req.Level))
+}
+
+// UpdateLocation renames a location or changes its area or shape
+func (s *LocationService) UpdateLocation(ctx context.Context, locationID string, req *models.UpdateLocationRequest) (*models.Location, error) {
+	location, err := s.locationRepo.FindByLocationID(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Shape) > 0 && location.Level != models.LocationLevelZone && location.Level != models.LocationLevelRoom {
+		return nil, fmt.Errorf("validation failed: only zones and rooms have a shape")
+	}
+
+	return s.locationRepo.Update(ctx, locationID, bson.M{
+		"name":  req.Name,
+		"area":  req.Area,
+		"shape": req.Shape,
+	})
+}
+
+// DeleteLocation removes a location that has no child locations and no devices
+func (s *LocationService) DeleteLocation(ctx context.Context, locationID string) error {
+	if _, err := s.locationRepo.FindByLocationID(ctx, locationID); err != nil {
+		return err
+	}
+
+	children, err := s.locationRepo.CountChildren(ctx, locationID)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return fmt.Errorf("validation failed: location has %d child locations", children)
+	}
+	devices, err := s.deviceRepo.CountByLocation(ctx, locationID)
+	if err != nil {
+		return err
+	}
+	if devices > 0 {
+		return fmt.Errorf("validation failed: location has %d assigned devices", devices)
+	}
+
+	if err := s.locationRepo.Delete(ctx, locationID); err != nil {
+		return err
+	}
+	// Deleted devices that may still be restored no longer point at the location
+	return s.deviceRepo.ClearHierarchy(ctx, locationID)
+}
+
+// AssignDevices places devices on a floor, in a zone or in a room. A device must already belong
+// to the location's building; devices that cannot be assigned are reported without failing the
+// others.
+func (s *LocationService) AssignDevices(ctx context.Context, locationID string, req *models.AssignDevicesRequest) (*models.AssignDevicesResult, error) {
+	location, err := s.locationRepo.FindByLocationID(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+	if location.Level != models.LocationLevelFloor && location.Level != models.LocationLevelZone && location.Level != models.LocationLevelRoom {
+		return nil, fmt.Errorf("validation failed: devices are assigned to floors, zones or rooms")
+	}
+
+	ancestors, err := s.locationRepo.FindByLocationIDs(ctx, location.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load parent locations: %w", err)
+	}
+	hierarchy, floor, room := deviceHierarchy(location, ancestors)
+
+	result := &models.AssignDevicesResult{LocationID: locationID, Assigned: []string{}}
+	for _, deviceID := range req.DeviceIDs {
+		device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
+		if err != nil {
+			result.Failed = append(result.Failed, models.DeviceAssignError{DeviceID: deviceID, Error: err.Error()})
+			continue
+		}
+		if device.Location.BuildingID != location.BuildingID {
+			result.Failed = append(result.Failed, models.DeviceAssignError{
+				DeviceID: deviceID,
+				Error:    fmt.Sprintf("device belongs to building %s", device.Location.BuildingID),
+			})
+			continue
+		}
+		if _, err := s.deviceRepo.SetHierarchy(ctx, deviceID, hierarchy, floor, room); err != nil {
+			result.Failed = append(result.Failed, models.DeviceAssignError{DeviceID: deviceID, Error: err.Error()})
+			continue
+		}
+		result.Assigned = append(result.Assigned, deviceID)
+	}
+
+	return result, nil
+}
+
+// UnassignDevice removes a device in a location from the hierarchy; it stays in its building
+func (s *LocationService) UnassignDevice(ctx context.Context, locationID, deviceID string) error {
+	device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if device.Location.Hierarchy == nil || !containsString(device.Location.Hierarchy.Path, locationID) {
+		return fmt.Errorf("device is not assigned to location %s", locationID)
+	}
+
+	_, err = s.deviceRepo.SetHierarchy(ctx, deviceID, nil, "", "")
+	return err
+}
+
+// ListLocationDevices lists the devices in a location or in any location below it
+func (s *LocationService) ListLocationDevices(ctx context.Context, locationID string) ([]*models.DeviceResponse, error) {
+	if _, err := s.locationRepo.FindByLocationID(ctx, locationID); err != nil {
+		return nil, err
+	}
+
+	devices, err := s.deviceRepo.FindByLocation(ctx, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	responses := make([]*models.DeviceResponse, len(devices))
+	for i, device := range devices {
+		responses[i] = device.ToResponse()
+	}
+	return responses, nil
+}
+
+// UploadFloorPlan stores a floor's plan and the zones drawn on it. Zones with an ID update that
+// zone of the floor and the others are created; every shape must lie within the plan.
+func (s *LocationService) UploadFloorPlan(ctx context.Context, floorID string, req *models.FloorPlanRequest, userID string) (*models.FloorPlanView, error) {
+	floor, err := s.locationRepo.FindByLocationID(ctx, floorID)
+	if err != nil {
+		return nil, err
+	}
+	if floor.Level != models.LocationLevelFloor {
+		return nil, fmt.Errorf("validation failed: floor plans are uploaded for floors")
+	}
+
+	for i, zone := range req.Zones {
+		for _, point := range zone.Shape {
+			if point.X > req.Width || point.Y > req.Height {
+				return nil, fmt.Errorf("validation failed: zone %d lies outside the %gx%g plan", i, req.Width, req.Height)
+			}
+		}
+		if zone.LocationID == "" {
+			continue
+		}
+		existing, err := s.locationRepo.FindByLocationID(ctx, zone.LocationID)
+		if err != nil || existing.Level != models.LocationLevelZone || existing.ParentID != floorID {
+			return nil, fmt.Errorf("validation failed: zone %d: %s is not a zone of floor %s", i, zone.LocationID, floorID)
+		}
+	}
+
+	for _, zone := range req.Zones {
+		if zone.LocationID != "" {
+			if _, err := s.locationRepo.Update(ctx, zone.LocationID, bson.M{
+				"name":  zone.Name,
+				"area":  zone.Area,
+				"shape": zone.Shape,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to update zone %s: %w", zone.LocationID, err)
+			}
+			continue
+		}
+		if _, err := s.CreateLocation(ctx, &models.CreateLocationRequest{
+			Level:    models.LocationLevelZone,
+			Name:     zone.Name,
+			ParentID: floorID,
+			Area:     zone.Area,
+			Shape:    zone.Shape,
+		}, userID); err != nil {
+			return nil, fmt.Errorf("failed to create zone %s: %w", zone.Name, err)
+		}
+	}
+
+	if _, err := s.locationRepo.Update(ctx, floorID, bson.M{"floor_plan": &models.FloorPlan{
+		ImageURL:   req.ImageURL,
+		Width:      req.Width,
+		Height:     req.Height,
+		UploadedBy: userID,
+		UploadedAt: time.Now(),
+	}}); err != nil {
+		return nil, fmt.Errorf("failed to store floor plan: %w", err)
+	}
+
+	return s.GetFloorPlan(ctx, floorID)
+}
+
+// GetFloorPlan returns a floor with its plan, its zones and rooms and the devices in each, for
+// the dashboard
+func (s *LocationService) GetFloorPlan(ctx context.Context, floorID string) (*models.FloorPlanView, error) {
+	floor, err := s.locationRepo.FindByLocationID(ctx, floorID)
+	if err != nil {
+		return nil, err
+	}
+	if floor.Level != models.LocationLevelFloor {
+		return nil, fmt.Errorf("validation failed: floor plans are kept for floors")
+	}
+
+	locations, err := s.locationRepo.FindAll(ctx, floor.BuildingID, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list zones: %w", err)
+	}
+	view := &models.FloorPlanView{Floor: floor, Areas: []*models.FloorPlanArea{}, Unzoned: []models.FloorPlanDevice{}}
+	areas := make(map[string]*models.FloorPlanArea)
+	for _, location := range locations {
+		if containsString(location.Path, floorID) {
+			area := &models.FloorPlanArea{Location: location, Devices: []models.FloorPlanDevice{}}
+			areas[location.LocationID] = area
+			view.Areas = append(view.Areas, area)
+		}
+	}
+
+	devices, err := s.deviceRepo.FindByLocation(ctx, floorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	for _, device := range devices {
+		entry := models.FloorPlanDevice{
+			DeviceID: device.DeviceID,
+			Name:     device.Name,
+			Type:     device.Type,
+			Status:   string(device.Status),
+		}
+		area := areas[device.Location.Hierarchy.RoomID]
+		if area == nil {
+			area = areas[device.Location.Hierarchy.ZoneID]
+		}
+		if area == nil {
+			view.Unzoned = append(view.Unzoned, entry)
+			continue
+		}
+		area.Devices = append(area.Devices, entry)
+		if device.Status == models.DeviceStatusOnline {
+			area.OnlineDevices++
+		}
+	}
+
+	return view, nil
+}
+
+// deviceHierarchy builds the hierarchy of devices assigned to a location from the location and its
+// ancestors, with the floor and room names the devices take
+func deviceHierarchy(location *models.Location, ancestors map[string]*models.Location) (*models.DeviceHierarchy, string, string) {
+	hierarchy := &models.DeviceHierarchy{
+		LocationID: location.LocationID,
+		Path:       append(append([]string{}, location.Path...), location.LocationID),
+	}
+
+	var floor, room string
+	for _, id := range hierarchy.Path {
+		level := location.Level
+		name := location.Name
+		if id != location.LocationID {
+			ancestor, ok := ancestors[id]
+			if !ok {
+				continue
+			}
+			level, name = ancestor.Level, ancestor.Name
+		}
+		switch level {
+		case models.LocationLevelSite:
+			hierarchy.SiteID = id
+		case models.LocationLevelFloor:
+			hierarchy.FloorID = id
+			floor = name
+		case models.LocationLevelZone:
+			hierarchy.ZoneID = id
+		case models.LocationLevelRoom:
+			hierarchy.RoomID = id
+			room = name
+		}
+	}
+	return hierarchy, floor, room
+}
+
+// containsString reports whether a list holds a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// levelIn reports whether a level is one of the given levels
+func levelIn(level models.LocationLevel, levels []models.LocationLevel) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
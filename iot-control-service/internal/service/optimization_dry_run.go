@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"iot-control-service/internal/models"
+)
+
+// dryRunCommandMetrics maps the commands whose effect can be estimated to the metric they change
+var dryRunCommandMetrics = map[string]string{
+	"SET_TEMP":       "temperature",
+	"SET_BRIGHTNESS": "brightness",
+	"REDUCE_POWER":   "power",
+	"CURTAIL":        "power",
+	"TURN_OFF":       "power",
+}
+
+// DryRunOptimization previews an optimization scenario against the current device state. Each
+// action is reported as one that would succeed, be skipped or conflict, with the estimated change
+// of the device's metrics. The scenario is not created and no command is sent.
+func (s *OptimizationService) DryRunOptimization(ctx context.Context, req *models.ApplyOptimizationRequest) (*models.ScenarioDryRun, error) {
+	if err := s.validateApplyOptimization(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	if err := s.expandLocationActions(ctx, req); err != nil {
+		return nil, err
+	}
+
+	report := &models.ScenarioDryRun{
+		SourceScenarioID: req.ScenarioID,
+		BuildingID:       req.BuildingID,
+		Actions:          make([]models.DryRunAction, 0, len(req.Actions)),
+		GeneratedAt:      time.Now(),
+	}
+	report.Summary.TotalActions = len(req.Actions)
+
+	deviceTypes := make(map[string]*models.DeviceType)
+	for _, action := range req.Actions {
+		result, err := s.dryRunAction(ctx, req.BuildingID, action, deviceTypes)
+		if err != nil {
+			return nil, err
+		}
+		report.Actions = append(report.Actions, *result)
+
+		switch result.Outcome {
+		case models.DryRunOutcomeSucceed:
+			report.Summary.WouldSucceed++
+			for _, change := range result.Changes {
+				if change.Metric == "power" && change.Delta != nil {
+					report.Summary.PowerChangeKW += *change.Delta
+				}
+			}
+		case models.DryRunOutcomeSkip:
+			report.Summary.Skipped++
+		case models.DryRunOutcomeConflict:
+			report.Summary.Conflicts++
+		case models.DryRunOutcomeFail:
+			report.Summary.Failed++
+		}
+	}
+
+	return report, nil
+}
+
+// dryRunAction predicts the outcome of a single action. Device types are cached across the
+// actions of a scenario.
+func (s *OptimizationService) dryRunAction(ctx context.Context, buildingID string, action models.OptimizationAction, deviceTypes map[string]*models.DeviceType) (*models.DryRunAction, error) {
+	result := &models.DryRunAction{
+		DeviceID:   action.DeviceID,
+		LocationID: action.LocationID,
+		Command:    action.Command,
+		Params:     action.Params,
+		Outcome:    models.DryRunOutcomeSucceed,
+	}
+	if action.Command == "" {
+		result.Outcome, result.Reason = models.DryRunOutcomeFail, "command is required"
+		return result, nil
+	}
+
+	device, err := s.deviceRepo.FindByDeviceID(ctx, action.DeviceID)
+	if err != nil {
+		if err.Error() == "device not found" {
+			result.Outcome, result.Reason = models.DryRunOutcomeFail, "device not found"
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to load device %s: %w", action.DeviceID, err)
+	}
+	result.DeviceStatus = string(device.Status)
+
+	deviceType, ok := deviceTypes[device.Type]
+	if !ok {
+		deviceType, err = s.deviceTypeRepo.FindByName(ctx, device.Type)
+		if err != nil && err.Error() != "device type not found" {
+			return nil, fmt.Errorf("failed to load device type %s: %w", device.Type, err)
+		}
+		deviceTypes[device.Type] = deviceType
+	}
+
+	switch {
+	case deviceType != nil && !deviceType.SupportsCommand(action.Command):
+		result.Outcome = models.DryRunOutcomeFail
+		result.Reason = fmt.Sprintf("%s devices do not support %s", deviceType.Name, action.Command)
+		return result, nil
+	case device.Location.BuildingID != "" && device.Location.BuildingID != buildingID:
+		result.Outcome = models.DryRunOutcomeSkip
+		result.Reason = fmt.Sprintf("excluded: device belongs to building %s", device.Location.BuildingID)
+		return result, nil
+	case device.Status == models.DeviceStatusMaintenance:
+		result.Outcome, result.Reason = models.DryRunOutcomeConflict, "device is under maintenance"
+	case device.Status == models.DeviceStatusOffline || device.Status == models.DeviceStatusError:
+		result.Outcome = models.DryRunOutcomeSkip
+		result.Reason = fmt.Sprintf("device is %s", device.Status)
+	}
+
+	// A scenario already pending or running on the device would issue competing commands
+	if result.Outcome == models.DryRunOutcomeSucceed {
+		active, err := s.optimizationRepo.FindActiveByDevice(ctx, action.DeviceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check scenarios of device %s: %w", action.DeviceID, err)
+		}
+		for _, scenario := range active {
+			result.Conflicts = append(result.Conflicts, scenario.ScenarioID)
+		}
+		if len(result.Conflicts) > 0 {
+			result.Outcome = models.DryRunOutcomeConflict
+			result.Reason = fmt.Sprintf("device is controlled by %d pending or running scenarios", len(result.Conflicts))
+		}
+	}
+
+	metrics := map[string]interface{}{}
+	if telemetry, err := s.telemetryRepo.FindLatestByDevice(ctx, action.DeviceID); err == nil {
+		metrics = telemetry.Metrics
+	} else if err.Error() != "no telemetry found for device" {
+		return nil, fmt.Errorf("failed to load state of device %s: %w", action.DeviceID, err)
+	}
+	result.Changes = estimateStateChanges(action, metrics)
+	return result, nil
+}
+
+// estimateStateChanges estimates how an action changes a device's metrics. Numeric params named
+// after a reported metric are targets for it; the metric a command changes can also be targeted
+// with a "value" param, and power reductions with a "percent" param.
+func estimateStateChanges(action models.OptimizationAction, metrics map[string]interface{}) []models.StateChange {
+	targets := make(map[string]float64)
+	for name, value := range action.Params {
+		if _, reported := metrics[name]; !reported {
+			continue
+		}
+		if target, ok := toFloat(value); ok {
+			targets[name] = target
+		}
+	}
+
+	if metric, ok := dryRunCommandMetrics[action.Command]; ok {
+		if _, set := targets[metric]; !set {
+			current, reported := toFloat(metrics[metric])
+			switch {
+			case action.Command == "TURN_OFF":
+				targets[metric] = 0
+			case paramFloat(action.Params, "value") != nil:
+				targets[metric] = *paramFloat(action.Params, "value")
+			case metric == "power" && reported && paramFloat(action.Params, "percent") != nil:
+				targets[metric] = current * (1 - *paramFloat(action.Params, "percent")/100)
+			}
+		}
+	}
+
+	changes := make([]models.StateChange, 0, len(targets))
+	for metric, target := range targets {
+		change := models.StateChange{Metric: metric, Target: target}
+		if current, ok := toFloat(metrics[metric]); ok {
+			delta := target - current
+			change.Current = &current
+			change.Delta = &delta
+			if current != 0 {
+				percent := delta / current * 100
+				change.DeltaPercent = &percent
+			}
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Metric < changes[j].Metric })
+	return changes
+}
+
+// paramFloat returns a numeric action param, or nil when it is missing or not a number
+func paramFloat(params map[string]interface{}, name string) *float64 {
+	value, ok := toFloat(params[name])
+	if !ok {
+		return nil
+	}
+	return &value
+}
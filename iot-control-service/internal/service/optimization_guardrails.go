@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"iot-control-service/internal/events"
+	"iot-control-service/internal/models"
+)
+
+const (
+	// comfortMetric is the telemetry metric the comfort guardrail watches, in °C
+	comfortMetric = "temperature"
+	// defaultComfortToleranceCelsius is how far the temperature may leave a comfort band without one
+	defaultComfortToleranceCelsius = 0.5
+	// maxComfortToleranceCelsius bounds the tolerance of a comfort band
+	maxComfortToleranceCelsius = 5.0
+	// defaultComfortMonitorMinutes and maxComfortMonitorMinutes bound how long devices are watched
+	// after a scenario completes
+	defaultComfortMonitorMinutes = 60
+	maxComfortMonitorMinutes     = 1440
+)
+
+// CheckComfortBand reports the intervention the comfort guardrail makes for a device's latest
+// reading: whether its temperature left the band by more than the tolerance. Readings taken
+// before since, i.e. before the scenario acted on the device, are ignored.
+func CheckComfortBand(band *models.ComfortBand, deviceID string, reading *models.Telemetry, since time.Time) (*models.GuardrailIntervention, bool) {
+	if band == nil || reading == nil || reading.Timestamp.Before(since) {
+		return nil, false
+	}
+	temperature, ok := toFloat(reading.Metrics[comfortMetric])
+	if !ok {
+		return nil, false
+	}
+
+	intervention := &models.GuardrailIntervention{DeviceID: deviceID, Temperature: temperature, ReadingAt: reading.Timestamp}
+	switch {
+	case band.MinTemperature != nil && temperature < *band.MinTemperature-band.ToleranceCelsius:
+		intervention.Bound, intervention.Limit = models.ComfortBoundMin, *band.MinTemperature
+	case band.MaxTemperature != nil && temperature > *band.MaxTemperature+band.ToleranceCelsius:
+		intervention.Bound, intervention.Limit = models.ComfortBoundMax, *band.MaxTemperature
+	default:
+		return nil, false
+	}
+	return intervention, true
+}
+
+// checkComfort checks the latest temperature of the devices a scenario has acted on against its
+// comfort band and steps in for each device that left it. It reports whether it stepped in.
+func (s *OptimizationService) checkComfort(ctx context.Context, scenario *models.OptimizationScenario, since time.Time, running bool) bool {
+	if scenario.Comfort == nil {
+		return false
+	}
+
+	var deviceIDs []string
+	seen := make(map[string]bool)
+	for _, action := range scenario.Actions {
+		if (action.Status == models.ActionStatusSent || action.Status == models.ActionStatusApplied) && !seen[action.DeviceID] {
+			seen[action.DeviceID] = true
+			deviceIDs = append(deviceIDs, action.DeviceID)
+		}
+	}
+	if len(deviceIDs) == 0 {
+		return false
+	}
+
+	latest, err := s.telemetryRepo.FindLatestMetricsByDevice(ctx, deviceIDs)
+	if err != nil {
+		log.Printf("Failed to check comfort of scenario %s: %v", scenario.ScenarioID, err)
+		return false
+	}
+
+	intervened := false
+	for _, deviceID := range deviceIDs {
+		if intervention, violated := CheckComfortBand(scenario.Comfort, deviceID, latest[deviceID], since); violated {
+			s.intervene(ctx, scenario, intervention, running)
+			intervened = true
+		}
+	}
+	return intervened
+}
+
+// intervene rolls back a scenario's actions on a device whose temperature left the comfort band,
+// records the intervention on the scenario and publishes it for the scenario's execution log
+func (s *OptimizationService) intervene(ctx context.Context, scenario *models.OptimizationScenario, intervention *models.GuardrailIntervention, running bool) {
+	deviceID := intervention.DeviceID
+	rolledBack, notRolledBack := s.rollbackActions(ctx, scenario, func(action models.OptimizationAction) bool {
+		return action.DeviceID == deviceID
+	})
+
+	direction := "below"
+	if intervention.Bound == models.ComfortBoundMax {
+		direction = "above"
+	}
+	intervention.ActionsRolledBack = rolledBack
+	intervention.ActionsNotRolledBack = notRolledBack
+	intervention.Paused = running
+	intervention.TriggeredAt = time.Now()
+	intervention.Message = fmt.Sprintf("comfort guardrail: device %s reported %.1f°C, %s the comfort limit of %.1f°C; %d actions rolled back",
+		deviceID, intervention.Temperature, direction, intervention.Limit, rolledBack)
+	if notRolledBack > 0 {
+		intervention.Message += fmt.Sprintf(", %d could not be rolled back", notRolledBack)
+	}
+	if running {
+		intervention.Message += "; execution paused"
+	}
+	log.Printf("Scenario %s %s", scenario.ScenarioID, intervention.Message)
+
+	scenario.Interventions = append(scenario.Interventions, *intervention)
+	if err := s.optimizationRepo.AddGuardrailIntervention(ctx, scenario.ScenarioID, *intervention); err != nil {
+		log.Printf("Failed to record comfort guardrail intervention on scenario %s: %v", scenario.ScenarioID, err)
+	}
+
+	s.eventBus.Publish(ctx, events.ComfortGuardrailTriggered, &events.ComfortGuardrailTriggeredData{
+		ScenarioID:           scenario.ScenarioID,
+		SourceScenarioID:     scenario.SourceScenarioID,
+		BuildingID:           scenario.BuildingID,
+		DeviceID:             deviceID,
+		Temperature:          intervention.Temperature,
+		Bound:                intervention.Bound,
+		Limit:                intervention.Limit,
+		ActionsRolledBack:    rolledBack,
+		ActionsNotRolledBack: notRolledBack,
+		Paused:               running,
+		TriggeredAt:          intervention.TriggeredAt,
+	})
+}
+
+// guardedDevices returns the devices of a scenario the comfort guardrail stepped in on
+func guardedDevices(scenario *models.OptimizationScenario) map[string]bool {
+	guarded := make(map[string]bool, len(scenario.Interventions))
+	for _, intervention := range scenario.Interventions {
+		guarded[intervention.DeviceID] = true
+	}
+	return guarded
+}
+
+// pauseScenario stops the execution of a scenario the comfort guardrail stepped in on. Its
+// pending actions are kept so the scenario can be resumed.
+func (s *OptimizationService) pauseScenario(ctx context.Context, scenario *models.OptimizationScenario, progress float64) {
+	reason := "paused by the comfort guardrail"
+	if n := len(scenario.Interventions); n > 0 {
+		reason = scenario.Interventions[n-1].Message
+	}
+
+	_ = s.optimizationRepo.UpdateProgress(ctx, scenario.ScenarioID, progress, models.OptimizationStatusPaused)
+	if _, err := s.optimizationRepo.Update(ctx, scenario.ID.Hex(), bson.M{"error_msg": reason}); err != nil {
+		log.Printf("Failed to record pause of scenario %s: %v", scenario.ScenarioID, err)
+	}
+}
+
+// CheckComfortGuardrails checks the devices of completed scenarios still within their comfort
+// monitoring window and returns how many scenarios the guardrail stepped in on. Running scenarios
+// are checked by their execution.
+func (s *OptimizationService) CheckComfortGuardrails(ctx context.Context) (int, error) {
+	now := time.Now()
+	scenarios, err := s.optimizationRepo.FindComfortMonitored(ctx, now.Add(-maxComfortMonitorMinutes*time.Minute))
+	if err != nil {
+		return 0, err
+	}
+
+	intervened := 0
+	for _, scenario := range scenarios {
+		if scenario.CompletedAt == nil || scenario.StartedAt == nil {
+			continue
+		}
+		window := time.Duration(scenario.Comfort.MonitorMinutes) * time.Minute
+		if now.After(scenario.CompletedAt.Add(window)) {
+			continue
+		}
+		if s.checkComfort(ctx, scenario, *scenario.StartedAt, false) {
+			intervened++
+		}
+	}
+	return intervened, nil
+}
+
+// StartComfortGuardrailWorker periodically checks the comfort of completed scenarios until the
+// context is cancelled
+func (s *OptimizationService) StartComfortGuardrailWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			intervened, err := s.CheckComfortGuardrails(ctx)
+			if err != nil {
+				log.Printf("Failed to check comfort guardrails: %v", err)
+				continue
+			}
+			if intervened > 0 {
+				log.Printf("Comfort guardrail stepped in on %d scenarios", intervened)
+			}
+		}
+	}
+}
+
+// validateComfortBand validates the comfort band of a scenario and fills in its defaults
+func validateComfortBand(band *models.ComfortBand) error {
+	if band.MinTemperature == nil && band.MaxTemperature == nil {
+		return fmt.Errorf("comfort band requires minTemperature or maxTemperature")
+	}
+	if band.MinTemperature != nil && band.MaxTemperature != nil && *band.MinTemperature > *band.MaxTemperature {
+		return fmt.Errorf("comfort band minTemperature is above maxTemperature")
+	}
+	if band.ToleranceCelsius < 0 || band.ToleranceCelsius > maxComfortToleranceCelsius {
+		return fmt.Errorf("comfort band toleranceCelsius must be between 0 and %g", maxComfortToleranceCelsius)
+	}
+	if band.MonitorMinutes < 0 || band.MonitorMinutes > maxComfortMonitorMinutes {
+		return fmt.Errorf("comfort band monitorMinutes must be between 0 and %d", maxComfortMonitorMinutes)
+	}
+
+	if band.ToleranceCelsius == 0 {
+		band.ToleranceCelsius = defaultComfortToleranceCelsius
+	}
+	if band.MonitorMinutes == 0 {
+		band.MonitorMinutes = defaultComfortMonitorMinutes
+	}
+	return nil
+}
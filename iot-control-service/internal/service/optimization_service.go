@@ -2,26 +2,60 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"iot-control-service/internal/events"
 	"iot-control-service/internal/integrations"
 	"iot-control-service/internal/models"
 	"iot-control-service/internal/repository"
+	"iot-control-service/internal/tracing"
+	"shared/jobs"
 )
 
+const (
+	// scenarioExecutionJob is the job type executing applied optimization scenarios
+	scenarioExecutionJob = "scenario_execution"
+	// scenarioExecutionTimeout bounds an execution, which waits on each action's command
+	scenarioExecutionTimeout = 30 * time.Minute
+	// maxActionAttempts and maxRetryBackoffSeconds bound action retry policies, keeping retries
+	// well within the execution timeout
+	maxActionAttempts      = 5
+	maxRetryBackoffSeconds = 30
+	// maxScenarioActions bounds the actions a scenario expands to once actions on locations are
+	// replaced by actions on their devices
+	maxScenarioActions = 1000
+)
+
+// scenarioJobPayload identifies a scenario awaiting execution and the device predictions fetched
+// when it was applied
+type scenarioJobPayload struct {
+	ScenarioID  string                              `bson:"scenario_id"`
+	Predictions map[string]*models.DevicePrediction `bson:"predictions,omitempty"`
+}
+
 // OptimizationService handles optimization scenario business logic
-// Integration: Uses ForecastClient to fetch device predictions before executing optimization
+// Integration: Uses predictions pushed by the Forecast service, fetching them with ForecastClient
+// only for devices without a cached prediction
 // Integration: Uses AnalyticsClient to check for anomalies before applying changes
 type OptimizationService struct {
 	optimizationRepo *repository.OptimizationRepository
 	commandRepo      *repository.CommandRepository
 	deviceRepo       *repository.DeviceRepository
+	deviceTypeRepo   *repository.DeviceTypeRepository
+	telemetryRepo    *repository.TelemetryRepository
 	forecastClient   *integrations.ForecastClient
+	predictionCache  *integrations.PredictionCache
 	analyticsClient  *integrations.AnalyticsClient
+	eventBus         *events.Bus
+	jobQueue         *jobs.Queue
 }
 
 // NewOptimizationService creates a new optimization service
@@ -29,16 +63,31 @@ func NewOptimizationService(
 	optimizationRepo *repository.OptimizationRepository,
 	commandRepo *repository.CommandRepository,
 	deviceRepo *repository.DeviceRepository,
+	deviceTypeRepo *repository.DeviceTypeRepository,
+	telemetryRepo *repository.TelemetryRepository,
 	forecastClient *integrations.ForecastClient,
+	predictionCache *integrations.PredictionCache,
 	analyticsClient *integrations.AnalyticsClient,
+	eventBus *events.Bus,
+	jobQueue *jobs.Queue,
 ) *OptimizationService {
-	return &OptimizationService{
+	s := &OptimizationService{
 		optimizationRepo: optimizationRepo,
 		commandRepo:      commandRepo,
 		deviceRepo:       deviceRepo,
+		deviceTypeRepo:   deviceTypeRepo,
+		telemetryRepo:    telemetryRepo,
 		forecastClient:   forecastClient,
+		predictionCache:  predictionCache,
 		analyticsClient:  analyticsClient,
+		eventBus:         eventBus,
+		jobQueue:         jobQueue,
 	}
+	jobQueue.Register(scenarioExecutionJob, s.processScenarioJob, jobs.Options{
+		Timeout:  scenarioExecutionTimeout,
+		OnFailed: s.failScenarioJob,
+	})
+	return s
 }
 
 // ApplyOptimization applies an optimization scenario
@@ -49,24 +98,22 @@ func (s *OptimizationService) ApplyOptimization(ctx context.Context, req *models
 	if err := s.validateApplyOptimization(req); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
+	if err := s.expandLocationActions(ctx, req); err != nil {
+		return nil, err
+	}
 
 	// Generate scenario ID
 	scenarioID := uuid.New().String()
 
-	// Integration: Fetch predictions for each device to optimize execution timing
-	// This allows us to schedule actions when energy savings will be maximized
-	devicePredictions := make(map[string]*models.DevicePrediction)
-	for _, action := range req.Actions {
-		if s.forecastClient != nil {
-			prediction, err := s.forecastClient.GetDevicePrediction(ctx, action.DeviceID, "")
-			if err == nil && prediction != nil {
-				devicePredictions[action.DeviceID] = prediction
-				log.Printf("[Integration] Fetched prediction for device %s: trend=%s, savings potential=%.2f%%",
-					action.DeviceID, prediction.Trend, prediction.TrendPercentage)
-			}
-		}
+	var policy models.ExecutionPolicy
+	if req.Policy != nil {
+		policy = *req.Policy
 	}
 
+	// Integration: Look up predictions for each device to optimize execution timing
+	// This allows us to schedule actions when energy savings will be maximized
+	devicePredictions := s.devicePredictions(ctx, req.Actions)
+
 	// Integration: Check for anomalies that might conflict with optimization actions
 	// Skip actions for devices with active critical anomalies
 	var filteredActions []models.OptimizationAction
@@ -90,13 +137,17 @@ func (s *OptimizationService) ApplyOptimization(ctx context.Context, req *models
 
 	// Create scenario with validated actions
 	scenario := &models.OptimizationScenario{
-		ScenarioID:      scenarioID,
-		ForecastID:      req.ForecastID,
-		BuildingID:      req.BuildingID,
-		Actions:         filteredActions,
-		ExecutionStatus: models.OptimizationStatusPending,
-		Progress:        0.0,
-		CreatedBy:       userID,
+		ScenarioID:       scenarioID,
+		SourceScenarioID: req.ScenarioID,
+		ScenarioType:     req.ScenarioType,
+		ForecastID:       req.ForecastID,
+		BuildingID:       req.BuildingID,
+		Actions:          filteredActions,
+		ExecutionStatus:  models.OptimizationStatusPending,
+		Progress:         0.0,
+		Policy:           policy,
+		Comfort:          req.Comfort,
+		CreatedBy:        userID,
 	}
 
 	createdScenario, err := s.optimizationRepo.Create(ctx, scenario)
@@ -105,11 +156,90 @@ func (s *OptimizationService) ApplyOptimization(ctx context.Context, req *models
 	}
 
 	// Start execution asynchronously, passing device predictions for optimized scheduling
-	go s.executeScenario(context.Background(), createdScenario, devicePredictions)
+	payload := &scenarioJobPayload{ScenarioID: createdScenario.ScenarioID, Predictions: devicePredictions}
+	if _, err := s.jobQueue.Enqueue(ctx, scenarioExecutionJob, payload, nil); err != nil {
+		_ = s.optimizationRepo.UpdateProgress(ctx, createdScenario.ScenarioID, 0.0, models.OptimizationStatusFailed)
+		return nil, err
+	}
 
 	return createdScenario.ToResponse(), nil
 }
 
+// processScenarioJob executes a queued scenario. A scenario that is already finished is skipped,
+// and actions already carried out by an interrupted run are not sent again.
+func (s *OptimizationService) processScenarioJob(ctx context.Context, job *jobs.Job) error {
+	var payload scenarioJobPayload
+	if err := job.Decode(&payload); err != nil {
+		return jobs.Permanent(fmt.Errorf("invalid scenario job payload: %w", err))
+	}
+
+	scenario, err := s.optimizationRepo.FindByScenarioID(ctx, payload.ScenarioID)
+	if err != nil {
+		if err.Error() == "scenario not found" {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	if scenario.ExecutionStatus != models.OptimizationStatusPending && scenario.ExecutionStatus != models.OptimizationStatusRunning {
+		return nil
+	}
+
+	s.executeScenario(ctx, scenario, payload.Predictions)
+	return ctx.Err()
+}
+
+// failScenarioJob marks the scenario of a job that failed for good as failed
+func (s *OptimizationService) failScenarioJob(ctx context.Context, job *jobs.Job, reason string) {
+	var payload scenarioJobPayload
+	if err := job.Decode(&payload); err != nil {
+		return
+	}
+
+	scenario, err := s.optimizationRepo.FindByScenarioID(ctx, payload.ScenarioID)
+	if err != nil || (scenario.ExecutionStatus != models.OptimizationStatusPending && scenario.ExecutionStatus != models.OptimizationStatusRunning) {
+		return
+	}
+	if err := s.optimizationRepo.UpdateProgress(ctx, payload.ScenarioID, scenario.Progress, models.OptimizationStatusFailed); err != nil {
+		log.Printf("Failed to fail scenario %s: %v", payload.ScenarioID, err)
+		return
+	}
+	if _, err := s.optimizationRepo.Update(ctx, scenario.ID.Hex(), bson.M{"error_msg": reason}); err != nil {
+		log.Printf("Failed to record failure of scenario %s: %v", payload.ScenarioID, err)
+	}
+}
+
+// devicePredictions returns the predictions of the devices of the actions. Predictions pushed by
+// the Forecast service are used while fresh; only the other devices are fetched synchronously.
+func (s *OptimizationService) devicePredictions(ctx context.Context, actions []models.OptimizationAction) map[string]*models.DevicePrediction {
+	predictions := make(map[string]*models.DevicePrediction)
+	fetched := 0
+	for _, action := range actions {
+		if _, ok := predictions[action.DeviceID]; ok {
+			continue
+		}
+		if prediction, ok := s.predictionCache.Get(action.DeviceID); ok {
+			predictions[action.DeviceID] = prediction
+			continue
+		}
+		if s.forecastClient == nil {
+			continue
+		}
+
+		prediction, err := s.forecastClient.GetDevicePrediction(ctx, action.DeviceID, "")
+		if err == nil && prediction != nil {
+			predictions[action.DeviceID] = prediction
+			fetched++
+			log.Printf("[Integration] Fetched prediction for device %s: trend=%s, savings potential=%.2f%%",
+				action.DeviceID, prediction.Trend, prediction.TrendPercentage)
+		}
+	}
+
+	if fetched < len(predictions) {
+		log.Printf("[Integration] Used %d cached device predictions, fetched %d", len(predictions)-fetched, fetched)
+	}
+	return predictions
+}
+
 // GetOptimizationStatus retrieves the status of an optimization scenario
 func (s *OptimizationService) GetOptimizationStatus(ctx context.Context, scenarioID string) (*models.OptimizationScenarioResponse, error) {
 	scenario, err := s.optimizationRepo.FindByScenarioID(ctx, scenarioID)
@@ -119,89 +249,405 @@ func (s *OptimizationService) GetOptimizationStatus(ctx context.Context, scenari
 	return scenario.ToResponse(), nil
 }
 
-// executeScenario executes an optimization scenario
+// executeScenario executes an optimization scenario. Failed actions are sent again as their retry
+// policy allows. Once the share of failed actions exceeds the scenario's failure threshold, the
+// execution is aborted and the actions already carried out are rolled back. After each action the
+// comfort guardrail checks the devices acted on, and the execution is paused once it steps in.
 // Integration: Uses device predictions to optimize action timing and expected impact
 func (s *OptimizationService) executeScenario(ctx context.Context, scenario *models.OptimizationScenario, predictions map[string]*models.DevicePrediction) {
-	// Update status to running
-	_ = s.optimizationRepo.UpdateProgress(ctx, scenario.ScenarioID, 0.0, models.OptimizationStatusRunning)
+	ctx, span := tracing.Start(ctx, "execute scenario", trace.WithAttributes(
+		tracing.ScenarioIDKey.String(scenario.ScenarioID),
+		tracing.BuildingIDKey.String(scenario.BuildingID),
+		attribute.Int("scenario.actions", len(scenario.Actions)),
+	))
+	defer span.End()
+
+	// Update status to running, unless resuming an interrupted execution
+	startedAt := time.Now()
+	if scenario.ExecutionStatus == models.OptimizationStatusPending {
+		_ = s.optimizationRepo.UpdateProgress(ctx, scenario.ScenarioID, 0.0, models.OptimizationStatusRunning)
+	} else if scenario.StartedAt != nil {
+		startedAt = *scenario.StartedAt
+	}
 
 	totalActions := float64(len(scenario.Actions))
 	completedActions := 0.0
+	failedActions := 0
+	abortReason := ""
+	paused := false
+	guarded := guardedDevices(scenario)
 
 	// Execute each action
-	for _, action := range scenario.Actions {
-		// Validate device exists
-		_, err := s.deviceRepo.FindByDeviceID(ctx, action.DeviceID)
-		if err != nil {
-			// Update action status to failed
-			s.updateActionStatus(ctx, scenario.ScenarioID, action.DeviceID, "FAILED", "")
-			completedActions++
-			continue
+	for i := range scenario.Actions {
+		if ctx.Err() != nil {
+			return
 		}
 
-		// Integration: Use prediction data to enhance command parameters
-		// If device has an increasing consumption trend, prioritize this action
-		var priority string = "NORMAL"
-		if pred, ok := predictions[action.DeviceID]; ok && pred != nil {
-			if pred.Trend == "INCREASING" && pred.TrendPercentage > 10 {
-				priority = "HIGH"
-				log.Printf("[Integration] Elevating priority for device %s due to increasing trend (%.1f%%)",
-					action.DeviceID, pred.TrendPercentage)
+		action := &scenario.Actions[i]
+		switch action.Status {
+		case models.ActionStatusSent, models.ActionStatusApplied:
+			// Actions carried out by an interrupted execution are not sent again
+		case models.ActionStatusFailed:
+			failedActions++
+		case models.ActionStatusRolledBack:
+			// Rolled back by the comfort guardrail and kept when the scenario was resumed
+		default:
+			if guarded[action.DeviceID] {
+				s.updateAction(ctx, scenario.ScenarioID, i, bson.M{"last_error": "not sent: the comfort guardrail rolled back the scenario's actions on the device"})
+				break
+			}
+			actionCtx, actionSpan := tracing.Start(ctx, "execute action "+action.Command,
+				trace.WithAttributes(tracing.EntityAttributes(scenario.BuildingID, action.DeviceID)...))
+			action.Status, action.CommandID = s.executeAction(actionCtx, scenario, i, predictions)
+			actionSpan.SetAttributes(attribute.String("action.status", action.Status), attribute.String("command.id", action.CommandID))
+			actionSpan.End()
+			if action.Status == models.ActionStatusFailed {
+				failedActions++
 			}
 		}
 
-		// Create command with enriched context from predictions
-		commandID := uuid.New().String()
-		params := action.Params
-		if params == nil {
-			params = make(map[string]interface{})
+		completedActions++
+		progress := completedActions / totalActions
+		_ = s.optimizationRepo.UpdateProgress(ctx, scenario.ScenarioID, progress, models.OptimizationStatusRunning)
+
+		threshold := scenario.Policy.FailureThresholdPercent
+		if threshold > 0 && float64(failedActions)/totalActions*100 > threshold {
+			abortReason = fmt.Sprintf("aborted after %d of %d actions failed, exceeding the failure threshold of %g%%",
+				failedActions, len(scenario.Actions), threshold)
+			break
 		}
-		params["optimization_priority"] = priority
 
-		command := &models.DeviceCommand{
-			CommandID: commandID,
-			DeviceID:  action.DeviceID,
-			Command:   action.Command,
-			Params:    params,
-			Status:    models.CommandStatusPending,
-			IssuedBy:  scenario.CreatedBy,
+		if s.checkComfort(ctx, scenario, startedAt, true) {
+			paused = true
+			break
 		}
+	}
 
-		_, err = s.commandRepo.Create(ctx, command)
-		if err != nil {
-			s.updateActionStatus(ctx, scenario.ScenarioID, action.DeviceID, "FAILED", "")
-			completedActions++
-			continue
+	status := models.OptimizationStatusCompleted
+	if paused {
+		status = models.OptimizationStatusPaused
+		s.pauseScenario(ctx, scenario, completedActions/totalActions)
+	} else if abortReason != "" {
+		rolledBack, notRolledBack := s.rollbackScenario(ctx, scenario)
+		abortReason += fmt.Sprintf("; %d actions rolled back", rolledBack)
+		if notRolledBack > 0 {
+			abortReason += fmt.Sprintf(", %d could not be rolled back", notRolledBack)
+		}
+		log.Printf("Scenario %s %s", scenario.ScenarioID, abortReason)
+
+		status = models.OptimizationStatusFailed
+		_ = s.optimizationRepo.UpdateProgress(ctx, scenario.ScenarioID, completedActions/totalActions, status)
+		if _, err := s.optimizationRepo.Update(ctx, scenario.ID.Hex(), bson.M{"error_msg": abortReason}); err != nil {
+			log.Printf("Failed to record abort of scenario %s: %v", scenario.ScenarioID, err)
+		}
+	} else {
+		// Mark scenario as completed
+		_ = s.optimizationRepo.UpdateProgress(ctx, scenario.ScenarioID, 1.0, status)
+	}
+
+	appliedActions := 0
+	executed := make([]events.ExecutedAction, 0, len(scenario.Actions))
+	for _, action := range scenario.Actions[:int(completedActions)] {
+		if action.Status == models.ActionStatusApplied {
+			appliedActions++
+		}
+		executed = append(executed, executedAction(action, action.Status, action.CommandID))
+	}
+
+	s.eventBus.Publish(ctx, events.ScenarioExecuted, &events.ScenarioExecutedData{
+		ScenarioID:       scenario.ScenarioID,
+		SourceScenarioID: scenario.SourceScenarioID,
+		ScenarioType:     scenario.ScenarioType,
+		BuildingID:       scenario.BuildingID,
+		Status:           string(status),
+		ActionsApplied:   appliedActions,
+		ActionsFailed:    failedActions,
+		Actions:          executed,
+		StartedAt:        startedAt,
+		CompletedAt:      time.Now(),
+	})
+}
+
+// executeAction sends the command of the action at the given position, sending it again after a
+// failure while its retry policy allows, and returns the action's final status and command
+func (s *OptimizationService) executeAction(ctx context.Context, scenario *models.OptimizationScenario, index int, predictions map[string]*models.DevicePrediction) (string, string) {
+	action := &scenario.Actions[index]
+
+	// Validate device exists
+	if _, err := s.deviceRepo.FindByDeviceID(ctx, action.DeviceID); err != nil {
+		s.updateAction(ctx, scenario.ScenarioID, index, bson.M{
+			"status":     models.ActionStatusFailed,
+			"last_error": "device not found",
+		})
+		return models.ActionStatusFailed, ""
+	}
+
+	// Integration: Use prediction data to enhance command parameters
+	// If device has an increasing consumption trend, prioritize this action
+	var priority string = "NORMAL"
+	pred, ok := predictions[action.DeviceID]
+	if !ok || pred == nil {
+		pred, ok = s.predictionCache.Get(action.DeviceID)
+	}
+	if ok && pred != nil {
+		if pred.Trend == "INCREASING" && pred.TrendPercentage > 10 {
+			priority = "HIGH"
+			log.Printf("[Integration] Elevating priority for device %s due to increasing trend (%.1f%%)",
+				action.DeviceID, pred.TrendPercentage)
 		}
+	}
 
-		// Update action with command ID
-		s.updateActionStatus(ctx, scenario.ScenarioID, action.DeviceID, "SENT", commandID)
+	// Keep what the action changes so an aborted execution can restore it
+	updates := bson.M{}
+	if previous := s.previousState(ctx, *action); len(previous) > 0 {
+		action.PreviousState = previous
+		updates["previous_state"] = previous
+	}
 
-		// Wait for command to be applied (simplified - in production, use proper async handling)
-		time.Sleep(1 * time.Second)
+	policy := scenario.Policy.Retry
+	if action.Retry != nil {
+		policy = *action.Retry
+	}
+	backoff := time.Duration(policy.BackoffSeconds) * time.Second
 
-		// Check command status
-		cmd, err := s.commandRepo.FindByCommandID(ctx, commandID)
-		if err == nil {
-			if cmd.Status == models.CommandStatusApplied {
-				s.updateActionStatus(ctx, scenario.ScenarioID, action.DeviceID, "APPLIED", commandID)
-			} else if cmd.Status == models.CommandStatusFailed {
-				s.updateActionStatus(ctx, scenario.ScenarioID, action.DeviceID, "FAILED", commandID)
+	status, commandID, lastError := models.ActionStatusFailed, "", ""
+	for attempt := 1; attempt == 1 || attempt <= policy.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return status, commandID
+			case <-time.After(backoff):
 			}
+			backoff *= 2
 		}
 
-		completedActions++
-		progress := completedActions / totalActions
-		_ = s.optimizationRepo.UpdateProgress(ctx, scenario.ScenarioID, progress, models.OptimizationStatusRunning)
+		action.Attempts++
+		status, commandID, lastError = s.sendAction(ctx, scenario, index, priority)
+		if status != models.ActionStatusFailed {
+			break
+		}
+	}
+
+	updates["status"] = status
+	updates["command_id"] = commandID
+	updates["attempts"] = action.Attempts
+	updates["last_error"] = lastError
+	s.updateAction(ctx, scenario.ScenarioID, index, updates)
+	return status, commandID
+}
+
+// sendAction creates the command of an action and waits briefly for the device to apply it. It
+// returns the action's status, the command and, for a failed attempt, why it failed.
+func (s *OptimizationService) sendAction(ctx context.Context, scenario *models.OptimizationScenario, index int, priority string) (string, string, string) {
+	action := scenario.Actions[index]
+
+	// Create command with enriched context from predictions
+	commandID := uuid.New().String()
+	params := make(map[string]interface{}, len(action.Params)+1)
+	for name, value := range action.Params {
+		params[name] = value
+	}
+	params["optimization_priority"] = priority
+
+	command := &models.DeviceCommand{
+		CommandID:  commandID,
+		DeviceID:   action.DeviceID,
+		BuildingID: scenario.BuildingID,
+		Command:    action.Command,
+		Params:     params,
+		Status:     models.CommandStatusPending,
+		IssuedBy:   scenario.CreatedBy,
+	}
+
+	if _, err := s.commandRepo.Create(ctx, command); err != nil {
+		return models.ActionStatusFailed, "", fmt.Sprintf("failed to create command: %v", err)
+	}
+
+	// Update action with command ID
+	s.updateAction(ctx, scenario.ScenarioID, index, bson.M{
+		"status":     models.ActionStatusSent,
+		"command_id": commandID,
+	})
+
+	// Wait for command to be applied (simplified - in production, use proper async handling)
+	time.Sleep(1 * time.Second)
+
+	// Check command status
+	cmd, err := s.commandRepo.FindByCommandID(ctx, commandID)
+	if err == nil {
+		if cmd.Status == models.CommandStatusApplied {
+			return models.ActionStatusApplied, commandID, ""
+		} else if cmd.Status == models.CommandStatusFailed {
+			return models.ActionStatusFailed, commandID, cmd.ErrorMsg
+		}
+	}
+	return models.ActionStatusSent, commandID, ""
+}
+
+// previousState returns the values the device last reported for the metrics an action changes,
+// which undo the action unless it has its own rollback command or is undone by the opposite command
+func (s *OptimizationService) previousState(ctx context.Context, action models.OptimizationAction) map[string]interface{} {
+	if _, opposite := oppositeCommands[action.Command]; opposite || action.RollbackCommand != "" {
+		return nil
+	}
+
+	telemetry, err := s.telemetryRepo.FindLatestByDevice(ctx, action.DeviceID)
+	if err != nil {
+		return nil
+	}
+
+	previous := make(map[string]interface{})
+	for _, change := range estimateStateChanges(action, telemetry.Metrics) {
+		if change.Current != nil {
+			previous[change.Metric] = *change.Current
+		}
 	}
+	return previous
+}
 
-	// Mark scenario as completed
-	_ = s.optimizationRepo.UpdateProgress(ctx, scenario.ScenarioID, 1.0, models.OptimizationStatusCompleted)
+// oppositeCommands maps the commands undone by another command to that command
+var oppositeCommands = map[string]string{
+	"TURN_ON":  "TURN_OFF",
+	"TURN_OFF": "TURN_ON",
 }
 
-// updateActionStatus updates the status of an action in a scenario
-func (s *OptimizationService) updateActionStatus(ctx context.Context, scenarioID, deviceID, status, commandID string) {
-	s.optimizationRepo.UpdateActionStatus(ctx, scenarioID, deviceID, status, commandID)
+// rollbackScenario undoes the actions of a scenario that were sent or applied, last first, and
+// returns how many were rolled back and how many could not be
+func (s *OptimizationService) rollbackScenario(ctx context.Context, scenario *models.OptimizationScenario) (int, int) {
+	return s.rollbackActions(ctx, scenario, func(models.OptimizationAction) bool { return true })
+}
+
+// rollbackActions undoes the sent or applied actions of a scenario that match a filter, last
+// first, and returns how many were rolled back and how many could not be
+func (s *OptimizationService) rollbackActions(ctx context.Context, scenario *models.OptimizationScenario, match func(models.OptimizationAction) bool) (int, int) {
+	rolledBack, notRolledBack := 0, 0
+	for i := len(scenario.Actions) - 1; i >= 0; i-- {
+		action := &scenario.Actions[i]
+		if action.Status != models.ActionStatusSent && action.Status != models.ActionStatusApplied {
+			continue
+		}
+		if !match(*action) {
+			continue
+		}
+
+		command, params, ok := rollbackCommand(*action)
+		if !ok {
+			s.updateAction(ctx, scenario.ScenarioID, i, bson.M{"last_error": "no rollback command: the device's previous state is unknown"})
+			notRolledBack++
+			continue
+		}
+
+		rollback := &models.DeviceCommand{
+			CommandID:  uuid.New().String(),
+			DeviceID:   action.DeviceID,
+			BuildingID: scenario.BuildingID,
+			Command:    command,
+			Params:     params,
+			Status:     models.CommandStatusPending,
+			IssuedBy:   scenario.CreatedBy,
+		}
+		if _, err := s.commandRepo.Create(ctx, rollback); err != nil {
+			s.updateAction(ctx, scenario.ScenarioID, i, bson.M{"last_error": fmt.Sprintf("failed to create rollback command: %v", err)})
+			notRolledBack++
+			continue
+		}
+
+		action.Status = models.ActionStatusRolledBack
+		action.RollbackCommandID = rollback.CommandID
+		s.updateAction(ctx, scenario.ScenarioID, i, bson.M{
+			"status":              models.ActionStatusRolledBack,
+			"rollback_command_id": rollback.CommandID,
+		})
+		rolledBack++
+	}
+	return rolledBack, notRolledBack
+}
+
+// rollbackCommand returns the command and params that undo an action: its own rollback command,
+// the opposite command, or the action's command with the device's previous values
+func rollbackCommand(action models.OptimizationAction) (string, map[string]interface{}, bool) {
+	if action.RollbackCommand != "" {
+		return action.RollbackCommand, action.RollbackParams, true
+	}
+	if opposite, ok := oppositeCommands[action.Command]; ok {
+		return opposite, map[string]interface{}{}, true
+	}
+	if len(action.PreviousState) > 0 {
+		return action.Command, action.PreviousState, true
+	}
+	return "", nil, false
+}
+
+// ResumeScenario executes the actions of a completed, failed or paused scenario that were not
+// carried out again: failed, timed out, rolled back and pending actions. Actions that were sent or
+// applied are kept, and actions on devices the comfort guardrail stepped in on are not sent again.
+// The scenario is queued for execution like a newly applied one.
+func (s *OptimizationService) ResumeScenario(ctx context.Context, scenarioID string) (*models.OptimizationScenarioResponse, error) {
+	scenario, err := s.optimizationRepo.FindByScenarioID(ctx, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	switch scenario.ExecutionStatus {
+	case models.OptimizationStatusCompleted, models.OptimizationStatusFailed, models.OptimizationStatusPaused:
+	default:
+		return nil, fmt.Errorf("validation failed: only completed, failed or paused scenarios can be resumed, scenario is %s", scenario.ExecutionStatus)
+	}
+
+	// Devices that left the comfort band are not acted on again
+	guarded := guardedDevices(scenario)
+
+	var resumed []models.OptimizationAction
+	for i := range scenario.Actions {
+		action := &scenario.Actions[i]
+		if action.Status == models.ActionStatusSent || action.Status == models.ActionStatusApplied {
+			continue
+		}
+		if guarded[action.DeviceID] {
+			continue
+		}
+		action.Status = models.ActionStatusPending
+		action.CommandID = ""
+		action.Attempts = 0
+		action.LastError = ""
+		action.PreviousState = nil
+		action.RollbackCommandID = ""
+		resumed = append(resumed, *action)
+	}
+	if len(resumed) == 0 {
+		return nil, errors.New("validation failed: scenario has no failed or pending actions")
+	}
+
+	updated, err := s.optimizationRepo.Resume(ctx, scenarioID, scenario.Actions)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &scenarioJobPayload{ScenarioID: scenarioID, Predictions: s.devicePredictions(ctx, resumed)}
+	if _, err := s.jobQueue.Enqueue(ctx, scenarioExecutionJob, payload, nil); err != nil {
+		_ = s.optimizationRepo.UpdateProgress(ctx, scenarioID, updated.Progress, models.OptimizationStatusFailed)
+		return nil, err
+	}
+
+	log.Printf("Resumed %d of %d actions of scenario %s", len(resumed), len(scenario.Actions), scenarioID)
+	return updated.ToResponse(), nil
+}
+
+// executedAction records the outcome of a scenario action for the ScenarioExecuted event
+func executedAction(action models.OptimizationAction, status, commandID string) events.ExecutedAction {
+	return events.ExecutedAction{
+		DeviceID:   action.DeviceID,
+		Command:    action.Command,
+		Status:     status,
+		CommandID:  commandID,
+		ExecutedAt: time.Now(),
+	}
+}
+
+// updateAction updates fields of the action at the given position in a scenario
+func (s *OptimizationService) updateAction(ctx context.Context, scenarioID string, index int, updates bson.M) {
+	if err := s.optimizationRepo.UpdateAction(ctx, scenarioID, index, updates); err != nil {
+		log.Printf("Failed to update action %d of scenario %s: %v", index, scenarioID, err)
+	}
 }
 
 // validateApplyOptimization validates an apply optimization request
@@ -215,5 +661,82 @@ func (s *OptimizationService) validateApplyOptimization(req *models.ApplyOptimiz
 	if len(req.Actions) == 0 {
 		return fmt.Errorf("at least one action is required")
 	}
+	if req.Policy != nil {
+		if err := validateRetryPolicy(req.Policy.Retry); err != nil {
+			return err
+		}
+		if req.Policy.FailureThresholdPercent < 0 || req.Policy.FailureThresholdPercent > 100 {
+			return fmt.Errorf("failureThresholdPercent must be between 0 and 100")
+		}
+	}
+	for i, action := range req.Actions {
+		if action.DeviceID == "" && action.LocationID == "" {
+			return fmt.Errorf("action %d: deviceId or locationId is required", i)
+		}
+		if action.DeviceType != "" && (action.LocationID == "" || action.DeviceID != "") {
+			return fmt.Errorf("action %d: deviceType only applies to actions on a location", i)
+		}
+		if action.Retry != nil {
+			if err := validateRetryPolicy(*action.Retry); err != nil {
+				return fmt.Errorf("action %d: %w", i, err)
+			}
+		}
+		if action.RollbackParams != nil && action.RollbackCommand == "" {
+			return fmt.Errorf("action %d: rollbackParams require a rollbackCommand", i)
+		}
+	}
+	if req.Comfort != nil {
+		if err := validateComfortBand(req.Comfort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandLocationActions replaces each action on a location with one action per device of the
+// scenario's building assigned to that location or below it, optionally only devices of the
+// action's device type
+func (s *OptimizationService) expandLocationActions(ctx context.Context, req *models.ApplyOptimizationRequest) error {
+	expanded := make([]models.OptimizationAction, 0, len(req.Actions))
+	for i, action := range req.Actions {
+		if action.DeviceID != "" {
+			expanded = append(expanded, action)
+			continue
+		}
+
+		devices, err := s.deviceRepo.FindByLocation(ctx, action.LocationID)
+		if err != nil {
+			return fmt.Errorf("failed to list devices of location %s: %w", action.LocationID, err)
+		}
+		matched := 0
+		for _, device := range devices {
+			if device.Location.BuildingID != req.BuildingID || (action.DeviceType != "" && device.Type != action.DeviceType) {
+				continue
+			}
+			deviceAction := action
+			deviceAction.DeviceID = device.DeviceID
+			deviceAction.DeviceType = ""
+			expanded = append(expanded, deviceAction)
+			matched++
+		}
+		if matched == 0 {
+			return fmt.Errorf("validation failed: action %d: no matching devices of building %s are assigned to location %s", i, req.BuildingID, action.LocationID)
+		}
+	}
+	if len(expanded) > maxScenarioActions {
+		return fmt.Errorf("validation failed: the scenario expands to %d actions, more than %d", len(expanded), maxScenarioActions)
+	}
+	req.Actions = expanded
+	return nil
+}
+
+// validateRetryPolicy validates the retry policy of a scenario or action
+func validateRetryPolicy(policy models.RetryPolicy) error {
+	if policy.MaxAttempts < 0 || policy.MaxAttempts > maxActionAttempts {
+		return fmt.Errorf("maxAttempts must be between 1 and %d", maxActionAttempts)
+	}
+	if policy.BackoffSeconds < 0 || policy.BackoffSeconds > maxRetryBackoffSeconds {
+		return fmt.Errorf("backoffSeconds must be between 0 and %d", maxRetryBackoffSeconds)
+	}
 	return nil
 }
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/mqtt"
+	"iot-control-service/internal/repository"
+	"iot-control-service/internal/tenant"
+)
+
+// maxProvisioningTokenTTL caps how long a claim token may stay valid
+const maxProvisioningTokenTTL = 30 * 24 * time.Hour
+
+// ProvisioningService handles claim tokens and the self-registration of new devices
+type ProvisioningService struct {
+	provisioningRepo *repository.ProvisioningRepository
+	deviceRepo       *repository.DeviceRepository
+	deviceService    *DeviceService
+	defaultTTL       time.Duration
+}
+
+// NewProvisioningService creates a new provisioning service
+func NewProvisioningService(
+	provisioningRepo *repository.ProvisioningRepository,
+	deviceRepo *repository.DeviceRepository,
+	deviceService *DeviceService,
+	defaultTTL time.Duration,
+) *ProvisioningService {
+	return &ProvisioningService{
+		provisioningRepo: provisioningRepo,
+		deviceRepo:       deviceRepo,
+		deviceService:    deviceService,
+		defaultTTL:       defaultTTL,
+	}
+}
+
+// CreateToken generates a one-time claim token bound to a building. The token is returned
+// once; only its hash is stored.
+func (s *ProvisioningService) CreateToken(ctx context.Context, req *models.CreateProvisioningTokenRequest, userID string) (*models.CreateProvisioningTokenResponse, error) {
+	if strings.TrimSpace(req.BuildingID) == "" {
+		return nil, fmt.Errorf("validation failed: building ID is required")
+	}
+	if !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return nil, fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
+	if req.ExpiresInHours < 0 {
+		return nil, fmt.Errorf("validation failed: expiresInHours must not be negative")
+	}
+	ttl := s.defaultTTL
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	if ttl > maxProvisioningTokenTTL {
+		return nil, fmt.Errorf("validation failed: tokens may be valid for at most %d hours", int(maxProvisioningTokenTTL.Hours()))
+	}
+
+	deviceType := ""
+	if req.DeviceType != "" {
+		deviceType = NormalizeDeviceTypeName(req.DeviceType)
+		if _, err := s.deviceService.deviceTypeRepo.FindByName(ctx, deviceType); err != nil {
+			if err.Error() == "device type not found" {
+				return nil, fmt.Errorf("validation failed: unknown device type %s", req.DeviceType)
+			}
+			return nil, err
+		}
+	}
+
+	secret, err := randomSecret()
+	if err != nil {
+		return nil, err
+	}
+	token := &models.ProvisioningToken{
+		TokenHash:   hashSecret(secret),
+		TokenPrefix: secret[:8],
+		BuildingID:  req.BuildingID,
+		DeviceType:  deviceType,
+		Description: req.Description,
+		Status:      models.ProvisioningTokenActive,
+		ExpiresAt:   time.Now().Add(ttl),
+		CreatedBy:   userID,
+	}
+
+	created, err := s.provisioningRepo.Create(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provisioning token: %w", err)
+	}
+
+	return &models.CreateProvisioningTokenResponse{Token: secret, ProvisioningToken: created}, nil
+}
+
+// ListOutstandingTokens lists tokens that can still be claimed
+func (s *ProvisioningService) ListOutstandingTokens(ctx context.Context, buildingID string) ([]*models.ProvisioningToken, error) {
+	return s.provisioningRepo.FindOutstanding(ctx, buildingID)
+}
+
+// RevokeToken invalidates an outstanding token
+func (s *ProvisioningService) RevokeToken(ctx context.Context, tokenID, userID string) (*models.ProvisioningToken, error) {
+	return s.provisioningRepo.Revoke(ctx, tokenID, userID)
+}
+
+// ClaimDevice registers a new device in the token's building and issues its credentials. The
+// token is reserved before the device is created so it can only be used once; it is released
+// again if registration fails.
+func (s *ProvisioningService) ClaimDevice(ctx context.Context, req *models.ClaimDeviceRequest) (*models.ClaimDeviceResponse, error) {
+	if req.Token == "" || req.DeviceID == "" || req.Type == "" {
+		return nil, fmt.Errorf("validation failed: token, deviceId and type are required")
+	}
+
+	token, err := s.provisioningRepo.Reserve(ctx, hashSecret(req.Token), req.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.registerClaimedDevice(ctx, token, req)
+	if err != nil {
+		if releaseErr := s.provisioningRepo.Release(ctx, token.ID); releaseErr != nil {
+			log.Printf("Failed to release provisioning token %s: %v", token.ID.Hex(), releaseErr)
+		}
+		return nil, err
+	}
+
+	log.Printf("Device %s claimed provisioning token %s in building %s", req.DeviceID, token.ID.Hex(), token.BuildingID)
+	return response, nil
+}
+
+// registerClaimedDevice creates the device of a reserved token and stores its credential hash
+func (s *ProvisioningService) registerClaimedDevice(ctx context.Context, token *models.ProvisioningToken, req *models.ClaimDeviceRequest) (*models.ClaimDeviceResponse, error) {
+	if token.DeviceType != "" && NormalizeDeviceTypeName(req.Type) != token.DeviceType {
+		return nil, fmt.Errorf("validation failed: token only allows devices of type %s", token.DeviceType)
+	}
+
+	location := req.Location
+	location.BuildingID = token.BuildingID
+	device, err := s.deviceService.RegisterDevice(ctx, &models.RegisterDeviceRequest{
+		DeviceID:     req.DeviceID,
+		Type:         req.Type,
+		Model:        req.Model,
+		Name:         req.Name,
+		BuildingID:   token.BuildingID,
+		Location:     location,
+		Capabilities: req.Capabilities,
+		Metadata:     req.Metadata,
+	}, token.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomSecret()
+	if err == nil {
+		_, err = s.deviceRepo.Update(ctx, device.ID, bson.M{"credential_hash": hashSecret(secret)})
+	}
+	if err != nil {
+		// Without credentials the device cannot connect, so undo the registration
+		if deleteErr := s.deviceRepo.PurgeByDeviceID(ctx, device.DeviceID); deleteErr != nil {
+			log.Printf("Failed to remove device %s after issuing credentials failed: %v", device.DeviceID, deleteErr)
+		}
+		return nil, fmt.Errorf("failed to issue device credentials: %w", err)
+	}
+
+	return &models.ClaimDeviceResponse{
+		Device:       device,
+		DeviceSecret: secret,
+		Topics: map[string]string{
+			mqtt.TopicKindTelemetry: mqtt.DeviceTopic(token.BuildingID, device.DeviceID, mqtt.TopicKindTelemetry),
+			mqtt.TopicKindCommand:   mqtt.DeviceTopic(token.BuildingID, device.DeviceID, mqtt.TopicKindCommand),
+			mqtt.TopicKindAck:       mqtt.DeviceTopic(token.BuildingID, device.DeviceID, mqtt.TopicKindAck),
+		},
+	}, nil
+}
+
+// randomSecret returns a random 32-byte secret, hex encoded
+func randomSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashSecret returns the SHA-256 hash of a token or device secret, hex encoded
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
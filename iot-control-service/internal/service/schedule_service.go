@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/repository"
+)
+
+// scheduleBatchSize bounds how many due scheduled commands are dispatched per tick
+const scheduleBatchSize = 100
+
+// ScheduleService handles commands scheduled for a future time or on a recurring cron schedule
+type ScheduleService struct {
+	scheduleRepo   *repository.ScheduledCommandRepository
+	deviceRepo     *repository.DeviceRepository
+	controlService *ControlService
+	locator        BuildingLocator
+}
+
+// NewScheduleService creates a new schedule service
+func NewScheduleService(
+	scheduleRepo *repository.ScheduledCommandRepository,
+	deviceRepo *repository.DeviceRepository,
+	controlService *ControlService,
+	locator BuildingLocator,
+) *ScheduleService {
+	return &ScheduleService{
+		scheduleRepo:   scheduleRepo,
+		deviceRepo:     deviceRepo,
+		controlService: controlService,
+		locator:        locator,
+	}
+}
+
+// ScheduleCommand creates a scheduled command for a device from a request with scheduledAt or cron set
+func (s *ScheduleService) ScheduleCommand(ctx context.Context, deviceID string, req *models.SendCommandRequest, userID string) (*models.ScheduledCommand, error) {
+	device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("device not found: %w", err)
+	}
+
+	if err := s.controlService.validateCommand(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	now := time.Now()
+	schedule := &models.ScheduledCommand{
+		ScheduleID: uuid.New().String(),
+		DeviceID:   deviceID,
+		BuildingID: device.Location.BuildingID,
+		Command:    req.Command,
+		Params:     req.Params,
+		TTLSeconds: req.TTLSeconds,
+		Status:     models.ScheduleStatusActive,
+		CreatedBy:  userID,
+	}
+
+	switch {
+	case req.ScheduledAt != nil && req.Cron != "":
+		return nil, errors.New("validation failed: scheduledAt and cron are mutually exclusive")
+	case req.ScheduledAt != nil:
+		if !req.ScheduledAt.After(now) {
+			return nil, errors.New("validation failed: scheduledAt must be in the future")
+		}
+		scheduledAt := req.ScheduledAt.UTC()
+		schedule.ScheduledAt = &scheduledAt
+		schedule.NextRunAt = &scheduledAt
+	default:
+		cron, err := ParseCron(req.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("validation failed: %w", err)
+		}
+		loc, err := buildingLocation(ctx, s.locator, schedule.BuildingID)
+		if err != nil {
+			return nil, err
+		}
+		next := cron.Next(now, loc)
+		if next.IsZero() {
+			return nil, errors.New("validation failed: cron expression never matches")
+		}
+		schedule.Cron = req.Cron
+		schedule.NextRunAt = &next
+	}
+
+	return s.scheduleRepo.Create(ctx, schedule)
+}
+
+// ListScheduledCommands lists scheduled commands for a device
+func (s *ScheduleService) ListScheduledCommands(ctx context.Context, deviceID, status string, page, limit int) ([]*models.ScheduledCommand, int64, error) {
+	if err := checkDeviceAccess(ctx, s.deviceRepo, deviceID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.scheduleRepo.FindByDeviceID(ctx, deviceID, status, page, limit)
+}
+
+// PauseScheduledCommand stops an active scheduled command from running until it is resumed
+func (s *ScheduleService) PauseScheduledCommand(ctx context.Context, deviceID, scheduleID string) (*models.ScheduledCommand, error) {
+	schedule, err := s.findForDevice(ctx, deviceID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Status != models.ScheduleStatusActive {
+		return nil, errors.New("scheduled command is not active")
+	}
+
+	return s.transition(ctx, schedule, models.ScheduleStatusActive, models.ScheduleStatusPaused, schedule.NextRunAt)
+}
+
+// ResumeScheduledCommand reactivates a paused scheduled command. Recurring commands continue
+// with their next occurrence; a one-off command whose time passed while paused runs right away.
+func (s *ScheduleService) ResumeScheduledCommand(ctx context.Context, deviceID, scheduleID string) (*models.ScheduledCommand, error) {
+	schedule, err := s.findForDevice(ctx, deviceID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Status != models.ScheduleStatusPaused {
+		return nil, errors.New("scheduled command is not paused")
+	}
+
+	nextRunAt := schedule.NextRunAt
+	if schedule.IsRecurring() {
+		cron, err := ParseCron(schedule.Cron)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := s.scheduleLocation(ctx, schedule)
+		if err != nil {
+			return nil, err
+		}
+		next := cron.Next(time.Now(), loc)
+		nextRunAt = &next
+	}
+
+	return s.transition(ctx, schedule, models.ScheduleStatusPaused, models.ScheduleStatusActive, nextRunAt)
+}
+
+// CancelScheduledCommand permanently cancels a pending scheduled command
+func (s *ScheduleService) CancelScheduledCommand(ctx context.Context, deviceID, scheduleID string) (*models.ScheduledCommand, error) {
+	schedule, err := s.findForDevice(ctx, deviceID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Status != models.ScheduleStatusActive && schedule.Status != models.ScheduleStatusPaused {
+		return nil, errors.New("scheduled command has already finished")
+	}
+
+	return s.transition(ctx, schedule, schedule.Status, models.ScheduleStatusCancelled, nil)
+}
+
+// findForDevice retrieves a scheduled command and checks that it belongs to the device and
+// that the device is accessible to the caller
+func (s *ScheduleService) findForDevice(ctx context.Context, deviceID, scheduleID string) (*models.ScheduledCommand, error) {
+	if err := checkDeviceAccess(ctx, s.deviceRepo, deviceID); err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.scheduleRepo.FindByScheduleID(ctx, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.DeviceID != deviceID {
+		return nil, errors.New("scheduled command not found")
+	}
+	return schedule, nil
+}
+
+// scheduleLocation returns the time zone of the building of a scheduled command's device.
+// Commands scheduled before the building was recorded look it up from the device.
+func (s *ScheduleService) scheduleLocation(ctx context.Context, schedule *models.ScheduledCommand) (*time.Location, error) {
+	buildingID := schedule.BuildingID
+	if buildingID == "" {
+		device, err := s.deviceRepo.FindByDeviceID(ctx, schedule.DeviceID)
+		if err != nil {
+			return nil, err
+		}
+		buildingID = device.Location.BuildingID
+	}
+	return buildingLocation(ctx, s.locator, buildingID)
+}
+
+// transition changes a schedule's status, failing when a concurrent change got there first
+func (s *ScheduleService) transition(ctx context.Context, schedule *models.ScheduledCommand, from, to models.ScheduleStatus, nextRunAt *time.Time) (*models.ScheduledCommand, error) {
+	updated, err := s.scheduleRepo.TransitionStatus(ctx, schedule.ScheduleID, []models.ScheduleStatus{from}, to, nextRunAt)
+	if err != nil {
+		if err.Error() == "scheduled command not found" {
+			return nil, errors.New("scheduled command was modified concurrently")
+		}
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DispatchDueCommands sends every scheduled command whose run is due and returns how many were sent.
+// Runs missed while the service was down are dispatched once, after which recurring commands
+// continue with their next future occurrence.
+func (s *ScheduleService) DispatchDueCommands(ctx context.Context) (int, error) {
+	now := time.Now()
+	due, err := s.scheduleRepo.FindDue(ctx, now, scheduleBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	dispatched := 0
+	for _, schedule := range due {
+		runAt := *schedule.NextRunAt
+
+		var next *time.Time
+		if schedule.IsRecurring() {
+			cron, err := ParseCron(schedule.Cron)
+			if err != nil {
+				log.Printf("Scheduled command %s has an invalid cron expression: %v", schedule.ScheduleID, err)
+				continue
+			}
+			// The run stays due and is retried on the next tick
+			loc, err := s.scheduleLocation(ctx, schedule)
+			if err != nil {
+				log.Printf("Failed to schedule the next run of scheduled command %s: %v", schedule.ScheduleID, err)
+				continue
+			}
+			if n := cron.Next(now, loc); !n.IsZero() {
+				next = &n
+			}
+		}
+
+		claimed, err := s.scheduleRepo.ClaimRun(ctx, schedule.ScheduleID, runAt, next)
+		if err != nil {
+			log.Printf("Failed to claim scheduled command %s: %v", schedule.ScheduleID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		req := &models.SendCommandRequest{
+			Command:    schedule.Command,
+			Params:     schedule.Params,
+			TTLSeconds: schedule.TTLSeconds,
+		}
+		// The idempotency key makes a retried run return the command already sent for it
+		idempotencyKey := fmt.Sprintf("schedule:%s:%d", schedule.ScheduleID, runAt.Unix())
+
+		commandID, errorMsg := "", ""
+		response, _, err := s.controlService.SendCommand(ctx, schedule.DeviceID, req, schedule.CreatedBy, idempotencyKey)
+		if err != nil {
+			errorMsg = err.Error()
+			log.Printf("Failed to dispatch scheduled command %s: %v", schedule.ScheduleID, err)
+		} else {
+			commandID = response.CommandID
+			dispatched++
+		}
+
+		if err := s.scheduleRepo.RecordRun(ctx, schedule.ScheduleID, commandID, errorMsg, now); err != nil {
+			log.Printf("Failed to record run of scheduled command %s: %v", schedule.ScheduleID, err)
+		}
+	}
+
+	return dispatched, nil
+}
+
+// StartSchedulerWorker periodically dispatches due scheduled commands until the context is cancelled
+func (s *ScheduleService) StartSchedulerWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			dispatched, err := s.DispatchDueCommands(ctx)
+			if err != nil {
+				log.Printf("Failed to dispatch scheduled commands: %v", err)
+				continue
+			}
+			if dispatched > 0 {
+				log.Printf("Dispatched %d scheduled commands", dispatched)
+			}
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"iot-control-service/internal/config"
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/mqtt"
+	"iot-control-service/internal/repository"
+)
+
+// simulatedTypes are the device types the simulator cycles through, with each one's rated power in kW
+var simulatedTypes = []struct {
+	Type         string
+	Model        string
+	RatedPower   float64
+	Floor        string
+	Capabilities []string
+}{
+	{"HVAC", "SIM-AHU-200", 12.0, "1", []string{"TURN_ON", "TURN_OFF", "SET_TEMP", "SET_MODE", "REDUCE_POWER", "CURTAIL"}},
+	{"LIGHTING", "SIM-LED-40", 2.5, "1", []string{"TURN_ON", "TURN_OFF", "SET_BRIGHTNESS", "REDUCE_POWER", "CURTAIL"}},
+	{"EQUIPMENT", "SIM-PUMP-7", 5.0, "B", []string{"TURN_ON", "TURN_OFF", "REDUCE_POWER", "CURTAIL"}},
+	{"SENSOR", "SIM-ENV-1", 0, "2", []string{}},
+}
+
+// simulatedDevice holds the state a virtual device reports in its telemetry
+type simulatedDevice struct {
+	deviceID    string
+	buildingID  string
+	deviceType  string
+	ratedPower  float64
+	on          bool
+	mode        string
+	setpoint    float64 // HVAC target temperature
+	temperature float64
+	brightness  float64 // LIGHTING level in percent
+	powerLimit  float64 // share of rated power the device may draw, lowered by REDUCE_POWER and CURTAIL
+	energy      float64 // cumulative kWh
+}
+
+// SimulatorService runs virtual devices for demos and testing without hardware. Simulated devices
+// are registered like real ones, publish telemetry over MQTT on a schedule and acknowledge the
+// commands they receive, so the rest of the platform cannot tell them apart from real devices.
+type SimulatorService struct {
+	deviceRepo *repository.DeviceRepository
+	mqttClient *mqtt.Client
+	config     config.SimulatorConfig
+
+	mu      sync.Mutex
+	devices []*simulatedDevice
+	random  *rand.Rand
+}
+
+// NewSimulatorService creates a new simulator service
+func NewSimulatorService(deviceRepo *repository.DeviceRepository, mqttClient *mqtt.Client, cfg config.SimulatorConfig) *SimulatorService {
+	return &SimulatorService{
+		deviceRepo: deviceRepo,
+		mqttClient: mqttClient,
+		config:     cfg,
+		random:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// RegisterDevices registers the simulated devices that do not exist yet and subscribes to their commands
+func (s *SimulatorService) RegisterDevices(ctx context.Context) error {
+	if s.mqttClient == nil {
+		return fmt.Errorf("simulator requires an MQTT connection")
+	}
+
+	for i := 0; i < s.config.DeviceCount; i++ {
+		spec := simulatedTypes[i%len(simulatedTypes)]
+		deviceID := fmt.Sprintf("%s-%s-%02d", s.config.BuildingID, spec.Type, i+1)
+
+		device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
+		if err != nil {
+			if err.Error() != "device not found" {
+				return err
+			}
+			device, err = s.deviceRepo.Create(ctx, &models.Device{
+				DeviceID: deviceID,
+				Type:     spec.Type,
+				Model:    spec.Model,
+				Location: models.DeviceLocation{
+					BuildingID: s.config.BuildingID,
+					Floor:      spec.Floor,
+					Room:       fmt.Sprintf("Simulated %d", i+1),
+				},
+				Capabilities: spec.Capabilities,
+				Status:       models.DeviceStatusOnline,
+				LastSeen:     time.Now(),
+				Metadata:     map[string]interface{}{"simulated": true},
+				CreatedBy:    "simulator",
+			})
+			if err != nil {
+				return fmt.Errorf("failed to register simulated device %s: %w", deviceID, err)
+			}
+		}
+
+		sim := &simulatedDevice{
+			deviceID:    device.DeviceID,
+			buildingID:  device.Location.BuildingID,
+			deviceType:  spec.Type,
+			ratedPower:  spec.RatedPower,
+			on:          true,
+			mode:        "AUTO",
+			setpoint:    21,
+			temperature: 21 + s.random.Float64()*2 - 1,
+			brightness:  80,
+			powerLimit:  1,
+		}
+		if err := s.mqttClient.SubscribeToCommands(sim.buildingID, sim.deviceID, func(command *models.DeviceCommand) {
+			s.handleCommand(sim, command)
+		}); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		s.devices = append(s.devices, sim)
+		s.mu.Unlock()
+	}
+
+	log.Printf("Simulator running %d devices in building %s", len(s.devices), s.config.BuildingID)
+	return nil
+}
+
+// StartSimulatorWorker publishes telemetry of every simulated device until the context is cancelled
+func (s *SimulatorService) StartSimulatorWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			devices := append([]*simulatedDevice(nil), s.devices...)
+			s.mu.Unlock()
+
+			for _, device := range devices {
+				s.publishTelemetry(device, interval)
+			}
+		}
+	}
+}
+
+// handleCommand applies a command to a simulated device after the configured delay and acknowledges it.
+// Like a real device, it discards expired commands without acknowledging them.
+func (s *SimulatorService) handleCommand(device *simulatedDevice, command *models.DeviceCommand) {
+	if command.IsExpired(time.Now()) {
+		return
+	}
+
+	go func() {
+		time.Sleep(s.config.AckDelay)
+
+		ack := &models.CommandAck{
+			CommandID: command.CommandID,
+			DeviceID:  device.deviceID,
+			Status:    "APPLIED",
+			Timestamp: time.Now(),
+		}
+
+		s.mu.Lock()
+		failed := s.random.Intn(100) < s.config.FailurePercent
+		var err error
+		if failed {
+			err = fmt.Errorf("simulated device failure")
+		} else {
+			err = device.apply(command)
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			ack.Status = "FAILED"
+			ack.ErrorMsg = err.Error()
+		}
+		if err := s.mqttClient.PublishAck(device.buildingID, device.deviceID, ack); err != nil {
+			log.Printf("Simulator failed to acknowledge command %s: %v", command.CommandID, err)
+			return
+		}
+
+		// Report the new state right away so the command can be verified against telemetry
+		if err == nil {
+			s.publishTelemetry(device, 0)
+		}
+	}()
+}
+
+// apply changes the device state as the command requests
+func (d *simulatedDevice) apply(command *models.DeviceCommand) error {
+	if d.deviceType == "SENSOR" {
+		return fmt.Errorf("sensors do not accept commands")
+	}
+
+	switch command.Command {
+	case "TURN_ON":
+		d.on = true
+	case "TURN_OFF":
+		d.on = false
+	case "SET_TEMP":
+		if d.deviceType != "HVAC" {
+			return fmt.Errorf("unsupported command: %s", command.Command)
+		}
+		setpoint, ok := numericParam(command.Params, "temperature", "setpoint", "value")
+		if !ok {
+			return fmt.Errorf("temperature parameter is required")
+		}
+		d.setpoint = setpoint
+	case "SET_MODE":
+		if d.deviceType != "HVAC" {
+			return fmt.Errorf("unsupported command: %s", command.Command)
+		}
+		mode, ok := command.Params["mode"].(string)
+		if !ok {
+			return fmt.Errorf("mode parameter is required")
+		}
+		d.mode = mode
+	case "SET_BRIGHTNESS":
+		if d.deviceType != "LIGHTING" {
+			return fmt.Errorf("unsupported command: %s", command.Command)
+		}
+		brightness, ok := numericParam(command.Params, "brightness", "value")
+		if !ok {
+			return fmt.Errorf("brightness parameter is required")
+		}
+		d.brightness = math.Max(0, math.Min(brightness, 100))
+	case "REDUCE_POWER", "CURTAIL":
+		reduction, ok := numericParam(command.Params, "percent", "reduction")
+		if !ok {
+			reduction = 20
+			if command.Command == "CURTAIL" {
+				reduction = 50
+			}
+		}
+		d.powerLimit = math.Max(0, 1-reduction/100)
+	default:
+		return fmt.Errorf("unsupported command: %s", command.Command)
+	}
+	return nil
+}
+
+// publishTelemetry advances the device's simulated state by elapsed and publishes its readings
+func (s *SimulatorService) publishTelemetry(device *simulatedDevice, elapsed time.Duration) {
+	now := time.Now()
+
+	s.mu.Lock()
+	metrics := device.step(now, elapsed, s.random)
+	s.mu.Unlock()
+
+	telemetry := &models.Telemetry{
+		DeviceID:  device.deviceID,
+		Timestamp: now,
+		Metrics:   metrics,
+	}
+	if err := s.mqttClient.PublishTelemetry(device.buildingID, device.deviceID, telemetry); err != nil {
+		log.Printf("Simulator failed to publish telemetry of %s: %v", device.deviceID, err)
+	}
+}
+
+// step advances the device state and returns its current readings. Load follows a working-day
+// profile: higher during weekday office hours, lower at night and over weekends.
+func (d *simulatedDevice) step(now time.Time, elapsed time.Duration, random *rand.Rand) map[string]interface{} {
+	occupancy := occupancyLevel(now)
+	outdoor := 12 + 6*math.Sin(float64(now.Hour()-9)/24*2*math.Pi)
+
+	metrics := map[string]interface{}{}
+	power := 0.0
+
+	switch d.deviceType {
+	case "HVAC":
+		// The room drifts toward the outdoor temperature and the unit pulls it back toward the setpoint
+		target := outdoor
+		if d.on {
+			target = d.setpoint
+			power = d.ratedPower * math.Min(0.2+math.Abs(outdoor-d.setpoint)/15, 1) * (0.6 + 0.4*occupancy)
+		}
+		d.temperature += (target-d.temperature)*0.5 + random.NormFloat64()*0.1
+		metrics["temperature"] = round1(d.temperature)
+		metrics["humidity"] = round1(45 + 10*occupancy + random.NormFloat64())
+		metrics["mode"] = d.mode
+	case "LIGHTING":
+		if d.on {
+			power = d.ratedPower * d.brightness / 100 * (0.1 + 0.9*occupancy)
+		}
+		metrics["brightness"] = d.brightness
+	case "EQUIPMENT":
+		if d.on {
+			power = d.ratedPower * (0.4 + 0.6*occupancy)
+		}
+	case "SENSOR":
+		metrics["temperature"] = round1(20 + 2*occupancy + random.NormFloat64()*0.3)
+		metrics["humidity"] = round1(40 + 10*occupancy + random.NormFloat64())
+		metrics["co2"] = math.Round(420 + 600*occupancy + random.NormFloat64()*20)
+		metrics["occupancy"] = math.Round(occupancy * 50)
+		return metrics
+	}
+
+	power = math.Min(power, d.ratedPower*d.powerLimit)
+	if power > 0 {
+		power = math.Max(power*(1+random.NormFloat64()*0.03), 0)
+	}
+	d.energy += power * elapsed.Hours()
+
+	metrics["power"] = round2(power)
+	metrics["energy"] = round2(d.energy)
+	metrics["status"] = "OFF"
+	if d.on {
+		metrics["status"] = "ON"
+	}
+	return metrics
+}
+
+// occupancyLevel returns how occupied a building is at a time, from 0 (empty) to 1 (full)
+func occupancyLevel(t time.Time) float64 {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return 0.05
+	}
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	if hour < 7 || hour >= 19 {
+		return 0.05
+	}
+	// Ramp up in the morning, peak around midday and ramp down in the evening
+	return 0.05 + 0.95*math.Sin((hour-7)/12*math.Pi)
+}
+
+// numericParam returns the first of the named command parameters that holds a number
+func numericParam(params map[string]interface{}, names ...string) (float64, bool) {
+	for _, name := range names {
+		if value, ok := toFloat(params[name]); ok {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// round1 rounds a value to one decimal place
+func round1(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+// round2 rounds a value to two decimal places
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -2,33 +2,49 @@ package service
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"iot-control-service/internal/cache"
 	"iot-control-service/internal/models"
 	"iot-control-service/internal/repository"
+	"iot-control-service/internal/tenant"
 )
 
+// DeviceStateCacheKind is the cache kind of device states, keyed by device ID
+const DeviceStateCacheKind = "device_state"
+
+// cachedDeviceState is a cached device state with the building it belongs to, so cache hits
+// are scoped to the caller's organization like repository reads
+type cachedDeviceState struct {
+	BuildingID string             `json:"buildingId"`
+	State      models.DeviceState `json:"state"`
+}
+
 // StateService handles device state business logic
 type StateService struct {
 	deviceRepo    *repository.DeviceRepository
 	telemetryRepo *repository.TelemetryRepository
+	cache         *cache.Cache
 }
 
-// NewStateService creates a new state service
+// NewStateService creates a new state service. The cache may be nil.
 func NewStateService(
 	deviceRepo *repository.DeviceRepository,
 	telemetryRepo *repository.TelemetryRepository,
+	stateCache *cache.Cache,
 ) *StateService {
 	return &StateService{
 		deviceRepo:    deviceRepo,
 		telemetryRepo: telemetryRepo,
+		cache:         stateCache,
 	}
 }
 
 // GetLiveState retrieves live state for all devices
 func (s *StateService) GetLiveState(ctx context.Context) (*models.LiveStateResponse, error) {
 	// Get all online devices
-	devices, _, err := s.deviceRepo.FindAll(ctx, "", "", "ONLINE", 1, 1000)
+	devices, _, err := s.deviceRepo.FindAll(ctx, "", "", "ONLINE", 1, 1000, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -49,6 +65,7 @@ func (s *StateService) GetLiveState(ctx context.Context) (*models.LiveStateRespo
 	for _, device := range devices {
 		state := models.DeviceState{
 			DeviceID:   device.DeviceID,
+			Type:       device.Type,
 			Status:     string(device.Status),
 			LastSeen:   device.LastSeen,
 			LastUpdate: device.UpdatedAt,
@@ -78,30 +95,42 @@ func (s *StateService) GetLiveState(ctx context.Context) (*models.LiveStateRespo
 	}, nil
 }
 
-// GetDeviceState retrieves state for a specific device
+// GetDeviceState retrieves state for a specific device. States are cached until the device
+// changes or reports telemetry.
 func (s *StateService) GetDeviceState(ctx context.Context, deviceID string) (*models.DeviceState, error) {
-	device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
+	cached, err := cache.GetOrLoad(ctx, s.cache, DeviceStateCacheKind, deviceID, func() (cachedDeviceState, error) {
+		return s.loadDeviceState(ctx, deviceID)
+	})
 	if err != nil {
 		return nil, err
 	}
+	if !tenant.AllowsBuilding(ctx, cached.BuildingID) {
+		return nil, errors.New("device not found")
+	}
+	return &cached.State, nil
+}
 
-	telemetry, err := s.telemetryRepo.FindLatestByDevice(ctx, deviceID)
+// loadDeviceState builds the state of a device from its record and latest telemetry
+func (s *StateService) loadDeviceState(ctx context.Context, deviceID string) (cachedDeviceState, error) {
+	device, err := s.deviceRepo.FindByDeviceID(ctx, deviceID)
 	if err != nil {
-		// Device exists but no telemetry yet
-		return &models.DeviceState{
-			DeviceID:   device.DeviceID,
-			Status:     string(device.Status),
-			LastSeen:   device.LastSeen,
-			Metrics:    make(map[string]interface{}),
-			LastUpdate: device.UpdatedAt,
-		}, nil
+		return cachedDeviceState{}, err
 	}
 
-	return &models.DeviceState{
+	state := models.DeviceState{
 		DeviceID:   device.DeviceID,
+		Type:       device.Type,
 		Status:     string(device.Status),
 		LastSeen:   device.LastSeen,
-		Metrics:    telemetry.Metrics,
-		LastUpdate: telemetry.Timestamp,
-	}, nil
+		Metrics:    make(map[string]interface{}),
+		LastUpdate: device.UpdatedAt,
+	}
+
+	// A device without telemetry yet keeps empty metrics
+	if telemetry, err := s.telemetryRepo.FindLatestByDevice(ctx, deviceID); err == nil {
+		state.Metrics = telemetry.Metrics
+		state.LastUpdate = telemetry.Timestamp
+	}
+
+	return cachedDeviceState{BuildingID: device.Location.BuildingID, State: state}, nil
 }
@@ -3,33 +3,64 @@ package service
 import (
 	"context"
 	"fmt"
+	"math"
+	"regexp"
+	"strings"
 	"time"
 
+	"iot-control-service/internal/cache"
+	"iot-control-service/internal/events"
 	"iot-control-service/internal/models"
 	"iot-control-service/internal/repository"
 )
 
+// metricNamePattern restricts metric names so they can be used safely as document field paths
+var metricNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// maxQueryMetrics limits the number of metrics in a single aggregation query
+const maxQueryMetrics = 10
+
+const (
+	// maxExportMetrics limits the number of metric columns in a telemetry export
+	maxExportMetrics = 50
+	// exportBatchSize is the number of documents fetched per round trip while exporting
+	exportBatchSize = 1000
+	// autoRawExportRange is the longest range the auto resolution exports raw readings for
+	autoRawExportRange = 48 * time.Hour
+	// autoHourlyExportRange is the longest range the auto resolution exports hourly buckets for;
+	// longer ranges are exported as daily buckets
+	autoHourlyExportRange = 90 * 24 * time.Hour
+)
+
 // TelemetryService handles telemetry business logic
 type TelemetryService struct {
 	telemetryRepo *repository.TelemetryRepository
 	deviceRepo    *repository.DeviceRepository
+	cache         *cache.Cache
+	eventBus      *events.Bus
 }
 
-// NewTelemetryService creates a new telemetry service
+// NewTelemetryService creates a new telemetry service. The cache holds device states, which
+// are invalidated when a device reports telemetry; it may be nil. Devices coming back online
+// are announced on the event bus, which may also be nil.
 func NewTelemetryService(
 	telemetryRepo *repository.TelemetryRepository,
 	deviceRepo *repository.DeviceRepository,
+	stateCache *cache.Cache,
+	eventBus *events.Bus,
 ) *TelemetryService {
 	return &TelemetryService{
 		telemetryRepo: telemetryRepo,
 		deviceRepo:    deviceRepo,
+		cache:         stateCache,
+		eventBus:      eventBus,
 	}
 }
 
 // IngestTelemetry ingests a single telemetry message
 func (s *TelemetryService) IngestTelemetry(ctx context.Context, req *models.TelemetryIngestRequest, source string) (*models.TelemetryResponse, error) {
 	// Validate device exists
-	_, err := s.deviceRepo.FindByDeviceID(ctx, req.DeviceID)
+	device, err := s.deviceRepo.FindByDeviceID(ctx, req.DeviceID)
 	if err != nil {
 		return nil, fmt.Errorf("device not found: %w", err)
 	}
@@ -46,6 +77,11 @@ func (s *TelemetryService) IngestTelemetry(ctx context.Context, req *models.Tele
 		telemetry.Timestamp = time.Now()
 	}
 
+	applyCalibration(device, telemetry)
+	if err := splitEnergyFlows(telemetry); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
 	createdTelemetry, err := s.telemetryRepo.Create(ctx, telemetry)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create telemetry: %w", err)
@@ -56,7 +92,9 @@ func (s *TelemetryService) IngestTelemetry(ctx context.Context, req *models.Tele
 		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		s.deviceRepo.UpdateLastSeen(bgCtx, req.DeviceID)
+		s.cache.Invalidate(bgCtx, DeviceStateCacheKind, req.DeviceID)
 	}()
+	s.announceOnline(ctx, device)
 
 	return createdTelemetry.ToResponse(), nil
 }
@@ -68,17 +106,19 @@ func (s *TelemetryService) IngestBulkTelemetry(ctx context.Context, req *models.
 	}
 
 	telemetryList := make([]*models.Telemetry, 0, len(req.Telemetry))
-	deviceIDs := make(map[string]bool)
+	devices := make(map[string]*models.Device)
 
 	now := time.Now()
 	for _, t := range req.Telemetry {
 		// Validate device exists
-		if _, exists := deviceIDs[t.DeviceID]; !exists {
-			_, err := s.deviceRepo.FindByDeviceID(ctx, t.DeviceID)
+		device, exists := devices[t.DeviceID]
+		if !exists {
+			var err error
+			device, err = s.deviceRepo.FindByDeviceID(ctx, t.DeviceID)
 			if err != nil {
 				return nil, fmt.Errorf("device %s not found: %w", t.DeviceID, err)
 			}
-			deviceIDs[t.DeviceID] = true
+			devices[t.DeviceID] = device
 		}
 
 		telemetry := &models.Telemetry{
@@ -92,6 +132,11 @@ func (s *TelemetryService) IngestBulkTelemetry(ctx context.Context, req *models.
 			telemetry.Timestamp = now
 		}
 
+		applyCalibration(device, telemetry)
+		if err := splitEnergyFlows(telemetry); err != nil {
+			return nil, fmt.Errorf("validation failed: device %s: %w", t.DeviceID, err)
+		}
+
 		telemetryList = append(telemetryList, telemetry)
 	}
 
@@ -105,10 +150,16 @@ func (s *TelemetryService) IngestBulkTelemetry(ctx context.Context, req *models.
 	go func() {
 		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		for deviceID := range deviceIDs {
+		deviceIDs := make([]string, 0, len(devices))
+		for deviceID := range devices {
 			s.deviceRepo.UpdateLastSeen(bgCtx, deviceID)
+			deviceIDs = append(deviceIDs, deviceID)
 		}
+		s.cache.Invalidate(bgCtx, DeviceStateCacheKind, deviceIDs...)
 	}()
+	for _, device := range devices {
+		s.announceOnline(ctx, device)
+	}
 
 	responses := make([]*models.TelemetryResponse, len(telemetryList))
 	for i, t := range telemetryList {
@@ -118,8 +169,52 @@ func (s *TelemetryService) IngestBulkTelemetry(ctx context.Context, req *models.
 	return responses, nil
 }
 
+// IngestDeviceTelemetry stores telemetry published by a device over MQTT.
+// Readings from devices that are not registered are stored uncalibrated.
+func (s *TelemetryService) IngestDeviceTelemetry(ctx context.Context, telemetry *models.Telemetry, source string) error {
+	device, err := s.deviceRepo.FindByDeviceID(ctx, telemetry.DeviceID)
+	if err == nil {
+		applyCalibration(device, telemetry)
+	}
+	if err := splitEnergyFlows(telemetry); err != nil {
+		return err
+	}
+
+	telemetry.Source = source
+	if _, err := s.telemetryRepo.Create(ctx, telemetry); err != nil {
+		return err
+	}
+
+	// Update device last seen
+	s.deviceRepo.UpdateLastSeen(ctx, telemetry.DeviceID)
+	s.cache.Invalidate(ctx, DeviceStateCacheKind, telemetry.DeviceID)
+	if device != nil {
+		s.announceOnline(ctx, device)
+	}
+	return nil
+}
+
+// announceOnline publishes a status change for a device that reported telemetry while it was not
+// online; reporting telemetry marks a device online
+func (s *TelemetryService) announceOnline(ctx context.Context, device *models.Device) {
+	if device.Status == models.DeviceStatusOnline {
+		return
+	}
+	s.eventBus.Publish(ctx, events.DeviceStatusChanged, &events.DeviceStatusChangedData{
+		DeviceID:       device.DeviceID,
+		BuildingID:     device.Location.BuildingID,
+		PreviousStatus: string(device.Status),
+		Status:         string(models.DeviceStatusOnline),
+		ChangedAt:      time.Now(),
+	})
+}
+
 // GetTelemetryHistory retrieves telemetry history for a device
 func (s *TelemetryService) GetTelemetryHistory(ctx context.Context, deviceID string, from, to time.Time, page, limit int) ([]*models.TelemetryResponse, int64, error) {
+	if err := checkDeviceAccess(ctx, s.deviceRepo, deviceID); err != nil {
+		return nil, 0, err
+	}
+
 	telemetry, total, err := s.telemetryRepo.FindByDeviceID(ctx, deviceID, from, to, page, limit)
 	if err != nil {
 		return nil, 0, err
@@ -135,9 +230,306 @@ func (s *TelemetryService) GetTelemetryHistory(ctx context.Context, deviceID str
 
 // GetLatestTelemetry retrieves the latest telemetry for a device
 func (s *TelemetryService) GetLatestTelemetry(ctx context.Context, deviceID string) (*models.TelemetryResponse, error) {
+	if err := checkDeviceAccess(ctx, s.deviceRepo, deviceID); err != nil {
+		return nil, err
+	}
+
 	telemetry, err := s.telemetryRepo.FindLatestByDevice(ctx, deviceID)
 	if err != nil {
 		return nil, err
 	}
 	return telemetry.ToResponse(), nil
 }
+
+// QueryTelemetry aggregates telemetry by device, building and/or time bucket
+func (s *TelemetryService) QueryTelemetry(ctx context.Context, req *models.TelemetryQueryRequest) (*models.TelemetryQueryResponse, error) {
+	query, err := buildTelemetryQuery(req)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	buckets, err := s.telemetryRepo.Aggregate(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate telemetry: %w", err)
+	}
+
+	response := &models.TelemetryQueryResponse{
+		Metrics:     query.Metrics,
+		Aggregation: query.Aggregation,
+		GroupBy:     query.GroupBy,
+		BuildingID:  query.BuildingID,
+		LocationID:  query.LocationID,
+		From:        query.From,
+		To:          query.To,
+		Buckets:     buckets,
+	}
+	if query.Aggregation == models.TelemetryAggregationPercentile {
+		response.Percentile = query.Percentile
+	}
+
+	return response, nil
+}
+
+// PrepareExport validates a telemetry export, resolves the auto resolution from the length of
+// the range and, when no metrics are requested, finds every metric the device reported in it
+func (s *TelemetryService) PrepareExport(ctx context.Context, req *models.TelemetryExportRequest) (*models.TelemetryExport, error) {
+	export, err := buildTelemetryExport(req)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	if err := checkDeviceAccess(ctx, s.deviceRepo, export.DeviceID); err != nil {
+		return nil, err
+	}
+
+	if len(export.Metrics) == 0 {
+		names, err := s.telemetryRepo.MetricNames(ctx, export.DeviceID, export.From, export.To)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list telemetry metrics: %w", err)
+		}
+		for _, name := range names {
+			// Downsampling uses metric names as field paths
+			if !metricNamePattern.MatchString(name) {
+				continue
+			}
+			if len(export.Metrics) == maxExportMetrics {
+				break
+			}
+			export.Metrics = append(export.Metrics, name)
+		}
+	}
+
+	return export, nil
+}
+
+// StreamExport streams the rows of a prepared telemetry export to fn in timestamp order
+func (s *TelemetryService) StreamExport(ctx context.Context, export *models.TelemetryExport, fn func(*models.TelemetryExportRow) error) error {
+	return s.telemetryRepo.StreamExport(ctx, export, exportBatchSize, fn)
+}
+
+// buildTelemetryExport validates export parameters and applies defaults
+func buildTelemetryExport(req *models.TelemetryExportRequest) (*models.TelemetryExport, error) {
+	format := strings.ToLower(req.Format)
+	if format != "" && format != models.TelemetryExportFormatCSV {
+		return nil, fmt.Errorf("unsupported format %q", req.Format)
+	}
+
+	export := &models.TelemetryExport{
+		DeviceID:   req.DeviceID,
+		From:       req.From,
+		To:         req.To,
+		Resolution: models.TelemetryExportResolution(strings.ToLower(req.Resolution)),
+		Metrics:    splitList(req.Metrics),
+	}
+
+	if len(export.Metrics) > maxExportMetrics {
+		return nil, fmt.Errorf("at most %d metrics can be exported", maxExportMetrics)
+	}
+	for _, metric := range export.Metrics {
+		if !metricNamePattern.MatchString(metric) {
+			return nil, fmt.Errorf("invalid metric name %q", metric)
+		}
+	}
+
+	if export.To.IsZero() {
+		export.To = time.Now()
+	}
+	if export.From.IsZero() {
+		export.From = export.To.AddDate(0, 0, -7) // Default to last 7 days
+	}
+	if !export.From.Before(export.To) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	switch export.Resolution {
+	case "", models.TelemetryResolutionAuto:
+		switch span := export.To.Sub(export.From); {
+		case span <= autoRawExportRange:
+			export.Resolution = models.TelemetryResolutionRaw
+		case span <= autoHourlyExportRange:
+			export.Resolution = models.TelemetryResolutionHour
+		default:
+			export.Resolution = models.TelemetryResolutionDay
+		}
+	case models.TelemetryResolutionRaw, models.TelemetryResolutionMinute,
+		models.TelemetryResolutionHour, models.TelemetryResolutionDay:
+	default:
+		return nil, fmt.Errorf("unsupported resolution %q", req.Resolution)
+	}
+
+	return export, nil
+}
+
+// buildTelemetryQuery validates query parameters and applies defaults
+func buildTelemetryQuery(req *models.TelemetryQueryRequest) (*models.TelemetryQuery, error) {
+	query := &models.TelemetryQuery{
+		Metrics:     splitList(req.Metrics),
+		Aggregation: models.TelemetryAggregation(strings.ToLower(req.Aggregation)),
+		Percentile:  req.Percentile,
+		DeviceIDs:   splitList(req.DeviceIDs),
+		BuildingID:  req.BuildingID,
+		LocationID:  req.LocationID,
+		From:        req.From,
+		To:          req.To,
+		GroupBy:     []models.TelemetryGroupBy{},
+	}
+
+	if len(query.Metrics) == 0 || len(query.Metrics) > maxQueryMetrics {
+		return nil, fmt.Errorf("between 1 and %d metrics are required", maxQueryMetrics)
+	}
+	for _, metric := range query.Metrics {
+		if !metricNamePattern.MatchString(metric) {
+			return nil, fmt.Errorf("invalid metric name %q", metric)
+		}
+	}
+
+	switch query.Aggregation {
+	case "":
+		query.Aggregation = models.TelemetryAggregationAvg
+	case models.TelemetryAggregationSum, models.TelemetryAggregationAvg, models.TelemetryAggregationMin,
+		models.TelemetryAggregationMax, models.TelemetryAggregationCount:
+	case models.TelemetryAggregationPercentile:
+		if query.Percentile == 0 {
+			query.Percentile = 95
+		}
+		if query.Percentile <= 0 || query.Percentile > 100 {
+			return nil, fmt.Errorf("percentile must be between 0 and 100")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported aggregation %q", req.Aggregation)
+	}
+
+	hasPeriod := false
+	for _, group := range splitList(req.GroupBy) {
+		g := models.TelemetryGroupBy(strings.ToLower(group))
+		switch g {
+		case models.TelemetryGroupByDevice, models.TelemetryGroupByBuilding,
+			models.TelemetryGroupByFloor, models.TelemetryGroupByZone, models.TelemetryGroupByRoom:
+		case models.TelemetryGroupByHour, models.TelemetryGroupByDay:
+			if hasPeriod {
+				return nil, fmt.Errorf("groupBy may contain only one of hour or day")
+			}
+			hasPeriod = true
+		default:
+			return nil, fmt.Errorf("unsupported groupBy %q", group)
+		}
+		if !query.HasGroup(g) {
+			query.GroupBy = append(query.GroupBy, g)
+		}
+	}
+
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.AddDate(0, 0, -7) // Default to last 7 days
+	}
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	return query, nil
+}
+
+// applyCalibration corrects the numeric metrics a device has calibration factors for,
+// keeping the original readings in RawMetrics
+func applyCalibration(device *models.Device, telemetry *models.Telemetry) {
+	if len(device.Calibration) == 0 {
+		return
+	}
+
+	metrics := make(map[string]interface{}, len(telemetry.Metrics))
+	for name, value := range telemetry.Metrics {
+		metrics[name] = value
+	}
+
+	for name, calibration := range device.Calibration {
+		raw, ok := telemetry.Metrics[name]
+		if !ok {
+			continue
+		}
+		value, ok := metricValue(raw)
+		if !ok {
+			continue
+		}
+
+		if telemetry.RawMetrics == nil {
+			telemetry.RawMetrics = make(map[string]interface{})
+		}
+		telemetry.RawMetrics[name] = raw
+		metrics[name] = calibration.Apply(value)
+	}
+
+	telemetry.Metrics = metrics
+}
+
+// splitEnergyFlows replaces the signed grid and battery readings of bidirectional meters with
+// their directional metrics, and rejects negative directional energy flows. The signed readings
+// are kept in RawMetrics.
+func splitEnergyFlows(telemetry *models.Telemetry) error {
+	for _, split := range []struct{ signed, positive, negative string }{
+		{models.MetricGridNet, models.MetricGridImport, models.MetricGridExport},
+		{models.MetricBatteryNet, models.MetricBatteryCharge, models.MetricBatteryDischarge},
+	} {
+		raw, ok := telemetry.Metrics[split.signed]
+		if !ok {
+			continue
+		}
+		value, ok := metricValue(raw)
+		if !ok {
+			return fmt.Errorf("metric %s must be a number", split.signed)
+		}
+		if _, ok := telemetry.Metrics[split.positive]; ok {
+			return fmt.Errorf("metric %s cannot be reported together with %s", split.signed, split.positive)
+		}
+		if _, ok := telemetry.Metrics[split.negative]; ok {
+			return fmt.Errorf("metric %s cannot be reported together with %s", split.signed, split.negative)
+		}
+
+		if telemetry.RawMetrics == nil {
+			telemetry.RawMetrics = make(map[string]interface{})
+		}
+		telemetry.RawMetrics[split.signed] = raw
+		delete(telemetry.Metrics, split.signed)
+		telemetry.Metrics[split.positive] = math.Max(value, 0)
+		telemetry.Metrics[split.negative] = math.Max(-value, 0)
+	}
+
+	for _, name := range models.EnergyFlowMetrics {
+		raw, ok := telemetry.Metrics[name]
+		if !ok {
+			continue
+		}
+		if value, ok := metricValue(raw); !ok || value < 0 {
+			return fmt.Errorf("metric %s must be a non-negative number", name)
+		}
+	}
+	return nil
+}
+
+// metricValue converts a reported metric to a float
+func metricValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// splitList splits a comma-separated query parameter, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
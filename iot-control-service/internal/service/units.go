@@ -0,0 +1,90 @@
+package service
+
+import "fmt"
+
+// unitScale places a unit in a dimension with its factor to the dimension's base unit
+type unitScale struct {
+	dimension string
+	factor    float64
+}
+
+// linearUnits are the units converted by a factor, with common spellings of each
+var linearUnits = map[string]unitScale{
+	"Wh":  {"energy", 1},
+	"kWh": {"energy", 1e3},
+	"MWh": {"energy", 1e6},
+	"W":   {"power", 1},
+	"kW":  {"power", 1e3},
+	"MW":  {"power", 1e6},
+	"VA":  {"apparent_power", 1},
+	"kVA": {"apparent_power", 1e3},
+	"mA":  {"current", 1e-3},
+	"A":   {"current", 1},
+	"V":   {"voltage", 1},
+	"kV":  {"voltage", 1e3},
+	"L":   {"volume", 1},
+	"l":   {"volume", 1},
+	"m3":  {"volume", 1e3},
+	"m³":  {"volume", 1e3},
+	"Pa":  {"pressure", 1},
+	"kPa": {"pressure", 1e3},
+	"bar": {"pressure", 1e5},
+}
+
+// temperatureUnits maps spellings of temperature units to C, F or K
+var temperatureUnits = map[string]string{
+	"C": "C", "°C": "C", "degC": "C",
+	"F": "F", "°F": "F", "degF": "F",
+	"K": "K",
+}
+
+// ConvertUnit converts a value between units of the same dimension, e.g. Wh to kWh or °F to °C.
+// Units are case-sensitive, so mW and MW are not confused.
+func ConvertUnit(value float64, from, to string) (float64, error) {
+	if from == to {
+		return value, nil
+	}
+
+	if fromTemp, ok := temperatureUnits[from]; ok {
+		toTemp, ok := temperatureUnits[to]
+		if !ok {
+			return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+		}
+		return celsiusTo(toCelsius(value, fromTemp), toTemp), nil
+	}
+
+	fromScale, ok := linearUnits[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %s", from)
+	}
+	toScale, ok := linearUnits[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %s", to)
+	}
+	if fromScale.dimension != toScale.dimension {
+		return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+	}
+	return value * fromScale.factor / toScale.factor, nil
+}
+
+// toCelsius converts a temperature in C, F or K to °C
+func toCelsius(value float64, unit string) float64 {
+	switch unit {
+	case "F":
+		return (value - 32) * 5 / 9
+	case "K":
+		return value - 273.15
+	}
+	return value
+}
+
+// celsiusTo converts a temperature in °C to C, F or K
+func celsiusTo(value float64, unit string) float64 {
+	switch unit {
+	case "F":
+		return value*9/5 + 32
+	case "K":
+		return value + 273.15
+	}
+	return value
+}
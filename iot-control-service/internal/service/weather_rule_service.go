@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/repository"
+	"iot-control-service/internal/tenant"
+)
+
+// weatherRuleBatchSize bounds how many due weather rules are evaluated per tick
+const weatherRuleBatchSize = 50
+
+// maxWeatherLookaheadHours bounds how far past its run time a rule looks at the forecast
+const maxWeatherLookaheadHours = 48
+
+// WeatherForecaster provides the hourly weather forecast of a building location
+type WeatherForecaster interface {
+	GetWeatherForecast(ctx context.Context, buildingID string, hours int) ([]models.WeatherPoint, error)
+}
+
+// WeatherRuleService handles rules that send device commands depending on the weather forecast
+type WeatherRuleService struct {
+	ruleRepo         *repository.WeatherRuleRepository
+	executionRepo    *repository.WeatherRuleExecutionRepository
+	deviceRepo       *repository.DeviceRepository
+	optimizationRepo *repository.OptimizationRepository
+	controlService   *ControlService
+	forecaster       WeatherForecaster
+	locator          BuildingLocator
+}
+
+// NewWeatherRuleService creates a new weather rule service
+func NewWeatherRuleService(
+	ruleRepo *repository.WeatherRuleRepository,
+	executionRepo *repository.WeatherRuleExecutionRepository,
+	deviceRepo *repository.DeviceRepository,
+	optimizationRepo *repository.OptimizationRepository,
+	controlService *ControlService,
+	forecaster WeatherForecaster,
+	locator BuildingLocator,
+) *WeatherRuleService {
+	return &WeatherRuleService{
+		ruleRepo:         ruleRepo,
+		executionRepo:    executionRepo,
+		deviceRepo:       deviceRepo,
+		optimizationRepo: optimizationRepo,
+		controlService:   controlService,
+		forecaster:       forecaster,
+		locator:          locator,
+	}
+}
+
+// CreateRule validates and stores a new weather rule
+func (s *WeatherRuleService) CreateRule(ctx context.Context, req *models.WeatherRuleRequest, userID string) (*models.WeatherRule, error) {
+	rule := &models.WeatherRule{
+		RuleID:    uuid.New().String(),
+		CreatedBy: userID,
+	}
+	if err := s.applyRequest(ctx, rule, req); err != nil {
+		return nil, err
+	}
+	return s.ruleRepo.Create(ctx, rule)
+}
+
+// GetRule retrieves a weather rule
+func (s *WeatherRuleService) GetRule(ctx context.Context, ruleID string) (*models.WeatherRule, error) {
+	return s.ruleRepo.FindByRuleID(ctx, ruleID)
+}
+
+// ListRules lists weather rules, optionally of one building
+func (s *WeatherRuleService) ListRules(ctx context.Context, req *models.ListWeatherRulesRequest) ([]*models.WeatherRule, int64, error) {
+	return s.ruleRepo.FindAll(ctx, req.BuildingID, req.Page, req.Limit)
+}
+
+// UpdateRule replaces a weather rule; its next evaluation is recalculated from the new schedule
+func (s *WeatherRuleService) UpdateRule(ctx context.Context, ruleID string, req *models.WeatherRuleRequest) (*models.WeatherRule, error) {
+	rule, err := s.ruleRepo.FindByRuleID(ctx, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyRequest(ctx, rule, req); err != nil {
+		return nil, err
+	}
+	return s.ruleRepo.Replace(ctx, rule)
+}
+
+// DeleteRule removes a weather rule and its execution log
+func (s *WeatherRuleService) DeleteRule(ctx context.Context, ruleID string) error {
+	if err := s.ruleRepo.Delete(ctx, ruleID); err != nil {
+		return err
+	}
+	if err := s.executionRepo.DeleteByRuleID(ctx, ruleID); err != nil {
+		log.Printf("Failed to delete execution log of weather rule %s: %v", ruleID, err)
+	}
+	return nil
+}
+
+// ListExecutions lists the evaluations of a weather rule, newest first
+func (s *WeatherRuleService) ListExecutions(ctx context.Context, ruleID string, page, limit int) ([]*models.WeatherRuleExecution, int64, error) {
+	if _, err := s.ruleRepo.FindByRuleID(ctx, ruleID); err != nil {
+		return nil, 0, err
+	}
+	return s.executionRepo.FindByRuleID(ctx, ruleID, page, limit)
+}
+
+// EvaluateNow evaluates a rule against the forecast from the current hour, regardless of its
+// schedule. A dry run logs whether the condition is met without sending commands.
+func (s *WeatherRuleService) EvaluateNow(ctx context.Context, ruleID string, dryRun bool) (*models.WeatherRuleExecution, error) {
+	rule, err := s.ruleRepo.FindByRuleID(ctx, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluate(ctx, rule, time.Now().UTC(), models.WeatherRuleTriggerManual, dryRun)
+}
+
+// EvaluateDueRules evaluates every enabled rule whose run is due and returns how many triggered.
+// Runs missed while the service was down are evaluated once, after which rules continue with
+// their next future run.
+func (s *WeatherRuleService) EvaluateDueRules(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	due, err := s.ruleRepo.FindDue(ctx, now, weatherRuleBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	triggered := 0
+	for _, rule := range due {
+		runAt := *rule.NextRunAt
+
+		cron, err := ParseCron(rule.Cron)
+		if err != nil {
+			log.Printf("Weather rule %s has an invalid cron expression: %v", rule.RuleID, err)
+			continue
+		}
+		// The run stays due and is retried on the next tick
+		loc, err := buildingLocation(ctx, s.locator, rule.BuildingID)
+		if err != nil {
+			log.Printf("Failed to schedule the next run of weather rule %s: %v", rule.RuleID, err)
+			continue
+		}
+		claimed, err := s.ruleRepo.ClaimRun(ctx, rule.RuleID, runAt, cron.Next(now, loc))
+		if err != nil {
+			log.Printf("Failed to claim weather rule %s: %v", rule.RuleID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		execution, err := s.evaluate(ctx, rule, runAt, models.WeatherRuleTriggerSchedule, false)
+		if err != nil {
+			log.Printf("Failed to evaluate weather rule %s: %v", rule.RuleID, err)
+			continue
+		}
+		if execution.Triggered {
+			triggered++
+		}
+	}
+
+	return triggered, nil
+}
+
+// StartWeatherRuleWorker periodically evaluates due weather rules until the context is cancelled
+func (s *WeatherRuleService) StartWeatherRuleWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			triggered, err := s.EvaluateDueRules(ctx)
+			if err != nil {
+				log.Printf("Failed to evaluate weather rules: %v", err)
+				continue
+			}
+			if triggered > 0 {
+				log.Printf("Triggered %d weather rules", triggered)
+			}
+		}
+	}
+}
+
+// evaluate checks a rule's condition against the forecast for its run, sends its commands when
+// the condition is met and logs the outcome
+func (s *WeatherRuleService) evaluate(ctx context.Context, rule *models.WeatherRule, runAt time.Time, trigger string, dryRun bool) (*models.WeatherRuleExecution, error) {
+	execution := &models.WeatherRuleExecution{
+		RuleID:      rule.RuleID,
+		BuildingID:  rule.BuildingID,
+		Trigger:     trigger,
+		DryRun:      dryRun,
+		RunAt:       runAt,
+		Condition:   rule.Condition,
+		EvaluatedAt: time.Now().UTC(),
+	}
+
+	_, to := rule.Condition.Window(runAt)
+	hours := int(to.Sub(execution.EvaluatedAt.Truncate(time.Hour)).Hours())
+	if hours < 1 {
+		hours = 1
+	}
+
+	points, err := s.forecaster.GetWeatherForecast(ctx, rule.BuildingID, hours)
+	if err != nil {
+		execution.Error = fmt.Sprintf("failed to get weather forecast: %v", err)
+	} else if value, at, ok := rule.Condition.Observe(points, runAt); !ok {
+		execution.Error = "weather forecast has no data for the evaluation window"
+	} else {
+		execution.ObservedValue = &value
+		execution.ObservedAt = &at
+		execution.Triggered = rule.Condition.Met(value)
+	}
+
+	if execution.Triggered && !dryRun {
+		idempotencyKey := fmt.Sprintf("weather-rule:%s:%d", rule.RuleID, runAt.Unix())
+		for i, action := range rule.Actions {
+			execution.Actions = append(execution.Actions, s.runAction(ctx, rule, action, fmt.Sprintf("%s:%d", idempotencyKey, i)))
+		}
+	}
+
+	if !dryRun {
+		if err := s.ruleRepo.RecordEvaluation(ctx, rule.RuleID, execution.EvaluatedAt, execution.Triggered); err != nil {
+			log.Printf("Failed to record evaluation of weather rule %s: %v", rule.RuleID, err)
+		}
+	}
+	return s.executionRepo.Create(ctx, execution)
+}
+
+// runAction sends one action of a triggered rule unless an optimization scenario is controlling
+// the device and the rule leaves such devices alone
+func (s *WeatherRuleService) runAction(ctx context.Context, rule *models.WeatherRule, action models.WeatherRuleAction, idempotencyKey string) models.WeatherRuleActionResult {
+	result := models.WeatherRuleActionResult{DeviceID: action.DeviceID, Command: action.Command}
+
+	scenarios, err := s.optimizationRepo.FindActiveByDevice(ctx, action.DeviceID)
+	if err != nil {
+		result.Status = models.WeatherActionFailed
+		result.Reason = fmt.Sprintf("failed to check optimization scenarios: %v", err)
+		return result
+	}
+	if len(scenarios) > 0 {
+		result.ScenarioID = scenarios[0].ScenarioID
+		if rule.ConflictPolicy != models.WeatherConflictOverride {
+			result.Status = models.WeatherActionSkipped
+			result.Reason = fmt.Sprintf("device is controlled by optimization scenario %s", result.ScenarioID)
+			return result
+		}
+		result.Reason = fmt.Sprintf("overrides optimization scenario %s", result.ScenarioID)
+	}
+
+	req := &models.SendCommandRequest{
+		Command:    action.Command,
+		Params:     action.Params,
+		TTLSeconds: action.TTLSeconds,
+	}
+	response, _, err := s.controlService.SendCommand(ctx, action.DeviceID, req, rule.CreatedBy, idempotencyKey)
+	if err != nil {
+		result.Status = models.WeatherActionFailed
+		result.Reason = err.Error()
+		log.Printf("Weather rule %s failed to send %s to device %s: %v", rule.RuleID, action.Command, action.DeviceID, err)
+		return result
+	}
+
+	result.Status = models.WeatherActionSent
+	result.CommandID = response.CommandID
+	return result
+}
+
+// applyRequest validates a rule request and copies it onto the rule, scheduling its next run
+func (s *WeatherRuleService) applyRequest(ctx context.Context, rule *models.WeatherRule, req *models.WeatherRuleRequest) error {
+	if !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return fmt.Errorf("validation failed: building %s does not belong to your organization", req.BuildingID)
+	}
+	switch req.Condition.Metric {
+	case models.WeatherMetricTemperature, models.WeatherMetricHumidity, models.WeatherMetricCloudCover, models.WeatherMetricWindSpeed:
+	default:
+		return fmt.Errorf("validation failed: unknown weather metric %q", req.Condition.Metric)
+	}
+	switch req.Condition.Operator {
+	case models.WeatherOperatorGT, models.WeatherOperatorGTE, models.WeatherOperatorLT, models.WeatherOperatorLTE:
+	default:
+		return fmt.Errorf("validation failed: unknown operator %q", req.Condition.Operator)
+	}
+	if req.Condition.LookaheadHours < 0 || req.Condition.LookaheadHours > maxWeatherLookaheadHours {
+		return fmt.Errorf("validation failed: lookaheadHours must be between 0 and %d", maxWeatherLookaheadHours)
+	}
+
+	conflictPolicy := req.ConflictPolicy
+	switch conflictPolicy {
+	case "":
+		conflictPolicy = models.WeatherConflictSkip
+	case models.WeatherConflictSkip, models.WeatherConflictOverride:
+	default:
+		return fmt.Errorf("validation failed: unknown conflict policy %q", req.ConflictPolicy)
+	}
+
+	cron, err := ParseCron(req.Cron)
+	if err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	loc, err := buildingLocation(ctx, s.locator, req.BuildingID)
+	if err != nil {
+		return err
+	}
+	next := cron.Next(time.Now(), loc)
+	if next.IsZero() {
+		return errors.New("validation failed: cron expression never matches")
+	}
+
+	for _, action := range req.Actions {
+		device, err := s.deviceRepo.FindByDeviceID(ctx, action.DeviceID)
+		if err != nil {
+			if err.Error() == "device not found" {
+				return fmt.Errorf("validation failed: device %s not found", action.DeviceID)
+			}
+			return err
+		}
+		if device.Location.BuildingID != req.BuildingID {
+			return fmt.Errorf("validation failed: device %s is not in building %s", action.DeviceID, req.BuildingID)
+		}
+		if err := s.controlService.validateCommand(&models.SendCommandRequest{Command: action.Command, Params: action.Params}); err != nil {
+			return fmt.Errorf("validation failed: device %s: %w", action.DeviceID, err)
+		}
+	}
+
+	rule.Name = req.Name
+	rule.Description = req.Description
+	rule.BuildingID = req.BuildingID
+	rule.Condition = req.Condition
+	rule.Cron = req.Cron
+	rule.Actions = req.Actions
+	rule.ConflictPolicy = conflictPolicy
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	rule.NextRunAt = nil
+	if rule.Enabled {
+		rule.NextRunAt = &next
+	}
+	return nil
+}
@@ -0,0 +1,486 @@
+// Package settings holds runtime tunables stored in MongoDB. A value set through the admin API
+// overrides the default from the environment and is reloaded by every instance without a restart.
+package settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Kind is the type of a setting's value
+type Kind string
+
+const (
+	KindInt      Kind = "INT"
+	KindFloat    Kind = "FLOAT"
+	KindBool     Kind = "BOOL"
+	KindDuration Kind = "DURATION" // Go duration string, e.g. "90s" or "15m"
+)
+
+// Change actions recorded in the history
+const (
+	ActionSet   = "SET"
+	ActionReset = "RESET"
+)
+
+// Setting describes a tunable and its current value. Overridden is set when the value comes from
+// the admin API rather than the environment default.
+type Setting struct {
+	Key         string      `json:"key"`
+	Kind        Kind        `json:"kind"`
+	Description string      `json:"description"`
+	Value       interface{} `json:"value"`
+	Default     interface{} `json:"default"`
+	Min         interface{} `json:"min,omitempty"`
+	Max         interface{} `json:"max,omitempty"`
+	Overridden  bool        `json:"overridden"`
+	UpdatedBy   string      `json:"updatedBy,omitempty"`
+	UpdatedAt   *time.Time  `json:"updatedAt,omitempty"`
+}
+
+// Change is an entry of a setting's change history
+type Change struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Key       string             `bson:"key" json:"key"`
+	Action    string             `bson:"action" json:"action"`
+	OldValue  interface{}        `bson:"old_value" json:"oldValue"`
+	NewValue  interface{}        `bson:"new_value" json:"newValue"`
+	ChangedBy string             `bson:"changed_by" json:"changedBy"`
+	ChangedAt time.Time          `bson:"changed_at" json:"changedAt"`
+}
+
+// UpdateRequest represents the request to change a setting's value
+type UpdateRequest struct {
+	Value interface{} `json:"value"`
+}
+
+// override is a stored value overriding a setting's default
+type override struct {
+	Key       string      `bson:"_id"`
+	Value     interface{} `bson:"value"`
+	UpdatedBy string      `bson:"updated_by"`
+	UpdatedAt time.Time   `bson:"updated_at"`
+}
+
+// setting is a registered tunable. value holds the parsed current value.
+type setting struct {
+	key         string
+	kind        Kind
+	description string
+	def         interface{}
+	min, max    interface{}
+	parse       func(raw interface{}) (interface{}, error)
+	onChange    []func()
+
+	value      interface{}
+	override   *override
+	overridden bool
+}
+
+// Store holds the registered settings and their values. Any number of service instances can
+// share its collections; each picks up changes on its next reload.
+type Store struct {
+	collection *mongo.Collection
+	history    *mongo.Collection
+
+	mu       sync.RWMutex
+	settings map[string]*setting
+}
+
+// NewStore creates a store on the settings and settings history collections
+func NewStore(collection, history *mongo.Collection) *Store {
+	return &Store{
+		collection: collection,
+		history:    history,
+		settings:   make(map[string]*setting),
+	}
+}
+
+// Int is a handle to an integer setting
+type Int struct {
+	store *Store
+	key   string
+}
+
+// Get returns the current value
+func (v *Int) Get() int { return v.store.value(v.key).(int) }
+
+// OnChange calls fn whenever the value changes
+func (v *Int) OnChange(fn func()) { v.store.onChange(v.key, fn) }
+
+// Float is a handle to a floating point setting
+type Float struct {
+	store *Store
+	key   string
+}
+
+// Get returns the current value
+func (v *Float) Get() float64 { return v.store.value(v.key).(float64) }
+
+// OnChange calls fn whenever the value changes
+func (v *Float) OnChange(fn func()) { v.store.onChange(v.key, fn) }
+
+// Bool is a handle to a feature flag
+type Bool struct {
+	store *Store
+	key   string
+}
+
+// Get returns the current value
+func (v *Bool) Get() bool { return v.store.value(v.key).(bool) }
+
+// OnChange calls fn whenever the value changes
+func (v *Bool) OnChange(fn func()) { v.store.onChange(v.key, fn) }
+
+// Duration is a handle to a duration setting
+type Duration struct {
+	store *Store
+	key   string
+}
+
+// Get returns the current value
+func (v *Duration) Get() time.Duration { return v.store.value(v.key).(time.Duration) }
+
+// OnChange calls fn whenever the value changes
+func (v *Duration) OnChange(fn func()) { v.store.onChange(v.key, fn) }
+
+// RegisterInt adds an integer setting between min and max. Register every setting before Load.
+func (s *Store) RegisterInt(key, description string, def, min, max int) *Int {
+	s.register(&setting{
+		key: key, kind: KindInt, description: description, def: def, min: min, max: max,
+		parse: func(raw interface{}) (interface{}, error) {
+			f, ok := toFloat(raw)
+			if !ok || f != math.Trunc(f) {
+				return nil, errors.New("must be an integer")
+			}
+			if f < float64(min) || f > float64(max) {
+				return nil, fmt.Errorf("must be between %d and %d", min, max)
+			}
+			return int(f), nil
+		},
+	})
+	return &Int{store: s, key: key}
+}
+
+// RegisterFloat adds a floating point setting between min and max
+func (s *Store) RegisterFloat(key, description string, def, min, max float64) *Float {
+	s.register(&setting{
+		key: key, kind: KindFloat, description: description, def: def, min: min, max: max,
+		parse: func(raw interface{}) (interface{}, error) {
+			f, ok := toFloat(raw)
+			if !ok || math.IsNaN(f) {
+				return nil, errors.New("must be a number")
+			}
+			if f < min || f > max {
+				return nil, fmt.Errorf("must be between %g and %g", min, max)
+			}
+			return f, nil
+		},
+	})
+	return &Float{store: s, key: key}
+}
+
+// RegisterBool adds a feature flag
+func (s *Store) RegisterBool(key, description string, def bool) *Bool {
+	s.register(&setting{
+		key: key, kind: KindBool, description: description, def: def,
+		parse: func(raw interface{}) (interface{}, error) {
+			b, ok := raw.(bool)
+			if !ok {
+				return nil, errors.New("must be true or false")
+			}
+			return b, nil
+		},
+	})
+	return &Bool{store: s, key: key}
+}
+
+// RegisterDuration adds a duration setting between min and max
+func (s *Store) RegisterDuration(key, description string, def, min, max time.Duration) *Duration {
+	s.register(&setting{
+		key: key, kind: KindDuration, description: description, def: def, min: min, max: max,
+		parse: func(raw interface{}) (interface{}, error) {
+			str, ok := raw.(string)
+			if !ok {
+				return nil, errors.New(`must be a duration such as "90s" or "15m"`)
+			}
+			d, err := time.ParseDuration(str)
+			if err != nil {
+				return nil, errors.New(`must be a duration such as "90s" or "15m"`)
+			}
+			if d < min || d > max {
+				return nil, fmt.Errorf("must be between %s and %s", min, max)
+			}
+			return d, nil
+		},
+	})
+	return &Duration{store: s, key: key}
+}
+
+// register adds a setting with its default as the current value
+func (s *Store) register(st *setting) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st.value = st.def
+	s.settings[st.key] = st
+}
+
+// value returns the current value of a registered setting
+func (s *Store) value(key string) interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings[key].value
+}
+
+// onChange adds a change callback to a registered setting
+func (s *Store) onChange(key string, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.settings[key]
+	st.onChange = append(st.onChange, fn)
+}
+
+// Load reads the stored overrides and applies them. Overrides of unknown settings are ignored,
+// and invalid ones leave the default in place.
+func (s *Store) Load(ctx context.Context) error {
+	cursor, err := s.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var stored []override
+	if err := cursor.All(ctx, &stored); err != nil {
+		return err
+	}
+
+	overrides := make(map[string]*override, len(stored))
+	for i := range stored {
+		overrides[stored[i].Key] = &stored[i]
+	}
+
+	s.mu.Lock()
+	var changed []func()
+	for key, st := range s.settings {
+		value := st.def
+		o := overrides[key]
+		if o != nil {
+			parsed, err := st.parse(o.Value)
+			if err != nil {
+				log.Printf("Ignoring invalid stored value of setting %s: %v", key, err)
+				o = nil
+			} else {
+				value = parsed
+			}
+		}
+		if value != st.value {
+			log.Printf("Setting %s changed to %v", key, display(st.kind, value))
+			changed = append(changed, st.onChange...)
+		}
+		st.value = value
+		st.override = o
+		st.overridden = o != nil
+	}
+	s.mu.Unlock()
+
+	// Callbacks may read settings, so they run without the lock
+	for _, fn := range changed {
+		fn()
+	}
+	return nil
+}
+
+// Start reloads the settings every interval until the context is cancelled, so changes made
+// through another instance are picked up
+func (s *Store) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Load(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to reload settings: %v", err)
+			}
+		}
+	}
+}
+
+// List returns every registered setting ordered by key
+func (s *Store) List() []*Setting {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*Setting, 0, len(s.settings))
+	for _, st := range s.settings {
+		list = append(list, st.view())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+// Get returns a registered setting
+func (s *Store) Get(key string) (*Setting, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st, ok := s.settings[key]
+	if !ok {
+		return nil, errors.New("setting not found")
+	}
+	return st.view(), nil
+}
+
+// Set validates and stores a new value of a setting, records the change and applies it
+func (s *Store) Set(ctx context.Context, key string, raw interface{}, userID string) (*Setting, error) {
+	s.mu.RLock()
+	st, ok := s.settings[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, errors.New("setting not found")
+	}
+
+	parsed, err := st.parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %s %v", key, err)
+	}
+	old, _ := s.Get(key)
+
+	now := time.Now()
+	_, err = s.collection.UpdateOne(ctx,
+		bson.M{"_id": key},
+		bson.M{"$set": bson.M{"value": display(st.kind, parsed), "updated_by": userID, "updated_at": now}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.record(ctx, key, ActionSet, old.Value, display(st.kind, parsed), userID, now)
+
+	if err := s.Load(ctx); err != nil {
+		return nil, err
+	}
+	return s.Get(key)
+}
+
+// Reset removes the stored value of a setting, returning it to the environment default
+func (s *Store) Reset(ctx context.Context, key, userID string) (*Setting, error) {
+	old, err := s.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return nil, err
+	}
+	if result.DeletedCount > 0 {
+		s.record(ctx, key, ActionReset, old.Value, old.Default, userID, time.Now())
+	}
+
+	if err := s.Load(ctx); err != nil {
+		return nil, err
+	}
+	return s.Get(key)
+}
+
+// History returns the changes of a setting, or of every setting when key is empty, newest first
+func (s *Store) History(ctx context.Context, key string, page, limit int) ([]*Change, int64, error) {
+	filter := bson.M{}
+	if key != "" {
+		filter["key"] = key
+	}
+
+	total, err := s.history.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "changed_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := s.history.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	changes := []*Change{}
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, 0, err
+	}
+	return changes, total, nil
+}
+
+// record adds an entry to the change history; a failure is logged but does not undo the change
+func (s *Store) record(ctx context.Context, key, action string, oldValue, newValue interface{}, userID string, at time.Time) {
+	change := &Change{
+		Key:       key,
+		Action:    action,
+		OldValue:  oldValue,
+		NewValue:  newValue,
+		ChangedBy: userID,
+		ChangedAt: at,
+	}
+	if _, err := s.history.InsertOne(ctx, change); err != nil {
+		log.Printf("Failed to record change of setting %s: %v", key, err)
+	}
+}
+
+// view describes the setting; callers must hold the lock
+func (st *setting) view() *Setting {
+	view := &Setting{
+		Key:         st.key,
+		Kind:        st.kind,
+		Description: st.description,
+		Value:       display(st.kind, st.value),
+		Default:     display(st.kind, st.def),
+		Overridden:  st.overridden,
+	}
+	if st.min != nil {
+		view.Min = display(st.kind, st.min)
+		view.Max = display(st.kind, st.max)
+	}
+	if st.override != nil {
+		updatedAt := st.override.UpdatedAt
+		view.UpdatedBy = st.override.UpdatedBy
+		view.UpdatedAt = &updatedAt
+	}
+	return view
+}
+
+// display returns a value as shown in the API and stored in the database; durations are strings
+func display(kind Kind, value interface{}) interface{} {
+	if kind == KindDuration {
+		return value.(time.Duration).String()
+	}
+	return value
+}
+
+// toFloat converts a JSON or BSON number to float64
+func toFloat(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
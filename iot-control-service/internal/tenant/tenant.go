@@ -0,0 +1,48 @@
+// Package tenant carries the organization a request is scoped to and restricts repository
+// queries to the buildings it owns. Requests without a scope, such as those of super admins,
+// users outside any organization, internal services and background jobs, are not restricted.
+package tenant
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"iot-control-service/internal/models"
+)
+
+type contextKey struct{}
+
+// WithScope returns a copy of ctx carrying the tenant scope of a request
+func WithScope(ctx context.Context, scope *models.TenantScope) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope)
+}
+
+// FromContext returns the tenant scope carried by ctx, or nil when the request is not scoped
+func FromContext(ctx context.Context) *models.TenantScope {
+	scope, _ := ctx.Value(contextKey{}).(*models.TenantScope)
+	return scope
+}
+
+// BuildingFilter restricts a filter to the documents whose field holds a building of the
+// organization ctx is scoped to. A condition the filter already has on the field is kept.
+func BuildingFilter(ctx context.Context, filter bson.M, field string) bson.M {
+	scope := FromContext(ctx)
+	if scope == nil {
+		return filter
+	}
+
+	// An organization without buildings matches nothing rather than failing the query
+	restriction := bson.M{field: bson.M{"$in": append([]string{}, scope.BuildingIDs...)}}
+	if _, ok := filter[field]; ok {
+		return bson.M{"$and": []bson.M{filter, restriction}}
+	}
+	filter[field] = restriction[field]
+	return filter
+}
+
+// AllowsBuilding reports whether ctx may access the data of a building
+func AllowsBuilding(ctx context.Context, buildingID string) bool {
+	scope := FromContext(ctx)
+	return scope == nil || scope.AllowsBuilding(buildingID)
+}
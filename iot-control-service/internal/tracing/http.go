@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport traces the requests of an integration client and propagates the trace context to
+// the called service in the traceparent header
+type Transport struct {
+	Base http.RoundTripper
+	// External keeps the trace context from third-party APIs, whose calls are still traced here
+	External bool
+}
+
+// NewTransport returns a transport tracing the requests it sends through base, or through
+// http.DefaultTransport when base is nil
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// NewExternalTransport returns a transport tracing requests to a third-party API without sending
+// it the trace context
+func NewExternalTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base, External: true}
+}
+
+// WrapClient traces the requests of an HTTP client to other services, sent through its transport
+func WrapClient(client *http.Client) *http.Client {
+	client.Transport = NewTransport(client.Transport)
+	return client
+}
+
+// RoundTrip sends the request in a client span when it is part of a trace. Requests to other
+// services are sent as a copy carrying the trace context.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx, span := StartChild(req.Context(), req.Method+" "+req.URL.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	defer span.End()
+
+	if !t.External {
+		req = req.Clone(ctx)
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		RecordError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/mqtt"
+)
+
+// fakeDeviceLookup serves devices from a map of device IDs to buildings
+type fakeDeviceLookup struct {
+	buildings map[string]string
+	lookups   int
+}
+
+func (l *fakeDeviceLookup) FindByDeviceID(ctx context.Context, deviceID string) (*models.Device, error) {
+	l.lookups++
+	buildingID, ok := l.buildings[deviceID]
+	if !ok {
+		return nil, errors.New("device not found")
+	}
+	return &models.Device{DeviceID: deviceID, Location: models.DeviceLocation{BuildingID: buildingID}}, nil
+}
+
+func TestTopicAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	devices := &fakeDeviceLookup{buildings: map[string]string{"hvac-1": "building-a", "sensor-1": ""}}
+	authorizer := mqtt.NewTopicAuthorizer(devices, false)
+	topic := func(buildingID, deviceID string) mqtt.Topic {
+		return mqtt.Topic{BuildingID: buildingID, DeviceID: deviceID, Kind: mqtt.TopicKindTelemetry}
+	}
+
+	assert.NoError(t, authorizer.Authorize(ctx, topic("building-a", "hvac-1")))
+	assert.Error(t, authorizer.Authorize(ctx, topic("building-b", "hvac-1")), "another building's topic is rejected")
+	assert.Error(t, authorizer.Authorize(ctx, topic("", "hvac-1")), "a device with a building cannot use the flat topic")
+	assert.NoError(t, authorizer.Authorize(ctx, topic("", "sensor-1")), "a device without a building uses the flat topic")
+	assert.Error(t, authorizer.Authorize(ctx, topic("building-a", "unknown")))
+
+	// The cached building answers repeated messages
+	lookups := devices.lookups
+	assert.NoError(t, authorizer.Authorize(ctx, topic("building-a", "hvac-1")))
+	assert.Equal(t, lookups, devices.lookups)
+
+	// A moved device is accepted on its new building's topic right away
+	devices.buildings["hvac-1"] = "building-b"
+	assert.NoError(t, authorizer.Authorize(ctx, topic("building-b", "hvac-1")))
+	assert.Error(t, authorizer.Authorize(ctx, topic("building-a", "hvac-1")), "the old building's topic is rejected after the move")
+
+	// Device updates forget the device, so the building it moved away from is not trusted
+	devices.buildings["hvac-1"] = "building-a"
+	authorizer.Forget("hvac-1")
+	assert.Error(t, authorizer.Authorize(ctx, topic("building-b", "hvac-1")))
+	assert.NoError(t, authorizer.Authorize(ctx, topic("building-a", "hvac-1")))
+
+	// A deleted device is rejected once it is forgotten
+	delete(devices.buildings, "hvac-1")
+	authorizer.Forget("hvac-1")
+	assert.Error(t, authorizer.Authorize(ctx, topic("building-a", "hvac-1")))
+}
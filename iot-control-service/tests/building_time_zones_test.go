@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"iot-control-service/internal/integrations"
+)
+
+// fakeTimeZoneSource serves building time zones from a map, or fails when err is set
+type fakeTimeZoneSource struct {
+	zones   map[string]string
+	err     error
+	lookups int
+}
+
+func (s *fakeTimeZoneSource) GetBuildingTimeZone(ctx context.Context, buildingID string) (string, error) {
+	s.lookups++
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.zones[buildingID], nil
+}
+
+func TestBuildingTimeZones(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeTimeZoneSource{zones: map[string]string{"building-1": "Europe/Berlin", "building-2": "Not/AZone"}}
+	zones := integrations.NewBuildingTimeZones(source)
+
+	loc, err := zones.Location(ctx, "building-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", loc.String())
+
+	// Cached lookups do not reach the registry
+	_, err = zones.Location(ctx, "building-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, source.lookups)
+
+	// Unregistered buildings and unknown zones are in UTC
+	loc, err = zones.Location(ctx, "building-2")
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+	loc, err = zones.Location(ctx, "building-3")
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	// A building that was never looked up cannot be scheduled while the registry is unreachable
+	source.err = errors.New("connection refused")
+	_, err = zones.Location(ctx, "building-4")
+	assert.Error(t, err)
+}
@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"iot-control-service/internal/cache"
+	"iot-control-service/internal/models"
+)
+
+// fakeRedis serves GET, SET, DEL and PING from memory, ignoring expiry
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	values   map[string]string
+	ttls     map[string]string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &fakeRedis{listener: listener, values: map[string]string{}, ttls: map[string]string{}}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			reply = "+PONG\r\n"
+		case "GET":
+			if value, ok := f.values[args[1]]; ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case "SET":
+			f.values[args[1]] = args[2]
+			if len(args) == 5 {
+				f.ttls[args[1]] = args[4]
+			}
+			reply = "+OK\r\n"
+		case "DEL":
+			deleted := 0
+			for _, key := range args[1:] {
+				if _, ok := f.values[key]; ok {
+					delete(f.values, key)
+					deleted++
+				}
+			}
+			reply = fmt.Sprintf(":%d\r\n", deleted)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// kindStats returns the statistics of a kind
+func kindStats(t *testing.T, c *cache.Cache, kind string) cache.KindStats {
+	for _, stats := range c.Stats().Kinds {
+		if stats.Kind == kind {
+			return stats
+		}
+	}
+	t.Fatalf("no statistics for kind %s", kind)
+	return cache.KindStats{}
+}
+
+// TestCacheDisabled tests that a cache without a Redis address always misses and loads
+func TestCacheDisabled(t *testing.T) {
+	ctx := context.Background()
+	c := cache.New(cache.Config{})
+	assert.False(t, c.Enabled())
+
+	loads := 0
+	for i := 0; i < 2; i++ {
+		state, err := cache.GetOrLoad(ctx, c, "device_state", "dev-1", func() (models.DeviceState, error) {
+			loads++
+			return models.DeviceState{DeviceID: "dev-1"}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "dev-1", state.DeviceID)
+	}
+	assert.Equal(t, 2, loads)
+	assert.NoError(t, c.HealthCheck(ctx))
+
+	var nilCache *cache.Cache
+	_, ok := cache.Get[models.DeviceState](ctx, nilCache, "device_state", "dev-1")
+	assert.False(t, ok)
+	nilCache.Invalidate(ctx, "device_state", "dev-1")
+	assert.False(t, nilCache.Stats().Enabled)
+}
+
+// TestCacheGetOrLoad tests caching, TTLs, invalidation and the hit and miss counters
+func TestCacheGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis(t)
+	c := cache.New(cache.Config{
+		Addr:   server.listener.Addr().String(),
+		Prefix: "iot",
+		TTLs:   map[string]time.Duration{"device_state": 30 * time.Second},
+	})
+	defer c.Close()
+	require.True(t, c.Enabled())
+	require.NoError(t, c.HealthCheck(ctx))
+
+	loads := 0
+	load := func() (models.DeviceState, error) {
+		loads++
+		return models.DeviceState{DeviceID: "dev-1", Status: "ONLINE", Metrics: map[string]interface{}{"power": 1.5}}, nil
+	}
+
+	first, err := cache.GetOrLoad(ctx, c, "device_state", "dev-1", load)
+	require.NoError(t, err)
+	second, err := cache.GetOrLoad(ctx, c, "device_state", "dev-1", load)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loads)
+	assert.Equal(t, first, second)
+	server.mu.Lock()
+	assert.Equal(t, "30000", server.ttls["iot:device_state:dev-1"])
+	server.mu.Unlock()
+
+	// Errors are returned and not cached
+	_, err = cache.GetOrLoad(ctx, c, "device_state", "dev-2", func() (models.DeviceState, error) {
+		return models.DeviceState{}, fmt.Errorf("device not found")
+	})
+	assert.EqualError(t, err, "device not found")
+
+	c.Invalidate(ctx, "device_state", "dev-1")
+	_, err = cache.GetOrLoad(ctx, c, "device_state", "dev-1", load)
+	require.NoError(t, err)
+	assert.Equal(t, 2, loads)
+
+	stats := kindStats(t, c, "device_state")
+	assert.Equal(t, int64(30), stats.TTLSeconds)
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(3), stats.Misses)
+	assert.Equal(t, int64(2), stats.Sets)
+	assert.Equal(t, int64(1), stats.Invalidations)
+	assert.Equal(t, int64(0), stats.Errors)
+	assert.InDelta(t, 25, stats.HitRatePercent, 0.001)
+}
+
+// TestCacheUnavailable tests that an unreachable Redis server turns lookups into counted misses
+func TestCacheUnavailable(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	c := cache.New(cache.Config{Addr: addr, Timeout: 100 * time.Millisecond})
+	state, err := cache.GetOrLoad(ctx, c, "device_state", "dev-1", func() (models.DeviceState, error) {
+		return models.DeviceState{DeviceID: "dev-1"}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "dev-1", state.DeviceID)
+	assert.Error(t, c.HealthCheck(ctx))
+
+	stats := kindStats(t, c, "device_state")
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, int64(2), stats.Errors)
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"iot-control-service/internal/models"
+)
+
+func TestCommandMatchesRequest(t *testing.T) {
+	// Parameters of a stored command, as decoded from BSON
+	stored := &models.DeviceCommand{
+		Command: "SET_TEMP",
+		Params: map[string]interface{}{
+			"temperature": 22.0,
+			"zones":       primitive.A{"north", "south"},
+			"schedule":    primitive.D{{Key: "from", Value: "08:00"}, {Key: "to", Value: "18:00"}},
+		},
+	}
+	// The same parameters decoded from a JSON request body
+	params := func() map[string]interface{} {
+		return map[string]interface{}{
+			"temperature": 22.0,
+			"zones":       []interface{}{"north", "south"},
+			"schedule":    map[string]interface{}{"to": "18:00", "from": "08:00"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		command *models.DeviceCommand
+		req     *models.SendCommandRequest
+		want    bool
+	}{
+		{"same parameters", stored, &models.SendCommandRequest{Command: "SET_TEMP", Params: params()}, true},
+		{"other command", stored, &models.SendCommandRequest{Command: "TURN_OFF", Params: params()}, false},
+		{"other value", stored, &models.SendCommandRequest{Command: "SET_TEMP", Params: func() map[string]interface{} {
+			p := params()
+			p["temperature"] = 23.0
+			return p
+		}()}, false},
+		{"other nested value", stored, &models.SendCommandRequest{Command: "SET_TEMP", Params: func() map[string]interface{} {
+			p := params()
+			p["zones"] = []interface{}{"south", "north"}
+			return p
+		}()}, false},
+		{"missing parameter", stored, &models.SendCommandRequest{Command: "SET_TEMP", Params: func() map[string]interface{} {
+			p := params()
+			delete(p, "schedule")
+			return p
+		}()}, false},
+		{"no parameters", &models.DeviceCommand{Command: "TURN_ON"}, &models.SendCommandRequest{Command: "TURN_ON", Params: map[string]interface{}{}}, true},
+		{"parameters added", &models.DeviceCommand{Command: "TURN_ON"}, &models.SendCommandRequest{Command: "TURN_ON", Params: map[string]interface{}{"level": 1}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.command.MatchesRequest(tt.req))
+		})
+	}
+}
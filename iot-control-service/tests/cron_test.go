@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"iot-control-service/internal/service"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+func TestParseCron_Rejected(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{"too few fields", "0 7 * *", "must have 5 fields"},
+		{"too many fields", "0 7 * * * 2024", "must have 5 fields"},
+		{"minute out of range", "60 * * * *", "invalid minute field"},
+		{"hour out of range", "0 24 * * *", "invalid hour field"},
+		{"day of month zero", "0 0 0 * *", "invalid day-of-month field"},
+		{"day of month out of range", "0 0 32 * *", "invalid day-of-month field"},
+		{"month out of range", "0 0 1 13 *", "invalid month field"},
+		{"day of week out of range", "0 0 * * 8", "invalid day-of-week field"},
+		{"zero step", "*/0 * * * *", "invalid step"},
+		{"negative step", "*/-5 * * * *", "invalid step"},
+		{"reversed range", "30-10 * * * *", "out of range"},
+		{"range past the maximum", "50-61 * * * *", "out of range"},
+		{"not a number", "x * * * *", "invalid value"},
+		{"malformed range", "1-x * * * *", "invalid range"},
+		{"empty list item", "1,,2 * * * *", "invalid value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ParseCron(tt.expr)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2024, 5, 10, 8, 15, 30, 0, time.UTC), time.Date(2024, 5, 10, 8, 16, 0, 0, time.UTC)},
+		{"strictly after a matching minute", "15 8 * * *", time.Date(2024, 5, 10, 8, 15, 0, 0, time.UTC), time.Date(2024, 5, 11, 8, 15, 0, 0, time.UTC)},
+		{"later the same hour", "45 8 * * *", time.Date(2024, 5, 10, 8, 15, 0, 0, time.UTC), time.Date(2024, 5, 10, 8, 45, 0, 0, time.UTC)},
+		{"list", "0 8,12,18 * * *", time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC), time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)},
+		{"step", "*/20 * * * *", time.Date(2024, 5, 10, 8, 41, 0, 0, time.UTC), time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)},
+		{"range with step", "0-30/15 9 * * *", time.Date(2024, 5, 10, 9, 16, 0, 0, time.UTC), time.Date(2024, 5, 10, 9, 30, 0, 0, time.UTC)},
+		{"value with step runs to the maximum", "50/5 * * * *", time.Date(2024, 5, 10, 9, 56, 0, 0, time.UTC), time.Date(2024, 5, 10, 10, 50, 0, 0, time.UTC)},
+		{"weekdays skip the weekend", "0 7 * * 1-5", time.Date(2024, 5, 10, 7, 0, 0, 0, time.UTC), time.Date(2024, 5, 13, 7, 0, 0, 0, time.UTC)},
+		{"sunday as 0", "0 7 * * 0", time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 12, 7, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 7 * * 7", time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 12, 7, 0, 0, 0, time.UTC)},
+		// With both day fields restricted, a day matching either one runs
+		{"day of month or day of week", "0 0 15 * 1", time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)},
+		{"day of month or day of week, month day first", "0 0 11 * 1", time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC)},
+		{"rolls over the hour", "5 * * * *", time.Date(2024, 5, 10, 8, 59, 0, 0, time.UTC), time.Date(2024, 5, 10, 9, 5, 0, 0, time.UTC)},
+		{"rolls over the day", "30 6 * * *", time.Date(2024, 5, 10, 23, 59, 0, 0, time.UTC), time.Date(2024, 5, 11, 6, 30, 0, 0, time.UTC)},
+		{"rolls over the month end", "0 0 1 * *", time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"rolls over the year end", "0 0 1 1 *", time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"31st skips shorter months", "0 0 31 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{"leap day in a leap year", "0 12 29 2 *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"leap day waits for the next leap year", "0 12 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"last day of february in a common year", "0 0 28 2 *", time.Date(2025, 2, 27, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{"never matches", "0 0 31 2 *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+		{"never matches in april", "0 0 31 4 *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := service.ParseCron(tt.expr)
+			require.NoError(t, err)
+
+			next := cron.Next(tt.after, time.UTC)
+			if tt.want.IsZero() {
+				assert.True(t, next.IsZero(), "expected no run, got %s", next)
+				return
+			}
+			assert.Equal(t, tt.want, next)
+		})
+	}
+}
+
+func TestCronNextInLocation(t *testing.T) {
+	berlin := loadLocation(t, "Europe/Berlin")
+
+	cron, err := service.ParseCron("0 13 * * 1-5")
+	require.NoError(t, err)
+
+	// 13:00 in Berlin is 11:00 UTC in summer and 12:00 UTC in winter
+	next := cron.Next(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), berlin)
+	assert.Equal(t, time.Date(2024, 7, 1, 11, 0, 0, 0, time.UTC), next.UTC())
+	next = cron.Next(time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), berlin)
+	assert.Equal(t, time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC), next.UTC())
+
+	// Days are matched in local time: 00:30 on Friday in Berlin is still Thursday in UTC
+	cron, err = service.ParseCron("30 0 * * 5")
+	require.NoError(t, err)
+	next = cron.Next(time.Date(2024, 7, 4, 22, 0, 0, 0, time.UTC), berlin)
+	assert.Equal(t, time.Date(2024, 7, 4, 22, 30, 0, 0, time.UTC), next.UTC())
+
+	// Without a location the expression is evaluated in UTC
+	cron, err = service.ParseCron("0 13 * * *")
+	require.NoError(t, err)
+	next = cron.Next(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), nil)
+	assert.Equal(t, time.Date(2024, 7, 1, 13, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestCronNextAcrossDST(t *testing.T) {
+	berlin := loadLocation(t, "Europe/Berlin")
+	newYork := loadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name  string
+		expr  string
+		loc   *time.Location
+		after time.Time
+		want  []time.Time // consecutive runs, in UTC
+	}{
+		{
+			// Berlin moves from +01:00 to +02:00 at 02:00 on 31 March 2024
+			name:  "daily run keeps its local hour over spring forward",
+			expr:  "0 7 * * *",
+			loc:   berlin,
+			after: time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2024, 3, 30, 6, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 31, 5, 0, 0, 0, time.UTC),
+				time.Date(2024, 4, 1, 5, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			// Berlin moves from +02:00 to +01:00 at 03:00 on 27 October 2024
+			name:  "daily run keeps its local hour over fall back",
+			expr:  "0 7 * * *",
+			loc:   berlin,
+			after: time.Date(2024, 10, 26, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2024, 10, 26, 5, 0, 0, 0, time.UTC),
+				time.Date(2024, 10, 27, 6, 0, 0, 0, time.UTC),
+				time.Date(2024, 10, 28, 6, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "run in the skipped hour is shifted past the gap",
+			expr:  "30 2 * * *",
+			loc:   berlin,
+			after: time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), // 03:30 CEST
+				time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "run in the repeated hour runs once",
+			expr:  "30 2 * * *",
+			loc:   berlin,
+			after: time.Date(2024, 10, 26, 12, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+				time.Date(2024, 10, 28, 1, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			// New York moves from -05:00 to -04:00 at 02:00 on 10 March 2024
+			name:  "skipped hour west of UTC",
+			expr:  "30 2 * * *",
+			loc:   newYork,
+			after: time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2024, 3, 10, 7, 30, 0, 0, time.UTC), // 03:30 EDT
+				time.Date(2024, 3, 11, 6, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			// New York moves from -04:00 to -05:00 at 02:00 on 3 November 2024
+			name:  "repeated hour west of UTC",
+			expr:  "30 1 * * *",
+			loc:   newYork,
+			after: time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+				time.Date(2024, 11, 4, 6, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "every 30 minutes over spring forward",
+			expr:  "*/30 * * * *",
+			loc:   berlin,
+			after: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), // 01:00 CET
+			want: []time.Time{
+				time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC), // 01:30 CET
+				time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC),  // 03:00 CEST
+				time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), // 03:30 CEST
+			},
+		},
+		{
+			name:  "every 30 minutes over fall back",
+			expr:  "*/30 * * * *",
+			loc:   berlin,
+			after: time.Date(2024, 10, 27, 0, 0, 0, 0, time.UTC), // 02:00 CEST
+			want: []time.Time{
+				time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+				time.Date(2024, 10, 27, 2, 0, 0, 0, time.UTC),  // 03:00 CET, the repeated hour is not run again
+				time.Date(2024, 10, 27, 2, 30, 0, 0, time.UTC), // 03:30 CET
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := service.ParseCron(tt.expr)
+			require.NoError(t, err)
+
+			after := tt.after
+			for i, want := range tt.want {
+				next := cron.Next(after, tt.loc)
+				assert.Equal(t, want, next.UTC(), "run %d", i+1)
+				after = next
+			}
+		})
+	}
+}
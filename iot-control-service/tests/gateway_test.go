@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/service"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestConvertUnit(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		from, to string
+		expected float64
+	}{
+		{"Wh to kWh", 1500, "Wh", "kWh", 1.5},
+		{"MWh to kWh", 2, "MWh", "kWh", 2000},
+		{"W to kW", 250, "W", "kW", 0.25},
+		{"mA to A", 1200, "mA", "A", 1.2},
+		{"m3 to L", 1.5, "m3", "L", 1500},
+		{"bar to kPa", 1, "bar", "kPa", 100},
+		{"F to C", 212, "°F", "°C", 100},
+		{"K to C", 273.15, "K", "degC", 0},
+		{"C to F", 20, "C", "F", 68},
+		{"same unit", 42, "kWh", "kWh", 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := service.ConvertUnit(tt.value, tt.from, tt.to)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.expected, converted, 1e-9)
+		})
+	}
+}
+
+func TestConvertUnit_Rejected(t *testing.T) {
+	_, err := service.ConvertUnit(1, "kWh", "kW")
+	assert.EqualError(t, err, "cannot convert kWh to kW")
+
+	_, err = service.ConvertUnit(1, "C", "kWh")
+	assert.EqualError(t, err, "cannot convert C to kWh")
+
+	_, err = service.ConvertUnit(1, "kwh", "Wh")
+	assert.EqualError(t, err, "unknown unit kwh")
+}
+
+func TestModbusDecoder_Words(t *testing.T) {
+	tests := []struct {
+		name     string
+		register models.RegisterMapping
+		words    []uint16
+		expected float64
+	}{
+		{"default uint16", models.RegisterMapping{}, []uint16{65535}, 65535},
+		{"int16", models.RegisterMapping{DataType: models.RegisterInt16}, []uint16{0xFFFE}, -2},
+		{"uint32 high first", models.RegisterMapping{DataType: models.RegisterUint32}, []uint16{0x0001, 0x0002}, 65538},
+		{"uint32 low first", models.RegisterMapping{DataType: models.RegisterUint32, WordOrder: models.RegisterLowWordFirst}, []uint16{0x0002, 0x0001}, 65538},
+		{"int32", models.RegisterMapping{DataType: models.RegisterInt32}, []uint16{0xFFFF, 0xFFFE}, -2},
+		{"float32 high first", models.RegisterMapping{DataType: models.RegisterFloat32}, []uint16{0x4366, 0x8000}, 230.5},
+		{"float32 low first", models.RegisterMapping{DataType: models.RegisterFloat32, WordOrder: models.RegisterLowWordFirst}, []uint16{0x8000, 0x4366}, 230.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := service.ModbusDecoder{}.Decode(tt.register, models.GatewayPointValue{Words: tt.words})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, decoded)
+		})
+	}
+}
+
+func TestModbusDecoder_Rejected(t *testing.T) {
+	decoder := service.ModbusDecoder{}
+
+	_, err := decoder.Decode(models.RegisterMapping{DataType: models.RegisterUint32}, models.GatewayPointValue{Words: []uint16{1}})
+	assert.EqualError(t, err, "UINT32 takes 2 words, got 1")
+
+	_, err = decoder.Decode(models.RegisterMapping{DataType: models.RegisterFloat32}, models.GatewayPointValue{Words: []uint16{0x7FC0, 0x0000}})
+	assert.Error(t, err, "NaN is not a reading")
+
+	_, err = decoder.Decode(models.RegisterMapping{}, models.GatewayPointValue{})
+	assert.Error(t, err)
+
+	assert.Error(t, decoder.ValidateMapping(models.RegisterMapping{DataType: "DOUBLE"}))
+	assert.Error(t, decoder.ValidateMapping(models.RegisterMapping{WordOrder: "MIDDLE"}))
+	assert.NoError(t, decoder.ValidateMapping(models.RegisterMapping{DataType: models.RegisterFloat32, WordOrder: models.RegisterLowWordFirst}))
+}
+
+func TestBACnetDecoder(t *testing.T) {
+	decoder := service.BACnetDecoder{}
+
+	decoded, err := decoder.Decode(models.RegisterMapping{}, models.GatewayPointValue{Value: floatPtr(21.5)})
+	require.NoError(t, err)
+	assert.Equal(t, 21.5, decoded)
+
+	_, err = decoder.Decode(models.RegisterMapping{}, models.GatewayPointValue{Words: []uint16{1}})
+	assert.Error(t, err)
+
+	assert.Error(t, decoder.ValidateMapping(models.RegisterMapping{DataType: models.RegisterUint16}))
+}
+
+func TestMapGatewayPoint(t *testing.T) {
+	t.Run("scale and unit conversion", func(t *testing.T) {
+		// A meter reporting energy in tenths of a Wh across two registers
+		register := models.RegisterMapping{
+			Address:    "40001",
+			Metric:     "energy",
+			DataType:   models.RegisterUint32,
+			Scale:      0.1,
+			Unit:       "Wh",
+			TargetUnit: "kWh",
+		}
+		value, err := service.MapGatewayPoint(service.ModbusDecoder{}, register, models.GatewayPointValue{
+			Address: "40001",
+			Words:   []uint16{0x0001, 0x86A0}, // 100000
+		})
+		require.NoError(t, err)
+		assert.InDelta(t, 10.0, value, 1e-9)
+	})
+
+	t.Run("zero scale is treated as one", func(t *testing.T) {
+		register := models.RegisterMapping{Address: "analogInput:1", Metric: "temperature", Offset: -0.5, Unit: "°F", TargetUnit: "°C"}
+		value, err := service.MapGatewayPoint(service.BACnetDecoder{}, register, models.GatewayPointValue{
+			Address: "analogInput:1",
+			Value:   floatPtr(68.5),
+		})
+		require.NoError(t, err)
+		assert.InDelta(t, 20.0, value, 1e-9)
+	})
+
+	t.Run("decode errors are returned", func(t *testing.T) {
+		register := models.RegisterMapping{Address: "40003", Metric: "power", DataType: models.RegisterInt32}
+		_, err := service.MapGatewayPoint(service.ModbusDecoder{}, register, models.GatewayPointValue{Address: "40003", Words: []uint16{1}})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/service"
+)
+
+func TestCheckComfortBand(t *testing.T) {
+	started := time.Date(2024, 7, 1, 14, 0, 0, 0, time.UTC)
+	band := &models.ComfortBand{MinTemperature: floatPtr(20), MaxTemperature: floatPtr(25), ToleranceCelsius: 0.5}
+	reading := func(temperature interface{}, at time.Time) *models.Telemetry {
+		return &models.Telemetry{DeviceID: "hvac-1", Timestamp: at, Metrics: map[string]interface{}{"temperature": temperature}}
+	}
+
+	t.Run("above the maximum", func(t *testing.T) {
+		intervention, violated := service.CheckComfortBand(band, "hvac-1", reading(26.2, started.Add(10*time.Minute)), started)
+		require.True(t, violated)
+		assert.Equal(t, "hvac-1", intervention.DeviceID)
+		assert.Equal(t, models.ComfortBoundMax, intervention.Bound)
+		assert.Equal(t, 25.0, intervention.Limit)
+		assert.Equal(t, 26.2, intervention.Temperature)
+	})
+
+	t.Run("below the minimum", func(t *testing.T) {
+		intervention, violated := service.CheckComfortBand(band, "hvac-1", reading(19, started.Add(time.Minute)), started)
+		require.True(t, violated)
+		assert.Equal(t, models.ComfortBoundMin, intervention.Bound)
+		assert.Equal(t, 20.0, intervention.Limit)
+	})
+
+	t.Run("within the tolerance", func(t *testing.T) {
+		_, violated := service.CheckComfortBand(band, "hvac-1", reading(25.4, started.Add(time.Minute)), started)
+		assert.False(t, violated)
+		_, violated = service.CheckComfortBand(band, "hvac-1", reading(19.6, started.Add(time.Minute)), started)
+		assert.False(t, violated)
+	})
+
+	t.Run("readings from before the scenario are ignored", func(t *testing.T) {
+		_, violated := service.CheckComfortBand(band, "hvac-1", reading(30.0, started.Add(-time.Minute)), started)
+		assert.False(t, violated)
+	})
+
+	t.Run("devices without a temperature are ignored", func(t *testing.T) {
+		_, violated := service.CheckComfortBand(band, "hvac-1", &models.Telemetry{Timestamp: started.Add(time.Minute), Metrics: map[string]interface{}{"power": 3.2}}, started)
+		assert.False(t, violated)
+		_, violated = service.CheckComfortBand(band, "hvac-1", nil, started)
+		assert.False(t, violated)
+	})
+
+	t.Run("one-sided band", func(t *testing.T) {
+		maxOnly := &models.ComfortBand{MaxTemperature: floatPtr(24)}
+		_, violated := service.CheckComfortBand(maxOnly, "hvac-1", reading(10, started.Add(time.Minute)), started)
+		assert.False(t, violated)
+		_, violated = service.CheckComfortBand(maxOnly, "hvac-1", reading(24.1, started.Add(time.Minute)), started)
+		assert.True(t, violated)
+	})
+}
@@ -0,0 +1,169 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/repository"
+)
+
+// setTempRequest returns a SET_TEMP request with nested parameters, as decoded from JSON
+func setTempRequest(temperature float64) *models.SendCommandRequest {
+	return &models.SendCommandRequest{
+		Command: "SET_TEMP",
+		Params: map[string]interface{}{
+			"temperature": temperature,
+			"zones":       []interface{}{"north", "south"},
+			"schedule":    map[string]interface{}{"from": "08:00", "to": "18:00"},
+		},
+	}
+}
+
+// TestIdempotentCommandReplay tests that a retry with the same idempotency key returns the
+// original command, and that reusing the key for another command or other parameters conflicts
+func TestIdempotentCommandReplay(t *testing.T) {
+	ctx := context.Background()
+	a := newApp(t)
+	seedDevice(t, a.devices, "hvac-1", "HVAC", "building-1")
+
+	original, replayed, err := a.control.SendCommand(ctx, "hvac-1", setTempRequest(22), operatorUser.ID, "key-1")
+	require.NoError(t, err)
+	assert.False(t, replayed)
+
+	// The stored parameters come back as BSON documents and arrays
+	retry, replayed, err := a.control.SendCommand(ctx, "hvac-1", setTempRequest(22), operatorUser.ID, "key-1")
+	require.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, original.CommandID, retry.CommandID)
+
+	_, _, err = a.control.SendCommand(ctx, "hvac-1", setTempRequest(23), operatorUser.ID, "key-1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "idempotency key already used")
+
+	_, _, err = a.control.SendCommand(ctx, "hvac-1", &models.SendCommandRequest{Command: "TURN_OFF"}, operatorUser.ID, "key-1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "idempotency key already used")
+
+	_, total, err := a.commands.FindByDeviceID(ctx, "hvac-1", "", 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
+
+// TestIdempotentCommandConcurrentRetries tests that concurrent requests with the same key
+// create a single command and all return it
+func TestIdempotentCommandConcurrentRetries(t *testing.T) {
+	ctx := context.Background()
+	a := newApp(t)
+	seedDevice(t, a.devices, "hvac-1", "HVAC", "building-1")
+
+	const requests = 8
+	var wg sync.WaitGroup
+	responses := make([]*models.CommandResponse, requests)
+	replays := make([]bool, requests)
+	errs := make([]error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], replays[i], errs[i] = a.control.SendCommand(ctx, "hvac-1", setTempRequest(21), operatorUser.ID, "key-race")
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for i := 0; i < requests; i++ {
+		require.NoError(t, errs[i], "request %d", i)
+		assert.Equal(t, responses[0].CommandID, responses[i].CommandID)
+		if !replays[i] {
+			created++
+		}
+	}
+	assert.Equal(t, 1, created, "exactly one request creates the command")
+
+	_, total, err := a.commands.FindByDeviceID(ctx, "hvac-1", "", 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
+
+// TestLateAckAfterExpiry tests that an acknowledgment arriving after the command expired does
+// not revive it
+func TestLateAckAfterExpiry(t *testing.T) {
+	ctx := context.Background()
+	a := newApp(t)
+	seedDevice(t, a.devices, "hvac-1", "HVAC", "building-1")
+
+	past := time.Now().Add(-time.Minute)
+	_, err := a.commands.Create(ctx, &models.DeviceCommand{
+		CommandID: "command-expired",
+		DeviceID:  "hvac-1",
+		Command:   "TURN_ON",
+		Status:    models.CommandStatusSent,
+		ExpiresAt: &past,
+	})
+	require.NoError(t, err)
+
+	_, err = a.control.ExpireStaleCommands(ctx)
+	require.NoError(t, err)
+
+	err = a.control.ProcessCommandAck(ctx, &models.CommandAck{CommandID: "command-expired", DeviceID: "hvac-1", Status: "APPLIED", Timestamp: time.Now()})
+	assert.Error(t, err)
+
+	command, err := a.commands.FindByCommandID(ctx, "command-expired")
+	require.NoError(t, err)
+	assert.Equal(t, models.CommandStatusExpired, command.Status)
+	assert.Nil(t, command.AppliedAt)
+}
+
+// TestCommandRepositoryAcknowledge tests that acknowledgments only apply to commands awaiting one
+func TestCommandRepositoryAcknowledge(t *testing.T) {
+	ctx := context.Background()
+	db := newDatabase(t, newConfig(t))
+	commands := repository.NewCommandRepository(db.GetCollections().DeviceCommands)
+
+	future := time.Now().Add(time.Hour)
+	for _, command := range []*models.DeviceCommand{
+		{CommandID: "sent", Status: models.CommandStatusSent},
+		{CommandID: "timed-out", Status: models.CommandStatusTimeout},
+		{CommandID: "expired", Status: models.CommandStatusExpired},
+		{CommandID: "awaiting-approval", Status: models.CommandStatusPendingApproval},
+	} {
+		command.DeviceID = "hvac-1"
+		command.Command = "TURN_ON"
+		command.ExpiresAt = &future
+		_, err := commands.Create(ctx, command)
+		require.NoError(t, err)
+	}
+
+	for commandID, expected := range map[string]bool{
+		"sent":              true,
+		"timed-out":         true,
+		"expired":           false,
+		"awaiting-approval": false,
+	} {
+		acknowledged, err := commands.Acknowledge(ctx, commandID, models.CommandStatusApplied, "")
+		require.NoError(t, err)
+		assert.Equal(t, expected, acknowledged, commandID)
+	}
+
+	// A redelivered acknowledgment changes nothing
+	acknowledged, err := commands.Acknowledge(ctx, "sent", models.CommandStatusFailed, "redelivered")
+	require.NoError(t, err)
+	assert.False(t, acknowledged)
+
+	command, err := commands.FindByCommandID(ctx, "sent")
+	require.NoError(t, err)
+	assert.Equal(t, models.CommandStatusApplied, command.Status)
+	assert.NotNil(t, command.AppliedAt)
+
+	command, err = commands.FindByCommandID(ctx, "expired")
+	require.NoError(t, err)
+	assert.Equal(t, models.CommandStatusExpired, command.Status)
+}
@@ -0,0 +1,409 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"iot-control-service/internal/cache"
+	"iot-control-service/internal/config"
+	"iot-control-service/internal/handlers"
+	"iot-control-service/internal/integrations"
+	"iot-control-service/internal/middleware"
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/mqtt"
+	"iot-control-service/internal/repository"
+	"iot-control-service/internal/service"
+	"iot-control-service/internal/settings"
+	"shared/jobs"
+)
+
+// fixtureUser is a user the fake Security service can log in
+type fixtureUser struct {
+	ID          string
+	Username    string
+	Password    string
+	Roles       []string
+	Permissions []string // "resource:action" pairs granted besides the admin role
+}
+
+// Users every fake Security service knows
+var (
+	operatorUser = fixtureUser{ID: "user-operator", Username: "operator", Password: "operator-pass", Roles: []string{"operator"}}
+	approverUser = fixtureUser{ID: "user-approver", Username: "approver", Password: "approver-pass", Roles: []string{"facility_manager"}, Permissions: []string{"commands:approve"}}
+	adminUser    = fixtureUser{ID: "user-admin", Username: "admin", Password: "admin-pass", Roles: []string{"admin"}}
+)
+
+// fakeSecurity stands in for the Security service: it logs in fixture users, validates the
+// tokens it issued, answers permission checks and records audit entries
+type fakeSecurity struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	users  map[string]fixtureUser // by username
+	tokens map[string]fixtureUser // by access token
+	audits []models.AuditLogRequest
+}
+
+// newFakeSecurity starts a fake Security service with the fixture users, stopped after the test
+func newFakeSecurity(t *testing.T) *fakeSecurity {
+	t.Helper()
+
+	s := &fakeSecurity{
+		users:  make(map[string]fixtureUser),
+		tokens: make(map[string]fixtureUser),
+	}
+	for _, user := range []fixtureUser{operatorUser, approverUser, adminUser} {
+		s.users[user.Username] = user
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/auth/login", s.login)
+	mux.HandleFunc("/auth/validate-token", s.validateToken)
+	mux.HandleFunc("/auth/check-permissions", s.checkPermissions)
+	mux.HandleFunc("/audit/log", s.auditLog)
+
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *fakeSecurity) login(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, "Invalid request body", err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[req.Username]
+	if !ok || user.Password != req.Password {
+		writeJSON(w, http.StatusUnauthorized, models.NewErrorResponse(models.ErrCodeUnauthorized, "Invalid credentials", ""))
+		return
+	}
+
+	token := "token-" + user.ID + "-" + time.Now().Format("150405.000000000")
+	s.tokens[token] = user
+	writeJSON(w, http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"accessToken": token,
+		"tokenType":   "Bearer",
+	}, ""))
+}
+
+func (s *fakeSecurity) validateToken(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	s.mu.Lock()
+	user, ok := s.tokens[token]
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, &models.TokenValidationResponse{Valid: false, Message: "invalid token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, &models.TokenValidationResponse{Valid: true, UserID: user.ID, Roles: user.Roles})
+}
+
+func (s *fakeSecurity) checkPermissions(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID   string `json:"userId"`
+		Resource string `json:"resource"`
+		Action   string `json:"action"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, "Invalid request body", err.Error()))
+		return
+	}
+
+	allowed := false
+	s.mu.Lock()
+	for _, user := range s.users {
+		if user.ID != req.UserID {
+			continue
+		}
+		for _, role := range user.Roles {
+			allowed = allowed || role == "admin"
+		}
+		for _, permission := range user.Permissions {
+			allowed = allowed || permission == req.Resource+":"+req.Action
+		}
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"allowed": allowed})
+}
+
+func (s *fakeSecurity) auditLog(w http.ResponseWriter, r *http.Request) {
+	var req models.AuditLogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, "Invalid request body", err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	s.audits = append(s.audits, req)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusCreated, models.NewSuccessResponse(nil, ""))
+}
+
+// hasAudit reports whether an audit entry with the action, resource ID and status was recorded
+func (s *fakeSecurity) hasAudit(action, resourceID, status string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, audit := range s.audits {
+		if audit.Action == action && audit.ResourceID == resourceID && audit.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// loginAs logs a fixture user in and returns the access token
+func (s *fakeSecurity) loginAs(t *testing.T, user fixtureUser) string {
+	t.Helper()
+
+	var resp struct {
+		Data struct {
+			AccessToken string `json:"accessToken"`
+		} `json:"data"`
+	}
+	status := doJSON(t, http.MethodPost, s.server.URL+"/auth/login", "", map[string]string{
+		"username": user.Username,
+		"password": user.Password,
+	}, &resp)
+	if status != http.StatusOK || resp.Data.AccessToken == "" {
+		t.Fatalf("login as %s: status %d", user.Username, status)
+	}
+	return resp.Data.AccessToken
+}
+
+// app is the IoT Control service wired as in cmd/main.go against the test servers, without
+// background workers so tests control when commands time out or expire
+type app struct {
+	cfg      *config.Config
+	server   *httptest.Server
+	security *fakeSecurity
+	mqtt     *mqtt.Client
+
+	devices     *repository.DeviceRepository
+	deviceTypes *repository.DeviceTypeRepository
+	commands    *repository.CommandRepository
+	schedules   *repository.ScheduledCommandRepository
+	control     *service.ControlService
+
+	optimizations  *repository.OptimizationRepository
+	weatherRules   *repository.WeatherRuleRepository
+	ruleExecutions *repository.WeatherRuleExecutionRepository
+}
+
+// newApp starts the service's HTTP API and MQTT subscriptions, stopped after the test
+func newApp(t *testing.T) *app {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	security := newFakeSecurity(t)
+	cfg := newConfig(t)
+	cfg.Security.URL = security.server.URL
+
+	db := newDatabase(t, cfg)
+	collections := db.GetCollections()
+	mqttClient := newMQTTClient(t, cfg, "service")
+
+	deviceRepo := repository.NewDeviceRepository(collections.Devices)
+	telemetryRepo := repository.NewTelemetryRepository(collections.Telemetry)
+	commandRepo := repository.NewCommandRepository(collections.DeviceCommands)
+	optimizationRepo := repository.NewOptimizationRepository(collections.OptimizationScenarios)
+	scheduleRepo := repository.NewScheduledCommandRepository(collections.ScheduledCommands)
+	deviceTypeRepo := repository.NewDeviceTypeRepository(collections.DeviceTypes)
+	provisioningRepo := repository.NewProvisioningRepository(collections.ProvisioningTokens)
+	weatherRuleRepo := repository.NewWeatherRuleRepository(collections.WeatherRules)
+	weatherRuleExecutionRepo := repository.NewWeatherRuleExecutionRepository(collections.WeatherRuleExecutions)
+	gatewayMappingRepo := repository.NewGatewayMappingRepository(collections.GatewayMappings)
+	locationRepo := repository.NewLocationRepository(collections.Locations)
+
+	securityClient := integrations.NewSecurityClient(cfg)
+	forecastClient := integrations.NewForecastClient(cfg)
+	predictionCache := integrations.NewPredictionCache(time.Minute)
+	analyticsClient := integrations.NewAnalyticsClient(cfg)
+	topicAuthorizer := mqtt.NewTopicAuthorizer(deviceRepo, false)
+	mqttClient.SetAuthorizer(topicAuthorizer)
+
+	settingsStore := settings.NewStore(collections.Settings, collections.SettingsHistory)
+	jobQueue := jobs.NewQueue(collections.Jobs, "iot-control-service", "job-secret", time.Second)
+	hotCache := cache.New(cache.Config{})
+
+	deviceService := service.NewDeviceService(deviceRepo, deviceTypeRepo, hotCache)
+	deviceService.SetTopicAuthorizer(topicAuthorizer)
+	deviceTypeService := service.NewDeviceTypeService(deviceTypeRepo, deviceRepo)
+	if err := deviceTypeService.InitializeDefaultTypes(ctx); err != nil {
+		t.Fatalf("initialize device types: %v", err)
+	}
+	telemetryService := service.NewTelemetryService(telemetryRepo, deviceRepo, hotCache, nil)
+	controlService := service.NewControlService(commandRepo, deviceRepo, deviceTypeRepo, telemetryRepo, optimizationRepo, mqttClient, nil, cfg.IoT.CommandTimeout, cfg.IoT.CommandRetries, cfg.IoT.CommandTTL, cfg.IoT.CommandApproval)
+	scheduleService := service.NewScheduleService(scheduleRepo, deviceRepo, controlService, nil)
+	optimizationService := service.NewOptimizationService(optimizationRepo, commandRepo, deviceRepo, deviceTypeRepo, telemetryRepo, forecastClient, predictionCache, analyticsClient, nil, jobQueue)
+	stateService := service.NewStateService(deviceRepo, telemetryRepo, hotCache)
+	provisioningService := service.NewProvisioningService(provisioningRepo, deviceRepo, deviceService, time.Hour)
+	weatherRuleService := service.NewWeatherRuleService(weatherRuleRepo, weatherRuleExecutionRepo, deviceRepo, optimizationRepo, controlService, forecastClient, nil)
+	gatewayService := service.NewGatewayService(gatewayMappingRepo, deviceRepo, telemetryService)
+	locationService := service.NewLocationService(locationRepo, deviceRepo)
+	retentionService := service.NewRetentionService(true, time.Hour)
+	healthService := service.NewHealthService("iot-control-service")
+	healthService.Register("mongodb", true, db.HealthCheck)
+	healthService.Register("mqtt", true, mqttClient.HealthCheck)
+
+	// Acks are processed as in setupMQTTSubscriptions
+	err := mqttClient.SubscribeToAllAcks(func(_ context.Context, deviceID string, ack *models.CommandAck) {
+		ackCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := controlService.ProcessCommandAck(ackCtx, ack); err != nil {
+			t.Logf("Ignoring ack %s: %v", ack.CommandID, err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("subscribe to acks: %v", err)
+	}
+
+	authMiddleware := middleware.NewAuthMiddleware(securityClient, cfg.Auth)
+	router := handlers.NewRouter(
+		handlers.NewDeviceHandler(deviceService, securityClient),
+		handlers.NewDeviceTypeHandler(deviceTypeService, securityClient),
+		handlers.NewTelemetryHandler(telemetryService, securityClient),
+		handlers.NewControlHandler(controlService, scheduleService, securityClient),
+		handlers.NewOptimizationHandler(optimizationService, securityClient),
+		handlers.NewStateHandler(stateService),
+		handlers.NewHealthHandler(healthService, mqttClient, hotCache),
+		handlers.NewRetentionHandler(retentionService),
+		handlers.NewAuthEventsHandler(authMiddleware.Permissions()),
+		handlers.NewProvisioningHandler(provisioningService, securityClient),
+		handlers.NewWeatherRuleHandler(weatherRuleService, securityClient),
+		handlers.NewGatewayHandler(gatewayService, securityClient),
+		handlers.NewJobHandler(jobQueue),
+		handlers.NewSettingsHandler(settingsStore, securityClient),
+		handlers.NewLocationHandler(locationService, securityClient),
+		authMiddleware,
+	)
+	engine := gin.New()
+	router.SetupRoutes(engine, cfg.HTTP)
+
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	return &app{
+		cfg:         cfg,
+		server:      server,
+		security:    security,
+		mqtt:        mqttClient,
+		devices:     deviceRepo,
+		deviceTypes: deviceTypeRepo,
+		commands:    commandRepo,
+		schedules:   scheduleRepo,
+		control:     controlService,
+
+		optimizations:  optimizationRepo,
+		weatherRules:   weatherRuleRepo,
+		ruleExecutions: weatherRuleExecutionRepo,
+	}
+}
+
+// seedDevice registers an online device of the given type in a building
+func seedDevice(t *testing.T, devices *repository.DeviceRepository, deviceID, deviceType, buildingID string) *models.Device {
+	t.Helper()
+
+	device, err := devices.Create(context.Background(), &models.Device{
+		DeviceID:     deviceID,
+		Name:         deviceID,
+		Type:         deviceType,
+		Model:        "IT-1",
+		Location:     models.DeviceLocation{BuildingID: buildingID, Floor: "1"},
+		Capabilities: []string{"TURN_ON", "TURN_OFF", "SET_TEMP"},
+		Status:       models.DeviceStatusOnline,
+		LastSeen:     time.Now(),
+		CreatedBy:    adminUser.ID,
+	})
+	if err != nil {
+		t.Fatalf("seed device %s: %v", deviceID, err)
+	}
+	return device
+}
+
+// simulateDevice acknowledges every command published to a device with the given status
+func simulateDevice(t *testing.T, cfg *config.Config, device *models.Device, status string) <-chan *models.DeviceCommand {
+	t.Helper()
+
+	client := newMQTTClient(t, cfg, "device-"+device.DeviceID)
+	received := make(chan *models.DeviceCommand, 10)
+	err := client.SubscribeToCommands(device.Location.BuildingID, device.DeviceID, func(command *models.DeviceCommand) {
+		received <- command
+		ack := &models.CommandAck{
+			CommandID: command.CommandID,
+			DeviceID:  device.DeviceID,
+			Status:    status,
+			Timestamp: time.Now(),
+		}
+		if err := client.PublishAck(device.Location.BuildingID, device.DeviceID, ack); err != nil {
+			t.Logf("Failed to ack command %s: %v", command.CommandID, err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("subscribe to commands of %s: %v", device.DeviceID, err)
+	}
+	return received
+}
+
+// doJSON sends a JSON request, decodes the JSON response into out when given and returns the
+// status code
+func doJSON(t *testing.T, method, url, token string, body, out interface{}) int {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("encode request: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &payload)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decode response of %s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// writeJSON writes a JSON response of the fake Security service
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
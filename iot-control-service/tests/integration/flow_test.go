@@ -0,0 +1,129 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"iot-control-service/internal/models"
+)
+
+// commandEnvelope is the API response carrying a single command
+type commandEnvelope struct {
+	Success bool                   `json:"success"`
+	Message string                 `json:"message"`
+	Data    models.CommandResponse `json:"data"`
+}
+
+// commandListEnvelope is the API response carrying a page of commands
+type commandListEnvelope struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Commands []models.CommandResponse `json:"commands"`
+		Total    int64                    `json:"total"`
+	} `json:"data"`
+}
+
+// TestLoginCommandAck tests the full flow of a user logging in, sending a command over the API,
+// the device acknowledging it over MQTT and the command being listed as applied
+func TestLoginCommandAck(t *testing.T) {
+	a := newApp(t)
+	device := seedDevice(t, a.devices, "hvac-1", "HVAC", "building-1")
+	received := simulateDevice(t, a.cfg, device, "APPLIED")
+
+	token := a.security.loginAs(t, operatorUser)
+
+	var sent commandEnvelope
+	status := doJSON(t, http.MethodPost, a.server.URL+"/api/v1/iot/device-control/hvac-1/command", token,
+		map[string]interface{}{"command": "SET_TEMP", "params": map[string]interface{}{"temperature": 22}}, &sent)
+	require.Equal(t, http.StatusCreated, status)
+	require.True(t, sent.Success)
+	assert.Equal(t, string(models.CommandStatusSent), sent.Data.Status)
+
+	select {
+	case command := <-received:
+		assert.Equal(t, sent.Data.CommandID, command.CommandID)
+	case <-time.After(10 * time.Second):
+		t.Fatal("device did not receive the command")
+	}
+
+	eventually(t, 10*time.Second, func() bool {
+		var list commandListEnvelope
+		doJSON(t, http.MethodGet, a.server.URL+"/api/v1/iot/device-control/hvac-1/commands", token, nil, &list)
+		return len(list.Data.Commands) == 1 && list.Data.Commands[0].Status == string(models.CommandStatusApplied)
+	}, "command was not applied")
+
+	eventually(t, 5*time.Second, func() bool {
+		return a.security.hasAudit("SEND_COMMAND", sent.Data.CommandID, "SUCCESS")
+	}, "command was not audited")
+}
+
+// TestCommandRequiresLogin tests that commands without a valid token are rejected
+func TestCommandRequiresLogin(t *testing.T) {
+	a := newApp(t)
+	seedDevice(t, a.devices, "hvac-1", "HVAC", "building-1")
+
+	status := doJSON(t, http.MethodPost, a.server.URL+"/api/v1/iot/device-control/hvac-1/command", "",
+		map[string]interface{}{"command": "TURN_ON"}, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status = doJSON(t, http.MethodPost, a.server.URL+"/api/v1/iot/device-control/hvac-1/command", "forged-token",
+		map[string]interface{}{"command": "TURN_ON"}, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+// TestApprovedCommandAck tests that a high-impact command is only sent to the device once
+// another user with the approval permission approves it
+func TestApprovedCommandAck(t *testing.T) {
+	a := newApp(t)
+	_, err := a.deviceTypes.Update(context.Background(), "HVAC", bson.M{"high_impact_commands": []string{"TURN_OFF"}})
+	require.NoError(t, err)
+	device := seedDevice(t, a.devices, "hvac-1", "HVAC", "building-1")
+	received := simulateDevice(t, a.cfg, device, "APPLIED")
+
+	operatorToken := a.security.loginAs(t, operatorUser)
+	approverToken := a.security.loginAs(t, approverUser)
+
+	var pending commandEnvelope
+	status := doJSON(t, http.MethodPost, a.server.URL+"/api/v1/iot/device-control/hvac-1/command", operatorToken,
+		map[string]interface{}{"command": "TURN_OFF"}, &pending)
+	require.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, string(models.CommandStatusPendingApproval), pending.Data.Status)
+
+	select {
+	case command := <-received:
+		t.Fatalf("command %s was sent before approval", command.CommandID)
+	case <-time.After(time.Second):
+	}
+
+	approveURL := a.server.URL + "/api/v1/iot/commands/" + pending.Data.CommandID + "/approve"
+	status = doJSON(t, http.MethodPost, approveURL, operatorToken, nil, nil)
+	assert.Equal(t, http.StatusForbidden, status, "the operator lacks the approval permission")
+
+	var approved commandEnvelope
+	status = doJSON(t, http.MethodPost, approveURL, approverToken, map[string]string{"comment": "planned maintenance"}, &approved)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, approverUser.ID, approved.Data.ReviewedBy)
+
+	select {
+	case command := <-received:
+		assert.Equal(t, pending.Data.CommandID, command.CommandID)
+	case <-time.After(10 * time.Second):
+		t.Fatal("device did not receive the approved command")
+	}
+
+	eventually(t, 10*time.Second, func() bool {
+		command, err := a.commands.FindByCommandID(context.Background(), pending.Data.CommandID)
+		return err == nil && command.Status == models.CommandStatusApplied
+	}, "approved command was not applied")
+
+	status = doJSON(t, http.MethodPost, approveURL, approverToken, nil, nil)
+	assert.Equal(t, http.StatusConflict, status, "a command is approved only once")
+}
@@ -0,0 +1,269 @@
+//go:build integration
+
+// Package integration tests repositories, the job queue, the MQTT client and full HTTP flows
+// against a real MongoDB and MQTT broker. The tests only build with the integration tag:
+//
+//	go test -tags=integration ./tests/integration/...
+//
+// The harness starts mongo and eclipse-mosquitto containers through the docker CLI and removes
+// them afterwards. Set IOT_IT_MONGODB_URI and IOT_IT_MQTT_BROKER (host:port) to run against
+// servers that are already running instead, e.g. the ones from docker-compose.
+package integration
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"iot-control-service/internal/config"
+	"iot-control-service/internal/mqtt"
+	"iot-control-service/internal/repository"
+)
+
+// Images the harness starts when no servers are configured
+const (
+	mongoImage     = "mongo:7"
+	mosquittoImage = "eclipse-mosquitto:2"
+)
+
+// startupTimeout bounds how long the harness waits for a container to accept connections
+const startupTimeout = 60 * time.Second
+
+// env holds the addresses of the servers shared by every test in the package
+var env struct {
+	mongoURI string
+	mqttHost string
+	mqttPort int
+}
+
+func TestMain(m *testing.M) {
+	code, err := run(m)
+	if err != nil {
+		log.Printf("Integration harness failed: %v", err)
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+// run starts the servers the tests need, runs the tests and stops the servers again
+func run(m *testing.M) (int, error) {
+	var containers []*container
+	defer func() {
+		for _, c := range containers {
+			c.stop()
+		}
+	}()
+
+	env.mongoURI = os.Getenv("IOT_IT_MONGODB_URI")
+	if env.mongoURI == "" {
+		c, err := startContainer(mongoImage, "27017/tcp")
+		if err != nil {
+			return 0, err
+		}
+		containers = append(containers, c)
+		env.mongoURI = "mongodb://" + c.address
+	}
+	if err := waitForMongo(env.mongoURI); err != nil {
+		return 0, err
+	}
+
+	broker := os.Getenv("IOT_IT_MQTT_BROKER")
+	if broker == "" {
+		// Mosquitto 2 only listens on localhost unless started with a config that allows clients
+		c, err := startContainer(mosquittoImage, "1883/tcp", "mosquitto", "-c", "/mosquitto-no-auth.conf")
+		if err != nil {
+			return 0, err
+		}
+		containers = append(containers, c)
+		broker = c.address
+	}
+	host, port, err := net.SplitHostPort(broker)
+	if err != nil {
+		return 0, fmt.Errorf("invalid MQTT broker address %q: %w", broker, err)
+	}
+	env.mqttHost = host
+	if env.mqttPort, err = strconv.Atoi(port); err != nil {
+		return 0, fmt.Errorf("invalid MQTT broker port %q: %w", port, err)
+	}
+	if err := waitForTCP(broker); err != nil {
+		return 0, err
+	}
+
+	return m.Run(), nil
+}
+
+// container is a docker container started for the test run
+type container struct {
+	id      string
+	address string // host:port the exposed port is published on
+}
+
+// startContainer runs an image with its ports published on random host ports and returns the
+// address the given container port is reachable on
+func startContainer(image, port string, args ...string) (*container, error) {
+	out, err := exec.Command("docker", append([]string{"run", "-d", "--rm", "-P", image}, args...)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", image, commandError(err))
+	}
+	c := &container{id: strings.TrimSpace(string(out))}
+
+	out, err = exec.Command("docker", "port", c.id, port).Output()
+	if err != nil {
+		c.stop()
+		return nil, fmt.Errorf("failed to find the published port of %s: %w", image, commandError(err))
+	}
+	// The first line is the IPv4 binding, e.g. 0.0.0.0:49153
+	binding := strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)[0]
+	_, hostPort, err := net.SplitHostPort(binding)
+	if err != nil {
+		c.stop()
+		return nil, fmt.Errorf("unexpected port binding %q of %s", binding, image)
+	}
+	c.address = net.JoinHostPort("127.0.0.1", hostPort)
+	return c, nil
+}
+
+// stop removes the container
+func (c *container) stop() {
+	if err := exec.Command("docker", "rm", "-f", c.id).Run(); err != nil {
+		log.Printf("Failed to remove container %s: %v", c.id, err)
+	}
+}
+
+// commandError includes the stderr of a failed docker command in its error
+func commandError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
+
+// waitForMongo waits until MongoDB answers a ping
+func waitForMongo(uri string) error {
+	deadline := time.Now().Add(startupTimeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(2*time.Second))
+		if err == nil {
+			err = client.Ping(ctx, nil)
+			client.Disconnect(ctx)
+		}
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("MongoDB at %s not ready: %w", uri, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// waitForTCP waits until a server accepts TCP connections
+func waitForTCP(address string) error {
+	deadline := time.Now().Add(startupTimeout)
+	for {
+		conn, err := net.DialTimeout("tcp", address, 2*time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s not reachable: %w", address, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// newConfig returns a configuration pointing at the shared servers with a scratch database
+// and MQTT client ID of its own
+func newConfig(t *testing.T) *config.Config {
+	t.Helper()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	return &config.Config{
+		Server: config.ServerConfig{Mode: "test"},
+		MongoDB: config.MongoDBConfig{
+			URI:                    env.mongoURI,
+			Database:               "iot_it_" + suffix,
+			Timeout:                10 * time.Second,
+			MaxPoolSize:            20,
+			ServerSelectionTimeout: 5 * time.Second,
+			RetryWrites:            false,
+		},
+		Security: config.SecurityServiceConfig{Timeout: 5 * time.Second},
+		Auth: config.AuthConfig{
+			PermissionCacheTTL: time.Minute,
+			SignatureWindow:    5 * time.Minute,
+		},
+		MQTT: config.MQTTConfig{
+			Broker:   env.mqttHost,
+			Port:     env.mqttPort,
+			ClientID: "iot-it-" + suffix,
+			QoS:      1,
+		},
+		IoT: config.IoTConfig{
+			CommandTimeout:  30 * time.Second,
+			CommandTTL:      15 * time.Minute,
+			CommandApproval: time.Hour,
+		},
+	}
+}
+
+// newDatabase connects to a scratch database with the service's indexes, dropped after the test
+func newDatabase(t *testing.T, cfg *config.Config) *repository.MongoDB {
+	t.Helper()
+
+	db, err := repository.NewMongoDB(cfg)
+	if err != nil {
+		t.Fatalf("connect to MongoDB: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db.Database.Drop(ctx)
+		db.Close(ctx)
+	})
+
+	if err := db.CreateIndexes(context.Background()); err != nil {
+		t.Fatalf("create indexes: %v", err)
+	}
+	return db
+}
+
+// newMQTTClient connects an MQTT client with the given client ID suffix, disconnected after the test
+func newMQTTClient(t *testing.T, cfg *config.Config, name string) *mqtt.Client {
+	t.Helper()
+
+	clientCfg := *cfg
+	clientCfg.MQTT.ClientID = cfg.MQTT.ClientID + "-" + name
+	client, err := mqtt.NewClient(&clientCfg)
+	if err != nil {
+		t.Fatalf("connect to MQTT broker: %v", err)
+	}
+	t.Cleanup(client.Disconnect)
+	return client
+}
+
+// eventually polls a condition until it holds or the timeout passes
+func eventually(t *testing.T, timeout time.Duration, condition func() bool, message string) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out after %s: %s", timeout, message)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,207 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/mqtt"
+	"iot-control-service/internal/repository"
+)
+
+// TestMQTTCommandRoundTrip tests that a published command reaches the device on its building
+// topic and the device's ack reaches the service
+func TestMQTTCommandRoundTrip(t *testing.T) {
+	cfg := newConfig(t)
+	db := newDatabase(t, cfg)
+	devices := repository.NewDeviceRepository(db.GetCollections().Devices)
+	device := seedDevice(t, devices, "hvac-1", "HVAC", "building-1")
+
+	service := newMQTTClient(t, cfg, "service")
+	service.SetAuthorizer(mqtt.NewTopicAuthorizer(devices, false))
+	acks := make(chan *models.CommandAck, 1)
+	require.NoError(t, service.SubscribeToAllAcks(func(_ context.Context, deviceID string, ack *models.CommandAck) {
+		acks <- ack
+	}))
+
+	received := simulateDevice(t, cfg, device, "APPLIED")
+
+	command := &models.DeviceCommand{
+		CommandID: "command-1",
+		DeviceID:  device.DeviceID,
+		Command:   "SET_TEMP",
+		Params:    map[string]interface{}{"temperature": 21.5},
+	}
+	require.NoError(t, service.PublishCommand(context.Background(), device.Location.BuildingID, device.DeviceID, command))
+
+	select {
+	case got := <-received:
+		assert.Equal(t, "command-1", got.CommandID)
+		assert.Equal(t, 21.5, got.Params["temperature"])
+	case <-time.After(10 * time.Second):
+		t.Fatal("device did not receive the command")
+	}
+
+	select {
+	case ack := <-acks:
+		assert.Equal(t, "command-1", ack.CommandID)
+		assert.Equal(t, "APPLIED", ack.Status)
+	case <-time.After(10 * time.Second):
+		t.Fatal("service did not receive the ack")
+	}
+}
+
+// TestMQTTRejectsForeignBuildingTopic tests that acks published on another building's topic are
+// not handed to the service
+func TestMQTTRejectsForeignBuildingTopic(t *testing.T) {
+	cfg := newConfig(t)
+	db := newDatabase(t, cfg)
+	devices := repository.NewDeviceRepository(db.GetCollections().Devices)
+	seedDevice(t, devices, "hvac-1", "HVAC", "building-1")
+
+	service := newMQTTClient(t, cfg, "service")
+	service.SetAuthorizer(mqtt.NewTopicAuthorizer(devices, false))
+	acks := make(chan *models.CommandAck, 1)
+	require.NoError(t, service.SubscribeToAllAcks(func(_ context.Context, deviceID string, ack *models.CommandAck) {
+		acks <- ack
+	}))
+
+	intruder := newMQTTClient(t, cfg, "intruder")
+	require.NoError(t, intruder.PublishAck("building-2", "hvac-1", &models.CommandAck{
+		CommandID: "command-1",
+		DeviceID:  "hvac-1",
+		Status:    "APPLIED",
+		Timestamp: time.Now(),
+	}))
+
+	select {
+	case ack := <-acks:
+		t.Fatalf("ack on a foreign building topic was accepted: %+v", ack)
+	case <-time.After(2 * time.Second):
+	}
+}
+
+// TestMQTTResubscribesAfterBrokerRestart tests that subscriptions are restored when the client
+// reconnects to a broker that lost them, and that the reconnect is counted
+func TestMQTTResubscribesAfterBrokerRestart(t *testing.T) {
+	cfg := newConfig(t)
+	proxy := newBrokerProxy(t, cfg.MQTT.Broker, cfg.MQTT.Port)
+	proxyCfg := *cfg
+	proxyCfg.MQTT.Broker, proxyCfg.MQTT.Port = proxy.host, proxy.port
+
+	service := newMQTTClient(t, &proxyCfg, "service")
+	telemetry := make(chan *models.Telemetry, 10)
+	require.NoError(t, service.SubscribeToAllTelemetry(func(_ context.Context, deviceID string, received *models.Telemetry) {
+		telemetry <- received
+	}))
+
+	// Dropping every connection looks like a broker restart to the client; with a clean session
+	// the broker forgets its subscriptions
+	proxy.restart()
+	eventually(t, 30*time.Second, func() bool {
+		stats := service.Stats()
+		return stats.Connected && stats.Reconnects >= 1 && stats.Resubscriptions >= 2
+	}, "service did not reconnect and restore its subscriptions")
+
+	device := newMQTTClient(t, cfg, "device")
+	require.NoError(t, device.PublishTelemetry("building-1", "meter-1", &models.Telemetry{
+		DeviceID:  "meter-1",
+		Timestamp: time.Now(),
+		Metrics:   map[string]interface{}{"power": 1.5},
+	}))
+
+	select {
+	case got := <-telemetry:
+		assert.Equal(t, "meter-1", got.DeviceID)
+	case <-time.After(10 * time.Second):
+		t.Fatal("service did not receive telemetry after the broker restart")
+	}
+
+	stats := service.Stats()
+	assert.Equal(t, 1, stats.ConnectionLosses)
+	assert.Zero(t, stats.ResubscribeFailures)
+	assert.Len(t, stats.Subscriptions, 2)
+}
+
+// brokerProxy forwards TCP connections to the MQTT broker and can drop them all at once
+type brokerProxy struct {
+	host     string
+	port     int
+	target   string
+	listener net.Listener
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+// newBrokerProxy starts a proxy to the broker on a random local port, closed after the test
+func newBrokerProxy(t *testing.T, brokerHost string, brokerPort int) *brokerProxy {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("start broker proxy: %v", err)
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	p := &brokerProxy{
+		host:     addr.IP.String(),
+		port:     addr.Port,
+		target:   net.JoinHostPort(brokerHost, strconv.Itoa(brokerPort)),
+		listener: listener,
+	}
+	go p.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		p.restart()
+	})
+	return p
+}
+
+// serve accepts client connections and pipes each to a new broker connection
+func (p *brokerProxy) serve() {
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		broker, err := net.Dial("tcp", p.target)
+		if err != nil {
+			client.Close()
+			continue
+		}
+
+		p.mu.Lock()
+		p.conns = append(p.conns, client, broker)
+		p.mu.Unlock()
+
+		go pipe(client, broker)
+		go pipe(broker, client)
+	}
+}
+
+// restart closes every proxied connection
+func (p *brokerProxy) restart() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = nil
+}
+
+// pipe copies from one connection to the other and closes both when either side ends
+func pipe(dst, src net.Conn) {
+	io.Copy(dst, src)
+	dst.Close()
+	src.Close()
+}
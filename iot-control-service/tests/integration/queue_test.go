@@ -0,0 +1,349 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"shared/jobs"
+)
+
+// pollInterval keeps idle workers and the exhausted-job sweep responsive in tests
+const pollInterval = 50 * time.Millisecond
+
+// newQueue creates a queue on a scratch jobs collection
+func newQueue(t *testing.T, workerID string) *jobs.Queue {
+	t.Helper()
+	db := newDatabase(t, newConfig(t))
+	return jobs.NewQueue(db.GetCollections().Jobs, workerID, "job-secret", pollInterval)
+}
+
+// startQueue runs a queue's workers until the test ends
+func startQueue(t *testing.T, queue *jobs.Queue, workers int) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		queue.Start(ctx, workers)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitForStatus waits until a job reaches a status and returns it
+func waitForStatus(t *testing.T, queue *jobs.Queue, id string, status jobs.Status) *jobs.Job {
+	t.Helper()
+	var job *jobs.Job
+	eventually(t, 10*time.Second, func() bool {
+		var err error
+		job, err = queue.Get(context.Background(), id)
+		return err == nil && job.Status == status
+	}, "job "+id+" "+string(status))
+	return job
+}
+
+// TestQueueClaimsEachJobOnce tests that instances sharing a collection run every job exactly once
+func TestQueueClaimsEachJobOnce(t *testing.T) {
+	ctx := context.Background()
+	db := newDatabase(t, newConfig(t))
+
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	handler := func(ctx context.Context, job *jobs.Job) error {
+		mu.Lock()
+		runs[job.ID.Hex()]++
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+
+	queues := []*jobs.Queue{
+		jobs.NewQueue(db.GetCollections().Jobs, "instance", "job-secret", pollInterval),
+		jobs.NewQueue(db.GetCollections().Jobs, "instance", "job-secret", pollInterval),
+	}
+	for _, queue := range queues {
+		queue.Register("test", handler, jobs.Options{})
+	}
+
+	const count = 40
+	ids := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		job, err := queues[i%2].Enqueue(ctx, "test", map[string]int{"n": i}, nil)
+		require.NoError(t, err)
+		ids = append(ids, job.ID.Hex())
+	}
+	for _, queue := range queues {
+		startQueue(t, queue, 4)
+	}
+
+	for _, id := range ids {
+		job := waitForStatus(t, queues[0], id, jobs.StatusSucceeded)
+		assert.Equal(t, 1, job.Attempts)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range ids {
+		assert.Equal(t, 1, runs[id], "job %s", id)
+	}
+}
+
+// TestQueueRetryBackoff tests that a failed attempt is retried after a delay and that a
+// permanent error fails the job at once
+func TestQueueRetryBackoff(t *testing.T) {
+	ctx := context.Background()
+	queue := newQueue(t, "instance")
+
+	var mu sync.Mutex
+	failures := make(map[string]string)
+	queue.Register("flaky", func(ctx context.Context, job *jobs.Job) error {
+		return errors.New("upstream unavailable")
+	}, jobs.Options{MaxAttempts: 3})
+	queue.Register("broken", func(ctx context.Context, job *jobs.Job) error {
+		return jobs.Permanent(errors.New("invalid payload"))
+	}, jobs.Options{MaxAttempts: 3, OnFailed: func(ctx context.Context, job *jobs.Job, reason string) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures[job.ID.Hex()] = reason
+	}})
+	startQueue(t, queue, 2)
+
+	submitter := &jobs.Identity{UserID: "user-1", OrgID: "org-1"}
+	flaky, err := queue.Enqueue(ctx, "flaky", nil, submitter)
+	require.NoError(t, err)
+	broken, err := queue.Enqueue(ctx, "broken", nil, nil)
+	require.NoError(t, err)
+
+	var job *jobs.Job
+	eventually(t, 10*time.Second, func() bool {
+		job, err = queue.Get(ctx, flaky.ID.Hex())
+		return err == nil && job.Attempts == 1 && job.Status == jobs.StatusPending
+	}, "flaky job scheduled for a retry")
+	assert.Equal(t, "upstream unavailable", job.LastError)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), job.RunAt, 5*time.Second, "first retry waits the base delay")
+	require.NotNil(t, job.Identity, "a retried job keeps its submitter")
+	assert.Equal(t, "user-1", job.Identity.UserID)
+
+	job = waitForStatus(t, queue, broken.ID.Hex(), jobs.StatusFailed)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "invalid payload", job.LastError)
+	mu.Lock()
+	assert.Equal(t, "invalid payload", failures[broken.ID.Hex()])
+	mu.Unlock()
+
+	// The retry is not due yet
+	time.Sleep(5 * pollInterval)
+	job, err = queue.Get(ctx, flaky.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, 1, job.Attempts)
+}
+
+// TestQueueReclaimsExpiredLock tests that a job whose run outlives its visibility timeout is
+// claimed again, and that the stale run cannot record its outcome over the new one even though
+// both run in the same instance
+func TestQueueReclaimsExpiredLock(t *testing.T) {
+	ctx := context.Background()
+	queue := newQueue(t, "instance")
+
+	release := []chan error{make(chan error), make(chan error)}
+	started := make(chan int, 2)
+	stop := make(chan struct{})
+	queue.Register("slow", func(ctx context.Context, job *jobs.Job) error {
+		started <- job.Attempts
+		// Ignore the timeout, like work stuck in a call without a deadline
+		select {
+		case err := <-release[job.Attempts-1]:
+			return err
+		case <-stop:
+			return nil
+		}
+	}, jobs.Options{Timeout: time.Second, MaxAttempts: 2})
+	startQueue(t, queue, 2)
+	t.Cleanup(func() { close(stop) })
+
+	job, err := queue.Enqueue(ctx, "slow", nil, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, <-started)
+	select {
+	case attempt := <-started:
+		assert.Equal(t, 2, attempt)
+	case <-time.After(10 * time.Second):
+		t.Fatal("job was not reclaimed after its lock expired")
+	}
+
+	// The first run fails after it lost its lock; the job is still running its second attempt
+	release[0] <- errors.New("stale failure")
+	time.Sleep(5 * pollInterval)
+	stored, err := queue.Get(ctx, job.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, jobs.StatusRunning, stored.Status)
+	assert.Equal(t, 2, stored.Attempts)
+	assert.Empty(t, stored.LastError)
+
+	release[1] <- nil
+	stored = waitForStatus(t, queue, job.ID.Hex(), jobs.StatusSucceeded)
+	assert.Equal(t, 2, stored.Attempts)
+	assert.Empty(t, stored.LockedBy)
+}
+
+// TestQueueFailsExhaustedJobs tests that a job whose last attempt timed out is failed, and
+// that the late run does not overwrite the failure
+func TestQueueFailsExhaustedJobs(t *testing.T) {
+	ctx := context.Background()
+	queue := newQueue(t, "instance")
+
+	release := make(chan struct{})
+	failed := make(chan string, 1)
+	queue.Register("stuck", func(ctx context.Context, job *jobs.Job) error {
+		<-release
+		return nil
+	}, jobs.Options{Timeout: 200 * time.Millisecond, MaxAttempts: 1, OnFailed: func(ctx context.Context, job *jobs.Job, reason string) {
+		failed <- reason
+	}})
+	startQueue(t, queue, 1)
+	var once sync.Once
+	t.Cleanup(func() { once.Do(func() { close(release) }) })
+
+	job, err := queue.Enqueue(ctx, "stuck", nil, nil)
+	require.NoError(t, err)
+
+	stored := waitForStatus(t, queue, job.ID.Hex(), jobs.StatusFailed)
+	assert.Equal(t, "job timed out", stored.LastError)
+	assert.NotNil(t, stored.FinishedAt)
+	select {
+	case reason := <-failed:
+		assert.Equal(t, "job timed out", reason)
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnFailed was not called")
+	}
+
+	once.Do(func() { close(release) })
+	time.Sleep(5 * pollInterval)
+	stored, err = queue.Get(ctx, job.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, jobs.StatusFailed, stored.Status)
+}
+
+// TestQueueCancelRetry tests the allowed cancel and retry transitions
+func TestQueueCancelRetry(t *testing.T) {
+	ctx := context.Background()
+	queue := newQueue(t, "instance")
+	queue.Register("test", func(ctx context.Context, job *jobs.Job) error { return nil }, jobs.Options{})
+
+	job, err := queue.Enqueue(ctx, "test", nil, nil)
+	require.NoError(t, err)
+	id := job.ID.Hex()
+
+	_, err = queue.Retry(ctx, id)
+	require.Error(t, err)
+	assert.Equal(t, "only failed or cancelled jobs can be retried", err.Error())
+
+	cancelled, err := queue.Cancel(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, jobs.StatusCancelled, cancelled.Status)
+	assert.NotNil(t, cancelled.FinishedAt)
+
+	_, err = queue.Cancel(ctx, id)
+	require.Error(t, err)
+	assert.Equal(t, "only pending jobs can be cancelled", err.Error())
+
+	retried, err := queue.Retry(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, jobs.StatusPending, retried.Status)
+	assert.Equal(t, 0, retried.Attempts)
+	assert.Nil(t, retried.FinishedAt)
+
+	startQueue(t, queue, 1)
+	succeeded := waitForStatus(t, queue, id, jobs.StatusSucceeded)
+	assert.Equal(t, 1, succeeded.Attempts)
+
+	_, err = queue.Cancel(ctx, id)
+	assert.Error(t, err)
+
+	_, err = queue.Cancel(ctx, "not-an-id")
+	require.Error(t, err)
+	assert.Equal(t, "invalid job ID format", err.Error())
+
+	_, err = queue.Retry(ctx, "000000000000000000000000")
+	require.Error(t, err)
+	assert.Equal(t, "job not found", err.Error())
+
+	_, err = queue.Enqueue(ctx, "unknown", nil, nil)
+	assert.Error(t, err)
+}
+
+// TestQueueIdentity tests that jobs acting for their submitter only run and retry with the
+// identity the queue signed, and that tokens stored by earlier versions are removed
+func TestQueueIdentity(t *testing.T) {
+	ctx := context.Background()
+	db := newDatabase(t, newConfig(t))
+	collection := db.GetCollections().Jobs
+	queue := jobs.NewQueue(collection, "instance", "job-secret", pollInterval)
+
+	var mu sync.Mutex
+	ran := make(map[string]string)
+	queue.Register("delegated", func(ctx context.Context, job *jobs.Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran[job.ID.Hex()] = job.Identity.UserID
+		return nil
+	}, jobs.Options{RequiresIdentity: true})
+
+	_, err := queue.Enqueue(ctx, "delegated", nil, nil)
+	assert.Error(t, err, "a job acting for its submitter needs an identity")
+
+	valid, err := queue.Enqueue(ctx, "delegated", nil, &jobs.Identity{UserID: "user-1", OrgID: "org-1"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, valid.Identity.Signature)
+
+	// An identity edited in the database no longer verifies
+	tampered, err := queue.Enqueue(ctx, "delegated", nil, &jobs.Identity{UserID: "user-2", OrgID: "org-1"})
+	require.NoError(t, err)
+	_, err = collection.UpdateByID(ctx, tampered.ID, bson.M{"$set": bson.M{"identity.user_id": "admin"}})
+	require.NoError(t, err)
+
+	// A job stored by an earlier version carries a bearer token and no identity
+	legacyID := primitive.NewObjectID()
+	_, err = collection.InsertOne(ctx, bson.M{
+		"_id":          legacyID,
+		"type":         "delegated",
+		"auth_token":   "bearer-token",
+		"status":       jobs.StatusFailed,
+		"attempts":     3,
+		"max_attempts": 3,
+		"run_at":       time.Now(),
+	})
+	require.NoError(t, err)
+
+	startQueue(t, queue, 1)
+
+	waitForStatus(t, queue, valid.ID.Hex(), jobs.StatusSucceeded)
+	failed := waitForStatus(t, queue, tampered.ID.Hex(), jobs.StatusFailed)
+	assert.Equal(t, jobs.ErrInvalidIdentity.Error(), failed.LastError)
+	assert.Equal(t, 1, failed.Attempts, "an invalid identity is not retried")
+	mu.Lock()
+	assert.Equal(t, map[string]string{valid.ID.Hex(): "user-1"}, ran)
+	mu.Unlock()
+
+	_, err = queue.Retry(ctx, tampered.ID.Hex())
+	assert.ErrorIs(t, err, jobs.ErrInvalidIdentity)
+
+	eventually(t, 5*time.Second, func() bool {
+		count, err := collection.CountDocuments(ctx, bson.M{"auth_token": bson.M{"$exists": true}})
+		return err == nil && count == 0
+	}, "stored tokens removed")
+	_, err = queue.Retry(ctx, legacyID.Hex())
+	assert.ErrorIs(t, err, jobs.ErrInvalidIdentity)
+}
@@ -0,0 +1,191 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/repository"
+	"iot-control-service/internal/service"
+)
+
+// TestDeviceRepository tests storing and finding devices
+func TestDeviceRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newDatabase(t, newConfig(t))
+	devices := repository.NewDeviceRepository(db.GetCollections().Devices)
+
+	seedDevice(t, devices, "hvac-1", "HVAC", "building-1")
+
+	device, err := devices.FindByDeviceID(ctx, "hvac-1")
+	require.NoError(t, err)
+	assert.Equal(t, "HVAC", device.Type)
+	assert.Equal(t, "building-1", device.Location.BuildingID)
+
+	_, err = devices.Create(ctx, &models.Device{DeviceID: "hvac-1", Type: "HVAC", Location: models.DeviceLocation{BuildingID: "building-1"}})
+	assert.Error(t, err, "device IDs are unique")
+
+	_, err = devices.FindByDeviceID(ctx, "missing")
+	assert.Error(t, err)
+}
+
+// TestCommandRepositoryStatus tests that status updates stamp the sent and applied times
+func TestCommandRepositoryStatus(t *testing.T) {
+	ctx := context.Background()
+	db := newDatabase(t, newConfig(t))
+	commands := repository.NewCommandRepository(db.GetCollections().DeviceCommands)
+
+	expiresAt := time.Now().Add(time.Minute)
+	_, err := commands.Create(ctx, &models.DeviceCommand{
+		CommandID: "command-1",
+		DeviceID:  "hvac-1",
+		Command:   "TURN_ON",
+		Status:    models.CommandStatusPending,
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, commands.UpdateStatus(ctx, "command-1", models.CommandStatusSent, ""))
+	command, err := commands.FindByCommandID(ctx, "command-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.CommandStatusSent, command.Status)
+	assert.NotNil(t, command.SentAt)
+
+	require.NoError(t, commands.UpdateStatus(ctx, "command-1", models.CommandStatusApplied, ""))
+	command, err = commands.FindByCommandID(ctx, "command-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.CommandStatusApplied, command.Status)
+	assert.NotNil(t, command.AppliedAt)
+}
+
+// TestCommandRepositoryExpireStale tests that only unacknowledged commands past their expiry expire
+func TestCommandRepositoryExpireStale(t *testing.T) {
+	ctx := context.Background()
+	db := newDatabase(t, newConfig(t))
+	commands := repository.NewCommandRepository(db.GetCollections().DeviceCommands)
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	for _, command := range []*models.DeviceCommand{
+		{CommandID: "sent-expired", Status: models.CommandStatusSent, ExpiresAt: &past},
+		{CommandID: "awaiting-expired", Status: models.CommandStatusPendingApproval, ExpiresAt: &past},
+		{CommandID: "sent-live", Status: models.CommandStatusSent, ExpiresAt: &future},
+		{CommandID: "applied-expired", Status: models.CommandStatusApplied, ExpiresAt: &past},
+	} {
+		command.DeviceID = "hvac-1"
+		command.Command = "TURN_OFF"
+		_, err := commands.Create(ctx, command)
+		require.NoError(t, err)
+	}
+
+	expired, err := commands.ExpireStale(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), expired)
+
+	for commandID, status := range map[string]models.CommandStatus{
+		"sent-expired":     models.CommandStatusExpired,
+		"awaiting-expired": models.CommandStatusExpired,
+		"sent-live":        models.CommandStatusSent,
+		"applied-expired":  models.CommandStatusApplied,
+	} {
+		command, err := commands.FindByCommandID(ctx, commandID)
+		require.NoError(t, err)
+		assert.Equal(t, status, command.Status, commandID)
+	}
+}
+
+// TestCommandRepositoryReview tests that a command awaiting approval can be reviewed only once
+func TestCommandRepositoryReview(t *testing.T) {
+	ctx := context.Background()
+	db := newDatabase(t, newConfig(t))
+	commands := repository.NewCommandRepository(db.GetCollections().DeviceCommands)
+
+	expiresAt := time.Now().Add(time.Hour)
+	_, err := commands.Create(ctx, &models.DeviceCommand{
+		CommandID: "command-1",
+		DeviceID:  "hvac-1",
+		Command:   "TURN_OFF",
+		Status:    models.CommandStatusPendingApproval,
+		IssuedBy:  operatorUser.ID,
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+
+	pending, total, err := commands.FindPendingApproval(ctx, time.Now(), nil, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, pending, 1)
+
+	deliverBy := time.Now().Add(15 * time.Minute)
+	approved, err := commands.Review(ctx, "command-1", models.CommandStatusSent, approverUser.ID, "ok", &deliverBy, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.CommandStatusSent, approved.Status)
+	assert.Equal(t, approverUser.ID, approved.ReviewedBy)
+	assert.NotNil(t, approved.SentAt)
+
+	_, err = commands.Review(ctx, "command-1", models.CommandStatusRejected, approverUser.ID, "", nil, time.Now())
+	assert.EqualError(t, err, "command is not awaiting approval")
+
+	_, total, err = commands.FindPendingApproval(ctx, time.Now(), nil, 1, 20)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
+
+// TestCommandRetention tests that retention purges only finished commands created before the
+// cutoff, and that a dry run only counts them
+func TestCommandRetention(t *testing.T) {
+	ctx := context.Background()
+	db := newDatabase(t, newConfig(t))
+	collection := db.GetCollections().DeviceCommands
+	commands := repository.NewCommandRepository(collection)
+
+	old := time.Now().AddDate(0, 0, -100)
+	recent := time.Now().AddDate(0, 0, -10)
+	for commandID, command := range map[string]models.DeviceCommand{
+		"old-applied":   {Status: models.CommandStatusApplied, CreatedAt: old},
+		"old-expired":   {Status: models.CommandStatusExpired, CreatedAt: old},
+		"old-rejected":  {Status: models.CommandStatusRejected, CreatedAt: old},
+		"old-sent":      {Status: models.CommandStatusSent, CreatedAt: old},
+		"old-timeout":   {Status: models.CommandStatusTimeout, CreatedAt: old},
+		"old-awaiting":  {Status: models.CommandStatusPendingApproval, CreatedAt: old},
+		"recent-failed": {Status: models.CommandStatusFailed, CreatedAt: recent},
+	} {
+		command.CommandID = commandID
+		command.DeviceID = "hvac-1"
+		command.Command = "TURN_OFF"
+		// Insert directly, as Create stamps the current time
+		_, err := collection.InsertOne(ctx, &command)
+		require.NoError(t, err)
+	}
+
+	retention := service.NewRetentionService(true, time.Hour)
+	retention.Register("device_commands", 90*24*time.Hour, commands.CountOlderThan, commands.DeleteOlderThan)
+
+	status := retention.Run(ctx)
+	require.Len(t, status.Collections, 1)
+	assert.Equal(t, int64(3), status.Collections[0].LastAffected)
+	assert.Zero(t, status.Collections[0].TotalPurged)
+	total, err := collection.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), total, "a dry run deletes nothing")
+
+	retention.SetDryRun(false)
+	status = retention.Run(ctx)
+	assert.Equal(t, int64(3), status.Collections[0].TotalPurged)
+
+	for _, commandID := range []string{"old-applied", "old-expired", "old-rejected"} {
+		_, err := commands.FindByCommandID(ctx, commandID)
+		assert.Error(t, err, commandID)
+	}
+	for _, commandID := range []string{"old-sent", "old-timeout", "old-awaiting", "recent-failed"} {
+		_, err := commands.FindByCommandID(ctx, commandID)
+		assert.NoError(t, err, commandID)
+	}
+}
@@ -0,0 +1,144 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/repository"
+	"iot-control-service/internal/service"
+	"iot-control-service/internal/tenant"
+)
+
+// TestScheduledCommandsTenantIsolation tests that an organization cannot see or change the
+// scheduled commands of another organization's devices
+func TestScheduledCommandsTenantIsolation(t *testing.T) {
+	ctx := context.Background()
+	db := newDatabase(t, newConfig(t))
+	collections := db.GetCollections()
+	devices := repository.NewDeviceRepository(collections.Devices)
+	schedules := repository.NewScheduledCommandRepository(collections.ScheduledCommands)
+	scheduleService := service.NewScheduleService(schedules, devices, nil, nil)
+
+	seedDevice(t, devices, "hvac-b", "HVAC", "building-b")
+	nextRunAt := time.Now().Add(time.Hour)
+	_, err := schedules.Create(ctx, &models.ScheduledCommand{
+		ScheduleID: "schedule-b",
+		DeviceID:   "hvac-b",
+		Command:    "TURN_OFF",
+		Status:     models.ScheduleStatusActive,
+		NextRunAt:  &nextRunAt,
+		CreatedBy:  adminUser.ID,
+	})
+	require.NoError(t, err)
+
+	orgA := tenant.WithScope(ctx, &models.TenantScope{OrgID: "org-a", BuildingIDs: []string{"building-a"}})
+	orgB := tenant.WithScope(ctx, &models.TenantScope{OrgID: "org-b", BuildingIDs: []string{"building-b"}})
+
+	_, _, err = scheduleService.ListScheduledCommands(orgA, "hvac-b", "", 1, 20)
+	assert.Error(t, err)
+	_, err = scheduleService.PauseScheduledCommand(orgA, "hvac-b", "schedule-b")
+	assert.Error(t, err)
+	_, err = scheduleService.ResumeScheduledCommand(orgA, "hvac-b", "schedule-b")
+	assert.Error(t, err)
+	_, err = scheduleService.CancelScheduledCommand(orgA, "hvac-b", "schedule-b")
+	assert.Error(t, err)
+
+	schedule, err := schedules.FindByScheduleID(ctx, "schedule-b")
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleStatusActive, schedule.Status, "another organization cannot change the schedule")
+
+	listed, total, err := scheduleService.ListScheduledCommands(orgB, "hvac-b", "", 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, listed, 1)
+
+	paused, err := scheduleService.PauseScheduledCommand(orgB, "hvac-b", "schedule-b")
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleStatusPaused, paused.Status)
+}
+
+// fixedLocator serves building time zones from a map; other buildings are in UTC
+type fixedLocator map[string]*time.Location
+
+func (l fixedLocator) Location(ctx context.Context, buildingID string) (*time.Location, error) {
+	if loc, ok := l[buildingID]; ok {
+		return loc, nil
+	}
+	return time.UTC, nil
+}
+
+// TestScheduledCommandDispatch tests that due scheduled commands are sent once, that one-off
+// commands complete and that recurring commands move to their next run in building local time
+func TestScheduledCommandDispatch(t *testing.T) {
+	ctx := context.Background()
+	a := newApp(t)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	scheduleService := service.NewScheduleService(a.schedules, a.devices, a.control, fixedLocator{"building-1": berlin})
+
+	seedDevice(t, a.devices, "hvac-1", "HVAC", "building-1")
+	seedDevice(t, a.devices, "hvac-2", "HVAC", "building-1")
+
+	recurring, err := scheduleService.ScheduleCommand(ctx, "hvac-1", &models.SendCommandRequest{Command: "TURN_ON", Cron: "0 7 * * *"}, operatorUser.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "building-1", recurring.BuildingID)
+	require.NotNil(t, recurring.NextRunAt)
+	assert.Equal(t, 7, recurring.NextRunAt.In(berlin).Hour(), "the cron hour is Berlin time")
+	assert.Equal(t, 0, recurring.NextRunAt.In(berlin).Minute())
+
+	_, err = scheduleService.ScheduleCommand(ctx, "hvac-1", &models.SendCommandRequest{Command: "TURN_ON", Cron: "0 0 31 2 *"}, operatorUser.ID)
+	assert.Error(t, err, "an expression that never matches is rejected")
+
+	// Make both schedules due
+	due := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	_, err = a.schedules.TransitionStatus(ctx, recurring.ScheduleID, []models.ScheduleStatus{models.ScheduleStatusActive}, models.ScheduleStatusActive, &due)
+	require.NoError(t, err)
+	oneOff, err := a.schedules.Create(ctx, &models.ScheduledCommand{
+		ScheduleID:  "one-off",
+		DeviceID:    "hvac-2",
+		BuildingID:  "building-1",
+		Command:     "TURN_OFF",
+		Status:      models.ScheduleStatusActive,
+		ScheduledAt: &due,
+		NextRunAt:   &due,
+		CreatedBy:   operatorUser.ID,
+	})
+	require.NoError(t, err)
+
+	dispatched, err := scheduleService.DispatchDueCommands(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, dispatched)
+
+	recurring, err = a.schedules.FindByScheduleID(ctx, recurring.ScheduleID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleStatusActive, recurring.Status)
+	assert.Equal(t, 1, recurring.RunCount)
+	assert.NotEmpty(t, recurring.LastCommandID)
+	require.NotNil(t, recurring.NextRunAt)
+	assert.True(t, recurring.NextRunAt.After(time.Now()))
+	assert.Equal(t, 7, recurring.NextRunAt.In(berlin).Hour())
+
+	oneOff, err = a.schedules.FindByScheduleID(ctx, oneOff.ScheduleID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleStatusCompleted, oneOff.Status)
+	assert.Nil(t, oneOff.NextRunAt)
+	assert.NotEmpty(t, oneOff.LastCommandID)
+
+	// Nothing is due any more, so nothing is sent twice
+	dispatched, err = scheduleService.DispatchDueCommands(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, dispatched)
+
+	for _, deviceID := range []string{"hvac-1", "hvac-2"} {
+		_, total, err := a.commands.FindByDeviceID(ctx, deviceID, "", 1, 20)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total, deviceID)
+	}
+}
@@ -0,0 +1,137 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"iot-control-service/internal/repository"
+	"iot-control-service/internal/settings"
+)
+
+// settingsInstance is one service instance's view of the shared settings
+type settingsInstance struct {
+	store    *settings.Store
+	interval *settings.Duration
+	limit    *settings.Int
+	enabled  *settings.Bool
+	changes  atomic.Int32
+}
+
+func newSettingsInstance(collections *repository.Collections) *settingsInstance {
+	store := settings.NewStore(collections.Settings, collections.SettingsHistory)
+	instance := &settingsInstance{
+		store:    store,
+		interval: store.RegisterDuration("telemetry.flush_interval", "How often buffered telemetry is written", 5*time.Second, time.Second, time.Minute),
+		limit:    store.RegisterInt("commands.rate_limit", "Commands per device and minute", 10, 1, 100),
+		enabled:  store.RegisterBool("automation.enabled", "Whether automation rules run", true),
+	}
+	instance.limit.OnChange(func() { instance.changes.Add(1) })
+	return instance
+}
+
+// TestSettingsOverrides tests that a stored value overrides the default on every instance, and
+// that resetting it restores the default
+func TestSettingsOverrides(t *testing.T) {
+	ctx := context.Background()
+	collections := newDatabase(t, newConfig(t)).GetCollections()
+	a := newSettingsInstance(collections)
+	b := newSettingsInstance(collections)
+	require.NoError(t, a.store.Load(ctx))
+	require.NoError(t, b.store.Load(ctx))
+	assert.Equal(t, 10, a.limit.Get())
+	assert.Zero(t, a.changes.Load(), "loading the defaults changes nothing")
+
+	// JSON numbers arrive as float64
+	setting, err := a.store.Set(ctx, "commands.rate_limit", float64(25), adminUser.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 25, setting.Value)
+	assert.Equal(t, 10, setting.Default)
+	assert.True(t, setting.Overridden)
+	assert.Equal(t, adminUser.ID, setting.UpdatedBy)
+	assert.Equal(t, 25, a.limit.Get())
+	assert.Equal(t, int32(1), a.changes.Load())
+
+	// The other instance picks the change up on its next reload
+	assert.Equal(t, 10, b.limit.Get())
+	require.NoError(t, b.store.Load(ctx))
+	assert.Equal(t, 25, b.limit.Get())
+	assert.Equal(t, int32(1), b.changes.Load())
+	require.NoError(t, b.store.Load(ctx))
+	assert.Equal(t, int32(1), b.changes.Load(), "reloading an unchanged value does not notify")
+
+	setting, err = a.store.Set(ctx, "telemetry.flush_interval", "15s", adminUser.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "15s", setting.Value)
+	assert.Equal(t, 15*time.Second, a.interval.Get())
+
+	setting, err = a.store.Reset(ctx, "commands.rate_limit", operatorUser.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10, setting.Value)
+	assert.False(t, setting.Overridden)
+	assert.Empty(t, setting.UpdatedBy)
+	assert.Equal(t, 10, a.limit.Get())
+
+	changes, total, err := a.store.History(ctx, "commands.rate_limit", 1, 20)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	assert.Equal(t, settings.ActionReset, changes[0].Action)
+	assert.Equal(t, operatorUser.ID, changes[0].ChangedBy)
+	assert.EqualValues(t, 25, changes[0].OldValue)
+	assert.EqualValues(t, 10, changes[0].NewValue)
+	assert.Equal(t, settings.ActionSet, changes[1].Action)
+	assert.EqualValues(t, 10, changes[1].OldValue)
+	assert.EqualValues(t, 25, changes[1].NewValue)
+
+	// Resetting a setting without a stored value records nothing
+	_, err = a.store.Reset(ctx, "automation.enabled", adminUser.ID)
+	require.NoError(t, err)
+	_, total, err = a.store.History(ctx, "", 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+}
+
+// TestSettingsValidation tests that invalid values are rejected and invalid stored values ignored
+func TestSettingsValidation(t *testing.T) {
+	ctx := context.Background()
+	collections := newDatabase(t, newConfig(t)).GetCollections()
+	instance := newSettingsInstance(collections)
+	require.NoError(t, instance.store.Load(ctx))
+
+	for key, value := range map[string]interface{}{
+		"commands.rate_limit":      float64(2.5),
+		"telemetry.flush_interval": "2h",
+		"automation.enabled":       "yes",
+	} {
+		_, err := instance.store.Set(ctx, key, value, adminUser.ID)
+		require.Error(t, err, key)
+		assert.Contains(t, err.Error(), "validation failed: "+key)
+	}
+	_, err := instance.store.Set(ctx, "commands.rate_limit", float64(500), adminUser.ID)
+	require.Error(t, err)
+	assert.Equal(t, "validation failed: commands.rate_limit must be between 1 and 100", err.Error())
+
+	_, err = instance.store.Set(ctx, "unknown", true, adminUser.ID)
+	require.Error(t, err)
+	assert.Equal(t, "setting not found", err.Error())
+
+	_, total, err := instance.store.History(ctx, "", 1, 20)
+	require.NoError(t, err)
+	assert.Zero(t, total, "rejected values are not recorded")
+
+	// A stored value that is no longer valid, e.g. after the range was narrowed, is ignored
+	_, err = collections.Settings.InsertOne(ctx, bson.M{"_id": "commands.rate_limit", "value": 1000, "updated_by": adminUser.ID, "updated_at": time.Now()})
+	require.NoError(t, err)
+	require.NoError(t, instance.store.Load(ctx))
+	assert.Equal(t, 10, instance.limit.Get())
+	setting, err := instance.store.Get("commands.rate_limit")
+	require.NoError(t, err)
+	assert.False(t, setting.Overridden)
+}
@@ -0,0 +1,153 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"iot-control-service/internal/models"
+	"iot-control-service/internal/service"
+)
+
+// fakeForecaster serves the same hourly weather for every building, from two hours ago
+type fakeForecaster struct {
+	temperature float64
+}
+
+func (f *fakeForecaster) GetWeatherForecast(ctx context.Context, buildingID string, hours int) ([]models.WeatherPoint, error) {
+	start := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	points := make([]models.WeatherPoint, hours+2)
+	for i := range points {
+		points[i] = models.WeatherPoint{Timestamp: start.Add(time.Duration(i) * time.Hour), Temperature: f.temperature}
+	}
+	return points, nil
+}
+
+// makeRuleDue moves a rule's next run into the past
+func makeRuleDue(t *testing.T, a *app, rule *models.WeatherRule) {
+	t.Helper()
+
+	due := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	rule.NextRunAt = &due
+	_, err := a.weatherRules.Replace(context.Background(), rule)
+	require.NoError(t, err)
+}
+
+// TestWeatherRulesFireOnSchedule tests that the worker evaluates rules when their cron run is
+// due, sends their commands once when the condition is met and leaves devices controlled by an
+// optimization scenario alone
+func TestWeatherRulesFireOnSchedule(t *testing.T) {
+	ctx := context.Background()
+	a := newApp(t)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	forecaster := &fakeForecaster{temperature: 32}
+	rules := service.NewWeatherRuleService(a.weatherRules, a.ruleExecutions, a.devices, a.optimizations, a.control, forecaster, fixedLocator{"building-1": berlin})
+
+	seedDevice(t, a.devices, "hvac-1", "HVAC", "building-1")
+	seedDevice(t, a.devices, "hvac-2", "HVAC", "building-1")
+	_, err = a.optimizations.Create(ctx, &models.OptimizationScenario{
+		ScenarioID:      "scenario-1",
+		BuildingID:      "building-1",
+		Actions:         []models.OptimizationAction{{DeviceID: "hvac-2", Command: "SET_TEMP", Status: models.ActionStatusPending}},
+		ExecutionStatus: models.OptimizationStatusRunning,
+		CreatedBy:       adminUser.ID,
+	})
+	require.NoError(t, err)
+
+	hot, err := rules.CreateRule(ctx, &models.WeatherRuleRequest{
+		Name:       "Pre-cool on hot afternoons",
+		BuildingID: "building-1",
+		Condition:  models.WeatherCondition{Metric: models.WeatherMetricTemperature, Operator: models.WeatherOperatorGT, Threshold: 30, LookaheadHours: 2},
+		Cron:       "0 13 * * *",
+		Actions: []models.WeatherRuleAction{
+			{DeviceID: "hvac-1", Command: "TURN_ON"},
+			{DeviceID: "hvac-2", Command: "TURN_ON"},
+		},
+	}, adminUser.ID)
+	require.NoError(t, err)
+	require.NotNil(t, hot.NextRunAt)
+	assert.Equal(t, 13, hot.NextRunAt.In(berlin).Hour(), "the cron hour is Berlin time")
+	assert.Equal(t, models.WeatherConflictSkip, hot.ConflictPolicy)
+
+	cold, err := rules.CreateRule(ctx, &models.WeatherRuleRequest{
+		Name:       "Pre-heat on frost",
+		BuildingID: "building-1",
+		Condition:  models.WeatherCondition{Metric: models.WeatherMetricTemperature, Operator: models.WeatherOperatorLT, Threshold: 0},
+		Cron:       "0 6 * * *",
+		Actions:    []models.WeatherRuleAction{{DeviceID: "hvac-1", Command: "TURN_OFF"}},
+	}, adminUser.ID)
+	require.NoError(t, err)
+
+	disabled := false
+	paused, err := rules.CreateRule(ctx, &models.WeatherRuleRequest{
+		Name:       "Paused",
+		BuildingID: "building-1",
+		Condition:  models.WeatherCondition{Metric: models.WeatherMetricTemperature, Operator: models.WeatherOperatorGT, Threshold: 30},
+		Cron:       "* * * * *",
+		Actions:    []models.WeatherRuleAction{{DeviceID: "hvac-1", Command: "TURN_OFF"}},
+		Enabled:    &disabled,
+	}, adminUser.ID)
+	require.NoError(t, err)
+	assert.Nil(t, paused.NextRunAt, "a disabled rule has no next run")
+
+	// Nothing is due yet
+	triggered, err := rules.EvaluateDueRules(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, triggered)
+
+	makeRuleDue(t, a, hot)
+	makeRuleDue(t, a, cold)
+	triggered, err = rules.EvaluateDueRules(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, triggered, "only the rule whose condition is met triggers")
+
+	executions, total, err := a.ruleExecutions.FindByRuleID(ctx, hot.RuleID, 1, 20)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	execution := executions[0]
+	assert.Equal(t, models.WeatherRuleTriggerSchedule, execution.Trigger)
+	assert.True(t, execution.Triggered)
+	require.NotNil(t, execution.ObservedValue)
+	assert.Equal(t, 32.0, *execution.ObservedValue)
+	require.Len(t, execution.Actions, 2)
+	assert.Equal(t, models.WeatherActionSent, execution.Actions[0].Status)
+	assert.NotEmpty(t, execution.Actions[0].CommandID)
+	assert.Equal(t, models.WeatherActionSkipped, execution.Actions[1].Status)
+	assert.Equal(t, "scenario-1", execution.Actions[1].ScenarioID)
+
+	executions, total, err = a.ruleExecutions.FindByRuleID(ctx, cold.RuleID, 1, 20)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total, "a rule whose condition is not met is still logged")
+	assert.False(t, executions[0].Triggered)
+	assert.Empty(t, executions[0].Actions)
+
+	_, total, err = a.ruleExecutions.FindByRuleID(ctx, paused.RuleID, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total, "a disabled rule is never evaluated")
+
+	hot, err = a.weatherRules.FindByRuleID(ctx, hot.RuleID)
+	require.NoError(t, err)
+	require.NotNil(t, hot.NextRunAt)
+	assert.True(t, hot.NextRunAt.After(time.Now()))
+	assert.Equal(t, 13, hot.NextRunAt.In(berlin).Hour())
+	assert.NotNil(t, hot.LastTriggeredAt)
+
+	// The run is not evaluated again
+	triggered, err = rules.EvaluateDueRules(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, triggered)
+
+	commands, total, err := a.commands.FindByDeviceID(ctx, "hvac-1", "", 1, 20)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	assert.Equal(t, "TURN_ON", commands[0].Command)
+	_, total, err = a.commands.FindByDeviceID(ctx, "hvac-2", "", 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+}
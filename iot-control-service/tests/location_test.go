@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"iot-control-service/internal/models"
+)
+
+func TestLocationParentLevels(t *testing.T) {
+	assert.Empty(t, models.LocationLevelSite.ParentLevels())
+	assert.Equal(t, []models.LocationLevel{models.LocationLevelSite}, models.LocationLevelBuilding.ParentLevels())
+	assert.Equal(t, []models.LocationLevel{models.LocationLevelBuilding}, models.LocationLevelFloor.ParentLevels())
+	assert.Equal(t, []models.LocationLevel{models.LocationLevelFloor}, models.LocationLevelZone.ParentLevels())
+	assert.Equal(t, []models.LocationLevel{models.LocationLevelZone, models.LocationLevelFloor}, models.LocationLevelRoom.ParentLevels())
+}
+
+func TestSummarizeLocationActions(t *testing.T) {
+	actions := []models.OptimizationAction{
+		{DeviceID: "light-1", LocationID: "zone-east", Status: models.ActionStatusApplied},
+		{DeviceID: "hvac-1", Status: models.ActionStatusApplied},
+		{DeviceID: "light-2", LocationID: "zone-east", Status: "TIMEOUT"},
+		{DeviceID: "light-3", LocationID: "zone-west", Status: models.ActionStatusPending},
+		{DeviceID: "light-4", LocationID: "zone-east", Status: models.ActionStatusRolledBack},
+		{DeviceID: "light-5", LocationID: "zone-west", Status: models.ActionStatusSent},
+	}
+
+	summaries := models.SummarizeLocationActions(actions)
+	assert.Equal(t, []models.LocationActionSummary{
+		{LocationID: "zone-east", Actions: 3, Applied: 1, Failed: 1, RolledBack: 1},
+		{LocationID: "zone-west", Actions: 2, Pending: 2},
+	}, summaries)
+
+	assert.Empty(t, models.SummarizeLocationActions([]models.OptimizationAction{{DeviceID: "hvac-1"}}))
+}
@@ -56,7 +56,7 @@ func TestTelemetryIngestion(t *testing.T) {
 	}
 
 	// Create service
-	telemetryService := service.NewTelemetryService(mockTelemetryRepo, mockDeviceRepo)
+	telemetryService := service.NewTelemetryService(mockTelemetryRepo, mockDeviceRepo, nil, nil)
 
 	// Test single telemetry ingestion
 	req := &models.TelemetryIngestRequest{
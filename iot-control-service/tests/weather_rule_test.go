@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"iot-control-service/internal/models"
+)
+
+func TestWeatherConditionMet(t *testing.T) {
+	tests := []struct {
+		operator models.WeatherOperator
+		value    float64
+		want     bool
+	}{
+		{models.WeatherOperatorGT, 30.5, true},
+		{models.WeatherOperatorGT, 30, false},
+		{models.WeatherOperatorGT, 29.5, false},
+		{models.WeatherOperatorGTE, 30.5, true},
+		{models.WeatherOperatorGTE, 30, true},
+		{models.WeatherOperatorGTE, 29.5, false},
+		{models.WeatherOperatorLT, 29.5, true},
+		{models.WeatherOperatorLT, 30, false},
+		{models.WeatherOperatorLT, 30.5, false},
+		{models.WeatherOperatorLTE, 29.5, true},
+		{models.WeatherOperatorLTE, 30, true},
+		{models.WeatherOperatorLTE, 30.5, false},
+		{"EQ", 30, false},
+	}
+
+	for _, tt := range tests {
+		condition := models.WeatherCondition{Metric: models.WeatherMetricTemperature, Operator: tt.operator, Threshold: 30}
+		assert.Equal(t, tt.want, condition.Met(tt.value), "%s %v", tt.operator, tt.value)
+	}
+}
+
+func TestWeatherConditionObserve(t *testing.T) {
+	runAt := time.Date(2024, 7, 1, 13, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return time.Date(2024, 7, 1, h, 0, 0, 0, time.UTC) }
+	points := []models.WeatherPoint{
+		{Timestamp: hour(12), Temperature: 35, Humidity: 20, CloudCover: 0, WindSpeed: 1},
+		{Timestamp: hour(13), Temperature: 28, Humidity: 40, CloudCover: 10, WindSpeed: 3},
+		{Timestamp: hour(14), Temperature: 31, Humidity: 35, CloudCover: 60, WindSpeed: 8},
+		{Timestamp: hour(15), Temperature: 33, Humidity: 30, CloudCover: 90, WindSpeed: 5},
+		{Timestamp: hour(16), Temperature: 36, Humidity: 25, CloudCover: 20, WindSpeed: 2},
+	}
+
+	tests := []struct {
+		name      string
+		condition models.WeatherCondition
+		runAt     time.Time
+		wantValue float64
+		wantAt    time.Time
+		wantMet   bool
+	}{
+		{
+			name:      "only the run hour without lookahead",
+			condition: models.WeatherCondition{Metric: models.WeatherMetricTemperature, Operator: models.WeatherOperatorGT, Threshold: 30},
+			runAt:     runAt,
+			wantValue: 28, wantAt: hour(13), wantMet: false,
+		},
+		{
+			name:      "highest value in the lookahead decides GT",
+			condition: models.WeatherCondition{Metric: models.WeatherMetricTemperature, Operator: models.WeatherOperatorGT, Threshold: 30, LookaheadHours: 2},
+			runAt:     runAt,
+			wantValue: 33, wantAt: hour(15), wantMet: true,
+		},
+		{
+			name:      "lowest value in the lookahead decides LT",
+			condition: models.WeatherCondition{Metric: models.WeatherMetricTemperature, Operator: models.WeatherOperatorLT, Threshold: 30, LookaheadHours: 3},
+			runAt:     runAt,
+			wantValue: 28, wantAt: hour(13), wantMet: true,
+		},
+		{
+			name:      "the hour after the lookahead is excluded",
+			condition: models.WeatherCondition{Metric: models.WeatherMetricTemperature, Operator: models.WeatherOperatorGTE, Threshold: 36, LookaheadHours: 2},
+			runAt:     runAt,
+			wantValue: 33, wantAt: hour(15), wantMet: false,
+		},
+		{
+			name:      "a run within the hour looks from the start of the hour",
+			condition: models.WeatherCondition{Metric: models.WeatherMetricTemperature, Operator: models.WeatherOperatorLTE, Threshold: 28},
+			runAt:     runAt.Add(40 * time.Minute),
+			wantValue: 28, wantAt: hour(13), wantMet: true,
+		},
+		{
+			name:      "humidity",
+			condition: models.WeatherCondition{Metric: models.WeatherMetricHumidity, Operator: models.WeatherOperatorLT, Threshold: 32, LookaheadHours: 3},
+			runAt:     runAt,
+			wantValue: 25, wantAt: hour(16), wantMet: true,
+		},
+		{
+			name:      "cloud cover",
+			condition: models.WeatherCondition{Metric: models.WeatherMetricCloudCover, Operator: models.WeatherOperatorGTE, Threshold: 90, LookaheadHours: 1},
+			runAt:     runAt,
+			wantValue: 60, wantAt: hour(14), wantMet: false,
+		},
+		{
+			name:      "wind speed",
+			condition: models.WeatherCondition{Metric: models.WeatherMetricWindSpeed, Operator: models.WeatherOperatorGT, Threshold: 7, LookaheadHours: 3},
+			runAt:     runAt,
+			wantValue: 8, wantAt: hour(14), wantMet: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, at, ok := tt.condition.Observe(points, tt.runAt)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantValue, value)
+			assert.Equal(t, tt.wantAt, at)
+			assert.Equal(t, tt.wantMet, tt.condition.Met(value))
+		})
+	}
+}
+
+func TestWeatherConditionObserve_NoData(t *testing.T) {
+	condition := models.WeatherCondition{Metric: models.WeatherMetricTemperature, Operator: models.WeatherOperatorGT, Threshold: 30, LookaheadHours: 1}
+	runAt := time.Date(2024, 7, 1, 13, 0, 0, 0, time.UTC)
+
+	_, _, ok := condition.Observe(nil, runAt)
+	assert.False(t, ok)
+
+	// Points outside the window do not count
+	points := []models.WeatherPoint{
+		{Timestamp: runAt.Add(-time.Hour), Temperature: 40},
+		{Timestamp: runAt.Add(2 * time.Hour), Temperature: 40},
+	}
+	_, _, ok = condition.Observe(points, runAt)
+	assert.False(t, ok)
+}
@@ -14,21 +14,32 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"security-service/internal/config"
+	"security-service/internal/events"
 	"security-service/internal/handlers"
 	"security-service/internal/integrations"
 	"security-service/internal/middleware"
 	"security-service/internal/repository"
 	"security-service/internal/service"
+	"security-service/internal/tracing"
 	"security-service/pkg/utils"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
+	// Set up tracing before anything opens connections, so they are instrumented
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
 	// Connect to MongoDB
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -53,15 +64,37 @@ func main() {
 	// Get collections
 	collections := mongoDB.GetCollections()
 
+	// PII fields are encrypted by the repositories that store them
+	fieldEncryptor, err := utils.NewFieldEncryptor(cfg.Encryption.PIIKeyID, cfg.Encryption.PIIKey, cfg.Encryption.PIIRetiredKeys, cfg.Encryption.PIIHashKey)
+	if err != nil {
+		log.Fatalf("Failed to initialize PII encryption: %v", err)
+	}
+
 	// Initialize repositories
-	userRepo := repository.NewUserRepository(collections.Users)
+	userRepo := repository.NewUserRepository(collections.Users, fieldEncryptor)
 	roleRepo := repository.NewRoleRepository(collections.Roles)
 	authRepo := repository.NewAuthRepository(collections.RefreshTokens, collections.AuthCredentials)
 	auditRepo := repository.NewAuditRepository(collections.AuditLogs)
-	notificationRepo := repository.NewNotificationRepository(collections.Notifications, collections.NotificationPrefs)
+	notificationRepo := repository.NewNotificationRepository(collections.Notifications, collections.NotificationPrefs, fieldEncryptor)
+	energyProviderRepo := repository.NewEnergyProviderRepository(collections.EnergyProviders)
+	kioskRepo := repository.NewKioskRepository(collections.KioskTokens)
+	signingKeyRepo := repository.NewSigningKeyRepository(collections.SigningKeys)
+	groupRepo := repository.NewGroupRepository(collections.Groups)
+	orgRepo := repository.NewOrganizationRepository(collections.Organizations)
+	usageRepo := repository.NewUsageRepository(collections.UsageCounters, collections.UsageSnapshots, collections.UsageAlerts)
+
+	// Role and account changes are pushed to services caching token validations
+	roleChangePublisher := integrations.NewRoleChangePublisher(cfg)
+
+	// Connect to the inter-service event bus
+	var eventBus *events.Bus
+	if cfg.Events.Enabled {
+		eventBus = events.NewBus(cfg, "security-service")
+		defer eventBus.Close()
+	}
 
 	// Initialize default roles
-	roleService := service.NewRoleService(roleRepo, auditRepo)
+	roleService := service.NewRoleService(roleRepo, userRepo, auditRepo, roleChangePublisher)
 	if err := roleService.InitializeDefaultRoles(ctx); err != nil {
 		log.Printf("Warning: Failed to initialize default roles: %v", err)
 	}
@@ -74,18 +107,62 @@ func main() {
 	)
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, roleRepo, authRepo, auditRepo, jwtManager)
-	userService := service.NewUserService(userRepo, roleRepo, auditRepo)
+	authService := service.NewAuthService(userRepo, roleRepo, groupRepo, authRepo, auditRepo, notificationRepo, kioskRepo, orgRepo, jwtManager, roleChangePublisher, cfg.JWT.ImpersonationTokenExpiry)
+	userService := service.NewUserService(userRepo, roleRepo, auditRepo, authRepo, kioskRepo, orgRepo, roleChangePublisher, eventBus)
+	groupService := service.NewGroupService(groupRepo, roleRepo, userRepo, auditRepo, roleChangePublisher)
 	auditService := service.NewAuditService(auditRepo)
+	kioskService := service.NewKioskService(kioskRepo, auditRepo, jwtManager, roleChangePublisher)
+	organizationService := service.NewOrganizationService(orgRepo, userRepo, auditRepo, roleChangePublisher)
+
+	// Start background workers
+	workerCtx, workerCancel := context.WithCancel(context.Background())
+	defer workerCancel()
+	go userService.StartPurgeWorker(workerCtx, cfg.SoftDelete.PurgeInterval, cfg.SoftDelete.Retention)
+
+	// Initialize data retention
+	retentionService := service.NewRetentionService(cfg.Retention.DryRun, cfg.Retention.Interval)
+	retentionService.Register("audit_logs", cfg.Retention.AuditLogs, auditRepo.CountOlderThan, auditRepo.DeleteOlderThan)
+	retentionService.Register("notifications", cfg.Retention.Notifications, notificationRepo.CountOlderThan, notificationRepo.DeleteOlderThan)
+	if cfg.Retention.Enabled {
+		go retentionService.StartWorker(workerCtx)
+	}
+
+	// Re-encrypt PII left plaintext or encrypted with a retired key
+	piiEncryptionService := service.NewPIIEncryptionService(userRepo, notificationRepo, auditRepo, fieldEncryptor, cfg.Encryption.ReencryptionInterval)
+	if cfg.Encryption.ReencryptionInterval > 0 {
+		go piiEncryptionService.StartWorker(workerCtx)
+	}
 
 	// Initialize external integrations
 	notificationClient := integrations.NewNotificationClient(cfg)
-	energyClient, err := integrations.NewEnergyProviderClient(cfg, authRepo)
+	encryptor, err := utils.NewEncryptor(cfg.Encryption.Key)
 	if err != nil {
-		log.Printf("Warning: Failed to initialize energy client: %v", err)
+		log.Fatalf("Failed to initialize encryptor: %v", err)
 	}
 
-	notificationService := service.NewNotificationService(notificationRepo, notificationClient)
+	notificationService := service.NewNotificationService(notificationRepo, userRepo, notificationClient, eventBus)
+	if cfg.Notification.AlertEnabled {
+		go notificationService.StartFailureAlertWorker(workerCtx, cfg.Notification.AlertInterval, cfg.Notification.AlertWindow, cfg.Notification.AlertFailureRatePercent, cfg.Notification.AlertMinSamples)
+	}
+
+	// Initialize usage metering
+	meteringService := service.NewMeteringService(usageRepo, orgRepo, userRepo, notificationService, cfg.Metering.AlertThresholds)
+	if cfg.Metering.Enabled {
+		go meteringService.StartWorker(workerCtx, cfg.Metering.CheckInterval)
+	}
+
+	// Initialize energy providers
+	energyService := service.NewEnergyService(energyProviderRepo, authRepo, auditRepo, encryptor)
+	if err := energyService.InitializeDefaultProvider(ctx, cfg.Energy); err != nil {
+		log.Printf("Warning: Failed to initialize default energy provider: %v", err)
+	}
+
+	// Load token signing keys; other instances' rotations are picked up by the worker
+	signingKeyService := service.NewSigningKeyService(signingKeyRepo, kioskRepo, auditRepo, jwtManager, encryptor, cfg.JWT)
+	if err := signingKeyService.Initialize(ctx); err != nil {
+		log.Fatalf("Failed to initialize signing keys: %v", err)
+	}
+	go signingKeyService.StartWorker(workerCtx, cfg.JWT.KeySyncInterval)
 
 	// Initialize default admin user
 	if err := userService.InitializeAdminUser(ctx); err != nil {
@@ -93,30 +170,69 @@ func main() {
 	}
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwtManager)
+	authMiddleware := middleware.NewAuthMiddleware(jwtManager, cfg.Internal.ServiceKey, auditRepo)
+	authMiddleware.ConfigureServiceAuth(cfg.Internal.ServiceSecrets, cfg.Internal.SharedSecretDevMode, cfg.Internal.SignatureWindow, cfg.Internal.AcceptServiceKey)
+
+	// Initialize health checks
+	healthService := service.NewHealthService("security-service")
+	healthService.Register("mongodb", true, mongoDB.HealthCheck)
+	healthService.Register("notifications", false, notificationClient.HealthCheck)
+	healthService.Register("energy_provider", false, energyService.HealthCheck)
+	if eventBus != nil {
+		healthService.Register("event_bus", false, eventBus.HealthCheck)
+	}
+
+	// Consume events published by other services
+	if eventBus != nil {
+		if err := events.NewSubscriber(eventBus, auditRepo, userRepo, roleRepo, groupRepo, notificationService, meteringService).Start(); err != nil {
+			log.Printf("Warning: Failed to subscribe to events: %v", err)
+		}
+	}
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	userHandler := handlers.NewUserHandler(userService)
 	roleHandler := handlers.NewRoleHandler(roleService)
+	groupHandler := handlers.NewGroupHandler(groupService)
 	auditHandler := handlers.NewAuditHandler(auditService)
 	notificationHandler := handlers.NewNotificationHandler(notificationService)
-	energyHandler := handlers.NewEnergyHandler(energyClient)
+	energyHandler := handlers.NewEnergyHandler(energyService)
+	healthHandler := handlers.NewHealthHandler(healthService)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
+	kioskHandler := handlers.NewKioskHandler(kioskService)
+	signingKeyHandler := handlers.NewSigningKeyHandler(signingKeyService)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService)
+	meteringHandler := handlers.NewMeteringHandler(meteringService)
+	piiEncryptionHandler := handlers.NewPIIEncryptionHandler(piiEncryptionService)
 
 	// Create router
 	router := handlers.NewRouter(
 		authHandler,
 		userHandler,
 		roleHandler,
+		groupHandler,
 		auditHandler,
 		notificationHandler,
 		energyHandler,
+		healthHandler,
+		retentionHandler,
+		kioskHandler,
+		signingKeyHandler,
+		organizationHandler,
+		meteringHandler,
+		piiEncryptionHandler,
 		authMiddleware,
 	)
 
+	// Meter this service's own API calls; other services report theirs as events
+	if cfg.Metering.Enabled {
+		router.UsageMeter = middleware.NewUsageMeter()
+		go router.UsageMeter.StartReporter(workerCtx, cfg.Metering.ReportInterval, meteringService.RecordAPICalls)
+	}
+
 	// Create Gin engine and setup routes
 	engine := gin.New()
-	router.SetupRoutes(engine)
+	router.SetupRoutes(engine, cfg.HTTP)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -149,5 +265,10 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Flush the spans still buffered for export
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+
 	log.Println("Server exited properly")
 }
@@ -5,9 +5,12 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+
+	"shared/signing"
 )
 
 // Config holds all application configuration
@@ -19,7 +22,37 @@ type Config struct {
 	Notification NotificationConfig
 	Energy       EnergyProviderConfig
 	Storage      StorageServiceConfig
+	SoftDelete   SoftDeleteConfig
+	Retention    RetentionConfig
+	Events       EventsConfig
+	Metering     MeteringConfig
+	Internal     InternalConfig
 	Logging      LoggingConfig
+	Tracing      TracingConfig
+	HTTP         HTTPConfig
+}
+
+// InternalConfig holds settings for service-to-service integration
+type InternalConfig struct {
+	// ServiceKey is the shared secret internal requests are signed with: calls to internal-only
+	// endpoints and this service's role-change notifications. Empty disables both.
+	// ServiceSecret overrides it for requests this service sends, and ServiceSecrets for
+	// requests from the named services. Requests from services without their own secret are only
+	// verified with ServiceKey when SharedSecretDevMode is set, since any service could otherwise
+	// sign as another.
+	ServiceKey          string
+	ServiceSecret       string
+	ServiceSecrets      map[string]string
+	SharedSecretDevMode bool
+	SignatureWindow     time.Duration
+	AcceptServiceKey    bool // also accept the unsigned X-Service-Key header during migration
+	RoleChangeWebhooks  []string
+}
+
+// SoftDeleteConfig holds retention settings for soft-deleted records
+type SoftDeleteConfig struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
 }
 
 // StorageServiceConfig holds Storage service integration settings
@@ -40,25 +73,109 @@ type MongoDBConfig struct {
 	URI      string
 	Database string
 	Timeout  time.Duration
+	// ReplicaSet, ReadPreference and WriteConcern are left to the URI and driver defaults when empty
+	ReplicaSet             string
+	ReadPreference         string
+	WriteConcern           string
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	ServerSelectionTimeout time.Duration
+	RetryWrites            bool
 }
 
 // JWTConfig holds JWT token configuration
 type JWTConfig struct {
-	Secret             string
-	AccessTokenExpiry  time.Duration
-	RefreshTokenExpiry time.Duration
+	Secret                   string
+	AccessTokenExpiry        time.Duration
+	RefreshTokenExpiry       time.Duration
+	ImpersonationTokenExpiry time.Duration
+	// SigningAlgorithm is used for rotated keys: HS256, RS256 or ES256
+	SigningAlgorithm string
+	// KeyRotationGrace is how long tokens signed with a rotated-out key stay valid
+	KeyRotationGrace time.Duration
+	// KeyRotationInterval rotates the signing key automatically; zero disables it
+	KeyRotationInterval time.Duration
+	// KeySyncInterval is how often the key ring is reloaded to pick up rotations by other instances
+	KeySyncInterval time.Duration
 }
 
 // EncryptionConfig holds encryption settings
 type EncryptionConfig struct {
 	Key string
+
+	// PII fields of users and notification preferences are encrypted with PIIKey under PIIKeyID.
+	// PIIRetiredKeys holds earlier keys by ID, still accepted for decryption until the
+	// re-encryption job has moved every record to the current key. PIIHashKey keys the lookup
+	// hashes and must not change once records are written.
+	PIIKeyID       string
+	PIIKey         string
+	PIIRetiredKeys map[string]string
+	PIIHashKey     string
+
+	// ReencryptionInterval is how often PII encrypted with a retired key, or not yet encrypted,
+	// is re-encrypted with the current key; zero disables the job
+	ReencryptionInterval time.Duration
 }
 
-// NotificationConfig holds notification service URLs
+// NotificationConfig holds notification service URLs and provider selection
 type NotificationConfig struct {
 	EmailURL string
 	SMSURL   string
 	PushURL  string
+
+	// Provider selection per notification type (gateway, smtp, sendgrid, twilio, fcm).
+	// The fallback provider is used when the primary provider fails.
+	EmailProvider         string
+	EmailFallbackProvider string
+	SMSProvider           string
+	SMSFallbackProvider   string
+	PushProvider          string
+	PushFallbackProvider  string
+	HealthCheckTimeout    time.Duration
+
+	// Failure rate alerting: a channel alerts when more than AlertFailureRatePercent of the
+	// notifications that finished sending in the rolling AlertWindow failed, given at least
+	// AlertMinSamples of them
+	AlertEnabled            bool
+	AlertInterval           time.Duration
+	AlertWindow             time.Duration
+	AlertFailureRatePercent int
+	AlertMinSamples         int
+
+	SMTP     SMTPConfig
+	SendGrid SendGridConfig
+	Twilio   TwilioConfig
+	FCM      FCMConfig
+}
+
+// SMTPConfig holds SMTP email provider settings
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SendGridConfig holds SendGrid email provider settings
+type SendGridConfig struct {
+	BaseURL string
+	APIKey  string
+	From    string
+}
+
+// TwilioConfig holds Twilio SMS provider settings
+type TwilioConfig struct {
+	BaseURL    string
+	AccountSID string
+	AuthToken  string
+	From       string
+}
+
+// FCMConfig holds Firebase Cloud Messaging push provider settings
+type FCMConfig struct {
+	URL       string
+	ServerKey string
 }
 
 // EnergyProviderConfig holds external energy provider settings
@@ -69,12 +186,53 @@ type EnergyProviderConfig struct {
 	ClientSecret string
 }
 
+// RetentionConfig holds per-collection data retention settings.
+// A retention of zero days disables purging for that collection.
+type RetentionConfig struct {
+	Enabled       bool
+	DryRun        bool
+	Interval      time.Duration
+	AuditLogs     time.Duration
+	Notifications time.Duration
+}
+
+// EventsConfig holds inter-service event bus settings.
+// Events are exchanged through the MQTT broker shared by all services.
+type EventsConfig struct {
+	Enabled  bool
+	Broker   string
+	Port     int
+	Username string
+	Password string
+	ClientID string
+	QoS      byte
+}
+
+// MeteringConfig holds usage metering settings. Every CheckInterval the completed days are
+// snapshotted for invoicing and organizations are alerted when their monthly usage crosses
+// AlertThresholds, in percent of their limits.
+type MeteringConfig struct {
+	Enabled         bool
+	ReportInterval  time.Duration // how often this service's own API calls are recorded
+	CheckInterval   time.Duration
+	AlertThresholds []int
+}
+
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
 	Level  string
 	Format string
 }
 
+// TracingConfig holds OpenTelemetry tracing settings. Spans are exported over OTLP/HTTP.
+type TracingConfig struct {
+	Enabled     bool
+	Endpoint    string  // collector host:port, or a full URL of its traces endpoint
+	Insecure    bool    // export over plain HTTP
+	ServiceName string  // service.name of the exported spans
+	SampleRatio float64 // share of traces started by this service that are recorded
+}
+
 // Load reads configuration from environment variables
 func Load() *Config {
 	// Load .env file if it exists
@@ -82,29 +240,86 @@ func Load() *Config {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	mode := getEnv("GIN_MODE", "debug")
+
 	return &Config{
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8080"),
 			Host: getEnv("SERVER_HOST", "0.0.0.0"),
-			Mode: getEnv("GIN_MODE", "debug"),
+			Mode: mode,
 		},
 		MongoDB: MongoDBConfig{
-			URI:      getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-			Database: getEnv("MONGODB_DATABASE", "security_service"),
-			Timeout:  time.Duration(getEnvAsInt("MONGODB_TIMEOUT", 10)) * time.Second,
+			URI:                    getEnv("MONGODB_URI", "mongodb://localhost:27017"),
+			Database:               getEnv("MONGODB_DATABASE", "security_service"),
+			Timeout:                time.Duration(getEnvAsInt("MONGODB_TIMEOUT", 10)) * time.Second,
+			ReplicaSet:             getEnv("MONGODB_REPLICA_SET", ""),
+			ReadPreference:         getEnv("MONGODB_READ_PREFERENCE", ""),
+			WriteConcern:           getEnv("MONGODB_WRITE_CONCERN", ""),
+			MaxPoolSize:            uint64(getEnvAsInt("MONGODB_MAX_POOL_SIZE", 100)),
+			MinPoolSize:            uint64(getEnvAsInt("MONGODB_MIN_POOL_SIZE", 10)),
+			ServerSelectionTimeout: time.Duration(getEnvAsInt("MONGODB_SERVER_SELECTION_TIMEOUT", 30)) * time.Second,
+			RetryWrites:            getEnvAsBool("MONGODB_RETRY_WRITES", true),
 		},
 		JWT: JWTConfig{
-			Secret:             getEnv("JWT_SECRET", "default-secret-change-me"),
-			AccessTokenExpiry:  parseDuration(getEnv("JWT_ACCESS_TOKEN_EXPIRY", "15m")),
-			RefreshTokenExpiry: parseDuration(getEnv("JWT_REFRESH_TOKEN_EXPIRY", "168h")), // 7 days
+			Secret:                   getEnv("JWT_SECRET", "default-secret-change-me"),
+			AccessTokenExpiry:        parseDuration(getEnv("JWT_ACCESS_TOKEN_EXPIRY", "15m")),
+			RefreshTokenExpiry:       parseDuration(getEnv("JWT_REFRESH_TOKEN_EXPIRY", "168h")), // 7 days
+			ImpersonationTokenExpiry: parseDuration(getEnv("JWT_IMPERSONATION_TOKEN_EXPIRY", "10m")),
+			SigningAlgorithm:         getEnv("JWT_SIGNING_ALGORITHM", "HS256"),
+			KeyRotationGrace:         parseDuration(getEnv("JWT_KEY_ROTATION_GRACE", "168h")), // refresh token lifetime
+			KeyRotationInterval:      parseDuration(getEnv("JWT_KEY_ROTATION_INTERVAL", "0")),
+			KeySyncInterval:          parseDuration(getEnv("JWT_KEY_SYNC_INTERVAL", "1m")),
 		},
 		Encryption: EncryptionConfig{
 			Key: getEnv("ENCRYPTION_KEY", "32-byte-encryption-key-here!!!!"),
+
+			PIIKeyID:             getEnv("PII_ENCRYPTION_KEY_ID", "1"),
+			PIIKey:               getEnv("PII_ENCRYPTION_KEY", getEnv("ENCRYPTION_KEY", "32-byte-encryption-key-here!!!!")),
+			PIIRetiredKeys:       signing.ParseSecrets(getEnv("PII_ENCRYPTION_RETIRED_KEYS", "")),
+			PIIHashKey:           getEnv("PII_HASH_KEY", getEnv("ENCRYPTION_KEY", "32-byte-encryption-key-here!!!!")),
+			ReencryptionInterval: time.Duration(getEnvAsInt("PII_REENCRYPTION_INTERVAL_HOURS", 24)) * time.Hour,
 		},
 		Notification: NotificationConfig{
 			EmailURL: getEnv("NOTIFICATION_EMAIL_URL", "http://localhost:8081/external/notifications/email"),
 			SMSURL:   getEnv("NOTIFICATION_SMS_URL", "http://localhost:8081/external/notifications/sms"),
 			PushURL:  getEnv("NOTIFICATION_PUSH_URL", "http://localhost:8081/external/notifications/push"),
+
+			EmailProvider:         getEnv("NOTIFICATION_EMAIL_PROVIDER", "gateway"),
+			EmailFallbackProvider: getEnv("NOTIFICATION_EMAIL_FALLBACK_PROVIDER", ""),
+			SMSProvider:           getEnv("NOTIFICATION_SMS_PROVIDER", "gateway"),
+			SMSFallbackProvider:   getEnv("NOTIFICATION_SMS_FALLBACK_PROVIDER", ""),
+			PushProvider:          getEnv("NOTIFICATION_PUSH_PROVIDER", "gateway"),
+			PushFallbackProvider:  getEnv("NOTIFICATION_PUSH_FALLBACK_PROVIDER", ""),
+			HealthCheckTimeout:    time.Duration(getEnvAsInt("NOTIFICATION_HEALTH_CHECK_TIMEOUT", 5)) * time.Second,
+
+			AlertEnabled:            getEnvAsBool("NOTIFICATION_ALERT_ENABLED", true),
+			AlertInterval:           time.Duration(getEnvAsInt("NOTIFICATION_ALERT_INTERVAL_MINUTES", 5)) * time.Minute,
+			AlertWindow:             time.Duration(getEnvAsInt("NOTIFICATION_ALERT_WINDOW_MINUTES", 60)) * time.Minute,
+			AlertFailureRatePercent: getEnvAsInt("NOTIFICATION_ALERT_FAILURE_RATE_PERCENT", 20),
+			AlertMinSamples:         getEnvAsInt("NOTIFICATION_ALERT_MIN_SAMPLES", 10),
+
+			SMTP: SMTPConfig{
+				Host:     getEnv("SMTP_HOST", ""),
+				Port:     getEnvAsInt("SMTP_PORT", 587),
+				Username: getEnv("SMTP_USERNAME", ""),
+				Password: getEnv("SMTP_PASSWORD", ""),
+				From:     getEnv("SMTP_FROM", ""),
+			},
+			SendGrid: SendGridConfig{
+				BaseURL: getEnv("SENDGRID_BASE_URL", "https://api.sendgrid.com"),
+				APIKey:  getEnv("SENDGRID_API_KEY", ""),
+				From:    getEnv("SENDGRID_FROM", ""),
+			},
+			Twilio: TwilioConfig{
+				BaseURL:    getEnv("TWILIO_BASE_URL", "https://api.twilio.com"),
+				AccountSID: getEnv("TWILIO_ACCOUNT_SID", ""),
+				AuthToken:  getEnv("TWILIO_AUTH_TOKEN", ""),
+				From:       getEnv("TWILIO_FROM_NUMBER", ""),
+			},
+			FCM: FCMConfig{
+				URL:       getEnv("FCM_URL", "https://fcm.googleapis.com/fcm/send"),
+				ServerKey: getEnv("FCM_SERVER_KEY", ""),
+			},
 		},
 		Energy: EnergyProviderConfig{
 			BaseURL:      getEnv("ENERGY_PROVIDER_BASE_URL", "https://api.energy-provider.com"),
@@ -116,13 +331,62 @@ func Load() *Config {
 			URL:     getEnv("STORAGE_SERVICE_URL", "http://localhost:8086/storage"),
 			Timeout: time.Duration(getEnvAsInt("STORAGE_SERVICE_TIMEOUT", 10)) * time.Second,
 		},
+		SoftDelete: SoftDeleteConfig{
+			Retention:     time.Duration(getEnvAsInt("SOFT_DELETE_RETENTION_DAYS", 30)) * 24 * time.Hour,
+			PurgeInterval: time.Duration(getEnvAsInt("SOFT_DELETE_PURGE_INTERVAL_HOURS", 24)) * time.Hour,
+		},
+		Retention: RetentionConfig{
+			Enabled:       getEnv("RETENTION_ENABLED", "true") == "true",
+			DryRun:        getEnv("RETENTION_DRY_RUN", "false") == "true",
+			Interval:      time.Duration(getEnvAsInt("RETENTION_INTERVAL_HOURS", 24)) * time.Hour,
+			AuditLogs:     time.Duration(getEnvAsInt("RETENTION_AUDIT_LOG_DAYS", 365)) * 24 * time.Hour,
+			Notifications: time.Duration(getEnvAsInt("RETENTION_NOTIFICATION_DAYS", 90)) * 24 * time.Hour,
+		},
+		Events: EventsConfig{
+			Enabled:  getEnv("EVENTS_ENABLED", "false") == "true",
+			Broker:   getEnv("EVENT_BUS_BROKER", "localhost"),
+			Port:     getEnvAsInt("EVENT_BUS_PORT", 1883),
+			Username: getEnv("EVENT_BUS_USERNAME", ""),
+			Password: getEnv("EVENT_BUS_PASSWORD", ""),
+			ClientID: getEnv("EVENT_BUS_CLIENT_ID", "security-service-events"),
+			QoS:      byte(getEnvAsInt("EVENT_BUS_QOS", 1)),
+		},
+		Metering: MeteringConfig{
+			Enabled:         getEnvAsBool("METERING_ENABLED", true),
+			ReportInterval:  time.Duration(getEnvAsInt("METERING_REPORT_INTERVAL_SECONDS", 60)) * time.Second,
+			CheckInterval:   time.Duration(getEnvAsInt("METERING_CHECK_INTERVAL_MINUTES", 60)) * time.Minute,
+			AlertThresholds: getEnvAsIntList("METERING_ALERT_THRESHOLDS", []int{80, 100}),
+		},
+		Internal: InternalConfig{
+			ServiceKey:          getEnv("INTERNAL_SERVICE_KEY", ""),
+			ServiceSecret:       getEnv("INTERNAL_SERVICE_SECRET", getEnv("INTERNAL_SERVICE_KEY", "")),
+			ServiceSecrets:      signing.ParseSecrets(getEnv("INTERNAL_SERVICE_SECRETS", "")),
+			SharedSecretDevMode: getEnvAsBool("INTERNAL_SHARED_SECRET_DEV_MODE", false),
+			SignatureWindow:     time.Duration(getEnvAsInt("INTERNAL_SIGNATURE_WINDOW_SECONDS", 300)) * time.Second,
+			AcceptServiceKey:    getEnvAsBool("INTERNAL_ACCEPT_SERVICE_KEY", false),
+			RoleChangeWebhooks:  getEnvAsList("ROLE_CHANGE_WEBHOOK_URLS"),
+		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "debug"),
 			Format: getEnv("LOG_FORMAT", "json"),
 		},
+		Tracing: TracingConfig{
+			Enabled:     getEnvAsBool("TRACING_ENABLED", false),
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
+			Insecure:    getEnvAsBool("OTEL_EXPORTER_OTLP_INSECURE", true),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "security-service"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1.0),
+		},
+		HTTP: loadHTTPConfig(mode),
 	}
 }
 
+// Validate checks settings that would make the service unsafe or unusable, so startup fails
+// instead of serving with them
+func (c *Config) Validate() error {
+	return c.HTTP.Validate(c.Server.Mode)
+}
+
 // getEnv retrieves an environment variable with a default fallback
 func getEnv(key, defaultVal string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -131,6 +395,37 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+// getEnvAsList retrieves a comma-separated environment variable as a list, skipping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvAsIntList retrieves a comma-separated environment variable as a list of integers,
+// falling back to the default when it is unset or has an invalid entry
+func getEnvAsIntList(key string, defaultVal []int) []int {
+	entries := getEnvAsList(key)
+	if len(entries) == 0 {
+		return defaultVal
+	}
+
+	values := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		value, err := strconv.Atoi(entry)
+		if err != nil {
+			log.Printf("Warning: invalid %s entry %q, using the default", key, entry)
+			return defaultVal
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
 // getEnvAsInt retrieves an environment variable as an integer
 func getEnvAsInt(key string, defaultVal int) int {
 	if value, exists := os.LookupEnv(key); exists {
@@ -141,6 +436,15 @@ func getEnvAsInt(key string, defaultVal int) int {
 	return defaultVal
 }
 
+func getEnvAsBool(key string, defaultVal bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultVal
+}
+
 // parseDuration parses a duration string with fallback
 func parseDuration(s string) time.Duration {
 	d, err := time.ParseDuration(s)
@@ -149,3 +453,13 @@ func parseDuration(s string) time.Duration {
 	}
 	return d
 }
+
+// getEnvAsFloat retrieves an environment variable as a float
+func getEnvAsFloat(key string, defaultVal float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultVal
+}
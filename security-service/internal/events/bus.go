@@ -0,0 +1,276 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"security-service/internal/config"
+	"security-service/internal/tracing"
+	"shared/signing"
+)
+
+// Timeouts for broker operations and event handlers
+const (
+	connectTimeout = 10 * time.Second
+	publishTimeout = 5 * time.Second
+	handlerTimeout = 30 * time.Second
+)
+
+// Handler processes a received event
+type Handler func(ctx context.Context, event *Event) error
+
+// Bus publishes and subscribes to domain events on the MQTT broker.
+// A nil *Bus is valid and drops published events, so publishers need no enabled checks.
+// Events are signed with the service's internal service secret, and received events are only
+// handled when they come from a known service and carry a valid signature.
+type Bus struct {
+	client   mqtt.Client
+	source   string
+	qos      byte
+	secret   string
+	verifier *signing.Verifier
+
+	mu       sync.Mutex
+	handlers map[Type][]Handler
+}
+
+// NewBus connects to the event broker. The connection is retried in the background,
+// so a broker that is down at startup only delays event delivery.
+func NewBus(cfg *config.Config, source string) *Bus {
+	bus := &Bus{
+		source:   source,
+		qos:      cfg.Events.QoS,
+		secret:   cfg.Internal.ServiceSecret,
+		verifier: signing.NewVerifier(cfg.Internal.ServiceSecrets, cfg.Internal.ServiceKey, cfg.Internal.SharedSecretDevMode, cfg.Internal.SignatureWindow),
+		handlers: make(map[Type][]Handler),
+	}
+	if bus.secret == "" {
+		log.Printf("Warning: no internal service secret set, other services will reject the events this service publishes")
+	}
+
+	// Replicas share the configured client ID, and the broker disconnects a client when another
+	// connects with the same ID, so every instance gets a suffix of its own
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(fmt.Sprintf("tcp://%s:%d", cfg.Events.Broker, cfg.Events.Port))
+	opts.SetClientID(fmt.Sprintf("%s-%s", cfg.Events.ClientID, primitive.NewObjectID().Hex()))
+	opts.SetCleanSession(true)
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(true)
+	opts.SetConnectRetryInterval(5 * time.Second)
+	opts.SetKeepAlive(60 * time.Second)
+	opts.SetPingTimeout(10 * time.Second)
+
+	if cfg.Events.Username != "" {
+		opts.SetUsername(cfg.Events.Username)
+	}
+	if cfg.Events.Password != "" {
+		opts.SetPassword(cfg.Events.Password)
+	}
+
+	// Subscriptions are lost with a clean session, so restore them on every connect
+	opts.SetOnConnectHandler(func(client mqtt.Client) {
+		log.Println("Event bus connected")
+		bus.resubscribe()
+	})
+
+	opts.SetConnectionLostHandler(func(client mqtt.Client, err error) {
+		log.Printf("Event bus connection lost: %v", err)
+	})
+
+	bus.client = mqtt.NewClient(opts)
+	token := bus.client.Connect()
+	if !token.WaitTimeout(connectTimeout) {
+		log.Printf("Warning: event bus not connected yet, retrying in background")
+	} else if token.Error() != nil {
+		log.Printf("Warning: failed to connect to event bus: %v", token.Error())
+	}
+
+	return bus
+}
+
+// Publish sends an event in the background. Delivery is best-effort and failures are logged.
+// The event carries the trace of ctx, which its handlers in other services continue.
+func (b *Bus) Publish(ctx context.Context, eventType Type, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", eventType, err)
+		return
+	}
+
+	ctx, span := tracing.StartChild(ctx, "publish "+string(eventType),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.system", "mqtt"), attribute.String("messaging.destination.name", Topic(eventType))),
+	)
+
+	event := &Event{
+		ID:          primitive.NewObjectID().Hex(),
+		Type:        eventType,
+		Source:      b.source,
+		OccurredAt:  time.Now(),
+		TraceParent: tracing.TraceParent(ctx),
+		Data:        payload,
+	}
+	message, err := b.sign(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", eventType, err)
+		span.End()
+		return
+	}
+
+	go func() {
+		defer span.End()
+		token := b.client.Publish(Topic(eventType), b.qos, false, message)
+		if !token.WaitTimeout(publishTimeout) {
+			log.Printf("Timed out publishing %s event", eventType)
+			tracing.RecordError(span, fmt.Errorf("timed out publishing %s event", eventType))
+		} else if token.Error() != nil {
+			log.Printf("Failed to publish %s event: %v", eventType, token.Error())
+			tracing.RecordError(span, token.Error())
+		}
+	}()
+}
+
+// Subscribe registers a handler for an event type
+func (b *Bus) Subscribe(eventType Type, handler Handler) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+	first := len(b.handlers[eventType]) == 1
+	b.mu.Unlock()
+
+	if !first || !b.client.IsConnected() {
+		return nil
+	}
+	return b.subscribe(eventType)
+}
+
+// HealthCheck reports whether the broker connection is up
+func (b *Bus) HealthCheck(ctx context.Context) error {
+	if !b.client.IsConnectionOpen() {
+		return fmt.Errorf("event bus not connected")
+	}
+	return nil
+}
+
+// Close disconnects from the broker
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+	b.client.Disconnect(250)
+}
+
+// resubscribe subscribes to every event type that has handlers
+func (b *Bus) resubscribe() {
+	b.mu.Lock()
+	eventTypes := make([]Type, 0, len(b.handlers))
+	for eventType := range b.handlers {
+		eventTypes = append(eventTypes, eventType)
+	}
+	b.mu.Unlock()
+
+	for _, eventType := range eventTypes {
+		if err := b.subscribe(eventType); err != nil {
+			log.Printf("Failed to subscribe to %s events: %v", eventType, err)
+		}
+	}
+}
+
+// subscribe subscribes to the topic of an event type and dispatches messages to its handlers
+func (b *Bus) subscribe(eventType Type) error {
+	token := b.client.Subscribe(Topic(eventType), b.qos, func(client mqtt.Client, msg mqtt.Message) {
+		event, err := b.verify(msg.Topic(), msg.Payload())
+		if err != nil {
+			log.Printf("Rejecting event on %s: %v", msg.Topic(), err)
+			return
+		}
+		go b.dispatch(event)
+	})
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timed out subscribing to %s", Topic(eventType))
+	}
+	return token.Error()
+}
+
+// sign encodes an event as a signed message. Without a secret the signature is left empty.
+func (b *Bus) sign(event *Event) ([]byte, error) {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := SignedEvent{Event: encoded}
+	if b.secret != "" {
+		timestamp := strconv.FormatInt(event.OccurredAt.Unix(), 10)
+		signed.Signature = signing.SignMessage(Topic(event.Type), event.Source, timestamp, event.ID, encoded, b.secret)
+	}
+	return json.Marshal(&signed)
+}
+
+// verify decodes a signed message received on a topic, checking that it was published on the
+// topic of its event type by a known service with a valid signature that has not been seen before
+func (b *Bus) verify(topic string, payload []byte) (*Event, error) {
+	var signed SignedEvent
+	if err := json.Unmarshal(payload, &signed); err != nil {
+		return nil, fmt.Errorf("malformed event: %w", err)
+	}
+	var event Event
+	if err := json.Unmarshal(signed.Event, &event); err != nil {
+		return nil, fmt.Errorf("malformed event: %w", err)
+	}
+
+	if Topic(event.Type) != topic {
+		return nil, fmt.Errorf("%s event published on the wrong topic", event.Type)
+	}
+	if !Sources[event.Source] {
+		return nil, fmt.Errorf("event %s from unknown source %q", event.ID, event.Source)
+	}
+	timestamp := strconv.FormatInt(event.OccurredAt.Unix(), 10)
+	if err := b.verifier.VerifyMessage(topic, event.Source, timestamp, event.ID, signed.Signature, signed.Event); err != nil {
+		return nil, fmt.Errorf("event %s from %s: %w", event.ID, event.Source, err)
+	}
+	return &event, nil
+}
+
+// dispatch runs every handler registered for an event, continuing the trace it was published in
+func (b *Bus) dispatch(event *Event) {
+	b.mu.Lock()
+	handlers := append([]Handler(nil), b.handlers[event.Type]...)
+	b.mu.Unlock()
+
+	traceCtx := tracing.WithTraceParent(context.Background(), event.TraceParent)
+	for _, handler := range handlers {
+		ctx, span := tracing.StartChild(traceCtx, "process "+string(event.Type),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "mqtt"),
+				attribute.String("messaging.message.id", event.ID),
+				attribute.String("messaging.event.source", event.Source),
+			),
+		)
+		ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+		if err := handler(ctx, event); err != nil {
+			log.Printf("Failed to handle %s event %s from %s: %v", event.Type, event.ID, event.Source, err)
+			tracing.RecordError(span, err)
+		}
+		cancel()
+		span.End()
+	}
+}
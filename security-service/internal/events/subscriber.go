@@ -0,0 +1,484 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"security-service/internal/models"
+)
+
+// AuditRecorder stores audit log entries
+type AuditRecorder interface {
+	Create(ctx context.Context, log *models.AuditLog) (*models.AuditLog, error)
+}
+
+// UserFinder looks up users by ID, role or group
+type UserFinder interface {
+	FindByID(ctx context.Context, id string) (*models.User, error)
+	FindByRoles(ctx context.Context, roles []string) ([]*models.User, error)
+	FindByGroups(ctx context.Context, groups []string) ([]*models.User, error)
+}
+
+// RoleFinder lists the defined roles
+type RoleFinder interface {
+	FindAll(ctx context.Context) ([]*models.Role, error)
+}
+
+// GroupFinder lists the defined groups keyed by name
+type GroupFinder interface {
+	FindAllByName(ctx context.Context) (map[string]*models.Group, error)
+}
+
+// Notifier sends event notifications to users on the channels chosen by their routing rules
+type Notifier interface {
+	NotifyEvent(ctx context.Context, event *models.NotificationEvent) ([]*models.NotificationResponse, error)
+}
+
+// UsageRecorder meters the usage of organizations for billing
+type UsageRecorder interface {
+	RecordUsage(ctx context.Context, buildingID, metric string, n int64, at time.Time) error
+	RecordUsageReport(ctx context.Context, report *UsageReportedData) error
+}
+
+// Subscriber reacts to domain events published by other services
+type Subscriber struct {
+	bus      *Bus
+	audit    AuditRecorder
+	users    UserFinder
+	roles    RoleFinder
+	groups   GroupFinder
+	notifier Notifier
+	usage    UsageRecorder
+}
+
+// NewSubscriber creates the Security service event subscriber
+func NewSubscriber(bus *Bus, audit AuditRecorder, users UserFinder, roles RoleFinder, groups GroupFinder, notifier Notifier, usage UsageRecorder) *Subscriber {
+	return &Subscriber{
+		bus:      bus,
+		audit:    audit,
+		users:    users,
+		roles:    roles,
+		groups:   groups,
+		notifier: notifier,
+		usage:    usage,
+	}
+}
+
+// Start registers handlers for the events this service consumes
+func (s *Subscriber) Start() error {
+	if err := s.bus.Subscribe(CommandApplied, s.onCommandApplied); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(ScenarioExecuted, s.onScenarioExecuted); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(BudgetThresholdCrossed, s.onBudgetThresholdCrossed); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(CommandApprovalRequested, s.onCommandApprovalRequested); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(ForecastCompleted, s.onForecastCompleted); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(UsageReported, s.onUsageReported); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(KPITargetMissed, s.onKPITargetMissed); err != nil {
+		return err
+	}
+	if err := s.bus.Subscribe(SavingsGoalMilestoneReached, s.onSavingsGoalMilestoneReached); err != nil {
+		return err
+	}
+	return s.bus.Subscribe(AnomalyDetected, s.onAnomalyDetected)
+}
+
+// onCommandApplied audits device commands confirmed by devices
+func (s *Subscriber) onCommandApplied(ctx context.Context, event *Event) error {
+	var data CommandAppliedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	return s.record(ctx, event, data.IssuedBy, "COMMAND_APPLIED", "device", data.DeviceID, map[string]interface{}{
+		"commandId":  data.CommandID,
+		"command":    data.Command,
+		"buildingId": data.BuildingID,
+	})
+}
+
+// onScenarioExecuted audits optimization scenarios executed against devices and meters the
+// execution
+func (s *Subscriber) onScenarioExecuted(ctx context.Context, event *Event) error {
+	var data ScenarioExecutedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	if err := s.usage.RecordUsage(ctx, data.BuildingID, models.MetricScenarioExecutions, 1, data.CompletedAt); err != nil {
+		log.Printf("Failed to meter execution of scenario %s: %v", data.ScenarioID, err)
+	}
+
+	return s.record(ctx, event, "", "SCENARIO_EXECUTED", "optimization_scenario", data.ScenarioID, map[string]interface{}{
+		"sourceScenarioId": data.SourceScenarioID,
+		"buildingId":       data.BuildingID,
+		"actionsApplied":   data.ActionsApplied,
+		"actionsFailed":    data.ActionsFailed,
+	})
+}
+
+// onForecastCompleted meters completed forecasts
+func (s *Subscriber) onForecastCompleted(ctx context.Context, event *Event) error {
+	var data ForecastCompletedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	return s.usage.RecordUsage(ctx, data.BuildingID, models.MetricForecastRuns, 1, data.CompletedAt)
+}
+
+// onUsageReported records the usage other services metered
+func (s *Subscriber) onUsageReported(ctx context.Context, event *Event) error {
+	var data UsageReportedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	return s.usage.RecordUsageReport(ctx, &data)
+}
+
+// onAnomalyDetected audits anomalies so they can be correlated with user activity and notifies
+// every active user allowed to read anomalies on the channels their routing rules choose. Without
+// a matching rule, high and critical anomalies are posted to the inbox and others are not sent.
+func (s *Subscriber) onAnomalyDetected(ctx context.Context, event *Event) error {
+	var data AnomalyDetectedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	recipients, err := s.findPermitted(ctx, "anomalies", "read")
+	if err != nil {
+		log.Printf("Failed to find recipients for anomaly %s: %v", data.AnomalyID, err)
+	}
+
+	var defaults []models.NotificationType
+	if data.Severity == "HIGH" || data.Severity == "CRITICAL" {
+		defaults = []models.NotificationType{models.NotificationTypeInApp}
+	}
+	subject := fmt.Sprintf("%s anomaly on %s", data.Severity, data.DeviceID)
+	content := fmt.Sprintf("A %s %s anomaly was detected on device %s at %s.",
+		data.Severity, data.Type, data.DeviceID, data.DetectedAt.UTC().Format(time.RFC3339))
+
+	notified := 0
+	for _, user := range recipients {
+		if s.notify(ctx, &models.NotificationEvent{
+			UserID:   user.ID.Hex(),
+			Category: models.NotificationCategoryAnomaly,
+			Severity: models.NotificationSeverity(data.Severity),
+			Subject:  subject,
+			Content:  content,
+			Metadata: map[string]string{
+				"anomalyId": data.AnomalyID,
+				"deviceId":  data.DeviceID,
+				"eventId":   event.ID,
+			},
+			DefaultChannels: defaults,
+		}) {
+			notified++
+		}
+	}
+
+	return s.record(ctx, event, "", "ANOMALY_DETECTED", "device", data.DeviceID, map[string]interface{}{
+		"anomalyId":     data.AnomalyID,
+		"type":          data.Type,
+		"severity":      data.Severity,
+		"buildingId":    data.BuildingID,
+		"usersNotified": notified,
+	})
+}
+
+// onBudgetThresholdCrossed notifies the budget's recipients and audits the crossing. Reaching
+// the full budget is a high severity event and earlier thresholds are medium; without a matching
+// routing rule the recipients are emailed and posted to their inbox. Recipients that cannot be
+// notified are logged and skipped so one does not block the others.
+func (s *Subscriber) onBudgetThresholdCrossed(ctx context.Context, event *Event) error {
+	var data BudgetThresholdCrossedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Energy budget %s reached %d%%", data.Name, data.Threshold)
+	content := fmt.Sprintf(
+		"%s has used %.1f of its %.1f kWh budget for %s and is projected to reach %.1f kWh by month-end.",
+		data.Name, data.ConsumedKWh, data.LimitKWh, data.Month, data.ProjectedKWh,
+	)
+	severity := models.NotificationSeverityMedium
+	if data.Threshold >= 100 {
+		severity = models.NotificationSeverityHigh
+	}
+
+	for _, userID := range data.NotifyUserIDs {
+		s.notify(ctx, &models.NotificationEvent{
+			UserID:   userID,
+			Category: models.NotificationCategoryBudget,
+			Severity: severity,
+			Subject:  subject,
+			Content:  content,
+			Metadata: map[string]string{
+				"budgetId":  data.BudgetID,
+				"threshold": strconv.Itoa(data.Threshold),
+				"eventId":   event.ID,
+			},
+			DefaultChannels: []models.NotificationType{models.NotificationTypeEmail, models.NotificationTypeInApp},
+		})
+	}
+
+	return s.record(ctx, event, "", "BUDGET_THRESHOLD_CROSSED", "energy_budget", data.BudgetID, map[string]interface{}{
+		"buildingId":  data.BuildingID,
+		"deviceId":    data.DeviceID,
+		"month":       data.Month,
+		"threshold":   data.Threshold,
+		"consumedKwh": data.ConsumedKWh,
+		"limitKwh":    data.LimitKWh,
+	})
+}
+
+// onKPITargetMissed notifies the custom KPI's recipients that a building missed its target and
+// audits the miss. The miss is a medium severity event; without a matching routing rule the
+// recipients are emailed and posted to their inbox.
+func (s *Subscriber) onKPITargetMissed(ctx context.Context, event *Event) error {
+	var data KPITargetMissedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	side := "at most"
+	if data.Comparison == "AT_LEAST" {
+		side = "at least"
+	}
+	unit := ""
+	if data.Unit != "" {
+		unit = " " + data.Unit
+	}
+	subject := fmt.Sprintf("KPI %s missed its target in %s", data.Name, data.BuildingID)
+	content := fmt.Sprintf(
+		"%s was %.2f%s for the %s period of building %s, but its target is %s %.2f%s.",
+		data.Name, data.Value, unit, strings.ToLower(data.Period), data.BuildingID, side, data.Target, unit,
+	)
+
+	for _, userID := range data.NotifyUserIDs {
+		s.notify(ctx, &models.NotificationEvent{
+			UserID:   userID,
+			Category: models.NotificationCategoryKPI,
+			Severity: models.NotificationSeverityMedium,
+			Subject:  subject,
+			Content:  content,
+			Metadata: map[string]string{
+				"definitionId": data.DefinitionID,
+				"key":          data.Key,
+				"buildingId":   data.BuildingID,
+				"eventId":      event.ID,
+			},
+			DefaultChannels: []models.NotificationType{models.NotificationTypeEmail, models.NotificationTypeInApp},
+		})
+	}
+
+	return s.record(ctx, event, "", "KPI_TARGET_MISSED", "kpi_definition", data.DefinitionID, map[string]interface{}{
+		"key":        data.Key,
+		"buildingId": data.BuildingID,
+		"period":     data.Period,
+		"value":      data.Value,
+		"unit":       data.Unit,
+		"comparison": data.Comparison,
+		"target":     data.Target,
+	})
+}
+
+// onSavingsGoalMilestoneReached notifies the savings goal's recipients that the building's savings
+// reached a milestone of the year's target and audits it. Milestones are low severity events;
+// without a matching routing rule the recipients are emailed and posted to their inbox.
+func (s *Subscriber) onSavingsGoalMilestoneReached(ctx context.Context, event *Event) error {
+	var data SavingsGoalMilestoneReachedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Savings goal %s reached %d%% of its target", data.Name, data.Milestone)
+	if data.Milestone >= 100 {
+		subject = fmt.Sprintf("Savings goal %s achieved", data.Name)
+	}
+	content := fmt.Sprintf(
+		"Building %s has saved %.1f kWh (%.1f%%) in %d against a target of %.1f%%, and is projected to save %.1f%% by year-end.",
+		data.BuildingID, data.SavedKWh, data.SavedPercent, data.Year, data.TargetPercent, data.ProjectedSavingsPercent,
+	)
+
+	for _, userID := range data.NotifyUserIDs {
+		s.notify(ctx, &models.NotificationEvent{
+			UserID:   userID,
+			Category: models.NotificationCategorySavings,
+			Severity: models.NotificationSeverityLow,
+			Subject:  subject,
+			Content:  content,
+			Metadata: map[string]string{
+				"goalId":     data.GoalID,
+				"buildingId": data.BuildingID,
+				"milestone":  strconv.Itoa(data.Milestone),
+				"eventId":    event.ID,
+			},
+			DefaultChannels: []models.NotificationType{models.NotificationTypeEmail, models.NotificationTypeInApp},
+		})
+	}
+
+	return s.record(ctx, event, "", "SAVINGS_GOAL_MILESTONE_REACHED", "savings_goal", data.GoalID, map[string]interface{}{
+		"buildingId":              data.BuildingID,
+		"year":                    data.Year,
+		"milestone":               data.Milestone,
+		"targetPercent":           data.TargetPercent,
+		"savedKwh":                data.SavedKWh,
+		"savedPercent":            data.SavedPercent,
+		"projectedSavingsPercent": data.ProjectedSavingsPercent,
+	})
+}
+
+// onCommandApprovalRequested notifies every active user allowed to approve commands, other than
+// the requester, about a high-impact command awaiting approval and audits the request. The
+// request is a high severity event; without a matching routing rule approvers are posted to
+// their inbox and emailed.
+func (s *Subscriber) onCommandApprovalRequested(ctx context.Context, event *Event) error {
+	var data CommandApprovalRequestedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	approvers, err := s.findPermitted(ctx, "commands", "approve")
+	if err != nil {
+		log.Printf("Failed to find approvers for command %s: %v", data.CommandID, err)
+	}
+
+	subject := fmt.Sprintf("Approval required: %s on %s", data.Command, data.DeviceID)
+	content := fmt.Sprintf(
+		"A %s command for %s device %s is awaiting approval until %s. Approve it with POST /iot/commands/%s/approve or reject it with POST /iot/commands/%s/reject.",
+		data.Command, data.DeviceType, data.DeviceID, data.ExpiresAt.UTC().Format(time.RFC3339), data.CommandID, data.CommandID,
+	)
+
+	notified := 0
+	for _, user := range approvers {
+		userID := user.ID.Hex()
+		if userID == data.RequestedBy {
+			continue
+		}
+
+		if s.notify(ctx, &models.NotificationEvent{
+			UserID:   userID,
+			Category: models.NotificationCategoryCommandApproval,
+			Severity: models.NotificationSeverityHigh,
+			Subject:  subject,
+			Content:  content,
+			Metadata: map[string]string{
+				"commandId": data.CommandID,
+				"deviceId":  data.DeviceID,
+				"eventId":   event.ID,
+			},
+			DefaultChannels: []models.NotificationType{models.NotificationTypeInApp, models.NotificationTypeEmail},
+		}) {
+			notified++
+		}
+	}
+
+	return s.record(ctx, event, data.RequestedBy, "COMMAND_APPROVAL_REQUESTED", "command", data.CommandID, map[string]interface{}{
+		"deviceId":          data.DeviceID,
+		"buildingId":        data.BuildingID,
+		"command":           data.Command,
+		"expiresAt":         data.ExpiresAt,
+		"approversNotified": notified,
+	})
+}
+
+// findPermitted returns the active users granted a permission by a direct role or by the roles
+// of a group they belong to, including roles inherited from parent groups
+func (s *Subscriber) findPermitted(ctx context.Context, resource, action string) ([]*models.User, error) {
+	roles, err := s.roles.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	granting := make(map[string]bool)
+	var roleNames []string
+	for _, role := range roles {
+		if role.HasPermission(resource, action) {
+			granting[role.Name] = true
+			roleNames = append(roleNames, role.Name)
+		}
+	}
+	if len(roleNames) == 0 {
+		return nil, nil
+	}
+
+	users, err := s.users.FindByRoles(ctx, roleNames)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.groups.FindAllByName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var groupNames []string
+	for name := range groups {
+		_, inherited := models.ResolveGroups([]string{name}, groups)
+		for _, role := range inherited {
+			if granting[role] {
+				groupNames = append(groupNames, name)
+				break
+			}
+		}
+	}
+	if len(groupNames) > 0 {
+		members, err := s.users.FindByGroups(ctx, groupNames)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, members...)
+	}
+
+	seen := make(map[string]bool, len(users))
+	permitted := make([]*models.User, 0, len(users))
+	for _, user := range users {
+		id := user.ID.Hex()
+		if seen[id] || !user.IsActive {
+			continue
+		}
+		seen[id] = true
+		permitted = append(permitted, user)
+	}
+	return permitted, nil
+}
+
+// notify sends an event notification to a user and reports whether it reached at least one
+// channel. Failed channels are logged.
+func (s *Subscriber) notify(ctx context.Context, notification *models.NotificationEvent) bool {
+	sent, err := s.notifier.NotifyEvent(ctx, notification)
+	if err != nil {
+		log.Printf("Failed to notify user %s of %s event: %v", notification.UserID, notification.Category, err)
+	}
+	return len(sent) > 0
+}
+
+// record stores an audit entry attributed to the service that published the event
+func (s *Subscriber) record(ctx context.Context, event *Event, userID, action, resource, resourceID string, details map[string]interface{}) error {
+	details["eventId"] = event.ID
+
+	_, err := s.audit.Create(ctx, &models.AuditLog{
+		UserID:     userID,
+		Service:    event.Source,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Details:    details,
+		Status:     "SUCCESS",
+	})
+	return err
+}
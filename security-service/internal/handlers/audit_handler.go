@@ -1,16 +1,30 @@
 package handlers
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"security-service/internal/middleware"
 	"security-service/internal/models"
 	"security-service/internal/service"
 )
 
+const (
+	// exportFlushEvery is the number of rows written between flushes during an export
+	exportFlushEvery = 500
+	// exportWriteTimeout bounds how long a single flush may take before the export is aborted
+	exportWriteTimeout = 30 * time.Second
+)
+
 // AuditHandler handles audit logging requests
 type AuditHandler struct {
 	auditService *service.AuditService
@@ -26,11 +40,7 @@ func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
 func (h *AuditHandler) CreateLog(c *gin.Context) {
 	var req models.AuditLogCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -57,7 +67,152 @@ func (h *AuditHandler) CreateLog(c *gin.Context) {
 
 // GetLogs retrieves audit logs with filters
 // GET /audit/logs
+// Passing a "cursor" query parameter (empty for the first page) switches to cursor-based pagination.
 func (h *AuditHandler) GetLogs(c *gin.Context) {
+	params, ok := h.parseQueryParams(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if cursor, useCursor := c.GetQuery("cursor"); useCursor {
+		result, err := h.auditService.GetLogsByCursor(c.Request.Context(), params, cursor, limit)
+		if err != nil {
+			if err.Error() == "invalid cursor" {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+					models.ErrCodeValidationFailed,
+					"Invalid cursor",
+					"",
+				))
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to retrieve audit logs",
+				err.Error(),
+			))
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewSuccessResponse(result, ""))
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	params.Page = page
+	params.Limit = limit
+
+	result, err := h.auditService.GetLogs(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve audit logs",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result, ""))
+}
+
+// ExportLogs streams audit logs matching the filters as NDJSON or CSV
+// GET /audit/logs/export?format=ndjson|csv
+func (h *AuditHandler) ExportLogs(c *gin.Context) {
+	params, ok := h.parseQueryParams(c)
+	if !ok {
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", models.AuditExportFormatNDJSON))
+	var contentType string
+	switch format {
+	case models.AuditExportFormatNDJSON:
+		contentType = "application/x-ndjson"
+	case models.AuditExportFormatCSV:
+		contentType = "text/csv; charset=utf-8"
+	default:
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			"Invalid 'format' parameter",
+			"Supported formats: ndjson, csv",
+		))
+		return
+	}
+
+	filename := fmt.Sprintf("audit-logs-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	// Exports can outlive the server write timeout; keep extending it while rows flow
+	controller := http.NewResponseController(c.Writer)
+	extendDeadline := func() {
+		_ = controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	}
+	extendDeadline()
+
+	var writeRow func(*models.AuditLogResponse) error
+	var flush func() error
+
+	if format == models.AuditExportFormatCSV {
+		csvWriter := csv.NewWriter(c.Writer)
+		if err := csvWriter.Write(auditCSVHeader); err != nil {
+			return
+		}
+		writeRow = func(log *models.AuditLogResponse) error {
+			return csvWriter.Write(auditCSVRecord(log))
+		}
+		flush = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	} else {
+		encoder := json.NewEncoder(c.Writer)
+		writeRow = func(log *models.AuditLogResponse) error {
+			return encoder.Encode(log)
+		}
+		flush = func() error { return nil }
+	}
+
+	rows := 0
+	err := h.auditService.ExportLogs(c.Request.Context(), params, func(log *models.AuditLogResponse) error {
+		if err := writeRow(log); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			extendDeadline()
+		}
+		return nil
+	})
+	if flushErr := flush(); err == nil {
+		err = flushErr
+	}
+	c.Writer.Flush()
+
+	// Headers are already sent, so failures can only be logged
+	status, errMsg := "SUCCESS", ""
+	if err != nil {
+		status, errMsg = "FAILURE", err.Error()
+		log.Printf("Audit log export aborted after %d rows: %v", rows, err)
+	}
+
+	// Exports are themselves audited for compliance
+	details := map[string]interface{}{"format": format, "rows": rows}
+	if logErr := h.auditService.Log(context.Background(), middleware.GetUserID(c), middleware.GetUsername(c), "security-service", "EXPORT_AUDIT_LOGS", "audit_log", "", status, errMsg, middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method, details); logErr != nil {
+		log.Printf("Failed to audit log export: %v", logErr)
+	}
+}
+
+// parseQueryParams parses the audit log filters shared by listing and export.
+// It writes an error response and returns false if a parameter is invalid.
+func (h *AuditHandler) parseQueryParams(c *gin.Context) (models.AuditLogQueryParams, bool) {
 	var params models.AuditLogQueryParams
 
 	// Parse query parameters
@@ -69,7 +224,7 @@ func (h *AuditHandler) GetLogs(c *gin.Context) {
 				"Invalid 'from' date format",
 				"Expected RFC3339 format (e.g., 2024-01-15T10:00:00Z)",
 			))
-			return
+			return params, false
 		}
 		params.From = t
 	}
@@ -82,7 +237,7 @@ func (h *AuditHandler) GetLogs(c *gin.Context) {
 				"Invalid 'to' date format",
 				"Expected RFC3339 format (e.g., 2024-01-15T10:00:00Z)",
 			))
-			return
+			return params, false
 		}
 		params.To = t
 	}
@@ -93,22 +248,119 @@ func (h *AuditHandler) GetLogs(c *gin.Context) {
 	params.Resource = c.Query("resource")
 	params.Status = c.Query("status")
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	params.Page = page
-	params.Limit = limit
+	return params, true
+}
 
-	result, err := h.auditService.GetLogs(c.Request.Context(), params)
+// auditCSVHeader is the column layout of CSV audit log exports
+var auditCSVHeader = []string{
+	"id", "timestamp", "userId", "username", "service", "action", "resource", "resourceId",
+	"status", "errorMsg", "ipAddress", "userAgent", "method", "requestPath", "details",
+}
+
+// auditCSVRecord converts an audit log into a CSV row matching auditCSVHeader
+func auditCSVRecord(log *models.AuditLogResponse) []string {
+	details := ""
+	if len(log.Details) > 0 {
+		if encoded, err := json.Marshal(log.Details); err == nil {
+			details = string(encoded)
+		}
+	}
+
+	return []string{
+		log.ID,
+		log.Timestamp.UTC().Format(time.RFC3339Nano),
+		log.UserID,
+		log.Username,
+		log.Service,
+		log.Action,
+		log.Resource,
+		log.ResourceID,
+		log.Status,
+		log.ErrorMsg,
+		log.IPAddress,
+		log.UserAgent,
+		log.Method,
+		log.RequestPath,
+		details,
+	}
+}
+
+// GetAccessReport summarizes who accessed a building's data and controls, as CSV or JSON
+// GET /audit/access-report?buildingId=&period=30d|4w|2024-06&format=csv|json
+func (h *AuditHandler) GetAccessReport(c *gin.Context) {
+	var params models.AccessReportQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+	format := params.Format
+	if format == "" {
+		format = models.AuditExportFormatCSV
+	}
+
+	report, err := h.auditService.GetAccessReport(c.Request.Context(), params.BuildingID, params.Period, time.Now().UTC())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			models.ErrCodeInternalError,
-			"Failed to retrieve audit logs",
-			err.Error(),
-		))
+		switch {
+		case strings.Contains(err.Error(), "does not belong to"):
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(models.ErrCodeForbidden, err.Error(), ""))
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to build access report",
+				err.Error(),
+			))
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(result, ""))
+	// Access reviews are themselves audited for compliance
+	details := map[string]interface{}{"buildingId": params.BuildingID, "period": params.Period, "format": format}
+	if logErr := h.auditService.Log(c.Request.Context(), middleware.GetUserID(c), middleware.GetUsername(c), "security-service", "EXPORT_ACCESS_REPORT", "audit_log", "", "SUCCESS", "", middleware.GetClientIP(c), middleware.GetUserAgent(c), c.Request.URL.Path, c.Request.Method, details); logErr != nil {
+		log.Printf("Failed to audit access report: %v", logErr)
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(report, ""))
+		return
+	}
+
+	filename := fmt.Sprintf("access-report-%s-%s.csv", params.BuildingID, report.GeneratedAt.Format("20060102T150405Z"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	csvWriter := csv.NewWriter(c.Writer)
+	_ = csvWriter.Write(accessReportCSVHeader)
+	for _, entry := range report.Entries {
+		_ = csvWriter.Write(accessReportCSVRecord(entry))
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		log.Printf("Failed to write access report: %v", err)
+	}
+}
+
+// accessReportCSVHeader is the column layout of CSV access reports
+var accessReportCSVHeader = []string{
+	"userId", "username", "category", "actions", "events", "failures", "firstAccess", "lastAccess", "ipAddresses",
+}
+
+// accessReportCSVRecord converts an access report entry into a CSV row matching accessReportCSVHeader
+func accessReportCSVRecord(entry *models.AccessReportEntry) []string {
+	return []string{
+		entry.UserID,
+		entry.Username,
+		entry.Category,
+		strings.Join(entry.Actions, ";"),
+		strconv.Itoa(entry.Events),
+		strconv.Itoa(entry.Failures),
+		entry.FirstAccess.UTC().Format(time.RFC3339),
+		entry.LastAccess.UTC().Format(time.RFC3339),
+		strings.Join(entry.IPAddresses, ";"),
+	}
 }
 
 // GetLog retrieves a specific audit log by ID
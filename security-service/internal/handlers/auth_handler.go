@@ -27,11 +27,7 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -56,11 +52,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req models.RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -84,11 +76,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 func (h *AuthHandler) Logout(c *gin.Context) {
 	var req models.RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -148,16 +136,34 @@ func (h *AuthHandler) ValidateToken(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetSigningKey returns the token verification keys for local validation by internal services
+// GET /auth/signing-key
+func (h *AuthHandler) GetSigningKey(c *gin.Context) {
+	response, err := h.authService.GetSigningKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to export signing keys",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// GetJWKS publishes the public keys of asymmetric signing keys in JSON Web Key Set format
+// GET /auth/jwks
+func (h *AuthHandler) GetJWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, h.authService.GetJWKS())
+}
+
 // CheckPermissions handles permission checks
 // POST /auth/check-permissions
 func (h *AuthHandler) CheckPermissions(c *gin.Context) {
 	var req models.CheckPermissionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -174,6 +180,40 @@ func (h *AuthHandler) CheckPermissions(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// DelegateToken issues a short-lived token of a user for work another service queued on their
+// behalf, after checking the user may still act in the organization
+// POST /auth/delegate-token
+func (h *AuthHandler) DelegateToken(c *gin.Context) {
+	var req models.DelegateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	callerService := c.GetString("callerService")
+	response, err := h.authService.DelegateToken(c.Request.Context(), &req, callerService, middleware.GetClientIP(c))
+	if err != nil {
+		switch err.Error() {
+		case "user not found", "invalid user ID format", "account is disabled", "user no longer belongs to the organization",
+			"organization not found", "organization is suspended":
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to delegate token",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
 // GetUserInfo returns user profile information
 // GET /auth/user-info
 func (h *AuthHandler) GetUserInfo(c *gin.Context) {
@@ -199,3 +239,170 @@ func (h *AuthHandler) GetUserInfo(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
 }
+
+// GetProfile returns the current user's profile
+// GET /auth/profile
+func (h *AuthHandler) GetProfile(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	response, err := h.authService.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				"User not found",
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to get profile",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, ""))
+}
+
+// UpdateProfile updates the current user's contact details
+// PUT /auth/profile
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	var req models.ProfileUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.authService.UpdateProfile(c.Request.Context(), userID, &req, ipAddress, userAgent)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				"User not found",
+				"",
+			))
+		case "email is already in use":
+			c.JSON(http.StatusConflict, models.NewErrorResponse(
+				models.ErrCodeConflict,
+				err.Error(),
+				"",
+			))
+		case "no updates provided":
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to update profile",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Profile updated successfully"))
+}
+
+// ChangePassword changes the current user's password and revokes their other sessions
+// POST /auth/change-password
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.authService.ChangePassword(c.Request.Context(), userID, &req, ipAddress, userAgent)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				"User not found",
+				"",
+			))
+		case "current password is incorrect":
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				err.Error(),
+				"",
+			))
+		case "new password must differ from the current password":
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to change password",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Password changed successfully"))
+}
+
+// Impersonate issues a short-lived token for an admin to act as another user
+// POST /auth/impersonate/{userId}
+func (h *AuthHandler) Impersonate(c *gin.Context) {
+	var req models.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ipAddress := middleware.GetClientIP(c)
+	userAgent := middleware.GetUserAgent(c)
+
+	response, err := h.authService.Impersonate(c.Request.Context(), userID, c.Param("userId"), req.Reason, ipAddress, userAgent)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				"User not found",
+				"",
+			))
+		case "invalid user ID format", "cannot impersonate yourself":
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		case "account is disabled", "cannot impersonate an admin":
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to impersonate user",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Impersonation started"))
+}
@@ -1,27 +1,29 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
-	"security-service/internal/integrations"
+	"security-service/internal/middleware"
 	"security-service/internal/models"
+	"security-service/internal/service"
 )
 
 // EnergyHandler handles external energy provider integration requests
 type EnergyHandler struct {
-	energyClient *integrations.EnergyProviderClient
+	energyService *service.EnergyService
 }
 
 // NewEnergyHandler creates a new energy handler
-func NewEnergyHandler(energyClient *integrations.EnergyProviderClient) *EnergyHandler {
-	return &EnergyHandler{energyClient: energyClient}
+func NewEnergyHandler(energyService *service.EnergyService) *EnergyHandler {
+	return &EnergyHandler{energyService: energyService}
 }
 
-// GetConsumption retrieves energy consumption data
-// GET /external-energy/consumption
+// GetConsumption retrieves energy consumption data from the provider serving the building
+// GET /external-energy/consumption?buildingId=&from=&to=&provider=
 func (h *EnergyHandler) GetConsumption(c *gin.Context) {
 	buildingID := c.Query("buildingId")
 	if buildingID == "" {
@@ -33,6 +35,71 @@ func (h *EnergyHandler) GetConsumption(c *gin.Context) {
 		return
 	}
 
+	from, to, ok := parseEnergyPeriod(c)
+	if !ok {
+		return
+	}
+
+	consumption, err := h.energyService.GetConsumption(c.Request.Context(), buildingID, c.Query("provider"), from, to)
+	if err != nil {
+		h.respondError(c, err, "Failed to retrieve energy consumption data")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(consumption, ""))
+}
+
+// GetTariffs retrieves tariff information for a region, or for the region a building is mapped to
+// GET /external-energy/tariffs?region=&buildingId=&provider=
+func (h *EnergyHandler) GetTariffs(c *gin.Context) {
+	region := c.Query("region")
+	buildingID := c.Query("buildingId")
+	if region == "" && buildingID == "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			"region or buildingId query parameter is required",
+			"",
+		))
+		return
+	}
+
+	if region == "" {
+		var err error
+		region, err = h.energyService.RegionForBuilding(c.Request.Context(), buildingID)
+		if err != nil {
+			h.respondError(c, err, "Failed to retrieve tariff data")
+			return
+		}
+	}
+
+	tariff, err := h.energyService.GetTariffs(c.Request.Context(), region, c.Query("provider"))
+	if err != nil {
+		h.respondError(c, err, "Failed to retrieve tariff data")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(tariff, ""))
+}
+
+// GetBuildingEnergy retrieves consumption and the applicable tariff from the provider serving a building
+// GET /external-energy/buildings/:buildingId?from=&to=
+func (h *EnergyHandler) GetBuildingEnergy(c *gin.Context) {
+	from, to, ok := parseEnergyPeriod(c)
+	if !ok {
+		return
+	}
+
+	energy, err := h.energyService.GetBuildingEnergy(c.Request.Context(), c.Param("buildingId"), from, to)
+	if err != nil {
+		h.respondError(c, err, "Failed to retrieve building energy data")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(energy, ""))
+}
+
+// parseEnergyPeriod parses the required from/to query parameters, writing a 400 response on failure
+func parseEnergyPeriod(c *gin.Context) (time.Time, time.Time, bool) {
 	fromStr := c.Query("from")
 	toStr := c.Query("to")
 
@@ -42,7 +109,7 @@ func (h *EnergyHandler) GetConsumption(c *gin.Context) {
 			"from and to query parameters are required",
 			"Expected RFC3339 format (e.g., 2024-01-15T00:00:00Z)",
 		))
-		return
+		return time.Time{}, time.Time{}, false
 	}
 
 	from, err := time.Parse(time.RFC3339, fromStr)
@@ -52,7 +119,7 @@ func (h *EnergyHandler) GetConsumption(c *gin.Context) {
 			"Invalid 'from' date format",
 			"Expected RFC3339 format (e.g., 2024-01-15T00:00:00Z)",
 		))
-		return
+		return time.Time{}, time.Time{}, false
 	}
 
 	to, err := time.Parse(time.RFC3339, toStr)
@@ -62,88 +129,125 @@ func (h *EnergyHandler) GetConsumption(c *gin.Context) {
 			"Invalid 'to' date format",
 			"Expected RFC3339 format (e.g., 2024-01-15T00:00:00Z)",
 		))
+		return time.Time{}, time.Time{}, false
+	}
+
+	return from, to, true
+}
+
+// RefreshToken refreshes the external API token
+// POST /external-energy/refresh-token
+func (h *EnergyHandler) RefreshToken(c *gin.Context) {
+	var req models.ExternalTokenRefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
-	consumption, err := h.energyClient.GetConsumption(c.Request.Context(), buildingID, from, to)
+	response, err := h.energyService.RefreshToken(c.Request.Context(), req.Provider)
 	if err != nil {
+		h.respondError(c, err, "Failed to refresh token")
+		return
+	}
+
+	if !response.Success {
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			models.ErrCodeExternalAPIError,
-			"Failed to retrieve energy consumption data",
-			err.Error(),
+			"Token refresh failed",
+			response.Message,
 		))
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(consumption, ""))
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Token refreshed successfully"))
 }
 
-// GetTariffs retrieves tariff information
-// GET /external-energy/tariffs
-func (h *EnergyHandler) GetTariffs(c *gin.Context) {
-	region := c.Query("region")
-	if region == "" {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"region query parameter is required",
-			"",
-		))
+// ListProviders retrieves all energy providers
+// GET /external-energy/providers
+func (h *EnergyHandler) ListProviders(c *gin.Context) {
+	providers, err := h.energyService.ListProviders(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err, "Failed to retrieve energy providers")
 		return
 	}
 
-	tariff, err := h.energyClient.GetTariffs(c.Request.Context(), region)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"providers": providers,
+	}, ""))
+}
+
+// GetProvider retrieves an energy provider by name
+// GET /external-energy/providers/:providerName
+func (h *EnergyHandler) GetProvider(c *gin.Context) {
+	provider, err := h.energyService.GetProvider(c.Request.Context(), c.Param("providerName"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			models.ErrCodeExternalAPIError,
-			"Failed to retrieve tariff data",
-			err.Error(),
-		))
+		h.respondError(c, err, "Failed to retrieve energy provider")
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(tariff, ""))
+	c.JSON(http.StatusOK, models.NewSuccessResponse(provider, ""))
 }
 
-// RefreshToken refreshes the external API token
-// POST /external-energy/refresh-token
-func (h *EnergyHandler) RefreshToken(c *gin.Context) {
-	var req models.ExternalTokenRefreshRequest
+// CreateProvider registers a new energy provider
+// POST /external-energy/providers
+func (h *EnergyHandler) CreateProvider(c *gin.Context) {
+	var req models.EnergyProviderCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
-	if req.Provider != "energy_provider" {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Unsupported provider",
-			"Currently only 'energy_provider' is supported",
-		))
+	provider, err := h.energyService.CreateProvider(c.Request.Context(), &req, middleware.GetUserID(c))
+	if err != nil {
+		h.respondError(c, err, "Failed to create energy provider")
 		return
 	}
 
-	response, err := h.energyClient.RefreshToken(c.Request.Context())
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(provider, "Energy provider created successfully"))
+}
+
+// UpdateProvider updates an energy provider
+// PUT /external-energy/providers/:providerName
+func (h *EnergyHandler) UpdateProvider(c *gin.Context) {
+	var req models.EnergyProviderUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	provider, err := h.energyService.UpdateProvider(c.Request.Context(), c.Param("providerName"), &req, middleware.GetUserID(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			models.ErrCodeExternalAPIError,
-			"Failed to refresh token",
-			err.Error(),
-		))
+		h.respondError(c, err, "Failed to update energy provider")
 		return
 	}
 
-	if !response.Success {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			models.ErrCodeExternalAPIError,
-			"Token refresh failed",
-			response.Message,
-		))
+	c.JSON(http.StatusOK, models.NewSuccessResponse(provider, "Energy provider updated successfully"))
+}
+
+// DeleteProvider removes an energy provider
+// DELETE /external-energy/providers/:providerName
+func (h *EnergyHandler) DeleteProvider(c *gin.Context) {
+	if err := h.energyService.DeleteProvider(c.Request.Context(), c.Param("providerName"), middleware.GetUserID(c)); err != nil {
+		h.respondError(c, err, "Failed to delete energy provider")
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(response, "Token refreshed successfully"))
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Energy provider deleted successfully"))
+}
+
+// respondError maps energy service errors to HTTP responses
+func (h *EnergyHandler) respondError(c *gin.Context, err error, message string) {
+	var serviceErr *service.ServiceError
+	switch {
+	case errors.As(err, &serviceErr):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	case err.Error() == "energy provider not found",
+		err.Error() == "no energy provider configured for building",
+		err.Error() == "no energy provider configured for region":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case err.Error() == "energy provider with this name already exists":
+		c.JSON(http.StatusConflict, models.NewErrorResponse(models.ErrCodeConflict, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeExternalAPIError, message, err.Error()))
+	}
 }
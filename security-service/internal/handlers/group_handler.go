@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// GroupHandler handles user group management requests
+type GroupHandler struct {
+	groupService *service.GroupService
+}
+
+// NewGroupHandler creates a new group handler
+func NewGroupHandler(groupService *service.GroupService) *GroupHandler {
+	return &GroupHandler{groupService: groupService}
+}
+
+// ListGroups retrieves all groups
+// GET /groups
+func (h *GroupHandler) ListGroups(c *gin.Context) {
+	groups, err := h.groupService.ListGroups(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve groups",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"groups": groups,
+	}, ""))
+}
+
+// GetGroup retrieves a group by name
+// GET /groups/:groupName
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	name := c.Param("groupName")
+
+	group, err := h.groupService.GetGroup(c.Request.Context(), name)
+	if err != nil {
+		h.handleError(c, err, "Failed to retrieve group")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(group, ""))
+}
+
+// CreateGroup creates a new group
+// POST /groups
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	var req models.GroupCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	creatorID := middleware.GetUserID(c)
+
+	group, err := h.groupService.CreateGroup(c.Request.Context(), &req, creatorID)
+	if err != nil {
+		h.handleError(c, err, "Failed to create group")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(group, "Group created successfully"))
+}
+
+// UpdateGroup updates an existing group
+// PUT /groups/:groupName
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	name := c.Param("groupName")
+
+	var req models.GroupUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	updaterID := middleware.GetUserID(c)
+
+	group, err := h.groupService.UpdateGroup(c.Request.Context(), name, &req, updaterID)
+	if err != nil {
+		h.handleError(c, err, "Failed to update group")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(group, "Group updated successfully"))
+}
+
+// DeleteGroup deletes a group
+// DELETE /groups/:groupName
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	name := c.Param("groupName")
+	deleterID := middleware.GetUserID(c)
+
+	if err := h.groupService.DeleteGroup(c.Request.Context(), name, deleterID); err != nil {
+		h.handleError(c, err, "Failed to delete group")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Group deleted successfully"))
+}
+
+// ListMembers lists the direct members of a group
+// GET /groups/:groupName/members
+func (h *GroupHandler) ListMembers(c *gin.Context) {
+	name := c.Param("groupName")
+
+	members, err := h.groupService.ListMembers(c.Request.Context(), name)
+	if err != nil {
+		h.handleError(c, err, "Failed to retrieve group members")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"members": members,
+	}, ""))
+}
+
+// AddMembers adds users to a group
+// POST /groups/:groupName/members
+func (h *GroupHandler) AddMembers(c *gin.Context) {
+	name := c.Param("groupName")
+
+	var req models.GroupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	updaterID := middleware.GetUserID(c)
+
+	if err := h.groupService.AddMembers(c.Request.Context(), name, req.UserIDs, updaterID); err != nil {
+		h.handleError(c, err, "Failed to add group members")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Group members added successfully"))
+}
+
+// RemoveMember removes a user from a group
+// DELETE /groups/:groupName/members/:userId
+func (h *GroupHandler) RemoveMember(c *gin.Context) {
+	name := c.Param("groupName")
+	userID := c.Param("userId")
+	updaterID := middleware.GetUserID(c)
+
+	if err := h.groupService.RemoveMember(c.Request.Context(), name, userID, updaterID); err != nil {
+		h.handleError(c, err, "Failed to remove group member")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Group member removed successfully"))
+}
+
+// handleError maps group service errors to HTTP responses
+func (h *GroupHandler) handleError(c *gin.Context, err error, message string) {
+	msg := err.Error()
+	switch {
+	case msg == "group not found" || strings.HasPrefix(msg, "user not found"):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeNotFound,
+			msg,
+			"",
+		))
+	case strings.Contains(msg, "already exists"):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(
+			models.ErrCodeConflict,
+			msg,
+			"",
+		))
+	case msg == "only super admins can grant the super_admin role":
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(
+			models.ErrCodeForbidden,
+			msg,
+			"",
+		))
+	case msg == "one or more roles do not exist",
+		msg == "no updates provided",
+		strings.HasPrefix(msg, "parent group"),
+		strings.HasPrefix(msg, "invalid user ID format"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			msg,
+			"",
+		))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			message,
+			msg,
+		))
+	}
+}
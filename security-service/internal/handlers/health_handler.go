@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// HealthHandler handles health check requests
+type HealthHandler struct {
+	healthService *service.HealthService
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(healthService *service.HealthService) *HealthHandler {
+	return &HealthHandler{healthService: healthService}
+}
+
+// Health reports that the service process is up
+// GET /health
+func (h *HealthHandler) Health(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  models.HealthStatusHealthy,
+		"service": "security-service",
+	})
+}
+
+// DeepHealth checks the service's dependencies and reports a component map with latencies.
+// Responds with 503 when a critical dependency is unavailable so it can back readiness probes.
+// GET /health/deep
+func (h *HealthHandler) DeepHealth(c *gin.Context) {
+	health := h.healthService.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if health.Status == models.HealthStatusUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, health)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// KioskHandler handles kiosk token management requests
+type KioskHandler struct {
+	kioskService *service.KioskService
+}
+
+// NewKioskHandler creates a new kiosk token handler
+func NewKioskHandler(kioskService *service.KioskService) *KioskHandler {
+	return &KioskHandler{kioskService: kioskService}
+}
+
+// CreateKioskToken issues a read-only dashboard token for a building
+// POST /admin/kiosk-tokens
+func (h *KioskHandler) CreateKioskToken(c *gin.Context) {
+	var req models.KioskTokenCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	response, err := h.kioskService.CreateKioskToken(c.Request.Context(), &req, middleware.GetUserID(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "does not belong to") {
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to create kiosk token",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(response, "Kiosk token created successfully"))
+}
+
+// ListKioskTokens lists kiosk tokens, optionally including revoked ones
+// GET /admin/kiosk-tokens
+func (h *KioskHandler) ListKioskTokens(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	includeRevoked := c.Query("includeRevoked") == "true"
+
+	kiosks, total, totalPages, err := h.kioskService.ListKioskTokens(c.Request.Context(), c.Query("buildingId"), includeRevoked, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve kiosk tokens",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"kioskTokens": kiosks,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"totalPages":  totalPages,
+	}, ""))
+}
+
+// RevokeKioskToken revokes a kiosk token
+// DELETE /admin/kiosk-tokens/:id
+func (h *KioskHandler) RevokeKioskToken(c *gin.Context) {
+	kiosk, err := h.kioskService.RevokeKioskToken(c.Request.Context(), c.Param("id"), middleware.GetUserID(c))
+	if err != nil {
+		switch err.Error() {
+		case "kiosk token not found":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				"Kiosk token not found",
+				"",
+			))
+		case "invalid kiosk token ID format":
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		case "kiosk token is already revoked":
+			c.JSON(http.StatusConflict, models.NewErrorResponse(
+				models.ErrCodeConflict,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to revoke kiosk token",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(kiosk, "Kiosk token revoked successfully"))
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// MeteringHandler handles usage metering requests of admins
+type MeteringHandler struct {
+	meteringService *service.MeteringService
+}
+
+// NewMeteringHandler creates a new metering handler
+func NewMeteringHandler(meteringService *service.MeteringService) *MeteringHandler {
+	return &MeteringHandler{meteringService: meteringService}
+}
+
+// GetUsage summarizes the metered usage of an organization or building in a period
+// GET /metering/usage?orgId=&buildingId=&period=
+func (h *MeteringHandler) GetUsage(c *gin.Context) {
+	var params models.UsageQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	usage, err := h.meteringService.GetUsage(c.Request.Context(), &params, time.Now().UTC())
+	if err != nil {
+		h.handleError(c, err, "Failed to retrieve usage")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(usage, ""))
+}
+
+// GetSnapshots lists the daily usage snapshots of an organization in a period
+// GET /metering/snapshots?orgId=&period=
+func (h *MeteringHandler) GetSnapshots(c *gin.Context) {
+	var params models.UsageSnapshotQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	snapshots, err := h.meteringService.GetSnapshots(c.Request.Context(), &params, time.Now().UTC())
+	if err != nil {
+		h.handleError(c, err, "Failed to retrieve usage snapshots")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(snapshots, ""))
+}
+
+// handleError maps metering service errors to HTTP responses
+func (h *MeteringHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case strings.Contains(err.Error(), "does not belong to"):
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(models.ErrCodeForbidden, err.Error(), ""))
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	case err.Error() == "organization not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, err.Error(), ""))
+	case err.Error() == "invalid organization ID format":
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(models.ErrCodeInternalError, message, err.Error()))
+	}
+}
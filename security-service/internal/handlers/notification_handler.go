@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"security-service/internal/middleware"
 	"security-service/internal/models"
 	"security-service/internal/service"
 )
@@ -26,11 +27,7 @@ func NewNotificationHandler(notificationService *service.NotificationService) *N
 func (h *NotificationHandler) SendNotification(c *gin.Context) {
 	var req models.NotificationSendRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -62,16 +59,42 @@ func (h *NotificationHandler) SendNotification(c *gin.Context) {
 	c.JSON(statusCode, models.NewSuccessResponse(notification, message))
 }
 
+// TestRoute shows which channels a hypothetical event would reach under a user's routing rules
+// POST /notifications/test-send
+func (h *NotificationHandler) TestRoute(c *gin.Context) {
+	var req models.NotificationRouteTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	decision, err := h.notificationService.TestRoute(c.Request.Context(), &req)
+	if err != nil {
+		if err.Error() == "invalid user ID format" || err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				"User not found",
+				err.Error(),
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to evaluate notification routing",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(decision, ""))
+}
+
 // UpdatePreferences updates user notification preferences
 // POST /notifications/preferences
 func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
 	var req models.NotificationPreferencesUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -129,11 +152,7 @@ func (h *NotificationHandler) UpdatePreferencesByUserID(c *gin.Context) {
 
 	var req models.NotificationPreferencesUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -215,3 +234,239 @@ func (h *NotificationHandler) GetLogs(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(result, ""))
 }
+
+// GetInbox retrieves the current user's in-app notifications with their unread count
+// GET /notifications/inbox?unreadOnly=&page=&limit=
+func (h *NotificationHandler) GetInbox(c *gin.Context) {
+	var params models.NotificationInboxQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	inbox, err := h.notificationService.GetInbox(c.Request.Context(), middleware.GetUserID(c), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve notification inbox",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(inbox, ""))
+}
+
+// MarkRead marks one of the current user's in-app notifications read
+// POST /notifications/inbox/:id/read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	notification, err := h.notificationService.MarkRead(c.Request.Context(), middleware.GetUserID(c), c.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid notification ID format":
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+		case "notification not found":
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				err.Error(),
+				"",
+			))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				models.ErrCodeInternalError,
+				"Failed to mark notification read",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(notification, "Notification marked as read"))
+}
+
+// MarkAllRead marks all of the current user's in-app notifications read
+// POST /notifications/inbox/read-all
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	result, err := h.notificationService.MarkAllRead(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to mark notifications read",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result, "Notifications marked as read"))
+}
+
+// GetProviderHealth reports the health of each notification provider and the per-type routing
+// GET /notifications/providers/health
+func (h *NotificationHandler) GetProviderHealth(c *gin.Context) {
+	status := h.notificationService.GetProviderHealth(c.Request.Context())
+	c.JSON(http.StatusOK, models.NewSuccessResponse(status, ""))
+}
+
+// GetStats reports notification delivery analytics, by default over the last 24 hours
+// GET /notifications/stats?from=&to=
+func (h *NotificationHandler) GetStats(c *gin.Context) {
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+
+	if raw := c.Query("from"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				"Invalid 'from' date format",
+				"Expected RFC3339 format",
+			))
+			return
+		}
+		from = t
+	}
+
+	if raw := c.Query("to"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				"Invalid 'to' date format",
+				"Expected RFC3339 format",
+			))
+			return
+		}
+		to = t
+	}
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			"'from' must be before 'to'",
+			"",
+		))
+		return
+	}
+
+	stats, err := h.notificationService.GetDeliveryStats(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve notification statistics",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(stats, ""))
+}
+
+// ListPushDevices lists the push devices registered by the current user
+// GET /notifications/push-devices
+func (h *NotificationHandler) ListPushDevices(c *gin.Context) {
+	devices, err := h.notificationService.ListPushDevices(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve push devices",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(devices, ""))
+}
+
+// RegisterPushDevice registers a device to receive the current user's push notifications
+// POST /notifications/push-devices
+func (h *NotificationHandler) RegisterPushDevice(c *gin.Context) {
+	var req models.PushDeviceRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	device, err := h.notificationService.RegisterPushDevice(c.Request.Context(), middleware.GetUserID(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to register push device",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(device, "Push device registered successfully"))
+}
+
+// UpdatePushDevicePreferences changes which notifications one of the current user's devices receives
+// PUT /notifications/push-devices/:token
+func (h *NotificationHandler) UpdatePushDevicePreferences(c *gin.Context) {
+	var req models.PushDevicePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	device, err := h.notificationService.UpdatePushDevicePreferences(c.Request.Context(), middleware.GetUserID(c), c.Param("token"), &req)
+	if err != nil {
+		h.handlePushDeviceError(c, err, "Failed to update push device")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(device, "Push device updated successfully"))
+}
+
+// UnregisterPushDevice stops push notifications to one of the current user's devices
+// DELETE /notifications/push-devices/:token
+func (h *NotificationHandler) UnregisterPushDevice(c *gin.Context) {
+	if err := h.notificationService.UnregisterPushDevice(c.Request.Context(), middleware.GetUserID(c), c.Param("token")); err != nil {
+		h.handlePushDeviceError(c, err, "Failed to unregister push device")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Push device unregistered successfully"))
+}
+
+// GetPushTokenHealth reports the delivery health of every registered push device
+// GET /notifications/push-devices/health?staleDays=
+func (h *NotificationHandler) GetPushTokenHealth(c *gin.Context) {
+	var params models.PushTokenHealthQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	report, err := h.notificationService.GetPushTokenHealth(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve push token health",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(report, ""))
+}
+
+// handlePushDeviceError maps push device errors to HTTP responses
+func (h *NotificationHandler) handlePushDeviceError(c *gin.Context, err error, message string) {
+	if err == service.ErrPushDeviceNotFound {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeNotFound,
+			err.Error(),
+			"",
+		))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+		models.ErrCodeInternalError,
+		message,
+		err.Error(),
+	))
+}
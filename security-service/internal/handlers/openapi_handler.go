@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"security-service/internal/models"
+	"security-service/internal/openapi"
+	"security-service/internal/validation"
+)
+
+// operations describes the query parameters, request bodies and response data of the handlers
+// for the OpenAPI document. Routes of other handlers are listed without schemas.
+var operations = map[string]openapi.Operation{
+	"AuditHandler.CreateLog":                          {Auth: openapi.AuthServiceKey, Body: models.AuditLogCreateRequest{}, Response: models.AuditLogResponse{}},
+	"AuditHandler.GetLogs":                            {Response: models.CursorAuditLogsResponse{}},
+	"AuditHandler.GetLog":                             {Response: models.AuditLogResponse{}},
+	"AuditHandler.GetAccessReport":                    {Query: models.AccessReportQueryParams{}, Response: models.AccessReport{}},
+	"AuthHandler.Login":                               {Auth: openapi.AuthNone, Body: models.LoginRequest{}, Response: models.LoginResponse{}},
+	"AuthHandler.RefreshToken":                        {Auth: openapi.AuthNone, Body: models.RefreshTokenRequest{}, Response: models.RefreshTokenResponse{}},
+	"AuthHandler.Logout":                              {Body: models.RefreshTokenRequest{}},
+	"AuthHandler.GetSigningKey":                       {Auth: openapi.AuthServiceKey, Response: models.SigningKeyResponse{}},
+	"AuthHandler.CheckPermissions":                    {Auth: openapi.AuthServiceKey, Body: models.CheckPermissionRequest{}},
+	"AuthHandler.GetUserInfo":                         {Response: models.UserInfoResponse{}},
+	"AuthHandler.GetProfile":                          {Response: models.ProfileResponse{}},
+	"AuthHandler.UpdateProfile":                       {Body: models.ProfileUpdateRequest{}, Response: models.ProfileResponse{}},
+	"AuthHandler.ChangePassword":                      {Body: models.ChangePasswordRequest{}, Response: models.ChangePasswordResponse{}},
+	"AuthHandler.Impersonate":                         {Body: models.ImpersonateRequest{}, Response: models.ImpersonateResponse{}},
+	"EnergyHandler.GetConsumption":                    {Response: models.EnergyConsumption{}},
+	"EnergyHandler.GetTariffs":                        {Response: models.Tariff{}},
+	"EnergyHandler.GetBuildingEnergy":                 {Response: models.BuildingEnergyResponse{}},
+	"EnergyHandler.RefreshToken":                      {Body: models.ExternalTokenRefreshRequest{}, Response: models.ExternalTokenRefreshResponse{}},
+	"EnergyHandler.GetProvider":                       {Response: models.EnergyProviderResponse{}},
+	"EnergyHandler.CreateProvider":                    {Body: models.EnergyProviderCreateRequest{}, Response: models.EnergyProviderResponse{}},
+	"EnergyHandler.UpdateProvider":                    {Body: models.EnergyProviderUpdateRequest{}, Response: models.EnergyProviderResponse{}},
+	"GroupHandler.GetGroup":                           {Response: models.GroupResponse{}},
+	"GroupHandler.CreateGroup":                        {Body: models.GroupCreateRequest{}, Response: models.GroupResponse{}},
+	"GroupHandler.UpdateGroup":                        {Body: models.GroupUpdateRequest{}, Response: models.GroupResponse{}},
+	"GroupHandler.AddMembers":                         {Body: models.GroupMembersRequest{}},
+	"KioskHandler.CreateKioskToken":                   {Body: models.KioskTokenCreateRequest{}, Response: models.KioskTokenCreateResponse{}},
+	"KioskHandler.RevokeKioskToken":                   {Response: models.KioskToken{}},
+	"NotificationHandler.SendNotification":            {Body: models.NotificationSendRequest{}, Response: models.NotificationResponse{}},
+	"NotificationHandler.TestRoute":                   {Body: models.NotificationRouteTestRequest{}, Response: models.NotificationRouteDecision{}},
+	"NotificationHandler.UpdatePreferences":           {Body: models.NotificationPreferencesUpdateRequest{}, Response: models.NotificationPreferences{}},
+	"NotificationHandler.GetPreferences":              {Response: models.NotificationPreferences{}},
+	"NotificationHandler.UpdatePreferencesByUserID":   {Body: models.NotificationPreferencesUpdateRequest{}, Response: models.NotificationPreferences{}},
+	"NotificationHandler.GetLogs":                     {Response: models.PaginatedNotificationsResponse{}},
+	"NotificationHandler.GetInbox":                    {Query: models.NotificationInboxQueryParams{}, Response: models.NotificationInboxResponse{}},
+	"NotificationHandler.MarkRead":                    {Response: models.NotificationResponse{}},
+	"NotificationHandler.MarkAllRead":                 {Response: models.NotificationsMarkedReadResponse{}},
+	"NotificationHandler.GetStats":                    {Response: models.NotificationDeliveryStats{}},
+	"NotificationHandler.ListPushDevices":             {Response: []models.PushDevice{}},
+	"NotificationHandler.RegisterPushDevice":          {Body: models.PushDeviceRegisterRequest{}, Response: models.PushDevice{}},
+	"NotificationHandler.UpdatePushDevicePreferences": {Body: models.PushDevicePreferencesRequest{}, Response: models.PushDevice{}},
+	"NotificationHandler.GetPushTokenHealth":          {Query: models.PushTokenHealthQueryParams{}, Response: models.PushTokenHealthReport{}},
+	"MeteringHandler.GetUsage":                        {Query: models.UsageQueryParams{}, Response: models.UsageSummary{}},
+	"MeteringHandler.GetSnapshots":                    {Query: models.UsageSnapshotQueryParams{}, Response: models.UsageSnapshotsResponse{}},
+	"OrganizationHandler.GetOrganization":             {Response: models.OrganizationResponse{}},
+	"OrganizationHandler.CreateOrganization":          {Body: models.OrganizationCreateRequest{}, Response: models.OrganizationResponse{}},
+	"OrganizationHandler.UpdateOrganization":          {Body: models.OrganizationUpdateRequest{}, Response: models.OrganizationResponse{}},
+	"PIIEncryptionHandler.GetStatus":                  {Response: models.PIIEncryptionStatus{}},
+	"PIIEncryptionHandler.Reencrypt":                  {Response: models.PIIReencryptionRun{}},
+	"RoleHandler.GetRole":                             {Response: models.RoleResponse{}},
+	"RoleHandler.GetRoleImpact":                       {Response: models.RoleImpactResponse{}},
+	"RoleHandler.PreviewRoleChange":                   {Body: models.RolePreviewRequest{}, Response: models.RolePreviewResponse{}},
+	"RoleHandler.CreateRole":                          {Body: models.RoleCreateRequest{}, Response: models.RoleResponse{}},
+	"RoleHandler.UpdateRole":                          {Body: models.RoleUpdateRequest{}, Response: models.RoleResponse{}},
+	"SigningKeyHandler.ListSigningKeys":               {Response: []*models.SigningKey{}},
+	"SigningKeyHandler.RotateSigningKey":              {Body: models.RotateSigningKeyRequest{}, Response: models.SigningKey{}},
+	"UserHandler.GetUser":                             {Response: models.UserResponse{}},
+	"UserHandler.CreateUser":                          {Body: models.UserCreateRequest{}, Response: models.UserResponse{}},
+	"UserHandler.UpdateUser":                          {Body: models.UserUpdateRequest{}, Response: models.UserResponse{}},
+	"UserHandler.RestoreUser":                         {Response: models.UserResponse{}},
+	"UserHandler.GetActivitySummary":                  {Query: models.UserActivityQueryParams{}, Response: models.UserActivitySummary{}},
+	"AuthHandler.GetJWKS":                             {Auth: openapi.AuthNone},
+}
+
+// OpenAPI serves the OpenAPI 3 document of the service. It is built on the first request, once
+// every route is registered on the engine.
+// GET /openapi.json
+func OpenAPI(engine *gin.Engine) gin.HandlerFunc {
+	var once sync.Once
+	var document openapi.Document
+	return func(c *gin.Context) {
+		once.Do(func() {
+			document = openapi.Build(openapi.Info{
+				Title:       "Security Service API",
+				Version:     "1.0.0",
+				Description: "Authentication, users, roles, audit logging and notifications",
+			}, engine.Routes(), operations)
+		})
+		c.JSON(http.StatusOK, document)
+	}
+}
+
+// respondValidationError responds to a request that failed binding, listing the fields that failed
+func respondValidationError(c *gin.Context, message string, err error) {
+	c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(message, err.Error(), validation.FieldErrors(err)))
+}
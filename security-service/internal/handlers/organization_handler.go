@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// OrganizationHandler handles organization management requests of super admins
+type OrganizationHandler struct {
+	orgService *service.OrganizationService
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(orgService *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{orgService: orgService}
+}
+
+// ListOrganizations lists organizations, optionally filtered by status
+// GET /organizations
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	orgs, total, totalPages, err := h.orgService.ListOrganizations(c.Request.Context(), c.Query("status"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve organizations",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"organizations": orgs,
+		"total":         total,
+		"page":          page,
+		"limit":         limit,
+		"totalPages":    totalPages,
+	}, ""))
+}
+
+// GetOrganization retrieves an organization by ID
+// GET /organizations/:id
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	org, err := h.orgService.GetOrganization(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err, "Failed to retrieve organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(org, ""))
+}
+
+// CreateOrganization creates a new organization
+// POST /organizations
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req models.OrganizationCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	org, err := h.orgService.CreateOrganization(c.Request.Context(), &req, middleware.GetUserID(c))
+	if err != nil {
+		h.handleError(c, err, "Failed to create organization")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(org, "Organization created successfully"))
+}
+
+// UpdateOrganization updates an organization's details, buildings or status
+// PUT /organizations/:id
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	var req models.OrganizationUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	org, err := h.orgService.UpdateOrganization(c.Request.Context(), c.Param("id"), &req, middleware.GetUserID(c))
+	if err != nil {
+		h.handleError(c, err, "Failed to update organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(org, "Organization updated successfully"))
+}
+
+// DeleteOrganization deletes an organization without users
+// DELETE /organizations/:id
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	if err := h.orgService.DeleteOrganization(c.Request.Context(), c.Param("id"), middleware.GetUserID(c)); err != nil {
+		h.handleError(c, err, "Failed to delete organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "Organization deleted successfully"))
+}
+
+// handleError maps organization service errors to HTTP responses
+func (h *OrganizationHandler) handleError(c *gin.Context, err error, message string) {
+	msg := err.Error()
+	switch {
+	case msg == "organization not found":
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			models.ErrCodeNotFound,
+			msg,
+			"",
+		))
+	case strings.Contains(msg, "already exists"),
+		strings.HasPrefix(msg, "building is already owned"),
+		strings.HasPrefix(msg, "organization still has"):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(
+			models.ErrCodeConflict,
+			msg,
+			"",
+		))
+	case msg == "invalid organization ID format", msg == "no updates provided", strings.HasPrefix(msg, "validation failed"):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			msg,
+			"",
+		))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			message,
+			msg,
+		))
+	}
+}
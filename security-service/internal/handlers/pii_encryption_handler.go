@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// PIIEncryptionHandler handles PII encryption key management requests
+type PIIEncryptionHandler struct {
+	piiEncryptionService *service.PIIEncryptionService
+}
+
+// NewPIIEncryptionHandler creates a new PII encryption handler
+func NewPIIEncryptionHandler(piiEncryptionService *service.PIIEncryptionService) *PIIEncryptionHandler {
+	return &PIIEncryptionHandler{piiEncryptionService: piiEncryptionService}
+}
+
+// GetStatus reports the PII encryption keys and the records awaiting re-encryption
+// GET /admin/pii-encryption
+func (h *PIIEncryptionHandler) GetStatus(c *gin.Context) {
+	status, err := h.piiEncryptionService.Status(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve PII encryption status",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(status, ""))
+}
+
+// Reencrypt re-encrypts PII that is plaintext or encrypted with a retired key with the current key
+// POST /admin/pii-encryption/reencrypt
+func (h *PIIEncryptionHandler) Reencrypt(c *gin.Context) {
+	run, err := h.piiEncryptionService.Reencrypt(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "already running") {
+			c.JSON(http.StatusConflict, models.NewErrorResponse(models.ErrCodeConflict, err.Error(), ""))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to re-encrypt PII",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(run, "PII re-encryption completed"))
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// RetentionHandler handles data retention requests
+type RetentionHandler struct {
+	retentionService *service.RetentionService
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(retentionService *service.RetentionService) *RetentionHandler {
+	return &RetentionHandler{retentionService: retentionService}
+}
+
+// GetStatus reports retention policies and purged record counts
+// GET /admin/retention
+func (h *RetentionHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.retentionService.Status(), ""))
+}
+
+// RunNow applies retention policies immediately
+// POST /admin/retention/run
+func (h *RetentionHandler) RunNow(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.retentionService.Run(c.Request.Context()), "Retention run completed"))
+}
@@ -64,16 +64,70 @@ func (h *RoleHandler) GetRole(c *gin.Context) {
 	c.JSON(http.StatusOK, models.NewSuccessResponse(role, ""))
 }
 
+// GetRoleImpact lists the users affected by changes to a role
+// GET /roles/:roleName/impact
+func (h *RoleHandler) GetRoleImpact(c *gin.Context) {
+	name := c.Param("roleName")
+
+	impact, err := h.roleService.GetRoleImpact(c.Request.Context(), name)
+	if err != nil {
+		if err.Error() == "role not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				"Role not found",
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to compute role impact",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(impact, ""))
+}
+
+// PreviewRoleChange reports the permissions users would lose under proposed role permissions
+// POST /roles/:roleName/preview
+func (h *RoleHandler) PreviewRoleChange(c *gin.Context) {
+	name := c.Param("roleName")
+
+	var req models.RolePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, "Invalid request body", err)
+		return
+	}
+
+	preview, err := h.roleService.PreviewRoleChange(c.Request.Context(), name, &req)
+	if err != nil {
+		if err.Error() == "role not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				"Role not found",
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to preview role change",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(preview, ""))
+}
+
 // CreateRole creates a new role
 // POST /roles
 func (h *RoleHandler) CreateRole(c *gin.Context) {
 	var req models.RoleCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -107,11 +161,7 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 
 	var req models.RoleUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -3,18 +3,31 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 
+	"security-service/internal/config"
 	"security-service/internal/middleware"
+	"security-service/internal/validation"
 )
 
 // Router holds all handler dependencies
 type Router struct {
-	AuthHandler         *AuthHandler
-	UserHandler         *UserHandler
-	RoleHandler         *RoleHandler
-	AuditHandler        *AuditHandler
-	NotificationHandler *NotificationHandler
-	EnergyHandler       *EnergyHandler
-	AuthMiddleware      *middleware.AuthMiddleware
+	AuthHandler          *AuthHandler
+	UserHandler          *UserHandler
+	RoleHandler          *RoleHandler
+	GroupHandler         *GroupHandler
+	AuditHandler         *AuditHandler
+	NotificationHandler  *NotificationHandler
+	EnergyHandler        *EnergyHandler
+	HealthHandler        *HealthHandler
+	RetentionHandler     *RetentionHandler
+	KioskHandler         *KioskHandler
+	SigningKeyHandler    *SigningKeyHandler
+	OrganizationHandler  *OrganizationHandler
+	MeteringHandler      *MeteringHandler
+	PIIEncryptionHandler *PIIEncryptionHandler
+	AuthMiddleware       *middleware.AuthMiddleware
+
+	// UsageMeter meters API calls for billing when set
+	UsageMeter *middleware.UsageMeter
 }
 
 // NewRouter creates a new router with all handlers
@@ -22,38 +35,61 @@ func NewRouter(
 	authHandler *AuthHandler,
 	userHandler *UserHandler,
 	roleHandler *RoleHandler,
+	groupHandler *GroupHandler,
 	auditHandler *AuditHandler,
 	notificationHandler *NotificationHandler,
 	energyHandler *EnergyHandler,
+	healthHandler *HealthHandler,
+	retentionHandler *RetentionHandler,
+	kioskHandler *KioskHandler,
+	signingKeyHandler *SigningKeyHandler,
+	organizationHandler *OrganizationHandler,
+	meteringHandler *MeteringHandler,
+	piiEncryptionHandler *PIIEncryptionHandler,
 	authMiddleware *middleware.AuthMiddleware,
 ) *Router {
 	return &Router{
-		AuthHandler:         authHandler,
-		UserHandler:         userHandler,
-		RoleHandler:         roleHandler,
-		AuditHandler:        auditHandler,
-		NotificationHandler: notificationHandler,
-		EnergyHandler:       energyHandler,
-		AuthMiddleware:      authMiddleware,
+		AuthHandler:          authHandler,
+		UserHandler:          userHandler,
+		RoleHandler:          roleHandler,
+		GroupHandler:         groupHandler,
+		AuditHandler:         auditHandler,
+		NotificationHandler:  notificationHandler,
+		EnergyHandler:        energyHandler,
+		HealthHandler:        healthHandler,
+		RetentionHandler:     retentionHandler,
+		KioskHandler:         kioskHandler,
+		SigningKeyHandler:    signingKeyHandler,
+		OrganizationHandler:  organizationHandler,
+		MeteringHandler:      meteringHandler,
+		PIIEncryptionHandler: piiEncryptionHandler,
+		AuthMiddleware:       authMiddleware,
 	}
 }
 
-// SetupRoutes configures all API routes
-func (r *Router) SetupRoutes(engine *gin.Engine) {
+// SetupRoutes configures all API routes, applying the given CORS and security header policies
+func (r *Router) SetupRoutes(engine *gin.Engine, policy config.HTTPConfig) {
+	// Register the custom binding rules and report validation errors by JSON field name
+	validation.Register()
+
 	// Apply common middleware
 	engine.Use(middleware.Recovery())
+	engine.Use(middleware.Tracing())
 	engine.Use(middleware.RequestID())
-	engine.Use(middleware.CORS())
-	engine.Use(middleware.SecurityHeaders())
+	engine.Use(middleware.Localization())
+	engine.Use(middleware.CORS(policy.CORS))
+	engine.Use(middleware.SecurityHeaders(policy.Headers))
 	engine.Use(middleware.RequestLogger())
+	if r.UsageMeter != nil {
+		engine.Use(r.UsageMeter.Middleware())
+	}
 
-	// Health check endpoint
-	engine.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "healthy",
-			"service": "security-service",
-		})
-	})
+	// Health check endpoints
+	engine.GET("/health", r.HealthHandler.Health)
+	engine.GET("/health/deep", r.HealthHandler.DeepHealth)
+
+	// OpenAPI document of the routes, for generating clients
+	engine.GET("/openapi.json", OpenAPI(engine))
 
 	// API v1 routes
 	api := engine.Group("/api/v1")
@@ -61,9 +97,13 @@ func (r *Router) SetupRoutes(engine *gin.Engine) {
 		r.setupAuthRoutes(api)
 		r.setupUserRoutes(api)
 		r.setupRoleRoutes(api)
+		r.setupGroupRoutes(api)
 		r.setupAuditRoutes(api)
 		r.setupNotificationRoutes(api)
 		r.setupEnergyRoutes(api)
+		r.setupAdminRoutes(api)
+		r.setupOrganizationRoutes(api)
+		r.setupMeteringRoutes(api)
 	}
 
 	// Legacy routes (without /api/v1 prefix for backward compatibility)
@@ -82,7 +122,16 @@ func (r *Router) setupAuthRoutes(rg *gin.RouterGroup) {
 		auth.GET("/validate-token", r.AuthHandler.ValidateToken)
 
 		// Permission check (for internal microservices)
-		auth.POST("/check-permissions", r.AuthHandler.CheckPermissions)
+		auth.POST("/check-permissions", r.AuthMiddleware.RequireServiceKey(), r.AuthHandler.CheckPermissions)
+
+		// Tokens of users on whose behalf work was queued (internal microservices only)
+		auth.POST("/delegate-token", r.AuthMiddleware.RequireServiceKey(), r.AuthHandler.DelegateToken)
+
+		// Signing key for local token validation (internal microservices only)
+		auth.GET("/signing-key", r.AuthMiddleware.RequireServiceKey(), r.AuthHandler.GetSigningKey)
+
+		// Public keys of asymmetric signing keys
+		auth.GET("/jwks", r.AuthHandler.GetJWKS)
 
 		// Protected routes
 		protected := auth.Group("")
@@ -90,6 +139,10 @@ func (r *Router) setupAuthRoutes(rg *gin.RouterGroup) {
 		{
 			protected.POST("/logout", r.AuthHandler.Logout)
 			protected.GET("/user-info", r.AuthHandler.GetUserInfo)
+			protected.GET("/profile", r.AuthHandler.GetProfile)
+			protected.PUT("/profile", r.AuthHandler.UpdateProfile)
+			protected.POST("/change-password", r.AuthMiddleware.ForbidImpersonation(), r.AuthHandler.ChangePassword)
+			protected.POST("/impersonate/:userId", r.AuthMiddleware.RequireAdmin(), r.AuthMiddleware.ForbidImpersonation(), r.AuthHandler.Impersonate)
 		}
 	}
 }
@@ -103,10 +156,13 @@ func (r *Router) setupUserRoutes(rg *gin.RouterGroup) {
 		users.GET("", r.AuthMiddleware.RequireAdmin(), r.UserHandler.ListUsers)
 		users.POST("", r.AuthMiddleware.RequireAdmin(), r.UserHandler.CreateUser)
 		users.DELETE("/:id", r.AuthMiddleware.RequireAdmin(), r.UserHandler.DeleteUser)
+		users.GET("/deleted", r.AuthMiddleware.RequireAdmin(), r.UserHandler.ListDeletedUsers)
+		users.GET("/activity-summary", r.AuthMiddleware.RequireAdmin(), r.UserHandler.GetActivitySummary)
+		users.POST("/:id/restore", r.AuthMiddleware.RequireAdmin(), r.UserHandler.RestoreUser)
 
 		// Protected routes (user can view their own details or admin can view any)
 		users.GET("/:id", r.UserHandler.GetUser)
-		users.PUT("/:id", r.UserHandler.UpdateUser)
+		users.PUT("/:id", r.AuthMiddleware.ForbidImpersonation(), r.UserHandler.UpdateUser)
 	}
 }
 
@@ -118,17 +174,35 @@ func (r *Router) setupRoleRoutes(rg *gin.RouterGroup) {
 		// Admin only routes
 		roles.GET("", r.RoleHandler.ListRoles)
 		roles.POST("", r.AuthMiddleware.RequireAdmin(), r.RoleHandler.CreateRole)
+		roles.GET("/:roleName/impact", r.AuthMiddleware.RequireAdmin(), r.RoleHandler.GetRoleImpact)
+		roles.POST("/:roleName/preview", r.AuthMiddleware.RequireAdmin(), r.RoleHandler.PreviewRoleChange)
 		roles.PUT("/:roleName", r.AuthMiddleware.RequireAdmin(), r.RoleHandler.UpdateRole)
 		roles.DELETE("/:roleName", r.AuthMiddleware.RequireAdmin(), r.RoleHandler.DeleteRole)
 	}
 }
 
+// setupGroupRoutes configures user group management routes
+func (r *Router) setupGroupRoutes(rg *gin.RouterGroup) {
+	groups := rg.Group("/groups")
+	groups.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		groups.GET("", r.GroupHandler.ListGroups)
+		groups.POST("", r.GroupHandler.CreateGroup)
+		groups.GET("/:groupName", r.GroupHandler.GetGroup)
+		groups.PUT("/:groupName", r.GroupHandler.UpdateGroup)
+		groups.DELETE("/:groupName", r.GroupHandler.DeleteGroup)
+		groups.GET("/:groupName/members", r.GroupHandler.ListMembers)
+		groups.POST("/:groupName/members", r.GroupHandler.AddMembers)
+		groups.DELETE("/:groupName/members/:userId", r.GroupHandler.RemoveMember)
+	}
+}
+
 // setupAuditRoutes configures audit logging routes
 func (r *Router) setupAuditRoutes(rg *gin.RouterGroup) {
 	audit := rg.Group("/audit")
 	{
-		// Allow internal services to log without full auth
-		audit.POST("/log", r.AuditHandler.CreateLog)
+		// Internal services log with a signed request instead of a user token
+		audit.POST("/log", r.AuthMiddleware.RequireServiceKey(), r.AuditHandler.CreateLog)
 
 		// Protected routes for viewing logs
 		protected := audit.Group("")
@@ -136,6 +210,8 @@ func (r *Router) setupAuditRoutes(rg *gin.RouterGroup) {
 		protected.Use(r.AuthMiddleware.RequireAdmin())
 		{
 			protected.GET("/logs", r.AuditHandler.GetLogs)
+			protected.GET("/logs/export", r.AuditHandler.ExportLogs)
+			protected.GET("/access-report", r.AuditHandler.GetAccessReport)
 			protected.GET("/logs/:id", r.AuditHandler.GetLog)
 		}
 	}
@@ -147,10 +223,21 @@ func (r *Router) setupNotificationRoutes(rg *gin.RouterGroup) {
 	notifications.Use(r.AuthMiddleware.RequireAuth())
 	{
 		notifications.POST("/send", r.NotificationHandler.SendNotification)
+		notifications.POST("/test-send", r.NotificationHandler.TestRoute)
 		notifications.POST("/preferences", r.NotificationHandler.UpdatePreferences)
 		notifications.GET("/preferences/:userId", r.NotificationHandler.GetPreferences)
 		notifications.PUT("/preferences/:userId", r.NotificationHandler.UpdatePreferencesByUserID)
 		notifications.GET("/logs", r.NotificationHandler.GetLogs)
+		notifications.GET("/inbox", r.NotificationHandler.GetInbox)
+		notifications.POST("/inbox/read-all", r.NotificationHandler.MarkAllRead)
+		notifications.POST("/inbox/:id/read", r.NotificationHandler.MarkRead)
+		notifications.GET("/push-devices", r.NotificationHandler.ListPushDevices)
+		notifications.POST("/push-devices", r.NotificationHandler.RegisterPushDevice)
+		notifications.GET("/push-devices/health", r.AuthMiddleware.RequireAdmin(), r.NotificationHandler.GetPushTokenHealth)
+		notifications.PUT("/push-devices/:token", r.NotificationHandler.UpdatePushDevicePreferences)
+		notifications.DELETE("/push-devices/:token", r.NotificationHandler.UnregisterPushDevice)
+		notifications.GET("/providers/health", r.AuthMiddleware.RequireAdmin(), r.NotificationHandler.GetProviderHealth)
+		notifications.GET("/stats", r.AuthMiddleware.RequireAdmin(), r.NotificationHandler.GetStats)
 	}
 }
 
@@ -161,7 +248,58 @@ func (r *Router) setupEnergyRoutes(rg *gin.RouterGroup) {
 	{
 		energy.GET("/consumption", r.EnergyHandler.GetConsumption)
 		energy.GET("/tariffs", r.EnergyHandler.GetTariffs)
+		energy.GET("/buildings/:buildingId", r.EnergyHandler.GetBuildingEnergy)
 		energy.POST("/refresh-token", r.AuthMiddleware.RequireAdmin(), r.EnergyHandler.RefreshToken)
+
+		providers := energy.Group("/providers")
+		providers.Use(r.AuthMiddleware.RequireAdmin())
+		{
+			providers.GET("", r.EnergyHandler.ListProviders)
+			providers.POST("", r.EnergyHandler.CreateProvider)
+			providers.GET("/:providerName", r.EnergyHandler.GetProvider)
+			providers.PUT("/:providerName", r.EnergyHandler.UpdateProvider)
+			providers.DELETE("/:providerName", r.EnergyHandler.DeleteProvider)
+		}
+	}
+}
+
+// setupAdminRoutes configures administrative maintenance routes
+func (r *Router) setupAdminRoutes(rg *gin.RouterGroup) {
+	admin := rg.Group("/admin")
+	admin.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		admin.GET("/retention", r.RetentionHandler.GetStatus)
+		admin.POST("/retention/run", r.RetentionHandler.RunNow)
+		admin.GET("/kiosk-tokens", r.KioskHandler.ListKioskTokens)
+		admin.POST("/kiosk-tokens", r.KioskHandler.CreateKioskToken)
+		admin.DELETE("/kiosk-tokens/:id", r.KioskHandler.RevokeKioskToken)
+		admin.GET("/signing-keys", r.SigningKeyHandler.ListSigningKeys)
+		admin.POST("/signing-keys/rotate", r.SigningKeyHandler.RotateSigningKey)
+		admin.GET("/pii-encryption", r.PIIEncryptionHandler.GetStatus)
+		admin.POST("/pii-encryption/reencrypt", r.PIIEncryptionHandler.Reencrypt)
+	}
+}
+
+// setupOrganizationRoutes configures organization management routes for super admins
+func (r *Router) setupOrganizationRoutes(rg *gin.RouterGroup) {
+	orgs := rg.Group("/organizations")
+	orgs.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireSuperAdmin(), r.AuthMiddleware.ForbidImpersonation())
+	{
+		orgs.GET("", r.OrganizationHandler.ListOrganizations)
+		orgs.POST("", r.OrganizationHandler.CreateOrganization)
+		orgs.GET("/:id", r.OrganizationHandler.GetOrganization)
+		orgs.PUT("/:id", r.OrganizationHandler.UpdateOrganization)
+		orgs.DELETE("/:id", r.OrganizationHandler.DeleteOrganization)
+	}
+}
+
+// setupMeteringRoutes configures usage metering routes for admins
+func (r *Router) setupMeteringRoutes(rg *gin.RouterGroup) {
+	metering := rg.Group("/metering")
+	metering.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		metering.GET("/usage", r.MeteringHandler.GetUsage)
+		metering.GET("/snapshots", r.MeteringHandler.GetSnapshots)
 	}
 }
 
@@ -173,13 +311,20 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 		auth.POST("/login", r.AuthHandler.Login)
 		auth.POST("/refresh", r.AuthHandler.RefreshToken)
 		auth.GET("/validate-token", r.AuthHandler.ValidateToken)
-		auth.POST("/check-permissions", r.AuthHandler.CheckPermissions)
+		auth.POST("/check-permissions", r.AuthMiddleware.RequireServiceKey(), r.AuthHandler.CheckPermissions)
+		auth.POST("/delegate-token", r.AuthMiddleware.RequireServiceKey(), r.AuthHandler.DelegateToken)
+		auth.GET("/signing-key", r.AuthMiddleware.RequireServiceKey(), r.AuthHandler.GetSigningKey)
+		auth.GET("/jwks", r.AuthHandler.GetJWKS)
 
 		protected := auth.Group("")
 		protected.Use(r.AuthMiddleware.RequireAuth())
 		{
 			protected.POST("/logout", r.AuthHandler.Logout)
 			protected.GET("/user-info", r.AuthHandler.GetUserInfo)
+			protected.GET("/profile", r.AuthHandler.GetProfile)
+			protected.PUT("/profile", r.AuthHandler.UpdateProfile)
+			protected.POST("/change-password", r.AuthMiddleware.ForbidImpersonation(), r.AuthHandler.ChangePassword)
+			protected.POST("/impersonate/:userId", r.AuthMiddleware.RequireAdmin(), r.AuthMiddleware.ForbidImpersonation(), r.AuthHandler.Impersonate)
 		}
 	}
 
@@ -190,8 +335,11 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 		users.GET("", r.AuthMiddleware.RequireAdmin(), r.UserHandler.ListUsers)
 		users.POST("", r.AuthMiddleware.RequireAdmin(), r.UserHandler.CreateUser)
 		users.GET("/:id", r.UserHandler.GetUser)
-		users.PUT("/:id", r.UserHandler.UpdateUser)
+		users.PUT("/:id", r.AuthMiddleware.ForbidImpersonation(), r.UserHandler.UpdateUser)
 		users.DELETE("/:id", r.AuthMiddleware.RequireAdmin(), r.UserHandler.DeleteUser)
+		users.GET("/deleted", r.AuthMiddleware.RequireAdmin(), r.UserHandler.ListDeletedUsers)
+		users.GET("/activity-summary", r.AuthMiddleware.RequireAdmin(), r.UserHandler.GetActivitySummary)
+		users.POST("/:id/restore", r.AuthMiddleware.RequireAdmin(), r.UserHandler.RestoreUser)
 	}
 
 	// Role routes
@@ -200,31 +348,60 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 	{
 		roles.GET("", r.RoleHandler.ListRoles)
 		roles.POST("", r.AuthMiddleware.RequireAdmin(), r.RoleHandler.CreateRole)
+		roles.GET("/:roleName/impact", r.AuthMiddleware.RequireAdmin(), r.RoleHandler.GetRoleImpact)
+		roles.POST("/:roleName/preview", r.AuthMiddleware.RequireAdmin(), r.RoleHandler.PreviewRoleChange)
 		roles.PUT("/:roleName", r.AuthMiddleware.RequireAdmin(), r.RoleHandler.UpdateRole)
 		roles.DELETE("/:roleName", r.AuthMiddleware.RequireAdmin(), r.RoleHandler.DeleteRole)
 	}
 
+	// Group routes
+	groups := engine.Group("/groups")
+	groups.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		groups.GET("", r.GroupHandler.ListGroups)
+		groups.POST("", r.GroupHandler.CreateGroup)
+		groups.GET("/:groupName", r.GroupHandler.GetGroup)
+		groups.PUT("/:groupName", r.GroupHandler.UpdateGroup)
+		groups.DELETE("/:groupName", r.GroupHandler.DeleteGroup)
+		groups.GET("/:groupName/members", r.GroupHandler.ListMembers)
+		groups.POST("/:groupName/members", r.GroupHandler.AddMembers)
+		groups.DELETE("/:groupName/members/:userId", r.GroupHandler.RemoveMember)
+	}
+
 	// Notification routes
 	notifications := engine.Group("/notifications")
 	notifications.Use(r.AuthMiddleware.RequireAuth())
 	{
 		notifications.POST("/send", r.NotificationHandler.SendNotification)
+		notifications.POST("/test-send", r.NotificationHandler.TestRoute)
 		notifications.POST("/preferences", r.NotificationHandler.UpdatePreferences)
 		notifications.GET("/preferences/:userId", r.NotificationHandler.GetPreferences)
 		notifications.PUT("/preferences/:userId", r.NotificationHandler.UpdatePreferencesByUserID)
 		notifications.GET("/logs", r.NotificationHandler.GetLogs)
+		notifications.GET("/inbox", r.NotificationHandler.GetInbox)
+		notifications.POST("/inbox/read-all", r.NotificationHandler.MarkAllRead)
+		notifications.POST("/inbox/:id/read", r.NotificationHandler.MarkRead)
+		notifications.GET("/push-devices", r.NotificationHandler.ListPushDevices)
+		notifications.POST("/push-devices", r.NotificationHandler.RegisterPushDevice)
+		notifications.GET("/push-devices/health", r.AuthMiddleware.RequireAdmin(), r.NotificationHandler.GetPushTokenHealth)
+		notifications.PUT("/push-devices/:token", r.NotificationHandler.UpdatePushDevicePreferences)
+		notifications.DELETE("/push-devices/:token", r.NotificationHandler.UnregisterPushDevice)
+		notifications.GET("/providers/health", r.AuthMiddleware.RequireAdmin(), r.NotificationHandler.GetProviderHealth)
+		notifications.GET("/stats", r.AuthMiddleware.RequireAdmin(), r.NotificationHandler.GetStats)
 	}
 
 	// Audit routes
 	audit := engine.Group("/audit")
 	{
-		audit.POST("/log", r.AuditHandler.CreateLog)
+		audit.POST("/log", r.AuthMiddleware.RequireServiceKey(), r.AuditHandler.CreateLog)
 
 		protected := audit.Group("")
 		protected.Use(r.AuthMiddleware.RequireAuth())
 		protected.Use(r.AuthMiddleware.RequireAdmin())
 		{
 			protected.GET("/logs", r.AuditHandler.GetLogs)
+			protected.GET("/logs/export", r.AuditHandler.ExportLogs)
+			protected.GET("/access-report", r.AuditHandler.GetAccessReport)
 		}
 	}
 
@@ -234,6 +411,51 @@ func (r *Router) setupLegacyRoutes(engine *gin.Engine) {
 	{
 		energy.GET("/consumption", r.EnergyHandler.GetConsumption)
 		energy.GET("/tariffs", r.EnergyHandler.GetTariffs)
+		energy.GET("/buildings/:buildingId", r.EnergyHandler.GetBuildingEnergy)
 		energy.POST("/refresh-token", r.AuthMiddleware.RequireAdmin(), r.EnergyHandler.RefreshToken)
+
+		providers := energy.Group("/providers")
+		providers.Use(r.AuthMiddleware.RequireAdmin())
+		{
+			providers.GET("", r.EnergyHandler.ListProviders)
+			providers.POST("", r.EnergyHandler.CreateProvider)
+			providers.GET("/:providerName", r.EnergyHandler.GetProvider)
+			providers.PUT("/:providerName", r.EnergyHandler.UpdateProvider)
+			providers.DELETE("/:providerName", r.EnergyHandler.DeleteProvider)
+		}
+	}
+
+	// Admin routes
+	admin := engine.Group("/admin")
+	admin.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		admin.GET("/retention", r.RetentionHandler.GetStatus)
+		admin.POST("/retention/run", r.RetentionHandler.RunNow)
+		admin.GET("/kiosk-tokens", r.KioskHandler.ListKioskTokens)
+		admin.POST("/kiosk-tokens", r.KioskHandler.CreateKioskToken)
+		admin.DELETE("/kiosk-tokens/:id", r.KioskHandler.RevokeKioskToken)
+		admin.GET("/signing-keys", r.SigningKeyHandler.ListSigningKeys)
+		admin.POST("/signing-keys/rotate", r.SigningKeyHandler.RotateSigningKey)
+		admin.GET("/pii-encryption", r.PIIEncryptionHandler.GetStatus)
+		admin.POST("/pii-encryption/reencrypt", r.PIIEncryptionHandler.Reencrypt)
+	}
+
+	// Organization routes
+	orgs := engine.Group("/organizations")
+	orgs.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireSuperAdmin(), r.AuthMiddleware.ForbidImpersonation())
+	{
+		orgs.GET("", r.OrganizationHandler.ListOrganizations)
+		orgs.POST("", r.OrganizationHandler.CreateOrganization)
+		orgs.GET("/:id", r.OrganizationHandler.GetOrganization)
+		orgs.PUT("/:id", r.OrganizationHandler.UpdateOrganization)
+		orgs.DELETE("/:id", r.OrganizationHandler.DeleteOrganization)
+	}
+
+	// Metering routes
+	metering := engine.Group("/metering")
+	metering.Use(r.AuthMiddleware.RequireAuth(), r.AuthMiddleware.RequireAdmin())
+	{
+		metering.GET("/usage", r.MeteringHandler.GetUsage)
+		metering.GET("/snapshots", r.MeteringHandler.GetSnapshots)
 	}
 }
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// SigningKeyHandler handles token signing key management requests
+type SigningKeyHandler struct {
+	signingKeyService *service.SigningKeyService
+}
+
+// NewSigningKeyHandler creates a new signing key handler
+func NewSigningKeyHandler(signingKeyService *service.SigningKeyService) *SigningKeyHandler {
+	return &SigningKeyHandler{signingKeyService: signingKeyService}
+}
+
+// ListSigningKeys lists the signing keys and their rotation status
+// GET /admin/signing-keys
+func (h *SigningKeyHandler) ListSigningKeys(c *gin.Context) {
+	keys, err := h.signingKeyService.ListKeys(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve signing keys",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(keys, ""))
+}
+
+// RotateSigningKey generates a new signing key and retires the current one after the grace period
+// POST /admin/signing-keys/rotate
+func (h *SigningKeyHandler) RotateSigningKey(c *gin.Context) {
+	var req models.RotateSigningKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondValidationError(c, "Invalid request body", err)
+			return
+		}
+	}
+
+	key, err := h.signingKeyService.RotateKey(c.Request.Context(), req.Algorithm, middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to rotate signing key",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(key, "Signing key rotated successfully"))
+}
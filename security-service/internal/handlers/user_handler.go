@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -91,11 +93,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req models.UserCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -111,6 +109,22 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 			))
 			return
 		}
+		if err.Error() == "only super admins can grant the super_admin role" || err.Error() == "cannot manage users of another organization" {
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				err.Error(),
+				"",
+			))
+			return
+		}
+		if err.Error() == "organization not found" || err.Error() == "invalid organization ID format" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				err.Error(),
+				"",
+			))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			models.ErrCodeInternalError,
 			"Failed to create user",
@@ -129,11 +143,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 	var req models.UserUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			models.ErrCodeValidationFailed,
-			"Invalid request body",
-			err.Error(),
-		))
+		respondValidationError(c, "Invalid request body", err)
 		return
 	}
 
@@ -149,6 +159,14 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 			))
 			return
 		}
+		if err.Error() == "only super admins can grant the super_admin role" {
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				err.Error(),
+				"",
+			))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			models.ErrCodeInternalError,
 			"Failed to update user",
@@ -194,3 +212,89 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(nil, "User deleted successfully"))
 }
+
+// ListDeletedUsers retrieves soft-deleted users awaiting purge
+// GET /users/deleted
+func (h *UserHandler) ListDeletedUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	users, total, totalPages, err := h.userService.ListDeletedUsers(c.Request.Context(), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to retrieve deleted users",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"users":      users,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": totalPages,
+	}, ""))
+}
+
+// RestoreUser restores a soft-deleted user
+// POST /users/:id/restore
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	id := c.Param("id")
+
+	if !objectIDRegex.MatchString(id) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			models.ErrCodeValidationFailed,
+			"Invalid user ID format",
+			"ID must be a valid 24-character hex string",
+		))
+		return
+	}
+
+	user, err := h.userService.RestoreUser(c.Request.Context(), id, middleware.GetUserID(c))
+	if err != nil {
+		if err.Error() == "deleted user not found" {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(
+				models.ErrCodeNotFound,
+				"Deleted user not found",
+				"",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to restore user",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(user, "User restored successfully"))
+}
+
+// GetActivitySummary summarizes the audited activity of every user in a period
+// GET /users/activity-summary?period=30d|4w|2024-06&flag=
+func (h *UserHandler) GetActivitySummary(c *gin.Context) {
+	var params models.UserActivityQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		respondValidationError(c, "Invalid query parameters", err)
+		return
+	}
+
+	summary, err := h.userService.GetActivitySummary(c.Request.Context(), &params, time.Now().UTC())
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(models.ErrCodeValidationFailed, err.Error(), ""))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			models.ErrCodeInternalError,
+			"Failed to summarize user activity",
+			err.Error(),
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(summary, ""))
+}
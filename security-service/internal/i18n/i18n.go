@@ -0,0 +1,176 @@
+// Package i18n negotiates the language of a request and translates the user-facing messages of
+// API responses. Messages are written in English in the code and looked up in per-language catalogs;
+// messages without a translation are returned unchanged.
+package i18n
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultLanguage is the language messages are written in and the fallback of the negotiation
+const DefaultLanguage = "en"
+
+// catalog holds the translations of one language. Messages are keys of Messages; Patterns are
+// messages with {} placeholders for variable parts, whose values are translated in turn through
+// Messages and Terms. Terms are nouns used in the patterns, keyed in lower case.
+type catalog struct {
+	Messages map[string]string
+	Patterns map[string]string
+	Terms    map[string]string
+}
+
+// pattern is a compiled catalog pattern
+type pattern struct {
+	re          *regexp.Regexp
+	translation string
+	literal     int
+}
+
+var (
+	catalogs = map[string]*catalog{
+		"de": germanCatalog,
+		"fr": frenchCatalog,
+		"es": spanishCatalog,
+	}
+	patterns = make(map[string][]pattern)
+)
+
+func init() {
+	for lang, cat := range catalogs {
+		compiled := make([]pattern, 0, len(cat.Patterns))
+		for source, translation := range cat.Patterns {
+			parts := strings.Split(source, "{}")
+			for i, part := range parts {
+				parts[i] = regexp.QuoteMeta(part)
+			}
+			compiled = append(compiled, pattern{
+				re:          regexp.MustCompile("^" + strings.Join(parts, "(.+?)") + "$"),
+				translation: translation,
+				literal:     len(source) - 2*(len(parts)-1),
+			})
+		}
+		// The most specific pattern wins
+		sort.Slice(compiled, func(i, j int) bool {
+			if compiled[i].literal != compiled[j].literal {
+				return compiled[i].literal > compiled[j].literal
+			}
+			return compiled[i].re.String() < compiled[j].re.String()
+		})
+		patterns[lang] = compiled
+	}
+}
+
+// Supported returns the languages messages can be translated to, the default language first
+func Supported() []string {
+	languages := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return append([]string{DefaultLanguage}, languages...)
+}
+
+// IsSupported reports whether lang is a supported language
+func IsSupported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok || lang == DefaultLanguage
+}
+
+// Negotiate picks the supported language that best matches an Accept-Language header. Region
+// subtags are ignored, so "de-AT" selects German. The default language is returned when the
+// header is empty or names no supported language.
+func Negotiate(acceptLanguage string) string {
+	best, bestQ := DefaultLanguage, 0.0
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(item, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.IndexAny(tag, "-_"); i >= 0 {
+			tag = tag[:i]
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if !IsSupported(tag) || q <= 0 || q <= bestQ {
+			continue
+		}
+		best, bestQ = tag, q
+	}
+	return best
+}
+
+// Translate returns msg in the given language, or msg itself when there is no translation
+func Translate(lang, msg string) string {
+	cat, ok := catalogs[lang]
+	if !ok || msg == "" {
+		return msg
+	}
+	if translated, ok := cat.Messages[msg]; ok {
+		return translated
+	}
+
+	for _, p := range patterns[lang] {
+		match := p.re.FindStringSubmatch(msg)
+		if match == nil {
+			continue
+		}
+		translated := p.translation
+		for _, value := range match[1:] {
+			translated = strings.Replace(translated, "{}", translateValue(lang, cat, value), 1)
+		}
+		return translated
+	}
+	return msg
+}
+
+// TranslateAll translates every message of msgs into a new slice
+func TranslateAll(lang string, msgs []string) []string {
+	if msgs == nil {
+		return nil
+	}
+	translated := make([]string, len(msgs))
+	for i, msg := range msgs {
+		translated[i] = Translate(lang, msg)
+	}
+	return translated
+}
+
+// translateValue translates the value of a pattern placeholder. Terms keep the capitalization of
+// the value, so "Device" and "device" both translate.
+func translateValue(lang string, cat *catalog, value string) string {
+	if term, ok := cat.Terms[strings.ToLower(value)]; ok {
+		first, _ := utf8.DecodeRuneInString(value)
+		if unicode.IsUpper(first) {
+			r, size := utf8.DecodeRuneInString(term)
+			return string(unicode.ToUpper(r)) + term[size:]
+		}
+		return term
+	}
+	return Translate(lang, value)
+}
+
+type contextKey struct{}
+
+// WithLanguage returns a copy of ctx carrying the language of a request
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext returns the language carried by ctx, or the default language
+func FromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(contextKey{}).(string); ok {
+		return lang
+	}
+	return DefaultLanguage
+}
@@ -6,18 +6,29 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
-	"security-service/internal/config"
 	"security-service/internal/models"
 	"security-service/internal/repository"
+	"security-service/internal/tracing"
 	"security-service/pkg/utils"
 )
 
-// EnergyProviderClient handles communication with external energy providers
+// EnergyProviderSettings holds the connection details of a single energy provider
+type EnergyProviderSettings struct {
+	Name         string
+	BaseURL      string
+	APIKey       string
+	ClientID     string
+	ClientSecret string
+}
+
+// EnergyProviderClient handles communication with an external energy provider
 type EnergyProviderClient struct {
 	httpClient   *http.Client
+	name         string
 	baseURL      string
 	apiKey       string
 	clientID     string
@@ -31,24 +42,40 @@ type EnergyProviderClient struct {
 	tokenExpiry time.Time
 }
 
-// NewEnergyProviderClient creates a new energy provider client
-func NewEnergyProviderClient(cfg *config.Config, authRepo *repository.AuthRepository) (*EnergyProviderClient, error) {
-	encryptor, err := utils.NewEncryptor(cfg.Encryption.Key)
-	if err != nil {
-		return nil, err
-	}
-
+// NewEnergyProviderClient creates a new client for one energy provider
+func NewEnergyProviderClient(settings EnergyProviderSettings, authRepo *repository.AuthRepository, encryptor *utils.Encryptor) *EnergyProviderClient {
 	return &EnergyProviderClient{
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: tracing.NewExternalTransport(http.DefaultTransport),
 		},
-		baseURL:      cfg.Energy.BaseURL,
-		apiKey:       cfg.Energy.APIKey,
-		clientID:     cfg.Energy.ClientID,
-		clientSecret: cfg.Energy.ClientSecret,
+		name:         settings.Name,
+		baseURL:      strings.TrimRight(settings.BaseURL, "/"),
+		apiKey:       settings.APIKey,
+		clientID:     settings.ClientID,
+		clientSecret: settings.ClientSecret,
 		authRepo:     authRepo,
 		encryptor:    encryptor,
-	}, nil
+	}
+}
+
+// Name returns the provider name this client talks to
+func (c *EnergyProviderClient) Name() string {
+	return c.name
+}
+
+// EnergyCredentialName returns the auth credential entry holding a provider's OAuth token.
+// The default provider keeps the original "energy_provider" entry.
+func EnergyCredentialName(providerName string) string {
+	if providerName == models.DefaultEnergyProviderName {
+		return providerName
+	}
+	return models.DefaultEnergyProviderName + ":" + providerName
+}
+
+// HealthCheck checks if the energy provider API is reachable
+func (c *EnergyProviderClient) HealthCheck(ctx context.Context) error {
+	return checkServiceHealth(ctx, c.httpClient, c.baseURL+"/health")
 }
 
 // EnergyTokenResponse represents the OAuth token response from energy provider
@@ -95,6 +122,7 @@ func (c *EnergyProviderClient) GetConsumption(ctx context.Context, buildingID st
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	consumption.Provider = c.name
 	consumption.RetrievedAt = time.Now()
 	return &consumption, nil
 }
@@ -134,6 +162,9 @@ func (c *EnergyProviderClient) GetTariffs(ctx context.Context, region string) (*
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	if tariff.Provider == "" {
+		tariff.Provider = c.name
+	}
 	tariff.RetrievedAt = time.Now()
 	return &tariff, nil
 }
@@ -164,7 +195,7 @@ func (c *EnergyProviderClient) RefreshToken(ctx context.Context) (*models.Extern
 
 	if resp.StatusCode != http.StatusOK {
 		return &models.ExternalTokenRefreshResponse{
-			Provider: "energy_provider",
+			Provider: c.name,
 			Success:  false,
 			Message:  fmt.Sprintf("token refresh failed with status: %d", resp.StatusCode),
 		}, nil
@@ -184,10 +215,10 @@ func (c *EnergyProviderClient) RefreshToken(ctx context.Context) (*models.Extern
 		return nil, fmt.Errorf("failed to encrypt token: %w", err)
 	}
 
-	if err := c.authRepo.UpdateAuthCredentialToken(ctx, "energy_provider", encryptedToken, expiresAt); err != nil {
+	if err := c.authRepo.UpdateAuthCredentialToken(ctx, EnergyCredentialName(c.name), encryptedToken, expiresAt); err != nil {
 		// If credential doesn't exist, create it
 		cred := &models.AuthCredential{
-			ServiceName:    "energy_provider",
+			ServiceName:    EnergyCredentialName(c.name),
 			EncryptedToken: encryptedToken,
 			TokenExpiresAt: &expiresAt,
 		}
@@ -203,7 +234,7 @@ func (c *EnergyProviderClient) RefreshToken(ctx context.Context) (*models.Extern
 	c.mu.Unlock()
 
 	return &models.ExternalTokenRefreshResponse{
-		Provider:  "energy_provider",
+		Provider:  c.name,
 		Success:   true,
 		ExpiresAt: expiresAt,
 		Message:   "Token refreshed successfully",
@@ -221,7 +252,7 @@ func (c *EnergyProviderClient) getAccessToken(ctx context.Context) (string, erro
 	c.mu.RUnlock()
 
 	// Try to get from database
-	cred, err := c.authRepo.FindAuthCredential(ctx, "energy_provider")
+	cred, err := c.authRepo.FindAuthCredential(ctx, EnergyCredentialName(c.name))
 	if err == nil && cred.TokenExpiresAt != nil && time.Now().Before(cred.TokenExpiresAt.Add(-5*time.Minute)) {
 		decryptedToken, err := c.encryptor.Decrypt(cred.EncryptedToken)
 		if err == nil {
@@ -252,9 +283,9 @@ func (c *EnergyProviderClient) getAccessToken(ctx context.Context) (string, erro
 func (c *EnergyProviderClient) handleErrorResponse(resp *http.Response) error {
 	var apiErr models.ExternalAPIError
 	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err == nil {
-		apiErr.Provider = "energy_provider"
+		apiErr.Provider = c.name
 		apiErr.StatusCode = resp.StatusCode
-		return fmt.Errorf("energy provider error: %s (status: %d)", apiErr.Message, apiErr.StatusCode)
+		return fmt.Errorf("energy provider %s error: %s (status: %d)", c.name, apiErr.Message, apiErr.StatusCode)
 	}
-	return fmt.Errorf("energy provider returned status: %d", resp.StatusCode)
+	return fmt.Errorf("energy provider %s returned status: %d", c.name, resp.StatusCode)
 }
@@ -2,34 +2,188 @@
 package integrations
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"security-service/internal/config"
+	"security-service/internal/models"
+	"security-service/internal/tracing"
 )
 
-// NotificationClient handles communication with external notification services
+// NotificationClient routes notifications to the provider configured for each type,
+// failing over to a secondary provider when the primary one fails
 type NotificationClient struct {
-	httpClient *http.Client
-	emailURL   string
-	smsURL     string
-	pushURL    string
+	providers     map[string]NotificationProvider
+	order         []string
+	routes        map[models.NotificationType]notificationRoute
+	healthTimeout time.Duration
+
+	mu    sync.Mutex
+	stats map[string]*providerStats
+}
+
+// notificationRoute holds the provider selection for a notification type
+type notificationRoute struct {
+	primary  string
+	fallback string
 }
 
-// NewNotificationClient creates a new notification client
+// NewNotificationClient creates a new notification client with the configured providers
 func NewNotificationClient(cfg *config.Config) *NotificationClient {
-	return &NotificationClient{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		emailURL: cfg.Notification.EmailURL,
-		smsURL:   cfg.Notification.SMSURL,
-		pushURL:  cfg.Notification.PushURL,
+	httpClient := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: tracing.NewExternalTransport(http.DefaultTransport),
+	}
+	notifCfg := cfg.Notification
+
+	c := &NotificationClient{
+		providers:     make(map[string]NotificationProvider),
+		routes:        make(map[models.NotificationType]notificationRoute),
+		healthTimeout: notifCfg.HealthCheckTimeout,
+		stats:         make(map[string]*providerStats),
+	}
+
+	// The gateway is always available; other providers are registered once configured
+	c.RegisterProvider(NewGatewayProvider(httpClient, notifCfg))
+	if notifCfg.SMTP.Host != "" {
+		c.RegisterProvider(NewSMTPProvider(notifCfg.SMTP))
+	}
+	if notifCfg.SendGrid.APIKey != "" {
+		c.RegisterProvider(NewSendGridProvider(httpClient, notifCfg.SendGrid))
+	}
+	if notifCfg.Twilio.AccountSID != "" {
+		c.RegisterProvider(NewTwilioSMSProvider(httpClient, notifCfg.Twilio))
+	}
+	if notifCfg.FCM.ServerKey != "" {
+		c.RegisterProvider(NewFCMProvider(httpClient, notifCfg.FCM))
+	}
+
+	c.SetRoute(models.NotificationTypeEmail, notifCfg.EmailProvider, notifCfg.EmailFallbackProvider)
+	c.SetRoute(models.NotificationTypeSMS, notifCfg.SMSProvider, notifCfg.SMSFallbackProvider)
+	c.SetRoute(models.NotificationTypePush, notifCfg.PushProvider, notifCfg.PushFallbackProvider)
+
+	return c
+}
+
+// RegisterProvider adds or replaces a notification provider
+func (c *NotificationClient) RegisterProvider(provider NotificationProvider) {
+	if _, exists := c.providers[provider.Name()]; !exists {
+		c.order = append(c.order, provider.Name())
 	}
+	c.providers[provider.Name()] = provider
+}
+
+// SetRoute selects the primary and fallback providers for a notification type.
+// Unknown or unsupported providers are ignored; the gateway is used if no valid primary remains.
+func (c *NotificationClient) SetRoute(notifType models.NotificationType, primary, fallback string) {
+	if !c.canDeliver(primary, notifType) {
+		if primary != "" && primary != ProviderGateway {
+			log.Printf("Notification provider %q is not configured for %s, using %s", primary, notifType, ProviderGateway)
+		}
+		primary = ProviderGateway
+	}
+	if fallback != "" && (fallback == primary || !c.canDeliver(fallback, notifType)) {
+		log.Printf("Ignoring notification fallback provider %q for %s", fallback, notifType)
+		fallback = ""
+	}
+
+	c.routes[notifType] = notificationRoute{primary: primary, fallback: fallback}
+}
+
+// canDeliver checks that a provider is registered and supports the notification type
+func (c *NotificationClient) canDeliver(name string, notifType models.NotificationType) bool {
+	provider, ok := c.providers[name]
+	return ok && supportsType(provider, notifType)
+}
+
+// Send delivers a message through its type's primary provider, failing over to the
+// fallback provider on error. It returns the name of the provider that delivered it.
+func (c *NotificationClient) Send(ctx context.Context, msg *NotificationMessage) (string, error) {
+	route, ok := c.routes[msg.Type]
+	if !ok {
+		return "", fmt.Errorf("unsupported notification type: %s", msg.Type)
+	}
+
+	primaryErr := c.sendWith(ctx, route.primary, msg)
+	if primaryErr == nil {
+		return route.primary, nil
+	}
+	if route.fallback == "" {
+		return route.primary, primaryErr
+	}
+
+	log.Printf("Notification provider %s failed, failing over to %s: %v", route.primary, route.fallback, primaryErr)
+	if err := c.sendWith(ctx, route.fallback, msg); err != nil {
+		return route.fallback, fmt.Errorf("%s: %v; fallback %s: %w", route.primary, primaryErr, route.fallback, err)
+	}
+
+	return route.fallback, nil
+}
+
+// sendWith delivers a message through a named provider and records failures
+func (c *NotificationClient) sendWith(ctx context.Context, name string, msg *NotificationMessage) error {
+	err := c.providers[name].Send(ctx, msg)
+	if err != nil {
+		now := time.Now()
+		c.mu.Lock()
+		c.stats[name] = &providerStats{lastFailure: &now, lastFailureMsg: err.Error()}
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// ProvidersStatus checks the health of every registered provider and reports routing
+func (c *NotificationClient) ProvidersStatus(ctx context.Context) *models.NotificationProvidersStatus {
+	health := make([]models.NotificationProviderHealth, len(c.order))
+
+	var wg sync.WaitGroup
+	for i, name := range c.order {
+		wg.Add(1)
+		go func(i int, provider NotificationProvider) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, c.healthTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := provider.HealthCheck(checkCtx)
+
+			entry := models.NotificationProviderHealth{
+				Name:      provider.Name(),
+				Types:     provider.Types(),
+				Healthy:   err == nil,
+				LatencyMs: time.Since(start).Milliseconds(),
+				CheckedAt: time.Now(),
+			}
+			if err != nil {
+				entry.Error = err.Error()
+			}
+
+			c.mu.Lock()
+			if stats, ok := c.stats[provider.Name()]; ok {
+				entry.LastFailure = stats.lastFailure
+				entry.LastFailureMsg = stats.lastFailureMsg
+			}
+			c.mu.Unlock()
+
+			health[i] = entry
+		}(i, c.providers[name])
+	}
+	wg.Wait()
+
+	routes := make([]models.NotificationRouteStatus, 0, len(c.routes))
+	for _, notifType := range []models.NotificationType{models.NotificationTypeEmail, models.NotificationTypeSMS, models.NotificationTypePush} {
+		if route, ok := c.routes[notifType]; ok {
+			routes = append(routes, models.NotificationRouteStatus{Type: notifType, Primary: route.primary, Fallback: route.fallback})
+		}
+	}
+
+	return &models.NotificationProvidersStatus{Providers: health, Routes: routes}
 }
 
 // EmailRequest represents the request body for sending email
@@ -61,90 +215,54 @@ type NotificationResponse struct {
 	Error     string `json:"error,omitempty"`
 }
 
-// SendEmail sends an email notification
-func (c *NotificationClient) SendEmail(ctx context.Context, to, subject, body string) error {
-	req := EmailRequest{
-		To:      to,
-		Subject: subject,
-		Body:    body,
-		IsHTML:  false,
+// HealthCheck reports an error when any provider currently selected for a notification type is unhealthy
+func (c *NotificationClient) HealthCheck(ctx context.Context) error {
+	status := c.ProvidersStatus(ctx)
+
+	routed := make(map[string]bool)
+	for _, route := range status.Routes {
+		routed[route.Primary] = true
 	}
 
-	return c.sendRequest(ctx, c.emailURL, req)
+	var failures []string
+	for _, provider := range status.Providers {
+		if routed[provider.Name] && !provider.Healthy {
+			failures = append(failures, fmt.Sprintf("%s: %s", provider.Name, provider.Error))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("unhealthy notification providers: %s", strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// SendEmail sends an email notification
+func (c *NotificationClient) SendEmail(ctx context.Context, to, subject, body string) error {
+	_, err := c.Send(ctx, &NotificationMessage{Type: models.NotificationTypeEmail, Recipient: to, Subject: subject, Body: body})
+	return err
 }
 
 // SendEmailHTML sends an HTML email notification
 func (c *NotificationClient) SendEmailHTML(ctx context.Context, to, subject, body string) error {
-	req := EmailRequest{
-		To:      to,
-		Subject: subject,
-		Body:    body,
-		IsHTML:  true,
-	}
-
-	return c.sendRequest(ctx, c.emailURL, req)
+	_, err := c.Send(ctx, &NotificationMessage{Type: models.NotificationTypeEmail, Recipient: to, Subject: subject, Body: body, IsHTML: true})
+	return err
 }
 
 // SendSMS sends an SMS notification
 func (c *NotificationClient) SendSMS(ctx context.Context, phoneNumber, message string) error {
-	req := SMSRequest{
-		PhoneNumber: phoneNumber,
-		Message:     message,
-	}
-
-	return c.sendRequest(ctx, c.smsURL, req)
+	_, err := c.Send(ctx, &NotificationMessage{Type: models.NotificationTypeSMS, Recipient: phoneNumber, Body: message})
+	return err
 }
 
 // SendPush sends a push notification
 func (c *NotificationClient) SendPush(ctx context.Context, deviceToken, title, body string) error {
-	req := PushRequest{
-		DeviceToken: deviceToken,
-		Title:       title,
-		Body:        body,
-	}
-
-	return c.sendRequest(ctx, c.pushURL, req)
+	_, err := c.Send(ctx, &NotificationMessage{Type: models.NotificationTypePush, Recipient: deviceToken, Subject: title, Body: body})
+	return err
 }
 
 // SendPushWithData sends a push notification with additional data
 func (c *NotificationClient) SendPushWithData(ctx context.Context, deviceToken, title, body string, data map[string]string) error {
-	req := PushRequest{
-		DeviceToken: deviceToken,
-		Title:       title,
-		Body:        body,
-		Data:        data,
-	}
-
-	return c.sendRequest(ctx, c.pushURL, req)
-}
-
-// sendRequest sends a request to the notification service
-func (c *NotificationClient) sendRequest(ctx context.Context, url string, payload interface{}) error {
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
-		var notifResp NotificationResponse
-		if err := json.NewDecoder(resp.Body).Decode(&notifResp); err == nil && notifResp.Error != "" {
-			return fmt.Errorf("notification service error: %s", notifResp.Error)
-		}
-		return fmt.Errorf("notification service returned status: %d", resp.StatusCode)
-	}
-
-	return nil
+	_, err := c.Send(ctx, &NotificationMessage{Type: models.NotificationTypePush, Recipient: deviceToken, Subject: title, Body: body, Data: data})
+	return err
 }
@@ -0,0 +1,501 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"security-service/internal/config"
+	"security-service/internal/models"
+)
+
+// Notification provider names
+const (
+	ProviderGateway  = "gateway"
+	ProviderSMTP     = "smtp"
+	ProviderSendGrid = "sendgrid"
+	ProviderTwilio   = "twilio"
+	ProviderFCM      = "fcm"
+)
+
+// ErrPushTokenRejected reports that a push provider rejected the device token as invalid or no
+// longer registered, so sending to it again cannot succeed
+var ErrPushTokenRejected = errors.New("push token rejected")
+
+// fcmRejectedTokenErrors are the FCM per-message errors of tokens that will never be delivered to
+var fcmRejectedTokenErrors = map[string]bool{
+	"NotRegistered":       true,
+	"InvalidRegistration": true,
+	"MismatchSenderId":    true,
+}
+
+// NotificationMessage is a provider-agnostic notification to deliver
+type NotificationMessage struct {
+	Type      models.NotificationType
+	Recipient string // email address, phone number, or device token
+	Subject   string
+	Body      string
+	IsHTML    bool
+	Data      map[string]string
+}
+
+// NotificationProvider delivers notifications through a specific backend
+type NotificationProvider interface {
+	// Name returns the provider name used in configuration
+	Name() string
+	// Types returns the notification types the provider can deliver
+	Types() []models.NotificationType
+	// Send delivers a message
+	Send(ctx context.Context, msg *NotificationMessage) error
+	// HealthCheck verifies the provider is reachable and configured
+	HealthCheck(ctx context.Context) error
+}
+
+// supportsType checks whether a provider can deliver a notification type
+func supportsType(p NotificationProvider, t models.NotificationType) bool {
+	for _, supported := range p.Types() {
+		if supported == t {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHTTPStatus converts a non-2xx response into an error including the response body
+func checkHTTPStatus(resp *http.Response, provider string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	var body bytes.Buffer
+	_, _ = body.ReadFrom(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s returned status %d: %s", provider, resp.StatusCode, strings.TrimSpace(body.String()))
+}
+
+// GatewayProvider sends notifications through the internal notification gateway URLs
+type GatewayProvider struct {
+	httpClient *http.Client
+	emailURL   string
+	smsURL     string
+	pushURL    string
+}
+
+// NewGatewayProvider creates a notification gateway provider
+func NewGatewayProvider(httpClient *http.Client, cfg config.NotificationConfig) *GatewayProvider {
+	return &GatewayProvider{
+		httpClient: httpClient,
+		emailURL:   cfg.EmailURL,
+		smsURL:     cfg.SMSURL,
+		pushURL:    cfg.PushURL,
+	}
+}
+
+// Name returns the provider name
+func (p *GatewayProvider) Name() string { return ProviderGateway }
+
+// Types returns the supported notification types
+func (p *GatewayProvider) Types() []models.NotificationType {
+	return []models.NotificationType{models.NotificationTypeEmail, models.NotificationTypeSMS, models.NotificationTypePush}
+}
+
+// Send posts the message to the gateway endpoint for its type
+func (p *GatewayProvider) Send(ctx context.Context, msg *NotificationMessage) error {
+	switch msg.Type {
+	case models.NotificationTypeEmail:
+		_, err := p.post(ctx, p.emailURL, EmailRequest{To: msg.Recipient, Subject: msg.Subject, Body: msg.Body, IsHTML: msg.IsHTML})
+		return err
+	case models.NotificationTypeSMS:
+		_, err := p.post(ctx, p.smsURL, SMSRequest{PhoneNumber: msg.Recipient, Message: msg.Body})
+		return err
+	case models.NotificationTypePush:
+		status, err := p.post(ctx, p.pushURL, PushRequest{DeviceToken: msg.Recipient, Title: msg.Subject, Body: msg.Body, Data: msg.Data})
+		// The gateway answers 404 or 410 for device tokens the push service no longer knows
+		if err != nil && (status == http.StatusNotFound || status == http.StatusGone) {
+			return fmt.Errorf("%v: %w", err, ErrPushTokenRejected)
+		}
+		return err
+	}
+	return fmt.Errorf("unsupported notification type: %s", msg.Type)
+}
+
+// HealthCheck verifies the gateway host accepts connections
+func (p *GatewayProvider) HealthCheck(ctx context.Context) error {
+	return dialURL(ctx, p.emailURL)
+}
+
+// post sends a JSON payload to the gateway and returns the response status, or 0 if no
+// response was received
+func (p *GatewayProvider) post(ctx context.Context, url string, payload interface{}) (int, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
+		var notifResp NotificationResponse
+		if err := json.NewDecoder(resp.Body).Decode(&notifResp); err == nil && notifResp.Error != "" {
+			return resp.StatusCode, fmt.Errorf("notification service error: %s", notifResp.Error)
+		}
+		return resp.StatusCode, fmt.Errorf("notification service returned status: %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// dialURL opens and closes a TCP connection to the host of a URL
+func dialURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	host := parsed.Host
+	if parsed.Port() == "" {
+		port := "80"
+		if parsed.Scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(parsed.Hostname(), port)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// SMTPProvider sends email through an SMTP server
+type SMTPProvider struct {
+	cfg config.SMTPConfig
+}
+
+// NewSMTPProvider creates an SMTP email provider
+func NewSMTPProvider(cfg config.SMTPConfig) *SMTPProvider {
+	return &SMTPProvider{cfg: cfg}
+}
+
+// Name returns the provider name
+func (p *SMTPProvider) Name() string { return ProviderSMTP }
+
+// Types returns the supported notification types
+func (p *SMTPProvider) Types() []models.NotificationType {
+	return []models.NotificationType{models.NotificationTypeEmail}
+}
+
+// Send delivers the email over SMTP
+func (p *SMTPProvider) Send(ctx context.Context, msg *NotificationMessage) error {
+	if msg.Type != models.NotificationTypeEmail {
+		return fmt.Errorf("unsupported notification type: %s", msg.Type)
+	}
+
+	contentType := "text/plain"
+	if msg.IsHTML {
+		contentType = "text/html"
+	}
+
+	// Header values must not contain line breaks, which would allow header injection
+	headerValue := strings.NewReplacer("\r", " ", "\n", " ").Replace
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", headerValue(p.cfg.From))
+	fmt.Fprintf(&body, "To: %s\r\n", headerValue(msg.Recipient))
+	fmt.Fprintf(&body, "Subject: %s\r\n", headerValue(msg.Subject))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&body, "Content-Type: %s; charset=\"UTF-8\"\r\n\r\n", contentType)
+	body.WriteString(msg.Body)
+
+	var auth smtp.Auth
+	if p.cfg.Username != "" {
+		auth = smtp.PlainAuth("", p.cfg.Username, p.cfg.Password, p.cfg.Host)
+	}
+
+	// net/smtp has no context support; run the send so cancellation is still honoured
+	addr := net.JoinHostPort(p.cfg.Host, strconv.Itoa(p.cfg.Port))
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, p.cfg.From, []string{msg.Recipient}, []byte(body.String()))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HealthCheck connects to the SMTP server and exchanges a greeting
+func (p *SMTPProvider) HealthCheck(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(p.cfg.Host, strconv.Itoa(p.cfg.Port)))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, p.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	return client.Quit()
+}
+
+// SendGridProvider sends email through the SendGrid v3 API
+type SendGridProvider struct {
+	httpClient *http.Client
+	cfg        config.SendGridConfig
+}
+
+// NewSendGridProvider creates a SendGrid email provider
+func NewSendGridProvider(httpClient *http.Client, cfg config.SendGridConfig) *SendGridProvider {
+	return &SendGridProvider{httpClient: httpClient, cfg: cfg}
+}
+
+// Name returns the provider name
+func (p *SendGridProvider) Name() string { return ProviderSendGrid }
+
+// Types returns the supported notification types
+func (p *SendGridProvider) Types() []models.NotificationType {
+	return []models.NotificationType{models.NotificationTypeEmail}
+}
+
+// Send delivers the email via the SendGrid mail send endpoint
+func (p *SendGridProvider) Send(ctx context.Context, msg *NotificationMessage) error {
+	if msg.Type != models.NotificationTypeEmail {
+		return fmt.Errorf("unsupported notification type: %s", msg.Type)
+	}
+
+	contentType := "text/plain"
+	if msg.IsHTML {
+		contentType = "text/html"
+	}
+
+	payload := map[string]interface{}{
+		"personalizations": []map[string]interface{}{
+			{"to": []map[string]string{{"email": msg.Recipient}}},
+		},
+		"from":    map[string]string{"email": p.cfg.From},
+		"subject": msg.Subject,
+		"content": []map[string]string{{"type": contentType, "value": msg.Body}},
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.BaseURL+"/v3/mail/send", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkHTTPStatus(resp, "sendgrid")
+}
+
+// HealthCheck verifies the API key against the SendGrid scopes endpoint
+func (p *SendGridProvider) HealthCheck(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.BaseURL+"/v3/scopes", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkHTTPStatus(resp, "sendgrid")
+}
+
+// TwilioSMSProvider sends SMS through the Twilio Messages API
+type TwilioSMSProvider struct {
+	httpClient *http.Client
+	cfg        config.TwilioConfig
+}
+
+// NewTwilioSMSProvider creates a Twilio SMS provider
+func NewTwilioSMSProvider(httpClient *http.Client, cfg config.TwilioConfig) *TwilioSMSProvider {
+	return &TwilioSMSProvider{httpClient: httpClient, cfg: cfg}
+}
+
+// Name returns the provider name
+func (p *TwilioSMSProvider) Name() string { return ProviderTwilio }
+
+// Types returns the supported notification types
+func (p *TwilioSMSProvider) Types() []models.NotificationType {
+	return []models.NotificationType{models.NotificationTypeSMS}
+}
+
+// Send delivers the SMS via Twilio
+func (p *TwilioSMSProvider) Send(ctx context.Context, msg *NotificationMessage) error {
+	if msg.Type != models.NotificationTypeSMS {
+		return fmt.Errorf("unsupported notification type: %s", msg.Type)
+	}
+
+	form := url.Values{}
+	form.Set("To", msg.Recipient)
+	form.Set("From", p.cfg.From)
+	form.Set("Body", msg.Body)
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", p.cfg.BaseURL, p.cfg.AccountSID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.cfg.AccountSID, p.cfg.AuthToken)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkHTTPStatus(resp, "twilio")
+}
+
+// HealthCheck verifies the account credentials against the Twilio account endpoint
+func (p *TwilioSMSProvider) HealthCheck(ctx context.Context) error {
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s.json", p.cfg.BaseURL, p.cfg.AccountSID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(p.cfg.AccountSID, p.cfg.AuthToken)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkHTTPStatus(resp, "twilio")
+}
+
+// FCMProvider sends push notifications through Firebase Cloud Messaging
+type FCMProvider struct {
+	httpClient *http.Client
+	cfg        config.FCMConfig
+}
+
+// NewFCMProvider creates an FCM push provider
+func NewFCMProvider(httpClient *http.Client, cfg config.FCMConfig) *FCMProvider {
+	return &FCMProvider{httpClient: httpClient, cfg: cfg}
+}
+
+// Name returns the provider name
+func (p *FCMProvider) Name() string { return ProviderFCM }
+
+// Types returns the supported notification types
+func (p *FCMProvider) Types() []models.NotificationType {
+	return []models.NotificationType{models.NotificationTypePush}
+}
+
+// Send delivers the push notification via FCM
+func (p *FCMProvider) Send(ctx context.Context, msg *NotificationMessage) error {
+	if msg.Type != models.NotificationTypePush {
+		return fmt.Errorf("unsupported notification type: %s", msg.Type)
+	}
+
+	payload := map[string]interface{}{
+		"to": msg.Recipient,
+		"notification": map[string]string{
+			"title": msg.Subject,
+			"body":  msg.Body,
+		},
+	}
+	if len(msg.Data) > 0 {
+		payload["data"] = msg.Data
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "key="+p.cfg.ServerKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkHTTPStatus(resp, "fcm"); err != nil {
+		return err
+	}
+
+	// FCM reports per-message failures with a 200 status
+	var result struct {
+		Failure int `json:"failure"`
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && result.Failure > 0 {
+		if len(result.Results) > 0 && result.Results[0].Error != "" {
+			if fcmRejectedTokenErrors[result.Results[0].Error] {
+				return fmt.Errorf("fcm delivery failed: %s: %w", result.Results[0].Error, ErrPushTokenRejected)
+			}
+			return fmt.Errorf("fcm delivery failed: %s", result.Results[0].Error)
+		}
+		return errors.New("fcm delivery failed")
+	}
+
+	return nil
+}
+
+// HealthCheck verifies the FCM endpoint is reachable.
+// FCM has no side-effect free endpoint to validate the server key, so only connectivity is checked.
+func (p *FCMProvider) HealthCheck(ctx context.Context) error {
+	return dialURL(ctx, p.cfg.URL)
+}
+
+// providerStats tracks the most recent delivery failure of a provider
+type providerStats struct {
+	lastFailure    *time.Time
+	lastFailureMsg string
+}
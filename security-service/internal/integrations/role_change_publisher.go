@@ -0,0 +1,104 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"security-service/internal/config"
+	"security-service/internal/models"
+	"security-service/internal/tracing"
+	"shared/signing"
+)
+
+// ServiceKeyHeader carries the shared service key, which internal endpoints accept in place of
+// a request signature only while INTERNAL_ACCEPT_SERVICE_KEY is enabled
+const ServiceKeyHeader = "X-Service-Key"
+
+// ServiceName identifies this service in signed internal requests
+const ServiceName = "security-service"
+
+// RoleChangePublisher notifies other services of role and account changes so they can
+// drop cached token validations
+type RoleChangePublisher struct {
+	httpClient *http.Client
+	webhooks   []string
+}
+
+// NewRoleChangePublisher creates a new role change publisher
+func NewRoleChangePublisher(cfg *config.Config) *RoleChangePublisher {
+	return &RoleChangePublisher{
+		httpClient: tracing.WrapClient(signing.NewClient(5*time.Second, ServiceName, cfg.Internal.ServiceSecret)),
+		webhooks:   cfg.Internal.RoleChangeWebhooks,
+	}
+}
+
+// Publish sends a role change event to every configured webhook in the background.
+// Delivery is best-effort: subscribers also expire cached validations on a short TTL.
+func (p *RoleChangePublisher) Publish(eventType, userID, role string) {
+	p.publish(&models.RoleChangeEvent{
+		Type:       eventType,
+		UserID:     userID,
+		Role:       role,
+		OccurredAt: time.Now(),
+	})
+}
+
+// PublishGroup sends a group membership or group change event to every configured webhook
+func (p *RoleChangePublisher) PublishGroup(eventType, userID, group string) {
+	p.publish(&models.RoleChangeEvent{
+		Type:       eventType,
+		UserID:     userID,
+		Group:      group,
+		OccurredAt: time.Now(),
+	})
+}
+
+// publish encodes an event and posts it to the webhooks in the background
+func (p *RoleChangePublisher) publish(event *models.RoleChangeEvent) {
+	if p == nil || len(p.webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode role change event: %v", err)
+		return
+	}
+
+	for _, webhook := range p.webhooks {
+		go func(url string) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := p.send(ctx, url, body); err != nil {
+				log.Printf("Failed to publish role change event to %s: %v", url, err)
+			}
+		}(webhook)
+	}
+}
+
+// send posts an encoded event to a single webhook
+func (p *RoleChangePublisher) send(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -2,23 +2,59 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"io"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"security-service/internal/models"
+	"security-service/internal/tenant"
 	"security-service/pkg/utils"
+	"shared/signing"
 )
 
+// AuditRecorder stores audit log entries
+type AuditRecorder interface {
+	Create(ctx context.Context, log *models.AuditLog) (*models.AuditLog, error)
+}
+
 // AuthMiddleware creates a new authentication middleware
 type AuthMiddleware struct {
 	jwtManager *utils.JWTManager
+	audit      AuditRecorder
+
+	// Internal requests must be signed; the plain service key is accepted only when enabled
+	serviceVerifier  *signing.Verifier
+	serviceKey       string
+	acceptServiceKey bool
+}
+
+// NewAuthMiddleware creates a new auth middleware instance.
+// serviceKey is the shared secret internal service calls are signed with; it verifies them only
+// once ConfigureServiceAuth enables it in dev mode.
+// audit records requests made with impersonation tokens; nil disables it.
+func NewAuthMiddleware(jwtManager *utils.JWTManager, serviceKey string, audit AuditRecorder) *AuthMiddleware {
+	return &AuthMiddleware{
+		jwtManager:      jwtManager,
+		audit:           audit,
+		serviceVerifier: signing.NewVerifier(nil, serviceKey, false, signing.DefaultWindow),
+		serviceKey:      serviceKey,
+	}
 }
 
-// NewAuthMiddleware creates a new auth middleware instance
-func NewAuthMiddleware(jwtManager *utils.JWTManager) *AuthMiddleware {
-	return &AuthMiddleware{jwtManager: jwtManager}
+// ConfigureServiceAuth sets per-service signing secrets, whether services without one may sign
+// with the shared service key (dev mode only), the allowed clock skew of signed requests and
+// whether the unsigned service key is still accepted
+func (m *AuthMiddleware) ConfigureServiceAuth(secrets map[string]string, sharedSecretDevMode bool, window time.Duration, acceptServiceKey bool) {
+	m.serviceVerifier = signing.NewVerifier(secrets, m.serviceKey, sharedSecretDevMode, window)
+	m.acceptServiceKey = acceptServiceKey
 }
 
 // RequireAuth validates the access token and sets user info in context
@@ -58,6 +94,16 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
+		// Kiosk tokens may only read dashboards in the analytics service
+		if claims.Kiosk != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				"Kiosk tokens cannot access this endpoint",
+				"",
+			))
+			return
+		}
+
 		// Set user info in context
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
@@ -65,7 +111,78 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		c.Set("roles", claims.Roles)
 		c.Set("token", token)
 
+		// Repository queries of the request are limited to the user's organization
+		if claims.Tenant != nil {
+			c.Set("tenant", claims.Tenant)
+			c.Request = c.Request.WithContext(tenant.WithScope(c.Request.Context(), claims.Tenant))
+		}
+
+		if len(claims.Masks) > 0 {
+			defer maskResponses(c, claims.Masks)()
+		}
+
+		if claims.Impersonation == nil {
+			c.Next()
+			return
+		}
+
+		c.Set("impersonation", claims.Impersonation)
 		c.Next()
+		m.auditImpersonatedRequest(c, claims)
+	}
+}
+
+// ForbidImpersonation rejects requests made with an impersonation token
+func (m *AuthMiddleware) ForbidImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetImpersonation(c) != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				"This action is not allowed while impersonating a user",
+				"",
+			))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// auditImpersonatedRequest records a request made by an admin acting as another user
+func (m *AuthMiddleware) auditImpersonatedRequest(c *gin.Context, claims *utils.CustomClaims) {
+	if m.audit == nil {
+		return
+	}
+
+	status := "SUCCESS"
+	if c.Writer.Status() >= http.StatusBadRequest {
+		status = "FAILURE"
+	}
+
+	auditLog := &models.AuditLog{
+		ID:         primitive.NewObjectID(),
+		UserID:     claims.UserID,
+		Username:   claims.Username,
+		Service:    "security-service",
+		Action:     "IMPERSONATED_REQUEST",
+		Resource:   "api",
+		ResourceID: c.Request.URL.Path,
+		Details: map[string]interface{}{
+			"impersonatorId":       claims.Impersonation.ImpersonatorID,
+			"impersonatorUsername": claims.Impersonation.ImpersonatorUsername,
+			"reason":               claims.Impersonation.Reason,
+			"statusCode":           c.Writer.Status(),
+		},
+		IPAddress:   GetClientIP(c),
+		UserAgent:   GetUserAgent(c),
+		Status:      status,
+		Timestamp:   time.Now(),
+		RequestPath: c.Request.URL.Path,
+		Method:      c.Request.Method,
+	}
+
+	if _, err := m.audit.Create(c.Request.Context(), auditLog); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
 	}
 }
 
@@ -96,7 +213,7 @@ func (m *AuthMiddleware) RequireRoles(roles ...string) gin.HandlerFunc {
 		hasRole := false
 		for _, requiredRole := range roles {
 			for _, userRole := range userRolesList {
-				if userRole == requiredRole || userRole == "admin" {
+				if userRole == requiredRole || userRole == "admin" || userRole == models.RoleSuperAdmin {
 					hasRole = true
 					break
 				}
@@ -124,6 +241,63 @@ func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
 	return m.RequireRoles("admin")
 }
 
+// RequireSuperAdmin restricts a route to super admins. Unlike other role checks, the admin
+// role does not grant access, since organization admins are admins too.
+func (m *AuthMiddleware) RequireSuperAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, role := range GetUserRoles(c) {
+			if role == models.RoleSuperAdmin {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, models.NewErrorResponse(
+			models.ErrCodeForbidden,
+			"Insufficient permissions",
+			"Required roles: "+models.RoleSuperAdmin,
+		))
+	}
+}
+
+// RequireServiceKey restricts a route to internal services that sign their requests with
+// their service secret. Replayed requests are rejected.
+func (m *AuthMiddleware) RequireServiceKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m.acceptServiceKey && m.serviceKey != "" {
+			key := c.GetHeader("X-Service-Key")
+			if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(m.serviceKey)) == 1 {
+				c.Next()
+				return
+			}
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.NewErrorResponse(
+				models.ErrCodeValidationFailed,
+				"Failed to read request body",
+				err.Error(),
+			))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		service, err := m.serviceVerifier.Verify(c.Request, body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.NewErrorResponse(
+				models.ErrCodeForbidden,
+				"Valid service signature is required",
+				err.Error(),
+			))
+			return
+		}
+
+		c.Set("callerService", service)
+		c.Next()
+	}
+}
+
 // OptionalAuth validates the token if present but doesn't require it
 func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -140,7 +314,7 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 		}
 
 		claims, err := m.jwtManager.ValidateAccessToken(token)
-		if err != nil {
+		if err != nil || claims.Kiosk != nil {
 			c.Next()
 			return
 		}
@@ -152,6 +326,11 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 		c.Set("roles", claims.Roles)
 		c.Set("token", token)
 
+		if claims.Tenant != nil {
+			c.Set("tenant", claims.Tenant)
+			c.Request = c.Request.WithContext(tenant.WithScope(c.Request.Context(), claims.Tenant))
+		}
+
 		c.Next()
 	}
 }
@@ -192,11 +371,30 @@ func GetToken(c *gin.Context) string {
 	return token.(string)
 }
 
+// GetImpersonation retrieves the impersonation details from context, or nil for regular tokens
+func GetImpersonation(c *gin.Context) *models.Impersonation {
+	impersonation, exists := c.Get("impersonation")
+	if !exists {
+		return nil
+	}
+	return impersonation.(*models.Impersonation)
+}
+
+// GetTenant retrieves the organization scope of the user from context, or nil when the user
+// is not limited to an organization
+func GetTenant(c *gin.Context) *models.TenantScope {
+	scope, exists := c.Get("tenant")
+	if !exists {
+		return nil
+	}
+	return scope.(*models.TenantScope)
+}
+
 // HasRole checks if the user has a specific role
 func HasRole(c *gin.Context, role string) bool {
 	roles := GetUserRoles(c)
 	for _, r := range roles {
-		if r == role || r == "admin" {
+		if r == role || r == "admin" || r == models.RoleSuperAdmin {
 			return true
 		}
 	}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -23,6 +27,7 @@ type AuditLog struct {
 	Timestamp   time.Time              `bson:"timestamp" json:"timestamp"`
 	RequestPath string                 `bson:"request_path" json:"requestPath"`
 	Method      string                 `bson:"method" json:"method"` // HTTP method
+	OrgID       string                 `bson:"org_id,omitempty" json:"orgId,omitempty"`
 }
 
 // AuditLogCreateRequest represents the request body for creating an audit log
@@ -45,7 +50,7 @@ type AuditLogCreateRequest struct {
 // AuditLogQueryParams represents the query parameters for filtering audit logs
 type AuditLogQueryParams struct {
 	From     time.Time `form:"from"`
-	To       time.Time `form:"to"`
+	To       time.Time `form:"to" binding:"omitempty,gtfield=From"`
 	UserID   string    `form:"userId"`
 	Service  string    `form:"service"`
 	Action   string    `form:"action"`
@@ -103,3 +108,108 @@ type PaginatedAuditLogsResponse struct {
 	Limit      int                 `json:"limit"`
 	TotalPages int                 `json:"totalPages"`
 }
+
+// CursorAuditLogsResponse represents a cursor-paginated list of audit logs
+type CursorAuditLogsResponse struct {
+	Logs       []*AuditLogResponse `json:"logs"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+	HasMore    bool                `json:"hasMore"`
+	Limit      int                 `json:"limit"`
+}
+
+// AuditLogCursor identifies a position in the audit log ordered by timestamp and ID (newest first)
+type AuditLogCursor struct {
+	Timestamp time.Time
+	ID        primitive.ObjectID
+}
+
+// Audit log export formats
+const (
+	AuditExportFormatNDJSON = "ndjson"
+	AuditExportFormatCSV    = "csv"
+)
+
+// Access report categories group the audited actions of a building access report
+const (
+	AccessCategoryLogin          = "LOGIN"
+	AccessCategoryDeviceCommand  = "DEVICE_COMMAND"
+	AccessCategoryReportDownload = "REPORT_DOWNLOAD"
+	AccessCategoryDataAccess     = "DATA_ACCESS"
+)
+
+// AccessCategory returns the access report category of an audit log
+func (a *AuditLog) AccessCategory() string {
+	switch {
+	case a.Action == "LOGIN":
+		return AccessCategoryLogin
+	case a.Resource == "command" || a.Action == "APPLY_OPTIMIZATION" || a.Action == "RESUME_OPTIMIZATION":
+		return AccessCategoryDeviceCommand
+	case a.Action == "DOWNLOAD_REPORT":
+		return AccessCategoryReportDownload
+	default:
+		return AccessCategoryDataAccess
+	}
+}
+
+// AccessReportQueryParams represents the query parameters of a building access report
+type AccessReportQueryParams struct {
+	BuildingID string `form:"buildingId" binding:"required"`
+	Period     string `form:"period"` // 30d, 4w or 2024-06; the last 30 days by default
+	Format     string `form:"format" binding:"omitempty,oneof=csv json"`
+}
+
+// AccessReport summarizes who accessed the data and controls of a building in a period,
+// grouped by user and access category
+type AccessReport struct {
+	BuildingID  string               `json:"buildingId"`
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	GeneratedAt time.Time            `json:"generatedAt"`
+	TotalEvents int                  `json:"totalEvents"`
+	Users       int                  `json:"users"`
+	Entries     []*AccessReportEntry `json:"entries"`
+}
+
+// AccessReportEntry is the activity of one user in one access category
+type AccessReportEntry struct {
+	UserID      string    `json:"userId"`
+	Username    string    `json:"username"`
+	Category    string    `json:"category"`
+	Actions     []string  `json:"actions"` // Distinct audited actions, e.g. SEND_COMMAND
+	Events      int       `json:"events"`
+	Failures    int       `json:"failures"`
+	FirstAccess time.Time `json:"firstAccess"`
+	LastAccess  time.Time `json:"lastAccess"`
+	IPAddresses []string  `json:"ipAddresses"`
+}
+
+// Encode serializes the cursor into an opaque URL-safe token
+func (c AuditLogCursor) Encode() string {
+	raw := strconv.FormatInt(c.Timestamp.UnixNano(), 10) + ":" + c.ID.Hex()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeAuditLogCursor parses a cursor token produced by AuditLogCursor.Encode
+func DecodeAuditLogCursor(token string) (*AuditLogCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid cursor")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	id, err := primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &AuditLogCursor{Timestamp: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
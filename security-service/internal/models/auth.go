@@ -36,10 +36,16 @@ type RefreshTokenResponse struct {
 
 // TokenValidationResponse represents the token validation response
 type TokenValidationResponse struct {
-	Valid   bool     `json:"valid"`
-	UserID  string   `json:"userId,omitempty"`
-	Roles   []string `json:"roles,omitempty"`
-	Message string   `json:"message,omitempty"`
+	Valid         bool           `json:"valid"`
+	UserID        string         `json:"userId,omitempty"`
+	Roles         []string       `json:"roles,omitempty"`
+	Groups        []string       `json:"groups,omitempty"`
+	Message       string         `json:"message,omitempty"`
+	Impersonation *Impersonation `json:"impersonation,omitempty"`
+	Kiosk         *KioskScope    `json:"kiosk,omitempty"`
+	Tenant        *TenantScope   `json:"tenant,omitempty"`
+	// Masks lists the response fields the caller may not see and how to mask them
+	Masks map[string]string `json:"masks,omitempty"`
 }
 
 // CheckPermissionRequest represents the permission check request body
@@ -87,10 +93,94 @@ type TokenClaims struct {
 
 // UserInfoResponse represents the user info response for /auth/user-info
 type UserInfoResponse struct {
-	ID        string   `json:"id"`
-	Username  string   `json:"username"`
-	Email     string   `json:"email"`
-	FirstName string   `json:"firstName"`
-	LastName  string   `json:"lastName"`
-	Roles     []string `json:"roles"`
+	ID            string         `json:"id"`
+	Username      string         `json:"username"`
+	Email         string         `json:"email"`
+	FirstName     string         `json:"firstName"`
+	LastName      string         `json:"lastName"`
+	Roles         []string       `json:"roles"`
+	Groups        []string       `json:"groups,omitempty"`
+	Impersonation *Impersonation `json:"impersonation,omitempty"`
+}
+
+// Impersonation identifies the admin acting as a user with an impersonation token.
+// Banner is display text for clients to show for as long as the token is in use.
+type Impersonation struct {
+	ImpersonatorID       string `json:"impersonatorId"`
+	ImpersonatorUsername string `json:"impersonatorUsername"`
+	Reason               string `json:"reason"`
+	Banner               string `json:"banner"`
+}
+
+// ImpersonateRequest represents the impersonation request body
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// ImpersonateResponse represents a short-lived access token for acting as another user.
+// No refresh token is issued, so impersonation ends when the token expires.
+type ImpersonateResponse struct {
+	AccessToken   string         `json:"accessToken"`
+	TokenType     string         `json:"tokenType"`
+	ExpiresIn     int64          `json:"expiresIn"`
+	UserID        string         `json:"userId"`
+	Roles         []string       `json:"roles"`
+	Impersonation *Impersonation `json:"impersonation"`
+}
+
+// DelegateTokenRequest asks for a token of a user on whose behalf a service queued work, in the
+// organization the work was queued in
+type DelegateTokenRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	OrgID  string `json:"orgId"`
+}
+
+// DelegateTokenResponse represents a delegated token response
+type DelegateTokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   int64  `json:"expiresIn"`
+}
+
+// Role change event types published to other services
+const (
+	RoleChangeUserRoles   = "USER_ROLES_CHANGED"
+	RoleChangeUserStatus  = "USER_STATUS_CHANGED"
+	RoleChangeRoleUpdated = "ROLE_UPDATED"
+	RoleChangeRoleDeleted = "ROLE_DELETED"
+	RoleChangePassword    = "PASSWORD_CHANGED"
+	RoleChangeKioskRevoke = "KIOSK_TOKEN_REVOKED"
+	RoleChangeUserGroups  = "USER_GROUPS_CHANGED"
+	RoleChangeGroupUpdate = "GROUP_UPDATED"
+	RoleChangeGroupDelete = "GROUP_DELETED"
+	RoleChangeOrgUpdated  = "ORGANIZATION_UPDATED"
+)
+
+// RoleChangeEvent notifies other services that cached permissions are outdated.
+// Events without a user ID affect every holder of the role or member of the group.
+type RoleChangeEvent struct {
+	Type       string    `json:"type"`
+	UserID     string    `json:"userId,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	Group      string    `json:"group,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// SigningKeyResponse exposes the access token signing keys to internal services for local validation.
+// Algorithm, Key and KeyID describe the current key; Keys also lists rotated-out keys that still
+// verify tokens during their grace period.
+type SigningKeyResponse struct {
+	Algorithm string            `json:"algorithm"`
+	Key       string            `json:"key"` // base64-encoded secret for HS256, PEM public key otherwise
+	KeyID     string            `json:"keyId"`
+	Issuer    string            `json:"issuer"`
+	Keys      []VerificationKey `json:"keys"`
+}
+
+// VerificationKey is a key internal services verify tokens with, matched by the token's "kid" header
+type VerificationKey struct {
+	KeyID     string     `json:"keyId"`
+	Algorithm string     `json:"algorithm"`
+	Key       string     `json:"key"` // base64-encoded secret for HS256, PEM public key otherwise
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
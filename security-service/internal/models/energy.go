@@ -1,12 +1,16 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 // EnergyConsumptionRequest represents the query parameters for energy consumption
 type EnergyConsumptionRequest struct {
 	BuildingID string    `form:"buildingId" binding:"required"`
 	From       time.Time `form:"from" binding:"required"`
-	To         time.Time `form:"to" binding:"required"`
+	To         time.Time `form:"to" binding:"required,gtfield=From"`
 }
 
 // EnergyConsumption represents energy consumption data from external provider
@@ -19,6 +23,7 @@ type EnergyConsumption struct {
 	CostEstimate float64                     `json:"costEstimate"`
 	Currency     string                      `json:"currency"`
 	Breakdown    []EnergyConsumptionBreakdown `json:"breakdown,omitempty"`
+	Provider     string                      `json:"provider,omitempty"`
 	RetrievedAt  time.Time                   `json:"retrievedAt"`
 }
 
@@ -81,3 +86,114 @@ type ExternalAPIError struct {
 	Message    string `json:"message"`
 	RetryAfter int    `json:"retryAfter,omitempty"` // seconds
 }
+
+// DefaultEnergyProviderName is the provider seeded from the ENERGY_PROVIDER_* settings
+const DefaultEnergyProviderName = "energy_provider"
+
+// EnergyProvider represents an external energy utility and the buildings it serves
+type EnergyProvider struct {
+	ID                    primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Name                  string                 `bson:"name" json:"name"`
+	DisplayName           string                 `bson:"display_name" json:"displayName"`
+	BaseURL               string                 `bson:"base_url" json:"baseUrl"`
+	EncryptedAPIKey       string                 `bson:"encrypted_api_key" json:"-"`
+	ClientID              string                 `bson:"client_id" json:"clientId"`
+	EncryptedClientSecret string                 `bson:"encrypted_client_secret" json:"-"`
+	Regions               []EnergyProviderRegion `bson:"regions" json:"regions"`
+	IsDefault             bool                   `bson:"is_default" json:"isDefault"`
+	Enabled               bool                   `bson:"enabled" json:"enabled"`
+	CreatedAt             time.Time              `bson:"created_at" json:"createdAt"`
+	UpdatedAt             time.Time              `bson:"updated_at" json:"updatedAt"`
+}
+
+// EnergyProviderRegion maps buildings to the region code a provider uses for them
+type EnergyProviderRegion struct {
+	Region      string   `bson:"region" json:"region" binding:"required"`
+	BuildingIDs []string `bson:"building_ids" json:"buildingIds"`
+}
+
+// RegionForBuilding returns the provider region a building is mapped to
+func (p *EnergyProvider) RegionForBuilding(buildingID string) (string, bool) {
+	for _, region := range p.Regions {
+		for _, id := range region.BuildingIDs {
+			if id == buildingID {
+				return region.Region, true
+			}
+		}
+	}
+	return "", false
+}
+
+// EnergyProviderCreateRequest represents the request body for registering an energy provider
+type EnergyProviderCreateRequest struct {
+	Name         string                 `json:"name" binding:"required,min=2,max=50"`
+	DisplayName  string                 `json:"displayName"`
+	BaseURL      string                 `json:"baseUrl" binding:"required,url"`
+	APIKey       string                 `json:"apiKey"`
+	ClientID     string                 `json:"clientId"`
+	ClientSecret string                 `json:"clientSecret"`
+	Regions      []EnergyProviderRegion `json:"regions" binding:"dive"`
+	IsDefault    bool                   `json:"isDefault"`
+	Enabled      *bool                  `json:"enabled"`
+}
+
+// EnergyProviderUpdateRequest represents the request body for updating an energy provider.
+// Omitted fields, including credentials, are left unchanged.
+type EnergyProviderUpdateRequest struct {
+	DisplayName  string                 `json:"displayName"`
+	BaseURL      string                 `json:"baseUrl" binding:"omitempty,url"`
+	APIKey       string                 `json:"apiKey"`
+	ClientID     string                 `json:"clientId"`
+	ClientSecret string                 `json:"clientSecret"`
+	Regions      []EnergyProviderRegion `json:"regions" binding:"omitempty,dive"`
+	IsDefault    *bool                  `json:"isDefault"`
+	Enabled      *bool                  `json:"enabled"`
+}
+
+// EnergyProviderResponse represents an energy provider returned in API responses, without secrets
+type EnergyProviderResponse struct {
+	ID              string                 `json:"id"`
+	Name            string                 `json:"name"`
+	DisplayName     string                 `json:"displayName"`
+	BaseURL         string                 `json:"baseUrl"`
+	ClientID        string                 `json:"clientId"`
+	HasAPIKey       bool                   `json:"hasApiKey"`
+	HasClientSecret bool                   `json:"hasClientSecret"`
+	Regions         []EnergyProviderRegion `json:"regions"`
+	IsDefault       bool                   `json:"isDefault"`
+	Enabled         bool                   `json:"enabled"`
+	CreatedAt       time.Time              `json:"createdAt"`
+	UpdatedAt       time.Time              `json:"updatedAt"`
+}
+
+// ToResponse converts an EnergyProvider to EnergyProviderResponse
+func (p *EnergyProvider) ToResponse() *EnergyProviderResponse {
+	regions := p.Regions
+	if regions == nil {
+		regions = []EnergyProviderRegion{}
+	}
+	return &EnergyProviderResponse{
+		ID:              p.ID.Hex(),
+		Name:            p.Name,
+		DisplayName:     p.DisplayName,
+		BaseURL:         p.BaseURL,
+		ClientID:        p.ClientID,
+		HasAPIKey:       p.EncryptedAPIKey != "",
+		HasClientSecret: p.EncryptedClientSecret != "",
+		Regions:         regions,
+		IsDefault:       p.IsDefault,
+		Enabled:         p.Enabled,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
+	}
+}
+
+// BuildingEnergyResponse combines consumption and tariff data from the provider serving a building
+type BuildingEnergyResponse struct {
+	BuildingID  string             `json:"buildingId"`
+	Provider    string             `json:"provider"`
+	Region      string             `json:"region,omitempty"`
+	Consumption *EnergyConsumption `json:"consumption"`
+	Tariff      *Tariff            `json:"tariff,omitempty"`
+	TariffError string             `json:"tariffError,omitempty"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KioskRole is the only role carried by kiosk tokens
+const KioskRole = "kiosk"
+
+// KioskToken is a read-only token for a public display showing one building's dashboards.
+// Only the record is stored; the signed token is returned once when it is created. KeyID is the
+// signing key of the token, which is kept past its rotation grace period while the token is active.
+type KioskToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name       string             `bson:"name" json:"name"`
+	BuildingID string             `bson:"building_id" json:"buildingId"`
+	KeyID      string             `bson:"key_id,omitempty" json:"keyId,omitempty"`
+	CreatedBy  string             `bson:"created_by" json:"createdBy"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expiresAt,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revokedAt,omitempty"`
+	RevokedBy  string             `bson:"revoked_by,omitempty" json:"revokedBy,omitempty"`
+}
+
+// IsActive reports whether the kiosk token is neither revoked nor expired
+func (k *KioskToken) IsActive() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt))
+}
+
+// KioskScope is the kiosk claim of an access token, limiting it to one building's dashboards
+type KioskScope struct {
+	TokenID    string `json:"tokenId"`
+	BuildingID string `json:"buildingId"`
+}
+
+// KioskTokenCreateRequest represents the request body for issuing a kiosk token.
+// A zero ExpiresInDays issues a token that never expires.
+type KioskTokenCreateRequest struct {
+	Name          string `json:"name" binding:"required,max=100"`
+	BuildingID    string `json:"buildingId" binding:"required"`
+	ExpiresInDays int    `json:"expiresInDays" binding:"min=0,max=3650"`
+}
+
+// KioskTokenCreateResponse returns the signed kiosk token together with its record
+type KioskTokenCreateResponse struct {
+	Token      string      `json:"token"`
+	TokenType  string      `json:"tokenType"`
+	KioskToken *KioskToken `json:"kioskToken"`
+}
@@ -0,0 +1,64 @@
+package models
+
+// Mask modes
+const (
+	// MaskModePartial keeps enough of a value to recognise it, e.g. j***@example.com
+	MaskModePartial = "PARTIAL"
+	// MaskModeHide replaces the value with null
+	MaskModeHide = "HIDE"
+)
+
+// MaskRule hides response fields from users whose roles lack the permission that reveals them
+type MaskRule struct {
+	Name     string   `json:"name"`
+	Resource string   `json:"resource"`
+	Action   string   `json:"action"`
+	Mode     string   `json:"mode"`
+	Fields   []string `json:"fields"`
+}
+
+// DataMaskRules lists the sensitive data in API responses of all services and the permission
+// that reveals each kind. Granting or revoking these permissions on a role decides what its
+// holders see; fields are matched by JSON name at any depth of a response.
+var DataMaskRules = []MaskRule{
+	{
+		Name:     "pii",
+		Resource: "users",
+		Action:   "read_pii",
+		Mode:     MaskModePartial,
+		Fields:   []string{"email", "phoneNumber"},
+	},
+	{
+		Name:     "financial",
+		Resource: "finance",
+		Action:   "read",
+		Mode:     MaskModeHide,
+		Fields: []string{
+			"cost", "totalCost", "energyCost", "demandCost", "averageRate", "costAmount", "estimatedCostImpact",
+			"savings", "expectedSavings", "actualSavings", "totalSavings", "energySavings", "demandSavings",
+			"potentialSavings", "totalPotentialSavings",
+		},
+	},
+}
+
+// ResponseMasks returns the mode of every response field the holder of the roles may not see,
+// keyed by JSON field name. Without roles, every rule applies.
+func ResponseMasks(roles []*Role) map[string]string {
+	masks := make(map[string]string)
+	for _, rule := range DataMaskRules {
+		revealed := false
+		for _, role := range roles {
+			if role.HasPermission(rule.Resource, rule.Action) {
+				revealed = true
+				break
+			}
+		}
+		if revealed {
+			continue
+		}
+		for _, field := range rule.Fields {
+			masks[field] = rule.Mode
+		}
+	}
+	return masks
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Metered usage, billed per organization
+const (
+	// MetricMonitoredDevices is the number of devices monitored in a building. It is a level, so
+	// the peak of a day or period is billed rather than a sum.
+	MetricMonitoredDevices = "monitored_devices"
+	// MetricAPICalls counts the API requests made by the organization's users to any service
+	MetricAPICalls = "api_calls"
+	// MetricForecastRuns counts completed forecasts
+	MetricForecastRuns = "forecast_runs"
+	// MetricScenarioExecutions counts optimization scenarios executed against devices
+	MetricScenarioExecutions = "scenario_executions"
+)
+
+// UsageMetrics lists the metered usage in the order it is reported
+var UsageMetrics = []string{MetricMonitoredDevices, MetricAPICalls, MetricForecastRuns, MetricScenarioExecutions}
+
+// IsUsageMetric reports whether name is a metered usage
+func IsUsageMetric(name string) bool {
+	for _, metric := range UsageMetrics {
+		if metric == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPeakMetric reports whether a metric is billed by its peak rather than its sum
+func IsPeakMetric(name string) bool {
+	return name == MetricMonitoredDevices
+}
+
+// UsageDateLayout is the layout of the UTC days usage is recorded for
+const UsageDateLayout = "2006-01-02"
+
+// UsageCounter is the usage of one metric by an organization in one of its buildings on a UTC
+// day. Usage not tied to a building, such as most API calls, has no building.
+type UsageCounter struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	OrgID      string             `bson:"org_id" json:"orgId"`
+	BuildingID string             `bson:"building_id" json:"buildingId,omitempty"`
+	Date       string             `bson:"date" json:"date"`
+	Metric     string             `bson:"metric" json:"metric"`
+	Value      int64              `bson:"value" json:"value"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// UsageSnapshot freezes the usage of an organization on a completed UTC day for invoicing. It is
+// taken once and not changed by usage reported afterwards.
+type UsageSnapshot struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID     string             `bson:"org_id" json:"orgId"`
+	Date      string             `bson:"date" json:"date"`
+	Metrics   map[string]int64   `bson:"metrics" json:"metrics"`
+	Buildings []BuildingUsage    `bson:"buildings" json:"buildings"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// BuildingUsage is the usage of a building; usage not tied to a building has no building ID
+type BuildingUsage struct {
+	BuildingID string           `bson:"building_id,omitempty" json:"buildingId,omitempty"`
+	Metrics    map[string]int64 `bson:"metrics" json:"metrics"`
+}
+
+// DailyUsage is the usage on a UTC day
+type DailyUsage struct {
+	Date    string           `json:"date"`
+	Metrics map[string]int64 `json:"metrics"`
+}
+
+// UsageSummary is the usage of an organization, or of one of its buildings, in a period.
+// Totals sum the metrics over the period, except monitored devices which is the daily peak.
+type UsageSummary struct {
+	OrgID       string             `json:"orgId,omitempty"`
+	BuildingID  string             `json:"buildingId,omitempty"`
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Totals      map[string]int64   `json:"totals"`
+	Buildings   []BuildingUsage    `json:"buildings"`
+	Daily       []DailyUsage       `json:"daily"`
+	Limits      []UsageLimitStatus `json:"limits,omitempty"`
+	GeneratedAt time.Time          `json:"generatedAt"`
+}
+
+// UsageLimitStatus is how much of a monthly usage limit an organization used in a month so far
+type UsageLimitStatus struct {
+	Metric  string  `json:"metric"`
+	Month   string  `json:"month"`
+	Limit   int64   `json:"limit"`
+	Used    int64   `json:"used"`
+	Percent float64 `json:"percent"`
+}
+
+// UsageAlert records that an organization's monthly usage crossed a threshold of its limit, so
+// each threshold is alerted once per month
+type UsageAlert struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID     string             `bson:"org_id" json:"orgId"`
+	Month     string             `bson:"month" json:"month"`
+	Metric    string             `bson:"metric" json:"metric"`
+	Threshold int                `bson:"threshold" json:"threshold"`
+	Used      int64              `bson:"used" json:"used"`
+	Limit     int64              `bson:"limit" json:"limit"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// UsageQueryParams represents the query parameters for reading metered usage. Period is a
+// number of days or weeks up to now ("30d", "4w") or a UTC month ("2024-06"). OrgID may only
+// be chosen by admins outside any organization; others read the usage of their own.
+type UsageQueryParams struct {
+	OrgID      string `form:"orgId"`
+	BuildingID string `form:"buildingId"`
+	Period     string `form:"period"`
+}
+
+// UsageSnapshotQueryParams represents the query parameters for listing daily usage snapshots
+type UsageSnapshotQueryParams struct {
+	OrgID  string `form:"orgId"`
+	Period string `form:"period"`
+}
+
+// UsageSnapshotsResponse lists the daily usage snapshots of an organization in a period
+type UsageSnapshotsResponse struct {
+	OrgID     string           `json:"orgId,omitempty"`
+	From      time.Time        `json:"from"`
+	To        time.Time        `json:"to"`
+	Snapshots []*UsageSnapshot `json:"snapshots"`
+}
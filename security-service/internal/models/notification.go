@@ -13,6 +13,7 @@ const (
 	NotificationTypeEmail NotificationType = "email"
 	NotificationTypeSMS   NotificationType = "sms"
 	NotificationTypePush  NotificationType = "push"
+	NotificationTypeInApp NotificationType = "in_app" // Stored in the user's inbox, not sent through a provider
 )
 
 // NotificationStatus represents the status of a notification
@@ -36,51 +37,269 @@ type Notification struct {
 	Status      NotificationStatus `bson:"status" json:"status"`
 	ErrorMsg    string             `bson:"error_msg,omitempty" json:"errorMsg,omitempty"`
 	Metadata    map[string]string  `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Provider    string             `bson:"provider,omitempty" json:"provider,omitempty"` // provider that handled delivery
 	SentAt      *time.Time         `bson:"sent_at,omitempty" json:"sentAt,omitempty"`
 	DeliveredAt *time.Time         `bson:"delivered_at,omitempty" json:"deliveredAt,omitempty"`
+	ReadAt      *time.Time         `bson:"read_at,omitempty" json:"readAt,omitempty"` // Set when an in-app notification is read
 	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
 }
 
 // NotificationSendRequest represents the request to send a notification
 type NotificationSendRequest struct {
 	UserID    string            `json:"userId" binding:"required"`
-	Type      NotificationType  `json:"type" binding:"required,oneof=email sms push"`
+	Type      NotificationType  `json:"type" binding:"required,oneof=email sms push in_app"`
 	Subject   string            `json:"subject"`
 	Content   string            `json:"content" binding:"required"`
-	Recipient string            `json:"recipient" binding:"required"`
+	Recipient string            `json:"recipient" binding:"required_unless=Type in_app"`
 	Metadata  map[string]string `json:"metadata"`
 }
 
+// NotificationCategory groups the events that notify users
+type NotificationCategory string
+
+const (
+	NotificationCategoryAnomaly         NotificationCategory = "anomaly"
+	NotificationCategoryBudget          NotificationCategory = "budget"
+	NotificationCategoryCommandApproval NotificationCategory = "command_approval"
+	NotificationCategoryUsage           NotificationCategory = "usage"
+	NotificationCategoryKPI             NotificationCategory = "kpi"
+	NotificationCategorySavings         NotificationCategory = "savings"
+)
+
+// NotificationSeverity represents how urgent an event is, using the anomaly severity levels
+type NotificationSeverity string
+
+const (
+	NotificationSeverityLow      NotificationSeverity = "LOW"
+	NotificationSeverityMedium   NotificationSeverity = "MEDIUM"
+	NotificationSeverityHigh     NotificationSeverity = "HIGH"
+	NotificationSeverityCritical NotificationSeverity = "CRITICAL"
+)
+
+// Rank orders severities from LOW (1) to CRITICAL (4); unknown severities rank 0
+func (s NotificationSeverity) Rank() int {
+	switch s {
+	case NotificationSeverityLow:
+		return 1
+	case NotificationSeverityMedium:
+		return 2
+	case NotificationSeverityHigh:
+		return 3
+	case NotificationSeverityCritical:
+		return 4
+	}
+	return 0
+}
+
+// NotificationRoutingRule sends events of a category at or above a severity to a set of channels.
+// An empty category or minimum severity matches every event.
+type NotificationRoutingRule struct {
+	Category    NotificationCategory `bson:"category,omitempty" json:"category,omitempty" binding:"omitempty,oneof=anomaly budget command_approval usage kpi savings"`
+	MinSeverity NotificationSeverity `bson:"min_severity,omitempty" json:"minSeverity,omitempty" binding:"omitempty,oneof=LOW MEDIUM HIGH CRITICAL"`
+	Channels    []NotificationType   `bson:"channels" json:"channels" binding:"required,min=1,dive,oneof=email sms push in_app"`
+}
+
+// Matches reports whether the rule applies to an event of the given category and severity
+func (r NotificationRoutingRule) Matches(category NotificationCategory, severity NotificationSeverity) bool {
+	if r.Category != "" && r.Category != category {
+		return false
+	}
+	return r.MinSeverity == "" || severity.Rank() >= r.MinSeverity.Rank()
+}
+
+// NotificationEvent represents an event to notify a user about on the channels chosen by their
+// routing rules. DefaultChannels are used when none of the user's rules match.
+type NotificationEvent struct {
+	UserID          string
+	Category        NotificationCategory
+	Severity        NotificationSeverity
+	Subject         string
+	Content         string
+	Metadata        map[string]string
+	DefaultChannels []NotificationType
+}
+
+// NotificationRouteTestRequest represents a hypothetical event to evaluate a user's routing rules against
+type NotificationRouteTestRequest struct {
+	UserID   string               `json:"userId" binding:"required"`
+	Category NotificationCategory `json:"category" binding:"required,oneof=anomaly budget command_approval usage kpi savings"`
+	Severity NotificationSeverity `json:"severity" binding:"required,oneof=LOW MEDIUM HIGH CRITICAL"`
+}
+
+// NotificationChannelRoute represents whether an event would be sent on a channel and to whom
+type NotificationChannelRoute struct {
+	Type       NotificationType `json:"type"`
+	Deliver    bool             `json:"deliver"`
+	Recipients []string         `json:"recipients,omitempty"`
+	Reason     string           `json:"reason,omitempty"` // Why the channel is skipped
+}
+
+// NotificationRouteDecision represents the channels an event is routed to. MatchedRule is the
+// index of the first matching routing rule, or nil when the default channels were used.
+type NotificationRouteDecision struct {
+	UserID      string                     `json:"userId"`
+	Category    NotificationCategory       `json:"category"`
+	Severity    NotificationSeverity       `json:"severity"`
+	MatchedRule *int                       `json:"matchedRule"`
+	Channels    []NotificationChannelRoute `json:"channels"`
+}
+
 // NotificationPreferences represents user notification preferences
 type NotificationPreferences struct {
-	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID              string             `bson:"user_id" json:"userId"`
-	EmailEnabled        bool               `bson:"email_enabled" json:"emailEnabled"`
-	SMSEnabled          bool               `bson:"sms_enabled" json:"smsEnabled"`
-	PushEnabled         bool               `bson:"push_enabled" json:"pushEnabled"`
-	EmailAddress        string             `bson:"email_address,omitempty" json:"emailAddress,omitempty"`
-	PhoneNumber         string             `bson:"phone_number,omitempty" json:"phoneNumber,omitempty"`
-	PushDeviceTokens    []string           `bson:"push_device_tokens,omitempty" json:"pushDeviceTokens,omitempty"`
-	QuietHoursEnabled   bool               `bson:"quiet_hours_enabled" json:"quietHoursEnabled"`
-	QuietHoursStart     string             `bson:"quiet_hours_start,omitempty" json:"quietHoursStart,omitempty"` // e.g., "22:00"
-	QuietHoursEnd       string             `bson:"quiet_hours_end,omitempty" json:"quietHoursEnd,omitempty"`     // e.g., "08:00"
-	NotificationTypes   []string           `bson:"notification_types,omitempty" json:"notificationTypes,omitempty"`
-	UpdatedAt           time.Time          `bson:"updated_at" json:"updatedAt"`
+	ID                primitive.ObjectID        `bson:"_id,omitempty" json:"id"`
+	UserID            string                    `bson:"user_id" json:"userId"`
+	EmailEnabled      bool                      `bson:"email_enabled" json:"emailEnabled"`
+	SMSEnabled        bool                      `bson:"sms_enabled" json:"smsEnabled"`
+	PushEnabled       bool                      `bson:"push_enabled" json:"pushEnabled"`
+	EmailAddress      string                    `bson:"email_address,omitempty" json:"emailAddress,omitempty"`
+	PhoneNumber       string                    `bson:"phone_number,omitempty" json:"phoneNumber,omitempty"`
+	PushDeviceTokens  []string                  `bson:"push_device_tokens" json:"pushDeviceTokens,omitempty"` // Unmanaged tokens set before devices were registered
+	PushDevices       []PushDevice              `bson:"push_devices" json:"pushDevices"`
+	PrunedPushDevices []PrunedPushDevice        `bson:"pruned_push_devices" json:"prunedPushDevices,omitempty"` // Most recently pruned first
+	QuietHoursEnabled bool                      `bson:"quiet_hours_enabled" json:"quietHoursEnabled"`
+	QuietHoursStart   string                    `bson:"quiet_hours_start,omitempty" json:"quietHoursStart,omitempty"` // e.g., "22:00"
+	QuietHoursEnd     string                    `bson:"quiet_hours_end,omitempty" json:"quietHoursEnd,omitempty"`     // e.g., "08:00"
+	NotificationTypes []string                  `bson:"notification_types,omitempty" json:"notificationTypes,omitempty"`
+	RoutingRules      []NotificationRoutingRule `bson:"routing_rules,omitempty" json:"routingRules,omitempty"`
+	UpdatedAt         time.Time                 `bson:"updated_at" json:"updatedAt"`
+
+	// EmailAddress and PhoneNumber are stored encrypted; their hashes support exact-match lookups
+	EmailAddressHash string `bson:"email_address_hash,omitempty" json:"-"`
+	PhoneNumberHash  string `bson:"phone_number_hash,omitempty" json:"-"`
+}
+
+// PushPlatform is the platform a push device runs on
+type PushPlatform string
+
+const (
+	PushPlatformIOS     PushPlatform = "ios"
+	PushPlatformAndroid PushPlatform = "android"
+	PushPlatformWeb     PushPlatform = "web"
+)
+
+// PushDevice is a device registered to receive a user's push notifications with its own
+// notification preferences and delivery health. An empty list of categories or minimum severity
+// accepts every event.
+type PushDevice struct {
+	Token               string                 `bson:"token" json:"token"`
+	Platform            PushPlatform           `bson:"platform" json:"platform"`
+	DeviceName          string                 `bson:"device_name,omitempty" json:"deviceName,omitempty"`
+	AppVersion          string                 `bson:"app_version,omitempty" json:"appVersion,omitempty"`
+	Enabled             bool                   `bson:"enabled" json:"enabled"`
+	Categories          []NotificationCategory `bson:"categories,omitempty" json:"categories,omitempty"`
+	MinSeverity         NotificationSeverity   `bson:"min_severity,omitempty" json:"minSeverity,omitempty"`
+	RegisteredAt        time.Time              `bson:"registered_at" json:"registeredAt"`
+	LastSuccessAt       *time.Time             `bson:"last_success_at,omitempty" json:"lastSuccessAt,omitempty"`
+	LastFailureAt       *time.Time             `bson:"last_failure_at,omitempty" json:"lastFailureAt,omitempty"`
+	LastError           string                 `bson:"last_error,omitempty" json:"lastError,omitempty"`
+	ConsecutiveFailures int                    `bson:"consecutive_failures" json:"consecutiveFailures"`
+}
+
+// Accepts reports whether the device wants events of the given category and severity
+func (d PushDevice) Accepts(category NotificationCategory, severity NotificationSeverity) bool {
+	if !d.Enabled {
+		return false
+	}
+	if len(d.Categories) > 0 {
+		found := false
+		for _, c := range d.Categories {
+			if c == category {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return d.MinSeverity == "" || severity.Rank() >= d.MinSeverity.Rank()
+}
+
+// PrunedPushDevice records a device removed because the push provider rejected its token
+type PrunedPushDevice struct {
+	Token      string       `bson:"token" json:"token"`
+	Platform   PushPlatform `bson:"platform,omitempty" json:"platform,omitempty"`
+	DeviceName string       `bson:"device_name,omitempty" json:"deviceName,omitempty"`
+	Reason     string       `bson:"reason" json:"reason"`
+	PrunedAt   time.Time    `bson:"pruned_at" json:"prunedAt"`
+}
+
+// PushDeviceRegisterRequest represents the request to register a device of the current user.
+// Registering a known token updates its metadata and preferences and resets its failures.
+type PushDeviceRegisterRequest struct {
+	Token       string                 `json:"token" binding:"required,max=4096"`
+	Platform    PushPlatform           `json:"platform" binding:"required,oneof=ios android web"`
+	DeviceName  string                 `json:"deviceName" binding:"max=100"`
+	AppVersion  string                 `json:"appVersion" binding:"max=50"`
+	Categories  []NotificationCategory `json:"categories" binding:"omitempty,dive,oneof=anomaly budget command_approval usage kpi savings"`
+	MinSeverity NotificationSeverity   `json:"minSeverity" binding:"omitempty,oneof=LOW MEDIUM HIGH CRITICAL"`
+}
+
+// PushDevicePreferencesRequest represents the request to change the notification preferences of
+// a device. Omitted fields are unchanged and an empty list of categories accepts every category.
+type PushDevicePreferencesRequest struct {
+	Enabled     *bool                  `json:"enabled"`
+	Categories  []NotificationCategory `json:"categories" binding:"omitempty,dive,oneof=anomaly budget command_approval usage kpi savings"`
+	MinSeverity *NotificationSeverity  `json:"minSeverity" binding:"omitempty,oneof=LOW MEDIUM HIGH CRITICAL ''"`
+}
+
+// Push device health states
+const (
+	PushDeviceHealthy  = "healthy"  // The last delivery succeeded
+	PushDeviceFailing  = "failing"  // The last delivery failed
+	PushDeviceStale    = "stale"    // Nothing was delivered to the device for the stale period
+	PushDeviceUnused   = "unused"   // Nothing was sent to the device yet
+	PushDeviceDisabled = "disabled" // The user turned the device off
+)
+
+// PushTokenHealthQueryParams represents the query parameters of the push token health report
+type PushTokenHealthQueryParams struct {
+	StaleDays int `form:"staleDays" binding:"omitempty,min=1,max=365"`
+}
+
+// PushDeviceHealth is the delivery health of a device in the token health report. Tokens are
+// masked to their last characters.
+type PushDeviceHealth struct {
+	UserID              string       `json:"userId"`
+	Token               string       `json:"token"`
+	Platform            PushPlatform `json:"platform"`
+	DeviceName          string       `json:"deviceName,omitempty"`
+	Status              string       `json:"status"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	LastError           string       `json:"lastError,omitempty"`
+	LastSuccessAt       *time.Time   `json:"lastSuccessAt,omitempty"`
+	LastFailureAt       *time.Time   `json:"lastFailureAt,omitempty"`
+	RegisteredAt        time.Time    `json:"registeredAt"`
+}
+
+// PushTokenHealthReport summarizes the delivery health of every registered push device so that
+// push notifications failing silently are noticed. Unhealthy lists the failing and stale devices.
+type PushTokenHealthReport struct {
+	Devices        int                  `json:"devices"`
+	ByStatus       map[string]int       `json:"byStatus"`
+	ByPlatform     map[PushPlatform]int `json:"byPlatform"`
+	LegacyTokens   int                  `json:"legacyTokens"`
+	PrunedRecent   int                  `json:"prunedRecent"` // Devices pruned within the stale period
+	StaleDays      int                  `json:"staleDays"`
+	Unhealthy      []PushDeviceHealth   `json:"unhealthy"`
+	RecentlyPruned []PrunedPushDevice   `json:"recentlyPruned"`
+	GeneratedAt    time.Time            `json:"generatedAt"`
 }
 
 // NotificationPreferencesUpdateRequest represents the request to update notification preferences
 type NotificationPreferencesUpdateRequest struct {
-	UserID              string   `json:"userId" binding:"required"`
-	EmailEnabled        *bool    `json:"emailEnabled"`
-	SMSEnabled          *bool    `json:"smsEnabled"`
-	PushEnabled         *bool    `json:"pushEnabled"`
-	EmailAddress        string   `json:"emailAddress"`
-	PhoneNumber         string   `json:"phoneNumber"`
-	PushDeviceTokens    []string `json:"pushDeviceTokens"`
-	QuietHoursEnabled   *bool    `json:"quietHoursEnabled"`
-	QuietHoursStart     string   `json:"quietHoursStart"`
-	QuietHoursEnd       string   `json:"quietHoursEnd"`
-	NotificationTypes   []string `json:"notificationTypes"`
+	UserID            string                    `json:"userId" binding:"required"`
+	EmailEnabled      *bool                     `json:"emailEnabled"`
+	SMSEnabled        *bool                     `json:"smsEnabled"`
+	PushEnabled       *bool                     `json:"pushEnabled"`
+	EmailAddress      string                    `json:"emailAddress"`
+	PhoneNumber       string                    `json:"phoneNumber"`
+	PushDeviceTokens  []string                  `json:"pushDeviceTokens"` // Deprecated: register devices at /notifications/push-devices
+	QuietHoursEnabled *bool                     `json:"quietHoursEnabled"`
+	QuietHoursStart   string                    `json:"quietHoursStart"`
+	QuietHoursEnd     string                    `json:"quietHoursEnd"`
+	NotificationTypes []string                  `json:"notificationTypes"`
+	RoutingRules      []NotificationRoutingRule `json:"routingRules" binding:"omitempty,dive"` // Replaces all rules; an empty list removes them
 }
 
 // NotificationLogQueryParams represents query parameters for notification logs
@@ -89,25 +308,27 @@ type NotificationLogQueryParams struct {
 	Type   NotificationType `form:"type"`
 	Status string           `form:"status"`
 	From   time.Time        `form:"from"`
-	To     time.Time        `form:"to"`
+	To     time.Time        `form:"to" binding:"omitempty,gtfield=From"`
 	Page   int              `form:"page"`
 	Limit  int              `form:"limit"`
 }
 
 // NotificationResponse represents the notification data returned in API responses
 type NotificationResponse struct {
-	ID          string            `json:"id"`
-	UserID      string            `json:"userId"`
-	Type        NotificationType  `json:"type"`
-	Subject     string            `json:"subject"`
-	Content     string            `json:"content"`
-	Recipient   string            `json:"recipient"`
+	ID          string             `json:"id"`
+	UserID      string             `json:"userId"`
+	Type        NotificationType   `json:"type"`
+	Subject     string             `json:"subject"`
+	Content     string             `json:"content"`
+	Recipient   string             `json:"recipient"`
 	Status      NotificationStatus `json:"status"`
-	ErrorMsg    string            `json:"errorMsg,omitempty"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
-	SentAt      *time.Time        `json:"sentAt,omitempty"`
-	DeliveredAt *time.Time        `json:"deliveredAt,omitempty"`
-	CreatedAt   time.Time         `json:"createdAt"`
+	ErrorMsg    string             `json:"errorMsg,omitempty"`
+	Metadata    map[string]string  `json:"metadata,omitempty"`
+	Provider    string             `json:"provider,omitempty"`
+	SentAt      *time.Time         `json:"sentAt,omitempty"`
+	DeliveredAt *time.Time         `json:"deliveredAt,omitempty"`
+	ReadAt      *time.Time         `json:"readAt,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
 }
 
 // ToResponse converts a Notification to NotificationResponse
@@ -122,8 +343,10 @@ func (n *Notification) ToResponse() *NotificationResponse {
 		Status:      n.Status,
 		ErrorMsg:    n.ErrorMsg,
 		Metadata:    n.Metadata,
+		Provider:    n.Provider,
 		SentAt:      n.SentAt,
 		DeliveredAt: n.DeliveredAt,
+		ReadAt:      n.ReadAt,
 		CreatedAt:   n.CreatedAt,
 	}
 }
@@ -136,3 +359,85 @@ type PaginatedNotificationsResponse struct {
 	Limit         int                     `json:"limit"`
 	TotalPages    int                     `json:"totalPages"`
 }
+
+// NotificationInboxQueryParams represents query parameters for the in-app inbox
+type NotificationInboxQueryParams struct {
+	UnreadOnly bool `form:"unreadOnly"`
+	Page       int  `form:"page"`
+	Limit      int  `form:"limit"`
+}
+
+// NotificationInboxResponse represents a page of a user's in-app notifications, newest first.
+// Unread counts all unread notifications of the user, not only those on the page.
+type NotificationInboxResponse struct {
+	Notifications []*NotificationResponse `json:"notifications"`
+	Total         int64                   `json:"total"`
+	Unread        int64                   `json:"unread"`
+	Page          int                     `json:"page"`
+	Limit         int                     `json:"limit"`
+	TotalPages    int                     `json:"totalPages"`
+}
+
+// NotificationsMarkedReadResponse represents the result of marking all in-app notifications read
+type NotificationsMarkedReadResponse struct {
+	Marked int64 `json:"marked"`
+}
+
+// NotificationProviderHealth represents the health of a notification provider
+type NotificationProviderHealth struct {
+	Name           string             `json:"name"`
+	Types          []NotificationType `json:"types"`
+	Healthy        bool               `json:"healthy"`
+	Error          string             `json:"error,omitempty"`
+	LatencyMs      int64              `json:"latencyMs"`
+	CheckedAt      time.Time          `json:"checkedAt"`
+	LastFailure    *time.Time         `json:"lastFailure,omitempty"`
+	LastFailureMsg string             `json:"lastFailureMsg,omitempty"`
+}
+
+// NotificationRouteStatus represents the provider selection for a notification type
+type NotificationRouteStatus struct {
+	Type     NotificationType `json:"type"`
+	Primary  string           `json:"primary"`
+	Fallback string           `json:"fallback,omitempty"`
+}
+
+// NotificationProvidersStatus represents provider health and routing returned by the API
+type NotificationProvidersStatus struct {
+	Providers []NotificationProviderHealth `json:"providers"`
+	Routes    []NotificationRouteStatus    `json:"routes"`
+}
+
+// NotificationLatencyStats summarizes how long notifications took to reach a status, in milliseconds
+type NotificationLatencyStats struct {
+	Count int   `json:"count"`
+	P50Ms int64 `json:"p50Ms"`
+	P90Ms int64 `json:"p90Ms"`
+	P95Ms int64 `json:"p95Ms"`
+	P99Ms int64 `json:"p99Ms"`
+	MaxMs int64 `json:"maxMs"`
+}
+
+// NotificationDeliveryBreakdown holds delivery counts and latencies of a group of notifications.
+// The failure rate is the share of notifications that finished sending which failed.
+type NotificationDeliveryBreakdown struct {
+	Type               NotificationType         `json:"type,omitempty"`
+	Provider           string                   `json:"provider,omitempty"`
+	Total              int64                    `json:"total"`
+	Pending            int64                    `json:"pending"`
+	Sent               int64                    `json:"sent"`
+	Delivered          int64                    `json:"delivered"`
+	Failed             int64                    `json:"failed"`
+	FailureRatePercent float64                  `json:"failureRatePercent"`
+	TimeToSent         NotificationLatencyStats `json:"timeToSent"`
+	TimeToDelivered    NotificationLatencyStats `json:"timeToDelivered"`
+}
+
+// NotificationDeliveryStats represents notification delivery analytics over a period
+type NotificationDeliveryStats struct {
+	From       time.Time                       `json:"from"`
+	To         time.Time                       `json:"to"`
+	Overall    NotificationDeliveryBreakdown   `json:"overall"`
+	ByType     []NotificationDeliveryBreakdown `json:"byType"`
+	ByProvider []NotificationDeliveryBreakdown `json:"byProvider"`
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RoleSuperAdmin is the role of operators who manage organizations. Super admins are not
+// scoped to an organization and see the data of all of them.
+const RoleSuperAdmin = "super_admin"
+
+// OrganizationStatus represents whether the members of an organization can sign in
+type OrganizationStatus string
+
+const (
+	OrganizationStatusActive    OrganizationStatus = "ACTIVE"
+	OrganizationStatusSuspended OrganizationStatus = "SUSPENDED"
+)
+
+// Organization is a tenant of a shared deployment. It owns its users, groups and custom roles,
+// and the buildings whose devices and data its members may access. A building belongs to at
+// most one organization.
+type Organization struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	BuildingIDs []string           `bson:"building_ids" json:"buildingIds"`
+	Status      OrganizationStatus `bson:"status" json:"status"`
+	UsageLimits map[string]int64   `bson:"usage_limits,omitempty" json:"usageLimits,omitempty"` // monthly limits keyed by metric
+	CreatedBy   string             `bson:"created_by" json:"createdBy"`
+	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// IsActive reports whether the members of the organization can sign in
+func (o *Organization) IsActive() bool {
+	return o.Status == OrganizationStatusActive
+}
+
+// Scope returns the tenant claim of access tokens issued to the organization's members
+func (o *Organization) Scope() *TenantScope {
+	buildingIDs := o.BuildingIDs
+	if buildingIDs == nil {
+		buildingIDs = []string{}
+	}
+	return &TenantScope{
+		OrgID:       o.ID.Hex(),
+		BuildingIDs: buildingIDs,
+	}
+}
+
+// TenantScope is the tenant claim of an access token, limiting it to the data of one
+// organization: its users and the buildings it owns
+type TenantScope struct {
+	OrgID       string   `json:"orgId"`
+	BuildingIDs []string `json:"buildingIds"`
+}
+
+// AllowsBuilding reports whether the building belongs to the organization
+func (t *TenantScope) AllowsBuilding(buildingID string) bool {
+	for _, id := range t.BuildingIDs {
+		if id == buildingID {
+			return true
+		}
+	}
+	return false
+}
+
+// OrganizationCreateRequest represents the request body for creating an organization
+type OrganizationCreateRequest struct {
+	Name        string   `json:"name" binding:"required,min=2,max=100"`
+	Description string   `json:"description" binding:"max=500"`
+	BuildingIDs []string `json:"buildingIds"`
+}
+
+// OrganizationUpdateRequest represents the request body for updating an organization. Omitted
+// fields are left unchanged; BuildingIDs replaces the buildings the organization owns and
+// UsageLimits its monthly usage limits, where a limit of 0 removes it.
+type OrganizationUpdateRequest struct {
+	Name        string             `json:"name" binding:"omitempty,min=2,max=100"`
+	Description *string            `json:"description" binding:"omitempty,max=500"`
+	BuildingIDs []string           `json:"buildingIds"`
+	Status      OrganizationStatus `json:"status" binding:"omitempty,oneof=ACTIVE SUSPENDED"`
+	UsageLimits map[string]int64   `json:"usageLimits"`
+}
+
+// OrganizationResponse represents an organization with the number of its users
+type OrganizationResponse struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	BuildingIDs []string           `json:"buildingIds"`
+	Status      OrganizationStatus `json:"status"`
+	UsageLimits map[string]int64   `json:"usageLimits,omitempty"`
+	UserCount   int64              `json:"userCount"`
+	CreatedBy   string             `json:"createdBy"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+}
+
+// ToResponse converts an Organization to OrganizationResponse
+func (o *Organization) ToResponse(userCount int64) *OrganizationResponse {
+	return &OrganizationResponse{
+		ID:          o.ID.Hex(),
+		Name:        o.Name,
+		Description: o.Description,
+		BuildingIDs: o.Scope().BuildingIDs,
+		Status:      o.Status,
+		UsageLimits: o.UsageLimits,
+		UserCount:   userCount,
+		CreatedBy:   o.CreatedBy,
+		CreatedAt:   o.CreatedAt,
+		UpdatedAt:   o.UpdatedAt,
+	}
+}
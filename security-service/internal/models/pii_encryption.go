@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// PIIReencryptionRun reports a run of the PII re-encryption job. Failed records could not be
+// decrypted, usually because their key is no longer configured, and were left as they are.
+type PIIReencryptionRun struct {
+	KeyID                  string     `json:"keyId"`
+	StartedAt              time.Time  `json:"startedAt"`
+	CompletedAt            *time.Time `json:"completedAt,omitempty"`
+	ReencryptedUsers       int64      `json:"reencryptedUsers"`
+	ReencryptedPreferences int64      `json:"reencryptedPreferences"`
+	Failed                 int64      `json:"failed"`
+	Error                  string     `json:"error,omitempty"`
+}
+
+// PIIEncryptionStatus reports the keys PII is encrypted with and the records still plaintext or
+// encrypted with a retired key
+type PIIEncryptionStatus struct {
+	KeyID              string              `json:"keyId"`
+	RetiredKeyIDs      []string            `json:"retiredKeyIds"`
+	IntervalHours      int                 `json:"intervalHours"`
+	PendingUsers       int64               `json:"pendingUsers"`
+	PendingPreferences int64               `json:"pendingPreferences"`
+	LastRun            *PIIReencryptionRun `json:"lastRun,omitempty"`
+}
@@ -10,9 +10,19 @@ type APIResponse struct {
 
 // APIError represents an error in the API response
 type APIError struct {
-	Code    string `json:"code"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details string       `json:"details,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"` // Fields that failed validation
+}
+
+// FieldError describes a request field that failed validation.
+// Rule is the binding rule it broke, e.g. "oneof", and Param the rule's parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
 	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
 }
 
 // NewSuccessResponse creates a successful API response
@@ -36,6 +46,13 @@ func NewErrorResponse(code, message, details string) *APIResponse {
 	}
 }
 
+// NewValidationErrorResponse creates an error API response for a request that failed validation
+func NewValidationErrorResponse(message, details string, fields []FieldError) *APIResponse {
+	response := NewErrorResponse(ErrCodeValidationFailed, message, details)
+	response.Error.Fields = fields
+	return response
+}
+
 // Common error codes
 const (
 	ErrCodeInvalidRequest     = "INVALID_REQUEST"
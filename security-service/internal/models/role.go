@@ -18,7 +18,8 @@ type Role struct {
 	Name        string             `bson:"name" json:"name" binding:"required"`
 	Description string             `bson:"description" json:"description"`
 	Permissions []Permission       `bson:"permissions" json:"permissions"`
-	IsSystem    bool               `bson:"is_system" json:"isSystem"` // System roles cannot be deleted
+	IsSystem    bool               `bson:"is_system" json:"isSystem"`               // System roles cannot be deleted
+	OrgID       string             `bson:"org_id,omitempty" json:"orgId,omitempty"` // Organization owning the role, empty for roles shared by all
 	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updatedAt"`
 }
@@ -43,6 +44,7 @@ type RoleResponse struct {
 	Description string       `json:"description"`
 	Permissions []Permission `json:"permissions"`
 	IsSystem    bool         `json:"isSystem"`
+	OrgID       string       `json:"orgId,omitempty"`
 	CreatedAt   time.Time    `json:"createdAt"`
 	UpdatedAt   time.Time    `json:"updatedAt"`
 }
@@ -55,6 +57,7 @@ func (r *Role) ToResponse() *RoleResponse {
 		Description: r.Description,
 		Permissions: r.Permissions,
 		IsSystem:    r.IsSystem,
+		OrgID:       r.OrgID,
 		CreatedAt:   r.CreatedAt,
 		UpdatedAt:   r.UpdatedAt,
 	}
@@ -73,3 +76,88 @@ func (r *Role) HasPermission(resource, action string) bool {
 	}
 	return false
 }
+
+// PermissionGrant represents a single resource/action pair granted by a role
+type PermissionGrant struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// Grants expands the role's permissions into resource/action pairs.
+// Wildcards are kept as-is so "*" grants are reported literally.
+func (r *Role) Grants() []PermissionGrant {
+	seen := make(map[PermissionGrant]bool)
+	var grants []PermissionGrant
+	for _, perm := range r.Permissions {
+		for _, action := range perm.Actions {
+			grant := PermissionGrant{Resource: perm.Resource, Action: action}
+			if !seen[grant] {
+				seen[grant] = true
+				grants = append(grants, grant)
+			}
+		}
+	}
+	return grants
+}
+
+// LostGrants returns the grants of the current role that a user would no longer hold
+// if its permissions were replaced, given the user's other roles
+func LostGrants(current *Role, proposed []Permission, otherRoles []*Role) []PermissionGrant {
+	proposedRole := &Role{Permissions: proposed}
+	var lost []PermissionGrant
+	for _, grant := range current.Grants() {
+		if proposedRole.HasPermission(grant.Resource, grant.Action) {
+			continue
+		}
+		retained := false
+		for _, other := range otherRoles {
+			if other.HasPermission(grant.Resource, grant.Action) {
+				retained = true
+				break
+			}
+		}
+		if !retained {
+			lost = append(lost, grant)
+		}
+	}
+	return lost
+}
+
+// RoleImpactUser represents a user holding a role
+type RoleImpactUser struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	IsActive bool     `json:"isActive"`
+	Roles    []string `json:"roles"`
+}
+
+// RoleImpactResponse lists the users affected by changes to a role
+type RoleImpactResponse struct {
+	RoleName  string           `json:"roleName"`
+	UserCount int              `json:"userCount"`
+	Users     []RoleImpactUser `json:"users"`
+}
+
+// RolePreviewRequest represents proposed permissions for a role
+type RolePreviewRequest struct {
+	Permissions []Permission `json:"permissions" binding:"required"`
+}
+
+// LostPermission represents a (user, resource, action) combination that would be lost
+type LostPermission struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// RolePreviewResponse reports the effect of replacing a role's permissions
+type RolePreviewResponse struct {
+	RoleName          string            `json:"roleName"`
+	UserCount         int               `json:"userCount"`
+	AffectedUserCount int               `json:"affectedUserCount"`
+	RemovedGrants     []PermissionGrant `json:"removedGrants"`
+	AddedGrants       []PermissionGrant `json:"addedGrants"`
+	Lost              []LostPermission  `json:"lost"`
+}
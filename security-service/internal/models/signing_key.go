@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SigningKeyStatus represents the lifecycle state of a token signing key
+type SigningKeyStatus string
+
+const (
+	SigningKeyStatusActive  SigningKeyStatus = "ACTIVE"
+	SigningKeyStatusRetired SigningKeyStatus = "RETIRED"
+)
+
+// SigningKey is a stored token signing key. The newest active key signs new tokens; retired keys
+// verify tokens until ExpiresAt. The configured JWT_SECRET key has no stored material and only
+// gets a record once it is rotated out.
+type SigningKey struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	KeyID     string             `bson:"key_id" json:"keyId"`
+	Algorithm string             `bson:"algorithm" json:"algorithm"`
+	Material  string             `bson:"material,omitempty" json:"-"` // encrypted secret or private key
+	Status    SigningKeyStatus   `bson:"status" json:"status"`
+	Current   bool               `bson:"-" json:"current"`
+	CreatedBy string             `bson:"created_by,omitempty" json:"createdBy,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+	RetiredAt *time.Time         `bson:"retired_at,omitempty" json:"retiredAt,omitempty"`
+	ExpiresAt *time.Time         `bson:"expires_at,omitempty" json:"expiresAt,omitempty"`
+}
+
+// RotateSigningKeyRequest represents a signing key rotation request.
+// The algorithm defaults to the configured JWT_SIGNING_ALGORITHM.
+type RotateSigningKeyRequest struct {
+	Algorithm string `json:"algorithm" binding:"omitempty,oneof=HS256 RS256 ES256"`
+}
+
+// JWK is a public signing key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the public key set published for verifying asymmetrically signed tokens
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
@@ -15,11 +15,23 @@ type User struct {
 	PasswordHash string             `bson:"password_hash" json:"-"`
 	FirstName    string             `bson:"first_name" json:"firstName"`
 	LastName     string             `bson:"last_name" json:"lastName"`
+	PhoneNumber  string             `bson:"phone_number,omitempty" json:"phoneNumber,omitempty"`
 	Roles        []string           `bson:"roles" json:"roles"`
+	Groups       []string           `bson:"groups,omitempty" json:"groups,omitempty"` // Groups the user belongs to directly
+	OrgID        string             `bson:"org_id,omitempty" json:"orgId,omitempty"`  // Organization the user belongs to, empty outside any
 	IsActive     bool               `bson:"is_active" json:"isActive"`
 	CreatedAt    time.Time          `bson:"created_at" json:"createdAt"`
 	UpdatedAt    time.Time          `bson:"updated_at" json:"updatedAt"`
 	LastLoginAt  *time.Time         `bson:"last_login_at,omitempty" json:"lastLoginAt,omitempty"`
+	DeletedAt    *time.Time         `bson:"deleted_at,omitempty" json:"deletedAt,omitempty"`
+	DeletedBy    string             `bson:"deleted_by,omitempty" json:"deletedBy,omitempty"`
+
+	// PasswordChangedAt invalidates access tokens issued before the last password change
+	PasswordChangedAt *time.Time `bson:"password_changed_at,omitempty" json:"-"`
+
+	// Email and PhoneNumber are stored encrypted; their hashes support exact-match lookups
+	EmailHash       string `bson:"email_hash,omitempty" json:"-"`
+	PhoneNumberHash string `bson:"phone_number_hash,omitempty" json:"-"`
 }
 
 // UserCreateRequest represents the request body for creating a new user
@@ -30,6 +42,9 @@ type UserCreateRequest struct {
 	FirstName string   `json:"firstName"`
 	LastName  string   `json:"lastName"`
 	Roles     []string `json:"roles"`
+	// OrgID places the user in an organization; only super admins may choose it, other admins
+	// create users in their own organization
+	OrgID string `json:"orgId"`
 }
 
 // UserUpdateRequest represents the request body for updating a user
@@ -49,11 +64,26 @@ type UserResponse struct {
 	Email       string     `json:"email"`
 	FirstName   string     `json:"firstName"`
 	LastName    string     `json:"lastName"`
+	PhoneNumber string     `json:"phoneNumber,omitempty"`
 	Roles       []string   `json:"roles"`
+	Groups      []string   `json:"groups,omitempty"`
+	OrgID       string     `json:"orgId,omitempty"`
 	IsActive    bool       `json:"isActive"`
 	CreatedAt   time.Time  `json:"createdAt"`
 	UpdatedAt   time.Time  `json:"updatedAt"`
 	LastLoginAt *time.Time `json:"lastLoginAt,omitempty"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+	DeletedBy   string     `json:"deletedBy,omitempty"`
+}
+
+// HasRole reports whether the user has been granted the named role
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
 
 // ToResponse converts a User to UserResponse
@@ -64,10 +94,61 @@ func (u *User) ToResponse() *UserResponse {
 		Email:       u.Email,
 		FirstName:   u.FirstName,
 		LastName:    u.LastName,
+		PhoneNumber: u.PhoneNumber,
 		Roles:       u.Roles,
+		Groups:      u.Groups,
+		OrgID:       u.OrgID,
 		IsActive:    u.IsActive,
 		CreatedAt:   u.CreatedAt,
 		UpdatedAt:   u.UpdatedAt,
 		LastLoginAt: u.LastLoginAt,
+		DeletedAt:   u.DeletedAt,
+		DeletedBy:   u.DeletedBy,
+	}
+}
+
+// ProfileUpdateRequest represents the request body for a user updating their own contact details
+type ProfileUpdateRequest struct {
+	Email       string `json:"email" binding:"omitempty,email"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	PhoneNumber string `json:"phoneNumber" binding:"omitempty,e164"`
+}
+
+// ChangePasswordRequest represents the request body for a user changing their own password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,min=8"`
+}
+
+// ProfileResponse represents the current user's profile with their notification settings
+type ProfileResponse struct {
+	User                    *UserResponse            `json:"user"`
+	NotificationPreferences *NotificationPreferences `json:"notificationPreferences"`
+	Links                   ProfileLinks             `json:"links"`
+}
+
+// ProfileLinks points to the endpoints that manage the profile and its notification settings
+type ProfileLinks struct {
+	Self                    string `json:"self"`
+	ChangePassword          string `json:"changePassword"`
+	NotificationPreferences string `json:"notificationPreferences"`
+}
+
+// NewProfileLinks builds the profile links for a user
+func NewProfileLinks(userID string) ProfileLinks {
+	return ProfileLinks{
+		Self:                    "/api/v1/auth/profile",
+		ChangePassword:          "/api/v1/auth/change-password",
+		NotificationPreferences: "/api/v1/notifications/preferences/" + userID,
 	}
 }
+
+// ChangePasswordResponse returns fresh tokens for the current session after a password change
+type ChangePasswordResponse struct {
+	AccessToken     string `json:"accessToken"`
+	RefreshToken    string `json:"refreshToken"`
+	TokenType       string `json:"tokenType"`
+	ExpiresIn       int64  `json:"expiresIn"`
+	SessionsRevoked int64  `json:"sessionsRevoked"`
+}
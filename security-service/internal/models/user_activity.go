@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// User activity flags point admins at accounts worth a closer look
+const (
+	// UserActivityFlagDormant marks enabled accounts that did not sign in during the period
+	UserActivityFlagDormant = "DORMANT"
+	// UserActivityFlagFailedLogins marks accounts with repeated failed logins in the period
+	UserActivityFlagFailedLogins = "FAILED_LOGINS"
+	// UserActivityFlagHighActivity marks accounts with far more audited actions than their peers
+	UserActivityFlagHighActivity = "HIGH_ACTIVITY"
+)
+
+// UserActivityQueryParams represents the query parameters of a user activity summary
+type UserActivityQueryParams struct {
+	Period string `form:"period"` // 30d, 4w or 2024-06; the last 30 days by default
+	Flag   string `form:"flag" binding:"omitempty,oneof=DORMANT FAILED_LOGINS HIGH_ACTIVITY"`
+}
+
+// UserActivityStats is the audited activity of one user in a period, aggregated from audit logs
+type UserActivityStats struct {
+	UserID           string     `bson:"_id"`
+	Actions          int        `bson:"actions"`
+	Failures         int        `bson:"failures"`
+	Logins           int        `bson:"logins"`
+	FailedLogins     int        `bson:"failed_logins"`
+	CommandsIssued   int        `bson:"commands_issued"`
+	ReportsGenerated int        `bson:"reports_generated"`
+	LastLogin        *time.Time `bson:"last_login"`
+	LastActivity     time.Time  `bson:"last_activity"`
+	IPAddresses      []string   `bson:"ip_addresses"`
+}
+
+// UserActivity summarizes what one user did in a period
+type UserActivity struct {
+	UserID           string     `json:"userId"`
+	Username         string     `json:"username"`
+	Roles            []string   `json:"roles"`
+	IsActive         bool       `json:"isActive"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	LastLoginAt      *time.Time `json:"lastLoginAt,omitempty"`    // Last successful login, also before the period
+	LastActivityAt   *time.Time `json:"lastActivityAt,omitempty"` // Last audited action in the period
+	Logins           int        `json:"logins"`
+	FailedLogins     int        `json:"failedLogins"`
+	CommandsIssued   int        `json:"commandsIssued"`
+	ReportsGenerated int        `json:"reportsGenerated"`
+	Actions          int        `json:"actions"` // Every audited action, including the above
+	Failures         int        `json:"failures"`
+	IPAddresses      int        `json:"ipAddresses"` // Distinct addresses the user acted from
+	Flags            []string   `json:"flags"`
+}
+
+// UserActivitySummary summarizes the activity of every user in a period
+type UserActivitySummary struct {
+	From        time.Time       `json:"from"`
+	To          time.Time       `json:"to"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+	Users       int             `json:"users"`
+	ActiveUsers int             `json:"activeUsers"` // Users with at least one audited action
+	Dormant     int             `json:"dormant"`
+	Flagged     int             `json:"flagged"`
+	Entries     []*UserActivity `json:"entries"`
+}
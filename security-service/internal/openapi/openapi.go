@@ -0,0 +1,525 @@
+// Package openapi builds an OpenAPI 3 document of the service's API. Paths come from the gin route
+// table, so every registered route is listed; routes described in an operation table also get
+// parameter, request and response schemas derived from the model types and their binding rules.
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Security requirements of an operation
+const (
+	AuthBearer     = "" // A user's access token; the default
+	AuthNone       = "none"
+	AuthServiceKey = "service"
+)
+
+// Operation describes the inputs and output of a route's handler. Query and Body are values of
+// the types the handler binds, Response a value of the type of the data it responds with.
+type Operation struct {
+	Summary  string
+	Query    interface{}
+	Body     interface{}
+	Response interface{}
+	Auth     string
+}
+
+// Info identifies the service in the document
+type Info struct {
+	Title       string
+	Version     string
+	Description string
+}
+
+// Document is an OpenAPI 3 document, ready to be encoded as JSON
+type Document map[string]interface{}
+
+var pathParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// Build documents the routes. Operations are looked up by the route's handler method, e.g.
+// "UserHandler.ListUsers", so a handler mounted on several paths is described once.
+// Routes under /internal default to service key authentication and /health routes to none.
+func Build(info Info, routes gin.RoutesInfo, operations map[string]Operation) Document {
+	sorted := make(gin.RoutesInfo, len(routes))
+	copy(sorted, routes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+
+	b := &schemaBuilder{components: map[string]interface{}{}}
+	b.components["APIError"] = b.schema(reflect.TypeOf(apiError{}))
+
+	paths := map[string]interface{}{}
+	operationIDs := map[string]int{}
+	for _, route := range sorted {
+		receiver, name := handlerMethod(route.Handler)
+		op := operations[receiver+"."+name]
+
+		operationID := name
+		if operationID == "" {
+			operationID = strings.ToLower(route.Method) + strings.NewReplacer("/", "_", ":", "", "*", "", ".", "_").Replace(route.Path)
+		}
+		if n := operationIDs[operationID]; n > 0 {
+			operationIDs[operationID]++
+			operationID += strconv.Itoa(n + 1)
+		} else {
+			operationIDs[operationID] = 1
+		}
+
+		summary := op.Summary
+		if summary == "" {
+			summary = words(name)
+		}
+		operation := map[string]interface{}{
+			"operationId": operationID,
+			"summary":     summary,
+			"tags":        []string{tag(route.Path)},
+			"responses":   b.responses(op.Response),
+		}
+
+		var parameters []interface{}
+		for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		if op.Query != nil {
+			parameters = append(parameters, b.queryParameters(reflect.TypeOf(op.Query))...)
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if op.Body != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": b.schema(reflect.TypeOf(op.Body))},
+				},
+			}
+		}
+
+		auth := op.Auth
+		switch {
+		case auth != AuthBearer:
+		case strings.HasPrefix(route.Path, "/internal/"):
+			auth = AuthServiceKey
+		case strings.HasPrefix(route.Path, "/health"), route.Path == "/openapi.json":
+			auth = AuthNone
+		}
+		switch auth {
+		case AuthNone:
+			operation["security"] = []interface{}{}
+		case AuthServiceKey:
+			operation["security"] = []interface{}{map[string]interface{}{"serviceKey": []string{}}}
+		}
+
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	infoObject := map[string]interface{}{"title": info.Title, "version": info.Version}
+	if info.Description != "" {
+		infoObject["description"] = info.Description
+	}
+	return Document{
+		"openapi": "3.0.3",
+		"info":    infoObject,
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": b.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"serviceKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-Service-Key"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerAuth": []string{}}},
+	}
+}
+
+// apiError mirrors the error of the response envelope, so the document does not depend on models
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+	Fields  []struct {
+		Field   string `json:"field"`
+		Rule    string `json:"rule"`
+		Param   string `json:"param,omitempty"`
+		Message string `json:"message"`
+	} `json:"fields,omitempty"`
+}
+
+// responses describes the response envelope with the operation's data, and the error envelope
+func (b *schemaBuilder) responses(data interface{}) map[string]interface{} {
+	success := map[string]interface{}{
+		"success": map[string]interface{}{"type": "boolean"},
+		"message": map[string]interface{}{"type": "string"},
+	}
+	if data != nil {
+		success["data"] = b.schema(reflect.TypeOf(data))
+	}
+	envelope := func(properties map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"type": "object", "properties": properties},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"2XX": map[string]interface{}{
+			"description": "Success",
+			"content":     envelope(success),
+		},
+		"default": map[string]interface{}{
+			"description": "Error; validation failures list the fields that failed",
+			"content": envelope(map[string]interface{}{
+				"success": map[string]interface{}{"type": "boolean"},
+				"error":   map[string]interface{}{"$ref": "#/components/schemas/APIError"},
+			}),
+		},
+	}
+}
+
+// queryParameters describes the query parameters of a query struct by their form tags
+func (b *schemaBuilder) queryParameters(t reflect.Type) []interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var parameters []interface{}
+	for _, field := range fields(t) {
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		rules := field.Tag.Get("binding")
+		parameter := map[string]interface{}{
+			"name":   name,
+			"in":     "query",
+			"schema": b.fieldSchema(field.Type, rules),
+		}
+		if hasRule(rules, "required") {
+			parameter["required"] = true
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// schemaBuilder derives JSON schemas of Go types, collecting named structs as components
+type schemaBuilder struct {
+	components map[string]interface{}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schema derives the schema of a type
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return map[string]interface{}{"type": "integer", "description": "Duration in nanoseconds"}
+	case t.Kind() != reflect.Struct && (t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)):
+		// Identifiers such as ObjectIDs encode themselves as strings
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name := componentName(t)
+		if _, ok := b.components[name]; !ok {
+			// Registered before it is built, so self-referencing types terminate
+			b.components[name] = map[string]interface{}{}
+			b.components[name] = b.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// object derives the schema of a struct from its JSON fields and their binding rules
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for _, field := range fields(t) {
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		rules := field.Tag.Get("binding")
+		properties[name] = b.fieldSchema(field.Type, rules)
+		if hasRule(rules, "required") {
+			required = append(required, name)
+		}
+	}
+
+	object := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+// fieldSchema derives the schema of a field, adding the constraints of its binding rules.
+// Rules after "dive" apply to the elements of a collection and are not described.
+func (b *schemaBuilder) fieldSchema(t reflect.Type, rules string) map[string]interface{} {
+	schema := b.schema(t)
+	if rules == "" {
+		return schema
+	}
+	if _, ok := schema["$ref"]; ok {
+		return schema
+	}
+	constrained := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		constrained[key] = value
+	}
+
+	var notes []string
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "dive" {
+			break
+		}
+		switch name {
+		case "oneof":
+			var values []interface{}
+			for _, value := range strings.Fields(param) {
+				values = append(values, enumValue(constrained["type"], value))
+			}
+			constrained["enum"] = values
+		case "min", "gte", "gt":
+			setBound(constrained, "min", param, name == "gt")
+		case "max", "lte", "lt":
+			setBound(constrained, "max", param, name == "lt")
+		case "email":
+			constrained["format"] = "email"
+		case "url":
+			constrained["format"] = "uri"
+		case "e164":
+			constrained["pattern"] = `^\+[1-9]\d{1,14}$`
+		case "timezone":
+			notes = append(notes, "an IANA time zone name")
+		case "gtfield":
+			notes = append(notes, "after "+lowerFirst(param))
+		case "gtefield":
+			notes = append(notes, "not before "+lowerFirst(param))
+		case "maxspan":
+			if params := strings.Fields(param); len(params) == 2 {
+				notes = append(notes, "at most "+params[1]+" after "+lowerFirst(params[0]))
+			}
+		}
+	}
+	if len(notes) > 0 {
+		constrained["description"] = "Must be " + strings.Join(notes, " and ")
+	}
+	return constrained
+}
+
+// setBound sets the lower or upper bound of a number, the length of a string or the size of an
+// array or object
+func setBound(schema map[string]interface{}, bound, param string, exclusive bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	var key string
+	switch schema["type"] {
+	case "string":
+		key = "Length"
+	case "array":
+		key = "Items"
+	case "object":
+		key = "Properties"
+	default:
+		if bound == "min" {
+			schema["minimum"] = value
+			schema["exclusiveMinimum"] = exclusive
+		} else {
+			schema["maximum"] = value
+			schema["exclusiveMaximum"] = exclusive
+		}
+		return
+	}
+
+	size := int(value)
+	if exclusive && bound == "min" {
+		size++
+	} else if exclusive {
+		size--
+	}
+	schema[bound+key] = size
+}
+
+// enumValue converts an enum value to the schema's type
+func enumValue(schemaType interface{}, value string) interface{} {
+	switch schemaType {
+	case "integer":
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	}
+	return value
+}
+
+// fields lists the exported fields of a struct, flattening embedded structs as encoding/json does
+func fields(t reflect.Type) []reflect.StructField {
+	var result []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				result = append(result, fields(embedded)...)
+				continue
+			}
+		}
+		if field.IsExported() {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// hasRule reports whether the binding rules contain a rule
+func hasRule(rules, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+// componentName names a struct's schema, qualified by its package when it is not a model
+func componentName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if pkg == "" || strings.HasSuffix(pkg, "/models") {
+		return t.Name()
+	}
+	return pkg[strings.LastIndex(pkg, "/")+1:] + "." + t.Name()
+}
+
+// handlerMethod extracts the receiver type and method name of a handler, e.g. "UserHandler"
+// and "ListUsers" from "security-service/internal/handlers.(*UserHandler).ListUsers-fm".
+// Closures and plain functions have no receiver; closures have no name either.
+func handlerMethod(handler string) (string, string) {
+	handler = handler[strings.LastIndex(handler, "/")+1:]
+	parts := strings.Split(strings.TrimSuffix(handler, "-fm"), ".")
+	method := parts[len(parts)-1]
+	if strings.HasPrefix(method, "func") {
+		return "", ""
+	}
+	if len(parts) < 3 {
+		return "", method
+	}
+	return strings.Trim(parts[len(parts)-2], "(*)"), method
+}
+
+// tag groups an operation by the first path segment after the API version
+func tag(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 2 && segments[0] == "api" {
+		return segments[2]
+	}
+	if len(segments) > 1 && segments[0] == "internal" {
+		return "internal"
+	}
+	return segments[0]
+}
+
+// words turns a handler name into a summary, e.g. "Get building KPIs" from "GetBuildingKPIs".
+// A word starts at a capital after a lower case letter, or at the last capital of an acronym
+// followed by lower case letters other than a plural "s".
+func words(name string) string {
+	runes := []rune(name)
+	var tokens []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		prevLower := unicode.IsLower(runes[i-1])
+		nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		plural := i+1 < len(runes) && runes[i+1] == 's' && (i+2 == len(runes) || unicode.IsUpper(runes[i+2]))
+		if prevLower || (nextLower && !plural) {
+			tokens = append(tokens, string(runes[start:i]))
+			start = i
+		}
+	}
+	tokens = append(tokens, string(runes[start:]))
+
+	for i := 1; i < len(tokens); i++ {
+		if token := []rune(tokens[i]); len(token) > 1 && unicode.IsLower(token[1]) {
+			tokens[i] = strings.ToLower(tokens[i])
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// lowerFirst turns a Go field name into its usual JSON name
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
@@ -12,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"security-service/internal/models"
+	"security-service/internal/tenant"
 )
 
 // AuditRepository handles audit log database operations
@@ -27,6 +28,9 @@ func NewAuditRepository(collection *mongo.Collection) *AuditRepository {
 // Create inserts a new audit log entry
 func (r *AuditRepository) Create(ctx context.Context, log *models.AuditLog) (*models.AuditLog, error) {
 	log.Timestamp = time.Now()
+	if log.OrgID == "" {
+		log.OrgID = tenant.OrgID(ctx)
+	}
 
 	result, err := r.collection.InsertOne(ctx, log)
 	if err != nil {
@@ -45,7 +49,7 @@ func (r *AuditRepository) FindByID(ctx context.Context, id string) (*models.Audi
 	}
 
 	var log models.AuditLog
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&log)
+	err = r.collection.FindOne(ctx, tenant.Filter(ctx, bson.M{"_id": objectID})).Decode(&log)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("audit log not found")
@@ -56,10 +60,10 @@ func (r *AuditRepository) FindByID(ctx context.Context, id string) (*models.Audi
 	return &log, nil
 }
 
-// Find retrieves audit logs with filters and pagination
-func (r *AuditRepository) Find(ctx context.Context, params models.AuditLogQueryParams) ([]*models.AuditLog, int64, error) {
-	// Build filter
-	filter := bson.M{}
+// buildFilter builds the MongoDB filter for audit log query parameters, limited to the
+// organization ctx is scoped to
+func (r *AuditRepository) buildFilter(ctx context.Context, params models.AuditLogQueryParams) bson.M {
+	filter := tenant.Filter(ctx, bson.M{})
 
 	// Time range filter
 	if !params.From.IsZero() || !params.To.IsZero() {
@@ -98,6 +102,13 @@ func (r *AuditRepository) Find(ctx context.Context, params models.AuditLogQueryP
 		filter["status"] = params.Status
 	}
 
+	return filter
+}
+
+// Find retrieves audit logs with filters and pagination
+func (r *AuditRepository) Find(ctx context.Context, params models.AuditLogQueryParams) ([]*models.AuditLog, int64, error) {
+	filter := r.buildFilter(ctx, params)
+
 	// Set default pagination
 	page := params.Page
 	limit := params.Limit
@@ -136,6 +147,146 @@ func (r *AuditRepository) Find(ctx context.Context, params models.AuditLogQueryP
 	return logs, total, nil
 }
 
+// FindAfter retrieves up to limit audit logs strictly older than the cursor (newest first).
+// A nil cursor starts from the most recent entry. Keyset pagination keeps deep pages cheap.
+func (r *AuditRepository) FindAfter(ctx context.Context, params models.AuditLogQueryParams, cursor *models.AuditLogCursor, limit int) ([]*models.AuditLog, error) {
+	filter := r.buildFilter(ctx, params)
+	if cursor != nil {
+		filter = bson.M{"$and": []bson.M{
+			filter,
+			{"$or": []bson.M{
+				{"timestamp": bson.M{"$lt": cursor.Timestamp}},
+				{"timestamp": cursor.Timestamp, "_id": bson.M{"$lt": cursor.ID}},
+			}},
+		}}
+	}
+
+	findOptions := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}})
+
+	mongoCursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer mongoCursor.Close(ctx)
+
+	var logs []*models.AuditLog
+	if err := mongoCursor.All(ctx, &logs); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// Stream iterates over all audit logs matching the filters (newest first) without
+// loading them into memory, calling fn for each entry. Iteration stops at the first error.
+func (r *AuditRepository) Stream(ctx context.Context, params models.AuditLogQueryParams, batchSize int32, fn func(*models.AuditLog) error) error {
+	return r.stream(ctx, r.buildFilter(ctx, params), batchSize, fn)
+}
+
+// StreamBuildingActivity iterates over the audit logs in a time range whose details reference a
+// building, either as buildingId or among buildingIds, limited to the organization ctx is scoped to
+func (r *AuditRepository) StreamBuildingActivity(ctx context.Context, buildingID string, from, to time.Time, batchSize int32, fn func(*models.AuditLog) error) error {
+	filter := tenant.Filter(ctx, bson.M{
+		"timestamp": bson.M{"$gte": from, "$lte": to},
+		"$or": []bson.M{
+			{"details.buildingId": buildingID},
+			{"details.buildingIds": buildingID},
+		},
+	})
+	return r.stream(ctx, filter, batchSize, fn)
+}
+
+// StreamLogins iterates over the login attempts of users in a time range. Logins happen before
+// a request is scoped to an organization, so they are matched by user only.
+func (r *AuditRepository) StreamLogins(ctx context.Context, userIDs []string, from, to time.Time, batchSize int32, fn func(*models.AuditLog) error) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	filter := bson.M{
+		"action":    "LOGIN",
+		"user_id":   bson.M{"$in": userIDs},
+		"timestamp": bson.M{"$gte": from, "$lte": to},
+	}
+	return r.stream(ctx, filter, batchSize, fn)
+}
+
+// AggregateUserActivity aggregates the audit logs in a time range into activity statistics per
+// user. userIDs limits it to some users, nil covers every user; logins happen before a request is
+// scoped to an organization, so organizations are covered by their users rather than by org_id.
+func (r *AuditRepository) AggregateUserActivity(ctx context.Context, userIDs []string, from, to time.Time) ([]*models.UserActivityStats, error) {
+	match := bson.M{
+		"timestamp": bson.M{"$gte": from, "$lte": to},
+		"user_id":   bson.M{"$ne": ""},
+	}
+	if userIDs != nil {
+		match["user_id"] = bson.M{"$in": userIDs}
+	}
+
+	// count sums the entries matching all the conditions
+	count := func(conditions ...bson.M) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$and": conditions}, 1, 0}}}
+	}
+	login := bson.M{"$eq": bson.A{"$action", "LOGIN"}}
+	succeeded := bson.M{"$eq": bson.A{"$status", "SUCCESS"}}
+	failed := bson.M{"$eq": bson.A{"$status", "FAILURE"}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":               "$user_id",
+			"actions":           bson.M{"$sum": 1},
+			"failures":          count(failed),
+			"logins":            count(login, succeeded),
+			"failed_logins":     count(login, failed),
+			"commands_issued":   count(bson.M{"$eq": bson.A{"$action", "SEND_COMMAND"}}, succeeded),
+			"reports_generated": count(bson.M{"$eq": bson.A{"$action", "GENERATE_REPORT"}}, succeeded),
+			"last_login":        bson.M{"$max": bson.M{"$cond": bson.A{bson.M{"$and": bson.A{login, succeeded}}, "$timestamp", nil}}},
+			"last_activity":     bson.M{"$max": "$timestamp"},
+			"ip_addresses":      bson.M{"$addToSet": "$ip_address"},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats []*models.UserActivityStats
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// stream iterates over the audit logs matching a filter, newest first
+func (r *AuditRepository) stream(ctx context.Context, filter bson.M, batchSize int32, fn func(*models.AuditLog) error) error {
+	findOptions := options.Find().
+		SetBatchSize(batchSize).
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var log models.AuditLog
+		if err := cursor.Decode(&log); err != nil {
+			return err
+		}
+		if err := fn(&log); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
 // FindByUser retrieves audit logs for a specific user
 func (r *AuditRepository) FindByUser(ctx context.Context, userID string, page, limit int) ([]*models.AuditLog, int64, error) {
 	return r.Find(ctx, models.AuditLogQueryParams{
@@ -210,6 +361,13 @@ func (r *AuditRepository) DeleteOlderThan(ctx context.Context, before time.Time)
 	return result.DeletedCount, nil
 }
 
+// CountOlderThan counts audit logs created before the given time
+func (r *AuditRepository) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"timestamp": bson.M{"$lt": before},
+	})
+}
+
 // CountByAction counts audit logs by action type
 func (r *AuditRepository) CountByAction(ctx context.Context, action string, from, to time.Time) (int64, error) {
 	filter := bson.M{"action": action}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"security-service/internal/models"
+)
+
+// EnergyProviderRepository handles energy provider database operations
+type EnergyProviderRepository struct {
+	collection *mongo.Collection
+}
+
+// NewEnergyProviderRepository creates a new energy provider repository
+func NewEnergyProviderRepository(collection *mongo.Collection) *EnergyProviderRepository {
+	return &EnergyProviderRepository{collection: collection}
+}
+
+// Create inserts a new energy provider
+func (r *EnergyProviderRepository) Create(ctx context.Context, provider *models.EnergyProvider) (*models.EnergyProvider, error) {
+	provider.CreatedAt = time.Now()
+	provider.UpdatedAt = time.Now()
+	if provider.Regions == nil {
+		provider.Regions = []models.EnergyProviderRegion{}
+	}
+
+	result, err := r.collection.InsertOne(ctx, provider)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("energy provider with this name already exists")
+		}
+		return nil, err
+	}
+
+	provider.ID = result.InsertedID.(primitive.ObjectID)
+	return provider, nil
+}
+
+// FindByName retrieves an energy provider by its name
+func (r *EnergyProviderRepository) FindByName(ctx context.Context, name string) (*models.EnergyProvider, error) {
+	return r.findOne(ctx, bson.M{"name": name})
+}
+
+// FindDefault retrieves the enabled default energy provider
+func (r *EnergyProviderRepository) FindDefault(ctx context.Context) (*models.EnergyProvider, error) {
+	return r.findOne(ctx, bson.M{"is_default": true, "enabled": true})
+}
+
+// FindByBuilding retrieves the enabled energy provider a building is mapped to
+func (r *EnergyProviderRepository) FindByBuilding(ctx context.Context, buildingID string) (*models.EnergyProvider, error) {
+	return r.findOne(ctx, bson.M{"regions.building_ids": buildingID, "enabled": true})
+}
+
+// FindByRegion retrieves the enabled energy providers serving a region, default provider first
+func (r *EnergyProviderRepository) FindByRegion(ctx context.Context, region string) ([]*models.EnergyProvider, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "is_default", Value: -1}, {Key: "name", Value: 1}})
+	return r.find(ctx, bson.M{"regions.region": region, "enabled": true}, findOptions)
+}
+
+// FindAll retrieves all energy providers
+func (r *EnergyProviderRepository) FindAll(ctx context.Context) ([]*models.EnergyProvider, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	return r.find(ctx, bson.M{}, findOptions)
+}
+
+// FindBuildingOwners retrieves providers other than the named one that serve any of the buildings
+func (r *EnergyProviderRepository) FindBuildingOwners(ctx context.Context, buildingIDs []string, excludeName string) ([]*models.EnergyProvider, error) {
+	return r.find(ctx, bson.M{
+		"regions.building_ids": bson.M{"$in": buildingIDs},
+		"name":                 bson.M{"$ne": excludeName},
+	})
+}
+
+// Update updates an existing energy provider by name
+func (r *EnergyProviderRepository) Update(ctx context.Context, name string, updates bson.M) (*models.EnergyProvider, error) {
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"name": name},
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var provider models.EnergyProvider
+	if err := result.Decode(&provider); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("energy provider not found")
+		}
+		return nil, err
+	}
+
+	return &provider, nil
+}
+
+// ClearDefault unsets the default flag on every provider except the named one
+func (r *EnergyProviderRepository) ClearDefault(ctx context.Context, exceptName string) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"name": bson.M{"$ne": exceptName}, "is_default": true},
+		bson.M{"$set": bson.M{"is_default": false, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// Delete removes an energy provider by name
+func (r *EnergyProviderRepository) Delete(ctx context.Context, name string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("energy provider not found")
+	}
+
+	return nil
+}
+
+// Count returns the number of registered energy providers
+func (r *EnergyProviderRepository) Count(ctx context.Context) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{})
+}
+
+// findOne retrieves a single energy provider matching the filter
+func (r *EnergyProviderRepository) findOne(ctx context.Context, filter bson.M) (*models.EnergyProvider, error) {
+	var provider models.EnergyProvider
+	err := r.collection.FindOne(ctx, filter).Decode(&provider)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("energy provider not found")
+		}
+		return nil, err
+	}
+
+	return &provider, nil
+}
+
+// find retrieves the energy providers matching the filter
+func (r *EnergyProviderRepository) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*models.EnergyProvider, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var providers []*models.EnergyProvider
+	if err := cursor.All(ctx, &providers); err != nil {
+		return nil, err
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"security-service/internal/models"
+	"security-service/internal/tenant"
+)
+
+// GroupRepository handles group database operations
+type GroupRepository struct {
+	collection *mongo.Collection
+}
+
+// NewGroupRepository creates a new group repository
+func NewGroupRepository(collection *mongo.Collection) *GroupRepository {
+	return &GroupRepository{collection: collection}
+}
+
+// Create inserts a new group into the database
+func (r *GroupRepository) Create(ctx context.Context, group *models.Group) (*models.Group, error) {
+	group.CreatedAt = time.Now()
+	group.UpdatedAt = time.Now()
+	if group.Roles == nil {
+		group.Roles = []string{}
+	}
+	if group.ParentGroups == nil {
+		group.ParentGroups = []string{}
+	}
+	if group.OrgID == "" {
+		group.OrgID = tenant.OrgID(ctx)
+	}
+
+	result, err := r.collection.InsertOne(ctx, group)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("group with this name already exists")
+		}
+		return nil, err
+	}
+
+	group.ID = result.InsertedID.(primitive.ObjectID)
+	return group, nil
+}
+
+// FindByName retrieves a group by its name
+func (r *GroupRepository) FindByName(ctx context.Context, name string) (*models.Group, error) {
+	var group models.Group
+	err := r.collection.FindOne(ctx, tenant.Filter(ctx, bson.M{"name": name})).Decode(&group)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("group not found")
+		}
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// FindAll retrieves all groups ordered by name
+func (r *GroupRepository) FindAll(ctx context.Context) ([]*models.Group, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, tenant.Filter(ctx, bson.M{}), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []*models.Group
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// FindAllByName retrieves all groups keyed by name, for resolving nested memberships
+func (r *GroupRepository) FindAllByName(ctx context.Context) (map[string]*models.Group, error) {
+	groups, err := r.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*models.Group, len(groups))
+	for _, group := range groups {
+		byName[group.Name] = group
+	}
+	return byName, nil
+}
+
+// Update updates an existing group by name
+func (r *GroupRepository) Update(ctx context.Context, name string, updates bson.M) (*models.Group, error) {
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		tenant.Filter(ctx, bson.M{"name": name}),
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var group models.Group
+	if err := result.Decode(&group); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("group not found")
+		}
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// Delete removes a group by name and drops it from the parent groups of other groups
+func (r *GroupRepository) Delete(ctx context.Context, name string) error {
+	result, err := r.collection.DeleteOne(ctx, tenant.Filter(ctx, bson.M{"name": name}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("group not found")
+	}
+
+	_, err = r.collection.UpdateMany(
+		ctx,
+		tenant.Filter(ctx, bson.M{"parent_groups": name}),
+		bson.M{
+			"$pull": bson.M{"parent_groups": name},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+// ExistsByName checks if a group exists with the given name
+func (r *GroupRepository) ExistsByName(ctx context.Context, name string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"name": name})
+	return count > 0, err
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"security-service/internal/models"
+	"security-service/internal/tenant"
+)
+
+// KioskRepository handles kiosk token database operations
+type KioskRepository struct {
+	collection *mongo.Collection
+}
+
+// NewKioskRepository creates a new kiosk token repository
+func NewKioskRepository(collection *mongo.Collection) *KioskRepository {
+	return &KioskRepository{collection: collection}
+}
+
+// Create inserts a new kiosk token record
+func (r *KioskRepository) Create(ctx context.Context, kiosk *models.KioskToken) (*models.KioskToken, error) {
+	kiosk.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, kiosk)
+	if err != nil {
+		return nil, err
+	}
+
+	kiosk.ID = result.InsertedID.(primitive.ObjectID)
+	return kiosk, nil
+}
+
+// FindByID retrieves a kiosk token record by ID
+func (r *KioskRepository) FindByID(ctx context.Context, id string) (*models.KioskToken, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid kiosk token ID format")
+	}
+
+	var kiosk models.KioskToken
+	err = r.collection.FindOne(ctx, tenant.BuildingFilter(ctx, bson.M{"_id": objectID}, "building_id")).Decode(&kiosk)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("kiosk token not found")
+		}
+		return nil, err
+	}
+
+	return &kiosk, nil
+}
+
+// FindAll retrieves kiosk tokens with pagination, newest first
+func (r *KioskRepository) FindAll(ctx context.Context, buildingID string, includeRevoked bool, page, limit int) ([]*models.KioskToken, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{}
+	if buildingID != "" {
+		filter["building_id"] = buildingID
+	}
+	if !includeRevoked {
+		filter["revoked_at"] = bson.M{"$exists": false}
+	}
+	filter = tenant.BuildingFilter(ctx, filter, "building_id")
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	kiosks := []*models.KioskToken{}
+	if err := cursor.All(ctx, &kiosks); err != nil {
+		return nil, 0, err
+	}
+
+	return kiosks, total, nil
+}
+
+// FindActive retrieves every kiosk token that is neither revoked nor expired, in all organizations
+func (r *KioskRepository) FindActive(ctx context.Context) ([]*models.KioskToken, error) {
+	filter := bson.M{
+		"revoked_at": bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	kiosks := []*models.KioskToken{}
+	if err := cursor.All(ctx, &kiosks); err != nil {
+		return nil, err
+	}
+	return kiosks, nil
+}
+
+// Revoke marks a kiosk token as revoked
+func (r *KioskRepository) Revoke(ctx context.Context, id, revokedBy string) (*models.KioskToken, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid kiosk token ID format")
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		tenant.BuildingFilter(ctx, bson.M{"_id": objectID, "revoked_at": bson.M{"$exists": false}}, "building_id"),
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_by": revokedBy}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var kiosk models.KioskToken
+	if err := result.Decode(&kiosk); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if _, findErr := r.FindByID(ctx, id); findErr != nil {
+				return nil, findErr
+			}
+			return nil, errors.New("kiosk token is already revoked")
+		}
+		return nil, err
+	}
+
+	return &kiosk, nil
+}
+
+// RevokeByCreator revokes every active kiosk token created by a user, returning how many were revoked
+func (r *KioskRepository) RevokeByCreator(ctx context.Context, createdBy, revokedBy string) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"created_by": createdBy, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_by": revokedBy}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
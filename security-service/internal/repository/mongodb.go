@@ -5,13 +5,23 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/description"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 
 	"security-service/internal/config"
+	"security-service/internal/models"
+	"security-service/internal/tracing"
 )
 
 // MongoDB holds the database connection and collections
@@ -19,17 +29,26 @@ type MongoDB struct {
 	Client   *mongo.Client
 	Database *mongo.Database
 	config   *config.Config
+	monitor  *topologyMonitor
 }
 
 // Collections holds references to all MongoDB collections
 type Collections struct {
 	Users              *mongo.Collection
 	Roles              *mongo.Collection
+	Groups             *mongo.Collection
 	AuthCredentials    *mongo.Collection
 	AuditLogs          *mongo.Collection
 	RefreshTokens      *mongo.Collection
 	Notifications      *mongo.Collection
 	NotificationPrefs  *mongo.Collection
+	EnergyProviders    *mongo.Collection
+	KioskTokens        *mongo.Collection
+	SigningKeys        *mongo.Collection
+	Organizations      *mongo.Collection
+	UsageCounters      *mongo.Collection
+	UsageSnapshots     *mongo.Collection
+	UsageAlerts        *mongo.Collection
 }
 
 // NewMongoDB creates a new MongoDB connection
@@ -37,12 +56,38 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoDB.Timeout)
 	defer cancel()
 
+	monitor := &topologyMonitor{}
+
 	// Create client options
 	clientOptions := options.Client().
 		ApplyURI(cfg.MongoDB.URI).
-		SetMaxPoolSize(100).
-		SetMinPoolSize(10).
-		SetMaxConnIdleTime(30 * time.Second)
+		SetMaxPoolSize(cfg.MongoDB.MaxPoolSize).
+		SetMinPoolSize(cfg.MongoDB.MinPoolSize).
+		SetMaxConnIdleTime(30 * time.Second).
+		SetServerSelectionTimeout(cfg.MongoDB.ServerSelectionTimeout).
+		SetRetryWrites(cfg.MongoDB.RetryWrites).
+		SetServerMonitor(&event.ServerMonitor{
+			TopologyDescriptionChanged: monitor.topologyChanged,
+		}).
+		SetMonitor(tracing.NewMongoMonitor())
+
+	if cfg.MongoDB.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(cfg.MongoDB.ReplicaSet)
+	}
+	if cfg.MongoDB.ReadPreference != "" {
+		readPref, err := parseReadPreference(cfg.MongoDB.ReadPreference)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetReadPreference(readPref)
+	}
+	if cfg.MongoDB.WriteConcern != "" {
+		writeConcern, err := parseWriteConcern(cfg.MongoDB.WriteConcern)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetWriteConcern(writeConcern)
+	}
 
 	// Connect to MongoDB
 	client, err := mongo.Connect(ctx, clientOptions)
@@ -61,22 +106,125 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 		Client:   client,
 		Database: client.Database(cfg.MongoDB.Database),
 		config:   cfg,
+		monitor:  monitor,
 	}, nil
 }
 
+// parseReadPreference parses a read preference mode such as "primary" or "secondaryPreferred"
+func parseReadPreference(value string) (*readpref.ReadPref, error) {
+	mode, err := readpref.ModeFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MongoDB read preference %q: %w", value, err)
+	}
+	return readpref.New(mode)
+}
+
+// parseWriteConcern accepts "majority" or the number of members that must acknowledge a write
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	if strings.EqualFold(value, "majority") {
+		return writeconcern.Majority(), nil
+	}
+	w, err := strconv.Atoi(value)
+	if err != nil || w < 0 {
+		return nil, fmt.Errorf("invalid MongoDB write concern %q: must be \"majority\" or a number", value)
+	}
+	return &writeconcern.WriteConcern{W: w}, nil
+}
+
+// topologyMonitor follows driver topology events to track the primary and any replica set
+// members that have become unreachable
+type topologyMonitor struct {
+	mu          sync.Mutex
+	primary     string
+	unavailable []string
+}
+
+// topologyChanged is called by the driver with the topology locked, so it must not run operations
+func (t *topologyMonitor) topologyChanged(e *event.TopologyDescriptionChangedEvent) {
+	var primary string
+	var unavailable []string
+	for _, server := range e.NewDescription.Servers {
+		switch {
+		case server.Kind == description.RSPrimary:
+			primary = server.Addr.String()
+		case server.Kind == description.RSGhost,
+			server.Kind == description.Unknown && server.LastError != nil:
+			// Members start as Unknown until their first heartbeat; only failed ones count
+			unavailable = append(unavailable, server.Addr.String())
+		}
+	}
+	sort.Strings(unavailable)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if primary != t.primary {
+		if primary == "" {
+			log.Printf("MongoDB primary %s is no longer available", t.primary)
+		} else {
+			log.Printf("MongoDB primary is now %s", primary)
+		}
+	}
+	if strings.Join(unavailable, ",") != strings.Join(t.unavailable, ",") {
+		if len(unavailable) > 0 {
+			log.Printf("MongoDB members unreachable: %s", strings.Join(unavailable, ", "))
+		} else {
+			log.Printf("All MongoDB members reachable")
+		}
+	}
+
+	t.primary = primary
+	t.unavailable = unavailable
+}
+
+// status reports unreachable members as a degraded state
+func (t *topologyMonitor) status() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.unavailable) == 0 {
+		return nil
+	}
+	return &models.DegradedError{
+		Reason: fmt.Sprintf("MongoDB members unreachable: %s", strings.Join(t.unavailable, ", ")),
+	}
+}
+
 // GetCollections returns all collection references
 func (m *MongoDB) GetCollections() *Collections {
 	return &Collections{
 		Users:              m.Database.Collection("users"),
 		Roles:              m.Database.Collection("roles"),
+		Groups:             m.Database.Collection("groups"),
 		AuthCredentials:    m.Database.Collection("auth_credentials"),
 		AuditLogs:          m.Database.Collection("audit_logs"),
 		RefreshTokens:      m.Database.Collection("refresh_tokens"),
 		Notifications:      m.Database.Collection("notifications"),
 		NotificationPrefs:  m.Database.Collection("notification_preferences"),
+		EnergyProviders:    m.Database.Collection("energy_providers"),
+		KioskTokens:        m.Database.Collection("kiosk_tokens"),
+		SigningKeys:        m.Database.Collection("signing_keys"),
+		Organizations:      m.Database.Collection("organizations"),
+		UsageCounters:      m.Database.Collection("usage_counters"),
+		UsageSnapshots:     m.Database.Collection("usage_snapshots"),
+		UsageAlerts:        m.Database.Collection("usage_alerts"),
 	}
 }
 
+// Ping verifies the MongoDB primary is reachable
+func (m *MongoDB) Ping(ctx context.Context) error {
+	return m.Client.Ping(ctx, readpref.Primary())
+}
+
+// HealthCheck verifies the MongoDB primary is reachable and reports the connection as degraded
+// while other replica set members are unreachable
+func (m *MongoDB) HealthCheck(ctx context.Context) error {
+	if err := m.Ping(ctx); err != nil {
+		return err
+	}
+	return m.monitor.status()
+}
+
 // Close closes the MongoDB connection
 func (m *MongoDB) Close(ctx context.Context) error {
 	if err := m.Client.Disconnect(ctx); err != nil {
@@ -97,8 +245,16 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 			Options: options.Index().SetUnique(true),
 		},
 		{
-			Keys:    map[string]interface{}{"email": 1},
-			Options: options.Index().SetUnique(true),
+			// Emails are stored encrypted, so uniqueness is enforced on their hashes. Sparse
+			// because users written before encryption have no hash until re-encrypted.
+			Keys:    map[string]interface{}{"email_hash": 1},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys: map[string]interface{}{"groups": 1},
+		},
+		{
+			Keys: map[string]interface{}{"org_id": 1},
 		},
 	}
 	if _, err := collections.Users.Indexes().CreateMany(ctx, userIndexes); err != nil {
@@ -116,6 +272,17 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		return fmt.Errorf("failed to create role indexes: %w", err)
 	}
 
+	// Groups collection indexes
+	groupIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"name": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := collections.Groups.Indexes().CreateMany(ctx, groupIndexes); err != nil {
+		return fmt.Errorf("failed to create group indexes: %w", err)
+	}
+
 	// Refresh tokens indexes
 	refreshTokenIndexes := []mongo.IndexModel{
 		{
@@ -144,6 +311,18 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: map[string]interface{}{"timestamp": -1},
 		},
+		{
+			// Supports keyset pagination and streaming exports
+			Keys: bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			// Supports building access reports
+			Keys: bson.D{{Key: "details.buildingId", Value: 1}, {Key: "timestamp", Value: -1}},
+		},
+		{
+			// Supports user activity summaries, which group a period's entries by user
+			Keys: bson.D{{Key: "timestamp", Value: -1}, {Key: "user_id", Value: 1}, {Key: "action", Value: 1}, {Key: "status", Value: 1}},
+		},
 	}
 	if _, err := collections.AuditLogs.Indexes().CreateMany(ctx, auditIndexes); err != nil {
 		return fmt.Errorf("failed to create audit log indexes: %w", err)
@@ -154,11 +333,34 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: map[string]interface{}{"user_id": 1, "created_at": -1},
 		},
+		{
+			Keys: map[string]interface{}{"created_at": -1},
+		},
 	}
 	if _, err := collections.Notifications.Indexes().CreateMany(ctx, notificationIndexes); err != nil {
 		return fmt.Errorf("failed to create notification indexes: %w", err)
 	}
 
+	// Notification preferences indexes
+	notificationPrefIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{"user_id": 1},
+		},
+		{
+			// Supports moving push devices between users and pruning rejected tokens
+			Keys: map[string]interface{}{"push_devices.token": 1},
+		},
+		{
+			Keys: map[string]interface{}{"email_address_hash": 1},
+		},
+		{
+			Keys: map[string]interface{}{"phone_number_hash": 1},
+		},
+	}
+	if _, err := collections.NotificationPrefs.Indexes().CreateMany(ctx, notificationPrefIndexes); err != nil {
+		return fmt.Errorf("failed to create notification preference indexes: %w", err)
+	}
+
 	// Auth credentials indexes
 	authCredIndexes := []mongo.IndexModel{
 		{
@@ -170,6 +372,95 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		return fmt.Errorf("failed to create auth credentials indexes: %w", err)
 	}
 
+	// Energy providers indexes
+	energyProviderIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"name": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"regions.building_ids": 1},
+		},
+		{
+			Keys: map[string]interface{}{"regions.region": 1},
+		},
+	}
+	if _, err := collections.EnergyProviders.Indexes().CreateMany(ctx, energyProviderIndexes); err != nil {
+		return fmt.Errorf("failed to create energy provider indexes: %w", err)
+	}
+
+	// Kiosk token indexes
+	kioskIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{"building_id": 1},
+		},
+		{
+			Keys: map[string]interface{}{"created_at": -1},
+		},
+	}
+	if _, err := collections.KioskTokens.Indexes().CreateMany(ctx, kioskIndexes); err != nil {
+		return fmt.Errorf("failed to create kiosk token indexes: %w", err)
+	}
+
+	// Signing key indexes
+	signingKeyIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"key_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"status": 1, "created_at": -1},
+		},
+	}
+	if _, err := collections.SigningKeys.Indexes().CreateMany(ctx, signingKeyIndexes); err != nil {
+		return fmt.Errorf("failed to create signing key indexes: %w", err)
+	}
+
+	// Organization indexes
+	organizationIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"name": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"building_ids": 1},
+		},
+	}
+	if _, err := collections.Organizations.Indexes().CreateMany(ctx, organizationIndexes); err != nil {
+		return fmt.Errorf("failed to create organization indexes: %w", err)
+	}
+
+	// Usage metering indexes; one counter per metric, building and day, and one snapshot and
+	// alert threshold per organization and period
+	usageCounterIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "org_id", Value: 1}, {Key: "date", Value: 1}, {Key: "building_id", Value: 1}, {Key: "metric", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"date": 1},
+		},
+	}
+	if _, err := collections.UsageCounters.Indexes().CreateMany(ctx, usageCounterIndexes); err != nil {
+		return fmt.Errorf("failed to create usage counter indexes: %w", err)
+	}
+
+	usageSnapshotIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "org_id", Value: 1}, {Key: "date", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := collections.UsageSnapshots.Indexes().CreateOne(ctx, usageSnapshotIndex); err != nil {
+		return fmt.Errorf("failed to create usage snapshot indexes: %w", err)
+	}
+
+	usageAlertIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "org_id", Value: 1}, {Key: "month", Value: 1}, {Key: "metric", Value: 1}, {Key: "threshold", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := collections.UsageAlerts.Indexes().CreateOne(ctx, usageAlertIndex); err != nil {
+		return fmt.Errorf("failed to create usage alert indexes: %w", err)
+	}
+
 	log.Println("MongoDB indexes created successfully")
 	return nil
 }
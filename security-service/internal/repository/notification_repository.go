@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"time"
 
@@ -12,26 +13,32 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"security-service/internal/models"
+	"security-service/pkg/utils"
 )
 
-// NotificationRepository handles notification database operations
+// NotificationRepository handles notification database operations.
+// The email addresses and phone numbers of preferences are encrypted on write and decrypted on read.
 type NotificationRepository struct {
 	notifications *mongo.Collection
 	preferences   *mongo.Collection
+	encryptor     *utils.FieldEncryptor
 }
 
 // NewNotificationRepository creates a new notification repository
-func NewNotificationRepository(notifications, preferences *mongo.Collection) *NotificationRepository {
+func NewNotificationRepository(notifications, preferences *mongo.Collection, encryptor *utils.FieldEncryptor) *NotificationRepository {
 	return &NotificationRepository{
 		notifications: notifications,
 		preferences:   preferences,
+		encryptor:     encryptor,
 	}
 }
 
 // Create inserts a new notification
 func (r *NotificationRepository) Create(ctx context.Context, notification *models.Notification) (*models.Notification, error) {
 	notification.CreatedAt = time.Now()
-	notification.Status = models.NotificationStatusPending
+	if notification.Status == "" {
+		notification.Status = models.NotificationStatusPending
+	}
 
 	result, err := r.notifications.InsertOne(ctx, notification)
 	if err != nil {
@@ -127,6 +134,11 @@ func (r *NotificationRepository) FindByUser(ctx context.Context, params models.N
 
 // UpdateStatus updates the status of a notification
 func (r *NotificationRepository) UpdateStatus(ctx context.Context, id string, status models.NotificationStatus, errorMsg string) error {
+	return r.UpdateDeliveryStatus(ctx, id, status, errorMsg, "")
+}
+
+// UpdateDeliveryStatus updates the status of a notification and the provider that handled it
+func (r *NotificationRepository) UpdateDeliveryStatus(ctx context.Context, id string, status models.NotificationStatus, errorMsg, provider string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.New("invalid notification ID format")
@@ -139,6 +151,9 @@ func (r *NotificationRepository) UpdateStatus(ctx context.Context, id string, st
 	if errorMsg != "" {
 		updates["error_msg"] = errorMsg
 	}
+	if provider != "" {
+		updates["provider"] = provider
+	}
 
 	now := time.Now()
 	if status == models.NotificationStatusSent {
@@ -156,10 +171,92 @@ func (r *NotificationRepository) UpdateStatus(ctx context.Context, id string, st
 	return err
 }
 
+// FindInbox retrieves a page of a user's in-app notifications, newest first
+func (r *NotificationRepository) FindInbox(ctx context.Context, userID string, unreadOnly bool, page, limit int) ([]*models.Notification, int64, error) {
+	filter := bson.M{"user_id": userID, "type": models.NotificationTypeInApp}
+	if unreadOnly {
+		filter["read_at"] = bson.M{"$exists": false}
+	}
+
+	total, err := r.notifications.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.notifications.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []*models.Notification
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+// CountUnread counts a user's unread in-app notifications
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID string) (int64, error) {
+	return r.notifications.CountDocuments(ctx, bson.M{
+		"user_id": userID,
+		"type":    models.NotificationTypeInApp,
+		"read_at": bson.M{"$exists": false},
+	})
+}
+
+// MarkRead marks one of a user's in-app notifications read. Notifications already read keep
+// the time they were first read.
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, id string) (*models.Notification, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid notification ID format")
+	}
+
+	filter := bson.M{"_id": objectID, "user_id": userID, "type": models.NotificationTypeInApp}
+	unread := bson.M{"_id": objectID, "user_id": userID, "type": models.NotificationTypeInApp, "read_at": bson.M{"$exists": false}}
+	if _, err := r.notifications.UpdateOne(ctx, unread, bson.M{"$set": bson.M{"read_at": time.Now()}}); err != nil {
+		return nil, err
+	}
+
+	var notification models.Notification
+	if err := r.notifications.FindOne(ctx, filter).Decode(&notification); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("notification not found")
+		}
+		return nil, err
+	}
+
+	return &notification, nil
+}
+
+// MarkAllRead marks all of a user's unread in-app notifications read and returns how many were
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	result, err := r.notifications.UpdateMany(
+		ctx,
+		bson.M{"user_id": userID, "type": models.NotificationTypeInApp, "read_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"read_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
 // GetPreferences retrieves notification preferences for a user
 func (r *NotificationRepository) GetPreferences(ctx context.Context, userID string) (*models.NotificationPreferences, error) {
 	var prefs models.NotificationPreferences
 	err := r.preferences.FindOne(ctx, bson.M{"user_id": userID}).Decode(&prefs)
+	if err == nil {
+		err = r.decryptPreferences(&prefs)
+	}
 
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -181,17 +278,129 @@ func (r *NotificationRepository) GetPreferences(ctx context.Context, userID stri
 func (r *NotificationRepository) SavePreferences(ctx context.Context, prefs *models.NotificationPreferences) error {
 	prefs.UpdatedAt = time.Now()
 
+	stored := *prefs
+	var err error
+	if stored.EmailAddress, err = r.encryptor.Encrypt(prefs.EmailAddress); err != nil {
+		return err
+	}
+	if stored.PhoneNumber, err = r.encryptor.Encrypt(prefs.PhoneNumber); err != nil {
+		return err
+	}
+	stored.EmailAddressHash = r.encryptor.Hash(prefs.EmailAddress)
+	stored.PhoneNumberHash = r.encryptor.Hash(prefs.PhoneNumber)
+
 	opts := options.Update().SetUpsert(true)
-	_, err := r.preferences.UpdateOne(
+	_, err = r.preferences.UpdateOne(
 		ctx,
 		bson.M{"user_id": prefs.UserID},
-		bson.M{"$set": prefs},
+		bson.M{"$set": &stored},
 		opts,
 	)
 
 	return err
 }
 
+// FindPreferencesByEmailAddress retrieves the preferences of every user notified at an email address
+func (r *NotificationRepository) FindPreferencesByEmailAddress(ctx context.Context, email string) ([]*models.NotificationPreferences, error) {
+	return r.findPreferences(ctx, piiLookupFilter(preferencePIIFields[0], email, r.encryptor))
+}
+
+// FindPreferencesByPhoneNumber retrieves the preferences of every user notified at a phone number
+func (r *NotificationRepository) FindPreferencesByPhoneNumber(ctx context.Context, phoneNumber string) ([]*models.NotificationPreferences, error) {
+	return r.findPreferences(ctx, piiLookupFilter(preferencePIIFields[1], phoneNumber, r.encryptor))
+}
+
+// decryptPreferences decrypts the contact details of preferences read from the database in place
+func (r *NotificationRepository) decryptPreferences(prefs *models.NotificationPreferences) error {
+	var err error
+	if prefs.EmailAddress, err = r.encryptor.Decrypt(prefs.EmailAddress); err != nil {
+		return fmt.Errorf("failed to decrypt notification email address: %w", err)
+	}
+	if prefs.PhoneNumber, err = r.encryptor.Decrypt(prefs.PhoneNumber); err != nil {
+		return fmt.Errorf("failed to decrypt notification phone number: %w", err)
+	}
+	return nil
+}
+
+// CountStalePreferencesPII counts notification preferences whose email address or phone number
+// is plaintext or encrypted with a retired key
+func (r *NotificationRepository) CountStalePreferencesPII(ctx context.Context) (int64, error) {
+	return r.preferences.CountDocuments(ctx, stalePIIFilter(preferencePIIFields, r.encryptor))
+}
+
+// ReencryptPreferencesPII encrypts the stale email addresses and phone numbers of every user's
+// notification preferences with the current key, returning the numbers of preferences
+// re-encrypted and that failed
+func (r *NotificationRepository) ReencryptPreferencesPII(ctx context.Context) (int64, int64, error) {
+	return reencryptPII(ctx, r.preferences, preferencePIIFields, r.encryptor)
+}
+
+// FindPreferencesByPushToken retrieves the preferences of every user a push token is registered to
+func (r *NotificationRepository) FindPreferencesByPushToken(ctx context.Context, token string) ([]*models.NotificationPreferences, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"push_devices.token": token},
+		{"push_device_tokens": token},
+	}}
+	return r.findPreferences(ctx, filter)
+}
+
+// FindPreferencesWithPushDevices retrieves the preferences of users with push devices, legacy
+// push tokens or pruned push devices
+func (r *NotificationRepository) FindPreferencesWithPushDevices(ctx context.Context) ([]*models.NotificationPreferences, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"push_devices.0": bson.M{"$exists": true}},
+		{"push_device_tokens.0": bson.M{"$exists": true}},
+		{"pruned_push_devices.0": bson.M{"$exists": true}},
+	}}
+	return r.findPreferences(ctx, filter)
+}
+
+// findPreferences retrieves the notification preferences matching a filter
+func (r *NotificationRepository) findPreferences(ctx context.Context, filter bson.M) ([]*models.NotificationPreferences, error) {
+	cursor, err := r.preferences.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var prefs []*models.NotificationPreferences
+	if err := cursor.All(ctx, &prefs); err != nil {
+		return nil, err
+	}
+
+	for _, p := range prefs {
+		if err := r.decryptPreferences(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return prefs, nil
+}
+
+// RecordPushDelivery records the outcome of a push notification on the user's device with the
+// token. A success resets the consecutive failures; an empty error message means success.
+func (r *NotificationRepository) RecordPushDelivery(ctx context.Context, userID, token, errorMsg string, at time.Time) error {
+	filter := bson.M{"user_id": userID, "push_devices.token": token}
+
+	update := bson.M{"$set": bson.M{
+		"push_devices.$.last_success_at":      at,
+		"push_devices.$.consecutive_failures": 0,
+		"push_devices.$.last_error":           "",
+	}}
+	if errorMsg != "" {
+		update = bson.M{
+			"$set": bson.M{
+				"push_devices.$.last_failure_at": at,
+				"push_devices.$.last_error":      errorMsg,
+			},
+			"$inc": bson.M{"push_devices.$.consecutive_failures": 1},
+		}
+	}
+
+	_, err := r.preferences.UpdateOne(ctx, filter, update)
+	return err
+}
+
 // GetPaginatedResponse returns a paginated notifications response
 func (r *NotificationRepository) GetPaginatedResponse(ctx context.Context, params models.NotificationLogQueryParams) (*models.PaginatedNotificationsResponse, error) {
 	notifications, total, err := r.FindByUser(ctx, params)
@@ -225,6 +434,32 @@ func (r *NotificationRepository) GetPaginatedResponse(ctx context.Context, param
 	}, nil
 }
 
+// FindForStats retrieves the delivery fields of notifications created in [from, to)
+func (r *NotificationRepository) FindForStats(ctx context.Context, from, to time.Time) ([]*models.Notification, error) {
+	filter := bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}
+	findOptions := options.Find().SetProjection(bson.M{
+		"type":         1,
+		"status":       1,
+		"provider":     1,
+		"sent_at":      1,
+		"delivered_at": 1,
+		"created_at":   1,
+	})
+
+	cursor, err := r.notifications.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []*models.Notification
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
 // CountPendingNotifications counts notifications with pending status
 func (r *NotificationRepository) CountPendingNotifications(ctx context.Context) (int64, error) {
 	return r.notifications.CountDocuments(ctx, bson.M{"status": models.NotificationStatusPending})
@@ -242,3 +477,10 @@ func (r *NotificationRepository) DeleteOlderThan(ctx context.Context, before tim
 
 	return result.DeletedCount, nil
 }
+
+// CountOlderThan counts notifications created before the given time
+func (r *NotificationRepository) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return r.notifications.CountDocuments(ctx, bson.M{
+		"created_at": bson.M{"$lt": before},
+	})
+}
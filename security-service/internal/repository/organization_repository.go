@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"security-service/internal/models"
+)
+
+// OrganizationRepository handles organization database operations
+type OrganizationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(collection *mongo.Collection) *OrganizationRepository {
+	return &OrganizationRepository{collection: collection}
+}
+
+// Create inserts a new organization into the database
+func (r *OrganizationRepository) Create(ctx context.Context, org *models.Organization) (*models.Organization, error) {
+	org.CreatedAt = time.Now()
+	org.UpdatedAt = time.Now()
+	if org.BuildingIDs == nil {
+		org.BuildingIDs = []string{}
+	}
+
+	result, err := r.collection.InsertOne(ctx, org)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("organization with this name already exists")
+		}
+		return nil, err
+	}
+
+	org.ID = result.InsertedID.(primitive.ObjectID)
+	return org, nil
+}
+
+// FindByID retrieves an organization by its ID
+func (r *OrganizationRepository) FindByID(ctx context.Context, id string) (*models.Organization, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid organization ID format")
+	}
+
+	var org models.Organization
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&org); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("organization not found")
+		}
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+// FindAll retrieves organizations with pagination, ordered by name
+func (r *OrganizationRepository) FindAll(ctx context.Context, status string, page, limit int) ([]*models.Organization, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	orgs := []*models.Organization{}
+	if err := cursor.All(ctx, &orgs); err != nil {
+		return nil, 0, err
+	}
+
+	return orgs, total, nil
+}
+
+// FindOwnersOfBuildings retrieves the organizations other than excludeID that own any of the buildings
+func (r *OrganizationRepository) FindOwnersOfBuildings(ctx context.Context, buildingIDs []string, excludeID primitive.ObjectID) ([]*models.Organization, error) {
+	filter := bson.M{
+		"building_ids": bson.M{"$in": buildingIDs},
+		"_id":          bson.M{"$ne": excludeID},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orgs []*models.Organization
+	if err := cursor.All(ctx, &orgs); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// FindWithUsageLimits retrieves the organizations that have monthly usage limits
+func (r *OrganizationRepository) FindWithUsageLimits(ctx context.Context) ([]*models.Organization, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"usage_limits": bson.M{"$exists": true, "$ne": bson.M{}}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orgs := []*models.Organization{}
+	if err := cursor.All(ctx, &orgs); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// Update updates an organization
+func (r *OrganizationRepository) Update(ctx context.Context, id string, updates bson.M) (*models.Organization, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid organization ID format")
+	}
+
+	updates["updated_at"] = time.Now()
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var org models.Organization
+	if err := result.Decode(&org); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("organization not found")
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("organization with this name already exists")
+		}
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+// Delete removes an organization
+func (r *OrganizationRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid organization ID format")
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("organization not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"security-service/pkg/utils"
+)
+
+// piiField is an encrypted document field and the field holding its lookup hash
+type piiField struct {
+	name string
+	hash string
+}
+
+var (
+	userPIIFields       = []piiField{{name: "email", hash: "email_hash"}, {name: "phone_number", hash: "phone_number_hash"}}
+	preferencePIIFields = []piiField{{name: "email_address", hash: "email_address_hash"}, {name: "phone_number", hash: "phone_number_hash"}}
+)
+
+// encryptPIIUpdates encrypts the PII fields set by an update and sets their lookup hashes
+func encryptPIIUpdates(updates bson.M, fields []piiField, encryptor *utils.FieldEncryptor) error {
+	for _, field := range fields {
+		value, ok := updates[field.name].(string)
+		if !ok {
+			continue
+		}
+
+		encrypted, err := encryptor.Encrypt(value)
+		if err != nil {
+			return err
+		}
+		updates[field.name] = encrypted
+		updates[field.hash] = encryptor.Hash(value)
+	}
+	return nil
+}
+
+// piiLookupFilter matches documents whose PII field holds a value, by its hash or, for records
+// written before encryption was enabled, by the plaintext value itself
+func piiLookupFilter(field piiField, value string, encryptor *utils.FieldEncryptor) bson.M {
+	return bson.M{"$or": []bson.M{
+		{field.hash: encryptor.Hash(value)},
+		{field.name: value},
+	}}
+}
+
+// stalePIIFilter matches documents with a PII field that is plaintext or encrypted with a key
+// other than the current one
+func stalePIIFilter(fields []piiField, encryptor *utils.FieldEncryptor) bson.M {
+	current := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(encryptor.CurrentPrefix())}
+
+	conditions := make([]bson.M, len(fields))
+	for i, field := range fields {
+		conditions[i] = bson.M{field.name: bson.M{"$gt": "", "$not": current}}
+	}
+	return bson.M{"$or": conditions}
+}
+
+// reencryptPII encrypts the stale PII fields of every matching document in a collection with
+// the current key. A document is only updated if its fields are unchanged since it was read, so
+// concurrent writes are never overwritten; documents that fail to decrypt are counted as failed
+// and left as they are.
+func reencryptPII(ctx context.Context, collection *mongo.Collection, fields []piiField, encryptor *utils.FieldEncryptor) (reencrypted, failed int64, err error) {
+	cursor, err := collection.Find(ctx, stalePIIFilter(fields, encryptor))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return reencrypted, failed, err
+		}
+
+		filter := bson.M{"_id": doc["_id"]}
+		updates := bson.M{}
+		for _, field := range fields {
+			stored, _ := doc[field.name].(string)
+			if !encryptor.NeedsRotation(stored) {
+				continue
+			}
+
+			value, err := encryptor.Decrypt(stored)
+			if err != nil {
+				log.Printf("Failed to decrypt %s of %s %v: %v", field.name, collection.Name(), doc["_id"], err)
+				updates = nil
+				break
+			}
+			filter[field.name] = stored
+			updates[field.name] = value
+		}
+		if updates == nil {
+			failed++
+			continue
+		}
+		if len(updates) == 0 {
+			continue
+		}
+
+		if err := encryptPIIUpdates(updates, fields, encryptor); err != nil {
+			return reencrypted, failed, err
+		}
+		result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": updates})
+		if err != nil {
+			return reencrypted, failed, err
+		}
+		reencrypted += result.ModifiedCount
+	}
+
+	return reencrypted, failed, cursor.Err()
+}
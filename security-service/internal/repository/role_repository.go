@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"security-service/internal/models"
+	"security-service/internal/tenant"
 )
 
 // RoleRepository handles role database operations
@@ -30,6 +31,9 @@ func (r *RoleRepository) Create(ctx context.Context, role *models.Role) (*models
 	if role.Permissions == nil {
 		role.Permissions = []models.Permission{}
 	}
+	if role.OrgID == "" {
+		role.OrgID = tenant.OrgID(ctx)
+	}
 
 	result, err := r.collection.InsertOne(ctx, role)
 	if err != nil {
@@ -51,7 +55,7 @@ func (r *RoleRepository) FindByID(ctx context.Context, id string) (*models.Role,
 	}
 
 	var role models.Role
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&role)
+	err = r.collection.FindOne(ctx, tenant.SharedFilter(ctx, bson.M{"_id": objectID})).Decode(&role)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("role not found")
@@ -65,7 +69,7 @@ func (r *RoleRepository) FindByID(ctx context.Context, id string) (*models.Role,
 // FindByName retrieves a role by its name
 func (r *RoleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
 	var role models.Role
-	err := r.collection.FindOne(ctx, bson.M{"name": name}).Decode(&role)
+	err := r.collection.FindOne(ctx, tenant.SharedFilter(ctx, bson.M{"name": name})).Decode(&role)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.New("role not found")
@@ -76,11 +80,11 @@ func (r *RoleRepository) FindByName(ctx context.Context, name string) (*models.R
 	return &role, nil
 }
 
-// FindAll retrieves all roles
+// FindAll retrieves all roles, limited to the shared roles and the organization's own when scoped
 func (r *RoleRepository) FindAll(ctx context.Context) ([]*models.Role, error) {
 	findOptions := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, findOptions)
+	cursor, err := r.collection.Find(ctx, tenant.SharedFilter(ctx, bson.M{}), findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +100,7 @@ func (r *RoleRepository) FindAll(ctx context.Context) ([]*models.Role, error) {
 
 // FindByNames retrieves multiple roles by their names
 func (r *RoleRepository) FindByNames(ctx context.Context, names []string) ([]*models.Role, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"name": bson.M{"$in": names}})
+	cursor, err := r.collection.Find(ctx, tenant.SharedFilter(ctx, bson.M{"name": bson.M{"$in": names}}))
 	if err != nil {
 		return nil, err
 	}
@@ -110,13 +114,13 @@ func (r *RoleRepository) FindByNames(ctx context.Context, names []string) ([]*mo
 	return roles, nil
 }
 
-// Update updates an existing role by name
+// Update updates an existing role by name. Organizations can only update their own roles.
 func (r *RoleRepository) Update(ctx context.Context, name string, updates bson.M) (*models.Role, error) {
 	updates["updated_at"] = time.Now()
 
 	result := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"name": name},
+		tenant.Filter(ctx, bson.M{"name": name}),
 		bson.M{"$set": updates},
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	)
@@ -132,11 +136,11 @@ func (r *RoleRepository) Update(ctx context.Context, name string, updates bson.M
 	return &role, nil
 }
 
-// Delete removes a role from the database by name
+// Delete removes a role from the database by name. Organizations can only delete their own roles.
 func (r *RoleRepository) Delete(ctx context.Context, name string) error {
 	// First check if it's a system role
 	var role models.Role
-	err := r.collection.FindOne(ctx, bson.M{"name": name}).Decode(&role)
+	err := r.collection.FindOne(ctx, tenant.Filter(ctx, bson.M{"name": name})).Decode(&role)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return errors.New("role not found")
@@ -148,7 +152,7 @@ func (r *RoleRepository) Delete(ctx context.Context, name string) error {
 		return errors.New("cannot delete system role")
 	}
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"name": name})
+	result, err := r.collection.DeleteOne(ctx, tenant.Filter(ctx, bson.M{"name": name}))
 	if err != nil {
 		return err
 	}
@@ -177,6 +181,14 @@ func (r *RoleRepository) InitializeDefaultRoles(ctx context.Context) error {
 				{Resource: "*", Actions: []string{"*"}},
 			},
 		},
+		{
+			Name:        models.RoleSuperAdmin,
+			Description: "Platform operator managing organizations, not scoped to any of them",
+			IsSystem:    true,
+			Permissions: []models.Permission{
+				{Resource: "*", Actions: []string{"*"}},
+			},
+		},
 		{
 			Name:        "user",
 			Description: "Standard user with basic access",
@@ -195,6 +207,7 @@ func (r *RoleRepository) InitializeDefaultRoles(ctx context.Context) error {
 				{Resource: "energy", Actions: []string{"read"}},
 				{Resource: "reports", Actions: []string{"read", "write"}},
 				{Resource: "alerts", Actions: []string{"read", "write"}},
+				{Resource: "finance", Actions: []string{"read"}},
 			},
 		},
 		{
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"security-service/internal/models"
+)
+
+// SigningKeyRepository handles token signing key database operations
+type SigningKeyRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSigningKeyRepository creates a new signing key repository
+func NewSigningKeyRepository(collection *mongo.Collection) *SigningKeyRepository {
+	return &SigningKeyRepository{collection: collection}
+}
+
+// Create inserts a new signing key
+func (r *SigningKeyRepository) Create(ctx context.Context, key *models.SigningKey) (*models.SigningKey, error) {
+	result, err := r.collection.InsertOne(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	key.ID = result.InsertedID.(primitive.ObjectID)
+	return key, nil
+}
+
+// FindAll retrieves all signing keys, newest first
+func (r *SigningKeyRepository) FindAll(ctx context.Context) ([]*models.SigningKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []*models.SigningKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RetireOlderThan retires active keys created before the given time. Only older keys are retired,
+// so concurrent rotations leave the newest key active.
+func (r *SigningKeyRepository) RetireOlderThan(ctx context.Context, before, retiredAt, expiresAt time.Time) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{
+			"status":     models.SigningKeyStatusActive,
+			"created_at": bson.M{"$lt": before},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":     models.SigningKeyStatusRetired,
+				"retired_at": retiredAt,
+				"expires_at": expiresAt,
+			},
+		},
+	)
+	return err
+}
+
+// RetireConfigured records that the configured key was rotated out. The first rotation wins,
+// so the configured key keeps its original grace period.
+func (r *SigningKeyRepository) RetireConfigured(ctx context.Context, keyID, algorithm string, retiredAt, expiresAt time.Time) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"key_id": keyID},
+		bson.M{
+			"$setOnInsert": bson.M{
+				"key_id":     keyID,
+				"algorithm":  algorithm,
+				"status":     models.SigningKeyStatusRetired,
+				"created_at": retiredAt,
+				"retired_at": retiredAt,
+				"expires_at": expiresAt,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"security-service/internal/models"
+)
+
+// UsageRepository handles metered usage database operations: daily usage counters, the daily
+// snapshots invoices are built from and the limit alerts already sent
+type UsageRepository struct {
+	counters  *mongo.Collection
+	snapshots *mongo.Collection
+	alerts    *mongo.Collection
+}
+
+// NewUsageRepository creates a new usage repository
+func NewUsageRepository(counters, snapshots, alerts *mongo.Collection) *UsageRepository {
+	return &UsageRepository{
+		counters:  counters,
+		snapshots: snapshots,
+		alerts:    alerts,
+	}
+}
+
+// counterFilter identifies the counter of a metric of an organization's building on a day
+func counterFilter(orgID, buildingID, date, metric string) bson.M {
+	return bson.M{"org_id": orgID, "building_id": buildingID, "date": date, "metric": metric}
+}
+
+// Add adds n to the usage of a metric on a day, creating the counter when it is the first usage
+func (r *UsageRepository) Add(ctx context.Context, orgID, buildingID, date, metric string, n int64) error {
+	_, err := r.counters.UpdateOne(
+		ctx,
+		counterFilter(orgID, buildingID, date, metric),
+		bson.M{"$inc": bson.M{"value": n}, "$set": bson.M{"updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// RecordPeak raises the usage of a level metric on a day to value if it is higher
+func (r *UsageRepository) RecordPeak(ctx context.Context, orgID, buildingID, date, metric string, value int64) error {
+	_, err := r.counters.UpdateOne(
+		ctx,
+		counterFilter(orgID, buildingID, date, metric),
+		bson.M{"$max": bson.M{"value": value}, "$set": bson.M{"updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// FindCounters retrieves the usage counters of the days from fromDate to toDate, inclusive.
+// An empty orgID or buildingID matches every organization or building.
+func (r *UsageRepository) FindCounters(ctx context.Context, orgID, buildingID, fromDate, toDate string) ([]*models.UsageCounter, error) {
+	filter := bson.M{"date": bson.M{"$gte": fromDate, "$lte": toDate}}
+	if orgID != "" {
+		filter["org_id"] = orgID
+	}
+	if buildingID != "" {
+		filter["building_id"] = buildingID
+	}
+
+	cursor, err := r.counters.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counters := []*models.UsageCounter{}
+	if err := cursor.All(ctx, &counters); err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
+
+// FindOrgIDs retrieves the organizations with usage on a day
+func (r *UsageRepository) FindOrgIDs(ctx context.Context, date string) ([]string, error) {
+	values, err := r.counters.Distinct(ctx, "org_id", bson.M{"date": date})
+	if err != nil {
+		return nil, err
+	}
+
+	orgIDs := make([]string, 0, len(values))
+	for _, value := range values {
+		if orgID, ok := value.(string); ok && orgID != "" {
+			orgIDs = append(orgIDs, orgID)
+		}
+	}
+	return orgIDs, nil
+}
+
+// CreateSnapshot stores the usage snapshot of an organization's day unless one was already
+// taken, and reports whether it was stored
+func (r *UsageRepository) CreateSnapshot(ctx context.Context, snapshot *models.UsageSnapshot) (bool, error) {
+	snapshot.CreatedAt = time.Now()
+
+	if _, err := r.snapshots.InsertOne(ctx, snapshot); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// FindSnapshots retrieves the usage snapshots of the days from fromDate to toDate, inclusive,
+// ordered by day. An empty orgID matches every organization.
+func (r *UsageRepository) FindSnapshots(ctx context.Context, orgID, fromDate, toDate string) ([]*models.UsageSnapshot, error) {
+	filter := bson.M{"date": bson.M{"$gte": fromDate, "$lte": toDate}}
+	if orgID != "" {
+		filter["org_id"] = orgID
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "org_id", Value: 1}})
+	cursor, err := r.snapshots.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	snapshots := []*models.UsageSnapshot{}
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// CreateAlert records a usage limit alert unless the threshold was already alerted in the
+// month, and reports whether it was recorded
+func (r *UsageRepository) CreateAlert(ctx context.Context, alert *models.UsageAlert) (bool, error) {
+	alert.CreatedAt = time.Now()
+
+	if _, err := r.alerts.InsertOne(ctx, alert); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,16 +12,86 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"security-service/internal/models"
+	"security-service/internal/tenant"
+	"security-service/pkg/utils"
 )
 
-// UserRepository handles user database operations
+// UserRepository handles user database operations.
+// Emails and phone numbers are encrypted on write and decrypted on read.
 type UserRepository struct {
 	collection *mongo.Collection
+	encryptor  *utils.FieldEncryptor
 }
 
 // NewUserRepository creates a new user repository
-func NewUserRepository(collection *mongo.Collection) *UserRepository {
-	return &UserRepository{collection: collection}
+func NewUserRepository(collection *mongo.Collection, encryptor *utils.FieldEncryptor) *UserRepository {
+	return &UserRepository{collection: collection, encryptor: encryptor}
+}
+
+// encrypt returns a copy of a user with its PII encrypted and lookup hashes set
+func (r *UserRepository) encrypt(user *models.User) (*models.User, error) {
+	stored := *user
+	var err error
+	if stored.Email, err = r.encryptor.Encrypt(user.Email); err != nil {
+		return nil, err
+	}
+	if stored.PhoneNumber, err = r.encryptor.Encrypt(user.PhoneNumber); err != nil {
+		return nil, err
+	}
+	stored.EmailHash = r.encryptor.Hash(user.Email)
+	stored.PhoneNumberHash = r.encryptor.Hash(user.PhoneNumber)
+	return &stored, nil
+}
+
+// decrypt decrypts the PII of users read from the database in place
+func (r *UserRepository) decrypt(users ...*models.User) error {
+	for _, user := range users {
+		var err error
+		if user.Email, err = r.encryptor.Decrypt(user.Email); err != nil {
+			return fmt.Errorf("failed to decrypt user email: %w", err)
+		}
+		if user.PhoneNumber, err = r.encryptor.Decrypt(user.PhoneNumber); err != nil {
+			return fmt.Errorf("failed to decrypt user phone number: %w", err)
+		}
+	}
+	return nil
+}
+
+// decodeOne decodes and decrypts a single user result, reporting a missing user with the message
+func (r *UserRepository) decodeOne(result *mongo.SingleResult, notFound string) (*models.User, error) {
+	var user models.User
+	if err := result.Decode(&user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New(notFound)
+		}
+		return nil, err
+	}
+
+	if err := r.decrypt(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// decodeAll decodes and decrypts every user of a cursor
+func (r *UserRepository) decodeAll(ctx context.Context, cursor *mongo.Cursor) ([]*models.User, error) {
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	if err := r.decrypt(users...); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// notDeleted restricts a filter to users that have not been soft deleted
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	return filter
 }
 
 // Create inserts a new user into the database
@@ -30,8 +101,16 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) (*models
 	if user.Roles == nil {
 		user.Roles = []string{}
 	}
+	if user.OrgID == "" {
+		user.OrgID = tenant.OrgID(ctx)
+	}
 
-	result, err := r.collection.InsertOne(ctx, user)
+	stored, err := r.encrypt(user)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.collection.InsertOne(ctx, stored)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			return nil, errors.New("user with this username or email already exists")
@@ -50,48 +129,43 @@ func (r *UserRepository) FindByID(ctx context.Context, id string) (*models.User,
 		return nil, errors.New("invalid user ID format")
 	}
 
-	var user models.User
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
-	}
-
-	return &user, nil
+	return r.decodeOne(r.collection.FindOne(ctx, tenant.Filter(ctx, notDeleted(bson.M{"_id": objectID}))), "user not found")
 }
 
 // FindByUsername retrieves a user by their username
 func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
-	var user models.User
-	err := r.collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
-	}
-
-	return &user, nil
+	return r.decodeOne(r.collection.FindOne(ctx, notDeleted(bson.M{"username": username})), "user not found")
 }
 
 // FindByEmail retrieves a user by their email
 func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
-	var user models.User
-	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	filter := piiLookupFilter(userPIIFields[0], email, r.encryptor)
+	return r.decodeOne(r.collection.FindOne(ctx, notDeleted(filter)), "user not found")
+}
+
+// FindAll retrieves all users with pagination, excluding soft-deleted users
+func (r *UserRepository) FindAll(ctx context.Context, page, limit int) ([]*models.User, int64, error) {
+	return r.findPage(ctx, tenant.Filter(ctx, notDeleted(bson.M{})), "created_at", page, limit)
+}
+
+// FindAllAccounts retrieves every user that has not been soft deleted, oldest first
+func (r *UserRepository) FindAllAccounts(ctx context.Context) ([]*models.User, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, tenant.Filter(ctx, notDeleted(bson.M{})), findOptions)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
-		}
 		return nil, err
 	}
 
-	return &user, nil
+	return r.decodeAll(ctx, cursor)
 }
 
-// FindAll retrieves all users with pagination
-func (r *UserRepository) FindAll(ctx context.Context, page, limit int) ([]*models.User, int64, error) {
+// FindDeleted retrieves soft-deleted users with pagination, most recently deleted first
+func (r *UserRepository) FindDeleted(ctx context.Context, page, limit int) ([]*models.User, int64, error) {
+	return r.findPage(ctx, tenant.Filter(ctx, bson.M{"deleted_at": bson.M{"$ne": nil}}), "deleted_at", page, limit)
+}
+
+// findPage retrieves users matching a filter with pagination, sorted descending by a field
+func (r *UserRepository) findPage(ctx context.Context, filter bson.M, sortField string, page, limit int) ([]*models.User, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -102,7 +176,7 @@ func (r *UserRepository) FindAll(ctx context.Context, page, limit int) ([]*model
 	skip := int64((page - 1) * limit)
 
 	// Get total count
-	total, err := r.collection.CountDocuments(ctx, bson.M{})
+	total, err := r.collection.CountDocuments(ctx, filter)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -111,16 +185,15 @@ func (r *UserRepository) FindAll(ctx context.Context, page, limit int) ([]*model
 	findOptions := options.Find().
 		SetSkip(skip).
 		SetLimit(int64(limit)).
-		SetSort(bson.D{{Key: "created_at", Value: -1}})
+		SetSort(bson.D{{Key: sortField, Value: -1}})
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, findOptions)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, 0, err
 	}
-	defer cursor.Close(ctx)
 
-	var users []*models.User
-	if err := cursor.All(ctx, &users); err != nil {
+	users, err := r.decodeAll(ctx, cursor)
+	if err != nil {
 		return nil, 0, err
 	}
 
@@ -134,45 +207,74 @@ func (r *UserRepository) Update(ctx context.Context, id string, updates bson.M)
 		return nil, errors.New("invalid user ID format")
 	}
 
+	if err := encryptPIIUpdates(updates, userPIIFields, r.encryptor); err != nil {
+		return nil, err
+	}
 	updates["updated_at"] = time.Now()
 
 	result := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"_id": objectID},
+		tenant.Filter(ctx, notDeleted(bson.M{"_id": objectID})),
 		bson.M{"$set": updates},
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	)
 
-	var user models.User
-	if err := result.Decode(&user); err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
-	}
-
-	return &user, nil
+	return r.decodeOne(result, "user not found")
 }
 
-// Delete removes a user from the database
-func (r *UserRepository) Delete(ctx context.Context, id string) error {
+// Delete soft deletes a user, keeping the record for audit trails until it is purged
+func (r *UserRepository) Delete(ctx context.Context, id, deletedBy string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.New("invalid user ID format")
 	}
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	now := time.Now()
+	result, err := r.collection.UpdateOne(
+		ctx,
+		tenant.Filter(ctx, notDeleted(bson.M{"_id": objectID})),
+		bson.M{"$set": bson.M{"deleted_at": now, "deleted_by": deletedBy, "updated_at": now}},
+	)
 	if err != nil {
 		return err
 	}
 
-	if result.DeletedCount == 0 {
+	if result.MatchedCount == 0 {
 		return errors.New("user not found")
 	}
 
 	return nil
 }
 
+// Restore reverses a soft delete
+func (r *UserRepository) Restore(ctx context.Context, id string) (*models.User, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		tenant.Filter(ctx, bson.M{"_id": objectID, "deleted_at": bson.M{"$ne": nil}}),
+		bson.M{
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	return r.decodeOne(result, "deleted user not found")
+}
+
+// PurgeDeletedBefore permanently removes users soft deleted before the given time
+func (r *UserRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
 // UpdateLastLogin updates the last login timestamp for a user
 func (r *UserRepository) UpdateLastLogin(ctx context.Context, id string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -183,37 +285,106 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, id string) error {
 	now := time.Now()
 	_, err = r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": objectID},
+		notDeleted(bson.M{"_id": objectID}),
 		bson.M{"$set": bson.M{"last_login_at": now, "updated_at": now}},
 	)
 
 	return err
 }
 
-// ExistsByUsername checks if a user exists with the given username
+// ExistsByUsername checks if a user exists with the given username.
+// Soft-deleted users are included since their username stays reserved until purged.
 func (r *UserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	count, err := r.collection.CountDocuments(ctx, bson.M{"username": username})
 	return count > 0, err
 }
 
-// ExistsByEmail checks if a user exists with the given email
+// ExistsByEmail checks if a user exists with the given email, including soft-deleted users
 func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
-	count, err := r.collection.CountDocuments(ctx, bson.M{"email": email})
+	count, err := r.collection.CountDocuments(ctx, piiLookupFilter(userPIIFields[0], email, r.encryptor))
 	return count > 0, err
 }
 
 // FindByRoles finds all users with specific roles
 func (r *UserRepository) FindByRoles(ctx context.Context, roles []string) ([]*models.User, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"roles": bson.M{"$in": roles}})
+	cursor, err := r.collection.Find(ctx, tenant.Filter(ctx, notDeleted(bson.M{"roles": bson.M{"$in": roles}})))
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var users []*models.User
-	if err := cursor.All(ctx, &users); err != nil {
+	return r.decodeAll(ctx, cursor)
+}
+
+// FindByGroups finds all users belonging directly to any of the groups
+func (r *UserRepository) FindByGroups(ctx context.Context, groups []string) ([]*models.User, error) {
+	cursor, err := r.collection.Find(ctx, tenant.Filter(ctx, notDeleted(bson.M{"groups": bson.M{"$in": groups}})))
+	if err != nil {
 		return nil, err
 	}
 
-	return users, nil
+	return r.decodeAll(ctx, cursor)
+}
+
+// CountByGroup counts the users belonging directly to a group
+func (r *UserRepository) CountByGroup(ctx context.Context, group string) (int64, error) {
+	return r.collection.CountDocuments(ctx, tenant.Filter(ctx, notDeleted(bson.M{"groups": group})))
+}
+
+// CountByOrg counts the users of an organization, including soft-deleted users
+func (r *UserRepository) CountByOrg(ctx context.Context, orgID string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"org_id": orgID})
+}
+
+// AddGroup adds a user to a group
+func (r *UserRepository) AddGroup(ctx context.Context, id, group string) error {
+	return r.updateGroups(ctx, id, bson.M{"$addToSet": bson.M{"groups": group}})
+}
+
+// RemoveGroup removes a user from a group
+func (r *UserRepository) RemoveGroup(ctx context.Context, id, group string) error {
+	return r.updateGroups(ctx, id, bson.M{"$pull": bson.M{"groups": group}})
+}
+
+// updateGroups applies a membership change to a user
+func (r *UserRepository) updateGroups(ctx context.Context, id string, update bson.M) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	update["$set"] = bson.M{"updated_at": time.Now()}
+	result, err := r.collection.UpdateOne(ctx, tenant.Filter(ctx, notDeleted(bson.M{"_id": objectID})), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
+
+// RemoveGroupFromAll removes every user from a group
+func (r *UserRepository) RemoveGroupFromAll(ctx context.Context, group string) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		tenant.Filter(ctx, bson.M{"groups": group}),
+		bson.M{
+			"$pull": bson.M{"groups": group},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+// CountStalePII counts users, including soft-deleted users, whose email or phone number is
+// plaintext or encrypted with a retired key
+func (r *UserRepository) CountStalePII(ctx context.Context) (int64, error) {
+	return r.collection.CountDocuments(ctx, stalePIIFilter(userPIIFields, r.encryptor))
+}
+
+// ReencryptPII encrypts the stale emails and phone numbers of every user, including soft-deleted
+// users, with the current key, returning the numbers of users re-encrypted and that failed
+func (r *UserRepository) ReencryptPII(ctx context.Context) (int64, int64, error) {
+	return reencryptPII(ctx, r.collection, userPIIFields, r.encryptor)
 }
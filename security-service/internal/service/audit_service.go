@@ -7,6 +7,13 @@ import (
 	"security-service/internal/repository"
 )
 
+const (
+	// maxCursorPageSize is the largest page served by cursor pagination
+	maxCursorPageSize = 1000
+	// exportBatchSize is the number of documents fetched per round trip while exporting
+	exportBatchSize = 500
+)
+
 // AuditService handles audit logging business logic
 type AuditService struct {
 	auditRepo *repository.AuditRepository
@@ -48,6 +55,58 @@ func (s *AuditService) GetLogs(ctx context.Context, params models.AuditLogQueryP
 	return s.auditRepo.GetPaginatedResponse(ctx, params)
 }
 
+// GetLogsByCursor retrieves audit logs using cursor-based pagination.
+// An empty cursor token starts from the most recent entry.
+func (s *AuditService) GetLogsByCursor(ctx context.Context, params models.AuditLogQueryParams, cursorToken string, limit int) (*models.CursorAuditLogsResponse, error) {
+	if limit < 1 || limit > maxCursorPageSize {
+		limit = 20
+	}
+
+	var cursor *models.AuditLogCursor
+	if cursorToken != "" {
+		var err error
+		cursor, err = models.DecodeAuditLogCursor(cursorToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Fetch one extra entry to know whether another page exists
+	logs, err := s.auditRepo.FindAfter(ctx, params, cursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(logs) > limit
+	if hasMore {
+		logs = logs[:limit]
+	}
+
+	logResponses := make([]*models.AuditLogResponse, len(logs))
+	for i, log := range logs {
+		logResponses[i] = log.ToResponse()
+	}
+
+	response := &models.CursorAuditLogsResponse{
+		Logs:    logResponses,
+		HasMore: hasMore,
+		Limit:   limit,
+	}
+	if hasMore {
+		last := logs[len(logs)-1]
+		response.NextCursor = models.AuditLogCursor{Timestamp: last.Timestamp, ID: last.ID}.Encode()
+	}
+
+	return response, nil
+}
+
+// ExportLogs streams every audit log matching the filters to fn, newest first
+func (s *AuditService) ExportLogs(ctx context.Context, params models.AuditLogQueryParams, fn func(*models.AuditLogResponse) error) error {
+	return s.auditRepo.Stream(ctx, params, exportBatchSize, func(log *models.AuditLog) error {
+		return fn(log.ToResponse())
+	})
+}
+
 // GetLogByID retrieves a specific audit log by ID
 func (s *AuditService) GetLogByID(ctx context.Context, id string) (*models.AuditLogResponse, error) {
 	log, err := s.auditRepo.FindByID(ctx, id)
@@ -7,8 +7,10 @@ import (
 	"log"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"security-service/internal/integrations"
 	"security-service/internal/models"
 	"security-service/internal/repository"
 	"security-service/pkg/utils"
@@ -16,27 +18,46 @@ import (
 
 // AuthService handles authentication business logic
 type AuthService struct {
-	userRepo   *repository.UserRepository
-	roleRepo   *repository.RoleRepository
-	authRepo   *repository.AuthRepository
-	auditRepo  *repository.AuditRepository
-	jwtManager *utils.JWTManager
+	userRepo         *repository.UserRepository
+	roleRepo         *repository.RoleRepository
+	groupRepo        *repository.GroupRepository
+	authRepo         *repository.AuthRepository
+	auditRepo        *repository.AuditRepository
+	notificationRepo *repository.NotificationRepository
+	kioskRepo        *repository.KioskRepository
+	orgRepo          *repository.OrganizationRepository
+	jwtManager       *utils.JWTManager
+	roleChanges      *integrations.RoleChangePublisher
+
+	impersonationExpiry time.Duration
 }
 
 // NewAuthService creates a new authentication service
 func NewAuthService(
 	userRepo *repository.UserRepository,
 	roleRepo *repository.RoleRepository,
+	groupRepo *repository.GroupRepository,
 	authRepo *repository.AuthRepository,
 	auditRepo *repository.AuditRepository,
+	notificationRepo *repository.NotificationRepository,
+	kioskRepo *repository.KioskRepository,
+	orgRepo *repository.OrganizationRepository,
 	jwtManager *utils.JWTManager,
+	roleChanges *integrations.RoleChangePublisher,
+	impersonationExpiry time.Duration,
 ) *AuthService {
 	return &AuthService{
-		userRepo:   userRepo,
-		roleRepo:   roleRepo,
-		authRepo:   authRepo,
-		auditRepo:  auditRepo,
-		jwtManager: jwtManager,
+		userRepo:            userRepo,
+		roleRepo:            roleRepo,
+		groupRepo:           groupRepo,
+		authRepo:            authRepo,
+		auditRepo:           auditRepo,
+		notificationRepo:    notificationRepo,
+		kioskRepo:           kioskRepo,
+		orgRepo:             orgRepo,
+		jwtManager:          jwtManager,
+		roleChanges:         roleChanges,
+		impersonationExpiry: impersonationExpiry,
 	}
 }
 
@@ -61,8 +82,14 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, ipAdd
 		return nil, errors.New("invalid username or password")
 	}
 
-	// Generate access token
-	accessToken, err := s.jwtManager.GenerateAccessToken(user)
+	// Generate access token carrying the roles inherited from the user's groups
+	user = resolveMemberships(ctx, s.groupRepo, user)
+	scope, err := s.tenantScope(ctx, user)
+	if err != nil {
+		s.logAuditEvent(ctx, user.ID.Hex(), user.Username, "LOGIN", "auth", "FAILURE", err.Error(), ipAddress, userAgent)
+		return nil, err
+	}
+	accessToken, err := s.jwtManager.GenerateAccessTokenWithMasks(user, scope, s.responseMasks(ctx, user.Roles))
 	if err != nil {
 		return nil, errors.New("failed to generate access token")
 	}
@@ -133,7 +160,12 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *models.RefreshToken
 	}
 
 	// Generate new access token
-	accessToken, err := s.jwtManager.GenerateAccessToken(user)
+	user = resolveMemberships(ctx, s.groupRepo, user)
+	scope, err := s.tenantScope(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.jwtManager.GenerateAccessTokenWithMasks(user, scope, s.responseMasks(ctx, user.Roles))
 	if err != nil {
 		return nil, errors.New("failed to generate access token")
 	}
@@ -168,6 +200,10 @@ func (s *AuthService) ValidateToken(ctx context.Context, token string) (*models.
 		}, nil
 	}
 
+	if claims.Kiosk != nil {
+		return s.validateKioskToken(ctx, claims), nil
+	}
+
 	// Verify user still exists and is active
 	user, err := s.userRepo.FindByID(ctx, claims.UserID)
 	if err != nil {
@@ -184,13 +220,255 @@ func (s *AuthService) ValidateToken(ctx context.Context, token string) (*models.
 		}, nil
 	}
 
+	if user.PasswordChangedAt != nil && claims.IssuedAt != nil && claims.IssuedAt.Time.Before(*user.PasswordChangedAt) {
+		return &models.TokenValidationResponse{
+			Valid:   false,
+			Message: "token was issued before the last password change",
+		}, nil
+	}
+
+	if claims.Impersonation != nil {
+		if err := s.checkImpersonator(ctx, claims.Impersonation.ImpersonatorID); err != nil {
+			return &models.TokenValidationResponse{
+				Valid:   false,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	if claims.Tenant != nil {
+		if org, err := s.orgRepo.FindByID(ctx, claims.Tenant.OrgID); err != nil || !org.IsActive() {
+			return &models.TokenValidationResponse{
+				Valid:   false,
+				Message: "organization is suspended",
+			}, nil
+		}
+	}
+
+	return &models.TokenValidationResponse{
+		Valid:         true,
+		UserID:        claims.UserID,
+		Roles:         claims.Roles,
+		Groups:        claims.Groups,
+		Impersonation: claims.Impersonation,
+		Tenant:        claims.Tenant,
+		Masks:         s.responseMasks(ctx, claims.Roles),
+	}, nil
+}
+
+// validateKioskToken checks that the kiosk token behind the claims has not been revoked
+func (s *AuthService) validateKioskToken(ctx context.Context, claims *utils.CustomClaims) *models.TokenValidationResponse {
+	kiosk, err := s.kioskRepo.FindByID(ctx, claims.Kiosk.TokenID)
+	if err != nil {
+		return &models.TokenValidationResponse{
+			Valid:   false,
+			Message: "kiosk token not found",
+		}
+	}
+
+	if !kiosk.IsActive() {
+		return &models.TokenValidationResponse{
+			Valid:   false,
+			Message: "kiosk token has been revoked",
+		}
+	}
+
 	return &models.TokenValidationResponse{
 		Valid:  true,
 		UserID: claims.UserID,
 		Roles:  claims.Roles,
+		Kiosk:  claims.Kiosk,
+		// Kiosks show dashboards on public screens, so nothing sensitive is revealed
+		Masks: models.ResponseMasks(nil),
+	}
+}
+
+// responseMasks returns the response fields holders of the roles may not see. If the roles
+// cannot be loaded, everything sensitive is masked.
+func (s *AuthService) responseMasks(ctx context.Context, roleNames []string) map[string]string {
+	roles, err := s.roleRepo.FindByNames(ctx, roleNames)
+	if err != nil {
+		log.Printf("Failed to load roles for response masking: %v", err)
+		return models.ResponseMasks(nil)
+	}
+	return models.ResponseMasks(roles)
+}
+
+// tenantScope returns the tenant claim of a user's access tokens: the organization they belong
+// to and its buildings. Super admins and users outside any organization get none, and members
+// of suspended organizations cannot obtain tokens.
+func (s *AuthService) tenantScope(ctx context.Context, user *models.User) (*models.TenantScope, error) {
+	if user.OrgID == "" || user.HasRole(models.RoleSuperAdmin) {
+		return nil, nil
+	}
+
+	org, err := s.orgRepo.FindByID(ctx, user.OrgID)
+	if err != nil {
+		log.Printf("Failed to load organization %s of user %s: %v", user.OrgID, user.ID.Hex(), err)
+		return nil, errors.New("organization not found")
+	}
+	if !org.IsActive() {
+		return nil, errors.New("organization is suspended")
+	}
+	return org.Scope(), nil
+}
+
+// Impersonate issues a short-lived access token that lets an admin act as another user.
+// The token carries the admin's identity and no refresh token is issued.
+func (s *AuthService) Impersonate(ctx context.Context, impersonatorID, targetUserID, reason, ipAddress, userAgent string) (*models.ImpersonateResponse, error) {
+	impersonator, err := s.userRepo.FindByID(ctx, impersonatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if targetUserID == impersonatorID {
+		s.logImpersonationEvent(ctx, impersonator, targetUserID, reason, "FAILURE", "cannot impersonate yourself", ipAddress, userAgent)
+		return nil, errors.New("cannot impersonate yourself")
+	}
+
+	target, err := s.userRepo.FindByID(ctx, targetUserID)
+	if err != nil {
+		s.logImpersonationEvent(ctx, impersonator, targetUserID, reason, "FAILURE", err.Error(), ipAddress, userAgent)
+		return nil, err
+	}
+	target = resolveMemberships(ctx, s.groupRepo, target)
+
+	if !target.IsActive {
+		s.logImpersonationEvent(ctx, impersonator, targetUserID, reason, "FAILURE", "account is disabled", ipAddress, userAgent)
+		return nil, errors.New("account is disabled")
+	}
+
+	if target.HasRole("admin") || target.HasRole(models.RoleSuperAdmin) {
+		s.logImpersonationEvent(ctx, impersonator, targetUserID, reason, "FAILURE", "cannot impersonate an admin", ipAddress, userAgent)
+		return nil, errors.New("cannot impersonate an admin")
+	}
+
+	impersonation := &models.Impersonation{
+		ImpersonatorID:       impersonator.ID.Hex(),
+		ImpersonatorUsername: impersonator.Username,
+		Reason:               reason,
+		Banner:               "Viewing as " + target.Username + " (impersonated by " + impersonator.Username + ")",
+	}
+
+	scope, err := s.tenantScope(ctx, target)
+	if err != nil {
+		s.logImpersonationEvent(ctx, impersonator, targetUserID, reason, "FAILURE", err.Error(), ipAddress, userAgent)
+		return nil, err
+	}
+
+	accessToken, err := s.jwtManager.GenerateImpersonationToken(target, scope, impersonation, s.impersonationExpiry)
+	if err != nil {
+		return nil, errors.New("failed to generate access token")
+	}
+
+	s.logImpersonationEvent(ctx, impersonator, targetUserID, reason, "SUCCESS", "", ipAddress, userAgent)
+
+	return &models.ImpersonateResponse{
+		AccessToken:   accessToken,
+		TokenType:     "Bearer",
+		ExpiresIn:     int64(s.impersonationExpiry.Seconds()),
+		UserID:        target.ID.Hex(),
+		Roles:         target.Roles,
+		Impersonation: impersonation,
 	}, nil
 }
 
+// delegatedTokenExpiry bounds a delegated token to about one run of a queued job
+const delegatedTokenExpiry = 15 * time.Minute
+
+// DelegateToken issues a short-lived access token for a user on whose behalf a service queued
+// work. The user must still be active and belong to the organization the work was queued in, so
+// queued work loses access together with its submitter. No refresh token is issued.
+func (s *AuthService) DelegateToken(ctx context.Context, req *models.DelegateTokenRequest, callerService, ipAddress string) (*models.DelegateTokenResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	fail := func(reason string) (*models.DelegateTokenResponse, error) {
+		s.logAuditEvent(ctx, user.ID.Hex(), user.Username, "DELEGATE_TOKEN", "auth", "FAILURE", reason, ipAddress, callerService)
+		return nil, errors.New(reason)
+	}
+	if !user.IsActive {
+		return fail("account is disabled")
+	}
+	if user.OrgID != req.OrgID {
+		return fail("user no longer belongs to the organization")
+	}
+
+	user = resolveMemberships(ctx, s.groupRepo, user)
+	scope, err := s.tenantScope(ctx, user)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	accessToken, err := s.jwtManager.GenerateDelegatedToken(user, scope, s.responseMasks(ctx, user.Roles), delegatedTokenExpiry)
+	if err != nil {
+		return nil, errors.New("failed to generate access token")
+	}
+
+	s.logAuditEvent(ctx, user.ID.Hex(), user.Username, "DELEGATE_TOKEN", "auth", "SUCCESS", "", ipAddress, callerService)
+
+	return &models.DelegateTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(delegatedTokenExpiry.Seconds()),
+	}, nil
+}
+
+// checkImpersonator verifies that the admin behind an impersonation token may still impersonate
+func (s *AuthService) checkImpersonator(ctx context.Context, impersonatorID string) error {
+	impersonator, err := s.userRepo.FindByID(ctx, impersonatorID)
+	if err != nil {
+		return errors.New("impersonator not found")
+	}
+	impersonator = resolveMemberships(ctx, s.groupRepo, impersonator)
+	if !impersonator.IsActive || !impersonator.HasRole("admin") {
+		return errors.New("impersonator is no longer allowed to impersonate")
+	}
+	return nil
+}
+
+// GetSigningKey returns the token verification keys so internal services can validate tokens locally
+func (s *AuthService) GetSigningKey() (*models.SigningKeyResponse, error) {
+	current := s.jwtManager.CurrentKey()
+	response := &models.SigningKeyResponse{
+		Algorithm: current.Algorithm,
+		KeyID:     current.ID,
+		Issuer:    "security-service",
+		Keys:      []models.VerificationKey{},
+	}
+
+	for _, key := range s.jwtManager.VerificationKeys() {
+		verificationKey, err := key.VerificationKey()
+		if err != nil {
+			return nil, err
+		}
+		response.Keys = append(response.Keys, models.VerificationKey{
+			KeyID:     key.ID,
+			Algorithm: key.Algorithm,
+			Key:       verificationKey,
+			ExpiresAt: key.ExpiresAt,
+		})
+		if key.ID == current.ID {
+			response.Key = verificationKey
+		}
+	}
+
+	return response, nil
+}
+
+// GetJWKS returns the public keys of asymmetric signing keys, so any party can verify tokens
+func (s *AuthService) GetJWKS() *models.JWKSet {
+	set := &models.JWKSet{Keys: []models.JWK{}}
+	for _, key := range s.jwtManager.VerificationKeys() {
+		if jwk := key.JWK(); jwk != nil {
+			set.Keys = append(set.Keys, *jwk)
+		}
+	}
+	return set
+}
+
 // CheckPermission checks if a user has permission for a specific action on a resource
 func (s *AuthService) CheckPermission(ctx context.Context, req *models.CheckPermissionRequest) (*models.CheckPermissionResponse, error) {
 	// Get user
@@ -209,7 +487,8 @@ func (s *AuthService) CheckPermission(ctx context.Context, req *models.CheckPerm
 		}, nil
 	}
 
-	// Get user's roles
+	// Get user's roles, including those inherited from groups
+	user = resolveMemberships(ctx, s.groupRepo, user)
 	roles, err := s.roleRepo.FindByNames(ctx, user.Roles)
 	if err != nil {
 		return &models.CheckPermissionResponse{
@@ -244,14 +523,181 @@ func (s *AuthService) GetUserInfo(ctx context.Context, token string) (*models.Us
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
+	user = resolveMemberships(ctx, s.groupRepo, user)
 
 	return &models.UserInfoResponse{
-		ID:        user.ID.Hex(),
-		Username:  user.Username,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Roles:     user.Roles,
+		ID:            user.ID.Hex(),
+		Username:      user.Username,
+		Email:         user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Roles:         user.Roles,
+		Groups:        user.Groups,
+		Impersonation: claims.Impersonation,
+	}, nil
+}
+
+// GetProfile returns the user's own profile with their notification preferences
+func (s *AuthService) GetProfile(ctx context.Context, userID string) (*models.ProfileResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := s.notificationRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ProfileResponse{
+		User:                    user.ToResponse(),
+		NotificationPreferences: prefs,
+		Links:                   models.NewProfileLinks(userID),
+	}, nil
+}
+
+// UpdateProfile updates the user's own contact details. Notification preferences that still
+// point at the old email address or phone number are moved to the new ones.
+func (s *AuthService) UpdateProfile(ctx context.Context, userID string, req *models.ProfileUpdateRequest, ipAddress, userAgent string) (*models.ProfileResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := bson.M{}
+
+	if req.Email != "" && req.Email != user.Email {
+		exists, err := s.userRepo.ExistsByEmail(ctx, req.Email)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, errors.New("email is already in use")
+		}
+		updates["email"] = req.Email
+	}
+	if req.FirstName != "" {
+		updates["first_name"] = req.FirstName
+	}
+	if req.LastName != "" {
+		updates["last_name"] = req.LastName
+	}
+	if req.PhoneNumber != "" {
+		updates["phone_number"] = req.PhoneNumber
+	}
+
+	if len(updates) == 0 {
+		return nil, errors.New("no updates provided")
+	}
+
+	updatedUser, err := s.userRepo.Update(ctx, userID, updates)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := s.notificationRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	if updatedUser.Email != user.Email && (prefs.EmailAddress == "" || prefs.EmailAddress == user.Email) {
+		prefs.EmailAddress = updatedUser.Email
+		changed = true
+	}
+	if updatedUser.PhoneNumber != user.PhoneNumber && (prefs.PhoneNumber == "" || prefs.PhoneNumber == user.PhoneNumber) {
+		prefs.PhoneNumber = updatedUser.PhoneNumber
+		changed = true
+	}
+	if changed {
+		if err := s.notificationRepo.SavePreferences(ctx, prefs); err != nil {
+			log.Printf("Failed to update notification contacts for user %s: %v", userID, err)
+		}
+	}
+
+	s.logAuditEvent(ctx, userID, user.Username, "UPDATE_PROFILE", "user", "SUCCESS", "", ipAddress, userAgent)
+
+	return &models.ProfileResponse{
+		User:                    updatedUser.ToResponse(),
+		NotificationPreferences: prefs,
+		Links:                   models.NewProfileLinks(userID),
+	}, nil
+}
+
+// ChangePassword changes the user's own password after verifying the current one. Every
+// existing session is revoked and fresh tokens are issued for the caller.
+func (s *AuthService) ChangePassword(ctx context.Context, userID string, req *models.ChangePasswordRequest, ipAddress, userAgent string) (*models.ChangePasswordResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !utils.CheckPassword(req.CurrentPassword, user.PasswordHash) {
+		s.logAuditEvent(ctx, userID, user.Username, "CHANGE_PASSWORD", "auth", "FAILURE", "Invalid current password", ipAddress, userAgent)
+		return nil, errors.New("current password is incorrect")
+	}
+
+	if req.NewPassword == req.CurrentPassword {
+		return nil, errors.New("new password must differ from the current password")
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+
+	// Token issue times have second precision, so tokens issued from now on must not predate the change
+	changedAt := time.Now().Truncate(time.Second)
+	updatedUser, err := s.userRepo.Update(ctx, userID, bson.M{
+		"password_hash":       hashedPassword,
+		"password_changed_at": changedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.authRepo.CountActiveTokensForUser(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to count sessions for user %s: %v", userID, err)
+	}
+	if err := s.authRepo.RevokeUserTokens(ctx, userID); err != nil {
+		return nil, errors.New("failed to revoke sessions")
+	}
+
+	// Services that validate tokens locally must re-check this user's tokens
+	s.roleChanges.Publish(models.RoleChangePassword, userID, "")
+
+	updatedUser = resolveMemberships(ctx, s.groupRepo, updatedUser)
+	scope, err := s.tenantScope(ctx, updatedUser)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.jwtManager.GenerateAccessTokenWithMasks(updatedUser, scope, s.responseMasks(ctx, updatedUser.Roles))
+	if err != nil {
+		return nil, errors.New("failed to generate access token")
+	}
+
+	refreshTokenString, expiresAt, err := s.jwtManager.GenerateRefreshToken(userID)
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
+	}
+
+	if err := s.authRepo.SaveRefreshToken(ctx, &models.RefreshToken{
+		UserID:    updatedUser.ID,
+		Token:     refreshTokenString,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return nil, errors.New("failed to save refresh token")
+	}
+
+	s.logAuditEvent(ctx, userID, user.Username, "CHANGE_PASSWORD", "auth", "SUCCESS", "", ipAddress, userAgent)
+
+	return &models.ChangePasswordResponse{
+		AccessToken:     accessToken,
+		RefreshToken:    refreshTokenString,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(s.jwtManager.GetAccessTokenExpiry().Seconds()),
+		SessionsRevoked: revoked,
 	}, nil
 }
 
@@ -277,3 +723,26 @@ func (s *AuthService) logAuditEvent(ctx context.Context, userID, username, actio
 		log.Printf("Failed to create audit log: %v", err)
 	}
 }
+
+// logImpersonationEvent logs the start of an impersonation with the target user and reason
+func (s *AuthService) logImpersonationEvent(ctx context.Context, impersonator *models.User, targetUserID, reason, status, errorMsg, ipAddress, userAgent string) {
+	auditLog := &models.AuditLog{
+		ID:         primitive.NewObjectID(),
+		UserID:     impersonator.ID.Hex(),
+		Username:   impersonator.Username,
+		Service:    "security-service",
+		Action:     "IMPERSONATE_START",
+		Resource:   "user",
+		ResourceID: targetUserID,
+		Details:    map[string]interface{}{"reason": reason},
+		Status:     status,
+		ErrorMsg:   errorMsg,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		Timestamp:  time.Now(),
+	}
+
+	if _, err := s.auditRepo.Create(ctx, auditLog); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+}
@@ -0,0 +1,557 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"security-service/internal/config"
+	"security-service/internal/integrations"
+	"security-service/internal/models"
+	"security-service/internal/repository"
+	"security-service/pkg/utils"
+)
+
+// providerNameRegex restricts provider names to identifiers safe for credential keys and URLs
+var providerNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// EnergyService manages energy providers and routes energy data requests to the provider serving a building
+type EnergyService struct {
+	providerRepo *repository.EnergyProviderRepository
+	authRepo     *repository.AuthRepository
+	auditRepo    *repository.AuditRepository
+	encryptor    *utils.Encryptor
+
+	mu      sync.Mutex
+	clients map[string]*cachedEnergyClient
+}
+
+// cachedEnergyClient keeps a provider client, and its token cache, until the provider changes
+type cachedEnergyClient struct {
+	client    *integrations.EnergyProviderClient
+	updatedAt time.Time
+}
+
+// NewEnergyService creates a new energy service
+func NewEnergyService(
+	providerRepo *repository.EnergyProviderRepository,
+	authRepo *repository.AuthRepository,
+	auditRepo *repository.AuditRepository,
+	encryptor *utils.Encryptor,
+) *EnergyService {
+	return &EnergyService{
+		providerRepo: providerRepo,
+		authRepo:     authRepo,
+		auditRepo:    auditRepo,
+		encryptor:    encryptor,
+		clients:      make(map[string]*cachedEnergyClient),
+	}
+}
+
+// InitializeDefaultProvider registers the environment-configured provider as the default when none exist
+func (s *EnergyService) InitializeDefaultProvider(ctx context.Context, cfg config.EnergyProviderConfig) error {
+	count, err := s.providerRepo.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 || cfg.BaseURL == "" {
+		return nil
+	}
+
+	enabled := true
+	_, err = s.CreateProvider(ctx, &models.EnergyProviderCreateRequest{
+		Name:         models.DefaultEnergyProviderName,
+		DisplayName:  "Default energy provider",
+		BaseURL:      cfg.BaseURL,
+		APIKey:       cfg.APIKey,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		IsDefault:    true,
+		Enabled:      &enabled,
+	}, "system")
+	return err
+}
+
+// CreateProvider registers a new energy provider
+func (s *EnergyService) CreateProvider(ctx context.Context, req *models.EnergyProviderCreateRequest, creatorID string) (*models.EnergyProviderResponse, error) {
+	if !providerNameRegex.MatchString(req.Name) {
+		return nil, NewServiceError("provider name may only contain lowercase letters, digits, '-' and '_'")
+	}
+
+	regions, err := s.validateRegions(ctx, req.Name, req.Regions)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &models.EnergyProvider{
+		Name:        req.Name,
+		DisplayName: req.DisplayName,
+		BaseURL:     req.BaseURL,
+		ClientID:    req.ClientID,
+		Regions:     regions,
+		IsDefault:   req.IsDefault,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}
+	if provider.DisplayName == "" {
+		provider.DisplayName = req.Name
+	}
+	if provider.EncryptedAPIKey, err = s.encryptSecret(req.APIKey); err != nil {
+		return nil, err
+	}
+	if provider.EncryptedClientSecret, err = s.encryptSecret(req.ClientSecret); err != nil {
+		return nil, err
+	}
+
+	created, err := s.providerRepo.Create(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if created.IsDefault {
+		if err := s.providerRepo.ClearDefault(ctx, created.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logAuditEvent(ctx, creatorID, "CREATE_ENERGY_PROVIDER", created.Name)
+
+	return created.ToResponse(), nil
+}
+
+// GetProvider retrieves an energy provider by name
+func (s *EnergyService) GetProvider(ctx context.Context, name string) (*models.EnergyProviderResponse, error) {
+	provider, err := s.providerRepo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return provider.ToResponse(), nil
+}
+
+// ListProviders retrieves all energy providers
+func (s *EnergyService) ListProviders(ctx context.Context) ([]*models.EnergyProviderResponse, error) {
+	providers, err := s.providerRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.EnergyProviderResponse, len(providers))
+	for i, provider := range providers {
+		responses[i] = provider.ToResponse()
+	}
+
+	return responses, nil
+}
+
+// UpdateProvider updates an energy provider's connection settings, credentials or region mapping
+func (s *EnergyService) UpdateProvider(ctx context.Context, name string, req *models.EnergyProviderUpdateRequest, updaterID string) (*models.EnergyProviderResponse, error) {
+	existing, err := s.providerRepo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := bson.M{}
+
+	if req.DisplayName != "" {
+		updates["display_name"] = req.DisplayName
+	}
+	if req.BaseURL != "" {
+		updates["base_url"] = req.BaseURL
+	}
+	if req.ClientID != "" {
+		updates["client_id"] = req.ClientID
+	}
+	if req.APIKey != "" {
+		encrypted, err := s.encryptSecret(req.APIKey)
+		if err != nil {
+			return nil, err
+		}
+		updates["encrypted_api_key"] = encrypted
+	}
+	if req.ClientSecret != "" {
+		encrypted, err := s.encryptSecret(req.ClientSecret)
+		if err != nil {
+			return nil, err
+		}
+		updates["encrypted_client_secret"] = encrypted
+	}
+	if req.Regions != nil {
+		regions, err := s.validateRegions(ctx, name, req.Regions)
+		if err != nil {
+			return nil, err
+		}
+		updates["regions"] = regions
+	}
+	if req.IsDefault != nil {
+		if !*req.IsDefault && existing.IsDefault {
+			return nil, NewServiceError("assign another default energy provider instead of unsetting the current one")
+		}
+		updates["is_default"] = *req.IsDefault
+	}
+	if req.Enabled != nil {
+		if !*req.Enabled && existing.IsDefault {
+			return nil, NewServiceError("cannot disable the default energy provider")
+		}
+		updates["enabled"] = *req.Enabled
+	}
+
+	if len(updates) == 0 {
+		return nil, NewServiceError("no updates provided")
+	}
+
+	updated, err := s.providerRepo.Update(ctx, name, updates)
+	if err != nil {
+		return nil, err
+	}
+
+	if updated.IsDefault && !existing.IsDefault {
+		if err := s.providerRepo.ClearDefault(ctx, updated.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	// Credentials or base URL may have changed, so drop the cached client and its token
+	s.forgetClient(name)
+	if req.BaseURL != "" || req.ClientID != "" || req.ClientSecret != "" {
+		_ = s.authRepo.DeleteAuthCredential(ctx, integrations.EnergyCredentialName(name))
+	}
+
+	s.logAuditEvent(ctx, updaterID, "UPDATE_ENERGY_PROVIDER", name)
+
+	return updated.ToResponse(), nil
+}
+
+// DeleteProvider removes an energy provider and its stored token
+func (s *EnergyService) DeleteProvider(ctx context.Context, name, deleterID string) error {
+	provider, err := s.providerRepo.FindByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if provider.IsDefault {
+		return NewServiceError("cannot delete the default energy provider")
+	}
+
+	if err := s.providerRepo.Delete(ctx, name); err != nil {
+		return err
+	}
+
+	s.forgetClient(name)
+	_ = s.authRepo.DeleteAuthCredential(ctx, integrations.EnergyCredentialName(name))
+
+	s.logAuditEvent(ctx, deleterID, "DELETE_ENERGY_PROVIDER", name)
+
+	return nil
+}
+
+// GetConsumption retrieves consumption data from the provider serving the building,
+// or from the named provider when one is given
+func (s *EnergyService) GetConsumption(ctx context.Context, buildingID, providerName string, from, to time.Time) (*models.EnergyConsumption, error) {
+	provider, _, err := s.resolveForBuilding(ctx, buildingID, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.clientFor(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetConsumption(ctx, buildingID, from, to)
+}
+
+// GetTariffs retrieves tariff data for a region from the named provider, a provider serving the
+// region, or the default provider
+func (s *EnergyService) GetTariffs(ctx context.Context, region, providerName string) (*models.Tariff, error) {
+	provider, err := s.resolveForRegion(ctx, region, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.clientFor(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetTariffs(ctx, region)
+}
+
+// GetBuildingEnergy retrieves consumption and the applicable tariff for a building from the provider serving it
+func (s *EnergyService) GetBuildingEnergy(ctx context.Context, buildingID string, from, to time.Time) (*models.BuildingEnergyResponse, error) {
+	provider, region, err := s.resolveForBuilding(ctx, buildingID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.clientFor(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	consumption, err := client.GetConsumption(ctx, buildingID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.BuildingEnergyResponse{
+		BuildingID:  buildingID,
+		Provider:    provider.Name,
+		Region:      region,
+		Consumption: consumption,
+	}
+
+	// Buildings served only through the default provider have no region mapping to price against
+	if region != "" {
+		tariff, err := client.GetTariffs(ctx, region)
+		if err != nil {
+			response.TariffError = err.Error()
+		} else {
+			response.Tariff = tariff
+		}
+	}
+
+	return response, nil
+}
+
+// RegionForBuilding returns the provider region a building is mapped to
+func (s *EnergyService) RegionForBuilding(ctx context.Context, buildingID string) (string, error) {
+	_, region, err := s.resolveForBuilding(ctx, buildingID, "")
+	if err != nil {
+		return "", err
+	}
+	if region == "" {
+		return "", NewServiceError("building is not mapped to a provider region")
+	}
+	return region, nil
+}
+
+// RefreshToken refreshes the OAuth token of the named provider
+func (s *EnergyService) RefreshToken(ctx context.Context, providerName string) (*models.ExternalTokenRefreshResponse, error) {
+	provider, err := s.providerRepo.FindByName(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.clientFor(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.RefreshToken(ctx)
+}
+
+// HealthCheck checks that every enabled energy provider is reachable
+func (s *EnergyService) HealthCheck(ctx context.Context) error {
+	providers, err := s.providerRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, provider := range providers {
+		if !provider.Enabled {
+			continue
+		}
+		client, err := s.clientFor(provider)
+		if err == nil {
+			err = client.HealthCheck(ctx)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", provider.Name, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// resolveForBuilding picks the provider for a building: the named provider, the provider the building
+// is mapped to, or the default provider. It also returns the provider region of the building, if mapped.
+func (s *EnergyService) resolveForBuilding(ctx context.Context, buildingID, providerName string) (*models.EnergyProvider, string, error) {
+	var provider *models.EnergyProvider
+	var err error
+
+	switch {
+	case providerName != "":
+		provider, err = s.providerRepo.FindByName(ctx, providerName)
+		if err == nil && !provider.Enabled {
+			return nil, "", NewServiceError("energy provider is disabled")
+		}
+	default:
+		provider, err = s.providerRepo.FindByBuilding(ctx, buildingID)
+		if err != nil && err.Error() == "energy provider not found" {
+			provider, err = s.providerRepo.FindDefault(ctx)
+			if err != nil && err.Error() == "energy provider not found" {
+				return nil, "", errors.New("no energy provider configured for building")
+			}
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	region, _ := provider.RegionForBuilding(buildingID)
+	return provider, region, nil
+}
+
+// resolveForRegion picks the provider for a region: the named provider, a provider serving the region,
+// or the default provider
+func (s *EnergyService) resolveForRegion(ctx context.Context, region, providerName string) (*models.EnergyProvider, error) {
+	if providerName != "" {
+		provider, err := s.providerRepo.FindByName(ctx, providerName)
+		if err != nil {
+			return nil, err
+		}
+		if !provider.Enabled {
+			return nil, NewServiceError("energy provider is disabled")
+		}
+		return provider, nil
+	}
+
+	providers, err := s.providerRepo.FindByRegion(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	if len(providers) > 0 {
+		return providers[0], nil
+	}
+
+	provider, err := s.providerRepo.FindDefault(ctx)
+	if err != nil {
+		if err.Error() == "energy provider not found" {
+			return nil, errors.New("no energy provider configured for region")
+		}
+		return nil, err
+	}
+	return provider, nil
+}
+
+// validateRegions normalizes a region mapping and checks that no building is served by two providers
+func (s *EnergyService) validateRegions(ctx context.Context, providerName string, regions []models.EnergyProviderRegion) ([]models.EnergyProviderRegion, error) {
+	normalized := make([]models.EnergyProviderRegion, 0, len(regions))
+	seenRegions := make(map[string]bool)
+	seenBuildings := make(map[string]string)
+	var buildingIDs []string
+
+	for _, region := range regions {
+		code := strings.TrimSpace(region.Region)
+		if code == "" {
+			return nil, NewServiceError("region code is required")
+		}
+		if seenRegions[code] {
+			return nil, NewServiceError(fmt.Sprintf("region %s is listed more than once", code))
+		}
+		seenRegions[code] = true
+
+		ids := make([]string, 0, len(region.BuildingIDs))
+		for _, id := range region.BuildingIDs {
+			id = strings.TrimSpace(id)
+			if id == "" {
+				continue
+			}
+			if other, ok := seenBuildings[id]; ok {
+				return nil, NewServiceError(fmt.Sprintf("building %s is mapped to both %s and %s", id, other, code))
+			}
+			seenBuildings[id] = code
+			ids = append(ids, id)
+			buildingIDs = append(buildingIDs, id)
+		}
+		normalized = append(normalized, models.EnergyProviderRegion{Region: code, BuildingIDs: ids})
+	}
+
+	if len(buildingIDs) == 0 {
+		return normalized, nil
+	}
+
+	owners, err := s.providerRepo.FindBuildingOwners(ctx, buildingIDs, providerName)
+	if err != nil {
+		return nil, err
+	}
+	for _, owner := range owners {
+		for _, id := range buildingIDs {
+			if _, ok := owner.RegionForBuilding(id); ok {
+				return nil, NewServiceError(fmt.Sprintf("building %s is already served by energy provider %s", id, owner.Name))
+			}
+		}
+	}
+
+	return normalized, nil
+}
+
+// clientFor returns the cached client for a provider, rebuilding it when the provider has changed
+func (s *EnergyService) clientFor(provider *models.EnergyProvider) (*integrations.EnergyProviderClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.clients[provider.Name]; ok && cached.updatedAt.Equal(provider.UpdatedAt) {
+		return cached.client, nil
+	}
+
+	apiKey, err := s.decryptSecret(provider.EncryptedAPIKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt API key for %s: %w", provider.Name, err)
+	}
+	clientSecret, err := s.decryptSecret(provider.EncryptedClientSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt client secret for %s: %w", provider.Name, err)
+	}
+
+	client := integrations.NewEnergyProviderClient(integrations.EnergyProviderSettings{
+		Name:         provider.Name,
+		BaseURL:      provider.BaseURL,
+		APIKey:       apiKey,
+		ClientID:     provider.ClientID,
+		ClientSecret: clientSecret,
+	}, s.authRepo, s.encryptor)
+
+	s.clients[provider.Name] = &cachedEnergyClient{client: client, updatedAt: provider.UpdatedAt}
+	return client, nil
+}
+
+// forgetClient drops the cached client of a provider
+func (s *EnergyService) forgetClient(name string) {
+	s.mu.Lock()
+	delete(s.clients, name)
+	s.mu.Unlock()
+}
+
+// encryptSecret encrypts a provider credential, leaving empty values empty
+func (s *EnergyService) encryptSecret(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	encrypted, err := s.encryptor.Encrypt(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt credential: %w", err)
+	}
+	return encrypted, nil
+}
+
+// decryptSecret decrypts a stored provider credential, leaving empty values empty
+func (s *EnergyService) decryptSecret(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	return s.encryptor.Decrypt(value)
+}
+
+// logAuditEvent logs an energy provider management audit event
+func (s *EnergyService) logAuditEvent(ctx context.Context, userID, action, providerName string) {
+	log := &models.AuditLog{
+		UserID:     userID,
+		Service:    "security-service",
+		Action:     action,
+		Resource:   "energy_provider",
+		ResourceID: providerName,
+		Status:     "SUCCESS",
+		Timestamp:  time.Now(),
+	}
+
+	s.auditRepo.Create(ctx, log)
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"security-service/internal/integrations"
+	"security-service/internal/models"
+	"security-service/internal/repository"
+)
+
+// GroupService handles group management business logic. Users receive the roles of the groups
+// they belong to, directly or through parent groups, in addition to their own roles.
+type GroupService struct {
+	groupRepo   *repository.GroupRepository
+	roleRepo    *repository.RoleRepository
+	userRepo    *repository.UserRepository
+	auditRepo   *repository.AuditRepository
+	roleChanges *integrations.RoleChangePublisher
+}
+
+// NewGroupService creates a new group service
+func NewGroupService(
+	groupRepo *repository.GroupRepository,
+	roleRepo *repository.RoleRepository,
+	userRepo *repository.UserRepository,
+	auditRepo *repository.AuditRepository,
+	roleChanges *integrations.RoleChangePublisher,
+) *GroupService {
+	return &GroupService{
+		groupRepo:   groupRepo,
+		roleRepo:    roleRepo,
+		userRepo:    userRepo,
+		auditRepo:   auditRepo,
+		roleChanges: roleChanges,
+	}
+}
+
+// CreateGroup creates a new group
+func (s *GroupService) CreateGroup(ctx context.Context, req *models.GroupCreateRequest, creatorID string) (*models.GroupResponse, error) {
+	exists, err := s.groupRepo.ExistsByName(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New("group with this name already exists")
+	}
+
+	if err := s.validateRoles(ctx, req.Roles); err != nil {
+		return nil, err
+	}
+	groups, err := s.groupRepo.FindAllByName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateParentGroups(req.Name, req.ParentGroups, groups); err != nil {
+		return nil, err
+	}
+
+	group := &models.Group{
+		Name:         req.Name,
+		Description:  req.Description,
+		Roles:        req.Roles,
+		ParentGroups: req.ParentGroups,
+		CreatedBy:    creatorID,
+	}
+
+	created, err := s.groupRepo.Create(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAuditEvent(ctx, creatorID, "CREATE_GROUP", created.Name, nil)
+
+	groups[created.Name] = created
+	return s.toResponse(ctx, created, groups)
+}
+
+// GetGroup retrieves a group by name
+func (s *GroupService) GetGroup(ctx context.Context, name string) (*models.GroupResponse, error) {
+	groups, err := s.groupRepo.FindAllByName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	group, ok := groups[name]
+	if !ok {
+		return nil, errors.New("group not found")
+	}
+	return s.toResponse(ctx, group, groups)
+}
+
+// ListGroups retrieves all groups
+func (s *GroupService) ListGroups(ctx context.Context) ([]*models.GroupResponse, error) {
+	list, err := s.groupRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*models.Group, len(list))
+	for _, group := range list {
+		groups[group.Name] = group
+	}
+
+	responses := make([]*models.GroupResponse, 0, len(list))
+	for _, group := range list {
+		response, err := s.toResponse(ctx, group, groups)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// UpdateGroup updates a group's description, roles or parent groups. Tokens of every user are
+// marked stale, since members of nested groups are affected too.
+func (s *GroupService) UpdateGroup(ctx context.Context, name string, req *models.GroupUpdateRequest, updaterID string) (*models.GroupResponse, error) {
+	groups, err := s.groupRepo.FindAllByName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := groups[name]; !ok {
+		return nil, errors.New("group not found")
+	}
+
+	updates := bson.M{}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Roles != nil {
+		if err := s.validateRoles(ctx, req.Roles); err != nil {
+			return nil, err
+		}
+		updates["roles"] = req.Roles
+	}
+	if req.ParentGroups != nil {
+		if err := validateParentGroups(name, req.ParentGroups, groups); err != nil {
+			return nil, err
+		}
+		updates["parent_groups"] = req.ParentGroups
+	}
+
+	if len(updates) == 0 {
+		return nil, errors.New("no updates provided")
+	}
+
+	updated, err := s.groupRepo.Update(ctx, name, updates)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAuditEvent(ctx, updaterID, "UPDATE_GROUP", name, nil)
+	if req.Roles != nil || req.ParentGroups != nil {
+		s.roleChanges.PublishGroup(models.RoleChangeGroupUpdate, "", name)
+	}
+
+	groups[name] = updated
+	return s.toResponse(ctx, updated, groups)
+}
+
+// DeleteGroup deletes a group, removing its members and dropping it from other groups' parents
+func (s *GroupService) DeleteGroup(ctx context.Context, name, deleterID string) error {
+	if err := s.groupRepo.Delete(ctx, name); err != nil {
+		return err
+	}
+	if err := s.userRepo.RemoveGroupFromAll(ctx, name); err != nil {
+		log.Printf("Failed to remove members of deleted group %s: %v", name, err)
+	}
+
+	s.logAuditEvent(ctx, deleterID, "DELETE_GROUP", name, nil)
+	s.roleChanges.PublishGroup(models.RoleChangeGroupDelete, "", name)
+
+	return nil
+}
+
+// ListMembers lists the users belonging directly to a group
+func (s *GroupService) ListMembers(ctx context.Context, name string) ([]*models.UserResponse, error) {
+	if _, err := s.groupRepo.FindByName(ctx, name); err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepo.FindByGroups(ctx, []string{name})
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.UserResponse, len(users))
+	for i, user := range users {
+		responses[i] = user.ToResponse()
+	}
+	return responses, nil
+}
+
+// AddMembers adds users to a group. Every user must exist.
+func (s *GroupService) AddMembers(ctx context.Context, name string, userIDs []string, updaterID string) error {
+	if _, err := s.groupRepo.FindByName(ctx, name); err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+			return fmt.Errorf("%w: %s", err, userID)
+		}
+	}
+
+	for _, userID := range userIDs {
+		if err := s.userRepo.AddGroup(ctx, userID, name); err != nil {
+			return err
+		}
+		s.logAuditEvent(ctx, updaterID, "ADD_GROUP_MEMBER", name, map[string]interface{}{"userId": userID})
+		s.roleChanges.PublishGroup(models.RoleChangeUserGroups, userID, name)
+	}
+
+	return nil
+}
+
+// RemoveMember removes a user from a group
+func (s *GroupService) RemoveMember(ctx context.Context, name, userID, updaterID string) error {
+	if _, err := s.groupRepo.FindByName(ctx, name); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.RemoveGroup(ctx, userID, name); err != nil {
+		return err
+	}
+
+	s.logAuditEvent(ctx, updaterID, "REMOVE_GROUP_MEMBER", name, map[string]interface{}{"userId": userID})
+	s.roleChanges.PublishGroup(models.RoleChangeUserGroups, userID, name)
+
+	return nil
+}
+
+// toResponse converts a group to its API response with its effective roles and member count
+func (s *GroupService) toResponse(ctx context.Context, group *models.Group, groups map[string]*models.Group) (*models.GroupResponse, error) {
+	_, inherited := models.ResolveGroups([]string{group.Name}, groups)
+	count, err := s.userRepo.CountByGroup(ctx, group.Name)
+	if err != nil {
+		return nil, err
+	}
+	return group.ToResponse(inherited, count), nil
+}
+
+// validateRoles checks that every role exists
+func (s *GroupService) validateRoles(ctx context.Context, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	if err := checkGrantableRoles(ctx, names); err != nil {
+		return err
+	}
+	roles, err := s.roleRepo.FindByNames(ctx, names)
+	if err != nil {
+		return err
+	}
+	if len(roles) != len(names) {
+		return errors.New("one or more roles do not exist")
+	}
+	return nil
+}
+
+// validateParentGroups checks that the parent groups exist and that making them parents of the
+// named group does not create a cycle
+func validateParentGroups(name string, parents []string, groups map[string]*models.Group) error {
+	for _, parent := range parents {
+		if _, ok := groups[parent]; !ok {
+			return fmt.Errorf("parent group %s does not exist", parent)
+		}
+	}
+
+	ancestors, _ := models.ResolveGroups(parents, groups)
+	for _, ancestor := range ancestors {
+		if ancestor == name {
+			return errors.New("parent groups would create a cycle")
+		}
+	}
+	return nil
+}
+
+// logAuditEvent logs a successful group management audit event
+func (s *GroupService) logAuditEvent(ctx context.Context, userID, action, groupName string, details map[string]interface{}) {
+	auditLog := &models.AuditLog{
+		UserID:     userID,
+		Service:    "security-service",
+		Action:     action,
+		Resource:   "group",
+		ResourceID: groupName,
+		Status:     "SUCCESS",
+		Details:    details,
+		Timestamp:  time.Now(),
+	}
+
+	s.auditRepo.Create(ctx, auditLog)
+}
+
+// resolveMemberships returns the user as carried in access tokens: with the roles inherited from
+// their groups added to their own, and every group they belong to directly or through parent
+// groups. If groups cannot be loaded, the user's own roles are kept.
+func resolveMemberships(ctx context.Context, groupRepo *repository.GroupRepository, user *models.User) *models.User {
+	if len(user.Groups) == 0 {
+		return user
+	}
+
+	groups, err := groupRepo.FindAllByName(ctx)
+	if err != nil {
+		log.Printf("Failed to load groups of user %s: %v", user.ID.Hex(), err)
+		return user
+	}
+
+	names, inherited := models.ResolveGroups(user.Groups, groups)
+	resolved := *user
+	resolved.Roles = models.EffectiveRoles(user.Roles, inherited)
+	resolved.Groups = names
+	return &resolved
+}
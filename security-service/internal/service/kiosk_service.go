@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"security-service/internal/integrations"
+	"security-service/internal/models"
+	"security-service/internal/repository"
+	"security-service/internal/tenant"
+	"security-service/pkg/utils"
+)
+
+// KioskService handles kiosk token business logic
+type KioskService struct {
+	kioskRepo   *repository.KioskRepository
+	auditRepo   *repository.AuditRepository
+	jwtManager  *utils.JWTManager
+	roleChanges *integrations.RoleChangePublisher
+}
+
+// NewKioskService creates a new kiosk token service
+func NewKioskService(
+	kioskRepo *repository.KioskRepository,
+	auditRepo *repository.AuditRepository,
+	jwtManager *utils.JWTManager,
+	roleChanges *integrations.RoleChangePublisher,
+) *KioskService {
+	return &KioskService{
+		kioskRepo:   kioskRepo,
+		auditRepo:   auditRepo,
+		jwtManager:  jwtManager,
+		roleChanges: roleChanges,
+	}
+}
+
+// CreateKioskToken issues a read-only token for one building's dashboards.
+// The signed token is only returned here and cannot be retrieved again.
+func (s *KioskService) CreateKioskToken(ctx context.Context, req *models.KioskTokenCreateRequest, creatorID string) (*models.KioskTokenCreateResponse, error) {
+	if !tenant.AllowsBuilding(ctx, req.BuildingID) {
+		return nil, errors.New("building does not belong to your organization")
+	}
+
+	// The token is signed before the record is stored, so the record holds the signing key's ID
+	kiosk := &models.KioskToken{
+		ID:         primitive.NewObjectID(),
+		Name:       req.Name,
+		BuildingID: req.BuildingID,
+		CreatedBy:  creatorID,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		kiosk.ExpiresAt = &expiresAt
+	}
+
+	token, err := s.jwtManager.GenerateKioskToken(kiosk)
+	if err != nil {
+		return nil, errors.New("failed to generate kiosk token")
+	}
+
+	kiosk, err = s.kioskRepo.Create(ctx, kiosk)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAuditEvent(ctx, creatorID, "CREATE_KIOSK_TOKEN", kiosk.ID.Hex(), map[string]interface{}{
+		"name":       kiosk.Name,
+		"buildingId": kiosk.BuildingID,
+	})
+
+	return &models.KioskTokenCreateResponse{
+		Token:      token,
+		TokenType:  "Bearer",
+		KioskToken: kiosk,
+	}, nil
+}
+
+// ListKioskTokens retrieves kiosk tokens with pagination
+func (s *KioskService) ListKioskTokens(ctx context.Context, buildingID string, includeRevoked bool, page, limit int) ([]*models.KioskToken, int64, int, error) {
+	kiosks, total, err := s.kioskRepo.FindAll(ctx, buildingID, includeRevoked, page, limit)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+
+	return kiosks, total, totalPages, nil
+}
+
+// RevokeKioskToken revokes a kiosk token so services stop accepting it
+func (s *KioskService) RevokeKioskToken(ctx context.Context, id, revokerID string) (*models.KioskToken, error) {
+	kiosk, err := s.kioskRepo.Revoke(ctx, id, revokerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Services caching token validations must drop the kiosk token
+	s.roleChanges.Publish(models.RoleChangeKioskRevoke, utils.KioskUserID(id), "")
+
+	s.logAuditEvent(ctx, revokerID, "REVOKE_KIOSK_TOKEN", id, map[string]interface{}{
+		"name":       kiosk.Name,
+		"buildingId": kiosk.BuildingID,
+	})
+
+	return kiosk, nil
+}
+
+// logAuditEvent logs a kiosk token management event
+func (s *KioskService) logAuditEvent(ctx context.Context, userID, action, resourceID string, details map[string]interface{}) {
+	auditLog := &models.AuditLog{
+		UserID:     userID,
+		Service:    "security-service",
+		Action:     action,
+		Resource:   "kiosk_token",
+		ResourceID: resourceID,
+		Details:    details,
+		Status:     "SUCCESS",
+		Timestamp:  time.Now(),
+	}
+
+	if _, err := s.auditRepo.Create(ctx, auditLog); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+}
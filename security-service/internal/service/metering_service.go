@@ -0,0 +1,507 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"security-service/internal/events"
+	"security-service/internal/models"
+	"security-service/internal/repository"
+	"security-service/internal/tenant"
+)
+
+const (
+	// usageMonthLayout is the layout of the UTC months usage limits apply to
+	usageMonthLayout = "2006-01"
+	// snapshotGrace is how long after a day ends its usage is snapshotted, so usage reported
+	// late by other services is included
+	snapshotGrace = time.Hour
+	// snapshotBackfillDays is how many completed days are snapshotted when they were missed,
+	// for example because the service was down at the time
+	snapshotBackfillDays = 7
+)
+
+// MeteringService meters the usage of organizations for billing: monitored devices, API calls,
+// forecast runs and scenario executions. Usage is counted per building and UTC day, snapshotted
+// once a day is complete and compared with the monthly limits of organizations.
+type MeteringService struct {
+	usageRepo  *repository.UsageRepository
+	orgRepo    *repository.OrganizationRepository
+	userRepo   *repository.UserRepository
+	notifier   *NotificationService
+	thresholds []int
+}
+
+// NewMeteringService creates a new metering service alerting at the given percentages of
+// usage limits
+func NewMeteringService(
+	usageRepo *repository.UsageRepository,
+	orgRepo *repository.OrganizationRepository,
+	userRepo *repository.UserRepository,
+	notifier *NotificationService,
+	thresholds []int,
+) *MeteringService {
+	sorted := append([]int{}, thresholds...)
+	sort.Ints(sorted)
+
+	return &MeteringService{
+		usageRepo:  usageRepo,
+		orgRepo:    orgRepo,
+		userRepo:   userRepo,
+		notifier:   notifier,
+		thresholds: sorted,
+	}
+}
+
+// RecordUsage adds usage of a building, such as a forecast run, to the organization owning it.
+// Usage of buildings without an organization is not metered.
+func (s *MeteringService) RecordUsage(ctx context.Context, buildingID, metric string, n int64, at time.Time) error {
+	if buildingID == "" {
+		return nil
+	}
+
+	owners, err := s.buildingOwners(ctx, []string{buildingID})
+	if err != nil {
+		return err
+	}
+	orgID := owners[buildingID]
+	if orgID == "" {
+		return nil
+	}
+
+	return s.usageRepo.Add(ctx, orgID, buildingID, usageDate(at), metric, n)
+}
+
+// RecordUsageReport records the usage a service metered since its previous report. API calls
+// not scoped to an organization, made by super admins or users outside any organization, are not
+// billed. Monitored devices are attributed to the organization owning their building.
+func (s *MeteringService) RecordUsageReport(ctx context.Context, report *events.UsageReportedData) error {
+	date := usageDate(report.ReportedAt)
+
+	for _, calls := range report.APICalls {
+		if calls.OrgID == "" || calls.Count <= 0 {
+			continue
+		}
+		if err := s.usageRepo.Add(ctx, calls.OrgID, calls.BuildingID, date, models.MetricAPICalls, calls.Count); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Devices) == 0 {
+		return nil
+	}
+	buildingIDs := make([]string, 0, len(report.Devices))
+	for _, devices := range report.Devices {
+		buildingIDs = append(buildingIDs, devices.BuildingID)
+	}
+	owners, err := s.buildingOwners(ctx, buildingIDs)
+	if err != nil {
+		return err
+	}
+	for _, devices := range report.Devices {
+		orgID := owners[devices.BuildingID]
+		if orgID == "" {
+			continue
+		}
+		if err := s.usageRepo.RecordPeak(ctx, orgID, devices.BuildingID, date, models.MetricMonitoredDevices, devices.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordAPICalls records the API calls this service metered itself
+func (s *MeteringService) RecordAPICalls(ctx context.Context, apiCalls []events.UsageCount) {
+	if len(apiCalls) == 0 {
+		return
+	}
+	if err := s.RecordUsageReport(ctx, &events.UsageReportedData{Service: "security-service", APICalls: apiCalls, ReportedAt: time.Now()}); err != nil {
+		log.Printf("Failed to record API usage: %v", err)
+	}
+}
+
+// GetUsage summarizes the usage of an organization, or of one of its buildings, in a period.
+// The usage of a whole organization includes the status of its monthly limits.
+func (s *MeteringService) GetUsage(ctx context.Context, params *models.UsageQueryParams, now time.Time) (*models.UsageSummary, error) {
+	orgID, err := usageOrg(ctx, params.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if params.BuildingID != "" && !tenant.AllowsBuilding(ctx, params.BuildingID) {
+		return nil, fmt.Errorf("building does not belong to your organization")
+	}
+
+	from, to, err := ParseAccessReportPeriod(params.Period, now)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	counters, err := s.usageRepo.FindCounters(ctx, orgID, params.BuildingID, usageDate(from), usageDate(to))
+	if err != nil {
+		return nil, err
+	}
+
+	summary := SummarizeUsage(counters)
+	summary.OrgID = orgID
+	summary.BuildingID = params.BuildingID
+	summary.From = from
+	summary.To = to
+	summary.GeneratedAt = now
+
+	if orgID != "" && params.BuildingID == "" {
+		org, err := s.orgRepo.FindByID(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		if len(org.UsageLimits) > 0 {
+			totals, err := s.monthToDate(ctx, orgID, now)
+			if err != nil {
+				return nil, err
+			}
+			summary.Limits = UsageLimitStatuses(org.UsageLimits, totals, now.UTC().Format(usageMonthLayout))
+		}
+	}
+
+	return summary, nil
+}
+
+// GetSnapshots retrieves the daily usage snapshots of an organization in a period
+func (s *MeteringService) GetSnapshots(ctx context.Context, params *models.UsageSnapshotQueryParams, now time.Time) (*models.UsageSnapshotsResponse, error) {
+	orgID, err := usageOrg(ctx, params.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, err := ParseAccessReportPeriod(params.Period, now)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	snapshots, err := s.usageRepo.FindSnapshots(ctx, orgID, usageDate(from), usageDate(to))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UsageSnapshotsResponse{OrgID: orgID, From: from, To: to, Snapshots: snapshots}, nil
+}
+
+// TakeSnapshots snapshots the usage of every organization on the recent completed days that were
+// not snapshotted yet and returns how many snapshots were taken
+func (s *MeteringService) TakeSnapshots(ctx context.Context, now time.Time) (int, error) {
+	taken := 0
+	for _, day := range SnapshotDays(now) {
+		date := usageDate(day)
+
+		existing, err := s.usageRepo.FindSnapshots(ctx, "", date, date)
+		if err != nil {
+			return taken, err
+		}
+		snapshotted := make(map[string]bool, len(existing))
+		for _, snapshot := range existing {
+			snapshotted[snapshot.OrgID] = true
+		}
+
+		orgIDs, err := s.usageRepo.FindOrgIDs(ctx, date)
+		if err != nil {
+			return taken, err
+		}
+		for _, orgID := range orgIDs {
+			if snapshotted[orgID] {
+				continue
+			}
+
+			counters, err := s.usageRepo.FindCounters(ctx, orgID, "", date, date)
+			if err != nil {
+				return taken, err
+			}
+			summary := SummarizeUsage(counters)
+			created, err := s.usageRepo.CreateSnapshot(ctx, &models.UsageSnapshot{
+				OrgID:     orgID,
+				Date:      date,
+				Metrics:   summary.Totals,
+				Buildings: summary.Buildings,
+			})
+			if err != nil {
+				return taken, err
+			}
+			if created {
+				taken++
+			}
+		}
+	}
+	return taken, nil
+}
+
+// CheckLimits compares the usage of the month so far with the limits of every active
+// organization and alerts its admins of each threshold crossed for the first time in the month.
+// It returns how many alerts were sent.
+func (s *MeteringService) CheckLimits(ctx context.Context, now time.Time) (int, error) {
+	orgs, err := s.orgRepo.FindWithUsageLimits(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	month := now.UTC().Format(usageMonthLayout)
+	alerted := 0
+	for _, org := range orgs {
+		if !org.IsActive() {
+			continue
+		}
+
+		orgID := org.ID.Hex()
+		totals, err := s.monthToDate(ctx, orgID, now)
+		if err != nil {
+			return alerted, err
+		}
+
+		for _, status := range UsageLimitStatuses(org.UsageLimits, totals, month) {
+			// Every crossed threshold is recorded so it is not alerted again, but only the
+			// highest new one is sent when usage jumped past several at once
+			var highest *models.UsageAlert
+			for _, threshold := range CrossedUsageThresholds(status.Used, status.Limit, s.thresholds) {
+				alert := &models.UsageAlert{
+					OrgID:     orgID,
+					Month:     month,
+					Metric:    status.Metric,
+					Threshold: threshold,
+					Used:      status.Used,
+					Limit:     status.Limit,
+				}
+				created, err := s.usageRepo.CreateAlert(ctx, alert)
+				if err != nil {
+					return alerted, err
+				}
+				if created {
+					highest = alert
+				}
+			}
+			if highest != nil {
+				s.notifyLimit(ctx, org, highest)
+				alerted++
+			}
+		}
+	}
+	return alerted, nil
+}
+
+// StartWorker periodically snapshots completed days and checks usage limits until the context
+// is cancelled
+func (s *MeteringService) StartWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if taken, err := s.TakeSnapshots(ctx, now); err != nil {
+				log.Printf("Failed to snapshot usage: %v", err)
+			} else if taken > 0 {
+				log.Printf("Snapshotted usage of %d organization days", taken)
+			}
+			if _, err := s.CheckLimits(ctx, now); err != nil {
+				log.Printf("Failed to check usage limits: %v", err)
+			}
+		}
+	}
+}
+
+// notifyLimit notifies the admins of an organization that its usage crossed a threshold of its
+// limit. Reaching the full limit is a high severity event and earlier thresholds are medium;
+// without a matching routing rule the admins are emailed and posted to their inbox.
+func (s *MeteringService) notifyLimit(ctx context.Context, org *models.Organization, alert *models.UsageAlert) {
+	admins, err := s.userRepo.FindByRoles(tenant.WithScope(ctx, org.Scope()), []string{"admin"})
+	if err != nil {
+		log.Printf("Failed to find admins of organization %s for usage alert: %v", org.Name, err)
+		return
+	}
+
+	subject := fmt.Sprintf("%s reached %d%% of its monthly %s limit", org.Name, alert.Threshold, alert.Metric)
+	content := fmt.Sprintf("%s has used %d of its limit of %d %s in %s.", org.Name, alert.Used, alert.Limit, alert.Metric, alert.Month)
+	severity := models.NotificationSeverityMedium
+	if alert.Threshold >= 100 {
+		severity = models.NotificationSeverityHigh
+	}
+
+	for _, admin := range admins {
+		if !admin.IsActive {
+			continue
+		}
+		if _, err := s.notifier.NotifyEvent(ctx, &models.NotificationEvent{
+			UserID:   admin.ID.Hex(),
+			Category: models.NotificationCategoryUsage,
+			Severity: severity,
+			Subject:  subject,
+			Content:  content,
+			Metadata: map[string]string{
+				"orgId":     alert.OrgID,
+				"metric":    alert.Metric,
+				"month":     alert.Month,
+				"threshold": strconv.Itoa(alert.Threshold),
+			},
+			DefaultChannels: []models.NotificationType{models.NotificationTypeEmail, models.NotificationTypeInApp},
+		}); err != nil {
+			log.Printf("Failed to notify user %s of usage alert: %v", admin.ID.Hex(), err)
+		}
+	}
+}
+
+// monthToDate returns the usage totals of an organization in the UTC month of now so far
+func (s *MeteringService) monthToDate(ctx context.Context, orgID string, now time.Time) (map[string]int64, error) {
+	now = now.UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	counters, err := s.usageRepo.FindCounters(ctx, orgID, "", usageDate(monthStart), usageDate(now))
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeUsage(counters).Totals, nil
+}
+
+// buildingOwners returns the organization owning each of the buildings that has one
+func (s *MeteringService) buildingOwners(ctx context.Context, buildingIDs []string) (map[string]string, error) {
+	orgs, err := s.orgRepo.FindOwnersOfBuildings(ctx, buildingIDs, primitive.NilObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]string)
+	for _, org := range orgs {
+		for _, buildingID := range org.BuildingIDs {
+			owners[buildingID] = org.ID.Hex()
+		}
+	}
+	return owners, nil
+}
+
+// usageOrg returns the organization whose usage a request may read. Members of an organization
+// read their own; admins outside any organization choose one, or read the usage of all of them.
+func usageOrg(ctx context.Context, requested string) (string, error) {
+	scoped := tenant.OrgID(ctx)
+	if scoped == "" {
+		return requested, nil
+	}
+	if requested != "" && requested != scoped {
+		return "", fmt.Errorf("organization %s does not belong to your account", requested)
+	}
+	return scoped, nil
+}
+
+// usageDate returns the UTC day usage at a time is recorded for
+func usageDate(at time.Time) string {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return at.UTC().Format(models.UsageDateLayout)
+}
+
+// SnapshotDays returns the recent completed UTC days whose usage can be snapshotted at now,
+// oldest first. A day is complete once snapshotGrace has passed since it ended.
+func SnapshotDays(now time.Time) []time.Time {
+	latest := now.UTC().Add(-snapshotGrace)
+	latest = time.Date(latest.Year(), latest.Month(), latest.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+
+	days := make([]time.Time, 0, snapshotBackfillDays)
+	for i := snapshotBackfillDays - 1; i >= 0; i-- {
+		days = append(days, latest.AddDate(0, 0, -i))
+	}
+	return days
+}
+
+// SummarizeUsage adds up usage counters by building and by day. Monitored devices are a level:
+// a building's value is its daily peak, a day's value the sum over its buildings and the total
+// the peak day. Every other metric is summed.
+func SummarizeUsage(counters []*models.UsageCounter) *models.UsageSummary {
+	buildings := make(map[string]map[string]int64)
+	daily := make(map[string]map[string]int64)
+	for _, counter := range counters {
+		if buildings[counter.BuildingID] == nil {
+			buildings[counter.BuildingID] = make(map[string]int64)
+		}
+		if daily[counter.Date] == nil {
+			daily[counter.Date] = make(map[string]int64)
+		}
+
+		if models.IsPeakMetric(counter.Metric) {
+			if counter.Value > buildings[counter.BuildingID][counter.Metric] {
+				buildings[counter.BuildingID][counter.Metric] = counter.Value
+			}
+		} else {
+			buildings[counter.BuildingID][counter.Metric] += counter.Value
+		}
+		daily[counter.Date][counter.Metric] += counter.Value
+	}
+
+	totals := make(map[string]int64, len(models.UsageMetrics))
+	for _, metric := range models.UsageMetrics {
+		totals[metric] = 0
+	}
+	summary := &models.UsageSummary{
+		Totals:    totals,
+		Buildings: make([]models.BuildingUsage, 0, len(buildings)),
+		Daily:     make([]models.DailyUsage, 0, len(daily)),
+	}
+
+	for date, metrics := range daily {
+		summary.Daily = append(summary.Daily, models.DailyUsage{Date: date, Metrics: metrics})
+		for metric, value := range metrics {
+			if !models.IsPeakMetric(metric) {
+				totals[metric] += value
+			} else if value > totals[metric] {
+				totals[metric] = value
+			}
+		}
+	}
+	sort.Slice(summary.Daily, func(i, j int) bool { return summary.Daily[i].Date < summary.Daily[j].Date })
+
+	for buildingID, metrics := range buildings {
+		summary.Buildings = append(summary.Buildings, models.BuildingUsage{BuildingID: buildingID, Metrics: metrics})
+	}
+	sort.Slice(summary.Buildings, func(i, j int) bool { return summary.Buildings[i].BuildingID < summary.Buildings[j].BuildingID })
+
+	return summary
+}
+
+// UsageLimitStatuses returns how much of each monthly limit the usage totals of a month use, in
+// the order of the metered usage
+func UsageLimitStatuses(limits, totals map[string]int64, month string) []models.UsageLimitStatus {
+	statuses := []models.UsageLimitStatus{}
+	for _, metric := range models.UsageMetrics {
+		limit := limits[metric]
+		if limit <= 0 {
+			continue
+		}
+		used := totals[metric]
+		statuses = append(statuses, models.UsageLimitStatus{
+			Metric:  metric,
+			Month:   month,
+			Limit:   limit,
+			Used:    used,
+			Percent: math.Round(float64(used)/float64(limit)*1000) / 10,
+		})
+	}
+	return statuses
+}
+
+// CrossedUsageThresholds returns the thresholds, in percent of the limit, that usage reached
+func CrossedUsageThresholds(used, limit int64, thresholds []int) []int {
+	crossed := []int{}
+	if limit <= 0 {
+		return crossed
+	}
+	for _, threshold := range thresholds {
+		if used*100 >= int64(threshold)*limit {
+			crossed = append(crossed, threshold)
+		}
+	}
+	return crossed
+}
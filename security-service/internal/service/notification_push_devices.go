@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"security-service/internal/integrations"
+	"security-service/internal/models"
+)
+
+const (
+	// maxPrunedPushDevices is how many pruned devices are kept per user for the health report
+	maxPrunedPushDevices = 10
+	// defaultPushStaleDays is how long a device may go without a delivery before it is stale
+	defaultPushStaleDays = 30
+)
+
+// ListPushDevices retrieves the push devices registered by a user
+func (s *NotificationService) ListPushDevices(ctx context.Context, userID string) ([]models.PushDevice, error) {
+	prefs, err := s.notificationRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs.PushDevices == nil {
+		return []models.PushDevice{}, nil
+	}
+	return prefs.PushDevices, nil
+}
+
+// RegisterPushDevice registers a device to receive the user's push notifications. A token
+// registered to another user is moved, since a device belongs to whoever signed in last.
+func (s *NotificationService) RegisterPushDevice(ctx context.Context, userID string, req *models.PushDeviceRegisterRequest) (*models.PushDevice, error) {
+	owners, err := s.notificationRepo.FindPreferencesByPushToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	for _, owner := range owners {
+		if owner.UserID == userID {
+			continue
+		}
+		RemovePushToken(owner, req.Token)
+		if err := s.notificationRepo.SavePreferences(ctx, owner); err != nil {
+			return nil, err
+		}
+	}
+
+	prefs, err := s.notificationRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	device := UpsertPushDevice(prefs, req, time.Now())
+	if err := s.notificationRepo.SavePreferences(ctx, prefs); err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+// UpdatePushDevicePreferences changes which notifications one of the user's devices receives
+func (s *NotificationService) UpdatePushDevicePreferences(ctx context.Context, userID, token string, req *models.PushDevicePreferencesRequest) (*models.PushDevice, error) {
+	prefs, err := s.notificationRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var device *models.PushDevice
+	for i := range prefs.PushDevices {
+		if prefs.PushDevices[i].Token == token {
+			device = &prefs.PushDevices[i]
+			break
+		}
+	}
+	if device == nil {
+		return nil, ErrPushDeviceNotFound
+	}
+
+	if req.Enabled != nil {
+		device.Enabled = *req.Enabled
+	}
+	if req.Categories != nil {
+		device.Categories = req.Categories
+	}
+	if req.MinSeverity != nil {
+		device.MinSeverity = *req.MinSeverity
+	}
+
+	if err := s.notificationRepo.SavePreferences(ctx, prefs); err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+// UnregisterPushDevice stops push notifications to one of the user's devices, whether it was
+// registered or set as a legacy token
+func (s *NotificationService) UnregisterPushDevice(ctx context.Context, userID, token string) error {
+	prefs, err := s.notificationRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if _, ok := RemovePushToken(prefs, token); !ok {
+		return ErrPushDeviceNotFound
+	}
+	return s.notificationRepo.SavePreferences(ctx, prefs)
+}
+
+// recordPushResult updates the health of the device a push notification was sent to. Devices
+// whose token the provider rejected are pruned so they are not sent to again.
+func (s *NotificationService) recordPushResult(ctx context.Context, userID, token string, sendErr error) {
+	now := time.Now()
+
+	if sendErr != nil && errors.Is(sendErr, integrations.ErrPushTokenRejected) {
+		prefs, err := s.notificationRepo.GetPreferences(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to load notification preferences of user %s to prune push token: %v", userID, err)
+			return
+		}
+		if PrunePushDevice(prefs, token, sendErr.Error(), now) {
+			if err := s.notificationRepo.SavePreferences(ctx, prefs); err != nil {
+				log.Printf("Failed to prune rejected push token of user %s: %v", userID, err)
+				return
+			}
+			log.Printf("Pruned push token %s of user %s rejected by the provider", MaskPushToken(token), userID)
+		}
+		return
+	}
+
+	errorMsg := ""
+	if sendErr != nil {
+		errorMsg = sendErr.Error()
+	}
+	if err := s.notificationRepo.RecordPushDelivery(ctx, userID, token, errorMsg, now); err != nil {
+		log.Printf("Failed to record push delivery for user %s: %v", userID, err)
+	}
+}
+
+// GetPushTokenHealth reports the delivery health of every registered push device
+func (s *NotificationService) GetPushTokenHealth(ctx context.Context, params models.PushTokenHealthQueryParams) (*models.PushTokenHealthReport, error) {
+	prefs, err := s.notificationRepo.FindPreferencesWithPushDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizePushTokenHealth(prefs, params.StaleDays, time.Now()), nil
+}
+
+// UpsertPushDevice adds a device to the preferences or, if the token is known, updates its
+// metadata and preferences and resets its failures. The device is enabled and replaces a legacy
+// token with the same value.
+func UpsertPushDevice(prefs *models.NotificationPreferences, req *models.PushDeviceRegisterRequest, now time.Time) *models.PushDevice {
+	prefs.PushDeviceTokens = removeString(prefs.PushDeviceTokens, req.Token)
+
+	for i := range prefs.PushDevices {
+		device := &prefs.PushDevices[i]
+		if device.Token != req.Token {
+			continue
+		}
+		device.Platform = req.Platform
+		device.DeviceName = req.DeviceName
+		device.AppVersion = req.AppVersion
+		device.Categories = req.Categories
+		device.MinSeverity = req.MinSeverity
+		device.Enabled = true
+		device.ConsecutiveFailures = 0
+		device.LastError = ""
+		return device
+	}
+
+	prefs.PushDevices = append(prefs.PushDevices, models.PushDevice{
+		Token:        req.Token,
+		Platform:     req.Platform,
+		DeviceName:   req.DeviceName,
+		AppVersion:   req.AppVersion,
+		Enabled:      true,
+		Categories:   req.Categories,
+		MinSeverity:  req.MinSeverity,
+		RegisteredAt: now,
+	})
+	return &prefs.PushDevices[len(prefs.PushDevices)-1]
+}
+
+// RemovePushToken removes a token from the registered devices and legacy tokens. It returns the
+// removed device, nil for a legacy token, and whether the token was found.
+func RemovePushToken(prefs *models.NotificationPreferences, token string) (*models.PushDevice, bool) {
+	var removed *models.PushDevice
+	found := false
+
+	devices := prefs.PushDevices[:0]
+	for _, device := range prefs.PushDevices {
+		if device.Token == token {
+			d := device
+			removed = &d
+			found = true
+			continue
+		}
+		devices = append(devices, device)
+	}
+	prefs.PushDevices = devices
+
+	if containsString(prefs.PushDeviceTokens, token) {
+		prefs.PushDeviceTokens = removeString(prefs.PushDeviceTokens, token)
+		found = true
+	}
+
+	return removed, found
+}
+
+// PrunePushDevice removes a token rejected by the push provider and records it at the front of
+// the recently pruned devices. It reports whether the token was found.
+func PrunePushDevice(prefs *models.NotificationPreferences, token, reason string, now time.Time) bool {
+	removed, ok := RemovePushToken(prefs, token)
+	if !ok {
+		return false
+	}
+
+	pruned := models.PrunedPushDevice{Token: token, Reason: reason, PrunedAt: now}
+	if removed != nil {
+		pruned.Platform = removed.Platform
+		pruned.DeviceName = removed.DeviceName
+	}
+	prefs.PrunedPushDevices = append([]models.PrunedPushDevice{pruned}, prefs.PrunedPushDevices...)
+	if len(prefs.PrunedPushDevices) > maxPrunedPushDevices {
+		prefs.PrunedPushDevices = prefs.PrunedPushDevices[:maxPrunedPushDevices]
+	}
+
+	return true
+}
+
+// PushRecipients returns the tokens an event of a category and severity is pushed to: the
+// registered devices accepting it and every legacy token
+func PushRecipients(prefs *models.NotificationPreferences, category models.NotificationCategory, severity models.NotificationSeverity) []string {
+	var tokens []string
+	for _, device := range prefs.PushDevices {
+		if device.Accepts(category, severity) {
+			tokens = append(tokens, device.Token)
+		}
+	}
+	return append(tokens, prefs.PushDeviceTokens...)
+}
+
+// PushDeviceStatus classifies the delivery health of a device. Devices without a delivery since
+// staleSince are stale, unless they were registered after it and nothing was sent to them yet.
+func PushDeviceStatus(device models.PushDevice, staleSince time.Time) string {
+	switch {
+	case !device.Enabled:
+		return models.PushDeviceDisabled
+	case device.ConsecutiveFailures > 0:
+		return models.PushDeviceFailing
+	case device.LastSuccessAt != nil && !device.LastSuccessAt.Before(staleSince):
+		return models.PushDeviceHealthy
+	case device.LastSuccessAt == nil && !device.RegisteredAt.Before(staleSince):
+		return models.PushDeviceUnused
+	}
+	return models.PushDeviceStale
+}
+
+// SummarizePushTokenHealth builds the push token health report of users' preferences. Failing
+// devices are listed first, most consecutive failures first.
+func SummarizePushTokenHealth(prefs []*models.NotificationPreferences, staleDays int, now time.Time) *models.PushTokenHealthReport {
+	if staleDays <= 0 {
+		staleDays = defaultPushStaleDays
+	}
+	staleSince := now.AddDate(0, 0, -staleDays)
+
+	report := &models.PushTokenHealthReport{
+		ByStatus:       make(map[string]int),
+		ByPlatform:     make(map[models.PushPlatform]int),
+		StaleDays:      staleDays,
+		Unhealthy:      []models.PushDeviceHealth{},
+		RecentlyPruned: []models.PrunedPushDevice{},
+		GeneratedAt:    now,
+	}
+	for _, status := range []string{models.PushDeviceHealthy, models.PushDeviceFailing, models.PushDeviceStale, models.PushDeviceUnused, models.PushDeviceDisabled} {
+		report.ByStatus[status] = 0
+	}
+
+	for _, p := range prefs {
+		report.LegacyTokens += len(p.PushDeviceTokens)
+
+		for _, device := range p.PushDevices {
+			status := PushDeviceStatus(device, staleSince)
+			report.Devices++
+			report.ByStatus[status]++
+			report.ByPlatform[device.Platform]++
+
+			if status == models.PushDeviceFailing || status == models.PushDeviceStale {
+				report.Unhealthy = append(report.Unhealthy, models.PushDeviceHealth{
+					UserID:              p.UserID,
+					Token:               MaskPushToken(device.Token),
+					Platform:            device.Platform,
+					DeviceName:          device.DeviceName,
+					Status:              status,
+					ConsecutiveFailures: device.ConsecutiveFailures,
+					LastError:           device.LastError,
+					LastSuccessAt:       device.LastSuccessAt,
+					LastFailureAt:       device.LastFailureAt,
+					RegisteredAt:        device.RegisteredAt,
+				})
+			}
+		}
+
+		for _, pruned := range p.PrunedPushDevices {
+			if pruned.PrunedAt.Before(staleSince) {
+				continue
+			}
+			pruned.Token = MaskPushToken(pruned.Token)
+			report.RecentlyPruned = append(report.RecentlyPruned, pruned)
+		}
+	}
+	report.PrunedRecent = len(report.RecentlyPruned)
+
+	sort.SliceStable(report.Unhealthy, func(i, j int) bool {
+		a, b := report.Unhealthy[i], report.Unhealthy[j]
+		if a.ConsecutiveFailures != b.ConsecutiveFailures {
+			return a.ConsecutiveFailures > b.ConsecutiveFailures
+		}
+		return a.UserID < b.UserID
+	})
+	sort.SliceStable(report.RecentlyPruned, func(i, j int) bool {
+		return report.RecentlyPruned[i].PrunedAt.After(report.RecentlyPruned[j].PrunedAt)
+	})
+
+	return report
+}
+
+// MaskPushToken hides all but the last characters of a push token so reports do not expose
+// credentials that can be pushed to
+func MaskPushToken(token string) string {
+	const visible = 6
+	if len(token) <= visible {
+		return "****"
+	}
+	return "****" + token[len(token)-visible:]
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// removeString returns values without any occurrence of value
+func removeString(values []string, value string) []string {
+	var kept []string
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
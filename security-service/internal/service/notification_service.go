@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"math"
+	"time"
 
+	"security-service/internal/events"
 	"security-service/internal/integrations"
 	"security-service/internal/models"
 	"security-service/internal/repository"
@@ -11,19 +14,32 @@ import (
 // NotificationService handles notification business logic
 type NotificationService struct {
 	notificationRepo *repository.NotificationRepository
+	userRepo         *repository.UserRepository
 	client           *integrations.NotificationClient
+	eventBus         *events.Bus
+
+	// alerting holds the channels whose failure rate is above the alert threshold, so each
+	// breach is announced once
+	alerting map[models.NotificationType]bool
 }
 
 // NewNotificationService creates a new notification service
-func NewNotificationService(notificationRepo *repository.NotificationRepository, client *integrations.NotificationClient) *NotificationService {
+func NewNotificationService(notificationRepo *repository.NotificationRepository, userRepo *repository.UserRepository, client *integrations.NotificationClient, eventBus *events.Bus) *NotificationService {
 	return &NotificationService{
 		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
 		client:           client,
+		eventBus:         eventBus,
+		alerting:         make(map[models.NotificationType]bool),
 	}
 }
 
 // SendNotification sends a notification to a user
 func (s *NotificationService) SendNotification(ctx context.Context, req *models.NotificationSendRequest) (*models.NotificationResponse, error) {
+	if req.Type == models.NotificationTypeInApp {
+		return s.createInApp(ctx, req)
+	}
+
 	// Check user preferences
 	prefs, err := s.notificationRepo.GetPreferences(ctx, req.UserID)
 	if err != nil {
@@ -61,30 +77,107 @@ func (s *NotificationService) SendNotification(ctx context.Context, req *models.
 		return nil, err
 	}
 
-	// Send notification via external service
-	var sendErr error
-	switch req.Type {
-	case models.NotificationTypeEmail:
-		sendErr = s.client.SendEmail(ctx, req.Recipient, req.Subject, req.Content)
-	case models.NotificationTypeSMS:
-		sendErr = s.client.SendSMS(ctx, req.Recipient, req.Content)
-	case models.NotificationTypePush:
-		sendErr = s.client.SendPush(ctx, req.Recipient, req.Subject, req.Content)
-	}
+	// Send notification via the configured provider, failing over if necessary
+	provider, sendErr := s.client.Send(ctx, &integrations.NotificationMessage{
+		Type:      req.Type,
+		Recipient: req.Recipient,
+		Subject:   req.Subject,
+		Body:      req.Content,
+	})
+	createdNotification.Provider = provider
 
 	// Update notification status
 	if sendErr != nil {
-		s.notificationRepo.UpdateStatus(ctx, createdNotification.ID.Hex(), models.NotificationStatusFailed, sendErr.Error())
+		s.notificationRepo.UpdateDeliveryStatus(ctx, createdNotification.ID.Hex(), models.NotificationStatusFailed, sendErr.Error(), provider)
 		createdNotification.Status = models.NotificationStatusFailed
 		createdNotification.ErrorMsg = sendErr.Error()
 	} else {
-		s.notificationRepo.UpdateStatus(ctx, createdNotification.ID.Hex(), models.NotificationStatusSent, "")
+		s.notificationRepo.UpdateDeliveryStatus(ctx, createdNotification.ID.Hex(), models.NotificationStatusSent, "", provider)
 		createdNotification.Status = models.NotificationStatusSent
 	}
+	if req.Type == models.NotificationTypePush {
+		s.recordPushResult(ctx, req.UserID, req.Recipient, sendErr)
+	}
 
 	return createdNotification.ToResponse(), nil
 }
 
+// createInApp stores an in-app notification in the user's inbox. It is delivered as soon as it
+// is stored, so it does not depend on the user's channel preferences or a provider.
+func (s *NotificationService) createInApp(ctx context.Context, req *models.NotificationSendRequest) (*models.NotificationResponse, error) {
+	now := time.Now()
+	notification, err := s.notificationRepo.Create(ctx, &models.Notification{
+		UserID:      req.UserID,
+		Type:        models.NotificationTypeInApp,
+		Subject:     req.Subject,
+		Content:     req.Content,
+		Recipient:   req.UserID,
+		Status:      models.NotificationStatusDelivered,
+		Metadata:    req.Metadata,
+		Provider:    "inbox",
+		SentAt:      &now,
+		DeliveredAt: &now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notification.ToResponse(), nil
+}
+
+// GetInbox retrieves a page of a user's in-app notifications with their unread count
+func (s *NotificationService) GetInbox(ctx context.Context, userID string, params models.NotificationInboxQueryParams) (*models.NotificationInboxResponse, error) {
+	page := params.Page
+	limit := params.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	notifications, total, err := s.notificationRepo.FindInbox(ctx, userID, params.UnreadOnly, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.NotificationResponse, len(notifications))
+	for i, n := range notifications {
+		responses[i] = n.ToResponse()
+	}
+
+	return &models.NotificationInboxResponse{
+		Notifications: responses,
+		Total:         total,
+		Unread:        unread,
+		Page:          page,
+		Limit:         limit,
+		TotalPages:    int(math.Ceil(float64(total) / float64(limit))),
+	}, nil
+}
+
+// MarkRead marks one of a user's in-app notifications read
+func (s *NotificationService) MarkRead(ctx context.Context, userID, id string) (*models.NotificationResponse, error) {
+	notification, err := s.notificationRepo.MarkRead(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return notification.ToResponse(), nil
+}
+
+// MarkAllRead marks all of a user's in-app notifications read
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID string) (*models.NotificationsMarkedReadResponse, error) {
+	marked, err := s.notificationRepo.MarkAllRead(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.NotificationsMarkedReadResponse{Marked: marked}, nil
+}
+
 // UpdatePreferences updates user notification preferences
 func (s *NotificationService) UpdatePreferences(ctx context.Context, req *models.NotificationPreferencesUpdateRequest) (*models.NotificationPreferences, error) {
 	// Get existing preferences
@@ -124,6 +217,9 @@ func (s *NotificationService) UpdatePreferences(ctx context.Context, req *models
 	if req.NotificationTypes != nil {
 		prefs.NotificationTypes = req.NotificationTypes
 	}
+	if req.RoutingRules != nil {
+		prefs.RoutingRules = req.RoutingRules
+	}
 
 	// Save preferences
 	if err := s.notificationRepo.SavePreferences(ctx, prefs); err != nil {
@@ -133,6 +229,138 @@ func (s *NotificationService) UpdatePreferences(ctx context.Context, req *models
 	return prefs, nil
 }
 
+// NotifyEvent sends an event to a user on every channel their routing rules choose for it. A
+// channel that fails does not stop the others; the first error is returned with the
+// notifications that were sent.
+func (s *NotificationService) NotifyEvent(ctx context.Context, event *models.NotificationEvent) ([]*models.NotificationResponse, error) {
+	decision, err := s.route(ctx, event.UserID, event.Category, event.Severity, event.DefaultChannels)
+	if err != nil {
+		return nil, err
+	}
+
+	var sent []*models.NotificationResponse
+	var firstErr error
+	for _, channel := range decision.Channels {
+		if !channel.Deliver {
+			continue
+		}
+		for _, recipient := range channel.Recipients {
+			notification, err := s.SendNotification(ctx, &models.NotificationSendRequest{
+				UserID:    event.UserID,
+				Type:      channel.Type,
+				Subject:   event.Subject,
+				Content:   event.Content,
+				Recipient: recipient,
+				Metadata:  event.Metadata,
+			})
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			sent = append(sent, notification)
+		}
+	}
+
+	return sent, firstErr
+}
+
+// TestRoute reports the channels a hypothetical event would reach without sending anything.
+// Channels without a matching rule fall back to email and in-app, the channels used by most events.
+func (s *NotificationService) TestRoute(ctx context.Context, req *models.NotificationRouteTestRequest) (*models.NotificationRouteDecision, error) {
+	return s.route(ctx, req.UserID, req.Category, req.Severity, []models.NotificationType{models.NotificationTypeEmail, models.NotificationTypeInApp})
+}
+
+// route loads a user's preferences and account and evaluates their routing rules
+func (s *NotificationService) route(ctx context.Context, userID string, category models.NotificationCategory, severity models.NotificationSeverity, defaults []models.NotificationType) (*models.NotificationRouteDecision, error) {
+	prefs, err := s.notificationRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return RouteNotification(prefs, user, category, severity, defaults), nil
+}
+
+// RouteNotification decides which channels an event reaches. The first routing rule matching the
+// event's category and severity chooses the channels, otherwise the defaults are used. Channels
+// disabled in the preferences or without a recipient are skipped. Email and SMS go to the address
+// in the preferences, falling back to the user's account, and push goes to every registered
+// device whose own preferences accept the event and to every legacy device token.
+func RouteNotification(prefs *models.NotificationPreferences, user *models.User, category models.NotificationCategory, severity models.NotificationSeverity, defaults []models.NotificationType) *models.NotificationRouteDecision {
+	decision := &models.NotificationRouteDecision{
+		UserID:   prefs.UserID,
+		Category: category,
+		Severity: severity,
+	}
+
+	channels := defaults
+	for i, rule := range prefs.RoutingRules {
+		if rule.Matches(category, severity) {
+			matched := i
+			decision.MatchedRule = &matched
+			channels = rule.Channels
+			break
+		}
+	}
+
+	var accountEmail, accountPhone string
+	if user != nil {
+		accountEmail, accountPhone = user.Email, user.PhoneNumber
+	}
+
+	seen := make(map[models.NotificationType]bool, len(channels))
+	for _, channelType := range channels {
+		if seen[channelType] {
+			continue
+		}
+		seen[channelType] = true
+
+		channel := models.NotificationChannelRoute{Type: channelType}
+		switch channelType {
+		case models.NotificationTypeEmail:
+			channel.Deliver = prefs.EmailEnabled
+			channel.Recipients = firstNonEmpty(prefs.EmailAddress, accountEmail)
+		case models.NotificationTypeSMS:
+			channel.Deliver = prefs.SMSEnabled
+			channel.Recipients = firstNonEmpty(prefs.PhoneNumber, accountPhone)
+		case models.NotificationTypePush:
+			channel.Deliver = prefs.PushEnabled
+			channel.Recipients = PushRecipients(prefs, category, severity)
+		case models.NotificationTypeInApp:
+			channel.Deliver = true
+			channel.Recipients = []string{prefs.UserID}
+		}
+
+		switch {
+		case !channel.Deliver:
+			channel.Reason = "channel is disabled in the user's preferences"
+		case len(channel.Recipients) == 0 && channelType == models.NotificationTypePush && len(prefs.PushDevices) > 0:
+			channel.Deliver = false
+			channel.Reason = "no push device accepts this event"
+		case len(channel.Recipients) == 0:
+			channel.Deliver = false
+			channel.Reason = "no recipient is known for this channel"
+		}
+		decision.Channels = append(decision.Channels, channel)
+	}
+
+	return decision
+}
+
+// firstNonEmpty returns the first non-empty value as a single recipient, or nil if all are empty
+func firstNonEmpty(values ...string) []string {
+	for _, value := range values {
+		if value != "" {
+			return []string{value}
+		}
+	}
+	return nil
+}
+
 // GetPreferences retrieves user notification preferences
 func (s *NotificationService) GetPreferences(ctx context.Context, userID string) (*models.NotificationPreferences, error) {
 	return s.notificationRepo.GetPreferences(ctx, userID)
@@ -143,9 +371,15 @@ func (s *NotificationService) GetLogs(ctx context.Context, params models.Notific
 	return s.notificationRepo.GetPaginatedResponse(ctx, params)
 }
 
+// GetProviderHealth checks the notification providers and reports per-type routing
+func (s *NotificationService) GetProviderHealth(ctx context.Context) *models.NotificationProvidersStatus {
+	return s.client.ProvidersStatus(ctx)
+}
+
 // Custom errors
 var (
 	ErrNotificationDisabled = NewServiceError("notification type is disabled for this user")
+	ErrPushDeviceNotFound   = NewServiceError("push device not found")
 )
 
 // ServiceError represents a service-level error
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"security-service/internal/events"
+	"security-service/internal/models"
+)
+
+// GetDeliveryStats reports notification delivery counts, failure rates and latency percentiles
+// for notifications created in [from, to), overall and per channel and provider
+func (s *NotificationService) GetDeliveryStats(ctx context.Context, from, to time.Time) (*models.NotificationDeliveryStats, error) {
+	notifications, err := s.notificationRepo.FindForStats(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeNotificationDelivery(notifications, from, to), nil
+}
+
+// SummarizeNotificationDelivery computes delivery statistics of notifications. Time to sent and
+// time to delivered are measured from creation.
+func SummarizeNotificationDelivery(notifications []*models.Notification, from, to time.Time) *models.NotificationDeliveryStats {
+	overall := newDeliveryAccumulator("", "")
+	byType := make(map[models.NotificationType]*deliveryAccumulator)
+	byProvider := make(map[string]*deliveryAccumulator)
+
+	for _, n := range notifications {
+		if byType[n.Type] == nil {
+			byType[n.Type] = newDeliveryAccumulator(n.Type, "")
+		}
+		groups := []*deliveryAccumulator{overall, byType[n.Type]}
+		if n.Provider != "" {
+			if byProvider[n.Provider] == nil {
+				byProvider[n.Provider] = newDeliveryAccumulator("", n.Provider)
+			}
+			groups = append(groups, byProvider[n.Provider])
+		}
+		for _, group := range groups {
+			group.add(n)
+		}
+	}
+
+	stats := &models.NotificationDeliveryStats{
+		From:       from,
+		To:         to,
+		Overall:    overall.breakdown(),
+		ByType:     make([]models.NotificationDeliveryBreakdown, 0, len(byType)),
+		ByProvider: make([]models.NotificationDeliveryBreakdown, 0, len(byProvider)),
+	}
+	for _, group := range byType {
+		stats.ByType = append(stats.ByType, group.breakdown())
+	}
+	for _, group := range byProvider {
+		stats.ByProvider = append(stats.ByProvider, group.breakdown())
+	}
+	sort.Slice(stats.ByType, func(i, j int) bool { return stats.ByType[i].Type < stats.ByType[j].Type })
+	sort.Slice(stats.ByProvider, func(i, j int) bool { return stats.ByProvider[i].Provider < stats.ByProvider[j].Provider })
+
+	return stats
+}
+
+// deliveryAccumulator collects the delivery outcomes of a group of notifications
+type deliveryAccumulator struct {
+	result          models.NotificationDeliveryBreakdown
+	timeToSent      []int64
+	timeToDelivered []int64
+}
+
+func newDeliveryAccumulator(notificationType models.NotificationType, provider string) *deliveryAccumulator {
+	return &deliveryAccumulator{result: models.NotificationDeliveryBreakdown{Type: notificationType, Provider: provider}}
+}
+
+func (a *deliveryAccumulator) add(n *models.Notification) {
+	a.result.Total++
+	switch n.Status {
+	case models.NotificationStatusPending:
+		a.result.Pending++
+	case models.NotificationStatusSent:
+		a.result.Sent++
+	case models.NotificationStatusDelivered:
+		a.result.Delivered++
+	case models.NotificationStatusFailed:
+		a.result.Failed++
+	}
+
+	if n.SentAt != nil {
+		a.timeToSent = append(a.timeToSent, n.SentAt.Sub(n.CreatedAt).Milliseconds())
+	}
+	if n.DeliveredAt != nil {
+		a.timeToDelivered = append(a.timeToDelivered, n.DeliveredAt.Sub(n.CreatedAt).Milliseconds())
+	}
+}
+
+func (a *deliveryAccumulator) breakdown() models.NotificationDeliveryBreakdown {
+	result := a.result
+	if attempted := result.Sent + result.Delivered + result.Failed; attempted > 0 {
+		result.FailureRatePercent = math.Round(float64(result.Failed)/float64(attempted)*10000) / 100
+	}
+	result.TimeToSent = latencyStats(a.timeToSent)
+	result.TimeToDelivered = latencyStats(a.timeToDelivered)
+	return result
+}
+
+// latencyStats computes nearest-rank percentiles of latencies in milliseconds
+func latencyStats(latencies []int64) models.NotificationLatencyStats {
+	if len(latencies) == 0 {
+		return models.NotificationLatencyStats{}
+	}
+
+	sorted := append([]int64(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) int64 {
+		rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return sorted[rank]
+	}
+
+	return models.NotificationLatencyStats{
+		Count: len(sorted),
+		P50Ms: percentile(50),
+		P90Ms: percentile(90),
+		P95Ms: percentile(95),
+		P99Ms: percentile(99),
+		MaxMs: sorted[len(sorted)-1],
+	}
+}
+
+// CheckFailureRates compares each channel's failure rate over the rolling window with the
+// threshold and publishes an alert when a channel rises above it. A channel alerts again only
+// after its rate has dropped back under the threshold. Channels with fewer than minSamples
+// finished notifications are not judged. Returns the alerts raised.
+func (s *NotificationService) CheckFailureRates(ctx context.Context, window time.Duration, thresholdPercent, minSamples int) ([]*events.NotificationFailureRateExceededData, error) {
+	now := time.Now()
+	notifications, err := s.notificationRepo.FindForStats(ctx, now.Add(-window), now)
+	if err != nil {
+		return nil, err
+	}
+	stats := SummarizeNotificationDelivery(notifications, now.Add(-window), now)
+
+	failedByProvider := make(map[models.NotificationType]map[string]int64)
+	for _, n := range notifications {
+		if n.Status != models.NotificationStatusFailed || n.Provider == "" {
+			continue
+		}
+		if failedByProvider[n.Type] == nil {
+			failedByProvider[n.Type] = make(map[string]int64)
+		}
+		failedByProvider[n.Type][n.Provider]++
+	}
+
+	var alerts []*events.NotificationFailureRateExceededData
+	for _, channel := range stats.ByType {
+		attempted := channel.Sent + channel.Delivered + channel.Failed
+		if attempted < int64(minSamples) {
+			continue
+		}
+		if channel.FailureRatePercent <= float64(thresholdPercent) {
+			delete(s.alerting, channel.Type)
+			continue
+		}
+		if s.alerting[channel.Type] {
+			continue
+		}
+		s.alerting[channel.Type] = true
+
+		alert := &events.NotificationFailureRateExceededData{
+			Channel:            string(channel.Type),
+			FailureRatePercent: channel.FailureRatePercent,
+			ThresholdPercent:   thresholdPercent,
+			Failed:             channel.Failed,
+			Attempted:          attempted,
+			FailedByProvider:   failedByProvider[channel.Type],
+			WindowMinutes:      int(window.Minutes()),
+			DetectedAt:         now,
+		}
+		log.Printf("Notification failure rate of %s is %.1f%% over the last %s (threshold %d%%)",
+			channel.Type, channel.FailureRatePercent, window, thresholdPercent)
+		s.eventBus.Publish(ctx, events.NotificationFailureRateExceeded, alert)
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// StartFailureAlertWorker periodically checks notification failure rates until the context is
+// cancelled
+func (s *NotificationService) StartFailureAlertWorker(ctx context.Context, interval, window time.Duration, thresholdPercent, minSamples int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.CheckFailureRates(ctx, window, thresholdPercent, minSamples); err != nil {
+				log.Printf("Failed to check notification failure rates: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"security-service/internal/integrations"
+	"security-service/internal/models"
+	"security-service/internal/repository"
+	"security-service/internal/tenant"
+)
+
+// OrganizationService handles organization management business logic. Organizations are managed
+// by super admins; their members only see the users, groups, roles and buildings they own.
+type OrganizationService struct {
+	orgRepo     *repository.OrganizationRepository
+	userRepo    *repository.UserRepository
+	auditRepo   *repository.AuditRepository
+	roleChanges *integrations.RoleChangePublisher
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(
+	orgRepo *repository.OrganizationRepository,
+	userRepo *repository.UserRepository,
+	auditRepo *repository.AuditRepository,
+	roleChanges *integrations.RoleChangePublisher,
+) *OrganizationService {
+	return &OrganizationService{
+		orgRepo:     orgRepo,
+		userRepo:    userRepo,
+		auditRepo:   auditRepo,
+		roleChanges: roleChanges,
+	}
+}
+
+// CreateOrganization creates a new organization owning the given buildings
+func (s *OrganizationService) CreateOrganization(ctx context.Context, req *models.OrganizationCreateRequest, creatorID string) (*models.OrganizationResponse, error) {
+	buildingIDs := uniqueStrings(req.BuildingIDs)
+	if err := s.checkBuildingOwnership(ctx, buildingIDs, primitive.NilObjectID); err != nil {
+		return nil, err
+	}
+
+	org, err := s.orgRepo.Create(ctx, &models.Organization{
+		Name:        req.Name,
+		Description: req.Description,
+		BuildingIDs: buildingIDs,
+		Status:      models.OrganizationStatusActive,
+		CreatedBy:   creatorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAuditEvent(ctx, creatorID, "CREATE_ORGANIZATION", org.ID.Hex(), map[string]interface{}{
+		"name":        org.Name,
+		"buildingIds": org.BuildingIDs,
+	})
+
+	return org.ToResponse(0), nil
+}
+
+// GetOrganization retrieves an organization with the number of its users
+func (s *OrganizationService) GetOrganization(ctx context.Context, id string) (*models.OrganizationResponse, error) {
+	org, err := s.orgRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(ctx, org)
+}
+
+// ListOrganizations retrieves organizations with pagination, optionally filtered by status
+func (s *OrganizationService) ListOrganizations(ctx context.Context, status string, page, limit int) ([]*models.OrganizationResponse, int64, int, error) {
+	orgs, total, err := s.orgRepo.FindAll(ctx, status, page, limit)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	responses := make([]*models.OrganizationResponse, 0, len(orgs))
+	for _, org := range orgs {
+		response, err := s.toResponse(ctx, org)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		responses = append(responses, response)
+	}
+
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+
+	return responses, total, totalPages, nil
+}
+
+// UpdateOrganization updates an organization. Changing its buildings or status invalidates the
+// cached token validations of every service, since tokens carry the tenant scope.
+func (s *OrganizationService) UpdateOrganization(ctx context.Context, id string, req *models.OrganizationUpdateRequest, updaterID string) (*models.OrganizationResponse, error) {
+	existing, err := s.orgRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := bson.M{}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.BuildingIDs != nil {
+		buildingIDs := uniqueStrings(req.BuildingIDs)
+		if err := s.checkBuildingOwnership(ctx, buildingIDs, existing.ID); err != nil {
+			return nil, err
+		}
+		updates["building_ids"] = buildingIDs
+	}
+	if req.Status != "" {
+		updates["status"] = req.Status
+	}
+	if req.UsageLimits != nil {
+		limits, err := usageLimits(req.UsageLimits)
+		if err != nil {
+			return nil, err
+		}
+		updates["usage_limits"] = limits
+	}
+	if len(updates) == 0 {
+		return nil, errors.New("no updates provided")
+	}
+
+	org, err := s.orgRepo.Update(ctx, id, updates)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.BuildingIDs != nil || (req.Status != "" && req.Status != existing.Status) {
+		s.roleChanges.Publish(models.RoleChangeOrgUpdated, "", "")
+	}
+
+	s.logAuditEvent(ctx, updaterID, "UPDATE_ORGANIZATION", id, map[string]interface{}{
+		"name":        org.Name,
+		"status":      org.Status,
+		"buildingIds": org.BuildingIDs,
+		"usageLimits": org.UsageLimits,
+	})
+
+	return s.toResponse(ctx, org)
+}
+
+// usageLimits validates the monthly usage limits of an organization and drops the removed ones
+func usageLimits(requested map[string]int64) (map[string]int64, error) {
+	limits := make(map[string]int64, len(requested))
+	for metric, limit := range requested {
+		if !models.IsUsageMetric(metric) {
+			return nil, fmt.Errorf("validation failed: unknown usage metric %s", metric)
+		}
+		if limit < 0 {
+			return nil, fmt.Errorf("validation failed: usage limit of %s must not be negative", metric)
+		}
+		if limit > 0 {
+			limits[metric] = limit
+		}
+	}
+	return limits, nil
+}
+
+// DeleteOrganization removes an organization that no longer has any users
+func (s *OrganizationService) DeleteOrganization(ctx context.Context, id, deleterID string) error {
+	org, err := s.orgRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	userCount, err := s.userRepo.CountByOrg(ctx, id)
+	if err != nil {
+		return err
+	}
+	if userCount > 0 {
+		return fmt.Errorf("organization still has %d users", userCount)
+	}
+
+	if err := s.orgRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.roleChanges.Publish(models.RoleChangeOrgUpdated, "", "")
+
+	s.logAuditEvent(ctx, deleterID, "DELETE_ORGANIZATION", id, map[string]interface{}{
+		"name": org.Name,
+	})
+
+	return nil
+}
+
+// checkBuildingOwnership rejects buildings already owned by another organization
+func (s *OrganizationService) checkBuildingOwnership(ctx context.Context, buildingIDs []string, orgID primitive.ObjectID) error {
+	if len(buildingIDs) == 0 {
+		return nil
+	}
+
+	owners, err := s.orgRepo.FindOwnersOfBuildings(ctx, buildingIDs, orgID)
+	if err != nil {
+		return err
+	}
+	if len(owners) > 0 {
+		return fmt.Errorf("building is already owned by organization %s", owners[0].Name)
+	}
+	return nil
+}
+
+// toResponse converts an organization to its response with the number of its users
+func (s *OrganizationService) toResponse(ctx context.Context, org *models.Organization) (*models.OrganizationResponse, error) {
+	userCount, err := s.userRepo.CountByOrg(ctx, org.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return org.ToResponse(userCount), nil
+}
+
+// logAuditEvent logs an organization management event
+func (s *OrganizationService) logAuditEvent(ctx context.Context, userID, action, resourceID string, details map[string]interface{}) {
+	auditLog := &models.AuditLog{
+		UserID:     userID,
+		Service:    "security-service",
+		Action:     action,
+		Resource:   "organization",
+		ResourceID: resourceID,
+		Details:    details,
+		Status:     "SUCCESS",
+		Timestamp:  time.Now(),
+	}
+
+	if _, err := s.auditRepo.Create(ctx, auditLog); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+}
+
+// checkGrantableRoles rejects granting the super_admin role from a request scoped to an
+// organization, so organization admins cannot escape their tenant
+func checkGrantableRoles(ctx context.Context, roles []string) error {
+	if tenant.FromContext(ctx) == nil {
+		return nil
+	}
+	for _, role := range roles {
+		if role == models.RoleSuperAdmin {
+			return errors.New("only super admins can grant the super_admin role")
+		}
+	}
+	return nil
+}
+
+// uniqueStrings returns the values without duplicates, keeping their order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	return unique
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"security-service/internal/models"
+	"security-service/internal/repository"
+	"security-service/pkg/utils"
+)
+
+// PIIEncryptionService re-encrypts user and notification preference PII with the current key,
+// so that retired keys can be removed after a rotation. Records written before encryption was
+// enabled are encrypted on the first run.
+type PIIEncryptionService struct {
+	userRepo         *repository.UserRepository
+	notificationRepo *repository.NotificationRepository
+	auditRepo        *repository.AuditRepository
+	encryptor        *utils.FieldEncryptor
+	interval         time.Duration
+
+	mu      sync.Mutex
+	running bool
+	lastRun *models.PIIReencryptionRun
+}
+
+// NewPIIEncryptionService creates a new PII encryption service
+func NewPIIEncryptionService(
+	userRepo *repository.UserRepository,
+	notificationRepo *repository.NotificationRepository,
+	auditRepo *repository.AuditRepository,
+	encryptor *utils.FieldEncryptor,
+	interval time.Duration,
+) *PIIEncryptionService {
+	return &PIIEncryptionService{
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		auditRepo:        auditRepo,
+		encryptor:        encryptor,
+		interval:         interval,
+	}
+}
+
+// Status reports the encryption keys and the number of records awaiting re-encryption
+func (s *PIIEncryptionService) Status(ctx context.Context) (*models.PIIEncryptionStatus, error) {
+	pendingUsers, err := s.userRepo.CountStalePII(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pendingPreferences, err := s.notificationRepo.CountStalePreferencesPII(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	retired := s.encryptor.RetiredKeyIDs()
+	sort.Strings(retired)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &models.PIIEncryptionStatus{
+		KeyID:              s.encryptor.KeyID(),
+		RetiredKeyIDs:      retired,
+		IntervalHours:      int(s.interval.Hours()),
+		PendingUsers:       pendingUsers,
+		PendingPreferences: pendingPreferences,
+		LastRun:            s.lastRun,
+	}, nil
+}
+
+// Reencrypt encrypts every user and notification preference record that is plaintext or
+// encrypted with a retired key with the current key. Only one run proceeds at a time.
+func (s *PIIEncryptionService) Reencrypt(ctx context.Context, userID string) (*models.PIIReencryptionRun, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, errors.New("PII re-encryption is already running")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	run := &models.PIIReencryptionRun{KeyID: s.encryptor.KeyID(), StartedAt: time.Now()}
+	err := s.reencrypt(ctx, run)
+	if err != nil {
+		run.Error = err.Error()
+	} else {
+		completedAt := time.Now()
+		run.CompletedAt = &completedAt
+	}
+
+	s.mu.Lock()
+	s.running = false
+	s.lastRun = run
+	s.mu.Unlock()
+
+	if run.ReencryptedUsers > 0 || run.ReencryptedPreferences > 0 || run.Failed > 0 || err != nil {
+		log.Printf("PII re-encryption with key %s: %d users, %d preferences, %d failed",
+			run.KeyID, run.ReencryptedUsers, run.ReencryptedPreferences, run.Failed)
+		s.logAuditEvent(ctx, userID, run)
+	}
+
+	return run, err
+}
+
+// reencrypt re-encrypts users, then notification preferences, recording the counts on the run
+func (s *PIIEncryptionService) reencrypt(ctx context.Context, run *models.PIIReencryptionRun) error {
+	users, failed, err := s.userRepo.ReencryptPII(ctx)
+	run.ReencryptedUsers = users
+	run.Failed += failed
+	if err != nil {
+		return err
+	}
+
+	preferences, failed, err := s.notificationRepo.ReencryptPreferencesPII(ctx)
+	run.ReencryptedPreferences = preferences
+	run.Failed += failed
+	return err
+}
+
+// StartWorker periodically re-encrypts stale PII until the context is cancelled. The first run
+// starts immediately, encrypting records written before encryption was enabled.
+func (s *PIIEncryptionService) StartWorker(ctx context.Context) {
+	if _, err := s.Reencrypt(ctx, "system"); err != nil {
+		log.Printf("Failed to re-encrypt PII: %v", err)
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Reencrypt(ctx, "system"); err != nil {
+				log.Printf("Failed to re-encrypt PII: %v", err)
+			}
+		}
+	}
+}
+
+// logAuditEvent logs a PII re-encryption run
+func (s *PIIEncryptionService) logAuditEvent(ctx context.Context, userID string, run *models.PIIReencryptionRun) {
+	auditLog := &models.AuditLog{
+		UserID:     userID,
+		Service:    "security-service",
+		Action:     "REENCRYPT_PII",
+		Resource:   "pii_encryption_key",
+		ResourceID: run.KeyID,
+		Details: map[string]interface{}{
+			"reencryptedUsers":       run.ReencryptedUsers,
+			"reencryptedPreferences": run.ReencryptedPreferences,
+			"failed":                 run.Failed,
+		},
+		Status:    "SUCCESS",
+		Timestamp: time.Now(),
+	}
+	if run.Error != "" {
+		auditLog.Status = "FAILURE"
+		auditLog.ErrorMsg = run.Error
+	}
+
+	if _, err := s.auditRepo.Create(ctx, auditLog); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"security-service/internal/models"
+)
+
+// RetentionFunc counts or deletes records created before a cutoff, returning the number affected
+type RetentionFunc func(ctx context.Context, before time.Time) (int64, error)
+
+// retentionPolicy is a registered collection retention rule
+type retentionPolicy struct {
+	collection string
+	retention  time.Duration
+	count      RetentionFunc
+	purge      RetentionFunc
+	status     models.RetentionCollectionStatus
+}
+
+// RetentionService periodically purges records older than each collection's retention period.
+// In dry-run mode it only counts the records that would be purged.
+type RetentionService struct {
+	dryRun   bool
+	interval time.Duration
+
+	mu       sync.Mutex
+	policies []*retentionPolicy
+}
+
+// NewRetentionService creates a new retention service
+func NewRetentionService(dryRun bool, interval time.Duration) *RetentionService {
+	return &RetentionService{dryRun: dryRun, interval: interval}
+}
+
+// Register adds a collection retention policy. A non-positive retention disables purging for the collection.
+func (s *RetentionService) Register(collection string, retention time.Duration, count, purge RetentionFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policies = append(s.policies, &retentionPolicy{
+		collection: collection,
+		retention:  retention,
+		count:      count,
+		purge:      purge,
+		status: models.RetentionCollectionStatus{
+			Collection:    collection,
+			RetentionDays: int(retention.Hours() / 24),
+			Enabled:       retention > 0,
+		},
+	})
+}
+
+// Run applies every enabled policy once and returns the updated status
+func (s *RetentionService) Run(ctx context.Context) *models.RetentionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, policy := range s.policies {
+		if policy.retention <= 0 {
+			continue
+		}
+
+		run := policy.purge
+		if s.dryRun {
+			run = policy.count
+		}
+
+		affected, err := run(ctx, now.Add(-policy.retention))
+		policy.status.LastRunAt = &now
+		policy.status.LastError = ""
+		if err != nil {
+			log.Printf("Retention purge failed for %s: %v", policy.collection, err)
+			policy.status.LastError = err.Error()
+			continue
+		}
+
+		policy.status.LastAffected = affected
+		if s.dryRun {
+			if affected > 0 {
+				log.Printf("Retention dry run: %d %s records would be purged", affected, policy.collection)
+			}
+			continue
+		}
+		policy.status.TotalPurged += affected
+		if affected > 0 {
+			log.Printf("Retention purged %d %s records", affected, policy.collection)
+		}
+	}
+
+	return s.statusLocked()
+}
+
+// Status reports the retention policies and their purge counters
+func (s *RetentionService) Status() *models.RetentionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statusLocked()
+}
+
+// statusLocked builds the status response; callers must hold the lock
+func (s *RetentionService) statusLocked() *models.RetentionStatus {
+	status := &models.RetentionStatus{
+		DryRun:          s.dryRun,
+		IntervalMinutes: int(s.interval.Minutes()),
+		Collections:     make([]models.RetentionCollectionStatus, len(s.policies)),
+	}
+	for i, policy := range s.policies {
+		status.Collections[i] = policy.status
+	}
+	return status
+}
+
+// StartWorker periodically applies retention policies until the context is cancelled
+func (s *RetentionService) StartWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Run(ctx)
+		}
+	}
+}
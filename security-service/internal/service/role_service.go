@@ -7,21 +7,26 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 
+	"security-service/internal/integrations"
 	"security-service/internal/models"
 	"security-service/internal/repository"
 )
 
 // RoleService handles role management business logic
 type RoleService struct {
-	roleRepo  *repository.RoleRepository
-	auditRepo *repository.AuditRepository
+	roleRepo    *repository.RoleRepository
+	userRepo    *repository.UserRepository
+	auditRepo   *repository.AuditRepository
+	roleChanges *integrations.RoleChangePublisher
 }
 
 // NewRoleService creates a new role service
-func NewRoleService(roleRepo *repository.RoleRepository, auditRepo *repository.AuditRepository) *RoleService {
+func NewRoleService(roleRepo *repository.RoleRepository, userRepo *repository.UserRepository, auditRepo *repository.AuditRepository, roleChanges *integrations.RoleChangePublisher) *RoleService {
 	return &RoleService{
-		roleRepo:  roleRepo,
-		auditRepo: auditRepo,
+		roleRepo:    roleRepo,
+		userRepo:    userRepo,
+		auditRepo:   auditRepo,
+		roleChanges: roleChanges,
 	}
 }
 
@@ -119,6 +124,7 @@ func (s *RoleService) UpdateRole(ctx context.Context, name string, req *models.R
 
 	// Log audit event
 	s.logAuditEvent(ctx, updaterID, "UPDATE_ROLE", "role", name, "SUCCESS", "")
+	s.roleChanges.Publish(models.RoleChangeRoleUpdated, "", name)
 
 	return updatedRole.ToResponse(), nil
 }
@@ -131,10 +137,112 @@ func (s *RoleService) DeleteRole(ctx context.Context, name, deleterID string) er
 
 	// Log audit event
 	s.logAuditEvent(ctx, deleterID, "DELETE_ROLE", "role", name, "SUCCESS", "")
+	s.roleChanges.Publish(models.RoleChangeRoleDeleted, "", name)
 
 	return nil
 }
 
+// GetRoleImpact lists the users who currently hold a role
+func (s *RoleService) GetRoleImpact(ctx context.Context, name string) (*models.RoleImpactResponse, error) {
+	if _, err := s.roleRepo.FindByName(ctx, name); err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepo.FindByRoles(ctx, []string{name})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.RoleImpactResponse{
+		RoleName:  name,
+		UserCount: len(users),
+		Users:     make([]models.RoleImpactUser, len(users)),
+	}
+	for i, user := range users {
+		response.Users[i] = models.RoleImpactUser{
+			ID:       user.ID.Hex(),
+			Username: user.Username,
+			Email:    user.Email,
+			IsActive: user.IsActive,
+			Roles:    user.Roles,
+		}
+	}
+
+	return response, nil
+}
+
+// PreviewRoleChange reports which (user, resource, action) combinations would be lost
+// if the role's permissions were replaced. Grants still provided by a user's other roles are not lost.
+func (s *RoleService) PreviewRoleChange(ctx context.Context, name string, req *models.RolePreviewRequest) (*models.RolePreviewResponse, error) {
+	role, err := s.roleRepo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepo.FindByRoles(ctx, []string{name})
+	if err != nil {
+		return nil, err
+	}
+
+	// Load every other role held by the affected users once
+	otherNames := make(map[string]bool)
+	for _, user := range users {
+		for _, roleName := range user.Roles {
+			if roleName != name {
+				otherNames[roleName] = true
+			}
+		}
+	}
+	rolesByName := make(map[string]*models.Role)
+	if len(otherNames) > 0 {
+		names := make([]string, 0, len(otherNames))
+		for roleName := range otherNames {
+			names = append(names, roleName)
+		}
+		otherRoles, err := s.roleRepo.FindByNames(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range otherRoles {
+			rolesByName[other.Name] = other
+		}
+	}
+
+	proposed := &models.Role{Permissions: req.Permissions}
+	response := &models.RolePreviewResponse{
+		RoleName:      name,
+		UserCount:     len(users),
+		RemovedGrants: models.LostGrants(role, req.Permissions, nil),
+		AddedGrants:   models.LostGrants(proposed, role.Permissions, nil),
+		Lost:          []models.LostPermission{},
+	}
+
+	for _, user := range users {
+		var otherRoles []*models.Role
+		for _, roleName := range user.Roles {
+			if other, ok := rolesByName[roleName]; ok && roleName != name {
+				otherRoles = append(otherRoles, other)
+			}
+		}
+
+		lost := models.LostGrants(role, req.Permissions, otherRoles)
+		if len(lost) == 0 {
+			continue
+		}
+		response.AffectedUserCount++
+		for _, grant := range lost {
+			response.Lost = append(response.Lost, models.LostPermission{
+				UserID:   user.ID.Hex(),
+				Username: user.Username,
+				Resource: grant.Resource,
+				Action:   grant.Action,
+			})
+		}
+	}
+
+	return response, nil
+}
+
 // InitializeDefaultRoles creates default system roles
 func (s *RoleService) InitializeDefaultRoles(ctx context.Context) error {
 	return s.roleRepo.InitializeDefaultRoles(ctx)
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"security-service/internal/config"
+	"security-service/internal/models"
+	"security-service/internal/repository"
+	"security-service/pkg/utils"
+)
+
+// SigningKeyService manages rotation of the keys access and refresh tokens are signed with.
+// Keys are stored encrypted so every instance loads the same key ring. Kiosk tokens are long-lived,
+// so retired keys past their grace period stay in the ring, for kiosk tokens only, while active
+// kiosk tokens were signed with them.
+type SigningKeyService struct {
+	keyRepo          *repository.SigningKeyRepository
+	kioskRepo        *repository.KioskRepository
+	auditRepo        *repository.AuditRepository
+	jwtManager       *utils.JWTManager
+	encryptor        *utils.Encryptor
+	configuredKey    *utils.SigningKey
+	algorithm        string
+	grace            time.Duration
+	rotationInterval time.Duration
+
+	mu               sync.Mutex
+	currentCreatedAt time.Time
+}
+
+// NewSigningKeyService creates a new signing key service
+func NewSigningKeyService(
+	keyRepo *repository.SigningKeyRepository,
+	kioskRepo *repository.KioskRepository,
+	auditRepo *repository.AuditRepository,
+	jwtManager *utils.JWTManager,
+	encryptor *utils.Encryptor,
+	jwtConfig config.JWTConfig,
+) *SigningKeyService {
+	return &SigningKeyService{
+		keyRepo:          keyRepo,
+		kioskRepo:        kioskRepo,
+		auditRepo:        auditRepo,
+		jwtManager:       jwtManager,
+		encryptor:        encryptor,
+		configuredKey:    utils.NewHMACKey(utils.DefaultKeyID, []byte(jwtConfig.Secret)),
+		algorithm:        jwtConfig.SigningAlgorithm,
+		grace:            jwtConfig.KeyRotationGrace,
+		rotationInterval: jwtConfig.KeyRotationInterval,
+	}
+}
+
+// Initialize loads the key ring and, when an asymmetric algorithm is configured, replaces the
+// configured HMAC key with a generated key pair
+func (s *SigningKeyService) Initialize(ctx context.Context) error {
+	if err := s.Reload(ctx); err != nil {
+		return err
+	}
+	if s.jwtManager.CurrentKey().ID == utils.DefaultKeyID && s.algorithm != utils.AlgorithmHS256 {
+		if _, err := s.RotateKey(ctx, s.algorithm, "system"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reload loads the stored keys into the JWT manager. The newest active key becomes the current
+// signing key; the configured key stays current until the first rotation. Expired keys are only
+// loaded if active kiosk tokens were signed with them.
+func (s *SigningKeyService) Reload(ctx context.Context) error {
+	records, err := s.keyRepo.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	kioskKeyIDs, err := s.kioskKeyIDs(ctx, records)
+	if err != nil {
+		return fmt.Errorf("failed to load kiosk token keys: %w", err)
+	}
+
+	now := time.Now()
+	keys := []*utils.SigningKey{}
+	currentKeyID := utils.DefaultKeyID
+	var currentCreatedAt time.Time
+	configuredRetired := false
+
+	for _, record := range records {
+		if record.KeyID == utils.DefaultKeyID {
+			configuredRetired = true
+			expired := record.ExpiresAt != nil && !now.Before(*record.ExpiresAt)
+			if !expired || kioskKeyIDs[record.KeyID] {
+				retired := *s.configuredKey
+				retired.ExpiresAt = record.ExpiresAt
+				retired.KioskOnly = expired
+				keys = append(keys, &retired)
+			}
+			continue
+		}
+		expired := record.ExpiresAt != nil && !now.Before(*record.ExpiresAt)
+		if expired && !kioskKeyIDs[record.KeyID] {
+			continue
+		}
+
+		material, err := s.encryptor.Decrypt(record.Material)
+		if err != nil {
+			log.Printf("Skipping signing key %s: failed to decrypt: %v", record.KeyID, err)
+			continue
+		}
+		key, err := utils.ParseSigningKey(record.KeyID, record.Algorithm, material, record.ExpiresAt)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", record.KeyID, err)
+			continue
+		}
+		key.KioskOnly = expired
+		keys = append(keys, key)
+
+		// Records are sorted newest first
+		if record.Status == models.SigningKeyStatusActive && currentKeyID == utils.DefaultKeyID {
+			currentKeyID = record.KeyID
+			currentCreatedAt = record.CreatedAt
+		}
+	}
+	if !configuredRetired {
+		keys = append(keys, s.configuredKey)
+	}
+
+	if err := s.jwtManager.SetKeys(keys, currentKeyID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.currentCreatedAt = currentCreatedAt
+	s.mu.Unlock()
+	return nil
+}
+
+// kioskKeyIDs returns the IDs of the keys active kiosk tokens were signed with. Kiosk tokens
+// issued before their key was recorded are attributed to the key that was current when they
+// were created.
+func (s *SigningKeyService) kioskKeyIDs(ctx context.Context, records []*models.SigningKey) (map[string]bool, error) {
+	kiosks, err := s.kioskRepo.FindActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keyIDs := make(map[string]bool)
+	for _, kiosk := range kiosks {
+		keyID := kiosk.KeyID
+		if keyID == "" {
+			keyID = keyCurrentAt(records, kiosk.CreatedAt)
+		}
+		keyIDs[keyID] = true
+	}
+	return keyIDs, nil
+}
+
+// keyCurrentAt returns the ID of the key that was current at a time: the newest generated key
+// created by then, or the configured key before the first rotation. Records are sorted newest first.
+func keyCurrentAt(records []*models.SigningKey, at time.Time) string {
+	for _, record := range records {
+		if record.KeyID != utils.DefaultKeyID && !record.CreatedAt.After(at) {
+			return record.KeyID
+		}
+	}
+	return utils.DefaultKeyID
+}
+
+// RotateKey generates a new signing key and makes it current. Tokens signed with the previous
+// key remain valid for the configured grace period.
+func (s *SigningKeyService) RotateKey(ctx context.Context, algorithm, userID string) (*models.SigningKey, error) {
+	if algorithm == "" {
+		algorithm = s.algorithm
+	}
+
+	keyID := primitive.NewObjectID().Hex()
+	_, material, err := utils.GenerateSigningKey(keyID, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encryptor.Encrypt(material)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+
+	previous := s.jwtManager.CurrentKey()
+	now := time.Now()
+	expiresAt := now.Add(s.grace)
+
+	record, err := s.keyRepo.Create(ctx, &models.SigningKey{
+		KeyID:     keyID,
+		Algorithm: algorithm,
+		Material:  encrypted,
+		Status:    models.SigningKeyStatusActive,
+		CreatedBy: userID,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.keyRepo.RetireOlderThan(ctx, now, now, expiresAt); err != nil {
+		return nil, err
+	}
+	if previous.ID == utils.DefaultKeyID {
+		if err := s.keyRepo.RetireConfigured(ctx, utils.DefaultKeyID, utils.AlgorithmHS256, now, expiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	s.logAuditEvent(ctx, userID, keyID, map[string]interface{}{
+		"algorithm":     algorithm,
+		"previousKeyId": previous.ID,
+		"graceUntil":    expiresAt,
+	})
+
+	record.Current = true
+	return record, nil
+}
+
+// ListKeys lists the stored signing keys without their material. The configured key is included
+// while it is still current.
+func (s *SigningKeyService) ListKeys(ctx context.Context) ([]*models.SigningKey, error) {
+	records, err := s.keyRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	currentKeyID := s.jwtManager.CurrentKey().ID
+	if currentKeyID == utils.DefaultKeyID {
+		records = append(records, &models.SigningKey{
+			KeyID:     utils.DefaultKeyID,
+			Algorithm: utils.AlgorithmHS256,
+			Status:    models.SigningKeyStatusActive,
+		})
+	}
+	for _, record := range records {
+		record.Current = record.KeyID == currentKeyID
+	}
+	return records, nil
+}
+
+// StartWorker periodically reloads the key ring, picking up rotations made by other instances,
+// and rotates the key when automatic rotation is configured
+func (s *SigningKeyService) StartWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil {
+				log.Printf("Failed to reload signing keys: %v", err)
+				continue
+			}
+			if s.rotationDue(time.Now()) {
+				if _, err := s.RotateKey(ctx, "", "system"); err != nil {
+					log.Printf("Failed to rotate signing key: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// rotationDue reports whether the current key is older than the rotation interval.
+// The configured key has no creation time and is rotated on the first check.
+func (s *SigningKeyService) rotationDue(now time.Time) bool {
+	if s.rotationInterval <= 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Sub(s.currentCreatedAt) >= s.rotationInterval
+}
+
+// logAuditEvent logs a signing key rotation
+func (s *SigningKeyService) logAuditEvent(ctx context.Context, userID, keyID string, details map[string]interface{}) {
+	auditLog := &models.AuditLog{
+		UserID:     userID,
+		Service:    "security-service",
+		Action:     "ROTATE_SIGNING_KEY",
+		Resource:   "signing_key",
+		ResourceID: keyID,
+		Details:    details,
+		Status:     "SUCCESS",
+		Timestamp:  time.Now(),
+	}
+
+	if _, err := s.auditRepo.Create(ctx, auditLog); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+}
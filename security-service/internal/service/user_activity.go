@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"security-service/internal/models"
+	"security-service/internal/tenant"
+)
+
+const (
+	// failedLoginFlagThreshold is the number of failed logins in a period that flags an account
+	failedLoginFlagThreshold = 5
+	// highActivityFactor is how many times the median number of actions flags an account
+	highActivityFactor = 3
+	// minHighActivityActions keeps quiet periods from flagging users with a handful of actions
+	minHighActivityActions = 100
+)
+
+// GetActivitySummary summarizes the audited activity of every user in a period, so admins can
+// spot dormant accounts and unusual activity. Organization-scoped admins see their own users.
+func (s *UserService) GetActivitySummary(ctx context.Context, params *models.UserActivityQueryParams, now time.Time) (*models.UserActivitySummary, error) {
+	from, to, err := ParseAccessReportPeriod(params.Period, now)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	users, err := s.userRepo.FindAllAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a scope every user's activity is aggregated, so logs of deleted users are skipped
+	// below rather than filtered by a list of every account
+	var userIDs []string
+	if tenant.FromContext(ctx) != nil {
+		userIDs = make([]string, len(users))
+		for i, user := range users {
+			userIDs[i] = user.ID.Hex()
+		}
+	}
+
+	stats, err := s.auditRepo.AggregateUserActivity(ctx, userIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := SummarizeUserActivity(users, stats, from, to, now)
+	if params.Flag != "" {
+		summary.Entries = filterUserActivity(summary.Entries, params.Flag)
+	}
+	return summary, nil
+}
+
+// SummarizeUserActivity combines users with their activity statistics in a period and flags
+// dormant accounts, repeated failed logins and activity far above that of other users. Entries
+// are ordered by username.
+func SummarizeUserActivity(users []*models.User, stats []*models.UserActivityStats, from, to, now time.Time) *models.UserActivitySummary {
+	byUser := make(map[string]*models.UserActivityStats, len(stats))
+	actions := make([]int, 0, len(stats))
+	for _, stat := range stats {
+		byUser[stat.UserID] = stat
+	}
+
+	summary := &models.UserActivitySummary{
+		From:        from,
+		To:          to,
+		GeneratedAt: now,
+		Users:       len(users),
+		Entries:     make([]*models.UserActivity, 0, len(users)),
+	}
+
+	for _, user := range users {
+		entry := &models.UserActivity{
+			UserID:      user.ID.Hex(),
+			Username:    user.Username,
+			Roles:       user.Roles,
+			IsActive:    user.IsActive,
+			CreatedAt:   user.CreatedAt,
+			LastLoginAt: user.LastLoginAt,
+			Flags:       []string{},
+		}
+		if stat, ok := byUser[entry.UserID]; ok {
+			lastActivity := stat.LastActivity
+			entry.LastActivityAt = &lastActivity
+			entry.Logins = stat.Logins
+			entry.FailedLogins = stat.FailedLogins
+			entry.CommandsIssued = stat.CommandsIssued
+			entry.ReportsGenerated = stat.ReportsGenerated
+			entry.Actions = stat.Actions
+			entry.Failures = stat.Failures
+			entry.IPAddresses = countAddresses(stat.IPAddresses)
+			if stat.LastLogin != nil && (entry.LastLoginAt == nil || stat.LastLogin.After(*entry.LastLoginAt)) {
+				entry.LastLoginAt = stat.LastLogin
+			}
+			actions = append(actions, stat.Actions)
+			summary.ActiveUsers++
+		}
+
+		// Accounts created during the period have not had the chance to sign in yet
+		if user.IsActive && user.CreatedAt.Before(from) && (entry.LastLoginAt == nil || entry.LastLoginAt.Before(from)) {
+			entry.Flags = append(entry.Flags, models.UserActivityFlagDormant)
+			summary.Dormant++
+		}
+		if entry.FailedLogins >= failedLoginFlagThreshold {
+			entry.Flags = append(entry.Flags, models.UserActivityFlagFailedLogins)
+		}
+		summary.Entries = append(summary.Entries, entry)
+	}
+
+	threshold := highActivityFactor * medianActions(actions)
+	if threshold < minHighActivityActions {
+		threshold = minHighActivityActions
+	}
+	for _, entry := range summary.Entries {
+		if entry.Actions >= threshold {
+			entry.Flags = append(entry.Flags, models.UserActivityFlagHighActivity)
+		}
+		if len(entry.Flags) > 0 {
+			summary.Flagged++
+		}
+	}
+
+	sort.SliceStable(summary.Entries, func(i, j int) bool {
+		return summary.Entries[i].Username < summary.Entries[j].Username
+	})
+	return summary
+}
+
+// filterUserActivity keeps the entries carrying a flag
+func filterUserActivity(entries []*models.UserActivity, flag string) []*models.UserActivity {
+	filtered := make([]*models.UserActivity, 0, len(entries))
+	for _, entry := range entries {
+		for _, f := range entry.Flags {
+			if f == flag {
+				filtered = append(filtered, entry)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// medianActions returns the median of the users' action counts, or 0 without any
+func medianActions(actions []int) int {
+	if len(actions) == 0 {
+		return 0
+	}
+	sorted := append([]int{}, actions...)
+	sort.Ints(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// countAddresses counts the non-empty IP addresses of a set
+func countAddresses(addresses []string) int {
+	count := 0
+	for _, address := range addresses {
+		if address != "" {
+			count++
+		}
+	}
+	return count
+}
@@ -3,21 +3,31 @@ package service
 import (
 	"context"
 	"errors"
+	"log"
 	"math"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 
+	"security-service/internal/events"
+	"security-service/internal/integrations"
 	"security-service/internal/models"
 	"security-service/internal/repository"
+	"security-service/internal/tenant"
 	"security-service/pkg/utils"
 )
 
 // UserService handles user management business logic
 type UserService struct {
-	userRepo  *repository.UserRepository
-	roleRepo  *repository.RoleRepository
-	auditRepo *repository.AuditRepository
+	userRepo    *repository.UserRepository
+	roleRepo    *repository.RoleRepository
+	auditRepo   *repository.AuditRepository
+	authRepo    *repository.AuthRepository
+	kioskRepo   *repository.KioskRepository
+	orgRepo     *repository.OrganizationRepository
+	roleChanges *integrations.RoleChangePublisher
+	eventBus    *events.Bus
 }
 
 // NewUserService creates a new user service
@@ -25,16 +35,34 @@ func NewUserService(
 	userRepo *repository.UserRepository,
 	roleRepo *repository.RoleRepository,
 	auditRepo *repository.AuditRepository,
+	authRepo *repository.AuthRepository,
+	kioskRepo *repository.KioskRepository,
+	orgRepo *repository.OrganizationRepository,
+	roleChanges *integrations.RoleChangePublisher,
+	eventBus *events.Bus,
 ) *UserService {
 	return &UserService{
-		userRepo:  userRepo,
-		roleRepo:  roleRepo,
-		auditRepo: auditRepo,
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		auditRepo:   auditRepo,
+		authRepo:    authRepo,
+		kioskRepo:   kioskRepo,
+		orgRepo:     orgRepo,
+		roleChanges: roleChanges,
+		eventBus:    eventBus,
 	}
 }
 
 // CreateUser creates a new user
 func (s *UserService) CreateUser(ctx context.Context, req *models.UserCreateRequest, creatorID string) (*models.UserResponse, error) {
+	orgID, err := s.resolveOrganization(ctx, req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantableRoles(ctx, req.Roles); err != nil {
+		return nil, err
+	}
+
 	// Validate roles exist
 	if len(req.Roles) > 0 {
 		roles, err := s.roleRepo.FindByNames(ctx, req.Roles)
@@ -60,6 +88,7 @@ func (s *UserService) CreateUser(ctx context.Context, req *models.UserCreateRequ
 		FirstName:    req.FirstName,
 		LastName:     req.LastName,
 		Roles:        req.Roles,
+		OrgID:        orgID,
 		IsActive:     true,
 	}
 
@@ -115,11 +144,15 @@ func (s *UserService) ListUsers(ctx context.Context, page, limit int) ([]*models
 // UpdateUser updates an existing user
 func (s *UserService) UpdateUser(ctx context.Context, id string, req *models.UserUpdateRequest, updaterID string) (*models.UserResponse, error) {
 	// Check if user exists
-	_, err := s.userRepo.FindByID(ctx, id)
+	existing, err := s.userRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := checkGrantableRoles(ctx, req.Roles); err != nil {
+		return nil, err
+	}
+
 	// Validate roles if provided
 	if len(req.Roles) > 0 {
 		roles, err := s.roleRepo.FindByNames(ctx, req.Roles)
@@ -169,6 +202,17 @@ func (s *UserService) UpdateUser(ctx context.Context, id string, req *models.Use
 	// Log audit event
 	s.logAuditEvent(ctx, updaterID, "UPDATE_USER", "user", id, "SUCCESS", "")
 
+	// Other services cache token validations, so tell them the user's access changed
+	if req.Roles != nil {
+		s.roleChanges.Publish(models.RoleChangeUserRoles, id, "")
+	}
+	if req.IsActive != nil {
+		s.roleChanges.Publish(models.RoleChangeUserStatus, id, "")
+		if !*req.IsActive && existing.IsActive {
+			s.deactivateAccess(ctx, updatedUser, updaterID, "DEACTIVATED")
+		}
+	}
+
 	return updatedUser.ToResponse(), nil
 }
 
@@ -193,16 +237,153 @@ func (s *UserService) DeleteUser(ctx context.Context, id, deleterID string) erro
 		}
 	}
 
-	if err := s.userRepo.Delete(ctx, id); err != nil {
+	if err := s.userRepo.Delete(ctx, id, deleterID); err != nil {
 		return err
 	}
 
 	// Log audit event
 	s.logAuditEvent(ctx, deleterID, "DELETE_USER", "user", id, "SUCCESS", "")
+	s.roleChanges.Publish(models.RoleChangeUserStatus, id, "")
+	s.deactivateAccess(ctx, user, deleterID, "DELETED")
 
 	return nil
 }
 
+// deactivateAccess revokes the sessions and kiosk tokens of a user who lost access, records the
+// result in the audit log and announces it on the event bus so other services release the
+// user's schedules, approvals and notifications
+func (s *UserService) deactivateAccess(ctx context.Context, user *models.User, actorID, reason string) {
+	userID := user.ID.Hex()
+	details := map[string]interface{}{"reason": reason}
+	status := "SUCCESS"
+	var failures []string
+
+	sessions, err := s.authRepo.CountActiveTokensForUser(ctx, userID)
+	if err == nil {
+		err = s.authRepo.RevokeUserTokens(ctx, userID)
+	}
+	if err != nil {
+		failures = append(failures, "sessions: "+err.Error())
+	} else {
+		details["sessionsRevoked"] = sessions
+	}
+
+	kiosks, err := s.kioskRepo.RevokeByCreator(ctx, userID, actorID)
+	if err != nil {
+		failures = append(failures, "kiosk tokens: "+err.Error())
+	} else {
+		details["kioskTokensRevoked"] = kiosks
+	}
+
+	errorMsg := ""
+	if len(failures) > 0 {
+		status = "FAILURE"
+		errorMsg = strings.Join(failures, "; ")
+	}
+
+	s.auditRepo.Create(ctx, &models.AuditLog{
+		UserID:     actorID,
+		Service:    "security-service",
+		Action:     "USER_DEACTIVATION_CASCADE",
+		Resource:   "user",
+		ResourceID: userID,
+		Status:     status,
+		ErrorMsg:   errorMsg,
+		Details:    details,
+		Timestamp:  time.Now(),
+	})
+
+	s.eventBus.Publish(ctx, events.UserDeactivated, &events.UserDeactivatedData{
+		UserID:        userID,
+		Username:      user.Username,
+		Reason:        reason,
+		DeactivatedBy: actorID,
+		DeactivatedAt: time.Now(),
+	})
+}
+
+// RestoreUser restores a soft-deleted user
+func (s *UserService) RestoreUser(ctx context.Context, id, restorerID string) (*models.UserResponse, error) {
+	user, err := s.userRepo.Restore(ctx, id)
+	if err != nil {
+		s.logAuditEvent(ctx, restorerID, "RESTORE_USER", "user", id, "FAILURE", err.Error())
+		return nil, err
+	}
+
+	s.logAuditEvent(ctx, restorerID, "RESTORE_USER", "user", id, "SUCCESS", "")
+	s.roleChanges.Publish(models.RoleChangeUserStatus, id, "")
+
+	return user.ToResponse(), nil
+}
+
+// ListDeletedUsers retrieves soft-deleted users awaiting purge
+func (s *UserService) ListDeletedUsers(ctx context.Context, page, limit int) ([]*models.UserResponse, int64, int, error) {
+	users, total, err := s.userRepo.FindDeleted(ctx, page, limit)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+
+	responses := make([]*models.UserResponse, len(users))
+	for i, user := range users {
+		responses[i] = user.ToResponse()
+	}
+
+	return responses, total, totalPages, nil
+}
+
+// PurgeDeletedUsers permanently removes users soft deleted longer than the retention period
+func (s *UserService) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.userRepo.PurgeDeletedBefore(ctx, time.Now().Add(-retention))
+}
+
+// StartPurgeWorker periodically purges soft-deleted users past retention until the context is cancelled
+func (s *UserService) StartPurgeWorker(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeDeletedUsers(ctx, retention)
+			if err != nil {
+				log.Printf("Failed to purge deleted users: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted users", purged)
+				s.logAuditEvent(ctx, "system", "PURGE_USERS", "user", "", "SUCCESS", "")
+			}
+		}
+	}
+}
+
+// resolveOrganization returns the organization a new user joins. Scoped callers can only create
+// users in their own organization; unscoped callers may place the user in any existing one.
+func (s *UserService) resolveOrganization(ctx context.Context, orgID string) (string, error) {
+	if scope := tenant.FromContext(ctx); scope != nil {
+		if orgID != "" && orgID != scope.OrgID {
+			return "", errors.New("cannot manage users of another organization")
+		}
+		return scope.OrgID, nil
+	}
+
+	if orgID == "" {
+		return "", nil
+	}
+	if _, err := s.orgRepo.FindByID(ctx, orgID); err != nil {
+		return "", err
+	}
+	return orgID, nil
+}
+
 // logAuditEvent logs a user management audit event
 func (s *UserService) logAuditEvent(ctx context.Context, userID, action, resource, resourceID, status, errorMsg string) {
 	log := &models.AuditLog{
@@ -219,7 +400,8 @@ func (s *UserService) logAuditEvent(ctx context.Context, userID, action, resourc
 	s.auditRepo.Create(ctx, log)
 }
 
-// InitializeAdminUser creates the default admin user if it doesn't exist
+// InitializeAdminUser creates the default admin user if it doesn't exist and makes sure an
+// existing platform-level admin holds the super admin role
 func (s *UserService) InitializeAdminUser(ctx context.Context) error {
 	exists, err := s.userRepo.ExistsByUsername(ctx, "admin")
 	if err != nil {
@@ -238,7 +420,7 @@ func (s *UserService) InitializeAdminUser(ctx context.Context) error {
 			PasswordHash: hashedPassword,
 			FirstName:    "System",
 			LastName:     "Administrator",
-			Roles:        []string{"admin"},
+			Roles:        []string{"admin", models.RoleSuperAdmin},
 			IsActive:     true,
 			CreatedAt:    time.Now(),
 			UpdatedAt:    time.Now(),
@@ -247,7 +429,24 @@ func (s *UserService) InitializeAdminUser(ctx context.Context) error {
 		if _, err := s.userRepo.Create(ctx, admin); err != nil {
 			return err
 		}
+		return nil
+	}
+
+	// Admin users created before organizations existed lack the super admin role and would be
+	// limited to their own tenant; grant it once to the platform-level bootstrap admin
+	admin, err := s.userRepo.FindByUsername(ctx, "admin")
+	if err != nil {
+		return err
+	}
+	if admin.OrgID != "" || admin.HasRole(models.RoleSuperAdmin) {
+		return nil
+	}
+
+	roles := append(append([]string{}, admin.Roles...), models.RoleSuperAdmin)
+	if _, err := s.userRepo.Update(ctx, admin.ID.Hex(), bson.M{"roles": roles}); err != nil {
+		return err
 	}
+	log.Printf("Granted %s to the bootstrap admin user", models.RoleSuperAdmin)
 
 	return nil
 }
@@ -0,0 +1,194 @@
+// Package validation configures the rules gin applies to request binding tags and turns binding
+// errors into field errors that clients can map back to their inputs
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"security-service/internal/models"
+)
+
+var registerOnce sync.Once
+
+// Register configures gin's validator. Fields are reported by their JSON or query parameter
+// names, and binding tags can use maxspan next to the built-in rules: maxspan=From 2160h holds
+// when a time is at most the duration after the named time field.
+func Register() {
+	registerOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(fieldName)
+		_ = v.RegisterValidation("maxspan", maxSpan)
+	})
+}
+
+// fieldName names a struct field by its JSON name, then its query parameter name
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// maxSpan checks that a time is at most a duration after another time field of the struct.
+// Unset times pass, so the rule combines with omitempty and the service defaults.
+func maxSpan(fl validator.FieldLevel) bool {
+	params := strings.Fields(fl.Param())
+	if len(params) != 2 {
+		return false
+	}
+	limit, err := time.ParseDuration(params[1])
+	if err != nil {
+		return false
+	}
+	end, ok := fl.Field().Interface().(time.Time)
+	if !ok {
+		return false
+	}
+	other := reflect.Indirect(fl.Parent()).FieldByName(params[0])
+	if !other.IsValid() {
+		return false
+	}
+	start, ok := other.Interface().(time.Time)
+	if !ok {
+		return false
+	}
+	if start.IsZero() || end.IsZero() {
+		return true
+	}
+	return end.Sub(start) <= limit
+}
+
+// FieldErrors describes the fields that failed binding. Errors that cannot be attributed to a
+// field, such as malformed JSON, yield none.
+func FieldErrors(err error) []models.FieldError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]models.FieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			fields = append(fields, models.FieldError{
+				Field:   fieldPath(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: message(fe),
+			})
+		}
+		return fields
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		return []models.FieldError{{
+			Field:   typeError.Field,
+			Rule:    "type",
+			Param:   typeError.Type.String(),
+			Message: fmt.Sprintf("must be %s, got %s", typeName(typeError.Type), typeError.Value),
+		}}
+	}
+	return nil
+}
+
+// fieldPath strips the request type from a validator namespace, e.g. "Request.items[0].name"
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// message describes a failed rule in words
+func message(fe validator.FieldError) string {
+	param := fe.Param()
+	kind := fe.Kind()
+	switch fe.Tag() {
+	case "required", "required_unless", "required_if", "required_with", "required_without":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "min", "gte":
+		return "must be at least " + measure(kind, param)
+	case "max", "lte":
+		return "must be at most " + measure(kind, param)
+	case "gt":
+		return "must be greater than " + measure(kind, param)
+	case "lt":
+		return "must be less than " + measure(kind, param)
+	case "len":
+		return "must be exactly " + measure(kind, param)
+	case "gtfield":
+		return "must be after " + lowerFirst(param)
+	case "gtefield":
+		return "must not be before " + lowerFirst(param)
+	case "ltfield":
+		return "must be before " + lowerFirst(param)
+	case "ltefield":
+		return "must not be after " + lowerFirst(param)
+	case "maxspan":
+		if params := strings.Fields(param); len(params) == 2 {
+			return fmt.Sprintf("must be at most %s after %s", params[1], lowerFirst(params[0]))
+		}
+	case "email":
+		return "must be an email address"
+	case "url":
+		return "must be a URL"
+	case "e164":
+		return "must be a phone number in E.164 format"
+	case "timezone":
+		return "must be an IANA time zone name"
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
+
+// measure qualifies a size limit by what it limits: characters, items or a value
+func measure(kind reflect.Kind, param string) string {
+	switch kind {
+	case reflect.String:
+		return param + " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return param + " items"
+	}
+	return param
+}
+
+// typeName names a Go type as the JSON type clients send
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// lowerFirst turns a Go field name into its usual JSON name
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EncryptedFieldPrefix marks a field value encrypted by a FieldEncryptor
+const EncryptedFieldPrefix = "enc:"
+
+// FieldEncryptor encrypts individual document fields with versioned keys, so that records can be
+// re-encrypted when the key is rotated. Encrypted values are stored as "enc:<keyID>:<ciphertext>";
+// values without the prefix were written before encryption was enabled and are read unchanged.
+// Hash derives a deterministic keyed digest of a value for exact-match lookups, since the
+// ciphertext of a value differs on every write.
+type FieldEncryptor struct {
+	keyID      string
+	encryptors map[string]*Encryptor
+	hashKey    []byte
+}
+
+// NewFieldEncryptor creates a field encryptor that encrypts with the key with the given ID and
+// decrypts with it or any of the retired keys, which are keyed by ID. The hash key must stay
+// the same across key rotations, or records can no longer be looked up.
+func NewFieldEncryptor(keyID, key string, retiredKeys map[string]string, hashKey string) (*FieldEncryptor, error) {
+	if keyID == "" || strings.Contains(keyID, ":") {
+		return nil, errors.New("encryption key ID must be non-empty and must not contain ':'")
+	}
+	if key == "" {
+		return nil, errors.New("encryption key is required")
+	}
+	if hashKey == "" {
+		return nil, errors.New("hash key is required")
+	}
+
+	f := &FieldEncryptor{
+		keyID:      keyID,
+		encryptors: make(map[string]*Encryptor, len(retiredKeys)+1),
+		hashKey:    []byte(hashKey),
+	}
+	for id, retired := range retiredKeys {
+		if id == keyID {
+			continue
+		}
+		encryptor, err := NewEncryptor(retired)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retired encryption key %q: %w", id, err)
+		}
+		f.encryptors[id] = encryptor
+	}
+
+	encryptor, err := NewEncryptor(key)
+	if err != nil {
+		return nil, err
+	}
+	f.encryptors[keyID] = encryptor
+
+	return f, nil
+}
+
+// KeyID returns the ID of the key values are encrypted with
+func (f *FieldEncryptor) KeyID() string {
+	return f.keyID
+}
+
+// RetiredKeyIDs returns the IDs of the keys only used for decryption
+func (f *FieldEncryptor) RetiredKeyIDs() []string {
+	ids := make([]string, 0, len(f.encryptors)-1)
+	for id := range f.encryptors {
+		if id != f.keyID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Encrypt encrypts a value with the current key. Empty values stay empty.
+func (f *FieldEncryptor) Encrypt(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	ciphertext, err := f.encryptors[f.keyID].Encrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return EncryptedFieldPrefix + f.keyID + ":" + ciphertext, nil
+}
+
+// Decrypt decrypts a stored value with the key it was encrypted with. Plaintext values are
+// returned unchanged.
+func (f *FieldEncryptor) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, EncryptedFieldPrefix) {
+		return value, nil
+	}
+
+	keyID, ciphertext, ok := strings.Cut(strings.TrimPrefix(value, EncryptedFieldPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted field")
+	}
+	encryptor, exists := f.encryptors[keyID]
+	if !exists {
+		return "", fmt.Errorf("unknown encryption key %q", keyID)
+	}
+	return encryptor.Decrypt(ciphertext)
+}
+
+// NeedsRotation reports whether a stored value is plaintext or encrypted with a key other than
+// the current one
+func (f *FieldEncryptor) NeedsRotation(value string) bool {
+	return value != "" && !strings.HasPrefix(value, f.CurrentPrefix())
+}
+
+// CurrentPrefix returns the prefix of values encrypted with the current key
+func (f *FieldEncryptor) CurrentPrefix() string {
+	return EncryptedFieldPrefix + f.keyID + ":"
+}
+
+// Hash returns the hex-encoded HMAC-SHA256 of a plaintext value. Empty values hash to empty.
+func (f *FieldEncryptor) Hash(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, f.hashKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
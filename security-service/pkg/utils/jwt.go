@@ -2,6 +2,9 @@ package utils
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -9,11 +12,16 @@ import (
 	"security-service/internal/models"
 )
 
-// JWTManager handles JWT token operations
+// JWTManager handles JWT token operations.
+// Tokens are signed with the current key and carry its ID in the "kid" header; any key in the
+// key ring that has not expired verifies tokens, so rotated-out keys keep working for a grace period.
 type JWTManager struct {
-	secretKey          []byte
 	accessTokenExpiry  time.Duration
 	refreshTokenExpiry time.Duration
+
+	mu           sync.RWMutex
+	keys         map[string]*SigningKey
+	currentKeyID string
 }
 
 // CustomClaims represents the JWT claims structure
@@ -22,27 +30,153 @@ type CustomClaims struct {
 	Username string   `json:"username"`
 	Email    string   `json:"email"`
 	Roles    []string `json:"roles"`
+	// Groups lists every group the user belongs to, directly or through parent groups. Roles
+	// already include the roles inherited from them.
+	Groups []string `json:"groups,omitempty"`
+	// Impersonation is set on tokens an admin obtained to act as the user
+	Impersonation *models.Impersonation `json:"impersonation,omitempty"`
+	// Kiosk is set on read-only tokens for public building dashboards
+	Kiosk *models.KioskScope `json:"kiosk,omitempty"`
+	// Tenant limits the token to the users and buildings of an organization, as of when the
+	// token was issued. It is not set for super admins and users outside any organization.
+	Tenant *models.TenantScope `json:"tenant,omitempty"`
+	// Masks lists the response fields the user may not see, as of when the token was issued
+	Masks map[string]string `json:"masks,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// NewJWTManager creates a new JWT manager
+// NewJWTManager creates a new JWT manager that signs with the configured secret until other keys are set
 func NewJWTManager(secret string, accessExpiry, refreshExpiry time.Duration) *JWTManager {
 	return &JWTManager{
-		secretKey:          []byte(secret),
 		accessTokenExpiry:  accessExpiry,
 		refreshTokenExpiry: refreshExpiry,
+		keys:               map[string]*SigningKey{DefaultKeyID: NewHMACKey(DefaultKeyID, []byte(secret))},
+		currentKeyID:       DefaultKeyID,
+	}
+}
+
+// SetKeys replaces the key ring. currentKeyID selects the key new tokens are signed with.
+func (m *JWTManager) SetKeys(keys []*SigningKey, currentKeyID string) error {
+	ring := make(map[string]*SigningKey, len(keys))
+	for _, key := range keys {
+		ring[key.ID] = key
+	}
+	current, ok := ring[currentKeyID]
+	if !ok {
+		return fmt.Errorf("current signing key %q is not in the key ring", currentKeyID)
+	}
+	if current.ExpiresAt != nil {
+		return fmt.Errorf("current signing key %q has been rotated out", currentKeyID)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = ring
+	m.currentKeyID = currentKeyID
+	return nil
+}
+
+// CurrentKey returns the key new tokens are signed with
+func (m *JWTManager) CurrentKey() *SigningKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keys[m.currentKeyID]
+}
+
+// VerificationKeys returns the keys that still verify tokens, ordered by ID
+func (m *JWTManager) VerificationKeys() []*SigningKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]*SigningKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		if !key.IsExpired(now) {
+			keys = append(keys, key)
+		}
 	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+// sign signs claims with the current key and records its ID in the token header
+func (m *JWTManager) sign(claims jwt.Claims) (string, error) {
+	token, _, err := m.signWithKeyID(claims)
+	return token, err
+}
+
+// signWithKeyID signs claims with the current key, also returning the key's ID
+func (m *JWTManager) signWithKeyID(claims jwt.Claims) (string, string, error) {
+	key := m.CurrentKey()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.signKey)
+	return signed, key.ID, err
+}
+
+// verificationKey looks up the key a token was signed with
+func (m *JWTManager) verificationKey(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header["kid"].(string)
+	if keyID == "" {
+		keyID = DefaultKeyID
+	}
+
+	m.mu.RLock()
+	key, ok := m.keys[keyID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if key.IsExpired(time.Now()) && !(key.KioskOnly && isKioskToken(token)) {
+		return nil, errors.New("signing key has been retired")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verifyKey, nil
+}
+
+// isKioskToken reports whether a token being verified carries a kiosk claim
+func isKioskToken(token *jwt.Token) bool {
+	claims, ok := token.Claims.(*CustomClaims)
+	return ok && claims.Kiosk != nil
 }
 
 // GenerateAccessToken creates a new access token for a user
 func (m *JWTManager) GenerateAccessToken(user *models.User) (string, error) {
+	return m.generateAccessToken(user, nil, nil, nil, m.accessTokenExpiry)
+}
+
+// GenerateAccessTokenWithMasks creates an access token scoped to the user's organization and
+// carrying the response fields the user may not see. A nil tenant leaves the token unscoped.
+func (m *JWTManager) GenerateAccessTokenWithMasks(user *models.User, tenant *models.TenantScope, masks map[string]string) (string, error) {
+	return m.generateAccessToken(user, tenant, nil, masks, m.accessTokenExpiry)
+}
+
+// GenerateImpersonationToken creates an access token for acting as a user on behalf of an admin
+func (m *JWTManager) GenerateImpersonationToken(user *models.User, tenant *models.TenantScope, impersonation *models.Impersonation, expiry time.Duration) (string, error) {
+	return m.generateAccessToken(user, tenant, impersonation, nil, expiry)
+}
+
+// GenerateDelegatedToken creates a short-lived access token for work a service runs on behalf
+// of a user
+func (m *JWTManager) GenerateDelegatedToken(user *models.User, tenant *models.TenantScope, masks map[string]string, expiry time.Duration) (string, error) {
+	return m.generateAccessToken(user, tenant, nil, masks, expiry)
+}
+
+func (m *JWTManager) generateAccessToken(user *models.User, tenant *models.TenantScope, impersonation *models.Impersonation, masks map[string]string, expiry time.Duration) (string, error) {
 	claims := CustomClaims{
-		UserID:   user.ID.Hex(),
-		Username: user.Username,
-		Email:    user.Email,
-		Roles:    user.Roles,
+		UserID:        user.ID.Hex(),
+		Username:      user.Username,
+		Email:         user.Email,
+		Roles:         user.Roles,
+		Groups:        user.Groups,
+		Impersonation: impersonation,
+		Tenant:        tenant,
+		Masks:         masks,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.accessTokenExpiry)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "security-service",
@@ -50,8 +184,43 @@ func (m *JWTManager) GenerateAccessToken(user *models.User) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.secretKey)
+	return m.sign(claims)
+}
+
+// GenerateKioskToken creates a read-only token scoped to one building's dashboards.
+// The token only expires if the kiosk record has an expiry. The ID of the key the token is
+// signed with is set on the record, so the key can be kept after rotation while the token is active.
+func (m *JWTManager) GenerateKioskToken(kiosk *models.KioskToken) (string, error) {
+	claims := CustomClaims{
+		UserID:   KioskUserID(kiosk.ID.Hex()),
+		Username: kiosk.Name,
+		Roles:    []string{models.KioskRole},
+		Kiosk: &models.KioskScope{
+			TokenID:    kiosk.ID.Hex(),
+			BuildingID: kiosk.BuildingID,
+		},
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "security-service",
+			Subject:   KioskUserID(kiosk.ID.Hex()),
+		},
+	}
+	if kiosk.ExpiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*kiosk.ExpiresAt)
+	}
+
+	token, keyID, err := m.signWithKeyID(claims)
+	if err != nil {
+		return "", err
+	}
+	kiosk.KeyID = keyID
+	return token, nil
+}
+
+// KioskUserID returns the identity kiosk tokens use in place of a user ID
+func KioskUserID(tokenID string) string {
+	return "kiosk:" + tokenID
 }
 
 // GenerateRefreshToken creates a new refresh token
@@ -66,8 +235,7 @@ func (m *JWTManager) GenerateRefreshToken(userID string) (string, time.Time, err
 		Subject:   userID,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString(m.secretKey)
+	signedToken, err := m.sign(claims)
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -77,13 +245,7 @@ func (m *JWTManager) GenerateRefreshToken(userID string) (string, time.Time, err
 
 // ValidateAccessToken validates an access token and returns the claims
 func (m *JWTManager) ValidateAccessToken(tokenString string) (*CustomClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return m.secretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, m.verificationKey)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -102,12 +264,7 @@ func (m *JWTManager) ValidateAccessToken(tokenString string) (*CustomClaims, err
 
 // ValidateRefreshToken validates a refresh token and returns the user ID
 func (m *JWTManager) ValidateRefreshToken(tokenString string) (string, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return m.secretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, m.verificationKey)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"security-service/internal/models"
+)
+
+// Supported token signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+// DefaultKeyID identifies the HMAC key configured with JWT_SECRET. Tokens without a key ID
+// in their header were issued before key rotation and are verified with it.
+const DefaultKeyID = "default"
+
+// rsaKeyBits is the size of generated RSA signing keys
+const rsaKeyBits = 2048
+
+// SigningKey is a key the JWT manager signs or verifies tokens with.
+// A key with ExpiresAt set has been rotated out and only verifies tokens until then.
+// KioskOnly marks a key past its grace period that is kept because active kiosk tokens were
+// signed with it; it only verifies kiosk tokens.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	ExpiresAt *time.Time
+	KioskOnly bool
+
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey creates an HS256 signing key from a shared secret
+func NewHMACKey(id string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Algorithm: AlgorithmHS256,
+		method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// GenerateSigningKey creates a new random key for the algorithm. The returned material is
+// the base64-encoded secret for HS256 or the PEM-encoded PKCS#8 private key otherwise,
+// and can be turned back into the key with ParseSigningKey.
+func GenerateSigningKey(id, algorithm string) (*SigningKey, string, error) {
+	var material string
+	switch algorithm {
+	case AlgorithmHS256:
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, "", err
+		}
+		material = base64.StdEncoding.EncodeToString(secret)
+	case AlgorithmRS256:
+		privateKey, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, "", err
+		}
+		if material, err = encodePrivateKey(privateKey); err != nil {
+			return nil, "", err
+		}
+	case AlgorithmES256:
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, "", err
+		}
+		if material, err = encodePrivateKey(privateKey); err != nil {
+			return nil, "", err
+		}
+	default:
+		return nil, "", fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	key, err := ParseSigningKey(id, algorithm, material, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, material, nil
+}
+
+// ParseSigningKey restores a signing key from material produced by GenerateSigningKey
+func ParseSigningKey(id, algorithm, material string, expiresAt *time.Time) (*SigningKey, error) {
+	if algorithm == AlgorithmHS256 {
+		secret, err := base64.StdEncoding.DecodeString(material)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HMAC key material: %w", err)
+		}
+		key := NewHMACKey(id, secret)
+		key.ExpiresAt = expiresAt
+		return key, nil
+	}
+
+	block, _ := pem.Decode([]byte(material))
+	if block == nil {
+		return nil, errors.New("invalid private key material")
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key material: %w", err)
+	}
+
+	key := &SigningKey{ID: id, Algorithm: algorithm, ExpiresAt: expiresAt, signKey: privateKey}
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != AlgorithmRS256 {
+			return nil, fmt.Errorf("RSA key cannot be used for %s", algorithm)
+		}
+		key.method = jwt.SigningMethodRS256
+		key.verifyKey = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		if algorithm != AlgorithmES256 {
+			return nil, fmt.Errorf("ECDSA key cannot be used for %s", algorithm)
+		}
+		key.method = jwt.SigningMethodES256
+		key.verifyKey = &k.PublicKey
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+	return key, nil
+}
+
+// IsAsymmetric reports whether the key verifies with a public key
+func (k *SigningKey) IsAsymmetric() bool {
+	return k.Algorithm != AlgorithmHS256
+}
+
+// IsExpired reports whether a rotated-out key no longer verifies tokens
+func (k *SigningKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// VerificationKey returns what an internal service needs to verify tokens signed with the key:
+// the base64-encoded secret for HS256, or the PEM-encoded public key otherwise
+func (k *SigningKey) VerificationKey() (string, error) {
+	if !k.IsAsymmetric() {
+		return base64.StdEncoding.EncodeToString(k.verifyKey.([]byte)), nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(k.verifyKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// JWK returns the public key in JSON Web Key format, or nil for HS256 keys
+func (k *SigningKey) JWK() *models.JWK {
+	jwk := &models.JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch publicKey := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size)))
+	default:
+		return nil
+	}
+	return jwk
+}
+
+// encodePrivateKey encodes a private key as PEM-encoded PKCS#8
+func encodePrivateKey(privateKey interface{}) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
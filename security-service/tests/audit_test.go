@@ -7,8 +7,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"security-service/internal/models"
+	"security-service/internal/service"
 )
 
 // TestAuditLogModel tests the AuditLog model
@@ -241,3 +243,112 @@ func TestAuditLogStatusValues(t *testing.T) {
 		})
 	}
 }
+
+// TestAuditLogCursor tests cursor encoding for keyset pagination
+func TestAuditLogCursor(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		cursor := models.AuditLogCursor{
+			Timestamp: time.Date(2024, 1, 15, 10, 0, 0, 123000000, time.UTC),
+			ID:        primitive.NewObjectID(),
+		}
+
+		decoded, err := models.DecodeAuditLogCursor(cursor.Encode())
+		require.NoError(t, err)
+		assert.True(t, cursor.Timestamp.Equal(decoded.Timestamp))
+		assert.Equal(t, cursor.ID, decoded.ID)
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		for _, token := range []string{"not-base64!", "bm9jb2xvbg", "YWJjOjEyMw"} {
+			_, err := models.DecodeAuditLogCursor(token)
+			assert.EqualError(t, err, "invalid cursor", token)
+		}
+	})
+}
+
+// TestAuditLogAccessCategory tests how audited actions are grouped in building access reports
+func TestAuditLogAccessCategory(t *testing.T) {
+	tests := []struct {
+		log      models.AuditLog
+		expected string
+	}{
+		{models.AuditLog{Action: "LOGIN", Resource: "auth"}, models.AccessCategoryLogin},
+		{models.AuditLog{Action: "SEND_COMMAND", Resource: "command"}, models.AccessCategoryDeviceCommand},
+		{models.AuditLog{Action: "APPROVE_COMMAND", Resource: "command"}, models.AccessCategoryDeviceCommand},
+		{models.AuditLog{Action: "APPLY_OPTIMIZATION", Resource: "optimization"}, models.AccessCategoryDeviceCommand},
+		{models.AuditLog{Action: "DOWNLOAD_REPORT", Resource: "report"}, models.AccessCategoryReportDownload},
+		{models.AuditLog{Action: "GENERATE_FORECAST", Resource: "forecast"}, models.AccessCategoryDataAccess},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.log.Action, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.log.AccessCategory())
+		})
+	}
+}
+
+// TestParseAccessReportPeriod tests the periods accepted by building access reports
+func TestParseAccessReportPeriod(t *testing.T) {
+	now := time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Default is the last 30 days", func(t *testing.T) {
+		from, to, err := service.ParseAccessReportPeriod("", now)
+		require.NoError(t, err)
+		assert.Equal(t, now.AddDate(0, 0, -30), from)
+		assert.Equal(t, now, to)
+	})
+
+	t.Run("Weeks", func(t *testing.T) {
+		from, _, err := service.ParseAccessReportPeriod("2w", now)
+		require.NoError(t, err)
+		assert.Equal(t, now.AddDate(0, 0, -14), from)
+	})
+
+	t.Run("Calendar month", func(t *testing.T) {
+		from, to, err := service.ParseAccessReportPeriod("2024-06", now)
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), from)
+		assert.Equal(t, time.Date(2024, 6, 30, 23, 59, 59, 999999999, time.UTC), to)
+	})
+
+	t.Run("Invalid periods", func(t *testing.T) {
+		for _, period := range []string{"d", "0d", "30m", "abc", "400d", "2024-08"} {
+			_, _, err := service.ParseAccessReportPeriod(period, now)
+			assert.Error(t, err, period)
+		}
+	})
+}
+
+// TestSummarizeAccess tests grouping building activity by user and access category
+func TestSummarizeAccess(t *testing.T) {
+	base := time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC)
+	logs := []*models.AuditLog{
+		{UserID: "u2", Username: "bob", Action: "LOGIN", Resource: "auth", Status: "SUCCESS", IPAddress: "10.0.0.2", Timestamp: base},
+		{UserID: "u2", Action: "SEND_COMMAND", Resource: "command", Status: "SUCCESS", IPAddress: "10.0.0.2", Timestamp: base.Add(time.Hour)},
+		{UserID: "u2", Action: "APPROVE_COMMAND", Resource: "command", Status: "FAILURE", IPAddress: "10.0.0.3", Timestamp: base.Add(30 * time.Minute)},
+		{UserID: "u1", Username: "alice", Action: "DOWNLOAD_REPORT", Resource: "report", Status: "SUCCESS", Timestamp: base.Add(2 * time.Hour)},
+		{UserID: "u1", Action: "LOGIN", Resource: "auth", Status: "SUCCESS", Timestamp: base.Add(time.Minute)},
+	}
+
+	report := service.SummarizeAccess("building-1", base, base.Add(24*time.Hour), logs)
+
+	assert.Equal(t, "building-1", report.BuildingID)
+	assert.Equal(t, 5, report.TotalEvents)
+	assert.Equal(t, 2, report.Users)
+	require.Len(t, report.Entries, 4)
+
+	// Ordered by username, then login, device commands, report downloads and data access
+	assert.Equal(t, "alice", report.Entries[0].Username)
+	assert.Equal(t, models.AccessCategoryLogin, report.Entries[0].Category)
+	assert.Equal(t, models.AccessCategoryReportDownload, report.Entries[1].Category)
+
+	commands := report.Entries[3]
+	assert.Equal(t, "bob", commands.Username, "username is taken from the user's login")
+	assert.Equal(t, models.AccessCategoryDeviceCommand, commands.Category)
+	assert.Equal(t, []string{"APPROVE_COMMAND", "SEND_COMMAND"}, commands.Actions)
+	assert.Equal(t, 2, commands.Events)
+	assert.Equal(t, 1, commands.Failures)
+	assert.Equal(t, base.Add(30*time.Minute), commands.FirstAccess)
+	assert.Equal(t, base.Add(time.Hour), commands.LastAccess)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.3"}, commands.IPAddresses)
+}
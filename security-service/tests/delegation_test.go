@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"security-service/internal/models"
+	"security-service/pkg/utils"
+)
+
+// TestDelegatedToken tests that a token issued for queued work carries the user's organization
+// and masks like a login token, but expires with the delegation
+func TestDelegatedToken(t *testing.T) {
+	jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+	user := &models.User{ID: primitive.NewObjectID(), Username: "analyst", Roles: []string{"analyst"}, OrgID: "org-1"}
+	scope := &models.TenantScope{OrgID: "org-1", BuildingIDs: []string{"building-1"}}
+	masks := map[string]string{"cost": "hidden"}
+
+	token, err := jwtManager.GenerateDelegatedToken(user, scope, masks, 2*time.Minute)
+	require.NoError(t, err)
+
+	claims, err := jwtManager.ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID.Hex(), claims.UserID)
+	assert.Equal(t, scope, claims.Tenant)
+	assert.Equal(t, masks, claims.Masks)
+	assert.Nil(t, claims.Impersonation)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), claims.ExpiresAt.Time, 5*time.Second)
+}
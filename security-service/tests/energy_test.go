@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/integrations"
+	"security-service/internal/models"
+)
+
+// TestEnergyProviderModel tests the EnergyProvider model
+func TestEnergyProviderModel(t *testing.T) {
+	provider := models.EnergyProvider{
+		Name:                  "north_grid",
+		BaseURL:               "https://api.north-grid.example",
+		EncryptedAPIKey:       "encrypted-key",
+		ClientID:              "client",
+		EncryptedClientSecret: "encrypted-secret",
+		Regions: []models.EnergyProviderRegion{
+			{Region: "NG-1", BuildingIDs: []string{"building-1", "building-2"}},
+			{Region: "NG-2", BuildingIDs: []string{"building-3"}},
+		},
+		Enabled: true,
+	}
+
+	t.Run("RegionForBuilding resolves mapped buildings", func(t *testing.T) {
+		region, ok := provider.RegionForBuilding("building-3")
+		assert.True(t, ok)
+		assert.Equal(t, "NG-2", region)
+
+		_, ok = provider.RegionForBuilding("building-9")
+		assert.False(t, ok)
+	})
+
+	t.Run("Secrets are not serialized", func(t *testing.T) {
+		data, err := json.Marshal(provider)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "encrypted-key")
+		assert.NotContains(t, string(data), "encrypted-secret")
+
+		response := provider.ToResponse()
+		assert.True(t, response.HasAPIKey)
+		assert.True(t, response.HasClientSecret)
+		assert.Len(t, response.Regions, 2)
+	})
+}
+
+// TestEnergyCredentialName tests the auth credential key used per provider
+func TestEnergyCredentialName(t *testing.T) {
+	assert.Equal(t, "energy_provider", integrations.EnergyCredentialName(models.DefaultEnergyProviderName))
+	assert.Equal(t, "energy_provider:north_grid", integrations.EnergyCredentialName("north_grid"))
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/events"
+	"shared/signing"
+)
+
+// TestEventTopics tests that every event type is published under the shared prefix
+func TestEventTopics(t *testing.T) {
+	assert.Equal(t, "energy/events/user_deactivated", events.Topic(events.UserDeactivated))
+	assert.Equal(t, "energy/events/scenario_executed", events.Topic(events.ScenarioExecuted))
+	assert.Equal(t, "energy/events/command_approval_requested", events.Topic(events.CommandApprovalRequested))
+}
+
+// TestEventDecode tests decoding typed payloads from the event envelope
+func TestEventDecode(t *testing.T) {
+	payload, err := json.Marshal(&events.UserDeactivatedData{
+		UserID:        "user-1",
+		Username:      "operator",
+		Reason:        "DEACTIVATED",
+		DeactivatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	raw, err := json.Marshal(&events.Event{
+		ID:         "event-1",
+		Type:       events.UserDeactivated,
+		Source:     "security-service",
+		OccurredAt: time.Now(),
+		Data:       payload,
+	})
+	require.NoError(t, err)
+
+	var event events.Event
+	require.NoError(t, json.Unmarshal(raw, &event))
+	assert.Equal(t, events.UserDeactivated, event.Type)
+
+	var data events.UserDeactivatedData
+	require.NoError(t, event.Decode(&data))
+	assert.Equal(t, "user-1", data.UserID)
+	assert.Equal(t, "DEACTIVATED", data.Reason)
+}
+
+// TestNilBus tests that a disabled event bus silently drops events
+func TestNilBus(t *testing.T) {
+	var bus *events.Bus
+	assert.NotPanics(t, func() {
+		bus.Publish(context.Background(), events.UserDeactivated, &events.UserDeactivatedData{UserID: "user-1"})
+		assert.NoError(t, bus.Subscribe(events.AnomalyDetected, nil))
+		bus.Close()
+	})
+}
+
+// TestEventSignatures tests that signed event messages are only accepted unmodified, on their
+// topic, from the signing service and once
+func TestEventSignatures(t *testing.T) {
+	verifier := signing.NewVerifier(map[string]string{"iot-control-service": "iot-secret"}, "internal-key", false, time.Minute)
+	topic := events.Topic(events.ScenarioExecuted)
+	body := []byte(`{"id":"event-1","type":"scenario_executed","source":"iot-control-service"}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	sig := signing.SignMessage(topic, "iot-control-service", timestamp, "event-1", body, "iot-secret")
+
+	assert.ErrorIs(t, verifier.VerifyMessage(topic, "iot-control-service", timestamp, "event-1", sig, []byte(`{"id":"event-1"}`)), signing.ErrInvalidSignature)
+	assert.ErrorIs(t, verifier.VerifyMessage(events.Topic(events.UserDeactivated), "iot-control-service", timestamp, "event-1", sig, body), signing.ErrInvalidSignature)
+	assert.ErrorIs(t, verifier.VerifyMessage(topic, "forecast-service", timestamp, "event-1", sig, body), signing.ErrInvalidSignature)
+	assert.ErrorIs(t, verifier.VerifyMessage(topic, "iot-control-service", timestamp, "event-1", "", body), signing.ErrMissingSignature)
+
+	require.NoError(t, verifier.VerifyMessage(topic, "iot-control-service", timestamp, "event-1", sig, body))
+	assert.ErrorIs(t, verifier.VerifyMessage(topic, "iot-control-service", timestamp, "event-1", sig, body), signing.ErrReplayed)
+
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	staleSig := signing.SignMessage(topic, "iot-control-service", stale, "event-2", body, "iot-secret")
+	assert.ErrorIs(t, verifier.VerifyMessage(topic, "iot-control-service", stale, "event-2", staleSig, body), signing.ErrExpired)
+
+	assert.True(t, events.Sources["iot-control-service"])
+	assert.False(t, events.Sources["unknown-service"])
+}
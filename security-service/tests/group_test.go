@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/models"
+	"security-service/pkg/utils"
+)
+
+// TestResolveGroups tests resolving nested group memberships and inherited roles
+func TestResolveGroups(t *testing.T) {
+	groups := map[string]*models.Group{
+		"staff":       {Name: "staff", Roles: []string{"user"}},
+		"engineering": {Name: "engineering", Roles: []string{"engineer"}, ParentGroups: []string{"staff"}},
+		"facilities":  {Name: "facilities", Roles: []string{"building_manager"}, ParentGroups: []string{"staff"}},
+		"hvac":        {Name: "hvac", Roles: []string{"operator"}, ParentGroups: []string{"engineering", "facilities"}},
+	}
+
+	t.Run("Direct group only", func(t *testing.T) {
+		names, roles := models.ResolveGroups([]string{"staff"}, groups)
+		assert.Equal(t, []string{"staff"}, names)
+		assert.Equal(t, []string{"user"}, roles)
+	})
+
+	t.Run("Nested groups inherit parent roles", func(t *testing.T) {
+		names, roles := models.ResolveGroups([]string{"hvac"}, groups)
+		assert.Equal(t, []string{"engineering", "facilities", "hvac", "staff"}, names)
+		assert.Equal(t, []string{"building_manager", "engineer", "operator", "user"}, roles)
+	})
+
+	t.Run("Unknown groups are skipped", func(t *testing.T) {
+		names, roles := models.ResolveGroups([]string{"deleted", "staff"}, groups)
+		assert.Equal(t, []string{"staff"}, names)
+		assert.Equal(t, []string{"user"}, roles)
+	})
+
+	t.Run("Cycles are visited once", func(t *testing.T) {
+		cyclic := map[string]*models.Group{
+			"a": {Name: "a", Roles: []string{"role_a"}, ParentGroups: []string{"b"}},
+			"b": {Name: "b", Roles: []string{"role_b"}, ParentGroups: []string{"a"}},
+		}
+
+		names, roles := models.ResolveGroups([]string{"a"}, cyclic)
+		assert.Equal(t, []string{"a", "b"}, names)
+		assert.Equal(t, []string{"role_a", "role_b"}, roles)
+	})
+
+	t.Run("No groups", func(t *testing.T) {
+		names, roles := models.ResolveGroups(nil, groups)
+		assert.Empty(t, names)
+		assert.Empty(t, roles)
+	})
+}
+
+// TestEffectiveRoles tests merging direct and group roles
+func TestEffectiveRoles(t *testing.T) {
+	roles := models.EffectiveRoles([]string{"user", "admin"}, []string{"operator", "user"})
+	assert.Equal(t, []string{"admin", "operator", "user"}, roles)
+
+	assert.Empty(t, models.EffectiveRoles(nil, nil))
+}
+
+// TestGroupResponse tests converting a group to its API response
+func TestGroupResponse(t *testing.T) {
+	group := &models.Group{
+		Name:         "hvac",
+		Description:  "HVAC technicians",
+		Roles:        []string{"operator"},
+		ParentGroups: []string{"engineering"},
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	response := group.ToResponse([]string{"engineer", "operator"}, 3)
+	assert.Equal(t, "hvac", response.Name)
+	assert.Equal(t, []string{"operator"}, response.Roles)
+	assert.Equal(t, []string{"engineer", "operator"}, response.EffectiveRoles)
+	assert.Equal(t, int64(3), response.MemberCount)
+
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Contains(t, decoded, "parentGroups")
+	assert.Contains(t, decoded, "effectiveRoles")
+	assert.Contains(t, decoded, "memberCount")
+}
+
+// TestGroupClaims tests that resolved groups are carried in access tokens
+func TestGroupClaims(t *testing.T) {
+	jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+
+	user := &models.User{
+		Username: "technician",
+		Roles:    []string{"operator", "user"},
+		Groups:   []string{"hvac", "staff"},
+	}
+	user.ID = [12]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+
+	token, err := jwtManager.GenerateAccessToken(user)
+	require.NoError(t, err)
+
+	claims, err := jwtManager.ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, user.Roles, claims.Roles)
+	assert.Equal(t, user.Groups, claims.Groups)
+
+	t.Run("Users without groups omit the claim", func(t *testing.T) {
+		user.Groups = nil
+		token, err := jwtManager.GenerateAccessToken(user)
+		require.NoError(t, err)
+
+		claims, err := jwtManager.ValidateAccessToken(token)
+		require.NoError(t, err)
+		assert.Empty(t, claims.Groups)
+	})
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// TestHealthService tests dependency health aggregation
+func TestHealthService(t *testing.T) {
+	healthy := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	t.Run("All components healthy", func(t *testing.T) {
+		svc := service.NewHealthService("security-service")
+		svc.Register("mongodb", true, healthy)
+		svc.Register("notifications", false, healthy)
+
+		health := svc.Check(context.Background())
+		assert.Equal(t, models.HealthStatusHealthy, health.Status)
+		assert.Equal(t, "security-service", health.Service)
+		assert.Len(t, health.Components, 2)
+	})
+
+	t.Run("Non-critical failure degrades", func(t *testing.T) {
+		svc := service.NewHealthService("security-service")
+		svc.Register("mongodb", true, healthy)
+		svc.Register("notifications", false, failing)
+
+		health := svc.Check(context.Background())
+		assert.Equal(t, models.HealthStatusDegraded, health.Status)
+
+		component, ok := health.Components["notifications"]
+		require.True(t, ok)
+		assert.Equal(t, models.HealthStatusUnhealthy, component.Status)
+		assert.Equal(t, "connection refused", component.Error)
+	})
+
+	t.Run("Critical failure is unhealthy", func(t *testing.T) {
+		svc := service.NewHealthService("security-service")
+		svc.Register("mongodb", true, failing)
+		svc.Register("notifications", false, failing)
+
+		health := svc.Check(context.Background())
+		assert.Equal(t, models.HealthStatusUnhealthy, health.Status)
+		assert.True(t, health.Components["mongodb"].Critical)
+	})
+	t.Run("Degraded critical component degrades", func(t *testing.T) {
+		degraded := func(ctx context.Context) error {
+			return &models.DegradedError{Reason: "MongoDB members unreachable: mongo-2:27017"}
+		}
+
+		svc := service.NewHealthService("security-service")
+		svc.Register("mongodb", true, degraded)
+
+		health := svc.Check(context.Background())
+		assert.Equal(t, models.HealthStatusDegraded, health.Status)
+		assert.Equal(t, models.HealthStatusDegraded, health.Components["mongodb"].Status)
+		assert.Equal(t, "MongoDB members unreachable: mongo-2:27017", health.Components["mongodb"].Error)
+	})
+}
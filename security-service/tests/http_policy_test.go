@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/config"
+	"security-service/internal/middleware"
+)
+
+// TestHTTPPolicyDefaults tests the relaxed development and strict release defaults
+func TestHTTPPolicyDefaults(t *testing.T) {
+	t.Run("Development", func(t *testing.T) {
+		t.Setenv("GIN_MODE", "debug")
+		cfg := config.Load()
+		assert.Equal(t, []string{"*"}, cfg.HTTP.CORS.AllowedOrigins)
+		assert.Zero(t, cfg.HTTP.Headers.HSTSMaxAge)
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		t.Setenv("GIN_MODE", "release")
+		cfg := config.Load()
+		assert.Empty(t, cfg.HTTP.CORS.AllowedOrigins)
+		assert.Equal(t, 365*24*time.Hour, cfg.HTTP.Headers.HSTSMaxAge)
+		assert.Contains(t, cfg.HTTP.Headers.ContentSecurityPolicy, "default-src 'none'")
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("Release with wildcard origin", func(t *testing.T) {
+		t.Setenv("GIN_MODE", "release")
+		t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+		assert.Error(t, config.Load().Validate())
+	})
+
+	t.Run("Release with explicit origins", func(t *testing.T) {
+		t.Setenv("GIN_MODE", "release")
+		t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com, https://admin.example.com")
+		cfg := config.Load()
+		assert.Equal(t, []string{"https://app.example.com", "https://admin.example.com"}, cfg.HTTP.CORS.AllowedOrigins)
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+// TestHTTPPolicyValidation tests the policy checks run at startup
+func TestHTTPPolicyValidation(t *testing.T) {
+	valid := func() config.HTTPConfig {
+		return config.HTTPConfig{
+			CORS: config.CORSConfig{
+				AllowedOrigins: []string{"https://app.example.com"},
+				AllowedMethods: []string{"GET", "POST"},
+			},
+			Headers: config.SecurityHeadersConfig{FrameOptions: "DENY"},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mode   string
+		modify func(*config.HTTPConfig)
+		valid  bool
+	}{
+		{"Explicit origin", "release", func(h *config.HTTPConfig) {}, true},
+		{"Wildcard in development", "debug", func(h *config.HTTPConfig) { h.CORS.AllowedOrigins = []string{"*"} }, true},
+		{"Wildcard in release", "release", func(h *config.HTTPConfig) { h.CORS.AllowedOrigins = []string{"*"} }, false},
+		{"Wildcard with credentials", "debug", func(h *config.HTTPConfig) {
+			h.CORS.AllowedOrigins = []string{"*"}
+			h.CORS.AllowCredentials = true
+		}, false},
+		{"Subdomain wildcard", "debug", func(h *config.HTTPConfig) { h.CORS.AllowedOrigins = []string{"https://*.example.com"} }, false},
+		{"Origin with path", "release", func(h *config.HTTPConfig) { h.CORS.AllowedOrigins = []string{"https://app.example.com/ui"} }, false},
+		{"Origin without scheme", "release", func(h *config.HTTPConfig) { h.CORS.AllowedOrigins = []string{"app.example.com"} }, false},
+		{"Lower-case method", "release", func(h *config.HTTPConfig) { h.CORS.AllowedMethods = []string{"get"} }, false},
+		{"Unknown frame option", "release", func(h *config.HTTPConfig) { h.Headers.FrameOptions = "ALLOW-FROM x" }, false},
+		{"Preload without subdomains", "release", func(h *config.HTTPConfig) {
+			h.Headers.HSTSMaxAge = 365 * 24 * time.Hour
+			h.Headers.HSTSPreload = true
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := valid()
+			tt.modify(&policy)
+			err := policy.Validate(tt.mode)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+// TestCORSMiddleware tests that only allowed origins receive CORS headers
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(policy config.CORSConfig) *gin.Engine {
+		router := gin.New()
+		router.Use(middleware.CORS(policy))
+		router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	request := func(router *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/ping", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Explicit origins", func(t *testing.T) {
+		router := newRouter(config.CORSConfig{
+			AllowedOrigins:   []string{"https://app.example.com"},
+			AllowedMethods:   []string{"GET", "POST"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		})
+
+		w := request(router, http.MethodGet, "https://app.example.com")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+		assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+		w = request(router, http.MethodGet, "https://evil.example.com")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+
+		w = request(router, http.MethodOptions, "https://app.example.com")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Any origin", func(t *testing.T) {
+		router := newRouter(config.CORSConfig{AllowedOrigins: []string{"*"}})
+		w := request(router, http.MethodGet, "https://anywhere.example.com")
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+}
+
+// TestSecurityHeadersMiddleware tests that the configured headers are set and empty ones omitted
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(policy config.SecurityHeadersConfig) http.Header {
+		router := gin.New()
+		router.Use(middleware.SecurityHeaders(policy))
+		router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Header()
+	}
+
+	headers := serve(config.SecurityHeadersConfig{
+		ContentSecurityPolicy: "default-src 'none'",
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           true,
+		FrameOptions:          "SAMEORIGIN",
+		ReferrerPolicy:        "no-referrer",
+	})
+	assert.Equal(t, "max-age=31536000; includeSubDomains; preload", headers.Get("Strict-Transport-Security"))
+	assert.Equal(t, "default-src 'none'", headers.Get("Content-Security-Policy"))
+	assert.Equal(t, "SAMEORIGIN", headers.Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", headers.Get("Referrer-Policy"))
+	assert.Equal(t, "nosniff", headers.Get("X-Content-Type-Options"))
+
+	headers = serve(config.SecurityHeadersConfig{})
+	assert.Empty(t, headers.Get("Strict-Transport-Security"))
+	assert.Empty(t, headers.Get("Content-Security-Policy"))
+	assert.Empty(t, headers.Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", headers.Get("X-Content-Type-Options"))
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/i18n"
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+)
+
+// TestNegotiateLanguage tests the selection of the response language from Accept-Language
+func TestNegotiateLanguage(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-AT,de;q=0.9,en;q=0.8", "de"},
+		{"it,fr;q=0.8,es;q=0.9", "es"},
+		{"fr;q=0,en;q=0.5", "en"},
+		{"ja,zh-CN", "en"},
+		{"*", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.expected, i18n.Negotiate(tt.header))
+		})
+	}
+}
+
+// TestTranslate tests catalog messages, patterns and the English fallback
+func TestTranslate(t *testing.T) {
+	assert.Equal(t, "Ungültiger Anfragetext", i18n.Translate("de", "Invalid request body"))
+	assert.Equal(t, "Benutzer erfolgreich erstellt", i18n.Translate("de", "User created successfully"))
+	assert.Equal(t, "rôle introuvable", i18n.Translate("fr", "role not found"))
+	assert.Equal(t, "Error al obtener: usuarios", i18n.Translate("es", "Failed to retrieve users"))
+	assert.Equal(t, "ungültiges ID-Format (Kiosk-Token)", i18n.Translate("de", "invalid kiosk token ID format"))
+	assert.Equal(t, "Validierung fehlgeschlagen: minLength must be positive", i18n.Translate("de", "validation failed: minLength must be positive"))
+
+	assert.Equal(t, "Invalid request body", i18n.Translate("en", "Invalid request body"))
+	assert.Equal(t, "Invalid request body", i18n.Translate("it", "Invalid request body"))
+	assert.Equal(t, "something unexpected", i18n.Translate("fr", "something unexpected"))
+}
+
+// TestLocalizedResponses tests that the localization middleware translates response messages
+func TestLocalizedResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.Localization())
+	router.GET("/users/:id", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(models.ErrCodeNotFound, "User not found", "id "+c.Param("id")))
+	})
+	router.GET("/profile", func(c *gin.Context) {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{"username": "jane"}, "Profile updated successfully"))
+	})
+	router.GET("/export", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/csv", []byte("message\nUser not found\n"))
+	})
+
+	t.Run("error message translated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/users/42", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Language", "de-DE")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "de", w.Header().Get("Content-Language"))
+		var response models.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.NotNil(t, response.Error)
+		assert.Equal(t, "Benutzer nicht gefunden", response.Error.Message)
+		assert.Equal(t, "id 42", response.Error.Details)
+	})
+
+	t.Run("success message translated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/profile", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Language", "fr")
+		router.ServeHTTP(w, req)
+
+		var response models.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "Profil : mise à jour réussie", response.Message)
+	})
+
+	t.Run("english unchanged", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/profile", nil)
+		require.NoError(t, err)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "en", w.Header().Get("Content-Language"))
+		var response models.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "Profile updated successfully", response.Message)
+	})
+
+	t.Run("non-json passes through", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/export", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Language", "es")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "message\nUser not found\n", w.Body.String())
+	})
+}
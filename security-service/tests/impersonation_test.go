@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/pkg/utils"
+)
+
+// recordingAudit collects audit entries written by the auth middleware
+type recordingAudit struct {
+	logs []*models.AuditLog
+}
+
+func (r *recordingAudit) Create(ctx context.Context, log *models.AuditLog) (*models.AuditLog, error) {
+	r.logs = append(r.logs, log)
+	return log, nil
+}
+
+// TestImpersonationToken tests that impersonation details survive the token round-trip
+func TestImpersonationToken(t *testing.T) {
+	jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+	user := &models.User{ID: primitive.NewObjectID(), Username: "operator", Roles: []string{"user"}}
+
+	impersonation := &models.Impersonation{
+		ImpersonatorID:       primitive.NewObjectID().Hex(),
+		ImpersonatorUsername: "admin",
+		Reason:               "Ticket 42",
+		Banner:               "Viewing as operator (impersonated by admin)",
+	}
+
+	token, err := jwtManager.GenerateImpersonationToken(user, nil, impersonation, 5*time.Minute)
+	require.NoError(t, err)
+
+	claims, err := jwtManager.ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID.Hex(), claims.UserID)
+	require.NotNil(t, claims.Impersonation)
+	assert.Equal(t, *impersonation, *claims.Impersonation)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), claims.ExpiresAt.Time, 5*time.Second)
+
+	t.Run("Regular tokens carry no impersonation", func(t *testing.T) {
+		token, err := jwtManager.GenerateAccessToken(user)
+		require.NoError(t, err)
+
+		claims, err := jwtManager.ValidateAccessToken(token)
+		require.NoError(t, err)
+		assert.Nil(t, claims.Impersonation)
+	})
+}
+
+// TestForbidImpersonation tests that restricted routes reject impersonation tokens and that
+// impersonated requests are audited
+func TestForbidImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+	user := &models.User{ID: primitive.NewObjectID(), Username: "operator", Roles: []string{"user"}}
+
+	regular, err := jwtManager.GenerateAccessToken(user)
+	require.NoError(t, err)
+	impersonated, err := jwtManager.GenerateImpersonationToken(user, nil, &models.Impersonation{
+		ImpersonatorID:       primitive.NewObjectID().Hex(),
+		ImpersonatorUsername: "admin",
+		Reason:               "Ticket 42",
+	}, 5*time.Minute)
+	require.NoError(t, err)
+
+	audit := &recordingAudit{}
+	authMiddleware := middleware.NewAuthMiddleware(jwtManager, "", audit)
+	router := gin.New()
+	router.Use(authMiddleware.RequireAuth())
+	router.GET("/profile", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	router.POST("/change-password", authMiddleware.ForbidImpersonation(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		expected int
+	}{
+		{"regular token on restricted route", http.MethodPost, "/change-password", regular, http.StatusOK},
+		{"impersonation token on restricted route", http.MethodPost, "/change-password", impersonated, http.StatusForbidden},
+		{"impersonation token on open route", http.MethodGet, "/profile", impersonated, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+
+	require.Len(t, audit.logs, 2)
+	assert.Equal(t, "IMPERSONATED_REQUEST", audit.logs[0].Action)
+	assert.Equal(t, "FAILURE", audit.logs[0].Status)
+	assert.Equal(t, "/change-password", audit.logs[0].RequestPath)
+	assert.Equal(t, "admin", audit.logs[0].Details["impersonatorUsername"])
+	assert.Equal(t, "SUCCESS", audit.logs[1].Status)
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/models"
+	"security-service/pkg/utils"
+)
+
+// TestSigningKeyRotation tests signing with rotated keys and the grace period for retired keys
+func TestSigningKeyRotation(t *testing.T) {
+	user := &models.User{Username: "testuser", Roles: []string{"user"}}
+	user.ID = [12]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+
+	t.Run("Tokens carry the key ID", func(t *testing.T) {
+		jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+
+		token, err := jwtManager.GenerateAccessToken(user)
+		require.NoError(t, err)
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &utils.CustomClaims{})
+		require.NoError(t, err)
+		assert.Equal(t, utils.DefaultKeyID, parsed.Header["kid"])
+	})
+
+	t.Run("Token without key ID uses the configured key", func(t *testing.T) {
+		jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+
+		claims := utils.CustomClaims{
+			UserID: user.ID.Hex(),
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret-key"))
+		require.NoError(t, err)
+
+		validated, err := jwtManager.ValidateAccessToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID.Hex(), validated.UserID)
+	})
+
+	t.Run("Previous key accepted during grace period", func(t *testing.T) {
+		jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+		oldToken, err := jwtManager.GenerateAccessToken(user)
+		require.NoError(t, err)
+
+		newKey, _, err := utils.GenerateSigningKey("key-2", utils.AlgorithmRS256)
+		require.NoError(t, err)
+		graceUntil := time.Now().Add(time.Hour)
+		previous := utils.NewHMACKey(utils.DefaultKeyID, []byte("test-secret-key"))
+		previous.ExpiresAt = &graceUntil
+		require.NoError(t, jwtManager.SetKeys([]*utils.SigningKey{newKey, previous}, "key-2"))
+
+		_, err = jwtManager.ValidateAccessToken(oldToken)
+		assert.NoError(t, err)
+
+		newToken, err := jwtManager.GenerateAccessToken(user)
+		require.NoError(t, err)
+		parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &utils.CustomClaims{})
+		require.NoError(t, err)
+		assert.Equal(t, "key-2", parsed.Header["kid"])
+		assert.Equal(t, "RS256", parsed.Method.Alg())
+		_, err = jwtManager.ValidateAccessToken(newToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Previous key rejected after grace period", func(t *testing.T) {
+		jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+		oldToken, err := jwtManager.GenerateAccessToken(user)
+		require.NoError(t, err)
+
+		newKey, _, err := utils.GenerateSigningKey("key-2", utils.AlgorithmHS256)
+		require.NoError(t, err)
+		expired := time.Now().Add(-time.Minute)
+		previous := utils.NewHMACKey(utils.DefaultKeyID, []byte("test-secret-key"))
+		previous.ExpiresAt = &expired
+		require.NoError(t, jwtManager.SetKeys([]*utils.SigningKey{newKey, previous}, "key-2"))
+
+		_, err = jwtManager.ValidateAccessToken(oldToken)
+		assert.Error(t, err)
+		assert.Len(t, jwtManager.VerificationKeys(), 1)
+	})
+
+	t.Run("Current key must be usable", func(t *testing.T) {
+		jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+		assert.Error(t, jwtManager.SetKeys([]*utils.SigningKey{}, "missing"))
+		assert.Equal(t, utils.DefaultKeyID, jwtManager.CurrentKey().ID)
+	})
+}
+
+// TestAsymmetricSigningKeys tests key generation, storage round trips and public key export
+func TestAsymmetricSigningKeys(t *testing.T) {
+	for _, algorithm := range []string{utils.AlgorithmRS256, utils.AlgorithmES256} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, material, err := utils.GenerateSigningKey("key-1", algorithm)
+			require.NoError(t, err)
+			assert.True(t, key.IsAsymmetric())
+
+			restored, err := utils.ParseSigningKey("key-1", algorithm, material, nil)
+			require.NoError(t, err)
+
+			jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+			require.NoError(t, jwtManager.SetKeys([]*utils.SigningKey{key}, "key-1"))
+			token, err := jwtManager.GenerateAccessToken(&models.User{Username: "testuser"})
+			require.NoError(t, err)
+
+			// A manager holding the restored key verifies tokens signed before the restart
+			restartedManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+			require.NoError(t, restartedManager.SetKeys([]*utils.SigningKey{restored}, "key-1"))
+			_, err = restartedManager.ValidateAccessToken(token)
+			assert.NoError(t, err)
+
+			publicKey, err := key.VerificationKey()
+			require.NoError(t, err)
+			assert.Contains(t, publicKey, "BEGIN PUBLIC KEY")
+
+			jwk := key.JWK()
+			require.NotNil(t, jwk)
+			assert.Equal(t, "key-1", jwk.Kid)
+			assert.Equal(t, algorithm, jwk.Alg)
+		})
+	}
+
+	t.Run("Unsupported algorithm", func(t *testing.T) {
+		_, _, err := utils.GenerateSigningKey("key-1", "none")
+		assert.Error(t, err)
+	})
+
+	t.Run("Algorithm mismatch", func(t *testing.T) {
+		_, material, err := utils.GenerateSigningKey("key-1", utils.AlgorithmRS256)
+		require.NoError(t, err)
+		_, err = utils.ParseSigningKey("key-1", utils.AlgorithmES256, material, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("HMAC keys have no JWK", func(t *testing.T) {
+		assert.Nil(t, utils.NewHMACKey("key-1", []byte("secret")).JWK())
+	})
+}
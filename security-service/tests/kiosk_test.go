@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/pkg/utils"
+)
+
+// TestKioskToken tests kiosk token claims and expiry
+func TestKioskToken(t *testing.T) {
+	jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+
+	t.Run("Non-expiring token", func(t *testing.T) {
+		kiosk := &models.KioskToken{ID: primitive.NewObjectID(), Name: "Lobby", BuildingID: "building-1"}
+
+		token, err := jwtManager.GenerateKioskToken(kiosk)
+		require.NoError(t, err)
+
+		claims, err := jwtManager.ValidateAccessToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, utils.KioskUserID(kiosk.ID.Hex()), claims.UserID)
+		assert.Equal(t, []string{models.KioskRole}, claims.Roles)
+		assert.Nil(t, claims.ExpiresAt)
+		require.NotNil(t, claims.Kiosk)
+		assert.Equal(t, kiosk.ID.Hex(), claims.Kiosk.TokenID)
+		assert.Equal(t, "building-1", claims.Kiosk.BuildingID)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Hour)
+		kiosk := &models.KioskToken{ID: primitive.NewObjectID(), BuildingID: "building-1", ExpiresAt: &expiresAt}
+
+		token, err := jwtManager.GenerateKioskToken(kiosk)
+		require.NoError(t, err)
+
+		_, err = jwtManager.ValidateAccessToken(token)
+		assert.Error(t, err)
+		assert.False(t, kiosk.IsActive())
+	})
+
+	t.Run("Revoked record", func(t *testing.T) {
+		revokedAt := time.Now()
+		kiosk := &models.KioskToken{RevokedAt: &revokedAt}
+		assert.False(t, kiosk.IsActive())
+		assert.True(t, (&models.KioskToken{}).IsActive())
+	})
+}
+
+// TestKioskTokenRejected tests that security service routes reject kiosk tokens
+func TestKioskTokenRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+	token, err := jwtManager.GenerateKioskToken(&models.KioskToken{ID: primitive.NewObjectID(), BuildingID: "building-1"})
+	require.NoError(t, err)
+
+	authMiddleware := middleware.NewAuthMiddleware(jwtManager, "", nil)
+	router := gin.New()
+	router.GET("/protected", authMiddleware.RequireAuth(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	router.GET("/optional", authMiddleware.OptionalAuth(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"userId": middleware.GetUserID(c)})
+	})
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/protected", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	req, err = http.NewRequest(http.MethodGet, "/optional", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"userId":""}`, w.Body.String())
+}
+
+// TestKioskTokenKeyRotation tests that kiosk tokens outlive the grace period of their signing key
+// while the key is kept for them, without the key verifying other tokens
+func TestKioskTokenKeyRotation(t *testing.T) {
+	jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+	key1, material1, err := utils.GenerateSigningKey("key-1", utils.AlgorithmHS256)
+	require.NoError(t, err)
+	require.NoError(t, jwtManager.SetKeys([]*utils.SigningKey{key1}, "key-1"))
+
+	kiosk := &models.KioskToken{ID: primitive.NewObjectID(), Name: "Lobby", BuildingID: "building-1"}
+	kioskToken, err := jwtManager.GenerateKioskToken(kiosk)
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", kiosk.KeyID)
+	userToken, err := jwtManager.GenerateAccessToken(&models.User{Username: "testuser"})
+	require.NoError(t, err)
+
+	// First rotation: key-1 is within its grace period
+	key2, material2, err := utils.GenerateSigningKey("key-2", utils.AlgorithmHS256)
+	require.NoError(t, err)
+	graceUntil := time.Now().Add(time.Hour)
+	retired1, err := utils.ParseSigningKey("key-1", utils.AlgorithmHS256, material1, &graceUntil)
+	require.NoError(t, err)
+	require.NoError(t, jwtManager.SetKeys([]*utils.SigningKey{key2, retired1}, "key-2"))
+
+	_, err = jwtManager.ValidateAccessToken(kioskToken)
+	assert.NoError(t, err)
+
+	// Second rotation: key-1 is past its grace period
+	key3, _, err := utils.GenerateSigningKey("key-3", utils.AlgorithmHS256)
+	require.NoError(t, err)
+	expired := time.Now().Add(-time.Minute)
+	expired1, err := utils.ParseSigningKey("key-1", utils.AlgorithmHS256, material1, &expired)
+	require.NoError(t, err)
+	retired2, err := utils.ParseSigningKey("key-2", utils.AlgorithmHS256, material2, &graceUntil)
+	require.NoError(t, err)
+
+	t.Run("Expired key rejects the kiosk token", func(t *testing.T) {
+		require.NoError(t, jwtManager.SetKeys([]*utils.SigningKey{key3, retired2, expired1}, "key-3"))
+
+		_, err := jwtManager.ValidateAccessToken(kioskToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("Key kept for kiosk tokens accepts the kiosk token only", func(t *testing.T) {
+		expired1.KioskOnly = true
+		require.NoError(t, jwtManager.SetKeys([]*utils.SigningKey{key3, retired2, expired1}, "key-3"))
+
+		claims, err := jwtManager.ValidateAccessToken(kioskToken)
+		require.NoError(t, err)
+		require.NotNil(t, claims.Kiosk)
+		assert.Equal(t, kiosk.ID.Hex(), claims.Kiosk.TokenID)
+
+		_, err = jwtManager.ValidateAccessToken(userToken)
+		assert.Error(t, err)
+
+		keyIDs := []string{}
+		for _, key := range jwtManager.VerificationKeys() {
+			keyIDs = append(keyIDs, key.ID)
+		}
+		assert.Equal(t, []string{"key-2", "key-3"}, keyIDs)
+	})
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/pkg/utils"
+)
+
+// TestResponseMasks tests that mask rules follow the permissions of the roles
+func TestResponseMasks(t *testing.T) {
+	admin := &models.Role{Name: "admin", Permissions: []models.Permission{{Resource: "*", Actions: []string{"*"}}}}
+	manager := &models.Role{Name: "building_manager", Permissions: []models.Permission{{Resource: "finance", Actions: []string{"read"}}}}
+	user := &models.Role{Name: "user", Permissions: []models.Permission{{Resource: "profile", Actions: []string{"read"}}}}
+
+	assert.Empty(t, models.ResponseMasks([]*models.Role{admin}))
+
+	masks := models.ResponseMasks([]*models.Role{manager})
+	assert.Equal(t, models.MaskModePartial, masks["email"])
+	assert.NotContains(t, masks, "totalCost")
+
+	masks = models.ResponseMasks([]*models.Role{user})
+	assert.Equal(t, models.MaskModePartial, masks["phoneNumber"])
+	assert.Equal(t, models.MaskModeHide, masks["totalCost"])
+
+	assert.Equal(t, masks, models.ResponseMasks(nil))
+}
+
+// TestMaskedResponses tests that the auth middleware masks the fields named in the token
+func TestMaskedResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+	user := &models.User{ID: primitive.NewObjectID(), Username: "operator", Roles: []string{"user"}}
+
+	masked, err := jwtManager.GenerateAccessTokenWithMasks(user, nil, models.ResponseMasks(nil))
+	require.NoError(t, err)
+	unmasked, err := jwtManager.GenerateAccessToken(user)
+	require.NoError(t, err)
+
+	authMiddleware := middleware.NewAuthMiddleware(jwtManager, "", nil)
+	router := gin.New()
+	router.Use(authMiddleware.RequireAuth())
+	router.GET("/users", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"users": []gin.H{
+			{"username": "jane", "email": "jane.doe@example.com", "phoneNumber": "+15551234567"},
+		}})
+	})
+	router.GET("/report", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"energyKWh": 1200, "totalCost": 180.5, "rates": []gin.H{{"name": "PEAK", "cost": 120}}})
+	})
+	router.GET("/export", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/csv", []byte("email\njane.doe@example.com\n"))
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		token    string
+		expected string
+	}{
+		{"pii masked", "/users", masked, `{"users":[{"email":"j***@example.com","phoneNumber":"***4567","username":"jane"}]}`},
+		{"pii unmasked", "/users", unmasked, `{"users":[{"email":"jane.doe@example.com","phoneNumber":"+15551234567","username":"jane"}]}`},
+		{"costs hidden", "/report", masked, `{"energyKWh":1200,"rates":[{"cost":null,"name":"PEAK"}],"totalCost":null}`},
+		{"non-json passes through", "/export", masked, "email\njane.doe@example.com\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, tt.path, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expected, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/internal/service"
+	"security-service/internal/tenant"
+)
+
+func TestSummarizeUsage(t *testing.T) {
+	counters := []*models.UsageCounter{
+		{OrgID: "org-1", BuildingID: "building-1", Date: "2024-06-01", Metric: models.MetricMonitoredDevices, Value: 10},
+		{OrgID: "org-1", BuildingID: "building-2", Date: "2024-06-01", Metric: models.MetricMonitoredDevices, Value: 5},
+		{OrgID: "org-1", BuildingID: "building-1", Date: "2024-06-02", Metric: models.MetricMonitoredDevices, Value: 12},
+		{OrgID: "org-1", BuildingID: "building-1", Date: "2024-06-01", Metric: models.MetricForecastRuns, Value: 3},
+		{OrgID: "org-1", BuildingID: "building-1", Date: "2024-06-02", Metric: models.MetricForecastRuns, Value: 4},
+		{OrgID: "org-1", Date: "2024-06-02", Metric: models.MetricAPICalls, Value: 250},
+	}
+
+	summary := service.SummarizeUsage(counters)
+
+	t.Run("counts are summed and devices are the peak day", func(t *testing.T) {
+		// 15 devices on June 1 across both buildings, 12 on June 2
+		assert.Equal(t, int64(15), summary.Totals[models.MetricMonitoredDevices])
+		assert.Equal(t, int64(7), summary.Totals[models.MetricForecastRuns])
+		assert.Equal(t, int64(250), summary.Totals[models.MetricAPICalls])
+		assert.Equal(t, int64(0), summary.Totals[models.MetricScenarioExecutions])
+	})
+
+	t.Run("per day", func(t *testing.T) {
+		require.Len(t, summary.Daily, 2)
+		assert.Equal(t, "2024-06-01", summary.Daily[0].Date)
+		assert.Equal(t, int64(15), summary.Daily[0].Metrics[models.MetricMonitoredDevices])
+		assert.Equal(t, "2024-06-02", summary.Daily[1].Date)
+		assert.Equal(t, int64(250), summary.Daily[1].Metrics[models.MetricAPICalls])
+	})
+
+	t.Run("per building", func(t *testing.T) {
+		require.Len(t, summary.Buildings, 3)
+		assert.Equal(t, "", summary.Buildings[0].BuildingID, "usage not tied to a building comes first")
+		assert.Equal(t, int64(250), summary.Buildings[0].Metrics[models.MetricAPICalls])
+		assert.Equal(t, "building-1", summary.Buildings[1].BuildingID)
+		assert.Equal(t, int64(12), summary.Buildings[1].Metrics[models.MetricMonitoredDevices])
+		assert.Equal(t, int64(7), summary.Buildings[1].Metrics[models.MetricForecastRuns])
+		assert.Equal(t, int64(5), summary.Buildings[2].Metrics[models.MetricMonitoredDevices])
+	})
+
+	t.Run("no usage", func(t *testing.T) {
+		empty := service.SummarizeUsage(nil)
+		assert.Len(t, empty.Totals, len(models.UsageMetrics))
+		assert.Empty(t, empty.Daily)
+		assert.Empty(t, empty.Buildings)
+	})
+}
+
+func TestUsageLimits(t *testing.T) {
+	t.Run("status of each limit in metric order", func(t *testing.T) {
+		limits := map[string]int64{models.MetricAPICalls: 1000, models.MetricMonitoredDevices: 40}
+		totals := map[string]int64{models.MetricAPICalls: 875, models.MetricMonitoredDevices: 10, models.MetricForecastRuns: 12}
+
+		statuses := service.UsageLimitStatuses(limits, totals, "2024-06")
+		require.Len(t, statuses, 2)
+		assert.Equal(t, models.MetricMonitoredDevices, statuses[0].Metric)
+		assert.Equal(t, 25.0, statuses[0].Percent)
+		assert.Equal(t, models.MetricAPICalls, statuses[1].Metric)
+		assert.Equal(t, int64(875), statuses[1].Used)
+		assert.Equal(t, 87.5, statuses[1].Percent)
+		assert.Equal(t, "2024-06", statuses[1].Month)
+	})
+
+	t.Run("crossed thresholds", func(t *testing.T) {
+		thresholds := []int{80, 100}
+		assert.Empty(t, service.CrossedUsageThresholds(799, 1000, thresholds))
+		assert.Equal(t, []int{80}, service.CrossedUsageThresholds(800, 1000, thresholds))
+		assert.Equal(t, []int{80, 100}, service.CrossedUsageThresholds(1200, 1000, thresholds))
+		assert.Empty(t, service.CrossedUsageThresholds(50, 0, thresholds), "no limit means no alert")
+	})
+}
+
+func TestSnapshotDays(t *testing.T) {
+	t.Run("yesterday is complete after the grace period", func(t *testing.T) {
+		days := service.SnapshotDays(time.Date(2024, 6, 10, 2, 0, 0, 0, time.UTC))
+		require.Len(t, days, 7)
+		assert.Equal(t, "2024-06-09", days[6].Format(models.UsageDateLayout))
+		assert.Equal(t, "2024-06-03", days[0].Format(models.UsageDateLayout))
+	})
+
+	t.Run("yesterday waits for late usage reports", func(t *testing.T) {
+		days := service.SnapshotDays(time.Date(2024, 6, 10, 0, 30, 0, 0, time.UTC))
+		assert.Equal(t, "2024-06-08", days[len(days)-1].Format(models.UsageDateLayout))
+	})
+}
+
+func TestUsageMeter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	meter := middleware.NewUsageMeter()
+
+	engine := gin.New()
+	engine.Use(meter.Middleware())
+	authenticated := func(c *gin.Context) {
+		c.Set("userID", "user-1")
+		c.Request = c.Request.WithContext(tenant.WithScope(c.Request.Context(), &models.TenantScope{OrgID: "org-1", BuildingIDs: []string{"building-1"}}))
+	}
+	engine.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/buildings/:buildingId", authenticated, func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/reports", authenticated, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/health", "/buildings/building-1", "/buildings/building-1", "/reports?buildingId=building-1", "/reports"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	counts := meter.Flush()
+	sort.Slice(counts, func(i, j int) bool { return counts[i].BuildingID < counts[j].BuildingID })
+	require.Len(t, counts, 2, "requests without a user are not metered")
+	assert.Equal(t, "org-1", counts[0].OrgID)
+	assert.Equal(t, "", counts[0].BuildingID)
+	assert.Equal(t, int64(1), counts[0].Count)
+	assert.Equal(t, "building-1", counts[1].BuildingID)
+	assert.Equal(t, int64(3), counts[1].Count)
+
+	assert.Empty(t, meter.Flush(), "flushing resets the meter")
+}
@@ -0,0 +1,528 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/config"
+	"security-service/internal/integrations"
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// stubProvider is a notification provider with a fixed outcome
+type stubProvider struct {
+	name  string
+	types []models.NotificationType
+	err   error
+	sent  int
+}
+
+func (p *stubProvider) Name() string                     { return p.name }
+func (p *stubProvider) Types() []models.NotificationType { return p.types }
+func (p *stubProvider) HealthCheck(ctx context.Context) error {
+	return p.err
+}
+func (p *stubProvider) Send(ctx context.Context, msg *integrations.NotificationMessage) error {
+	p.sent++
+	return p.err
+}
+
+// newTestNotificationClient creates a client whose gateway points at the given server
+func newTestNotificationClient(gatewayURL string) *integrations.NotificationClient {
+	return integrations.NewNotificationClient(&config.Config{
+		Notification: config.NotificationConfig{
+			EmailURL:           gatewayURL,
+			SMSURL:             gatewayURL,
+			PushURL:            gatewayURL,
+			EmailProvider:      "gateway",
+			SMSProvider:        "gateway",
+			PushProvider:       "gateway",
+			HealthCheckTimeout: time.Second,
+		},
+	})
+}
+
+// TestNotificationClientRouting tests provider selection and failover
+func TestNotificationClientRouting(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer gateway.Close()
+
+	email := []models.NotificationType{models.NotificationTypeEmail}
+	msg := &integrations.NotificationMessage{Type: models.NotificationTypeEmail, Recipient: "user@example.com", Subject: "Hi", Body: "Hello"}
+
+	t.Run("Primary provider delivers", func(t *testing.T) {
+		client := newTestNotificationClient(gateway.URL)
+		primary := &stubProvider{name: "smtp", types: email}
+		client.RegisterProvider(primary)
+		client.SetRoute(models.NotificationTypeEmail, "smtp", "gateway")
+
+		provider, err := client.Send(context.Background(), msg)
+		require.NoError(t, err)
+		assert.Equal(t, "smtp", provider)
+		assert.Equal(t, 1, primary.sent)
+	})
+
+	t.Run("Fails over to secondary provider", func(t *testing.T) {
+		client := newTestNotificationClient(gateway.URL)
+		primary := &stubProvider{name: "smtp", types: email, err: errors.New("connection refused")}
+		secondary := &stubProvider{name: "sendgrid", types: email}
+		client.RegisterProvider(primary)
+		client.RegisterProvider(secondary)
+		client.SetRoute(models.NotificationTypeEmail, "smtp", "sendgrid")
+
+		provider, err := client.Send(context.Background(), msg)
+		require.NoError(t, err)
+		assert.Equal(t, "sendgrid", provider)
+		assert.Equal(t, 1, primary.sent)
+		assert.Equal(t, 1, secondary.sent)
+	})
+
+	t.Run("Reports error when both providers fail", func(t *testing.T) {
+		client := newTestNotificationClient(gateway.URL)
+		client.RegisterProvider(&stubProvider{name: "smtp", types: email, err: errors.New("connection refused")})
+		client.SetRoute(models.NotificationTypeEmail, "smtp", "gateway")
+
+		_, err := client.Send(context.Background(), msg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "connection refused")
+	})
+
+	t.Run("Unsupported provider falls back to gateway", func(t *testing.T) {
+		client := newTestNotificationClient(gateway.URL)
+		client.RegisterProvider(&stubProvider{name: "twilio", types: []models.NotificationType{models.NotificationTypeSMS}})
+		client.SetRoute(models.NotificationTypeEmail, "twilio", "")
+
+		status := client.ProvidersStatus(context.Background())
+		for _, route := range status.Routes {
+			if route.Type == models.NotificationTypeEmail {
+				assert.Equal(t, "gateway", route.Primary)
+			}
+		}
+	})
+
+	t.Run("Health status records last failure", func(t *testing.T) {
+		client := newTestNotificationClient(gateway.URL)
+		client.RegisterProvider(&stubProvider{name: "smtp", types: email, err: errors.New("auth failed")})
+		client.SetRoute(models.NotificationTypeEmail, "smtp", "")
+
+		_, err := client.Send(context.Background(), msg)
+		require.Error(t, err)
+
+		status := client.ProvidersStatus(context.Background())
+		require.Len(t, status.Providers, 2)
+		smtp := status.Providers[1]
+		assert.Equal(t, "smtp", smtp.Name)
+		assert.False(t, smtp.Healthy)
+		require.NotNil(t, smtp.LastFailure)
+		assert.Equal(t, "auth failed", smtp.LastFailureMsg)
+	})
+}
+
+// TestSummarizeNotificationDelivery tests delivery counts, failure rates and latency percentiles
+func TestSummarizeNotificationDelivery(t *testing.T) {
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	after := func(ms int) *time.Time {
+		at := base.Add(time.Duration(ms) * time.Millisecond)
+		return &at
+	}
+
+	var notifications []*models.Notification
+	for i := 1; i <= 10; i++ {
+		notifications = append(notifications, &models.Notification{
+			Type:      models.NotificationTypeEmail,
+			Provider:  "smtp",
+			Status:    models.NotificationStatusSent,
+			CreatedAt: base,
+			SentAt:    after(i * 100),
+		})
+	}
+	notifications = append(notifications,
+		&models.Notification{Type: models.NotificationTypeSMS, Provider: "twilio", Status: models.NotificationStatusFailed, CreatedAt: base},
+		&models.Notification{Type: models.NotificationTypeSMS, Provider: "twilio", Status: models.NotificationStatusDelivered, CreatedAt: base, SentAt: after(50), DeliveredAt: after(2000)},
+		&models.Notification{Type: models.NotificationTypePush, Status: models.NotificationStatusPending, CreatedAt: base},
+	)
+
+	stats := service.SummarizeNotificationDelivery(notifications, base, base.Add(time.Hour))
+
+	t.Run("Overall counts", func(t *testing.T) {
+		assert.Equal(t, int64(13), stats.Overall.Total)
+		assert.Equal(t, int64(10), stats.Overall.Sent)
+		assert.Equal(t, int64(1), stats.Overall.Delivered)
+		assert.Equal(t, int64(1), stats.Overall.Failed)
+		assert.Equal(t, int64(1), stats.Overall.Pending)
+		// Pending notifications have not finished sending and do not count towards the rate
+		assert.Equal(t, 8.33, stats.Overall.FailureRatePercent)
+	})
+
+	t.Run("Breakdown per channel", func(t *testing.T) {
+		require.Len(t, stats.ByType, 3)
+		email, push, sms := stats.ByType[0], stats.ByType[1], stats.ByType[2]
+		assert.Equal(t, models.NotificationTypeEmail, email.Type)
+		assert.Equal(t, 0.0, email.FailureRatePercent)
+		assert.Equal(t, models.NotificationTypePush, push.Type)
+		assert.Equal(t, int64(1), push.Pending)
+		assert.Equal(t, models.NotificationTypeSMS, sms.Type)
+		assert.Equal(t, 50.0, sms.FailureRatePercent)
+		assert.Equal(t, 1, sms.TimeToDelivered.Count)
+		assert.Equal(t, int64(2000), sms.TimeToDelivered.P50Ms)
+	})
+
+	t.Run("Breakdown per provider", func(t *testing.T) {
+		require.Len(t, stats.ByProvider, 2)
+		assert.Equal(t, "smtp", stats.ByProvider[0].Provider)
+		assert.Equal(t, int64(10), stats.ByProvider[0].Total)
+		assert.Equal(t, "twilio", stats.ByProvider[1].Provider)
+		assert.Equal(t, int64(1), stats.ByProvider[1].Failed)
+	})
+
+	t.Run("Latency percentiles", func(t *testing.T) {
+		email := stats.ByType[0].TimeToSent
+		assert.Equal(t, 10, email.Count)
+		assert.Equal(t, int64(500), email.P50Ms)
+		assert.Equal(t, int64(900), email.P90Ms)
+		assert.Equal(t, int64(1000), email.P95Ms)
+		assert.Equal(t, int64(1000), email.P99Ms)
+		assert.Equal(t, int64(1000), email.MaxMs)
+	})
+
+	t.Run("Empty period", func(t *testing.T) {
+		empty := service.SummarizeNotificationDelivery(nil, base, base.Add(time.Hour))
+		assert.Equal(t, int64(0), empty.Overall.Total)
+		assert.Empty(t, empty.ByType)
+		assert.Equal(t, 0, empty.Overall.TimeToSent.Count)
+	})
+}
+
+// TestNotificationSendRequestValidation tests that only in-app notifications may omit the recipient
+func TestNotificationSendRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/notifications/send", func(c *gin.Context) {
+		var req models.NotificationSendRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"Email with recipient", `{"userId": "user-1", "type": "email", "content": "Hi", "recipient": "user@example.com"}`, http.StatusOK},
+		{"Email without recipient", `{"userId": "user-1", "type": "email", "content": "Hi"}`, http.StatusBadRequest},
+		{"In-app without recipient", `{"userId": "user-1", "type": "in_app", "content": "Hi"}`, http.StatusOK},
+		{"Unknown type", `{"userId": "user-1", "type": "fax", "content": "Hi", "recipient": "123"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/notifications/send", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+// TestNotificationReadState tests that the read time of in-app notifications is serialized only once read
+func TestNotificationReadState(t *testing.T) {
+	unread := &models.Notification{UserID: "user-1", Type: models.NotificationTypeInApp, Status: models.NotificationStatusDelivered}
+	data, err := json.Marshal(unread.ToResponse())
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "readAt")
+
+	readAt := time.Now()
+	unread.ReadAt = &readAt
+	assert.Equal(t, &readAt, unread.ToResponse().ReadAt)
+}
+
+// TestRouteNotification tests that the first matching routing rule chooses the channels of an event
+func TestRouteNotification(t *testing.T) {
+	user := &models.User{Email: "account@example.com", PhoneNumber: "+15550100"}
+	prefs := &models.NotificationPreferences{
+		UserID:           "user-1",
+		EmailEnabled:     true,
+		SMSEnabled:       true,
+		PushEnabled:      false,
+		PushDeviceTokens: []string{"token-1"},
+		RoutingRules: []models.NotificationRoutingRule{
+			{Category: models.NotificationCategoryAnomaly, MinSeverity: models.NotificationSeverityCritical, Channels: []models.NotificationType{models.NotificationTypeSMS, models.NotificationTypePush}},
+			{Category: models.NotificationCategoryAnomaly, Channels: []models.NotificationType{models.NotificationTypeInApp}},
+		},
+	}
+	defaults := []models.NotificationType{models.NotificationTypeEmail, models.NotificationTypeInApp}
+
+	t.Run("Severity selects the first matching rule", func(t *testing.T) {
+		decision := service.RouteNotification(prefs, user, models.NotificationCategoryAnomaly, models.NotificationSeverityCritical, defaults)
+		require.NotNil(t, decision.MatchedRule)
+		assert.Equal(t, 0, *decision.MatchedRule)
+		require.Len(t, decision.Channels, 2)
+		assert.True(t, decision.Channels[0].Deliver)
+		assert.Equal(t, []string{"+15550100"}, decision.Channels[0].Recipients)
+		assert.False(t, decision.Channels[1].Deliver)
+		assert.NotEmpty(t, decision.Channels[1].Reason)
+	})
+
+	t.Run("Lower severity falls through to the next rule", func(t *testing.T) {
+		decision := service.RouteNotification(prefs, user, models.NotificationCategoryAnomaly, models.NotificationSeverityLow, defaults)
+		require.NotNil(t, decision.MatchedRule)
+		assert.Equal(t, 1, *decision.MatchedRule)
+		require.Len(t, decision.Channels, 1)
+		assert.Equal(t, models.NotificationTypeInApp, decision.Channels[0].Type)
+		assert.Equal(t, []string{"user-1"}, decision.Channels[0].Recipients)
+	})
+
+	t.Run("Unmatched category uses the defaults", func(t *testing.T) {
+		decision := service.RouteNotification(prefs, user, models.NotificationCategoryBudget, models.NotificationSeverityHigh, defaults)
+		assert.Nil(t, decision.MatchedRule)
+		require.Len(t, decision.Channels, 2)
+		assert.Equal(t, models.NotificationTypeEmail, decision.Channels[0].Type)
+		assert.Equal(t, []string{"account@example.com"}, decision.Channels[0].Recipients)
+	})
+
+	t.Run("Preference address wins over the account", func(t *testing.T) {
+		withAddress := *prefs
+		withAddress.EmailAddress = "alerts@example.com"
+		decision := service.RouteNotification(&withAddress, user, models.NotificationCategoryBudget, models.NotificationSeverityHigh, defaults)
+		assert.Equal(t, []string{"alerts@example.com"}, decision.Channels[0].Recipients)
+	})
+}
+
+// TestPushTokenRejection tests that providers report tokens the push service rejects
+func TestPushTokenRejection(t *testing.T) {
+	msg := &integrations.NotificationMessage{Type: models.NotificationTypePush, Recipient: "token-1", Subject: "Hi", Body: "Hello"}
+
+	t.Run("FCM unregistered token", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"failure": 1, "results": [{"error": "NotRegistered"}]}`))
+		}))
+		defer server.Close()
+
+		err := integrations.NewFCMProvider(server.Client(), config.FCMConfig{URL: server.URL}).Send(context.Background(), msg)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, integrations.ErrPushTokenRejected)
+	})
+
+	t.Run("FCM transient failure", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"failure": 1, "results": [{"error": "Unavailable"}]}`))
+		}))
+		defer server.Close()
+
+		err := integrations.NewFCMProvider(server.Client(), config.FCMConfig{URL: server.URL}).Send(context.Background(), msg)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, integrations.ErrPushTokenRejected)
+	})
+
+	t.Run("Gateway gone token", func(t *testing.T) {
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		}))
+		defer gateway.Close()
+
+		_, err := newTestNotificationClient(gateway.URL).Send(context.Background(), msg)
+		assert.ErrorIs(t, err, integrations.ErrPushTokenRejected)
+	})
+
+	t.Run("Gateway outage", func(t *testing.T) {
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer gateway.Close()
+
+		_, err := newTestNotificationClient(gateway.URL).Send(context.Background(), msg)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, integrations.ErrPushTokenRejected)
+	})
+}
+
+// TestPushDevices tests registering, pruning and routing to push devices
+func TestPushDevices(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Registering replaces a legacy token and updates a known device", func(t *testing.T) {
+		prefs := &models.NotificationPreferences{UserID: "user-1", PushDeviceTokens: []string{"token-1", "token-2"}}
+
+		device := service.UpsertPushDevice(prefs, &models.PushDeviceRegisterRequest{Token: "token-1", Platform: models.PushPlatformIOS, DeviceName: "iPhone"}, now)
+		assert.True(t, device.Enabled)
+		assert.Equal(t, now, device.RegisteredAt)
+		assert.Equal(t, []string{"token-2"}, prefs.PushDeviceTokens)
+
+		prefs.PushDevices[0].ConsecutiveFailures = 3
+		prefs.PushDevices[0].Enabled = false
+		device = service.UpsertPushDevice(prefs, &models.PushDeviceRegisterRequest{Token: "token-1", Platform: models.PushPlatformIOS, AppVersion: "2.1.0"}, now.Add(time.Hour))
+		require.Len(t, prefs.PushDevices, 1)
+		assert.Equal(t, "2.1.0", device.AppVersion)
+		assert.Equal(t, 0, device.ConsecutiveFailures)
+		assert.True(t, device.Enabled)
+		assert.Equal(t, now, device.RegisteredAt, "re-registering keeps the registration time")
+	})
+
+	t.Run("Pruning records the rejected device", func(t *testing.T) {
+		prefs := &models.NotificationPreferences{
+			UserID:           "user-1",
+			PushDevices:      []models.PushDevice{{Token: "token-1", Platform: models.PushPlatformAndroid, DeviceName: "Pixel"}},
+			PushDeviceTokens: []string{"legacy"},
+		}
+
+		assert.True(t, service.PrunePushDevice(prefs, "token-1", "NotRegistered", now))
+		assert.Empty(t, prefs.PushDevices)
+		require.Len(t, prefs.PrunedPushDevices, 1)
+		assert.Equal(t, "Pixel", prefs.PrunedPushDevices[0].DeviceName)
+
+		assert.True(t, service.PrunePushDevice(prefs, "legacy", "NotRegistered", now.Add(time.Minute)))
+		assert.Empty(t, prefs.PushDeviceTokens)
+		assert.Equal(t, "legacy", prefs.PrunedPushDevices[0].Token, "most recently pruned first")
+
+		assert.False(t, service.PrunePushDevice(prefs, "unknown", "NotRegistered", now))
+	})
+
+	t.Run("Devices receive the events their preferences accept", func(t *testing.T) {
+		prefs := &models.NotificationPreferences{
+			UserID:      "user-1",
+			PushEnabled: true,
+			PushDevices: []models.PushDevice{
+				{Token: "phone", Enabled: true},
+				{Token: "tablet", Enabled: true, Categories: []models.NotificationCategory{models.NotificationCategoryBudget}},
+				{Token: "watch", Enabled: true, MinSeverity: models.NotificationSeverityCritical},
+				{Token: "old", Enabled: false},
+			},
+			PushDeviceTokens: []string{"legacy"},
+		}
+		push := []models.NotificationType{models.NotificationTypePush}
+
+		decision := service.RouteNotification(prefs, nil, models.NotificationCategoryAnomaly, models.NotificationSeverityHigh, push)
+		assert.Equal(t, []string{"phone", "legacy"}, decision.Channels[0].Recipients)
+
+		decision = service.RouteNotification(prefs, nil, models.NotificationCategoryBudget, models.NotificationSeverityCritical, push)
+		assert.Equal(t, []string{"phone", "tablet", "watch", "legacy"}, decision.Channels[0].Recipients)
+
+		onlyWatch := &models.NotificationPreferences{PushEnabled: true, PushDevices: prefs.PushDevices[2:3]}
+		decision = service.RouteNotification(onlyWatch, nil, models.NotificationCategoryAnomaly, models.NotificationSeverityLow, push)
+		assert.False(t, decision.Channels[0].Deliver)
+		assert.Equal(t, "no push device accepts this event", decision.Channels[0].Reason)
+	})
+}
+
+// TestSummarizePushTokenHealth tests the classification of devices in the token health report
+func TestSummarizePushTokenHealth(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	recent := now.AddDate(0, 0, -2)
+	old := now.AddDate(0, 0, -45)
+
+	prefs := []*models.NotificationPreferences{
+		{
+			UserID: "user-1",
+			PushDevices: []models.PushDevice{
+				{Token: "healthy-token", Platform: models.PushPlatformIOS, Enabled: true, RegisteredAt: old, LastSuccessAt: &recent},
+				{Token: "failing-token", Platform: models.PushPlatformAndroid, Enabled: true, RegisteredAt: old, LastSuccessAt: &recent, ConsecutiveFailures: 4, LastError: "fcm delivery failed: Unavailable"},
+				{Token: "stale-token", Platform: models.PushPlatformAndroid, Enabled: true, RegisteredAt: old, LastSuccessAt: &old},
+			},
+			PrunedPushDevices: []models.PrunedPushDevice{
+				{Token: "pruned-token", Reason: "NotRegistered", PrunedAt: recent},
+				{Token: "ancient-token", Reason: "NotRegistered", PrunedAt: old},
+			},
+		},
+		{
+			UserID:           "user-2",
+			PushDeviceTokens: []string{"legacy"},
+			PushDevices: []models.PushDevice{
+				{Token: "new-token", Platform: models.PushPlatformWeb, Enabled: true, RegisteredAt: recent},
+				{Token: "never-token", Platform: models.PushPlatformWeb, Enabled: true, RegisteredAt: old},
+				{Token: "off-token", Platform: models.PushPlatformWeb, Enabled: false, RegisteredAt: old},
+			},
+		},
+	}
+
+	report := service.SummarizePushTokenHealth(prefs, 0, now)
+
+	assert.Equal(t, 30, report.StaleDays)
+	assert.Equal(t, 6, report.Devices)
+	assert.Equal(t, 1, report.LegacyTokens)
+	assert.Equal(t, map[string]int{
+		models.PushDeviceHealthy:  1,
+		models.PushDeviceFailing:  1,
+		models.PushDeviceStale:    2,
+		models.PushDeviceUnused:   1,
+		models.PushDeviceDisabled: 1,
+	}, report.ByStatus)
+	assert.Equal(t, 3, report.ByPlatform[models.PushPlatformWeb])
+
+	require.Len(t, report.Unhealthy, 3)
+	assert.Equal(t, models.PushDeviceFailing, report.Unhealthy[0].Status)
+	assert.Equal(t, "****-token", report.Unhealthy[0].Token, "tokens are masked")
+	assert.Equal(t, "user-1", report.Unhealthy[1].UserID)
+	assert.Equal(t, "user-2", report.Unhealthy[2].UserID)
+
+	assert.Equal(t, 1, report.PrunedRecent)
+	assert.Equal(t, "****-token", report.RecentlyPruned[0].Token)
+}
+
+// TestPushDeviceRequestValidation tests the binding rules of push device requests
+func TestPushDeviceRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/register", func(c *gin.Context) {
+		var req models.PushDeviceRegisterRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	router.PUT("/preferences", func(c *gin.Context) {
+		var req models.PushDevicePreferencesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"Register", http.MethodPost, "/register", `{"token": "abc", "platform": "android", "categories": ["anomaly"]}`, http.StatusOK},
+		{"Register without platform", http.MethodPost, "/register", `{"token": "abc"}`, http.StatusBadRequest},
+		{"Register unknown platform", http.MethodPost, "/register", `{"token": "abc", "platform": "blackberry"}`, http.StatusBadRequest},
+		{"Register unknown category", http.MethodPost, "/register", `{"token": "abc", "platform": "ios", "categories": ["weather"]}`, http.StatusBadRequest},
+		{"Disable device", http.MethodPut, "/preferences", `{"enabled": false}`, http.StatusOK},
+		{"Clear minimum severity", http.MethodPut, "/preferences", `{"minSeverity": ""}`, http.StatusOK},
+		{"Unknown severity", http.MethodPut, "/preferences", `{"minSeverity": "URGENT"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/models"
+	"security-service/internal/openapi"
+	"security-service/internal/validation"
+)
+
+// reportRequest exercises the enum, range and date range rules
+type reportRequest struct {
+	Name   string    `json:"name" binding:"required,max=20"`
+	Format string    `json:"format" binding:"required,oneof=PDF CSV"`
+	Days   int       `json:"days" binding:"omitempty,min=1,max=31"`
+	From   time.Time `json:"from" binding:"required"`
+	To     time.Time `json:"to" binding:"required,gtfield=From,maxspan=From 720h"`
+}
+
+// reportHandler binds a report request, responding with its field errors
+type reportHandler struct{}
+
+func (h *reportHandler) CreateReport(c *gin.Context) {
+	var req reportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse("Invalid request body", err.Error(), validation.FieldErrors(err)))
+		return
+	}
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(req, ""))
+}
+
+// TestValidationFieldErrors tests that binding errors are reported per JSON field with the rule they broke
+func TestValidationFieldErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	validation.Register()
+
+	router := gin.New()
+	handler := &reportHandler{}
+	router.POST("/reports", handler.CreateReport)
+
+	post := func(body string) (int, *models.APIResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/reports", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response models.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, &response
+	}
+
+	code, response := post(`{"format":"XML","days":40,"from":"2025-01-10T00:00:00Z","to":"2025-03-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, models.ErrCodeValidationFailed, response.Error.Code)
+
+	fields := map[string]models.FieldError{}
+	for _, field := range response.Error.Fields {
+		fields[field.Field] = field
+	}
+	assert.Equal(t, "required", fields["name"].Rule)
+	assert.Equal(t, "oneof", fields["format"].Rule)
+	assert.Equal(t, "must be one of: PDF, CSV", fields["format"].Message)
+	assert.Equal(t, "must be at most 31", fields["days"].Message)
+	assert.Equal(t, "maxspan", fields["to"].Rule)
+
+	code, response = post(`{"name":"Q1","format":"PDF","from":"2025-01-10T00:00:00Z","to":"2025-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	require.Len(t, response.Error.Fields, 1)
+	assert.Equal(t, "to", response.Error.Fields[0].Field)
+	assert.Equal(t, "must be after from", response.Error.Fields[0].Message)
+
+	code, response = post(`{"name":"Q1","format":"PDF","days":"seven"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	require.Len(t, response.Error.Fields, 1)
+	assert.Equal(t, "days", response.Error.Fields[0].Field)
+	assert.Equal(t, "type", response.Error.Fields[0].Rule)
+
+	code, _ = post(`{"name":"Q1","format":"CSV","from":"2025-01-01T00:00:00Z","to":"2025-01-10T00:00:00Z"}`)
+	assert.Equal(t, http.StatusCreated, code)
+}
+
+// TestOpenAPIDocument tests that the document lists every route with the schemas of described operations
+func TestOpenAPIDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	handler := &reportHandler{}
+	router.POST("/api/v1/reports", handler.CreateReport)
+	router.GET("/api/v1/reports/:reportId", func(c *gin.Context) {})
+	router.GET("/health", func(c *gin.Context) {})
+
+	document := openapi.Build(openapi.Info{Title: "Test API", Version: "1.0.0"}, router.Routes(), map[string]openapi.Operation{
+		"reportHandler.CreateReport": {Body: reportRequest{}, Response: reportRequest{}},
+	})
+
+	encoded, err := json.Marshal(document)
+	require.NoError(t, err)
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string                   `json:"operationId"`
+			Summary     string                   `json:"summary"`
+			Tags        []string                 `json:"tags"`
+			Parameters  []map[string]interface{} `json:"parameters"`
+			Security    *[]interface{}           `json:"security"`
+			RequestBody struct {
+				Content map[string]struct {
+					Schema map[string]interface{} `json:"schema"`
+				} `json:"content"`
+			} `json:"requestBody"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string                          `json:"required"`
+				Properties map[string]map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(encoded, &doc))
+
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	require.Contains(t, doc.Paths, "/api/v1/reports")
+	require.Contains(t, doc.Paths, "/api/v1/reports/{reportId}")
+	require.Contains(t, doc.Paths, "/health")
+
+	create := doc.Paths["/api/v1/reports"]["post"]
+	assert.Equal(t, "CreateReport", create.OperationID)
+	assert.Equal(t, "Create report", create.Summary)
+	assert.Equal(t, []string{"reports"}, create.Tags)
+	assert.Nil(t, create.Security)
+	assert.Equal(t, "#/components/schemas/tests.reportRequest", create.RequestBody.Content["application/json"].Schema["$ref"])
+
+	schema := doc.Components.Schemas["tests.reportRequest"]
+	assert.ElementsMatch(t, []string{"name", "format", "from", "to"}, schema.Required)
+	assert.Equal(t, []interface{}{"PDF", "CSV"}, schema.Properties["format"]["enum"])
+	assert.Equal(t, float64(20), schema.Properties["name"]["maxLength"])
+	assert.Equal(t, float64(31), schema.Properties["days"]["maximum"])
+	assert.Equal(t, "date-time", schema.Properties["to"]["format"])
+	assert.Equal(t, "Must be after from and at most 720h after from", schema.Properties["to"]["description"])
+
+	get := doc.Paths["/api/v1/reports/{reportId}"]["get"]
+	require.Len(t, get.Parameters, 1)
+	assert.Equal(t, "reportId", get.Parameters[0]["name"])
+	assert.Equal(t, "path", get.Parameters[0]["in"])
+
+	health := doc.Paths["/health"]["get"]
+	require.NotNil(t, health.Security)
+	assert.Empty(t, *health.Security)
+}
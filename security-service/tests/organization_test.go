@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/internal/tenant"
+	"security-service/pkg/utils"
+)
+
+// TestTenantFilters tests that repository filters are limited to the scoped organization
+func TestTenantFilters(t *testing.T) {
+	unscoped := context.Background()
+	assert.Equal(t, bson.M{"name": "ops"}, tenant.Filter(unscoped, bson.M{"name": "ops"}))
+	assert.Equal(t, bson.M{}, tenant.BuildingFilter(unscoped, bson.M{}, "building_id"))
+	assert.True(t, tenant.AllowsBuilding(unscoped, "building-9"))
+
+	scoped := tenant.WithScope(unscoped, &models.TenantScope{OrgID: "org-1", BuildingIDs: []string{"building-1"}})
+	assert.Equal(t, "org-1", tenant.OrgID(scoped))
+	assert.Equal(t, bson.M{"name": "ops", "org_id": "org-1"}, tenant.Filter(scoped, bson.M{"name": "ops"}))
+	assert.Equal(t, bson.M{"org_id": bson.M{"$in": []interface{}{"org-1", nil}}}, tenant.SharedFilter(scoped, bson.M{}))
+	assert.True(t, tenant.AllowsBuilding(scoped, "building-1"))
+	assert.False(t, tenant.AllowsBuilding(scoped, "building-9"))
+
+	assert.Equal(t, bson.M{"building_id": bson.M{"$in": []string{"building-1"}}}, tenant.BuildingFilter(scoped, bson.M{}, "building_id"))
+	filter := tenant.BuildingFilter(scoped, bson.M{"building_id": "building-9"}, "building_id")
+	assert.Contains(t, filter, "$and")
+
+	empty := tenant.WithScope(unscoped, &models.TenantScope{OrgID: "org-2"})
+	assert.Equal(t, bson.M{"building_id": bson.M{"$in": []string{}}}, tenant.BuildingFilter(empty, bson.M{}, "building_id"))
+}
+
+// TestTenantScopedRequests tests that the auth middleware scopes requests to the organization
+// in the token and that only super admins pass the super admin check
+func TestTenantScopedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour)
+	authMiddleware := middleware.NewAuthMiddleware(jwtManager, "", nil)
+
+	org := &models.Organization{ID: primitive.NewObjectID(), Name: "Acme", BuildingIDs: []string{"building-1"}, Status: models.OrganizationStatusActive}
+	orgAdmin := &models.User{ID: primitive.NewObjectID(), Username: "acme-admin", Roles: []string{"admin"}, OrgID: org.ID.Hex()}
+	superAdmin := &models.User{ID: primitive.NewObjectID(), Username: "operator", Roles: []string{models.RoleSuperAdmin}}
+
+	scopedToken, err := jwtManager.GenerateAccessTokenWithMasks(orgAdmin, org.Scope(), nil)
+	require.NoError(t, err)
+	superToken, err := jwtManager.GenerateAccessTokenWithMasks(superAdmin, nil, nil)
+	require.NoError(t, err)
+
+	claims, err := jwtManager.ValidateAccessToken(scopedToken)
+	require.NoError(t, err)
+	require.NotNil(t, claims.Tenant)
+	assert.Equal(t, org.ID.Hex(), claims.Tenant.OrgID)
+	assert.Equal(t, []string{"building-1"}, claims.Tenant.BuildingIDs)
+
+	router := gin.New()
+	router.GET("/scope", authMiddleware.RequireAuth(), func(c *gin.Context) {
+		c.String(http.StatusOK, tenant.OrgID(c.Request.Context()))
+	})
+	router.GET("/organizations", authMiddleware.RequireAuth(), authMiddleware.RequireSuperAdmin(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/scope", scopedToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, org.ID.Hex(), w.Body.String())
+
+	w = request("/scope", superToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, request("/organizations", scopedToken).Code)
+	assert.Equal(t, http.StatusOK, request("/organizations", superToken).Code)
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/pkg/utils"
+)
+
+// TestFieldEncryption tests versioned PII field encryption, lookup hashes and key rotation
+func TestFieldEncryption(t *testing.T) {
+	encryptor, err := utils.NewFieldEncryptor("1", "first-pii-key", nil, "hash-key")
+	require.NoError(t, err)
+
+	t.Run("Values round trip and are tagged with the key ID", func(t *testing.T) {
+		encrypted, err := encryptor.Encrypt("user@example.com")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(encrypted, "enc:1:"))
+		assert.NotContains(t, encrypted, "user@example.com")
+
+		decrypted, err := encryptor.Decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "user@example.com", decrypted)
+		assert.False(t, encryptor.NeedsRotation(encrypted))
+	})
+
+	t.Run("Plaintext and empty values pass through", func(t *testing.T) {
+		decrypted, err := encryptor.Decrypt("legacy@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "legacy@example.com", decrypted)
+		assert.True(t, encryptor.NeedsRotation("legacy@example.com"))
+
+		encrypted, err := encryptor.Encrypt("")
+		require.NoError(t, err)
+		assert.Empty(t, encrypted)
+		assert.False(t, encryptor.NeedsRotation(""))
+		assert.Empty(t, encryptor.Hash(""))
+	})
+
+	t.Run("Hashes are deterministic and keyed", func(t *testing.T) {
+		first, _ := encryptor.Encrypt("+15550100")
+		second, _ := encryptor.Encrypt("+15550100")
+		assert.NotEqual(t, first, second)
+		assert.Equal(t, encryptor.Hash("+15550100"), encryptor.Hash("+15550100"))
+		assert.NotEqual(t, encryptor.Hash("+15550100"), encryptor.Hash("+15550101"))
+
+		other, err := utils.NewFieldEncryptor("1", "first-pii-key", nil, "other-hash-key")
+		require.NoError(t, err)
+		assert.NotEqual(t, encryptor.Hash("+15550100"), other.Hash("+15550100"))
+	})
+
+	t.Run("Rotated key decrypts values of the retired key", func(t *testing.T) {
+		old, err := encryptor.Encrypt("user@example.com")
+		require.NoError(t, err)
+
+		rotated, err := utils.NewFieldEncryptor("2", "second-pii-key", map[string]string{"1": "first-pii-key"}, "hash-key")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1"}, rotated.RetiredKeyIDs())
+		assert.True(t, rotated.NeedsRotation(old))
+
+		decrypted, err := rotated.Decrypt(old)
+		require.NoError(t, err)
+		assert.Equal(t, "user@example.com", decrypted)
+		// Hashes survive the rotation, so lookups keep working
+		assert.Equal(t, encryptor.Hash("user@example.com"), rotated.Hash("user@example.com"))
+
+		reencrypted, err := rotated.Encrypt(decrypted)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(reencrypted, "enc:2:"))
+		assert.False(t, rotated.NeedsRotation(reencrypted))
+
+		// Once the retired key is removed its values can no longer be read
+		_, err = encryptor.Decrypt(reencrypted)
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid configuration is rejected", func(t *testing.T) {
+		_, err := utils.NewFieldEncryptor("", "key", nil, "hash-key")
+		assert.Error(t, err)
+		_, err = utils.NewFieldEncryptor("a:b", "key", nil, "hash-key")
+		assert.Error(t, err)
+		_, err = utils.NewFieldEncryptor("1", "key", nil, "")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/models"
+)
+
+// TestProfileRequestValidation tests binding rules of the self-service profile requests
+func TestProfileRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.PUT("/auth/profile", func(c *gin.Context) {
+		var req models.ProfileUpdateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	router.POST("/auth/change-password", func(c *gin.Context) {
+		var req models.ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"Valid profile update", "PUT", "/auth/profile", `{"email": "new@example.com", "phoneNumber": "+15551234567"}`, http.StatusOK},
+		{"Invalid email", "PUT", "/auth/profile", `{"email": "not-an-email"}`, http.StatusBadRequest},
+		{"Invalid phone number", "PUT", "/auth/profile", `{"phoneNumber": "555-1234"}`, http.StatusBadRequest},
+		{"Valid password change", "POST", "/auth/change-password", `{"currentPassword": "old-password", "newPassword": "new-password"}`, http.StatusOK},
+		{"Missing current password", "POST", "/auth/change-password", `{"newPassword": "new-password"}`, http.StatusBadRequest},
+		{"New password too short", "POST", "/auth/change-password", `{"currentPassword": "old-password", "newPassword": "short"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+// TestProfileResponse tests the profile response model
+func TestProfileResponse(t *testing.T) {
+	links := models.NewProfileLinks("user-1")
+	assert.Equal(t, "/api/v1/auth/profile", links.Self)
+	assert.Equal(t, "/api/v1/notifications/preferences/user-1", links.NotificationPreferences)
+
+	t.Run("Password change time is not serialized", func(t *testing.T) {
+		changedAt := time.Now()
+		user := models.User{Username: "testuser", PhoneNumber: "+15551234567", PasswordChangedAt: &changedAt}
+
+		data, err := json.Marshal(user)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "passwordChangedAt")
+		assert.Equal(t, "+15551234567", user.ToResponse().PhoneNumber)
+	})
+}
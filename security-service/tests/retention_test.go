@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/service"
+)
+
+// memoryRecords is an in-memory collection of record timestamps for retention tests
+type memoryRecords struct {
+	mu      sync.Mutex
+	records []time.Time
+	cutoffs []time.Time
+}
+
+func (m *memoryRecords) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cutoffs = append(m.cutoffs, before)
+
+	var count int64
+	for _, record := range m.records {
+		if record.Before(before) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryRecords) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cutoffs = append(m.cutoffs, before)
+
+	kept := m.records[:0]
+	for _, record := range m.records {
+		if !record.Before(before) {
+			kept = append(kept, record)
+		}
+	}
+	deleted := int64(len(m.records) - len(kept))
+	m.records = kept
+	return deleted, nil
+}
+
+func newMemoryRecords(ages ...time.Duration) *memoryRecords {
+	m := &memoryRecords{}
+	for _, age := range ages {
+		m.records = append(m.records, time.Now().Add(-age))
+	}
+	return m
+}
+
+const day = 24 * time.Hour
+
+// TestRetentionCutoff tests that records older than the retention period are purged and newer
+// ones are kept
+func TestRetentionCutoff(t *testing.T) {
+	auditLogs := newMemoryRecords(400*day, 366*day, 364*day, day)
+	notifications := newMemoryRecords(100 * day)
+
+	retention := service.NewRetentionService(false, time.Hour)
+	retention.Register("audit_logs", 365*day, auditLogs.CountOlderThan, auditLogs.DeleteOlderThan)
+	retention.Register("notifications", 0, notifications.CountOlderThan, notifications.DeleteOlderThan)
+
+	status := retention.Run(context.Background())
+	require.Len(t, status.Collections, 2)
+
+	audit := status.Collections[0]
+	assert.True(t, audit.Enabled)
+	assert.Equal(t, 365, audit.RetentionDays)
+	assert.Equal(t, int64(2), audit.LastAffected)
+	assert.Equal(t, int64(2), audit.TotalPurged)
+	assert.Len(t, auditLogs.records, 2)
+	require.Len(t, auditLogs.cutoffs, 1)
+	assert.WithinDuration(t, time.Now().Add(-365*day), auditLogs.cutoffs[0], time.Minute)
+
+	// A retention of zero disables purging
+	assert.False(t, status.Collections[1].Enabled)
+	assert.Empty(t, notifications.cutoffs)
+	assert.Len(t, notifications.records, 1)
+
+	// Purged records are not purged again
+	status = retention.Run(context.Background())
+	assert.Zero(t, status.Collections[0].LastAffected)
+	assert.Equal(t, int64(2), status.Collections[0].TotalPurged)
+}
+
+// TestRetentionDryRun tests that a dry run counts the records it would purge without deleting them
+func TestRetentionDryRun(t *testing.T) {
+	auditLogs := newMemoryRecords(400*day, day)
+
+	retention := service.NewRetentionService(true, time.Hour)
+	retention.Register("audit_logs", 365*day, auditLogs.CountOlderThan, auditLogs.DeleteOlderThan)
+
+	status := retention.Run(context.Background())
+	assert.True(t, status.DryRun)
+	assert.Equal(t, int64(1), status.Collections[0].LastAffected)
+	assert.Zero(t, status.Collections[0].TotalPurged)
+	assert.NotNil(t, status.Collections[0].LastRunAt)
+	assert.Len(t, auditLogs.records, 2)
+}
@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"security-service/internal/config"
+	"security-service/internal/integrations"
+	"security-service/internal/middleware"
+	"security-service/internal/models"
+	"security-service/pkg/utils"
+	"shared/signing"
+)
+
+// TestRoleChangePublisher tests role change delivery to subscriber webhooks
+func TestRoleChangePublisher(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	events := make(chan models.RoleChangeEvent, 1)
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event models.RoleChangeEvent
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		received <- r
+		events <- event
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer subscriber.Close()
+
+	cfg := &config.Config{Internal: config.InternalConfig{
+		ServiceKey:         "internal-key",
+		ServiceSecret:      "internal-key",
+		RoleChangeWebhooks: []string{subscriber.URL},
+	}}
+	integrations.NewRoleChangePublisher(cfg).Publish(models.RoleChangeUserRoles, "user-1", "")
+
+	select {
+	case r := <-received:
+		assert.Empty(t, r.Header.Get(integrations.ServiceKeyHeader))
+		assert.Equal(t, integrations.ServiceName, r.Header.Get(signing.ServiceHeader))
+		assert.NotEmpty(t, r.Header.Get(signing.SignatureHeader))
+		event := <-events
+		assert.Equal(t, models.RoleChangeUserRoles, event.Type)
+		assert.Equal(t, "user-1", event.UserID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("role change event was not delivered")
+	}
+
+	t.Run("Nil publisher is a no-op", func(t *testing.T) {
+		var publisher *integrations.RoleChangePublisher
+		assert.NotPanics(t, func() { publisher.Publish(models.RoleChangeRoleDeleted, "", "operator") })
+	})
+}
+
+// TestRequireServiceKey tests the internal request signature guard
+func TestRequireServiceKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Outside dev mode only services with their own secret can sign; the shared key is rejected
+	newRouter := func(serviceKey string, devMode, acceptServiceKey bool) *gin.Engine {
+		authMiddleware := middleware.NewAuthMiddleware(utils.NewJWTManager("secret", time.Minute, time.Hour), serviceKey, nil)
+		authMiddleware.ConfigureServiceAuth(map[string]string{"iot-control-service": "iot-secret"}, devMode, time.Minute, acceptServiceKey)
+		router := gin.New()
+		router.POST("/audit/log", authMiddleware.RequireServiceKey(), func(c *gin.Context) {
+			body, _ := io.ReadAll(c.Request.Body)
+			c.JSON(http.StatusOK, gin.H{"caller": c.GetString("callerService"), "body": string(body)})
+		})
+		return router
+	}
+
+	body := []byte(`{"action":"LOGIN"}`)
+	newRequest := func(t *testing.T) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "/audit/log", bytes.NewReader(body))
+		require.NoError(t, err)
+		return req
+	}
+
+	tests := []struct {
+		name       string
+		configured string
+		devMode    bool
+		service    string
+		secret     string
+		expected   int
+	}{
+		{"shared key in dev mode", "internal-key", true, "forecast-service", "internal-key", http.StatusOK},
+		{"shared key outside dev mode", "internal-key", false, "forecast-service", "internal-key", http.StatusForbidden},
+		{"per-service secret", "internal-key", false, "iot-control-service", "iot-secret", http.StatusOK},
+		{"per-service secret in dev mode", "internal-key", true, "iot-control-service", "iot-secret", http.StatusOK},
+		{"shared key for service with own secret", "internal-key", true, "iot-control-service", "internal-key", http.StatusForbidden},
+		{"wrong key", "internal-key", true, "forecast-service", "other-key", http.StatusForbidden},
+		{"not configured", "", true, "forecast-service", "internal-key", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(t)
+			require.NoError(t, signing.Sign(req, body, tt.service, tt.secret))
+
+			w := httptest.NewRecorder()
+			newRouter(tt.configured, tt.devMode, false).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			if tt.expected == http.StatusOK {
+				assert.Contains(t, w.Body.String(), tt.service)
+				assert.Contains(t, w.Body.String(), "LOGIN", "body should still be readable by the handler")
+			}
+		})
+	}
+
+	t.Run("Unsigned request is rejected", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter("internal-key", true, false).ServeHTTP(w, newRequest(t))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Tampered body is rejected", func(t *testing.T) {
+		req := newRequest(t)
+		require.NoError(t, signing.Sign(req, []byte(`{"action":"LOGOUT"}`), "forecast-service", "internal-key"))
+
+		w := httptest.NewRecorder()
+		newRouter("internal-key", true, false).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Replayed request is rejected", func(t *testing.T) {
+		router := newRouter("internal-key", true, false)
+		req := newRequest(t)
+		require.NoError(t, signing.Sign(req, body, "forecast-service", "internal-key"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		replay := newRequest(t)
+		replay.Header = req.Header.Clone()
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, replay)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Stale request is rejected", func(t *testing.T) {
+		req := newRequest(t)
+		require.NoError(t, signing.Sign(req, body, "forecast-service", "internal-key"))
+		req.Header.Set(signing.TimestampHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+
+		w := httptest.NewRecorder()
+		newRouter("internal-key", true, false).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Service key only accepted when enabled", func(t *testing.T) {
+		for _, accept := range []bool{false, true} {
+			req := newRequest(t)
+			req.Header.Set(integrations.ServiceKeyHeader, "internal-key")
+
+			w := httptest.NewRecorder()
+			newRouter("internal-key", false, accept).ServeHTTP(w, req)
+			if accept {
+				assert.Equal(t, http.StatusOK, w.Code)
+			} else {
+				assert.Equal(t, http.StatusForbidden, w.Code)
+			}
+		}
+	})
+}
+
+// TestSigningTransport tests that clients sign requests so the receiving middleware accepts them
+func TestSigningTransport(t *testing.T) {
+	verifier := signing.NewVerifier(nil, "internal-key", true, time.Minute)
+	var verifyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, verifyErr = verifier.Verify(r, body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := signing.NewClient(time.Second, "analytics-service", "internal-key")
+	resp, err := client.Post(server.URL+"/internal/auth/role-changed?source=test", "application/json", bytes.NewReader([]byte(`{"type":"USER_ROLES"}`)))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.NoError(t, verifyErr)
+}
@@ -200,3 +200,44 @@ func TestDefaultRoles(t *testing.T) {
 		})
 	}
 }
+
+// TestLostGrants tests the permission diff used by role change previews
+func TestLostGrants(t *testing.T) {
+	current := &models.Role{
+		Permissions: []models.Permission{
+			{Resource: "buildings", Actions: []string{"read", "write"}},
+			{Resource: "energy", Actions: []string{"read"}},
+		},
+	}
+
+	t.Run("Removed actions are lost", func(t *testing.T) {
+		lost := models.LostGrants(current, []models.Permission{
+			{Resource: "buildings", Actions: []string{"read"}},
+		}, nil)
+
+		assert.ElementsMatch(t, []models.PermissionGrant{
+			{Resource: "buildings", Action: "write"},
+			{Resource: "energy", Action: "read"},
+		}, lost)
+	})
+
+	t.Run("Grants kept by other roles are not lost", func(t *testing.T) {
+		other := &models.Role{
+			Permissions: []models.Permission{{Resource: "energy", Actions: []string{"*"}}},
+		}
+
+		lost := models.LostGrants(current, []models.Permission{
+			{Resource: "buildings", Actions: []string{"read"}},
+		}, []*models.Role{other})
+
+		assert.Equal(t, []models.PermissionGrant{{Resource: "buildings", Action: "write"}}, lost)
+	})
+
+	t.Run("Wildcard proposal loses nothing", func(t *testing.T) {
+		lost := models.LostGrants(current, []models.Permission{
+			{Resource: "*", Actions: []string{"*"}},
+		}, nil)
+
+		assert.Empty(t, lost)
+	})
+}
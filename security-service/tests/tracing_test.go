@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"security-service/internal/config"
+	"security-service/internal/middleware"
+	"security-service/internal/tracing"
+)
+
+const callerTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// setupTracing records spans in memory, with tracing set up as a disabled service would
+func setupTracing(t *testing.T) *tracetest.InMemoryExporter {
+	_, err := tracing.Init(context.Background(), config.TracingConfig{})
+	require.NoError(t, err)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+// spanAttribute returns the value of a span attribute, or "" when it is not set
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) string {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := setupTracing(t)
+
+	engine := gin.New()
+	engine.Use(middleware.Tracing())
+	engine.GET("/buildings/:buildingId/report", func(c *gin.Context) {
+		c.String(http.StatusOK, tracing.TraceParent(c.Request.Context()))
+	})
+
+	t.Run("continues the caller's trace", func(t *testing.T) {
+		exporter.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/buildings/building-1/report?deviceId=meter-1", nil)
+		req.Header.Set("traceparent", callerTraceParent)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		span := spans[0]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+		assert.Equal(t, "GET /buildings/:buildingId/report", span.Name)
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+		assert.Equal(t, "building-1", spanAttribute(span, tracing.BuildingIDKey))
+		assert.Equal(t, "meter-1", spanAttribute(span, tracing.DeviceIDKey))
+		assert.Equal(t, "200", spanAttribute(span, "http.response.status_code"))
+
+		// Handlers see the request's span, which the services they call continue
+		assert.Contains(t, w.Body.String(), span.SpanContext.SpanID().String())
+	})
+
+	t.Run("starts a trace without a caller", func(t *testing.T) {
+		exporter.Reset()
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/buildings/building-1/report", nil))
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.False(t, spans[0].Parent.IsValid())
+		assert.Equal(t, "", spanAttribute(spans[0], tracing.DeviceIDKey))
+	})
+}
+
+func TestTracingTransport(t *testing.T) {
+	exporter := setupTracing(t)
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	send := func(t *testing.T, ctx context.Context, transport *tracing.Transport) {
+		received = ""
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/internal/users", nil)
+		require.NoError(t, err)
+		resp, err := (&http.Client{Transport: transport}).Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Empty(t, req.Header.Get("traceparent"), "the caller's request is not modified")
+	}
+
+	t.Run("propagates the trace to other services", func(t *testing.T) {
+		exporter.Reset()
+		ctx, parent := tracing.Start(context.Background(), "parent")
+		send(t, ctx, tracing.NewTransport(nil))
+		parent.End()
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+		client := spans[0]
+		assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+		assert.Equal(t, parent.SpanContext().TraceID(), client.SpanContext.TraceID())
+		assert.Equal(t, "204", spanAttribute(client, "http.response.status_code"))
+		assert.Contains(t, received, client.SpanContext.SpanID().String())
+	})
+
+	t.Run("keeps the trace from third parties", func(t *testing.T) {
+		exporter.Reset()
+		ctx, parent := tracing.Start(context.Background(), "parent")
+		send(t, ctx, tracing.NewExternalTransport(nil))
+		parent.End()
+
+		assert.Len(t, exporter.GetSpans(), 2, "the call is still traced")
+		assert.Empty(t, received)
+	})
+
+	t.Run("untraced requests are not traced", func(t *testing.T) {
+		exporter.Reset()
+		send(t, context.Background(), tracing.NewTransport(nil))
+
+		assert.Empty(t, exporter.GetSpans())
+		assert.Empty(t, received)
+	})
+}
+
+func TestTraceParent(t *testing.T) {
+	setupTracing(t)
+
+	assert.Empty(t, tracing.TraceParent(context.Background()))
+
+	ctx := tracing.WithTraceParent(context.Background(), callerTraceParent)
+	assert.Equal(t, callerTraceParent, tracing.TraceParent(ctx))
+
+	ctx = tracing.WithTraceParent(context.Background(), "not-a-traceparent")
+	assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"security-service/internal/models"
+	"security-service/internal/service"
+)
+
+// TestSummarizeUserActivity tests combining users with their audited activity
+func TestSummarizeUserActivity(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	from := now.AddDate(0, 0, -30)
+	longAgo := now.AddDate(-1, 0, 0)
+	recent := now.AddDate(0, 0, -2)
+
+	user := func(username string, createdAt time.Time, lastLogin *time.Time) *models.User {
+		return &models.User{ID: primitive.NewObjectID(), Username: username, IsActive: true, CreatedAt: createdAt, LastLoginAt: lastLogin}
+	}
+	alice := user("alice", longAgo, &recent)
+	bob := user("bob", longAgo, &longAgo)  // signed in last year only
+	carol := user("carol", recent, nil)    // created during the period
+	dave := user("dave", longAgo, &recent) // many failed logins and actions
+	erin := user("erin", longAgo, nil)
+	erin.IsActive = false // disabled accounts are not dormant
+
+	stats := []*models.UserActivityStats{
+		{UserID: alice.ID.Hex(), Actions: 12, Logins: 3, CommandsIssued: 4, ReportsGenerated: 1, LastActivity: recent, IPAddresses: []string{"10.0.0.1", ""}},
+		{UserID: dave.ID.Hex(), Actions: 400, Logins: 2, FailedLogins: 6, Failures: 7, LastActivity: recent, IPAddresses: []string{"10.0.0.2", "10.0.0.3"}},
+		{UserID: carol.ID.Hex(), Actions: 10, LastActivity: recent},
+	}
+
+	summary := service.SummarizeUserActivity([]*models.User{dave, bob, alice, erin, carol}, stats, from, now, now)
+	assert.Equal(t, 5, summary.Users)
+	assert.Equal(t, 3, summary.ActiveUsers)
+	assert.Equal(t, 1, summary.Dormant)
+	assert.Equal(t, 2, summary.Flagged)
+
+	require.Len(t, summary.Entries, 5)
+	byName := map[string]*models.UserActivity{}
+	for i, entry := range summary.Entries {
+		byName[entry.Username] = entry
+		if i > 0 {
+			assert.Less(t, summary.Entries[i-1].Username, entry.Username)
+		}
+	}
+
+	assert.Empty(t, byName["alice"].Flags)
+	assert.Equal(t, 4, byName["alice"].CommandsIssued)
+	assert.Equal(t, 1, byName["alice"].ReportsGenerated)
+	assert.Equal(t, 1, byName["alice"].IPAddresses)
+	require.NotNil(t, byName["alice"].LastActivityAt)
+
+	assert.Equal(t, []string{models.UserActivityFlagDormant}, byName["bob"].Flags)
+	assert.Nil(t, byName["bob"].LastActivityAt)
+	assert.Empty(t, byName["carol"].Flags)
+	assert.Empty(t, byName["erin"].Flags)
+	assert.Equal(t, []string{models.UserActivityFlagFailedLogins, models.UserActivityFlagHighActivity}, byName["dave"].Flags)
+}
+
+// TestSummarizeUserActivityQuietPeriod tests that few actions never count as high activity
+func TestSummarizeUserActivityQuietPeriod(t *testing.T) {
+	now := time.Now()
+	a := &models.User{ID: primitive.NewObjectID(), Username: "a", IsActive: true, CreatedAt: now}
+	b := &models.User{ID: primitive.NewObjectID(), Username: "b", IsActive: true, CreatedAt: now}
+	stats := []*models.UserActivityStats{
+		{UserID: a.ID.Hex(), Actions: 1, LastActivity: now},
+		{UserID: b.ID.Hex(), Actions: 20, LastActivity: now},
+	}
+
+	summary := service.SummarizeUserActivity([]*models.User{a, b}, stats, now.AddDate(0, 0, -7), now, now)
+	assert.Zero(t, summary.Flagged)
+}
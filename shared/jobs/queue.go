@@ -0,0 +1,699 @@
+// Package jobs runs asynchronous work from a queue persisted in MongoDB, so queued and
+// interrupted work survives restarts. It is shared by every service that queues work.
+package jobs
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"shared/signing"
+)
+
+// Status is the lifecycle state of a job
+type Status string
+
+const (
+	StatusPending   Status = "PENDING"
+	StatusRunning   Status = "RUNNING"
+	StatusSucceeded Status = "SUCCEEDED"
+	StatusFailed    Status = "FAILED"
+	StatusCancelled Status = "CANCELLED"
+)
+
+// Defaults for registered job types
+const (
+	defaultTimeout     = 5 * time.Minute
+	defaultMaxAttempts = 3
+	// retryBaseDelay is the wait before the first retry; it doubles with every further attempt
+	retryBaseDelay = 30 * time.Second
+	maxRetryDelay  = 30 * time.Minute
+	// Retention is how long finished jobs are kept
+	Retention = 7 * 24 * time.Hour
+)
+
+// Job is a unit of asynchronous work. Identity is the user who submitted it, for work that calls
+// other services on their behalf.
+type Job struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type        string             `bson:"type" json:"type"`
+	Payload     bson.M             `bson:"payload" json:"payload"`
+	Identity    *Identity          `bson:"identity,omitempty" json:"identity,omitempty"`
+	TraceParent string             `bson:"trace_parent,omitempty" json:"-"`
+	Status      Status             `bson:"status" json:"status"`
+	Attempts    int                `bson:"attempts" json:"attempts"`
+	MaxAttempts int                `bson:"max_attempts" json:"maxAttempts"`
+	RunAt       time.Time          `bson:"run_at" json:"runAt"`
+	LockedBy    string             `bson:"locked_by,omitempty" json:"lockedBy,omitempty"`
+	LockedUntil *time.Time         `bson:"locked_until,omitempty" json:"lockedUntil,omitempty"`
+	LockToken   string             `bson:"lock_token,omitempty" json:"-"`
+	LastError   string             `bson:"last_error,omitempty" json:"lastError,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updatedAt"`
+	StartedAt   *time.Time         `bson:"started_at,omitempty" json:"startedAt,omitempty"`
+	FinishedAt  *time.Time         `bson:"finished_at,omitempty" json:"finishedAt,omitempty"`
+}
+
+// Decode unmarshals the job payload into v
+func (j *Job) Decode(v interface{}) error {
+	data, err := bson.Marshal(j.Payload)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, v)
+}
+
+// Identity is the user a job acts for and the organization they submitted it in. No credentials
+// are stored: the queue signs the identity with the service secret when the job is queued, and the
+// handler exchanges it for a short-lived token of the user each time the job runs, so a job loses
+// access together with its submitter.
+type Identity struct {
+	UserID    string `bson:"user_id" json:"userId"`
+	OrgID     string `bson:"org_id,omitempty" json:"orgId,omitempty"`
+	Signature string `bson:"signature" json:"-"`
+}
+
+// ErrInvalidIdentity is returned for a job that must act for its submitter but carries no identity
+// signed by this service, such as a job queued before identities were stored
+var ErrInvalidIdentity = errors.New("job has no valid submitter identity; submit it again")
+
+// TypeStats counts the jobs of a type by status
+type TypeStats struct {
+	Type      string `json:"type"`
+	Pending   int64  `json:"pending"`
+	Running   int64  `json:"running"`
+	Succeeded int64  `json:"succeeded"`
+	Failed    int64  `json:"failed"`
+	Cancelled int64  `json:"cancelled"`
+}
+
+// Handler runs a job. Returning an error retries the job until its attempts are used up, unless
+// the error is wrapped with Permanent.
+type Handler func(ctx context.Context, job *Job) error
+
+// Options configure a job type
+type Options struct {
+	// Timeout bounds a run and is also the visibility timeout: a job whose worker has not
+	// finished within it, because the process stopped for example, is picked up again
+	Timeout     time.Duration
+	MaxAttempts int
+	// RequiresIdentity marks work done on behalf of the submitter: jobs must be queued with an
+	// identity, and jobs whose identity is missing or does not verify fail without running
+	RequiresIdentity bool
+	// OnFailed is called when a job has failed for good, to record the failure on the work item
+	OnFailed func(ctx context.Context, job *Job, reason string)
+}
+
+type registration struct {
+	handler Handler
+	options Options
+}
+
+// permanentError marks a job error that retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not worth retrying, so the job fails immediately
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Queue stores jobs in a collection and runs them on a pool of workers. Any number of service
+// instances can share a collection; each job is claimed by one worker at a time.
+type Queue struct {
+	collection   *mongo.Collection
+	workerID     string
+	secret       string
+	pollInterval time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]registration
+	wake     chan struct{}
+}
+
+// NewQueue creates a queue on the collection. workerID identifies this instance on claimed jobs,
+// secret signs the identities of submitters and idle workers check for new jobs every pollInterval.
+func NewQueue(collection *mongo.Collection, workerID, secret string, pollInterval time.Duration) *Queue {
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+	return &Queue{
+		collection:   collection,
+		workerID:     fmt.Sprintf("%s-%s", workerID, primitive.NewObjectID().Hex()),
+		secret:       secret,
+		pollInterval: pollInterval,
+		handlers:     make(map[string]registration),
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler of a job type. Register every type before starting the workers.
+func (q *Queue) Register(jobType string, handler Handler, opts Options) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = registration{handler: handler, options: opts}
+}
+
+// Enqueue stores a job of a registered type to run as soon as a worker is free. identity is the
+// user the job acts for, or nil for work of the service itself.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}, identity *Identity) (*Job, error) {
+	q.mu.RLock()
+	reg, ok := q.handlers[jobType]
+	q.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown job type: %s", jobType)
+	}
+	if reg.options.RequiresIdentity && (identity == nil || identity.UserID == "") {
+		return nil, fmt.Errorf("job type %s must be queued on behalf of a user", jobType)
+	}
+	if identity != nil && q.secret == "" {
+		return nil, errors.New("no secret configured to sign job identities")
+	}
+
+	var document bson.M
+	if payload != nil {
+		data, err := bson.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode job payload: %w", err)
+		}
+		if err := bson.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("failed to encode job payload: %w", err)
+		}
+	}
+
+	// The job continues the trace it was queued in
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	// The ID is assigned up front so the identity signature binds it to this job
+	now := time.Now()
+	job := &Job{
+		ID:          primitive.NewObjectID(),
+		Type:        jobType,
+		Payload:     document,
+		TraceParent: carrier.Get("traceparent"),
+		Status:      StatusPending,
+		MaxAttempts: reg.options.MaxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if identity != nil {
+		job.Identity = &Identity{UserID: identity.UserID, OrgID: identity.OrgID}
+		job.Identity.Signature = q.identitySignature(job)
+	}
+
+	if _, err := q.collection.InsertOne(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// identitySignature signs the identity of a job together with its ID and type, so an identity
+// cannot be edited or moved to another job in the collection
+func (q *Queue) identitySignature(job *Job) string {
+	return signing.SignFields(q.secret, job.ID.Hex(), job.Type, job.Identity.UserID, job.Identity.OrgID)
+}
+
+// checkIdentity verifies the identity of a job of a type that acts for its submitter
+func (q *Queue) checkIdentity(job *Job, reg registration) error {
+	if !reg.options.RequiresIdentity {
+		return nil
+	}
+	if job.Identity == nil || job.Identity.UserID == "" || q.secret == "" ||
+		!hmac.Equal([]byte(job.Identity.Signature), []byte(q.identitySignature(job))) {
+		return ErrInvalidIdentity
+	}
+	return nil
+}
+
+// Start runs the worker pool until ctx is cancelled. Jobs still running when ctx is cancelled
+// are picked up again once their visibility timeout expires.
+func (q *Queue) Start(ctx context.Context, workers int) {
+	if workers <= 0 {
+		workers = 1
+	}
+	q.dropStoredTokens(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			q.failExhausted(ctx)
+		}
+	}
+}
+
+// work claims and runs jobs until ctx is cancelled, sleeping while the queue is empty
+func (q *Queue) work(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, reg, err := q.claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job != nil {
+			q.run(ctx, job, reg)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-time.After(q.pollInterval):
+		}
+	}
+}
+
+// claim locks the next due job of any registered type: a pending job whose run time has come,
+// or a running job whose lock expired with attempts left. Every claim gets a token of its own,
+// so a run that outlived its lock cannot record its outcome over the run that reclaimed the job,
+// even when both run in the same instance.
+func (q *Queue) claim(ctx context.Context) (*Job, registration, error) {
+	q.mu.RLock()
+	registrations := make(map[string]registration, len(q.handlers))
+	for jobType, reg := range q.handlers {
+		registrations[jobType] = reg
+	}
+	q.mu.RUnlock()
+
+	for jobType, reg := range registrations {
+		now := time.Now()
+		lockedUntil := now.Add(reg.options.Timeout)
+		filter := bson.M{
+			"type": jobType,
+			"$or": []bson.M{
+				{"status": StatusPending, "run_at": bson.M{"$lte": now}},
+				{
+					"status":       StatusRunning,
+					"locked_until": bson.M{"$lte": now},
+					"$expr":        bson.M{"$lt": []interface{}{"$attempts", "$max_attempts"}},
+				},
+			},
+		}
+		update := bson.M{
+			"$set": bson.M{
+				"status":       StatusRunning,
+				"locked_by":    q.workerID,
+				"locked_until": lockedUntil,
+				"lock_token":   primitive.NewObjectID().Hex(),
+				"started_at":   now,
+				"updated_at":   now,
+			},
+			"$inc": bson.M{"attempts": 1},
+		}
+		opts := options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "run_at", Value: 1}}).
+			SetReturnDocument(options.After)
+
+		var job Job
+		err := q.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return nil, registration{}, err
+		}
+		return &job, reg, nil
+	}
+	return nil, registration{}, nil
+}
+
+// run executes a claimed job and records its outcome. A job queued in a traced request runs in
+// a span of that trace.
+func (q *Queue) run(ctx context.Context, job *Job, reg registration) {
+	runCtx, cancel := context.WithTimeout(ctx, reg.options.Timeout)
+	span := trace.SpanFromContext(runCtx)
+	if job.TraceParent != "" {
+		runCtx = otel.GetTextMapPropagator().Extract(runCtx, propagation.MapCarrier{"traceparent": job.TraceParent})
+		runCtx, span = otel.Tracer("energy-management/jobs").Start(runCtx, "job "+job.Type,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attribute.String("job.id", job.ID.Hex()), attribute.Int("job.attempt", job.Attempts)),
+		)
+	}
+	err := q.checkIdentity(job, reg)
+	if err != nil {
+		err = Permanent(err)
+	} else {
+		err = q.safeRun(runCtx, reg.handler, job)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	cancel()
+
+	// Record the outcome even when the queue is shutting down
+	recordCtx, recordCancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer recordCancel()
+
+	now := time.Now()
+	filter := bson.M{"_id": job.ID, "status": StatusRunning, "lock_token": job.LockToken}
+	unlock := bson.M{"locked_by": "", "locked_until": "", "lock_token": ""}
+
+	if err == nil {
+		q.update(recordCtx, filter, bson.M{
+			"$set":   bson.M{"status": StatusSucceeded, "finished_at": now, "updated_at": now, "last_error": ""},
+			"$unset": unlock,
+		})
+		return
+	}
+
+	if ctx.Err() != nil {
+		// Interrupted by shutdown: leave the job running so it is retried after the lock expires
+		return
+	}
+
+	log.Printf("Job %s (%s) attempt %d failed: %v", job.ID.Hex(), job.Type, job.Attempts, err)
+
+	var permanent *permanentError
+	if job.Attempts < job.MaxAttempts && !errors.As(err, &permanent) {
+		q.update(recordCtx, filter, bson.M{
+			"$set": bson.M{
+				"status":     StatusPending,
+				"run_at":     now.Add(retryDelay(job.Attempts)),
+				"last_error": err.Error(),
+				"updated_at": now,
+			},
+			"$unset": unlock,
+		})
+		return
+	}
+
+	if q.update(recordCtx, filter, bson.M{
+		"$set":   bson.M{"status": StatusFailed, "last_error": err.Error(), "finished_at": now, "updated_at": now},
+		"$unset": unlock,
+	}) && reg.options.OnFailed != nil {
+		reg.options.OnFailed(recordCtx, job, err.Error())
+	}
+}
+
+// safeRun calls a handler, turning a panic into an error
+func (q *Queue) safeRun(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(fmt.Errorf("job panicked: %v", r))
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// update applies an update to a job and reports whether it matched
+func (q *Queue) update(ctx context.Context, filter, update bson.M) bool {
+	result, err := q.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		log.Printf("Failed to update job: %v", err)
+		return false
+	}
+	return result.MatchedCount == 1
+}
+
+// failExhausted fails running jobs whose lock expired after their last attempt
+func (q *Queue) failExhausted(ctx context.Context) {
+	cursor, err := q.collection.Find(ctx, bson.M{
+		"status":       StatusRunning,
+		"locked_until": bson.M{"$lte": time.Now()},
+		"$expr":        bson.M{"$gte": []interface{}{"$attempts", "$max_attempts"}},
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to find timed out jobs: %v", err)
+		}
+		return
+	}
+	var jobs []*Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return
+	}
+
+	const reason = "job timed out"
+	for _, job := range jobs {
+		now := time.Now()
+		if !q.update(ctx, bson.M{"_id": job.ID, "status": StatusRunning, "locked_until": job.LockedUntil}, bson.M{
+			"$set":   bson.M{"status": StatusFailed, "last_error": reason, "finished_at": now, "updated_at": now},
+			"$unset": bson.M{"locked_by": "", "locked_until": "", "lock_token": ""},
+		}) {
+			continue
+		}
+		log.Printf("Job %s (%s) failed: %s", job.ID.Hex(), job.Type, reason)
+
+		q.mu.RLock()
+		reg, ok := q.handlers[job.Type]
+		q.mu.RUnlock()
+		if ok && reg.options.OnFailed != nil {
+			reg.options.OnFailed(ctx, job, reason)
+		}
+	}
+}
+
+// dropStoredTokens removes the bearer tokens that earlier versions stored on jobs. Those jobs have
+// no identity, so jobs acting for their submitter fail instead of running with the token.
+func (q *Queue) dropStoredTokens(ctx context.Context) {
+	_, err := q.collection.UpdateMany(ctx,
+		bson.M{"auth_token": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"auth_token": ""}},
+	)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Failed to remove stored job tokens: %v", err)
+	}
+}
+
+// retryDelay returns the wait before retrying a job that failed its given attempt
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// List retrieves jobs, optionally of one status and type, newest first, with pagination
+func (q *Queue) List(ctx context.Context, status, jobType string, page, limit int) ([]*Job, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	if jobType != "" {
+		filter["type"] = jobType
+	}
+
+	total, err := q.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := q.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []*Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// Get retrieves a job by ID
+func (q *Queue) Get(ctx context.Context, id string) (*Job, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid job ID format")
+	}
+
+	var job Job
+	if err := q.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&job); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("job not found")
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Retry queues a failed or cancelled job again with a fresh set of attempts. A job acting for its
+// submitter keeps their signed identity and is authorized again when it runs; one without a valid
+// identity is rejected with ErrInvalidIdentity.
+func (q *Queue) Retry(ctx context.Context, id string) (*Job, error) {
+	job, err := q.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	q.mu.RLock()
+	reg, ok := q.handlers[job.Type]
+	q.mu.RUnlock()
+	if ok {
+		if err := q.checkIdentity(job, reg); err != nil {
+			return nil, err
+		}
+	}
+
+	return q.transition(ctx, id, []Status{StatusFailed, StatusCancelled}, bson.M{
+		"$set": bson.M{
+			"status":     StatusPending,
+			"attempts":   0,
+			"run_at":     time.Now(),
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{"finished_at": "", "started_at": ""},
+	}, "only failed or cancelled jobs can be retried")
+}
+
+// Cancel stops a pending job from running
+func (q *Queue) Cancel(ctx context.Context, id string) (*Job, error) {
+	return q.transition(ctx, id, []Status{StatusPending}, bson.M{
+		"$set": bson.M{
+			"status":      StatusCancelled,
+			"finished_at": time.Now(),
+			"updated_at":  time.Now(),
+		},
+	}, "only pending jobs can be cancelled")
+}
+
+// transition updates a job that is in one of the given states
+func (q *Queue) transition(ctx context.Context, id string, from []Status, update bson.M, conflict string) (*Job, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid job ID format")
+	}
+
+	var job Job
+	err = q.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objectID, "status": bson.M{"$in": from}},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, err := q.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(conflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status == StatusPending {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	return &job, nil
+}
+
+// Stats counts jobs by type and status
+func (q *Queue) Stats(ctx context.Context) ([]TypeStats, error) {
+	cursor, err := q.collection.Aggregate(ctx, []bson.M{
+		{"$group": bson.M{
+			"_id":   bson.M{"type": "$type", "status": "$status"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"_id.type": 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID struct {
+			Type   string `bson:"type"`
+			Status Status `bson:"status"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	stats := []TypeStats{}
+	index := make(map[string]int)
+	for _, row := range rows {
+		i, ok := index[row.ID.Type]
+		if !ok {
+			i = len(stats)
+			index[row.ID.Type] = i
+			stats = append(stats, TypeStats{Type: row.ID.Type})
+		}
+		switch row.ID.Status {
+		case StatusPending:
+			stats[i].Pending = row.Count
+		case StatusRunning:
+			stats[i].Running = row.Count
+		case StatusSucceeded:
+			stats[i].Succeeded = row.Count
+		case StatusFailed:
+			stats[i].Failed = row.Count
+		case StatusCancelled:
+			stats[i].Cancelled = row.Count
+		}
+	}
+	return stats, nil
+}
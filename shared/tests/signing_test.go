@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"shared/signing"
+)
+
+// signedRequest builds a request signed by service with secret
+func signedRequest(t *testing.T, service, secret string, body []byte) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "/internal/audit?source=test", bytes.NewReader(body))
+	require.NoError(t, err)
+	require.NoError(t, signing.Sign(req, body, service, secret))
+	return req
+}
+
+// TestVerifier_SharedSecret tests that the shared secret only verifies services in dev mode
+func TestVerifier_SharedSecret(t *testing.T) {
+	body := []byte(`{"action":"LOGIN"}`)
+	secrets := map[string]string{"iot-control-service": "iot-secret"}
+
+	tests := []struct {
+		name    string
+		devMode bool
+		service string
+		secret  string
+		want    error
+	}{
+		{"per-service secret", false, "iot-control-service", "iot-secret", nil},
+		{"per-service secret in dev mode", true, "iot-control-service", "iot-secret", nil},
+		{"shared secret outside dev mode", false, "forecast-service", "shared-key", signing.ErrUnknownService},
+		{"shared secret in dev mode", true, "forecast-service", "shared-key", nil},
+		{"shared secret for a service with its own", true, "iot-control-service", "shared-key", signing.ErrInvalidSignature},
+		{"another service's secret", false, "forecast-service", "iot-secret", signing.ErrUnknownService},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := signing.NewVerifier(secrets, "shared-key", tt.devMode, time.Minute)
+			service, err := verifier.Verify(signedRequest(t, tt.service, tt.secret, body), body)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.service, service)
+		})
+	}
+
+	t.Run("no secrets at all", func(t *testing.T) {
+		verifier := signing.NewVerifier(nil, "", true, time.Minute)
+		_, err := verifier.Verify(signedRequest(t, "forecast-service", "shared-key", body), body)
+		assert.ErrorIs(t, err, signing.ErrUnknownService)
+	})
+}
+
+// TestVerifier_Nonces tests that a nonce is rejected while remembered and is scoped to its service
+func TestVerifier_Nonces(t *testing.T) {
+	secrets := map[string]string{"analytics-service": "secret", "forecast-service": "secret"}
+	verifier := signing.NewVerifier(secrets, "", false, time.Minute)
+	body := []byte(`{}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	verify := func(service, nonce string) error {
+		sig := signing.SignMessage("events/device", service, timestamp, nonce, body, "secret")
+		return verifier.VerifyMessage("events/device", service, timestamp, nonce, sig, body)
+	}
+
+	require.NoError(t, verify("analytics-service", "nonce-1"))
+	assert.ErrorIs(t, verify("analytics-service", "nonce-1"), signing.ErrReplayed)
+	assert.NoError(t, verify("forecast-service", "nonce-1"))
+	assert.NoError(t, verify("analytics-service", "nonce-2"))
+}
+
+// TestVerifier_ExpiredNonces tests that nonces outside the window no longer block new requests
+func TestVerifier_ExpiredNonces(t *testing.T) {
+	verifier := signing.NewVerifier(map[string]string{"analytics-service": "secret"}, "", false, time.Second)
+	body := []byte(`{}`)
+
+	verify := func(nonce string) error {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		sig := signing.SignMessage("events/device", "analytics-service", timestamp, nonce, body, "secret")
+		return verifier.VerifyMessage("events/device", "analytics-service", timestamp, nonce, sig, body)
+	}
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, verify(fmt.Sprintf("nonce-%d", i)))
+	}
+
+	// Once the first nonces expired, reusing one is indistinguishable from a fresh request
+	time.Sleep(2100 * time.Millisecond)
+	assert.NoError(t, verify("nonce-0"))
+	assert.ErrorIs(t, verify("nonce-0"), signing.ErrReplayed)
+}